@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/alucardeht/may-la-mcp/internal/config"
+	"github.com/alucardeht/may-la-mcp/internal/daemon"
+	"github.com/alucardeht/may-la-mcp/pkg/protocol"
+)
+
+// errToolFailed marks a tool call the daemon ran and reported as failed,
+// whose message is already printed
+var errToolFailed = errors.New("tool call failed")
+
+// runCLI implements the commands that call the workspace's daemon directly
+// and print the result, for use from scripts without an MCP client:
+//
+//	mayla tool <name> [--json '<args>']
+//	mayla tools [--names]
+//	mayla search <pattern> [path]
+//	mayla symbols <path> [query]
+//	mayla memory <query>
+func runCLI(command string, args []string) int {
+	var name string
+	var arguments map[string]interface{}
+	var namesOnly bool
+	var err error
+
+	switch command {
+	case "tools":
+		fs := flag.NewFlagSet("tools", flag.ContinueOnError)
+		fs.BoolVar(&namesOnly, "names", false, "print only the tool names")
+		err = fs.Parse(args)
+	case "tool":
+		name, arguments, err = parseToolArgs(args)
+	default:
+		name, arguments, err = shortcutArgs(command, args)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", command, err)
+		return 2
+	}
+
+	client, err := dialWorkspaceDaemon()
+	if err != nil {
+		cleanup()
+		fmt.Fprintf(os.Stderr, "%s: %v\n", command, err)
+		return 1
+	}
+	defer cleanup()
+	defer client.Close()
+
+	if command == "tools" {
+		err = listTools(client, namesOnly, os.Stdout)
+	} else {
+		err = callTool(client, name, arguments, os.Stdout)
+	}
+	switch {
+	case errors.Is(err, errToolFailed):
+		return 1
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "%s: %v\n", command, err)
+		return 1
+	}
+	return 0
+}
+
+// parseToolArgs reads `<name> [--json '<args>']`; --json - reads the
+// arguments from stdin
+func parseToolArgs(args []string) (string, map[string]interface{}, error) {
+	if len(args) == 0 || args[0] == "" || args[0][0] == '-' {
+		return "", nil, fmt.Errorf("usage: mayla tool <name> [--json '<args>']")
+	}
+	name := args[0]
+
+	fs := flag.NewFlagSet("tool", flag.ContinueOnError)
+	raw := fs.String("json", "{}", "tool arguments as a JSON object, or - to read them from stdin")
+	if err := fs.Parse(args[1:]); err != nil {
+		return "", nil, err
+	}
+
+	data := []byte(*raw)
+	if *raw == "-" {
+		var err error
+		if data, err = io.ReadAll(os.Stdin); err != nil {
+			return "", nil, err
+		}
+	}
+
+	var arguments map[string]interface{}
+	if err := json.Unmarshal(data, &arguments); err != nil {
+		return "", nil, fmt.Errorf("--json must be a JSON object: %w", err)
+	}
+	return name, arguments, nil
+}
+
+// shortcutArgs maps the friendlier commands to the tool they call. Paths
+// are made absolute since the daemon may run from another directory.
+func shortcutArgs(command string, args []string) (string, map[string]interface{}, error) {
+	optional := func(i int, fallback string) string {
+		if i < len(args) {
+			return args[i]
+		}
+		return fallback
+	}
+
+	switch command {
+	case "search":
+		if len(args) < 1 || len(args) > 2 {
+			return "", nil, fmt.Errorf("usage: mayla search <pattern> [path]")
+		}
+		path, err := filepath.Abs(optional(1, "."))
+		if err != nil {
+			return "", nil, err
+		}
+		return "search", map[string]interface{}{"pattern": args[0], "path": path, "recursive": true}, nil
+	case "symbols":
+		if len(args) < 1 || len(args) > 2 {
+			return "", nil, fmt.Errorf("usage: mayla symbols <path> [query]")
+		}
+		path, err := filepath.Abs(args[0])
+		if err != nil {
+			return "", nil, err
+		}
+		arguments := map[string]interface{}{"path": path}
+		if query := optional(1, ""); query != "" {
+			arguments["query"] = query
+		}
+		return "symbols", arguments, nil
+	case "memory":
+		if len(args) != 1 {
+			return "", nil, fmt.Errorf("usage: mayla memory <query>")
+		}
+		return "memory_search", map[string]interface{}{"query": args[0]}, nil
+	}
+	return "", nil, fmt.Errorf("unknown command %q", command)
+}
+
+// dialWorkspaceDaemon connects to the daemon of the current workspace,
+// starting it when none is running
+func dialWorkspaceDaemon() (*daemon.Client, error) {
+	instanceID = generateInstanceID()
+
+	cfg, err := config.LoadConfigWithInstance(instanceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	instanceDir = cfg.InstanceDir
+	authToken = cfg.AuthToken
+	endpoint, err := daemon.EndpointOf(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	setupCleanupHandlers()
+
+	// keep the daemon's logs out of the output scripts read
+	daemonOutput = io.Discard
+	log.SetOutput(io.Discard)
+	if err := ensureDaemon(endpoint); err != nil {
+		return nil, err
+	}
+
+	conn, err := connectWithRetry(context.Background(), endpoint, 5)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to daemon: %w", err)
+	}
+	client, err := newAuthenticatedClient(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to authenticate with daemon: %w", err)
+	}
+	return client, nil
+}
+
+func callTool(client *daemon.Client, name string, arguments map[string]interface{}, out io.Writer) error {
+	resp, err := client.SendRequest(context.Background(), &protocol.JSONRPCRequest{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  "tools/call",
+		Params:  map[string]interface{}{"name": name, "arguments": arguments},
+	})
+	if err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		return errors.New(resp.Error.Message)
+	}
+
+	var result struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+			URI  string `json:"uri"`
+		} `json:"content"`
+		IsError bool `json:"isError"`
+	}
+	data, err := json.Marshal(resp.Result)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return fmt.Errorf("unexpected tool result: %w", err)
+	}
+
+	if result.IsError {
+		out = os.Stderr
+	}
+	for _, content := range result.Content {
+		switch content.Type {
+		case "text":
+			fmt.Fprintln(out, indentJSON(content.Text))
+		case "resource_link":
+			fmt.Fprintf(out, "Full result: %s\n", content.URI)
+		}
+	}
+	if result.IsError {
+		return errToolFailed
+	}
+	return nil
+}
+
+// indentJSON pretty-prints text that is JSON and returns other text as is
+func indentJSON(text string) string {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(text), "", "  "); err != nil {
+		return text
+	}
+	return buf.String()
+}
+
+// toolInfo is a tool as tools/list describes it
+type toolInfo struct {
+	Name        string          `json:"name"`
+	Title       string          `json:"title"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"inputSchema"`
+}
+
+// fetchTools returns the daemon's tools by name
+func fetchTools(client *daemon.Client) ([]toolInfo, error) {
+	result, err := client.Call("tools/list", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var list struct {
+		Tools []toolInfo `json:"tools"`
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("unexpected tools list: %w", err)
+	}
+
+	sort.Slice(list.Tools, func(i, j int) bool { return list.Tools[i].Name < list.Tools[j].Name })
+	return list.Tools, nil
+}
+
+// listTools prints the tools with their titles, or only their names for
+// the shell completions
+func listTools(client *daemon.Client, namesOnly bool, out io.Writer) error {
+	tools, err := fetchTools(client)
+	if err != nil {
+		return err
+	}
+
+	width := 0
+	for _, tool := range tools {
+		width = max(width, len(tool.Name))
+	}
+	for _, tool := range tools {
+		if namesOnly {
+			fmt.Fprintln(out, tool.Name)
+		} else {
+			fmt.Fprintf(out, "%-*s  %s\n", width, tool.Name, tool.Title)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// cliCommands are the commands the completions offer
+var cliCommands = []struct {
+	name string
+	help string
+}{
+	{"tool", "Call a tool with JSON arguments"},
+	{"tools", "List the available tools"},
+	{"search", "Search file contents under a path"},
+	{"symbols", "List the symbols of a file"},
+	{"memory", "Search memories"},
+	{"repl", "Call tools interactively"},
+	{"completion", "Print a shell completion script"},
+	{"init", "Install May-la and configure MCP clients"},
+	{"self-update", "Update May-la to the latest release"},
+}
+
+var sessionFlags = []string{"--read-only", "--dry-run"}
+
+// runCompletion implements `mayla completion bash|zsh|fish`. Tool names
+// are completed by asking the daemon through `mayla tools --names`.
+func runCompletion(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: mayla completion bash|zsh|fish")
+		return 2
+	}
+
+	var err error
+	switch args[0] {
+	case "bash":
+		err = writeBashCompletion(os.Stdout)
+	case "zsh":
+		err = writeZshCompletion(os.Stdout)
+	case "fish":
+		err = writeFishCompletion(os.Stdout)
+	default:
+		fmt.Fprintf(os.Stderr, "completion: unsupported shell %q (want bash, zsh or fish)\n", args[0])
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "completion: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func commandNames() string {
+	names := make([]string, len(cliCommands))
+	for i, c := range cliCommands {
+		names[i] = c.name
+	}
+	return strings.Join(names, " ")
+}
+
+func writeBashCompletion(w io.Writer) error {
+	_, err := fmt.Fprintf(w, `# bash completion for mayla; load with: source <(mayla completion bash)
+_mayla() {
+    local cur="${COMP_WORDS[COMP_CWORD]}" cmd="" pos=0 i
+    for ((i = 1; i < COMP_CWORD; i++)); do
+        case "${COMP_WORDS[i]}" in
+            -*) ;;
+            *) cmd="${COMP_WORDS[i]}"; pos=$((COMP_CWORD - i)); break ;;
+        esac
+    done
+
+    case "$cmd" in
+        "")
+            COMPREPLY=($(compgen -W "%s %s" -- "$cur")) ;;
+        tool)
+            if [[ $pos -eq 1 ]]; then
+                COMPREPLY=($(compgen -W "$(mayla tools --names 2>/dev/null)" -- "$cur"))
+            else
+                COMPREPLY=($(compgen -W "--json" -- "$cur"))
+            fi ;;
+        tools)
+            COMPREPLY=($(compgen -W "--names" -- "$cur")) ;;
+        completion)
+            COMPREPLY=($(compgen -W "bash zsh fish" -- "$cur")) ;;
+        init)
+            COMPREPLY=($(compgen -W "--dir --client --dry-run" -- "$cur")) ;;
+        search|symbols)
+            COMPREPLY=($(compgen -f -- "$cur")) ;;
+    esac
+}
+complete -o default -F _mayla mayla
+`, commandNames(), strings.Join(sessionFlags, " "))
+	return err
+}
+
+func writeZshCompletion(w io.Writer) error {
+	var commands strings.Builder
+	for _, c := range cliCommands {
+		fmt.Fprintf(&commands, "        '%s:%s'\n", c.name, c.help)
+	}
+
+	_, err := fmt.Fprintf(w, `#compdef mayla
+# zsh completion for mayla; load with: source <(mayla completion zsh)
+
+_mayla() {
+    local -a commands args
+    commands=(
+%s    )
+    args=(${words[2,CURRENT-1]:#-*})
+
+    if (( ${#args} == 0 )); then
+        _describe 'command' commands
+        compadd -- %s
+        return
+    fi
+
+    case $args[1] in
+        tool)
+            if (( ${#args} == 1 )); then
+                compadd -- ${(f)"$(mayla tools --names 2>/dev/null)"}
+            else
+                compadd -- --json
+            fi ;;
+        tools) compadd -- --names ;;
+        completion) compadd bash zsh fish ;;
+        init) compadd -- --dir --client --dry-run ;;
+        search|symbols) _files ;;
+    esac
+}
+
+if [[ $funcstack[1] == _mayla ]]; then
+    _mayla "$@"
+else
+    compdef _mayla mayla
+fi
+`, commands.String(), strings.Join(sessionFlags, " "))
+	return err
+}
+
+func writeFishCompletion(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("# fish completion for mayla; load with: mayla completion fish | source\n")
+	b.WriteString("complete -c mayla -f\n")
+	for _, flag := range sessionFlags {
+		fmt.Fprintf(&b, "complete -c mayla -n __fish_use_subcommand -l %s\n", strings.TrimPrefix(flag, "--"))
+	}
+	for _, c := range cliCommands {
+		fmt.Fprintf(&b, "complete -c mayla -n __fish_use_subcommand -a %s -d '%s'\n", c.name, c.help)
+	}
+	b.WriteString(`complete -c mayla -n '__fish_seen_subcommand_from tool; and test (count (commandline -opc)) -le 2' -a '(mayla tools --names 2>/dev/null)'
+complete -c mayla -n '__fish_seen_subcommand_from tool' -l json -r -d 'Tool arguments as a JSON object'
+complete -c mayla -n '__fish_seen_subcommand_from tools' -l names -d 'Print only the tool names'
+complete -c mayla -n '__fish_seen_subcommand_from completion' -a 'bash zsh fish'
+complete -c mayla -n '__fish_seen_subcommand_from init' -l dir -r -d 'Install directory'
+complete -c mayla -n '__fish_seen_subcommand_from init' -l client -r -d 'Configure only this client'
+complete -c mayla -n '__fish_seen_subcommand_from init' -l dry-run -d 'Show what would change'
+complete -c mayla -n '__fish_seen_subcommand_from search symbols' -F
+`)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
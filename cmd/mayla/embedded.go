@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/alucardeht/may-la-mcp/internal/daemon"
+	"github.com/alucardeht/may-la-mcp/internal/installer"
+)
+
+// isDaemonInvocation reports whether this binary was started as the daemon,
+// either through a copy named mayla-daemon or through `mayla daemon`.
+func isDaemonInvocation() bool {
+	name := strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe")
+	return name == "mayla-daemon"
+}
+
+// runEmbeddedDaemon runs the daemon compiled into mayla. args are the
+// mayla-daemon arguments: <instance-id> [ppid].
+func runEmbeddedDaemon(args []string) int {
+	if len(args) < 1 {
+		fmt.Fprintf(os.Stderr, "Error: instance ID required\n")
+		fmt.Fprintf(os.Stderr, "Usage: %s daemon <instance-id> [ppid]\n", os.Args[0])
+		return 1
+	}
+
+	var parentPID int
+	if len(args) >= 2 {
+		if ppid, err := strconv.Atoi(args[1]); err == nil {
+			parentPID = ppid
+		}
+	}
+
+	if err := daemon.Run(args[0], parentPID); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runStandalone implements `mayla --standalone`: MCP is served over stdio by
+// this process, with no daemon binary, socket or background process.
+func runStandalone() int {
+	instanceID := generateInstanceID()
+	if err := daemon.RunStandalone(instanceID, session, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "standalone: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// daemonCommand returns the command used to launch the daemon. The
+// mayla-daemon binary next to mayla is preferred; when it is missing it is
+// extracted from this binary, and if that is not possible mayla runs itself
+// in daemon mode.
+func daemonCommand(execPath string) (string, []string) {
+	daemonPath := filepath.Join(filepath.Dir(execPath), installer.BinaryName("mayla-daemon"))
+	if _, err := os.Stat(daemonPath); err == nil {
+		return daemonPath, nil
+	}
+
+	if err := installer.ExtractDaemon(execPath, daemonPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Could not install %s (%v); running embedded daemon\n", daemonPath, err)
+		return execPath, []string{"daemon"}
+	}
+
+	fmt.Fprintf(os.Stderr, "Installed %s\n", daemonPath)
+	return daemonPath, nil
+}
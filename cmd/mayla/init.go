@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/alucardeht/may-la-mcp/internal/installer"
+)
+
+// runInit implements `mayla init`: it installs the binaries into ~/.mayla and
+// registers the May-la server in the config of every detected MCP client.
+func runInit(args []string) int {
+	fs := flag.NewFlagSet("init", flag.ContinueOnError)
+	dir := fs.String("dir", "", "install directory (default ~/.mayla)")
+	only := fs.String("client", "", "configure only this client (claude-desktop, cursor, windsurf, gemini)")
+	dryRun := fs.Bool("dry-run", false, "show what would change without writing anything")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	installDir := *dir
+	if installDir == "" {
+		d, err := installer.DefaultDir()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "init: %v\n", err)
+			return 1
+		}
+		installDir = d
+	}
+
+	execPath, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "init: %v\n", err)
+		return 1
+	}
+	if resolved, err := filepath.EvalSymlinks(execPath); err == nil {
+		execPath = resolved
+	}
+
+	if *dryRun {
+		fmt.Printf("Would install binaries from %s to %s\n", filepath.Dir(execPath), installDir)
+	} else {
+		installed, err := installer.InstallBinaries(filepath.Dir(execPath), installDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "init: %v\n", err)
+			return 1
+		}
+		for _, path := range installed {
+			fmt.Printf("Installed %s\n", path)
+		}
+		for _, sub := range []string{"instances", "crashes"} {
+			if err := os.MkdirAll(filepath.Join(installDir, sub), 0700); err != nil {
+				fmt.Fprintf(os.Stderr, "init: %v\n", err)
+				return 1
+			}
+		}
+	}
+
+	command := filepath.Join(installDir, installer.BinaryName("mayla"))
+
+	configured := 0
+	failed := false
+	for _, client := range installer.KnownClients() {
+		if *only != "" && client.Name != *only {
+			continue
+		}
+		if *only == "" && !client.Detected() {
+			continue
+		}
+
+		changed, err := client.Configure(command, *dryRun)
+		switch {
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "init: %s: %v\n", client.Name, err)
+			failed = true
+		case !changed:
+			fmt.Printf("%s: already configured (%s)\n", client.Name, client.ConfigPath)
+		case *dryRun:
+			fmt.Printf("%s: would update %s\n", client.Name, client.ConfigPath)
+		default:
+			fmt.Printf("%s: updated %s\n", client.Name, client.ConfigPath)
+		}
+		configured++
+	}
+
+	if configured == 0 {
+		fmt.Println("No supported MCP clients detected; add this server manually:")
+		fmt.Printf("  command: %s\n", command)
+	} else if !*dryRun {
+		fmt.Println("Restart your MCP clients to load May-la.")
+	}
+
+	if failed {
+		return 1
+	}
+	return 0
+}
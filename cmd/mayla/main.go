@@ -5,6 +5,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -13,13 +14,13 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"runtime"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/alucardeht/may-la-mcp/internal/config"
 	"github.com/alucardeht/may-la-mcp/internal/daemon"
+	"github.com/alucardeht/may-la-mcp/internal/recording"
 	"github.com/alucardeht/may-la-mcp/pkg/protocol"
 )
 
@@ -32,13 +33,43 @@ var (
 	daemonPID   int
 	daemonCmd   *exec.Cmd
 	instanceDir string
+	authToken   string
 	cleanupOnce sync.Once
-	daemonDone  chan struct{}
+	daemonMu    sync.Mutex
+
+	// daemonOutput receives the output of a daemon this process starts
+	daemonOutput io.Writer = os.Stderr
 )
 
 func main() {
+	if isDaemonInvocation() {
+		os.Exit(runEmbeddedDaemon(os.Args[1:]))
+	}
+
+	var args []string
+	session, args = parseSessionFlags(os.Args[1:])
+	recordPath, args := parseRecordFlag(args)
+
+	if len(args) > 0 {
+		switch args[0] {
+		case "daemon":
+			os.Exit(runEmbeddedDaemon(args[1:]))
+		case "--standalone", "-standalone":
+			os.Exit(runStandalone())
+		case "self-update":
+			os.Exit(runSelfUpdate(args[1:]))
+		case "init":
+			os.Exit(runInit(args[1:]))
+		case "tool", "tools", "search", "symbols", "memory":
+			os.Exit(runCLI(args[0], args[1:]))
+		case "repl":
+			os.Exit(runRepl(args[1:]))
+		case "completion":
+			os.Exit(runCompletion(args[1:]))
+		}
+	}
+
 	instanceID = generateInstanceID()
-	daemonDone = make(chan struct{})
 
 	cfg, err := config.LoadConfigWithInstance(instanceID)
 	if err != nil {
@@ -47,28 +78,26 @@ func main() {
 	}
 
 	instanceDir = cfg.InstanceDir
+	authToken = cfg.AuthToken
 
-	setupCleanupHandlers()
+	endpoint, err := daemon.EndpointOf(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
 
-	socketPath, existingHealthy := findExistingDaemon(cfg.SocketPath)
-	if existingHealthy {
-		log.Printf("Using existing daemon at %s\n", socketPath)
-		daemonPID = -1
-		daemonCmd = nil
-	} else {
-		pid, cmd, err := startDaemonForInstance(instanceID)
-		daemonPID = pid
-		daemonCmd = cmd
-		if err != nil {
-			cleanup()
-			fmt.Fprintf(os.Stderr, "Failed to start daemon: %v\n", err)
+	if recordPath != "" {
+		if err := startRecording(recordPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to start recording: %v\n", err)
 			os.Exit(1)
 		}
 	}
 
-	if err := waitForDaemonReady(cfg.SocketPath, 10*time.Second); err != nil {
+	setupCleanupHandlers()
+
+	if err := ensureDaemon(endpoint); err != nil {
 		cleanup()
-		fmt.Fprintf(os.Stderr, "Daemon failed to become ready: %v\n", err)
+		fmt.Fprintf(os.Stderr, "%v\n", err)
 		os.Exit(1)
 	}
 
@@ -76,10 +105,10 @@ func main() {
 	defer cancel()
 
 	if daemonCmd != nil {
-		go monitorDaemon(daemonCmd, cancel)
+		go monitorDaemon(daemonCmd)
 	}
 
-	conn, err := connectWithRetry(ctx, cfg.SocketPath, 5)
+	conn, err := connectWithRetry(ctx, endpoint, 5)
 	if err != nil {
 		cleanup()
 		fmt.Fprintf(os.Stderr, "Failed to connect to daemon: %v\n", err)
@@ -88,8 +117,14 @@ func main() {
 
 	defer conn.Close()
 
-	client := daemon.NewClient(conn)
-	if err := handleStdio(ctx, client, cfg.SocketPath); err != nil {
+	client, err := newAuthenticatedClient(conn)
+	if err != nil {
+		cleanup()
+		fmt.Fprintf(os.Stderr, "Failed to authenticate with daemon: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := handleStdio(ctx, client, endpoint); err != nil {
 		if ctx.Err() == nil {
 			log.Printf("Error handling stdio: %v", err)
 		}
@@ -157,46 +192,59 @@ func findWorkspaceRoot() string {
 	return cwd
 }
 
-func findExistingDaemon(socketPath string) (string, bool) {
-	if _, err := os.Stat(socketPath); err != nil {
-		return "", false
+// ensureDaemon uses the healthy daemon listening on endpoint, or starts one
+// for the instance, and waits for it to accept connections
+func ensureDaemon(endpoint daemon.Endpoint) error {
+	existing, existingHealthy := findExistingDaemon(endpoint)
+	if existingHealthy {
+		log.Printf("Using existing daemon at %s\n", existing)
+		daemonPID = -1
+		daemonCmd = nil
+	} else {
+		pid, cmd, err := startDaemonForInstance(instanceID)
+		daemonPID = pid
+		daemonCmd = cmd
+		if err != nil {
+			return fmt.Errorf("Failed to start daemon: %w", err)
+		}
+	}
+
+	if err := waitForDaemonReady(endpoint, 10*time.Second); err != nil {
+		return fmt.Errorf("Daemon failed to become ready: %w", err)
+	}
+	return nil
+}
+
+func findExistingDaemon(endpoint daemon.Endpoint) (string, bool) {
+	if endpoint.Network == "unix" {
+		if _, err := os.Stat(endpoint.Address); err != nil {
+			return "", false
+		}
 	}
 
-	if isSocketHealthy(socketPath) {
-		return socketPath, true
+	if isSocketHealthy(endpoint) {
+		return endpoint.String(), true
 	}
 
 	return "", false
 }
 
-func isSocketHealthy(socketPath string) bool {
-	conn, err := net.Dial("unix", socketPath)
+func isSocketHealthy(endpoint daemon.Endpoint) bool {
+	conn, err := endpoint.Dial()
 	if err != nil {
 		return false
 	}
-	defer conn.Close()
-
-	conn.SetDeadline(time.Now().Add(2 * time.Second))
-
-	req := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"id":      1,
-		"method":  "tools/list",
-		"params":  map[string]interface{}{},
-	}
 
-	encoder := json.NewEncoder(conn)
-	if err := encoder.Encode(req); err != nil {
+	client, err := newAuthenticatedClient(conn)
+	if err != nil {
 		return false
 	}
+	defer client.Close()
 
-	decoder := json.NewDecoder(conn)
-	var resp map[string]interface{}
-	if err := decoder.Decode(&resp); err != nil {
-		return false
-	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
 
-	return true
+	return client.Ping(ctx) == nil
 }
 
 func startDaemonForInstance(instanceID string) (int, *exec.Cmd, error) {
@@ -204,16 +252,16 @@ func startDaemonForInstance(instanceID string) (int, *exec.Cmd, error) {
 	if err != nil {
 		return 0, nil, err
 	}
-	daemonName := "mayla-daemon"
-	if runtime.GOOS == "windows" {
-		daemonName += ".exe"
+	if resolved, err := filepath.EvalSymlinks(execPath); err == nil {
+		execPath = resolved
 	}
-	daemonPath := filepath.Join(filepath.Dir(execPath), daemonName)
+	daemonPath, args := daemonCommand(execPath)
 
 	parentPID := os.Getpid()
-	cmd := exec.Command(daemonPath, instanceID, fmt.Sprintf("%d", parentPID))
-	cmd.Stdout = os.Stderr
-	cmd.Stderr = os.Stderr
+	args = append(args, instanceID, fmt.Sprintf("%d", parentPID))
+	cmd := exec.Command(daemonPath, args...)
+	cmd.Stdout = daemonOutput
+	cmd.Stderr = daemonOutput
 
 	if err := cmd.Start(); err != nil {
 		return 0, nil, fmt.Errorf("failed to start daemon: %w", err)
@@ -222,10 +270,10 @@ func startDaemonForInstance(instanceID string) (int, *exec.Cmd, error) {
 	return cmd.Process.Pid, cmd, nil
 }
 
-func waitForDaemonReady(socketPath string, timeout time.Duration) error {
+func waitForDaemonReady(endpoint daemon.Endpoint, timeout time.Duration) error {
 	deadline := time.Now().Add(timeout)
 	for time.Now().Before(deadline) {
-		conn, err := net.DialTimeout("unix", socketPath, 500*time.Millisecond)
+		conn, err := endpoint.DialTimeout(500 * time.Millisecond)
 		if err == nil {
 			conn.Close()
 			return nil
@@ -235,19 +283,32 @@ func waitForDaemonReady(socketPath string, timeout time.Duration) error {
 	return fmt.Errorf("daemon socket not ready after %v", timeout)
 }
 
-func monitorDaemon(cmd *exec.Cmd, cancelFunc context.CancelFunc) {
+// monitorDaemon reaps a daemon started by this process. The session keeps
+// running when it exits; the next heartbeat or request reconnects and
+// relaunches it.
+func monitorDaemon(cmd *exec.Cmd) {
 	err := cmd.Wait()
-	close(daemonDone)
+
+	daemonMu.Lock()
+	if daemonCmd == cmd {
+		daemonCmd = nil
+	}
+	daemonMu.Unlock()
 
 	log.Printf("Daemon process exited: %v", err)
-	cancelFunc()
 }
 
 func cleanup() {
 	cleanupOnce.Do(func() {
+		if recorder != nil {
+			recorder.Close()
+		}
+
+		daemonMu.Lock()
 		if daemonPID > 0 && daemonCmd != nil {
 			killDaemon(daemonPID)
 		}
+		daemonMu.Unlock()
 
 		if instanceDir != "" && daemonPID > 0 && strings.HasPrefix(instanceID, "fallback-") {
 			memDB := filepath.Join(instanceDir, "memory.db")
@@ -267,13 +328,13 @@ func cleanup() {
 	})
 }
 
-func connectWithRetry(ctx context.Context, socketPath string, maxRetries int) (net.Conn, error) {
+func connectWithRetry(ctx context.Context, endpoint daemon.Endpoint, maxRetries int) (net.Conn, error) {
 	for i := 0; i < maxRetries; i++ {
 		if ctx.Err() != nil {
 			return nil, ctx.Err()
 		}
 
-		conn, err := connectToDaemon(socketPath)
+		conn, err := connectToDaemon(endpoint)
 		if err == nil {
 			return conn, nil
 		}
@@ -287,9 +348,17 @@ func connectWithRetry(ctx context.Context, socketPath string, maxRetries int) (n
 	return nil, fmt.Errorf("failed to connect after %d retries", maxRetries)
 }
 
-func connectToDaemon(socketPath string) (net.Conn, error) {
-	connector := daemon.NewSocketConnector(socketPath)
-	return connector.Connect()
+func connectToDaemon(endpoint daemon.Endpoint) (net.Conn, error) {
+	return endpoint.Dial()
+}
+
+func newAuthenticatedClient(conn net.Conn) (*daemon.Client, error) {
+	client := daemon.NewClient(conn)
+	if err := client.Handshake(sessionHello()); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
 }
 
 type stdinReader struct {
@@ -324,7 +393,14 @@ func (r *stdinReader) readLoop() {
 		}
 
 		var req protocol.JSONRPCRequest
-		err := r.decoder.Decode(&req)
+		var raw json.RawMessage
+		err := r.decoder.Decode(&raw)
+		if err == nil {
+			if recorder != nil {
+				recorder.Record(recording.In, raw)
+			}
+			err = json.Unmarshal(raw, &req)
+		}
 
 		if err != nil {
 			if err == io.EOF {
@@ -379,85 +455,190 @@ func (r *stdinReader) close() {
 	close(r.done)
 }
 
-func handleStdio(ctx context.Context, client *daemon.Client, socketPath string) error {
+// stdioSession forwards MCP messages from stdin to the daemon. Requests are
+// forwarded concurrently so that a notifications/cancelled from the MCP client
+// can cancel a request that is still running on the daemon.
+type stdioSession struct {
+	endpoint daemon.Endpoint
+	writer   *protocol.FlushWriter
+	encoder  *json.Encoder
+	writeMu  sync.Mutex
+
+	clientMu sync.Mutex
+	client   *daemon.Client
+
+	inflightMu sync.Mutex
+	inflight   map[string]context.CancelFunc
+
+	toolsMu        sync.Mutex
+	retryableTools map[string]bool
+
+	stop    context.CancelCauseFunc
+	stopped context.Context
+}
+
+// errStdoutClosed ends the session quietly when the MCP client goes away
+var errStdoutClosed = errors.New("stdout closed")
+
+func handleStdio(ctx context.Context, client *daemon.Client, endpoint daemon.Endpoint) error {
 	reader := newStdinReader()
 	defer reader.close()
 
+	ctx, stop := context.WithCancelCause(ctx)
+	defer stop(nil)
+
 	writer := protocol.NewFlushWriter(os.Stdout)
-	encoder := json.NewEncoder(writer)
+	var output io.Writer = writer
+	if recorder != nil {
+		output = recorder.Tee(recording.Out, writer)
+	}
+	s := &stdioSession{
+		endpoint: endpoint,
+		writer:   writer,
+		encoder:  json.NewEncoder(output),
+		client:   client,
+		inflight: make(map[string]context.CancelFunc),
+		stop:     stop,
+		stopped:  ctx,
+
+		retryableTools: make(map[string]bool),
+	}
+	client.SetNotificationHandler(s.writeNotification)
 
-	var writeMu sync.Mutex
+	go s.heartbeat()
 
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-		}
+	var forwards sync.WaitGroup
+	defer forwards.Wait()
 
+	for {
 		req, err := reader.readRequest(ctx)
 		if err != nil {
-			if err == io.EOF || err == context.Canceled {
+			if err == io.EOF {
 				return nil
 			}
+			if ctx.Err() != nil {
+				if cause := context.Cause(ctx); cause != context.Canceled && cause != errStdoutClosed {
+					return cause
+				}
+				return ctx.Err()
+			}
 			return fmt.Errorf("failed to decode request: %w", err)
 		}
 
-		resp, err := client.SendRequest(req)
-		if err != nil {
-			if !client.IsHealthy() {
-				log.Println("Connection unhealthy, attempting reconnect...")
+		if req.Method == "notifications/cancelled" {
+			s.cancelRequest(req)
+			continue
+		}
 
-				if err := client.Close(); err != nil {
-					log.Printf("Error closing old connection: %v", err)
-				}
+		forwards.Add(1)
+		go func() {
+			defer forwards.Done()
+			s.forward(req)
+		}()
+	}
+}
 
-				newConn, reconnErr := connectWithRetry(ctx, socketPath, 3)
-				if reconnErr != nil {
-					return fmt.Errorf("reconnection failed: %w", reconnErr)
-				}
+func (s *stdioSession) forward(req *protocol.JSONRPCRequest) {
+	ctx, cancel := context.WithCancel(s.stopped)
+	defer cancel()
 
-				client = daemon.NewClient(newConn)
-				log.Println("Reconnected successfully")
+	key := requestKey(req.ID)
+	if req.ID != nil {
+		s.inflightMu.Lock()
+		s.inflight[key] = cancel
+		s.inflightMu.Unlock()
+		defer func() {
+			s.inflightMu.Lock()
+			delete(s.inflight, key)
+			s.inflightMu.Unlock()
+		}()
+	}
 
-				resp, err = client.SendRequest(req)
-				if err != nil {
-					return fmt.Errorf("request failed after reconnect: %w", err)
-				}
-			} else {
-				if req.ID != nil {
-					errResp := &protocol.JSONRPCResponse{
-						JSONRPC: "2.0",
-						ID:      req.ID,
-						Error: &protocol.JSONRPCError{
-							Code:    -32603,
-							Message: err.Error(),
-						},
-					}
-					writeMu.Lock()
-					encodeErr := encoder.Encode(errResp)
-					if encodeErr == nil {
-						writer.Flush()
-					}
-					writeMu.Unlock()
-					if encodeErr != nil {
-						return nil
-					}
-				}
-				continue
-			}
+	client := s.currentClient()
+	resp, err := client.SendRequest(ctx, req)
+	if err != nil && ctx.Err() == nil && !client.IsHealthy() {
+		log.Println("Connection unhealthy, attempting reconnect...")
+
+		client, err = s.reconnect(client)
+		if err != nil {
+			s.stop(fmt.Errorf("reconnection failed: %w", err))
+			return
 		}
 
-		if req.ID != nil {
-			writeMu.Lock()
-			err := encoder.Encode(resp)
-			if err == nil {
-				writer.Flush()
-			}
-			writeMu.Unlock()
-			if err != nil {
-				return nil
-			}
+		if s.isRetryable(req) {
+			resp, err = client.SendRequest(ctx, req)
+		} else {
+			err = errRequestInterrupted
+		}
+	}
+
+	if err == nil && req.Method == "tools/list" {
+		s.learnRetryableTools(resp)
+	}
+
+	if req.ID == nil {
+		return
+	}
+
+	if err != nil {
+		if s.stopped.Err() != nil {
+			return
+		}
+		resp = &protocol.JSONRPCResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error: &protocol.JSONRPCError{
+				Code:    -32603,
+				Message: err.Error(),
+			},
 		}
 	}
+
+	s.writeMu.Lock()
+	encodeErr := s.encoder.Encode(resp)
+	if encodeErr == nil {
+		encodeErr = s.writer.Flush()
+	}
+	s.writeMu.Unlock()
+	if encodeErr != nil {
+		s.stop(errStdoutClosed)
+	}
+}
+
+// writeNotification relays a daemon notification to the MCP client
+func (s *stdioSession) writeNotification(notification json.RawMessage) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if err := s.encoder.Encode(notification); err != nil {
+		return
+	}
+	s.writer.Flush()
+}
+
+// cancelRequest handles an MCP notifications/cancelled by cancelling the
+// matching in-flight request, which in turn cancels it on the daemon.
+func (s *stdioSession) cancelRequest(req *protocol.JSONRPCRequest) {
+	requestID, ok := req.Params["requestId"]
+	if !ok {
+		return
+	}
+
+	s.inflightMu.Lock()
+	cancel, ok := s.inflight[requestKey(requestID)]
+	s.inflightMu.Unlock()
+	if ok {
+		cancel()
+	}
+}
+
+func (s *stdioSession) currentClient() *daemon.Client {
+	s.clientMu.Lock()
+	defer s.clientMu.Unlock()
+	return s.client
+}
+
+// requestKey turns a decoded JSON-RPC ID into a key for the in-flight map
+func requestKey(id interface{}) string {
+	return fmt.Sprintf("%v", id)
 }
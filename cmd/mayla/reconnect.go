@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/daemon"
+	"github.com/alucardeht/may-la-mcp/pkg/protocol"
+)
+
+const (
+	heartbeatInterval = 15 * time.Second
+	heartbeatTimeout  = 5 * time.Second
+
+	reconnectAttempts  = 8
+	reconnectBaseDelay = 200 * time.Millisecond
+	reconnectMaxDelay  = 5 * time.Second
+	daemonReadyTimeout = 10 * time.Second
+)
+
+// errRequestInterrupted is returned for requests that may have had side
+// effects when the connection dropped, so they are not retried.
+var errRequestInterrupted = errors.New("connection to daemon lost while the request was running; it may or may not have completed")
+
+// heartbeat pings the daemon periodically so that a dead connection is
+// noticed and replaced before the next request needs it.
+func (s *stdioSession) heartbeat() {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopped.Done():
+			return
+		case <-ticker.C:
+		}
+
+		client := s.currentClient()
+		ctx, cancel := context.WithTimeout(s.stopped, heartbeatTimeout)
+		err := client.Ping(ctx)
+		cancel()
+		if err == nil || s.stopped.Err() != nil {
+			continue
+		}
+
+		log.Printf("Heartbeat failed: %v, reconnecting...", err)
+		if _, err := s.reconnect(client); err != nil {
+			s.stop(fmt.Errorf("reconnection failed: %w", err))
+			return
+		}
+	}
+}
+
+// reconnect replaces failed with a new connection, retrying with exponential
+// backoff and relaunching the daemon when nothing answers on the socket.
+// Concurrent callers that saw the same failed client share one reconnect.
+func (s *stdioSession) reconnect(failed *daemon.Client) (*daemon.Client, error) {
+	s.clientMu.Lock()
+	defer s.clientMu.Unlock()
+
+	if s.client != failed {
+		return s.client, nil
+	}
+
+	if err := failed.Close(); err != nil {
+		log.Printf("Error closing old connection: %v", err)
+	}
+
+	delay := reconnectBaseDelay
+	var lastErr error
+	for attempt := 1; attempt <= reconnectAttempts; attempt++ {
+		client, err := s.dial()
+		if err == nil {
+			log.Printf("Reconnected successfully (attempt %d)", attempt)
+			s.client = client
+			return client, nil
+		}
+		lastErr = err
+
+		if !isSocketHealthy(s.endpoint) {
+			if err := relaunchDaemon(s.endpoint); err != nil {
+				log.Printf("Failed to relaunch daemon: %v", err)
+			}
+		}
+
+		select {
+		case <-s.stopped.Done():
+			return nil, s.stopped.Err()
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if delay > reconnectMaxDelay {
+			delay = reconnectMaxDelay
+		}
+	}
+
+	return nil, fmt.Errorf("gave up after %d attempts: %w", reconnectAttempts, lastErr)
+}
+
+func (s *stdioSession) dial() (*daemon.Client, error) {
+	conn, err := connectToDaemon(s.endpoint)
+	if err != nil {
+		return nil, err
+	}
+	client, err := newAuthenticatedClient(conn)
+	if err != nil {
+		return nil, err
+	}
+	client.SetNotificationHandler(s.writeNotification)
+	return client, nil
+}
+
+// relaunchDaemon starts a new daemon for this instance and waits for its
+// socket. If another session's daemon wins the instance lock first, the new
+// process exits and the next attempt connects to the winner.
+func relaunchDaemon(endpoint daemon.Endpoint) error {
+	daemonMu.Lock()
+	running := daemonCmd != nil
+	daemonMu.Unlock()
+	if running {
+		return nil
+	}
+
+	log.Println("Daemon is not running, relaunching...")
+	pid, cmd, err := startDaemonForInstance(instanceID)
+	if err != nil {
+		return err
+	}
+
+	daemonMu.Lock()
+	daemonPID = pid
+	daemonCmd = cmd
+	daemonMu.Unlock()
+
+	go monitorDaemon(cmd)
+
+	return waitForDaemonReady(endpoint, daemonReadyTimeout)
+}
+
+// isRetryable reports whether req can be resent after a reconnect without
+// risking a duplicate side effect. Tool calls qualify when the tool declared
+// itself read-only in the last tools/list response; idempotentHint is not
+// enough, as edits that append and memory writes are annotated with it.
+func (s *stdioSession) isRetryable(req *protocol.JSONRPCRequest) bool {
+	switch req.Method {
+	case "initialize", "ping", "tools/list", "resources/list", "resources/read", "notifications/initialized":
+		return true
+	case "tools/call":
+		name, _ := req.Params["name"].(string)
+		s.toolsMu.Lock()
+		defer s.toolsMu.Unlock()
+		return s.retryableTools[name]
+	default:
+		return false
+	}
+}
+
+// learnRetryableTools records which tools are safe to retry from the
+// annotations in a tools/list response.
+func (s *stdioSession) learnRetryableTools(resp *protocol.JSONRPCResponse) {
+	result, ok := resp.Result.(map[string]interface{})
+	if !ok {
+		return
+	}
+	list, ok := result["tools"].([]interface{})
+	if !ok {
+		return
+	}
+
+	s.toolsMu.Lock()
+	defer s.toolsMu.Unlock()
+
+	for _, entry := range list {
+		tool, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := tool["name"].(string)
+		annotations, _ := tool["annotations"].(map[string]interface{})
+		readOnly, _ := annotations["readOnlyHint"].(bool)
+		s.retryableTools[name] = readOnly
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/daemon"
+	"github.com/alucardeht/may-la-mcp/pkg/protocol"
+)
+
+// fakeDaemon answers the shim's handshake and requests on a socket. The
+// first connection is dropped when a tool call arrives, as a daemon that
+// crashed while running it would.
+type fakeDaemon struct {
+	listener net.Listener
+
+	mu    sync.Mutex
+	conns int
+	// calls lists the tool calls received, by connection
+	calls map[int][]string
+}
+
+// newFakeDaemon listens on network, unix or tcp
+func newFakeDaemon(t *testing.T, network string) *fakeDaemon {
+	t.Helper()
+	address := "127.0.0.1:0"
+	if network == "unix" {
+		address = filepath.Join(t.TempDir(), "mayla.sock")
+	}
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &fakeDaemon{listener: listener, calls: make(map[int][]string)}
+	t.Cleanup(func() { listener.Close() })
+	go d.serve()
+	return d
+}
+
+func (d *fakeDaemon) endpoint() daemon.Endpoint {
+	addr := d.listener.Addr()
+	return daemon.Endpoint{Network: addr.Network(), Address: addr.String()}
+}
+
+func (d *fakeDaemon) serve() {
+	for {
+		conn, err := d.listener.Accept()
+		if err != nil {
+			return
+		}
+		d.mu.Lock()
+		d.conns++
+		n := d.conns
+		d.mu.Unlock()
+		go d.handle(conn, n)
+	}
+}
+
+func (d *fakeDaemon) handle(conn net.Conn, n int) {
+	defer conn.Close()
+	reader := protocol.NewFrameReader(conn)
+	writer := protocol.NewFrameWriter(conn)
+
+	for {
+		frame, err := reader.Read()
+		if err != nil {
+			return
+		}
+		switch frame.Type {
+		case protocol.FrameHello:
+			hello, _ := json.Marshal(protocol.Hello{Version: protocol.WireVersion})
+			writer.Write(&protocol.Frame{Type: protocol.FrameHello, ID: frame.ID, Payload: hello})
+		case protocol.FramePing:
+			writer.Write(&protocol.Frame{Type: protocol.FramePong, ID: frame.ID})
+		case protocol.FrameRequest:
+			var req protocol.JSONRPCRequest
+			json.Unmarshal(frame.Payload, &req)
+			if req.Method == "tools/call" {
+				name, _ := req.Params["name"].(string)
+				d.mu.Lock()
+				d.calls[n] = append(d.calls[n], name)
+				d.mu.Unlock()
+				if n == 1 {
+					return
+				}
+			}
+			resp, _ := json.Marshal(protocol.JSONRPCResponse{JSONRPC: "2.0", ID: req.ID, Result: map[string]interface{}{"ok": true}})
+			writer.Write(&protocol.Frame{Type: protocol.FrameResponse, ID: frame.ID, Payload: resp})
+		}
+	}
+}
+
+func (d *fakeDaemon) toolCalls() map[int][]string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	calls := make(map[int][]string, len(d.calls))
+	for n, names := range d.calls {
+		calls[n] = append([]string(nil), names...)
+	}
+	return calls
+}
+
+// newTestSession connects a stdio session to d, writing its responses to out
+func newTestSession(t *testing.T, d *fakeDaemon, out *bytes.Buffer) *stdioSession {
+	t.Helper()
+	ctx, stop := context.WithCancelCause(context.Background())
+	t.Cleanup(func() { stop(nil) })
+
+	writer := protocol.NewFlushWriter(out)
+	s := &stdioSession{
+		endpoint:       d.endpoint(),
+		writer:         writer,
+		encoder:        json.NewEncoder(writer),
+		inflight:       make(map[string]context.CancelFunc),
+		stop:           stop,
+		stopped:        ctx,
+		retryableTools: make(map[string]bool),
+	}
+	client, err := s.dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.client = client
+	t.Cleanup(func() { s.currentClient().Close() })
+
+	s.learnRetryableTools(&protocol.JSONRPCResponse{Result: map[string]interface{}{
+		"tools": []interface{}{
+			map[string]interface{}{"name": "read", "annotations": map[string]interface{}{"readOnlyHint": true, "idempotentHint": true}},
+			map[string]interface{}{"name": "edit", "annotations": map[string]interface{}{"readOnlyHint": false, "idempotentHint": true}},
+		},
+	}})
+	return s
+}
+
+func TestReconnectRetries(t *testing.T) {
+	tests := []struct {
+		tool    string
+		resent  bool
+		wantErr string
+	}{
+		{"read", true, ""},
+		{"edit", false, "may or may not have completed"},
+	}
+	for _, network := range []string{"unix", "tcp"} {
+		for _, tt := range tests {
+			t.Run(network+"/"+tt.tool, func(t *testing.T) {
+				d := newFakeDaemon(t, network)
+				var out bytes.Buffer
+				s := newTestSession(t, d, &out)
+
+				s.forward(&protocol.JSONRPCRequest{
+					JSONRPC: "2.0",
+					ID:      float64(1),
+					Method:  "tools/call",
+					Params:  map[string]interface{}{"name": tt.tool, "arguments": map[string]interface{}{"path": "main.go"}},
+				})
+
+				calls := d.toolCalls()
+				if len(calls[1]) != 1 {
+					t.Fatalf("expected the call on the first connection, got %v", calls)
+				}
+				if resent := len(calls[2]) > 0; resent != tt.resent {
+					t.Errorf("resent after reconnecting = %v, want %v (calls %v)", resent, tt.resent, calls)
+				}
+
+				var resp protocol.JSONRPCResponse
+				if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+					t.Fatalf("bad response %q: %v", out.String(), err)
+				}
+				switch {
+				case tt.wantErr == "" && resp.Error != nil:
+					t.Errorf("unexpected error %+v", resp.Error)
+				case tt.wantErr != "" && (resp.Error == nil || !strings.Contains(resp.Error.Message, tt.wantErr)):
+					t.Errorf("expected an error about %q, got %s", tt.wantErr, out.String())
+				}
+			})
+		}
+	}
+}
+
+func TestDialTCP(t *testing.T) {
+	d := newFakeDaemon(t, "tcp")
+	endpoint := d.endpoint()
+
+	if err := waitForDaemonReady(endpoint, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if existing, healthy := findExistingDaemon(endpoint); !healthy || existing != endpoint.String() {
+		t.Errorf("findExistingDaemon = %q, %v; want the daemon at %s", existing, healthy, endpoint)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/alucardeht/may-la-mcp/internal/recording"
+	"github.com/alucardeht/may-la-mcp/pkg/version"
+)
+
+// recorder captures the session's JSON-RPC traffic when --record is set
+var recorder *recording.Recorder
+
+// parseRecordFlag removes --record <file> (or --record=<file>) from args
+// and returns the file along with the remaining arguments
+func parseRecordFlag(args []string) (string, []string) {
+	var path string
+	rest := args[:0:0]
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case (arg == "--record" || arg == "-record") && i+1 < len(args):
+			path = args[i+1]
+			i++
+		case strings.HasPrefix(arg, "--record="), strings.HasPrefix(arg, "-record="):
+			path = arg[strings.Index(arg, "=")+1:]
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return path, rest
+}
+
+// startRecording records the session to path, with the workspace it runs
+// in so replays can substitute their own
+func startRecording(path string) error {
+	r, err := recording.Create(path, recording.Header{
+		Workspace: findWorkspaceRoot(),
+		Version:   version.Version,
+	})
+	if err != nil {
+		return err
+	}
+	recorder = r
+	return nil
+}
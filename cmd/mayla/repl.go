@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/alucardeht/may-la-mcp/internal/daemon"
+)
+
+// maxReplHistory is how many lines the REPL history file keeps
+const maxReplHistory = 500
+
+const replHelp = `Commands:
+  <tool> {"key": "value"}    call a tool with JSON arguments
+  <tool> key=value ...       call a tool; values that parse as JSON are
+                             used as such, the others as strings
+  tools                      list the tools
+  describe <tool>            show a tool's description and input schema
+  health                     show the daemon's component health
+  history                    list the previous commands
+  !!, !<n>                   run the last or the n-th command again
+  help                       show this help
+  exit, quit                 leave (also Ctrl-D)`
+
+// repl is an interactive session with the workspace's daemon
+type repl struct {
+	client      *daemon.Client
+	out         io.Writer
+	history     []string
+	historyPath string
+}
+
+// runRepl implements `mayla repl`
+func runRepl(args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintln(os.Stderr, "usage: mayla repl")
+		return 2
+	}
+
+	client, err := dialWorkspaceDaemon()
+	if err != nil {
+		cleanup()
+		fmt.Fprintf(os.Stderr, "repl: %v\n", err)
+		return 1
+	}
+	defer cleanup()
+	defer client.Close()
+
+	r := &repl{client: client, out: os.Stdout}
+	if home, err := os.UserHomeDir(); err == nil {
+		r.historyPath = filepath.Join(home, ".mayla", "repl_history")
+		r.loadHistory()
+	}
+
+	fmt.Fprintf(r.out, "Connected to the daemon of %s. Type help for the commands.\n", findWorkspaceRoot())
+	r.run(os.Stdin)
+	return 0
+}
+
+func (r *repl) run(in io.Reader) {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for {
+		fmt.Fprint(r.out, "mayla> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(r.out)
+			return
+		}
+
+		line, err := r.expand(strings.TrimSpace(scanner.Text()))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			continue
+		}
+		if line == "" {
+			continue
+		}
+		if line == "exit" || line == "quit" {
+			return
+		}
+		r.remember(line)
+
+		if err := r.execute(line); err != nil && !errors.Is(err, errToolFailed) {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		}
+	}
+}
+
+// expand replaces the history references !! and !<n> with the commands
+// they name
+func (r *repl) expand(line string) (string, error) {
+	if !strings.HasPrefix(line, "!") {
+		return line, nil
+	}
+	index := len(r.history)
+	if line != "!!" {
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 1 || n > len(r.history) {
+			return "", fmt.Errorf("no command %s in the history", line)
+		}
+		index = n
+	}
+	if index == 0 {
+		return "", fmt.Errorf("the history is empty")
+	}
+	line = r.history[index-1]
+	fmt.Fprintln(r.out, line)
+	return line, nil
+}
+
+func (r *repl) execute(line string) error {
+	command, rest, _ := strings.Cut(line, " ")
+	rest = strings.TrimSpace(rest)
+
+	switch command {
+	case "help":
+		fmt.Fprintln(r.out, replHelp)
+		return nil
+	case "history":
+		for i, entry := range r.history {
+			fmt.Fprintf(r.out, "%4d  %s\n", i+1, entry)
+		}
+		return nil
+	case "tools":
+		return listTools(r.client, false, r.out)
+	case "describe":
+		return r.describe(rest)
+	case "health":
+		return callTool(r.client, "health", nil, r.out)
+	}
+
+	arguments, err := parseReplArgs(rest)
+	if err != nil {
+		return err
+	}
+	return callTool(r.client, command, arguments, r.out)
+}
+
+func (r *repl) describe(name string) error {
+	tools, err := fetchTools(r.client)
+	if err != nil {
+		return err
+	}
+	for _, tool := range tools {
+		if tool.Name == name {
+			fmt.Fprintf(r.out, "%s — %s\n\n%s\n\n%s\n", tool.Name, tool.Title, tool.Description, indentJSON(string(tool.InputSchema)))
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown tool %q", name)
+}
+
+// parseReplArgs reads the arguments of a tool call, either a JSON object
+// or key=value words
+func parseReplArgs(s string) (map[string]interface{}, error) {
+	arguments := make(map[string]interface{})
+	if s == "" {
+		return arguments, nil
+	}
+	if strings.HasPrefix(s, "{") {
+		if err := json.Unmarshal([]byte(s), &arguments); err != nil {
+			return nil, fmt.Errorf("invalid JSON arguments: %w", err)
+		}
+		return arguments, nil
+	}
+
+	words, err := splitWords(s)
+	if err != nil {
+		return nil, err
+	}
+	for _, word := range words {
+		key, value, ok := strings.Cut(word, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("expected key=value, got %q", word)
+		}
+		var parsed interface{}
+		if err := json.Unmarshal([]byte(value), &parsed); err == nil {
+			arguments[key] = parsed
+		} else {
+			arguments[key] = value
+		}
+	}
+	return arguments, nil
+}
+
+// splitWords splits s on spaces outside single or double quotes, which
+// are removed
+func splitWords(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	var quote rune
+	inWord := false
+
+	for _, c := range s {
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+			word.WriteRune(c)
+		case c == '"' || c == '\'':
+			quote, inWord = c, true
+		case c == ' ' || c == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(c)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+func (r *repl) loadHistory() {
+	data, err := os.ReadFile(r.historyPath)
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			r.history = append(r.history, line)
+		}
+	}
+}
+
+// remember appends line to the history, keeping the file to the last
+// maxReplHistory commands
+func (r *repl) remember(line string) {
+	if len(r.history) > 0 && r.history[len(r.history)-1] == line {
+		return
+	}
+	r.history = append(r.history, line)
+	if len(r.history) > maxReplHistory {
+		r.history = r.history[len(r.history)-maxReplHistory:]
+	}
+
+	if r.historyPath == "" {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(r.historyPath), 0700); err != nil {
+		return
+	}
+	os.WriteFile(r.historyPath, []byte(strings.Join(r.history, "\n")+"\n"), 0600)
+}
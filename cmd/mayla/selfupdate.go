@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+
+	"github.com/alucardeht/may-la-mcp/internal/daemon"
+	"github.com/alucardeht/may-la-mcp/internal/selfupdate"
+	"github.com/alucardeht/may-la-mcp/pkg/version"
+)
+
+// runSelfUpdate implements `mayla self-update`: it replaces mayla and
+// mayla-daemon with the binaries of the latest (or requested) release and
+// stops running daemons so the next session starts the new version.
+func runSelfUpdate(args []string) int {
+	fs := flag.NewFlagSet("self-update", flag.ContinueOnError)
+	checkOnly := fs.Bool("check", false, "only report whether an update is available")
+	tag := fs.String("version", "", "install a specific release tag instead of the latest")
+	force := fs.Bool("force", false, "reinstall even if already up to date")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	execPath, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "self-update: %v\n", err)
+		return 1
+	}
+	if resolved, err := filepath.EvalSymlinks(execPath); err == nil {
+		execPath = resolved
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	updater := selfupdate.NewUpdater(filepath.Dir(execPath))
+
+	release, err := updater.FetchRelease(ctx, *tag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "self-update: %v\n", err)
+		return 1
+	}
+
+	newer := selfupdate.IsNewer(release.TagName, version.Version)
+	if *checkOnly {
+		if newer {
+			fmt.Printf("Update available: %s (current %s)\n", release.TagName, version.Version)
+		} else {
+			fmt.Printf("mayla %s is up to date\n", version.Version)
+		}
+		return 0
+	}
+
+	if !newer && *tag == "" && !*force {
+		fmt.Printf("mayla %s is up to date\n", version.Version)
+		return 0
+	}
+
+	fmt.Printf("Updating mayla %s -> %s...\n", version.Version, release.TagName)
+	if err := updater.Apply(ctx, release); err != nil {
+		fmt.Fprintf(os.Stderr, "self-update: %v\n", err)
+		return 1
+	}
+
+	stopped := stopRunningDaemons()
+	fmt.Printf("Installed %s in %s", release.TagName, updater.InstallDir)
+	if stopped > 0 {
+		fmt.Printf(" (stopped %d running daemon(s); they restart with the new version on next use)", stopped)
+	}
+	fmt.Println()
+	return 0
+}
+
+// stopRunningDaemons terminates every instance daemon recorded under
+// ~/.mayla/instances so that sessions respawn them from the new binary.
+func stopRunningDaemons() int {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return 0
+	}
+
+	pidFiles, _ := filepath.Glob(filepath.Join(homeDir, ".mayla", "instances", "*", "daemon.pid"))
+	stopped := 0
+	for _, path := range pidFiles {
+		pidFile := daemon.NewPIDFile(path)
+		if !pidFile.IsProcessAlive() {
+			continue
+		}
+		pid, err := pidFile.Read()
+		if err != nil || pid == 0 {
+			continue
+		}
+		killDaemon(pid)
+		stopped++
+	}
+	return stopped
+}
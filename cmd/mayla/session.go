@@ -0,0 +1,32 @@
+package main
+
+import (
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+	"github.com/alucardeht/may-la-mcp/pkg/protocol"
+)
+
+// session holds the options this client asks the daemon to apply
+var session tools.SessionOptions
+
+// parseSessionFlags removes the session flags from args and returns the
+// options they select along with the remaining arguments.
+func parseSessionFlags(args []string) (tools.SessionOptions, []string) {
+	var opts tools.SessionOptions
+	rest := args[:0:0]
+	for _, arg := range args {
+		switch arg {
+		case "--read-only", "-read-only":
+			opts.ReadOnly = true
+		case "--dry-run", "-dry-run":
+			opts.DryRun = true
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return opts, rest
+}
+
+// sessionHello is the Hello sent on every connection to the daemon
+func sessionHello() protocol.Hello {
+	return protocol.Hello{Token: authToken, ReadOnly: session.ReadOnly, DryRun: session.DryRun, Streaming: true}
+}
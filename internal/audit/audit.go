@@ -0,0 +1,127 @@
+// Package audit keeps a log of the changes tools make: one JSON line per
+// mutating tool call, with the paths it touched, whether it succeeded and
+// the client and session it is attributed to. The log is rotated once it
+// grows past a size limit, keeping one previous file.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+const defaultMaxSize = 10 * 1024 * 1024
+
+// pathKeys are the tool arguments naming the files a call touches
+var pathKeys = []string{"path", "paths", "source", "destination", "file", "files"}
+
+type Entry struct {
+	Time        time.Time          `json:"time"`
+	Tool        string             `json:"tool"`
+	Paths       []string           `json:"paths,omitempty"`
+	OK          bool               `json:"ok"`
+	Error       string             `json:"error,omitempty"`
+	Attribution *tools.Attribution `json:"attribution,omitempty"`
+}
+
+type Log struct {
+	path    string
+	maxSize int64
+	mu      sync.Mutex
+}
+
+func Open(path string) (*Log, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	return &Log{path: path, maxSize: defaultMaxSize}, nil
+}
+
+func (l *Log) Path() string {
+	return l.path
+}
+
+// Record appends the entry for a mutating tool call
+func (l *Log) Record(record tools.WriteRecord) error {
+	entry := Entry{
+		Time:        time.Now().UTC(),
+		Tool:        record.Tool,
+		Paths:       paths(record.Input),
+		OK:          record.Err == nil,
+		Attribution: record.Attribution,
+	}
+	if record.Err != nil {
+		entry.Error = record.Err.Error()
+	}
+	return l.Append(entry)
+}
+
+func (l *Log) Append(entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if info, err := os.Stat(l.path); err == nil && info.Size()+int64(len(line)) > l.maxSize {
+		if err := os.Rename(l.path, l.path+".1"); err != nil {
+			return fmt.Errorf("failed to rotate audit log: %w", err)
+		}
+	}
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+	_, err = file.Write(line)
+	return err
+}
+
+// paths returns the file arguments of a tool call, sorted and without
+// duplicates
+func paths(input json.RawMessage) []string {
+	var args map[string]json.RawMessage
+	if err := json.Unmarshal(input, &args); err != nil {
+		return nil
+	}
+
+	seen := map[string]bool{}
+	for _, key := range pathKeys {
+		raw, ok := args[key]
+		if !ok {
+			continue
+		}
+		var one string
+		if json.Unmarshal(raw, &one) == nil && one != "" {
+			seen[one] = true
+			continue
+		}
+		var many []string
+		if json.Unmarshal(raw, &many) == nil {
+			for _, p := range many {
+				if p != "" {
+					seen[p] = true
+				}
+			}
+		}
+	}
+
+	if len(seen) == 0 {
+		return nil
+	}
+	out := make([]string, 0, len(seen))
+	for p := range seen {
+		out = append(out, p)
+	}
+	sort.Strings(out)
+	return out
+}
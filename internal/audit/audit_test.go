@@ -0,0 +1,59 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+func TestLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "instance", "audit.log")
+	l, err := Open(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	attribution := &tools.Attribution{Client: "cursor", ClientVersion: "1.4", SessionID: "s1"}
+	l.Record(tools.WriteRecord{Tool: "move", Input: json.RawMessage(`{"source": "/a.go", "destination": "/b.go"}`), Attribution: attribution})
+	l.Record(tools.WriteRecord{Tool: "delete", Input: json.RawMessage(`{"paths": ["/c.go", "/a.go"], "path": "/c.go"}`), Err: errors.New("permission denied")})
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("expected one JSON entry per line: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("expected two entries, got %d", len(entries))
+	}
+	if e := entries[0]; e.Tool != "move" || !e.OK || strings.Join(e.Paths, ",") != "/a.go,/b.go" || e.Attribution == nil || *e.Attribution != *attribution {
+		t.Errorf("expected the attributed move of both paths, got %+v", e)
+	}
+	if e := entries[1]; e.OK || e.Error != "permission denied" || strings.Join(e.Paths, ",") != "/a.go,/c.go" || e.Attribution != nil {
+		t.Errorf("expected the failed delete with deduplicated paths, got %+v", e)
+	}
+
+	l.maxSize = 1
+	l.Record(tools.WriteRecord{Tool: "write", Input: json.RawMessage(`{"path": "/d.go"}`)})
+	if _, err := os.Stat(path + ".1"); err != nil {
+		t.Errorf("expected the full log to be rotated: %v", err)
+	}
+	if data, _ := os.ReadFile(path); strings.Count(string(data), "\n") != 1 || !strings.Contains(string(data), "/d.go") {
+		t.Errorf("expected a new log holding the last entry, got %q", data)
+	}
+}
@@ -0,0 +1,547 @@
+// Package checkpoint snapshots the files of a workspace so a set of
+// changes can be rolled back in one go. File contents live once in a
+// content-addressed object store shared by all checkpoints; a checkpoint is
+// a manifest of paths, modes and content hashes. Ignored paths are left
+// out, as they are from listings and the index.
+package checkpoint
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/gitignore"
+	"github.com/alucardeht/may-la-mcp/internal/paths"
+)
+
+type Config struct {
+	// Dir holds the object store and the manifests of every workspace
+	Dir string `yaml:"dir"`
+	// Keep is how many checkpoints are kept per workspace; older ones are
+	// dropped as new ones are made
+	Keep int `yaml:"keep"`
+	// MaxFileSize is the largest file snapshotted, in bytes; larger ones
+	// are skipped
+	MaxFileSize int64 `yaml:"max_file_size"`
+}
+
+func DefaultConfig() Config {
+	homeDir, _ := os.UserHomeDir()
+	return Config{
+		Dir:         filepath.Join(homeDir, ".mayla", "checkpoints"),
+		Keep:        20,
+		MaxFileSize: 8 * 1024 * 1024,
+	}
+}
+
+// File is one file of a checkpoint
+type File struct {
+	// Path is relative to the workspace root, slash-separated
+	Path string      `json:"path"`
+	Hash string      `json:"hash"`
+	Mode fs.FileMode `json:"mode"`
+	Size int64       `json:"size"`
+}
+
+// Checkpoint is the manifest of one snapshot of a workspace
+type Checkpoint struct {
+	ID      string    `json:"id"`
+	Name    string    `json:"name,omitempty"`
+	Root    string    `json:"root"`
+	Created time.Time `json:"created"`
+	Files   []File    `json:"files"`
+	// Skipped are the files left out for being over the size limit
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+// Bytes is the total size of the files of c
+func (c *Checkpoint) Bytes() int64 {
+	var total int64
+	for _, f := range c.Files {
+		total += f.Size
+	}
+	return total
+}
+
+// Plan is what restoring a checkpoint changes in its workspace, by path
+// relative to the root
+type Plan struct {
+	Checkpoint *Checkpoint
+	// Created are files of the checkpoint missing from the workspace
+	Created []string
+	// Modified are files whose content or mode differs from the checkpoint
+	Modified []string
+	// Deleted are files made since the checkpoint
+	Deleted []string
+}
+
+// Changes is the number of files the plan touches
+func (p *Plan) Changes() int {
+	return len(p.Created) + len(p.Modified) + len(p.Deleted)
+}
+
+type Store struct {
+	cfg Config
+	mu  sync.Mutex
+	now func() time.Time
+}
+
+// New returns a store for cfg, or nil when it has no directory
+func New(cfg Config) *Store {
+	if cfg.Dir == "" {
+		return nil
+	}
+	defaults := DefaultConfig()
+	if cfg.Keep <= 0 {
+		cfg.Keep = defaults.Keep
+	}
+	if cfg.MaxFileSize <= 0 {
+		cfg.MaxFileSize = defaults.MaxFileSize
+	}
+	return &Store{cfg: cfg, now: time.Now}
+}
+
+// Create snapshots the files under root that are not ignored. Contents
+// already in the store are not written again.
+func (s *Store) Create(root, name string) (*Checkpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	root = paths.Canonical(root)
+	cp, err := s.create(root, name)
+	if err != nil {
+		return nil, err
+	}
+	s.prune(root)
+	return cp, nil
+}
+
+func (s *Store) create(root, name string) (*Checkpoint, error) {
+	files, err := s.walk(root)
+	if err != nil {
+		return nil, err
+	}
+
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, fmt.Errorf("failed to name checkpoint: %w", err)
+	}
+	now := s.now()
+	cp := &Checkpoint{
+		ID:      now.Format("20060102-150405") + "-" + hex.EncodeToString(suffix),
+		Name:    name,
+		Root:    root,
+		Created: now,
+		Files:   []File{},
+	}
+
+	for _, rel := range files {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if info.Size() > s.cfg.MaxFileSize {
+			cp.Skipped = append(cp.Skipped, rel)
+			continue
+		}
+		hash, err := s.store(path)
+		if err != nil {
+			return nil, err
+		}
+		cp.Files = append(cp.Files, File{Path: rel, Hash: hash, Mode: info.Mode().Perm(), Size: info.Size()})
+	}
+
+	if err := s.writeManifest(cp); err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// List returns the checkpoints of the workspace at root, newest first
+func (s *Store) List(root string) ([]*Checkpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.list(paths.Canonical(root))
+}
+
+// Get returns the checkpoint of root with id, or its newest one when id is
+// empty
+func (s *Store) Get(root, id string) (*Checkpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.get(paths.Canonical(root), id)
+}
+
+func (s *Store) get(root, id string) (*Checkpoint, error) {
+	if id == "" {
+		checkpoints, err := s.list(root)
+		if err != nil {
+			return nil, err
+		}
+		if len(checkpoints) == 0 {
+			return nil, fmt.Errorf("no checkpoints for %s", root)
+		}
+		return checkpoints[0], nil
+	}
+	if strings.ContainsAny(id, `/\`) || strings.HasPrefix(id, ".") {
+		return nil, fmt.Errorf("invalid checkpoint id: %s", id)
+	}
+	cp, err := readManifest(filepath.Join(s.workspaceDir(root), id+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("checkpoint not found: %s", id)
+	}
+	return cp, err
+}
+
+// Object returns the content stored under hash
+func (s *Store) Object(hash string) ([]byte, error) {
+	if len(hash) != sha256.Size*2 {
+		return nil, fmt.Errorf("invalid object hash: %s", hash)
+	}
+	data, err := os.ReadFile(s.objectPath(hash))
+	if err != nil {
+		return nil, fmt.Errorf("checkpoint content %s missing from the store: %w", hash[:12], err)
+	}
+	return data, nil
+}
+
+// Plan compares the workspace at root with its checkpoint id, or its
+// newest one when id is empty. With keepNew, files made since the
+// checkpoint are left alone instead of deleted.
+func (s *Store) Plan(root, id string, keepNew bool) (*Plan, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.plan(paths.Canonical(root), id, keepNew)
+}
+
+func (s *Store) plan(root, id string, keepNew bool) (*Plan, error) {
+	cp, err := s.get(root, id)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{Checkpoint: cp}
+	saved := make(map[string]bool, len(cp.Files)+len(cp.Skipped))
+	for _, rel := range cp.Skipped {
+		saved[rel] = true
+	}
+	for _, f := range cp.Files {
+		saved[f.Path] = true
+		path := filepath.Join(root, filepath.FromSlash(f.Path))
+		info, err := os.Lstat(path)
+		if err != nil {
+			plan.Created = append(plan.Created, f.Path)
+			continue
+		}
+		if !info.Mode().IsRegular() || info.Mode().Perm() != f.Mode {
+			plan.Modified = append(plan.Modified, f.Path)
+			continue
+		}
+		hash, err := hashFile(path)
+		if err != nil || hash != f.Hash {
+			plan.Modified = append(plan.Modified, f.Path)
+		}
+	}
+
+	if !keepNew {
+		current, err := s.walk(root)
+		if err != nil {
+			return nil, err
+		}
+		for _, rel := range current {
+			if !saved[rel] {
+				plan.Deleted = append(plan.Deleted, rel)
+			}
+		}
+	}
+	return plan, nil
+}
+
+// Restore brings the workspace at root back to its checkpoint id, or its
+// newest one when id is empty, and returns what it changed. The workspace
+// as it was is saved first, as the undo checkpoint; a restore changing
+// nothing has none.
+func (s *Store) Restore(root, id string, keepNew bool) (plan *Plan, undo *Checkpoint, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	root = paths.Canonical(root)
+	plan, err = s.plan(root, id, keepNew)
+	if err != nil || plan.Changes() == 0 {
+		return plan, nil, err
+	}
+
+	undo, err = s.create(root, "before restoring "+plan.Checkpoint.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to save the current state: %w", err)
+	}
+	// pruned after the restore, so the contents it needs are still there
+	defer s.prune(root, plan.Checkpoint.ID)
+
+	files := make(map[string]File, len(plan.Checkpoint.Files))
+	for _, f := range plan.Checkpoint.Files {
+		files[f.Path] = f
+	}
+	for _, rel := range append(append([]string(nil), plan.Created...), plan.Modified...) {
+		if err := s.restoreFile(root, files[rel]); err != nil {
+			return nil, undo, err
+		}
+	}
+	for _, rel := range plan.Deleted {
+		if err := os.Remove(filepath.Join(root, filepath.FromSlash(rel))); err != nil && !os.IsNotExist(err) {
+			return nil, undo, fmt.Errorf("failed to delete %s: %w", rel, err)
+		}
+	}
+	return plan, undo, nil
+}
+
+func (s *Store) restoreFile(root string, f File) error {
+	data, err := s.Object(f.Hash)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(root, filepath.FromSlash(f.Path))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", f.Path, err)
+	}
+	// a symlink or directory now standing at the path is replaced
+	if info, err := os.Lstat(path); err == nil && !info.Mode().IsRegular() {
+		if err := os.RemoveAll(path); err != nil {
+			return fmt.Errorf("failed to replace %s: %w", f.Path, err)
+		}
+	}
+
+	tmp := path + ".mayla-restore"
+	if err := os.WriteFile(tmp, data, f.Mode); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", f.Path, err)
+	}
+	if err := os.Chmod(tmp, f.Mode); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to restore %s: %w", f.Path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to restore %s: %w", f.Path, err)
+	}
+	return nil
+}
+
+// Files returns the files under root a checkpoint would hold, relative to
+// it and sorted, whatever their size
+func (s *Store) Files(root string) ([]string, error) {
+	return s.walk(paths.Canonical(root))
+}
+
+// walk returns the regular files under root that are not ignored, relative
+// to it and sorted
+func (s *Store) walk(root string) ([]string, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("path not found: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+
+	matcher := gitignore.NewMatcher(root)
+	var files []string
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			return nil
+		}
+		if path == root {
+			return nil
+		}
+		if matcher.Ignored(path, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			matcher.Enter(path)
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk %s: %w", root, err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// store copies the file at path into the object store unless its content
+// is already there, and returns its hash
+func (s *Store) store(path string) (string, error) {
+	hash, err := hashFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	object := s.objectPath(hash)
+	if _, err := os.Stat(object); err == nil {
+		return hash, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(object), 0700); err != nil {
+		return "", fmt.Errorf("failed to create checkpoint store: %w", err)
+	}
+	tmp := object + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to store %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, object); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("failed to store %s: %w", path, err)
+	}
+	return hash, nil
+}
+
+func (s *Store) writeManifest(cp *Checkpoint) error {
+	dir := s.workspaceDir(cp.Root)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create checkpoint store: %w", err)
+	}
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, cp.ID+".json"), data, 0600); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) list(root string) ([]*Checkpoint, error) {
+	entries, err := os.ReadDir(s.workspaceDir(root))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list checkpoints: %w", err)
+	}
+
+	var checkpoints []*Checkpoint
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		cp, err := readManifest(filepath.Join(s.workspaceDir(root), entry.Name()))
+		if err != nil {
+			continue
+		}
+		checkpoints = append(checkpoints, cp)
+	}
+	sort.Slice(checkpoints, func(i, j int) bool {
+		return checkpoints[i].Created.After(checkpoints[j].Created)
+	})
+	return checkpoints, nil
+}
+
+// prune drops the checkpoints of root beyond the newest Keep, apart from
+// the kept ones, then the contents no checkpoint of any workspace refers to
+func (s *Store) prune(root string, kept ...string) {
+	checkpoints, err := s.list(root)
+	if err != nil || len(checkpoints) <= s.cfg.Keep {
+		return
+	}
+	for _, cp := range checkpoints[s.cfg.Keep:] {
+		if !slices.Contains(kept, cp.ID) {
+			os.Remove(filepath.Join(s.workspaceDir(root), cp.ID+".json"))
+		}
+	}
+	s.collectGarbage()
+}
+
+func (s *Store) collectGarbage() {
+	manifests, err := filepath.Glob(filepath.Join(s.cfg.Dir, "workspaces", "*", "*.json"))
+	if err != nil {
+		return
+	}
+	used := make(map[string]bool)
+	for _, path := range manifests {
+		cp, err := readManifest(path)
+		if err != nil {
+			// an unreadable manifest may still need its contents
+			return
+		}
+		for _, f := range cp.Files {
+			used[f.Hash] = true
+		}
+	}
+
+	objects := filepath.Join(s.cfg.Dir, "objects")
+	filepath.WalkDir(objects, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		hash := filepath.Base(filepath.Dir(path)) + d.Name()
+		if !used[hash] {
+			os.Remove(path)
+		}
+		return nil
+	})
+}
+
+// workspaceDir holds the manifests of the workspace at root
+func (s *Store) workspaceDir(root string) string {
+	sum := sha256.Sum256([]byte(root))
+	return filepath.Join(s.cfg.Dir, "workspaces", hex.EncodeToString(sum[:8]))
+}
+
+func (s *Store) objectPath(hash string) string {
+	return filepath.Join(s.cfg.Dir, "objects", hash[:2], hash[2:])
+}
+
+func readManifest(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint %s: %w", filepath.Base(path), err)
+	}
+	return &cp, nil
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,145 @@
+package checkpoint
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestCreateAndRestore(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".gitignore"), "build/\n")
+	writeFile(t, filepath.Join(root, "main.go"), "package main\n")
+	writeFile(t, filepath.Join(root, "pkg", "util.go"), "package pkg\n")
+	writeFile(t, filepath.Join(root, "build", "out.bin"), "binary")
+
+	store := New(Config{Dir: t.TempDir()})
+	cp, err := store.Create(root, "before refactor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved []string
+	for _, f := range cp.Files {
+		saved = append(saved, f.Path)
+	}
+	if !slices.Equal(saved, []string{".gitignore", "main.go", "pkg/util.go"}) {
+		t.Fatalf("checkpoint holds %v, want the files that are not ignored", saved)
+	}
+
+	writeFile(t, filepath.Join(root, "main.go"), "package main\n\nfunc main() {}\n")
+	os.Remove(filepath.Join(root, "pkg", "util.go"))
+	writeFile(t, filepath.Join(root, "pkg", "new.go"), "package pkg\n")
+	writeFile(t, filepath.Join(root, "build", "out.bin"), "rebuilt")
+
+	plan, undo, err := store.Restore(root, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(plan.Created, []string{"pkg/util.go"}) || !slices.Equal(plan.Modified, []string{"main.go"}) || !slices.Equal(plan.Deleted, []string{"pkg/new.go"}) {
+		t.Fatalf("unexpected plan %+v", plan)
+	}
+	if readFile(t, filepath.Join(root, "main.go")) != "package main\n" || readFile(t, filepath.Join(root, "pkg", "util.go")) != "package pkg\n" {
+		t.Error("files were not restored")
+	}
+	if _, err := os.Stat(filepath.Join(root, "pkg", "new.go")); !os.IsNotExist(err) {
+		t.Error("a file made since the checkpoint was kept")
+	}
+	if readFile(t, filepath.Join(root, "build", "out.bin")) != "rebuilt" {
+		t.Error("an ignored file was restored")
+	}
+
+	// the undo checkpoint takes the restore back
+	if undo == nil {
+		t.Fatal("expected an undo checkpoint")
+	}
+	if _, _, err := store.Restore(root, undo.ID, false); err != nil {
+		t.Fatal(err)
+	}
+	if readFile(t, filepath.Join(root, "pkg", "new.go")) != "package pkg\n" {
+		t.Error("undo did not bring back the new file")
+	}
+
+	plan, undo, err = store.Restore(root, undo.ID, false)
+	if err != nil || plan.Changes() != 0 || undo != nil {
+		t.Errorf("restoring an unchanged workspace should change nothing, got %+v, %v", plan, err)
+	}
+}
+
+func TestRestoreKeepNew(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "a.txt"), "a")
+
+	store := New(Config{Dir: t.TempDir()})
+	cp, err := store.Create(root, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(root, "b.txt"), "b")
+
+	plan, err := store.Plan(root, cp.ID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Changes() != 0 {
+		t.Errorf("keep_new should leave new files alone, got %+v", plan)
+	}
+	if _, err := store.Plan(root, "../escape", false); err == nil {
+		t.Error("expected an invalid id to be rejected")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	root := t.TempDir()
+	dir := t.TempDir()
+	store := New(Config{Dir: dir, Keep: 2})
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	var first *Checkpoint
+	for i, content := range []string{"one", "two", "three"} {
+		now = now.Add(time.Minute)
+		writeFile(t, filepath.Join(root, "f.txt"), content)
+		cp, err := store.Create(root, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			first = cp
+		}
+	}
+
+	checkpoints, err := store.List(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checkpoints) != 2 || checkpoints[0].Created.Before(checkpoints[1].Created) {
+		t.Fatalf("expected the 2 newest checkpoints, newest first, got %d", len(checkpoints))
+	}
+	if _, err := store.Get(root, first.ID); err == nil {
+		t.Error("the oldest checkpoint should be pruned")
+	}
+	if _, err := store.Object(first.Files[0].Hash); err == nil {
+		t.Error("the content only the pruned checkpoint used should be collected")
+	}
+}
@@ -4,9 +4,26 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/alucardeht/may-la-mcp/internal/checkpoint"
+	"github.com/alucardeht/may-la-mcp/internal/dbcrypt"
+	"github.com/alucardeht/may-la-mcp/internal/events"
+	"github.com/alucardeht/may-la-mcp/internal/extract"
+	"github.com/alucardeht/may-la-mcp/internal/index"
+	"github.com/alucardeht/may-la-mcp/internal/injection"
+	"github.com/alucardeht/may-la-mcp/internal/intel"
+	"github.com/alucardeht/may-la-mcp/internal/license"
 	"github.com/alucardeht/may-la-mcp/internal/lsp"
+	"github.com/alucardeht/may-la-mcp/internal/memsync"
+	"github.com/alucardeht/may-la-mcp/internal/metrics"
+	"github.com/alucardeht/may-la-mcp/internal/redact"
+	"github.com/alucardeht/may-la-mcp/internal/scheduler"
+	"github.com/alucardeht/may-la-mcp/internal/spill"
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+	"github.com/alucardeht/may-la-mcp/internal/tools/memory"
 	"github.com/alucardeht/may-la-mcp/internal/watcher"
 )
 
@@ -18,12 +35,17 @@ type IndexConfig struct {
 	WorkerCount     int      `yaml:"worker_count"`
 	RateLimit       int      `yaml:"rate_limit"`
 	ExcludePatterns []string `yaml:"exclude_patterns"`
+	RecentFiles     int      `yaml:"recent_files"`
+
+	LanguageMaxFileSize map[string]int64 `yaml:"language_max_file_size"`
 }
 
 type Config struct {
 	DaemonAddr      string
 	DaemonPort      int
 	SocketPath      string
+	Transport       string
+	AuthToken       string
 	DatabasePath    string
 	LogLevel        string
 	MaxConnections  int
@@ -32,6 +54,46 @@ type Config struct {
 	Index           IndexConfig
 	LSP             lsp.ManagerConfig `yaml:"lsp"`
 	Watcher         watcher.WatcherConfig
+	Redaction       redact.Config
+	// Injection flags tool results that read like instructions to the
+	// agent; a workspace can adjust it in .mayla/injection.yaml
+	Injection       injection.Config
+	// DeniedPaths are refused to every tool, except under AllowedPaths;
+	// see tools.PathGuard
+	DeniedPaths     []string
+	AllowedPaths    []string
+	CrashDir        string
+	// AuditLog is where every change a tool makes is logged, with the
+	// client and session it came from; empty disables the log
+	AuditLog        string
+	Metrics         metrics.Config
+	Summarizer      intel.SummarizerConfig
+	// Results spills tool results over the response size limit to files
+	Results         spill.Config
+	// Checkpoints holds the workspace snapshots of checkpoint_create
+	Checkpoints     checkpoint.Config
+	// Encryption keeps the index and memory databases encrypted at rest
+	Encryption      dbcrypt.Config
+	// Events delivers significant daemon events to webhooks and commands
+	Events          events.Config
+	// MemorySync shares memories with a team through a remote
+	MemorySync      memsync.Config
+	// AutoMemory drafts memories from tool activity for review
+	AutoMemory      memory.SuggestConfig
+	// Scheduler runs the periodic maintenance jobs and backups
+	Scheduler       scheduler.Config
+	// StorageBackend stores the index and memories (sqlite by default)
+	StorageBackend  string
+	// ReadOnly disables every mutating tool for all sessions
+	ReadOnly        bool
+	// DryRun makes every session preview mutating tools instead of applying them
+	DryRun          bool
+	// NamespacedTools lists tools by qualified name (files/read, memory/read)
+	NamespacedTools bool
+	// Extract returns and indexes the text of PDF and DOCX documents
+	Extract         extract.Config
+	// License is the policy license_check enforces
+	License         license.Config
 }
 
 func Load() *Config {
@@ -42,9 +104,11 @@ func Load() *Config {
 	indexDBPath := filepath.Join(maylaDir, "index.db")
 
 	return &Config{
-		DaemonAddr:     "127.0.0.1",
-		DaemonPort:     8765,
+		DaemonAddr:     daemonAddr(),
+		DaemonPort:     daemonPort(),
 		SocketPath:     socketPath,
+		Transport:      transport(),
+		AuthToken:      os.Getenv("MAYLA_AUTH_TOKEN"),
 		DatabasePath:   dbPath,
 		LogLevel:       "info",
 		MaxConnections: 100,
@@ -55,6 +119,7 @@ func Load() *Config {
 			MaxQueueSize: 1000,
 			WorkerCount:  2,
 			RateLimit:    100,
+			RecentFiles:  50,
 			ExcludePatterns: []string{
 				"**/node_modules/**",
 				"**/.git/**",
@@ -64,6 +129,7 @@ func Load() *Config {
 				"**/build/**",
 				"**/dist/**",
 			},
+			LanguageMaxFileSize: index.DefaultLanguageMaxFileSize(),
 		},
 		LSP: lsp.DefaultManagerConfig(),
 		Watcher: watcher.WatcherConfig{
@@ -82,8 +148,253 @@ func Load() *Config {
 				"**/vendor/**",
 			},
 			WatchHidden: false,
+			JournalPath: filepath.Join(maylaDir, "changes.jsonl"),
 		},
+		Redaction:    redactionConfig(),
+		Injection:    injectionConfig(),
+		DeniedPaths:  deniedPaths(),
+		AllowedPaths: envPaths("MAYLA_ALLOWED_PATHS"),
+		CrashDir:     filepath.Join(maylaDir, "crashes"),
+		AuditLog:     filepath.Join(maylaDir, "audit.log"),
+		Metrics:      metrics.DefaultConfig(),
+		Summarizer:   summarizerConfig(),
+		Results:      resultsConfig(),
+		Checkpoints:  checkpointsConfig(),
+		Encryption:   encryptionConfig(),
+		MemorySync:   memorySyncConfig(),
+		AutoMemory:   autoMemoryConfig(),
+		Events:       eventsConfig(),
+		Scheduler:    schedulerConfig(),
+		Extract:      extractConfig(),
+		License:      licenseConfig(),
+		ReadOnly:     envFlag("MAYLA_READ_ONLY"),
+		DryRun:       envFlag("MAYLA_DRY_RUN"),
+
+		NamespacedTools: envFlag("MAYLA_NAMESPACED_TOOLS"),
+		StorageBackend:  os.Getenv("MAYLA_STORAGE_BACKEND"),
+	}
+}
+
+// transport reads how clients reach the daemon from MAYLA_TRANSPORT: unix,
+// the default, for the instance socket, or tcp for MAYLA_DAEMON_ADDR and
+// MAYLA_DAEMON_PORT, which also needs MAYLA_AUTH_TOKEN
+func transport() string {
+	if t := strings.ToLower(strings.TrimSpace(os.Getenv("MAYLA_TRANSPORT"))); t != "" {
+		return t
+	}
+	return "unix"
+}
+
+// daemonAddr is the host the tcp transport listens on and dials, loopback
+// unless MAYLA_DAEMON_ADDR says otherwise
+func daemonAddr() string {
+	if addr := strings.TrimSpace(os.Getenv("MAYLA_DAEMON_ADDR")); addr != "" {
+		return addr
 	}
+	return "127.0.0.1"
+}
+
+// daemonPort is the port of the tcp transport, from MAYLA_DAEMON_PORT
+func daemonPort() int {
+	if port, err := strconv.Atoi(os.Getenv("MAYLA_DAEMON_PORT")); err == nil && port > 0 && port <= 65535 {
+		return port
+	}
+	return 8765
+}
+
+// redactionConfig redacts secrets from tool results; MAYLA_REDACT_OPT_OUT
+// lets individual calls ask for raw output with "redact": false
+func redactionConfig() redact.Config {
+	cfg := redact.DefaultConfig()
+	cfg.AllowOptOut = envFlag("MAYLA_REDACT_OPT_OUT")
+	return cfg
+}
+
+// injectionConfig turns prompt injection scanning on with
+// MAYLA_INJECTION_SCAN. MAYLA_INJECTION_SKIP_TOOLS lists tools, comma
+// separated, whose results are not scanned, and
+// MAYLA_INJECTION_TRUST_WORKSPACE lets workspace files turn scanning off
+// and skip tools too.
+func injectionConfig() injection.Config {
+	cfg := injection.Config{
+		Enabled:        envFlag("MAYLA_INJECTION_SCAN"),
+		TrustWorkspace: envFlag("MAYLA_INJECTION_TRUST_WORKSPACE"),
+	}
+	for _, name := range strings.Split(os.Getenv("MAYLA_INJECTION_SKIP_TOOLS"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			cfg.SkipTools = append(cfg.SkipTools, name)
+		}
+	}
+	return cfg
+}
+
+// deniedPaths is the default denylist plus the paths in MAYLA_DENIED_PATHS;
+// MAYLA_ALLOWED_PATHS lists exceptions to it
+func deniedPaths() []string {
+	return append(tools.DefaultDeniedPaths(), envPaths("MAYLA_DENIED_PATHS")...)
+}
+
+// envPaths returns the paths of the environment variable, a list separated
+// like PATH
+func envPaths(name string) []string {
+	var list []string
+	for _, path := range filepath.SplitList(os.Getenv(name)) {
+		if path = strings.TrimSpace(path); path != "" {
+			list = append(list, path)
+		}
+	}
+	return list
+}
+
+// envFlag reports whether the environment variable is set to a true value
+func envFlag(name string) bool {
+	v, err := strconv.ParseBool(os.Getenv(name))
+	return err == nil && v
+}
+
+// extractConfig turns document text extraction on with
+// MAYLA_EXTRACT_DOCUMENTS; MAYLA_EXTRACT_MAX_SIZE sets the largest document
+// extracted, in bytes (0 for no limit)
+func extractConfig() extract.Config {
+	cfg := extract.DefaultConfig()
+	cfg.Enabled = envFlag("MAYLA_EXTRACT_DOCUMENTS")
+	if size, err := strconv.ParseInt(os.Getenv("MAYLA_EXTRACT_MAX_SIZE"), 10, 64); err == nil && size >= 0 {
+		cfg.MaxSize = size
+	}
+	return cfg
+}
+
+// summarizerConfig enables the LLM summarizer only when MAYLA_SUMMARIZER
+// names a provider (ollama or openai); MAYLA_SUMMARIZER_ENDPOINT,
+// MAYLA_SUMMARIZER_MODEL and MAYLA_SUMMARIZER_API_KEY override its defaults
+func summarizerConfig() intel.SummarizerConfig {
+	cfg := intel.DefaultSummarizerConfig()
+	if provider := os.Getenv("MAYLA_SUMMARIZER"); provider != "" {
+		cfg.Enabled = true
+		cfg.Provider = provider
+	}
+	cfg.Endpoint = os.Getenv("MAYLA_SUMMARIZER_ENDPOINT")
+	cfg.Model = os.Getenv("MAYLA_SUMMARIZER_MODEL")
+	cfg.APIKey = os.Getenv("MAYLA_SUMMARIZER_API_KEY")
+	return cfg
+}
+
+// resultsConfig reads the response size limit, in bytes (0 disables
+// spilling), from MAYLA_MAX_RESPONSE_SIZE and how long spilled results are
+// kept from MAYLA_RESULTS_TTL (e.g., 6h)
+func resultsConfig() spill.Config {
+	cfg := spill.DefaultConfig()
+	if size, err := strconv.Atoi(os.Getenv("MAYLA_MAX_RESPONSE_SIZE")); err == nil && size >= 0 {
+		cfg.MaxResponseSize = size
+	}
+	if ttl, err := time.ParseDuration(os.Getenv("MAYLA_RESULTS_TTL")); err == nil && ttl > 0 {
+		cfg.TTL = ttl
+	}
+	return cfg
+}
+
+// checkpointsConfig reads how many checkpoints are kept per workspace from
+// MAYLA_CHECKPOINT_KEEP
+func checkpointsConfig() checkpoint.Config {
+	cfg := checkpoint.DefaultConfig()
+	if keep, err := strconv.Atoi(os.Getenv("MAYLA_CHECKPOINT_KEEP")); err == nil && keep > 0 {
+		cfg.Keep = keep
+	}
+	return cfg
+}
+
+// encryptionConfig turns database encryption on with MAYLA_DB_ENCRYPTION;
+// the passphrase is MAYLA_DB_PASSPHRASE or, with MAYLA_DB_KEYCHAIN, read
+// from the OS keychain. The passphrase alone, without encryption on, lets
+// an encrypted database be decrypted back to a plain one.
+func encryptionConfig() dbcrypt.Config {
+	return dbcrypt.Config{
+		Enabled:    envFlag("MAYLA_DB_ENCRYPTION"),
+		Passphrase: os.Getenv("MAYLA_DB_PASSPHRASE"),
+		Keychain:   envFlag("MAYLA_DB_KEYCHAIN"),
+	}
+}
+
+// memorySyncConfig shares memories through the remote in MAYLA_MEMORY_SYNC
+// (an http(s), s3 or git URL), authenticated to HTTP endpoints with
+// MAYLA_MEMORY_SYNC_TOKEN. MAYLA_MEMORY_SYNC_POLICY sets the category
+// policies and MAYLA_MEMORY_SYNC_INTERVAL how often to sync (e.g., 10m).
+func memorySyncConfig() memsync.Config {
+	cfg := memsync.Config{
+		Remote:   os.Getenv("MAYLA_MEMORY_SYNC"),
+		Token:    os.Getenv("MAYLA_MEMORY_SYNC_TOKEN"),
+		Policies: os.Getenv("MAYLA_MEMORY_SYNC_POLICY"),
+	}
+	if interval, err := time.ParseDuration(os.Getenv("MAYLA_MEMORY_SYNC_INTERVAL")); err == nil && interval > 0 {
+		cfg.Interval = interval
+	}
+	return cfg
+}
+
+// autoMemoryConfig turns memory suggestions on with MAYLA_AUTO_MEMORY;
+// MAYLA_AUTO_MEMORY_THRESHOLD sets how many times a query is searched for
+// before it is suggested
+func autoMemoryConfig() memory.SuggestConfig {
+	cfg := memory.SuggestConfig{Enabled: envFlag("MAYLA_AUTO_MEMORY")}
+	if threshold, err := strconv.Atoi(os.Getenv("MAYLA_AUTO_MEMORY_THRESHOLD")); err == nil && threshold > 0 {
+		cfg.RepeatThreshold = threshold
+	}
+	return cfg
+}
+
+// eventsConfig delivers daemon events to the webhook in MAYLA_EVENT_WEBHOOK
+// (a Slack incoming webhook works as is) and to the shell command in
+// MAYLA_EVENT_COMMAND. MAYLA_EVENTS limits both to a comma-separated list
+// of event types, with wildcards such as lsp.*.
+func eventsConfig() events.Config {
+	webhook := os.Getenv("MAYLA_EVENT_WEBHOOK")
+	command := os.Getenv("MAYLA_EVENT_COMMAND")
+	if webhook == "" && command == "" {
+		return events.Config{}
+	}
+
+	var filter []string
+	for _, pattern := range strings.Split(os.Getenv("MAYLA_EVENTS"), ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			filter = append(filter, pattern)
+		}
+	}
+	return events.Config{Subscribers: []events.SubscriberConfig{
+		{Events: filter, Webhook: webhook, Command: command},
+	}}
+}
+
+// schedulerConfig reads job schedule overrides from MAYLA_SCHEDULE, e.g.
+// "reindex_verify=0 2 * * *;index_gc=off", and turns nightly backups on
+// with MAYLA_BACKUP_DIR, keeping the last MAYLA_BACKUP_KEEP of them
+func schedulerConfig() scheduler.Config {
+	cfg := scheduler.Config{
+		Schedules:  os.Getenv("MAYLA_SCHEDULE"),
+		BackupDir:  os.Getenv("MAYLA_BACKUP_DIR"),
+		BackupKeep: 7,
+	}
+	if keep, err := strconv.Atoi(os.Getenv("MAYLA_BACKUP_KEEP")); err == nil && keep > 0 {
+		cfg.BackupKeep = keep
+	}
+	return cfg
+}
+
+// licenseConfig reads the SPDX identifiers dependencies may use from
+// MAYLA_LICENSE_ALLOW (comma-separated) and the header template source
+// files must start with from the file in MAYLA_LICENSE_HEADER_FILE
+func licenseConfig() license.Config {
+	var cfg license.Config
+	for _, id := range strings.Split(os.Getenv("MAYLA_LICENSE_ALLOW"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			cfg.Allow = append(cfg.Allow, id)
+		}
+	}
+	if file := os.Getenv("MAYLA_LICENSE_HEADER_FILE"); file != "" {
+		if data, err := os.ReadFile(file); err == nil {
+			cfg.Header = string(data)
+		}
+	}
+	return cfg
 }
 
 func (c *Config) EnsureDirectories() error {
@@ -115,9 +426,11 @@ func LoadConfigWithInstance(instanceID string) (*Config, error) {
 	}
 
 	return &Config{
-		DaemonAddr:     "127.0.0.1",
-		DaemonPort:     8765,
+		DaemonAddr:     daemonAddr(),
+		DaemonPort:     daemonPort(),
 		SocketPath:     filepath.Join(instanceDir, "daemon.sock"),
+		Transport:      transport(),
+		AuthToken:      os.Getenv("MAYLA_AUTH_TOKEN"),
 		DatabasePath:   filepath.Join(instanceDir, "mayla.db"),
 		LogLevel:       "info",
 		MaxConnections: 100,
@@ -130,6 +443,7 @@ func LoadConfigWithInstance(instanceID string) (*Config, error) {
 			MaxQueueSize: 1000,
 			WorkerCount:  2,
 			RateLimit:    100,
+			RecentFiles:  50,
 			ExcludePatterns: []string{
 				"**/node_modules/**",
 				"**/.git/**",
@@ -139,6 +453,7 @@ func LoadConfigWithInstance(instanceID string) (*Config, error) {
 				"**/build/**",
 				"**/dist/**",
 			},
+			LanguageMaxFileSize: index.DefaultLanguageMaxFileSize(),
 		},
 		LSP: lsp.DefaultManagerConfig(),
 		Watcher: watcher.WatcherConfig{
@@ -157,6 +472,29 @@ func LoadConfigWithInstance(instanceID string) (*Config, error) {
 				"**/vendor/**",
 			},
 			WatchHidden: false,
+			JournalPath: filepath.Join(instanceDir, "changes.jsonl"),
 		},
+		Redaction:    redactionConfig(),
+		Injection:    injectionConfig(),
+		DeniedPaths:  deniedPaths(),
+		AllowedPaths: envPaths("MAYLA_ALLOWED_PATHS"),
+		CrashDir:     filepath.Join(maylaDir, "crashes"),
+		AuditLog:     filepath.Join(instanceDir, "audit.log"),
+		Metrics:      metrics.DefaultConfig(),
+		Summarizer:   summarizerConfig(),
+		Results:      resultsConfig(),
+		Checkpoints:  checkpointsConfig(),
+		Encryption:   encryptionConfig(),
+		MemorySync:   memorySyncConfig(),
+		AutoMemory:   autoMemoryConfig(),
+		Events:       eventsConfig(),
+		Scheduler:    schedulerConfig(),
+		Extract:      extractConfig(),
+		License:      licenseConfig(),
+		ReadOnly:     envFlag("MAYLA_READ_ONLY"),
+		DryRun:       envFlag("MAYLA_DRY_RUN"),
+
+		NamespacedTools: envFlag("MAYLA_NAMESPACED_TOOLS"),
+		StorageBackend:  os.Getenv("MAYLA_STORAGE_BACKEND"),
 	}, nil
 }
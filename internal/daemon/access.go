@@ -0,0 +1,99 @@
+package daemon
+
+import (
+	"sync"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/paths"
+)
+
+const (
+	accessBuffer        = 1024
+	accessFlushInterval = time.Second
+	accessFlushBatch    = 256
+)
+
+// accessLog queues the files the tools report as accessed and stores them
+// in batches from its own goroutine, so a search hitting hundreds of files
+// does not wait on the index.
+type accessLog struct {
+	write  func(paths ...string) error
+	paths  chan string
+	done   chan struct{}
+	mu     sync.RWMutex
+	closed bool
+}
+
+func newAccessLog(write func(paths ...string) error) *accessLog {
+	l := &accessLog{
+		write: write,
+		paths: make(chan string, accessBuffer),
+		done:  make(chan struct{}),
+	}
+	go l.writeLoop()
+	return l
+}
+
+// Record queues path; it is dropped when the queue is full
+func (l *accessLog) Record(path string) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.closed {
+		return
+	}
+
+	select {
+	case l.paths <- path:
+	default:
+		log.Debug("access queue full, dropping file access", "path", path)
+	}
+}
+
+func (l *accessLog) writeLoop() {
+	defer close(l.done)
+
+	ticker := time.NewTicker(accessFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]string, 0, accessFlushBatch)
+	for {
+		select {
+		case path, ok := <-l.paths:
+			if !ok {
+				l.flush(batch)
+				return
+			}
+			batch = append(batch, paths.Canonical(path))
+			if len(batch) >= accessFlushBatch {
+				l.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			l.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+func (l *accessLog) flush(batch []string) {
+	if len(batch) == 0 {
+		return
+	}
+	if err := l.write(batch...); err != nil {
+		log.Debug("failed to record file accesses", "count", len(batch), "error", err)
+	}
+}
+
+// Close stores the queued accesses and stops the writer
+func (l *accessLog) Close() {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return
+	}
+	l.closed = true
+	close(l.paths)
+	l.mu.Unlock()
+
+	<-l.done
+}
@@ -0,0 +1,39 @@
+package daemon
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestAccessLogBatches(t *testing.T) {
+	var mu sync.Mutex
+	var batches [][]string
+	l := newAccessLog(func(paths ...string) error {
+		mu.Lock()
+		batches = append(batches, append([]string(nil), paths...))
+		mu.Unlock()
+		return nil
+	})
+
+	dir := t.TempDir()
+	for _, name := range []string{"a.go", "b.go", "c.go"} {
+		l.Record(filepath.Join(dir, name))
+	}
+	l.Close()
+	l.Record(filepath.Join(dir, "late.go"))
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(batches) != 1 {
+		t.Fatalf("Expected one batch, got %d: %v", len(batches), batches)
+	}
+	if len(batches[0]) != 3 {
+		t.Fatalf("Expected three paths in the batch, got %v", batches[0])
+	}
+	for _, path := range batches[0] {
+		if !filepath.IsAbs(path) {
+			t.Errorf("Expected a canonical path, got %q", path)
+		}
+	}
+}
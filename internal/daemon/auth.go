@@ -0,0 +1,107 @@
+package daemon
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/pkg/protocol"
+)
+
+// maxHelloSize bounds the payload of the hello frame, which is read before
+// the peer is authenticated
+const maxHelloSize = 4 << 10
+
+var (
+	ErrPeerRejected = errors.New("connection rejected: peer belongs to another user")
+	ErrAuthFailed   = errors.New("authentication failed")
+
+	errPeerCredUnsupported = errors.New("peer credentials not supported on this platform")
+
+	// daemonUID is the user peers must run as, replaced in tests
+	daemonUID = os.Getuid
+)
+
+// verifyPeer rejects Unix socket connections whose peer process is owned by
+// a different user than the daemon. Non-Unix connections are left to the
+// shared-secret handshake.
+func verifyPeer(conn net.Conn) error {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return nil
+	}
+
+	uid, err := peerUID(unixConn)
+	if err != nil {
+		if errors.Is(err, errPeerCredUnsupported) {
+			return nil
+		}
+		return fmt.Errorf("failed to read peer credentials: %w", err)
+	}
+
+	if uid != daemonUID() {
+		return fmt.Errorf("%w (uid %d)", ErrPeerRejected, uid)
+	}
+
+	return nil
+}
+
+// handshake reads the client's Hello frame, checks its wire version and,
+// when the daemon is configured with a shared secret, its token, then answers
+// with the daemon's own Hello. It returns the client's Hello.
+func (d *Daemon) handshake(conn net.Conn, reader *protocol.FrameReader, writer *protocol.FrameWriter) (*protocol.Hello, error) {
+	if err := conn.SetDeadline(time.Now().Add(10 * time.Second)); err != nil {
+		return nil, err
+	}
+
+	frame, err := reader.ReadLimited(maxHelloSize)
+	if err != nil {
+		if errors.Is(err, protocol.ErrUnsupportedVersion) || errors.Is(err, protocol.ErrFrameTooLarge) {
+			writer.Write(errorFrame(0, err))
+		}
+		return nil, fmt.Errorf("failed to read hello: %w", err)
+	}
+
+	if frame.Type != protocol.FrameHello {
+		err := fmt.Errorf("expected hello frame, got %s", frame.Type)
+		writer.Write(errorFrame(frame.ID, err))
+		return nil, err
+	}
+
+	var hello protocol.Hello
+	if err := json.Unmarshal(frame.Payload, &hello); err != nil {
+		err = fmt.Errorf("invalid hello: %w", err)
+		writer.Write(errorFrame(frame.ID, err))
+		return nil, err
+	}
+
+	if hello.Version != protocol.WireVersion {
+		err := fmt.Errorf("%w: client speaks %d, daemon %d", protocol.ErrUnsupportedVersion, hello.Version, protocol.WireVersion)
+		writer.Write(errorFrame(frame.ID, err))
+		return nil, err
+	}
+
+	if d.config.AuthToken != "" &&
+		subtle.ConstantTimeCompare([]byte(hello.Token), []byte(d.config.AuthToken)) != 1 {
+		writer.Write(errorFrame(frame.ID, ErrAuthFailed))
+		return nil, ErrAuthFailed
+	}
+
+	payload, err := json.Marshal(protocol.Hello{Version: protocol.WireVersion})
+	if err != nil {
+		return nil, err
+	}
+	if err := writer.Write(&protocol.Frame{Type: protocol.FrameHello, ID: frame.ID, Payload: payload}); err != nil {
+		return nil, err
+	}
+
+	return &hello, conn.SetDeadline(time.Time{})
+}
+
+func errorFrame(id uint64, err error) *protocol.Frame {
+	return &protocol.Frame{Type: protocol.FrameError, ID: id, Payload: []byte(err.Error())}
+}
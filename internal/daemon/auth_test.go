@@ -0,0 +1,141 @@
+package daemon
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/alucardeht/may-la-mcp/internal/config"
+	"github.com/alucardeht/may-la-mcp/pkg/protocol"
+)
+
+// shakeHands runs the daemon side of the handshake against a client
+// sending hello, returning the frame it answered with and its error
+func shakeHands(t *testing.T, token string, hello protocol.Hello) (*protocol.Frame, error) {
+	t.Helper()
+	d := &Daemon{config: &config.Config{AuthToken: token}}
+
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := d.handshake(server, protocol.NewFrameReader(server), protocol.NewFrameWriter(server))
+		done <- err
+	}()
+
+	payload, _ := json.Marshal(hello)
+	if err := protocol.NewFrameWriter(client).Write(&protocol.Frame{Type: protocol.FrameHello, ID: 1, Payload: payload}); err != nil {
+		t.Fatal(err)
+	}
+	reply, err := protocol.NewFrameReader(client).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reply, <-done
+}
+
+func TestHandshakeToken(t *testing.T) {
+	tests := []struct {
+		name      string
+		token     string
+		sent      string
+		wantError bool
+	}{
+		{"no token configured", "", "", false},
+		{"matching token", "s3cret", "s3cret", false},
+		{"wrong token", "s3cret", "guess", true},
+		{"missing token", "s3cret", "", true},
+		{"token prefix", "s3cret", "s3c", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reply, err := shakeHands(t, tt.token, protocol.Hello{Version: protocol.WireVersion, Token: tt.sent})
+			if !tt.wantError {
+				if err != nil || reply.Type != protocol.FrameHello {
+					t.Fatalf("expected the handshake to succeed, got %v and a %s frame", err, reply.Type)
+				}
+				return
+			}
+			if !errors.Is(err, ErrAuthFailed) {
+				t.Errorf("expected ErrAuthFailed, got %v", err)
+			}
+			if reply.Type != protocol.FrameError || !strings.Contains(string(reply.Payload), "authentication failed") {
+				t.Errorf("expected an authentication error frame, got %s %q", reply.Type, reply.Payload)
+			}
+		})
+	}
+}
+
+func TestHandshakeHelloSize(t *testing.T) {
+	d := &Daemon{config: &config.Config{}}
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := d.handshake(server, protocol.NewFrameReader(server), protocol.NewFrameWriter(server))
+		done <- err
+	}()
+
+	// only the length of a frame announcing a 1 MiB payload is sent: the
+	// daemon must refuse it without waiting for, or allocating, the rest
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], 10+1<<20)
+	if _, err := client.Write(length[:]); err != nil {
+		t.Fatal(err)
+	}
+	reply, err := protocol.NewFrameReader(client).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Type != protocol.FrameError {
+		t.Errorf("expected an error frame, got %s", reply.Type)
+	}
+	if err := <-done; !errors.Is(err, protocol.ErrFrameTooLarge) {
+		t.Errorf("expected ErrFrameTooLarge, got %v", err)
+	}
+}
+
+func TestVerifyPeerUID(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skip("peer credentials are only read on Linux and macOS")
+	}
+
+	path := filepath.Join(t.TempDir(), "d.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	client, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if err := verifyPeer(conn); err != nil {
+		t.Fatalf("expected a peer of the same user to be accepted, got %v", err)
+	}
+
+	original := daemonUID
+	defer func() { daemonUID = original }()
+	daemonUID = func() int { return original() + 1 }
+
+	if err := verifyPeer(conn); !errors.Is(err, ErrPeerRejected) {
+		t.Errorf("expected a peer of another user to be rejected, got %v", err)
+	}
+}
@@ -1,8 +1,9 @@
 package daemon
 
 import (
-	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"sync"
@@ -12,63 +13,241 @@ import (
 	"github.com/alucardeht/may-la-mcp/pkg/protocol"
 )
 
+const (
+	requestTimeout   = 5 * time.Minute
+	handshakeTimeout = 10 * time.Second
+	writeTimeout     = 30 * time.Second
+)
+
+var ErrConnectionClosed = errors.New("daemon connection closed")
+
+// remoteError is a failure the daemon reported in an Error frame
+type remoteError struct {
+	message string
+}
+
+func (e *remoteError) Error() string {
+	return "daemon error: " + e.message
+}
+
+// Client talks to a daemon over the framed wire protocol. Requests are
+// multiplexed by frame ID, so several may be in flight at once and one that
+// times out or is cancelled does not poison the connection.
 type Client struct {
-	conn    net.Conn
-	writer  *bufio.Writer
-	encoder *json.Encoder
-	decoder *json.Decoder
-	mu      sync.Mutex
-	healthy atomic.Bool
+	conn      net.Conn
+	reader    *protocol.FrameReader
+	writer    *protocol.FrameWriter
+	nextID    atomic.Uint64
+	mu        sync.Mutex
+	pending   map[uint64]chan *protocol.Frame
+	healthy   atomic.Bool
+	done      chan struct{}
+	closeOnce sync.Once
+	onNotify  atomic.Pointer[NotificationHandler]
 }
 
+// NotificationHandler receives the JSON-RPC notifications the daemon pushes
+// to the client, such as indexing progress and file changes, and the
+// progress notifications streaming partial results of pending requests.
+type NotificationHandler func(notification json.RawMessage)
+
 func NewClient(conn net.Conn) *Client {
-	writer := bufio.NewWriter(conn)
 	c := &Client{
 		conn:    conn,
-		writer:  writer,
-		encoder: json.NewEncoder(writer),
-		decoder: json.NewDecoder(conn),
+		reader:  protocol.NewFrameReader(conn),
+		writer:  protocol.NewFrameWriter(conn),
+		pending: make(map[uint64]chan *protocol.Frame),
+		done:    make(chan struct{}),
 	}
 	c.healthy.Store(true)
+	go c.readLoop()
 	return c
 }
 
-func (c *Client) SendRequest(req *protocol.JSONRPCRequest) (*protocol.JSONRPCResponse, error) {
+func (c *Client) readLoop() {
+	defer c.markClosed()
+
+	for {
+		frame, err := c.reader.Read()
+		if err != nil {
+			return
+		}
+
+		if frame.ID == 0 && frame.Type == protocol.FrameError {
+			log.Warn("daemon closed connection", "reason", string(frame.Payload))
+			return
+		}
+
+		if frame.Type == protocol.FrameNotification {
+			c.notify(frame.Payload)
+			continue
+		}
+
+		if frame.Type == protocol.FramePartial {
+			// Partial results of cancelled requests are dropped like
+			// their replies
+			c.mu.Lock()
+			_, ok := c.pending[frame.ID]
+			c.mu.Unlock()
+			if ok {
+				c.notify(frame.Payload)
+			}
+			continue
+		}
+
+		c.mu.Lock()
+		ch, ok := c.pending[frame.ID]
+		delete(c.pending, frame.ID)
+		c.mu.Unlock()
+
+		// Replies to cancelled requests have no waiter and are dropped
+		if ok {
+			ch <- frame
+		}
+	}
+}
+
+func (c *Client) notify(notification json.RawMessage) {
+	if h := c.onNotify.Load(); h != nil {
+		(*h)(notification)
+	}
+}
+
+func (c *Client) markClosed() {
+	c.healthy.Store(false)
+	c.closeOnce.Do(func() { close(c.done) })
+}
+
+func (c *Client) write(frame *protocol.Frame) error {
+	if err := c.conn.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+		c.markClosed()
+		return fmt.Errorf("set write deadline: %w", err)
+	}
+	if err := c.writer.Write(frame); err != nil {
+		c.markClosed()
+		return err
+	}
+	return nil
+}
+
+// roundTrip sends a frame and waits for the daemon's reply carrying the same
+// ID. If ctx ends first, an in-flight request is cancelled on the daemon.
+func (c *Client) roundTrip(ctx context.Context, frameType protocol.FrameType, payload []byte) (*protocol.Frame, error) {
 	if !c.healthy.Load() {
 		return nil, fmt.Errorf("connection unhealthy")
 	}
 
+	id := c.nextID.Add(1)
+	ch := make(chan *protocol.Frame, 1)
+
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	c.pending[id] = ch
+	c.mu.Unlock()
 
-	if err := c.conn.SetWriteDeadline(time.Now().Add(30 * time.Second)); err != nil {
-		c.healthy.Store(false)
-		return nil, fmt.Errorf("set write deadline: %w", err)
+	forget := func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
 	}
 
-	if err := c.encoder.Encode(req); err != nil {
-		c.healthy.Store(false)
+	if err := c.write(&protocol.Frame{Type: frameType, ID: id, Payload: payload}); err != nil {
+		forget()
 		return nil, err
 	}
-	if err := c.writer.Flush(); err != nil {
-		c.healthy.Store(false)
-		return nil, err
+
+	select {
+	case frame := <-ch:
+		if frame.Type == protocol.FrameError {
+			return nil, &remoteError{message: string(frame.Payload)}
+		}
+		return frame, nil
+	case <-ctx.Done():
+		forget()
+		if frameType == protocol.FrameRequest {
+			c.write(&protocol.Frame{Type: protocol.FrameCancel, ID: id})
+		}
+		return nil, ctx.Err()
+	case <-c.done:
+		return nil, ErrConnectionClosed
 	}
+}
 
-	if err := c.conn.SetReadDeadline(time.Now().Add(5 * time.Minute)); err != nil {
-		c.healthy.Store(false)
-		return nil, fmt.Errorf("set read deadline: %w", err)
+// Handshake opens the session: it sends the wire version, the shared secret
+// and session options from hello, and waits for the daemon to accept them.
+// It must be the first call on a new client.
+func (c *Client) Handshake(hello protocol.Hello) error {
+	hello.Version = protocol.WireVersion
+	payload, err := json.Marshal(hello)
+	if err != nil {
+		return err
 	}
 
-	var resp protocol.JSONRPCResponse
-	if err := c.decoder.Decode(&resp); err != nil {
-		c.healthy.Store(false)
+	ctx, cancel := context.WithTimeout(context.Background(), handshakeTimeout)
+	defer cancel()
+
+	frame, err := c.roundTrip(ctx, protocol.FrameHello, payload)
+	if err != nil {
+		c.markClosed()
+		var remote *remoteError
+		if errors.As(err, &remote) && remote.message == ErrAuthFailed.Error() {
+			return ErrAuthFailed
+		}
+		return fmt.Errorf("handshake failed: %w", err)
+	}
+	if frame.Type != protocol.FrameHello {
+		c.markClosed()
+		return fmt.Errorf("handshake failed: unexpected %s frame", frame.Type)
+	}
+
+	var reply protocol.Hello
+	if err := json.Unmarshal(frame.Payload, &reply); err != nil {
+		c.markClosed()
+		return fmt.Errorf("handshake failed: %w", err)
+	}
+	if reply.Version != protocol.WireVersion {
+		c.markClosed()
+		return fmt.Errorf("%w: daemon speaks %d, client %d", protocol.ErrUnsupportedVersion, reply.Version, protocol.WireVersion)
+	}
+
+	return nil
+}
+
+// SendRequest forwards a JSON-RPC request and waits up to five minutes for
+// its response. Cancelling ctx cancels the request on the daemon.
+func (c *Client) SendRequest(ctx context.Context, req *protocol.JSONRPCRequest) (*protocol.JSONRPCResponse, error) {
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	frame, err := c.roundTrip(ctx, protocol.FrameRequest, payload)
+	if err != nil {
 		return nil, err
 	}
 
+	var resp protocol.JSONRPCResponse
+	if err := json.Unmarshal(frame.Payload, &resp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
 	return &resp, nil
 }
 
+// Ping sends a heartbeat and waits for the daemon's pong
+func (c *Client) Ping(ctx context.Context) error {
+	frame, err := c.roundTrip(ctx, protocol.FramePing, nil)
+	if err != nil {
+		return err
+	}
+	if frame.Type != protocol.FramePong {
+		return fmt.Errorf("unexpected %s frame in reply to ping", frame.Type)
+	}
+	return nil
+}
+
 func (c *Client) Call(method string, params map[string]interface{}) (interface{}, error) {
 	req := &protocol.JSONRPCRequest{
 		JSONRPC: "2.0",
@@ -77,7 +256,7 @@ func (c *Client) Call(method string, params map[string]interface{}) (interface{}
 		Params:  params,
 	}
 
-	resp, err := c.SendRequest(req)
+	resp, err := c.SendRequest(context.Background(), req)
 	if err != nil {
 		return nil, err
 	}
@@ -89,11 +268,22 @@ func (c *Client) Call(method string, params map[string]interface{}) (interface{}
 	return resp.Result, nil
 }
 
+// SetNotificationHandler installs h to receive daemon notifications;
+// notifications arriving while no handler is set are dropped.
+func (c *Client) SetNotificationHandler(h NotificationHandler) {
+	if h == nil {
+		c.onNotify.Store(nil)
+		return
+	}
+	c.onNotify.Store(&h)
+}
+
 func (c *Client) IsHealthy() bool {
 	return c.healthy.Load()
 }
 
 func (c *Client) Close() error {
+	c.markClosed()
 	if c.conn != nil {
 		return c.conn.Close()
 	}
@@ -0,0 +1,97 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/pkg/version"
+)
+
+// maxCrashReports bounds how many crash files are kept on disk
+const maxCrashReports = 50
+
+// CrashReporter writes a report for every recovered panic and counts them
+type CrashReporter struct {
+	dir   string
+	count atomic.Int64
+	mu    sync.Mutex
+	last  string
+}
+
+func NewCrashReporter(dir string) *CrashReporter {
+	return &CrashReporter{dir: dir}
+}
+
+// Report logs the panic, writes a crash file and returns its name as the
+// crash identifier.
+func (c *CrashReporter) Report(component string, value interface{}, stack []byte) string {
+	n := c.count.Add(1)
+	log.Error("panic recovered", "component", component, "panic", value, "stack", string(stack))
+
+	if c.dir == "" {
+		return ""
+	}
+
+	now := time.Now()
+	name := fmt.Sprintf("crash-%s-%d.log", now.Format("20060102-150405.000"), n)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "time: %s\n", now.Format(time.RFC3339Nano))
+	fmt.Fprintf(&b, "version: %s\n", version.Version)
+	fmt.Fprintf(&b, "pid: %d\n", os.Getpid())
+	fmt.Fprintf(&b, "component: %s\n", component)
+	fmt.Fprintf(&b, "panic: %v\n\n", value)
+	b.Write(stack)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		log.Error("failed to create crash dir", "dir", c.dir, "error", err)
+		return ""
+	}
+	if err := os.WriteFile(filepath.Join(c.dir, name), []byte(b.String()), 0600); err != nil {
+		log.Error("failed to write crash report", "error", err)
+		return ""
+	}
+	c.last = name
+	c.prune()
+
+	return name
+}
+
+// Recover is deferred by goroutines that must survive a panic; it reports
+// the panic and lets the caller continue.
+func (c *CrashReporter) Recover(component string) {
+	if p := recover(); p != nil {
+		c.Report(component, p, debug.Stack())
+	}
+}
+
+func (c *CrashReporter) Count() int64 {
+	return c.count.Load()
+}
+
+func (c *CrashReporter) Last() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.last
+}
+
+func (c *CrashReporter) prune() {
+	entries, err := filepath.Glob(filepath.Join(c.dir, "crash-*.log"))
+	if err != nil || len(entries) <= maxCrashReports {
+		return
+	}
+	sort.Strings(entries)
+	for _, old := range entries[:len(entries)-maxCrashReports] {
+		os.Remove(old)
+	}
+}
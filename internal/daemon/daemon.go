@@ -1,10 +1,12 @@
 package daemon
 
 import (
-	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"path/filepath"
@@ -13,17 +15,33 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/alucardeht/may-la-mcp/internal/audit"
+	"github.com/alucardeht/may-la-mcp/internal/checkpoint"
 	"github.com/alucardeht/may-la-mcp/internal/config"
+	"github.com/alucardeht/may-la-mcp/internal/events"
+	"github.com/alucardeht/may-la-mcp/internal/extract"
+	"github.com/alucardeht/may-la-mcp/internal/gitignore"
+	"github.com/alucardeht/may-la-mcp/internal/goanalysis"
 	"github.com/alucardeht/may-la-mcp/internal/index"
+	"github.com/alucardeht/may-la-mcp/internal/injection"
+	"github.com/alucardeht/may-la-mcp/internal/intel"
 	"github.com/alucardeht/may-la-mcp/internal/logger"
 	"github.com/alucardeht/may-la-mcp/internal/lsp"
 	"github.com/alucardeht/may-la-mcp/internal/mcp"
+	"github.com/alucardeht/may-la-mcp/internal/memsync"
+	"github.com/alucardeht/may-la-mcp/internal/metrics"
+	"github.com/alucardeht/may-la-mcp/internal/paths"
+	"github.com/alucardeht/may-la-mcp/internal/redact"
 	"github.com/alucardeht/may-la-mcp/internal/router"
+	"github.com/alucardeht/may-la-mcp/internal/scheduler"
+	"github.com/alucardeht/may-la-mcp/internal/spill"
 	"github.com/alucardeht/may-la-mcp/internal/tools"
+	"github.com/alucardeht/may-la-mcp/internal/tools/data"
 	"github.com/alucardeht/may-la-mcp/internal/tools/docs"
 	"github.com/alucardeht/may-la-mcp/internal/tools/files"
 	"github.com/alucardeht/may-la-mcp/internal/tools/memory"
 	"github.com/alucardeht/may-la-mcp/internal/tools/search"
+	"github.com/alucardeht/may-la-mcp/internal/tools/workspace"
 	"github.com/alucardeht/may-la-mcp/internal/watcher"
 	"github.com/alucardeht/may-la-mcp/pkg/protocol"
 )
@@ -41,7 +59,7 @@ type Daemon struct {
 	shutdownOnce   sync.Once
 	startTime      time.Time
 	config         *config.Config
-	indexStore     *index.IndexStore
+	indexStore     index.Store
 	indexWorker    *index.IndexWorker
 	lspManager     *lsp.Manager
 	routerInstance *router.Router
@@ -50,17 +68,29 @@ type Daemon struct {
 	lifecycle      *LifecycleManager
 	shuttingDown   atomic.Bool
 	activeConns    sync.WaitGroup
-	memoryStore    *memory.MemoryStore
+	memoryStore    memory.MemoryRepo
+	memorySync     *memsync.Syncer
+	searchHistory  *search.HistoryStore
+	crashes        *CrashReporter
+	notifier       *notifier
+	metrics        *metrics.Store
+	events         *events.Bus
+	scheduler      *scheduler.Scheduler
+	accessLog      *accessLog
 }
 
+// memorySaveInterval bounds how long changes to an encrypted memory
+// database wait to be saved
+const memorySaveInterval = 5 * time.Second
+
 func NewDaemon(cfg *config.Config) (*Daemon, error) {
 	log.Info("initializing daemon", "socket", cfg.SocketPath)
 
-	indexStore, err := index.NewIndexStore(cfg.Index.DBPath)
+	indexStore, err := index.Open(cfg.StorageBackend, cfg.Index.DBPath, cfg.Encryption)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create index store: %w", err)
 	}
-	log.Info("index store initialized", "path", cfg.Index.DBPath)
+	log.Info("index store initialized", "path", cfg.Index.DBPath, "encrypted", cfg.Encryption.Enabled)
 
 	indexWorkerConfig := index.WorkerConfig{
 		WorkerCount:     cfg.Index.WorkerCount,
@@ -68,6 +98,8 @@ func NewDaemon(cfg *config.Config) (*Daemon, error) {
 		RateLimit:       cfg.Index.RateLimit,
 		MaxFileSize:     cfg.Index.MaxFileSize,
 		ExcludePatterns: cfg.Index.ExcludePatterns,
+
+		LanguageMaxFileSize: cfg.Index.LanguageMaxFileSize,
 	}
 	indexWorker := index.NewIndexWorker(indexStore, indexWorkerConfig)
 	log.Info("index worker initialized", "workers", cfg.Index.WorkerCount)
@@ -76,6 +108,7 @@ func NewDaemon(cfg *config.Config) (*Daemon, error) {
 	log.Info("LSP manager initialized")
 
 	routerInstance := router.NewRouter(indexStore, lspManager)
+	routerInstance.SetGoAnalyzer(goanalysis.New())
 	log.Info("router initialized")
 
 	watcherInstance, err := watcher.New(cfg.Watcher, indexWorker)
@@ -98,37 +131,239 @@ func NewDaemon(cfg *config.Config) (*Daemon, error) {
 		routerInstance: routerInstance,
 		fileWatcher:    watcherInstance,
 		execSem:        make(chan struct{}, 50),
-		lifecycle:      NewLifecycleManager(filepath.Dir(cfg.SocketPath), cfg.SocketPath),
+		lifecycle:      NewLifecycleManager(filepath.Dir(cfg.SocketPath)),
+		crashes:        NewCrashReporter(cfg.CrashDir),
+		notifier:       newNotifier(),
 	}
 
+	tools.SetPanicHandler(d.crashes.Report)
+
 	d.server = mcp.NewServer(d.registry)
 
+	redactor, err := redact.New(cfg.Redaction)
+	if err != nil {
+		d.cleanupComponents()
+		return nil, fmt.Errorf("failed to configure redaction: %w", err)
+	}
+	d.server.SetRedactor(redactor)
+	d.setupInjectionScanner()
+
+	if results := spill.New(cfg.Results); results != nil {
+		if removed := results.Prune(); removed > 0 {
+			log.Info("expired results removed", "count", removed)
+		}
+		d.server.SetSpill(results)
+	}
+
+	if cfg.Metrics.Enabled {
+		if err := d.setupMetrics(redactor); err != nil {
+			log.Warn("metrics disabled", "error", err)
+		}
+	}
+
+	if cfg.AuditLog != "" {
+		if auditLog, err := audit.Open(cfg.AuditLog); err != nil {
+			log.Warn("audit log disabled", "error", err)
+		} else {
+			tools.SetWriteRecorder(func(record tools.WriteRecord) {
+				if err := auditLog.Record(record); err != nil {
+					log.Warn("failed to write audit log", "tool", record.Tool, "error", err)
+				}
+			})
+		}
+	}
+
+	if bus := events.New(cfg.Events); bus != nil {
+		d.events = bus
+		events.SetDefault(bus)
+		log.Info("event subscribers configured", "count", len(cfg.Events.Subscribers))
+	}
+
+	tools.SetDeniedPaths(cfg.DeniedPaths)
+	tools.SetAllowedPaths(cfg.AllowedPaths)
+	extract.SetConfig(cfg.Extract)
+	// list and find hide what the index leaves out, following changes made
+	// with the exclude tools
+	gitignore.SetPatternSource(d.indexWorker.ExcludePatterns)
+
+	if summarizer, err := intel.NewSummarizer(cfg.Summarizer); err != nil {
+		log.Warn("LLM summarizer disabled", "error", err)
+	} else if summarizer != nil {
+		intel.SetSummarizer(summarizer)
+		log.Info("LLM summarizer enabled", "provider", cfg.Summarizer.Provider)
+	}
+
+	if cfg.Index.Enabled && cfg.Index.RecentFiles > 0 {
+		d.accessLog = newAccessLog(d.indexStore.RecordAccess)
+		tools.SetAccessRecorder(d.accessLog.Record)
+	}
+
 	if err := d.registerAllTools(); err != nil {
 		d.cleanupComponents()
 		return nil, fmt.Errorf("failed to register tools: %w", err)
 	}
+	d.loadToolDefaults()
+
+	if err := d.setupScheduler(); err != nil {
+		d.cleanupComponents()
+		return nil, fmt.Errorf("scheduler: %w", err)
+	}
 
 	return d, nil
 }
 
+// setupInjectionScanner turns on prompt injection warnings as configured,
+// adjusted by the workspace the daemon serves. A bad workspace file is
+// logged and the daemon configuration used instead, as are the settings
+// of an untrusted one that would weaken scanning.
+func (d *Daemon) setupInjectionScanner() {
+	cfg := d.config.Injection
+	if root, err := os.Getwd(); err == nil {
+		workspace, ignored, err := injection.LoadWorkspace(root, cfg)
+		if err != nil {
+			log.Warn("workspace injection settings ignored", "error", err)
+		} else {
+			cfg = workspace
+		}
+		if len(ignored) > 0 {
+			log.Warn("workspace injection settings ignored, set MAYLA_INJECTION_TRUST_WORKSPACE to apply them", "settings", ignored)
+		}
+	}
+
+	scanner, err := injection.New(cfg)
+	if err != nil {
+		log.Warn("prompt injection scanning disabled", "error", err)
+		return
+	}
+	if scanner != nil {
+		d.server.SetInjectionScanner(scanner)
+		log.Info("prompt injection scanning enabled", "custom_rules", len(cfg.Rules), "skipped_tools", len(cfg.SkipTools))
+	}
+}
+
+// loadToolDefaults applies the tool defaults of the workspace the daemon
+// serves, the directory it runs in. Bad entries are logged and skipped.
+func (d *Daemon) loadToolDefaults() {
+	root, err := os.Getwd()
+	if err != nil {
+		return
+	}
+	defaults, err := tools.LoadToolDefaults(root)
+	if err != nil {
+		log.Warn("tool defaults ignored", "error", err)
+		return
+	}
+	if err := d.registry.SetDefaults(defaults); err != nil {
+		log.Warn("some tool defaults ignored", "file", tools.ToolDefaultsFile, "error", err)
+	}
+	if len(defaults) > 0 {
+		log.Info("tool defaults loaded", "file", tools.ToolDefaultsFile, "tools", len(defaults))
+	}
+}
+
 func (d *Daemon) registerAllTools() error {
-	d.registry.Register(tools.NewHealthTool())
+	d.registry.SetNamespaced(d.config.NamespacedTools)
+
+	if err := d.registry.RegisterIn("system", tools.NewHealthTool(d.healthProbes()...)); err != nil {
+		return fmt.Errorf("system: %w", err)
+	}
+	if err := d.registry.RegisterIn("system", tools.NewBatchTool(d.registry)); err != nil {
+		return fmt.Errorf("system: %w", err)
+	}
+	if err := d.registry.RegisterIn("system", tools.NewContextBudgetTool()); err != nil {
+		return fmt.Errorf("system: %w", err)
+	}
+
+	if d.metrics != nil {
+		if err := d.registry.RegisterIn("system", metrics.NewMetricsTool(d.metrics)); err != nil {
+			return fmt.Errorf("metrics: %w", err)
+		}
+	}
+
+	for _, tool := range tools.NewScratchTools() {
+		if err := d.registry.RegisterIn("scratch", tool); err != nil {
+			return fmt.Errorf("scratch: %w", err)
+		}
+	}
+
+	if d.lspManager != nil {
+		if err := d.registry.RegisterIn("lsp", lsp.NewStatusTool(d.lspManager)); err != nil {
+			return fmt.Errorf("lsp: %w", err)
+		}
+		if err := d.registry.RegisterIn("lsp", files.NewCodeActionsTool(d.lspManager)); err != nil {
+			return fmt.Errorf("lsp: %w", err)
+		}
+		if err := d.registry.RegisterIn("lsp", files.NewApplyCodeActionTool(d.lspManager)); err != nil {
+			return fmt.Errorf("lsp: %w", err)
+		}
+	}
+
+	if d.fileWatcher != nil && d.indexWorker != nil {
+		if err := d.registry.RegisterIn("index", watcher.NewExcludesTool(d.fileWatcher)); err != nil {
+			return fmt.Errorf("index: %w", err)
+		}
+	}
+	if d.indexWorker != nil && d.indexStore != nil {
+		if err := d.registry.RegisterIn("index", index.NewStatusTool(d.indexWorker, d.indexStore)); err != nil {
+			return fmt.Errorf("index: %w", err)
+		}
+	}
+	if d.fileWatcher != nil && d.fileWatcher.Journal() != nil {
+		if err := d.registry.RegisterIn("index", watcher.NewRecentChangesTool(d.fileWatcher)); err != nil {
+			return fmt.Errorf("index: %w", err)
+		}
+	}
+
+	if d.indexStore != nil {
+		repoMap := workspace.NewRepoMapTool(d.indexStore)
+		if err := d.registry.RegisterIn("workspace", repoMap); err != nil {
+			return fmt.Errorf("workspace: %w", err)
+		}
+		if err := d.registry.RegisterIn("workspace", workspace.NewSpecReverseTool(repoMap)); err != nil {
+			return fmt.Errorf("workspace: %w", err)
+		}
+		if err := d.registry.RegisterIn("workspace", workspace.NewProjectInfoTool(d.indexStore)); err != nil {
+			return fmt.Errorf("workspace: %w", err)
+		}
+		if err := d.registry.RegisterIn("workspace", workspace.NewUnusedSymbolsTool(d.indexStore)); err != nil {
+			return fmt.Errorf("workspace: %w", err)
+		}
+		if err := d.registry.RegisterIn("workspace", workspace.NewEncodingReportTool(d.indexStore)); err != nil {
+			return fmt.Errorf("workspace: %w", err)
+		}
+		if err := d.registry.RegisterIn("workspace", workspace.NewImpactAnalysisTool(d.indexStore)); err != nil {
+			return fmt.Errorf("workspace: %w", err)
+		}
+	}
+	if err := d.registry.RegisterIn("workspace", workspace.NewLicenseCheckTool(d.config.License)); err != nil {
+		return fmt.Errorf("workspace: %w", err)
+	}
+	if err := d.registry.RegisterIn("workspace", workspace.NewSummarizeChangesTool()); err != nil {
+		return fmt.Errorf("workspace: %w", err)
+	}
+	if checkpoints := checkpoint.New(d.config.Checkpoints); checkpoints != nil {
+		for _, tool := range workspace.NewCheckpointTools(checkpoints) {
+			if err := d.registry.RegisterIn("workspace", tool); err != nil {
+				return fmt.Errorf("workspace: %w", err)
+			}
+		}
+	}
 
 	for _, tool := range files.GetTools() {
-		if err := d.registry.Register(tool); err != nil {
+		if err := d.registry.RegisterIn("files", tool); err != nil {
 			return fmt.Errorf("files: %w", err)
 		}
 	}
 
-	for _, tool := range docs.GetTools() {
-		if err := d.registry.Register(tool); err != nil {
+	for _, tool := range docs.GetTools(d.indexStore) {
+		if err := d.registry.RegisterIn("docs", tool); err != nil {
 			return fmt.Errorf("docs: %w", err)
 		}
 	}
 
-	for _, tool := range search.GetTools(d.routerInstance) {
-		if err := d.registry.Register(tool); err != nil {
-			return fmt.Errorf("search: %w", err)
+	for _, tool := range data.GetTools() {
+		if err := d.registry.RegisterIn("data", tool); err != nil {
+			return fmt.Errorf("data: %w", err)
 		}
 	}
 
@@ -136,17 +371,59 @@ func (d *Daemon) registerAllTools() error {
 	if err := os.MkdirAll(instanceDir, 0700); err != nil {
 		return fmt.Errorf("failed to create instance directory: %w", err)
 	}
-	dbPath := filepath.Join(instanceDir, "memory.db")
 
 	var err error
-	d.memoryStore, err = memory.NewMemoryStore(dbPath)
+	d.searchHistory, err = search.NewHistoryStore(filepath.Join(instanceDir, "search.db"))
+	if err != nil {
+		return fmt.Errorf("search history: %w", err)
+	}
+
+	for _, tool := range search.GetToolsWithHistory(d.routerInstance, d.searchHistory) {
+		if err := d.registry.RegisterIn("search", tool); err != nil {
+			return fmt.Errorf("search: %w", err)
+		}
+	}
+
+	dbPath := filepath.Join(instanceDir, "memory.db")
+
+	// memories cannot be rebuilt like the index, so an encrypted memory
+	// database is saved soon after every change
+	memoryEncryption := d.config.Encryption
+	memoryEncryption.SaveInterval = min(memoryEncryption.SaveInterval, memorySaveInterval)
+	if memoryEncryption.SaveInterval <= 0 {
+		memoryEncryption.SaveInterval = memorySaveInterval
+	}
+	memoryStore, err := memory.Open(d.config.StorageBackend, dbPath, memoryEncryption)
 	if err != nil {
 		return fmt.Errorf("memory: %w", err)
 	}
+	d.memoryStore = memoryStore
 
 	memTools := memory.GetToolsFromStore(d.memoryStore)
 	for _, tool := range memTools {
-		if err := d.registry.Register(tool); err != nil {
+		if err := d.registry.RegisterIn("memory", tool); err != nil {
+			return fmt.Errorf("memory: %w", err)
+		}
+	}
+
+	if d.config.AutoMemory.Enabled {
+		suggester := memory.NewSuggester(d.config.AutoMemory)
+		if err := d.registry.RegisterIn("memory", memory.NewMemorySuggestionsTool(d.memoryStore, suggester)); err != nil {
+			return fmt.Errorf("memory: %w", err)
+		}
+		tools.SetActivityRecorder(suggester.Observe)
+		log.Info("memory suggestions enabled")
+	}
+
+	syncConfig := d.config.MemorySync
+	syncConfig.StatePath = filepath.Join(instanceDir, "memory-sync.json")
+	syncConfig.GitDir = filepath.Join(instanceDir, "memory-sync")
+	d.memorySync, err = memsync.New(syncConfig, d.memoryStore)
+	if err != nil {
+		return fmt.Errorf("memory sync: %w", err)
+	}
+	if d.memorySync != nil {
+		if err := d.registry.RegisterIn("memory", memsync.NewSyncTool(d.memorySync)); err != nil {
 			return fmt.Errorf("memory: %w", err)
 		}
 	}
@@ -157,6 +434,11 @@ func (d *Daemon) registerAllTools() error {
 func (d *Daemon) Start() error {
 	log.Info("daemon starting", "socket", d.socketPath)
 
+	endpoint, err := EndpointOf(d.config)
+	if err != nil {
+		return fmt.Errorf("cannot start: %w", err)
+	}
+
 	if err := d.lifecycle.AcquireInstanceLock(); err != nil {
 		return fmt.Errorf("cannot start: %w", err)
 	}
@@ -166,11 +448,11 @@ func (d *Daemon) Start() error {
 	}
 
 	socketDir := filepath.Dir(d.socketPath)
-	if err := os.MkdirAll(socketDir, 0755); err != nil {
+	if err := os.MkdirAll(socketDir, 0700); err != nil {
 		return fmt.Errorf("failed to create socket dir: %w", err)
 	}
 
-	listener, err := net.Listen("unix", d.socketPath)
+	listener, err := net.Listen(endpoint.Network, endpoint.Address)
 	if err != nil {
 		return fmt.Errorf("failed to listen: %w", err)
 	}
@@ -182,8 +464,8 @@ func (d *Daemon) Start() error {
 		return fmt.Errorf("failed to register daemon: %w", err)
 	}
 
-	if runtime.GOOS != "windows" {
-		if err := os.Chmod(d.socketPath, 0700); err != nil {
+	if endpoint.Network == "unix" && runtime.GOOS != "windows" {
+		if err := os.Chmod(d.socketPath, 0600); err != nil {
 			d.lifecycle.Cleanup()
 			d.listener.Close()
 			os.Remove(d.socketPath)
@@ -191,8 +473,58 @@ func (d *Daemon) Start() error {
 		}
 	}
 
-	log.Info("listening on socket", "path", d.socketPath)
+	log.Info("listening", "network", endpoint.Network, "address", endpoint.Address, "auth", d.config.AuthToken != "")
+
+	d.startBackground()
+
+	go d.acceptConnections()
+
+	return nil
+}
+
+// ServeStream serves MCP requests read from reader in-process, writing
+// responses to writer, until reader is exhausted. The daemon takes the
+// instance lock but opens no socket, so clients cannot share it.
+func (d *Daemon) ServeStream(session tools.SessionOptions, reader io.Reader, writer io.Writer) error {
+	log.Info("daemon serving in-process stream")
 
+	if err := d.lifecycle.AcquireInstanceLock(); err != nil {
+		return fmt.Errorf("cannot start: %w", err)
+	}
+
+	notifications, unsubscribe := d.notifier.subscribe()
+	defer unsubscribe()
+	go func() {
+		for payload := range notifications {
+			if err := d.server.Notify(payload); err != nil {
+				log.Debug("failed to write notification", "error", err)
+			}
+		}
+	}()
+
+	d.startBackground()
+
+	scratchpad := tools.NewScratchpad()
+	defer scratchpad.Clear()
+
+	ctx := tools.WithSession(context.Background(), d.sessionOptions(session))
+	ctx = tools.WithScratchpad(ctx, scratchpad)
+	ctx = tools.WithContextBudget(ctx, tools.NewContextBudget())
+	ctx = tools.WithAttribution(ctx, tools.NewSessionID())
+	return d.server.ProcessStream(ctx, reader, writer)
+}
+
+// sessionOptions applies the daemon-wide switches on top of what the client
+// asked for; a client can restrict its own session but not lift the daemon's.
+func (d *Daemon) sessionOptions(requested tools.SessionOptions) tools.SessionOptions {
+	requested.ReadOnly = requested.ReadOnly || d.config.ReadOnly
+	requested.DryRun = requested.DryRun || d.config.DryRun
+	return requested
+}
+
+// startBackground starts indexing, file watching and memory sync; they
+// stop when the daemon shuts down.
+func (d *Daemon) startBackground() {
 	ctx, cancel := context.WithCancel(context.Background())
 	go func() {
 		<-d.shutdown
@@ -201,22 +533,76 @@ func (d *Daemon) Start() error {
 
 	if d.config.Index.Enabled && d.indexWorker != nil {
 		d.indexWorker.Start()
+		d.enqueueRecentFiles()
+		go d.reportIndexProgress(ctx)
+	}
+
+	if d.memorySync != nil {
+		go d.memorySync.Run(ctx)
+	}
+
+	if d.scheduler != nil {
+		go d.scheduler.Run(ctx)
 	}
 
 	if d.config.Watcher.Enabled && d.fileWatcher != nil {
+		d.fileWatcher.SetChangeHandler(d.publishFileChanges)
 		if err := d.fileWatcher.Start(ctx); err != nil {
 			log.Warn("failed to start watcher", "error", err)
 		} else {
-			cwd, err := os.Getwd()
-			if err == nil {
-				d.fileWatcher.AddRoot(cwd)
+			if cwd, err := os.Getwd(); err == nil {
+				// the watcher keys the root by its canonical path, and so
+				// does the index the warm-up reads
+				cwd = paths.Canonical(cwd)
+				if d.fileWatcher.AddRoot(cwd) == nil {
+					go d.warmUpLSP(ctx, cwd)
+				}
 			}
 		}
 	}
+}
 
-	go d.acceptConnections()
+// warmUpLSP pre-starts the language servers for the languages the index
+// has seen under root, so the first LSP query does not wait for them. The
+// manager only acts when auto_start is set.
+func (d *Daemon) warmUpLSP(ctx context.Context, root string) {
+	if d.lspManager == nil || !d.config.LSP.Enabled || !d.config.LSP.AutoStart {
+		return
+	}
 
-	return nil
+	detected, err := d.indexStore.GetLanguages(root)
+	if err != nil {
+		log.Warn("failed to read indexed languages", "error", err)
+		return
+	}
+
+	langs := make([]lsp.Language, len(detected))
+	for i, lang := range detected {
+		langs[i] = lsp.Language(lang)
+	}
+	d.lspManager.WarmUp(ctx, root, langs)
+}
+
+// enqueueRecentFiles queues the files most recently touched through the tools
+// with high priority, ahead of the watcher's bulk walk of the workspace.
+func (d *Daemon) enqueueRecentFiles() {
+	limit := d.config.Index.RecentFiles
+	if limit <= 0 {
+		return
+	}
+
+	if err := d.indexStore.PruneRecentFiles(limit); err != nil {
+		log.Warn("failed to prune recent files", "error", err)
+	}
+
+	paths, err := d.indexStore.GetRecentFiles(limit)
+	if err != nil {
+		log.Warn("failed to load recent files", "error", err)
+		return
+	}
+
+	queued := d.indexWorker.EnqueueBatch(paths, index.PriorityHigh)
+	log.Info("prioritized recent files for indexing", "count", queued)
 }
 
 func (d *Daemon) acceptConnections() {
@@ -249,106 +635,135 @@ func (d *Daemon) handleConnection(conn net.Conn) {
 		d.connMu.Unlock()
 		d.activeConns.Done()
 	}()
+	defer d.crashes.Recover("connection")
 
-	writer := bufio.NewWriter(conn)
-	decoder := json.NewDecoder(conn)
-	encoder := json.NewEncoder(writer)
+	if err := verifyPeer(conn); err != nil {
+		log.Warn("rejecting connection", "error", err)
+		return
+	}
+
+	reader := protocol.NewFrameReader(conn)
+	writer := protocol.NewFrameWriter(conn)
+
+	hello, err := d.handshake(conn, reader, writer)
+	if err != nil {
+		// Readiness probes connect and hang up without saying hello
+		if errors.Is(err, io.EOF) {
+			return
+		}
+		log.Warn("rejecting connection", "error", err)
+		return
+	}
+
+	notifications, unsubscribe := d.notifier.subscribe()
+	defer unsubscribe()
+	go d.forwardNotifications(conn, writer, notifications)
+
+	session := d.sessionOptions(tools.SessionOptions{ReadOnly: hello.ReadOnly, DryRun: hello.DryRun})
+	if session.ReadOnly {
+		log.Info("client session is read-only")
+	}
+	if session.DryRun {
+		log.Info("client session is in dry-run mode")
+	}
+
+	scratchpad := tools.NewScratchpad()
+	defer scratchpad.Clear()
+
+	sessionID := tools.NewSessionID()
+	log.Debug("client session opened", "session", sessionID)
+
+	ctx := tools.WithScratchpad(tools.WithSession(context.Background(), session), scratchpad)
+	ctx = tools.WithContextBudget(ctx, tools.NewContextBudget())
+	inflight := newInflightRequests(tools.WithAttribution(ctx, sessionID))
+	var requests sync.WaitGroup
+	defer requests.Wait()
+	defer inflight.cancelAll()
 
 	for {
-		if err := conn.SetDeadline(time.Now().Add(5 * time.Minute)); err != nil {
+		if err := conn.SetReadDeadline(time.Now().Add(5 * time.Minute)); err != nil {
 			log.Error("failed to update connection deadline", "error", err)
 			return
 		}
 
-		var raw json.RawMessage
-		if err := decoder.Decode(&raw); err != nil {
+		frame, err := reader.Read()
+		if err != nil {
+			if errors.Is(err, protocol.ErrUnsupportedVersion) || errors.Is(err, protocol.ErrFrameTooLarge) {
+				d.writeFrame(conn, writer, errorFrame(0, err))
+			}
 			return
 		}
 
-		if len(raw) == 0 {
-			continue
+		switch frame.Type {
+		case protocol.FrameRequest:
+			ctx := inflight.start(frame.ID)
+			if hello.Streaming {
+				ctx = mcp.WithNotifier(ctx, d.partialWriter(conn, writer, frame.ID))
+			}
+			requests.Add(1)
+			go func() {
+				defer requests.Done()
+				defer inflight.finish(frame.ID)
+				defer d.crashes.Recover("request")
+				d.handleRequestFrame(ctx, conn, writer, frame)
+			}()
+		case protocol.FrameCancel:
+			inflight.cancel(frame.ID)
+		case protocol.FramePing:
+			d.writeFrame(conn, writer, &protocol.Frame{Type: protocol.FramePong, ID: frame.ID})
+		default:
+			d.writeFrame(conn, writer, errorFrame(frame.ID, fmt.Errorf("unexpected %s frame", frame.Type)))
 		}
+	}
+}
 
-		if raw[0] == '[' {
-			d.handleBatch(raw, encoder, writer)
-		} else {
-			d.handleSingleRequest(raw, encoder, writer)
-		}
+func (d *Daemon) forwardNotifications(conn net.Conn, writer *protocol.FrameWriter, notifications <-chan []byte) {
+	for payload := range notifications {
+		d.writeFrame(conn, writer, &protocol.Frame{Type: protocol.FrameNotification, Payload: payload})
 	}
 }
 
-func (d *Daemon) handleBatch(raw json.RawMessage, encoder *json.Encoder, writer *bufio.Writer) {
-	var batch []mcp.Request
-	if err := json.Unmarshal(raw, &batch); err != nil {
-		errResp := &mcp.Response{
-			JSONRPC: "2.0",
-			ID:      nil,
-			Error: &protocol.JSONRPCError{
-				Code:    -32700,
-				Message: "Parse error",
-			},
-		}
+// partialWriter returns the function sending the notifications of the
+// request with the given frame ID as partial frames; the response frame
+// written after them ends the request's stream
+func (d *Daemon) partialWriter(conn net.Conn, writer *protocol.FrameWriter, id uint64) mcp.NotifyFunc {
+	return func(notification json.RawMessage) {
 		if d.shuttingDown.Load() {
 			return
 		}
-		if err := encoder.Encode(errResp); err != nil {
-			log.Error("failed to encode parse error response", "error", err)
-			return
-		}
-		if err := writer.Flush(); err != nil {
-			log.Error("failed to flush parse error response", "error", err)
-			return
-		}
-		return
+		d.writeFrame(conn, writer, &protocol.Frame{Type: protocol.FramePartial, ID: id, Payload: notification})
 	}
+}
 
-	select {
-	case d.execSem <- struct{}{}:
-		responses := d.server.HandleBatch(batch)
-		<-d.execSem
-		if d.shuttingDown.Load() {
-			return
-		}
-		if err := encoder.Encode(responses); err != nil {
-			log.Error("failed to encode batch responses", "error", err)
-			return
-		}
-		if err := writer.Flush(); err != nil {
-			log.Error("failed to flush batch responses", "error", err)
-			return
-		}
-	case <-time.After(30 * time.Second):
-		if d.shuttingDown.Load() {
-			return
-		}
-		busyResps := make([]*mcp.Response, len(batch))
-		for i, req := range batch {
-			if req.ID != nil {
-				busyResps[i] = &mcp.Response{
-					JSONRPC: "2.0",
-					ID:      req.ID,
-					Error: &protocol.JSONRPCError{
-						Code:    -32603,
-						Message: "server busy, try again later",
-					},
-				}
-			}
-		}
-		if err := encoder.Encode(busyResps); err != nil {
-			log.Error("failed to encode busy response", "error", err)
-			return
-		}
-		if err := writer.Flush(); err != nil {
-			log.Error("failed to flush busy response", "error", err)
-			return
-		}
+func (d *Daemon) writeFrame(conn net.Conn, writer *protocol.FrameWriter, frame *protocol.Frame) {
+	if err := conn.SetWriteDeadline(time.Now().Add(30 * time.Second)); err != nil {
+		log.Error("failed to update write deadline", "error", err)
+		return
+	}
+	if err := writer.Write(frame); err != nil {
+		log.Error("failed to write frame", "type", frame.Type.String(), "error", err)
 	}
 }
 
-func (d *Daemon) handleSingleRequest(raw json.RawMessage, encoder *json.Encoder, writer *bufio.Writer) {
+// handleRequestFrame executes the JSON-RPC request or batch carried by frame
+// and answers with a response frame of the same ID. Requests cancelled before
+// they start executing get no response.
+func (d *Daemon) handleRequestFrame(ctx context.Context, conn net.Conn, writer *protocol.FrameWriter, frame *protocol.Frame) {
+	payload := bytes.TrimSpace(frame.Payload)
+	isBatch := len(payload) > 0 && payload[0] == '['
+
+	var batch []mcp.Request
 	var req mcp.Request
-	if err := json.Unmarshal(raw, &req); err != nil {
-		errResp := &mcp.Response{
+	var err error
+	if isBatch {
+		err = json.Unmarshal(payload, &batch)
+	} else {
+		err = json.Unmarshal(payload, &req)
+	}
+
+	var result interface{}
+	if err != nil {
+		result = &mcp.Response{
 			JSONRPC: "2.0",
 			ID:      nil,
 			Error: &protocol.JSONRPCError{
@@ -356,55 +771,109 @@ func (d *Daemon) handleSingleRequest(raw json.RawMessage, encoder *json.Encoder,
 				Message: "Parse error",
 			},
 		}
-		if d.shuttingDown.Load() {
-			return
-		}
-		if err := encoder.Encode(errResp); err != nil {
-			log.Error("failed to encode parse error response", "error", err)
-			return
-		}
-		if err := writer.Flush(); err != nil {
-			log.Error("failed to flush parse error response", "error", err)
+	} else {
+		select {
+		case d.execSem <- struct{}{}:
+			if isBatch {
+				result = d.server.HandleBatch(ctx, batch)
+			} else {
+				result = d.server.HandleRequest(ctx, &req)
+			}
+			<-d.execSem
+		case <-ctx.Done():
 			return
+		case <-time.After(30 * time.Second):
+			if isBatch {
+				busyResps := make([]*mcp.Response, 0, len(batch))
+				for _, r := range batch {
+					if r.ID != nil {
+						busyResps = append(busyResps, busyResponse(r.ID))
+					}
+				}
+				result = busyResps
+			} else {
+				result = busyResponse(req.ID)
+			}
 		}
+	}
+
+	if d.shuttingDown.Load() {
 		return
 	}
 
-	select {
-	case d.execSem <- struct{}{}:
-		resp := d.server.HandleRequest(&req)
-		<-d.execSem
-		if d.shuttingDown.Load() {
-			return
-		}
-		if err := encoder.Encode(resp); err != nil {
-			log.Error("failed to encode single response", "error", err)
-			return
-		}
-		if err := writer.Flush(); err != nil {
-			log.Error("failed to flush single response", "error", err)
-			return
-		}
-	case <-time.After(30 * time.Second):
-		if d.shuttingDown.Load() {
-			return
-		}
-		busyResp := &mcp.Response{
+	data, err := json.Marshal(result)
+	if err != nil {
+		log.Error("failed to encode response", "error", err)
+		data, _ = json.Marshal(&mcp.Response{
 			JSONRPC: "2.0",
 			ID:      req.ID,
 			Error: &protocol.JSONRPCError{
 				Code:    -32603,
-				Message: "server busy, try again later",
+				Message: fmt.Sprintf("failed to encode response: %v", err),
 			},
-		}
-		if err := encoder.Encode(busyResp); err != nil {
-			log.Error("failed to encode busy response", "error", err)
-			return
-		}
-		if err := writer.Flush(); err != nil {
-			log.Error("failed to flush busy response", "error", err)
-			return
-		}
+		})
+	}
+
+	d.writeFrame(conn, writer, &protocol.Frame{Type: protocol.FrameResponse, ID: frame.ID, Payload: data})
+}
+
+func busyResponse(id interface{}) *mcp.Response {
+	return &mcp.Response{
+		JSONRPC: "2.0",
+		ID:      id,
+		Error: &protocol.JSONRPCError{
+			Code:    -32603,
+			Message: "server busy, try again later",
+		},
+	}
+}
+
+// inflightRequests tracks the cancel functions of the requests being executed
+// on one connection, keyed by frame ID.
+type inflightRequests struct {
+	mu      sync.Mutex
+	base    context.Context
+	cancels map[uint64]context.CancelFunc
+}
+
+// newInflightRequests tracks a connection's requests; each one runs under a
+// child of base.
+func newInflightRequests(base context.Context) *inflightRequests {
+	return &inflightRequests{base: base, cancels: make(map[uint64]context.CancelFunc)}
+}
+
+func (r *inflightRequests) start(id uint64) context.Context {
+	ctx, cancel := context.WithCancel(r.base)
+	r.mu.Lock()
+	r.cancels[id] = cancel
+	r.mu.Unlock()
+	return ctx
+}
+
+func (r *inflightRequests) finish(id uint64) {
+	r.mu.Lock()
+	cancel, ok := r.cancels[id]
+	delete(r.cancels, id)
+	r.mu.Unlock()
+	if ok {
+		cancel()
+	}
+}
+
+func (r *inflightRequests) cancel(id uint64) {
+	r.mu.Lock()
+	cancel, ok := r.cancels[id]
+	r.mu.Unlock()
+	if ok {
+		cancel()
+	}
+}
+
+func (r *inflightRequests) cancelAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, cancel := range r.cancels {
+		cancel()
 	}
 }
 
@@ -459,12 +928,39 @@ func (d *Daemon) cleanupComponents() {
 		d.lspManager.StopAll(context.Background())
 	}
 
+	tools.SetWriteRecorder(nil)
+	tools.SetActivityRecorder(nil)
+	gitignore.SetPatternSource(nil)
+
+	if d.events != nil {
+		events.SetDefault(nil)
+		d.events.Close()
+	}
+
+	if d.metrics != nil {
+		tools.SetExecutionRecorder(nil)
+		if err := d.metrics.Close(); err != nil {
+			log.Error("failed to close metrics store", "error", err)
+		}
+	}
+
 	if d.memoryStore != nil {
 		if err := d.memoryStore.Close(); err != nil {
 			log.Error("failed to close memory store", "error", err)
 		}
 	}
 
+	if d.searchHistory != nil {
+		if err := d.searchHistory.Close(); err != nil {
+			log.Error("failed to close search history", "error", err)
+		}
+	}
+
+	if d.accessLog != nil {
+		tools.SetAccessRecorder(nil)
+		d.accessLog.Close()
+	}
+
 	if d.indexStore != nil {
 		d.indexStore.Close()
 	}
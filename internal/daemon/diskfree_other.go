@@ -0,0 +1,9 @@
+//go:build !unix && !windows
+
+package daemon
+
+import "errors"
+
+func diskFree(path string) (uint64, error) {
+	return 0, errors.New("disk free space not supported on this platform")
+}
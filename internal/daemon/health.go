@@ -0,0 +1,133 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/alucardeht/may-la-mcp/internal/lsp"
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+const (
+	diskFreeDegraded = 1 << 30
+	diskFreeDown     = 100 << 20
+)
+
+func healthOK() tools.ComponentHealth {
+	return tools.ComponentHealth{Status: tools.StatusOK}
+}
+
+func healthDegraded(format string, args ...interface{}) tools.ComponentHealth {
+	return tools.ComponentHealth{Status: tools.StatusDegraded, Reason: fmt.Sprintf(format, args...)}
+}
+
+func healthDown(format string, args ...interface{}) tools.ComponentHealth {
+	return tools.ComponentHealth{Status: tools.StatusDown, Reason: fmt.Sprintf(format, args...)}
+}
+
+func (d *Daemon) healthProbes() []tools.HealthProbe {
+	return []tools.HealthProbe{
+		{Name: "index_store", Check: d.checkIndexStore},
+		{Name: "index_worker", Check: d.checkIndexWorker},
+		{Name: "watcher", Check: d.checkWatcher},
+		{Name: "lsp", Check: d.checkLSP},
+		{Name: "disk", Check: d.checkDisk},
+		{Name: "crashes", Check: d.checkCrashes},
+		{Name: "scheduler", Check: d.checkScheduler},
+	}
+}
+
+func (d *Daemon) checkCrashes(ctx context.Context) tools.ComponentHealth {
+	count := d.crashes.Count()
+	if count == 0 {
+		return healthOK()
+	}
+	return healthDegraded("%d panics recovered since start, last report: %s", count, d.crashes.Last())
+}
+
+func (d *Daemon) checkIndexStore(ctx context.Context) tools.ComponentHealth {
+	if d.indexStore == nil {
+		return healthDown("index store not initialized")
+	}
+	if err := d.indexStore.Ping(ctx); err != nil {
+		return healthDown("%v", err)
+	}
+	return healthOK()
+}
+
+func (d *Daemon) checkIndexWorker(ctx context.Context) tools.ComponentHealth {
+	if !d.config.Index.Enabled {
+		return healthDegraded("indexing disabled")
+	}
+	if d.indexWorker == nil {
+		return healthDown("index worker not initialized")
+	}
+	stats := d.indexWorker.GetStats()
+	if !stats.IsRunning {
+		return healthDown("index worker not running")
+	}
+	if stats.InQueue >= int64(d.config.Index.MaxQueueSize) {
+		return healthDegraded("index queue saturated (%d pending)", stats.InQueue)
+	}
+	return healthOK()
+}
+
+func (d *Daemon) checkWatcher(ctx context.Context) tools.ComponentHealth {
+	if !d.config.Watcher.Enabled {
+		return healthDegraded("watcher disabled")
+	}
+	if d.fileWatcher == nil {
+		return healthDown("watcher not initialized")
+	}
+	if !d.fileWatcher.IsRunning() {
+		return healthDown("watcher event loop not running")
+	}
+	if d.fileWatcher.RootCount() == 0 {
+		return healthDegraded("no workspace roots watched")
+	}
+	return healthOK()
+}
+
+func (d *Daemon) checkLSP(ctx context.Context) tools.ComponentHealth {
+	if d.lspManager == nil {
+		return healthDown("LSP manager not initialized")
+	}
+	if d.lspManager.IsClosed() {
+		return healthDown("LSP manager closed")
+	}
+
+	var failed []string
+	for _, stats := range d.lspManager.Stats() {
+		if stats.State == lsp.StateError {
+			failed = append(failed, fmt.Sprintf("%s (%s)", stats.Language, stats.Root))
+		}
+	}
+	if len(failed) > 0 {
+		return healthDegraded("language servers in error state: %v", failed)
+	}
+	if crashing := d.lspManager.RepeatedCrashes(); len(crashing) > 0 {
+		return healthDegraded("language servers crashing repeatedly: %v", crashing)
+	}
+	return healthOK()
+}
+
+func (d *Daemon) checkDisk(ctx context.Context) tools.ComponentHealth {
+	dir := d.config.InstanceDir
+	if dir == "" {
+		dir = filepath.Dir(d.socketPath)
+	}
+
+	free, err := diskFree(dir)
+	if err != nil {
+		return healthDegraded("cannot read free space for %s: %v", dir, err)
+	}
+
+	switch {
+	case free < diskFreeDown:
+		return healthDown("only %d MB free in %s", free>>20, dir)
+	case free < diskFreeDegraded:
+		return healthDegraded("only %d MB free in %s", free>>20, dir)
+	}
+	return healthOK()
+}
@@ -0,0 +1,217 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/index"
+	"github.com/alucardeht/may-la-mcp/internal/scheduler"
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+	"github.com/alucardeht/may-la-mcp/internal/tools/memory"
+)
+
+// backupLayout names each backup directory after the time it was taken,
+// which also sorts them oldest first
+const backupLayout = "20060102-150405"
+
+// setupScheduler schedules the maintenance jobs, each on its default
+// schedule unless the configuration overrides it or turns it off
+func (d *Daemon) setupScheduler() error {
+	cfg := d.config.Scheduler
+	overrides, err := scheduler.ParseSchedules(cfg.Schedules)
+	if err != nil {
+		return err
+	}
+
+	jobs := []struct {
+		name     string
+		schedule string
+		run      func(ctx context.Context) (string, error)
+	}{
+		{"reindex_verify", "@nightly", d.verifyIndex},
+		{"memory_purge", "0 4 * * *", d.purgeMemoryTrash},
+		{"index_gc", "30 4 * * 0", d.compactIndex},
+		{"backup", "0 2 * * *", d.backupDatabases},
+	}
+
+	known := make(map[string]bool, len(jobs))
+	d.scheduler = scheduler.New()
+	for _, job := range jobs {
+		known[job.name] = true
+		expr := job.schedule
+		if override, ok := overrides[job.name]; ok {
+			expr = override
+		}
+		if strings.EqualFold(expr, scheduler.Off) || (job.name == "backup" && cfg.BackupDir == "") {
+			continue
+		}
+
+		schedule, err := scheduler.Parse(expr)
+		if err != nil {
+			return fmt.Errorf("%s: %w", job.name, err)
+		}
+		d.scheduler.Add(scheduler.Job{Name: job.name, Schedule: schedule, Run: job.run})
+	}
+
+	for name := range overrides {
+		if !known[name] {
+			return fmt.Errorf("unknown scheduled job %q", name)
+		}
+	}
+	return nil
+}
+
+// verifyIndex compares every indexed file with the disk, reindexing the
+// ones changed behind the watcher's back and dropping the deleted ones
+func (d *Daemon) verifyIndex(ctx context.Context) (string, error) {
+	if d.indexStore == nil || d.indexWorker == nil {
+		return "indexing disabled", nil
+	}
+
+	paths, err := d.indexStore.GetIndexedPaths("")
+	if err != nil {
+		return "", err
+	}
+
+	var stale, removed int
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		file, err := d.indexStore.GetFile(path)
+		if err != nil || file == nil {
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			if err := d.indexStore.DeleteFile(path); err != nil {
+				return "", err
+			}
+			removed++
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if !info.ModTime().Equal(file.ModTime) || info.Size() != file.Size {
+			if d.indexWorker.Enqueue(index.IndexJob{Path: path, Priority: index.PriorityLow}) {
+				stale++
+			}
+		}
+	}
+
+	return fmt.Sprintf("checked %d files, %d reindexed, %d removed", len(paths), stale, removed), nil
+}
+
+func (d *Daemon) purgeMemoryTrash(ctx context.Context) (string, error) {
+	if d.memoryStore == nil {
+		return "memory store not initialized", nil
+	}
+	purged, err := d.memoryStore.PurgeDeleted(memory.DefaultTrashRetention)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("purged %d deleted memories", purged), nil
+}
+
+func (d *Daemon) compactIndex(ctx context.Context) (string, error) {
+	if d.indexStore == nil {
+		return "indexing disabled", nil
+	}
+	stats, err := d.indexStore.Compact(ctx)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("removed %d orphaned symbols, %d references and %d doc sections",
+		stats.OrphanedSymbols, stats.OrphanedReferences, stats.OrphanedDocs), nil
+}
+
+// backupDatabases copies the databases into a new dated directory of the
+// backup directory and removes the oldest backups beyond the ones kept
+func (d *Daemon) backupDatabases(ctx context.Context) (string, error) {
+	cfg := d.config.Scheduler
+	dir := filepath.Join(cfg.BackupDir, time.Now().Format(backupLayout))
+
+	type backuper interface {
+		Backup(dest string) error
+	}
+	databases := map[string]backuper{}
+	if d.indexStore != nil {
+		databases["index.db"] = d.indexStore
+	}
+	if d.memoryStore != nil {
+		databases["memory.db"] = d.memoryStore
+	}
+	if d.searchHistory != nil {
+		databases["search.db"] = d.searchHistory
+	}
+
+	for name, db := range databases {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		if err := db.Backup(filepath.Join(dir, name)); err != nil {
+			return "", fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	pruned, err := pruneBackups(cfg.BackupDir, cfg.BackupKeep)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("backed up %d databases to %s, pruned %d old backups", len(databases), dir, pruned), nil
+}
+
+// pruneBackups removes the oldest backup directories beyond keep, leaving
+// anything else in dir alone
+func pruneBackups(dir string, keep int) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	var backups []string
+	for _, entry := range entries {
+		if _, err := time.Parse(backupLayout, entry.Name()); entry.IsDir() && err == nil {
+			backups = append(backups, entry.Name())
+		}
+	}
+	sort.Strings(backups)
+
+	pruned := 0
+	for len(backups)-pruned > keep {
+		if err := os.RemoveAll(filepath.Join(dir, backups[pruned])); err != nil {
+			return pruned, err
+		}
+		pruned++
+	}
+	return pruned, nil
+}
+
+// checkScheduler reports the last run of each scheduled job, degraded
+// when one of them failed
+func (d *Daemon) checkScheduler(ctx context.Context) tools.ComponentHealth {
+	if d.scheduler == nil {
+		return healthDown("scheduler not initialized")
+	}
+
+	statuses := d.scheduler.Status()
+	health := healthOK()
+	var failed []string
+	for _, status := range statuses {
+		if status.LastError != "" {
+			failed = append(failed, status.Name)
+		}
+	}
+	if len(failed) > 0 {
+		health = healthDegraded("last run failed: %s", strings.Join(failed, ", "))
+	}
+	health.Details = statuses
+	return health
+}
@@ -2,22 +2,18 @@ package daemon
 
 import (
 	"fmt"
-	"net"
 	"path/filepath"
-	"time"
 )
 
 type LifecycleManager struct {
-	lockFile   *LockFile
-	pidFile    *PIDFile
-	socketPath string
+	lockFile *LockFile
+	pidFile  *PIDFile
 }
 
-func NewLifecycleManager(baseDir, socketPath string) *LifecycleManager {
+func NewLifecycleManager(baseDir string) *LifecycleManager {
 	return &LifecycleManager{
-		lockFile:   NewLockFile(filepath.Join(baseDir, "daemon.lock")),
-		pidFile:    NewPIDFile(filepath.Join(baseDir, "daemon.pid")),
-		socketPath: socketPath,
+		lockFile: NewLockFile(filepath.Join(baseDir, "daemon.lock")),
+		pidFile:  NewPIDFile(filepath.Join(baseDir, "daemon.pid")),
 	}
 }
 
@@ -36,15 +32,6 @@ func (lm *LifecycleManager) ValidateNoOtherInstance() error {
 	return lm.AcquireInstanceLock()
 }
 
-func (lm *LifecycleManager) isSocketResponsive() bool {
-	conn, err := net.DialTimeout("unix", lm.socketPath, 500*time.Millisecond)
-	if err != nil {
-		return false
-	}
-	conn.Close()
-	return true
-}
-
 func (lm *LifecycleManager) RegisterRunningDaemon() error {
 	return lm.pidFile.Write()
 }
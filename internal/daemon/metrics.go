@@ -0,0 +1,60 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/metrics"
+	"github.com/alucardeht/may-la-mcp/internal/redact"
+	"github.com/alucardeht/may-la-mcp/internal/router"
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+// setupMetrics opens the instance's metrics database and starts recording
+// tool calls and router queries. Slow-query parameters are redacted before
+// they are stored.
+func (d *Daemon) setupMetrics(redactor *redact.Redactor) error {
+	instanceDir := filepath.Dir(d.config.SocketPath)
+	if err := os.MkdirAll(instanceDir, 0700); err != nil {
+		return fmt.Errorf("failed to create instance directory: %w", err)
+	}
+
+	store, err := metrics.NewStore(filepath.Join(instanceDir, "metrics.db"), d.config.Metrics)
+	if err != nil {
+		return err
+	}
+	d.metrics = store
+
+	tools.SetExecutionRecorder(func(name string, input json.RawMessage, latency time.Duration, err error) {
+		sample := metrics.Sample{
+			Category: metrics.CategoryTool,
+			Name:     name,
+			Latency:  latency,
+			OK:       err == nil,
+		}
+		if store.IsSlow(latency) {
+			if redacted, _, err := redactor.RedactJSON(input); err == nil {
+				sample.Params = string(redacted)
+			} else {
+				sample.Params = redactor.RedactString(string(input))
+			}
+		}
+		store.Record(sample)
+	})
+
+	d.routerInstance.SetObserver(func(stats router.QueryStats) {
+		store.Record(metrics.Sample{
+			Category: metrics.CategoryRouter,
+			Name:     stats.Operation,
+			Source:   string(stats.Source),
+			Latency:  stats.Latency,
+			OK:       stats.Err == nil && stats.Count > 0,
+			Fallback: stats.Fallback,
+		})
+	})
+
+	return nil
+}
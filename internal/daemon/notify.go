@@ -0,0 +1,148 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/events"
+	"github.com/alucardeht/may-la-mcp/internal/index"
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+	"github.com/alucardeht/may-la-mcp/internal/watcher"
+	"github.com/alucardeht/may-la-mcp/pkg/protocol"
+)
+
+const (
+	// NotifyIndexProgress reports indexing counters while the queue drains
+	NotifyIndexProgress = "mayla/indexProgress"
+	// NotifyFileChanged reports external changes inside the workspace roots
+	NotifyFileChanged = "mayla/fileChanged"
+
+	indexProgressInterval = 2 * time.Second
+	notificationBuffer    = 64
+)
+
+// notifier fans server-initiated notifications out to every connected
+// session. Each subscriber has a bounded queue; when a slow client falls
+// behind, notifications for it are dropped rather than stalling the daemon.
+type notifier struct {
+	mu          sync.Mutex
+	subscribers map[chan []byte]struct{}
+}
+
+func newNotifier() *notifier {
+	return &notifier{subscribers: make(map[chan []byte]struct{})}
+}
+
+// subscribe registers a session and returns its queue of encoded
+// notifications and the function that unregisters it and closes the queue.
+func (n *notifier) subscribe() (<-chan []byte, func()) {
+	ch := make(chan []byte, notificationBuffer)
+
+	n.mu.Lock()
+	n.subscribers[ch] = struct{}{}
+	n.mu.Unlock()
+
+	return ch, func() {
+		n.mu.Lock()
+		delete(n.subscribers, ch)
+		close(ch)
+		n.mu.Unlock()
+	}
+}
+
+func (n *notifier) publish(method string, params map[string]interface{}) {
+	payload, err := json.Marshal(&protocol.JSONRPCRequest{
+		JSONRPC: "2.0",
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		log.Error("failed to encode notification", "method", method, "error", err)
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for ch := range n.subscribers {
+		select {
+		case ch <- payload:
+		default:
+			log.Debug("dropping notification for slow client", "method", method)
+		}
+	}
+}
+
+// publishFileChanges is installed as the watcher's change handler. A change
+// to the tool defaults file reloads them first, and one to a project root
+// file (go.mod, package.json, ...) has the LSP manager detect subproject
+// roots again.
+func (d *Daemon) publishFileChanges(events []watcher.FileEvent) {
+	changes := make([]map[string]interface{}, 0, len(events))
+	paths := make([]string, 0, len(events))
+	reloadDefaults := false
+	for _, event := range events {
+		reloadDefaults = reloadDefaults || strings.HasSuffix(filepath.ToSlash(event.Path), "/"+tools.ToolDefaultsFile)
+		paths = append(paths, event.Path)
+		changes = append(changes, map[string]interface{}{
+			"path": event.Path,
+			"type": event.Type.String(),
+		})
+	}
+
+	if reloadDefaults {
+		d.loadToolDefaults()
+	}
+	if d.lspManager != nil && d.lspManager.ForgetRoots(paths) {
+		log.Debug("project root files changed, LSP roots forgotten")
+	}
+
+	d.notifier.publish(NotifyFileChanged, map[string]interface{}{
+		"changes": changes,
+	})
+}
+
+// reportIndexProgress publishes the index worker's counters whenever they
+// change, including a final report with done set once the queue is empty,
+// which is also published as an index completed event.
+func (d *Daemon) reportIndexProgress(ctx context.Context) {
+	ticker := time.NewTicker(indexProgressInterval)
+	defer ticker.Stop()
+
+	var last index.WorkerStats
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		stats := d.indexWorker.GetStats()
+		if stats.Indexed == last.Indexed && stats.Failed == last.Failed &&
+			stats.Skipped == last.Skipped && stats.InQueue == last.InQueue {
+			continue
+		}
+		last = stats
+
+		d.notifier.publish(NotifyIndexProgress, map[string]interface{}{
+			"indexed": stats.Indexed,
+			"failed":  stats.Failed,
+			"skipped": stats.Skipped,
+			"pending": stats.InQueue,
+			"done":    stats.InQueue == 0,
+		})
+
+		if stats.InQueue == 0 {
+			events.Publish(events.IndexCompleted, fmt.Sprintf("Indexing completed: %d files indexed, %d failed", stats.Indexed, stats.Failed), map[string]interface{}{
+				"indexed": stats.Indexed,
+				"failed":  stats.Failed,
+				"skipped": stats.Skipped,
+			})
+		}
+	}
+}
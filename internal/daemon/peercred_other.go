@@ -0,0 +1,11 @@
+//go:build !linux && !darwin
+
+package daemon
+
+import "net"
+
+// peerUID is not supported on this platform; access control relies on the
+// permissions of the socket file and its parent directory
+func peerUID(conn *net.UnixConn) (int, error) {
+	return -1, errPeerCredUnsupported
+}
@@ -0,0 +1,145 @@
+package daemon
+
+import (
+	"fmt"
+	"io"
+	stdlog "log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/config"
+	"github.com/alucardeht/may-la-mcp/internal/logger"
+	"github.com/alucardeht/may-la-mcp/internal/redact"
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+// Run starts the daemon for instanceID and blocks until a shutdown signal
+// arrives or parentPID (when > 0) exits. It is the body of mayla-daemon and of
+// the daemon copy that mayla installs from itself.
+func Run(instanceID string, parentPID int) error {
+	cfg, closeLog, err := setupInstance(instanceID, "daemon", os.Stderr)
+	if err != nil {
+		return err
+	}
+	defer closeLog()
+
+	stdlog.Printf("Daemon started for instance %s with workspace %s", instanceID, cfg.InstanceDir)
+
+	d, err := NewDaemon(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create daemon: %w", err)
+	}
+
+	if err := d.Start(); err != nil {
+		d.cleanupComponents()
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	if parentPID > 0 {
+		go monitorParentProcess(parentPID, func() {
+			d.Shutdown()
+			os.Exit(0)
+		})
+	}
+
+	waitForShutdownSignal()
+
+	stdlog.Println("Shutting down daemon...")
+	d.Shutdown()
+	return nil
+}
+
+// RunStandalone serves MCP for instanceID over in/out inside the calling
+// process, without a socket or a separate daemon process. Logs go to stderr
+// and the instance log file, never to out.
+func RunStandalone(instanceID string, session tools.SessionOptions, in io.Reader, out io.Writer) error {
+	cfg, closeLog, err := setupInstance(instanceID, "standalone", os.Stderr)
+	if err != nil {
+		return err
+	}
+	defer closeLog()
+
+	d, err := NewDaemon(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create daemon: %w", err)
+	}
+	defer d.Shutdown()
+
+	return d.ServeStream(session, in, out)
+}
+
+// setupInstance loads the config for instanceID and routes the standard and
+// structured loggers to stderr and ~/.mayla/logs/<prefix>-<instance>.log,
+// redacting secrets when configured, with the rules of the workspace's
+// redact.WorkspaceFile added.
+func setupInstance(instanceID, prefix string, stderr io.Writer) (*config.Config, func(), error) {
+	logCfg := logger.DefaultConfig()
+	logCfg.Level = slog.LevelDebug
+	logCfg.Output = stderr
+	logger.Init(logCfg)
+
+	closeLog := func() {}
+	var logOutput io.Writer = stderr
+
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		logsDir := filepath.Join(homeDir, ".mayla", "logs")
+		os.MkdirAll(logsDir, 0700)
+
+		logFile := filepath.Join(logsDir, fmt.Sprintf("%s-%s.log", prefix, instanceID))
+		if f, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644); err == nil {
+			logOutput = io.MultiWriter(stderr, f)
+			closeLog = func() { f.Close() }
+		}
+	}
+	stdlog.SetOutput(logOutput)
+
+	cfg, err := config.LoadConfigWithInstance(instanceID)
+	if err != nil {
+		closeLog()
+		return nil, nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if root, err := os.Getwd(); err == nil {
+		redaction, err := redact.LoadWorkspace(root, cfg.Redaction)
+		if err != nil {
+			stdlog.Printf("Workspace redaction rules ignored: %v", err)
+		} else {
+			cfg.Redaction = redaction
+		}
+	}
+
+	redactor, err := redact.New(cfg.Redaction)
+	if err != nil {
+		closeLog()
+		return nil, nil, fmt.Errorf("invalid redaction config: %w", err)
+	}
+	if redactor != nil {
+		stdlog.SetOutput(redact.NewWriter(logOutput, redactor))
+
+		logCfg.Output = redact.NewWriter(stderr, redactor)
+		logger.Init(logCfg)
+	}
+
+	if err := cfg.EnsureDirectories(); err != nil {
+		closeLog()
+		return nil, nil, fmt.Errorf("failed to ensure directories: %w", err)
+	}
+
+	return cfg, closeLog, nil
+}
+
+func monitorParentProcess(ppid int, shutdownFunc func()) {
+	stdlog.Printf("Started monitoring parent process (PID: %d)", ppid)
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if !processExists(ppid) {
+			stdlog.Println("Parent process died, triggering graceful shutdown")
+			shutdownFunc()
+			return
+		}
+	}
+}
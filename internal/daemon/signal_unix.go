@@ -0,0 +1,16 @@
+//go:build unix
+
+package daemon
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// waitForShutdownSignal blocks until SIGINT or SIGTERM is received
+func waitForShutdownSignal() {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	<-sigChan
+}
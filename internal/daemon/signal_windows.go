@@ -0,0 +1,16 @@
+//go:build windows
+
+package daemon
+
+import (
+	"os"
+	"os/signal"
+)
+
+// waitForShutdownSignal blocks until an interrupt signal is received
+func waitForShutdownSignal() {
+	sigChan := make(chan os.Signal, 1)
+	// Windows only supports os.Interrupt (Ctrl+C)
+	signal.Notify(sigChan, os.Interrupt)
+	<-sigChan
+}
@@ -1,70 +1,47 @@
 package daemon
 
 import (
+	"errors"
 	"fmt"
 	"net"
-	"os"
-	"path/filepath"
-	"runtime"
-)
-
-type SocketListener struct {
-	path     string
-	listener net.Listener
-}
+	"strconv"
+	"time"
 
-func NewSocketListener(socketPath string) *SocketListener {
-	return &SocketListener{
-		path: socketPath,
-	}
-}
-
-func (sl *SocketListener) Start() error {
-	dir := filepath.Dir(sl.path)
-	if err := os.MkdirAll(dir, 0700); err != nil {
-		return err
-	}
-
-	if err := os.Remove(sl.path); err != nil && !os.IsNotExist(err) {
-		return err
-	}
-
-	listener, err := net.Listen("unix", sl.path)
-	if err != nil {
-		return err
-	}
-
-	sl.listener = listener
-	if runtime.GOOS == "windows" {
-		return nil
-	}
-	return os.Chmod(sl.path, 0700)
-}
+	"github.com/alucardeht/may-la-mcp/internal/config"
+)
 
-func (sl *SocketListener) Accept() (net.Conn, error) {
-	if sl.listener == nil {
-		return nil, fmt.Errorf("listener not started")
-	}
-	return sl.listener.Accept()
+// Endpoint is where the daemon listens and its clients dial: the instance
+// socket, or a TCP address when the transport is tcp
+type Endpoint struct {
+	Network string
+	Address string
 }
 
-func (sl *SocketListener) Close() error {
-	if sl.listener == nil {
-		return nil
+// EndpointOf returns the endpoint cfg.Transport selects. A TCP endpoint is
+// reachable by anyone on the network, so it needs an auth token.
+func EndpointOf(cfg *config.Config) (Endpoint, error) {
+	switch cfg.Transport {
+	case "", "unix":
+		return Endpoint{Network: "unix", Address: cfg.SocketPath}, nil
+	case "tcp":
+		if cfg.AuthToken == "" {
+			return Endpoint{}, errors.New("tcp transport requires an auth token (MAYLA_AUTH_TOKEN)")
+		}
+		address := net.JoinHostPort(cfg.DaemonAddr, strconv.Itoa(cfg.DaemonPort))
+		return Endpoint{Network: "tcp", Address: address}, nil
+	default:
+		return Endpoint{}, fmt.Errorf("unknown transport %q (want unix or tcp)", cfg.Transport)
 	}
-	return sl.listener.Close()
 }
 
-type SocketConnector struct {
-	path string
+func (e Endpoint) Dial() (net.Conn, error) {
+	return net.Dial(e.Network, e.Address)
 }
 
-func NewSocketConnector(socketPath string) *SocketConnector {
-	return &SocketConnector{
-		path: socketPath,
-	}
+func (e Endpoint) DialTimeout(timeout time.Duration) (net.Conn, error) {
+	return net.DialTimeout(e.Network, e.Address, timeout)
 }
 
-func (sc *SocketConnector) Connect() (net.Conn, error) {
-	return net.Dial("unix", sc.path)
+func (e Endpoint) String() string {
+	return e.Network + ":" + e.Address
 }
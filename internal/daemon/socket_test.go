@@ -0,0 +1,106 @@
+package daemon
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/alucardeht/may-la-mcp/internal/config"
+	"github.com/alucardeht/may-la-mcp/pkg/protocol"
+)
+
+func TestEndpointOf(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       config.Config
+		want      Endpoint
+		wantError string
+	}{
+		{
+			name: "unix",
+			cfg:  config.Config{Transport: "unix", SocketPath: "/tmp/mayla.sock"},
+			want: Endpoint{Network: "unix", Address: "/tmp/mayla.sock"},
+		},
+		{
+			name: "tcp",
+			cfg:  config.Config{Transport: "tcp", DaemonAddr: "127.0.0.1", DaemonPort: 8765, AuthToken: "s3cret"},
+			want: Endpoint{Network: "tcp", Address: "127.0.0.1:8765"},
+		},
+		{
+			name:      "tcp without a token",
+			cfg:       config.Config{Transport: "tcp", DaemonAddr: "127.0.0.1", DaemonPort: 8765},
+			wantError: "requires an auth token",
+		},
+		{
+			name:      "unknown transport",
+			cfg:       config.Config{Transport: "udp"},
+			wantError: "unknown transport",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EndpointOf(&tt.cfg)
+			if tt.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+					t.Fatalf("expected an error about %q, got %v", tt.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEndpointTCP(t *testing.T) {
+	cfg := &config.Config{Transport: "tcp", DaemonAddr: "127.0.0.1", AuthToken: "s3cret"}
+	d := &Daemon{config: cfg}
+	endpoint, err := EndpointOf(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen(endpoint.Network, endpoint.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	// the listener picked a free port for DaemonPort 0
+	endpoint.Address = listener.Addr().String()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				d.handshake(conn, protocol.NewFrameReader(conn), protocol.NewFrameWriter(conn))
+			}()
+		}
+	}()
+
+	for _, tt := range []struct {
+		token string
+		want  error
+	}{
+		{"s3cret", nil},
+		{"guess", ErrAuthFailed},
+	} {
+		conn, err := endpoint.Dial()
+		if err != nil {
+			t.Fatal(err)
+		}
+		client := NewClient(conn)
+		err = client.Handshake(protocol.Hello{Token: tt.token})
+		client.Close()
+		if !errors.Is(err, tt.want) {
+			t.Errorf("token %q: handshake returned %v, want %v", tt.token, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,543 @@
+// Package dbcrypt keeps SQLite databases encrypted at rest. An encrypted
+// database is decrypted into a shared in-memory database when it is
+// opened, and its file is rewritten from it periodically and on close, so
+// the plaintext never reaches the disk. Files are sealed with AES-256-GCM
+// under a key derived from a passphrase, which comes from the configuration
+// or the OS keychain.
+//
+// Opening is transparent in both directions: with encryption on, a plain
+// database file is encrypted in place; with it off, an encrypted file is
+// decrypted back into a plain one when the passphrase is still available.
+package dbcrypt
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"modernc.org/sqlite"
+	"modernc.org/sqlite/vfs"
+
+	"github.com/alucardeht/may-la-mcp/internal/logger"
+)
+
+var log = logger.ForComponent("dbcrypt")
+
+const (
+	magic          = "MAYLAENC"
+	formatVersion  = 1
+	saltSize       = 16
+	keyIterations  = 600000
+	defaultSaveGap = 30 * time.Second
+)
+
+var sqliteHeader = []byte("SQLite format 3\x00")
+
+// ErrWrongPassphrase is returned when a file does not decrypt with the key
+var ErrWrongPassphrase = errors.New("wrong passphrase or corrupted database")
+
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+	// Passphrase the key is derived from; when empty and Keychain is set
+	// it is read from the OS keychain
+	Passphrase string `yaml:"passphrase"`
+	Keychain   bool   `yaml:"keychain"`
+	// SaveInterval is how often changes are written back to the encrypted
+	// file; changes made since the last save are lost if the process dies
+	SaveInterval time.Duration `yaml:"save_interval"`
+}
+
+// passphrase returns the configured passphrase, or "" when there is none
+func (c Config) passphrase() (string, error) {
+	if c.Passphrase != "" {
+		return c.Passphrase, nil
+	}
+	if c.Keychain {
+		return keychainPassphrase()
+	}
+	return "", nil
+}
+
+// Database is an open database, encrypted or plain
+type Database struct {
+	DB *sql.DB
+
+	path     string
+	key      []byte
+	salt     []byte
+	holder   *sql.Conn
+	mu       sync.Mutex
+	version  int64
+	stop     chan struct{}
+	stopped  sync.WaitGroup
+	closeErr error
+	closed   bool
+}
+
+// Open opens the database at path according to cfg
+func Open(path string, cfg Config) (*Database, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	encrypted := isEncrypted(data)
+
+	if !cfg.Enabled {
+		if encrypted {
+			if err := decryptInPlace(path, data, cfg); err != nil {
+				return nil, err
+			}
+		}
+		db, err := sql.Open("sqlite", path)
+		if err != nil {
+			return nil, err
+		}
+		return &Database{DB: db}, nil
+	}
+
+	passphrase, err := cfg.passphrase()
+	if err != nil {
+		return nil, err
+	}
+	if passphrase == "" {
+		return nil, fmt.Errorf("database encryption is enabled but no passphrase is configured")
+	}
+
+	var plain, salt []byte
+	switch {
+	case encrypted:
+		salt = data[len(magic)+1 : len(magic)+1+saltSize]
+	case len(data) > 0:
+		if plain, err = checkpointed(path); err != nil {
+			return nil, fmt.Errorf("failed to read %s for encryption: %w", path, err)
+		}
+		log.Info("encrypting database", "path", path)
+		fallthrough
+	default:
+		salt = make([]byte, saltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+	}
+	key := deriveKey(passphrase, salt)
+	if encrypted {
+		if plain, err = open(data, key); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	d, err := openMemory(plain)
+	if err != nil {
+		return nil, err
+	}
+	d.path = path
+	d.key = key
+	d.salt = salt
+
+	// a plain file is replaced by its encryption right away; a new
+	// database is first saved once it has changed
+	if !encrypted && len(plain) > 0 {
+		if err := d.save(); err != nil {
+			d.discard()
+			return nil, err
+		}
+		os.Remove(path + "-wal")
+		os.Remove(path + "-shm")
+	}
+
+	interval := cfg.SaveInterval
+	if interval <= 0 {
+		interval = defaultSaveGap
+	}
+	d.stop = make(chan struct{})
+	d.stopped.Add(1)
+	go d.saveEvery(interval)
+	return d, nil
+}
+
+// Encrypted reports whether the database is kept encrypted
+func (d *Database) Encrypted() bool {
+	return d.key != nil
+}
+
+// Flush writes the changes made since the last save to the encrypted file
+func (d *Database) Flush() error {
+	if !d.Encrypted() {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return nil
+	}
+	return d.saveIfChanged()
+}
+
+// Backup writes a consistent copy of the database to dest, encrypted
+// with the same key when the database is
+func (d *Database) Backup(dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0700); err != nil {
+		return err
+	}
+	if !d.Encrypted() {
+		// VACUUM INTO refuses to overwrite an existing file
+		if err := os.Remove(dest); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if _, err := d.DB.Exec("VACUUM INTO ?", dest); err != nil {
+			return fmt.Errorf("failed to back up database: %w", err)
+		}
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return fmt.Errorf("database is closed")
+	}
+	sealed, err := d.sealed()
+	if err != nil {
+		return err
+	}
+	return writeAtomic(dest, sealed)
+}
+
+// Close saves an encrypted database and closes it
+func (d *Database) Close() error {
+	if !d.Encrypted() {
+		return d.DB.Close()
+	}
+
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return d.closeErr
+	}
+	d.closed = true
+	d.mu.Unlock()
+
+	close(d.stop)
+	d.stopped.Wait()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	err := d.saveIfChanged()
+	d.discard()
+	d.closeErr = err
+	return err
+}
+
+func (d *Database) saveEvery(interval time.Duration) {
+	defer d.stopped.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.stop:
+			return
+		case <-ticker.C:
+			if err := d.Flush(); err != nil {
+				log.Error("failed to save encrypted database", "path", d.path, "error", err)
+			}
+		}
+	}
+}
+
+// saveIfChanged saves when another connection committed since the last
+// save, which SQLite reports through the holder's data_version
+func (d *Database) saveIfChanged() error {
+	var version int64
+	if err := d.holder.QueryRowContext(context.Background(), "PRAGMA data_version").Scan(&version); err != nil {
+		return err
+	}
+	if version == d.version {
+		return nil
+	}
+	if err := d.save(); err != nil {
+		return err
+	}
+	d.version = version
+	return nil
+}
+
+func (d *Database) save() error {
+	sealed, err := d.sealed()
+	if err != nil {
+		return err
+	}
+	return writeAtomic(d.path, sealed)
+}
+
+// sealed serializes the database and encrypts the image
+func (d *Database) sealed() ([]byte, error) {
+	var plain []byte
+	err := d.holder.Raw(func(conn interface{}) error {
+		var err error
+		plain, err = conn.(serializer).Serialize()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize database: %w", err)
+	}
+	return seal(plain, d.key, d.salt)
+}
+
+func (d *Database) discard() {
+	d.holder.Close()
+	d.DB.Close()
+}
+
+type serializer interface {
+	Serialize() ([]byte, error)
+}
+
+type backuper interface {
+	NewBackup(string) (*sqlite.Backup, error)
+}
+
+// openMemory opens a shared in-memory database holding the SQLite image
+// plain, or an empty one. The pool's connections all see it; the holder
+// connection keeps it alive and serializes it.
+func openMemory(plain []byte) (*Database, error) {
+	name := make([]byte, 8)
+	rand.Read(name)
+	uri := "file:/mayla-" + hex.EncodeToString(name) + "?vfs=memdb"
+
+	db, err := sql.Open("sqlite", uri)
+	if err != nil {
+		return nil, err
+	}
+	holder, err := db.Conn(context.Background())
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	d := &Database{DB: db, holder: holder}
+
+	if len(plain) > 0 {
+		if err := restore(uri, plain); err != nil {
+			d.discard()
+			return nil, fmt.Errorf("failed to load database: %w", err)
+		}
+	}
+	if err := holder.QueryRowContext(context.Background(), "PRAGMA data_version").Scan(&d.version); err != nil {
+		d.discard()
+		return nil, err
+	}
+	return d, nil
+}
+
+// restore copies the SQLite image plain into the database at uri. The
+// image is read through a read-only file system kept in memory.
+func restore(uri string, plain []byte) error {
+	// a WAL database would look for its log next to the image: mark it
+	// as using a rollback journal, which the copy does not need either
+	if len(plain) > 19 && plain[18] == 2 {
+		plain = append([]byte(nil), plain...)
+		plain[18], plain[19] = 1, 1
+	}
+
+	vfsName, fsys, err := vfs.New(imageFS{name: "image.db", data: plain})
+	if err != nil {
+		return err
+	}
+	defer fsys.Close()
+
+	conn, err := (&sqlite.Driver{}).Open("file:image.db?vfs=" + vfsName + "&mode=ro&immutable=1")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	src, ok := conn.(backuper)
+	if !ok {
+		return fmt.Errorf("sqlite driver cannot back up")
+	}
+	backup, err := src.NewBackup(uri)
+	if err != nil {
+		return err
+	}
+	for more := true; more; {
+		if more, err = backup.Step(-1); err != nil {
+			backup.Finish()
+			return err
+		}
+	}
+	return backup.Finish()
+}
+
+// imageFS is a read-only file system holding the one database image
+// restore reads
+type imageFS struct {
+	name string
+	data []byte
+}
+
+func (f imageFS) Open(name string) (fs.File, error) {
+	if name != f.name {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return &imageFile{Reader: bytes.NewReader(f.data), fs: f}, nil
+}
+
+// imageFile is an open imageFS image; the VFS reads it by seeking
+type imageFile struct {
+	*bytes.Reader
+	fs imageFS
+}
+
+func (f *imageFile) Stat() (fs.FileInfo, error) { return f, nil }
+func (f *imageFile) Close() error               { return nil }
+
+func (f *imageFile) Name() string       { return f.fs.name }
+func (f *imageFile) Size() int64        { return int64(len(f.fs.data)) }
+func (f *imageFile) Mode() fs.FileMode  { return 0444 }
+func (f *imageFile) ModTime() time.Time { return time.Time{} }
+func (f *imageFile) IsDir() bool        { return false }
+func (f *imageFile) Sys() interface{}   { return nil }
+
+// checkpointed returns the image of the plain database at path with its
+// write-ahead log folded in
+func checkpointed(path string) ([]byte, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(context.Background(), "PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return nil, err
+	}
+
+	var plain []byte
+	err = conn.Raw(func(c interface{}) error {
+		var err error
+		plain, err = c.(serializer).Serialize()
+		return err
+	})
+	return plain, err
+}
+
+// decryptInPlace replaces the encrypted file at path by the plain database
+func decryptInPlace(path string, data []byte, cfg Config) error {
+	passphrase, err := cfg.passphrase()
+	if err != nil {
+		return err
+	}
+	if passphrase == "" {
+		return fmt.Errorf("database %s is encrypted: configure its passphrase to open it", path)
+	}
+	salt := data[len(magic)+1 : len(magic)+1+saltSize]
+	plain, err := open(data, deriveKey(passphrase, salt))
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	log.Info("decrypting database", "path", path)
+	return writeAtomic(path, plain)
+}
+
+func isEncrypted(data []byte) bool {
+	return len(data) >= len(magic)+1+saltSize && bytes.HasPrefix(data, []byte(magic))
+}
+
+func deriveKey(passphrase string, salt []byte) []byte {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, keyIterations, 32)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// seal encrypts plain into the file format: magic, version, salt, nonce
+// and ciphertext, with everything before the ciphertext authenticated
+func seal(plain, key, salt []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	header := append([]byte(magic), formatVersion)
+	header = append(header, salt...)
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	header = append(header, nonce...)
+	return gcm.Seal(header, nonce, plain, header), nil
+}
+
+func open(data, key []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if data[len(magic)] != formatVersion {
+		return nil, fmt.Errorf("unsupported encrypted database version %d", data[len(magic)])
+	}
+	headerSize := len(magic) + 1 + saltSize + gcm.NonceSize()
+	if len(data) < headerSize+gcm.Overhead() {
+		return nil, ErrWrongPassphrase
+	}
+	header := data[:headerSize]
+	nonce := header[headerSize-gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, data[headerSize:], header)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	if !bytes.HasPrefix(plain, sqliteHeader) {
+		return nil, ErrWrongPassphrase
+	}
+	return plain, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func writeAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,139 @@
+package dbcrypt
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func count(t *testing.T, db *sql.DB) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow("SELECT count(*) FROM notes").Scan(&n); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return n
+}
+
+func TestEncryptedDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.db")
+	cfg := Config{Enabled: true, Passphrase: "correct horse"}
+
+	d, err := Open(path, cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !d.Encrypted() {
+		t.Fatal("expected an encrypted database")
+	}
+	if _, err := d.DB.Exec("CREATE TABLE notes (body TEXT); INSERT INTO notes VALUES ('proprietary secret')"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := d.Flush(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	d.DB.Exec("INSERT INTO notes VALUES ('second')")
+	if err := d.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if !isEncrypted(data) || bytes.Contains(data, []byte("proprietary secret")) || bytes.Contains(data, sqliteHeader) {
+		t.Fatal("expected the file to hold no plaintext")
+	}
+	if _, err := os.Stat(path + "-wal"); err == nil {
+		t.Error("expected no write-ahead log next to an encrypted database")
+	}
+
+	if _, err := Open(path, Config{Enabled: true, Passphrase: "wrong"}); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("expected a wrong passphrase to be refused, got %v", err)
+	}
+	if _, err := Open(path, Config{}); err == nil {
+		t.Error("expected an encrypted database not to open without its passphrase")
+	}
+
+	d, err = Open(path, cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := count(t, d.DB); n != 2 {
+		t.Errorf("expected both rows after reopening, got %d", n)
+	}
+	d.Close()
+
+	// with encryption off, the passphrase decrypts the file back
+	d, err = Open(path, Config{Passphrase: "correct horse"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.Encrypted() || count(t, d.DB) != 2 {
+		t.Error("expected a plain database with both rows")
+	}
+	d.Close()
+	if data, _ := os.ReadFile(path); !bytes.HasPrefix(data, sqliteHeader) {
+		t.Error("expected the file to be a plain SQLite database again")
+	}
+}
+
+func TestEncryptPlainDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "memory.db")
+	plain, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain.Exec("PRAGMA journal_mode=WAL")
+	if _, err := plain.Exec("CREATE TABLE notes (body TEXT); INSERT INTO notes VALUES ('a'), ('b'), ('c')"); err != nil {
+		t.Fatal(err)
+	}
+	plain.Close()
+
+	d, err := Open(path, Config{Enabled: true, Passphrase: "p"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer d.Close()
+
+	if n := count(t, d.DB); n != 3 {
+		t.Errorf("expected the plain rows to be carried over, got %d", n)
+	}
+	if data, _ := os.ReadFile(path); !isEncrypted(data) {
+		t.Error("expected the plain file to be encrypted in place on open")
+	}
+}
+
+func TestBackup(t *testing.T) {
+	for _, cfg := range []Config{{}, {Enabled: true, Passphrase: "correct horse"}} {
+		dir := t.TempDir()
+		d, err := Open(filepath.Join(dir, "index.db"), cfg)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		d.DB.Exec("CREATE TABLE notes (body TEXT); INSERT INTO notes VALUES ('kept')")
+
+		dest := filepath.Join(dir, "backups", "index.db")
+		if err := d.Backup(dest); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		// a second backup replaces the first
+		if err := d.Backup(dest); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		d.Close()
+
+		data, _ := os.ReadFile(dest)
+		if isEncrypted(data) != cfg.Enabled {
+			t.Errorf("expected the backup to be encrypted only with encryption on (%v)", cfg.Enabled)
+		}
+		restored, err := Open(dest, cfg)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if n := count(t, restored.DB); n != 1 {
+			t.Errorf("expected the row in the backup, got %d", n)
+		}
+		restored.Close()
+	}
+}
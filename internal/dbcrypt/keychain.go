@@ -0,0 +1,38 @@
+package dbcrypt
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// The keychain item holding the passphrase
+const (
+	keychainService = "may-la-mcp"
+	keychainAccount = "database"
+)
+
+// keychainPassphrase reads the passphrase from the macOS keychain or, on
+// Linux, the Secret Service through secret-tool
+func keychainPassphrase() (string, error) {
+	var cmd *exec.Cmd
+	var hint string
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", keychainService, "-a", keychainAccount, "-w")
+		hint = fmt.Sprintf("security add-generic-password -s %s -a %s -w", keychainService, keychainAccount)
+	case "linux":
+		cmd = exec.Command("secret-tool", "lookup", "service", keychainService, "account", keychainAccount)
+		hint = fmt.Sprintf("secret-tool store --label=May-la service %s account %s", keychainService, keychainAccount)
+	default:
+		return "", fmt.Errorf("the OS keychain is not supported on %s: configure the passphrase instead", runtime.GOOS)
+	}
+
+	out, err := cmd.Output()
+	passphrase := strings.TrimRight(string(out), "\r\n")
+	if err != nil || passphrase == "" {
+		return "", fmt.Errorf("no database passphrase in the keychain (store one with: %s)", hint)
+	}
+	return passphrase, nil
+}
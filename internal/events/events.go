@@ -0,0 +1,185 @@
+// Package events is a small bus for significant daemon events, such as
+// the index finishing or a language server crashing repeatedly, delivered
+// to webhook and command subscribers so they can reach Slack or a local
+// notifier. Publishing never blocks: each subscriber has a bounded queue
+// and events are dropped for one that falls behind.
+package events
+
+import (
+	"path"
+	"sync"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/logger"
+)
+
+var log = logger.ForComponent("events")
+
+const (
+	// IndexCompleted is published when the index queue drains
+	IndexCompleted = "index.completed"
+	// LSPCrashedRepeatedly is published when a language server crashes
+	// several times in a short window or is given up on
+	LSPCrashedRepeatedly = "lsp.crashed_repeatedly"
+	// PolicyViolation is published when a tool call is refused by the
+	// read-only mode or the denied paths
+	PolicyViolation = "policy.violation"
+
+	queueSize = 64
+)
+
+type Event struct {
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	// Text is a one-line summary, which is also what Slack shows
+	Text string                 `json:"text"`
+	Data map[string]interface{} `json:"data,omitempty"`
+}
+
+// Subscriber receives the events it matches
+type Subscriber interface {
+	Deliver(event Event) error
+}
+
+type SubscriberConfig struct {
+	// Events are the event types delivered, with path-style wildcards
+	// (lsp.*); empty means every event
+	Events []string `yaml:"events"`
+	// Webhook is a URL the events are POSTed to as JSON
+	Webhook string `yaml:"webhook"`
+	// Command is run through the shell for each event, with the event
+	// JSON on stdin
+	Command string `yaml:"command"`
+}
+
+type Config struct {
+	Subscribers []SubscriberConfig `yaml:"subscribers"`
+}
+
+type subscription struct {
+	events []string
+	sub    Subscriber
+	queue  chan Event
+}
+
+func (s *subscription) matches(eventType string) bool {
+	if len(s.events) == 0 {
+		return true
+	}
+	for _, pattern := range s.events {
+		if ok, _ := path.Match(pattern, eventType); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *subscription) run(done *sync.WaitGroup) {
+	defer done.Done()
+	for event := range s.queue {
+		if err := s.sub.Deliver(event); err != nil {
+			log.Warn("failed to deliver event", "type", event.Type, "error", err)
+		}
+	}
+}
+
+type Bus struct {
+	mu     sync.RWMutex
+	subs   []*subscription
+	closed bool
+	done   sync.WaitGroup
+}
+
+// New returns a bus delivering to the subscribers of cfg, or nil when
+// there are none
+func New(cfg Config) *Bus {
+	if len(cfg.Subscribers) == 0 {
+		return nil
+	}
+	b := &Bus{}
+	for _, sc := range cfg.Subscribers {
+		if sc.Webhook != "" {
+			b.Subscribe(sc.Events, NewWebhook(sc.Webhook))
+		}
+		if sc.Command != "" {
+			b.Subscribe(sc.Events, NewCommand(sc.Command))
+		}
+	}
+	return b
+}
+
+// Subscribe delivers the events matching patterns to sub
+func (b *Bus) Subscribe(patterns []string, sub Subscriber) {
+	s := &subscription{events: patterns, sub: sub, queue: make(chan Event, queueSize)}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.subs = append(b.subs, s)
+	b.done.Add(1)
+	go s.run(&b.done)
+}
+
+// Publish queues the event for its subscribers
+func (b *Bus) Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return
+	}
+	for _, s := range b.subs {
+		if !s.matches(event.Type) {
+			continue
+		}
+		select {
+		case s.queue <- event:
+		default:
+			log.Debug("dropping event for slow subscriber", "type", event.Type)
+		}
+	}
+}
+
+// Close stops accepting events and waits for the queued ones to be
+// delivered
+func (b *Bus) Close() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	for _, s := range b.subs {
+		close(s.queue)
+	}
+	b.mu.Unlock()
+	b.done.Wait()
+}
+
+var (
+	defaultMu  sync.RWMutex
+	defaultBus *Bus
+)
+
+// SetDefault installs the bus used by Publish; nil disables it.
+func SetDefault(b *Bus) {
+	defaultMu.Lock()
+	defaultBus = b
+	defaultMu.Unlock()
+}
+
+// Publish sends an event of the given type to the installed bus
+func Publish(eventType, text string, data map[string]interface{}) {
+	defaultMu.RLock()
+	b := defaultBus
+	defaultMu.RUnlock()
+
+	if b != nil {
+		b.Publish(Event{Type: eventType, Text: text, Data: data})
+	}
+}
@@ -0,0 +1,82 @@
+package events
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type recorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *recorder) Deliver(event Event) error {
+	r.mu.Lock()
+	r.events = append(r.events, event)
+	r.mu.Unlock()
+	return nil
+}
+
+func TestBusFiltersEvents(t *testing.T) {
+	b := &Bus{}
+	all, lsp := &recorder{}, &recorder{}
+	b.Subscribe(nil, all)
+	b.Subscribe([]string{"lsp.*"}, lsp)
+
+	b.Publish(Event{Type: IndexCompleted, Text: "done"})
+	b.Publish(Event{Type: LSPCrashedRepeatedly, Text: "gopls"})
+	b.Close()
+	b.Publish(Event{Type: PolicyViolation})
+
+	if len(all.events) != 2 {
+		t.Errorf("expected both events before close, got %d", len(all.events))
+	}
+	if len(lsp.events) != 1 || lsp.events[0].Type != LSPCrashedRepeatedly {
+		t.Errorf("expected only the lsp event, got %+v", lsp.events)
+	}
+	if all.events[0].Time.IsZero() {
+		t.Error("expected events to be timestamped")
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	var got map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer server.Close()
+
+	b := New(Config{Subscribers: []SubscriberConfig{{Webhook: server.URL}}})
+	b.Publish(Event{Type: IndexCompleted, Text: "Indexing completed", Data: map[string]interface{}{"indexed": 3}})
+	b.Close()
+
+	if got["type"] != IndexCompleted || got["text"] != "Indexing completed" {
+		t.Errorf("expected the event with a Slack text field, got %v", got)
+	}
+}
+
+func TestCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+	out := filepath.Join(t.TempDir(), "event")
+
+	b := New(Config{Subscribers: []SubscriberConfig{{Command: `echo "$MAYLA_EVENT" > ` + out + `; cat >> ` + out}}})
+	b.Publish(Event{Type: PolicyViolation, Text: "Refused write"})
+	b.Close()
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(string(data), PolicyViolation+"\n") || !strings.Contains(string(data), `"text":"Refused write"`) {
+		t.Errorf("expected the event type and JSON, got %q", data)
+	}
+}
@@ -0,0 +1,81 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const deliveryTimeout = 30 * time.Second
+
+// Webhook POSTs each event as JSON. The text field makes the payload a
+// valid Slack incoming webhook message.
+type Webhook struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhook(url string) *Webhook {
+	return &Webhook{url: url, client: &http.Client{Timeout: deliveryTimeout}}
+}
+
+func (w *Webhook) Deliver(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s: %s", w.url, resp.Status)
+	}
+	return nil
+}
+
+// Command runs a shell command for each event, with the event JSON on
+// stdin and its type and text in MAYLA_EVENT and MAYLA_EVENT_TEXT, e.g.
+// notify-send "May-la" "$MAYLA_EVENT_TEXT"
+type Command struct {
+	command string
+}
+
+func NewCommand(command string) *Command {
+	return &Command{command: command}
+}
+
+func (c *Command) Deliver(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), deliveryTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", c.command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", c.command)
+	}
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = append(os.Environ(), "MAYLA_EVENT="+event.Type, "MAYLA_EVENT_TEXT="+event.Text)
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("event command failed: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
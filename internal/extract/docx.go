@@ -0,0 +1,199 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// maxDocxPart bounds one decompressed part of a DOCX package
+const maxDocxPart = 64 << 20
+
+// DOCX returns the text of a Word document as markdown: headings, by their
+// outline level, become # headings, numbered and bulleted paragraphs list
+// items and table rows lines of cells separated by " | ", with a blank line
+// between blocks.
+func DOCX(data []byte) (string, error) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("not a DOCX file: %w", err)
+	}
+
+	document, err := readDocxPart(r, "word/document.xml")
+	if err != nil {
+		return "", err
+	}
+	var levels map[string]int
+	if styles, err := readDocxPart(r, "word/styles.xml"); err == nil {
+		levels = headingStyles(styles)
+	}
+
+	text, err := docxText(document, levels)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(text) == "" {
+		return "", errNoText
+	}
+	return text, nil
+}
+
+func readDocxPart(r *zip.Reader, name string) ([]byte, error) {
+	f, err := r.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("not a DOCX file: %w", err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxDocxPart+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxDocxPart {
+		return nil, fmt.Errorf("%s is over %d bytes", name, maxDocxPart)
+	}
+	return data, nil
+}
+
+// headingStyles returns the heading level of each paragraph style that has
+// one: from its outline level, or from a "heading N" or "Title" name, which
+// holds in every language Word is localized to since names stay English
+func headingStyles(styles []byte) map[string]int {
+	levels := make(map[string]int)
+	decoder := xml.NewDecoder(bytes.NewReader(styles))
+	var id string
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return levels
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "style":
+			id = attr(start, "styleId")
+		case "name":
+			name := strings.ToLower(attr(start, "val"))
+			if name == "title" {
+				levels[id] = 1
+			} else if n, ok := strings.CutPrefix(name, "heading "); ok {
+				if level, err := strconv.Atoi(n); err == nil && level >= 1 && level <= 6 {
+					levels[id] = level
+				}
+			}
+		case "outlineLvl":
+			if level, err := strconv.Atoi(attr(start, "val")); err == nil && level < 6 && id != "" {
+				levels[id] = level + 1
+			}
+		}
+	}
+}
+
+func attr(start xml.StartElement, local string) string {
+	for _, a := range start.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// docxText walks the body of document.xml, writing each paragraph as one
+// markdown block
+func docxText(document []byte, levels map[string]int) (string, error) {
+	var out strings.Builder
+	var paragraph strings.Builder
+	var cells []string
+	heading, listItem, inText, tableDepth := 0, false, false, 0
+
+	block := func(text string) {
+		if out.Len() > 0 {
+			out.WriteString("\n\n")
+		}
+		out.WriteString(text)
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(document))
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid document.xml: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				paragraph.Reset()
+				heading, listItem = 0, false
+			case "pStyle":
+				heading = levels[attr(t, "val")]
+			case "outlineLvl":
+				if level, err := strconv.Atoi(attr(t, "val")); err == nil && level < 6 {
+					heading = level + 1
+				}
+			case "numPr":
+				listItem = true
+			case "t":
+				inText = true
+			case "tab":
+				paragraph.WriteByte('\t')
+			case "br", "cr":
+				paragraph.WriteByte('\n')
+			case "tbl":
+				tableDepth++
+			case "tr":
+				cells = cells[:0]
+			}
+		case xml.CharData:
+			if inText {
+				paragraph.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				text := strings.TrimSpace(paragraph.String())
+				switch {
+				case tableDepth > 0:
+					// paragraphs of a cell are joined into the cell
+					if len(cells) == 0 {
+						cells = append(cells, "")
+					}
+					cells[len(cells)-1] = strings.TrimSpace(cells[len(cells)-1] + " " + text)
+				case text == "":
+				case heading > 0:
+					block(strings.Repeat("#", heading) + " " + text)
+				case listItem:
+					block("- " + text)
+				default:
+					block(text)
+				}
+			case "tc":
+				if tableDepth > 0 {
+					cells = append(cells, "")
+				}
+			case "tr":
+				if len(cells) > 0 && cells[len(cells)-1] == "" {
+					cells = cells[:len(cells)-1]
+				}
+				if row := strings.Join(cells, " | "); strings.Trim(row, " |") != "" {
+					block(row)
+				}
+				cells = cells[:0]
+			case "tbl":
+				tableDepth--
+			}
+		}
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,102 @@
+// Package extract reads the text out of document formats, PDF and DOCX, so
+// the read tool can return it and the indexer can make documents searchable.
+// Both readers are pure Go; extraction is off until enabled in the config.
+package extract
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Formats extract recognizes, by file extension
+const (
+	FormatPDF  = "pdf"
+	FormatDOCX = "docx"
+)
+
+// PageSeparator separates the pages of extracted PDF text
+const PageSeparator = "\f"
+
+// ErrTooLarge is returned for documents over the configured size limit
+var ErrTooLarge = errors.New("document too large to extract")
+
+type Config struct {
+	// Enabled turns extraction on for read and the index
+	Enabled bool `yaml:"enabled"`
+	// MaxSize is the largest document extracted, in bytes
+	MaxSize int64 `yaml:"max_size"`
+}
+
+func DefaultConfig() Config {
+	return Config{MaxSize: 20 * 1024 * 1024}
+}
+
+var (
+	mu     sync.RWMutex
+	config = DefaultConfig()
+)
+
+// SetConfig configures extraction for the read tool and the indexer
+func SetConfig(cfg Config) {
+	mu.Lock()
+	defer mu.Unlock()
+	config = cfg
+}
+
+func current() Config {
+	mu.RLock()
+	defer mu.RUnlock()
+	return config
+}
+
+// Format returns the document format of path when extraction is enabled
+// and path is a document, and "" otherwise
+func Format(path string) string {
+	if !current().Enabled {
+		return ""
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".pdf":
+		return FormatPDF
+	case ".docx":
+		return FormatDOCX
+	}
+	return ""
+}
+
+// File returns the text of the document at path
+func File(path string) (string, error) {
+	format := Format(path)
+	if format == "" {
+		return "", fmt.Errorf("%s is not an extractable document", path)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if limit := current().MaxSize; limit > 0 && info.Size() > limit {
+		return "", fmt.Errorf("%w: %s is %d bytes, the limit is %d", ErrTooLarge, path, info.Size(), limit)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return Bytes(format, data)
+}
+
+// Bytes returns the text of a document in the given format
+func Bytes(format string, data []byte) (string, error) {
+	switch format {
+	case FormatPDF:
+		return PDF(data)
+	case FormatDOCX:
+		return DOCX(data)
+	}
+	return "", fmt.Errorf("unknown document format %q", format)
+}
@@ -0,0 +1,239 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// buildPDF writes a PDF of the given objects, numbered from 1, with a
+// cross-reference table and a trailer pointing at object 1 as the catalog
+func buildPDF(objects []string, trailer string) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R %s >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, trailer, xref)
+	return b.Bytes()
+}
+
+func stream(dict, data string) string {
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+}
+
+func deflate(data string) string {
+	var b bytes.Buffer
+	w := zlib.NewWriter(&b)
+	w.Write([]byte(data))
+	w.Close()
+	return b.String()
+}
+
+func samplePDF() []byte {
+	cmap := `/CIDInit /ProcSet findresource begin
+12 dict begin
+begincmap
+1 begincodespacerange
+<0000> <FFFF>
+endcodespacerange
+2 beginbfchar
+<0001> <0048>
+<0002> <00E9>
+endbfchar
+1 beginbfrange
+<0010> <0012> <0061>
+endbfrange
+endcmap
+end
+end`
+	page1 := "BT /F1 12 Tf 72 720 Td (Design notes) Tj 0 -14 Td [(Wor) -400 (ld) 20 (s)] TJ T* (caf\\351) Tj ET"
+	page2 := "BT /F2 12 Tf 72 720 Td <000100020010> Tj 0 -14 Td <00110012> Tj ET"
+
+	return buildPDF([]string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 /Resources << /Font << /F1 5 0 R /F2 6 0 R >> >> >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 7 0 R >>",
+		"<< /Type /Page /Parent 2 0 R /Contents [8 0 R] >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type0 /BaseFont /Custom /Encoding /Identity-H /ToUnicode 9 0 R >>",
+		stream("", page1),
+		stream("/Filter /FlateDecode", deflate(page2)),
+		stream("", cmap),
+	}, "")
+}
+
+func TestPDF(t *testing.T) {
+	text, err := PDF(samplePDF())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pages := strings.Split(text, PageSeparator)
+	if len(pages) != 2 {
+		t.Fatalf("expected 2 pages, got %d: %q", len(pages), text)
+	}
+	if want := "Design notes\nWor lds\ncafé"; pages[0] != want {
+		t.Errorf("page 1: expected %q, got %q", want, pages[0])
+	}
+	if want := "Héa\nbc"; pages[1] != want {
+		t.Errorf("page 2: expected %q, got %q", want, pages[1])
+	}
+}
+
+func TestPDFObjectStreams(t *testing.T) {
+	// the page tree and font live in a compressed object stream, as
+	// PDF 1.5 writers put them
+	packed := []string{
+		"<< /Type /Pages /Kids [5 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 4 0 R /Resources << /Font << /F1 6 0 R >> >> /Contents 2 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	}
+	var header, body strings.Builder
+	for i, obj := range packed {
+		fmt.Fprintf(&header, "%d %d ", i+4, body.Len())
+		body.WriteString(obj + "\n")
+	}
+	objStm := stream(fmt.Sprintf("/Type /ObjStm /N %d /First %d /Filter /FlateDecode", len(packed), header.Len()),
+		deflate(header.String()+body.String()))
+
+	text, err := PDF(buildPDF([]string{
+		"<< /Type /Catalog /Pages 4 0 R >>",
+		stream("", "BT /F1 10 Tf (Packed) Tj ET"),
+		objStm,
+	}, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "Packed" {
+		t.Errorf("expected %q, got %q", "Packed", text)
+	}
+}
+
+func TestPDFErrors(t *testing.T) {
+	if _, err := PDF([]byte("not a pdf")); err == nil {
+		t.Error("expected an error for data that is not a PDF")
+	}
+
+	encrypted := buildPDF([]string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [] /Count 0 >>",
+		"<< /Filter /Standard /V 2 /R 3 >>",
+	}, "/Encrypt 3 0 R")
+	if _, err := PDF(encrypted); !errors.Is(err, errEncrypted) {
+		t.Errorf("expected errEncrypted, got %v", err)
+	}
+
+	scanned := buildPDF([]string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>",
+		stream("", "q 612 0 0 792 0 0 cm /Im1 Do Q"),
+	}, "")
+	if _, err := PDF(scanned); !errors.Is(err, errNoText) {
+		t.Errorf("expected errNoText for a page without text, got %v", err)
+	}
+}
+
+func buildDOCX(t *testing.T, parts map[string]string) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	w := zip.NewWriter(&b)
+	for name, content := range parts {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+const wordNS = `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"`
+
+func TestDOCX(t *testing.T) {
+	styles := `<w:styles ` + wordNS + `>
+<w:style w:type="paragraph" w:styleId="Titel"><w:name w:val="Title"/></w:style>
+<w:style w:type="paragraph" w:styleId="berschrift2"><w:name w:val="heading 2"/></w:style>
+<w:style w:type="paragraph" w:styleId="Custom"><w:name w:val="Custom"/><w:pPr><w:outlineLvl w:val="2"/></w:pPr></w:style>
+</w:styles>`
+	document := `<w:document ` + wordNS + `><w:body>
+<w:p><w:pPr><w:pStyle w:val="Titel"/></w:pPr><w:r><w:t>Cache design</w:t></w:r></w:p>
+<w:p><w:r><w:t xml:space="preserve">Entries expire </w:t></w:r><w:r><w:t>after an hour.</w:t></w:r></w:p>
+<w:p><w:pPr><w:pStyle w:val="berschrift2"/></w:pPr><w:r><w:t>Eviction</w:t></w:r></w:p>
+<w:p><w:pPr><w:numPr><w:ilvl w:val="0"/><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t>LRU</w:t></w:r></w:p>
+<w:p></w:p>
+<w:p><w:pPr><w:pStyle w:val="Custom"/></w:pPr><w:r><w:t>Limits</w:t></w:r></w:p>
+<w:tbl>
+<w:tr><w:tc><w:p><w:r><w:t>Key</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Value</w:t></w:r></w:p></w:tc></w:tr>
+<w:tr><w:tc><w:p><w:r><w:t>size</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>64</w:t><w:tab/><w:t>MB</w:t></w:r></w:p></w:tc></w:tr>
+</w:tbl>
+</w:body></w:document>`
+
+	text, err := DOCX(buildDOCX(t, map[string]string{
+		"word/document.xml": document,
+		"word/styles.xml":   styles,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "# Cache design\n\nEntries expire after an hour.\n\n## Eviction\n\n- LRU\n\n### Limits\n\nKey | Value\n\nsize | 64\tMB"
+	if text != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, text)
+	}
+
+	if _, err := DOCX([]byte("not a zip")); err == nil {
+		t.Error("expected an error for data that is not a DOCX file")
+	}
+	if _, err := DOCX(buildDOCX(t, map[string]string{"xl/workbook.xml": "<workbook/>"})); err == nil {
+		t.Error("expected an error for a zip without word/document.xml")
+	}
+}
+
+func TestFile(t *testing.T) {
+	dir := t.TempDir()
+	pdf := filepath.Join(dir, "notes.PDF")
+	if err := os.WriteFile(pdf, samplePDF(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	defer SetConfig(DefaultConfig())
+
+	if format := Format(pdf); format != "" {
+		t.Errorf("expected no format while extraction is disabled, got %q", format)
+	}
+
+	SetConfig(Config{Enabled: true, MaxSize: 1 << 20})
+	if format := Format(pdf); format != FormatPDF {
+		t.Errorf("expected %q, got %q", FormatPDF, format)
+	}
+	if format := Format(filepath.Join(dir, "main.go")); format != "" {
+		t.Errorf("expected no format for a source file, got %q", format)
+	}
+	text, err := File(pdf)
+	if err != nil || !strings.HasPrefix(text, "Design notes") {
+		t.Errorf("unexpected text %q, error %v", text, err)
+	}
+
+	SetConfig(Config{Enabled: true, MaxSize: 100})
+	if _, err := File(pdf); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("expected ErrTooLarge, got %v", err)
+	}
+}
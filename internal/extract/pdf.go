@@ -0,0 +1,602 @@
+package extract
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+const (
+	// maxDecodedStream bounds one decompressed stream, so a small file
+	// cannot inflate into an unbounded amount of memory
+	maxDecodedStream = 64 << 20
+	// maxFormDepth bounds nested form XObjects
+	maxFormDepth = 8
+	// tjSpace is the TJ adjustment, in thousandths of a text unit, beyond
+	// which a gap between two strings is read as a space
+	tjSpace = 250
+)
+
+var errNoText = errors.New("no text found")
+
+// PDF returns the text of a PDF document, page by page in reading order,
+// with a form feed between pages as pdftotext writes it. Text is decoded
+// through each font's ToUnicode map when it has one and its encoding
+// otherwise; scanned pages without a text layer give no text.
+func PDF(data []byte) (string, error) {
+	doc, err := parsePDF(data)
+	if err != nil {
+		return "", err
+	}
+
+	pages := doc.pages()
+	if len(pages) == 0 {
+		return "", fmt.Errorf("no pages found")
+	}
+
+	texts := make([]string, len(pages))
+	found := false
+	for i, page := range pages {
+		w := &textWriter{}
+		resources := doc.dict(page["Resources"])
+		doc.run(w, doc.contents(page["Contents"]), resources, 0)
+		texts[i] = strings.TrimSpace(w.String())
+		found = found || texts[i] != ""
+	}
+	if !found {
+		return "", errNoText
+	}
+	return strings.Join(texts, PageSeparator), nil
+}
+
+// pages returns the page dictionaries in document order, each with its
+// inherited Resources filled in. A file whose page tree cannot be walked
+// gives its page objects in object number order.
+func (d *pdfDocument) pages() []pdfDict {
+	var root pdfDict
+	for i := len(d.trailers) - 1; i >= 0 && root == nil; i-- {
+		root = d.dict(d.trailers[i]["Root"])
+	}
+	if root == nil {
+		for _, num := range d.numbers() {
+			if dict := d.dict(d.objects[num]); dict["Type"] == pdfName("Catalog") {
+				root = dict
+				break
+			}
+		}
+	}
+
+	var pages []pdfDict
+	seen := make(map[int]bool)
+	var walk func(node interface{}, inherited interface{})
+	walk = func(node interface{}, inherited interface{}) {
+		if ref, ok := node.(pdfRef); ok {
+			if seen[ref.num] {
+				return
+			}
+			seen[ref.num] = true
+		}
+		dict := d.dict(node)
+		if dict == nil {
+			return
+		}
+		if r, ok := dict["Resources"]; ok {
+			inherited = r
+		}
+		kids, ok := d.resolve(dict["Kids"]).(pdfArray)
+		if !ok {
+			if dict["Type"] == pdfName("Page") || dict["Contents"] != nil {
+				page := pdfDict{"Contents": dict["Contents"], "Resources": inherited}
+				pages = append(pages, page)
+			}
+			return
+		}
+		for _, kid := range kids {
+			walk(kid, inherited)
+		}
+	}
+	if root != nil {
+		walk(root["Pages"], nil)
+	}
+
+	if len(pages) == 0 {
+		for _, num := range d.numbers() {
+			if dict := d.dict(d.objects[num]); dict["Type"] == pdfName("Page") {
+				pages = append(pages, dict)
+			}
+		}
+	}
+	return pages
+}
+
+// contents returns the decoded content of a page, whose Contents is one
+// stream or an array of streams to be read as one
+func (d *pdfDocument) contents(obj interface{}) []byte {
+	var parts []interface{}
+	switch v := d.resolve(obj).(type) {
+	case pdfArray:
+		parts = v
+	case *pdfStream:
+		parts = []interface{}{v}
+	}
+
+	var data []byte
+	for _, part := range parts {
+		stream, ok := d.resolve(part).(*pdfStream)
+		if !ok {
+			continue
+		}
+		decoded, err := d.decode(stream)
+		if err != nil {
+			continue
+		}
+		data = append(data, decoded...)
+		data = append(data, '\n')
+	}
+	return data
+}
+
+// run interprets a content stream, writing the text it shows to w. Only the
+// operators placing and showing text are followed; graphics are ignored.
+func (d *pdfDocument) run(w *textWriter, content []byte, resources pdfDict, depth int) {
+	fonts := map[pdfName]*pdfFont{}
+	fontDicts := d.dict(resources["Font"])
+	font := func(name pdfName) *pdfFont {
+		if f, ok := fonts[name]; ok {
+			return f
+		}
+		f := d.font(d.dict(fontDicts[name]))
+		fonts[name] = f
+		return f
+	}
+
+	var current *pdfFont
+	var y float64
+	l := &pdfLexer{data: content}
+	var operands []interface{}
+	for {
+		tok, err := l.token()
+		if err == io.EOF {
+			return
+		}
+		op, isOperator := tok.(pdfKeyword)
+		if !isOperator || op == "<<" || op == "[" || op == "true" || op == "false" || op == "null" {
+			obj, _ := l.complete(tok)
+			operands = append(operands, obj)
+			continue
+		}
+
+		switch op {
+		case "Tf":
+			if len(operands) >= 2 {
+				if name, ok := operands[len(operands)-2].(pdfName); ok {
+					current = font(name)
+				}
+			}
+		case "Td", "TD":
+			if len(operands) >= 2 {
+				tx, _ := operands[len(operands)-2].(float64)
+				ty, _ := operands[len(operands)-1].(float64)
+				if ty != 0 {
+					y += ty
+					w.newline()
+				} else if tx != 0 {
+					w.space()
+				}
+			}
+		case "Tm":
+			if len(operands) >= 6 {
+				f, _ := operands[len(operands)-1].(float64)
+				if math.Abs(f-y) > 0.5 {
+					w.newline()
+				} else {
+					w.space()
+				}
+				y = f
+			}
+		case "T*":
+			w.newline()
+		case "Tj":
+			if len(operands) >= 1 {
+				w.write(current.decode(operands[len(operands)-1]))
+			}
+		case "'":
+			w.newline()
+			if len(operands) >= 1 {
+				w.write(current.decode(operands[len(operands)-1]))
+			}
+		case "\"":
+			w.newline()
+			if len(operands) >= 3 {
+				w.write(current.decode(operands[len(operands)-1]))
+			}
+		case "TJ":
+			if len(operands) >= 1 {
+				items, _ := operands[len(operands)-1].(pdfArray)
+				for _, item := range items {
+					if n, ok := item.(float64); ok {
+						if n < -tjSpace {
+							w.space()
+						}
+						continue
+					}
+					w.write(current.decode(item))
+				}
+			}
+		case "Do":
+			if len(operands) >= 1 && depth < maxFormDepth {
+				name, _ := operands[len(operands)-1].(pdfName)
+				form, ok := d.resolve(d.dict(resources["XObject"])[name]).(*pdfStream)
+				if ok && form.dict["Subtype"] == pdfName("Form") {
+					formResources := d.dict(form.dict["Resources"])
+					if formResources == nil {
+						formResources = resources
+					}
+					if data, err := d.decode(form); err == nil {
+						d.run(w, data, formResources, depth+1)
+					}
+				}
+			}
+		case "BI":
+			skipInlineImage(l)
+		}
+		operands = operands[:0]
+	}
+}
+
+// skipInlineImage moves l past the data of an inline image, whose binary
+// bytes run from ID to an EI surrounded by white space
+func skipInlineImage(l *pdfLexer) {
+	for {
+		tok, err := l.token()
+		if err != nil {
+			return
+		}
+		if tok == pdfKeyword("ID") {
+			break
+		}
+	}
+	for i := l.pos + 1; i+2 <= len(l.data); i++ {
+		if l.data[i] == 'E' && l.data[i+1] == 'I' && isPDFSpace(l.data[i-1]) &&
+			(i+2 == len(l.data) || isPDFSpace(l.data[i+2])) {
+			l.pos = i + 2
+			return
+		}
+	}
+	l.pos = len(l.data)
+}
+
+// textWriter collects extracted text, keeping single spaces and line breaks
+type textWriter struct {
+	strings.Builder
+}
+
+func (w *textWriter) last() byte {
+	if w.Len() == 0 {
+		return '\n'
+	}
+	return w.String()[w.Len()-1]
+}
+
+func (w *textWriter) newline() {
+	if w.last() != '\n' {
+		w.WriteByte('\n')
+	}
+}
+
+func (w *textWriter) space() {
+	if c := w.last(); c != ' ' && c != '\n' {
+		w.WriteByte(' ')
+	}
+}
+
+func (w *textWriter) write(s string) {
+	w.WriteString(s)
+}
+
+// pdfFont maps the character codes of shown strings to text
+type pdfFont struct {
+	// toUnicode maps codes, by their bytes, to text
+	toUnicode map[string]string
+	// codeLengths are the byte lengths codes come in, shortest first
+	codeLengths []int
+	// simple maps single-byte codes of fonts without a ToUnicode map
+	simple *[256]string
+}
+
+// font builds the decoder of a font dictionary; a missing font decodes
+// bytes as Latin-1
+func (d *pdfDocument) font(dict pdfDict) *pdfFont {
+	f := &pdfFont{}
+	composite := dict["Subtype"] == pdfName("Type0")
+
+	if cmap, ok := d.resolve(dict["ToUnicode"]).(*pdfStream); ok {
+		if data, err := d.decode(cmap); err == nil {
+			f.toUnicode, f.codeLengths = parseCMap(data)
+		}
+	}
+	if len(f.codeLengths) == 0 {
+		f.codeLengths = []int{1}
+		if composite {
+			f.codeLengths = []int{2}
+		}
+	}
+	if !composite {
+		f.simple = d.simpleEncoding(dict)
+	}
+	return f
+}
+
+// decode returns the text a shown string stands for. Codes a composite
+// font cannot map, having no ToUnicode entry, give no text.
+func (f *pdfFont) decode(obj interface{}) string {
+	s, ok := obj.(pdfString)
+	if !ok {
+		return ""
+	}
+	if f == nil {
+		return latin1(string(s))
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		matched := false
+		for _, n := range f.codeLengths {
+			if i+n > len(s) {
+				break
+			}
+			if text, ok := f.toUnicode[string(s[i:i+n])]; ok {
+				b.WriteString(text)
+				i += n
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+		if f.simple == nil {
+			i += f.codeLengths[0]
+			continue
+		}
+		b.WriteString(f.simple[s[i]])
+		i++
+	}
+	return b.String()
+}
+
+func latin1(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		b.WriteRune(rune(s[i]))
+	}
+	return b.String()
+}
+
+// simpleEncoding returns the byte to text table of a simple font: its base
+// encoding with the Differences applied
+func (d *pdfDocument) simpleEncoding(dict pdfDict) *[256]string {
+	var table [256]string
+	base := d.resolve(dict["Encoding"])
+	encoding, _ := base.(pdfDict)
+	if encoding != nil {
+		base = d.resolve(encoding["BaseEncoding"])
+	}
+
+	var cm *charmap.Charmap
+	switch base {
+	case pdfName("WinAnsiEncoding"):
+		cm = charmap.Windows1252
+	case pdfName("MacRomanEncoding"):
+		cm = charmap.Macintosh
+	}
+	for i := range table {
+		if cm != nil {
+			table[i] = string(cm.DecodeByte(byte(i)))
+		} else {
+			table[i] = string(rune(i))
+		}
+	}
+
+	if differences, ok := d.resolve(encoding["Differences"]).(pdfArray); ok {
+		code := 0
+		for _, item := range differences {
+			switch v := d.resolve(item).(type) {
+			case float64:
+				code = int(v)
+			case pdfName:
+				if code >= 0 && code < len(table) {
+					if text, ok := glyphText(string(v)); ok {
+						table[code] = text
+					}
+				}
+				code++
+			}
+		}
+	}
+	return &table
+}
+
+// glyphNames maps the glyph names of the Adobe Standard Latin character
+// set that are not single letters or uniXXXX names
+var glyphNames = map[string]string{
+	"space": " ", "exclam": "!", "quotedbl": "\"", "numbersign": "#", "dollar": "$",
+	"percent": "%", "ampersand": "&", "quotesingle": "'", "quoteright": "’",
+	"parenleft": "(", "parenright": ")", "asterisk": "*", "plus": "+", "comma": ",",
+	"hyphen": "-", "minus": "−", "period": ".", "slash": "/", "colon": ":",
+	"semicolon": ";", "less": "<", "equal": "=", "greater": ">", "question": "?",
+	"at": "@", "bracketleft": "[", "backslash": "\\", "bracketright": "]",
+	"asciicircum": "^", "underscore": "_", "grave": "`", "quoteleft": "‘",
+	"braceleft": "{", "bar": "|", "braceright": "}", "asciitilde": "~",
+	"zero": "0", "one": "1", "two": "2", "three": "3", "four": "4", "five": "5",
+	"six": "6", "seven": "7", "eight": "8", "nine": "9",
+	"quotedblleft": "“", "quotedblright": "”", "endash": "–",
+	"emdash": "—", "bullet": "•", "ellipsis": "…", "dagger": "†",
+	"fi": "fi", "fl": "fl", "ff": "ff", "ffi": "ffi", "ffl": "ffl",
+	"copyright": "©", "registered": "®", "trademark": "™", "degree": "°",
+	"section": "§", "paragraph": "¶", "nbspace": " ",
+	"aacute": "á", "agrave": "à", "acircumflex": "â", "atilde": "ã", "adieresis": "ä",
+	"eacute": "é", "egrave": "è", "ecircumflex": "ê", "edieresis": "ë",
+	"iacute": "í", "igrave": "ì", "icircumflex": "î", "idieresis": "ï",
+	"oacute": "ó", "ograve": "ò", "ocircumflex": "ô", "otilde": "õ", "odieresis": "ö",
+	"uacute": "ú", "ugrave": "ù", "ucircumflex": "û", "udieresis": "ü",
+	"ccedilla": "ç", "ntilde": "ñ", "germandbls": "ß",
+	"Aacute": "Á", "Eacute": "É", "Iacute": "Í", "Oacute": "Ó", "Uacute": "Ú",
+	"Ccedilla": "Ç", "Ntilde": "Ñ", "Adieresis": "Ä", "Odieresis": "Ö", "Udieresis": "Ü",
+}
+
+// glyphText returns the text of a glyph name
+func glyphText(name string) (string, bool) {
+	if text, ok := glyphNames[name]; ok {
+		return text, true
+	}
+	if len(name) == 1 {
+		return name, true
+	}
+	// uniXXXX names a BMP character, uXXXX to uXXXXXX any character
+	if hexCode, ok := strings.CutPrefix(name, "uni"); ok && len(hexCode) >= 4 {
+		if r, err := strconv.ParseUint(hexCode[:4], 16, 32); err == nil {
+			return string(rune(r)), true
+		}
+	}
+	if hexCode, ok := strings.CutPrefix(name, "u"); ok && len(hexCode) >= 4 && len(hexCode) <= 6 {
+		if r, err := strconv.ParseUint(hexCode, 16, 32); err == nil {
+			return string(rune(r)), true
+		}
+	}
+	return "", false
+}
+
+// parseCMap reads the mappings of a ToUnicode CMap and the byte lengths of
+// its codes. Destinations are UTF-16BE.
+func parseCMap(data []byte) (map[string]string, []int) {
+	mapping := make(map[string]string)
+	lengths := make(map[int]bool)
+
+	l := &pdfLexer{data: data}
+	var operands []interface{}
+	section := ""
+	for {
+		tok, err := l.token()
+		if err != nil {
+			break
+		}
+		if kw, ok := tok.(pdfKeyword); ok && kw != "[" && kw != "<<" {
+			switch kw {
+			case "begincodespacerange", "beginbfchar", "beginbfrange":
+				section = string(kw)
+			case "endcodespacerange", "endbfchar", "endbfrange":
+				section = ""
+			}
+			operands = operands[:0]
+			continue
+		}
+
+		obj, _ := l.complete(tok)
+		operands = append(operands, obj)
+		switch section {
+		case "begincodespacerange":
+			if len(operands) == 2 {
+				if lo, ok := operands[0].(pdfString); ok && len(lo) > 0 {
+					lengths[len(lo)] = true
+				}
+				operands = operands[:0]
+			}
+		case "beginbfchar":
+			if len(operands) == 2 {
+				src, _ := operands[0].(pdfString)
+				dst, _ := operands[1].(pdfString)
+				mapping[string(src)] = utf16BE(dst)
+				lengths[len(src)] = true
+				operands = operands[:0]
+			}
+		case "beginbfrange":
+			if len(operands) == 3 {
+				addBFRange(mapping, operands)
+				if lo, ok := operands[0].(pdfString); ok {
+					lengths[len(lo)] = true
+				}
+				operands = operands[:0]
+			}
+		default:
+			operands = operands[:0]
+		}
+	}
+
+	var sorted []int
+	for n := range lengths {
+		if n > 0 {
+			sorted = append(sorted, n)
+		}
+	}
+	sort.Ints(sorted)
+	return mapping, sorted
+}
+
+// addBFRange maps the codes from lo to hi either to consecutive text,
+// starting at a string destination, or to the strings of an array
+func addBFRange(mapping map[string]string, operands []interface{}) {
+	lo, ok1 := operands[0].(pdfString)
+	hi, ok2 := operands[1].(pdfString)
+	if !ok1 || !ok2 || len(lo) != len(hi) || len(lo) == 0 || len(lo) > 4 {
+		return
+	}
+	start, end := codeValue(lo), codeValue(hi)
+	if end < start || end-start > 0xFFFF {
+		return
+	}
+
+	switch dst := operands[2].(type) {
+	case pdfString:
+		units := utf16Units(dst)
+		if len(units) == 0 {
+			return
+		}
+		for code := start; code <= end; code++ {
+			mapping[codeBytes(code, len(lo))] = string(utf16.Decode(units))
+			units[len(units)-1]++
+		}
+	case pdfArray:
+		for i, item := range dst {
+			if s, ok := item.(pdfString); ok && start+uint32(i) <= end {
+				mapping[codeBytes(start+uint32(i), len(lo))] = utf16BE(s)
+			}
+		}
+	}
+}
+
+func codeValue(b pdfString) uint32 {
+	var v uint32
+	for i := 0; i < len(b); i++ {
+		v = v<<8 | uint32(b[i])
+	}
+	return v
+}
+
+func codeBytes(v uint32, n int) string {
+	b := make([]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		b[i] = byte(v)
+		v >>= 8
+	}
+	return string(b)
+}
+
+func utf16Units(s pdfString) []uint16 {
+	units := make([]uint16, 0, len(s)/2)
+	for i := 0; i+1 < len(s); i += 2 {
+		units = append(units, uint16(s[i])<<8|uint16(s[i+1]))
+	}
+	return units
+}
+
+func utf16BE(s pdfString) string {
+	return string(utf16.Decode(utf16Units(s)))
+}
@@ -0,0 +1,506 @@
+package extract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// The PDF object model, as far as text extraction needs it. Numbers are
+// float64, names, strings and keywords have their own types, and
+// indirect references stay unresolved until looked up.
+type (
+	pdfName    string
+	pdfString  string
+	pdfKeyword string
+	pdfArray   []interface{}
+	pdfDict    map[pdfName]interface{}
+	pdfRef     struct{ num, gen int }
+	pdfStream  struct {
+		dict pdfDict
+		raw  []byte
+	}
+)
+
+var errEncrypted = errors.New("encrypted PDFs are not supported")
+
+// pdfLexer reads PDF tokens and objects from data
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+func isPDFSpace(c byte) bool {
+	switch c {
+	case 0, '\t', '\n', '\f', '\r', ' ':
+		return true
+	}
+	return false
+}
+
+func isPDFDelimiter(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		switch {
+		case isPDFSpace(c):
+			l.pos++
+		case c == '%':
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// token returns the next token: a number, a name, a string, a keyword or
+// one of the delimiters "<<", ">>", "[" and "]". It returns io.EOF at the
+// end of data.
+func (l *pdfLexer) token() (interface{}, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, io.EOF
+	}
+
+	c := l.data[l.pos]
+	switch {
+	case c == '/':
+		return l.name(), nil
+	case c == '(':
+		return l.literalString(), nil
+	case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+		l.pos += 2
+		return pdfKeyword("<<"), nil
+	case c == '>' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '>':
+		l.pos += 2
+		return pdfKeyword(">>"), nil
+	case c == '<':
+		return l.hexString(), nil
+	case c == '[' || c == ']' || c == '{' || c == '}':
+		l.pos++
+		return pdfKeyword(l.data[l.pos-1 : l.pos]), nil
+	case c == ')' || c == '>':
+		// stray delimiters in damaged files are skipped
+		l.pos++
+		return l.token()
+	}
+
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	word := string(l.data[start:l.pos])
+	if n, err := strconv.ParseFloat(word, 64); err == nil {
+		return n, nil
+	}
+	return pdfKeyword(word), nil
+}
+
+func (l *pdfLexer) name() pdfName {
+	l.pos++
+	var name []byte
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		c := l.data[l.pos]
+		if c == '#' && l.pos+2 < len(l.data) {
+			if b, err := hex.DecodeString(string(l.data[l.pos+1 : l.pos+3])); err == nil {
+				name = append(name, b[0])
+				l.pos += 3
+				continue
+			}
+		}
+		name = append(name, c)
+		l.pos++
+	}
+	return pdfName(name)
+}
+
+func (l *pdfLexer) literalString() pdfString {
+	l.pos++
+	var s []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return pdfString(s)
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return pdfString(s)
+			}
+			c = l.data[l.pos]
+			l.pos++
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if c >= '0' && c <= '7' {
+					v := int(c - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(v)
+				}
+			}
+		}
+		s = append(s, c)
+	}
+	return pdfString(s)
+}
+
+func (l *pdfLexer) hexString() pdfString {
+	l.pos++
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		if c := l.data[l.pos]; !isPDFSpace(c) {
+			digits = append(digits, c)
+		}
+		l.pos++
+	}
+	l.pos++
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	b, _ := hex.DecodeString(string(digits))
+	return pdfString(b)
+}
+
+// object reads a whole object: a dictionary or array with its contents,
+// an indirect reference "n g R", or a single token
+func (l *pdfLexer) object() (interface{}, error) {
+	tok, err := l.token()
+	if err != nil {
+		return nil, err
+	}
+	return l.complete(tok)
+}
+
+// complete finishes the object tok starts
+func (l *pdfLexer) complete(tok interface{}) (interface{}, error) {
+	switch tok {
+	case pdfKeyword("<<"):
+		dict := pdfDict{}
+		for {
+			key, err := l.token()
+			if err != nil {
+				return dict, err
+			}
+			if key == pdfKeyword(">>") {
+				return dict, nil
+			}
+			name, ok := key.(pdfName)
+			if !ok {
+				continue
+			}
+			value, err := l.object()
+			if err != nil {
+				return dict, err
+			}
+			if value == pdfKeyword(">>") {
+				return dict, nil
+			}
+			dict[name] = value
+		}
+	case pdfKeyword("["):
+		var array pdfArray
+		for {
+			item, err := l.object()
+			if err != nil {
+				return array, err
+			}
+			if item == pdfKeyword("]") {
+				return array, nil
+			}
+			array = append(array, item)
+		}
+	case pdfKeyword("true"):
+		return true, nil
+	case pdfKeyword("false"):
+		return false, nil
+	case pdfKeyword("null"):
+		return nil, nil
+	}
+
+	// n g R is a reference; anything else after a number is put back
+	if num, ok := tok.(float64); ok {
+		save := l.pos
+		if gen, err := l.token(); err == nil {
+			if g, ok := gen.(float64); ok {
+				if r, err := l.token(); err == nil && r == pdfKeyword("R") {
+					return pdfRef{int(num), int(g)}, nil
+				}
+			}
+		}
+		l.pos = save
+	}
+	return tok, nil
+}
+
+// pdfDocument holds the objects of a PDF file by number
+type pdfDocument struct {
+	objects  map[int]interface{}
+	trailers []pdfDict
+}
+
+var objectHeader = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+
+// parsePDF finds every object of data by scanning for "n g obj" headers
+// rather than trusting the cross-reference table, which also reads files
+// whose offsets are damaged. Objects defined again by an incremental update
+// replace the earlier definition, and objects inside object streams are
+// unpacked.
+func parsePDF(data []byte) (*pdfDocument, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data[:min(len(data), 1024)], "\x00\t\n\f\r "), []byte("%PDF-")) {
+		return nil, fmt.Errorf("not a PDF file")
+	}
+
+	doc := &pdfDocument{objects: make(map[int]interface{})}
+	end := 0
+	for _, loc := range objectHeader.FindAllSubmatchIndex(data, -1) {
+		// a header inside the previous object's stream is stream data
+		if loc[0] < end {
+			continue
+		}
+		num, _ := strconv.Atoi(string(data[loc[2]:loc[3]]))
+		l := &pdfLexer{data: data, pos: loc[1]}
+		obj, err := l.object()
+		if err != nil && obj == nil {
+			continue
+		}
+		if dict, ok := obj.(pdfDict); ok {
+			if stream, ok := readStream(l, dict); ok {
+				obj = stream
+			}
+		}
+		doc.objects[num] = obj
+		end = l.pos
+	}
+
+	for _, at := range trailerKeyword.FindAllIndex(data, -1) {
+		l := &pdfLexer{data: data, pos: at[1]}
+		if dict, ok := mustObject(l).(pdfDict); ok {
+			doc.trailers = append(doc.trailers, dict)
+		}
+	}
+	// PDF 1.5 cross-reference streams carry the trailer entries
+	for _, num := range doc.numbers() {
+		if stream, ok := doc.objects[num].(*pdfStream); ok && stream.dict["Type"] == pdfName("XRef") {
+			doc.trailers = append(doc.trailers, stream.dict)
+		}
+	}
+	for _, trailer := range doc.trailers {
+		if _, ok := trailer["Encrypt"]; ok {
+			return nil, errEncrypted
+		}
+	}
+
+	doc.unpackObjectStreams()
+	return doc, nil
+}
+
+var trailerKeyword = regexp.MustCompile(`\btrailer\b`)
+
+func mustObject(l *pdfLexer) interface{} {
+	obj, _ := l.object()
+	return obj
+}
+
+// readStream reads the stream following dict, when there is one. A direct
+// /Length is trusted if the data it gives ends at "endstream"; otherwise the
+// data runs up to the next "endstream".
+func readStream(l *pdfLexer, dict pdfDict) (*pdfStream, bool) {
+	save := l.pos
+	if tok, err := l.token(); err != nil || tok != pdfKeyword("stream") {
+		l.pos = save
+		return nil, false
+	}
+	start := l.pos
+	if start < len(l.data) && l.data[start] == '\r' {
+		start++
+	}
+	if start < len(l.data) && l.data[start] == '\n' {
+		start++
+	}
+
+	if n, ok := dict["Length"].(float64); ok {
+		end := start + int(n)
+		if n >= 0 && end <= len(l.data) {
+			rest := bytes.TrimLeft(l.data[end:min(len(l.data), end+32)], "\x00\t\n\f\r ")
+			if bytes.HasPrefix(rest, []byte("endstream")) {
+				l.pos = end
+				return &pdfStream{dict: dict, raw: l.data[start:end]}, true
+			}
+		}
+	}
+
+	end := bytes.Index(l.data[start:], []byte("endstream"))
+	if end < 0 {
+		return &pdfStream{dict: dict, raw: l.data[start:]}, true
+	}
+	l.pos = start + end
+	return &pdfStream{dict: dict, raw: bytes.TrimRight(l.data[start:start+end], "\r\n")}, true
+}
+
+func (d *pdfDocument) numbers() []int {
+	nums := make([]int, 0, len(d.objects))
+	for num := range d.objects {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+	return nums
+}
+
+// unpackObjectStreams adds the objects compressed into object streams;
+// an object defined directly in the file keeps that definition
+func (d *pdfDocument) unpackObjectStreams() {
+	for _, num := range d.numbers() {
+		stream, ok := d.objects[num].(*pdfStream)
+		if !ok || stream.dict["Type"] != pdfName("ObjStm") {
+			continue
+		}
+		data, err := d.decode(stream)
+		if err != nil {
+			continue
+		}
+		count, _ := stream.dict["N"].(float64)
+		first, _ := d.resolve(stream.dict["First"]).(float64)
+		if int(first) > len(data) {
+			continue
+		}
+
+		header := &pdfLexer{data: data[:int(first)]}
+		for i := 0; i < int(count); i++ {
+			objNum, err1 := header.token()
+			offset, err2 := header.token()
+			n, ok1 := objNum.(float64)
+			o, ok2 := offset.(float64)
+			if err1 != nil || err2 != nil || !ok1 || !ok2 {
+				break
+			}
+			if _, exists := d.objects[int(n)]; exists {
+				continue
+			}
+			l := &pdfLexer{data: data, pos: int(first) + int(o)}
+			if obj, err := l.object(); err == nil {
+				d.objects[int(n)] = obj
+			}
+		}
+	}
+}
+
+// resolve follows references until it reaches a direct object; a missing
+// object resolves to nil
+func (d *pdfDocument) resolve(obj interface{}) interface{} {
+	for i := 0; i < 32; i++ {
+		ref, ok := obj.(pdfRef)
+		if !ok {
+			return obj
+		}
+		obj = d.objects[ref.num]
+	}
+	return nil
+}
+
+func (d *pdfDocument) dict(obj interface{}) pdfDict {
+	switch v := d.resolve(obj).(type) {
+	case pdfDict:
+		return v
+	case *pdfStream:
+		return v.dict
+	}
+	return nil
+}
+
+// decode applies a stream's filters. Flate, ASCIIHex and ASCII85 are
+// supported, which covers the text content of nearly every PDF; other
+// filters, used for images, fail.
+func (d *pdfDocument) decode(stream *pdfStream) ([]byte, error) {
+	var filters pdfArray
+	switch f := d.resolve(stream.dict["Filter"]).(type) {
+	case pdfName:
+		filters = pdfArray{f}
+	case pdfArray:
+		filters = f
+	}
+
+	data := stream.raw
+	for _, f := range filters {
+		var err error
+		switch d.resolve(f) {
+		case pdfName("FlateDecode"), pdfName("Fl"):
+			data, err = inflate(data)
+		case pdfName("ASCIIHexDecode"), pdfName("AHx"):
+			data = []byte((&pdfLexer{data: append(append([]byte{'<'}, data...), '>')}).hexString())
+		case pdfName("ASCII85Decode"), pdfName("A85"):
+			data, err = decodeASCII85(data)
+		default:
+			err = fmt.Errorf("unsupported filter %v", f)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// inflate decompresses zlib data, keeping what was read before a truncated
+// or corrupt end
+func inflate(data []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	out, err := io.ReadAll(io.LimitReader(r, maxDecodedStream))
+	if err != nil && len(out) == 0 {
+		return nil, err
+	}
+	return out, nil
+}
+
+func decodeASCII85(data []byte) ([]byte, error) {
+	data = bytes.TrimPrefix(bytes.TrimSpace(data), []byte("<~"))
+	if end := bytes.Index(data, []byte("~>")); end >= 0 {
+		data = data[:end]
+	}
+	out := make([]byte, 4*len(data))
+	n, _, err := ascii85.Decode(out, data, true)
+	return out[:n], err
+}
@@ -0,0 +1,77 @@
+package gitignore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIgnored(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, ".gitignore"), []byte("# build output\nnode_modules/\n*.log\n!keep.log\n/dist\ndocs/**/draft.md\n"), 0644)
+	os.MkdirAll(filepath.Join(root, "web"), 0755)
+	os.WriteFile(filepath.Join(root, "web", ".gitignore"), []byte("cache\n"), 0644)
+
+	rules := Load(root, "")
+	rules = append(rules, Load(root, "web")...)
+	if Load(root, "missing") != nil {
+		t.Error("a directory without .gitignore should have no rules")
+	}
+
+	cases := []struct {
+		rel     string
+		isDir   bool
+		ignored bool
+	}{
+		{"node_modules", true, true},
+		{"web/node_modules", true, true},
+		{"node_modules", false, false},
+		{"server.log", false, true},
+		{"logs/keep.log", false, false},
+		{"dist", true, true},
+		{"web/dist", true, false},
+		{"docs/a/b/draft.md", false, true},
+		{"docs/draft.md", false, true},
+		{"web/cache", true, true},
+		{"cache", true, false},
+		{"main.go", false, false},
+	}
+	for _, c := range cases {
+		if got := rules.Ignored(c.rel, c.isDir); got != c.ignored {
+			t.Errorf("Ignored(%q, %v) = %v, want %v", c.rel, c.isDir, got, c.ignored)
+		}
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	root := t.TempDir()
+	os.Mkdir(filepath.Join(root, ".git"), 0755)
+	os.WriteFile(filepath.Join(root, ".gitignore"), []byte("*.log\n"), 0644)
+	os.MkdirAll(filepath.Join(root, "web", "src"), 0755)
+	os.WriteFile(filepath.Join(root, "web", ".gitignore"), []byte("cache/\n"), 0644)
+
+	SetPatternSource(func() []string { return []string{"**/node_modules/**"} })
+	defer SetPatternSource(nil)
+
+	// starting below the checkout root still applies the root's rules
+	m := NewMatcher(filepath.Join(root, "web"))
+	m.Enter(filepath.Join(root, "web", "src"))
+
+	cases := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"web/server.log", false, true},
+		{"web/cache", true, true},
+		{"web/src/cache", true, true},
+		{"web/node_modules", true, true},
+		{"web/src/main.go", false, false},
+		{"web/.git", true, true},
+	}
+	for _, c := range cases {
+		if got := m.Ignored(filepath.Join(root, c.path), c.isDir); got != c.ignored {
+			t.Errorf("Ignored(%q, %v) = %v, want %v", c.path, c.isDir, got, c.ignored)
+		}
+	}
+}
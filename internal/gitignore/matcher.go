@@ -0,0 +1,101 @@
+package gitignore
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/alucardeht/may-la-mcp/internal/glob"
+	"github.com/alucardeht/may-la-mcp/internal/vcs"
+)
+
+var (
+	sourceMu sync.RWMutex
+	// patternSource returns the configured glob patterns ignored on top
+	// of the .gitignore files
+	patternSource func() []string
+)
+
+// SetPatternSource makes matchers also ignore the glob patterns source
+// returns, such as the index exclude patterns. It is called for each new
+// matcher, so patterns changed at runtime apply to the next walk.
+func SetPatternSource(source func() []string) {
+	sourceMu.Lock()
+	defer sourceMu.Unlock()
+	patternSource = source
+}
+
+func configuredPatterns() []string {
+	sourceMu.RLock()
+	defer sourceMu.RUnlock()
+	if patternSource == nil {
+		return nil
+	}
+	return patternSource()
+}
+
+// Matcher tells which entries of a tree being walked are ignored: .git
+// directories, paths the .gitignore files along the way ignore and paths
+// matching the configured patterns
+type Matcher struct {
+	// root is where the .gitignore rules are relative to, the checkout
+	// holding base when there is one; patterns are relative to base
+	root     string
+	base     string
+	rules    Rules
+	patterns []string
+}
+
+// NewMatcher starts a matcher for the tree at dir. Inside a git checkout
+// the .gitignore files from its root down to dir apply as well.
+func NewMatcher(dir string) *Matcher {
+	m := &Matcher{root: dir, base: dir, patterns: configuredPatterns()}
+	if repo := vcs.Find(dir); repo != nil {
+		m.root = repo.Root
+	}
+
+	m.rules = Load(m.root, "")
+	if rel := m.rel(m.root, dir); rel != "" {
+		parts := strings.Split(rel, "/")
+		for i := range parts {
+			m.rules = append(m.rules, Load(m.root, strings.Join(parts[:i+1], "/"))...)
+		}
+	}
+	return m
+}
+
+// Enter adds the rules of the .gitignore in dir, a directory the walk
+// descends into
+func (m *Matcher) Enter(dir string) {
+	if rel := m.rel(m.root, dir); rel != "" && rel != "." {
+		m.rules = append(m.rules, Load(m.root, rel)...)
+	}
+}
+
+// Ignored reports whether path, an entry under the tree, is ignored.
+// Walkers skip ignored directories, so only the entry itself is matched.
+func (m *Matcher) Ignored(path string, isDir bool) bool {
+	if isDir && filepath.Base(path) == ".git" {
+		return true
+	}
+	if rel := m.rel(m.root, path); rel != "" && m.rules.Ignored(rel, isDir) {
+		return true
+	}
+	rel := m.rel(m.base, path)
+	for _, pattern := range m.patterns {
+		if glob.Match(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// rel is path relative to dir, slash-separated, or "" when it is not under
+// dir
+func (m *Matcher) rel(dir, path string) string {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,205 @@
+// Package glob matches paths against glob patterns the same way in every
+// tool. Patterns use doublestar syntax: * and ? within a name, [...]
+// classes, ** across directories and {a,b} alternatives. The extglob
+// groups @(a|b) and ?(a|b) are rewritten into alternatives, and in a Set a
+// leading ! negates a pattern.
+//
+// A pattern containing a slash matches the whole slash-separated path; one
+// without matches a single name: Match checks the last one, Covers any of
+// them, the way exclude and ignore rules reach everything under a matched
+// directory.
+package glob
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// Normalize validates pattern and returns it in plain doublestar syntax,
+// with its extglob groups rewritten into alternatives
+func Normalize(pattern string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		if c == '\\' && i+1 < len(pattern) {
+			b.WriteString(pattern[i : i+2])
+			i++
+			continue
+		}
+		if (c != '@' && c != '?') || i+1 == len(pattern) || pattern[i+1] != '(' {
+			b.WriteByte(c)
+			continue
+		}
+
+		end := closingParen(pattern, i+1)
+		if end < 0 {
+			return "", fmt.Errorf("invalid glob %q: unclosed %c(", pattern, c)
+		}
+		inner, err := Normalize(pattern[i+2 : end])
+		if err != nil {
+			return "", err
+		}
+		b.WriteByte('{')
+		b.WriteString(strings.Join(splitAlternatives(inner), ","))
+		if c == '?' {
+			b.WriteByte(',')
+		}
+		b.WriteByte('}')
+		i = end
+	}
+
+	normalized := b.String()
+	if !doublestar.ValidatePattern(normalized) {
+		return "", fmt.Errorf("invalid glob %q", pattern)
+	}
+	return normalized, nil
+}
+
+// Validate reports whether pattern is a valid glob
+func Validate(pattern string) error {
+	_, err := Normalize(pattern)
+	return err
+}
+
+// Match reports whether path, slash-separated, matches pattern. Invalid
+// patterns match nothing.
+func Match(pattern, path string) bool {
+	pattern, err := Normalize(pattern)
+	if err != nil {
+		return false
+	}
+	return match(pattern, path)
+}
+
+// Covers reports whether path or one of the directories it is under
+// matches pattern
+func Covers(pattern, path string) bool {
+	pattern, err := Normalize(pattern)
+	if err != nil {
+		return false
+	}
+	return covers(strings.TrimSuffix(pattern, "/"), path)
+}
+
+func covers(pattern, path string) bool {
+	for path != "" && path != "." && path != "/" {
+		if match(pattern, path) {
+			return true
+		}
+		i := strings.LastIndexByte(path, '/')
+		if i < 0 {
+			break
+		}
+		path = path[:i]
+	}
+	return false
+}
+
+func match(pattern, path string) bool {
+	if !strings.Contains(pattern, "/") {
+		name := path[strings.LastIndexByte(path, '/')+1:]
+		ok, _ := doublestar.Match(pattern, name)
+		return ok
+	}
+	if strings.HasPrefix(pattern, "/") && !strings.HasPrefix(path, "/") {
+		pattern = pattern[1:]
+	}
+	ok, _ := doublestar.Match(pattern, path)
+	return ok
+}
+
+// Set is a list of patterns where the ones starting with ! exclude
+type Set struct {
+	include []string
+	exclude []string
+}
+
+func NewSet(patterns []string) (*Set, error) {
+	s := &Set{}
+	for _, pattern := range patterns {
+		negate := strings.HasPrefix(pattern, "!")
+		normalized, err := Normalize(strings.TrimPrefix(pattern, "!"))
+		if err != nil {
+			return nil, err
+		}
+		if negate {
+			s.exclude = append(s.exclude, strings.TrimSuffix(normalized, "/"))
+		} else {
+			s.include = append(s.include, normalized)
+		}
+	}
+	return s, nil
+}
+
+// Match reports whether path matches one of the set's patterns, or there
+// are none, and is not excluded
+func (s *Set) Match(path string) bool {
+	if s.Excludes(path) {
+		return false
+	}
+	if len(s.include) == 0 {
+		return true
+	}
+	for _, pattern := range s.include {
+		if match(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// Excludes reports whether a negated pattern covers path; walkers skip
+// the directories it excludes
+func (s *Set) Excludes(path string) bool {
+	for _, pattern := range s.exclude {
+		if covers(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// closingParen returns the index of the parenthesis closing the one at
+// open, or -1
+func closingParen(pattern string, open int) int {
+	depth := 0
+	for i := open; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// splitAlternatives splits the body of an extglob group on the | outside
+// of braces
+func splitAlternatives(body string) []string {
+	var alternatives []string
+	depth, start := 0, 0
+	for i := 0; i < len(body); i++ {
+		switch body[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case '|':
+			if depth == 0 {
+				alternatives = append(alternatives, body[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(alternatives, body[start:])
+}
@@ -0,0 +1,94 @@
+package glob
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "src/pkg/main.go", true},
+		{"*.go", "src/main.go/readme", false},
+		{"src/*.go", "src/main.go", true},
+		{"src/*.go", "src/pkg/main.go", false},
+		{"src/**/*.go", "src/pkg/deep/main.go", true},
+		{"/src/*.go", "src/main.go", true},
+		{"*.{js,ts}", "web/app.ts", true},
+		{"*.{js,ts}", "web/app.css", false},
+		{"*.@(js|ts)", "web/app.js", true},
+		{"*.@(js|ts)", "web/app.jsx", false},
+		{"app?(.min).js", "app.js", true},
+		{"app?(.min).js", "app.min.js", true},
+		{"app?(.min).js", "app.dev.js", false},
+		{"@(src|lib)/**/*.@(go|rs)", "lib/a/b.rs", true},
+		{"**/node_modules/**", "/home/user/app/node_modules/lib/index.js", true},
+		{"**/node_modules/**", "/home/user/app/node_modules", true},
+		{"**/*.log", "/home/user/app/debug.log", true},
+		{"[", "x", false},
+	}
+	for _, c := range cases {
+		if got := Match(c.pattern, c.path); got != c.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", c.pattern, c.path, got, c.want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	cases := map[string]string{
+		"*.@(js|ts)":    "*.{js,ts}",
+		"a?(b|c)d":      "a{b,c,}d",
+		"@(x|@(y|z))":   "{x,{y,z}}",
+		`\@(literal)`:   `\@(literal)`,
+		"file?.txt":     "file?.txt",
+		"*(1).txt":      "*(1).txt",
+		"**/{a,b}/*.go": "**/{a,b}/*.go",
+	}
+	for pattern, want := range cases {
+		got, err := Normalize(pattern)
+		if err != nil || got != want {
+			t.Errorf("Normalize(%q) = %q, %v; want %q", pattern, got, err, want)
+		}
+	}
+	for _, pattern := range []string{"@(a|b", "[abc", "{a,b"} {
+		if err := Validate(pattern); err == nil {
+			t.Errorf("Validate(%q) should fail", pattern)
+		}
+	}
+}
+
+func TestCoversAndSet(t *testing.T) {
+	if !Covers("vendor", "vendor/pkg/x.go") || !Covers("vendor/", "a/vendor/x.go") || Covers("vendor", "vendored/x.go") {
+		t.Error("a name pattern should cover everything under a matching directory")
+	}
+	if !Covers("build/out", "build/out/a/b.o") || Covers("build/out", "x/build/out/b.o") {
+		t.Error("a path pattern should cover the subtree it names")
+	}
+
+	set, err := NewSet([]string{"**/*.go", "!*_test.go", "!vendor"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]bool{
+		"main.go":          true,
+		"pkg/util.go":      true,
+		"pkg/util_test.go": false,
+		"vendor/lib/a.go":  false,
+		"README.md":        false,
+	} {
+		if got := set.Match(path); got != want {
+			t.Errorf("Set.Match(%q) = %v, want %v", path, got, want)
+		}
+	}
+	if !set.Excludes("vendor") {
+		t.Error("the excluded directory itself should be excluded")
+	}
+
+	excludesOnly, _ := NewSet([]string{"!*.min.js"})
+	if !excludesOnly.Match("app.js") || excludesOnly.Match("dist/app.min.js") {
+		t.Error("a set of exclusions only should match everything else")
+	}
+	if _, err := NewSet([]string{"{a"}); err == nil {
+		t.Error("invalid pattern should fail")
+	}
+}
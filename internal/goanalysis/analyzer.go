@@ -0,0 +1,146 @@
+// Package goanalysis answers questions about Go code from the type checker
+// rather than from patterns: where an identifier is really defined, every
+// use of a declaration, which types implement an interface and the method
+// sets of a type. A module is loaded the way go/packages loads it, listing
+// packages with go list, type-checking the module's own packages from source
+// and importing the rest from the compiler's export data, and it is kept
+// until go.mod, go.sum or one of its Go files changes.
+package goanalysis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/logger"
+	"github.com/alucardeht/may-la-mcp/internal/paths"
+)
+
+var log = logger.ForComponent("goanalysis")
+
+// ErrNotGo is returned for paths outside a Go module
+var ErrNotGo = errors.New("not in a Go module")
+
+// loadTimeout bounds listing and type-checking one module
+const loadTimeout = 2 * time.Minute
+
+// Analyzer loads Go modules on demand and caches one program per module
+type Analyzer struct {
+	mu       sync.Mutex
+	programs map[string]*program
+	// loading serializes loads of the same module
+	loading map[string]*sync.Mutex
+}
+
+func New() *Analyzer {
+	return &Analyzer{
+		programs: make(map[string]*program),
+		loading:  make(map[string]*sync.Mutex),
+	}
+}
+
+// Applies reports whether path, a Go file or a directory, is inside a Go
+// module the analyzer can load
+func Applies(path string) bool {
+	if info, err := os.Stat(path); err != nil || (!info.IsDir() && filepath.Ext(path) != ".go") {
+		return false
+	}
+	return moduleRoot(path) != ""
+}
+
+// moduleRoot returns the directory of the go.mod governing path, or ""
+func moduleRoot(path string) string {
+	dir := path
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		dir = filepath.Dir(path)
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// program returns the loaded module holding path, loading it when it is
+// not cached or has changed since it was loaded
+func (a *Analyzer) program(ctx context.Context, path string) (*program, error) {
+	root := moduleRoot(paths.Canonical(path))
+	if root == "" {
+		return nil, fmt.Errorf("%s: %w", path, ErrNotGo)
+	}
+
+	a.mu.Lock()
+	lock, ok := a.loading[root]
+	if !ok {
+		lock = &sync.Mutex{}
+		a.loading[root] = lock
+	}
+	a.mu.Unlock()
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	a.mu.Lock()
+	prog := a.programs[root]
+	a.mu.Unlock()
+	if prog != nil && !prog.stale() {
+		return prog, nil
+	}
+
+	started := time.Now()
+	loadCtx, cancel := context.WithTimeout(ctx, loadTimeout)
+	defer cancel()
+	prog, err := load(loadCtx, root)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("go module loaded", "root", root, "packages", len(prog.packages), "duration_ms", time.Since(started).Milliseconds())
+
+	a.mu.Lock()
+	a.programs[root] = prog
+	a.mu.Unlock()
+	return prog, nil
+}
+
+// Invalidate drops the cached program of the module holding path
+func (a *Analyzer) Invalidate(path string) {
+	root := moduleRoot(paths.Canonical(path))
+	a.mu.Lock()
+	delete(a.programs, root)
+	a.mu.Unlock()
+}
+
+// stamp is what a file or directory looked like when a program was loaded
+type stamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func stampOf(path string) (stamp, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return stamp{}, false
+	}
+	return stamp{info.ModTime(), info.Size()}, true
+}
+
+// stale reports whether go.mod, go.sum, a loaded file or a package
+// directory, which changes when files are added or removed, has changed
+func (p *program) stale() bool {
+	for path, was := range p.stamps {
+		now, ok := stampOf(path)
+		if ok != was.exists || now != was.stamp {
+			return true
+		}
+	}
+	return false
+}
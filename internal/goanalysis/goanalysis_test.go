@@ -0,0 +1,206 @@
+package goanalysis
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/paths"
+)
+
+var module = map[string]string{
+	"go.mod": "module example.com/shapes\n\ngo 1.21\n",
+	"shape/shape.go": `package shape
+
+import "fmt"
+
+// Shape has an area
+type Shape interface {
+	Area() float64
+}
+
+type Named struct {
+	Label string
+}
+
+func (n Named) Name() string { return n.Label }
+
+func (n *Named) Rename(label string) { n.Label = label }
+
+type Square struct {
+	Named
+	Side float64
+}
+
+func (s Square) Area() float64 { return s.Side * s.Side }
+
+type Circle struct {
+	Radius float64
+}
+
+func (c *Circle) Area() float64 { return 3 * c.Radius * c.Radius }
+
+func (c *Circle) Error() string { return fmt.Sprint(c.Radius) }
+`,
+	"main.go": `package main
+
+import (
+	"fmt"
+
+	"example.com/shapes/shape"
+)
+
+func main() {
+	var s shape.Shape = shape.Square{Side: 2}
+	fmt.Println(s.Area())
+}
+`,
+}
+
+func writeModule(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go is not installed")
+	}
+	root := paths.Canonical(t.TempDir())
+	for name, content := range module {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestDefinition(t *testing.T) {
+	root := writeModule(t)
+	a := New()
+	ctx := context.Background()
+
+	// Area in s.Area() of main.go resolves to the interface method
+	sym, err := a.Definition(ctx, Target{Path: filepath.Join(root, "main.go"), Line: 11, Column: 18})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sym.Name != "Area" || sym.Kind != "method" || sym.File != filepath.Join(root, "shape", "shape.go") || sym.Line != 7 || sym.Column != 2 {
+		t.Errorf("unexpected definition %+v", sym)
+	}
+
+	sym, err = a.Definition(ctx, Target{Path: root, Name: "Square.Rename"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sym.Line != 16 || sym.Signature != "func (*Named).Rename(label string)" {
+		t.Errorf("unexpected promoted method %+v", sym)
+	}
+
+	if _, err := a.Definition(ctx, Target{Path: root, Name: "Missing"}); err == nil {
+		t.Error("expected an error for an undeclared name")
+	}
+	if Applies(t.TempDir()) {
+		t.Error("expected a directory without go.mod not to apply")
+	}
+}
+
+func TestReferences(t *testing.T) {
+	root := writeModule(t)
+	refs, err := New().References(context.Background(), Target{Path: root, Name: "Shape.Area"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refs) != 2 {
+		t.Fatalf("expected the declaration and one use, got %+v", refs)
+	}
+	if refs[0].File != filepath.Join(root, "main.go") || refs[0].Line != 11 || refs[0].Kind != "usage" || refs[0].Context != "fmt.Println(s.Area())" {
+		t.Errorf("unexpected use %+v", refs[0])
+	}
+	if refs[1].Line != 7 || refs[1].Kind != "definition" {
+		t.Errorf("unexpected declaration %+v", refs[1])
+	}
+}
+
+func TestImplementations(t *testing.T) {
+	root := writeModule(t)
+	a := New()
+	ctx := context.Background()
+
+	impls, err := a.Implementations(ctx, Target{Path: root, Name: "Shape"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]bool{}
+	for _, impl := range impls {
+		got[impl.Name] = impl.Pointer
+	}
+	if len(got) != 2 || got["Square"] || !got["Circle"] {
+		t.Errorf("expected Square and *Circle, got %+v", impls)
+	}
+
+	impls, err = a.Implementations(ctx, Target{Path: root, Name: "Circle"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(impls) != 2 || impls[0].Name != "Shape" || impls[1].Name != "error" || !impls[1].Pointer {
+		t.Errorf("expected Shape and error, got %+v", impls)
+	}
+
+	if _, err := a.Implementations(ctx, Target{Path: root, Name: "Square.Side"}); err == nil {
+		t.Error("expected an error for a field")
+	}
+}
+
+func TestMethodSet(t *testing.T) {
+	root := writeModule(t)
+	_, methods, err := New().MethodSet(context.Background(), Target{Path: root, Name: "Square"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, m := range methods {
+		entry := m.Name
+		if m.Promoted != "" {
+			entry = m.Promoted + "." + entry
+		}
+		if m.PointerOnly {
+			entry = "*" + entry
+		}
+		got = append(got, entry)
+	}
+	if strings.Join(got, " ") != "Area Named.Name *Named.Rename" {
+		t.Errorf("unexpected method set %v", got)
+	}
+}
+
+func TestInvalidation(t *testing.T) {
+	root := writeModule(t)
+	a := New()
+	ctx := context.Background()
+
+	if _, err := a.Implementations(ctx, Target{Path: root, Name: "Shape"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// a new implementation in a new file changes the package directory
+	triangle := "package shape\n\ntype Triangle struct{}\n\nfunc (Triangle) Area() float64 { return 0 }\n"
+	if err := os.WriteFile(filepath.Join(root, "shape", "triangle.go"), []byte(triangle), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// make sure the directory's modification time moves on coarse clocks
+	past := time.Now().Add(-time.Hour)
+	os.Chtimes(filepath.Join(root, "shape"), past, past)
+
+	impls, err := a.Implementations(ctx, Target{Path: root, Name: "Shape"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(impls) != 3 {
+		t.Errorf("expected the new type after the change, got %+v", impls)
+	}
+}
@@ -0,0 +1,219 @@
+package goanalysis
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// listedPackage is the part of go list -json output the loader uses
+type listedPackage struct {
+	ImportPath string
+	Name       string
+	Dir        string
+	GoFiles    []string
+	CgoFiles   []string
+	Export     string
+	ImportMap  map[string]string
+	Module     *struct {
+		Main bool
+	}
+	Error *struct {
+		Err string
+	}
+}
+
+// pkg is a type-checked package of the module
+type pkg struct {
+	path  string
+	dir   string
+	files []*ast.File
+	types *types.Package
+	info  *types.Info
+	// errors are the first type errors, which leave the package partly
+	// checked but still usable
+	errors []string
+}
+
+// recorded is the stamp of a path when the program was loaded
+type recorded struct {
+	stamp  stamp
+	exists bool
+}
+
+// program is a loaded module
+type program struct {
+	root     string
+	fset     *token.FileSet
+	packages []*pkg // in dependency order
+	byPath   map[string]*pkg
+	byFile   map[string]*pkg
+	// sources holds the text of files positions were reported in, to turn
+	// byte offsets into columns and lines into context; mu guards it, as
+	// files outside the module are read on first use
+	mu      sync.Mutex
+	sources map[string][]string
+	stamps  map[string]recorded
+}
+
+// maxPackageErrors bounds the type errors kept per package
+const maxPackageErrors = 10
+
+// load lists the packages of the module at root with their dependencies
+// and type-checks the module's packages from source, importing the others
+// from export data
+func load(ctx context.Context, root string) (*program, error) {
+	listed, err := list(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+
+	prog := &program{
+		root:    root,
+		fset:    token.NewFileSet(),
+		byPath:  make(map[string]*pkg),
+		byFile:  make(map[string]*pkg),
+		sources: make(map[string][]string),
+		stamps:  make(map[string]recorded),
+	}
+	for _, name := range []string{"go.mod", "go.sum"} {
+		prog.record(filepath.Join(root, name))
+	}
+
+	exports := make(map[string]string)
+	for _, lp := range listed {
+		exports[lp.ImportPath] = lp.Export
+	}
+	gc := importer.ForCompiler(prog.fset, "gc", func(path string) (io.ReadCloser, error) {
+		export := exports[path]
+		if export == "" {
+			return nil, fmt.Errorf("no export data for %s", path)
+		}
+		return os.Open(export)
+	})
+
+	for _, lp := range listed {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if lp.Module == nil || !lp.Module.Main {
+			continue
+		}
+		p := prog.check(lp, gc)
+		prog.packages = append(prog.packages, p)
+		prog.byPath[p.path] = p
+	}
+	if len(prog.packages) == 0 {
+		return nil, fmt.Errorf("no Go packages in %s", root)
+	}
+	return prog, nil
+}
+
+// list runs go list in root. It never switches toolchains, which would
+// download one.
+func list(ctx context.Context, root string) ([]*listedPackage, error) {
+	cmd := exec.CommandContext(ctx, "go", "list", "-e", "-json", "-deps", "-export", "./...")
+	cmd.Dir = root
+	cmd.Env = append(os.Environ(), "GOTOOLCHAIN=local")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil && len(out) == 0 {
+		return nil, fmt.Errorf("go list: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var listed []*listedPackage
+	decoder := json.NewDecoder(bytes.NewReader(out))
+	for decoder.More() {
+		lp := &listedPackage{}
+		if err := decoder.Decode(lp); err != nil {
+			return nil, fmt.Errorf("go list output: %w", err)
+		}
+		listed = append(listed, lp)
+	}
+	return listed, nil
+}
+
+// check parses and type-checks one package of the module. Packages of the
+// module come from earlier checks, so objects are shared across them;
+// other imports come from export data.
+func (prog *program) check(lp *listedPackage, gc types.Importer) *pkg {
+	p := &pkg{path: lp.ImportPath, dir: lp.Dir}
+	prog.record(lp.Dir)
+	if lp.Error != nil {
+		p.errors = append(p.errors, lp.Error.Err)
+	}
+
+	for _, name := range append(append([]string{}, lp.GoFiles...), lp.CgoFiles...) {
+		file := filepath.Join(lp.Dir, name)
+		src, err := os.ReadFile(file)
+		if err != nil {
+			p.errors = append(p.errors, err.Error())
+			continue
+		}
+		prog.record(file)
+		prog.sources[file] = strings.Split(string(src), "\n")
+
+		f, err := parser.ParseFile(prog.fset, file, src, parser.ParseComments|parser.SkipObjectResolution)
+		if err != nil {
+			p.errors = append(p.errors, err.Error())
+		}
+		if f != nil {
+			p.files = append(p.files, f)
+			prog.byFile[file] = p
+		}
+	}
+
+	conf := types.Config{
+		Importer: importerFunc(func(path string) (*types.Package, error) {
+			if mapped, ok := lp.ImportMap[path]; ok {
+				path = mapped
+			}
+			if dep := prog.byPath[path]; dep != nil {
+				return dep.types, nil
+			}
+			return gc.Import(path)
+		}),
+		FakeImportC: true,
+		Error: func(err error) {
+			if len(p.errors) < maxPackageErrors {
+				p.errors = append(p.errors, err.Error())
+			}
+		},
+	}
+	p.info = &types.Info{
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+	}
+	// type errors are collected above; the package is usable regardless
+	p.types, _ = conf.Check(lp.ImportPath, prog.fset, p.files, p.info)
+	if len(p.errors) > 0 {
+		log.Debug("go package has errors", "package", p.path, "errors", len(p.errors), "first", p.errors[0])
+	}
+	return p
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) {
+	return f(path)
+}
+
+// record remembers the current stamp of path for stale
+func (prog *program) record(path string) {
+	s, ok := stampOf(path)
+	prog.stamps[path] = recorded{stamp: s, exists: ok}
+}
@@ -0,0 +1,456 @@
+package goanalysis
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/alucardeht/may-la-mcp/internal/paths"
+	mtypes "github.com/alucardeht/may-la-mcp/internal/types"
+)
+
+// Target selects a declaration: the identifier at Line and Column (1-based,
+// column in characters) of Path, or, when Name is set, the declaration
+// named Name, Type.Method or Type.Field, looked up first in the package of
+// Path (a file or directory) and then across the module
+type Target struct {
+	Path   string
+	Line   int
+	Column int
+	Name   string
+}
+
+// Implementation is a type implementing an interface, or an interface a
+// type implements
+type Implementation struct {
+	mtypes.Symbol
+	Package string `json:"package"`
+	// Pointer is set when only the pointer type *T implements the interface
+	Pointer bool `json:"pointer,omitempty"`
+}
+
+// Method is a method in a type's method set
+type Method struct {
+	mtypes.Symbol
+	// PointerOnly is set for methods with a pointer receiver, which only *T
+	// has in its method set
+	PointerOnly bool `json:"pointer_only,omitempty"`
+	// Promoted is the path of embedded fields the method is promoted
+	// through, e.g., Base or Base.Logger
+	Promoted string `json:"promoted,omitempty"`
+}
+
+// Definition returns the declaration of the target
+func (a *Analyzer) Definition(ctx context.Context, target Target) (*mtypes.Symbol, error) {
+	prog, obj, err := a.resolve(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	sym := prog.symbol(obj)
+	return &sym, nil
+}
+
+// References returns the declaration of the target and every use of it in
+// the module, at most max of them (0 for all)
+func (a *Analyzer) References(ctx context.Context, target Target, max int) ([]mtypes.Reference, error) {
+	prog, obj, err := a.resolve(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	obj = origin(obj)
+
+	var refs []mtypes.Reference
+	for _, p := range prog.packages {
+		for _, uses := range []map[*ast.Ident]types.Object{p.info.Defs, p.info.Uses} {
+			for ident, used := range uses {
+				if used == nil || origin(used) != obj {
+					continue
+				}
+				kind := mtypes.RefUsage
+				if ident.Pos() == obj.Pos() {
+					kind = mtypes.RefDefinition
+				}
+				file, line, column := prog.position(ident.Pos())
+				refs = append(refs, mtypes.Reference{
+					File:    file,
+					Line:    line,
+					Column:  column,
+					Context: strings.TrimSpace(prog.line(file, line)),
+					Kind:    kind,
+				})
+			}
+		}
+	}
+
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].File != refs[j].File {
+			return refs[i].File < refs[j].File
+		}
+		if refs[i].Line != refs[j].Line {
+			return refs[i].Line < refs[j].Line
+		}
+		return refs[i].Column < refs[j].Column
+	})
+	if max > 0 && len(refs) > max {
+		refs = refs[:max]
+	}
+	return refs, nil
+}
+
+// Implementations returns the types of the module implementing the target
+// interface, or, for a concrete type, the interfaces of the module and the
+// error interface it implements
+func (a *Analyzer) Implementations(ctx context.Context, target Target) ([]Implementation, error) {
+	prog, obj, err := a.resolve(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	typeName, ok := obj.(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("%s is a %s, not a type", obj.Name(), kindOf(obj))
+	}
+
+	iface, isInterface := typeName.Type().Underlying().(*types.Interface)
+	if isInterface && iface.Empty() {
+		return nil, fmt.Errorf("every type implements the empty interface %s", obj.Name())
+	}
+
+	var impls []Implementation
+	add := func(candidate *types.TypeName, pointer bool) {
+		impls = append(impls, Implementation{
+			Symbol:  prog.symbol(candidate),
+			Package: candidate.Pkg().Path(),
+			Pointer: pointer,
+		})
+	}
+
+	for _, candidate := range prog.namedTypes() {
+		if candidate == typeName {
+			continue
+		}
+		_, candidateIsInterface := candidate.Type().Underlying().(*types.Interface)
+		if isInterface {
+			if candidateIsInterface {
+				continue
+			}
+			if types.Implements(candidate.Type(), iface) {
+				add(candidate, false)
+			} else if types.Implements(types.NewPointer(candidate.Type()), iface) {
+				add(candidate, true)
+			}
+			continue
+		}
+
+		other, ok := candidate.Type().Underlying().(*types.Interface)
+		if !ok || other.Empty() {
+			continue
+		}
+		if types.Implements(typeName.Type(), other) {
+			add(candidate, false)
+		} else if types.Implements(types.NewPointer(typeName.Type()), other) {
+			add(candidate, true)
+		}
+	}
+
+	if !isInterface {
+		errorType := types.Universe.Lookup("error").(*types.TypeName)
+		errorIface := errorType.Type().Underlying().(*types.Interface)
+		if types.Implements(typeName.Type(), errorIface) {
+			impls = append(impls, Implementation{Symbol: prog.symbol(errorType)})
+		} else if types.Implements(types.NewPointer(typeName.Type()), errorIface) {
+			impls = append(impls, Implementation{Symbol: prog.symbol(errorType), Pointer: true})
+		}
+	}
+	return impls, nil
+}
+
+// MethodSet returns the methods of the target type: those of T, then
+// those only *T has
+func (a *Analyzer) MethodSet(ctx context.Context, target Target) (*mtypes.Symbol, []Method, error) {
+	prog, obj, err := a.resolve(ctx, target)
+	if err != nil {
+		return nil, nil, err
+	}
+	typeName, ok := obj.(*types.TypeName)
+	if !ok {
+		return nil, nil, fmt.Errorf("%s is a %s, not a type", obj.Name(), kindOf(obj))
+	}
+
+	typ := typeName.Type()
+	valueSet := types.NewMethodSet(typ)
+	var methods []Method
+	add := func(sel *types.Selection, pointerOnly bool) {
+		methods = append(methods, Method{
+			Symbol:      prog.symbol(sel.Obj()),
+			PointerOnly: pointerOnly,
+			Promoted:    embeddedPath(typ, sel.Index()),
+		})
+	}
+	for i := 0; i < valueSet.Len(); i++ {
+		add(valueSet.At(i), false)
+	}
+	if _, isInterface := typ.Underlying().(*types.Interface); !isInterface {
+		pointerSet := types.NewMethodSet(types.NewPointer(typ))
+		for i := 0; i < pointerSet.Len(); i++ {
+			if sel := pointerSet.At(i); valueSet.Lookup(sel.Obj().Pkg(), sel.Obj().Name()) == nil {
+				add(sel, true)
+			}
+		}
+	}
+
+	sym := prog.symbol(typeName)
+	return &sym, methods, nil
+}
+
+// embeddedPath names the embedded fields a selection at index goes through
+func embeddedPath(typ types.Type, index []int) string {
+	var names []string
+	for _, i := range index[:len(index)-1] {
+		if ptr, ok := typ.Underlying().(*types.Pointer); ok {
+			typ = ptr.Elem()
+		}
+		st, ok := typ.Underlying().(*types.Struct)
+		if !ok {
+			break
+		}
+		field := st.Field(i)
+		names = append(names, field.Name())
+		typ = field.Type()
+	}
+	return strings.Join(names, ".")
+}
+
+// resolve loads the module of the target and finds its declaration
+func (a *Analyzer) resolve(ctx context.Context, target Target) (*program, types.Object, error) {
+	prog, err := a.program(ctx, target.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	path := paths.Canonical(target.Path)
+
+	var obj types.Object
+	if target.Name != "" {
+		obj, err = prog.lookup(path, target.Name)
+	} else {
+		obj, err = prog.objectAt(path, target.Line, target.Column)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return prog, obj, nil
+}
+
+// objectAt returns the object the identifier at line and column denotes
+func (prog *program) objectAt(file string, line, column int) (types.Object, error) {
+	p := prog.byFile[file]
+	if p == nil {
+		return nil, fmt.Errorf("%s is not part of a package of the module", file)
+	}
+	text := prog.line(file, line)
+	if text == "" && line > len(prog.sources[file]) {
+		return nil, fmt.Errorf("line %d is past the end of %s", line, file)
+	}
+	offset := mtypes.ByteFromColumn(strings.TrimSuffix(text, "\r"), column)
+
+	tf := prog.fset.File(fileStart(p, prog.fset, file))
+	if tf == nil {
+		return nil, fmt.Errorf("%s is not loaded", file)
+	}
+	pos := tf.LineStart(line) + token.Pos(offset)
+
+	for _, f := range p.files {
+		if prog.fset.File(f.Pos()) != tf {
+			continue
+		}
+		var found types.Object
+		ast.Inspect(f, func(n ast.Node) bool {
+			if found != nil || n == nil || pos < n.Pos() || pos > n.End() {
+				return false
+			}
+			if ident, ok := n.(*ast.Ident); ok {
+				if obj := p.info.Defs[ident]; obj != nil {
+					found = obj
+				} else if obj := p.info.Uses[ident]; obj != nil {
+					found = obj
+				}
+			}
+			return true
+		})
+		if found == nil {
+			return nil, fmt.Errorf("no declared identifier at %s:%d:%d", file, line, column)
+		}
+		return found, nil
+	}
+	return nil, fmt.Errorf("%s is not loaded", file)
+}
+
+// fileStart returns the position of the start of file in p
+func fileStart(p *pkg, fset *token.FileSet, file string) token.Pos {
+	for _, f := range p.files {
+		if fset.File(f.Pos()).Name() == file {
+			return f.Pos()
+		}
+	}
+	return token.NoPos
+}
+
+// lookup finds name, or Type.Member, in the packages at path and then in
+// the rest of the module
+func (prog *program) lookup(path, name string) (types.Object, error) {
+	typeName, member, hasMember := strings.Cut(name, ".")
+
+	for _, p := range prog.preferred(path) {
+		if p.types == nil {
+			continue
+		}
+		obj := p.types.Scope().Lookup(typeName)
+		if obj == nil {
+			continue
+		}
+		if !hasMember {
+			return obj, nil
+		}
+		if _, ok := obj.(*types.TypeName); ok {
+			if found, _, _ := types.LookupFieldOrMethod(obj.Type(), true, p.types, member); found != nil {
+				return found, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%s is not declared at package level in the module", name)
+}
+
+// preferred orders the module's packages: those at or under path first
+func (prog *program) preferred(path string) []*pkg {
+	dir := path
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		dir = filepath.Dir(path)
+	}
+	var near, far []*pkg
+	for _, p := range prog.packages {
+		if p.dir == dir || strings.HasPrefix(p.dir, dir+string(filepath.Separator)) {
+			near = append(near, p)
+		} else {
+			far = append(far, p)
+		}
+	}
+	return append(near, far...)
+}
+
+// namedTypes returns the non-generic types declared at package level in the
+// module
+func (prog *program) namedTypes() []*types.TypeName {
+	var named []*types.TypeName
+	for _, p := range prog.packages {
+		if p.types == nil {
+			continue
+		}
+		scope := p.types.Scope()
+		for _, name := range scope.Names() {
+			tn, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || tn.IsAlias() {
+				continue
+			}
+			if n, ok := tn.Type().(*types.Named); ok && n.TypeParams().Len() > 0 {
+				continue
+			}
+			named = append(named, tn)
+		}
+	}
+	return named
+}
+
+// symbol describes obj in the form the router reports symbols
+func (prog *program) symbol(obj types.Object) mtypes.Symbol {
+	sym := mtypes.Symbol{
+		Name:       obj.Name(),
+		Kind:       kindOf(obj),
+		IsExported: obj.Exported(),
+		Signature:  types.ObjectString(obj, qualifier(obj.Pkg())),
+	}
+	if obj.Pos().IsValid() {
+		sym.File, sym.Line, sym.Column = prog.position(obj.Pos())
+		sym.ColumnEnd = sym.Column + len([]rune(obj.Name()))
+	}
+	return sym
+}
+
+// qualifier writes package names relative to pkg
+func qualifier(pkg *types.Package) types.Qualifier {
+	return func(other *types.Package) string {
+		if other == pkg {
+			return ""
+		}
+		return other.Name()
+	}
+}
+
+func kindOf(obj types.Object) string {
+	switch o := obj.(type) {
+	case *types.Func:
+		if o.Type().(*types.Signature).Recv() != nil {
+			return mtypes.KindMethod
+		}
+		return mtypes.KindFunction
+	case *types.TypeName:
+		switch o.Type().Underlying().(type) {
+		case *types.Interface:
+			return mtypes.KindInterface
+		case *types.Struct:
+			return mtypes.KindStruct
+		}
+		return mtypes.KindType
+	case *types.Var:
+		if o.IsField() {
+			return mtypes.KindField
+		}
+		return mtypes.KindVariable
+	case *types.Const:
+		return mtypes.KindConst
+	case *types.PkgName:
+		return mtypes.KindModule
+	}
+	return mtypes.KindOther
+}
+
+// origin maps the methods and fields of generic type instances to their
+// declarations
+func origin(obj types.Object) types.Object {
+	switch o := obj.(type) {
+	case *types.Func:
+		return o.Origin()
+	case *types.Var:
+		return o.Origin()
+	}
+	return obj
+}
+
+// position returns the file, line and column in characters of pos
+func (prog *program) position(pos token.Pos) (string, int, int) {
+	position := prog.fset.Position(pos)
+	text := prog.line(position.Filename, position.Line)
+	return position.Filename, position.Line, mtypes.ColumnFromByte(text, position.Column-1)
+}
+
+// line returns line (1-based) of file, reading files outside the module,
+// such as those of the standard library, on first use
+func (prog *program) line(file string, line int) string {
+	prog.mu.Lock()
+	defer prog.mu.Unlock()
+	lines, ok := prog.sources[file]
+	if !ok {
+		if data, err := os.ReadFile(file); err == nil {
+			lines = strings.Split(string(data), "\n")
+		}
+		prog.sources[file] = lines
+	}
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	return strings.TrimSuffix(lines[line-1], "\r")
+}
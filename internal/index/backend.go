@@ -0,0 +1,109 @@
+package index
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/dbcrypt"
+)
+
+// BackendSQLite is the default storage backend, an embedded SQLite file
+const BackendSQLite = "sqlite"
+
+// FileStore keeps the indexed files, their status and the recently
+// accessed ones
+type FileStore interface {
+	UpsertFile(file *IndexedFile) (int64, error)
+	GetFile(path string) (*IndexedFile, error)
+	GetFileByID(id int64) (*IndexedFile, error)
+	GetFilesByStatus(status FileStatus, limit int) ([]*IndexedFile, error)
+	UpdateFileMetadata(path string, modTime time.Time, size int64) error
+	UpdateFileStatus(path string, status FileStatus, errorMsg string) error
+	DeleteFile(path string) error
+
+	GetFilePathsByLanguage(language, pathPrefix string) ([]string, error)
+	GetLanguages(pathPrefix string) ([]string, error)
+	GetLanguageStats(pathPrefix string) ([]*LanguageStats, error)
+	GetEncodingStats(pathPrefix string, examples int) ([]*EncodingStats, error)
+	GetIndexedPaths(pathPrefix string) ([]string, error)
+
+	RecordAccess(paths ...string) error
+	GetRecentFiles(limit int) ([]string, error)
+	PruneRecentFiles(keep int) error
+}
+
+// SymbolStore keeps the symbols found in indexed files and the references
+// between them
+type SymbolStore interface {
+	InsertSymbols(fileID int64, symbols []*IndexedSymbol) error
+	ClearFileSymbols(fileID int64) error
+	GetSymbolsByFile(fileID int64) ([]*IndexedSymbol, error)
+	GetSymbolByID(id int64) (*IndexedSymbol, error)
+	SearchSymbols(query string, limit int) ([]*IndexedSymbol, error)
+	GetSymbolRanges(kinds []string, name, language, pathPrefix string) ([]*SymbolRange, error)
+	GetExportedSymbols(kinds []string, pathPrefix string) ([]*SymbolRange, error)
+	GetSymbolUses(kinds []string, pathPrefix string) ([]*SymbolUse, error)
+
+	InsertReferences(symbolID int64, refs []*SymbolReference) error
+	GetReferencesForSymbol(symbolID int64) ([]*SymbolReference, error)
+	GetReferencesInFile(fileID int64) ([]*SymbolReference, error)
+}
+
+// DocStore keeps the sections of indexed documentation files
+type DocStore interface {
+	InsertDocSections(fileID int64, sections []*DocSection) error
+	CountDocSections(pathPrefix string) (int, error)
+	SearchDocs(query, pathPrefix string, limit int) ([]*DocMatch, error)
+}
+
+// RetryStore keeps the files whose indexing was dropped or failed until a
+// retry succeeds
+type RetryStore interface {
+	// AddRetry records a file to retry; a file already recorded keeps its
+	// attempts and next attempt, taking the new reason and error
+	AddRetry(entry *RetryEntry) error
+	// GetDueRetries returns the files due at now with fewer than
+	// maxAttempts attempts, the longest waiting first
+	GetDueRetries(now time.Time, maxAttempts, limit int) ([]*RetryEntry, error)
+	UpdateRetry(path string, attempts int, nextAttempt time.Time) error
+	RemoveRetry(path string) error
+	// GetRetries returns up to limit files to retry, most attempted first,
+	// and how many there are in all
+	GetRetries(limit int) ([]*RetryEntry, int, error)
+}
+
+// Store is a complete index storage backend. IndexStore, on SQLite, is
+// the default; other backends implement the same interfaces.
+type Store interface {
+	FileStore
+	SymbolStore
+	DocStore
+	RetryStore
+
+	GetStats() (*IndexStats, error)
+	// Ping performs a write/read round-trip against the backend
+	Ping(ctx context.Context) error
+	// Compact removes leftovers of deleted files and reclaims space
+	Compact(ctx context.Context) (*CompactStats, error)
+	// Backup writes a consistent copy of the index to dest
+	Backup(dest string) error
+	Close() error
+}
+
+var _ Store = (*IndexStore)(nil)
+
+// Open opens the index of the named backend at dbPath; encryption applies
+// to backends that keep the index in a local file
+func Open(backend, dbPath string, encryption dbcrypt.Config) (Store, error) {
+	switch backend {
+	case "", BackendSQLite:
+		store, err := OpenIndexStore(dbPath, encryption)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unknown index backend %q (want %s)", backend, BackendSQLite)
+	}
+}
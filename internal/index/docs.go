@@ -0,0 +1,133 @@
+package index
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/alucardeht/may-la-mcp/internal/extract"
+)
+
+var (
+	markdownHeading = regexp.MustCompile(`^ {0,3}(#{1,6})\s+(.*?)\s*#*\s*$`)
+	markdownFence   = regexp.MustCompile("^ {0,3}(```|~~~)")
+)
+
+// SplitMarkdown cuts a markdown document into one section per ATX heading,
+// each holding the text up to the next heading. Text before the first
+// heading is a section with an empty heading. Headings inside fenced code
+// blocks and YAML frontmatter are ignored.
+func SplitMarkdown(content string) []*DocSection {
+	var sections []*DocSection
+	current := &DocSection{Line: 1}
+	var body []string
+	anchors := make(map[string]int)
+	inFence := false
+	lines := strings.Split(content, "\n")
+	skip := FrontmatterLines(lines)
+
+	flush := func() {
+		current.Content = strings.TrimSpace(strings.Join(body, "\n"))
+		if current.Heading != "" || current.Content != "" {
+			sections = append(sections, current)
+		}
+		body = nil
+	}
+
+	for i, line := range lines {
+		if i < skip {
+			continue
+		}
+		if markdownFence.MatchString(line) {
+			inFence = !inFence
+		}
+		m := markdownHeading.FindStringSubmatch(line)
+		if inFence || m == nil {
+			body = append(body, line)
+			continue
+		}
+
+		flush()
+		current = &DocSection{
+			Heading: m[2],
+			Level:   len(m[1]),
+			Line:    i + 1,
+			Anchor:  uniqueAnchor(HeadingAnchor(m[2]), anchors),
+		}
+	}
+	flush()
+
+	return sections
+}
+
+// FrontmatterLines returns how many of lines, from the first, are a YAML
+// frontmatter block: a "---" line, the YAML and a closing "---" or "..."
+// line. It returns 0 when the document has no closed frontmatter.
+func FrontmatterLines(lines []string) int {
+	if len(lines) == 0 || strings.TrimRight(lines[0], " \t\r") != "---" {
+		return 0
+	}
+	for i := 1; i < len(lines); i++ {
+		switch strings.TrimRight(lines[i], " \t\r") {
+		case "---", "...":
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// MarkdownTitle returns the text of the first heading in content, or ""
+func MarkdownTitle(content string) string {
+	for _, section := range SplitMarkdown(content) {
+		if section.Heading != "" {
+			return section.Heading
+		}
+	}
+	return ""
+}
+
+// HeadingAnchor turns a heading into the fragment GitHub links it with:
+// lower case, punctuation dropped and spaces turned into hyphens
+func HeadingAnchor(heading string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(heading) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteRune('-')
+		}
+	}
+	return b.String()
+}
+
+// SplitPages cuts the text extracted from a PDF into one section per page,
+// headed "Page N". Pages without text are left out.
+func SplitPages(content string) []*DocSection {
+	var sections []*DocSection
+	line := 1
+	for i, page := range strings.Split(content, extract.PageSeparator) {
+		if text := strings.TrimSpace(page); text != "" {
+			heading := fmt.Sprintf("Page %d", i+1)
+			sections = append(sections, &DocSection{
+				Heading: heading,
+				Anchor:  HeadingAnchor(heading),
+				Level:   1,
+				Line:    line,
+				Content: text,
+			})
+		}
+		line += strings.Count(page, "\n")
+	}
+	return sections
+}
+
+func uniqueAnchor(anchor string, seen map[string]int) string {
+	n := seen[anchor]
+	seen[anchor]++
+	if n == 0 {
+		return anchor
+	}
+	return fmt.Sprintf("%s-%d", anchor, n)
+}
@@ -0,0 +1,81 @@
+package index
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// generatedHeaderBytes is how much of a file is scanned for generator markers
+	generatedHeaderBytes = 2048
+
+	// minifiedMinSize and minifiedAvgLineLength flag bundled or minified
+	// sources: long files made of very long lines.
+	minifiedMinSize       = 4096
+	minifiedAvgLineLength = 300
+)
+
+var generatedNameSuffixes = []string{
+	"_pb2.py",
+	"_pb2_grpc.py",
+	".pb.go",
+	".pb.gw.go",
+	"_grpc.pb.go",
+	".pb.ts",
+	"_pb.js",
+	"_pb.d.ts",
+	".g.dart",
+	".designer.cs",
+}
+
+var generatedMarkers = []string{
+	"code generated",
+	"do not edit",
+	"@generated",
+	"autogenerated",
+	"auto-generated",
+	"automatically generated",
+}
+
+// detectGeneratedByName reports generated or minified files that can be
+// recognised from their path alone.
+func detectGeneratedByName(path string) (bool, string) {
+	base := strings.ToLower(filepath.Base(path))
+
+	if strings.Contains(base, ".min.") {
+		return true, "minified file name"
+	}
+
+	for _, suffix := range generatedNameSuffixes {
+		if strings.HasSuffix(base, suffix) {
+			return true, "generated file name"
+		}
+	}
+
+	return false, ""
+}
+
+// detectGeneratedContent looks for generator markers near the top of the file
+// and for minified content with an unusually high average line length.
+func detectGeneratedContent(content string) (bool, string) {
+	header := content
+	if len(header) > generatedHeaderBytes {
+		header = header[:generatedHeaderBytes]
+	}
+	header = strings.ToLower(header)
+
+	for _, marker := range generatedMarkers {
+		if strings.Contains(header, marker) {
+			return true, "generated marker: " + marker
+		}
+	}
+
+	if len(content) >= minifiedMinSize {
+		lines := strings.Count(content, "\n") + 1
+		if len(content)/lines > minifiedAvgLineLength {
+			return true, "minified content"
+		}
+	}
+
+	return false, ""
+}
@@ -0,0 +1,59 @@
+package index
+
+import (
+	"context"
+	"fmt"
+)
+
+// CompactStats reports what a compaction removed
+type CompactStats struct {
+	OrphanedSymbols    int64 `json:"orphaned_symbols"`
+	OrphanedReferences int64 `json:"orphaned_references"`
+	OrphanedDocs       int64 `json:"orphaned_docs"`
+}
+
+// Compact removes the rows left behind by deleted files, merges the
+// full-text indexes and reclaims the free pages of the database
+func (s *IndexStore) Compact(ctx context.Context) (*CompactStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// foreign keys are enabled on one pooled connection only, so deletes
+	// through the others leave their children behind
+	stats := &CompactStats{}
+	orphans := []struct {
+		query string
+		count *int64
+	}{
+		{`DELETE FROM symbols WHERE file_id NOT IN (SELECT id FROM files)`, &stats.OrphanedSymbols},
+		{`DELETE FROM symbol_refs WHERE file_id NOT IN (SELECT id FROM files) OR symbol_id NOT IN (SELECT id FROM symbols)`, &stats.OrphanedReferences},
+		{`DELETE FROM doc_sections WHERE file_id NOT IN (SELECT id FROM files)`, &stats.OrphanedDocs},
+	}
+	for _, o := range orphans {
+		result, err := s.db.ExecContext(ctx, o.query)
+		if err != nil {
+			return nil, fmt.Errorf("remove orphans: %w", err)
+		}
+		*o.count, _ = result.RowsAffected()
+	}
+
+	for _, stmt := range []string{
+		`INSERT INTO symbols_fts(symbols_fts) VALUES ('optimize')`,
+		`INSERT INTO doc_sections_fts(doc_sections_fts) VALUES ('optimize')`,
+		`PRAGMA optimize`,
+		`VACUUM`,
+	} {
+		if _, err := s.db.ExecContext(ctx, stmt); err != nil {
+			return nil, fmt.Errorf("compact: %w", err)
+		}
+	}
+
+	return stats, nil
+}
+
+// Backup writes a consistent copy of the index to dest
+func (s *IndexStore) Backup(dest string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.database.Backup(dest)
+}
@@ -1,6 +1,6 @@
 package index
 
-const SchemaVersion = 1
+const SchemaVersion = 3
 
 const schemaSQL = `
 -- Schema version tracking
@@ -18,7 +18,10 @@ CREATE TABLE IF NOT EXISTS files (
     status TEXT DEFAULT 'pending',
     error_message TEXT,
     indexed_at DATETIME,
-    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    mtime INTEGER,
+    size INTEGER,
+    branch TEXT
 );
 
 CREATE INDEX IF NOT EXISTS idx_files_path ON files(path);
@@ -38,7 +41,8 @@ CREATE TABLE IF NOT EXISTS symbols (
     column_end INTEGER,
     visibility TEXT,
     documentation TEXT,
-    is_exported INTEGER DEFAULT 0
+    is_exported INTEGER DEFAULT 0,
+    cell INTEGER
 );
 
 CREATE INDEX IF NOT EXISTS idx_symbols_file ON symbols(file_id);
@@ -83,8 +87,82 @@ CREATE TABLE IF NOT EXISTS symbol_refs (
 
 CREATE INDEX IF NOT EXISTS idx_refs_symbol ON symbol_refs(symbol_id);
 CREATE INDEX IF NOT EXISTS idx_refs_file ON symbol_refs(file_id);
+
+-- Sections of markdown files, split at headings
+CREATE TABLE IF NOT EXISTS doc_sections (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    file_id INTEGER NOT NULL REFERENCES files(id) ON DELETE CASCADE,
+    heading TEXT,
+    anchor TEXT,
+    level INTEGER,
+    line INTEGER NOT NULL,
+    content TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_doc_sections_file ON doc_sections(file_id);
+
+-- FTS5 for documentation search
+CREATE VIRTUAL TABLE IF NOT EXISTS doc_sections_fts USING fts5(
+    heading, content,
+    content=doc_sections,
+    content_rowid=id
+);
+
+CREATE TRIGGER IF NOT EXISTS doc_sections_ai AFTER INSERT ON doc_sections BEGIN
+    INSERT INTO doc_sections_fts(rowid, heading, content)
+    VALUES (NEW.id, NEW.heading, NEW.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS doc_sections_ad AFTER DELETE ON doc_sections BEGIN
+    INSERT INTO doc_sections_fts(doc_sections_fts, rowid, heading, content)
+    VALUES ('delete', OLD.id, OLD.heading, OLD.content);
+END;
+
+-- Single-row table used by health probes for a write/read round-trip
+CREATE TABLE IF NOT EXISTS health_probe (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    nonce INTEGER NOT NULL
+);
+
+-- Files recently touched by tools, indexed first on startup
+CREATE TABLE IF NOT EXISTS recent_files (
+    path TEXT PRIMARY KEY,
+    access_count INTEGER DEFAULT 1,
+    last_accessed DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_recent_files_accessed ON recent_files(last_accessed);
+
+-- Files whose indexing was dropped on a full queue or failed, retried with
+-- backoff; times are Unix nanoseconds
+CREATE TABLE IF NOT EXISTS index_retries (
+    path TEXT PRIMARY KEY,
+    priority INTEGER DEFAULT 0,
+    reason TEXT NOT NULL,
+    error_message TEXT,
+    attempts INTEGER DEFAULT 0,
+    next_attempt INTEGER NOT NULL,
+    first_seen INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_index_retries_next ON index_retries(next_attempt);
 `
 
+type columnMigration struct {
+	table      string
+	column     string
+	definition string
+}
+
+// columnMigrations lists columns added after version 1; CREATE TABLE IF NOT
+// EXISTS leaves existing tables untouched, so they are added with ALTER TABLE.
+var columnMigrations = []columnMigration{
+	{"files", "mtime", "INTEGER"},
+	{"files", "size", "INTEGER"},
+	{"symbols", "cell", "INTEGER"},
+	{"files", "branch", "TEXT"},
+}
+
 func GetSchema() string {
 	return schemaSQL
 }
@@ -1,6 +1,7 @@
 package index
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"os"
@@ -10,23 +11,33 @@ import (
 	"time"
 
 	_ "modernc.org/sqlite"
+
+	"github.com/alucardeht/may-la-mcp/internal/dbcrypt"
 )
 
 type IndexStore struct {
-	db *sql.DB
-	mu sync.RWMutex
+	db       *sql.DB
+	database *dbcrypt.Database
+	mu       sync.RWMutex
 }
 
 func NewIndexStore(dbPath string) (*IndexStore, error) {
+	return OpenIndexStore(dbPath, dbcrypt.Config{})
+}
+
+// OpenIndexStore opens the index at dbPath, kept encrypted at rest when
+// encryption is enabled
+func OpenIndexStore(dbPath string, encryption dbcrypt.Config) (*IndexStore, error) {
 	dir := filepath.Dir(dbPath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return nil, fmt.Errorf("create index dir: %w", err)
 	}
 
-	db, err := sql.Open("sqlite", dbPath)
+	database, err := dbcrypt.Open(dbPath, encryption)
 	if err != nil {
 		return nil, err
 	}
+	db := database.DB
 
 	if err := db.Ping(); err != nil {
 		return nil, err
@@ -44,7 +55,7 @@ func NewIndexStore(dbPath string) (*IndexStore, error) {
 		return nil, err
 	}
 
-	store := &IndexStore{db: db}
+	store := &IndexStore{db: db, database: database}
 	if err := store.initSchema(); err != nil {
 		return nil, err
 	}
@@ -69,12 +80,77 @@ func (s *IndexStore) initSchema() error {
 		return fmt.Errorf("failed to execute schema: %w", err)
 	}
 
+	if err := s.migrateColumns(); err != nil {
+		return err
+	}
+
 	_, _ = s.db.Exec(`INSERT OR IGNORE INTO schema_version (version) VALUES (?)`, GetSchemaVersion())
 	return nil
 }
 
+func (s *IndexStore) migrateColumns() error {
+	for _, m := range columnMigrations {
+		exists, err := s.columnExists(m.table, m.column)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		if _, err := s.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", m.table, m.column, m.definition)); err != nil {
+			return fmt.Errorf("add column %s.%s: %w", m.table, m.column, err)
+		}
+	}
+	return nil
+}
+
+func (s *IndexStore) columnExists(table, column string) (bool, error) {
+	rows, err := s.db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, fmt.Errorf("table info %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dflt, &pk); err != nil {
+			return false, fmt.Errorf("scan table info: %w", err)
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
 func (s *IndexStore) Close() error {
-	return s.db.Close()
+	return s.database.Close()
+}
+
+// Ping performs a write/read round-trip against the database
+func (s *IndexStore) Ping(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	nonce := time.Now().UnixNano()
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO health_probe (id, nonce) VALUES (1, ?)
+		ON CONFLICT(id) DO UPDATE SET nonce = excluded.nonce
+	`, nonce); err != nil {
+		return fmt.Errorf("health write: %w", err)
+	}
+
+	var got int64
+	if err := s.db.QueryRowContext(ctx, `SELECT nonce FROM health_probe WHERE id = 1`).Scan(&got); err != nil {
+		return fmt.Errorf("health read: %w", err)
+	}
+	if got != nonce {
+		return fmt.Errorf("health read returned stale value")
+	}
+
+	return nil
 }
 
 func (s *IndexStore) UpsertFile(file *IndexedFile) (int64, error) {
@@ -83,8 +159,8 @@ func (s *IndexStore) UpsertFile(file *IndexedFile) (int64, error) {
 
 	now := time.Now().UTC()
 	result, err := s.db.Exec(`
-		INSERT INTO files (path, content_hash, encoding, language, status, error_message, indexed_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		INSERT INTO files (path, content_hash, encoding, language, status, error_message, indexed_at, updated_at, mtime, size, branch)
+		VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, ?, ?, ?)
 		ON CONFLICT(path) DO UPDATE SET
 			content_hash = excluded.content_hash,
 			encoding = excluded.encoding,
@@ -92,8 +168,11 @@ func (s *IndexStore) UpsertFile(file *IndexedFile) (int64, error) {
 			status = excluded.status,
 			error_message = excluded.error_message,
 			indexed_at = excluded.indexed_at,
-			updated_at = CURRENT_TIMESTAMP
-	`, file.Path, file.ContentHash, file.Encoding, file.Language, file.Status, file.ErrorMessage, now)
+			updated_at = CURRENT_TIMESTAMP,
+			mtime = excluded.mtime,
+			size = excluded.size,
+			branch = excluded.branch
+	`, file.Path, file.ContentHash, file.Encoding, file.Language, file.Status, file.ErrorMessage, now, modTimeValue(file.ModTime), file.Size, file.Branch)
 
 	if err != nil {
 		return 0, fmt.Errorf("upsert file: %w", err)
@@ -117,13 +196,14 @@ func (s *IndexStore) GetFile(path string) (*IndexedFile, error) {
 	file := &IndexedFile{}
 	var indexedAt, updatedAt sql.NullTime
 	var errorMsg sql.NullString
+	var mtime, size sql.NullInt64
 
 	err := s.db.QueryRow(`
-		SELECT id, path, content_hash, encoding, language, status, error_message, indexed_at, updated_at
+		SELECT id, path, content_hash, encoding, language, status, error_message, indexed_at, updated_at, mtime, size, COALESCE(branch, '')
 		FROM files WHERE path = ?
 	`, path).Scan(
 		&file.ID, &file.Path, &file.ContentHash, &file.Encoding, &file.Language,
-		&file.Status, &errorMsg, &indexedAt, &updatedAt,
+		&file.Status, &errorMsg, &indexedAt, &updatedAt, &mtime, &size, &file.Branch,
 	)
 
 	if err == sql.ErrNoRows {
@@ -142,6 +222,12 @@ func (s *IndexStore) GetFile(path string) (*IndexedFile, error) {
 	if updatedAt.Valid {
 		file.UpdatedAt = updatedAt.Time
 	}
+	if mtime.Valid {
+		file.ModTime = time.Unix(0, mtime.Int64)
+	}
+	if size.Valid {
+		file.Size = size.Int64
+	}
 
 	return file, nil
 }
@@ -153,13 +239,14 @@ func (s *IndexStore) GetFileByID(id int64) (*IndexedFile, error) {
 	file := &IndexedFile{}
 	var indexedAt, updatedAt sql.NullTime
 	var errorMsg sql.NullString
+	var mtime, size sql.NullInt64
 
 	err := s.db.QueryRow(`
-		SELECT id, path, content_hash, encoding, language, status, error_message, indexed_at, updated_at
+		SELECT id, path, content_hash, encoding, language, status, error_message, indexed_at, updated_at, mtime, size, COALESCE(branch, '')
 		FROM files WHERE id = ?
 	`, id).Scan(
 		&file.ID, &file.Path, &file.ContentHash, &file.Encoding, &file.Language,
-		&file.Status, &errorMsg, &indexedAt, &updatedAt,
+		&file.Status, &errorMsg, &indexedAt, &updatedAt, &mtime, &size, &file.Branch,
 	)
 
 	if err == sql.ErrNoRows {
@@ -178,6 +265,12 @@ func (s *IndexStore) GetFileByID(id int64) (*IndexedFile, error) {
 	if updatedAt.Valid {
 		file.UpdatedAt = updatedAt.Time
 	}
+	if mtime.Valid {
+		file.ModTime = time.Unix(0, mtime.Int64)
+	}
+	if size.Valid {
+		file.Size = size.Int64
+	}
 
 	return file, nil
 }
@@ -187,7 +280,7 @@ func (s *IndexStore) GetFilesByStatus(status FileStatus, limit int) ([]*IndexedF
 	defer s.mu.RUnlock()
 
 	rows, err := s.db.Query(`
-		SELECT id, path, content_hash, encoding, language, status, error_message, indexed_at, updated_at
+		SELECT id, path, content_hash, encoding, language, status, error_message, indexed_at, updated_at, mtime, size, COALESCE(branch, '')
 		FROM files WHERE status = ? ORDER BY updated_at ASC LIMIT ?
 	`, status, limit)
 
@@ -202,10 +295,11 @@ func (s *IndexStore) GetFilesByStatus(status FileStatus, limit int) ([]*IndexedF
 		file := &IndexedFile{}
 		var indexedAt, updatedAt sql.NullTime
 		var errorMsg sql.NullString
+		var mtime, size sql.NullInt64
 
 		err := rows.Scan(
 			&file.ID, &file.Path, &file.ContentHash, &file.Encoding, &file.Language,
-			&file.Status, &errorMsg, &indexedAt, &updatedAt,
+			&file.Status, &errorMsg, &indexedAt, &updatedAt, &mtime, &size, &file.Branch,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("scan file: %w", err)
@@ -220,6 +314,12 @@ func (s *IndexStore) GetFilesByStatus(status FileStatus, limit int) ([]*IndexedF
 		if updatedAt.Valid {
 			file.UpdatedAt = updatedAt.Time
 		}
+		if mtime.Valid {
+			file.ModTime = time.Unix(0, mtime.Int64)
+		}
+		if size.Valid {
+			file.Size = size.Int64
+		}
 
 		files = append(files, file)
 	}
@@ -227,6 +327,29 @@ func (s *IndexStore) GetFilesByStatus(status FileStatus, limit int) ([]*IndexedF
 	return files, rows.Err()
 }
 
+// UpdateFileMetadata records a new mtime/size for a file whose content did
+// not change, so the next check can skip hashing.
+func (s *IndexStore) UpdateFileMetadata(path string, modTime time.Time, size int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec(`
+		UPDATE files SET mtime = ?, size = ? WHERE path = ?
+	`, modTimeValue(modTime), size, path)
+	if err != nil {
+		return fmt.Errorf("update file metadata: %w", err)
+	}
+
+	return nil
+}
+
+func modTimeValue(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UnixNano()
+}
+
 func (s *IndexStore) DeleteFile(path string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -276,8 +399,8 @@ func (s *IndexStore) InsertSymbols(fileID int64, symbols []*IndexedSymbol) error
 	}
 
 	stmt, err := tx.Prepare(`
-		INSERT INTO symbols (file_id, name, kind, signature, line_start, line_end, column_start, column_end, visibility, documentation, is_exported)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO symbols (file_id, name, kind, signature, line_start, line_end, column_start, column_end, visibility, documentation, is_exported, cell)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`)
 	if err != nil {
 		return fmt.Errorf("prepare stmt: %w", err)
@@ -288,7 +411,7 @@ func (s *IndexStore) InsertSymbols(fileID int64, symbols []*IndexedSymbol) error
 		_, err := stmt.Exec(
 			fileID, sym.Name, sym.Kind, sym.Signature,
 			sym.LineStart, sym.LineEnd, sym.ColumnStart, sym.ColumnEnd,
-			sym.Visibility, sym.Documentation, sym.IsExported,
+			sym.Visibility, sym.Documentation, sym.IsExported, sym.Cell,
 		)
 		if err != nil {
 			return fmt.Errorf("insert symbol %s: %w", sym.Name, err)
@@ -303,7 +426,7 @@ func (s *IndexStore) GetSymbolsByFile(fileID int64) ([]*IndexedSymbol, error) {
 	defer s.mu.RUnlock()
 
 	rows, err := s.db.Query(`
-		SELECT id, file_id, name, kind, signature, line_start, line_end, column_start, column_end, visibility, documentation, is_exported
+		SELECT id, file_id, name, kind, signature, line_start, line_end, column_start, column_end, visibility, documentation, is_exported, cell
 		FROM symbols WHERE file_id = ? ORDER BY line_start ASC
 	`, fileID)
 
@@ -318,12 +441,12 @@ func (s *IndexStore) GetSymbolsByFile(fileID int64) ([]*IndexedSymbol, error) {
 		sym := &IndexedSymbol{}
 		var signature, visibility, documentation sql.NullString
 		var lineEnd, columnStart, columnEnd sql.NullInt64
-		var isExported sql.NullInt64
+		var isExported, cell sql.NullInt64
 
 		err := rows.Scan(
 			&sym.ID, &sym.FileID, &sym.Name, &sym.Kind, &signature,
 			&sym.LineStart, &lineEnd, &columnStart, &columnEnd,
-			&visibility, &documentation, &isExported,
+			&visibility, &documentation, &isExported, &cell,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("scan symbol: %w", err)
@@ -350,6 +473,10 @@ func (s *IndexStore) GetSymbolsByFile(fileID int64) ([]*IndexedSymbol, error) {
 		if isExported.Valid {
 			sym.IsExported = isExported.Int64 != 0
 		}
+		if cell.Valid {
+			index := int(cell.Int64)
+			sym.Cell = &index
+		}
 
 		symbols = append(symbols, sym)
 	}
@@ -363,7 +490,7 @@ func (s *IndexStore) SearchSymbols(query string, limit int) ([]*IndexedSymbol, e
 
 	rows, err := s.db.Query(`
 		SELECT s.id, s.file_id, s.name, s.kind, s.signature, s.line_start, s.line_end,
-		       s.column_start, s.column_end, s.visibility, s.documentation, s.is_exported
+		       s.column_start, s.column_end, s.visibility, s.documentation, s.is_exported, s.cell
 		FROM symbols s
 		INNER JOIN symbols_fts fts ON s.id = fts.rowid
 		WHERE symbols_fts MATCH ? LIMIT ?
@@ -380,12 +507,12 @@ func (s *IndexStore) SearchSymbols(query string, limit int) ([]*IndexedSymbol, e
 		sym := &IndexedSymbol{}
 		var signature, visibility, documentation sql.NullString
 		var lineEnd, columnStart, columnEnd sql.NullInt64
-		var isExported sql.NullInt64
+		var isExported, cell sql.NullInt64
 
 		err := rows.Scan(
 			&sym.ID, &sym.FileID, &sym.Name, &sym.Kind, &signature,
 			&sym.LineStart, &lineEnd, &columnStart, &columnEnd,
-			&visibility, &documentation, &isExported,
+			&visibility, &documentation, &isExported, &cell,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("scan symbol: %w", err)
@@ -412,6 +539,10 @@ func (s *IndexStore) SearchSymbols(query string, limit int) ([]*IndexedSymbol, e
 		if isExported.Valid {
 			sym.IsExported = isExported.Int64 != 0
 		}
+		if cell.Valid {
+			index := int(cell.Int64)
+			sym.Cell = &index
+		}
 
 		symbols = append(symbols, sym)
 	}
@@ -426,15 +557,15 @@ func (s *IndexStore) GetSymbolByID(id int64) (*IndexedSymbol, error) {
 	sym := &IndexedSymbol{}
 	var signature, visibility, documentation sql.NullString
 	var lineEnd, columnStart, columnEnd sql.NullInt64
-	var isExported sql.NullInt64
+	var isExported, cell sql.NullInt64
 
 	err := s.db.QueryRow(`
-		SELECT id, file_id, name, kind, signature, line_start, line_end, column_start, column_end, visibility, documentation, is_exported
+		SELECT id, file_id, name, kind, signature, line_start, line_end, column_start, column_end, visibility, documentation, is_exported, cell
 		FROM symbols WHERE id = ?
 	`, id).Scan(
 		&sym.ID, &sym.FileID, &sym.Name, &sym.Kind, &signature,
 		&sym.LineStart, &lineEnd, &columnStart, &columnEnd,
-		&visibility, &documentation, &isExported,
+		&visibility, &documentation, &isExported, &cell,
 	)
 
 	if err == sql.ErrNoRows {
@@ -465,6 +596,10 @@ func (s *IndexStore) GetSymbolByID(id int64) (*IndexedSymbol, error) {
 	if isExported.Valid {
 		sym.IsExported = isExported.Int64 != 0
 	}
+	if cell.Valid {
+		index := int(cell.Int64)
+		sym.Cell = &index
+	}
 
 	return sym, nil
 }
@@ -599,6 +734,632 @@ func (s *IndexStore) GetReferencesInFile(fileID int64) ([]*SymbolReference, erro
 	return refs, rows.Err()
 }
 
+// GetFilePathsByLanguage returns indexed file paths for language, limited to
+// pathPrefix (a file or directory) when it is not empty.
+func (s *IndexStore) GetFilePathsByLanguage(language, pathPrefix string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query := `SELECT path FROM files WHERE language = ?`
+	args := []interface{}{language}
+	if pathPrefix != "" {
+		query += ` AND (path = ? OR path LIKE ? ESCAPE '\')`
+		args = append(args, pathPrefix, likePrefix(pathPrefix))
+	}
+	query += ` ORDER BY path`
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("get files by language: %w", err)
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, fmt.Errorf("scan file path: %w", err)
+		}
+		paths = append(paths, path)
+	}
+
+	return paths, rows.Err()
+}
+
+// GetLanguages returns the languages of indexed files under pathPrefix, most
+// files first.
+func (s *IndexStore) GetLanguages(pathPrefix string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query := `SELECT language FROM files WHERE language != ''`
+	var args []interface{}
+	if pathPrefix != "" {
+		query += ` AND (path = ? OR path LIKE ? ESCAPE '\')`
+		args = append(args, pathPrefix, likePrefix(pathPrefix))
+	}
+	query += ` GROUP BY language ORDER BY COUNT(*) DESC, language`
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("get languages: %w", err)
+	}
+	defer rows.Close()
+
+	var languages []string
+	for rows.Next() {
+		var language string
+		if err := rows.Scan(&language); err != nil {
+			return nil, fmt.Errorf("scan language: %w", err)
+		}
+		languages = append(languages, language)
+	}
+
+	return languages, rows.Err()
+}
+
+// GetLanguageStats returns file, byte and symbol counts per language of the
+// indexed files under pathPrefix, most files first. Files of no known
+// language are counted under "".
+func (s *IndexStore) GetLanguageStats(pathPrefix string) ([]*LanguageStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query := `
+		SELECT COALESCE(f.language, ''), COUNT(*), COALESCE(SUM(f.size), 0),
+		       COALESCE(SUM((SELECT COUNT(*) FROM symbols s WHERE s.file_id = f.id)), 0)
+		FROM files f
+		WHERE f.status = ?`
+	args := []interface{}{StatusIndexed}
+	if pathPrefix != "" {
+		query += ` AND (f.path = ? OR f.path LIKE ? ESCAPE '\')`
+		args = append(args, pathPrefix, likePrefix(pathPrefix))
+	}
+	query += ` GROUP BY COALESCE(f.language, '') ORDER BY COUNT(*) DESC, 1`
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("get language stats: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []*LanguageStats
+	for rows.Next() {
+		st := &LanguageStats{}
+		if err := rows.Scan(&st.Language, &st.Files, &st.Bytes, &st.Symbols); err != nil {
+			return nil, fmt.Errorf("scan language stats: %w", err)
+		}
+		stats = append(stats, st)
+	}
+
+	return stats, rows.Err()
+}
+
+// GetEncodingStats returns file and byte counts per detected encoding of
+// the files under pathPrefix, most files first, with up to examples paths
+// of each. Files stored as metadata only, such as generated ones, are
+// counted too; an unknown encoding is counted under "".
+func (s *IndexStore) GetEncodingStats(pathPrefix string, examples int) ([]*EncodingStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	filter := ` WHERE status IN (?, ?)`
+	args := []interface{}{StatusIndexed, StatusSkippedGenerated}
+	if pathPrefix != "" {
+		filter += ` AND (path = ? OR path LIKE ? ESCAPE '\')`
+		args = append(args, pathPrefix, likePrefix(pathPrefix))
+	}
+
+	rows, err := s.db.Query(`
+		SELECT COALESCE(encoding, ''), COUNT(*), COALESCE(SUM(size), 0)
+		FROM files`+filter+`
+		GROUP BY COALESCE(encoding, '') ORDER BY COUNT(*) DESC, 1`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("get encoding stats: %w", err)
+	}
+
+	var stats []*EncodingStats
+	for rows.Next() {
+		st := &EncodingStats{}
+		if err := rows.Scan(&st.Encoding, &st.Files, &st.Bytes); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan encoding stats: %w", err)
+		}
+		stats = append(stats, st)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if examples <= 0 {
+		return stats, nil
+	}
+
+	for _, st := range stats {
+		rows, err := s.db.Query(`SELECT path FROM files`+filter+`
+			AND COALESCE(encoding, '') = ? ORDER BY path LIMIT ?`,
+			append(args, st.Encoding, examples)...)
+		if err != nil {
+			return nil, fmt.Errorf("get encoding examples: %w", err)
+		}
+		for rows.Next() {
+			var path string
+			if err := rows.Scan(&path); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("scan encoding example: %w", err)
+			}
+			st.Examples = append(st.Examples, path)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return stats, nil
+}
+
+// GetIndexedPaths returns the paths of the indexed files under pathPrefix
+func (s *IndexStore) GetIndexedPaths(pathPrefix string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query := `SELECT path FROM files WHERE status = ?`
+	args := []interface{}{StatusIndexed}
+	if pathPrefix != "" {
+		query += ` AND (path = ? OR path LIKE ? ESCAPE '\')`
+		args = append(args, pathPrefix, likePrefix(pathPrefix))
+	}
+	query += ` ORDER BY path`
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("get indexed paths: %w", err)
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, fmt.Errorf("scan file path: %w", err)
+		}
+		paths = append(paths, path)
+	}
+
+	return paths, rows.Err()
+}
+
+// InsertDocSections replaces the doc sections stored for a markdown file
+func (s *IndexStore) InsertDocSections(fileID int64, sections []*DocSection) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM doc_sections WHERE file_id = ?", fileID); err != nil {
+		return fmt.Errorf("clear doc sections: %w", err)
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO doc_sections (file_id, heading, anchor, level, line, content)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("prepare stmt: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, section := range sections {
+		if _, err := stmt.Exec(fileID, section.Heading, section.Anchor, section.Level, section.Line, section.Content); err != nil {
+			return fmt.Errorf("insert doc section %q: %w", section.Heading, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// CountDocSections returns how many doc sections are indexed under
+// pathPrefix, or in total when it is empty
+func (s *IndexStore) CountDocSections(pathPrefix string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query := `SELECT COUNT(*) FROM doc_sections d JOIN files f ON f.id = d.file_id`
+	var args []interface{}
+	if pathPrefix != "" {
+		query += ` WHERE f.path = ? OR f.path LIKE ? ESCAPE '\'`
+		args = append(args, pathPrefix, likePrefix(pathPrefix))
+	}
+
+	var count int
+	if err := s.db.QueryRow(query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("count doc sections: %w", err)
+	}
+	return count, nil
+}
+
+// SearchDocs runs a full-text query over the indexed doc sections under
+// pathPrefix, best matches first. Every word of query must appear in the
+// heading or the text; the snippet marks matches with **.
+func (s *IndexStore) SearchDocs(query, pathPrefix string, limit int) ([]*DocMatch, error) {
+	match := ftsTerms(query)
+	if match == "" {
+		return nil, nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sqlQuery := `
+		SELECT d.file_id, f.path, COALESCE(d.heading, ''), COALESCE(d.anchor, ''), COALESCE(d.level, 0), d.line,
+		       snippet(doc_sections_fts, 1, '**', '**', '...', 24), bm25(doc_sections_fts, 5.0, 1.0)
+		FROM doc_sections_fts
+		JOIN doc_sections d ON d.id = doc_sections_fts.rowid
+		JOIN files f ON f.id = d.file_id
+		WHERE doc_sections_fts MATCH ?`
+	args := []interface{}{match}
+	if pathPrefix != "" {
+		sqlQuery += ` AND (f.path = ? OR f.path LIKE ? ESCAPE '\')`
+		args = append(args, pathPrefix, likePrefix(pathPrefix))
+	}
+	sqlQuery += ` ORDER BY 8, f.path, d.line LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := s.db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("search docs: %w", err)
+	}
+	defer rows.Close()
+
+	var matches []*DocMatch
+	for rows.Next() {
+		m := &DocMatch{}
+		if err := rows.Scan(&m.FileID, &m.Path, &m.Heading, &m.Anchor, &m.Level, &m.Line, &m.Snippet, &m.Rank); err != nil {
+			return nil, fmt.Errorf("scan doc match: %w", err)
+		}
+		matches = append(matches, m)
+	}
+
+	return matches, rows.Err()
+}
+
+// ftsTerms turns free text into an FTS5 query requiring every word, quoting
+// each one so punctuation in it is not read as query syntax
+func ftsTerms(query string) string {
+	var terms []string
+	for _, word := range strings.Fields(query) {
+		terms = append(terms, `"`+strings.ReplaceAll(word, `"`, `""`)+`"`)
+	}
+	return strings.Join(terms, " ")
+}
+
+// GetSymbolRanges returns the line ranges of symbols whose stored kind is one
+// of kinds. Name, language and pathPrefix narrow the result when not empty.
+func (s *IndexStore) GetSymbolRanges(kinds []string, name, language, pathPrefix string) ([]*SymbolRange, error) {
+	if len(kinds) == 0 {
+		return nil, nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(kinds)), ",")
+	query := `
+		SELECT s.file_id, f.path, COALESCE(f.branch, ''), s.name, s.kind, s.line_start, s.line_end
+		FROM symbols s
+		INNER JOIN files f ON f.id = s.file_id
+		WHERE s.kind IN (` + placeholders + `)`
+	args := make([]interface{}, 0, len(kinds)+4)
+	for _, kind := range kinds {
+		args = append(args, kind)
+	}
+	if name != "" {
+		query += ` AND s.name = ?`
+		args = append(args, name)
+	}
+	if language != "" {
+		query += ` AND f.language = ?`
+		args = append(args, language)
+	}
+	if pathPrefix != "" {
+		query += ` AND (f.path = ? OR f.path LIKE ? ESCAPE '\')`
+		args = append(args, pathPrefix, likePrefix(pathPrefix))
+	}
+	query += ` ORDER BY f.path, s.line_start`
+
+	return s.querySymbolRanges(query, args...)
+}
+
+// GetExportedSymbols returns the exported symbols whose stored kind is one of
+// kinds, limited to pathPrefix when it is not empty.
+func (s *IndexStore) GetExportedSymbols(kinds []string, pathPrefix string) ([]*SymbolRange, error) {
+	if len(kinds) == 0 {
+		return nil, nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(kinds)), ",")
+	query := `
+		SELECT s.file_id, f.path, COALESCE(f.branch, ''), s.name, s.kind, s.line_start, s.line_end
+		FROM symbols s
+		INNER JOIN files f ON f.id = s.file_id
+		WHERE s.is_exported = 1 AND s.kind IN (` + placeholders + `)`
+	args := make([]interface{}, 0, len(kinds)+2)
+	for _, kind := range kinds {
+		args = append(args, kind)
+	}
+	if pathPrefix != "" {
+		query += ` AND (f.path = ? OR f.path LIKE ? ESCAPE '\')`
+		args = append(args, pathPrefix, likePrefix(pathPrefix))
+	}
+	query += ` ORDER BY f.path, s.line_start`
+
+	return s.querySymbolRanges(query, args...)
+}
+
+// GetSymbolUses returns the symbols whose stored kind is one of kinds,
+// limited to pathPrefix when it is not empty, with their recorded
+// reference counts
+func (s *IndexStore) GetSymbolUses(kinds []string, pathPrefix string) ([]*SymbolUse, error) {
+	if len(kinds) == 0 {
+		return nil, nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(kinds)), ",")
+	query := `
+		SELECT s.file_id, f.path, COALESCE(f.branch, ''), s.name, s.kind, s.line_start, s.line_end, s.is_exported,
+			(SELECT COUNT(*) FROM symbol_refs r WHERE r.symbol_id = s.id AND r.kind != 'definition')
+		FROM symbols s
+		INNER JOIN files f ON f.id = s.file_id
+		WHERE s.kind IN (` + placeholders + `)`
+	args := make([]interface{}, 0, len(kinds)+2)
+	for _, kind := range kinds {
+		args = append(args, kind)
+	}
+	if pathPrefix != "" {
+		query += ` AND (f.path = ? OR f.path LIKE ? ESCAPE '\')`
+		args = append(args, pathPrefix, likePrefix(pathPrefix))
+	}
+	query += ` ORDER BY f.path, s.line_start`
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("get symbol uses: %w", err)
+	}
+	defer rows.Close()
+
+	var uses []*SymbolUse
+	for rows.Next() {
+		u := &SymbolUse{}
+		var lineEnd sql.NullInt64
+		var exported sql.NullBool
+		if err := rows.Scan(&u.FileID, &u.Path, &u.Branch, &u.Name, &u.Kind, &u.LineStart, &lineEnd, &exported, &u.References); err != nil {
+			return nil, fmt.Errorf("scan symbol use: %w", err)
+		}
+		u.LineEnd = int(lineEnd.Int64)
+		u.Exported = exported.Bool
+		uses = append(uses, u)
+	}
+
+	return uses, rows.Err()
+}
+
+func (s *IndexStore) querySymbolRanges(query string, args ...interface{}) ([]*SymbolRange, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("get symbol ranges: %w", err)
+	}
+	defer rows.Close()
+
+	var ranges []*SymbolRange
+	for rows.Next() {
+		r := &SymbolRange{}
+		var lineEnd sql.NullInt64
+		if err := rows.Scan(&r.FileID, &r.Path, &r.Branch, &r.Name, &r.Kind, &r.LineStart, &lineEnd); err != nil {
+			return nil, fmt.Errorf("scan symbol range: %w", err)
+		}
+		if lineEnd.Valid {
+			r.LineEnd = int(lineEnd.Int64)
+		}
+		ranges = append(ranges, r)
+	}
+
+	return ranges, rows.Err()
+}
+
+func likePrefix(dir string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(strings.TrimSuffix(dir, string(filepath.Separator)))
+	return escaped + string(filepath.Separator) + "%"
+}
+
+// RecordAccess counts an access to each of paths, all in one transaction
+func (s *IndexStore) RecordAccess(paths ...string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("record access: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO recent_files (path, access_count, last_accessed) VALUES (?, 1, ?)
+		ON CONFLICT(path) DO UPDATE SET
+			access_count = access_count + 1,
+			last_accessed = excluded.last_accessed
+	`)
+	if err != nil {
+		return fmt.Errorf("record access: %w", err)
+	}
+	defer stmt.Close()
+
+	now := time.Now()
+	for _, path := range paths {
+		if _, err := stmt.Exec(path, now); err != nil {
+			return fmt.Errorf("record access: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("record access: %w", err)
+	}
+	return nil
+}
+
+// GetRecentFiles returns the most recently accessed paths, newest first
+func (s *IndexStore) GetRecentFiles(limit int) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query(`
+		SELECT path FROM recent_files ORDER BY last_accessed DESC LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("get recent files: %w", err)
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, fmt.Errorf("scan recent file: %w", err)
+		}
+		paths = append(paths, path)
+	}
+
+	return paths, rows.Err()
+}
+
+// PruneRecentFiles keeps only the keep most recently accessed paths
+func (s *IndexStore) PruneRecentFiles(keep int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec(`
+		DELETE FROM recent_files WHERE path NOT IN (
+			SELECT path FROM recent_files ORDER BY last_accessed DESC LIMIT ?
+		)
+	`, keep)
+	if err != nil {
+		return fmt.Errorf("prune recent files: %w", err)
+	}
+	return nil
+}
+
+func (s *IndexStore) AddRetry(entry *RetryEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	next := entry.NextAttempt
+	if next.IsZero() {
+		next = now
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO index_retries (path, priority, reason, error_message, attempts, next_attempt, first_seen)
+		VALUES (?, ?, ?, ?, 0, ?, ?)
+		ON CONFLICT(path) DO UPDATE SET
+			reason = excluded.reason,
+			error_message = excluded.error_message
+	`, entry.Path, entry.Priority, entry.Reason, entry.Error, next.UnixNano(), now.UnixNano())
+	if err != nil {
+		return fmt.Errorf("add retry: %w", err)
+	}
+	return nil
+}
+
+func (s *IndexStore) GetDueRetries(now time.Time, maxAttempts, limit int) ([]*RetryEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query(`
+		SELECT path, priority, reason, error_message, attempts, next_attempt, first_seen
+		FROM index_retries WHERE next_attempt <= ? AND attempts < ?
+		ORDER BY next_attempt ASC LIMIT ?
+	`, now.UnixNano(), maxAttempts, limit)
+	if err != nil {
+		return nil, fmt.Errorf("get due retries: %w", err)
+	}
+	defer rows.Close()
+	return scanRetries(rows)
+}
+
+func (s *IndexStore) UpdateRetry(path string, attempts int, nextAttempt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec(`
+		UPDATE index_retries SET attempts = ?, next_attempt = ? WHERE path = ?
+	`, attempts, nextAttempt.UnixNano(), path)
+	if err != nil {
+		return fmt.Errorf("update retry: %w", err)
+	}
+	return nil
+}
+
+func (s *IndexStore) RemoveRetry(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.db.Exec(`DELETE FROM index_retries WHERE path = ?`, path); err != nil {
+		return fmt.Errorf("remove retry: %w", err)
+	}
+	return nil
+}
+
+func (s *IndexStore) GetRetries(limit int) ([]*RetryEntry, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var total int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM index_retries`).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count retries: %w", err)
+	}
+	rows, err := s.db.Query(`
+		SELECT path, priority, reason, error_message, attempts, next_attempt, first_seen
+		FROM index_retries ORDER BY attempts DESC, first_seen ASC LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("get retries: %w", err)
+	}
+	defer rows.Close()
+	entries, err := scanRetries(rows)
+	return entries, total, err
+}
+
+func scanRetries(rows *sql.Rows) ([]*RetryEntry, error) {
+	var entries []*RetryEntry
+	for rows.Next() {
+		entry := &RetryEntry{}
+		var errorMsg sql.NullString
+		var next, first int64
+		if err := rows.Scan(&entry.Path, &entry.Priority, &entry.Reason, &errorMsg, &entry.Attempts, &next, &first); err != nil {
+			return nil, fmt.Errorf("scan retry: %w", err)
+		}
+		entry.Error = errorMsg.String
+		entry.NextAttempt = time.Unix(0, next)
+		entry.FirstSeen = time.Unix(0, first)
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
 func (s *IndexStore) GetStats() (*IndexStats, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -610,19 +1371,50 @@ func (s *IndexStore) GetStats() (*IndexStats, error) {
 			COUNT(*) as total_files,
 			COALESCE(SUM(CASE WHEN status = 'indexed' THEN 1 ELSE 0 END), 0) as indexed_files,
 			COALESCE(SUM(CASE WHEN status = 'failed' THEN 1 ELSE 0 END), 0) as failed_files,
-			COALESCE(SUM(CASE WHEN status = 'skipped' THEN 1 ELSE 0 END), 0) as skipped_files,
-			MAX(indexed_at) as last_indexed_at
+			COALESCE(SUM(CASE WHEN status = 'skipped' OR status = 'skipped_generated' THEN 1 ELSE 0 END), 0) as skipped_files
 		FROM files
-	`).Scan(&stats.TotalFiles, &stats.IndexedFiles, &stats.FailedFiles, &stats.SkippedFiles, &stats.LastIndexedAt)
+	`).Scan(&stats.TotalFiles, &stats.IndexedFiles, &stats.FailedFiles, &stats.SkippedFiles)
 
 	if err != nil {
 		return nil, fmt.Errorf("get stats: %w", err)
 	}
 
+	// MAX() would lose the column type and return the time as text
+	var lastIndexedAt sql.NullTime
+	err = s.db.QueryRow(`
+		SELECT indexed_at FROM files WHERE indexed_at IS NOT NULL ORDER BY indexed_at DESC LIMIT 1
+	`).Scan(&lastIndexedAt)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("get last indexed time: %w", err)
+	}
+	if lastIndexedAt.Valid {
+		stats.LastIndexedAt = lastIndexedAt.Time
+	}
+
 	err = s.db.QueryRow("SELECT COUNT(*) FROM symbols").Scan(&stats.TotalSymbols)
 	if err != nil {
 		return nil, fmt.Errorf("get symbol count: %w", err)
 	}
 
+	rows, err := s.db.Query(`SELECT branch, COUNT(*) FROM files WHERE branch IS NOT NULL AND branch != '' GROUP BY branch`)
+	if err != nil {
+		return nil, fmt.Errorf("get branch counts: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var branch string
+		var count int
+		if err := rows.Scan(&branch, &count); err != nil {
+			return nil, fmt.Errorf("scan branch count: %w", err)
+		}
+		if stats.Branches == nil {
+			stats.Branches = make(map[string]int)
+		}
+		stats.Branches[branch] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get branch counts: %w", err)
+	}
+
 	return stats, nil
 }
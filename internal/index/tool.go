@@ -0,0 +1,116 @@
+package index
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+const defaultStatusFailures = 50
+
+type StatusRequest struct {
+	MaxResults int `json:"max_results,omitempty"`
+}
+
+// RetryStatus is a file of the retry list as index_status reports it
+type RetryStatus struct {
+	*RetryEntry
+	// GaveUp is set once the file used up its retries; it is indexed
+	// again after its next change
+	GaveUp bool `json:"gave_up,omitempty"`
+}
+
+type StatusTool struct {
+	worker *IndexWorker
+	store  Store
+}
+
+func NewStatusTool(worker *IndexWorker, store Store) *StatusTool {
+	return &StatusTool{worker: worker, store: store}
+}
+
+func (t *StatusTool) Name() string {
+	return "index_status"
+}
+
+func (t *StatusTool) Description() string {
+	return "Report the index: file and symbol counts, the worker's queues per priority and their starvation counters, and the files whose indexing was dropped on a full queue or failed, with their error, attempts and next retry"
+}
+
+func (t *StatusTool) Title() string {
+	return "Index Status"
+}
+
+func (t *StatusTool) Annotations() map[string]bool {
+	return tools.ReadOnlyAnnotations()
+}
+
+func (t *StatusTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"max_results": {
+				"type": "integer",
+				"description": "Maximum failed files listed (default: 50)"
+			}
+		}
+	}`)
+}
+
+func (t *StatusTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var req StatusRequest
+	if len(input) > 0 {
+		if err := json.Unmarshal(input, &req); err != nil {
+			return nil, fmt.Errorf("invalid request: %w", err)
+		}
+	}
+	if req.MaxResults <= 0 {
+		req.MaxResults = defaultStatusFailures
+	}
+
+	stats, err := t.store.GetStats()
+	if err != nil {
+		return nil, err
+	}
+	entries, total, err := t.store.GetRetries(req.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+
+	failures := make([]RetryStatus, 0, len(entries))
+	for _, entry := range entries {
+		failures = append(failures, RetryStatus{RetryEntry: entry, GaveUp: entry.Attempts >= t.worker.config.MaxRetryAttempts})
+	}
+
+	worker := t.worker.GetStats()
+	return map[string]interface{}{
+		"index": stats,
+		"worker": map[string]interface{}{
+			"running":         worker.IsRunning,
+			"indexed":         worker.Indexed,
+			"failed":          worker.Failed,
+			"skipped":         worker.Skipped,
+			"pending":         worker.InQueue,
+			"queued":          map[string]int{"high": worker.QueuedHigh, "normal": worker.QueuedNormal, "low": worker.QueuedLow},
+			"passed_over":     worker.PassedOver,
+			"aged":            worker.Aged,
+			"max_normal_wait": worker.MaxNormalWait.Round(time.Millisecond).String(),
+			"max_low_wait":    worker.MaxLowWait.Round(time.Millisecond).String(),
+			"dropped":         worker.Dropped,
+			"retried":         worker.Retried,
+		},
+		"retries": map[string]interface{}{
+			"total":        total,
+			"max_attempts": t.worker.config.MaxRetryAttempts,
+			"files":        failures,
+			"truncated":    total > len(failures),
+		},
+	}, nil
+}
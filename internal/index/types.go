@@ -9,6 +9,8 @@ const (
 	StatusIndexed FileStatus = "indexed"
 	StatusFailed  FileStatus = "failed"
 	StatusSkipped FileStatus = "skipped"
+
+	StatusSkippedGenerated FileStatus = "skipped_generated"
 )
 
 type IndexedFile struct {
@@ -21,6 +23,11 @@ type IndexedFile struct {
 	ErrorMessage string     `json:"error_message,omitempty"`
 	IndexedAt    time.Time  `json:"indexed_at"`
 	UpdatedAt    time.Time  `json:"updated_at"`
+	ModTime      time.Time  `json:"mtime"`
+	Size         int64      `json:"size"`
+	// Branch is the git branch checked out when the file was last indexed,
+	// empty outside a repository and on a detached HEAD
+	Branch string `json:"branch,omitempty"`
 }
 
 type IndexedSymbol struct {
@@ -36,6 +43,62 @@ type IndexedSymbol struct {
 	Visibility    string `json:"visibility,omitempty"`
 	Documentation string `json:"documentation,omitempty"`
 	IsExported    bool   `json:"is_exported"`
+	// Cell is the index of the notebook cell holding the symbol, whose lines
+	// count from the start of that cell
+	Cell *int `json:"cell,omitempty"`
+}
+
+// DocSection is the text under one heading of an indexed markdown file
+type DocSection struct {
+	FileID  int64  `json:"file_id"`
+	Path    string `json:"path"`
+	Heading string `json:"heading"`
+	Anchor  string `json:"anchor"`
+	Level   int    `json:"level"`
+	Line    int    `json:"line"`
+	Content string `json:"content,omitempty"`
+}
+
+// DocMatch is a doc section matching a full-text query, with a snippet of
+// the matching text and its bm25 rank (lower is better)
+type DocMatch struct {
+	DocSection
+	Snippet string  `json:"snippet"`
+	Rank    float64 `json:"rank"`
+}
+
+type SymbolRange struct {
+	FileID    int64  `json:"file_id"`
+	Path      string `json:"path"`
+	Branch    string `json:"branch,omitempty"`
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`
+	LineStart int    `json:"line_start"`
+	LineEnd   int    `json:"line_end"`
+}
+
+// SymbolUse is a symbol with the number of references to it, other than
+// its definition, recorded in symbol_refs
+type SymbolUse struct {
+	SymbolRange
+	Exported   bool `json:"exported"`
+	References int  `json:"references"`
+}
+
+type LanguageStats struct {
+	Language string `json:"language"`
+	Files    int    `json:"files"`
+	Bytes    int64  `json:"bytes"`
+	Symbols  int    `json:"symbols"`
+}
+
+// EncodingStats counts the indexed files detected in one encoding, with a
+// few of their paths
+type EncodingStats struct {
+	Encoding string   `json:"encoding"`
+	Files    int      `json:"files"`
+	Bytes    int64    `json:"bytes"`
+	Examples []string `json:"examples,omitempty"`
 }
 
 type SymbolReference struct {
@@ -55,15 +118,37 @@ type IndexStats struct {
 	SkippedFiles  int       `json:"skipped_files"`
 	TotalSymbols  int       `json:"total_symbols"`
 	LastIndexedAt time.Time `json:"last_indexed_at"`
+	// Branches counts the files indexed on each git branch
+	Branches map[string]int `json:"branches,omitempty"`
 }
 
 type IndexJob struct {
 	Path     string
 	Priority JobPriority
+	// EnqueuedAt is set by Enqueue and measures how long the job waited
+	EnqueuedAt time.Time
 }
 
 type JobPriority int
 
+// Reasons a file is waiting to be retried
+const (
+	RetryDropped = "dropped"
+	RetryFailed  = "failed"
+)
+
+// RetryEntry is a file whose indexing is retried: dropped because its
+// queue was full, or failed
+type RetryEntry struct {
+	Path        string      `json:"path"`
+	Priority    JobPriority `json:"priority"`
+	Reason      string      `json:"reason"`
+	Error       string      `json:"error,omitempty"`
+	Attempts    int         `json:"attempts"`
+	NextAttempt time.Time   `json:"next_attempt"`
+	FirstSeen   time.Time   `json:"first_seen"`
+}
+
 const (
 	PriorityLow JobPriority = iota
 	PriorityNormal
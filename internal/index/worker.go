@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"math"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -12,7 +13,13 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/alucardeht/may-la-mcp/internal/extract"
+	"github.com/alucardeht/may-la-mcp/internal/glob"
 	"github.com/alucardeht/may-la-mcp/internal/logger"
+	"github.com/alucardeht/may-la-mcp/internal/notebook"
+	"github.com/alucardeht/may-la-mcp/internal/paths"
+	"github.com/alucardeht/may-la-mcp/internal/types"
+	"github.com/alucardeht/may-la-mcp/internal/vcs"
 )
 
 var log = logger.ForComponent("indexer")
@@ -23,6 +30,47 @@ type WorkerConfig struct {
 	RateLimit       int
 	MaxFileSize     int64
 	ExcludePatterns []string
+
+	// LanguageMaxFileSize caps symbol extraction per language; larger files
+	// are stored as metadata only.
+	LanguageMaxFileSize map[string]int64
+
+	// AgingInterval is how long a queue with work may go unserved before
+	// it is served ahead of the higher ones
+	AgingInterval time.Duration
+
+	// RetryInterval is how often dropped and failed files due for a retry
+	// are queued again; MaxRetryAttempts is how many retries a file gets
+	RetryInterval    time.Duration
+	MaxRetryAttempts int
+}
+
+const (
+	defaultAgingInterval    = 2 * time.Second
+	defaultRetryInterval    = 15 * time.Second
+	defaultMaxRetryAttempts = 8
+
+	// retryBaseDelay doubles with every failed attempt up to retryMaxDelay
+	retryBaseDelay = 30 * time.Second
+	retryMaxDelay  = time.Hour
+	// retryBatch bounds the files queued again per retry round
+	retryBatch = 100
+)
+
+// queueWeights is the share of dequeues each priority is offered first
+// when every queue has work: out of 13, high 8, normal 4 and low 1
+var queueWeights = [...]int{PriorityLow: 1, PriorityNormal: 4, PriorityHigh: 8}
+
+func DefaultLanguageMaxFileSize() map[string]int64 {
+	return map[string]int64{
+		"javascript": 512 * 1024,
+		"typescript": 1024 * 1024,
+		"python":     1024 * 1024,
+		"go":         2 * 1024 * 1024,
+		"java":       1024 * 1024,
+		"c":          2 * 1024 * 1024,
+		"cpp":        2 * 1024 * 1024,
+	}
 }
 
 func DefaultWorkerConfig() WorkerConfig {
@@ -40,6 +88,10 @@ func DefaultWorkerConfig() WorkerConfig {
 			"**/build/**",
 			"**/dist/**",
 		},
+		LanguageMaxFileSize: DefaultLanguageMaxFileSize(),
+		AgingInterval:       defaultAgingInterval,
+		RetryInterval:       defaultRetryInterval,
+		MaxRetryAttempts:    defaultMaxRetryAttempts,
 	}
 }
 
@@ -51,10 +103,28 @@ type WorkerStats struct {
 	IsRunning   bool
 	StartedAt   time.Time
 	LastIndexed time.Time
+
+	// Queued is the number of jobs waiting per priority
+	QueuedHigh   int
+	QueuedNormal int
+	QueuedLow    int
+	// PassedOver counts the dequeues that served a higher priority while
+	// lower-priority jobs waited, Aged the jobs served ahead of higher
+	// priorities because their queue had waited AgingInterval
+	PassedOver int64
+	Aged       int64
+	// MaxNormalWait and MaxLowWait are the longest a normal and a low
+	// priority job waited to be picked up
+	MaxNormalWait time.Duration
+	MaxLowWait    time.Duration
+	// Dropped counts the jobs a full queue turned away, Retried the jobs
+	// queued again by the retry loop
+	Dropped int64
+	Retried int64
 }
 
 type IndexWorker struct {
-	store  *IndexStore
+	store  Store
 	config WorkerConfig
 
 	highQueue   chan IndexJob
@@ -69,9 +139,23 @@ type IndexWorker struct {
 
 	stats   WorkerStats
 	statsMu sync.RWMutex
+
+	// excludes start as config.ExcludePatterns and can be changed while
+	// the worker runs
+	excludes  []string
+	excludeMu sync.RWMutex
+
+	// turn rotates the weighted order queues are tried in; lastServed
+	// holds when each priority's queue was last served, in Unix nanoseconds
+	turn       atomic.Uint64
+	lastServed [3]atomic.Int64
+
+	// retries holds the paths waiting in the store's retry list, with when
+	// they were last added to it
+	retries sync.Map
 }
 
-func NewIndexWorker(store *IndexStore, config WorkerConfig) *IndexWorker {
+func NewIndexWorker(store Store, config WorkerConfig) *IndexWorker {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	w := &IndexWorker{
@@ -82,12 +166,26 @@ func NewIndexWorker(store *IndexStore, config WorkerConfig) *IndexWorker {
 		lowQueue:    make(chan IndexJob, config.MaxQueueSize*2),
 		ctx:         ctx,
 		cancel:      cancel,
+		excludes:    config.ExcludePatterns,
 	}
 
 	if config.RateLimit > 0 {
 		interval := time.Second / time.Duration(config.RateLimit)
 		w.rateLimiter = time.NewTicker(interval)
 	}
+	if w.config.AgingInterval <= 0 {
+		w.config.AgingInterval = defaultAgingInterval
+	}
+	if w.config.RetryInterval <= 0 {
+		w.config.RetryInterval = defaultRetryInterval
+	}
+	if w.config.MaxRetryAttempts <= 0 {
+		w.config.MaxRetryAttempts = defaultMaxRetryAttempts
+	}
+	now := time.Now().UnixNano()
+	for i := range w.lastServed {
+		w.lastServed[i].Store(now)
+	}
 
 	return w
 }
@@ -104,6 +202,9 @@ func (w *IndexWorker) Start() {
 		w.wg.Add(1)
 		go w.worker(i)
 	}
+
+	w.wg.Add(1)
+	go w.retryLoop()
 }
 
 func (w *IndexWorker) Stop() {
@@ -122,7 +223,20 @@ func (w *IndexWorker) Stop() {
 	log.Info("index worker stopped")
 }
 
+// Enqueue queues job. A job its queue has no room for is recorded in the
+// store's retry list and queued again later.
 func (w *IndexWorker) Enqueue(job IndexJob) bool {
+	if w.push(job) {
+		return true
+	}
+	log.Warn("job enqueue failed - queue full", "path", job.Path, "priority", job.Priority)
+	atomic.AddInt64(&w.stats.Dropped, 1)
+	w.scheduleRetry(paths.Canonical(job.Path), job.Priority, RetryDropped, "queue full")
+	return false
+}
+
+// push queues job without blocking, reporting whether there was room
+func (w *IndexWorker) push(job IndexJob) bool {
 	var queue chan IndexJob
 	switch job.Priority {
 	case PriorityHigh:
@@ -139,13 +253,15 @@ func (w *IndexWorker) Enqueue(job IndexJob) bool {
 		log.Error("CRITICAL: queue channel is nil!", "priority", job.Priority)
 		return false
 	}
+	if job.EnqueuedAt.IsZero() {
+		job.EnqueuedAt = time.Now()
+	}
 
 	select {
 	case queue <- job:
 		atomic.AddInt64(&w.stats.InQueue, 1)
 		return true
 	default:
-		log.Warn("job enqueue failed - queue full", "path", job.Path, "priority", job.Priority)
 		return false
 	}
 }
@@ -165,6 +281,13 @@ func (w *IndexWorker) GetStats() WorkerStats {
 	defer w.statsMu.RUnlock()
 	stats := w.stats
 	stats.InQueue = atomic.LoadInt64(&w.stats.InQueue)
+	stats.PassedOver = atomic.LoadInt64(&w.stats.PassedOver)
+	stats.Aged = atomic.LoadInt64(&w.stats.Aged)
+	stats.Dropped = atomic.LoadInt64(&w.stats.Dropped)
+	stats.Retried = atomic.LoadInt64(&w.stats.Retried)
+	stats.QueuedHigh = len(w.highQueue)
+	stats.QueuedNormal = len(w.normalQueue)
+	stats.QueuedLow = len(w.lowQueue)
 	return stats
 }
 
@@ -186,25 +309,9 @@ func (w *IndexWorker) worker(id int) {
 			}
 		}
 
-		var job IndexJob
-		var ok bool
-
-		select {
-		case job, ok = <-w.highQueue:
-		default:
-			select {
-			case job, ok = <-w.normalQueue:
-			default:
-				select {
-				case job, ok = <-w.lowQueue:
-				default:
-					time.Sleep(10 * time.Millisecond)
-					continue
-				}
-			}
-		}
-
+		job, ok := w.dequeue()
 		if !ok {
+			time.Sleep(10 * time.Millisecond)
 			continue
 		}
 
@@ -214,9 +321,91 @@ func (w *IndexWorker) worker(id int) {
 	}
 }
 
+// dequeue takes the next job. A queue that has waited AgingInterval since
+// it was last served goes first, lowest priority first; otherwise queues
+// are tried in an order rotating by queueWeights, so that sustained high
+// priority traffic cannot starve the lower queues.
+func (w *IndexWorker) dequeue() (IndexJob, bool) {
+	queues := [...]chan IndexJob{PriorityLow: w.lowQueue, PriorityNormal: w.normalQueue, PriorityHigh: w.highQueue}
+
+	first, aged := w.firstQueue(queues[:])
+	order := []JobPriority{first, PriorityHigh, PriorityNormal, PriorityLow}
+	for _, p := range order {
+		select {
+		case job, ok := <-queues[p]:
+			if !ok {
+				continue
+			}
+			w.served(p, job, aged && p == first, queues[:])
+			return job, true
+		default:
+		}
+	}
+	return IndexJob{}, false
+}
+
+// firstQueue picks the priority tried first and whether it was picked by
+// aging
+func (w *IndexWorker) firstQueue(queues []chan IndexJob) (JobPriority, bool) {
+	now := time.Now().UnixNano()
+	for _, p := range []JobPriority{PriorityLow, PriorityNormal} {
+		if len(queues[p]) > 0 && now-w.lastServed[p].Load() >= int64(w.config.AgingInterval) {
+			return p, true
+		}
+	}
+
+	total := 0
+	for _, weight := range queueWeights {
+		total += weight
+	}
+	slot := int(w.turn.Add(1) % uint64(total))
+	for _, p := range []JobPriority{PriorityHigh, PriorityNormal, PriorityLow} {
+		if slot < queueWeights[p] {
+			return p, false
+		}
+		slot -= queueWeights[p]
+	}
+	return PriorityHigh, false
+}
+
+// served records that p's queue was served with job and updates the
+// starvation counters
+func (w *IndexWorker) served(p JobPriority, job IndexJob, aged bool, queues []chan IndexJob) {
+	w.lastServed[p].Store(time.Now().UnixNano())
+	if aged {
+		atomic.AddInt64(&w.stats.Aged, 1)
+	}
+	for lower := PriorityLow; lower < p; lower++ {
+		if len(queues[lower]) > 0 {
+			atomic.AddInt64(&w.stats.PassedOver, 1)
+			break
+		}
+	}
+	// an empty queue is not starving, so its clock starts when work arrives
+	for other := PriorityLow; other <= PriorityHigh; other++ {
+		if other != p && len(queues[other]) == 0 {
+			w.lastServed[other].Store(time.Now().UnixNano())
+		}
+	}
+
+	if job.EnqueuedAt.IsZero() || p == PriorityHigh {
+		return
+	}
+	wait := time.Since(job.EnqueuedAt)
+	w.statsMu.Lock()
+	if p == PriorityLow && wait > w.stats.MaxLowWait {
+		w.stats.MaxLowWait = wait
+	} else if p == PriorityNormal && wait > w.stats.MaxNormalWait {
+		w.stats.MaxNormalWait = wait
+	}
+	w.statsMu.Unlock()
+}
+
 func (w *IndexWorker) processJob(job IndexJob) {
-	path := job.Path
+	path := paths.Canonical(job.Path)
 	log.Debug("processing file", "path", path)
+	started := time.Now()
+	defer w.settleRetry(path, started)
 
 	if w.shouldExclude(path) {
 		w.recordSkipped()
@@ -227,6 +416,10 @@ func (w *IndexWorker) processJob(job IndexJob) {
 	info, err := os.Stat(path)
 	if err != nil {
 		w.recordFailed(path, err.Error())
+		if os.IsNotExist(err) {
+			// a file deleted since it was queued is not retried
+			w.forgetRetry(path)
+		}
 		log.Warn("failed to index", "path", path, "error", err)
 		return
 	}
@@ -243,8 +436,27 @@ func (w *IndexWorker) processJob(job IndexJob) {
 	}
 
 	existing, _ := w.store.GetFile(path)
+	lang := DetectLanguage(path)
+	// Documents are indexed as their format while extraction is enabled, so
+	// turning it on or off re-indexes them
+	document := extract.Format(path)
+	if document != "" {
+		lang = document
+	}
+
+	// A file recorded under another language (markdown used to have none)
+	// is re-indexed even if unchanged
+	if existing != nil && existing.Language != lang {
+		existing = nil
+	}
 
-	content, encoding, err := ReadFileAsUTF8(path)
+	if existing != nil && (existing.Status == StatusIndexed || existing.Status == StatusSkippedGenerated) &&
+		existing.Size == info.Size() && existing.ModTime.Equal(info.ModTime()) {
+		log.Debug("skipped file", "path", path, "reason", "metadata unchanged")
+		return
+	}
+
+	content, encoding, err := readContent(path, document)
 	if err != nil {
 		w.recordFailed(path, err.Error())
 		log.Warn("failed to index", "path", path, "error", err)
@@ -255,11 +467,19 @@ func (w *IndexWorker) processJob(job IndexJob) {
 	hashStr := hex.EncodeToString(hash[:])
 
 	if existing != nil && existing.ContentHash == hashStr {
+		if err := w.store.UpdateFileMetadata(path, info.ModTime(), info.Size()); err != nil {
+			log.Debug("failed to update file metadata", "path", path, "error", err)
+		}
 		log.Debug("skipped file", "path", path, "reason", "content unchanged")
 		return
 	}
 
-	lang := detectLanguage(path)
+	// extracted prose and notebook outputs have long lines but are never
+	// minified
+	if generated, reason := w.detectGenerated(path, lang, info.Size(), content); generated && document == "" && lang != "notebook" {
+		w.indexMetadataOnly(path, hashStr, encoding.Encoding, lang, info, reason)
+		return
+	}
 
 	file := &IndexedFile{
 		Path:        path,
@@ -268,6 +488,9 @@ func (w *IndexWorker) processJob(job IndexJob) {
 		Language:    lang,
 		Status:      StatusIndexed,
 		IndexedAt:   time.Now(),
+		ModTime:     info.ModTime(),
+		Size:        info.Size(),
+		Branch:      vcs.Branch(path),
 	}
 
 	fileID, err := w.store.UpsertFile(file)
@@ -286,6 +509,14 @@ func (w *IndexWorker) processJob(job IndexJob) {
 		}
 	}
 
+	if sections := docSections(lang, content); sections != nil {
+		if err := w.store.InsertDocSections(fileID, sections); err != nil {
+			w.recordFailed(path, err.Error())
+			log.Warn("failed to index", "path", path, "error", err)
+			return
+		}
+	}
+
 	symbolCount := len(symbols)
 	w.recordIndexed()
 	log.Info("file indexed successfully", "path", path, "symbols", symbolCount)
@@ -297,20 +528,143 @@ func (w *IndexWorker) processJob(job IndexJob) {
 	}
 }
 
+// readContent returns the text of the file at path, extracted from it when
+// it is a document
+func readContent(path, document string) (string, EncodingResult, error) {
+	if document == "" {
+		return ReadFileAsUTF8(path)
+	}
+	content, err := extract.File(path)
+	if err != nil {
+		return "", EncodingResult{}, err
+	}
+	return content, EncodingResult{Encoding: "utf-8", Confidence: 1}, nil
+}
+
+// docSections returns the doc sections indexed for a file of lang: markdown
+// and DOCX text by heading, PDF text by page
+func docSections(lang, content string) []*DocSection {
+	switch lang {
+	case "markdown", extract.FormatDOCX:
+		return SplitMarkdown(content)
+	case extract.FormatPDF:
+		return SplitPages(content)
+	}
+	return nil
+}
+
+func (w *IndexWorker) detectGenerated(path, lang string, size int64, content string) (bool, string) {
+	if limit, ok := w.config.LanguageMaxFileSize[lang]; ok && limit > 0 && size > limit {
+		return true, "exceeds language size limit"
+	}
+	if generated, reason := detectGeneratedByName(path); generated {
+		return true, reason
+	}
+	return detectGeneratedContent(content)
+}
+
+// indexMetadataOnly records a generated or oversized file without symbols so
+// it stays visible in the index but does not pollute symbol search.
+func (w *IndexWorker) indexMetadataOnly(path, hash, encoding, lang string, info os.FileInfo, reason string) {
+	file := &IndexedFile{
+		Path:         path,
+		ContentHash:  hash,
+		Encoding:     encoding,
+		Language:     lang,
+		Status:       StatusSkippedGenerated,
+		ErrorMessage: reason,
+		IndexedAt:    time.Now(),
+		ModTime:      info.ModTime(),
+		Size:         info.Size(),
+		Branch:       vcs.Branch(path),
+	}
+
+	fileID, err := w.store.UpsertFile(file)
+	if err != nil {
+		w.recordFailed(path, err.Error())
+		log.Warn("failed to index", "path", path, "error", err)
+		return
+	}
+
+	if err := w.store.ClearFileSymbols(fileID); err != nil {
+		log.Debug("failed to clear symbols", "path", path, "error", err)
+	}
+	if err := w.store.InsertDocSections(fileID, nil); err != nil {
+		log.Debug("failed to clear doc sections", "path", path, "error", err)
+	}
+
+	w.recordSkipped()
+	log.Debug("skipped file", "path", path, "reason", reason)
+}
+
 func (w *IndexWorker) shouldExclude(path string) bool {
-	for _, pattern := range w.config.ExcludePatterns {
-		if matched, _ := filepath.Match(pattern, path); matched {
-			return true
+	return w.IsExcluded(path)
+}
+
+// IsExcluded reports whether path is under one of the exclude patterns
+func (w *IndexWorker) IsExcluded(path string) bool {
+	w.excludeMu.RLock()
+	defer w.excludeMu.RUnlock()
+	return MatchesAny(w.excludes, path)
+}
+
+// ExcludePatterns returns the exclude patterns in effect
+func (w *IndexWorker) ExcludePatterns() []string {
+	w.excludeMu.RLock()
+	defer w.excludeMu.RUnlock()
+	return append([]string(nil), w.excludes...)
+}
+
+// SetExcludePatterns replaces the exclude patterns and returns the
+// previous ones. Files already indexed stay until PurgeExcluded.
+func (w *IndexWorker) SetExcludePatterns(patterns []string) []string {
+	w.excludeMu.Lock()
+	defer w.excludeMu.Unlock()
+	previous := w.excludes
+	w.excludes = append([]string(nil), patterns...)
+	return previous
+}
+
+// IndexedSize returns the size path had when it was last indexed
+func (w *IndexWorker) IndexedSize(path string) (int64, bool) {
+	file, err := w.store.GetFile(path)
+	if err != nil || file == nil {
+		return 0, false
+	}
+	return file.Size, true
+}
+
+// PurgeExcluded removes the indexed files the exclude patterns now cover
+// and returns how many it removed
+func (w *IndexWorker) PurgeExcluded(ctx context.Context) (int, error) {
+	paths, err := w.store.GetIndexedPaths("")
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return removed, err
+		}
+		if !w.IsExcluded(path) {
+			continue
 		}
+		if err := w.store.DeleteFile(path); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
 
-		if strings.Contains(pattern, "**") {
-			parts := strings.Split(pattern, "**")
-			for _, part := range parts {
-				part = strings.Trim(part, "/")
-				if part != "" && strings.Contains(path, "/"+part+"/") {
-					return true
-				}
-			}
+// MatchesAny reports whether path, or a directory it is under, matches
+// one of patterns
+func MatchesAny(patterns []string, path string) bool {
+	path = filepath.ToSlash(path)
+	for _, pattern := range patterns {
+		if glob.Covers(pattern, path) {
+			return true
 		}
 	}
 	return false
@@ -326,13 +680,111 @@ func (w *IndexWorker) recordIndexed() {
 func (w *IndexWorker) recordFailed(path, errMsg string) {
 	atomic.AddInt64(&w.stats.Failed, 1)
 	w.store.UpdateFileStatus(path, StatusFailed, errMsg)
+	w.scheduleRetry(path, PriorityLow, RetryFailed, errMsg)
+}
+
+// scheduleRetry adds path to the store's retry list. A dropped job is
+// retried on the next round, a failed one after retryBaseDelay.
+func (w *IndexWorker) scheduleRetry(path string, priority JobPriority, reason, errMsg string) {
+	_, pending := w.retries.Swap(path, time.Now())
+	if pending && reason == RetryDropped {
+		return
+	}
+	entry := &RetryEntry{Path: path, Priority: priority, Reason: reason, Error: errMsg}
+	if reason == RetryFailed {
+		entry.NextAttempt = time.Now().Add(retryBaseDelay)
+	}
+	if err := w.store.AddRetry(entry); err != nil {
+		log.Warn("failed to record retry", "path", path, "error", err)
+	}
+}
+
+// settleRetry takes path off the retry list once a job for it ran without
+// failing, that is without scheduling a retry after started
+func (w *IndexWorker) settleRetry(path string, started time.Time) {
+	if at, ok := w.retries.Load(path); ok && at.(time.Time).Before(started) {
+		w.forgetRetry(path)
+	}
+}
+
+func (w *IndexWorker) forgetRetry(path string) {
+	if _, ok := w.retries.LoadAndDelete(path); !ok {
+		return
+	}
+	if err := w.store.RemoveRetry(path); err != nil {
+		log.Debug("failed to remove retry", "path", path, "error", err)
+	}
+}
+
+// retryLoop queues the files of the retry list that are due every
+// RetryInterval, until the worker stops
+func (w *IndexWorker) retryLoop() {
+	defer w.wg.Done()
+
+	// files left on the list by an earlier run are tracked again
+	if entries, _, err := w.store.GetRetries(math.MaxInt32); err == nil {
+		for _, entry := range entries {
+			w.retries.LoadOrStore(entry.Path, entry.FirstSeen)
+		}
+	}
+
+	ticker := time.NewTicker(w.config.RetryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+			w.retryDue(time.Now())
+		}
+	}
+}
+
+// retryDue queues the files due at now, backing each off exponentially
+// before its next attempt. Files that got MaxRetryAttempts stay listed
+// until they are indexed after their next change.
+func (w *IndexWorker) retryDue(now time.Time) int {
+	due, err := w.store.GetDueRetries(now, w.config.MaxRetryAttempts, retryBatch)
+	if err != nil {
+		log.Warn("failed to read retries", "error", err)
+		return 0
+	}
+
+	queued := 0
+	for _, entry := range due {
+		if !w.push(IndexJob{Path: entry.Path, Priority: entry.Priority, EnqueuedAt: now}) {
+			// the queues are still full; the rest waits for the next round
+			break
+		}
+		attempts := entry.Attempts + 1
+		if err := w.store.UpdateRetry(entry.Path, attempts, now.Add(retryDelay(attempts))); err != nil {
+			log.Debug("failed to update retry", "path", entry.Path, "error", err)
+		}
+		atomic.AddInt64(&w.stats.Retried, 1)
+		queued++
+	}
+	if queued > 0 {
+		log.Info("retrying index jobs", "count", queued)
+	}
+	return queued
+}
+
+// retryDelay is the wait before the attempt after the given one
+func retryDelay(attempts int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempts && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, retryMaxDelay)
 }
 
 func (w *IndexWorker) recordSkipped() {
 	atomic.AddInt64(&w.stats.Skipped, 1)
 }
 
-func detectLanguage(path string) string {
+// DetectLanguage returns the language indexed for path by its extension, or
+// "" when the extension is not known
+func DetectLanguage(path string) string {
 	ext := strings.ToLower(filepath.Ext(path))
 	switch ext {
 	case ".go":
@@ -363,6 +815,10 @@ func detectLanguage(path string) string {
 		return "scala"
 	case ".cs":
 		return "csharp"
+	case ".md", ".markdown", ".mdx":
+		return "markdown"
+	case ".ipynb":
+		return "notebook"
 	default:
 		return ""
 	}
@@ -372,6 +828,9 @@ func extractSymbols(content, language string) []*IndexedSymbol {
 	if language == "" {
 		return nil
 	}
+	if language == "notebook" {
+		return notebookSymbols(content)
+	}
 
 	var patterns map[string]*regexp.Regexp
 	switch language {
@@ -390,33 +849,64 @@ func extractSymbols(content, language string) []*IndexedSymbol {
 	}
 
 	var symbols []*IndexedSymbol
-	lines := strings.Split(content, "\n")
+	lines := types.SplitLines(content)
 
 	for lineNum, line := range lines {
+		found := make(map[string][]int)
+		var matched []string
 		for kind, re := range patterns {
-			matches := re.FindStringSubmatch(line)
-			if len(matches) > 1 {
-				name := matches[1]
-				sym := &IndexedSymbol{
-					Name:       name,
-					Kind:       kind,
-					LineStart:  lineNum + 1,
-					LineEnd:    lineNum + 1,
-					IsExported: isExported(name, language),
-				}
-
-				if len(matches) > 2 {
-					sym.Signature = strings.TrimSpace(matches[0])
-				}
-
-				symbols = append(symbols, sym)
+			loc := re.FindStringSubmatchIndex(line)
+			if len(loc) > 3 && loc[2] >= 0 {
+				kind = types.NormalizeKind(kind)
+				found[kind] = loc
+				matched = append(matched, kind)
 			}
 		}
+
+		for _, kind := range types.PreferSpecific(matched) {
+			loc := found[kind]
+			name := line[loc[2]:loc[3]]
+			sym := &IndexedSymbol{
+				Name:        name,
+				Kind:        kind,
+				LineStart:   lineNum + 1,
+				LineEnd:     lineNum + 1,
+				ColumnStart: types.ColumnFromByte(line, loc[2]),
+				ColumnEnd:   types.ColumnFromByte(line, loc[3]),
+				IsExported:  isExported(name, language),
+			}
+
+			if len(loc) > 5 {
+				sym.Signature = strings.TrimSpace(line[loc[0]:loc[1]])
+			}
+
+			symbols = append(symbols, sym)
+		}
 	}
 
 	return symbols
 }
 
+// notebookSymbols extracts the symbols of each code cell of a notebook in
+// the kernel's language, with lines counted from the start of the cell
+func notebookSymbols(content string) []*IndexedSymbol {
+	nb, err := notebook.Parse([]byte(content))
+	if err != nil {
+		return nil
+	}
+	var symbols []*IndexedSymbol
+	for _, cell := range nb.Cells {
+		if cell.Type != notebook.CodeCell {
+			continue
+		}
+		for _, sym := range extractSymbols(cell.Source, nb.Language) {
+			sym.Cell = &cell.Index
+			symbols = append(symbols, sym)
+		}
+	}
+	return symbols
+}
+
 func isExported(name, language string) bool {
 	if name == "" {
 		return false
@@ -0,0 +1,183 @@
+package index
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestWorker(t *testing.T, store Store, config WorkerConfig) *IndexWorker {
+	t.Helper()
+	if store == nil {
+		s, err := NewIndexStore(filepath.Join(t.TempDir(), "index.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { s.Close() })
+		store = s
+	}
+	config.RateLimit = 0
+	w := NewIndexWorker(store, config)
+	t.Cleanup(w.cancel)
+	return w
+}
+
+// fill keeps n jobs of priority queued
+func fill(t *testing.T, w *IndexWorker, priority JobPriority, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if !w.push(IndexJob{Path: "/busy", Priority: priority}) {
+			t.Fatalf("queue of priority %d is full", priority)
+		}
+	}
+}
+
+func TestDequeueAging(t *testing.T) {
+	config := DefaultWorkerConfig()
+	config.AgingInterval = 50 * time.Millisecond
+	w := newTestWorker(t, nil, config)
+
+	fill(t, w, PriorityHigh, 10)
+	fill(t, w, PriorityNormal, 10)
+	w.push(IndexJob{Path: "/old", Priority: PriorityLow})
+
+	// before the low queue has waited AgingInterval, high goes first
+	if job, ok := w.dequeue(); !ok || job.Priority != PriorityHigh {
+		t.Fatalf("first dequeue: got %+v, %v; want a high priority job", job, ok)
+	}
+
+	time.Sleep(config.AgingInterval)
+	job, ok := w.dequeue()
+	if !ok || job.Path != "/old" {
+		t.Fatalf("after AgingInterval: got %+v, %v; want the low priority job", job, ok)
+	}
+	stats := w.GetStats()
+	if stats.Aged != 1 {
+		t.Errorf("Aged = %d, want 1", stats.Aged)
+	}
+	if stats.MaxLowWait < config.AgingInterval {
+		t.Errorf("MaxLowWait = %v, want at least %v", stats.MaxLowWait, config.AgingInterval)
+	}
+}
+
+func TestDequeueRotation(t *testing.T) {
+	// aging never kicks in, so only the weighted rotation serves low
+	config := DefaultWorkerConfig()
+	config.AgingInterval = time.Hour
+	w := newTestWorker(t, nil, config)
+
+	total := 0
+	for _, weight := range queueWeights {
+		total += weight
+	}
+	w.push(IndexJob{Path: "/old", Priority: PriorityLow})
+	for i := 0; i < total; i++ {
+		// high and normal traffic never lets up
+		fill(t, w, PriorityHigh, 1)
+		fill(t, w, PriorityNormal, 1)
+		job, ok := w.dequeue()
+		if !ok {
+			t.Fatal("nothing dequeued with every queue holding work")
+		}
+		if job.Path == "/old" {
+			if stats := w.GetStats(); stats.PassedOver != int64(i) {
+				t.Errorf("PassedOver = %d, want %d", stats.PassedOver, i)
+			}
+			return
+		}
+	}
+	t.Fatalf("low priority job not dequeued within %d dequeues", total)
+}
+
+// failingStore fails every write of a file, as a disk that is full would
+type failingStore struct {
+	*IndexStore
+}
+
+func (s failingStore) UpsertFile(*IndexedFile) (int64, error) {
+	return 0, errFailingStore
+}
+
+var errFailingStore = errors.New("disk full")
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, retryBaseDelay},
+		{2, 2 * retryBaseDelay},
+		{3, 4 * retryBaseDelay},
+		{7, 64 * retryBaseDelay},
+		{8, retryMaxDelay},
+		{100, retryMaxDelay},
+	}
+	for _, tt := range tests {
+		if got := retryDelay(tt.attempts); got != tt.want {
+			t.Errorf("retryDelay(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestRetryLimit(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(path, []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewIndexStore(filepath.Join(t.TempDir(), "index.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	config := DefaultWorkerConfig()
+	config.MaxRetryAttempts = 3
+	w := newTestWorker(t, failingStore{store}, config)
+
+	w.processJob(IndexJob{Path: path, Priority: PriorityNormal})
+	now := time.Now()
+
+	// the first retry waits retryBaseDelay, and the wait doubles from the
+	// second failed retry on
+	waits := []time.Duration{retryBaseDelay, retryBaseDelay, 2 * retryBaseDelay}
+	for i, delay := range waits {
+		attempt := i + 1
+		if n := w.retryDue(now.Add(delay - time.Second)); n != 0 {
+			t.Fatalf("attempt %d: %d files retried before their backoff", attempt, n)
+		}
+		now = now.Add(delay + time.Second)
+		if n := w.retryDue(now); n != 1 {
+			t.Fatalf("attempt %d: %d files retried, want 1", attempt, n)
+		}
+		job, ok := w.dequeue()
+		if !ok || job.Path != path || job.Priority != PriorityLow {
+			t.Fatalf("attempt %d: dequeued %+v, %v", attempt, job, ok)
+		}
+		w.processJob(job)
+	}
+
+	// a file that keeps failing is not queued again once out of attempts
+	for _, later := range []time.Duration{retryMaxDelay, 24 * time.Hour, 365 * 24 * time.Hour} {
+		if n := w.retryDue(now.Add(later)); n != 0 {
+			t.Fatalf("%d files retried past MaxRetryAttempts", n)
+		}
+	}
+	if _, ok := w.dequeue(); ok {
+		t.Error("a job was queued past MaxRetryAttempts")
+	}
+
+	entries, _, err := store.GetRetries(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Attempts != config.MaxRetryAttempts || entries[0].Reason != RetryFailed {
+		t.Errorf("retry list: got %+v", entries)
+	}
+	stats := w.GetStats()
+	if stats.Retried != int64(config.MaxRetryAttempts) || stats.Failed != int64(config.MaxRetryAttempts+1) {
+		t.Errorf("stats: Retried = %d, Failed = %d", stats.Retried, stats.Failed)
+	}
+}
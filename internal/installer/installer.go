@@ -0,0 +1,218 @@
+package installer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// ServerName is the key used for the May-la entry in client configs
+const ServerName = "may-la"
+
+// Binaries lists the executables that make up an installation
+var Binaries = []string{"mayla", "mayla-daemon"}
+
+// DefaultDir returns ~/.mayla
+func DefaultDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".mayla"), nil
+}
+
+func BinaryName(binary string) string {
+	if runtime.GOOS == "windows" {
+		return binary + ".exe"
+	}
+	return binary
+}
+
+// InstallBinaries copies the binaries found in srcDir into dstDir, replacing
+// existing files atomically. It returns the installed paths.
+func InstallBinaries(srcDir, dstDir string) ([]string, error) {
+	if err := os.MkdirAll(dstDir, 0700); err != nil {
+		return nil, fmt.Errorf("create install dir: %w", err)
+	}
+
+	var installed []string
+	for _, binary := range Binaries {
+		name := BinaryName(binary)
+		src := filepath.Join(srcDir, name)
+		dst := filepath.Join(dstDir, name)
+
+		// mayla embeds the daemon, so a release that ships only mayla
+		// still yields a working mayla-daemon.
+		if _, err := os.Stat(src); os.IsNotExist(err) && binary != "mayla" {
+			src = filepath.Join(srcDir, BinaryName("mayla"))
+		}
+
+		if sameFile(src, dst) {
+			installed = append(installed, dst)
+			continue
+		}
+
+		if err := copyExecutable(src, dst); err != nil {
+			return installed, fmt.Errorf("install %s: %w", name, err)
+		}
+		installed = append(installed, dst)
+	}
+
+	return installed, nil
+}
+
+// ExtractDaemon installs the mayla binary at execPath as the daemon binary
+// dst. mayla runs as the daemon when invoked under that name.
+func ExtractDaemon(execPath, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return fmt.Errorf("create install dir: %w", err)
+	}
+	if err := copyExecutable(execPath, dst); err != nil {
+		return fmt.Errorf("install %s: %w", filepath.Base(dst), err)
+	}
+	return nil
+}
+
+func sameFile(a, b string) bool {
+	ai, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bi, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(ai, bi)
+}
+
+func copyExecutable(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".mayla-install-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, in); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return err
+	}
+
+	if runtime.GOOS == "windows" {
+		old := dst + ".old"
+		os.Remove(old)
+		if err := os.Rename(dst, old); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+// Client describes an MCP client whose JSON config holds an "mcpServers" map
+type Client struct {
+	Name       string
+	ConfigPath string
+}
+
+// KnownClients returns the MCP clients May-la knows how to configure on this
+// platform.
+func KnownClients() []Client {
+	home, _ := os.UserHomeDir()
+	configDir, _ := os.UserConfigDir()
+
+	clients := []Client{
+		{Name: "claude-desktop", ConfigPath: filepath.Join(configDir, "Claude", "claude_desktop_config.json")},
+		{Name: "cursor", ConfigPath: filepath.Join(home, ".cursor", "mcp.json")},
+		{Name: "windsurf", ConfigPath: filepath.Join(home, ".codeium", "windsurf", "mcp_config.json")},
+		{Name: "gemini", ConfigPath: filepath.Join(home, ".gemini", "settings.json")},
+	}
+	return clients
+}
+
+// Detected reports whether the client appears to be installed, i.e. its
+// config directory exists.
+func (c Client) Detected() bool {
+	_, err := os.Stat(filepath.Dir(c.ConfigPath))
+	return err == nil
+}
+
+// Configure writes or updates the May-la entry in the client's config and
+// returns whether the file changed. Other settings are preserved and the
+// previous file is kept as <config>.bak.
+func (c Client) Configure(command string, dryRun bool) (bool, error) {
+	doc := map[string]interface{}{}
+
+	original, err := os.ReadFile(c.ConfigPath)
+	if err != nil && !os.IsNotExist(err) {
+		return false, fmt.Errorf("read %s: %w", c.ConfigPath, err)
+	}
+	if len(bytes.TrimSpace(original)) > 0 {
+		if err := json.Unmarshal(original, &doc); err != nil {
+			return false, fmt.Errorf("parse %s: %w", c.ConfigPath, err)
+		}
+	}
+
+	servers, _ := doc["mcpServers"].(map[string]interface{})
+	if servers == nil {
+		servers = map[string]interface{}{}
+	}
+
+	entry, _ := servers[ServerName].(map[string]interface{})
+	if entry == nil {
+		entry = map[string]interface{}{}
+	}
+	if existing, _ := entry["command"].(string); existing == command {
+		return false, nil
+	}
+	entry["command"] = command
+	if _, ok := entry["args"]; !ok {
+		entry["args"] = []string{}
+	}
+	servers[ServerName] = entry
+	doc["mcpServers"] = servers
+
+	if dryRun {
+		return true, nil
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return false, err
+	}
+	data = append(data, '\n')
+
+	if err := os.MkdirAll(filepath.Dir(c.ConfigPath), 0755); err != nil {
+		return false, err
+	}
+	if len(original) > 0 {
+		if err := os.WriteFile(c.ConfigPath+".bak", original, 0600); err != nil {
+			return false, fmt.Errorf("backup %s: %w", c.ConfigPath, err)
+		}
+	}
+
+	tmp := c.ConfigPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return false, err
+	}
+	if err := os.Rename(tmp, c.ConfigPath); err != nil {
+		os.Remove(tmp)
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,150 @@
+package installer
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigure(t *testing.T) {
+	const command = "/home/user/.mayla/mayla"
+
+	tests := []struct {
+		name     string
+		existing string // "" means the config does not exist
+		wantErr  bool
+		servers  []string // entries expected in mcpServers afterwards
+		theme    string   // other setting expected to survive
+	}{
+		{
+			name:    "missing config",
+			servers: []string{ServerName},
+		},
+		{
+			name:     "keeps other servers",
+			existing: `{"theme": "dark", "mcpServers": {"github": {"command": "gh-mcp", "args": ["--stdio"]}}}`,
+			servers:  []string{"github", ServerName},
+			theme:    "dark",
+		},
+		{
+			name:     "updates a stale entry",
+			existing: `{"mcpServers": {"` + ServerName + `": {"command": "/old/mayla", "args": ["--verbose"]}}}`,
+			servers:  []string{ServerName},
+		},
+		{
+			name:     "malformed JSON",
+			existing: `{"mcpServers": {"github": `,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := Client{Name: "test", ConfigPath: filepath.Join(t.TempDir(), "config", "mcp.json")}
+			if tt.existing != "" {
+				os.MkdirAll(filepath.Dir(client.ConfigPath), 0755)
+				if err := os.WriteFile(client.ConfigPath, []byte(tt.existing), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			changed, err := client.Configure(command, false)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Expected an error")
+				}
+				if content, _ := os.ReadFile(client.ConfigPath); string(content) != tt.existing {
+					t.Errorf("Expected the config to be left alone, got %q", content)
+				}
+				if _, err := os.Stat(client.ConfigPath + ".bak"); !os.IsNotExist(err) {
+					t.Error("Expected no backup of a config that was not rewritten")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !changed {
+				t.Error("Expected the config to change")
+			}
+
+			written, err := os.ReadFile(client.ConfigPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var doc struct {
+				Theme      string                            `json:"theme"`
+				MCPServers map[string]map[string]interface{} `json:"mcpServers"`
+			}
+			if err := json.Unmarshal(written, &doc); err != nil {
+				t.Fatalf("Expected valid JSON, got %v", err)
+			}
+			if len(doc.MCPServers) != len(tt.servers) {
+				t.Errorf("Expected servers %v, got %v", tt.servers, doc.MCPServers)
+			}
+			for _, name := range tt.servers {
+				if doc.MCPServers[name] == nil {
+					t.Errorf("Expected server %s to be kept", name)
+				}
+			}
+			if doc.Theme != tt.theme {
+				t.Errorf("Expected theme %q to be kept, got %q", tt.theme, doc.Theme)
+			}
+			if got := doc.MCPServers[ServerName]["command"]; got != command {
+				t.Errorf("Expected command %s, got %v", command, got)
+			}
+			if tt.existing != "" {
+				if backup, _ := os.ReadFile(client.ConfigPath + ".bak"); string(backup) != tt.existing {
+					t.Errorf("Expected the previous config as backup, got %q", backup)
+				}
+			}
+
+			changed, err = client.Configure(command, false)
+			if err != nil || changed {
+				t.Fatalf("Expected a re-run to change nothing, got changed=%v err=%v", changed, err)
+			}
+			if again, _ := os.ReadFile(client.ConfigPath); string(again) != string(written) {
+				t.Errorf("Expected a re-run to leave the config alone, got %q", again)
+			}
+		})
+	}
+}
+
+func TestConfigureKeepsEntrySettings(t *testing.T) {
+	client := Client{Name: "test", ConfigPath: filepath.Join(t.TempDir(), "mcp.json")}
+	existing := `{"mcpServers": {"` + ServerName + `": {"command": "/old/mayla", "args": ["--verbose"], "env": {"A": "1"}}}}`
+	os.WriteFile(client.ConfigPath, []byte(existing), 0600)
+
+	if _, err := client.Configure("/new/mayla", false); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var doc map[string]map[string]map[string]interface{}
+	content, _ := os.ReadFile(client.ConfigPath)
+	if err := json.Unmarshal(content, &doc); err != nil {
+		t.Fatal(err)
+	}
+	entry := doc["mcpServers"][ServerName]
+	if entry["command"] != "/new/mayla" {
+		t.Errorf("Expected the command to be updated, got %v", entry["command"])
+	}
+	if args, _ := entry["args"].([]interface{}); len(args) != 1 || args[0] != "--verbose" {
+		t.Errorf("Expected args to be kept, got %v", entry["args"])
+	}
+	if entry["env"] == nil {
+		t.Error("Expected env to be kept")
+	}
+}
+
+func TestConfigureDryRun(t *testing.T) {
+	client := Client{Name: "test", ConfigPath: filepath.Join(t.TempDir(), "mcp.json")}
+
+	changed, err := client.Configure("/new/mayla", true)
+	if err != nil || !changed {
+		t.Fatalf("Expected a dry run to report a change, got changed=%v err=%v", changed, err)
+	}
+	if _, err := os.Stat(client.ConfigPath); !os.IsNotExist(err) {
+		t.Error("Expected a dry run not to write the config")
+	}
+}
@@ -0,0 +1,228 @@
+package intel
+
+import (
+	"regexp"
+	"strings"
+)
+
+// maxDocLine caps the one-line docs returned by DocLine
+const maxDocLine = 120
+
+var (
+	goImportLine   = regexp.MustCompile(`^\s*import\s+(?:[A-Za-z_.]+\s+)?"([^"]+)"`)
+	goImportBlock  = regexp.MustCompile(`^\s*import\s*\(\s*$`)
+	goImportSpec   = regexp.MustCompile(`^\s*(?:[A-Za-z_.]+\s+)?"([^"]+)"`)
+	jsImport       = regexp.MustCompile(`^\s*(?:import|export)\s.*?\bfrom\s+["']([^"']+)["']`)
+	jsBareImport   = regexp.MustCompile(`^\s*import\s+["']([^"']+)["']`)
+	jsRequire      = regexp.MustCompile(`\brequire\(\s*["']([^"']+)["']\s*\)`)
+	pyImport       = regexp.MustCompile(`^import\s+([A-Za-z0-9_.]+(?:\s*,\s*[A-Za-z0-9_.]+)*)`)
+	pyFromImport   = regexp.MustCompile(`^from\s+([A-Za-z0-9_.]+)\s+import\b`)
+	rustUse        = regexp.MustCompile(`^\s*(?:pub\s+)?use\s+([^;{]+)`)
+	javaImport     = regexp.MustCompile(`^\s*import\s+(?:static\s+)?([A-Za-z0-9_.*]+)\s*;?`)
+	cInclude       = regexp.MustCompile(`^\s*#\s*include\s+[<"]([^>"]+)[>"]`)
+	todoMarker     = regexp.MustCompile(`\b(TODO|FIXME|XXX|HACK)\b`)
+	commentLine    = regexp.MustCompile(`^\s*(//+!?|/\*+|\*+/?|#+)\s?`)
+	annotationLine = regexp.MustCompile(`^\s*(@\w|#\[)`)
+	docstringStart = regexp.MustCompile(`^\s*[rbuRBU]?("""|''')`)
+)
+
+// ExtractImports returns the modules, packages or headers content imports,
+// in source order and without duplicates
+func ExtractImports(content, language string) []string {
+	var imports []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		name = strings.TrimSpace(name)
+		if name != "" && !seen[name] {
+			seen[name] = true
+			imports = append(imports, name)
+		}
+	}
+
+	inBlock := false
+	for _, line := range strings.Split(content, "\n") {
+		switch language {
+		case "go":
+			if inBlock {
+				if strings.HasPrefix(strings.TrimSpace(line), ")") {
+					inBlock = false
+				} else if m := goImportSpec.FindStringSubmatch(line); m != nil {
+					add(m[1])
+				}
+				continue
+			}
+			if goImportBlock.MatchString(line) {
+				inBlock = true
+			} else if m := goImportLine.FindStringSubmatch(line); m != nil {
+				add(m[1])
+			}
+		case "typescript", "javascript":
+			if m := jsImport.FindStringSubmatch(line); m != nil {
+				add(m[1])
+			} else if m := jsBareImport.FindStringSubmatch(line); m != nil {
+				add(m[1])
+			}
+			for _, m := range jsRequire.FindAllStringSubmatch(line, -1) {
+				add(m[1])
+			}
+		case "python":
+			if m := pyFromImport.FindStringSubmatch(line); m != nil {
+				add(m[1])
+			} else if m := pyImport.FindStringSubmatch(line); m != nil {
+				for _, name := range strings.Split(m[1], ",") {
+					add(name)
+				}
+			}
+		case "rust":
+			if m := rustUse.FindStringSubmatch(line); m != nil {
+				add(strings.TrimSuffix(strings.TrimSpace(m[1]), "::"))
+			}
+		case "java", "kotlin", "scala":
+			if m := javaImport.FindStringSubmatch(line); m != nil {
+				add(m[1])
+			}
+		case "c", "cpp":
+			if m := cInclude.FindStringSubmatch(line); m != nil {
+				add(m[1])
+			}
+		}
+	}
+
+	return imports
+}
+
+// DocLine returns the first line of the comment documenting the declaration
+// on line (1-based): for Python the docstring right below it, otherwise the
+// comment block right above it, skipping annotations and attributes
+func DocLine(lines []string, line int) string {
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+
+	if strings.HasSuffix(strings.TrimSpace(lines[line-1]), ":") {
+		if doc := docstring(lines[line:]); doc != "" {
+			return doc
+		}
+	}
+
+	for _, text := range commentBlock(lines, line) {
+		if doc := cleanDocLine(commentLine.ReplaceAllString(text, "")); doc != "" {
+			return doc
+		}
+	}
+
+	return ""
+}
+
+// DocComment returns the whole comment documenting the declaration on line
+// (1-based), found as DocLine finds it, without comment markers
+func DocComment(lines []string, line int) string {
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+
+	var text []string
+	if strings.HasSuffix(strings.TrimSpace(lines[line-1]), ":") {
+		text = docstringLines(lines[line:])
+	}
+	if len(text) == 0 {
+		for _, comment := range commentBlock(lines, line) {
+			text = append(text, strings.TrimSpace(strings.TrimSuffix(commentLine.ReplaceAllString(comment, ""), "*/")))
+		}
+	}
+
+	for len(text) > 0 && text[0] == "" {
+		text = text[1:]
+	}
+	for len(text) > 0 && text[len(text)-1] == "" {
+		text = text[:len(text)-1]
+	}
+	return strings.Join(text, "\n")
+}
+
+// commentBlock returns the comment lines right above the declaration on
+// line, skipping annotations and attributes in between
+func commentBlock(lines []string, line int) []string {
+	var block []string
+	for i := line - 2; i >= 0; i-- {
+		text := lines[i]
+		if annotationLine.MatchString(text) && len(block) == 0 {
+			continue
+		}
+		if !commentLine.MatchString(text) || strings.HasPrefix(strings.TrimSpace(text), "#include") {
+			break
+		}
+		block = append([]string{text}, block...)
+	}
+	return block
+}
+
+// docstring returns the first line of the Python docstring opening lines
+func docstring(lines []string) string {
+	for i, text := range lines {
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		m := docstringStart.FindStringSubmatch(text)
+		if m == nil {
+			return ""
+		}
+		first := strings.TrimSpace(text[strings.Index(text, m[1])+len(m[1]):])
+		if first == "" && i+1 < len(lines) {
+			first = strings.TrimSpace(lines[i+1])
+		}
+		return cleanDocLine(strings.TrimSuffix(first, m[1]))
+	}
+	return ""
+}
+
+// docstringLines returns the lines of the Python docstring opening lines,
+// quotes and indentation removed
+func docstringLines(lines []string) []string {
+	for i, text := range lines {
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		m := docstringStart.FindStringSubmatch(text)
+		if m == nil {
+			return nil
+		}
+		rest := text[strings.Index(text, m[1])+len(m[1]):]
+		if end := strings.Index(rest, m[1]); end >= 0 {
+			return []string{strings.TrimSpace(rest[:end])}
+		}
+		doc := []string{strings.TrimSpace(rest)}
+		for _, next := range lines[i+1:] {
+			if end := strings.Index(next, m[1]); end >= 0 {
+				return append(doc, strings.TrimSpace(next[:end]))
+			}
+			doc = append(doc, strings.TrimSpace(next))
+		}
+		return doc
+	}
+	return nil
+}
+
+// cleanDocLine trims a comment line to its first sentence, capped at
+// maxDocLine characters
+func cleanDocLine(text string) string {
+	text = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), "*/"))
+	if i := strings.Index(text, ". "); i >= 0 {
+		text = text[:i+1]
+	}
+	if runes := []rune(text); len(runes) > maxDocLine {
+		text = string(runes[:maxDocLine-3]) + "..."
+	}
+	return text
+}
+
+// CountTODOs counts the TODO, FIXME, XXX and HACK markers in content
+func CountTODOs(content string) int {
+	return len(todoMarker.FindAllStringIndex(content, -1))
+}
+
+// EstimateTokens approximates how many LLM tokens text takes, at about
+// four characters per token
+func EstimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
@@ -14,6 +14,17 @@ const (
 	ResponseModeRaw      ResponseMode = "RAW"
 )
 
+// ParseResponseMode parses a response mode as tools take it: compact,
+// detailed or raw, in any case
+func ParseResponseMode(s string) (ResponseMode, error) {
+	mode := ResponseMode(strings.ToUpper(s))
+	switch mode {
+	case ResponseModeCompact, ResponseModeDetailed, ResponseModeRaw:
+		return mode, nil
+	}
+	return "", fmt.Errorf("invalid response_mode %q: expected compact, detailed or raw", s)
+}
+
 type ResponseFormatter struct {
 	Mode           ResponseMode
 	MaxLength      int
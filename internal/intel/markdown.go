@@ -0,0 +1,54 @@
+package intel
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	mdFence      = regexp.MustCompile("(?s)```[^\\n]*\\n(.*?)\\n?```")
+	mdImage      = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	mdLink       = regexp.MustCompile(`\[([^\]]+)\]\([^)]*\)`)
+	mdInlineCode = regexp.MustCompile("`([^`]+)`")
+	mdEmphasis   = regexp.MustCompile(`(\*\*|\*)([^*\n]+)(\*\*|\*)`)
+	// Underscores only mark emphasis outside words, so snake_case survives
+	mdUnderscore = regexp.MustCompile(`(^|\W)(__|_)([^_\n]+)(__|_)(\W|$)`)
+	mdHeading    = regexp.MustCompile(`(?m)^#{1,6}\s+`)
+	mdRule       = regexp.MustCompile(`(?m)^\s*(-{3,}|\*{3,}|_{3,})\s*$`)
+	mdEscape     = regexp.MustCompile(`\\([\\` + "`" + `*_{}\[\]()#+\-.!|])`)
+	blankLines   = regexp.MustCompile(`\n{3,}`)
+)
+
+// CodeBlocks returns the contents of the fenced code blocks in markdown
+func CodeBlocks(markdown string) []string {
+	var blocks []string
+	for _, m := range mdFence.FindAllStringSubmatch(markdown, -1) {
+		if block := strings.TrimSpace(m[1]); block != "" {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
+
+// StripMarkdown turns markdown into plain text: fences, headings, rules,
+// emphasis and escapes are dropped, links keep their text and code keeps
+// its content
+func StripMarkdown(markdown string) string {
+	text := mdFence.ReplaceAllString(markdown, "$1")
+	text = mdImage.ReplaceAllString(text, "$1")
+	text = mdLink.ReplaceAllString(text, "$1")
+	text = mdInlineCode.ReplaceAllString(text, "$1")
+	text = mdEmphasis.ReplaceAllString(text, "$2")
+	text = mdUnderscore.ReplaceAllString(text, "$1$3$5")
+	text = mdHeading.ReplaceAllString(text, "")
+	text = mdRule.ReplaceAllString(text, "")
+	text = mdEscape.ReplaceAllString(text, "$1")
+	text = blankLines.ReplaceAllString(text, "\n\n")
+	return strings.TrimSpace(text)
+}
+
+// StripCodeBlocks removes the fenced code blocks from markdown and strips
+// what is left, giving the prose around them
+func StripCodeBlocks(markdown string) string {
+	return StripMarkdown(mdFence.ReplaceAllString(markdown, ""))
+}
@@ -0,0 +1,103 @@
+package intel
+
+import (
+	"fmt"
+	"time"
+)
+
+// maxOmittedIDs is how many of the best omitted snippets are named in the
+// omission summary
+const maxOmittedIDs = 5
+
+// Snippet is a candidate for a packed context: a search match, a symbol,
+// a memory or anything else with text and a relevance score
+type Snippet struct {
+	ID        string    `json:"id"`
+	Source    string    `json:"source,omitempty"`
+	Content   string    `json:"content"`
+	Relevance float64   `json:"relevance"`
+	Timestamp time.Time `json:"-"`
+	Frequency int       `json:"-"`
+	Proximity float64   `json:"-"`
+	Tokens    int       `json:"tokens"`
+}
+
+func (s *Snippet) GetID() string           { return s.ID }
+func (s *Snippet) GetRelevance() float64   { return s.Relevance }
+func (s *Snippet) GetTimestamp() time.Time { return s.Timestamp }
+func (s *Snippet) GetFrequency() int       { return s.Frequency }
+func (s *Snippet) GetProximity() float64   { return s.Proximity }
+
+// Omissions summarizes the snippets left out of a packed context
+type Omissions struct {
+	Count      int            `json:"count"`
+	Tokens     int            `json:"tokens"`
+	Duplicates int            `json:"duplicates,omitempty"`
+	BySource   map[string]int `json:"by_source,omitempty"`
+	TopIDs     []string       `json:"top_ids,omitempty"`
+}
+
+// PackedContext is the set of snippets chosen to fit a token budget, best
+// ranked first
+type PackedContext struct {
+	Snippets []Snippet `json:"snippets"`
+	Tokens   int       `json:"tokens"`
+	Budget   int       `json:"budget"`
+	Omitted  Omissions `json:"omitted"`
+}
+
+// PackContext ranks snippets with criteria (DefaultRankCriteria when zero)
+// and greedily keeps the best ones whose estimated tokens still fit in
+// maxTokens, skipping repeated content. A snippet too large for what is
+// left does not stop smaller ones further down from being packed. With
+// maxTokens <= 0 every distinct snippet is kept, in rank order.
+func PackContext(snippets []Snippet, maxTokens int, criteria RankCriteria) PackedContext {
+	if criteria == (RankCriteria{}) {
+		criteria = DefaultRankCriteria
+	}
+
+	// Rank matches results back by ID, so IDs have to be unique
+	candidates := make([]Rankable, len(snippets))
+	seenIDs := make(map[string]bool, len(snippets))
+	for i := range snippets {
+		snippet := snippets[i]
+		if snippet.ID == "" || seenIDs[snippet.ID] {
+			snippet.ID = fmt.Sprintf("%s#%d", snippet.ID, i)
+		}
+		seenIDs[snippet.ID] = true
+		snippet.Tokens = EstimateTokens(snippet.Content)
+		candidates[i] = &snippet
+	}
+
+	packed := PackedContext{Snippets: []Snippet{}, Budget: maxTokens}
+	seenContent := make(map[string]bool, len(snippets))
+	for _, item := range Rank(candidates, criteria) {
+		snippet := item.(*Snippet)
+
+		duplicate := seenContent[snippet.Content]
+		if !duplicate && (maxTokens <= 0 || packed.Tokens+snippet.Tokens <= maxTokens) {
+			seenContent[snippet.Content] = true
+			packed.Snippets = append(packed.Snippets, *snippet)
+			packed.Tokens += snippet.Tokens
+			continue
+		}
+
+		packed.Omitted.Count++
+		if duplicate {
+			packed.Omitted.Duplicates++
+			continue
+		}
+		packed.Omitted.Tokens += snippet.Tokens
+		if snippet.Source != "" {
+			if packed.Omitted.BySource == nil {
+				packed.Omitted.BySource = make(map[string]int)
+			}
+			packed.Omitted.BySource[snippet.Source]++
+		}
+		if len(packed.Omitted.TopIDs) < maxOmittedIDs {
+			packed.Omitted.TopIDs = append(packed.Omitted.TopIDs, snippet.ID)
+		}
+	}
+
+	return packed
+}
@@ -1,6 +1,7 @@
 package intel
 
 import (
+	"math"
 	"sort"
 	"time"
 )
@@ -36,11 +37,34 @@ var DefaultRankCriteria = RankCriteria{
 	ProximityWeight:  0.2,
 }
 
+// Rank orders items by their score under criteria, best first. Ties are
+// broken by relevance, then recency, then frequency, then ID and finally
+// input order, so the same items always rank the same way whatever order
+// they come in.
 func Rank(items []Rankable, criteria RankCriteria) []Rankable {
 	if len(items) == 0 {
 		return items
 	}
 
+	ranked := scoreItems(items, criteria)
+	result := make([]Rankable, len(ranked))
+	for i, entry := range ranked {
+		result[i] = entry.item
+	}
+
+	return result
+}
+
+// rankEntry keeps each normalized RankItem next to the item it came from
+type rankEntry struct {
+	RankItem
+	item  Rankable
+	index int
+}
+
+// scoreItems normalizes and scores items and sorts them best first
+func scoreItems(items []Rankable, criteria RankCriteria) []rankEntry {
+	entries := make([]rankEntry, len(items))
 	rankItems := make([]RankItem, len(items))
 	for i, item := range items {
 		rankItems[i] = RankItem{
@@ -54,65 +78,82 @@ func Rank(items []Rankable, criteria RankCriteria) []Rankable {
 
 	normalizeScores(rankItems)
 
+	now := time.Now()
 	for i := range rankItems {
-		rankItems[i].Score = calculateRankScore(rankItems[i], criteria)
+		rankItems[i].Score = calculateRankScore(rankItems[i], criteria, now)
+		entries[i] = rankEntry{RankItem: rankItems[i], item: items[i], index: i}
 	}
 
-	sort.Slice(rankItems, func(i, j int) bool {
-		return rankItems[i].Score > rankItems[j].Score
+	sort.Slice(entries, func(i, j int) bool {
+		return rankedBefore(entries[i], entries[j])
 	})
 
-	result := make([]Rankable, len(rankItems))
-	for i, rankItem := range rankItems {
-		for _, original := range items {
-			if original.GetID() == rankItem.ID {
-				result[i] = original
-				break
-			}
-		}
-	}
+	return entries
+}
 
-	return result
+func rankedBefore(a, b rankEntry) bool {
+	switch {
+	case a.Score != b.Score:
+		return a.Score > b.Score
+	case a.Relevance != b.Relevance:
+		return a.Relevance > b.Relevance
+	case !a.Timestamp.Equal(b.Timestamp):
+		return a.Timestamp.After(b.Timestamp)
+	case a.Frequency != b.Frequency:
+		return a.Frequency > b.Frequency
+	case a.ID != b.ID:
+		return a.ID < b.ID
+	default:
+		return a.index < b.index
+	}
 }
 
-func calculateRankScore(item RankItem, criteria RankCriteria) float64 {
+func calculateRankScore(item RankItem, criteria RankCriteria, now time.Time) float64 {
 	score := 0.0
 
 	score += item.Relevance * criteria.RelevanceWeight
-	score += normalizeRecency(item.Timestamp) * criteria.RecencyWeight
+	score += normalizeRecency(item.Timestamp, now) * criteria.RecencyWeight
 	score += normalizeFrequency(item.Frequency) * criteria.FrequencyWeight
 	score += item.Proximity * criteria.ProximityWeight
 
 	return score
 }
 
+// normalizeScores scales relevance to [0, 1] against the most relevant item
+// and clamps proximity to [0, 1]. Negative or NaN relevance counts as 0.
 func normalizeScores(items []RankItem) {
 	if len(items) == 0 {
 		return
 	}
 
 	maxRelevance := 0.0
-	maxFrequency := 0
-
-	for _, item := range items {
-		if item.Relevance > maxRelevance {
-			maxRelevance = item.Relevance
+	for i := range items {
+		if math.IsNaN(items[i].Relevance) || items[i].Relevance < 0 {
+			items[i].Relevance = 0
 		}
-		if item.Frequency > maxFrequency {
-			maxFrequency = item.Frequency
+		if items[i].Relevance > maxRelevance {
+			maxRelevance = items[i].Relevance
 		}
 	}
 
 	for i := range items {
-		if maxRelevance > 0 {
+		if math.IsInf(maxRelevance, 1) {
+			items[i].Relevance = boolScore(math.IsInf(items[i].Relevance, 1))
+		} else if maxRelevance > 0 {
 			items[i].Relevance = items[i].Relevance / maxRelevance
 		}
 		items[i].Proximity = normalizeProximity(items[i].Proximity)
 	}
 }
 
-func normalizeRecency(timestamp time.Time) float64 {
-	now := time.Now()
+func boolScore(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func normalizeRecency(timestamp time.Time, now time.Time) float64 {
 	hoursSince := now.Sub(timestamp).Hours()
 
 	if hoursSince < 24 {
@@ -141,6 +182,8 @@ func normalizeFrequency(frequency int) float64 {
 		return 0.6
 	case frequency >= 5:
 		return 0.4
+	case frequency <= 0:
+		return 0
 	default:
 		return float64(frequency) / 5.0
 	}
@@ -150,7 +193,7 @@ func normalizeProximity(proximity float64) float64 {
 	if proximity > 1.0 {
 		proximity = 1.0
 	}
-	if proximity < 0 {
+	if proximity < 0 || math.IsNaN(proximity) {
 		proximity = 0
 	}
 	return proximity
@@ -201,35 +244,20 @@ func NewSimpleRankable(id string, relevance float64, timestamp time.Time, freque
 	}
 }
 
+// FilterByThreshold ranks items and keeps those scoring at least
+// scoreThreshold, using the same normalized scores Rank orders by
 func FilterByThreshold(items []Rankable, scoreThreshold float64, criteria RankCriteria) []Rankable {
-	ranked := Rank(items, criteria)
 	var filtered []Rankable
 
-	for _, item := range ranked {
-		score := calculateItemScore(item, criteria)
-		if score >= scoreThreshold {
-			filtered = append(filtered, item)
+	for _, entry := range scoreItems(items, criteria) {
+		if entry.Score >= scoreThreshold {
+			filtered = append(filtered, entry.item)
 		}
 	}
 
 	return filtered
 }
 
-func calculateItemScore(item Rankable, criteria RankCriteria) float64 {
-	relevance := item.GetRelevance()
-	recency := normalizeRecency(item.GetTimestamp())
-	frequency := normalizeFrequency(item.GetFrequency())
-	proximity := normalizeProximity(item.GetProximity())
-
-	score := 0.0
-	score += relevance * criteria.RelevanceWeight
-	score += recency * criteria.RecencyWeight
-	score += frequency * criteria.FrequencyWeight
-	score += proximity * criteria.ProximityWeight
-
-	return score
-}
-
 func TopN(items []Rankable, n int, criteria RankCriteria) []Rankable {
 	ranked := Rank(items, criteria)
 
@@ -270,13 +298,14 @@ func RankStrings(values []string) []string {
 		freq[v]++
 	}
 
+	now := time.Now()
 	items := make([]Rankable, 0, len(freq))
 	for value, count := range freq {
 		items = append(items, &StringRankable{
 			value:    value,
 			score:    1.0,
 			count:    count,
-			lastSeen: time.Now(),
+			lastSeen: now,
 		})
 	}
 
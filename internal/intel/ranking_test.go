@@ -0,0 +1,147 @@
+package intel
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+// randomRankables builds n items drawn from small value sets, so that many
+// of them tie on score
+func randomRankables(r *rand.Rand, n int) []Rankable {
+	base := time.Now()
+	items := make([]Rankable, n)
+	for i := range items {
+		items[i] = NewSimpleRankable(
+			fmt.Sprintf("item-%d", r.Intn(n)),
+			float64(r.Intn(4)),
+			base.Add(-time.Duration(r.Intn(3))*48*time.Hour),
+			r.Intn(3)*10,
+			float64(r.Intn(3))/2,
+		)
+	}
+	return items
+}
+
+func rankedIDs(items []Rankable) []string {
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = item.GetID()
+	}
+	return ids
+}
+
+func TestRankProperties(t *testing.T) {
+	property := func(seed int64, size uint8) bool {
+		r := rand.New(rand.NewSource(seed))
+		items := randomRankables(r, int(size)+1)
+		ranked := Rank(items, DefaultRankCriteria)
+
+		// Every item comes back exactly once, itself and not a same-ID twin
+		if len(ranked) != len(items) {
+			return false
+		}
+		remaining := make(map[Rankable]int)
+		for _, item := range items {
+			remaining[item]++
+		}
+		for _, item := range ranked {
+			if remaining[item] == 0 {
+				return false
+			}
+			remaining[item]--
+		}
+
+		// Scores never increase down the list
+		entries := scoreItems(items, DefaultRankCriteria)
+		for i := 1; i < len(entries); i++ {
+			if entries[i].Score > entries[i-1].Score {
+				return false
+			}
+		}
+
+		// Shuffling the input does not change the order of distinct items
+		shuffled := append([]Rankable(nil), items...)
+		r.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+		want, got := rankedIDs(ranked), rankedIDs(Rank(shuffled, DefaultRankCriteria))
+		for i := range want {
+			if want[i] != got[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	if err := quick.Check(property, &quick.Config{MaxCount: 300}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRankTieBreaks(t *testing.T) {
+	now := time.Now()
+	items := []Rankable{
+		NewSimpleRankable("b", 1, now, 1, 0),
+		NewSimpleRankable("a", 1, now, 1, 0),
+		NewSimpleRankable("c", 1, now.Add(time.Minute), 1, 0),
+	}
+
+	// Equal scores: the newer item first, then by ID
+	got := rankedIDs(Rank(items, RankCriteria{RelevanceWeight: 1}))
+	if fmt.Sprint(got) != "[c a b]" {
+		t.Errorf("expected [c a b], got %v", got)
+	}
+}
+
+func TestRankNormalization(t *testing.T) {
+	now := time.Now()
+	items := []Rankable{
+		NewSimpleRankable("negative", -5, now, -3, -1),
+		NewSimpleRankable("nan", math.NaN(), now, 0, math.NaN()),
+		NewSimpleRankable("best", 10, now, 0, 2),
+		NewSimpleRankable("half", 5, now, 0, 0.5),
+	}
+
+	for _, entry := range scoreItems(items, DefaultRankCriteria) {
+		if math.IsNaN(entry.Score) || entry.Score < 0 || entry.Score > 1 {
+			t.Errorf("%s: expected a score in [0, 1], got %v", entry.ID, entry.Score)
+		}
+	}
+
+	got := rankedIDs(Rank(items, DefaultRankCriteria))
+	if got[0] != "best" || got[1] != "half" {
+		t.Errorf("expected best then half, got %v", got)
+	}
+
+	// FilterByThreshold scores with the same normalized relevance as Rank
+	kept := FilterByThreshold(items, 0.5, RankCriteria{RelevanceWeight: 1})
+	if fmt.Sprint(rankedIDs(kept)) != "[best half]" {
+		t.Errorf("expected best and half over the threshold, got %v", rankedIDs(kept))
+	}
+}
+
+func TestRankStringsDeterministic(t *testing.T) {
+	values := []string{"go", "rust", "go", "zig", "c", "rust", "go"}
+	want := fmt.Sprint(RankStrings(values))
+	for i := 0; i < 20; i++ {
+		if got := fmt.Sprint(RankStrings(values)); got != want {
+			t.Fatalf("expected %s on every run, got %s", want, got)
+		}
+	}
+	if want != "[go rust c zig]" {
+		t.Errorf("expected [go rust c zig], got %s", want)
+	}
+}
+
+func benchmarkRank(b *testing.B, n int) {
+	items := randomRankables(rand.New(rand.NewSource(1)), n)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Rank(items, DefaultRankCriteria)
+	}
+}
+
+func BenchmarkRank10k(b *testing.B)  { benchmarkRank(b, 10_000) }
+func BenchmarkRank100k(b *testing.B) { benchmarkRank(b, 100_000) }
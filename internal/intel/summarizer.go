@@ -0,0 +1,306 @@
+package intel
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/logger"
+)
+
+var log = logger.ForComponent("intel")
+
+const (
+	ProviderOllama = "ollama"
+	ProviderOpenAI = "openai"
+
+	SummarySourceHeuristic = "heuristic"
+	SummarySourceLLM       = "llm"
+
+	defaultOllamaEndpoint = "http://localhost:11434"
+	defaultOllamaModel    = "llama3.2"
+	defaultOpenAIEndpoint = "https://api.openai.com/v1"
+	defaultOpenAIModel    = "gpt-4o-mini"
+
+	// maxSummarizerResponse caps how much of an LLM reply is read
+	maxSummarizerResponse = 1 << 20
+)
+
+// Summarizer condenses content to about maxLen characters
+type Summarizer interface {
+	Summarize(ctx context.Context, content string, maxLen int) (string, error)
+}
+
+// SummarizerConfig selects an external LLM to summarize with. It is only
+// used when Enabled; otherwise the heuristic Summarize is used.
+type SummarizerConfig struct {
+	Enabled   bool          `yaml:"enabled"`
+	Provider  string        `yaml:"provider"`
+	Endpoint  string        `yaml:"endpoint"`
+	Model     string        `yaml:"model"`
+	APIKey    string        `yaml:"-"`
+	Timeout   time.Duration `yaml:"timeout"`
+	CacheSize int           `yaml:"cache_size"`
+}
+
+func DefaultSummarizerConfig() SummarizerConfig {
+	return SummarizerConfig{
+		Provider:  ProviderOllama,
+		Timeout:   30 * time.Second,
+		CacheSize: 256,
+	}
+}
+
+// HeuristicSummarizer is the extractive Summarize behind the Summarizer
+// interface
+type HeuristicSummarizer struct{}
+
+func (HeuristicSummarizer) Summarize(ctx context.Context, content string, maxLen int) (string, error) {
+	return Summarize(content, maxLen), nil
+}
+
+var (
+	summarizerMu sync.RWMutex
+	summarizer   Summarizer
+)
+
+// SetSummarizer installs the summarizer SummarizeContent tries first; nil
+// goes back to the heuristic one
+func SetSummarizer(s Summarizer) {
+	summarizerMu.Lock()
+	defer summarizerMu.Unlock()
+	summarizer = s
+}
+
+// SummarizeContent summarizes content with the installed summarizer,
+// falling back to the heuristic one when none is installed or it fails.
+// It also reports which of the two produced the summary.
+func SummarizeContent(ctx context.Context, content string, maxLen int) (string, string) {
+	summarizerMu.RLock()
+	s := summarizer
+	summarizerMu.RUnlock()
+
+	if s != nil && len(content) > maxLen {
+		summary, err := s.Summarize(ctx, content, maxLen)
+		if err == nil && summary != "" {
+			return summary, SummarySourceLLM
+		}
+		if err != nil {
+			log.Warn("summarizer failed, using heuristic", "error", err)
+		}
+	}
+	return Summarize(content, maxLen), SummarySourceHeuristic
+}
+
+// NewSummarizer returns the LLM summarizer described by cfg, or nil when it
+// is not enabled
+func NewSummarizer(cfg SummarizerConfig) (Summarizer, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	s, err := NewLLMSummarizer(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// LLMSummarizer asks an Ollama or OpenAI-compatible endpoint for summaries
+// and caches them by content
+type LLMSummarizer struct {
+	config SummarizerConfig
+	client *http.Client
+
+	mu    sync.Mutex
+	cache map[string]string
+	order []string
+}
+
+func NewLLMSummarizer(cfg SummarizerConfig) (*LLMSummarizer, error) {
+	switch cfg.Provider {
+	case "", ProviderOllama:
+		cfg.Provider = ProviderOllama
+		if cfg.Endpoint == "" {
+			cfg.Endpoint = defaultOllamaEndpoint
+		}
+		if cfg.Model == "" {
+			cfg.Model = defaultOllamaModel
+		}
+	case ProviderOpenAI:
+		if cfg.Endpoint == "" {
+			cfg.Endpoint = defaultOpenAIEndpoint
+		}
+		if cfg.Model == "" {
+			cfg.Model = defaultOpenAIModel
+		}
+	default:
+		return nil, fmt.Errorf("unknown summarizer provider %q (want %s or %s)", cfg.Provider, ProviderOllama, ProviderOpenAI)
+	}
+	cfg.Endpoint = strings.TrimRight(cfg.Endpoint, "/")
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultSummarizerConfig().Timeout
+	}
+
+	return &LLMSummarizer{
+		config: cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		cache:  make(map[string]string),
+	}, nil
+}
+
+func (s *LLMSummarizer) Summarize(ctx context.Context, content string, maxLen int) (string, error) {
+	if len(content) <= maxLen {
+		return content, nil
+	}
+
+	key := summaryKey(content, maxLen)
+	if summary, ok := s.cached(key); ok {
+		return summary, nil
+	}
+
+	prompt := fmt.Sprintf("Summarize the following content in at most %d characters. "+
+		"Keep names of files, functions and types exactly as written. "+
+		"Reply with the summary only.\n\n%s", maxLen, content)
+
+	var summary string
+	var err error
+	if s.config.Provider == ProviderOpenAI {
+		summary, err = s.chatCompletion(ctx, prompt, maxLen)
+	} else {
+		summary, err = s.generate(ctx, prompt, maxLen)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	summary = strings.TrimSpace(summary)
+	if summary == "" {
+		return "", fmt.Errorf("%s returned an empty summary", s.config.Provider)
+	}
+	if len(summary) > maxLen {
+		summary = Truncate(summary, maxLen, TruncateModeSmart)
+	}
+
+	s.remember(key, summary)
+	return summary, nil
+}
+
+// generate calls Ollama's /api/generate
+func (s *LLMSummarizer) generate(ctx context.Context, prompt string, maxLen int) (string, error) {
+	body := map[string]interface{}{
+		"model":  s.config.Model,
+		"prompt": prompt,
+		"stream": false,
+		"options": map[string]interface{}{
+			"num_predict": summaryTokens(maxLen),
+		},
+	}
+
+	var reply struct {
+		Response string `json:"response"`
+	}
+	if err := s.post(ctx, s.config.Endpoint+"/api/generate", body, &reply); err != nil {
+		return "", err
+	}
+	return reply.Response, nil
+}
+
+// chatCompletion calls an OpenAI-compatible /chat/completions
+func (s *LLMSummarizer) chatCompletion(ctx context.Context, prompt string, maxLen int) (string, error) {
+	body := map[string]interface{}{
+		"model": s.config.Model,
+		"messages": []map[string]string{
+			{"role": "user", "content": prompt},
+		},
+		"max_tokens": summaryTokens(maxLen),
+	}
+
+	var reply struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := s.post(ctx, s.config.Endpoint+"/chat/completions", body, &reply); err != nil {
+		return "", err
+	}
+	if len(reply.Choices) == 0 {
+		return "", fmt.Errorf("%s returned no choices", s.config.Provider)
+	}
+	return reply.Choices[0].Message.Content, nil
+}
+
+func (s *LLMSummarizer) post(ctx context.Context, url string, body interface{}, reply interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.config.APIKey)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("summarize: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("summarize: unexpected status %s", resp.Status)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxSummarizerResponse)).Decode(reply); err != nil {
+		return fmt.Errorf("decode summary: %w", err)
+	}
+	return nil
+}
+
+func (s *LLMSummarizer) cached(key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	summary, ok := s.cache[key]
+	return summary, ok
+}
+
+// remember caches summary, evicting the oldest entries past CacheSize
+func (s *LLMSummarizer) remember(key, summary string) {
+	if s.config.CacheSize <= 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.cache[key]; !ok {
+		s.order = append(s.order, key)
+	}
+	s.cache[key] = summary
+	for len(s.order) > s.config.CacheSize {
+		delete(s.cache, s.order[0])
+		s.order = s.order[1:]
+	}
+}
+
+func summaryKey(content string, maxLen int) string {
+	sum := sha256.Sum256([]byte(content))
+	return fmt.Sprintf("%s:%d", hex.EncodeToString(sum[:]), maxLen)
+}
+
+// summaryTokens is the reply length asked of the model for maxLen
+// characters, with some slack since the reply is cut to maxLen anyway
+func summaryTokens(maxLen int) int {
+	return (maxLen+3)/4 + 32
+}
@@ -118,10 +118,16 @@ func truncateSmart(content string, maxLen int) string {
 	result := strings.Builder{}
 	omittedLines := 0
 
-	for _, line := range lines {
+	for i, line := range lines {
 		if result.Len()+len(line)+1 > maxLen {
-			omittedLines++
-			continue
+			omittedLines = len(lines) - i
+			if i == 0 {
+				// A first line longer than the budget is cut rather than
+				// dropped, so something is left
+				result.WriteString(line[:maxLen])
+				omittedLines--
+			}
+			break
 		}
 
 		if result.Len() > 0 {
@@ -134,10 +140,11 @@ func truncateSmart(content string, maxLen int) string {
 
 	if omittedLines > 0 {
 		indicator := fmt.Sprintf("\n\n... (%d more lines)", omittedLines)
-		if len(finalResult)+len(indicator) > maxLen {
-			finalResult = finalResult[:maxLen-len(indicator)] + indicator
-		} else {
+		switch {
+		case len(finalResult)+len(indicator) <= maxLen:
 			finalResult = finalResult + indicator
+		case maxLen > len(indicator):
+			finalResult = finalResult[:maxLen-len(indicator)] + indicator
 		}
 	}
 
@@ -0,0 +1,283 @@
+package license
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Ecosystems dependencies are read from
+const (
+	EcosystemGo     = "go"
+	EcosystemNpm    = "npm"
+	EcosystemPython = "python"
+)
+
+// Dependency is a dependency of the project with the license it declares
+// in its manifest or, failing that, the one its license file holds
+type Dependency struct {
+	Ecosystem string `json:"ecosystem"`
+	Name      string `json:"name"`
+	Version   string `json:"version,omitempty"`
+	License   string `json:"license,omitempty"`
+	// Source is where the license was read from
+	Source   string `json:"source,omitempty"`
+	Indirect bool   `json:"indirect,omitempty"`
+	Dev      bool   `json:"dev,omitempty"`
+	Status   string `json:"status"`
+}
+
+var (
+	goRequire   = regexp.MustCompile(`^\s*(?:require\s+)?([^\s()]+)\s+(v[^\s]+)(\s*//\s*indirect)?`)
+	pyClassifer = regexp.MustCompile(`^Classifier:\s*License :: (?:OSI Approved :: )?(.+)$`)
+)
+
+// Dependencies lists the dependencies of the project at root that can be
+// found locally: Go modules required by go.mod, in vendor or the module
+// cache; npm packages of package.json installed in node_modules; and the
+// Python distributions installed in a virtual environment under root.
+// Each is checked against allow.
+func Dependencies(root string, allow []string) []Dependency {
+	var deps []Dependency
+	deps = append(deps, goDependencies(root)...)
+	deps = append(deps, npmDependencies(root)...)
+	deps = append(deps, pythonDependencies(root)...)
+	for i := range deps {
+		deps[i].Status = Check(deps[i].License, allow)
+	}
+	sort.SliceStable(deps, func(i, j int) bool {
+		if deps[i].Ecosystem != deps[j].Ecosystem {
+			return deps[i].Ecosystem < deps[j].Ecosystem
+		}
+		return deps[i].Name < deps[j].Name
+	})
+	return deps
+}
+
+func goDependencies(root string) []Dependency {
+	file, err := os.Open(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	cache := goModCache()
+	var deps []Dependency
+	inRequire := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "require ("):
+			inRequire = true
+			continue
+		case inRequire && line == ")":
+			inRequire = false
+			continue
+		case !inRequire && !strings.HasPrefix(line, "require "):
+			continue
+		}
+		match := goRequire.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		dep := Dependency{Ecosystem: EcosystemGo, Name: match[1], Version: match[2], Indirect: match[3] != ""}
+		for _, dir := range []string{
+			filepath.Join(root, "vendor", filepath.FromSlash(match[1])),
+			filepath.Join(cache, filepath.FromSlash(escapeModulePath(match[1])+"@"+escapeModulePath(match[2]))),
+		} {
+			if id, file := IdentifyDir(dir); file != "" {
+				dep.License, dep.Source = id, file
+				break
+			}
+		}
+		deps = append(deps, dep)
+	}
+	return deps
+}
+
+// goModCache returns the module cache directory the go command uses
+func goModCache() string {
+	if dir := os.Getenv("GOMODCACHE"); dir != "" {
+		return dir
+	}
+	if gopath := os.Getenv("GOPATH"); gopath != "" {
+		return filepath.Join(filepath.SplitList(gopath)[0], "pkg", "mod")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, "go", "pkg", "mod")
+}
+
+// escapeModulePath escapes upper-case letters the way the module cache
+// stores them, as ! and the lower-case letter
+func escapeModulePath(path string) string {
+	var b strings.Builder
+	for _, r := range path {
+		if unicode.IsUpper(r) {
+			b.WriteByte('!')
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// packageJSON is the part of package.json the checker reads
+type packageJSON struct {
+	Version         string                  `json:"version"`
+	License         json.RawMessage         `json:"license"`
+	Licenses        []struct{ Type string } `json:"licenses"`
+	Dependencies    map[string]string       `json:"dependencies"`
+	DevDependencies map[string]string       `json:"devDependencies"`
+}
+
+// declaredLicense reads license, which is a string or {"type": ...}, or
+// the deprecated licenses list
+func (p *packageJSON) declaredLicense() string {
+	var id string
+	if json.Unmarshal(p.License, &id) == nil && id != "" {
+		return id
+	}
+	var typed struct{ Type string }
+	if json.Unmarshal(p.License, &typed) == nil && typed.Type != "" {
+		return typed.Type
+	}
+	var ids []string
+	for _, l := range p.Licenses {
+		ids = append(ids, l.Type)
+	}
+	return strings.Join(ids, " OR ")
+}
+
+func readPackageJSON(path string) (*packageJSON, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pkg := &packageJSON{}
+	if err := json.Unmarshal(data, pkg); err != nil {
+		return nil, err
+	}
+	return pkg, nil
+}
+
+func npmDependencies(root string) []Dependency {
+	project, err := readPackageJSON(filepath.Join(root, "package.json"))
+	if err != nil {
+		return nil
+	}
+
+	var deps []Dependency
+	add := func(names map[string]string, dev bool) {
+		for name, wanted := range names {
+			dep := Dependency{Ecosystem: EcosystemNpm, Name: name, Version: wanted, Dev: dev}
+			dir := filepath.Join(root, "node_modules", filepath.FromSlash(name))
+			manifest := filepath.Join(dir, "package.json")
+			if pkg, err := readPackageJSON(manifest); err == nil {
+				dep.Version = pkg.Version
+				if id := pkg.declaredLicense(); id != "" {
+					dep.License, dep.Source = id, manifest
+				}
+			}
+			if dep.License == "" {
+				if id, file := IdentifyDir(dir); file != "" {
+					dep.License, dep.Source = id, file
+				}
+			}
+			deps = append(deps, dep)
+		}
+	}
+	add(project.Dependencies, false)
+	add(project.DevDependencies, true)
+	return deps
+}
+
+// virtualEnvs are the directories a project's virtual environment is
+// usually created in
+var virtualEnvs = []string{".venv", "venv", "env"}
+
+func pythonDependencies(root string) []Dependency {
+	var deps []Dependency
+	for _, env := range virtualEnvs {
+		patterns := []string{
+			filepath.Join(root, env, "lib", "python*", "site-packages", "*.dist-info", "METADATA"),
+			filepath.Join(root, env, "Lib", "site-packages", "*.dist-info", "METADATA"),
+		}
+		for _, pattern := range patterns {
+			matches, _ := filepath.Glob(pattern)
+			for _, metadata := range matches {
+				if dep, ok := readDistInfo(metadata); ok {
+					deps = append(deps, dep)
+				}
+			}
+		}
+	}
+	return deps
+}
+
+// readDistInfo reads a distribution's METADATA: License-Expression, then
+// License, then license classifiers, then the license file next to it
+func readDistInfo(metadata string) (Dependency, bool) {
+	file, err := os.Open(metadata)
+	if err != nil {
+		return Dependency{}, false
+	}
+	defer file.Close()
+
+	dep := Dependency{Ecosystem: EcosystemPython, Source: metadata}
+	var expression, declared string
+	var classifiers []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			// the headers end at the first blank line; the description follows
+			break
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "Name":
+			dep.Name = value
+		case "Version":
+			dep.Version = value
+		case "License-Expression":
+			expression = value
+		case "License":
+			declared = value
+		case "Classifier":
+			if match := pyClassifer.FindStringSubmatch(line); match != nil {
+				classifiers = append(classifiers, Normalize(match[1]))
+			}
+		}
+	}
+	if dep.Name == "" {
+		return Dependency{}, false
+	}
+
+	switch {
+	case expression != "":
+		dep.License = expression
+	case declared != "" && !strings.Contains(declared, "\n") && len(declared) < 80:
+		dep.License = Normalize(declared)
+	case len(classifiers) > 0:
+		dep.License = strings.Join(classifiers, " OR ")
+	default:
+		if id, file := IdentifyDir(filepath.Dir(metadata)); file != "" {
+			dep.License, dep.Source = id, file
+		}
+	}
+	return dep, true
+}
@@ -0,0 +1,166 @@
+package license
+
+import (
+	"strings"
+)
+
+// Status of a dependency's license against the allowlist
+const (
+	StatusAllowed   = "allowed"
+	StatusConflict  = "conflict"
+	StatusUnknown   = "unknown"
+	StatusUnchecked = "unchecked"
+)
+
+// aliases maps the informal names manifests use to SPDX identifiers, by
+// lowercase name
+var aliases = map[string]string{
+	"mit license":                           "MIT",
+	"the mit license":                       "MIT",
+	"expat":                                 "MIT",
+	"apache 2.0":                            "Apache-2.0",
+	"apache-2":                              "Apache-2.0",
+	"apache 2":                              "Apache-2.0",
+	"apache license 2.0":                    "Apache-2.0",
+	"apache license, version 2.0":           "Apache-2.0",
+	"apache software license":               "Apache-2.0",
+	"asl 2.0":                               "Apache-2.0",
+	"bsd":                                   "BSD-3-Clause",
+	"bsd license":                           "BSD-3-Clause",
+	"new bsd license":                       "BSD-3-Clause",
+	"3-clause bsd":                          "BSD-3-Clause",
+	"simplified bsd":                        "BSD-2-Clause",
+	"2-clause bsd":                          "BSD-2-Clause",
+	"isc license":                           "ISC",
+	"isc license (iscl)":                    "ISC",
+	"mozilla public license 2.0 (mpl 2.0)":  "MPL-2.0",
+	"mpl 2.0":                               "MPL-2.0",
+	"gplv2":                                 "GPL-2.0",
+	"gplv3":                                 "GPL-3.0",
+	"gpl-2.0-only":                          "GPL-2.0",
+	"gpl-2.0-or-later":                      "GPL-2.0",
+	"gpl-3.0-only":                          "GPL-3.0",
+	"gpl-3.0-or-later":                      "GPL-3.0",
+	"lgplv3":                                "LGPL-3.0",
+	"lgpl-2.1-only":                         "LGPL-2.1",
+	"lgpl-2.1-or-later":                     "LGPL-2.1",
+	"lgpl-3.0-only":                         "LGPL-3.0",
+	"lgpl-3.0-or-later":                     "LGPL-3.0",
+	"agpl-3.0-only":                         "AGPL-3.0",
+	"agpl-3.0-or-later":                     "AGPL-3.0",
+	"gnu general public license v2 (gplv2)": "GPL-2.0",
+	"gnu general public license v3 (gplv3)": "GPL-3.0",
+	"gnu lesser general public license v3 (lgplv3)": "LGPL-3.0",
+	"the unlicense (unlicense)":                     "Unlicense",
+	"public domain":                                 "Unlicense",
+}
+
+// Normalize returns the SPDX identifier of a license name, or the name
+// unchanged when it is not a known alias
+func Normalize(name string) string {
+	name = strings.TrimSpace(name)
+	if id, ok := aliases[strings.ToLower(name)]; ok {
+		return id
+	}
+	return name
+}
+
+// Check evaluates an SPDX expression, such as "MIT OR Apache-2.0" or
+// "(BSD-3-Clause AND Zlib)", against allow. An empty allowlist leaves the
+// license unchecked; an empty or unparseable expression is unknown.
+func Check(expression string, allow []string) string {
+	expression = strings.TrimSpace(expression)
+	if expression == "" || strings.EqualFold(expression, "UNKNOWN") || strings.HasPrefix(strings.ToUpper(expression), "SEE LICENSE") {
+		return StatusUnknown
+	}
+	if len(allow) == 0 {
+		return StatusUnchecked
+	}
+
+	allowed := make(map[string]bool, len(allow))
+	for _, id := range allow {
+		allowed[strings.ToLower(Normalize(id))] = true
+	}
+
+	p := &exprParser{tokens: tokenize(expression), allowed: allowed}
+	ok := p.or()
+	if p.failed || p.pos != len(p.tokens) {
+		// not an expression: the whole text may still be one license name
+		if allowed[strings.ToLower(Normalize(expression))] {
+			return StatusAllowed
+		}
+		return StatusUnknown
+	}
+	if ok {
+		return StatusAllowed
+	}
+	return StatusConflict
+}
+
+// tokenize splits an expression into parentheses, operators and license
+// identifiers; npm's old "MIT/Apache-2.0" form reads as OR
+func tokenize(expression string) []string {
+	expression = strings.NewReplacer("(", " ( ", ")", " ) ", "/", " OR ").Replace(expression)
+	return strings.Fields(expression)
+}
+
+// exprParser evaluates an expression by recursive descent: OR binds
+// looser than AND, and WITH attaches an exception to a license
+type exprParser struct {
+	tokens  []string
+	pos     int
+	allowed map[string]bool
+	failed  bool
+}
+
+func (p *exprParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return strings.ToUpper(p.tokens[p.pos])
+	}
+	return ""
+}
+
+func (p *exprParser) or() bool {
+	ok := p.and()
+	for p.peek() == "OR" {
+		p.pos++
+		// evaluate both sides so the whole expression is parsed
+		right := p.and()
+		ok = ok || right
+	}
+	return ok
+}
+
+func (p *exprParser) and() bool {
+	ok := p.operand()
+	for p.peek() == "AND" {
+		p.pos++
+		right := p.operand()
+		ok = ok && right
+	}
+	return ok
+}
+
+func (p *exprParser) operand() bool {
+	switch p.peek() {
+	case "(":
+		p.pos++
+		ok := p.or()
+		if p.peek() != ")" {
+			p.failed = true
+			return false
+		}
+		p.pos++
+		return ok
+	case "", ")", "OR", "AND", "WITH":
+		p.failed = true
+		return false
+	}
+
+	id := p.tokens[p.pos]
+	p.pos++
+	if p.peek() == "WITH" {
+		p.pos += 2
+	}
+	return p.allowed[strings.ToLower(Normalize(strings.TrimSuffix(id, "+")))]
+}
@@ -0,0 +1,66 @@
+package license
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// headerLines is how many lines at the top of a file may hold the header
+const headerLines = 40
+
+var (
+	placeholder    = regexp.MustCompile(`\\\{([A-Za-z_]+)\\\}`)
+	commentMarkers = regexp.MustCompile(`^(?://+|#+|/\*+|\*+/?|--+|;+|<!--|"""|''')\s*|\s*(?:\*+/|-->|"""|''')$`)
+)
+
+// Header matches the header template of Config against the start of files
+type Header struct {
+	pattern *regexp.Regexp
+}
+
+// NewHeader compiles a header template. The template is written without
+// comment markers, or with the markers of any language: they are ignored
+// on both sides, as are blank lines and surrounding whitespace.
+func NewHeader(template string) (*Header, error) {
+	lines := headerText(template, -1)
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("header template is empty")
+	}
+
+	expr := regexp.QuoteMeta(strings.Join(lines, "\n"))
+	expr = placeholder.ReplaceAllStringFunc(expr, func(match string) string {
+		if strings.EqualFold(placeholder.FindStringSubmatch(match)[1], "year") {
+			return `\d{4}(?:\s*[-,]\s*\d{4})*`
+		}
+		return `.+?`
+	})
+	pattern, err := regexp.Compile("(?m)^" + expr + "$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid header template: %w", err)
+	}
+	return &Header{pattern: pattern}, nil
+}
+
+// Matches reports whether content starts with the header, after an
+// optional shebang, build constraints or other lines before it
+func (h *Header) Matches(content string) bool {
+	return h.pattern.MatchString(strings.Join(headerText(content, headerLines), "\n"))
+}
+
+// headerText returns the first max lines (all for -1) of text without
+// comment markers and blank lines
+func headerText(text string, max int) []string {
+	var lines []string
+	for i, line := range strings.Split(text, "\n") {
+		if max >= 0 && i == max {
+			break
+		}
+		line = strings.TrimSpace(line)
+		line = strings.TrimSpace(commentMarkers.ReplaceAllString(line, ""))
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,120 @@
+// Package license identifies licenses: the project's from its license file
+// or manifest, its dependencies' from what they declare or ship, and
+// whether an SPDX expression is satisfied by an allowlist. It also checks
+// source files for a required header.
+package license
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Config is the license policy of the daemon. Projects can override both
+// fields through the tool's parameters.
+type Config struct {
+	// Allow lists the SPDX identifiers dependencies may be licensed under;
+	// empty leaves dependencies unchecked
+	Allow []string
+	// Header is the template every source file must start with; empty
+	// disables the header check. {year} matches a year or a range of
+	// years, any other {name} matches any text.
+	Header string
+}
+
+// licenseFiles are the names a license file goes by, matched ignoring case
+// and extension
+var licenseFiles = []string{"license", "licence", "copying", "unlicense"}
+
+// signature recognizes one license by phrases all found in its text
+type signature struct {
+	id      string
+	phrases []string
+}
+
+// signatures are checked in order, so variants come before the licenses
+// they contain the phrases of
+var signatures = []signature{
+	{id: "AGPL-3.0", phrases: []string{"gnu affero general public license", "version 3"}},
+	{id: "LGPL-3.0", phrases: []string{"gnu lesser general public license", "version 3"}},
+	{id: "LGPL-2.1", phrases: []string{"gnu lesser general public license", "version 2.1"}},
+	{id: "GPL-3.0", phrases: []string{"gnu general public license", "version 3"}},
+	{id: "GPL-2.0", phrases: []string{"gnu general public license", "version 2"}},
+	{id: "Apache-2.0", phrases: []string{"apache license", "version 2.0"}},
+	{id: "MPL-2.0", phrases: []string{"mozilla public license", "2.0"}},
+	{id: "EPL-2.0", phrases: []string{"eclipse public license", "2.0"}},
+	{id: "Unlicense", phrases: []string{"free and unencumbered software released into the public domain"}},
+	{id: "CC0-1.0", phrases: []string{"cc0 1.0 universal"}},
+	{id: "MIT", phrases: []string{"permission is hereby granted, free of charge"}},
+	{id: "ISC", phrases: []string{"permission to use, copy, modify, and", "distribute this software for any purpose with or without fee is hereby granted"}},
+	{id: "BSD-3-Clause", phrases: []string{"redistribution and use in source and binary forms", "endorse or promote products"}},
+	{id: "BSD-2-Clause", phrases: []string{"redistribution and use in source and binary forms"}},
+}
+
+var (
+	spdxTag    = regexp.MustCompile(`SPDX-License-Identifier:\s*([^\n*]+?)\s*(?:\*/|-->)?\s*$`)
+	whitespace = regexp.MustCompile(`\s+`)
+)
+
+// Identify returns the SPDX identifier of a license text, or "" when it is
+// not one it knows. An SPDX-License-Identifier tag wins over the text.
+func Identify(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if match := spdxTag.FindStringSubmatch(strings.TrimSpace(line)); match != nil {
+			return match[1]
+		}
+	}
+
+	normalized := whitespace.ReplaceAllString(strings.ToLower(text), " ")
+	for _, sig := range signatures {
+		if containsAll(normalized, sig.phrases) {
+			return sig.id
+		}
+	}
+	return ""
+}
+
+// FindLicenseFile returns the license file of dir, or ""
+func FindLicenseFile(dir string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := strings.ToLower(entry.Name())
+		base := strings.TrimSuffix(name, filepath.Ext(name))
+		for _, candidate := range licenseFiles {
+			if base == candidate || name == candidate || strings.HasPrefix(base, candidate+"-") {
+				return filepath.Join(dir, entry.Name())
+			}
+		}
+	}
+	return ""
+}
+
+// IdentifyDir identifies the license of the file FindLicenseFile finds in
+// dir, returning the identifier and the file
+func IdentifyDir(dir string) (string, string) {
+	file := FindLicenseFile(dir)
+	if file == "" {
+		return "", ""
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", ""
+	}
+	return Identify(string(data)), file
+}
+
+func containsAll(text string, phrases []string) bool {
+	for _, phrase := range phrases {
+		if !strings.Contains(text, phrase) {
+			return false
+		}
+	}
+	return true
+}
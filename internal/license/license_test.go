@@ -0,0 +1,170 @@
+package license
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const mitText = `MIT License
+
+Copyright (c) 2024 Example
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction.
+`
+
+const gplText = `GNU GENERAL PUBLIC LICENSE
+Version 3, 29 June 2007
+`
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIdentify(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{mitText, "MIT"},
+		{gplText, "GPL-3.0"},
+		{"GNU LESSER GENERAL PUBLIC LICENSE\nVersion 2.1, February 1999", "LGPL-2.1"},
+		{"Apache License\n   Version 2.0, January 2004", "Apache-2.0"},
+		{"// SPDX-License-Identifier: MPL-2.0 OR MIT\npackage x", "MPL-2.0 OR MIT"},
+		{"/* SPDX-License-Identifier: BSD-2-Clause */", "BSD-2-Clause"},
+		{"All rights reserved.", ""},
+	}
+	for _, tt := range tests {
+		if got := Identify(tt.text); got != tt.want {
+			t.Errorf("Identify(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	allow := []string{"MIT", "Apache-2.0", "BSD-3-Clause"}
+	tests := []struct {
+		expression string
+		allow      []string
+		want       string
+	}{
+		{"MIT", allow, StatusAllowed},
+		{"mit license", allow, StatusAllowed},
+		{"GPL-3.0", allow, StatusConflict},
+		{"MIT OR GPL-3.0", allow, StatusAllowed},
+		{"MIT AND GPL-3.0", allow, StatusConflict},
+		{"(MIT OR GPL-2.0) AND Apache-2.0", allow, StatusAllowed},
+		{"Apache-2.0 WITH LLVM-exception", allow, StatusAllowed},
+		{"MIT/GPL-2.0", allow, StatusAllowed},
+		{"Apache License, Version 2.0", allow, StatusAllowed},
+		{"", allow, StatusUnknown},
+		{"SEE LICENSE IN LICENSE.txt", allow, StatusUnknown},
+		{"(MIT", allow, StatusUnknown},
+		{"GPL-3.0", nil, StatusUnchecked},
+	}
+	for _, tt := range tests {
+		if got := Check(tt.expression, tt.allow); got != tt.want {
+			t.Errorf("Check(%q) = %q, want %q", tt.expression, got, tt.want)
+		}
+	}
+}
+
+func TestHeader(t *testing.T) {
+	header, err := NewHeader("Copyright {year} {owner}\nLicensed under the Apache License, Version 2.0.\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    bool
+	}{
+		{"go", "// Copyright 2024 Acme Inc.\n// Licensed under the Apache License, Version 2.0.\n\npackage main\n", true},
+		{"range", "/*\n * Copyright 2019-2024 Acme Inc.\n * Licensed under the Apache License, Version 2.0.\n */\n", true},
+		{"shebang", "#!/usr/bin/env python\n# Copyright 2024 Acme\n# Licensed under the Apache License, Version 2.0.\n", true},
+		{"missing", "package main\n\nfunc main() {}\n", false},
+		{"no year", "// Copyright Acme\n// Licensed under the Apache License, Version 2.0.\n", false},
+		{"partial", "// Copyright 2024 Acme\npackage main\n", false},
+	}
+	for _, tt := range tests {
+		if got := header.Matches(tt.content); got != tt.want {
+			t.Errorf("%s: Matches = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := NewHeader("//\n\n"); err == nil {
+		t.Error("expected an error for an empty template")
+	}
+}
+
+func TestDependencies(t *testing.T) {
+	root := t.TempDir()
+	cache := t.TempDir()
+	t.Setenv("GOMODCACHE", cache)
+
+	writeFile(t, filepath.Join(root, "go.mod"), `module example.com/app
+
+go 1.22
+
+require github.com/Example/permissive v1.2.0
+
+require (
+	example.com/copyleft v0.3.0 // indirect
+	example.com/missing v1.0.0
+)
+`)
+	writeFile(t, filepath.Join(cache, "github.com", "!example", "permissive@v1.2.0", "LICENSE"), mitText)
+	writeFile(t, filepath.Join(root, "vendor", "example.com", "copyleft", "COPYING"), gplText)
+
+	writeFile(t, filepath.Join(root, "package.json"), `{
+		"dependencies": {"left-pad": "^1.3.0"},
+		"devDependencies": {"old-style": "1.0.0"}
+	}`)
+	writeFile(t, filepath.Join(root, "node_modules", "left-pad", "package.json"), `{"version": "1.3.0", "license": "WTFPL"}`)
+	writeFile(t, filepath.Join(root, "node_modules", "old-style", "package.json"), `{"version": "1.0.0", "licenses": [{"type": "MIT"}, {"type": "Apache-2.0"}]}`)
+
+	writeFile(t, filepath.Join(root, ".venv", "lib", "python3.12", "site-packages", "requests-2.31.0.dist-info", "METADATA"),
+		"Metadata-Version: 2.1\nName: requests\nVersion: 2.31.0\nClassifier: License :: OSI Approved :: Apache Software License\n\nLicense: not a header\n")
+
+	deps := Dependencies(root, []string{"MIT", "Apache-2.0"})
+	got := map[string]Dependency{}
+	for _, dep := range deps {
+		got[dep.Name] = dep
+	}
+	want := map[string]struct {
+		license string
+		status  string
+	}{
+		"github.com/Example/permissive": {"MIT", StatusAllowed},
+		"example.com/copyleft":          {"GPL-3.0", StatusConflict},
+		"example.com/missing":           {"", StatusUnknown},
+		"left-pad":                      {"WTFPL", StatusConflict},
+		"old-style":                     {"MIT OR Apache-2.0", StatusAllowed},
+		"requests":                      {"Apache-2.0", StatusAllowed},
+	}
+	if len(got) != len(want) {
+		t.Errorf("expected %d dependencies, got %+v", len(want), deps)
+	}
+	for name, w := range want {
+		dep, ok := got[name]
+		if !ok {
+			t.Errorf("missing dependency %s", name)
+			continue
+		}
+		if dep.License != w.license || dep.Status != w.status {
+			t.Errorf("%s: got license %q (%s), want %q (%s)", name, dep.License, dep.Status, w.license, w.status)
+		}
+	}
+	if !got["example.com/copyleft"].Indirect || !got["old-style"].Dev {
+		t.Errorf("expected indirect and dev flags, got %+v", deps)
+	}
+}
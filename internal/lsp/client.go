@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -30,12 +31,23 @@ type Client struct {
 	lastRequest  time.Time
 	mu           sync.RWMutex
 	closedCh     chan struct{}
+
+	// docMu serializes requests that open a document, so two of them never
+	// open the same URI at once
+	docMu sync.Mutex
+
+	// editSink collects the workspace/applyEdit requests the server sends
+	// while ExecuteCommand runs; without one they are refused
+	editMu   sync.Mutex
+	editSink func(WorkspaceEdit)
 }
 
 type ClientConfig struct {
 	Language       Language
 	InitTimeout    time.Duration
 	RequestTimeout time.Duration
+	Settings       map[string]interface{}
+	Section        string
 }
 
 func DefaultClientConfig(lang Language) ClientConfig {
@@ -91,6 +103,86 @@ type clientHandler struct {
 }
 
 func (h *clientHandler) Handle(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) {
+	if req.Notif {
+		return
+	}
+	switch req.Method {
+	case "workspace/configuration":
+		h.configuration(ctx, conn, req)
+	case "workspace/applyEdit":
+		h.applyEdit(ctx, conn, req)
+	}
+}
+
+// applyEdit hands a server's edit to the running ExecuteCommand, which
+// applies it once the command is done
+func (h *clientHandler) applyEdit(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) {
+	var params ApplyWorkspaceEditParams
+	if req.Params != nil {
+		if err := json.Unmarshal(*req.Params, &params); err != nil {
+			conn.ReplyWithError(ctx, req.ID, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams, Message: err.Error()})
+			return
+		}
+	}
+
+	h.client.editMu.Lock()
+	sink := h.client.editSink
+	h.client.editMu.Unlock()
+	if sink == nil {
+		conn.Reply(ctx, req.ID, ApplyWorkspaceEditResult{FailureReason: "no command is running"})
+		return
+	}
+	sink(params.Edit)
+	conn.Reply(ctx, req.ID, ApplyWorkspaceEditResult{Applied: true})
+}
+
+func (h *clientHandler) configuration(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) {
+	var params ConfigurationParams
+	if req.Params != nil {
+		if err := json.Unmarshal(*req.Params, &params); err != nil {
+			conn.ReplyWithError(ctx, req.ID, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams, Message: err.Error()})
+			return
+		}
+	}
+
+	results := make([]interface{}, len(params.Items))
+	for i, item := range params.Items {
+		results[i] = h.client.configurationSection(item.Section)
+	}
+	conn.Reply(ctx, req.ID, results)
+}
+
+// configurationSection answers one workspace/configuration item from the
+// server settings: the whole settings for the server's own section (or no
+// section), the value at the dotted path below it, or nil.
+func (c *Client) configurationSection(section string) interface{} {
+	if section == "" || section == c.config.Section {
+		return c.config.Settings
+	}
+
+	rest, ok := strings.CutPrefix(section, c.config.Section+".")
+	if c.config.Section == "" || !ok {
+		return nil
+	}
+
+	var value interface{} = c.config.Settings
+	for _, key := range strings.Split(rest, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = m[key]
+	}
+	return value
+}
+
+// settingsNotification is the didChangeConfiguration payload: the settings
+// nested under the server's section when it has one
+func (c *Client) settingsNotification() DidChangeConfigurationParams {
+	if c.config.Section == "" {
+		return DidChangeConfigurationParams{Settings: c.config.Settings}
+	}
+	return DidChangeConfigurationParams{Settings: map[string]interface{}{c.config.Section: c.config.Settings}}
 }
 
 func (c *Client) Initialize(ctx context.Context, rootURI string) error {
@@ -109,13 +201,53 @@ func (c *Client) Initialize(ctx context.Context, rootURI string) error {
 		ProcessID: os.Getpid(),
 		RootURI:   rootURI,
 		Capabilities: map[string]interface{}{
+			"workspace": map[string]interface{}{
+				"configuration":          true,
+				"didChangeConfiguration": map[string]interface{}{},
+				"applyEdit":              true,
+				"workspaceEdit": map[string]interface{}{
+					"documentChanges":    true,
+					"resourceOperations": []string{"create", "rename", "delete"},
+				},
+				"executeCommand": map[string]interface{}{},
+			},
 			"textDocument": map[string]interface{}{
 				"documentSymbol": map[string]interface{}{
 					"hierarchicalDocumentSymbolSupport": true,
 				},
+				"references": map[string]interface{}{},
+				"implementation": map[string]interface{}{
+					"linkSupport": true,
+				},
+				"completion": map[string]interface{}{
+					"completionItem": map[string]interface{}{
+						"documentationFormat": []string{"plaintext", "markdown"},
+						"deprecatedSupport":   true,
+					},
+				},
+				"hover": map[string]interface{}{
+					"contentFormat": []string{"markdown", "plaintext"},
+				},
+				"codeAction": map[string]interface{}{
+					"codeActionLiteralSupport": map[string]interface{}{
+						"codeActionKind": map[string]interface{}{
+							"valueSet": []string{"quickfix", "refactor", "refactor.extract", "refactor.inline", "refactor.rewrite", "source", "source.organizeImports", "source.fixAll"},
+						},
+					},
+					"isPreferredSupport": true,
+					"disabledSupport":    true,
+					"dataSupport":        true,
+					"resolveSupport": map[string]interface{}{
+						"properties": []string{"edit"},
+					},
+				},
+				"synchronization": map[string]interface{}{},
 			},
 		},
 	}
+	if len(c.config.Settings) > 0 {
+		params.InitializationOptions = c.config.Settings
+	}
 
 	var result InitializeResult
 	if err := c.conn.Call(initCtx, "initialize", params, &result); err != nil {
@@ -132,6 +264,13 @@ func (c *Client) Initialize(ctx context.Context, rootURI string) error {
 		return fmt.Errorf("initialized notification failed: %w", err)
 	}
 
+	if len(c.config.Settings) > 0 {
+		if err := c.conn.Notify(initCtx, "workspace/didChangeConfiguration", c.settingsNotification()); err != nil {
+			c.state.Store(StateError)
+			return fmt.Errorf("didChangeConfiguration notification failed: %w", err)
+		}
+	}
+
 	c.state.Store(StateReady)
 	return nil
 }
@@ -190,6 +329,298 @@ func (c *Client) DocumentSymbols(ctx context.Context, uri string) ([]DocumentSym
 	return convertToDocumentSymbols(flatSymbols), nil
 }
 
+func (c *Client) References(ctx context.Context, uri string, pos Position, includeDeclaration bool) ([]Location, error) {
+	if !c.IsReady() {
+		return nil, ErrNotInitialized
+	}
+
+	c.recordRequest()
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, c.config.RequestTimeout)
+	defer cancel()
+
+	params := ReferenceParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Position:     pos,
+		Context:      ReferenceContext{IncludeDeclaration: includeDeclaration},
+	}
+
+	var locations []Location
+	if err := c.conn.Call(timeoutCtx, "textDocument/references", params, &locations); err != nil {
+		c.recordError()
+		return nil, fmt.Errorf("references request failed: %w", err)
+	}
+
+	return locations, nil
+}
+
+// DocumentVersion is the version documents are opened with, so edits the
+// server computes for them name it
+const DocumentVersion = 1
+
+// withDocument opens uri with text for the duration of fn, so the server
+// sees unsaved content and needs no prior didOpen
+func (c *Client) withDocument(ctx context.Context, uri, languageID, text string, fn func() error) error {
+	c.docMu.Lock()
+	defer c.docMu.Unlock()
+
+	open := DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{URI: uri, LanguageID: languageID, Version: DocumentVersion, Text: text},
+	}
+	if err := c.conn.Notify(ctx, "textDocument/didOpen", open); err != nil {
+		return fmt.Errorf("didOpen notification failed: %w", err)
+	}
+	defer c.conn.Notify(context.Background(), "textDocument/didClose", DidCloseTextDocumentParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+	})
+
+	return fn()
+}
+
+// Completion asks for completions at pos in uri, opened with text
+func (c *Client) Completion(ctx context.Context, uri, languageID, text string, pos Position) (*CompletionList, error) {
+	if !c.IsReady() {
+		return nil, ErrNotInitialized
+	}
+
+	c.recordRequest()
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, c.config.RequestTimeout)
+	defer cancel()
+
+	params := CompletionParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Position:     pos,
+	}
+
+	var rawResult json.RawMessage
+	err := c.withDocument(timeoutCtx, uri, languageID, text, func() error {
+		return c.conn.Call(timeoutCtx, "textDocument/completion", params, &rawResult)
+	})
+	if err != nil {
+		c.recordError()
+		return nil, fmt.Errorf("completion request failed: %w", err)
+	}
+
+	list := &CompletionList{}
+	if len(rawResult) == 0 || string(rawResult) == "null" {
+		return list, nil
+	}
+	if err := json.Unmarshal(rawResult, &list.Items); err == nil {
+		return list, nil
+	}
+	if err := json.Unmarshal(rawResult, list); err != nil {
+		c.recordError()
+		return nil, fmt.Errorf("failed to parse completion response: %w", err)
+	}
+	return list, nil
+}
+
+// Hover asks for hover information at pos in uri, opened with text. It
+// returns nil when the server has nothing to show there.
+func (c *Client) Hover(ctx context.Context, uri, languageID, text string, pos Position) (*Hover, error) {
+	if !c.IsReady() {
+		return nil, ErrNotInitialized
+	}
+
+	c.recordRequest()
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, c.config.RequestTimeout)
+	defer cancel()
+
+	params := HoverParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Position:     pos,
+	}
+
+	var hover *Hover
+	err := c.withDocument(timeoutCtx, uri, languageID, text, func() error {
+		return c.conn.Call(timeoutCtx, "textDocument/hover", params, &hover)
+	})
+	if err != nil {
+		c.recordError()
+		return nil, fmt.Errorf("hover request failed: %w", err)
+	}
+	return hover, nil
+}
+
+// Implementation asks for the implementations of the interface, abstract
+// class or method at pos in uri, opened with text
+func (c *Client) Implementation(ctx context.Context, uri, languageID, text string, pos Position) ([]Location, error) {
+	if !c.IsReady() {
+		return nil, ErrNotInitialized
+	}
+
+	c.recordRequest()
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, c.config.RequestTimeout)
+	defer cancel()
+
+	params := ImplementationParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Position:     pos,
+	}
+
+	var rawResult json.RawMessage
+	err := c.withDocument(timeoutCtx, uri, languageID, text, func() error {
+		return c.conn.Call(timeoutCtx, "textDocument/implementation", params, &rawResult)
+	})
+	if err != nil {
+		c.recordError()
+		return nil, fmt.Errorf("implementation request failed: %w", err)
+	}
+
+	locations, err := parseLocations(rawResult)
+	if err != nil {
+		c.recordError()
+		return nil, fmt.Errorf("failed to parse implementation response: %w", err)
+	}
+	return locations, nil
+}
+
+// CodeActions asks for the code actions available for rng in uri, opened
+// with text, limited to the kinds in only when given. Bare commands in the
+// result are returned as actions holding just the command.
+func (c *Client) CodeActions(ctx context.Context, uri, languageID, text string, rng Range, only []string) ([]CodeAction, error) {
+	if !c.IsReady() {
+		return nil, ErrNotInitialized
+	}
+
+	c.recordRequest()
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, c.config.RequestTimeout)
+	defer cancel()
+
+	params := CodeActionParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Range:        rng,
+		Context:      CodeActionContext{Diagnostics: []Diagnostic{}, Only: only},
+	}
+
+	var rawResult []json.RawMessage
+	err := c.withDocument(timeoutCtx, uri, languageID, text, func() error {
+		return c.conn.Call(timeoutCtx, "textDocument/codeAction", params, &rawResult)
+	})
+	if err != nil {
+		c.recordError()
+		return nil, fmt.Errorf("codeAction request failed: %w", err)
+	}
+
+	actions := make([]CodeAction, 0, len(rawResult))
+	for _, raw := range rawResult {
+		var action CodeAction
+		if err := json.Unmarshal(raw, &action); err != nil {
+			c.recordError()
+			return nil, fmt.Errorf("failed to parse code action: %w", err)
+		}
+		// a Command has a string command where a CodeAction has an object
+		var command Command
+		if json.Unmarshal(raw, &command) == nil && command.Command != "" {
+			action = CodeAction{Title: command.Title, Command: &command}
+		}
+		actions = append(actions, action)
+	}
+	return actions, nil
+}
+
+// ResolveCodeAction asks the server to fill in the edit of an action it
+// left out, with uri opened with text
+func (c *Client) ResolveCodeAction(ctx context.Context, uri, languageID, text string, action CodeAction) (*CodeAction, error) {
+	if !c.IsReady() {
+		return nil, ErrNotInitialized
+	}
+
+	c.recordRequest()
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, c.config.RequestTimeout)
+	defer cancel()
+
+	var resolved CodeAction
+	err := c.withDocument(timeoutCtx, uri, languageID, text, func() error {
+		return c.conn.Call(timeoutCtx, "codeAction/resolve", action, &resolved)
+	})
+	if err != nil {
+		c.recordError()
+		return nil, fmt.Errorf("codeAction/resolve request failed: %w", err)
+	}
+	return &resolved, nil
+}
+
+// ExecuteCommand runs a command of a code action, with uri opened with
+// text, and returns the edits the server asked to apply while it ran. They
+// are not applied here: the caller applies them, or only shows them.
+func (c *Client) ExecuteCommand(ctx context.Context, uri, languageID, text string, command Command) ([]WorkspaceEdit, error) {
+	if !c.IsReady() {
+		return nil, ErrNotInitialized
+	}
+
+	c.recordRequest()
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, c.config.RequestTimeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	var edits []WorkspaceEdit
+	err := c.withDocument(timeoutCtx, uri, languageID, text, func() error {
+		c.editMu.Lock()
+		c.editSink = func(edit WorkspaceEdit) {
+			mu.Lock()
+			edits = append(edits, edit)
+			mu.Unlock()
+		}
+		c.editMu.Unlock()
+		defer func() {
+			c.editMu.Lock()
+			c.editSink = nil
+			c.editMu.Unlock()
+		}()
+
+		params := ExecuteCommandParams{Command: command.Command, Arguments: command.Arguments}
+		var result json.RawMessage
+		return c.conn.Call(timeoutCtx, "workspace/executeCommand", params, &result)
+	})
+	if err != nil {
+		c.recordError()
+		return nil, fmt.Errorf("executeCommand request failed: %w", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	return edits, nil
+}
+
+// parseLocations reads a Location, Location[] or LocationLink[] result
+func parseLocations(raw json.RawMessage) ([]Location, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	if raw[0] == '{' {
+		var location Location
+		if err := json.Unmarshal(raw, &location); err != nil {
+			return nil, err
+		}
+		return []Location{location}, nil
+	}
+
+	var entries []struct {
+		Location
+		TargetURI            string `json:"targetUri"`
+		TargetSelectionRange Range  `json:"targetSelectionRange"`
+	}
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return nil, err
+	}
+	locations := make([]Location, len(entries))
+	for i, e := range entries {
+		if e.TargetURI != "" {
+			locations[i] = Location{URI: e.TargetURI, Range: e.TargetSelectionRange}
+		} else {
+			locations[i] = e.Location
+		}
+	}
+	return locations, nil
+}
+
 func convertToDocumentSymbols(flat []SymbolInformation) []DocumentSymbol {
 	symbols := make([]DocumentSymbol, len(flat))
 	for i, s := range flat {
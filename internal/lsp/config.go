@@ -12,6 +12,12 @@ type ServerConfig struct {
 	InitTimeout    time.Duration `yaml:"init_timeout" json:"init_timeout"`
 	RequestTimeout time.Duration `yaml:"request_timeout" json:"request_timeout"`
 	MaxRestarts    int           `yaml:"max_restarts" json:"max_restarts"`
+
+	// Settings are the server's own options. They are sent as
+	// initializationOptions, pushed with workspace/didChangeConfiguration
+	// under SettingsSection, and answer workspace/configuration requests.
+	Settings        map[string]interface{} `yaml:"settings,omitempty" json:"settings,omitempty"`
+	SettingsSection string                 `yaml:"settings_section,omitempty" json:"settings_section,omitempty"`
 }
 
 type ManagerConfig struct {
@@ -32,92 +38,100 @@ func DefaultManagerConfig() ManagerConfig {
 		MaxConcurrent:  3,
 		Servers: map[Language]ServerConfig{
 			LangGo: {
-				Language:       LangGo,
-				Command:        "gopls",
-				Args:           []string{"serve"},
-				RootPatterns:   []string{"go.mod", "go.work"},
-				Extensions:     []string{".go"},
-				Enabled:        true,
-				InitTimeout:    10 * time.Second,
-				RequestTimeout: 30 * time.Second,
-				MaxRestarts:    3,
+				Language:        LangGo,
+				Command:         "gopls",
+				Args:            []string{"serve"},
+				RootPatterns:    []string{"go.mod", "go.work"},
+				Extensions:      []string{".go"},
+				Enabled:         true,
+				InitTimeout:     10 * time.Second,
+				RequestTimeout:  30 * time.Second,
+				MaxRestarts:     3,
+				SettingsSection: "gopls",
 			},
 			LangTypeScript: {
-				Language:       LangTypeScript,
-				Command:        "typescript-language-server",
-				Args:           []string{"--stdio"},
-				RootPatterns:   []string{"package.json", "tsconfig.json"},
-				Extensions:     []string{".ts", ".tsx"},
-				Enabled:        true,
-				InitTimeout:    15 * time.Second,
-				RequestTimeout: 30 * time.Second,
-				MaxRestarts:    3,
+				Language:        LangTypeScript,
+				Command:         "typescript-language-server",
+				Args:            []string{"--stdio"},
+				RootPatterns:    []string{"package.json", "tsconfig.json"},
+				Extensions:      []string{".ts", ".tsx"},
+				Enabled:         true,
+				InitTimeout:     15 * time.Second,
+				RequestTimeout:  30 * time.Second,
+				MaxRestarts:     3,
+				SettingsSection: "typescript",
 			},
 			LangJavaScript: {
-				Language:       LangJavaScript,
-				Command:        "typescript-language-server",
-				Args:           []string{"--stdio"},
-				RootPatterns:   []string{"package.json"},
-				Extensions:     []string{".js", ".jsx", ".mjs"},
-				Enabled:        true,
-				InitTimeout:    15 * time.Second,
-				RequestTimeout: 30 * time.Second,
-				MaxRestarts:    3,
+				Language:        LangJavaScript,
+				Command:         "typescript-language-server",
+				Args:            []string{"--stdio"},
+				RootPatterns:    []string{"package.json"},
+				Extensions:      []string{".js", ".jsx", ".mjs"},
+				Enabled:         true,
+				InitTimeout:     15 * time.Second,
+				RequestTimeout:  30 * time.Second,
+				MaxRestarts:     3,
+				SettingsSection: "javascript",
 			},
 			LangPython: {
-				Language:       LangPython,
-				Command:        "pylsp",
-				Args:           []string{},
-				RootPatterns:   []string{"pyproject.toml", "setup.py", "requirements.txt"},
-				Extensions:     []string{".py"},
-				Enabled:        true,
-				InitTimeout:    10 * time.Second,
-				RequestTimeout: 30 * time.Second,
-				MaxRestarts:    3,
+				Language:        LangPython,
+				Command:         "pylsp",
+				Args:            []string{},
+				RootPatterns:    []string{"pyproject.toml", "setup.py", "requirements.txt"},
+				Extensions:      []string{".py"},
+				Enabled:         true,
+				InitTimeout:     10 * time.Second,
+				RequestTimeout:  30 * time.Second,
+				MaxRestarts:     3,
+				SettingsSection: "pylsp",
 			},
 			LangRust: {
-				Language:       LangRust,
-				Command:        "rust-analyzer",
-				Args:           []string{},
-				RootPatterns:   []string{"Cargo.toml"},
-				Extensions:     []string{".rs"},
-				Enabled:        true,
-				InitTimeout:    20 * time.Second,
-				RequestTimeout: 30 * time.Second,
-				MaxRestarts:    2,
+				Language:        LangRust,
+				Command:         "rust-analyzer",
+				Args:            []string{},
+				RootPatterns:    []string{"Cargo.toml"},
+				Extensions:      []string{".rs"},
+				Enabled:         true,
+				InitTimeout:     20 * time.Second,
+				RequestTimeout:  30 * time.Second,
+				MaxRestarts:     2,
+				SettingsSection: "rust-analyzer",
 			},
 			LangCpp: {
-				Language:       LangCpp,
-				Command:        "clangd",
-				Args:           []string{},
-				RootPatterns:   []string{"compile_commands.json", "CMakeLists.txt", "Makefile"},
-				Extensions:     []string{".cpp", ".cc", ".cxx", ".hpp", ".h"},
-				Enabled:        true,
-				InitTimeout:    10 * time.Second,
-				RequestTimeout: 30 * time.Second,
-				MaxRestarts:    3,
+				Language:        LangCpp,
+				Command:         "clangd",
+				Args:            []string{},
+				RootPatterns:    []string{"compile_commands.json", "CMakeLists.txt", "Makefile"},
+				Extensions:      []string{".cpp", ".cc", ".cxx", ".hpp", ".h"},
+				Enabled:         true,
+				InitTimeout:     10 * time.Second,
+				RequestTimeout:  30 * time.Second,
+				MaxRestarts:     3,
+				SettingsSection: "clangd",
 			},
 			LangC: {
-				Language:       LangC,
-				Command:        "clangd",
-				Args:           []string{},
-				RootPatterns:   []string{"compile_commands.json", "Makefile"},
-				Extensions:     []string{".c", ".h"},
-				Enabled:        true,
-				InitTimeout:    10 * time.Second,
-				RequestTimeout: 30 * time.Second,
-				MaxRestarts:    3,
+				Language:        LangC,
+				Command:         "clangd",
+				Args:            []string{},
+				RootPatterns:    []string{"compile_commands.json", "Makefile"},
+				Extensions:      []string{".c", ".h"},
+				Enabled:         true,
+				InitTimeout:     10 * time.Second,
+				RequestTimeout:  30 * time.Second,
+				MaxRestarts:     3,
+				SettingsSection: "clangd",
 			},
 			LangJava: {
-				Language:       LangJava,
-				Command:        "jdtls",
-				Args:           []string{},
-				RootPatterns:   []string{"pom.xml", "build.gradle", "settings.gradle"},
-				Extensions:     []string{".java"},
-				Enabled:        false,
-				InitTimeout:    30 * time.Second,
-				RequestTimeout: 30 * time.Second,
-				MaxRestarts:    2,
+				Language:        LangJava,
+				Command:         "jdtls",
+				Args:            []string{},
+				RootPatterns:    []string{"pom.xml", "build.gradle", "settings.gradle"},
+				Extensions:      []string{".java"},
+				Enabled:         false,
+				InitTimeout:     30 * time.Second,
+				RequestTimeout:  30 * time.Second,
+				MaxRestarts:     2,
+				SettingsSection: "java",
 			},
 		},
 	}
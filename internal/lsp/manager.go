@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -21,16 +23,44 @@ var (
 	log = logger.ForComponent("lsp")
 )
 
-type Manager struct {
-	config    ManagerConfig
-	processes map[Language]*Process
-	starting  map[Language]bool
+// sessionKey names one language server: a language in one project root. A
+// monorepo gets a server per subproject, each rooted at the directory
+// holding its go.mod, package.json or other root pattern.
+type sessionKey struct {
+	lang Language
+	root string
+}
 
-	idleTimers map[Language]*time.Timer
-	lastAccess map[Language]time.Time
+// rootKey is a directory whose project root was looked up for a language
+type rootKey struct {
+	lang Language
+	dir  string
+}
 
-	mu       sync.RWMutex
-	timerMu  sync.Mutex
+type rootEntry struct {
+	root  string
+	found bool
+}
+
+type Manager struct {
+	config    ManagerConfig
+	processes map[sessionKey]*Process
+	starting  map[sessionKey]bool
+
+	idleTimers map[sessionKey]*time.Timer
+	lastAccess map[sessionKey]time.Time
+	warmUps    map[sessionKey]WarmUpStatus
+	supervised map[sessionKey]*supervision
+
+	// roots caches the project root detected for each directory a query
+	// started from, until ForgetRoots sees a root pattern file change
+	roots  map[rootKey]rootEntry
+	rootMu sync.Mutex
+
+	mu      sync.RWMutex
+	timerMu sync.Mutex
+	// startMu is held while a server starts, on demand or by the
+	// supervisor, so only one start per session runs at a time
 	startMu  sync.Mutex
 	closed   bool
 	closedCh chan struct{}
@@ -39,27 +69,185 @@ type Manager struct {
 func NewManager(config ManagerConfig) *Manager {
 	return &Manager{
 		config:     config,
-		processes:  make(map[Language]*Process),
-		starting:   make(map[Language]bool),
-		idleTimers: make(map[Language]*time.Timer),
-		lastAccess: make(map[Language]time.Time),
+		processes:  make(map[sessionKey]*Process),
+		starting:   make(map[sessionKey]bool),
+		idleTimers: make(map[sessionKey]*time.Timer),
+		lastAccess: make(map[sessionKey]time.Time),
+		warmUps:    make(map[sessionKey]WarmUpStatus),
+		supervised: make(map[sessionKey]*supervision),
+		roots:      make(map[rootKey]rootEntry),
 		closedCh:   make(chan struct{}),
 	}
 }
 
 func (m *Manager) GetSymbols(ctx context.Context, path string) ([]DocumentSymbol, error) {
+	client, uri, err := m.clientForFile(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debug("querying LSP for symbols", "path", path)
+
+	symbols, err := client.DocumentSymbols(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debug("LSP returned symbols", "path", path, "count", len(symbols))
+
+	return symbols, nil
+}
+
+// GetReferences asks the language server for every reference to the symbol at
+// pos (zero-based, UTF-16 character offset) in path, declaration included.
+func (m *Manager) GetReferences(ctx context.Context, path string, pos Position) ([]Location, error) {
+	client, uri, err := m.clientForFile(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debug("querying LSP for references", "path", path, "line", pos.Line, "character", pos.Character)
+
+	locations, err := client.References(ctx, uri, pos, true)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debug("LSP returned references", "path", path, "count", len(locations))
+
+	return locations, nil
+}
+
+// GetCompletions asks the language server for completions at pos
+// (zero-based, UTF-16 character offset) in path, with text as the file's
+// current content.
+func (m *Manager) GetCompletions(ctx context.Context, path, text string, pos Position) (*CompletionList, error) {
+	client, uri, err := m.clientForFile(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debug("querying LSP for completions", "path", path, "line", pos.Line, "character", pos.Character)
+
+	list, err := client.Completion(ctx, uri, languageID(path, m.DetectLanguage(path)), text, pos)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debug("LSP returned completions", "path", path, "count", len(list.Items))
+
+	return list, nil
+}
+
+// GetHover asks the language server for hover information at pos
+// (zero-based, UTF-16 character offset) in path, with text as the file's
+// current content. It returns the hover as markdown, empty when the server
+// has nothing there.
+func (m *Manager) GetHover(ctx context.Context, path, text string, pos Position) (string, error) {
+	client, uri, err := m.clientForFile(ctx, path)
+	if err != nil {
+		return "", err
+	}
+
+	log.Debug("querying LSP for hover", "path", path, "line", pos.Line, "character", pos.Character)
+
+	hover, err := client.Hover(ctx, uri, languageID(path, m.DetectLanguage(path)), text, pos)
+	if err != nil || hover == nil {
+		return "", err
+	}
+	return HoverText(hover.Contents), nil
+}
+
+// GetImplementations asks the language server for the implementations of
+// the symbol at pos (zero-based, UTF-16 character offset) in path, with
+// text as the file's current content.
+func (m *Manager) GetImplementations(ctx context.Context, path, text string, pos Position) ([]Location, error) {
+	client, uri, err := m.clientForFile(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debug("querying LSP for implementations", "path", path, "line", pos.Line, "character", pos.Character)
+
+	locations, err := client.Implementation(ctx, uri, languageID(path, m.DetectLanguage(path)), text, pos)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debug("LSP returned implementations", "path", path, "count", len(locations))
+
+	return locations, nil
+}
+
+// GetCodeActions asks the language server for the code actions of rng in
+// path, with text as the file's current content, limited to the kinds in
+// only when given.
+func (m *Manager) GetCodeActions(ctx context.Context, path, text string, rng Range, only []string) ([]CodeAction, error) {
+	client, uri, err := m.clientForFile(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debug("querying LSP for code actions", "path", path, "line", rng.Start.Line, "end_line", rng.End.Line)
+
+	actions, err := client.CodeActions(ctx, uri, languageID(path, m.DetectLanguage(path)), text, rng, only)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debug("LSP returned code actions", "path", path, "count", len(actions))
+
+	return actions, nil
+}
+
+// ResolveCodeAction fills in the edit of a code action of path that the
+// server left out of the list
+func (m *Manager) ResolveCodeAction(ctx context.Context, path, text string, action CodeAction) (*CodeAction, error) {
+	client, uri, err := m.clientForFile(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return client.ResolveCodeAction(ctx, uri, languageID(path, m.DetectLanguage(path)), text, action)
+}
+
+// ExecuteCommand runs the command of a code action of path and returns the
+// edits the server asked to apply meanwhile, unapplied
+func (m *Manager) ExecuteCommand(ctx context.Context, path, text string, command Command) ([]WorkspaceEdit, error) {
+	client, uri, err := m.clientForFile(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debug("executing LSP command", "path", path, "command", command.Command)
+
+	return client.ExecuteCommand(ctx, uri, languageID(path, m.DetectLanguage(path)), text, command)
+}
+
+// languageID is the LSP language identifier of path; JSX flavours have
+// their own
+func languageID(path string, lang Language) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".tsx":
+		return "typescriptreact"
+	case ".jsx":
+		return "javascriptreact"
+	}
+	return string(lang)
+}
+
+func (m *Manager) clientForFile(ctx context.Context, path string) (*Client, string, error) {
 	if m.isClosed() {
-		return nil, ErrManagerClosed
+		return nil, "", ErrManagerClosed
 	}
 
 	lang := m.DetectLanguage(path)
 	if lang == "" {
-		return nil, ErrLanguageNotSupported
+		return nil, "", ErrLanguageNotSupported
 	}
 
 	serverConfig, ok := m.config.Servers[lang]
 	if !ok || !serverConfig.Enabled {
-		return nil, fmt.Errorf("%w: %s", ErrLanguageNotSupported, lang)
+		return nil, "", fmt.Errorf("%w: %s", ErrLanguageNotSupported, lang)
 	}
 
 	rootPath, found := m.FindProjectRoot(path, lang)
@@ -69,41 +257,35 @@ func (m *Manager) GetSymbols(ctx context.Context, path string) ([]DocumentSymbol
 
 	process, err := m.getOrStartProcess(ctx, lang, rootPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get lsp process: %w", err)
+		return nil, "", fmt.Errorf("failed to get lsp process: %w", err)
 	}
 
-	m.recordAccess(lang)
+	m.recordAccess(sessionKey{lang, rootPath})
 
 	client := process.Client()
 	if client == nil || !client.IsReady() {
-		return nil, fmt.Errorf("lsp client not ready for %s", lang)
+		return nil, "", fmt.Errorf("lsp client not ready for %s", lang)
 	}
 
 	absPath, err := filepath.Abs(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get absolute path: %w", err)
+		return nil, "", fmt.Errorf("failed to get absolute path: %w", err)
 	}
-	uri := "file://" + absPath
-
-	log.Debug("querying LSP for symbols", "path", path)
 
-	symbols, err := client.DocumentSymbols(ctx, uri)
-	if err != nil {
-		return nil, err
-	}
-
-	log.Debug("LSP returned symbols", "path", path, "count", len(symbols))
-
-	return symbols, nil
+	return client, PathToURI(absPath), nil
 }
 
+// getOrStartProcess returns the server of lang rooted at rootPath, starting
+// it when there is none. Servers of the language under other roots keep
+// running; when MaxConcurrent are already up, the least recently queried
+// one is stopped to make room.
 func (m *Manager) getOrStartProcess(ctx context.Context, lang Language, rootPath string) (*Process, error) {
+	key := sessionKey{lang, rootPath}
+
 	m.mu.RLock()
-	if proc, exists := m.processes[lang]; exists {
-		if proc.State() == StateReady && proc.RootPath() == rootPath {
-			m.mu.RUnlock()
-			return proc, nil
-		}
+	if proc, exists := m.processes[key]; exists && proc.State() == StateReady {
+		m.mu.RUnlock()
+		return proc, nil
 	}
 	m.mu.RUnlock()
 
@@ -111,22 +293,17 @@ func (m *Manager) getOrStartProcess(ctx context.Context, lang Language, rootPath
 	defer m.startMu.Unlock()
 
 	m.mu.Lock()
-	if proc, exists := m.processes[lang]; exists {
-		if proc.State() == StateReady && proc.RootPath() == rootPath {
+	if proc, exists := m.processes[key]; exists {
+		if proc.State() == StateReady {
 			m.mu.Unlock()
 			return proc, nil
 		}
-		if oldProc := m.processes[lang]; oldProc != nil {
-			if oldProc.State() == StateReady {
-				stopCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
-				oldProc.Stop(stopCtx)
-				cancel()
-			}
-			delete(m.processes, lang)
-		}
+		// a crashed server waiting for its restart is replaced now; it is
+		// stopped so it cannot come back next to the new one
+		m.stopProcessLocked(ctx, key, "replaced")
 	}
 
-	if m.starting[lang] {
+	if m.starting[key] {
 		m.mu.Unlock()
 
 		ticker := time.NewTicker(100 * time.Millisecond)
@@ -139,11 +316,11 @@ func (m *Manager) getOrStartProcess(ctx context.Context, lang Language, rootPath
 				return nil, ctx.Err()
 			case <-ticker.C:
 				m.mu.RLock()
-				if proc, exists := m.processes[lang]; exists && proc.State() == StateReady && proc.RootPath() == rootPath {
+				if proc, exists := m.processes[key]; exists && proc.State() == StateReady {
 					m.mu.RUnlock()
 					return proc, nil
 				}
-				if !m.starting[lang] {
+				if !m.starting[key] {
 					m.mu.RUnlock()
 					return nil, fmt.Errorf("LSP for %s failed to start", lang)
 				}
@@ -153,47 +330,67 @@ func (m *Manager) getOrStartProcess(ctx context.Context, lang Language, rootPath
 			}
 		}
 	}
-	m.starting[lang] = true
+	if m.config.MaxConcurrent > 0 {
+		for len(m.processes) >= m.config.MaxConcurrent {
+			m.evictLocked(ctx)
+		}
+	}
+	m.starting[key] = true
 	m.mu.Unlock()
 
 	serverConfig, ok := m.config.Servers[lang]
 	if !ok {
 		m.mu.Lock()
-		delete(m.starting, lang)
+		delete(m.starting, key)
 		m.mu.Unlock()
 		return nil, fmt.Errorf("no server configured for language: %s", lang)
 	}
 
 	proc := NewProcess(serverConfig)
+	m.supervise(proc)
 	log.Info("starting LSP", "language", lang, "root", rootPath)
 	err := proc.Start(ctx, rootPath)
 
 	m.mu.Lock()
-	delete(m.starting, lang)
+	delete(m.starting, key)
 	if err != nil {
 		m.mu.Unlock()
 		return nil, fmt.Errorf("failed to start LSP: %w", err)
 	}
-	m.processes[lang] = proc
-	m.setupIdleTimer(lang)
+	m.processes[key] = proc
+	m.resetSupervisionLocked(key)
+	m.setupIdleTimer(key)
 	m.mu.Unlock()
 
 	return proc, nil
 }
 
+// evictLocked stops the server queried least recently
+func (m *Manager) evictLocked(ctx context.Context) {
+	var oldest sessionKey
+	var oldestAccess time.Time
+	first := true
+	for key := range m.processes {
+		access := m.lastAccess[key]
+		if first || access.Before(oldestAccess) {
+			oldest, oldestAccess, first = key, access, false
+		}
+	}
+	m.stopProcessLocked(ctx, oldest, "max_concurrent")
+}
 
-func (m *Manager) stopProcessLocked(ctx context.Context, lang Language) error {
-	proc, exists := m.processes[lang]
+func (m *Manager) stopProcessLocked(ctx context.Context, key sessionKey, reason string) error {
+	proc, exists := m.processes[key]
 	if !exists {
 		return nil
 	}
 
-	log.Info("stopping LSP", "language", lang, "reason", "idle")
+	log.Info("stopping LSP", "language", key.lang, "root", key.root, "reason", reason)
 
 	m.timerMu.Lock()
-	if timer, exists := m.idleTimers[lang]; exists {
+	if timer, exists := m.idleTimers[key]; exists {
 		timer.Stop()
-		delete(m.idleTimers, lang)
+		delete(m.idleTimers, key)
 	}
 	m.timerMu.Unlock()
 
@@ -204,25 +401,25 @@ func (m *Manager) stopProcessLocked(ctx context.Context, lang Language) error {
 		proc.Kill()
 	}
 
-	delete(m.processes, lang)
-	delete(m.lastAccess, lang)
+	delete(m.processes, key)
+	delete(m.lastAccess, key)
 
 	return nil
 }
 
-func (m *Manager) setupIdleTimer(lang Language) {
+func (m *Manager) setupIdleTimer(key sessionKey) {
 	m.timerMu.Lock()
 	defer m.timerMu.Unlock()
 
-	if timer, exists := m.idleTimers[lang]; exists {
+	if timer, exists := m.idleTimers[key]; exists {
 		timer.Stop()
 	}
 
-	log.Debug("LSP idle timer set", "language", lang, "timeout", m.config.IdleTimeout)
+	log.Debug("LSP idle timer set", "language", key.lang, "root", key.root, "timeout", m.config.IdleTimeout)
 
-	m.idleTimers[lang] = time.AfterFunc(m.config.IdleTimeout, func() {
+	m.idleTimers[key] = time.AfterFunc(m.config.IdleTimeout, func() {
 		m.mu.Lock()
-		lastAccess, accessExists := m.lastAccess[lang]
+		lastAccess, accessExists := m.lastAccess[key]
 		if !accessExists {
 			m.mu.Unlock()
 			return
@@ -231,16 +428,16 @@ func (m *Manager) setupIdleTimer(lang Language) {
 			m.mu.Unlock()
 			return
 		}
-		proc, procExists := m.processes[lang]
+		proc, procExists := m.processes[key]
 		if !procExists {
 			m.mu.Unlock()
 			return
 		}
-		delete(m.processes, lang)
-		delete(m.lastAccess, lang)
+		delete(m.processes, key)
+		delete(m.lastAccess, key)
 		m.mu.Unlock()
 
-		log.Info("stopping LSP", "language", lang, "reason", "idle")
+		log.Info("stopping LSP", "language", key.lang, "root", key.root, "reason", "idle")
 
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		if err := proc.Stop(ctx); err != nil {
@@ -250,18 +447,19 @@ func (m *Manager) setupIdleTimer(lang Language) {
 	})
 }
 
-func (m *Manager) recordAccess(lang Language) {
+func (m *Manager) recordAccess(key sessionKey) {
 	m.mu.Lock()
-	m.lastAccess[lang] = time.Now()
+	m.lastAccess[key] = time.Now()
 	m.mu.Unlock()
 
-	m.setupIdleTimer(lang)
+	m.setupIdleTimer(key)
 }
 
-func (m *Manager) GetProcess(lang Language) *Process {
+// GetProcess returns the server of lang rooted at rootPath, or nil
+func (m *Manager) GetProcess(lang Language, rootPath string) *Process {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
-	return m.processes[lang]
+	return m.processes[sessionKey{lang, rootPath}]
 }
 
 func (m *Manager) StartProcess(ctx context.Context, lang Language, rootPath string) error {
@@ -273,10 +471,21 @@ func (m *Manager) StartProcess(ctx context.Context, lang Language, rootPath stri
 	return err
 }
 
+// StopProcess stops the servers of lang under every root
 func (m *Manager) StopProcess(ctx context.Context, lang Language) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	return m.stopProcessLocked(ctx, lang)
+
+	var lastErr error
+	for key := range m.processes {
+		if key.lang != lang {
+			continue
+		}
+		if err := m.stopProcessLocked(ctx, key, "stopped"); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
 }
 
 func (m *Manager) StopAll(ctx context.Context) error {
@@ -286,8 +495,8 @@ func (m *Manager) StopAll(ctx context.Context) error {
 	log.Info("stopping all LSP processes")
 
 	var lastErr error
-	for lang := range m.processes {
-		if err := m.stopProcessLocked(ctx, lang); err != nil {
+	for key := range m.processes {
+		if err := m.stopProcessLocked(ctx, key, "shutdown"); err != nil {
 			lastErr = err
 		}
 	}
@@ -310,28 +519,40 @@ func (m *Manager) Close() error {
 	defer cancel()
 
 	var lastErr error
-	for lang := range m.processes {
-		if err := m.stopProcessLocked(ctx, lang); err != nil {
+	for key := range m.processes {
+		if err := m.stopProcessLocked(ctx, key, "shutdown"); err != nil {
 			lastErr = err
 		}
 	}
 	return lastErr
 }
 
+func (m *Manager) IsClosed() bool {
+	return m.isClosed()
+}
+
 func (m *Manager) isClosed() bool {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 	return m.closed
 }
 
-func (m *Manager) Stats() map[Language]LSPStats {
+// Stats returns the stats of every running server, sorted by language and
+// root
+func (m *Manager) Stats() []LSPStats {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	stats := make(map[Language]LSPStats)
-	for lang, proc := range m.processes {
-		stats[lang] = proc.Stats()
+	stats := make([]LSPStats, 0, len(m.processes))
+	for _, proc := range m.processes {
+		stats = append(stats, proc.Stats())
 	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Language != stats[j].Language {
+			return stats[i].Language < stats[j].Language
+		}
+		return stats[i].Root < stats[j].Root
+	})
 	return stats
 }
 
@@ -352,23 +573,41 @@ func (m *Manager) DetectLanguage(path string) Language {
 	return ""
 }
 
+// FindProjectRoot returns the project root of path for lang: the nearest
+// directory above it holding one of the language's root patterns. In a
+// monorepo that is the subproject path belongs to, not the repository root.
 func (m *Manager) FindProjectRoot(path string, lang Language) (string, bool) {
+	return m.findRootFrom(filepath.Dir(path), lang)
+}
+
+// findRootFrom walks up from dir to the first directory holding one of the
+// language's root patterns. The answer is cached for dir and every
+// directory passed on the way.
+func (m *Manager) findRootFrom(dir string, lang Language) (string, bool) {
 	config, ok := m.config.Servers[lang]
 	if !ok {
 		return "", false
 	}
 
-	dir := filepath.Dir(path)
 	absDir, err := filepath.Abs(dir)
 	if err != nil {
 		return "", false
 	}
 
+	var entry rootEntry
+	var walked []string
+walk:
 	for {
+		if cached, ok := m.cachedRoot(lang, absDir); ok {
+			entry = cached
+			break
+		}
+		walked = append(walked, absDir)
 		for _, pattern := range config.RootPatterns {
 			checkPath := filepath.Join(absDir, pattern)
 			if _, err := os.Stat(checkPath); err == nil {
-				return absDir, true
+				entry = rootEntry{root: absDir, found: true}
+				break walk
 			}
 		}
 
@@ -379,7 +618,39 @@ func (m *Manager) FindProjectRoot(path string, lang Language) (string, bool) {
 		absDir = parent
 	}
 
-	return "", false
+	m.rootMu.Lock()
+	for _, dir := range walked {
+		m.roots[rootKey{lang, dir}] = entry
+	}
+	m.rootMu.Unlock()
+
+	return entry.root, entry.found
+}
+
+func (m *Manager) cachedRoot(lang Language, dir string) (rootEntry, bool) {
+	m.rootMu.Lock()
+	defer m.rootMu.Unlock()
+	entry, ok := m.roots[rootKey{lang, dir}]
+	return entry, ok
+}
+
+// ForgetRoots drops the cached project roots when one of paths is a root
+// pattern file such as go.mod or package.json, so a subproject added or
+// removed since is routed to its own server. It reports whether it did.
+func (m *Manager) ForgetRoots(paths []string) bool {
+	for _, path := range paths {
+		name := filepath.Base(path)
+		for _, config := range m.config.Servers {
+			if !slices.Contains(config.RootPatterns, name) {
+				continue
+			}
+			m.rootMu.Lock()
+			m.roots = make(map[rootKey]rootEntry)
+			m.rootMu.Unlock()
+			return true
+		}
+	}
+	return false
 }
 
 func (m *Manager) IsLanguageSupported(lang Language) bool {
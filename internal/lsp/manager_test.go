@@ -0,0 +1,215 @@
+package lsp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// TestMain lets the test binary stand in for a language server: with
+// MAYLA_FAKE_LSP set it answers initialize, after MAYLA_FAKE_LSP_DELAY,
+// and every other request over stdio
+func TestMain(m *testing.M) {
+	if os.Getenv("MAYLA_FAKE_LSP") != "" {
+		serveFakeLSP()
+		return
+	}
+	os.Exit(m.Run())
+}
+
+func serveFakeLSP() {
+	delay, _ := time.ParseDuration(os.Getenv("MAYLA_FAKE_LSP_DELAY"))
+	stream := jsonrpc2.NewBufferedStream(&stdioReadWriteCloser{reader: os.Stdin, writer: os.Stdout}, jsonrpc2.VSCodeObjectCodec{})
+	conn := jsonrpc2.NewConn(context.Background(), stream, jsonrpc2.HandlerWithError(func(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (interface{}, error) {
+		switch req.Method {
+		case "initialize":
+			time.Sleep(delay)
+			return map[string]interface{}{"capabilities": map[string]interface{}{}}, nil
+		case "exit":
+			os.Exit(0)
+		}
+		return nil, nil
+	}))
+	<-conn.DisconnectNotify()
+}
+
+// newFakeManager returns a manager whose Go server is the fake server,
+// taking delay to initialize
+func newFakeManager(t *testing.T, delay time.Duration) *Manager {
+	t.Helper()
+	t.Setenv("MAYLA_FAKE_LSP", "1")
+	t.Setenv("MAYLA_FAKE_LSP_DELAY", delay.String())
+
+	config := DefaultManagerConfig()
+	server := config.Servers[LangGo]
+	server.Command = os.Args[0]
+	server.Args = nil
+	server.Settings = nil
+	server.MaxRestarts = 5
+	server.InitTimeout = 10 * time.Second
+	config.Servers = map[Language]ServerConfig{LangGo: server}
+
+	m := NewManager(config)
+	t.Cleanup(func() { m.Close() })
+	return m
+}
+
+// crash kills the server of proc behind the manager's back and waits for
+// the supervisor to notice
+func crash(t *testing.T, proc *Process) {
+	t.Helper()
+	proc.mu.RLock()
+	cmd := proc.cmd
+	proc.mu.RUnlock()
+	cmd.Process.Kill()
+	waitFor(t, func() bool { return proc.State() == StateError })
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func writeFile(t *testing.T, path string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFindProjectRootPerSubproject(t *testing.T) {
+	repo := t.TempDir()
+	writeFile(t, filepath.Join(repo, "services", "api", "go.mod"))
+	writeFile(t, filepath.Join(repo, "web", "package.json"))
+	writeFile(t, filepath.Join(repo, "web", "tsconfig.json"))
+
+	m := NewManager(DefaultManagerConfig())
+
+	tests := []struct {
+		path string
+		lang Language
+		root string
+	}{
+		{filepath.Join(repo, "services", "api", "internal", "h", "h.go"), LangGo, filepath.Join(repo, "services", "api")},
+		{filepath.Join(repo, "services", "api", "main.go"), LangGo, filepath.Join(repo, "services", "api")},
+		{filepath.Join(repo, "web", "src", "app.ts"), LangTypeScript, filepath.Join(repo, "web")},
+	}
+	for _, tt := range tests {
+		root, found := m.FindProjectRoot(tt.path, tt.lang)
+		if !found || root != tt.root {
+			t.Errorf("FindProjectRoot(%s, %s) = %q, %v; want %q", tt.path, tt.lang, root, found, tt.root)
+		}
+	}
+
+	if _, found := m.FindProjectRoot(filepath.Join(repo, "web", "src", "app.go"), LangGo); found {
+		t.Error("Go file outside any module should have no root")
+	}
+}
+
+func TestFindProjectRootCache(t *testing.T) {
+	repo := t.TempDir()
+	writeFile(t, filepath.Join(repo, "go.mod"))
+	file := filepath.Join(repo, "tools", "gen", "main.go")
+
+	m := NewManager(DefaultManagerConfig())
+	if root, _ := m.FindProjectRoot(file, LangGo); root != repo {
+		t.Fatalf("root = %q, want %q", root, repo)
+	}
+
+	// a nested module is not seen until a root file change is reported
+	nested := filepath.Join(repo, "tools", "go.mod")
+	writeFile(t, nested)
+	if root, _ := m.FindProjectRoot(file, LangGo); root != repo {
+		t.Errorf("cached root = %q, want %q", root, repo)
+	}
+
+	if m.ForgetRoots([]string{filepath.Join(repo, "tools", "gen", "main.go")}) {
+		t.Error("ForgetRoots should ignore files that are not root patterns")
+	}
+	if !m.ForgetRoots([]string{nested}) {
+		t.Fatal("ForgetRoots should act on a go.mod change")
+	}
+	if root, _ := m.FindProjectRoot(file, LangGo); root != filepath.Dir(nested) {
+		t.Errorf("root after ForgetRoots = %q, want %q", root, filepath.Dir(nested))
+	}
+}
+
+func TestRestartRacingQuery(t *testing.T) {
+	m := newFakeManager(t, 300*time.Millisecond)
+	root := t.TempDir()
+	ctx := context.Background()
+
+	proc, err := m.getOrStartProcess(ctx, LangGo, root)
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	crash(t, proc)
+
+	// restart ahead of the supervisor's backoff and query while the server
+	// is still initializing
+	restarted := make(chan struct{})
+	go func() {
+		m.restart(proc)
+		close(restarted)
+	}()
+	waitFor(t, func() bool { return proc.State() == StateStarting })
+
+	got, err := m.getOrStartProcess(ctx, LangGo, root)
+	<-restarted
+	if err != nil {
+		t.Fatalf("query during restart: %v", err)
+	}
+	if got != proc {
+		t.Fatal("query started a second server instead of waiting for the restart")
+	}
+
+	m.mu.RLock()
+	sessions, current := len(m.processes), m.processes[sessionKey{LangGo, root}]
+	m.mu.RUnlock()
+	if sessions != 1 || current != proc || proc.State() != StateReady {
+		t.Errorf("want the restarted server as the only session, got %d sessions, current %p (want %p), state %s", sessions, current, proc, proc.State())
+	}
+}
+
+func TestQueryStopsCrashedServer(t *testing.T) {
+	m := newFakeManager(t, 0)
+	root := t.TempDir()
+	ctx := context.Background()
+
+	proc, err := m.getOrStartProcess(ctx, LangGo, root)
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	crash(t, proc)
+
+	// a query before the supervisor's restart replaces the crashed server
+	replacement, err := m.getOrStartProcess(ctx, LangGo, root)
+	if err != nil {
+		t.Fatalf("query after crash: %v", err)
+	}
+	if replacement == proc {
+		t.Fatal("want a new server for the crashed one")
+	}
+	if state := proc.State(); state != StateStopped {
+		t.Errorf("replaced server state = %s, want %s", state, StateStopped)
+	}
+
+	// the restart scheduled for the crash must leave the replacement alone
+	m.restart(proc)
+	if state := proc.State(); state != StateStopped {
+		t.Errorf("replaced server restarted, state %s", state)
+	}
+}
@@ -30,6 +30,9 @@ type Process struct {
 	startedAt    time.Time
 	lastError    error
 
+	exited chan struct{}
+	onExit func(err error, uptime time.Duration)
+
 	mu       sync.RWMutex
 	stopOnce sync.Once
 }
@@ -69,7 +72,9 @@ func (p *Process) Start(ctx context.Context, rootPath string) error {
 	p.rootPath = rootPath
 	p.stopOnce = sync.Once{}
 
-	p.cmd = exec.CommandContext(ctx, path, p.config.Args...)
+	// The server outlives the request that started it; Stop, Kill and the
+	// idle timer end it
+	p.cmd = exec.Command(path, p.config.Args...)
 	p.cmd.Dir = rootPath
 	p.cmd.Env = append(os.Environ(),
 		fmt.Sprintf("HOME=%s", os.Getenv("HOME")),
@@ -100,11 +105,15 @@ func (p *Process) Start(ctx context.Context, rootPath string) error {
 	}
 
 	p.startedAt = time.Now()
+	p.exited = make(chan struct{})
+	go p.watch(p.cmd, p.exited)
 
 	clientConfig := ClientConfig{
 		Language:       p.config.Language,
 		InitTimeout:    p.config.InitTimeout,
 		RequestTimeout: p.config.RequestTimeout,
+		Settings:       p.config.Settings,
+		Section:        p.config.SettingsSection,
 	}
 
 	client, err := NewClient(ctx, stdin, stdout, clientConfig)
@@ -157,16 +166,11 @@ func (p *Process) Stop(ctx context.Context) error {
 				err = sigErr
 			}
 
-			done := make(chan error, 1)
-			go func() {
-				done <- p.cmd.Wait()
-			}()
-
 			select {
-			case <-done:
+			case <-p.exited:
 			case <-time.After(3 * time.Second):
 				p.cmd.Process.Kill()
-				<-done
+				<-p.exited
 			}
 		}
 
@@ -177,6 +181,49 @@ func (p *Process) Stop(ctx context.Context) error {
 	return err
 }
 
+// SetExitHandler sets the function called when the server exits without
+// being stopped, with the exit error and how long it had been running
+func (p *Process) SetExitHandler(fn func(err error, uptime time.Duration)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onExit = fn
+}
+
+// watch waits for cmd to exit. An exit that Stop, Kill or a failed Start did
+// not cause (they clear p.cmd first) is a crash: the process goes to
+// StateError and the exit handler runs.
+func (p *Process) watch(cmd *exec.Cmd, exited chan struct{}) {
+	waitErr := cmd.Wait()
+	close(exited)
+
+	p.mu.Lock()
+	if p.cmd != cmd {
+		p.mu.Unlock()
+		return
+	}
+
+	if waitErr == nil {
+		waitErr = errors.New("exit status 0")
+	}
+	err := fmt.Errorf("%s exited unexpectedly: %w", p.config.Command, waitErr)
+	uptime := time.Since(p.startedAt)
+
+	p.state.Store(StateError)
+	p.lastError = err
+	if p.client != nil {
+		p.client.Close()
+	}
+	p.client = nil
+	p.cmd = nil
+	onExit := p.onExit
+	p.mu.Unlock()
+
+	log.Warn("LSP exited unexpectedly", "language", p.config.Language, "error", waitErr, "uptime", uptime)
+	if onExit != nil {
+		onExit(err, uptime)
+	}
+}
+
 func (p *Process) Kill() error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -233,6 +280,7 @@ func (p *Process) Stats() LSPStats {
 
 	stats := LSPStats{
 		Language: p.config.Language,
+		Root:     p.rootPath,
 		State:    p.getState(),
 	}
 
@@ -0,0 +1,228 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/events"
+)
+
+const (
+	restartBaseDelay = time.Second
+	restartMaxDelay  = time.Minute
+
+	// stableUptime is how long a server has to run before a crash starts a
+	// fresh backoff instead of continuing the last one
+	stableUptime = 5 * time.Minute
+
+	// maxRestartEvents is how many restart events are kept per server
+	maxRestartEvents = 50
+
+	// crashWindow and repeatedCrashes decide when crashes are reported as
+	// repeated in health
+	crashWindow     = 10 * time.Minute
+	repeatedCrashes = 3
+)
+
+type RestartEventKind string
+
+const (
+	EventCrash         RestartEventKind = "crash"
+	EventRestarted     RestartEventKind = "restarted"
+	EventRestartFailed RestartEventKind = "restart_failed"
+	EventGaveUp        RestartEventKind = "gave_up"
+)
+
+// RestartEvent is one entry of a server's crash and restart log
+type RestartEvent struct {
+	Time     time.Time        `json:"time"`
+	Language Language         `json:"language"`
+	Root     string           `json:"root,omitempty"`
+	Kind     RestartEventKind `json:"kind"`
+	Attempt  int              `json:"attempt,omitempty"`
+	DelayMs  int64            `json:"delay_ms,omitempty"`
+	Error    string           `json:"error,omitempty"`
+}
+
+// supervision is the restart state of one server
+type supervision struct {
+	attempts int
+	crashes  int
+	restarts int
+	gaveUp   bool
+	events   []RestartEvent
+}
+
+// supervise has the manager restart proc when it crashes
+func (m *Manager) supervise(proc *Process) {
+	proc.SetExitHandler(func(err error, uptime time.Duration) {
+		m.handleFailure(proc, EventCrash, err, uptime)
+	})
+}
+
+// handleFailure records a crash or failed restart of proc and schedules the
+// next restart with exponential backoff, giving up once the attempts since
+// the server was last stable exceed MaxRestarts.
+func (m *Manager) handleFailure(proc *Process, kind RestartEventKind, err error, uptime time.Duration) {
+	lang, root := proc.Language(), proc.RootPath()
+	key := sessionKey{lang, root}
+
+	m.mu.Lock()
+	if m.closed || m.processes[key] != proc {
+		m.mu.Unlock()
+		return
+	}
+
+	sup := m.supervisionLocked(key)
+	if uptime >= stableUptime {
+		sup.attempts = 0
+	}
+	if kind == EventCrash {
+		sup.crashes++
+	}
+	sup.attempts++
+	attempt := sup.attempts
+
+	if attempt > m.config.Servers[lang].MaxRestarts {
+		sup.gaveUp = true
+		m.recordEventLocked(sup, RestartEvent{Language: lang, Root: root, Kind: kind, Attempt: attempt, Error: err.Error()})
+		m.recordEventLocked(sup, RestartEvent{Language: lang, Root: root, Kind: EventGaveUp, Attempt: attempt})
+		crashes := sup.crashes
+		m.mu.Unlock()
+		log.Error("LSP keeps failing, not restarting", "language", lang, "root", root, "restarts", attempt-1)
+		events.Publish(events.LSPCrashedRepeatedly, fmt.Sprintf("%s language server for %s keeps failing and was given up on", lang, root), map[string]interface{}{
+			"language": lang,
+			"root":     root,
+			"crashes":  crashes,
+			"gave_up":  true,
+			"error":    err.Error(),
+		})
+		return
+	}
+
+	delay := restartDelay(attempt)
+	m.recordEventLocked(sup, RestartEvent{
+		Language: lang,
+		Root:     root,
+		Kind:     kind,
+		Attempt:  attempt,
+		DelayMs:  delay.Milliseconds(),
+		Error:    err.Error(),
+	})
+	// reported once, when the crashes in the window reach the threshold
+	repeated := kind == EventCrash && recentCrashes(sup.events) == repeatedCrashes
+	m.mu.Unlock()
+
+	if repeated {
+		events.Publish(events.LSPCrashedRepeatedly, fmt.Sprintf("%s language server for %s crashed %d times in %v", lang, root, repeatedCrashes, crashWindow), map[string]interface{}{
+			"language": lang,
+			"root":     root,
+			"crashes":  repeatedCrashes,
+			"window":   crashWindow.String(),
+			"error":    err.Error(),
+		})
+	}
+
+	log.Info("restarting LSP", "language", lang, "root", root, "attempt", attempt, "delay", delay)
+	time.AfterFunc(delay, func() {
+		m.restart(proc)
+	})
+}
+
+// restart starts proc again under startMu, so a query arriving meanwhile
+// waits for it instead of starting a second server
+func (m *Manager) restart(proc *Process) {
+	lang, root := proc.Language(), proc.RootPath()
+	key := sessionKey{lang, root}
+
+	m.startMu.Lock()
+	defer m.startMu.Unlock()
+
+	m.mu.RLock()
+	current := !m.closed && m.processes[key] == proc
+	m.mu.RUnlock()
+	if !current || proc.State() != StateError {
+		return
+	}
+
+	if err := proc.Start(context.Background(), root); err != nil {
+		m.handleFailure(proc, EventRestartFailed, err, 0)
+		return
+	}
+
+	m.mu.Lock()
+	sup := m.supervisionLocked(key)
+	sup.restarts++
+	m.recordEventLocked(sup, RestartEvent{Language: lang, Root: root, Kind: EventRestarted, Attempt: sup.attempts})
+	m.mu.Unlock()
+
+	log.Info("LSP restarted", "language", lang, "root", root)
+}
+
+// restartDelay doubles from restartBaseDelay with each attempt, up to
+// restartMaxDelay
+func restartDelay(attempt int) time.Duration {
+	delay := restartBaseDelay
+	for i := 1; i < attempt && delay < restartMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, restartMaxDelay)
+}
+
+func (m *Manager) supervisionLocked(key sessionKey) *supervision {
+	sup, ok := m.supervised[key]
+	if !ok {
+		sup = &supervision{}
+		m.supervised[key] = sup
+	}
+	return sup
+}
+
+func (m *Manager) recordEventLocked(sup *supervision, event RestartEvent) {
+	event.Time = time.Now()
+	sup.events = append(sup.events, event)
+	if len(sup.events) > maxRestartEvents {
+		sup.events = sup.events[len(sup.events)-maxRestartEvents:]
+	}
+}
+
+// resetSupervisionLocked starts a fresh backoff for the server of key after
+// it was started on demand
+func (m *Manager) resetSupervisionLocked(key sessionKey) {
+	if sup, ok := m.supervised[key]; ok {
+		sup.attempts = 0
+		sup.gaveUp = false
+	}
+}
+
+// RepeatedCrashes returns the languages with a server, under any root, that
+// gave up restarting or crashed at least repeatedCrashes times within
+// crashWindow
+func (m *Manager) RepeatedCrashes() []Language {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var langs []Language
+	for key, sup := range m.supervised {
+		if sup.gaveUp || recentCrashes(sup.events) >= repeatedCrashes {
+			if !slices.Contains(langs, key.lang) {
+				langs = append(langs, key.lang)
+			}
+		}
+	}
+	slices.Sort(langs)
+	return langs
+}
+
+func recentCrashes(events []RestartEvent) int {
+	since := time.Now().Add(-crashWindow)
+	count := 0
+	for _, event := range events {
+		if event.Kind == EventCrash && event.Time.After(since) {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,53 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+type StatusTool struct {
+	manager *Manager
+}
+
+func NewStatusTool(manager *Manager) *StatusTool {
+	return &StatusTool{manager: manager}
+}
+
+func (t *StatusTool) Name() string {
+	return "lsp_status"
+}
+
+func (t *StatusTool) Description() string {
+	return "Report the configured language servers, one entry per project root a server runs under: whether they are installed, their process state and request stats, and the readiness of servers pre-started on workspace attach (auto_start)"
+}
+
+func (t *StatusTool) Title() string {
+	return "Language Server Status"
+}
+
+func (t *StatusTool) Annotations() map[string]bool {
+	return tools.ReadOnlyAnnotations()
+}
+
+func (t *StatusTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {},
+		"required": []
+	}`)
+}
+
+func (t *StatusTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	return map[string]interface{}{
+		"enabled":        t.manager.config.Enabled,
+		"auto_start":     t.manager.config.AutoStart,
+		"max_concurrent": t.manager.config.MaxConcurrent,
+		"servers":        t.manager.Status(),
+	}, nil
+}
@@ -1,6 +1,10 @@
 package lsp
 
-import "time"
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
 
 type LSPState string
 
@@ -135,6 +139,7 @@ const (
 
 type LSPStats struct {
 	Language     Language      `json:"language"`
+	Root         string        `json:"root,omitempty"`
 	State        LSPState      `json:"state"`
 	RequestCount int64         `json:"request_count"`
 	ErrorCount   int64         `json:"error_count"`
@@ -146,9 +151,23 @@ type LSPStats struct {
 }
 
 type InitializeParams struct {
-	ProcessID    int         `json:"processId"`
-	RootURI      string      `json:"rootUri"`
-	Capabilities interface{} `json:"capabilities"`
+	ProcessID             int         `json:"processId"`
+	RootURI               string      `json:"rootUri"`
+	InitializationOptions interface{} `json:"initializationOptions,omitempty"`
+	Capabilities          interface{} `json:"capabilities"`
+}
+
+type DidChangeConfigurationParams struct {
+	Settings interface{} `json:"settings"`
+}
+
+type ConfigurationItem struct {
+	ScopeURI string `json:"scopeUri,omitempty"`
+	Section  string `json:"section,omitempty"`
+}
+
+type ConfigurationParams struct {
+	Items []ConfigurationItem `json:"items"`
 }
 
 type InitializeResult struct {
@@ -158,6 +177,7 @@ type InitializeResult struct {
 type ServerCapabilities struct {
 	TextDocumentSync        interface{} `json:"textDocumentSync,omitempty"`
 	DocumentSymbolProvider  interface{} `json:"documentSymbolProvider,omitempty"`
+	ReferencesProvider      interface{} `json:"referencesProvider,omitempty"`
 }
 
 type DocumentSymbolParams struct {
@@ -167,3 +187,264 @@ type DocumentSymbolParams struct {
 type TextDocumentIdentifier struct {
 	URI string `json:"uri"`
 }
+
+type ReferenceContext struct {
+	IncludeDeclaration bool `json:"includeDeclaration"`
+}
+
+type ReferenceParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+	Context      ReferenceContext       `json:"context"`
+}
+
+type TextDocumentItem struct {
+	URI        string `json:"uri"`
+	LanguageID string `json:"languageId"`
+	Version    int    `json:"version"`
+	Text       string `json:"text"`
+}
+
+type DidOpenTextDocumentParams struct {
+	TextDocument TextDocumentItem `json:"textDocument"`
+}
+
+type DidCloseTextDocumentParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type TextDocumentPositionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+}
+
+type CompletionParams = TextDocumentPositionParams
+
+type HoverParams = TextDocumentPositionParams
+
+type ImplementationParams = TextDocumentPositionParams
+
+// Hover is a hover response. Contents is MarkupContent, a MarkedString or
+// an array of MarkedStrings; HoverText flattens it.
+type Hover struct {
+	Contents json.RawMessage `json:"contents"`
+	Range    *Range          `json:"range,omitempty"`
+}
+
+type CompletionItemKind int
+
+const (
+	CompletionKindText          CompletionItemKind = 1
+	CompletionKindMethod        CompletionItemKind = 2
+	CompletionKindFunction      CompletionItemKind = 3
+	CompletionKindConstructor   CompletionItemKind = 4
+	CompletionKindField         CompletionItemKind = 5
+	CompletionKindVariable      CompletionItemKind = 6
+	CompletionKindClass         CompletionItemKind = 7
+	CompletionKindInterface     CompletionItemKind = 8
+	CompletionKindModule        CompletionItemKind = 9
+	CompletionKindProperty      CompletionItemKind = 10
+	CompletionKindUnit          CompletionItemKind = 11
+	CompletionKindValue         CompletionItemKind = 12
+	CompletionKindEnum          CompletionItemKind = 13
+	CompletionKindKeyword       CompletionItemKind = 14
+	CompletionKindSnippet       CompletionItemKind = 15
+	CompletionKindColor         CompletionItemKind = 16
+	CompletionKindFile          CompletionItemKind = 17
+	CompletionKindReference     CompletionItemKind = 18
+	CompletionKindFolder        CompletionItemKind = 19
+	CompletionKindEnumMember    CompletionItemKind = 20
+	CompletionKindConstant      CompletionItemKind = 21
+	CompletionKindStruct        CompletionItemKind = 22
+	CompletionKindEvent         CompletionItemKind = 23
+	CompletionKindOperator      CompletionItemKind = 24
+	CompletionKindTypeParameter CompletionItemKind = 25
+)
+
+func (k CompletionItemKind) String() string {
+	names := map[CompletionItemKind]string{
+		CompletionKindText:          "text",
+		CompletionKindMethod:        "method",
+		CompletionKindFunction:      "function",
+		CompletionKindConstructor:   "constructor",
+		CompletionKindField:         "field",
+		CompletionKindVariable:      "variable",
+		CompletionKindClass:         "class",
+		CompletionKindInterface:     "interface",
+		CompletionKindModule:        "module",
+		CompletionKindProperty:      "property",
+		CompletionKindUnit:          "unit",
+		CompletionKindValue:         "value",
+		CompletionKindEnum:          "enum",
+		CompletionKindKeyword:       "keyword",
+		CompletionKindSnippet:       "snippet",
+		CompletionKindColor:         "color",
+		CompletionKindFile:          "file",
+		CompletionKindReference:     "reference",
+		CompletionKindFolder:        "folder",
+		CompletionKindEnumMember:    "enumMember",
+		CompletionKindConstant:      "constant",
+		CompletionKindStruct:        "struct",
+		CompletionKindEvent:         "event",
+		CompletionKindOperator:      "operator",
+		CompletionKindTypeParameter: "typeParameter",
+	}
+	if name, ok := names[k]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+type CompletionTextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
+
+type CompletionItem struct {
+	Label         string              `json:"label"`
+	Kind          CompletionItemKind  `json:"kind,omitempty"`
+	Tags          []int               `json:"tags,omitempty"`
+	Detail        string              `json:"detail,omitempty"`
+	Documentation json.RawMessage     `json:"documentation,omitempty"`
+	Deprecated    bool                `json:"deprecated,omitempty"`
+	SortText      string              `json:"sortText,omitempty"`
+	FilterText    string              `json:"filterText,omitempty"`
+	InsertText    string              `json:"insertText,omitempty"`
+	TextEdit      *CompletionTextEdit `json:"textEdit,omitempty"`
+}
+
+// CompletionList is a completion response. Servers may answer with a bare
+// item array, which the client wraps as a complete list.
+type CompletionList struct {
+	IsIncomplete bool             `json:"isIncomplete"`
+	Items        []CompletionItem `json:"items"`
+}
+
+// HoverText flattens hover contents into markdown, fencing MarkedString
+// code with its language
+func HoverText(contents json.RawMessage) string {
+	var markup struct {
+		Kind     string `json:"kind"`
+		Value    string `json:"value"`
+		Language string `json:"language"`
+	}
+	var text string
+	var parts []json.RawMessage
+
+	switch {
+	case json.Unmarshal(contents, &text) == nil:
+		return text
+	case json.Unmarshal(contents, &parts) == nil:
+		texts := make([]string, 0, len(parts))
+		for _, part := range parts {
+			if t := HoverText(part); t != "" {
+				texts = append(texts, t)
+			}
+		}
+		return strings.Join(texts, "\n\n")
+	case json.Unmarshal(contents, &markup) == nil:
+		if markup.Language != "" {
+			return "```" + markup.Language + "\n" + markup.Value + "\n```"
+		}
+		return markup.Value
+	}
+	return ""
+}
+
+// Diagnostic is a problem a server reported in a document, passed back to
+// it as the context of a code action request
+type Diagnostic struct {
+	Range    Range           `json:"range"`
+	Severity int             `json:"severity,omitempty"`
+	Code     json.RawMessage `json:"code,omitempty"`
+	Source   string          `json:"source,omitempty"`
+	Message  string          `json:"message"`
+}
+
+type CodeActionContext struct {
+	Diagnostics []Diagnostic `json:"diagnostics"`
+	Only        []string     `json:"only,omitempty"`
+}
+
+type CodeActionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+	Context      CodeActionContext      `json:"context"`
+}
+
+type Command struct {
+	Title     string            `json:"title"`
+	Command   string            `json:"command"`
+	Arguments []json.RawMessage `json:"arguments,omitempty"`
+}
+
+// CodeAction is a quick fix or refactoring a server offers for a range. It
+// carries an edit, a command to execute, or both; servers with lazy
+// resolution fill in the edit on codeAction/resolve.
+type CodeAction struct {
+	Title       string       `json:"title"`
+	Kind        string       `json:"kind,omitempty"`
+	Diagnostics []Diagnostic `json:"diagnostics,omitempty"`
+	IsPreferred bool         `json:"isPreferred,omitempty"`
+	Disabled    *struct {
+		Reason string `json:"reason"`
+	} `json:"disabled,omitempty"`
+	Edit    *WorkspaceEdit  `json:"edit,omitempty"`
+	Command *Command        `json:"command,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+type ExecuteCommandParams struct {
+	Command   string            `json:"command"`
+	Arguments []json.RawMessage `json:"arguments,omitempty"`
+}
+
+type TextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
+
+type OptionalVersionedTextDocumentIdentifier struct {
+	URI     string `json:"uri"`
+	Version *int   `json:"version"`
+}
+
+// FileOperationOptions are the options of a create, rename or delete
+// document change
+type FileOperationOptions struct {
+	Overwrite         bool `json:"overwrite,omitempty"`
+	IgnoreIfExists    bool `json:"ignoreIfExists,omitempty"`
+	Recursive         bool `json:"recursive,omitempty"`
+	IgnoreIfNotExists bool `json:"ignoreIfNotExists,omitempty"`
+}
+
+// DocumentChange is one entry of WorkspaceEdit.DocumentChanges: a
+// TextDocumentEdit when Kind is empty, otherwise a create, rename or delete
+// file operation
+type DocumentChange struct {
+	Kind         string                                   `json:"kind,omitempty"`
+	TextDocument *OptionalVersionedTextDocumentIdentifier `json:"textDocument,omitempty"`
+	Edits        []TextEdit                               `json:"edits,omitempty"`
+	URI          string                                   `json:"uri,omitempty"`
+	OldURI       string                                   `json:"oldUri,omitempty"`
+	NewURI       string                                   `json:"newUri,omitempty"`
+	Options      *FileOperationOptions                    `json:"options,omitempty"`
+}
+
+// WorkspaceEdit is a set of changes to many documents. Servers send either
+// Changes, keyed by URI, or the ordered DocumentChanges, which take
+// precedence when both are set.
+type WorkspaceEdit struct {
+	Changes         map[string][]TextEdit `json:"changes,omitempty"`
+	DocumentChanges []DocumentChange      `json:"documentChanges,omitempty"`
+}
+
+type ApplyWorkspaceEditParams struct {
+	Label string        `json:"label,omitempty"`
+	Edit  WorkspaceEdit `json:"edit"`
+}
+
+type ApplyWorkspaceEditResult struct {
+	Applied       bool   `json:"applied"`
+	FailureReason string `json:"failureReason,omitempty"`
+}
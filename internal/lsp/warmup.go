@@ -0,0 +1,207 @@
+package lsp
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+type WarmUpState string
+
+const (
+	WarmUpPending  WarmUpState = "pending"
+	WarmUpStarting WarmUpState = "starting"
+	WarmUpReady    WarmUpState = "ready"
+	WarmUpFailed   WarmUpState = "failed"
+	WarmUpSkipped  WarmUpState = "skipped"
+)
+
+// WarmUpStatus is the outcome of pre-starting one language server for a
+// workspace root
+type WarmUpStatus struct {
+	Language   Language    `json:"language"`
+	Root       string      `json:"root"`
+	State      WarmUpState `json:"state"`
+	Reason     string      `json:"reason,omitempty"`
+	StartedAt  time.Time   `json:"started_at,omitempty"`
+	ReadyAt    time.Time   `json:"ready_at,omitempty"`
+	DurationMs int64       `json:"duration_ms,omitempty"`
+}
+
+// ServerStatus describes a configured language server for lsp_status, one
+// per project root it runs under
+type ServerStatus struct {
+	Language  Language      `json:"language"`
+	Root      string        `json:"root,omitempty"`
+	Command   string        `json:"command"`
+	Enabled   bool          `json:"enabled"`
+	Installed bool          `json:"installed"`
+	State     LSPState      `json:"state"`
+	Stats     *LSPStats     `json:"stats,omitempty"`
+	WarmUp    *WarmUpStatus `json:"warm_up,omitempty"`
+
+	Crashes  int            `json:"crashes,omitempty"`
+	Restarts int            `json:"restarts,omitempty"`
+	GaveUp   bool           `json:"gave_up,omitempty"`
+	Events   []RestartEvent `json:"restart_events,omitempty"`
+}
+
+// WarmUp pre-starts the servers for langs (most used first) under root so
+// the first query does not pay their startup cost. It does nothing unless
+// AutoStart is set, and keeps at most MaxConcurrent servers running,
+// counting those already up. It blocks until every start has finished;
+// the servers live until ctx is done or they go idle.
+func (m *Manager) WarmUp(ctx context.Context, root string, langs []Language) {
+	if !m.config.AutoStart || m.isClosed() {
+		return
+	}
+
+	m.mu.RLock()
+	running := len(m.processes)
+	m.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, lang := range langs {
+		config, ok := m.config.Servers[lang]
+		if !ok || !config.Enabled {
+			continue
+		}
+
+		rootPath, found := m.findRootFrom(root, lang)
+		if !found {
+			rootPath = root
+		}
+
+		if proc := m.GetProcess(lang, rootPath); proc != nil && proc.State() == StateReady && proc.RootPath() == rootPath {
+			m.setWarmUp(WarmUpStatus{Language: lang, Root: rootPath, State: WarmUpReady, Reason: "already running"})
+			continue
+		}
+		if !NewProcess(config).IsInstalled() {
+			m.setWarmUp(WarmUpStatus{Language: lang, Root: rootPath, State: WarmUpSkipped, Reason: config.Command + " not installed"})
+			continue
+		}
+		if m.config.MaxConcurrent > 0 && running >= m.config.MaxConcurrent {
+			m.setWarmUp(WarmUpStatus{Language: lang, Root: rootPath, State: WarmUpSkipped, Reason: "max_concurrent reached"})
+			continue
+		}
+		running++
+
+		m.setWarmUp(WarmUpStatus{Language: lang, Root: rootPath, State: WarmUpPending})
+		wg.Add(1)
+		go func(lang Language, rootPath string) {
+			defer wg.Done()
+			m.warmUpOne(ctx, lang, rootPath)
+		}(lang, rootPath)
+	}
+	wg.Wait()
+}
+
+func (m *Manager) warmUpOne(ctx context.Context, lang Language, rootPath string) {
+	status := WarmUpStatus{Language: lang, Root: rootPath, State: WarmUpStarting, StartedAt: time.Now()}
+	m.setWarmUp(status)
+	log.Info("warming up LSP", "language", lang, "root", rootPath)
+
+	_, err := m.getOrStartProcess(ctx, lang, rootPath)
+	status.DurationMs = time.Since(status.StartedAt).Milliseconds()
+	if err != nil {
+		status.State = WarmUpFailed
+		status.Reason = err.Error()
+		m.setWarmUp(status)
+		log.Warn("LSP warm-up failed", "language", lang, "error", err)
+		return
+	}
+
+	// An access starts the idle clock, so a warmed server nobody queries
+	// still stops after IdleTimeout
+	m.recordAccess(sessionKey{lang, rootPath})
+
+	status.State = WarmUpReady
+	status.ReadyAt = time.Now()
+	m.setWarmUp(status)
+	log.Info("LSP warmed up", "language", lang, "duration_ms", status.DurationMs)
+}
+
+func (m *Manager) setWarmUp(status WarmUpStatus) {
+	m.mu.Lock()
+	m.warmUps[sessionKey{status.Language, status.Root}] = status
+	m.mu.Unlock()
+}
+
+// statusEvents is how many recent restart events Status reports per server
+const statusEvents = 10
+
+// Status reports every configured server with its process state, the
+// result of the last warm-up and its recent crashes and restarts, sorted by
+// language. A language with servers under several project roots gets an
+// entry per root.
+func (m *Manager) Status() []ServerStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	statuses := make([]ServerStatus, 0, len(m.config.Servers))
+	for lang, config := range m.config.Servers {
+		base := ServerStatus{
+			Language:  lang,
+			Command:   config.Command,
+			Enabled:   config.Enabled,
+			Installed: NewProcess(config).IsInstalled(),
+			State:     StateStopped,
+		}
+		keys := m.sessionKeysLocked(lang)
+		if len(keys) == 0 {
+			statuses = append(statuses, base)
+			continue
+		}
+		for _, key := range keys {
+			status := base
+			status.Root = key.root
+			if proc, ok := m.processes[key]; ok {
+				stats := proc.Stats()
+				status.State = stats.State
+				status.Stats = &stats
+			}
+			if warmUp, ok := m.warmUps[key]; ok {
+				status.WarmUp = &warmUp
+			}
+			if sup, ok := m.supervised[key]; ok {
+				status.Crashes = sup.crashes
+				status.Restarts = sup.restarts
+				status.GaveUp = sup.gaveUp
+				status.Events = append([]RestartEvent(nil), sup.events[max(0, len(sup.events)-statusEvents):]...)
+			}
+			statuses = append(statuses, status)
+		}
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Language != statuses[j].Language {
+			return statuses[i].Language < statuses[j].Language
+		}
+		return statuses[i].Root < statuses[j].Root
+	})
+	return statuses
+}
+
+// sessionKeysLocked returns the servers of lang the manager knows of:
+// running, warmed up or supervised
+func (m *Manager) sessionKeysLocked(lang Language) []sessionKey {
+	seen := make(map[sessionKey]bool)
+	var keys []sessionKey
+	add := func(key sessionKey) {
+		if key.lang == lang && !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	for key := range m.processes {
+		add(key)
+	}
+	for key := range m.warmUps {
+		add(key)
+	}
+	for key := range m.supervised {
+		add(key)
+	}
+	return keys
+}
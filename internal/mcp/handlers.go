@@ -1,12 +1,19 @@
 package mcp
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"runtime/debug"
+	"sync"
 	"time"
 
+	"github.com/alucardeht/may-la-mcp/internal/injection"
+	"github.com/alucardeht/may-la-mcp/internal/intel"
 	"github.com/alucardeht/may-la-mcp/internal/logger"
+	"github.com/alucardeht/may-la-mcp/internal/redact"
+	"github.com/alucardeht/may-la-mcp/internal/spill"
 	"github.com/alucardeht/may-la-mcp/internal/tools"
 	"github.com/alucardeht/may-la-mcp/pkg/protocol"
 	"github.com/alucardeht/may-la-mcp/pkg/version"
@@ -19,6 +26,9 @@ type Handler struct {
 	startTime time.Time
 	initialized bool
 	clientInfo ClientInfo
+	redactor   *redact.Redactor
+	spill      *spill.Store
+	injection  *injection.Scanner
 }
 
 type ClientInfo struct {
@@ -35,7 +45,7 @@ func NewHandler(registry *tools.Registry) *Handler {
 	}
 }
 
-func (h *Handler) Handle(req *Request) *Response {
+func (h *Handler) Handle(ctx context.Context, req *Request) *Response {
 	resp := &Response{
 		JSONRPC: "2.0",
 		ID:      req.ID,
@@ -43,7 +53,7 @@ func (h *Handler) Handle(req *Request) *Response {
 
 	switch req.Method {
 	case "initialize":
-		result, err := h.handleInitialize(req)
+		result, err := h.handleInitialize(ctx, req)
 		if err != nil {
 			resp.Error = &protocol.JSONRPCError{
 				Code:    -32603,
@@ -55,12 +65,21 @@ func (h *Handler) Handle(req *Request) *Response {
 	case "ping":
 		resp.Result = map[string]interface{}{}
 	case "tools/list":
-		resp.Result = h.handleListTools()
+		resp.Result = h.handleListTools(ctx)
 	case "tools/call":
-		result, err := h.handleCallTool(req)
+		result, err := h.handleCallTool(ctx, req)
+		if err != nil {
+			resp.Error = toolCallError(err)
+		} else {
+			resp.Result = result
+		}
+	case "resources/list":
+		resp.Result = h.handleListResources()
+	case "resources/read":
+		result, err := h.handleReadResource(req)
 		if err != nil {
 			resp.Error = &protocol.JSONRPCError{
-				Code:    -32603,
+				Code:    -32002,
 				Message: err.Error(),
 			}
 		} else {
@@ -79,7 +98,7 @@ func (h *Handler) Handle(req *Request) *Response {
 	return resp
 }
 
-func (h *Handler) handleInitialize(req *Request) (interface{}, error) {
+func (h *Handler) handleInitialize(ctx context.Context, req *Request) (interface{}, error) {
 	initReq := struct {
 		ProtocolVersion string `json:"protocolVersion"`
 		ClientInfo struct {
@@ -99,14 +118,21 @@ func (h *Handler) handleInitialize(req *Request) (interface{}, error) {
 
 	h.clientInfo.Name = initReq.ClientInfo.Name
 	h.clientInfo.Version = initReq.ClientInfo.Version
+	tools.SetClient(ctx, initReq.ClientInfo.Name, initReq.ClientInfo.Version)
 
 	negotiatedVersion := negotiateProtocolVersion(initReq.ProtocolVersion)
 
+	capabilities := map[string]interface{}{
+		"tools": map[string]interface{}{},
+	}
+	// spilled results are served as resources
+	if h.spill != nil {
+		capabilities["resources"] = map[string]interface{}{}
+	}
+
 	return map[string]interface{}{
 		"protocolVersion": negotiatedVersion,
-		"capabilities": map[string]interface{}{
-			"tools": map[string]interface{}{},
-		},
+		"capabilities":    capabilities,
 		"serverInfo": map[string]interface{}{
 			"name":    "May-la MCP Server",
 			"version": version.Version,
@@ -124,21 +150,41 @@ func negotiateProtocolVersion(clientVersion string) string {
 	return version.ProtocolVersion
 }
 
-func (h *Handler) handleListTools() interface{} {
-	toolsList := h.registry.List()
-	toolsData := make([]map[string]interface{}, len(toolsList))
+// handleListTools lists the registered tools, under their qualified names
+// when the registry is namespaced and with the worked examples of those
+// that have some. In a read-only session the
+// mutating ones stay listed but are marked disabled; in a dry-run session
+// their descriptions say they only preview changes.
+func (h *Handler) handleListTools(ctx context.Context) interface{} {
+	session := tools.SessionFrom(ctx)
+
+	entries := h.registry.Dump()
+	namespaced := h.registry.Namespaced()
+	toolsData := make([]map[string]interface{}, len(entries))
 
-	for i, t := range toolsList {
+	for i, entry := range entries {
+		t := entry.Tool
 		var schema interface{}
 		if err := json.Unmarshal(t.Schema(), &schema); err != nil {
 			schema = json.RawMessage(t.Schema())
 		}
 
+		examples := tools.ExamplesOf(t)
+		description := t.Description() + tools.DescribeExamples(examples)
+
 		toolData := map[string]interface{}{
-			"name":        t.Name(),
-			"description": t.Description(),
+			"name":        entry.Listed(namespaced),
+			"description": description,
 			"inputSchema": schema,
 		}
+		if len(examples) > 0 {
+			toolData["_meta"] = map[string]interface{}{"examples": examples}
+		}
+
+		// a dry run returns a preview instead of the tool's result
+		if output, ok := t.(tools.OutputTool); ok && !(session.DryRun && tools.IsMutating(t)) {
+			toolData["outputSchema"] = json.RawMessage(output.OutputSchema())
+		}
 
 		if annotated, ok := t.(tools.AnnotatedTool); ok {
 			if title := annotated.Title(); title != "" {
@@ -149,6 +195,29 @@ func (h *Handler) handleListTools() interface{} {
 			}
 		}
 
+		_, previews := t.(tools.DryRunner)
+		var disabled string
+		switch {
+		case !tools.IsMutating(t):
+		case session.ReadOnly:
+			disabled = "read-only mode"
+		case session.DryRun && previews:
+			toolData["description"] = "[Dry-run: previews changes without applying them] " + description
+		case session.DryRun:
+			disabled = "dry-run mode"
+		}
+
+		if disabled != "" {
+			toolData["description"] = "[Disabled: " + disabled + "] " + description
+			annotations := map[string]bool{"disabled": true}
+			if existing, ok := toolData["annotations"].(map[string]bool); ok {
+				for k, v := range existing {
+					annotations[k] = v
+				}
+			}
+			toolData["annotations"] = annotations
+		}
+
 		toolsData[i] = toolData
 	}
 
@@ -161,19 +230,20 @@ func (h *Handler) handleInitializedNotification(req *Request) {
 	h.initialized = true
 }
 
-func (h *Handler) handleCallTool(req *Request) (result interface{}, err error) {
+func (h *Handler) handleCallTool(ctx context.Context, req *Request) (result interface{}, err error) {
 	defer func() {
 		if r := recover(); r != nil {
-			err = fmt.Errorf("tool execution panicked: %v", r)
-			log.Error("tool panic recovered",
-				"panic", r,
-				"stack", string(debug.Stack()))
+			crashID := tools.ReportPanic("tools/call", r, debug.Stack())
+			result, err = nil, tools.NewPanicError("tools/call", r, crashID)
 		}
 	}()
 
 	callReq := struct {
 		Name      string          `json:"name"`
 		Arguments json.RawMessage `json:"arguments"`
+		Meta      struct {
+			ProgressToken interface{} `json:"progressToken"`
+		} `json:"_meta"`
 	}{}
 
 	paramsData, err := json.Marshal(req.Params)
@@ -189,7 +259,13 @@ func (h *Handler) handleCallTool(req *Request) (result interface{}, err error) {
 		return nil, fmt.Errorf("tool name is required")
 	}
 
-	result, err = h.registry.ExecuteWithTimeout(callReq.Name, callReq.Arguments, 4*time.Minute)
+	// A call with a progress token streams its partial results as progress
+	// notifications, when the transport can deliver them
+	if notify := notifierFrom(ctx); notify != nil && callReq.Meta.ProgressToken != nil {
+		ctx = tools.WithStream(ctx, h.streamProgress(callReq.Name, callReq.Meta.ProgressToken, callReq.Arguments, notify))
+	}
+
+	result, err = h.registry.ExecuteWithTimeout(ctx, callReq.Name, callReq.Arguments, 4*time.Minute)
 	if err != nil {
 		return nil, err
 	}
@@ -199,12 +275,283 @@ func (h *Handler) handleCallTool(req *Request) (result interface{}, err error) {
 		return nil, fmt.Errorf("failed to marshal result: %w", err)
 	}
 
-	return map[string]interface{}{
+	if h.redactor != nil && !h.redactionOptedOut(callReq.Arguments) {
+		redacted, count, err := h.redactor.RedactJSON(resultJSON)
+		if err != nil {
+			return nil, fmt.Errorf("failed to redact result: %w", err)
+		}
+		if count > 0 {
+			log.Info("redacted secrets from tool result", "tool", callReq.Name, "count", count)
+			resultJSON = redacted
+		}
+	}
+
+	// scanned before spilling, so a truncated result still carries the
+	// warning for what was cut
+	warning := h.scanResult(callReq.Name, callReq.Arguments, resultJSON)
+
+	if h.spill.Exceeds(resultJSON) {
+		response, err := h.spillResult(callReq.Name, resultJSON)
+		if err == nil {
+			return h.withTokenEstimate(ctx, callReq.Name, withWarning(response, warning)), nil
+		}
+		log.Warn("failed to spill oversized result, returning it whole", "tool", callReq.Name, "size", len(resultJSON), "error", err)
+	}
+
+	response := map[string]interface{}{
 		"content": []map[string]interface{}{
 			{
 				"type": "text",
 				"text": string(resultJSON),
 			},
 		},
+	}
+	// Tools with an output schema also return the result as structured
+	// content, which clients can validate against it
+	if tool, ok := h.registry.Get(callReq.Name); ok {
+		if _, preview := result.(*tools.Preview); !preview && isOutputTool(tool) {
+			response["structuredContent"] = json.RawMessage(resultJSON)
+		}
+	}
+	return h.withTokenEstimate(ctx, callReq.Name, withWarning(response, warning)), nil
+}
+
+// scanResult returns the prompt injection warning for a result, or "" when
+// it has no findings or is not scanned. Findings are located by the path
+// the call was given when the result does not name one.
+func (h *Handler) scanResult(name string, arguments json.RawMessage, resultJSON []byte) string {
+	if h.injection.Skips(name) {
+		return ""
+	}
+	var args struct {
+		Path string `json:"path"`
+		File string `json:"file"`
+	}
+	json.Unmarshal(arguments, &args)
+	location := args.Path
+	if location == "" {
+		location = args.File
+	}
+
+	findings, err := h.injection.ScanJSON(resultJSON, location)
+	if err != nil {
+		log.Warn("failed to scan result for prompt injection", "tool", name, "error", err)
+		return ""
+	}
+	if len(findings) == 0 {
+		return ""
+	}
+	log.Warn("possible prompt injection in tool result", "tool", name, "findings", len(findings))
+	return injection.Warning(findings)
+}
+
+// withWarning puts warning ahead of the content of a tool call response
+// and flags it in _meta
+func withWarning(response map[string]interface{}, warning string) map[string]interface{} {
+	if warning == "" {
+		return response
+	}
+	content, _ := response["content"].([]map[string]interface{})
+	response["content"] = append([]map[string]interface{}{{"type": "text", "text": warning}}, content...)
+	meta, _ := response["_meta"].(map[string]interface{})
+	if meta == nil {
+		meta = map[string]interface{}{}
+	}
+	meta["injection_warning"] = true
+	response["_meta"] = meta
+	return response
+}
+
+// withTokenEstimate puts the estimated tokens of the text content of a tool
+// call response in its _meta and adds them to the session's context budget,
+// whose running total goes along with it
+func (h *Handler) withTokenEstimate(ctx context.Context, name string, response map[string]interface{}) map[string]interface{} {
+	tokens := 0
+	content, _ := response["content"].([]map[string]interface{})
+	for _, block := range content {
+		if text, ok := block["text"].(string); ok {
+			tokens += intel.EstimateTokens(text)
+		}
+	}
+
+	meta, _ := response["_meta"].(map[string]interface{})
+	if meta == nil {
+		meta = map[string]interface{}{}
+	}
+	meta["estimated_tokens"] = tokens
+	if budget := tools.ContextBudgetFrom(ctx); budget != nil {
+		if canonical, ok := h.registry.Resolve(name); ok {
+			name = canonical
+		}
+		meta["session_tokens"] = budget.Add(name, tokens)
+	}
+	response["_meta"] = meta
+	return response
+}
+
+// streamProgress returns the function sending each partial result of a
+// tool call as a notifications/progress carrying the result's JSON in its
+// message. Partial results are redacted like final ones; one that cannot be
+// redacted is not sent.
+func (h *Handler) streamProgress(name string, token interface{}, arguments json.RawMessage, notify NotifyFunc) tools.StreamFunc {
+	redact := h.redactor != nil && !h.redactionOptedOut(arguments)
+
+	var mu sync.Mutex
+	progress := 0
+	return func(partial interface{}) {
+		data, err := json.Marshal(partial)
+		if err != nil {
+			log.Warn("failed to encode partial result", "tool", name, "error", err)
+			return
+		}
+		if redact {
+			redacted, _, err := h.redactor.RedactJSON(data)
+			if err != nil {
+				log.Warn("failed to redact partial result", "tool", name, "error", err)
+				return
+			}
+			data = redacted
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		progress++
+		notification, err := json.Marshal(&protocol.JSONRPCRequest{
+			JSONRPC: "2.0",
+			Method:  "notifications/progress",
+			Params: map[string]interface{}{
+				"progressToken": token,
+				"progress":      progress,
+				"message":       string(data),
+			},
+		})
+		if err != nil {
+			log.Warn("failed to encode progress notification", "tool", name, "error", err)
+			return
+		}
+		notify(notification)
+	}
+}
+
+// spillResult writes a result over the response size limit to a file and
+// returns a preview of it with a link to the whole result. The preview is
+// not the structured result, so structuredContent is left out.
+func (h *Handler) spillResult(name string, resultJSON []byte) (map[string]interface{}, error) {
+	saved, err := h.spill.Save(name, resultJSON)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("spilled oversized tool result", "tool", name, "size", saved.Size, "path", saved.Path)
+
+	notice := fmt.Sprintf("[Truncated: the result is %d bytes, over the %d-byte response limit. The whole result is in %s until %s; read it with resources/read on %s or with the read tool's offset and limit.]",
+		saved.Size, h.spill.Limit(), saved.Path, saved.Expires.Format(time.RFC3339), saved.URI)
+
+	return map[string]interface{}{
+		"content": []map[string]interface{}{
+			{
+				"type": "text",
+				"text": saved.Preview + "\n\n" + notice,
+			},
+			{
+				"type":        "resource_link",
+				"uri":         saved.URI,
+				"name":        saved.Name,
+				"description": fmt.Sprintf("Full result of %s", name),
+				"mimeType":    "application/json",
+				"size":        saved.Size,
+			},
+		},
+		"_meta": map[string]interface{}{
+			"truncated": true,
+			"size":      saved.Size,
+			"expires":   saved.Expires.Format(time.RFC3339),
+		},
+	}, nil
+}
+
+// handleListResources lists the spilled results still kept
+func (h *Handler) handleListResources() interface{} {
+	resources := []map[string]interface{}{}
+	if h.spill != nil {
+		for _, result := range h.spill.List() {
+			resources = append(resources, map[string]interface{}{
+				"uri":      result.URI,
+				"name":     result.Name,
+				"mimeType": "application/json",
+				"size":     result.Size,
+			})
+		}
+	}
+	return map[string]interface{}{"resources": resources}
+}
+
+func (h *Handler) handleReadResource(req *Request) (interface{}, error) {
+	readReq := struct {
+		URI string `json:"uri"`
+	}{}
+
+	paramsData, err := json.Marshal(req.Params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal params: %w", err)
+	}
+	if err := json.Unmarshal(paramsData, &readReq); err != nil {
+		return nil, fmt.Errorf("failed to parse resource read request: %w", err)
+	}
+	if h.spill == nil {
+		return nil, fmt.Errorf("resource not found: %s", readReq.URI)
+	}
+
+	data, err := h.spill.Read(readReq.URI)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"contents": []map[string]interface{}{
+			{
+				"uri":      readReq.URI,
+				"mimeType": "application/json",
+				"text":     string(data),
+			},
+		},
 	}, nil
 }
+
+func isOutputTool(tool tools.Tool) bool {
+	_, ok := tool.(tools.OutputTool)
+	return ok
+}
+
+// redactionOptedOut reports whether the caller asked to skip redaction via a
+// "redact": false argument and the configuration allows it.
+func (h *Handler) redactionOptedOut(arguments json.RawMessage) bool {
+	if !h.redactor.AllowOptOut() || len(arguments) == 0 {
+		return false
+	}
+
+	var args struct {
+		Redact *bool `json:"redact"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return false
+	}
+	return args.Redact != nil && !*args.Redact
+}
+
+func toolCallError(err error) *protocol.JSONRPCError {
+	var toolErr *tools.ToolError
+	if errors.As(err, &toolErr) {
+		rpcErr := &protocol.JSONRPCError{
+			Code:    toolErr.Code,
+			Message: toolErr.Message,
+		}
+		if toolErr.Data != nil {
+			rpcErr.Data = toolErr.Data
+		}
+		return rpcErr
+	}
+
+	return &protocol.JSONRPCError{
+		Code:    -32603,
+		Message: err.Error(),
+	}
+}
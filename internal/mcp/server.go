@@ -1,9 +1,15 @@
 package mcp
 
 import (
+	"context"
 	"encoding/json"
 	"io"
+	"runtime/debug"
+	"sync"
 
+	"github.com/alucardeht/may-la-mcp/internal/injection"
+	"github.com/alucardeht/may-la-mcp/internal/redact"
+	"github.com/alucardeht/may-la-mcp/internal/spill"
 	"github.com/alucardeht/may-la-mcp/internal/tools"
 	"github.com/alucardeht/may-la-mcp/pkg/protocol"
 )
@@ -11,6 +17,9 @@ import (
 type Server struct {
 	registry *tools.Registry
 	handler  *Handler
+
+	streamMu sync.Mutex
+	stream   *json.Encoder
 }
 
 func NewServer(registry *tools.Registry) *Server {
@@ -20,14 +29,26 @@ func NewServer(registry *tools.Registry) *Server {
 	}
 }
 
-func (s *Server) HandleRequest(req *Request) *Response {
-	return s.handler.Handle(req)
+func (s *Server) HandleRequest(ctx context.Context, req *Request) (resp *Response) {
+	defer func() {
+		if p := recover(); p != nil {
+			component := "request " + req.Method
+			crashID := tools.ReportPanic(component, p, debug.Stack())
+			resp = &Response{
+				JSONRPC: "2.0",
+				ID:      req.ID,
+				Error:   toolCallError(tools.NewPanicError(component, p, crashID)),
+			}
+		}
+	}()
+
+	return s.handler.Handle(ctx, req)
 }
 
-func (s *Server) HandleBatch(batch []Request) []*Response {
+func (s *Server) HandleBatch(ctx context.Context, batch []Request) []*Response {
 	responses := make([]*Response, 0, len(batch))
 	for _, req := range batch {
-		resp := s.HandleRequest(&req)
+		resp := s.HandleRequest(ctx, &req)
 		if req.ID != nil {
 			responses = append(responses, resp)
 		}
@@ -35,9 +56,43 @@ func (s *Server) HandleBatch(batch []Request) []*Response {
 	return responses
 }
 
-func (s *Server) ProcessStream(reader io.Reader, writer io.Writer) error {
+// NotifyFunc sends an encoded JSON-RPC notification to the client of the
+// request being handled, ahead of its response
+type NotifyFunc func(notification json.RawMessage)
+
+type notifierKey struct{}
+
+// WithNotifier lets the request handled under ctx notify its client through
+// fn while it runs, which tool calls use to stream partial results
+func WithNotifier(ctx context.Context, fn NotifyFunc) context.Context {
+	return context.WithValue(ctx, notifierKey{}, fn)
+}
+
+func notifierFrom(ctx context.Context) NotifyFunc {
+	fn, _ := ctx.Value(notifierKey{}).(NotifyFunc)
+	return fn
+}
+
+// ProcessStream serves newline-delimited JSON-RPC from reader until EOF.
+// Every request runs under ctx, which carries the session options.
+// Requests are served one at a time, so notifications a request sends reach
+// the stream before its response.
+func (s *Server) ProcessStream(ctx context.Context, reader io.Reader, writer io.Writer) error {
 	decoder := json.NewDecoder(reader)
-	encoder := json.NewEncoder(writer)
+	ctx = WithNotifier(ctx, func(notification json.RawMessage) {
+		if err := s.Notify(notification); err != nil {
+			log.Debug("failed to write notification", "error", err)
+		}
+	})
+
+	s.streamMu.Lock()
+	s.stream = json.NewEncoder(writer)
+	s.streamMu.Unlock()
+	defer func() {
+		s.streamMu.Lock()
+		s.stream = nil
+		s.streamMu.Unlock()
+	}()
 
 	for {
 		var raw json.RawMessage
@@ -53,7 +108,7 @@ func (s *Server) ProcessStream(reader io.Reader, writer io.Writer) error {
 					Message: "Parse error",
 				},
 			}
-			encoder.Encode(resp)
+			s.writeStream(resp)
 			continue
 		}
 
@@ -72,12 +127,15 @@ func (s *Server) ProcessStream(reader io.Reader, writer io.Writer) error {
 						Message: "Parse error",
 					},
 				}
-				encoder.Encode(resp)
+				s.writeStream(resp)
 				continue
 			}
 
-			responses := s.HandleBatch(batch)
-			if err := encoder.Encode(responses); err != nil {
+			responses := s.HandleBatch(ctx, batch)
+			if len(responses) == 0 {
+				continue
+			}
+			if err := s.writeStream(responses); err != nil {
 				return err
 			}
 		} else {
@@ -91,18 +149,52 @@ func (s *Server) ProcessStream(reader io.Reader, writer io.Writer) error {
 						Message: "Parse error",
 					},
 				}
-				encoder.Encode(resp)
+				s.writeStream(resp)
 				continue
 			}
 
-			resp := s.HandleRequest(&req)
-			if err := encoder.Encode(resp); err != nil {
+			resp := s.HandleRequest(ctx, &req)
+			if req.ID == nil {
+				continue
+			}
+			if err := s.writeStream(resp); err != nil {
 				return err
 			}
 		}
 	}
 }
 
+// Notify writes an encoded server-initiated notification to the stream being
+// served by ProcessStream. It does nothing when no stream is active.
+func (s *Server) Notify(notification json.RawMessage) error {
+	return s.writeStream(notification)
+}
+
+func (s *Server) writeStream(v interface{}) error {
+	s.streamMu.Lock()
+	defer s.streamMu.Unlock()
+	if s.stream == nil {
+		return nil
+	}
+	return s.stream.Encode(v)
+}
+
+func (s *Server) SetRedactor(r *redact.Redactor) {
+	s.handler.redactor = r
+}
+
+// SetInjectionScanner makes results with passages that read like
+// instructions to the agent carry a warning; nil turns scanning off
+func (s *Server) SetInjectionScanner(scanner *injection.Scanner) {
+	s.handler.injection = scanner
+}
+
+// SetSpill makes results over the store's size limit spill to files; nil
+// returns every result whole
+func (s *Server) SetSpill(store *spill.Store) {
+	s.handler.spill = store
+}
+
 func (s *Server) Registry() *tools.Registry {
 	return s.registry
 }
@@ -0,0 +1,138 @@
+package memsync
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	gitSnapshotFile = "memories.json"
+	gitBranch       = "main"
+)
+
+// gitRemote keeps the snapshot as memories.json in a git repository,
+// through a working clone; a rejected push means another member pushed
+// first
+type gitRemote struct {
+	url    string
+	branch string
+	dir    string
+}
+
+func newGitRemote(raw, dir string) (*gitRemote, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("git memory sync needs git installed: %w", err)
+	}
+	if dir == "" {
+		return nil, fmt.Errorf("git memory sync needs a working directory")
+	}
+	url, branch, _ := strings.Cut(strings.TrimPrefix(raw, "git+"), "#")
+	if branch == "" {
+		branch = gitBranch
+	}
+	return &gitRemote{url: url, branch: branch, dir: dir}, nil
+}
+
+func (r *gitRemote) git(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = r.dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return stdout.String(), fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// clone prepares the working clone, pointing it at the remote
+func (r *gitRemote) clone(ctx context.Context) error {
+	if _, err := os.Stat(filepath.Join(r.dir, ".git")); err == nil {
+		_, err := r.git(ctx, "remote", "set-url", "origin", r.url)
+		return err
+	}
+	if err := os.MkdirAll(r.dir, 0700); err != nil {
+		return err
+	}
+	if _, err := r.git(ctx, "init", "--quiet"); err != nil {
+		return err
+	}
+	_, err := r.git(ctx, "remote", "add", "origin", r.url)
+	return err
+}
+
+func (r *gitRemote) Pull(ctx context.Context) (*Snapshot, string, error) {
+	if err := r.clone(ctx); err != nil {
+		return nil, "", err
+	}
+
+	if _, err := r.git(ctx, "fetch", "--quiet", "origin", r.branch); err != nil {
+		// a new repository, or one without the branch yet, is empty
+		if strings.Contains(err.Error(), "couldn't find remote ref") {
+			os.Remove(filepath.Join(r.dir, gitSnapshotFile))
+			return &Snapshot{}, "", nil
+		}
+		return nil, "", err
+	}
+	if _, err := r.git(ctx, "checkout", "--quiet", "-B", r.branch, "FETCH_HEAD"); err != nil {
+		return nil, "", err
+	}
+	revision, err := r.git(ctx, "rev-parse", "HEAD")
+	if err != nil {
+		return nil, "", err
+	}
+
+	data, err := os.ReadFile(filepath.Join(r.dir, gitSnapshotFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, "", err
+	}
+	snapshot, err := decodeSnapshot(data)
+	if err != nil {
+		return nil, "", err
+	}
+	return snapshot, revision, nil
+}
+
+func (r *gitRemote) Push(ctx context.Context, snapshot *Snapshot, revision string) error {
+	data, err := encodeSnapshot(snapshot)
+	if err != nil {
+		return err
+	}
+
+	if revision == "" {
+		// the first snapshot starts the branch from an empty history
+		if _, err := r.git(ctx, "symbolic-ref", "HEAD", "refs/heads/"+r.branch); err != nil {
+			return err
+		}
+		r.git(ctx, "update-ref", "-d", "refs/heads/"+r.branch)
+	} else if _, err := r.git(ctx, "checkout", "--quiet", "-B", r.branch, revision); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(filepath.Join(r.dir, gitSnapshotFile), data, 0600); err != nil {
+		return err
+	}
+	if _, err := r.git(ctx, "add", gitSnapshotFile); err != nil {
+		return err
+	}
+	if _, err := r.git(ctx,
+		"-c", "user.name=May-la", "-c", "user.email=mayla@localhost", "-c", "commit.gpgsign=false",
+		"commit", "--quiet", "--allow-empty", "-m", "Sync memories",
+	); err != nil {
+		return err
+	}
+
+	if _, err := r.git(ctx, "push", "--quiet", "origin", "HEAD:refs/heads/"+r.branch); err != nil {
+		if strings.Contains(err.Error(), "rejected") || strings.Contains(err.Error(), "fetch first") {
+			return ErrConflict
+		}
+		return err
+	}
+	return nil
+}
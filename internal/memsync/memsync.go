@@ -0,0 +1,451 @@
+// Package memsync keeps memories consistent across a team by syncing them
+// with a shared remote: an HTTP endpoint, an S3 bucket or a git repository
+// holding one JSON snapshot. Each category has a policy saying which way it
+// syncs. When both sides changed a memory, the later updated_at wins;
+// memories deleted locally are carried to the remote as tombstones so the
+// deletion reaches the other members too.
+package memsync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/logger"
+	"github.com/alucardeht/may-la-mcp/internal/tools/memory"
+)
+
+var log = logger.ForComponent("memsync")
+
+const (
+	snapshotVersion = 1
+	maxAttempts     = 3
+	// tombstoneTTL is how long a deletion is kept in the snapshot for
+	// members who have not synced since
+	tombstoneTTL = 30 * 24 * time.Hour
+)
+
+// ErrConflict is returned by a remote when the snapshot changed since it
+// was pulled; the sync is retried from a fresh pull
+var ErrConflict = errors.New("remote snapshot changed since it was pulled")
+
+// Policy says which way a category syncs
+type Policy string
+
+const (
+	PolicyBoth Policy = "both"
+	PolicyPush Policy = "push"
+	PolicyPull Policy = "pull"
+	PolicyOff  Policy = "off"
+)
+
+func (p Policy) pushes() bool { return p == PolicyBoth || p == PolicyPush }
+func (p Policy) pulls() bool  { return p == PolicyBoth || p == PolicyPull }
+
+// anyCategory keys the policy of the categories not listed
+const anyCategory = "*"
+
+type Config struct {
+	// Remote is the URL of the shared snapshot; empty disables syncing
+	Remote string `yaml:"remote"`
+	// Token is sent as a bearer token to HTTP remotes
+	Token string `yaml:"token"`
+	// Policies lists each category's policy, such as
+	// "conventions=both,context=pull,*=off"; "*" covers the categories
+	// not listed, which are otherwise not synced
+	Policies string        `yaml:"policies"`
+	Interval time.Duration `yaml:"interval"`
+	// StatePath records what the last sync saw, to tell local deletions
+	// from memories that were never synced
+	StatePath string `yaml:"state_path"`
+	// GitDir is the working clone of a git remote
+	GitDir string `yaml:"git_dir"`
+}
+
+// DefaultPolicies share the conventions and decisions categories both ways
+// and keep the rest local
+const DefaultPolicies = "conventions=both,decisions=both"
+
+// ParsePolicies reads a policy list such as "conventions=both,context=pull,*=off"
+func ParsePolicies(s string) (map[memory.Category]Policy, error) {
+	policies := map[memory.Category]Policy{}
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		category, policy, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid sync policy %q: want category=policy", item)
+		}
+		switch p := Policy(strings.TrimSpace(policy)); p {
+		case PolicyBoth, PolicyPush, PolicyPull, PolicyOff:
+			policies[memory.Category(strings.TrimSpace(category))] = p
+		default:
+			return nil, fmt.Errorf("invalid sync policy %q for %s (want both, push, pull or off)", policy, category)
+		}
+	}
+	return policies, nil
+}
+
+// Snapshot is the content of the remote: every synced memory, with
+// deletions as tombstones
+type Snapshot struct {
+	Version  int      `json:"version"`
+	Memories []*Entry `json:"memories"`
+}
+
+type Entry struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Content   string          `json:"content,omitempty"`
+	Category  memory.Category `json:"category"`
+	Tags      []string        `json:"tags,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	DeletedAt *time.Time      `json:"deleted_at,omitempty"`
+}
+
+// stamp is when the entry last changed, its deletion included
+func (e *Entry) stamp() time.Time {
+	if e.DeletedAt != nil {
+		return *e.DeletedAt
+	}
+	return e.UpdatedAt
+}
+
+func (e *Entry) deleted() bool {
+	return e.DeletedAt != nil
+}
+
+// Remote stores the shared snapshot
+type Remote interface {
+	// Pull returns the snapshot and the revision it was read at; an empty
+	// remote returns an empty snapshot and revision
+	Pull(ctx context.Context) (*Snapshot, string, error)
+	// Push replaces the snapshot, failing with ErrConflict when the remote
+	// is no longer at revision
+	Push(ctx context.Context, snapshot *Snapshot, revision string) error
+}
+
+// Result is what a sync changed on each side
+type Result struct {
+	Remote  string `json:"remote"`
+	Pulled  int    `json:"pulled"`
+	Pushed  int    `json:"pushed"`
+	Deleted int    `json:"deleted"`
+}
+
+// Store is the memory storage a Syncer reads and updates
+type Store interface {
+	Export(categories []memory.Category) ([]*memory.Memory, error)
+	Import(memory *memory.Memory) error
+	Delete(identifier string) (string, *time.Time, error)
+}
+
+type Syncer struct {
+	store    Store
+	remote   Remote
+	config   Config
+	policies map[memory.Category]Policy
+	mu       sync.Mutex
+	now      func() time.Time
+}
+
+// New returns a syncer for cfg, or nil when no remote is configured
+func New(cfg Config, store Store) (*Syncer, error) {
+	if cfg.Remote == "" {
+		return nil, nil
+	}
+	remote, err := OpenRemote(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return NewWithRemote(cfg, store, remote)
+}
+
+// NewWithRemote returns a syncer using remote instead of the one cfg names
+func NewWithRemote(cfg Config, store Store, remote Remote) (*Syncer, error) {
+	if cfg.Policies == "" {
+		cfg.Policies = DefaultPolicies
+	}
+	policies, err := ParsePolicies(cfg.Policies)
+	if err != nil {
+		return nil, err
+	}
+	return &Syncer{store: store, remote: remote, config: cfg, policies: policies, now: time.Now}, nil
+}
+
+func (s *Syncer) policy(category memory.Category) Policy {
+	if p, ok := s.policies[category]; ok {
+		return p
+	}
+	if p, ok := s.policies[anyCategory]; ok {
+		return p
+	}
+	return PolicyOff
+}
+
+// Run syncs every interval until ctx is done
+func (s *Syncer) Run(ctx context.Context) {
+	interval := s.config.Interval
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if result, err := s.Sync(ctx); err != nil {
+			log.Warn("memory sync failed", "remote", s.config.Remote, "error", err)
+		} else if result.Pulled+result.Pushed+result.Deleted > 0 {
+			log.Info("memories synced", "pulled", result.Pulled, "pushed", result.Pushed, "deleted", result.Deleted)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync pulls the remote snapshot, merges it with the local memories and
+// pushes the result, retrying when another member pushed in between
+func (s *Syncer) Sync(ctx context.Context) (*Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for attempt := 1; ; attempt++ {
+		result, err := s.syncOnce(ctx)
+		if errors.Is(err, ErrConflict) && attempt < maxAttempts {
+			continue
+		}
+		return result, err
+	}
+}
+
+func (s *Syncer) syncOnce(ctx context.Context) (*Result, error) {
+	snapshot, revision, err := s.remote.Pull(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pull memories: %w", err)
+	}
+	exported, err := s.store.Export(nil)
+	if err != nil {
+		return nil, err
+	}
+	synced := s.loadState()
+	now := s.now().UTC()
+
+	remote := map[string]*Entry{}
+	for _, e := range snapshot.Memories {
+		remote[e.Name] = e
+	}
+	local := map[string]*Entry{}
+	for _, m := range exported {
+		local[m.Name] = entryOf(m)
+	}
+
+	names := map[string]bool{}
+	for name := range remote {
+		names[name] = true
+	}
+	for name := range local {
+		names[name] = true
+	}
+	for name := range synced {
+		names[name] = true
+	}
+
+	result := &Result{Remote: s.config.Remote}
+	merged := map[string]*Entry{}
+	for name, e := range remote {
+		merged[name] = e
+	}
+	changed := false
+
+	for name := range names {
+		l, r := local[name], remote[name]
+		if l == nil {
+			// a memory seen by the last sync and gone since was deleted here
+			if _, ok := synced[name]; ok && r != nil && !r.deleted() {
+				deletedAt := now
+				l = &Entry{ID: r.ID, Name: name, Category: r.Category, DeletedAt: &deletedAt}
+			}
+		}
+		if l == nil && r == nil {
+			continue
+		}
+
+		category := r.categoryOr(l)
+		policy := s.policy(category)
+		if policy == PolicyOff {
+			continue
+		}
+
+		switch {
+		case r == nil || (l != nil && l.stamp().After(r.stamp())):
+			if policy.pushes() && !(r == nil && l.deleted()) {
+				merged[name] = l
+				result.Pushed++
+				changed = true
+			}
+		case l == nil || r.stamp().After(l.stamp()):
+			if !policy.pulls() {
+				continue
+			}
+			if r.deleted() {
+				if l != nil && !l.deleted() {
+					if _, _, err := s.store.Delete(name); err != nil {
+						return nil, fmt.Errorf("failed to delete memory %s: %w", name, err)
+					}
+					delete(local, name)
+					result.Deleted++
+				}
+				continue
+			}
+			if err := s.store.Import(r.memory()); err != nil {
+				return nil, fmt.Errorf("failed to import memory %s: %w", name, err)
+			}
+			local[name] = r
+			result.Pulled++
+		}
+	}
+
+	for name, e := range merged {
+		if e.deleted() && now.Sub(*e.DeletedAt) > tombstoneTTL {
+			delete(merged, name)
+			changed = true
+		}
+	}
+
+	if changed {
+		if err := s.remote.Push(ctx, snapshotOf(merged), revision); err != nil {
+			return nil, err
+		}
+	}
+
+	state := map[string]time.Time{}
+	for name, e := range local {
+		if !e.deleted() && s.policy(e.Category) != PolicyOff {
+			state[name] = e.UpdatedAt
+		}
+	}
+	if err := s.saveState(state); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// categoryOr returns the entry's category, or other's when e is nil
+func (e *Entry) categoryOr(other *Entry) memory.Category {
+	if e != nil && e.Category != "" {
+		return e.Category
+	}
+	if other != nil {
+		return other.Category
+	}
+	return ""
+}
+
+func entryOf(m *memory.Memory) *Entry {
+	return &Entry{
+		ID:        m.ID,
+		Name:      m.Name,
+		Content:   m.Content,
+		Category:  m.Category,
+		Tags:      m.Tags,
+		CreatedAt: m.CreatedAt.UTC(),
+		UpdatedAt: m.UpdatedAt.UTC(),
+	}
+}
+
+func (e *Entry) memory() *memory.Memory {
+	tags := e.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return &memory.Memory{
+		ID:        e.ID,
+		Name:      e.Name,
+		Content:   e.Content,
+		Category:  e.Category,
+		Tags:      tags,
+		CreatedAt: e.CreatedAt,
+		UpdatedAt: e.UpdatedAt,
+	}
+}
+
+func snapshotOf(entries map[string]*Entry) *Snapshot {
+	snapshot := &Snapshot{Version: snapshotVersion, Memories: make([]*Entry, 0, len(entries))}
+	for _, e := range entries {
+		snapshot.Memories = append(snapshot.Memories, e)
+	}
+	sort.Slice(snapshot.Memories, func(i, j int) bool {
+		return snapshot.Memories[i].Name < snapshot.Memories[j].Name
+	})
+	return snapshot
+}
+
+func decodeSnapshot(data []byte) (*Snapshot, error) {
+	snapshot := &Snapshot{}
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return snapshot, nil
+	}
+	if err := json.Unmarshal(data, snapshot); err != nil {
+		return nil, fmt.Errorf("invalid memory snapshot: %w", err)
+	}
+	if snapshot.Version > snapshotVersion {
+		return nil, fmt.Errorf("memory snapshot version %d is newer than this daemon supports", snapshot.Version)
+	}
+	return snapshot, nil
+}
+
+func encodeSnapshot(snapshot *Snapshot) ([]byte, error) {
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// loadState returns the memories present after the last sync with their
+// updated_at; a missing or unreadable state means nothing was synced yet
+func (s *Syncer) loadState() map[string]time.Time {
+	state := map[string]time.Time{}
+	if s.config.StatePath == "" {
+		return state
+	}
+	data, err := os.ReadFile(s.config.StatePath)
+	if err != nil {
+		return state
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		log.Warn("ignoring unreadable memory sync state", "path", s.config.StatePath, "error", err)
+		return map[string]time.Time{}
+	}
+	return state
+}
+
+func (s *Syncer) saveState(state map[string]time.Time) error {
+	if s.config.StatePath == "" {
+		return nil
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.config.StatePath), 0700); err != nil {
+		return err
+	}
+	tmp := s.config.StatePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.config.StatePath)
+}
@@ -0,0 +1,119 @@
+package memsync
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const requestTimeout = 30 * time.Second
+
+// OpenRemote returns the remote cfg.Remote names:
+//
+//	https://team.example.com/memories.json   an HTTP endpoint (GET and PUT)
+//	s3://bucket/path/memories.json           an S3 object
+//	git+ssh://git@host/team/memories.git     a git repository
+//
+// Git remotes also accept git@host:repo.git and any URL ending in .git, with
+// an optional #branch.
+func OpenRemote(cfg Config) (Remote, error) {
+	url := cfg.Remote
+	switch {
+	case strings.HasPrefix(url, "s3://"):
+		return newS3Remote(url)
+	case strings.HasPrefix(url, "git+"), strings.HasPrefix(url, "git@"), strings.HasSuffix(strings.SplitN(url, "#", 2)[0], ".git"):
+		return newGitRemote(url, cfg.GitDir)
+	case strings.HasPrefix(url, "http://"), strings.HasPrefix(url, "https://"):
+		return &httpRemote{url: url, client: &http.Client{Timeout: requestTimeout}, token: cfg.Token}, nil
+	default:
+		return nil, fmt.Errorf("unsupported memory sync remote %q (want an http(s), s3 or git URL)", url)
+	}
+}
+
+// httpRemote keeps the snapshot at a URL that answers GET and PUT, using
+// ETags so concurrent pushes do not overwrite each other
+type httpRemote struct {
+	url    string
+	client *http.Client
+	token  string
+	// sign authenticates a request carrying body, instead of the token
+	sign func(req *http.Request, body []byte) error
+}
+
+func (r *httpRemote) do(ctx context.Context, method string, body []byte, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, r.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	switch {
+	case r.sign != nil:
+		if err := r.sign(req, body); err != nil {
+			return nil, err
+		}
+	case r.token != "":
+		req.Header.Set("Authorization", "Bearer "+r.token)
+	}
+	return r.client.Do(req)
+}
+
+func (r *httpRemote) Pull(ctx context.Context) (*Snapshot, string, error) {
+	resp, err := r.do(ctx, http.MethodGet, nil, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return &Snapshot{}, "", nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("GET %s: %s", r.url, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	snapshot, err := decodeSnapshot(data)
+	if err != nil {
+		return nil, "", err
+	}
+	return snapshot, resp.Header.Get("ETag"), nil
+}
+
+func (r *httpRemote) Push(ctx context.Context, snapshot *Snapshot, revision string) error {
+	data, err := encodeSnapshot(snapshot)
+	if err != nil {
+		return err
+	}
+	header := http.Header{}
+	if revision == "" {
+		header.Set("If-None-Match", "*")
+	} else {
+		header.Set("If-Match", revision)
+	}
+
+	resp, err := r.do(ctx, http.MethodPut, data, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode == http.StatusPreconditionFailed, resp.StatusCode == http.StatusConflict:
+		return ErrConflict
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("PUT %s: %s", r.url, resp.Status)
+	}
+	return nil
+}
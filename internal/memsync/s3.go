@@ -0,0 +1,127 @@
+package memsync
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// newS3Remote keeps the snapshot in the object s3://bucket/key, signed
+// with the standard AWS_* credentials. The region and, for S3-compatible
+// stores, the endpoint can be given as query parameters:
+// s3://bucket/key?region=eu-west-1&endpoint=https://minio.local:9000
+func newS3Remote(raw string) (*httpRemote, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid S3 remote: %w", err)
+	}
+	bucket, key := u.Host, strings.TrimPrefix(u.Path, "/")
+	if bucket == "" || key == "" {
+		return nil, fmt.Errorf("invalid S3 remote %q: want s3://bucket/key", raw)
+	}
+
+	creds := s3Credentials{
+		accessKey: os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		token:     os.Getenv("AWS_SESSION_TOKEN"),
+		region:    u.Query().Get("region"),
+	}
+	if creds.accessKey == "" || creds.secretKey == "" {
+		return nil, fmt.Errorf("S3 memory sync needs AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	if creds.region == "" {
+		creds.region = os.Getenv("AWS_REGION")
+	}
+	if creds.region == "" {
+		creds.region = "us-east-1"
+	}
+
+	object := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucket, creds.region, key)
+	if endpoint := u.Query().Get("endpoint"); endpoint != "" {
+		object = strings.TrimSuffix(endpoint, "/") + "/" + bucket + "/" + key
+	}
+
+	return &httpRemote{
+		url:    object,
+		client: &http.Client{Timeout: requestTimeout},
+		sign: func(req *http.Request, body []byte) error {
+			creds.sign(req, body, time.Now())
+			return nil
+		},
+	}, nil
+}
+
+type s3Credentials struct {
+	accessKey string
+	secretKey string
+	token     string
+	region    string
+}
+
+// sign adds an AWS Signature Version 4 to req, covering its host and
+// every header already set
+func (c s3Credentials) sign(req *http.Request, body []byte, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+
+	payloadHash := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+	if c.token != "" {
+		req.Header.Set("X-Amz-Security-Token", c.token)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := day + "/" + c.region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+c.secretKey), day)
+	key = hmacSHA256(key, c.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.accessKey, scope, signedHeaders, signature,
+	))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
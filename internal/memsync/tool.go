@@ -0,0 +1,63 @@
+package memsync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+// SyncTool runs a memory sync on demand, between the periodic ones
+type SyncTool struct {
+	syncer *Syncer
+}
+
+func NewSyncTool(syncer *Syncer) *SyncTool {
+	return &SyncTool{syncer: syncer}
+}
+
+func (t *SyncTool) Name() string {
+	return "memory_sync"
+}
+
+func (t *SyncTool) Description() string {
+	return `Sync memories with the team's shared remote now.
+
+Pulls memories others changed and pushes local changes, following each
+category's sync policy; when both sides changed a memory the later update
+wins. Syncs also run periodically, so this is only needed to share or pick
+up a change right away.`
+}
+
+func (t *SyncTool) Title() string {
+	return "Sync Memories"
+}
+
+func (t *SyncTool) Annotations() map[string]bool {
+	annotations := tools.NonIdempotentWriteAnnotations()
+	annotations["openWorldHint"] = true
+	return annotations
+}
+
+func (t *SyncTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {}
+	}`)
+}
+
+func (t *SyncTool) OutputSchema() json.RawMessage {
+	return tools.OutputSchemaOf(Result{})
+}
+
+func (t *SyncTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	result, err := t.syncer.Sync(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("memory sync failed: %w", err)
+	}
+	return result, nil
+}
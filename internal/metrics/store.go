@@ -0,0 +1,502 @@
+package metrics
+
+import (
+	"database/sql"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/logger"
+
+	_ "modernc.org/sqlite"
+)
+
+var log = logger.ForComponent("metrics")
+
+// Sample categories
+const (
+	CategoryTool   = "tool"
+	CategoryRouter = "router"
+)
+
+const (
+	sampleBuffer  = 1024
+	flushInterval = time.Second
+	flushBatch    = 256
+	pruneInterval = time.Hour
+)
+
+type Config struct {
+	Enabled       bool          `yaml:"enabled"`
+	SlowThreshold time.Duration `yaml:"slow_threshold"`
+	Retention     time.Duration `yaml:"retention"`
+	MaxParamsSize int           `yaml:"max_params_size"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Enabled:       true,
+		SlowThreshold: time.Second,
+		Retention:     7 * 24 * time.Hour,
+		MaxParamsSize: 2048,
+	}
+}
+
+// Sample is one measured operation. For tools, OK means the call succeeded;
+// for router queries it means the answering tier returned results.
+type Sample struct {
+	At       time.Time
+	Category string
+	Name     string
+	Source   string
+	Latency  time.Duration
+	OK       bool
+	Fallback bool
+	Params   string
+}
+
+// Store persists samples and slow queries in SQLite. Samples are queued and
+// written in batches by a background goroutine so recording never blocks the
+// measured operation; when the queue is full samples are dropped.
+type Store struct {
+	db      *sql.DB
+	config  Config
+	samples chan Sample
+	done    chan struct{}
+	mu      sync.RWMutex
+	closed  bool
+}
+
+func NewStore(dbPath string, cfg Config) (*Store, error) {
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	for _, pragma := range []string{"PRAGMA journal_mode=WAL", "PRAGMA busy_timeout=5000"} {
+		if _, err := db.Exec(pragma); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	s := &Store{
+		db:      db,
+		config:  cfg,
+		samples: make(chan Sample, sampleBuffer),
+		done:    make(chan struct{}),
+	}
+	if err := s.initSchema(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	s.prune()
+
+	go s.writeLoop()
+	return s, nil
+}
+
+func (s *Store) initSchema() error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS samples (
+		at INTEGER NOT NULL,
+		category TEXT NOT NULL,
+		name TEXT NOT NULL,
+		source TEXT NOT NULL DEFAULT '',
+		latency_us INTEGER NOT NULL,
+		ok INTEGER NOT NULL,
+		fallback INTEGER NOT NULL DEFAULT 0
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_samples_at ON samples(at);
+
+	CREATE TABLE IF NOT EXISTS slow_queries (
+		at INTEGER NOT NULL,
+		category TEXT NOT NULL,
+		name TEXT NOT NULL,
+		source TEXT NOT NULL DEFAULT '',
+		latency_us INTEGER NOT NULL,
+		params TEXT
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_slow_queries_at ON slow_queries(at);
+	`
+
+	for _, stmt := range strings.Split(schema, ";") {
+		stmt = strings.TrimSpace(stmt)
+		if stmt == "" {
+			continue
+		}
+		if _, err := s.db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// prune drops samples and slow queries older than the retention window
+func (s *Store) prune() {
+	if s.config.Retention <= 0 {
+		return
+	}
+	cutoff := time.Now().Add(-s.config.Retention).UnixMilli()
+	for _, table := range []string{"samples", "slow_queries"} {
+		if _, err := s.db.Exec("DELETE FROM "+table+" WHERE at < ?", cutoff); err != nil {
+			log.Warn("failed to prune metrics", "table", table, "error", err)
+		}
+	}
+}
+
+// Record queues a sample. Params are only kept when the sample is slow.
+func (s *Store) Record(sample Sample) {
+	if sample.At.IsZero() {
+		sample.At = time.Now()
+	}
+	if !s.IsSlow(sample.Latency) {
+		sample.Params = ""
+	} else if max := s.config.MaxParamsSize; max > 0 && len(sample.Params) > max {
+		sample.Params = sample.Params[:max] + "…"
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return
+	}
+
+	select {
+	case s.samples <- sample:
+	default:
+		log.Debug("metrics queue full, dropping sample", "name", sample.Name)
+	}
+}
+
+// IsSlow reports whether latency crosses the slow-query threshold
+func (s *Store) IsSlow(latency time.Duration) bool {
+	return s.config.SlowThreshold > 0 && latency >= s.config.SlowThreshold
+}
+
+func (s *Store) writeLoop() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	pruneTicker := time.NewTicker(pruneInterval)
+	defer pruneTicker.Stop()
+
+	batch := make([]Sample, 0, flushBatch)
+	for {
+		select {
+		case sample, ok := <-s.samples:
+			if !ok {
+				s.write(batch)
+				return
+			}
+			batch = append(batch, sample)
+			if len(batch) >= flushBatch {
+				s.write(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			s.write(batch)
+			batch = batch[:0]
+		case <-pruneTicker.C:
+			s.prune()
+		}
+	}
+}
+
+func (s *Store) write(batch []Sample) {
+	if len(batch) == 0 {
+		return
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		log.Warn("failed to write metrics", "error", err)
+		return
+	}
+
+	for _, sample := range batch {
+		at := sample.At.UnixMilli()
+		latency := sample.Latency.Microseconds()
+		if _, err := tx.Exec(
+			"INSERT INTO samples (at, category, name, source, latency_us, ok, fallback) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			at, sample.Category, sample.Name, sample.Source, latency, sample.OK, sample.Fallback,
+		); err != nil {
+			tx.Rollback()
+			log.Warn("failed to write metrics", "error", err)
+			return
+		}
+
+		if s.IsSlow(sample.Latency) {
+			if _, err := tx.Exec(
+				"INSERT INTO slow_queries (at, category, name, source, latency_us, params) VALUES (?, ?, ?, ?, ?, ?)",
+				at, sample.Category, sample.Name, sample.Source, latency, sample.Params,
+			); err != nil {
+				tx.Rollback()
+				log.Warn("failed to write slow query", "error", err)
+				return
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Warn("failed to write metrics", "error", err)
+	}
+}
+
+// Close flushes queued samples and closes the database
+func (s *Store) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.samples)
+	s.mu.Unlock()
+
+	<-s.done
+	return s.db.Close()
+}
+
+// LatencyStats aggregates the latencies of a group of samples
+type LatencyStats struct {
+	Count int     `json:"count"`
+	OK    int     `json:"ok"`
+	P50Ms float64 `json:"p50_ms"`
+	P95Ms float64 `json:"p95_ms"`
+	MaxMs float64 `json:"max_ms"`
+}
+
+type ToolStats struct {
+	Name string `json:"name"`
+	LatencyStats
+	ErrorRate float64 `json:"error_rate"`
+}
+
+type TierStats struct {
+	Source string `json:"source"`
+	LatencyStats
+	HitRate float64 `json:"hit_rate"`
+}
+
+type RouterStats struct {
+	Operation    string      `json:"operation"`
+	Count        int         `json:"count"`
+	Fallbacks    int         `json:"fallbacks"`
+	FallbackRate float64     `json:"fallback_rate"`
+	Tiers        []TierStats `json:"tiers"`
+}
+
+type SlowQuery struct {
+	At        time.Time       `json:"at"`
+	Category  string          `json:"category"`
+	Name      string          `json:"name"`
+	Source    string          `json:"source,omitempty"`
+	LatencyMs float64         `json:"latency_ms"`
+	Params    json.RawMessage `json:"params,omitempty"`
+}
+
+type Summary struct {
+	Since       time.Time     `json:"since"`
+	Tools       []ToolStats   `json:"tools"`
+	Router      []RouterStats `json:"router"`
+	SlowQueries []SlowQuery   `json:"slow_queries"`
+}
+
+// Summarize aggregates samples recorded since the given time and returns up
+// to slowLimit of the slowest queries in that window.
+func (s *Store) Summarize(since time.Time, slowLimit int) (*Summary, error) {
+	rows, err := s.db.Query(
+		"SELECT category, name, source, latency_us, ok, fallback FROM samples WHERE at >= ?",
+		since.UnixMilli(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type group struct {
+		latencies []int64
+		ok        int
+		fallbacks int
+	}
+	toolGroups := make(map[string]*group)
+	tierGroups := make(map[[2]string]*group)
+	opGroups := make(map[string]*group)
+
+	get := func(m map[string]*group, key string) *group {
+		g, ok := m[key]
+		if !ok {
+			g = &group{}
+			m[key] = g
+		}
+		return g
+	}
+
+	for rows.Next() {
+		var category, name, source string
+		var latency int64
+		var ok, fallback bool
+		if err := rows.Scan(&category, &name, &source, &latency, &ok, &fallback); err != nil {
+			return nil, err
+		}
+
+		var g *group
+		switch category {
+		case CategoryTool:
+			g = get(toolGroups, name)
+		case CategoryRouter:
+			op := get(opGroups, name)
+			op.latencies = append(op.latencies, latency)
+			if fallback {
+				op.fallbacks++
+			}
+			key := [2]string{name, source}
+			if g = tierGroups[key]; g == nil {
+				g = &group{}
+				tierGroups[key] = g
+			}
+		default:
+			continue
+		}
+		g.latencies = append(g.latencies, latency)
+		if ok {
+			g.ok++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	summary := &Summary{
+		Since:       since,
+		Tools:       []ToolStats{},
+		Router:      []RouterStats{},
+		SlowQueries: []SlowQuery{},
+	}
+
+	for name, g := range toolGroups {
+		stats := latencyStats(g.latencies, g.ok)
+		summary.Tools = append(summary.Tools, ToolStats{
+			Name:         name,
+			LatencyStats: stats,
+			ErrorRate:    float64(stats.Count-stats.OK) / float64(stats.Count),
+		})
+	}
+	sort.Slice(summary.Tools, func(i, j int) bool {
+		return summary.Tools[i].Count > summary.Tools[j].Count
+	})
+
+	for op, g := range opGroups {
+		stats := RouterStats{
+			Operation:    op,
+			Count:        len(g.latencies),
+			Fallbacks:    g.fallbacks,
+			FallbackRate: float64(g.fallbacks) / float64(len(g.latencies)),
+			Tiers:        []TierStats{},
+		}
+		for key, tier := range tierGroups {
+			if key[0] != op {
+				continue
+			}
+			latency := latencyStats(tier.latencies, tier.ok)
+			source := key[1]
+			if source == "" {
+				source = "error"
+			}
+			stats.Tiers = append(stats.Tiers, TierStats{
+				Source:       source,
+				LatencyStats: latency,
+				HitRate:      float64(latency.OK) / float64(latency.Count),
+			})
+		}
+		sort.Slice(stats.Tiers, func(i, j int) bool {
+			return stats.Tiers[i].Count > stats.Tiers[j].Count
+		})
+		summary.Router = append(summary.Router, stats)
+	}
+	sort.Slice(summary.Router, func(i, j int) bool {
+		return summary.Router[i].Operation < summary.Router[j].Operation
+	})
+
+	if slowLimit > 0 {
+		slow, err := s.slowQueries(since, slowLimit)
+		if err != nil {
+			return nil, err
+		}
+		summary.SlowQueries = slow
+	}
+
+	return summary, nil
+}
+
+func (s *Store) slowQueries(since time.Time, limit int) ([]SlowQuery, error) {
+	rows, err := s.db.Query(
+		"SELECT at, category, name, source, latency_us, params FROM slow_queries WHERE at >= ? ORDER BY latency_us DESC LIMIT ?",
+		since.UnixMilli(), limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []SlowQuery{}
+	for rows.Next() {
+		var at, latency int64
+		var q SlowQuery
+		var params sql.NullString
+		if err := rows.Scan(&at, &q.Category, &q.Name, &q.Source, &latency, &params); err != nil {
+			return nil, err
+		}
+		q.At = time.UnixMilli(at)
+		q.LatencyMs = float64(latency) / 1000
+		if params.Valid && params.String != "" {
+			if json.Valid([]byte(params.String)) {
+				q.Params = json.RawMessage(params.String)
+			} else {
+				encoded, _ := json.Marshal(params.String)
+				q.Params = encoded
+			}
+		}
+		result = append(result, q)
+	}
+	return result, rows.Err()
+}
+
+func latencyStats(latencies []int64, ok int) LatencyStats {
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	return LatencyStats{
+		Count: len(latencies),
+		OK:    ok,
+		P50Ms: percentile(latencies, 0.50),
+		P95Ms: percentile(latencies, 0.95),
+		MaxMs: percentile(latencies, 1),
+	}
+}
+
+// percentile returns the nearest-rank percentile of sorted microsecond
+// latencies, in milliseconds.
+func percentile(sorted []int64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(p*float64(len(sorted))+0.5) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return float64(sorted[rank]) / 1000
+}
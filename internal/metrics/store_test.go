@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSummarize(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.SlowThreshold = 50 * time.Millisecond
+	dbPath := filepath.Join(t.TempDir(), "metrics.db")
+	store, err := NewStore(dbPath, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i <= 10; i++ {
+		store.Record(Sample{Category: CategoryTool, Name: "read", Latency: time.Duration(i) * time.Millisecond, OK: i != 10})
+	}
+	store.Record(Sample{Category: CategoryTool, Name: "search", Latency: 80 * time.Millisecond, OK: true, Params: `{"pattern":"x"}`})
+	store.Record(Sample{Category: CategoryRouter, Name: "symbols", Source: "index", Latency: time.Millisecond, OK: true})
+	store.Record(Sample{Category: CategoryRouter, Name: "symbols", Source: "regex", Latency: time.Millisecond, OK: false, Fallback: true})
+
+	// Close flushes the queue; reopen to read what was persisted
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+	store, err = NewStore(dbPath, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	summary, err := store.Summarize(time.Now().Add(-time.Hour), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	read := summary.Tools[0]
+	if read.Name != "read" || read.Count != 10 || read.P50Ms != 5 || read.P95Ms != 10 || read.ErrorRate != 0.1 {
+		t.Errorf("unexpected read stats: %+v", read)
+	}
+
+	if len(summary.Router) != 1 || summary.Router[0].FallbackRate != 0.5 || len(summary.Router[0].Tiers) != 2 {
+		t.Errorf("unexpected router stats: %+v", summary.Router)
+	}
+
+	if len(summary.SlowQueries) != 1 || summary.SlowQueries[0].Name != "search" || string(summary.SlowQueries[0].Params) != `{"pattern":"x"}` {
+		t.Errorf("unexpected slow queries: %+v", summary.SlowQueries)
+	}
+}
@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+type MetricsRequest struct {
+	Since     string `json:"since,omitempty"`
+	SlowLimit *int   `json:"slow_limit,omitempty"`
+}
+
+type MetricsTool struct {
+	store *Store
+}
+
+func NewMetricsTool(store *Store) *MetricsTool {
+	return &MetricsTool{store: store}
+}
+
+func (t *MetricsTool) Name() string {
+	return "metrics"
+}
+
+func (t *MetricsTool) Description() string {
+	return "Report tool and router latency percentiles, tier hit rates, fallback frequency and the slow-query log"
+}
+
+func (t *MetricsTool) Title() string {
+	return "Query Metrics"
+}
+
+func (t *MetricsTool) Annotations() map[string]bool {
+	return tools.ReadOnlyAnnotations()
+}
+
+func (t *MetricsTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"since": {
+				"type": "string",
+				"description": "Aggregate samples from this long ago, as a Go duration (default: 24h)"
+			},
+			"slow_limit": {
+				"type": "integer",
+				"description": "Maximum number of slow queries to return, slowest first (default: 20)"
+			}
+		},
+		"required": []
+	}`)
+}
+
+func (t *MetricsTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var req MetricsRequest
+	if len(input) > 0 {
+		if err := json.Unmarshal(input, &req); err != nil {
+			return nil, fmt.Errorf("invalid request: %w", err)
+		}
+	}
+
+	window := 24 * time.Hour
+	if req.Since != "" {
+		d, err := time.ParseDuration(req.Since)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid since %q: expected a positive duration such as 1h or 30m", req.Since)
+		}
+		window = d
+	}
+
+	slowLimit := 20
+	if req.SlowLimit != nil {
+		slowLimit = *req.SlowLimit
+	}
+
+	return t.store.Summarize(time.Now().Add(-window), slowLimit)
+}
@@ -0,0 +1,386 @@
+// Package miniyaml reads and writes the subset of YAML that frontmatter and
+// small config files hold: scalars, flow and block lists, nested mappings
+// and | or > block scalars. Anchors and tags are not understood, and a
+// multi-document stream is rejected rather than merged.
+package miniyaml
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	number   = regexp.MustCompile(`^[-+]?(\d+\.?\d*|\.\d+)([eE][-+]?\d+)?$`)
+	plainKey = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]*$`)
+)
+
+// Parse parses a whole document. Lines holding only a comment are skipped,
+// so they may appear at any indentation. A document holding neither a list
+// nor a mapping is an error, and so is a stream of several documents: a
+// "---" separator followed by more content.
+func Parse(text string) (interface{}, error) {
+	var lines []string
+	content, separator := false, 0
+	for i, line := range strings.Split(text, "\n") {
+		if marker := strings.TrimRight(line, " \t\r"); marker == "---" || strings.HasPrefix(marker, "--- ") {
+			if content && separator == 0 {
+				separator = i + 1
+			}
+			continue
+		}
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+			continue
+		}
+		if trimmed != "" {
+			if separator > 0 {
+				return nil, fmt.Errorf("line %d starts a second document; multi-document YAML is not supported", separator)
+			}
+			content = true
+		}
+		lines = append(lines, line)
+	}
+	lines = dedent(lines)
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	if len(lines) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	if !strings.HasPrefix(lines[0], "-") && !isMappingLine(lines[0]) {
+		return nil, fmt.Errorf("line %q is neither a list item nor a key", lines[0])
+	}
+	return parseBlock(lines), nil
+}
+
+// ParseValue parses the value of a key from the text after its colon
+// and the lines under it
+func ParseValue(inline string, block []string) interface{} {
+	block = dedent(block)
+	inline = stripComment(inline)
+	if strings.HasPrefix(inline, "|") || strings.HasPrefix(inline, ">") {
+		sep := "\n"
+		if inline[0] == '>' {
+			sep = " "
+		}
+		text := strings.Join(block, sep)
+		if sep == "\n" && !strings.HasSuffix(inline, "-") && text != "" {
+			text += "\n"
+		}
+		return text
+	}
+	if inline != "" || len(block) == 0 {
+		return ParseScalar(inline)
+	}
+	return parseBlock(block)
+}
+
+// parseBlock parses dedented lines holding a block list or mapping
+func parseBlock(lines []string) interface{} {
+	if len(lines) == 0 {
+		return nil
+	}
+	if strings.HasPrefix(lines[0], "-") {
+		list := []interface{}{}
+		for len(lines) > 0 {
+			item := strings.TrimSpace(strings.TrimPrefix(lines[0], "-"))
+			end := 1
+			for end < len(lines) && !strings.HasPrefix(lines[end], "-") {
+				end++
+			}
+			children := lines[1:end]
+			lines = lines[end:]
+
+			switch {
+			case len(children) == 0 && !isMappingLine(item):
+				list = append(list, ParseScalar(item))
+			case item == "":
+				list = append(list, parseBlock(dedent(children)))
+			default:
+				list = append(list, parseBlock(append([]string{item}, dedent(children)...)))
+			}
+		}
+		return list
+	}
+
+	mapping := map[string]interface{}{}
+	for i := 0; i < len(lines); {
+		key, inline := SplitMappingLine(lines[i])
+		end := i + 1
+		for end < len(lines) && (lines[end] == "" || IsIndented(lines[end]) || strings.HasPrefix(lines[end], "-")) {
+			end++
+		}
+		if key != "" {
+			mapping[key] = ParseValue(inline, lines[i+1:end])
+		}
+		i = end
+	}
+	return mapping
+}
+
+// ParseScalar parses a single-line value: a quoted or plain string, a
+// number, a boolean, null or a flow list or mapping
+func ParseScalar(s string) interface{} {
+	s = strings.TrimSpace(stripComment(s))
+	switch {
+	case s == "" || s == "~" || s == "null" || s == "Null" || s == "NULL":
+		return nil
+	case s == "true" || s == "True" || s == "TRUE":
+		return true
+	case s == "false" || s == "False" || s == "FALSE":
+		return false
+	case len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"':
+		if unquoted, err := strconv.Unquote(s); err == nil {
+			return unquoted
+		}
+		return s[1 : len(s)-1]
+	case len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'':
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'")
+	case len(s) >= 2 && s[0] == '[' && s[len(s)-1] == ']':
+		list := []interface{}{}
+		for _, item := range splitFlow(s[1 : len(s)-1]) {
+			list = append(list, ParseScalar(item))
+		}
+		return list
+	case len(s) >= 2 && s[0] == '{' && s[len(s)-1] == '}':
+		mapping := map[string]interface{}{}
+		for _, item := range splitFlow(s[1 : len(s)-1]) {
+			if key, value := SplitMappingLine(item); key != "" {
+				mapping[key] = ParseScalar(value)
+			}
+		}
+		return mapping
+	case number.MatchString(s):
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	}
+	return s
+}
+
+// SplitMappingLine splits "key: value" into its unquoted key and the text
+// after the colon; key is "" when line is not a mapping
+func SplitMappingLine(line string) (string, string) {
+	line = strings.TrimSpace(line)
+	start := 0
+	if line != "" && (line[0] == '"' || line[0] == '\'') {
+		if close := strings.IndexByte(line[1:], line[0]); close >= 0 {
+			start = close + 2
+		}
+	}
+	colon := -1
+	for i := start; i < len(line); i++ {
+		if line[i] == ':' && (i+1 == len(line) || line[i+1] == ' ' || line[i+1] == '\t') {
+			colon = i
+			break
+		}
+	}
+	if colon <= 0 {
+		return "", ""
+	}
+	key, _ := ParseScalar(line[:colon]).(string)
+	if key == "" {
+		key = strings.TrimSpace(line[:colon])
+	}
+	return key, strings.TrimSpace(line[colon+1:])
+}
+
+func isMappingLine(s string) bool {
+	if s == "" || strings.ContainsRune(`"'[{`, rune(s[0])) {
+		return false
+	}
+	key, _ := SplitMappingLine(s)
+	return key != ""
+}
+
+// splitFlow splits the inside of a flow list or mapping on its top-level
+// commas
+func splitFlow(s string) []string {
+	var items []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		case c == ',' && depth == 0:
+			items = append(items, s[start:i])
+			start = i + 1
+		}
+	}
+	if last := strings.TrimSpace(s[start:]); last != "" || len(items) > 0 {
+		items = append(items, s[start:])
+	}
+	return items
+}
+
+// stripComment drops a " #" comment outside quotes
+func stripComment(s string) string {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t'):
+			return strings.TrimSpace(s[:i])
+		}
+	}
+	return strings.TrimSpace(s)
+}
+
+// dedent trims trailing carriage returns and the indentation the non-blank
+// lines share, dropping blank lines at the end
+func dedent(lines []string) []string {
+	indent := -1
+	var out []string
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		out = append(out, line)
+		if line == "" {
+			continue
+		}
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	for len(out) > 0 && out[len(out)-1] == "" {
+		out = out[:len(out)-1]
+	}
+	for i, line := range out {
+		if len(line) >= indent && indent > 0 {
+			out[i] = line[indent:]
+		}
+	}
+	return out
+}
+
+func IsIndented(line string) bool {
+	return strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")
+}
+
+// Render renders key and value as block YAML indented by indent spaces
+func Render(key string, value interface{}, indent int) []string {
+	pad := strings.Repeat(" ", indent)
+	if !plainKey.MatchString(key) {
+		key = strconv.Quote(key)
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			return []string{pad + key + ": {}"}
+		}
+		lines := []string{pad + key + ":"}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			lines = append(lines, Render(k, v[k], indent+2)...)
+		}
+		return lines
+	case []interface{}:
+		if len(v) == 0 {
+			return []string{pad + key + ": []"}
+		}
+		lines := []string{pad + key + ":"}
+		for _, item := range v {
+			lines = append(lines, renderItem(item, indent+2)...)
+		}
+		return lines
+	}
+	return []string{pad + key + ": " + renderScalar(value)}
+}
+
+func renderItem(item interface{}, indent int) []string {
+	pad := strings.Repeat(" ", indent)
+	switch v := item.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			return []string{pad + "- {}"}
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var lines []string
+		for _, k := range keys {
+			lines = append(lines, Render(k, v[k], indent+2)...)
+		}
+		lines[0] = pad + "- " + strings.TrimLeft(lines[0], " ")
+		return lines
+	case []interface{}:
+		if len(v) == 0 {
+			return []string{pad + "- []"}
+		}
+		lines := []string{pad + "-"}
+		for _, sub := range v {
+			lines = append(lines, renderItem(sub, indent+2)...)
+		}
+		return lines
+	}
+	return []string{pad + "- " + renderScalar(item)}
+}
+
+// renderScalar renders a JSON scalar, quoting strings that would not
+// read back as the same string unquoted
+func renderScalar(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case string:
+		if plainString(v) {
+			return v
+		}
+		return strconv.Quote(v)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return strconv.Quote(fmt.Sprint(value))
+	}
+	return string(data)
+}
+
+func plainString(s string) bool {
+	if s == "" || s != strings.TrimSpace(s) || strings.ContainsAny(s, "\n\r\t") {
+		return false
+	}
+	if strings.ContainsRune("-?:,[]{}#&*!|>'\"%@`", rune(s[0])) {
+		return false
+	}
+	if strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") {
+		return false
+	}
+	parsed, ok := ParseScalar(s).(string)
+	return ok && parsed == s
+}
@@ -0,0 +1,64 @@
+package miniyaml
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	got, err := Parse(`---
+# settings
+name: demo
+limits:
+  max: 10   # per call
+  ratio: 0.5
+
+tags: [a, "b c"]
+steps:
+    # indented comment
+  - build
+  - run: tests
+    verbose: true
+note: |
+  line one
+  line two
+`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := map[string]interface{}{
+		"name":   "demo",
+		"limits": map[string]interface{}{"max": int64(10), "ratio": 0.5},
+		"tags":   []interface{}{"a", "b c"},
+		"steps":  []interface{}{"build", map[string]interface{}{"run": "tests", "verbose": true}},
+		"note":   "line one\nline two\n",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected document\ngot:  %#v\nwant: %#v", got, want)
+	}
+
+	if got, err := Parse("# nothing\n"); err != nil || !reflect.DeepEqual(got, map[string]interface{}{}) {
+		t.Errorf("expected an empty mapping, got %v (%v)", got, err)
+	}
+	if _, err := Parse("just a string"); err == nil {
+		t.Error("expected error for a document that is not a list or mapping")
+	}
+}
+
+func TestParseMultiDocument(t *testing.T) {
+	if _, err := Parse("a: 1\n---\nb: 2\n"); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected a second document to be rejected at line 2, got %v", err)
+	}
+	if _, err := Parse("- x\n--- # next\n- y\n"); err == nil {
+		t.Error("expected a separator with a comment to start a second document")
+	}
+
+	got, err := Parse("---\na: 1\n---\n\n")
+	if err != nil {
+		t.Fatalf("a leading marker and an empty trailing document should parse: %v", err)
+	}
+	if want := map[string]interface{}{"a": int64(1)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+}
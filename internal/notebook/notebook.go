@@ -0,0 +1,230 @@
+// Package notebook reads and rewrites Jupyter notebooks (.ipynb). Tools see
+// a notebook as its cells rather than the JSON holding them: read returns
+// the cells, edit changes the source of one cell and the indexer extracts
+// symbols cell by cell. Fields this package does not model, such as cell
+// metadata and outputs, are written back as they were read.
+package notebook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Cell types
+const (
+	CodeCell     = "code"
+	MarkdownCell = "markdown"
+	RawCell      = "raw"
+)
+
+// defaultLanguage is the kernel language of notebooks that do not name one
+const defaultLanguage = "python"
+
+// Cell is one cell of a notebook; Index is its 0-based position
+type Cell struct {
+	Index          int      `json:"index"`
+	Type           string   `json:"cell_type"`
+	Source         string   `json:"source"`
+	ExecutionCount *int     `json:"execution_count,omitempty"`
+	Outputs        []Output `json:"outputs,omitempty"`
+}
+
+// Output is one output of a code cell. Text holds a stream's text, the
+// text/plain form of a result or display, or an error's name and value;
+// MimeTypes lists the other forms of a result or display, such as image/png.
+type Output struct {
+	Type      string   `json:"output_type"`
+	Text      string   `json:"text,omitempty"`
+	MimeTypes []string `json:"mime_types,omitempty"`
+}
+
+// Notebook is a parsed notebook
+type Notebook struct {
+	Cells []Cell
+	// Language is the kernel language, e.g., python or julia
+	Language string
+
+	doc   map[string]json.RawMessage
+	cells []map[string]json.RawMessage
+}
+
+// IsNotebook reports whether path names a Jupyter notebook
+func IsNotebook(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".ipynb")
+}
+
+// Parse parses a notebook in the nbformat 4 JSON format
+func Parse(data []byte) (*Notebook, error) {
+	nb := &Notebook{}
+	if err := json.Unmarshal(data, &nb.doc); err != nil {
+		return nil, fmt.Errorf("invalid notebook: %w", err)
+	}
+	raw, ok := nb.doc["cells"]
+	if !ok {
+		return nil, fmt.Errorf("invalid notebook: no cells (only nbformat 4 is supported)")
+	}
+	if err := json.Unmarshal(raw, &nb.cells); err != nil {
+		return nil, fmt.Errorf("invalid notebook cells: %w", err)
+	}
+
+	for i, fields := range nb.cells {
+		cell := Cell{Index: i, Source: multiline(fields["source"])}
+		json.Unmarshal(fields["cell_type"], &cell.Type)
+		if count, ok := fields["execution_count"]; ok {
+			json.Unmarshal(count, &cell.ExecutionCount)
+		}
+		if outputs, ok := fields["outputs"]; ok {
+			cell.Outputs = parseOutputs(outputs)
+		}
+		nb.Cells = append(nb.Cells, cell)
+	}
+
+	nb.Language = language(nb.doc["metadata"])
+	return nb, nil
+}
+
+// multiline decodes an nbformat multiline string, which is a string or a
+// list of lines
+func multiline(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var lines []string
+	json.Unmarshal(raw, &lines)
+	return strings.Join(lines, "")
+}
+
+func parseOutputs(raw json.RawMessage) []Output {
+	var outputs []struct {
+		OutputType string                     `json:"output_type"`
+		Text       json.RawMessage            `json:"text"`
+		Data       map[string]json.RawMessage `json:"data"`
+		Ename      string                     `json:"ename"`
+		Evalue     string                     `json:"evalue"`
+	}
+	json.Unmarshal(raw, &outputs)
+
+	var parsed []Output
+	for _, o := range outputs {
+		out := Output{Type: o.OutputType}
+		switch o.OutputType {
+		case "stream":
+			out.Text = multiline(o.Text)
+		case "error":
+			out.Text = o.Ename + ": " + o.Evalue
+		default:
+			for mime, value := range o.Data {
+				if mime == "text/plain" {
+					out.Text = multiline(value)
+				} else {
+					out.MimeTypes = append(out.MimeTypes, mime)
+				}
+			}
+			sort.Strings(out.MimeTypes)
+		}
+		parsed = append(parsed, out)
+	}
+	return parsed
+}
+
+func language(raw json.RawMessage) string {
+	var metadata struct {
+		Kernelspec struct {
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+	}
+	json.Unmarshal(raw, &metadata)
+	for _, name := range []string{metadata.LanguageInfo.Name, metadata.Kernelspec.Language} {
+		if name != "" {
+			return strings.ToLower(name)
+		}
+	}
+	return defaultLanguage
+}
+
+// Cell returns the cell at index
+func (nb *Notebook) Cell(index int) (*Cell, error) {
+	if index < 0 || index >= len(nb.Cells) {
+		return nil, fmt.Errorf("cell %d out of range: the notebook has %d cells", index, len(nb.Cells))
+	}
+	return &nb.Cells[index], nil
+}
+
+// SetSource replaces the source of the cell at index
+func (nb *Notebook) SetSource(index int, source string) error {
+	cell, err := nb.Cell(index)
+	if err != nil {
+		return err
+	}
+	raw, err := encode(splitLines(source), "")
+	if err != nil {
+		return err
+	}
+	cell.Source = source
+	nb.cells[index]["source"] = raw
+	return nil
+}
+
+// splitLines splits source into lines that keep their newline, the form
+// Jupyter saves sources in
+func splitLines(source string) []string {
+	lines := strings.SplitAfter(source, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Marshal encodes the notebook as Jupyter saves it: keys sorted, one space
+// of indentation and a final newline
+func (nb *Notebook) Marshal() ([]byte, error) {
+	cells, err := encode(nb.cells, "")
+	if err != nil {
+		return nil, err
+	}
+	nb.doc["cells"] = cells
+	data, err := encode(nb.doc, " ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// encode marshals v without escaping <, > and &, which are common in code
+// and which Jupyter writes as they are
+func encode(v interface{}, indent string) (json.RawMessage, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", indent)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// Render writes the cells as text in the percent format editors use for
+// notebooks as scripts, each cell headed by a "# %%" line naming its index
+// and type
+func (nb *Notebook) Render() string {
+	var b strings.Builder
+	for _, cell := range nb.Cells {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "# %%%% [%d] %s\n", cell.Index, cell.Type)
+		b.WriteString(cell.Source)
+		if cell.Source != "" && !strings.HasSuffix(cell.Source, "\n") {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,133 @@
+package notebook
+
+import (
+	"strings"
+	"testing"
+)
+
+const sample = `{
+ "cells": [
+  {
+   "cell_type": "code",
+   "execution_count": 1,
+   "id": "a1",
+   "metadata": {},
+   "outputs": [
+    {
+     "data": {
+      "image/png": "iVBORw0KGgo=",
+      "text/plain": [
+       "<Figure size 640x480>"
+      ]
+     },
+     "metadata": {},
+     "output_type": "display_data"
+    },
+    {
+     "ename": "NameError",
+     "evalue": "name 'x' is not defined",
+     "output_type": "error",
+     "traceback": []
+    }
+   ],
+   "source": "if a < b && c:\n    plot()"
+  },
+  {
+   "cell_type": "markdown",
+   "id": "b2",
+   "metadata": {},
+   "source": []
+  }
+ ],
+ "metadata": {
+  "language_info": {
+   "name": "Julia"
+  }
+ },
+ "nbformat": 4,
+ "nbformat_minor": 5
+}
+`
+
+func TestParse(t *testing.T) {
+	nb, err := Parse([]byte(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nb.Language != "julia" {
+		t.Errorf("expected language julia, got %q", nb.Language)
+	}
+	if len(nb.Cells) != 2 {
+		t.Fatalf("expected 2 cells, got %d", len(nb.Cells))
+	}
+
+	code := nb.Cells[0]
+	if code.Type != CodeCell || code.Source != "if a < b && c:\n    plot()" || code.ExecutionCount == nil || *code.ExecutionCount != 1 {
+		t.Errorf("unexpected code cell %+v", code)
+	}
+	if len(code.Outputs) != 2 {
+		t.Fatalf("expected 2 outputs, got %+v", code.Outputs)
+	}
+	if out := code.Outputs[0]; out.Text != "<Figure size 640x480>" || len(out.MimeTypes) != 1 || out.MimeTypes[0] != "image/png" {
+		t.Errorf("unexpected display output %+v", out)
+	}
+	if out := code.Outputs[1]; out.Text != "NameError: name 'x' is not defined" {
+		t.Errorf("unexpected error output %+v", out)
+	}
+	if cell := nb.Cells[1]; cell.Index != 1 || cell.Type != MarkdownCell || cell.Source != "" {
+		t.Errorf("unexpected markdown cell %+v", cell)
+	}
+
+	if _, err := Parse([]byte(`{"worksheets": []}`)); err == nil {
+		t.Error("expected an error for an nbformat 3 notebook")
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	nb, err := Parse([]byte(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unchanged, err := nb.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// sources are written as lists of lines, as Jupyter saves them
+	want := strings.Replace(sample, `"source": "if a < b && c:\n    plot()"`, "\"source\": [\n    \"if a < b && c:\\n\",\n    \"    plot()\"\n   ]", 1)
+	if err := nb.SetSource(0, nb.Cells[0].Source); err != nil {
+		t.Fatal(err)
+	}
+	data, err := nb.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, data)
+	}
+	if string(unchanged) != sample {
+		t.Errorf("expected an unchanged notebook to be written as read, got\n%s", unchanged)
+	}
+
+	if err := nb.SetSource(1, "# Results\n"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = nb.Marshal()
+	if !strings.Contains(string(data), "\"source\": [\n    \"# Results\\n\"\n   ]") {
+		t.Errorf("markdown source not written as lines:\n%s", data)
+	}
+	if err := nb.SetSource(2, "x"); err == nil {
+		t.Error("expected an error for a cell out of range")
+	}
+}
+
+func TestRender(t *testing.T) {
+	nb, err := Parse([]byte(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# %% [0] code\nif a < b && c:\n    plot()\n\n# %% [1] markdown\n"
+	if got := nb.Render(); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
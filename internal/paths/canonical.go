@@ -0,0 +1,146 @@
+// Package paths gives every file one identity however its path is spelled.
+// The watcher, the indexer, the router and the file tools all key files by
+// Canonical, so a file reached through a symlink, a relative path or, on a
+// case-insensitive file system, another letter case is still one file.
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// caseInsensitiveOS is set where file systems are case-insensitive by
+// default; each directory is still checked, since either kind can be
+// mounted anywhere
+var caseInsensitiveOS = runtime.GOOS == "darwin" || runtime.GOOS == "windows"
+
+// Canonical returns the identity of path: absolute and clean, with
+// symlinks resolved and, on a case-insensitive file system, every name
+// spelled as on disk. The part of path that does not exist yet is kept as
+// given, so a file about to be created gets the identity it will have.
+func Canonical(path string) string {
+	if path == "" {
+		return ""
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+
+	existing, missing := abs, ""
+	for {
+		resolved, err := filepath.EvalSymlinks(existing)
+		if err == nil {
+			existing = resolved
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return abs
+		}
+		missing = filepath.Join(filepath.Base(existing), missing)
+		existing = parent
+	}
+
+	if caseInsensitiveOS {
+		existing = diskCase(existing)
+	}
+	if missing == "" {
+		return existing
+	}
+	return filepath.Join(existing, missing)
+}
+
+// diskCase respells each name of an existing absolute path as its
+// directory lists it
+func diskCase(path string) string {
+	volume := filepath.VolumeName(path)
+	current := volume + string(filepath.Separator)
+	for _, name := range strings.Split(strings.TrimPrefix(path[len(volume):], string(filepath.Separator)), string(filepath.Separator)) {
+		if name == "" {
+			continue
+		}
+		if foldsCase(current, name) {
+			if listed, ok := listedName(current, name); ok {
+				name = listed
+			}
+		}
+		current = filepath.Join(current, name)
+	}
+	return current
+}
+
+// foldsCase reports whether dir finds name under another letter case too
+func foldsCase(dir, name string) bool {
+	swapped := swapCase(name)
+	if swapped == name {
+		return false
+	}
+	a, err := os.Lstat(filepath.Join(dir, name))
+	if err != nil {
+		return false
+	}
+	b, err := os.Lstat(filepath.Join(dir, swapped))
+	return err == nil && os.SameFile(a, b)
+}
+
+func swapCase(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsUpper(r) {
+			return unicode.ToLower(r)
+		}
+		return unicode.ToUpper(r)
+	}, s)
+}
+
+// maxListings bounds the directory listings kept by listedName
+const maxListings = 1024
+
+type listing struct {
+	modTime time.Time
+	names   map[string]string
+}
+
+var (
+	listingsMu sync.Mutex
+	listings   = make(map[string]*listing)
+)
+
+// listedName returns the entry of dir that equals name ignoring case.
+// Listings are cached until the directory changes.
+func listedName(dir, name string) (string, bool) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", false
+	}
+
+	listingsMu.Lock()
+	cached := listings[dir]
+	listingsMu.Unlock()
+
+	if cached == nil || !cached.modTime.Equal(info.ModTime()) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return "", false
+		}
+		cached = &listing{modTime: info.ModTime(), names: make(map[string]string, len(entries))}
+		for _, entry := range entries {
+			cached.names[strings.ToLower(entry.Name())] = entry.Name()
+		}
+
+		listingsMu.Lock()
+		if len(listings) >= maxListings {
+			clear(listings)
+		}
+		listings[dir] = cached
+		listingsMu.Unlock()
+	}
+
+	listed, ok := cached.names[strings.ToLower(name)]
+	return listed, ok
+}
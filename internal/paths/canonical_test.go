@@ -0,0 +1,87 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCanonical(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	real := filepath.Join(root, "real")
+	if err := os.MkdirAll(filepath.Join(real, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(real, "sub", "main.go")
+	if err := os.WriteFile(file, []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(root, "link")
+	if err := os.Symlink(real, link); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(real); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"absolute", file, file},
+		{"through a symlink", filepath.Join(link, "sub", "main.go"), file},
+		{"relative", filepath.Join("sub", "main.go"), file},
+		{"unclean", filepath.Join(link, "sub", "..", "sub", ".", "main.go"), file},
+		{"missing file", filepath.Join(link, "sub", "new.go"), filepath.Join(real, "sub", "new.go")},
+		{"missing directories", filepath.Join(link, "a", "b", "c.go"), filepath.Join(real, "a", "b", "c.go")},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Canonical(tt.path); got != tt.want {
+				t.Errorf("Canonical(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListedName(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "ReadMe.MD"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, ok := listedName(dir, "readme.md"); !ok || got != "ReadMe.MD" {
+		t.Errorf("listedName(readme.md) = %q, %v; want ReadMe.MD", got, ok)
+	}
+	if _, ok := listedName(dir, "other.md"); ok {
+		t.Error("listedName found a file that does not exist")
+	}
+}
+
+func TestDiskCaseKeepsCaseSensitiveNames(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "Makefile")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if foldsCase(dir, "Makefile") {
+		t.Skip("the temp directory is case-insensitive")
+	}
+	if got := diskCase(path); got != path {
+		t.Errorf("diskCase(%q) = %q", path, got)
+	}
+}
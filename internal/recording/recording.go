@@ -0,0 +1,146 @@
+// Package recording captures the JSON-RPC traffic of an MCP session to a
+// file, one message per line, so the session can later be replayed
+// against the handler as a regression test.
+package recording
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Directions of a recorded message
+const (
+	// In is a message from the MCP client
+	In = "in"
+	// Out is a message to the MCP client
+	Out = "out"
+	// Meta is the header describing the session
+	Meta = "meta"
+)
+
+// Entry is one line of a recording
+type Entry struct {
+	Time      time.Time       `json:"time"`
+	Direction string          `json:"dir"`
+	Message   json.RawMessage `json:"message"`
+}
+
+// Header is the message of the Meta entry a recording starts with
+type Header struct {
+	// Workspace is the directory the session ran in; replays substitute
+	// their own for it
+	Workspace string `json:"workspace"`
+	Version   string `json:"version,omitempty"`
+}
+
+type Recorder struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+// Create starts a recording at path, truncating an existing one
+func Create(path string, header Header) (*Recorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, err
+	}
+	r := &Recorder{file: file, enc: json.NewEncoder(file)}
+
+	data, err := json.Marshal(header)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := r.Record(Meta, data); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// Record appends a message. Messages that are not JSON are recorded as
+// strings so the line stays valid.
+func (r *Recorder) Record(direction string, message []byte) error {
+	message = trimNewline(message)
+	if !json.Valid(message) {
+		message, _ = json.Marshal(string(message))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.enc.Encode(Entry{Time: time.Now().UTC(), Direction: direction, Message: message})
+}
+
+// Tee returns a writer passing writes on to w and recording each one as
+// a message in direction; it expects one message per write, as
+// json.Encoder does
+func (r *Recorder) Tee(direction string, w io.Writer) io.Writer {
+	return &tee{r: r, direction: direction, w: w}
+}
+
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
+
+type tee struct {
+	r         *Recorder
+	direction string
+	w         io.Writer
+}
+
+func (t *tee) Write(p []byte) (int, error) {
+	n, err := t.w.Write(p)
+	if n > 0 {
+		t.r.Record(t.direction, p[:n])
+	}
+	return n, err
+}
+
+func trimNewline(b []byte) []byte {
+	for len(b) > 0 && (b[len(b)-1] == '\n' || b[len(b)-1] == '\r') {
+		b = b[:len(b)-1]
+	}
+	return b
+}
+
+// Load reads the recording at path
+func Load(path string) (*Header, []Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	var header Header
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if entry.Direction == Meta {
+			if err := json.Unmarshal(entry.Message, &header); err != nil {
+				return nil, nil, fmt.Errorf("%s:%d: bad header: %w", path, line, err)
+			}
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	return &header, entries, nil
+}
@@ -0,0 +1,45 @@
+package recording
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecordAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	r, err := Create(path, Header{Workspace: "/work", Version: "dev"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	r.Record(In, []byte(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+	var stdout bytes.Buffer
+	out := r.Tee(Out, &stdout)
+	out.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{}}` + "\n"))
+	r.Record(In, []byte("not json"))
+	if err := r.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if stdout.String() != `{"jsonrpc":"2.0","id":1,"result":{}}`+"\n" {
+		t.Errorf("expected the write to pass through, got %q", stdout.String())
+	}
+
+	header, entries, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if header.Workspace != "/work" {
+		t.Errorf("expected the header, got %+v", header)
+	}
+	if len(entries) != 3 || entries[0].Direction != In || entries[1].Direction != Out {
+		t.Fatalf("expected the messages in order, got %+v", entries)
+	}
+	if string(entries[1].Message) != `{"jsonrpc":"2.0","id":1,"result":{}}` {
+		t.Errorf("expected the message without its newline, got %s", entries[1].Message)
+	}
+	if string(entries[2].Message) != `"not json"` {
+		t.Errorf("expected invalid JSON to be kept as a string, got %s", entries[2].Message)
+	}
+}
@@ -0,0 +1,276 @@
+package redact
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/alucardeht/may-la-mcp/internal/miniyaml"
+)
+
+// secretGroup is the name of the capture group that, when present in a rule
+// pattern, limits masking to that group (e.g. keep the key of an .env line
+// and mask only its value).
+const secretGroup = "secret"
+
+// WorkspaceFile adds redaction rules for one project, relative to its root:
+//
+//	rules:
+//	  internal_token: "itk_[A-Za-z0-9]{32}"
+//	  db_url: "postgres://[^:]+:(?P<secret>[^@]+)@"
+//
+// The file can only add rules; the built-in ones always apply.
+const WorkspaceFile = ".mayla/redact.yaml"
+
+type RuleConfig struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+}
+
+type Config struct {
+	Enabled bool `json:"enabled"`
+	// AllowOptOut lets a tool call pass "redact": false to get raw
+	// output; only the operator can turn it on
+	AllowOptOut bool         `json:"allow_opt_out"`
+	Rules       []RuleConfig `json:"rules"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Enabled: true,
+	}
+}
+
+// LoadWorkspace adds the rules of the workspace file of the project at root
+// to cfg; a project without the file keeps cfg as is. A file with a rule
+// that does not compile is rejected whole.
+func LoadWorkspace(root string, cfg Config) (Config, error) {
+	data, err := os.ReadFile(filepath.Join(root, WorkspaceFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read %s: %w", WorkspaceFile, err)
+	}
+
+	parsed, err := miniyaml.Parse(string(data))
+	if err != nil {
+		return cfg, fmt.Errorf("invalid %s: %w", WorkspaceFile, err)
+	}
+	settings, ok := parsed.(map[string]interface{})
+	if !ok {
+		return cfg, fmt.Errorf("invalid %s: expected a mapping", WorkspaceFile)
+	}
+	rules, ok := settings["rules"].(map[string]interface{})
+	if !ok {
+		return cfg, nil
+	}
+
+	names := make([]string, 0, len(rules))
+	for name := range rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	added := append([]RuleConfig(nil), cfg.Rules...)
+	for _, name := range names {
+		pattern, ok := rules[name].(string)
+		if !ok {
+			return cfg, fmt.Errorf("invalid %s: rule %s must be a pattern", WorkspaceFile, name)
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return cfg, fmt.Errorf("invalid %s: rule %s: %w", WorkspaceFile, name, err)
+		}
+		added = append(added, RuleConfig{Name: name, Pattern: pattern})
+	}
+	cfg.Rules = added
+	return cfg, nil
+}
+
+type Rule struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+var builtinRules = []Rule{
+	{"private_key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`)},
+	{"aws_access_key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"aws_secret_key", regexp.MustCompile(`(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?(?P<secret>[A-Za-z0-9/+=]{40})`)},
+	{"jwt", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`)},
+	{"github_token", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`)},
+	{"slack_token", regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}\b`)},
+	{"env_secret", regexp.MustCompile(`(?m)^\s*(?:export\s+)?[A-Za-z0-9_]*(?i:secret|token|passw(?:or)?d|api_?key|private_key|access_key|credentials?)[A-Za-z0-9_]*\s*=\s*["']?(?P<secret>[^\s"']+)`)},
+}
+
+// DefaultRules returns the built-in secret detection rules
+func DefaultRules() []Rule {
+	rules := make([]Rule, len(builtinRules))
+	copy(rules, builtinRules)
+	return rules
+}
+
+type Redactor struct {
+	rules       []Rule
+	allowOptOut bool
+	redacted    atomic.Int64
+}
+
+// New builds a redactor from the built-in rules plus any user-configured
+// rules. It returns nil when redaction is disabled.
+func New(cfg Config) (*Redactor, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	rules := DefaultRules()
+	for _, rc := range cfg.Rules {
+		re, err := regexp.Compile(rc.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction rule %q: %w", rc.Name, err)
+		}
+		rules = append(rules, Rule{Name: rc.Name, Pattern: re})
+	}
+
+	return &Redactor{rules: rules, allowOptOut: cfg.AllowOptOut}, nil
+}
+
+// AllowOptOut reports whether individual calls may disable redaction
+func (r *Redactor) AllowOptOut() bool {
+	return r != nil && r.allowOptOut
+}
+
+// Redacted returns the total number of secrets masked by this redactor
+func (r *Redactor) Redacted() int64 {
+	if r == nil {
+		return 0
+	}
+	return r.redacted.Load()
+}
+
+// Redact masks every secret found in s and returns the result together with
+// the number of matches that were masked.
+func (r *Redactor) Redact(s string) (string, int) {
+	if r == nil || s == "" {
+		return s, 0
+	}
+
+	total := 0
+	for _, rule := range r.rules {
+		var n int
+		s, n = applyRule(s, rule)
+		total += n
+	}
+
+	if total > 0 {
+		r.redacted.Add(int64(total))
+	}
+	return s, total
+}
+
+// RedactString is Redact without the match count
+func (r *Redactor) RedactString(s string) string {
+	out, _ := r.Redact(s)
+	return out
+}
+
+// RedactJSON masks secrets inside every string value of a JSON document,
+// leaving keys and structure untouched.
+func (r *Redactor) RedactJSON(data []byte) ([]byte, int, error) {
+	if r == nil {
+		return data, 0, nil
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, 0, err
+	}
+
+	count := 0
+	doc = r.redactValue(doc, &count)
+	if count == 0 {
+		return data, 0, nil
+	}
+
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return nil, 0, err
+	}
+	return out, count, nil
+}
+
+func (r *Redactor) redactValue(v interface{}, count *int) interface{} {
+	switch val := v.(type) {
+	case string:
+		out, n := r.Redact(val)
+		*count += n
+		return out
+	case []interface{}:
+		for i := range val {
+			val[i] = r.redactValue(val[i], count)
+		}
+		return val
+	case map[string]interface{}:
+		for k := range val {
+			val[k] = r.redactValue(val[k], count)
+		}
+		return val
+	default:
+		return v
+	}
+}
+
+func applyRule(s string, rule Rule) (string, int) {
+	matches := rule.Pattern.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return s, 0
+	}
+
+	group := rule.Pattern.SubexpIndex(secretGroup)
+	mask := "[REDACTED:" + rule.Name + "]"
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if group > 0 && m[2*group] >= 0 {
+			start, end = m[2*group], m[2*group+1]
+		}
+		b.WriteString(s[last:start])
+		b.WriteString(mask)
+		last = end
+	}
+	b.WriteString(s[last:])
+
+	return b.String(), len(matches)
+}
+
+type writer struct {
+	w io.Writer
+	r *Redactor
+}
+
+// NewWriter wraps w so that everything written through it is redacted first.
+// Each Write is redacted independently, which matches line-oriented loggers.
+func NewWriter(w io.Writer, r *Redactor) io.Writer {
+	if r == nil {
+		return w
+	}
+	return &writer{w: w, r: r}
+}
+
+func (rw *writer) Write(p []byte) (int, error) {
+	out, n := rw.r.Redact(string(p))
+	if n == 0 {
+		return rw.w.Write(p)
+	}
+	if _, err := io.WriteString(rw.w, out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -9,7 +9,7 @@ func InitializeFileTools(registry *Registry) error {
 	tools := files.GetTools()
 
 	for _, tool := range tools {
-		if err := registry.Register(tool); err != nil {
+		if err := registry.RegisterIn("files", tool); err != nil {
 			log.Printf("Failed to register file tool: %v", err)
 			return err
 		}
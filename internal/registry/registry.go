@@ -1,93 +1,15 @@
+// Package registry predates tools.Registry and is kept for code that still
+// imports it; its registry and tools are those of package tools.
 package registry
 
 import (
-	"context"
-	"encoding/json"
-	"fmt"
-	"log"
-	"sync"
+	"github.com/alucardeht/may-la-mcp/internal/tools"
 )
 
-type Tool interface {
-	Name() string
-	Description() string
-	Schema() json.RawMessage
-	Execute(ctx context.Context, input json.RawMessage) (interface{}, error)
-}
+type Tool = tools.Tool
 
-type Registry struct {
-	mu    sync.RWMutex
-	tools map[string]Tool
-}
+type Registry = tools.Registry
 
 func NewRegistry() *Registry {
-	return &Registry{
-		tools: make(map[string]Tool),
-	}
-}
-
-func (r *Registry) Register(tool Tool) error {
-	if tool.Name() == "" {
-		return fmt.Errorf("tool name cannot be empty")
-	}
-
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	if _, exists := r.tools[tool.Name()]; exists {
-		return fmt.Errorf("tool '%s' already registered", tool.Name())
-	}
-
-	r.tools[tool.Name()] = tool
-	log.Printf("Registered tool: %s", tool.Name())
-	return nil
-}
-
-func (r *Registry) Get(name string) (Tool, error) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-
-	tool, exists := r.tools[name]
-	if !exists {
-		return nil, fmt.Errorf("tool '%s' not found", name)
-	}
-	return tool, nil
-}
-
-func (r *Registry) List() []Tool {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-
-	tools := make([]Tool, 0, len(r.tools))
-	for _, tool := range r.tools {
-		tools = append(tools, tool)
-	}
-	return tools
-}
-
-func (r *Registry) Execute(ctx context.Context, name string, input json.RawMessage) (interface{}, error) {
-	tool, err := r.Get(name)
-	if err != nil {
-		return nil, err
-	}
-
-	return tool.Execute(ctx, input)
-}
-
-func (r *Registry) GetToolDefinitions() []map[string]interface{} {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-
-	definitions := make([]map[string]interface{}, 0, len(r.tools))
-
-	for _, tool := range r.tools {
-		def := map[string]interface{}{
-			"name":        tool.Name(),
-			"description": tool.Description(),
-			"inputSchema": tool.Schema(),
-		}
-		definitions = append(definitions, def)
-	}
-
-	return definitions
+	return tools.NewRegistry()
 }
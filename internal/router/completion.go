@@ -0,0 +1,171 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"github.com/alucardeht/may-la-mcp/internal/index"
+	"github.com/alucardeht/may-la-mcp/internal/lsp"
+	"github.com/alucardeht/may-la-mcp/internal/paths"
+	"github.com/alucardeht/may-la-mcp/internal/types"
+)
+
+var ErrNoLanguageServer = errors.New("completion needs a language server")
+
+// CompletionResult is the answer to a completion query. Incomplete is set
+// when the server says typing further would change the list.
+type CompletionResult struct {
+	Items      []Completion  `json:"items"`
+	Count      int           `json:"count"`
+	Incomplete bool          `json:"incomplete"`
+	Latency    time.Duration `json:"latency_ms"`
+}
+
+// QueryCompletions asks the language server for completions at line and
+// column (both 1-based, column in characters) of path. content replaces the
+// file's text on disk when not nil, so unsaved edits can be completed.
+// Completion has no index or regex tier.
+func (r *Router) QueryCompletions(ctx context.Context, path string, line, column int, content *string, maxResults int) (*CompletionResult, error) {
+	start := time.Now()
+	result, err := r.queryCompletions(ctx, path, line, column, content, maxResults)
+	if err != nil {
+		r.observe(OperationCompletions, start, SourceLSP, 0, false, err)
+	} else {
+		r.observe(OperationCompletions, start, SourceLSP, result.Count, false, nil)
+	}
+	return result, err
+}
+
+func (r *Router) queryCompletions(ctx context.Context, path string, line, column int, content *string, maxResults int) (*CompletionResult, error) {
+	start := time.Now()
+	if r.lspManager == nil {
+		return nil, ErrNoLanguageServer
+	}
+
+	absPath := paths.Canonical(path)
+
+	var text string
+	var err error
+	if content != nil {
+		text = *content
+	} else {
+		text, _, err = index.ReadFileAsUTF8(absPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	pos, err := textPosition(text, line, column)
+	if err != nil {
+		return nil, err
+	}
+
+	// The server may still be starting, so completion gets the whole query
+	// budget rather than the LSP tier's
+	lspCtx, cancel := WithTimeout(ctx, r.timeouts.Total)
+	defer cancel()
+
+	list, err := r.lspManager.GetCompletions(lspCtx, absPath, text, pos)
+	if err != nil {
+		return nil, fmt.Errorf("lsp completion: %w", err)
+	}
+
+	items := list.Items
+	sort.SliceStable(items, func(i, j int) bool {
+		return sortKey(items[i]) < sortKey(items[j])
+	})
+
+	incomplete := list.IsIncomplete
+	if maxResults > 0 && len(items) > maxResults {
+		items = items[:maxResults]
+		incomplete = true
+	}
+
+	completions := make([]Completion, len(items))
+	for i, item := range items {
+		completions[i] = Completion{
+			Label:         item.Label,
+			Kind:          item.Kind.String(),
+			Detail:        item.Detail,
+			Documentation: documentationText(item.Documentation),
+			InsertText:    insertText(item),
+			Deprecated:    item.Deprecated || hasDeprecatedTag(item.Tags),
+		}
+	}
+
+	return &CompletionResult{
+		Items:      completions,
+		Count:      len(completions),
+		Incomplete: incomplete,
+		Latency:    time.Since(start),
+	}, nil
+}
+
+// textPosition converts a line and column, as types defines them, into
+// the zero-based UTF-16 position LSP expects. The column may be one past
+// the end of the line, where completion usually happens.
+func textPosition(text string, line, column int) (lsp.Position, error) {
+	lines := types.SplitLines(text)
+	if line < 1 || line > len(lines) {
+		return lsp.Position{}, fmt.Errorf("line %d is outside the file (1-%d)", line, len(lines))
+	}
+
+	lineText := lines[line-1]
+	chars := utf8.RuneCountInString(lineText)
+	if column < 1 || column > chars+1 {
+		return lsp.Position{}, fmt.Errorf("column %d is outside line %d (1-%d)", column, line, chars+1)
+	}
+
+	return lsp.Position{Line: line - 1, Character: types.UTF16FromColumn(lineText, column)}, nil
+}
+
+func sortKey(item lsp.CompletionItem) string {
+	if item.SortText != "" {
+		return item.SortText
+	}
+	return item.Label
+}
+
+// insertText is what accepting the item inserts: the text edit, the insert
+// text or the label, in the order LSP gives them precedence
+func insertText(item lsp.CompletionItem) string {
+	switch {
+	case item.TextEdit != nil && item.TextEdit.NewText != "":
+		return item.TextEdit.NewText
+	case item.InsertText != "":
+		return item.InsertText
+	}
+	return ""
+}
+
+// documentationText flattens LSP documentation, a string or MarkupContent
+func documentationText(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text
+	}
+	var markup struct {
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(raw, &markup); err == nil {
+		return markup.Value
+	}
+	return ""
+}
+
+func hasDeprecatedTag(tags []int) bool {
+	for _, tag := range tags {
+		if tag == 1 {
+			return true
+		}
+	}
+	return false
+}
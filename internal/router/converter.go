@@ -8,7 +8,7 @@ import (
 func FromIndexedSymbol(indexed *index.IndexedSymbol) types.Symbol {
 	return types.Symbol{
 		Name:          indexed.Name,
-		Kind:          indexed.Kind,
+		Kind:          types.NormalizeKind(indexed.Kind),
 		File:          "",
 		Line:          indexed.LineStart,
 		LineEnd:       indexed.LineEnd,
@@ -17,6 +17,7 @@ func FromIndexedSymbol(indexed *index.IndexedSymbol) types.Symbol {
 		Signature:     indexed.Signature,
 		Documentation: indexed.Documentation,
 		IsExported:    indexed.IsExported,
+		Cell:          indexed.Cell,
 	}
 }
 
@@ -26,7 +26,7 @@ func (h *FileHasher) ComputeHash(path string) (string, error) {
 	return hex.EncodeToString(hasher.Sum(nil)), nil
 }
 
-func IsFileFresh(store *index.IndexStore, path string) (bool, error) {
+func IsFileFresh(store index.FileStore, path string) (bool, error) {
 	indexed, err := store.GetFile(path)
 	if err != nil {
 		return false, err
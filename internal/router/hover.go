@@ -0,0 +1,201 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/alucardeht/may-la-mcp/internal/index"
+	"github.com/alucardeht/may-la-mcp/internal/intel"
+	"github.com/alucardeht/may-la-mcp/internal/lsp"
+	"github.com/alucardeht/may-la-mcp/internal/paths"
+	"github.com/alucardeht/may-la-mcp/internal/types"
+)
+
+// HoverQuery selects what to describe: the symbol at Line and Column
+// (1-based, column in characters) of Path, or the definition of Symbol,
+// looked up in the index and preferring one in or under Path.
+type HoverQuery struct {
+	Path      string
+	Line      int
+	Column    int
+	Symbol    string
+	MaxLength int
+}
+
+// HoverResult is the type information and documentation of a symbol.
+// Fallback is set when the index answered instead of the language server.
+type HoverResult struct {
+	Symbol        string        `json:"symbol,omitempty"`
+	File          string        `json:"file"`
+	Line          int           `json:"line"`
+	Signature     string        `json:"signature,omitempty"`
+	Documentation string        `json:"documentation,omitempty"`
+	Source        QuerySource   `json:"source"`
+	Fallback      bool          `json:"fallback,omitempty"`
+	Truncated     bool          `json:"truncated,omitempty"`
+	Latency       time.Duration `json:"latency_ms"`
+}
+
+func (r *Router) QueryHover(ctx context.Context, q HoverQuery) (*HoverResult, error) {
+	start := time.Now()
+	result, err := r.queryHover(ctx, q)
+	if err != nil {
+		r.observe(OperationHover, start, "", 0, false, err)
+	} else {
+		r.observe(OperationHover, start, result.Source, 1, result.Fallback, nil)
+	}
+	return result, err
+}
+
+func (r *Router) queryHover(ctx context.Context, q HoverQuery) (*HoverResult, error) {
+	start := time.Now()
+
+	result, err := r.hoverTarget(q)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.lspManager != nil {
+		// The server may still be starting, so hover gets the whole query
+		// budget rather than the LSP tier's
+		lspCtx, cancel := WithTimeout(ctx, r.timeouts.Total)
+		markdown, err := r.lspHover(lspCtx, result.File, q)
+		cancel()
+		if err != nil {
+			log.Debug("LSP hover failed", "file", result.File, "error", err)
+		}
+		if markdown != "" {
+			result.Source = SourceLSP
+			if blocks := intel.CodeBlocks(markdown); len(blocks) > 0 {
+				result.Signature = blocks[0]
+				result.Documentation = intel.StripCodeBlocks(markdown)
+			} else {
+				result.Documentation = intel.StripMarkdown(markdown)
+			}
+		}
+	}
+
+	if result.Source == "" {
+		if err := r.indexHover(result, q.Path); err != nil {
+			return nil, err
+		}
+	}
+
+	if q.MaxLength > 0 {
+		signature := intel.Truncate(result.Signature, q.MaxLength, intel.TruncateModeSmart)
+		documentation := intel.Truncate(result.Documentation, q.MaxLength, intel.TruncateModeSmart)
+		result.Truncated = signature != result.Signature || documentation != result.Documentation
+		result.Signature, result.Documentation = signature, documentation
+	}
+
+	result.Latency = time.Since(start)
+	return result, nil
+}
+
+// hoverTarget resolves the file, line and symbol name the query is about
+func (r *Router) hoverTarget(q HoverQuery) (*HoverResult, error) {
+	if q.Symbol != "" {
+		if r.index == nil {
+			return nil, fmt.Errorf("hover by symbol name needs the index")
+		}
+		def, file, err := r.findDefinition(q.Symbol, q.Path)
+		if err != nil {
+			return nil, err
+		}
+		return &HoverResult{Symbol: q.Symbol, File: file, Line: def.LineStart}, nil
+	}
+
+	absPath := paths.Canonical(q.Path)
+	text, _, err := index.ReadFileAsUTF8(absPath)
+	if err != nil {
+		return nil, err
+	}
+	pos, err := textPosition(text, q.Line, q.Column)
+	if err != nil {
+		return nil, err
+	}
+
+	lineText := types.SplitLines(text)[pos.Line]
+	return &HoverResult{
+		Symbol: identifierAt(lineText, types.ByteFromColumn(lineText, q.Column)),
+		File:   absPath,
+		Line:   q.Line,
+	}, nil
+}
+
+// lspHover asks the language server about the query position, or the
+// definition of the named symbol
+func (r *Router) lspHover(ctx context.Context, file string, q HoverQuery) (string, error) {
+	var pos lsp.Position
+	var err error
+	if q.Symbol != "" {
+		file, pos, err = r.resolveDefinitionPosition(q.Symbol, q.Path)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	text, _, err := index.ReadFileAsUTF8(file)
+	if err != nil {
+		return "", err
+	}
+
+	if q.Symbol == "" {
+		pos, err = textPosition(text, q.Line, q.Column)
+		if err != nil {
+			return "", err
+		}
+	}
+	return r.lspManager.GetHover(ctx, file, text, pos)
+}
+
+// indexHover fills result from the indexed signature and doc comment of
+// the symbol's definition
+func (r *Router) indexHover(result *HoverResult, scope string) error {
+	if r.index == nil || result.Symbol == "" {
+		return fmt.Errorf("no hover information at %s:%d", result.File, result.Line)
+	}
+
+	if scope == "" {
+		scope = result.File
+	}
+	def, file, err := r.findDefinition(result.Symbol, scope)
+	if err != nil {
+		return fmt.Errorf("no hover information for %s: %w", result.Symbol, err)
+	}
+
+	result.File = file
+	result.Line = def.LineStart
+	result.Signature = def.Signature
+	result.Documentation = def.Documentation
+	result.Source = SourceIndex
+	result.Fallback = true
+	return nil
+}
+
+// identifierAt returns the identifier around byte offset col of line
+func identifierAt(line string, col int) string {
+	isIdent := func(r rune) bool {
+		return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+	}
+
+	start := col
+	for start > 0 {
+		r, size := utf8.DecodeLastRuneInString(line[:start])
+		if !isIdent(r) {
+			break
+		}
+		start -= size
+	}
+	end := col
+	for _, r := range line[col:] {
+		if !isIdent(r) {
+			break
+		}
+		end += len(string(r))
+	}
+	return line[start:end]
+}
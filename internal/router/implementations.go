@@ -0,0 +1,291 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/goanalysis"
+	"github.com/alucardeht/may-la-mcp/internal/index"
+	"github.com/alucardeht/may-la-mcp/internal/lsp"
+	"github.com/alucardeht/may-la-mcp/internal/paths"
+	"github.com/alucardeht/may-la-mcp/internal/types"
+)
+
+// ImplementationsQuery selects an interface, abstract class or protocol:
+// the symbol at Line and Column (1-based, column in characters) of Path, or
+// the definition of Symbol, preferring one in or under Path. The source
+// heuristics look for implementations under Root, Path's directory when
+// empty.
+type ImplementationsQuery struct {
+	Path       string
+	Line       int
+	Column     int
+	Symbol     string
+	Root       string
+	MaxResults int
+}
+
+// Implementation is a type implementing the queried interface
+type Implementation struct {
+	Name   string `json:"name"`
+	Kind   string `json:"kind,omitempty"`
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column,omitempty"`
+	// Pointer is set for Go types whose pointer type implements the
+	// interface but not the type itself
+	Pointer bool `json:"pointer,omitempty"`
+}
+
+// ImplementationGroup holds the implementations declared in one package:
+// a Go import path, or otherwise a directory relative to the root
+type ImplementationGroup struct {
+	Package string           `json:"package"`
+	Items   []Implementation `json:"items"`
+}
+
+// ImplementationsResult lists implementations grouped by package. Fallback
+// is set when the source heuristics answered instead of the Go type
+// checker or the language server.
+type ImplementationsResult struct {
+	Symbol   string                `json:"symbol"`
+	Groups   []ImplementationGroup `json:"groups"`
+	Count    int                   `json:"count"`
+	Source   QuerySource           `json:"source"`
+	Fallback bool                  `json:"fallback,omitempty"`
+	Latency  time.Duration         `json:"latency_ms"`
+}
+
+// packaged is an implementation with the package it is grouped under
+type packaged struct {
+	Implementation
+	pkg string
+}
+
+func (r *Router) QueryImplementations(ctx context.Context, q ImplementationsQuery) (*ImplementationsResult, error) {
+	start := time.Now()
+	result, err := r.queryImplementations(ctx, q)
+	if err != nil {
+		r.observe(OperationImplementations, start, "", 0, false, err)
+	} else {
+		r.observe(OperationImplementations, start, result.Source, result.Count, result.Fallback, nil)
+	}
+	return result, err
+}
+
+func (r *Router) queryImplementations(ctx context.Context, q ImplementationsQuery) (*ImplementationsResult, error) {
+	start := time.Now()
+	q.Path = paths.Canonical(q.Path)
+	if q.Root == "" {
+		q.Root = q.Path
+		if info, err := os.Stat(q.Path); err == nil && !info.IsDir() {
+			q.Root = filepath.Dir(q.Path)
+		}
+	}
+	q.Root = paths.Canonical(q.Root)
+
+	name := q.Symbol
+	if name == "" {
+		text, _, err := index.ReadFileAsUTF8(q.Path)
+		if err != nil {
+			return nil, err
+		}
+		pos, err := textPosition(text, q.Line, q.Column)
+		if err != nil {
+			return nil, err
+		}
+		lineText := types.SplitLines(text)[pos.Line]
+		name = identifierAt(lineText, types.ByteFromColumn(lineText, q.Column))
+		if name == "" {
+			return nil, fmt.Errorf("no identifier at %s:%d:%d", q.Path, q.Line, q.Column)
+		}
+	}
+	log.Debug("querying implementations", "symbol", name, "path", q.Path)
+
+	var found []packaged
+	source := SourceRegex
+	tried := false
+
+	if r.goAnalyzer != nil && goanalysis.Applies(q.Path) {
+		tried = true
+		goCtx, cancel := WithTimeout(ctx, r.timeouts.Go)
+		impls, err := r.goAnalyzer.Implementations(goCtx, goanalysis.Target{Path: q.Path, Line: q.Line, Column: q.Column, Name: q.Symbol})
+		cancel()
+		if err != nil {
+			log.Debug("Go analyzer failed", "symbol", name, "error", err)
+		} else {
+			source = SourceGo
+			for _, impl := range impls {
+				found = append(found, packaged{
+					Implementation: Implementation{
+						Name:    impl.Name,
+						Kind:    impl.Kind,
+						File:    impl.File,
+						Line:    impl.Line,
+						Column:  impl.Column,
+						Pointer: impl.Pointer,
+					},
+					pkg: impl.Package,
+				})
+			}
+		}
+	}
+
+	if source == SourceRegex && r.lspManager != nil {
+		tried = true
+		// The server may still be starting, so the query gets the whole
+		// budget rather than the LSP tier's
+		lspCtx, cancel := WithTimeout(ctx, r.timeouts.Total)
+		impls, err := r.lspImplementations(lspCtx, q)
+		cancel()
+		if err != nil {
+			log.Debug("LSP implementations failed", "symbol", name, "error", err)
+		} else if len(impls) > 0 {
+			source = SourceLSP
+			found = impls
+		}
+	}
+
+	if source == SourceRegex {
+		impls, err := heuristicImplementations(ctx, name, q.Root)
+		if err != nil {
+			return nil, err
+		}
+		found = impls
+	}
+
+	result := groupImplementations(found, q.MaxResults)
+	result.Symbol = name
+	result.Source = source
+	result.Fallback = source == SourceRegex && tried
+	result.Latency = time.Since(start)
+	return result, nil
+}
+
+// lspImplementations asks the language server about the query position, or
+// the definition of the named symbol
+func (r *Router) lspImplementations(ctx context.Context, q ImplementationsQuery) ([]packaged, error) {
+	file := q.Path
+	var pos lsp.Position
+	var err error
+	if q.Symbol != "" {
+		if r.index == nil {
+			return nil, fmt.Errorf("finding %s by name needs the index", q.Symbol)
+		}
+		file, pos, err = r.resolveDefinitionPosition(q.Symbol, q.Path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	text, _, err := index.ReadFileAsUTF8(file)
+	if err != nil {
+		return nil, err
+	}
+	if q.Symbol == "" {
+		pos, err = textPosition(text, q.Line, q.Column)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	locations, err := r.lspManager.GetImplementations(ctx, file, text, pos)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make(map[string][]string)
+	var impls []packaged
+	for _, loc := range locations {
+		filePath := lsp.URIToPath(loc.URI)
+		fileLines, ok := lines[filePath]
+		if !ok {
+			if content, _, err := index.ReadFileAsUTF8(filePath); err == nil {
+				fileLines = types.SplitLines(content)
+			}
+			lines[filePath] = fileLines
+		}
+
+		lineText := ""
+		if loc.Range.Start.Line < len(fileLines) {
+			lineText = fileLines[loc.Range.Start.Line]
+		}
+		column := types.ColumnFromUTF16(lineText, loc.Range.Start.Character)
+		impls = append(impls, packaged{
+			Implementation: Implementation{
+				Name:   identifierAt(lineText, types.ByteFromColumn(lineText, column)),
+				Kind:   declarationKind(lineText),
+				File:   filePath,
+				Line:   loc.Range.Start.Line + 1,
+				Column: column,
+			},
+			pkg: packageDir(q.Root, filePath),
+		})
+	}
+	return impls, nil
+}
+
+var (
+	classKeyword     = regexp.MustCompile(`\bclass\b`)
+	structKeyword    = regexp.MustCompile(`\bstruct\b`)
+	goMethodDecl     = regexp.MustCompile(`^\s*func\s*\(`)
+	interfaceKeyword = regexp.MustCompile(`\binterface\b`)
+)
+
+// declarationKind guesses the kind of the declaration on line
+func declarationKind(line string) string {
+	switch {
+	case goMethodDecl.MatchString(line):
+		return types.KindMethod
+	case classKeyword.MatchString(line):
+		return types.KindClass
+	case structKeyword.MatchString(line):
+		return types.KindStruct
+	case interfaceKeyword.MatchString(line):
+		return types.KindInterface
+	}
+	return ""
+}
+
+// packageDir names the package of file: its directory relative to root,
+// or the absolute directory outside root
+func packageDir(root, file string) string {
+	dir := filepath.Dir(file)
+	if rel, err := filepath.Rel(root, dir); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return dir
+}
+
+// groupImplementations sorts implementations by package, file and line and
+// groups them, keeping at most max (0 for all)
+func groupImplementations(found []packaged, max int) *ImplementationsResult {
+	sort.SliceStable(found, func(i, j int) bool {
+		if found[i].pkg != found[j].pkg {
+			return found[i].pkg < found[j].pkg
+		}
+		if found[i].File != found[j].File {
+			return found[i].File < found[j].File
+		}
+		return found[i].Line < found[j].Line
+	})
+	if max > 0 && len(found) > max {
+		found = found[:max]
+	}
+
+	result := &ImplementationsResult{Groups: []ImplementationGroup{}, Count: len(found)}
+	for _, impl := range found {
+		if n := len(result.Groups); n == 0 || result.Groups[n-1].Package != impl.pkg {
+			result.Groups = append(result.Groups, ImplementationGroup{Package: impl.pkg})
+		}
+		group := &result.Groups[len(result.Groups)-1]
+		group.Items = append(group.Items, impl.Implementation)
+	}
+	return result
+}
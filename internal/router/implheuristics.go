@@ -0,0 +1,436 @@
+package router
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/alucardeht/may-la-mcp/internal/index"
+	"github.com/alucardeht/may-la-mcp/internal/types"
+)
+
+// declaration is a class, interface or type the heuristics found
+type declaration struct {
+	name   string
+	kind   string
+	file   string
+	line   int
+	column int
+	// bases are the names a class extends or implements, or the interfaces
+	// a Go or TypeScript interface embeds
+	bases []string
+	// methods are the methods declared in a class or a Go interface
+	methods []string
+	// abstract is set for TypeScript interfaces, Python protocols and Go
+	// interfaces, which are never reported as implementations
+	abstract bool
+}
+
+// goMethods are the methods a Go type declares, by receiver type
+type goMethods struct {
+	value   map[string]bool
+	pointer map[string]bool
+}
+
+// sourceModel is what the heuristics read from the files under a root
+type sourceModel struct {
+	decls []*declaration
+	// receivers maps a Go package directory and type name to its methods
+	receivers map[string]*goMethods
+}
+
+var (
+	tsClassDecl     = regexp.MustCompile(`^\s*(?:export\s+)?(?:default\s+)?(?:declare\s+)?(?:abstract\s+)?class\s+([A-Za-z_$][\w$]*)(.*)$`)
+	tsInterfaceDecl = regexp.MustCompile(`^\s*(?:export\s+)?(?:declare\s+)?interface\s+([A-Za-z_$][\w$]*)(.*)$`)
+	tsExtends       = regexp.MustCompile(`\bextends\s+([^{]+?)(?:\bimplements\b|\{|$)`)
+	tsImplements    = regexp.MustCompile(`\bimplements\s+([^{]+)`)
+	typeArguments   = regexp.MustCompile(`<[^<>]*>|\[[^\[\]]*\]`)
+
+	pyClassDecl  = regexp.MustCompile(`^(\s*)class\s+([A-Za-z_]\w*)\s*(?:\[[^\]]*\])?\s*(?:\((.*)\))?\s*:`)
+	pyMethodDecl = regexp.MustCompile(`^(\s*)(?:async\s+)?def\s+([A-Za-z_]\w*)\s*\(`)
+
+	goTypeDecl      = regexp.MustCompile(`^type\s+([A-Za-z_]\w*)(?:\[[^\]]*\])?\s+(\S+)`)
+	goInlineMethods = regexp.MustCompile(`interface\s*\{(.*)\}`)
+	goIfaceMethod   = regexp.MustCompile(`^\s*([A-Za-z_]\w*)\s*\(`)
+	goIfaceEmbed    = regexp.MustCompile(`^\s*([A-Za-z_][\w.]*)\s*$`)
+	goMethodRecv    = regexp.MustCompile(`^func\s*\(\s*(?:[A-Za-z_]\w*\s+)?(\*?)\s*([A-Za-z_]\w*)(?:\[[^\]]*\])?\s*\)\s*([A-Za-z_]\w*)\s*[\[(]`)
+)
+
+// heuristicImplementations finds the implementations of name under root
+// from declarations alone: classes extending or implementing it, directly
+// or through other classes, Python classes with every method of a
+// protocol, and Go types with every method of an interface
+func heuristicImplementations(ctx context.Context, name, root string) ([]packaged, error) {
+	model, err := readSourceModel(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[*declaration]bool)
+	pointer := make(map[*declaration]bool)
+
+	// nominal: everything extending or implementing name, transitively
+	reached := map[string]bool{name: true}
+	for queue := []string{name}; len(queue) > 0; queue = queue[1:] {
+		for _, d := range model.decls {
+			if reached[d.name] || !containsName(d.bases, queue[0]) {
+				continue
+			}
+			reached[d.name] = true
+			queue = append(queue, d.name)
+			if !d.abstract {
+				found[d] = true
+			}
+		}
+	}
+
+	// structural: Python protocols and Go interfaces
+	for _, target := range model.decls {
+		if target.name != name || !target.abstract {
+			continue
+		}
+		switch detectLanguage(target.file) {
+		case "python":
+			required := model.protocolMethods(target)
+			if len(required) == 0 {
+				continue
+			}
+			for _, d := range model.decls {
+				if !d.abstract && detectLanguage(d.file) == "python" && hasAll(d.methods, required) {
+					found[d] = true
+				}
+			}
+		case "go":
+			required := model.interfaceMethods(target, map[*declaration]bool{})
+			if len(required) == 0 {
+				continue
+			}
+			for _, d := range model.decls {
+				if d.abstract || detectLanguage(d.file) != "go" {
+					continue
+				}
+				methods := model.receivers[receiverKey(filepath.Dir(d.file), d.name)]
+				if methods == nil {
+					continue
+				}
+				valueOnly, all := true, true
+				for _, m := range required {
+					if !methods.value[m] {
+						valueOnly = false
+						if !methods.pointer[m] {
+							all = false
+						}
+					}
+				}
+				if all {
+					found[d] = true
+					pointer[d] = !valueOnly
+				}
+			}
+		}
+	}
+
+	var impls []packaged
+	for _, d := range model.decls {
+		if !found[d] {
+			continue
+		}
+		impls = append(impls, packaged{
+			Implementation: Implementation{
+				Name:    d.name,
+				Kind:    d.kind,
+				File:    d.file,
+				Line:    d.line,
+				Column:  d.column,
+				Pointer: pointer[d],
+			},
+			pkg: packageDir(root, d.file),
+		})
+	}
+	return impls, nil
+}
+
+// protocolMethods are the methods a class must have to satisfy a protocol,
+// including those of the protocols it extends
+func (m *sourceModel) protocolMethods(protocol *declaration) []string {
+	var required []string
+	for _, method := range protocol.methods {
+		if !strings.HasPrefix(method, "__") {
+			required = append(required, method)
+		}
+	}
+	for _, base := range protocol.bases {
+		for _, d := range m.decls {
+			if d != protocol && d.name == base && d.abstract && detectLanguage(d.file) == "python" {
+				required = append(required, m.protocolMethods(d)...)
+			}
+		}
+	}
+	return required
+}
+
+// interfaceMethods are the methods of a Go interface, with those of the
+// interfaces it embeds
+func (m *sourceModel) interfaceMethods(iface *declaration, seen map[*declaration]bool) []string {
+	seen[iface] = true
+	required := append([]string{}, iface.methods...)
+	for _, base := range iface.bases {
+		for _, d := range m.decls {
+			if !seen[d] && d.name == base && d.abstract && filepath.Dir(d.file) == filepath.Dir(iface.file) {
+				required = append(required, m.interfaceMethods(d, seen)...)
+			}
+		}
+	}
+	return required
+}
+
+// readSourceModel reads the declarations of the Go, TypeScript, JavaScript
+// and Python files under root
+func readSourceModel(ctx context.Context, root string) (*sourceModel, error) {
+	model := &sourceModel{receivers: make(map[string]*goMethods)}
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != root && skipHeuristicDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		lang := detectLanguage(path)
+		if lang != "go" && lang != "typescript" && lang != "javascript" && lang != "python" {
+			return nil
+		}
+		content, _, err := index.ReadFileAsUTF8(path)
+		if err != nil {
+			return nil
+		}
+		lines := types.SplitLines(content)
+		switch lang {
+		case "go":
+			model.readGo(path, lines)
+		case "python":
+			model.readPython(path, lines)
+		default:
+			model.readTypeScript(path, lines)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return model, nil
+}
+
+// skipHeuristicDir reports whether a directory holds dependencies, build
+// output or tooling state rather than the project's own sources
+func skipHeuristicDir(name string) bool {
+	switch name {
+	case "node_modules", "vendor", "__pycache__", "dist", "build", "target":
+		return true
+	}
+	return strings.HasPrefix(name, ".")
+}
+
+func (m *sourceModel) readTypeScript(path string, lines []string) {
+	for i, line := range lines {
+		if match := tsClassDecl.FindStringSubmatchIndex(line); match != nil {
+			rest := typeArguments.ReplaceAllString(line[match[4]:], "")
+			var bases []string
+			if ext := tsExtends.FindStringSubmatch(rest); ext != nil {
+				bases = append(bases, baseNames(ext[1])...)
+			}
+			if impl := tsImplements.FindStringSubmatch(rest); impl != nil {
+				bases = append(bases, baseNames(impl[1])...)
+			}
+			m.decls = append(m.decls, &declaration{
+				name:   line[match[2]:match[3]],
+				kind:   types.KindClass,
+				file:   path,
+				line:   i + 1,
+				column: types.ColumnFromByte(line, match[2]),
+				bases:  bases,
+			})
+		} else if match := tsInterfaceDecl.FindStringSubmatchIndex(line); match != nil {
+			rest := typeArguments.ReplaceAllString(line[match[4]:], "")
+			var bases []string
+			if ext := tsExtends.FindStringSubmatch(rest); ext != nil {
+				bases = baseNames(ext[1])
+			}
+			m.decls = append(m.decls, &declaration{
+				name:     line[match[2]:match[3]],
+				kind:     types.KindInterface,
+				file:     path,
+				line:     i + 1,
+				column:   types.ColumnFromByte(line, match[2]),
+				bases:    bases,
+				abstract: true,
+			})
+		}
+	}
+}
+
+func (m *sourceModel) readPython(path string, lines []string) {
+	// open is the class whose body is being read, with the indentation of
+	// its class statement and of its body
+	var open *declaration
+	classIndent, bodyIndent := 0, -1
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if open != nil && indent <= classIndent {
+			open = nil
+		}
+
+		if match := pyClassDecl.FindStringSubmatchIndex(line); match != nil {
+			var bases []string
+			abstract := false
+			if match[6] >= 0 {
+				for _, base := range strings.Split(line[match[6]:match[7]], ",") {
+					base = strings.TrimSpace(typeArguments.ReplaceAllString(base, ""))
+					if base == "" || strings.Contains(base, "=") {
+						continue
+					}
+					base = lastSegment(base)
+					if base == "Protocol" {
+						abstract = true
+					}
+					bases = append(bases, base)
+				}
+			}
+			open = &declaration{
+				name:     line[match[4]:match[5]],
+				kind:     types.KindClass,
+				file:     path,
+				line:     i + 1,
+				column:   types.ColumnFromByte(line, match[4]),
+				bases:    bases,
+				abstract: abstract,
+			}
+			m.decls = append(m.decls, open)
+			classIndent, bodyIndent = indent, -1
+			continue
+		}
+
+		if open == nil {
+			continue
+		}
+		if bodyIndent < 0 {
+			bodyIndent = indent
+		}
+		if match := pyMethodDecl.FindStringSubmatch(line); match != nil && indent == bodyIndent {
+			open.methods = append(open.methods, match[2])
+		}
+	}
+}
+
+func (m *sourceModel) readGo(path string, lines []string) {
+	dir := filepath.Dir(path)
+	var iface *declaration
+
+	for i, line := range lines {
+		if iface != nil {
+			if strings.HasPrefix(line, "}") {
+				iface = nil
+			} else if match := goIfaceMethod.FindStringSubmatch(line); match != nil {
+				iface.methods = append(iface.methods, match[1])
+			} else if match := goIfaceEmbed.FindStringSubmatch(line); match != nil {
+				iface.bases = append(iface.bases, lastSegment(match[1]))
+			}
+			continue
+		}
+
+		if match := goTypeDecl.FindStringSubmatchIndex(line); match != nil {
+			d := &declaration{
+				name:   line[match[2]:match[3]],
+				kind:   types.KindType,
+				file:   path,
+				line:   i + 1,
+				column: types.ColumnFromByte(line, match[2]),
+			}
+			switch {
+			case strings.HasPrefix(line[match[4]:], "interface"):
+				d.kind, d.abstract = types.KindInterface, true
+				if inline := goInlineMethods.FindStringSubmatch(line); inline != nil {
+					for _, part := range strings.Split(inline[1], ";") {
+						if method := goIfaceMethod.FindStringSubmatch(part); method != nil {
+							d.methods = append(d.methods, method[1])
+						}
+					}
+				} else if strings.HasSuffix(strings.TrimSpace(line), "{") {
+					iface = d
+				}
+			case strings.HasPrefix(line[match[4]:], "struct"):
+				d.kind = types.KindStruct
+			}
+			m.decls = append(m.decls, d)
+			continue
+		}
+
+		if match := goMethodRecv.FindStringSubmatch(line); match != nil {
+			key := receiverKey(dir, match[2])
+			methods := m.receivers[key]
+			if methods == nil {
+				methods = &goMethods{value: make(map[string]bool), pointer: make(map[string]bool)}
+				m.receivers[key] = methods
+			}
+			if match[1] == "*" {
+				methods.pointer[match[3]] = true
+			} else {
+				methods.value[match[3]] = true
+			}
+		}
+	}
+}
+
+func receiverKey(dir, typeName string) string {
+	return dir + "\x00" + typeName
+}
+
+// baseNames splits a list of extended or implemented types into names
+func baseNames(list string) []string {
+	var names []string
+	for _, base := range strings.Split(list, ",") {
+		if base = strings.TrimSpace(base); base != "" {
+			names = append(names, lastSegment(base))
+		}
+	}
+	return names
+}
+
+// lastSegment drops the module or namespace of a qualified name
+func lastSegment(name string) string {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAll(have, want []string) bool {
+	for _, w := range want {
+		if !containsName(have, w) {
+			return false
+		}
+	}
+	return true
+}
@@ -2,26 +2,34 @@ package router
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
 
+	"github.com/alucardeht/may-la-mcp/internal/goanalysis"
 	"github.com/alucardeht/may-la-mcp/internal/index"
 	"github.com/alucardeht/may-la-mcp/internal/logger"
 	"github.com/alucardeht/may-la-mcp/internal/lsp"
+	"github.com/alucardeht/may-la-mcp/internal/notebook"
+	"github.com/alucardeht/may-la-mcp/internal/paths"
+	"github.com/alucardeht/may-la-mcp/internal/types"
+	"github.com/alucardeht/may-la-mcp/internal/vcs"
 )
 
 var log = logger.ForComponent("router")
 
 type Router struct {
-	index      *index.IndexStore
+	index      index.Store
 	lspManager *lsp.Manager
+	goAnalyzer *goanalysis.Analyzer
 	timeouts   TimeoutConfig
+	observer   QueryObserver
 }
 
-func NewRouter(indexStore *index.IndexStore, lspManager *lsp.Manager) *Router {
+func NewRouter(indexStore index.Store, lspManager *lsp.Manager) *Router {
 	return &Router{
 		index:      indexStore,
 		lspManager: lspManager,
@@ -29,7 +37,7 @@ func NewRouter(indexStore *index.IndexStore, lspManager *lsp.Manager) *Router {
 	}
 }
 
-func NewRouterWithConfig(indexStore *index.IndexStore, lspManager *lsp.Manager, timeouts TimeoutConfig) *Router {
+func NewRouterWithConfig(indexStore index.Store, lspManager *lsp.Manager, timeouts TimeoutConfig) *Router {
 	return &Router{
 		index:      indexStore,
 		lspManager: lspManager,
@@ -37,8 +45,52 @@ func NewRouterWithConfig(indexStore *index.IndexStore, lspManager *lsp.Manager,
 	}
 }
 
+// SetObserver installs o to be told about every completed query. It must be
+// called before the router serves queries.
+func (r *Router) SetObserver(o QueryObserver) {
+	r.observer = o
+}
+
+// SetGoAnalyzer enables the Go tier, which answers reference queries in Go
+// modules from the type checker. It must be called before the router serves
+// queries.
+func (r *Router) SetGoAnalyzer(a *goanalysis.Analyzer) {
+	r.goAnalyzer = a
+}
+
+// GoAnalyzer returns the analyzer of the Go tier, or nil when it is disabled
+func (r *Router) GoAnalyzer() *goanalysis.Analyzer {
+	return r.goAnalyzer
+}
+
+func (r *Router) observe(operation string, start time.Time, source QuerySource, count int, fallback bool, err error) {
+	if r.observer == nil {
+		return
+	}
+	r.observer(QueryStats{
+		Operation: operation,
+		Source:    source,
+		Latency:   time.Since(start),
+		Count:     count,
+		Fallback:  fallback,
+		Err:       err,
+	})
+}
+
 func (r *Router) QuerySymbols(ctx context.Context, path string, query string, kinds []string, opts QueryOptions) (*QueryResult[Symbol], error) {
 	start := time.Now()
+	result, err := r.querySymbols(ctx, path, query, kinds, opts)
+	if err != nil {
+		r.observe(OperationSymbols, start, "", 0, false, err)
+	} else {
+		r.observe(OperationSymbols, start, result.Source, result.Count, result.Fallback, nil)
+	}
+	return result, err
+}
+
+func (r *Router) querySymbols(ctx context.Context, path string, query string, kinds []string, opts QueryOptions) (*QueryResult[Symbol], error) {
+	start := time.Now()
+	path = paths.Canonical(path)
 	log.Debug("querying symbols", "path", path, "query", query)
 
 	if opts.Timeout > 0 {
@@ -93,7 +145,7 @@ func (r *Router) QuerySymbols(ctx context.Context, path string, query string, ki
 
 		if err == nil {
 			result.Latency = time.Since(start)
-			result.Fallback = true
+			result.Fallback = !opts.SkipIndex || !opts.SkipLSP
 			log.Debug("query completed", "source", result.Source, "count", result.Count, "latency_ms", result.Latency.Milliseconds())
 			return result, nil
 		}
@@ -103,7 +155,7 @@ func (r *Router) QuerySymbols(ctx context.Context, path string, query string, ki
 	return &QueryResult[Symbol]{
 		Items:   []Symbol{},
 		Count:   0,
-		Source:  SourceIndex,
+		Source:  opts.emptySource(),
 		Latency: time.Since(start),
 	}, nil
 }
@@ -128,7 +180,7 @@ func (r *Router) queryIndexSymbols(ctx context.Context, path string, query strin
 			continue
 		}
 
-		if len(kinds) > 0 && !containsKind(kinds, sym.Kind) {
+		if !types.MatchesKind(kinds, sym.Kind) {
 			continue
 		}
 
@@ -152,15 +204,20 @@ func (r *Router) queryLSPSymbols(ctx context.Context, path string, query string,
 		return nil, err
 	}
 
+	var lines []string
+	if content, _, err := index.ReadFileAsUTF8(path); err == nil {
+		lines = types.SplitLines(content)
+	}
+
 	var symbols []Symbol
-	flatSymbols := flattenLSPSymbols(lspSymbols, path)
+	flatSymbols := flattenLSPSymbols(lspSymbols, path, lines)
 
 	for _, sym := range flatSymbols {
 		if query != "" && !strings.Contains(strings.ToLower(sym.Name), strings.ToLower(query)) {
 			continue
 		}
 
-		if len(kinds) > 0 && !containsKind(kinds, sym.Kind) {
+		if !types.MatchesKind(kinds, sym.Kind) {
 			continue
 		}
 
@@ -178,34 +235,54 @@ func (r *Router) queryLSPSymbols(ctx context.Context, path string, query string,
 	}, nil
 }
 
-func flattenLSPSymbols(symbols []lsp.DocumentSymbol, filePath string) []Symbol {
+// flattenLSPSymbols converts document symbols to the types coordinates;
+// lines is the file's text, without which UTF-16 offsets are taken as
+// columns
+func flattenLSPSymbols(symbols []lsp.DocumentSymbol, filePath string, lines []string) []Symbol {
 	var result []Symbol
 	for _, s := range symbols {
 		sym := Symbol{
 			Name:      s.Name,
-			Kind:      s.Kind.String(),
+			Kind:      types.KindFromLSP(int(s.Kind)),
 			File:      filePath,
 			Line:      s.Range.Start.Line + 1,
 			LineEnd:   s.Range.End.Line + 1,
-			Column:    s.Range.Start.Character + 1,
-			ColumnEnd: s.Range.End.Character + 1,
+			Column:    lspColumn(lines, s.Range.Start),
+			ColumnEnd: lspColumn(lines, s.Range.End),
 			Signature: s.Detail,
 		}
 		result = append(result, sym)
 
 		if len(s.Children) > 0 {
-			result = append(result, flattenLSPSymbols(s.Children, filePath)...)
+			result = append(result, flattenLSPSymbols(s.Children, filePath, lines)...)
 		}
 	}
 	return result
 }
 
+// lspColumn returns the column of an LSP position in lines
+func lspColumn(lines []string, pos lsp.Position) int {
+	if pos.Line < 0 || pos.Line >= len(lines) {
+		return pos.Character + 1
+	}
+	return types.ColumnFromUTF16(lines[pos.Line], pos.Character)
+}
+
 func (r *Router) queryRegexSymbols(ctx context.Context, path string, query string, kinds []string, opts QueryOptions) (*QueryResult[Symbol], error) {
 	content, _, err := index.ReadFileAsUTF8(path)
 	if err != nil {
 		return nil, err
 	}
 
+	if notebook.IsNotebook(path) {
+		symbols := notebookSymbolsRegex(content, path, query, kinds, opts.MaxResults)
+		return &QueryResult[Symbol]{
+			Items:  symbols,
+			Count:  len(symbols),
+			Source: SourceRegex,
+		}, nil
+	}
+
 	lang := detectLanguage(path)
 	if lang == "" {
 		return &QueryResult[Symbol]{
@@ -242,6 +319,9 @@ func (r *Router) updateIndexFromSymbols(path string, symbols []Symbol) {
 		Language:    detectLanguage(path),
 		Status:      index.StatusIndexed,
 		IndexedAt:   time.Now(),
+		ModTime:     info.ModTime(),
+		Size:        info.Size(),
+		Branch:      vcs.Branch(path),
 	}
 
 	fileID, err := r.index.UpsertFile(file)
@@ -261,15 +341,27 @@ func (r *Router) updateIndexFromSymbols(path string, symbols []Symbol) {
 			ColumnEnd:     s.ColumnEnd,
 			Documentation: s.Documentation,
 			IsExported:    s.IsExported,
+			Cell:          s.Cell,
 		})
 	}
 
 	r.index.InsertSymbols(fileID, indexed)
-	_ = info
 }
 
 func (r *Router) QueryReferences(ctx context.Context, symbol string, path string, opts QueryOptions) (*QueryResult[Reference], error) {
 	start := time.Now()
+	result, err := r.queryReferences(ctx, symbol, path, opts)
+	if err != nil {
+		r.observe(OperationReferences, start, "", 0, false, err)
+	} else {
+		r.observe(OperationReferences, start, result.Source, result.Count, result.Fallback, nil)
+	}
+	return result, err
+}
+
+func (r *Router) queryReferences(ctx context.Context, symbol string, path string, opts QueryOptions) (*QueryResult[Reference], error) {
+	start := time.Now()
+	path = paths.Canonical(path)
 	log.Debug("querying references", "symbol", symbol, "path", path)
 
 	if opts.Timeout > 0 {
@@ -278,6 +370,7 @@ func (r *Router) QueryReferences(ctx context.Context, symbol string, path string
 		defer cancel()
 	}
 
+	var indexResult *QueryResult[Reference]
 	if !opts.SkipIndex && r.index != nil {
 		log.Debug("trying index", "path", path)
 		indexCtx, indexCancel := WithTimeout(ctx, r.timeouts.Index)
@@ -285,21 +378,59 @@ func (r *Router) QueryReferences(ctx context.Context, symbol string, path string
 		indexCancel()
 
 		if err == nil && result != nil && len(result.Items) > 0 {
+			indexResult = result
+		}
+	}
+
+	if !opts.SkipGo && r.goAnalyzer != nil && goanalysis.Applies(path) {
+		log.Debug("trying Go analyzer", "path", path)
+		goCtx, goCancel := WithTimeout(ctx, r.timeouts.Go)
+		result, err := r.queryGoReferences(goCtx, symbol, path, opts)
+		goCancel()
+
+		// the type checker is exact, so its results replace the index's
+		if err == nil && len(result.Items) > 0 {
 			result.Latency = time.Since(start)
 			log.Debug("references found", "source", result.Source, "count", result.Count)
 			return result, nil
 		}
+		if err != nil {
+			log.Debug("Go analyzer failed", "symbol", symbol, "error", err)
+		}
+	}
+
+	if !opts.SkipLSP && r.lspManager != nil && r.index != nil {
+		log.Debug("trying LSP", "path", path)
+		lspCtx, lspCancel := WithTimeout(ctx, r.timeouts.LSP)
+		result, err := r.queryLSPReferences(lspCtx, symbol, path, opts)
+		lspCancel()
+
+		if err == nil && result != nil && len(result.Items) > 0 {
+			if indexResult != nil {
+				result.Items = mergeReferences(result.Items, indexResult.Items, opts.MaxResults)
+				result.Count = len(result.Items)
+			}
+			result.Latency = time.Since(start)
+			log.Debug("references found", "source", result.Source, "count", result.Count)
+			return result, nil
+		}
+	}
+
+	if indexResult != nil {
+		indexResult.Latency = time.Since(start)
+		log.Debug("references found", "source", indexResult.Source, "count", indexResult.Count)
+		return indexResult, nil
 	}
 
 	if opts.AllowFallback {
-		log.Info("falling back to regex", "path", path, "reason", "index failed")
+		log.Info("falling back to regex", "path", path, "reason", "index and LSP failed")
 		regexCtx, regexCancel := WithTimeout(ctx, r.timeouts.Regex)
 		result, err := r.queryRegexReferences(regexCtx, symbol, path, opts)
 		regexCancel()
 
 		if err == nil {
 			result.Latency = time.Since(start)
-			result.Fallback = true
+			result.Fallback = !opts.SkipIndex || !opts.SkipLSP || !opts.SkipGo
 			log.Debug("references found", "source", result.Source, "count", result.Count)
 			return result, nil
 		}
@@ -309,7 +440,7 @@ func (r *Router) QueryReferences(ctx context.Context, symbol string, path string
 	return &QueryResult[Reference]{
 		Items:   []Reference{},
 		Count:   0,
-		Source:  SourceIndex,
+		Source:  opts.emptySource(),
 		Latency: time.Since(start),
 	}, nil
 }
@@ -349,6 +480,175 @@ func (r *Router) queryIndexReferences(ctx context.Context, symbol string, path s
 	}, nil
 }
 
+// queryGoReferences finds the uses of symbol, a package-level name or
+// Type.Member, in the Go module holding path
+func (r *Router) queryGoReferences(ctx context.Context, symbol string, path string, opts QueryOptions) (*QueryResult[Reference], error) {
+	refs, err := r.goAnalyzer.References(ctx, goanalysis.Target{Path: path, Name: symbol}, opts.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	return &QueryResult[Reference]{
+		Items:  refs,
+		Count:  len(refs),
+		Source: SourceGo,
+	}, nil
+}
+
+func (r *Router) queryLSPReferences(ctx context.Context, symbol string, path string, opts QueryOptions) (*QueryResult[Reference], error) {
+	defFile, pos, err := r.resolveDefinitionPosition(symbol, path)
+	if err != nil {
+		return nil, err
+	}
+
+	locations, err := r.lspManager.GetReferences(ctx, defFile, pos)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make(map[string][]string)
+	seen := make(map[string]bool)
+	var references []Reference
+	for _, loc := range locations {
+		filePath := lsp.URIToPath(loc.URI)
+
+		fileLines, ok := lines[filePath]
+		if !ok {
+			if content, _, err := index.ReadFileAsUTF8(filePath); err == nil {
+				fileLines = types.SplitLines(content)
+			}
+			lines[filePath] = fileLines
+		}
+
+		lineText := ""
+		if loc.Range.Start.Line < len(fileLines) {
+			lineText = fileLines[loc.Range.Start.Line]
+		}
+
+		ref := Reference{
+			File:    filePath,
+			Line:    loc.Range.Start.Line + 1,
+			Column:  types.ColumnFromUTF16(lineText, loc.Range.Start.Character),
+			Context: strings.TrimSpace(lineText),
+			Kind:    classifyReference(lineText, symbol),
+		}
+		if filePath == defFile && loc.Range.Start.Line == pos.Line {
+			ref.Kind = types.RefDefinition
+		}
+
+		key := referenceKey(ref)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		references = append(references, ref)
+
+		if len(references) >= opts.MaxResults {
+			break
+		}
+	}
+
+	return &QueryResult[Reference]{
+		Items:  references,
+		Count:  len(references),
+		Source: SourceLSP,
+	}, nil
+}
+
+// resolveDefinitionPosition finds where symbol is defined using the index and
+// converts it to an LSP position. A definition inside path is preferred when
+// several symbols share the name.
+func (r *Router) resolveDefinitionPosition(symbol string, path string) (string, lsp.Position, error) {
+	def, defFile, err := r.findDefinition(symbol, path)
+	if err != nil {
+		return "", lsp.Position{}, err
+	}
+
+	content, _, err := index.ReadFileAsUTF8(defFile)
+	if err != nil {
+		return "", lsp.Position{}, err
+	}
+
+	fileLines := types.SplitLines(content)
+	line := def.LineStart - 1
+	if line < 0 || line >= len(fileLines) {
+		return "", lsp.Position{}, fmt.Errorf("definition of %s is outside %s", symbol, defFile)
+	}
+
+	text := fileLines[line]
+	col := 0
+	if def.ColumnStart > 0 {
+		col = types.ByteFromColumn(text, def.ColumnStart)
+	}
+	if idx := strings.Index(text[col:], symbol); idx >= 0 {
+		col += idx
+	} else if idx := strings.Index(text, symbol); idx >= 0 {
+		col = idx
+	}
+
+	return defFile, lsp.Position{Line: line, Character: types.UTF16FromColumn(text, types.ColumnFromByte(text, col))}, nil
+}
+
+// findDefinition looks symbol up in the index, preferring a definition in
+// path, then one under it, then any other.
+func (r *Router) findDefinition(symbol string, path string) (*index.IndexedSymbol, string, error) {
+	indexed, err := r.index.SearchSymbols(symbol, 50)
+	if err != nil {
+		return nil, "", err
+	}
+
+	absPath := paths.Canonical(path)
+
+	var def *index.IndexedSymbol
+	var defFile string
+	for _, s := range indexed {
+		if s.Name != symbol {
+			continue
+		}
+		file, err := r.index.GetFileByID(s.FileID)
+		if err != nil || file == nil {
+			continue
+		}
+		if def == nil || file.Path == absPath || strings.HasPrefix(file.Path, absPath+string(filepath.Separator)) {
+			def, defFile = s, file.Path
+			if file.Path == absPath {
+				break
+			}
+		}
+	}
+
+	if def == nil {
+		return nil, "", fmt.Errorf("definition of %s not found in index", symbol)
+	}
+	return def, defFile, nil
+}
+
+// mergeReferences appends index references not already reported by the
+// language server.
+func mergeReferences(primary, secondary []Reference, maxResults int) []Reference {
+	seen := make(map[string]bool, len(primary))
+	for _, ref := range primary {
+		seen[referenceKey(ref)] = true
+	}
+
+	merged := primary
+	for _, ref := range secondary {
+		if len(merged) >= maxResults {
+			break
+		}
+		key := referenceKey(ref)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		merged = append(merged, ref)
+	}
+	return merged
+}
+
+func referenceKey(ref Reference) string {
+	return fmt.Sprintf("%s:%d:%d", filepath.Clean(ref.File), ref.Line, ref.Column)
+}
+
 func (r *Router) queryRegexReferences(ctx context.Context, symbol string, searchPath string, opts QueryOptions) (*QueryResult[Reference], error) {
 	var references []Reference
 
@@ -375,13 +675,13 @@ func (r *Router) queryRegexReferences(ctx context.Context, symbol string, search
 			return nil
 		}
 
-		lines := strings.Split(content, "\n")
+		lines := types.SplitLines(content)
 		for lineNum, line := range lines {
 			if pattern.MatchString(line) {
 				loc := pattern.FindStringIndex(line)
 				col := 0
 				if loc != nil {
-					col = loc[0] + 1
+					col = types.ColumnFromByte(line, loc[0])
 				}
 
 				references = append(references, Reference{
@@ -412,15 +712,6 @@ func (r *Router) queryRegexReferences(ctx context.Context, symbol string, search
 	}, nil
 }
 
-func containsKind(kinds []string, kind string) bool {
-	for _, k := range kinds {
-		if strings.EqualFold(k, kind) {
-			return true
-		}
-	}
-	return false
-}
-
 func detectLanguage(path string) string {
 	ext := strings.ToLower(filepath.Ext(path))
 	switch ext {
@@ -448,18 +739,18 @@ func detectLanguage(path string) string {
 func classifyReference(line, symbol string) string {
 	lower := strings.ToLower(line)
 	if strings.Contains(lower, "import") || strings.Contains(lower, "require") {
-		return "import"
+		return types.RefImport
 	}
 	if strings.Contains(lower, "func ") || strings.Contains(lower, "function ") ||
 		strings.Contains(lower, "def ") || strings.Contains(lower, "class ") {
-		return "definition"
+		return types.RefDefinition
 	}
-	return "usage"
+	return types.RefUsage
 }
 
 func extractSymbolsRegex(content, filePath, lang, query string, kinds []string, maxResults int) []Symbol {
 	var symbols []Symbol
-	lines := strings.Split(content, "\n")
+	lines := types.SplitLines(content)
 
 	patterns := getLanguagePatterns(lang)
 	if patterns == nil {
@@ -467,31 +758,42 @@ func extractSymbolsRegex(content, filePath, lang, query string, kinds []string,
 	}
 
 	for lineNum, line := range lines {
+		names := make(map[string][]int)
+		var matched []string
 		for kind, re := range patterns {
-			if len(kinds) > 0 && !containsKind(kinds, kind) {
-				continue
+			loc := re.FindStringSubmatchIndex(line)
+			if len(loc) > 3 && loc[2] >= 0 {
+				kind = types.NormalizeKind(kind)
+				names[kind] = loc[2:4]
+				matched = append(matched, kind)
 			}
+		}
 
-			matches := re.FindStringSubmatch(line)
-			if len(matches) > 1 {
-				name := matches[1]
+		for _, kind := range types.PreferSpecific(matched) {
+			span := names[kind]
+			name := line[span[0]:span[1]]
 
-				if query != "" && !strings.Contains(strings.ToLower(name), strings.ToLower(query)) {
-					continue
-				}
+			if !types.MatchesKind(kinds, kind) {
+				continue
+			}
 
-				symbols = append(symbols, Symbol{
-					Name:       name,
-					Kind:       kind,
-					File:       filePath,
-					Line:       lineNum + 1,
-					Signature:  strings.TrimSpace(line),
-					IsExported: isExported(name, lang),
-				})
+			if query != "" && !strings.Contains(strings.ToLower(name), strings.ToLower(query)) {
+				continue
+			}
 
-				if len(symbols) >= maxResults {
-					return symbols
-				}
+			symbols = append(symbols, Symbol{
+				Name:       name,
+				Kind:       kind,
+				File:       filePath,
+				Line:       lineNum + 1,
+				Column:     types.ColumnFromByte(line, span[0]),
+				ColumnEnd:  types.ColumnFromByte(line, span[1]),
+				Signature:  strings.TrimSpace(line),
+				IsExported: isExported(name, lang),
+			})
+
+			if len(symbols) >= maxResults {
+				return symbols
 			}
 		}
 	}
@@ -499,6 +801,29 @@ func extractSymbolsRegex(content, filePath, lang, query string, kinds []string,
 	return symbols
 }
 
+// notebookSymbolsRegex extracts symbols from the code cells of a notebook,
+// each with the index of its cell and lines counted from the cell's start
+func notebookSymbolsRegex(content, filePath, query string, kinds []string, maxResults int) []Symbol {
+	symbols := []Symbol{}
+	nb, err := notebook.Parse([]byte(content))
+	if err != nil {
+		return symbols
+	}
+	for _, cell := range nb.Cells {
+		if cell.Type != notebook.CodeCell {
+			continue
+		}
+		for _, sym := range extractSymbolsRegex(cell.Source, filePath, nb.Language, query, kinds, maxResults-len(symbols)) {
+			sym.Cell = &cell.Index
+			symbols = append(symbols, sym)
+		}
+		if len(symbols) >= maxResults {
+			break
+		}
+	}
+	return symbols
+}
+
 func isExported(name, lang string) bool {
 	if name == "" {
 		return false
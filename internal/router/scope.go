@@ -0,0 +1,107 @@
+package router
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/alucardeht/may-la-mcp/internal/paths"
+	"github.com/alucardeht/may-la-mcp/internal/types"
+)
+
+var ErrIndexUnavailable = errors.New("index not available")
+
+type LineRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// SearchScope is the set of files (and, optionally, line ranges inside them)
+// a content search should be restricted to.
+type SearchScope struct {
+	Files  []string
+	Ranges map[string][]LineRange
+}
+
+// Contains reports whether line of file lies inside the scope's ranges. Files
+// without ranges are fully in scope.
+func (s *SearchScope) Contains(file string, line int) bool {
+	ranges, ok := s.Ranges[file]
+	if !ok {
+		return s.Ranges == nil
+	}
+	for _, r := range ranges {
+		if line >= r.Start && line <= r.End {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveSearchScope uses the index to pre-select candidate files under root
+// by language and, when withinKind is set, the line ranges of matching
+// symbols (optionally only those named withinName).
+func (r *Router) ResolveSearchScope(root, language, withinKind, withinName string) (*SearchScope, error) {
+	if r == nil || r.index == nil {
+		return nil, ErrIndexUnavailable
+	}
+
+	root = paths.Canonical(root)
+
+	if withinKind == "" {
+		files, err := r.index.GetFilePathsByLanguage(language, root)
+		if err != nil {
+			return nil, err
+		}
+		return &SearchScope{Files: files}, nil
+	}
+
+	symbols, err := r.index.GetSymbolRanges(types.KindAliases(withinKind), withinName, language, root)
+	if err != nil {
+		return nil, err
+	}
+
+	scope := &SearchScope{Ranges: make(map[string][]LineRange)}
+	nextStarts := make(map[int64][]int)
+	for _, sym := range symbols {
+		end := sym.LineEnd
+		if end <= sym.LineStart {
+			// Regex-indexed symbols only record their first line; extend the
+			// range up to the next symbol in the same file.
+			starts, ok := nextStarts[sym.FileID]
+			if !ok {
+				starts = r.symbolStarts(sym.FileID)
+				nextStarts[sym.FileID] = starts
+			}
+			end = nextSymbolLine(starts, sym.LineStart)
+		}
+
+		if _, ok := scope.Ranges[sym.Path]; !ok {
+			scope.Files = append(scope.Files, sym.Path)
+		}
+		scope.Ranges[sym.Path] = append(scope.Ranges[sym.Path], LineRange{Start: sym.LineStart, End: end})
+	}
+
+	return scope, nil
+}
+
+func (r *Router) symbolStarts(fileID int64) []int {
+	symbols, err := r.index.GetSymbolsByFile(fileID)
+	if err != nil {
+		return nil
+	}
+	starts := make([]int, 0, len(symbols))
+	for _, s := range symbols {
+		starts = append(starts, s.LineStart)
+	}
+	sort.Ints(starts)
+	return starts
+}
+
+func nextSymbolLine(starts []int, line int) int {
+	i := sort.SearchInts(starts, line+1)
+	if i < len(starts) {
+		return starts[i] - 1
+	}
+	return math.MaxInt32
+}
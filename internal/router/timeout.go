@@ -8,6 +8,8 @@ import (
 type TimeoutConfig struct {
 	Index time.Duration
 	LSP   time.Duration
+	// Go bounds a Go tier query, including loading the module the first time
+	Go    time.Duration
 	Regex time.Duration
 	Total time.Duration
 }
@@ -16,6 +18,7 @@ func DefaultTimeoutConfig() TimeoutConfig {
 	return TimeoutConfig{
 		Index: 50 * time.Millisecond,
 		LSP:   2 * time.Second,
+		Go:    10 * time.Second,
 		Regex: 5 * time.Second,
 		Total: 10 * time.Second,
 	}
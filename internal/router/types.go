@@ -1,6 +1,7 @@
 package router
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/alucardeht/may-la-mcp/internal/types"
@@ -12,12 +13,40 @@ const (
 	SourceIndex QuerySource = "index"
 	SourceLSP   QuerySource = "lsp"
 	SourceRegex QuerySource = "regex"
+	// SourceGo is the Go type checker, which answers for Go modules only
+	SourceGo QuerySource = "go"
+	// SourceAuto lets the router try index, LSP and regex in order
+	SourceAuto QuerySource = "auto"
 )
 
+// Operations reported to a QueryObserver
+const (
+	OperationSymbols         = "symbols"
+	OperationReferences      = "references"
+	OperationCompletions     = "completions"
+	OperationHover           = "hover"
+	OperationImplementations = "implementations"
+)
+
+// QueryStats describes one completed router query
+type QueryStats struct {
+	Operation string
+	Source    QuerySource
+	Latency   time.Duration
+	Count     int
+	Fallback  bool
+	Err       error
+}
+
+// QueryObserver receives the stats of every routed query
+type QueryObserver func(QueryStats)
+
 type Symbol = types.Symbol
 
 type Reference = types.Reference
 
+type Completion = types.Completion
+
 type QueryResult[T any] struct {
 	Items    []T           `json:"items"`
 	Count    int           `json:"count"`
@@ -32,6 +61,7 @@ type QueryOptions struct {
 	Timeout       time.Duration `json:"timeout"`
 	SkipIndex     bool          `json:"skip_index"`
 	SkipLSP       bool          `json:"skip_lsp"`
+	SkipGo        bool          `json:"skip_go"`
 	UpdateIndex   bool          `json:"update_index"`
 	AllowFallback bool          `json:"allow_fallback"`
 }
@@ -46,3 +76,39 @@ func DefaultQueryOptions() QueryOptions {
 		AllowFallback: true,
 	}
 }
+
+// WithSource pins the query to a single tier. "auto" or "" keeps the normal
+// index → Go → LSP → regex order; "regex" skips straight to the fallback.
+func (o QueryOptions) WithSource(source string) (QueryOptions, error) {
+	switch QuerySource(source) {
+	case "", SourceAuto:
+	case SourceIndex:
+		o.SkipLSP = true
+		o.SkipGo = true
+	case SourceLSP:
+		o.SkipIndex = true
+		o.SkipGo = true
+	case SourceGo:
+		o.SkipIndex = true
+		o.SkipLSP = true
+	case SourceRegex:
+		o.SkipIndex = true
+		o.SkipLSP = true
+		o.SkipGo = true
+		o.AllowFallback = true
+	default:
+		return o, fmt.Errorf("unknown source %q (expected index, go, lsp, regex or auto)", source)
+	}
+	return o, nil
+}
+
+// emptySource is the tier reported when no tier produced results
+func (o QueryOptions) emptySource() QuerySource {
+	if o.SkipIndex && !o.SkipLSP {
+		return SourceLSP
+	}
+	if o.SkipIndex && !o.SkipGo {
+		return SourceGo
+	}
+	return SourceIndex
+}
@@ -0,0 +1,217 @@
+// Package scheduler runs the daemon's periodic maintenance jobs, such as
+// the nightly index verification and database backups, on cron-like
+// schedules. Jobs run one at a time so they never compete for the same
+// database, and the outcome of each job's last run is kept for the health
+// report.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/logger"
+)
+
+var log = logger.ForComponent("scheduler")
+
+// Off disables a job in Config.Schedules
+const Off = "off"
+
+type Config struct {
+	// Schedules overrides the cron expression of jobs by name, or turns a
+	// job off, as "job=expression" pairs separated by semicolons
+	Schedules string `yaml:"schedules"`
+	// BackupDir receives dated copies of the databases; backups are off
+	// when it is empty
+	BackupDir string `yaml:"backup_dir"`
+	// BackupKeep is how many backups are kept in BackupDir
+	BackupKeep int `yaml:"backup_keep"`
+}
+
+// ParseSchedules parses "job=expression" pairs separated by semicolons,
+// since cron expressions contain commas, e.g. "backup=0 2 * * *;index_gc=off"
+func ParseSchedules(s string) (map[string]string, error) {
+	schedules := make(map[string]string)
+	for _, pair := range strings.Split(s, ";") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		name, expr, ok := strings.Cut(pair, "=")
+		name, expr = strings.TrimSpace(name), strings.TrimSpace(expr)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid schedule %q: want job=expression", pair)
+		}
+		if !strings.EqualFold(expr, Off) {
+			if _, err := Parse(expr); err != nil {
+				return nil, err
+			}
+		}
+		schedules[name] = expr
+	}
+	return schedules, nil
+}
+
+// Job is a periodic task. Run returns a short summary of what it did.
+type Job struct {
+	Name     string
+	Schedule *Schedule
+	Run      func(ctx context.Context) (string, error)
+}
+
+// Status is the outcome of a job's last run and when it runs next
+type Status struct {
+	Name         string    `json:"name"`
+	Schedule     string    `json:"schedule"`
+	NextRun      time.Time `json:"next_run"`
+	LastRun      time.Time `json:"last_run,omitzero"`
+	LastDuration string    `json:"last_duration,omitempty"`
+	LastResult   string    `json:"last_result,omitempty"`
+	LastError    string    `json:"last_error,omitempty"`
+	Runs         int       `json:"runs"`
+}
+
+type entry struct {
+	job    Job
+	status Status
+}
+
+type Scheduler struct {
+	// running serializes the jobs, scheduled or run on demand
+	running sync.Mutex
+	mu      sync.Mutex
+	entries map[string]*entry
+	now     func() time.Time
+	wake    chan struct{}
+}
+
+func New() *Scheduler {
+	return &Scheduler{
+		entries: make(map[string]*entry),
+		now:     time.Now,
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Add schedules job, replacing a job of the same name
+func (s *Scheduler) Add(job Job) {
+	s.mu.Lock()
+	s.entries[job.Name] = &entry{
+		job: job,
+		status: Status{
+			Name:     job.Name,
+			Schedule: job.Schedule.String(),
+			NextRun:  job.Schedule.Next(s.now()),
+		},
+	}
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Status returns the status of every job, by name
+func (s *Scheduler) Status() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]Status, 0, len(s.entries))
+	for _, e := range s.entries {
+		statuses = append(statuses, e.status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// RunNow runs the named job immediately, outside its schedule
+func (s *Scheduler) RunNow(ctx context.Context, name string) (Status, error) {
+	s.mu.Lock()
+	e, ok := s.entries[name]
+	s.mu.Unlock()
+	if !ok {
+		return Status{}, fmt.Errorf("unknown job %q", name)
+	}
+	s.run(ctx, e)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return e.status, nil
+}
+
+// Run runs the jobs as they come due until ctx is cancelled
+func (s *Scheduler) Run(ctx context.Context) {
+	for {
+		next, due := s.nextDue()
+
+		var timer *time.Timer
+		var fire <-chan time.Time
+		if due != nil {
+			timer = time.NewTimer(next.Sub(s.now()))
+			fire = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		case <-s.wake:
+			if timer != nil {
+				timer.Stop()
+			}
+		case <-fire:
+			s.run(ctx, due)
+		}
+	}
+}
+
+func (s *Scheduler) nextDue() (time.Time, *entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var next time.Time
+	var due *entry
+	for _, e := range s.entries {
+		if e.status.NextRun.IsZero() {
+			continue
+		}
+		if due == nil || e.status.NextRun.Before(next) {
+			next, due = e.status.NextRun, e
+		}
+	}
+	return next, due
+}
+
+func (s *Scheduler) run(ctx context.Context, e *entry) {
+	s.running.Lock()
+	defer s.running.Unlock()
+
+	start := s.now()
+	log.Info("running scheduled job", "job", e.job.Name)
+	result, err := e.job.Run(ctx)
+	duration := s.now().Sub(start)
+
+	if err != nil {
+		log.Warn("scheduled job failed", "job", e.job.Name, "error", err)
+	} else {
+		log.Info("scheduled job finished", "job", e.job.Name, "duration", duration, "result", result)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.status.LastRun = start
+	e.status.LastDuration = duration.Round(time.Millisecond).String()
+	e.status.LastResult = result
+	e.status.LastError = ""
+	if err != nil {
+		e.status.LastError = err.Error()
+	}
+	e.status.Runs++
+	e.status.NextRun = e.job.Schedule.Next(s.now())
+}
@@ -0,0 +1,116 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	// a Friday
+	from := time.Date(2026, 10, 16, 14, 7, 30, 0, time.UTC)
+
+	cases := []struct {
+		spec string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2026, 10, 16, 14, 15, 0, 0, time.UTC)},
+		{"@nightly", time.Date(2026, 10, 17, 3, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2026, 10, 16, 15, 0, 0, 0, time.UTC)},
+		{"30 2 * * 1-5", time.Date(2026, 10, 19, 2, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)},
+		{"0 12 1,15 * *", time.Date(2026, 11, 1, 12, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// either day field matches when both are restricted
+		{"0 9 1 * 6", time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		s, err := Parse(c.spec)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.spec, err)
+			continue
+		}
+		if got := s.Next(from); !got.Equal(c.want) {
+			t.Errorf("%s: expected %v, got %v", c.spec, c.want, got)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* * * 13 *", "5-1 * * * *", "*/0 * * * *", "@often"} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+
+	schedules, err := ParseSchedules("backup=0 2 * * 1,3; index_gc=off")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if schedules["backup"] != "0 2 * * 1,3" || schedules["index_gc"] != Off {
+		t.Errorf("unexpected schedules %v", schedules)
+	}
+	if _, err := ParseSchedules("backup=daily"); err == nil {
+		t.Error("expected an invalid expression to be refused")
+	}
+}
+
+func TestRunRecordsStatus(t *testing.T) {
+	s := New()
+	schedule, _ := Parse("* * * * *")
+	s.Add(Job{Name: "ok", Schedule: schedule, Run: func(ctx context.Context) (string, error) {
+		return "did things", nil
+	}})
+	s.Add(Job{Name: "broken", Schedule: schedule, Run: func(ctx context.Context) (string, error) {
+		return "", errors.New("disk full")
+	}})
+
+	if _, err := s.RunNow(context.Background(), "ok"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s.RunNow(context.Background(), "broken")
+	if _, err := s.RunNow(context.Background(), "missing"); err == nil {
+		t.Error("expected an unknown job to be refused")
+	}
+
+	statuses := s.Status()
+	if len(statuses) != 2 || statuses[0].Name != "broken" {
+		t.Fatalf("expected both jobs by name, got %+v", statuses)
+	}
+	if statuses[0].LastError != "disk full" || statuses[0].Runs != 1 {
+		t.Errorf("expected the failure to be recorded, got %+v", statuses[0])
+	}
+	if statuses[1].LastResult != "did things" || statuses[1].LastRun.IsZero() || !statuses[1].NextRun.After(statuses[1].LastRun) {
+		t.Errorf("expected the run to be recorded, got %+v", statuses[1])
+	}
+}
+
+func TestRunFiresDueJobs(t *testing.T) {
+	s := New()
+	// pretend each minute boundary is a moment away
+	start := time.Now()
+	s.now = func() time.Time {
+		return start.Truncate(time.Minute).Add(time.Minute - 10*time.Millisecond + time.Since(start))
+	}
+
+	ran := make(chan struct{}, 1)
+	schedule, _ := Parse("* * * * *")
+	s.Add(Job{Name: "tick", Schedule: schedule, Run: func(ctx context.Context) (string, error) {
+		select {
+		case ran <- struct{}{}:
+		default:
+		}
+		return "", nil
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+
+	select {
+	case <-ran:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the due job to run")
+	}
+}
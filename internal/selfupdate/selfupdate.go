@@ -0,0 +1,357 @@
+package selfupdate
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultRepo = "alucardeht/may-la-mcp"
+	apiBaseURL  = "https://api.github.com"
+)
+
+// Binaries lists the executables shipped in every release
+var Binaries = []string{"mayla", "mayla-daemon"}
+
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+type Asset struct {
+	Name               string `json:"name"`
+	BrowserDownloadURL string `json:"browser_download_url"`
+}
+
+type Release struct {
+	TagName string  `json:"tag_name"`
+	Assets  []Asset `json:"assets"`
+}
+
+func (r *Release) asset(name string) (Asset, bool) {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return Asset{}, false
+}
+
+type Updater struct {
+	Repo       string
+	InstallDir string
+	HTTPClient *http.Client
+	GOOS       string
+	GOARCH     string
+}
+
+func NewUpdater(installDir string) *Updater {
+	return &Updater{
+		Repo:       DefaultRepo,
+		InstallDir: installDir,
+		HTTPClient: &http.Client{Timeout: 5 * time.Minute},
+		GOOS:       runtime.GOOS,
+		GOARCH:     runtime.GOARCH,
+	}
+}
+
+// FetchRelease returns the release for tag, or the latest release when tag
+// is empty.
+func (u *Updater) FetchRelease(ctx context.Context, tag string) (*Release, error) {
+	url := fmt.Sprintf("%s/repos/%s/releases/latest", apiBaseURL, u.Repo)
+	if tag != "" {
+		url = fmt.Sprintf("%s/repos/%s/releases/tags/%s", apiBaseURL, u.Repo, tag)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := u.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch release: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch release: unexpected status %s", resp.Status)
+	}
+
+	var release Release
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, fmt.Errorf("decode release: %w", err)
+	}
+	return &release, nil
+}
+
+// AssetName returns the release asset name for a binary on this platform,
+// matching the goreleaser name_template "{{ .Binary }}-{{ .Os }}_{{ .Arch }}".
+func (u *Updater) AssetName(binary string) string {
+	name := fmt.Sprintf("%s-%s_%s", binary, u.GOOS, u.GOARCH)
+	if u.GOOS == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+func (u *Updater) checksumsName() string {
+	return fmt.Sprintf("checksums_%s.txt", u.GOOS)
+}
+
+func (u *Updater) binaryPath(binary string) string {
+	if u.GOOS == "windows" {
+		binary += ".exe"
+	}
+	return filepath.Join(u.InstallDir, binary)
+}
+
+// Apply downloads every binary of release, verifies it against the release
+// checksums and only then swaps them into InstallDir. The replaced binaries
+// are kept as <name>.old; if any swap fails, all of them are restored.
+func (u *Updater) Apply(ctx context.Context, release *Release) error {
+	checksumAsset, ok := release.asset(u.checksumsName())
+	if !ok {
+		return fmt.Errorf("release %s has no %s", release.TagName, u.checksumsName())
+	}
+
+	checksumData, err := u.download(ctx, checksumAsset.BrowserDownloadURL)
+	if err != nil {
+		return fmt.Errorf("download checksums: %w", err)
+	}
+	checksums := parseChecksums(checksumData)
+
+	staged := make(map[string]string, len(Binaries))
+	defer func() {
+		for _, tmp := range staged {
+			os.Remove(tmp)
+		}
+	}()
+
+	for _, binary := range Binaries {
+		name := u.AssetName(binary)
+		asset, ok := release.asset(name)
+		if !ok {
+			return fmt.Errorf("release %s has no asset %s", release.TagName, name)
+		}
+
+		expected, ok := checksums[name]
+		if !ok {
+			return fmt.Errorf("no checksum for %s", name)
+		}
+
+		tmp, err := u.stage(ctx, asset.BrowserDownloadURL, expected)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		staged[binary] = tmp
+	}
+
+	if err := u.install(staged); err != nil {
+		return err
+	}
+	clear(staged)
+
+	versionFile := filepath.Join(u.InstallDir, "version")
+	if err := os.WriteFile(versionFile, []byte(release.TagName+"\n"), 0644); err != nil {
+		return fmt.Errorf("write version file: %w", err)
+	}
+
+	return nil
+}
+
+// stage downloads url into a temporary file next to the install target and
+// verifies its SHA-256.
+func (u *Updater) stage(ctx context.Context, url, expected string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := u.HTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("download: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("download: unexpected status %s", resp.Status)
+	}
+
+	tmp, err := os.CreateTemp(u.InstallDir, ".mayla-update-*")
+	if err != nil {
+		return "", err
+	}
+
+	hasher := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, hasher), resp.Body); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("download: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+
+	if got := hex.EncodeToString(hasher.Sum(nil)); !strings.EqualFold(got, expected) {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("%w: expected %s, got %s", ErrChecksumMismatch, expected, got)
+	}
+
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+
+	return tmp.Name(), nil
+}
+
+func (u *Updater) download(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := u.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// rename is os.Rename, replaced in tests to make a swap fail
+var rename = os.Rename
+
+// install swaps every staged binary into InstallDir. When one fails, the
+// binaries already swapped are put back, so the install dir never mixes two
+// releases.
+func (u *Updater) install(staged map[string]string) error {
+	type swapped struct {
+		target   string
+		backedUp bool
+	}
+	var done []swapped
+
+	for _, binary := range Binaries {
+		target := u.binaryPath(binary)
+		backedUp, err := swap(staged[binary], target)
+		if err != nil {
+			for i := len(done) - 1; i >= 0; i-- {
+				if rerr := restore(done[i].target, done[i].backedUp); rerr != nil {
+					return fmt.Errorf("install %s: %w (restoring %s: %v)", binary, err, done[i].target, rerr)
+				}
+			}
+			return fmt.Errorf("install %s: %w", binary, err)
+		}
+		done = append(done, swapped{target, backedUp})
+	}
+	return nil
+}
+
+// swap replaces target with staged, keeping the old file as target.old. A
+// running executable cannot be overwritten on Windows, so it is moved aside
+// rather than replaced in place. It reports whether there was an old file.
+func swap(staged, target string) (bool, error) {
+	old := target + ".old"
+	os.Remove(old)
+
+	backedUp := true
+	if err := rename(target, old); err != nil {
+		if !os.IsNotExist(err) {
+			return false, err
+		}
+		backedUp = false
+	}
+
+	if err := rename(staged, target); err != nil {
+		if backedUp {
+			rename(old, target)
+		}
+		return false, err
+	}
+	return backedUp, nil
+}
+
+// restore undoes a swap of target, putting back target.old or, when there
+// was no old file, removing the new one
+func restore(target string, backedUp bool) error {
+	if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if !backedUp {
+		return nil
+	}
+	return rename(target+".old", target)
+}
+
+// parseChecksums reads a goreleaser checksums file ("<sha256>  <name>" lines)
+func parseChecksums(data []byte) map[string]string {
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 {
+			sums[strings.TrimPrefix(fields[1], "*")] = fields[0]
+		}
+	}
+	return sums
+}
+
+// IsNewer reports whether latest is a newer version than current. Versions
+// that are not dotted numbers (e.g. "dev") are always considered outdated.
+func IsNewer(latest, current string) bool {
+	l, lok := parseVersion(latest)
+	c, cok := parseVersion(current)
+	if !lok {
+		return false
+	}
+	if !cok {
+		return true
+	}
+	for i := 0; i < len(l) || i < len(c); i++ {
+		var lv, cv int
+		if i < len(l) {
+			lv = l[i]
+		}
+		if i < len(c) {
+			cv = c[i]
+		}
+		if lv != cv {
+			return lv > cv
+		}
+	}
+	return false
+}
+
+func parseVersion(v string) ([]int, bool) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	if v == "" {
+		return nil, false
+	}
+	parts := strings.Split(v, ".")
+	nums := make([]int, len(parts))
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, false
+		}
+		nums[i] = n
+	}
+	return nums, true
+}
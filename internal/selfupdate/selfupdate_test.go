@@ -0,0 +1,144 @@
+package selfupdate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestRelease serves a release whose binaries hold "new <binary>" and
+// returns it with an updater installing into a directory that already has
+// the old binaries. corrupt names a binary whose listed checksum is wrong.
+func newTestRelease(t *testing.T, corrupt string) (*Updater, *Release) {
+	t.Helper()
+
+	u := NewUpdater(t.TempDir())
+	u.GOOS, u.GOARCH = "linux", "amd64"
+
+	files := make(map[string]string)
+	var checksums strings.Builder
+	for _, binary := range Binaries {
+		name := u.AssetName(binary)
+		files[name] = "new " + binary
+		sum := sha256.Sum256([]byte(files[name]))
+		if binary == corrupt {
+			sum = sha256.Sum256([]byte("something else"))
+		}
+		fmt.Fprintf(&checksums, "%s  %s\n", hex.EncodeToString(sum[:]), name)
+
+		if err := os.WriteFile(u.binaryPath(binary), []byte("old "+binary), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files[u.checksumsName()] = checksums.String()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := files[strings.TrimPrefix(r.URL.Path, "/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(content))
+	}))
+	t.Cleanup(server.Close)
+
+	release := &Release{TagName: "v9.9.9"}
+	for name := range files {
+		release.Assets = append(release.Assets, Asset{Name: name, BrowserDownloadURL: server.URL + "/" + name})
+	}
+	return u, release
+}
+
+func readInstalled(t *testing.T, u *Updater, binary string) string {
+	t.Helper()
+	content, err := os.ReadFile(u.binaryPath(binary))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+// assertNoLeftovers fails when staged downloads were left in the install dir
+func assertNoLeftovers(t *testing.T, u *Updater) {
+	t.Helper()
+	leftovers, _ := filepath.Glob(filepath.Join(u.InstallDir, ".mayla-update-*"))
+	if len(leftovers) > 0 {
+		t.Errorf("Expected staged files to be removed, found %v", leftovers)
+	}
+}
+
+func TestApply(t *testing.T) {
+	u, release := newTestRelease(t, "")
+
+	if err := u.Apply(context.Background(), release); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, binary := range Binaries {
+		if got := readInstalled(t, u, binary); got != "new "+binary {
+			t.Errorf("Expected %s to be updated, got %q", binary, got)
+		}
+		if old, _ := os.ReadFile(u.binaryPath(binary) + ".old"); string(old) != "old "+binary {
+			t.Errorf("Expected the previous %s kept as .old, got %q", binary, old)
+		}
+	}
+	if version, _ := os.ReadFile(filepath.Join(u.InstallDir, "version")); string(version) != "v9.9.9\n" {
+		t.Errorf("Unexpected version file %q", version)
+	}
+	assertNoLeftovers(t, u)
+}
+
+func TestApplyChecksumMismatch(t *testing.T) {
+	u, release := newTestRelease(t, "mayla-daemon")
+
+	err := u.Apply(context.Background(), release)
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("Expected a checksum mismatch, got %v", err)
+	}
+
+	for _, binary := range Binaries {
+		if got := readInstalled(t, u, binary); got != "old "+binary {
+			t.Errorf("Expected %s to be left alone, got %q", binary, got)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(u.InstallDir, "version")); !os.IsNotExist(err) {
+		t.Error("Expected no version file after a failed update")
+	}
+	assertNoLeftovers(t, u)
+}
+
+func TestApplyRollsBackFailedSwap(t *testing.T) {
+	u, release := newTestRelease(t, "")
+
+	daemon := u.binaryPath("mayla-daemon")
+	rename = func(from, to string) error {
+		if to == daemon && strings.Contains(from, ".mayla-update-") {
+			return errors.New("disk full")
+		}
+		return os.Rename(from, to)
+	}
+	defer func() { rename = os.Rename }()
+
+	err := u.Apply(context.Background(), release)
+	if err == nil || !strings.Contains(err.Error(), "install mayla-daemon") {
+		t.Fatalf("Expected the daemon swap to fail, got %v", err)
+	}
+
+	for _, binary := range Binaries {
+		if got := readInstalled(t, u, binary); got != "old "+binary {
+			t.Errorf("Expected %s to be restored, got %q", binary, got)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(u.InstallDir, "version")); !os.IsNotExist(err) {
+		t.Error("Expected no version file after a failed update")
+	}
+	assertNoLeftovers(t, u)
+}
@@ -0,0 +1,232 @@
+// Package spill keeps tool results too large for a response in files, so a
+// response can carry a preview and a link to the whole result instead of
+// losing what does not fit. Files expire after a TTL and are pruned
+// whenever a new one is written.
+package spill
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+type Config struct {
+	// Dir holds the result files
+	Dir string `yaml:"dir"`
+	// MaxResponseSize is the largest encoded result returned inline, in
+	// bytes; 0 disables spilling
+	MaxResponseSize int `yaml:"max_response_size"`
+	// PreviewSize is how much of a spilled result the response keeps
+	PreviewSize int `yaml:"preview_size"`
+	// TTL is how long result files are kept
+	TTL time.Duration `yaml:"ttl"`
+}
+
+func DefaultConfig() Config {
+	homeDir, _ := os.UserHomeDir()
+	return Config{
+		Dir:             filepath.Join(homeDir, ".mayla", "results"),
+		MaxResponseSize: 512 * 1024,
+		PreviewSize:     8 * 1024,
+		TTL:             24 * time.Hour,
+	}
+}
+
+// Result is a result written to a file
+type Result struct {
+	Path    string
+	URI     string
+	Name    string
+	Size    int
+	Expires time.Time
+	// Preview is the start of the result, cut at a character boundary
+	Preview string
+}
+
+type Store struct {
+	cfg Config
+	mu  sync.Mutex
+	now func() time.Time
+}
+
+// New returns a store for cfg, or nil when spilling is disabled
+func New(cfg Config) *Store {
+	if cfg.MaxResponseSize <= 0 || cfg.Dir == "" {
+		return nil
+	}
+	if cfg.PreviewSize <= 0 || cfg.PreviewSize > cfg.MaxResponseSize {
+		cfg.PreviewSize = min(DefaultConfig().PreviewSize, cfg.MaxResponseSize)
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = DefaultConfig().TTL
+	}
+	return &Store{cfg: cfg, now: time.Now}
+}
+
+func (s *Store) TTL() time.Duration {
+	return s.cfg.TTL
+}
+
+// Limit is the largest result returned inline, in bytes
+func (s *Store) Limit() int {
+	return s.cfg.MaxResponseSize
+}
+
+// Exceeds reports whether data is too large to return inline
+func (s *Store) Exceeds(data []byte) bool {
+	return s != nil && len(data) > s.cfg.MaxResponseSize
+}
+
+// Save writes data, the result of tool, to a new file
+func (s *Store) Save(tool string, data []byte) (*Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.cfg.Dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create results dir: %w", err)
+	}
+	s.prune()
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, fmt.Errorf("failed to name result: %w", err)
+	}
+	now := s.now()
+	name := fmt.Sprintf("%s-%s-%s.json", safeName(tool), now.Format("20060102-150405"), hex.EncodeToString(suffix))
+	path := filepath.Join(s.cfg.Dir, name)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write result: %w", err)
+	}
+
+	return &Result{
+		Path:    path,
+		URI:     URI(path),
+		Name:    name,
+		Size:    len(data),
+		Expires: now.Add(s.cfg.TTL),
+		Preview: preview(data, s.cfg.PreviewSize),
+	}, nil
+}
+
+// Prune removes the result files older than the TTL and returns how many
+// it removed
+func (s *Store) Prune() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.prune()
+}
+
+func (s *Store) prune() int {
+	entries, err := os.ReadDir(s.cfg.Dir)
+	if err != nil {
+		return 0
+	}
+	cutoff := s.now().Add(-s.cfg.TTL)
+	removed := 0
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		if os.Remove(filepath.Join(s.cfg.Dir, entry.Name())) == nil {
+			removed++
+		}
+	}
+	return removed
+}
+
+// List returns the result files still kept, newest first
+func (s *Store) List() []Result {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := os.ReadDir(s.cfg.Dir)
+	if err != nil {
+		return nil
+	}
+	cutoff := s.now().Add(-s.cfg.TTL)
+	var results []Result
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.ModTime().Before(cutoff) {
+			continue
+		}
+		path := filepath.Join(s.cfg.Dir, entry.Name())
+		results = append(results, Result{
+			Path:    path,
+			URI:     URI(path),
+			Name:    entry.Name(),
+			Size:    int(info.Size()),
+			Expires: info.ModTime().Add(s.cfg.TTL),
+		})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Expires.After(results[j].Expires)
+	})
+	return results
+}
+
+// Read returns the result a URI from Save or List points to. Other URIs,
+// and paths outside the results dir, are not found.
+func (s *Store) Read(uri string) ([]byte, error) {
+	path, ok := strings.CutPrefix(uri, "file://")
+	if !ok {
+		return nil, fmt.Errorf("not a result: %s", uri)
+	}
+	if len(path) > 2 && path[0] == '/' && path[2] == ':' {
+		path = path[1:]
+	}
+	path = filepath.FromSlash(path)
+	if filepath.Dir(path) != filepath.Clean(s.cfg.Dir) || !strings.HasSuffix(path, ".json") {
+		return nil, fmt.Errorf("not a result: %s", uri)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("result expired or not found: %s", uri)
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// URI returns the file URI of path
+func URI(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return "file://" + path
+}
+
+func safeName(tool string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ' ' {
+			return '-'
+		}
+		return r
+	}, tool)
+}
+
+func preview(data []byte, size int) string {
+	if len(data) <= size {
+		return string(data)
+	}
+	for size > 0 && !utf8.RuneStart(data[size]) {
+		size--
+	}
+	return string(data[:size])
+}
@@ -0,0 +1,55 @@
+package spill
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "results")
+	if New(Config{Dir: dir}) != nil {
+		t.Fatal("expected spilling to be disabled without a size limit")
+	}
+	store := New(Config{Dir: dir, MaxResponseSize: 100, PreviewSize: 10, TTL: time.Hour})
+
+	small, large := []byte(`{"ok": true}`), []byte(`{"c": "`+strings.Repeat("é", 100)+`"}`)
+	if store.Exceeds(small) || !store.Exceeds(large) {
+		t.Fatal("expected only the large result to exceed the limit")
+	}
+
+	saved, err := store.Save("files/read", large)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(saved.Name, "files-read-") || saved.Size != len(large) || filepath.Dir(saved.Path) != dir {
+		t.Errorf("expected a file named after the tool in the results dir, got %+v", saved)
+	}
+	if saved.Preview != `{"c": "é` {
+		t.Errorf("expected the preview cut back to a character boundary, got %q", saved.Preview)
+	}
+
+	data, err := store.Read(saved.URI)
+	if err != nil || string(data) != string(large) {
+		t.Fatalf("expected the whole result back, got %d bytes, %v", len(data), err)
+	}
+	for _, uri := range []string{saved.Path, URI(filepath.Join(dir, "..", "secret.json")), URI("/etc/passwd")} {
+		if _, err := store.Read(uri); err == nil {
+			t.Errorf("expected %s not to be readable", uri)
+		}
+	}
+	if list := store.List(); len(list) != 1 || list[0].URI != saved.URI {
+		t.Errorf("expected the saved result to be listed, got %+v", list)
+	}
+
+	old := time.Now().Add(-2 * time.Hour)
+	os.Chtimes(saved.Path, old, old)
+	if removed := store.Prune(); removed != 1 {
+		t.Errorf("expected the expired result to be pruned, removed %d", removed)
+	}
+	if _, err := store.Read(saved.URI); err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Errorf("expected a pruned result to read as expired, got %v", err)
+	}
+}
@@ -0,0 +1,33 @@
+package tools
+
+import "sync"
+
+// AccessRecorder is notified of files the user works with through the tools.
+// It is called on the request path with the path as the tool got it, so it
+// should queue the path and leave resolving and storing it to later.
+type AccessRecorder func(path string)
+
+var (
+	accessMu       sync.RWMutex
+	accessRecorder AccessRecorder
+)
+
+// SetAccessRecorder installs the recorder used by RecordAccess; nil disables
+// tracking.
+func SetAccessRecorder(r AccessRecorder) {
+	accessMu.Lock()
+	accessRecorder = r
+	accessMu.Unlock()
+}
+
+// RecordAccess reports that path was read, edited or matched by a search
+func RecordAccess(path string) {
+	accessMu.RLock()
+	r := accessRecorder
+	accessMu.RUnlock()
+
+	if r == nil || path == "" {
+		return
+	}
+	r(path)
+}
@@ -0,0 +1,51 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// Activity is a tool call as the agent saw it: the input it sent and the
+// result or error it got back
+type Activity struct {
+	Tool      string
+	Input     json.RawMessage
+	Result    interface{}
+	Err       error
+	SessionID string
+}
+
+// ActivityRecorder is told about every tool call the registry runs, apart
+// from dry-run previews of mutating tools
+type ActivityRecorder func(Activity)
+
+var (
+	activityMu       sync.RWMutex
+	activityRecorder ActivityRecorder
+)
+
+// SetActivityRecorder installs the recorder used by RecordActivity; nil
+// disables it.
+func SetActivityRecorder(r ActivityRecorder) {
+	activityMu.Lock()
+	activityRecorder = r
+	activityMu.Unlock()
+}
+
+// RecordActivity reports a finished tool call, made in the session carried
+// by ctx, to the installed recorder
+func RecordActivity(ctx context.Context, name string, input json.RawMessage, result interface{}, err error) {
+	activityMu.RLock()
+	r := activityRecorder
+	activityMu.RUnlock()
+
+	if r == nil {
+		return
+	}
+	activity := Activity{Tool: name, Input: input, Result: result, Err: err}
+	if attribution, ok := AttributionFrom(ctx); ok {
+		activity.SessionID = attribution.SessionID
+	}
+	r(activity)
+}
@@ -0,0 +1,107 @@
+package tools
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+)
+
+// Attribution identifies where a change came from: the MCP client named in
+// the initialize handshake and the session it was made in, so agent writes
+// can be told apart from human ones during review
+type Attribution struct {
+	Client        string `json:"client,omitempty"`
+	ClientVersion string `json:"client_version,omitempty"`
+	SessionID     string `json:"session_id"`
+}
+
+// sessionAttribution is shared by every request of a session; the client
+// is filled in when the session's initialize request arrives
+type sessionAttribution struct {
+	mu          sync.RWMutex
+	attribution Attribution
+}
+
+type attributionKey struct{}
+
+// NewSessionID returns a random identifier for a client session
+func NewSessionID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// WithAttribution starts attributing the requests run under ctx to the
+// session sessionID
+func WithAttribution(ctx context.Context, sessionID string) context.Context {
+	return context.WithValue(ctx, attributionKey{}, &sessionAttribution{
+		attribution: Attribution{SessionID: sessionID},
+	})
+}
+
+// SetClient records the client of the session carried by ctx
+func SetClient(ctx context.Context, name, version string) {
+	s, ok := ctx.Value(attributionKey{}).(*sessionAttribution)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	s.attribution.Client = name
+	s.attribution.ClientVersion = version
+	s.mu.Unlock()
+}
+
+// AttributionFrom returns the attribution of the session carried by ctx
+func AttributionFrom(ctx context.Context) (Attribution, bool) {
+	s, ok := ctx.Value(attributionKey{}).(*sessionAttribution)
+	if !ok {
+		return Attribution{}, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.attribution, true
+}
+
+// WriteRecord is a call to a mutating tool that ran, successfully or not;
+// rejected and dry-run calls change nothing and are not recorded
+type WriteRecord struct {
+	Tool        string
+	Input       json.RawMessage
+	Err         error
+	Attribution *Attribution
+}
+
+// WriteRecorder is told about every mutating tool call the registry runs
+type WriteRecorder func(WriteRecord)
+
+var (
+	writeMu       sync.RWMutex
+	writeRecorder WriteRecorder
+)
+
+// SetWriteRecorder installs the recorder used by RecordWrite; nil disables
+// it.
+func SetWriteRecorder(r WriteRecorder) {
+	writeMu.Lock()
+	writeRecorder = r
+	writeMu.Unlock()
+}
+
+// RecordWrite reports a mutating tool call, attributed to the session
+// carried by ctx, to the installed recorder
+func RecordWrite(ctx context.Context, name string, input json.RawMessage, err error) {
+	writeMu.RLock()
+	r := writeRecorder
+	writeMu.RUnlock()
+
+	if r == nil {
+		return
+	}
+	record := WriteRecord{Tool: name, Input: input, Err: err}
+	if attribution, ok := AttributionFrom(ctx); ok {
+		record.Attribution = &attribution
+	}
+	r(record)
+}
@@ -0,0 +1,74 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+type readTool struct{ namedTool }
+
+func (t *readTool) Title() string                { return t.name }
+func (t *readTool) Annotations() map[string]bool { return ReadOnlyAnnotations() }
+
+func TestRecordWrite(t *testing.T) {
+	var records []WriteRecord
+	SetWriteRecorder(func(record WriteRecord) { records = append(records, record) })
+	defer SetWriteRecorder(nil)
+
+	r := NewRegistry()
+	r.RegisterIn("files", &namedTool{name: "write"})
+	r.RegisterIn("files", &readTool{namedTool{name: "read"}})
+
+	ctx := WithAttribution(context.Background(), "s1")
+	SetClient(ctx, "claude-code", "2.0.1")
+	input := json.RawMessage(`{"path": "/tmp/a.go"}`)
+
+	r.Execute(ctx, "files/write", input)
+	r.Execute(ctx, "read", input)
+	r.Execute(WithSession(ctx, SessionOptions{DryRun: true}), "write", input)
+	r.Execute(WithSession(ctx, SessionOptions{ReadOnly: true}), "write", input)
+	r.Execute(context.Background(), "write", input)
+
+	if len(records) != 2 {
+		t.Fatalf("expected the two writes that ran to be recorded, got %+v", records)
+	}
+	if records[0].Tool != "write" || string(records[0].Input) != string(input) || records[0].Err != nil {
+		t.Errorf("expected the write under its canonical name, got %+v", records[0])
+	}
+	want := Attribution{Client: "claude-code", ClientVersion: "2.0.1", SessionID: "s1"}
+	if records[0].Attribution == nil || *records[0].Attribution != want {
+		t.Errorf("expected the write attributed to %+v, got %+v", want, records[0].Attribution)
+	}
+	if records[1].Attribution != nil {
+		t.Errorf("expected no attribution outside a session, got %+v", records[1].Attribution)
+	}
+}
+
+func TestRecordActivity(t *testing.T) {
+	var activities []Activity
+	SetActivityRecorder(func(activity Activity) { activities = append(activities, activity) })
+	defer SetActivityRecorder(nil)
+
+	r := NewRegistry()
+	r.RegisterIn("files", &namedTool{name: "write"})
+	r.RegisterIn("files", &readTool{namedTool{name: "read"}})
+
+	ctx := WithAttribution(context.Background(), "s1")
+	input := json.RawMessage(`{"path": "/tmp/a.go"}`)
+
+	r.Execute(ctx, "files/read", input)
+	r.Execute(WithSession(ctx, SessionOptions{DryRun: true}), "write", input)
+	r.Execute(WithSession(ctx, SessionOptions{ReadOnly: true}), "write", input)
+	r.Execute(ctx, "write", input)
+
+	if len(activities) != 2 {
+		t.Fatalf("expected the read and the write that ran, got %+v", activities)
+	}
+	if a := activities[0]; a.Tool != "read" || a.Result != "read" || a.SessionID != "s1" {
+		t.Errorf("expected the read under its canonical name with its result, got %+v", a)
+	}
+	if a := activities[1]; a.Tool != "write" || a.Err != nil {
+		t.Errorf("expected the write, got %+v", a)
+	}
+}
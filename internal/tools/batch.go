@@ -0,0 +1,151 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	maxBatchCalls    = 50
+	batchCallTimeout = 4 * time.Minute
+)
+
+// BatchTool runs several tool calls in one round-trip. Calls execute in
+// order, so a later call may rely on the effects of an earlier one.
+type BatchTool struct {
+	registry *Registry
+}
+
+func NewBatchTool(registry *Registry) *BatchTool {
+	return &BatchTool{registry: registry}
+}
+
+func (t *BatchTool) dispatchesTools() {}
+
+func (t *BatchTool) Name() string {
+	return "batch"
+}
+
+func (t *BatchTool) Description() string {
+	return `Execute several tool calls in one round-trip.
+
+Calls run sequentially in the given order and each reports its own result or error. Set stop_on_error to skip the remaining calls after the first failure. A batch cannot contain another batch.`
+}
+
+func (t *BatchTool) Title() string {
+	return "Batch Tool Calls"
+}
+
+// Annotations are the most permissive of any tool the batch may run
+func (t *BatchTool) Annotations() map[string]bool {
+	return DestructiveAnnotations()
+}
+
+func (t *BatchTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"calls": {
+				"type": "array",
+				"maxItems": 50,
+				"items": {
+					"type": "object",
+					"properties": {
+						"name": {
+							"type": "string",
+							"description": "Tool name"
+						},
+						"arguments": {
+							"type": "object",
+							"description": "Tool arguments"
+						}
+					},
+					"required": ["name"]
+				},
+				"description": "Tool calls to execute in order"
+			},
+			"stop_on_error": {
+				"type": "boolean",
+				"description": "Skip remaining calls after the first failure (default: false)"
+			}
+		},
+		"required": ["calls"]
+	}`)
+}
+
+type batchCall struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type batchResult struct {
+	Name    string      `json:"name"`
+	Result  interface{} `json:"result,omitempty"`
+	Error   string      `json:"error,omitempty"`
+	Skipped bool        `json:"skipped,omitempty"`
+}
+
+func (t *BatchTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var req struct {
+		Calls       []batchCall `json:"calls"`
+		StopOnError bool        `json:"stop_on_error"`
+	}
+	if err := json.Unmarshal(input, &req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	if len(req.Calls) == 0 {
+		return nil, fmt.Errorf("calls must not be empty")
+	}
+	if len(req.Calls) > maxBatchCalls {
+		return nil, fmt.Errorf("too many calls: %d (max %d)", len(req.Calls), maxBatchCalls)
+	}
+	for i, call := range req.Calls {
+		if call.Name == "" {
+			return nil, fmt.Errorf("call %d: tool name is required", i)
+		}
+		// batch also answers to its qualified name and any aliases
+		if name, _ := t.registry.Resolve(call.Name); name == t.Name() {
+			return nil, fmt.Errorf("call %d: batches cannot be nested", i)
+		}
+	}
+
+	results := make([]batchResult, len(req.Calls))
+	succeeded, failed := 0, 0
+	stopped := false
+	for i, call := range req.Calls {
+		results[i].Name = call.Name
+		if stopped || ctx.Err() != nil {
+			results[i].Skipped = true
+			continue
+		}
+
+		args := call.Arguments
+		if len(args) == 0 {
+			args = json.RawMessage(`{}`)
+		}
+
+		value, err := t.registry.ExecuteWithTimeout(ctx, call.Name, args, batchCallTimeout)
+		if err != nil {
+			results[i].Error = err.Error()
+			failed++
+			stopped = req.StopOnError
+			continue
+		}
+		results[i].Result = value
+		succeeded++
+	}
+
+	return map[string]interface{}{
+		"results":   results,
+		"succeeded": succeeded,
+		"failed":    failed,
+		"skipped":   len(req.Calls) - succeeded - failed,
+	}, nil
+}
@@ -0,0 +1,31 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestBatchNesting(t *testing.T) {
+	r := NewRegistry()
+	if err := r.RegisterIn("system", NewBatchTool(r)); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Alias("multi", "batch"); err != nil {
+		t.Fatal(err)
+	}
+	r.Register(&namedTool{"read"})
+
+	for _, name := range []string{"batch", "system/batch", "multi"} {
+		input := json.RawMessage(`{"calls": [{"name": "read"}, {"name": "` + name + `", "arguments": {"calls": [{"name": "read"}]}}]}`)
+		_, err := r.Execute(context.Background(), "batch", input)
+		if err == nil || !strings.Contains(err.Error(), "cannot be nested") {
+			t.Errorf("%s: expected a nesting error, got %v", name, err)
+		}
+	}
+
+	if _, err := r.Execute(context.Background(), "system/batch", json.RawMessage(`{"calls": [{"name": "read"}]}`)); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
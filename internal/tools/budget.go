@@ -0,0 +1,194 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ContextBudget tallies the estimated tokens of the tool results one
+// session has received, so an agent can tell how much of its context window
+// tool output has taken.
+type ContextBudget struct {
+	mu     sync.Mutex
+	calls  int
+	tokens int
+	byTool map[string]*ToolUsage
+}
+
+// ToolUsage is what the results of one tool have cost a session
+type ToolUsage struct {
+	Tool   string `json:"tool"`
+	Calls  int    `json:"calls"`
+	Tokens int    `json:"estimated_tokens"`
+	// Largest is the estimate of the largest single result
+	Largest int `json:"largest"`
+}
+
+func NewContextBudget() *ContextBudget {
+	return &ContextBudget{byTool: make(map[string]*ToolUsage)}
+}
+
+type contextBudgetKey struct{}
+
+func WithContextBudget(ctx context.Context, budget *ContextBudget) context.Context {
+	return context.WithValue(ctx, contextBudgetKey{}, budget)
+}
+
+// ContextBudgetFrom returns the context budget of the session ctx belongs
+// to, or nil outside of a session
+func ContextBudgetFrom(ctx context.Context) *ContextBudget {
+	budget, _ := ctx.Value(contextBudgetKey{}).(*ContextBudget)
+	return budget
+}
+
+// Add counts a result of tool estimated at tokens and returns the session's
+// running total
+func (b *ContextBudget) Add(tool string, tokens int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	usage, ok := b.byTool[tool]
+	if !ok {
+		usage = &ToolUsage{Tool: tool}
+		b.byTool[tool] = usage
+	}
+	usage.Calls++
+	usage.Tokens += tokens
+	usage.Largest = max(usage.Largest, tokens)
+
+	b.calls++
+	b.tokens += tokens
+	return b.tokens
+}
+
+// Usage returns the number of results counted, their total estimate and
+// the per-tool figures, costliest tool first
+func (b *ContextBudget) Usage() (calls, tokens int, byTool []ToolUsage) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	byTool = make([]ToolUsage, 0, len(b.byTool))
+	for _, usage := range b.byTool {
+		byTool = append(byTool, *usage)
+	}
+	sort.Slice(byTool, func(i, j int) bool {
+		if byTool[i].Tokens != byTool[j].Tokens {
+			return byTool[i].Tokens > byTool[j].Tokens
+		}
+		return byTool[i].Tool < byTool[j].Tool
+	})
+	return b.calls, b.tokens, byTool
+}
+
+// Reset starts the tally over, as after the agent has compacted its context
+func (b *ContextBudget) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.calls, b.tokens = 0, 0
+	b.byTool = make(map[string]*ToolUsage)
+}
+
+type ContextBudgetRequest struct {
+	Window int  `json:"window,omitempty"`
+	Reset  bool `json:"reset,omitempty"`
+}
+
+type ContextBudgetResponse struct {
+	Calls           int         `json:"calls"`
+	EstimatedTokens int         `json:"estimated_tokens"`
+	Window          int         `json:"window,omitempty"`
+	Remaining       *int        `json:"remaining,omitempty"`
+	UsedPercent     float64     `json:"used_percent,omitempty"`
+	Tools           []ToolUsage `json:"tools"`
+	Reset           bool        `json:"reset,omitempty"`
+}
+
+type ContextBudgetTool struct{}
+
+// NewContextBudgetTool returns the tool reporting the context budget of
+// the calling session
+func NewContextBudgetTool() *ContextBudgetTool {
+	return &ContextBudgetTool{}
+}
+
+func (t *ContextBudgetTool) sessionScoped() {}
+
+func (t *ContextBudgetTool) Name() string {
+	return "context_budget"
+}
+
+func (t *ContextBudgetTool) Description() string {
+	return `Report how many tokens the tool results of this session have taken, in total and per tool.
+
+Every tool result carries its own estimate in _meta.estimated_tokens; this tool sums them for the session (about 4 characters per token, a heuristic rather than the model's tokenizer). Pass window, the size of your context window in tokens, to get what remains of it. Use it before a large read or search to decide whether to narrow it with limits, offsets or summaries. reset starts the tally over, for instance after the conversation was compacted.`
+}
+
+func (t *ContextBudgetTool) Title() string {
+	return "Context Budget"
+}
+
+func (t *ContextBudgetTool) Annotations() map[string]bool {
+	return NonIdempotentWriteAnnotations()
+}
+
+func (t *ContextBudgetTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"window": {
+				"type": "integer",
+				"description": "Context window size in tokens, to compute what remains of it"
+			},
+			"reset": {
+				"type": "boolean",
+				"description": "Start the tally over after reporting it (default: false)"
+			}
+		}
+	}`)
+}
+
+func (t *ContextBudgetTool) OutputSchema() json.RawMessage {
+	return OutputSchemaOf(ContextBudgetResponse{})
+}
+
+func (t *ContextBudgetTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var req ContextBudgetRequest
+	if len(input) > 0 {
+		if err := json.Unmarshal(input, &req); err != nil {
+			return nil, fmt.Errorf("invalid request: %w", err)
+		}
+	}
+	if req.Window < 0 {
+		return nil, fmt.Errorf("window must be positive")
+	}
+
+	budget := ContextBudgetFrom(ctx)
+	if budget == nil {
+		return nil, fmt.Errorf("no context budget: token tallies need a client session")
+	}
+
+	calls, tokens, byTool := budget.Usage()
+	resp := &ContextBudgetResponse{
+		Calls:           calls,
+		EstimatedTokens: tokens,
+		Tools:           byTool,
+	}
+	if req.Window > 0 {
+		remaining := max(0, req.Window-tokens)
+		resp.Window = req.Window
+		resp.Remaining = &remaining
+		resp.UsedPercent = float64(tokens*1000/req.Window) / 10
+	}
+	if req.Reset {
+		budget.Reset()
+		resp.Reset = true
+	}
+	return resp, nil
+}
@@ -0,0 +1,43 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestContextBudgetTool(t *testing.T) {
+	r := NewRegistry()
+	if err := r.RegisterIn("system", NewContextBudgetTool()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.Execute(context.Background(), "context_budget", json.RawMessage(`{}`)); err == nil {
+		t.Error("expected an error outside of a session")
+	}
+
+	budget := NewContextBudget()
+	budget.Add("read", 300)
+	budget.Add("search", 150)
+	if total := budget.Add("read", 50); total != 500 {
+		t.Fatalf("running total = %d, want 500", total)
+	}
+
+	// a read-only session reports and resets its own budget as usual
+	ctx := WithSession(WithContextBudget(context.Background(), budget), SessionOptions{ReadOnly: true})
+	result, err := r.Execute(ctx, "system/context_budget", json.RawMessage(`{"window":1000,"reset":true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := result.(*ContextBudgetResponse)
+	if resp.Calls != 3 || resp.EstimatedTokens != 500 || *resp.Remaining != 500 || resp.UsedPercent != 50 {
+		t.Errorf("unexpected totals: %+v", resp)
+	}
+	if len(resp.Tools) != 2 || resp.Tools[0] != (ToolUsage{Tool: "read", Calls: 2, Tokens: 350, Largest: 300}) {
+		t.Errorf("unexpected per-tool usage: %+v", resp.Tools)
+	}
+
+	if calls, tokens, _ := budget.Usage(); calls != 0 || tokens != 0 {
+		t.Errorf("reset left %d calls and %d tokens", calls, tokens)
+	}
+}
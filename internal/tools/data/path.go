@@ -0,0 +1,307 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// pathStep is one step of a query path: a key, an index, or a wildcard
+// that fans out over every element or value
+type pathStep struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// queryPath is a parsed JSONPath-like expression
+type queryPath struct {
+	steps []pathStep
+	// fans is set when a wildcard makes the path select many values
+	fans bool
+}
+
+// parsePath parses the subset of JSONPath the tool understands: an
+// optional $ or leading dot, then .key, ["key"], [n] (negative from the
+// end), [*] or [] and .* steps. "items[*].name" and "$.items[0]" are paths.
+func parsePath(expr string) (*queryPath, error) {
+	expr = strings.TrimSpace(expr)
+	expr = strings.TrimPrefix(expr, "$")
+	p := &queryPath{}
+
+	for i := 0; i < len(expr); {
+		switch expr[i] {
+		case '.':
+			i++
+			if i < len(expr) && expr[i] == '*' {
+				p.steps = append(p.steps, pathStep{wildcard: true})
+				p.fans = true
+				i++
+				continue
+			}
+			start := i
+			for i < len(expr) && expr[i] != '.' && expr[i] != '[' {
+				i++
+			}
+			if start == i {
+				if i == len(expr) && len(p.steps) == 0 {
+					continue
+				}
+				return nil, fmt.Errorf("invalid path %q: empty key at %d", expr, start)
+			}
+			p.steps = append(p.steps, pathStep{key: expr[start:i]})
+		case '[':
+			end := strings.IndexByte(expr[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid path %q: unclosed [", expr)
+			}
+			inner := strings.TrimSpace(expr[i+1 : i+end])
+			i += end + 1
+			switch {
+			case inner == "" || inner == "*":
+				p.steps = append(p.steps, pathStep{wildcard: true})
+				p.fans = true
+			case inner[0] == '"' || inner[0] == '\'':
+				key := strings.Trim(inner, `"'`)
+				p.steps = append(p.steps, pathStep{key: key})
+			default:
+				n, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid path %q: bad index [%s]", expr, inner)
+				}
+				p.steps = append(p.steps, pathStep{index: n, isIndex: true})
+			}
+		default:
+			if i > 0 {
+				return nil, fmt.Errorf("invalid path %q: unexpected %q at %d", expr, expr[i], i)
+			}
+			// a path may start with a bare key: items[0].name
+			start := i
+			for i < len(expr) && expr[i] != '.' && expr[i] != '[' {
+				i++
+			}
+			p.steps = append(p.steps, pathStep{key: expr[start:i]})
+		}
+	}
+	return p, nil
+}
+
+// eval returns the values the path selects from root
+func (p *queryPath) eval(root interface{}) []interface{} {
+	values := []interface{}{root}
+	for _, step := range p.steps {
+		var next []interface{}
+		for _, value := range values {
+			next = append(next, step.apply(value)...)
+		}
+		values = next
+	}
+	return values
+}
+
+func (s pathStep) apply(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if s.wildcard {
+			out := make([]interface{}, 0, len(v))
+			for _, key := range sortedKeys(v) {
+				out = append(out, v[key])
+			}
+			return out
+		}
+		if s.isIndex {
+			return nil
+		}
+		if child, ok := v[s.key]; ok {
+			return []interface{}{child}
+		}
+	case []interface{}:
+		if s.wildcard {
+			return v
+		}
+		if s.isIndex {
+			i := s.index
+			if i < 0 {
+				i += len(v)
+			}
+			if i >= 0 && i < len(v) {
+				return []interface{}{v[i]}
+			}
+		}
+	}
+	return nil
+}
+
+// lookup returns the single value path selects in row, if any
+func lookup(row interface{}, path *queryPath) (interface{}, bool) {
+	values := path.eval(row)
+	if len(values) == 0 {
+		return nil, false
+	}
+	if path.fans {
+		return values, true
+	}
+	return values[0], true
+}
+
+var filterOperators = []string{"==", "!=", ">=", "<=", "=~", ">", "<", " contains "}
+
+// rowFilter is one where condition: field op value
+type rowFilter struct {
+	field *queryPath
+	op    string
+	value interface{}
+	re    *regexp.Regexp
+}
+
+// parseFilter parses a where condition such as `age >= 30`,
+// `status == "active"`, `name =~ ^a` or `tags contains go`. The value is
+// read as JSON when it parses as JSON and as a plain string otherwise.
+func parseFilter(expr string) (*rowFilter, error) {
+	at, op := -1, ""
+	for _, candidate := range filterOperators {
+		i := strings.Index(expr, candidate)
+		if i > 0 && (at < 0 || i < at) {
+			at, op = i, candidate
+		}
+	}
+	if at < 0 {
+		return nil, fmt.Errorf("invalid filter %q: expected field, one of == != > >= < <= =~ contains, and a value", expr)
+	}
+
+	field, err := parsePath(strings.TrimSpace(expr[:at]))
+	if err != nil {
+		return nil, err
+	}
+	raw := strings.TrimSpace(expr[at+len(op):])
+	f := &rowFilter{field: field, op: strings.TrimSpace(op)}
+	if f.op == "=~" {
+		if f.re, err = regexp.Compile(raw); err != nil {
+			return nil, fmt.Errorf("invalid filter %q: %w", expr, err)
+		}
+		return f, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &f.value); err != nil {
+		f.value = raw
+	}
+	return f, nil
+}
+
+func (f *rowFilter) matches(row interface{}) bool {
+	value, ok := lookup(row, f.field)
+	switch f.op {
+	case "==":
+		return ok && equal(value, f.value)
+	case "!=":
+		return !ok || !equal(value, f.value)
+	case "=~":
+		return ok && f.re.MatchString(stringOf(value))
+	case "contains":
+		if !ok {
+			return false
+		}
+		if list, isList := value.([]interface{}); isList {
+			for _, item := range list {
+				if equal(item, f.value) {
+					return true
+				}
+			}
+			return false
+		}
+		return strings.Contains(stringOf(value), stringOf(f.value))
+	}
+
+	if !ok {
+		return false
+	}
+	cmp, comparable := compare(value, f.value)
+	if !comparable {
+		return false
+	}
+	switch f.op {
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	return false
+}
+
+func equal(a, b interface{}) bool {
+	if cmp, ok := compare(a, b); ok {
+		return cmp == 0
+	}
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return stringOf(a) == stringOf(b)
+}
+
+// compare orders two numbers, or two strings; values of other or mixed
+// types are not comparable
+func compare(a, b interface{}) (int, bool) {
+	if x, ok := number(a); ok {
+		if y, ok := number(b); ok {
+			switch {
+			case x < y:
+				return -1, true
+			case x > y:
+				return 1, true
+			}
+			return 0, true
+		}
+		return 0, false
+	}
+	x, aok := a.(string)
+	y, bok := b.(string)
+	if !aok || !bok {
+		return 0, false
+	}
+	return strings.Compare(x, y), true
+}
+
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int64:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func stringOf(v interface{}) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case nil:
+		return ""
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,584 @@
+package data
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alucardeht/may-la-mcp/internal/miniyaml"
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+const (
+	defaultQueryLimit = 50
+	maxQueryLimit     = 1000
+	// maxDocumentSize bounds the JSON and YAML files decoded whole; CSV
+	// and JSON Lines are streamed and have no limit
+	maxDocumentSize = 64 * 1024 * 1024
+	// maxResultBytes bounds the encoded rows or result returned
+	maxResultBytes = 256 * 1024
+	maxResultKeys  = 200
+)
+
+type QueryRequest struct {
+	Path      string   `json:"path"`
+	Format    string   `json:"format,omitempty"`
+	Query     string   `json:"query,omitempty"`
+	Where     []string `json:"where,omitempty"`
+	Select    []string `json:"select,omitempty"`
+	Limit     int      `json:"limit,omitempty"`
+	Offset    int      `json:"offset,omitempty"`
+	Header    *bool    `json:"header,omitempty"`
+	Delimiter string   `json:"delimiter,omitempty"`
+}
+
+type QueryResponse struct {
+	Path      string                   `json:"path"`
+	Format    string                   `json:"format"`
+	Columns   []string                 `json:"columns,omitempty"`
+	Rows      []map[string]interface{} `json:"rows,omitempty"`
+	Result    interface{}              `json:"result,omitempty"`
+	Keys      []string                 `json:"keys,omitempty"`
+	Total     int                      `json:"total"`
+	Returned  int                      `json:"returned"`
+	Truncated bool                     `json:"truncated,omitempty"`
+}
+
+type QueryTool struct{}
+
+func (t *QueryTool) Name() string {
+	return "data_query"
+}
+
+func (t *QueryTool) Description() string {
+	return `Query a CSV, TSV, JSON, JSON Lines or YAML file without reading it whole into the conversation.
+
+query is a JSONPath-like path selecting what to look at: "$" (the default) is the document, ".items" or ["items"] a key, [0] or [-1] an element, [*] and .* every element or value ("$.users[*].address"). When the selection is a list, where filters its rows ("age >= 30", "status == \"active\"", "name =~ ^a", "tags contains go"; all must hold), select keeps the given fields, and limit and offset page through the rows. Any other selection is returned as result.
+
+CSV rows are objects keyed by the header, which is inferred: a first row of distinct non-numeric names is a header, otherwise columns are named col1, col2, ...; numbers and booleans in cells are typed. A YAML file must hold a single document; a stream of documents separated by "---" is rejected. The output is bounded: rows stop at limit or about 256KB, and an oversized object is summarized by its keys.`
+}
+
+func (t *QueryTool) Title() string {
+	return "Query Data File"
+}
+
+func (t *QueryTool) Annotations() map[string]bool {
+	return tools.ReadOnlyAnnotations()
+}
+
+func (t *QueryTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"path": {
+				"type": "string",
+				"description": "Path to the data file"
+			},
+			"format": {
+				"type": "string",
+				"description": "File format (default: from the extension)",
+				"enum": ["csv", "tsv", "json", "jsonl", "yaml"]
+			},
+			"query": {
+				"type": "string",
+				"description": "JSONPath-like selection (default: $, the whole document; e.g., $.items[*].meta, [\"key with spaces\"], [-1])"
+			},
+			"where": {
+				"type": "array",
+				"items": {"type": "string"},
+				"description": "Row filters as field operator value, all of which must hold; operators: == != > >= < <= =~ contains (e.g., [\"age >= 30\", \"country == BR\"])"
+			},
+			"select": {
+				"type": "array",
+				"items": {"type": "string"},
+				"description": "Fields kept in each row; dotted paths reach nested fields (e.g., [\"name\", \"address.city\"])"
+			},
+			"limit": {
+				"type": "integer",
+				"description": "Maximum rows returned (default: 50, max: 1000)"
+			},
+			"offset": {
+				"type": "integer",
+				"description": "Matching rows skipped before the first one returned (default: 0)",
+				"minimum": 0
+			},
+			"header": {
+				"type": "boolean",
+				"description": "Whether the first CSV row is a header (default: inferred)"
+			},
+			"delimiter": {
+				"type": "string",
+				"description": "CSV field delimiter (default: , for csv and tab for tsv)"
+			}
+		},
+		"required": ["path"]
+	}`)
+}
+
+func (t *QueryTool) Examples() []tools.Example {
+	return []tools.Example{
+		{
+			Description: "Active users over 30 from a CSV export, two columns",
+			Arguments:   json.RawMessage(`{"path": "/home/user/app/users.csv", "where": ["age > 30", "status == active"], "select": ["name", "email"], "limit": 2}`),
+			Result:      json.RawMessage(`{"path": "/home/user/app/users.csv", "format": "csv", "columns": ["name", "email"], "rows": [{"name": "Ana", "email": "ana@example.com"}, {"name": "Rui", "email": "rui@example.com"}], "total": 14, "returned": 2, "truncated": true}`),
+		},
+		{
+			Description: "One value from a YAML config",
+			Arguments:   json.RawMessage(`{"path": "/home/user/app/config.yaml", "query": "$.database.pool.max"}`),
+			Result:      json.RawMessage(`{"path": "/home/user/app/config.yaml", "format": "yaml", "result": 20, "total": 1, "returned": 1}`),
+		},
+	}
+}
+
+func (t *QueryTool) OutputSchema() json.RawMessage {
+	return tools.OutputSchemaOf(QueryResponse{})
+}
+
+func (t *QueryTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var req QueryRequest
+	if err := json.Unmarshal(input, &req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+	if req.Path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+	if err := tools.CheckPath(req.Path); err != nil {
+		return nil, err
+	}
+	if req.Offset < 0 {
+		return nil, fmt.Errorf("offset must not be negative")
+	}
+	if req.Limit <= 0 {
+		req.Limit = defaultQueryLimit
+	}
+	req.Limit = min(req.Limit, maxQueryLimit)
+
+	format, err := detectFormat(req.Path, req.Format)
+	if err != nil {
+		return nil, err
+	}
+	path, err := parsePath(req.Query)
+	if err != nil {
+		return nil, err
+	}
+	var filters []*rowFilter
+	for _, expr := range req.Where {
+		filter, err := parseFilter(expr)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+	var fields []*queryPath
+	for _, field := range req.Select {
+		parsed, err := parsePath(field)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, parsed)
+	}
+
+	q := &query{req: req, filters: filters, fields: fields}
+	resp := &QueryResponse{Path: req.Path, Format: format}
+
+	switch format {
+	case "csv", "tsv":
+		if len(path.steps) > 0 {
+			return nil, fmt.Errorf("query applies to JSON and YAML documents; filter CSV rows with where and select")
+		}
+		err = q.streamCSV(ctx, format, resp)
+	case "jsonl":
+		err = q.streamJSONL(ctx, path, resp)
+	default:
+		var doc interface{}
+		if doc, err = loadDocument(req.Path, format); err == nil {
+			err = q.run(ctx, path.selectRows(doc), resp)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// detectFormat returns format, or the one path's extension implies
+func detectFormat(path, format string) (string, error) {
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".csv":
+			format = "csv"
+		case ".tsv", ".tab":
+			format = "tsv"
+		case ".json", ".geojson":
+			format = "json"
+		case ".jsonl", ".ndjson":
+			format = "jsonl"
+		case ".yaml", ".yml":
+			format = "yaml"
+		default:
+			return "", fmt.Errorf("cannot infer the format of %s: set format", path)
+		}
+	}
+	switch format {
+	case "csv", "tsv", "json", "jsonl", "yaml":
+		return format, nil
+	}
+	return "", fmt.Errorf("invalid format %q: expected csv, tsv, json, jsonl or yaml", format)
+}
+
+func loadDocument(path, format string) (interface{}, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+	if info.Size() > maxDocumentSize {
+		return nil, fmt.Errorf("file too large to query as %s (%d bytes, max %d)", format, info.Size(), maxDocumentSize)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	if format == "yaml" {
+		doc, err := miniyaml.Parse(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid YAML: %w", err)
+		}
+		return doc, nil
+	}
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	return doc, nil
+}
+
+// selectRows applies the path to doc. A fanning path or one selecting a
+// list yields rows; anything else is a single result.
+func (p *queryPath) selectRows(doc interface{}) selection {
+	values := p.eval(doc)
+	if p.fans {
+		return selection{rows: values, isRows: true}
+	}
+	if len(values) == 0 {
+		return selection{}
+	}
+	if list, ok := values[0].([]interface{}); ok {
+		return selection{rows: list, isRows: true}
+	}
+	return selection{result: values[0], found: true}
+}
+
+type selection struct {
+	rows   []interface{}
+	isRows bool
+	result interface{}
+	found  bool
+}
+
+// query filters, projects and pages rows into a response
+type query struct {
+	req     QueryRequest
+	filters []*rowFilter
+	fields  []*queryPath
+	size    int
+}
+
+// add offers one row to the response; rows past the page are only counted
+func (q *query) add(row interface{}, resp *QueryResponse) {
+	for _, filter := range q.filters {
+		if !filter.matches(row) {
+			return
+		}
+	}
+	resp.Total++
+	if resp.Total <= q.req.Offset || resp.Truncated {
+		return
+	}
+	if resp.Returned >= q.req.Limit {
+		resp.Truncated = true
+		return
+	}
+
+	projected := q.project(row)
+	encoded, _ := json.Marshal(projected)
+	if q.size+len(encoded) > maxResultBytes && resp.Returned > 0 {
+		resp.Truncated = true
+		return
+	}
+	q.size += len(encoded)
+	resp.Rows = append(resp.Rows, projected)
+	resp.Returned++
+}
+
+// project keeps the selected fields of row; rows that are not objects are
+// wrapped as {"value": row}
+func (q *query) project(row interface{}) map[string]interface{} {
+	obj, ok := row.(map[string]interface{})
+	if !ok {
+		obj = map[string]interface{}{"value": row}
+	}
+	if len(q.fields) == 0 {
+		return obj
+	}
+	out := make(map[string]interface{}, len(q.fields))
+	for i, field := range q.fields {
+		if value, ok := lookup(row, field); ok {
+			out[q.req.Select[i]] = value
+		}
+	}
+	return out
+}
+
+func (q *query) run(ctx context.Context, sel selection, resp *QueryResponse) error {
+	if !sel.isRows {
+		if len(q.filters) > 0 || len(q.fields) > 0 {
+			if !sel.found {
+				return nil
+			}
+			if _, isObj := sel.result.(map[string]interface{}); !isObj {
+				return fmt.Errorf("where and select need rows, but the query selects a %s", typeName(sel.result))
+			}
+			return q.run(ctx, selection{rows: []interface{}{sel.result}, isRows: true}, resp)
+		}
+		if sel.found {
+			resp.Total, resp.Returned = 1, 1
+			resp.Result = sel.result
+			boundResult(resp)
+		}
+		return nil
+	}
+
+	for i, row := range sel.rows {
+		if i%1000 == 0 && ctx.Err() != nil {
+			return ctx.Err()
+		}
+		q.add(row, resp)
+	}
+	resp.Columns = columnsOf(resp.Rows, q.req.Select)
+	return nil
+}
+
+// boundResult replaces a result too large to return by a summary: the
+// keys of an object, the first elements of a list, the start of a string
+func boundResult(resp *QueryResponse) {
+	encoded, _ := json.Marshal(resp.Result)
+	if len(encoded) <= maxResultBytes {
+		return
+	}
+	resp.Truncated = true
+	switch v := resp.Result.(type) {
+	case map[string]interface{}:
+		keys := sortedKeys(v)
+		if len(keys) > maxResultKeys {
+			keys = keys[:maxResultKeys]
+		}
+		resp.Keys = keys
+		resp.Result = nil
+	case string:
+		cut := maxResultBytes
+		for cut > 0 && !utf8.RuneStart(v[cut]) {
+			cut--
+		}
+		resp.Result = v[:cut]
+	default:
+		resp.Result = nil
+	}
+}
+
+// streamJSONL reads one row per line; a query applies to each line and
+// the values it selects are the rows
+func (q *query) streamJSONL(ctx context.Context, path *queryPath, resp *QueryResponse) error {
+	file, err := os.Open(q.req.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if line%1000 == 0 && ctx.Err() != nil {
+			return ctx.Err()
+		}
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var row interface{}
+		if err := json.Unmarshal([]byte(text), &row); err != nil {
+			return fmt.Errorf("invalid JSON on line %d: %w", line, err)
+		}
+		for _, value := range path.eval(row) {
+			q.add(value, resp)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	resp.Columns = columnsOf(resp.Rows, q.req.Select)
+	return nil
+}
+
+func (q *query) streamCSV(ctx context.Context, format string, resp *QueryResponse) error {
+	file, err := os.Open(q.req.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(bufio.NewReader(file))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.ReuseRecord = true
+	switch {
+	case q.req.Delimiter != "":
+		delimiter, size := utf8.DecodeRuneInString(q.req.Delimiter)
+		if size != len(q.req.Delimiter) {
+			return fmt.Errorf("invalid delimiter %q: expected a single character", q.req.Delimiter)
+		}
+		reader.Comma = delimiter
+	case format == "tsv":
+		reader.Comma = '\t'
+	}
+
+	var header []string
+	for n := 0; ; n++ {
+		if n%1000 == 0 && ctx.Err() != nil {
+			return ctx.Err()
+		}
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("invalid CSV: %w", err)
+		}
+
+		if header == nil {
+			isHeader := isHeaderRow(record)
+			if q.req.Header != nil {
+				isHeader = *q.req.Header
+			}
+			header = columnNames(record, isHeader)
+			if isHeader {
+				continue
+			}
+		}
+		for len(header) < len(record) {
+			header = append(header, fmt.Sprintf("col%d", len(header)+1))
+		}
+
+		row := make(map[string]interface{}, len(record))
+		for i, cell := range record {
+			row[header[i]] = typedCell(cell)
+		}
+		q.add(row, resp)
+	}
+
+	if len(q.req.Select) > 0 {
+		resp.Columns = q.req.Select
+	} else {
+		resp.Columns = header
+	}
+	return nil
+}
+
+// isHeaderRow guesses whether record names the columns: every cell is
+// set, none is a number and no two are equal
+func isHeaderRow(record []string) bool {
+	seen := make(map[string]bool, len(record))
+	for _, cell := range record {
+		cell = strings.TrimSpace(cell)
+		if cell == "" || seen[cell] {
+			return false
+		}
+		if _, err := strconv.ParseFloat(cell, 64); err == nil {
+			return false
+		}
+		seen[cell] = true
+	}
+	return len(record) > 0
+}
+
+func columnNames(record []string, isHeader bool) []string {
+	names := make([]string, len(record))
+	for i, cell := range record {
+		names[i] = fmt.Sprintf("col%d", i+1)
+		if isHeader && strings.TrimSpace(cell) != "" {
+			names[i] = strings.TrimSpace(cell)
+		}
+	}
+	return names
+}
+
+// typedCell reads a cell holding a number or boolean as one; any other
+// cell, and numbers with leading zeros such as zip codes, stay strings
+func typedCell(cell string) interface{} {
+	trimmed := strings.TrimSpace(cell)
+	switch trimmed {
+	case "true", "TRUE", "True":
+		return true
+	case "false", "FALSE", "False":
+		return false
+	}
+	digits := strings.TrimPrefix(trimmed, "-")
+	if len(digits) > 1 && digits[0] == '0' && digits[1] != '.' {
+		return cell
+	}
+	if n, err := strconv.ParseInt(trimmed, 10, 64); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(trimmed, 64); err == nil && !strings.ContainsAny(trimmed, "nNiI") {
+		return f
+	}
+	return cell
+}
+
+// columnsOf lists the keys of rows in first-seen order, or the selected
+// fields when there are some
+func columnsOf(rows []map[string]interface{}, selected []string) []string {
+	if len(selected) > 0 {
+		return selected
+	}
+	var columns []string
+	seen := map[string]bool{}
+	for _, row := range rows {
+		for _, key := range sortedKeys(row) {
+			if !seen[key] {
+				seen[key] = true
+				columns = append(columns, key)
+			}
+		}
+	}
+	return columns
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "number"
+}
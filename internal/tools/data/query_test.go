@@ -0,0 +1,143 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runQuery(t *testing.T, req QueryRequest) *QueryResponse {
+	t.Helper()
+	input, _ := json.Marshal(req)
+	result, err := (&QueryTool{}).Execute(context.Background(), input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return result.(*QueryResponse)
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestQueryCSV(t *testing.T) {
+	path := writeFile(t, "users.csv", "name,age,status,zip\nAna,34,active,01310\nRui,28,active,20040\nEva,41,inactive,30110\nLia,39,active,40020\n")
+
+	resp := runQuery(t, QueryRequest{Path: path, Where: []string{"age > 30", "status == active"}, Select: []string{"name", "zip"}})
+	if resp.Format != "csv" || resp.Total != 2 || resp.Returned != 2 || resp.Truncated {
+		t.Fatalf("expected two matching rows, got %+v", resp)
+	}
+	if resp.Rows[0]["name"] != "Ana" || resp.Rows[0]["zip"] != "01310" || resp.Rows[1]["name"] != "Lia" {
+		t.Errorf("expected Ana and Lia with zip codes kept as strings, got %v", resp.Rows)
+	}
+	if len(resp.Rows[0]) != 2 || strings.Join(resp.Columns, ",") != "name,zip" {
+		t.Errorf("expected only the selected columns, got %v %v", resp.Columns, resp.Rows[0])
+	}
+
+	paged := runQuery(t, QueryRequest{Path: path, Limit: 1, Offset: 1})
+	if paged.Total != 4 || paged.Returned != 1 || !paged.Truncated || paged.Rows[0]["name"] != "Rui" || paged.Rows[0]["age"] != int64(28) {
+		t.Errorf("expected the second row of four, typed, got %+v", paged)
+	}
+
+	headless := writeFile(t, "points.csv", "1,2\n3,4\n")
+	resp = runQuery(t, QueryRequest{Path: headless, Where: []string{"col2 >= 4"}})
+	if strings.Join(resp.Columns, ",") != "col1,col2" || resp.Total != 1 || resp.Rows[0]["col1"] != int64(3) {
+		t.Errorf("expected a numeric first row to be data, got %+v", resp)
+	}
+}
+
+func TestQueryJSON(t *testing.T) {
+	path := writeFile(t, "data.json", `{"users": [
+		{"name": "ana", "tags": ["go", "sql"], "address": {"city": "Recife"}},
+		{"name": "rui", "tags": ["js"], "address": {"city": "Porto"}},
+		{"name": "eva", "tags": ["go"]}
+	], "meta": {"version": 3}}`)
+
+	resp := runQuery(t, QueryRequest{Path: path, Query: "$.users", Where: []string{"tags contains go"}, Select: []string{"name", "address.city"}})
+	if resp.Total != 2 || resp.Rows[0]["name"] != "ana" || resp.Rows[0]["address.city"] != "Recife" {
+		t.Errorf("expected ana and eva, with the nested city, got %+v", resp.Rows)
+	}
+	if _, ok := resp.Rows[1]["address.city"]; ok {
+		t.Errorf("expected a missing field to be left out, got %v", resp.Rows[1])
+	}
+
+	resp = runQuery(t, QueryRequest{Path: path, Query: `$["meta"].version`})
+	if resp.Result != float64(3) || resp.Rows != nil {
+		t.Errorf("expected the scalar as result, got %+v", resp)
+	}
+	resp = runQuery(t, QueryRequest{Path: path, Query: "users[-1].name"})
+	if resp.Result != "eva" {
+		t.Errorf("expected a negative index from the end, got %+v", resp.Result)
+	}
+	resp = runQuery(t, QueryRequest{Path: path, Query: "$.users[*].address.city"})
+	if resp.Total != 2 || resp.Rows[1]["value"] != "Porto" {
+		t.Errorf("expected a wildcard to fan out into rows, got %+v", resp.Rows)
+	}
+	resp = runQuery(t, QueryRequest{Path: path, Query: "$.missing"})
+	if resp.Total != 0 || resp.Result != nil {
+		t.Errorf("expected nothing for a missing key, got %+v", resp)
+	}
+}
+
+func TestQueryYAMLAndJSONL(t *testing.T) {
+	yaml := writeFile(t, "config.yml", "database:\n  pool:\n    max: 20\nservices:\n  - name: api\n    port: 8080\n  - name: web\n    port: 3000\n")
+	resp := runQuery(t, QueryRequest{Path: yaml, Query: "$.database.pool.max"})
+	if resp.Format != "yaml" || resp.Result != int64(20) {
+		t.Errorf("expected the pool size, got %+v", resp)
+	}
+	resp = runQuery(t, QueryRequest{Path: yaml, Query: "services", Where: []string{"port < 5000"}})
+	if resp.Total != 1 || resp.Rows[0]["name"] != "web" {
+		t.Errorf("expected the web service, got %+v", resp.Rows)
+	}
+
+	stream := writeFile(t, "manifests.yaml", "kind: Service\nname: api\n---\nkind: Deployment\nname: api\n")
+	input, _ := json.Marshal(QueryRequest{Path: stream, Query: "kind"})
+	if _, err := (&QueryTool{}).Execute(context.Background(), input); err == nil || !strings.Contains(err.Error(), "multi-document") {
+		t.Errorf("expected multi-document YAML to be rejected, got %v", err)
+	}
+
+	jsonl := writeFile(t, "events.jsonl", "{\"level\": \"error\", \"msg\": \"disk full\"}\n\n{\"level\": \"info\", \"msg\": \"ok\"}\n{\"level\": \"error\", \"msg\": \"timeout\"}\n")
+	resp = runQuery(t, QueryRequest{Path: jsonl, Where: []string{`level == "error"`, "msg =~ ^t"}})
+	if resp.Total != 1 || resp.Rows[0]["msg"] != "timeout" {
+		t.Errorf("expected the timeout error, got %+v", resp.Rows)
+	}
+}
+
+func TestQueryBounds(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("{")
+	for i := 0; i < 5000; i++ {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(&b, `"key%04d": "%s"`, i, strings.Repeat("v", 100))
+	}
+	b.WriteString("}")
+	path := writeFile(t, "big.json", b.String())
+
+	resp := runQuery(t, QueryRequest{Path: path})
+	if !resp.Truncated || resp.Result != nil || len(resp.Keys) == 0 || len(resp.Keys) > maxResultKeys {
+		t.Errorf("expected an oversized object to be summarized by its keys, got truncated=%v keys=%d", resp.Truncated, len(resp.Keys))
+	}
+
+	for _, req := range []QueryRequest{
+		{Path: path, Format: "xml"},
+		{Path: filepath.Join(filepath.Dir(path), "notes.txt")},
+		{Path: path, Query: "$.a[x]"},
+		{Path: path, Where: []string{"no operator"}},
+	} {
+		input, _ := json.Marshal(req)
+		if _, err := (&QueryTool{}).Execute(context.Background(), input); err == nil {
+			t.Errorf("expected an error for %+v", req)
+		}
+	}
+}
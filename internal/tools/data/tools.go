@@ -0,0 +1,11 @@
+// Package data holds tools that query structured data files: CSV, TSV,
+// JSON, JSON Lines and YAML.
+package data
+
+import "github.com/alucardeht/may-la-mcp/internal/tools"
+
+func GetTools() []tools.Tool {
+	return []tools.Tool{
+		&QueryTool{},
+	}
+}
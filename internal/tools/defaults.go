@@ -0,0 +1,224 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/alucardeht/may-la-mcp/internal/miniyaml"
+)
+
+// ToolDefaultsFile holds a project's tool defaults, relative to its root
+const ToolDefaultsFile = ".mayla/tooldefaults.yaml"
+
+// allToolsSection is the section of the defaults file that applies to every
+// tool taking the parameter
+const allToolsSection = "all"
+
+// ToolDefaults are the parameter values a project wants when a call leaves
+// them out, by tool and then by parameter:
+//
+//	all:
+//	  max_results: 200
+//	search:
+//	  exclude: ["vendor/**", "*.min.js"]
+//	edit:
+//	  match_mode: ignore_whitespace
+//	read:
+//	  limit: 400
+//
+// Tools may be named by any name the registry answers to. Parameter names
+// match the tool's schema ignoring case, underscores and dashes.
+type ToolDefaults map[string]map[string]interface{}
+
+// LoadToolDefaults reads the tool defaults of the project at root; a
+// project without the file has none
+func LoadToolDefaults(root string) (ToolDefaults, error) {
+	data, err := os.ReadFile(filepath.Join(root, ToolDefaultsFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return ToolDefaults{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", ToolDefaultsFile, err)
+	}
+
+	parsed, err := miniyaml.Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", ToolDefaultsFile, err)
+	}
+	sections, ok := parsed.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid %s: expected a mapping of tools", ToolDefaultsFile)
+	}
+
+	defaults := ToolDefaults{}
+	for tool, section := range sections {
+		switch params := section.(type) {
+		case map[string]interface{}:
+			defaults[tool] = params
+		case nil:
+		default:
+			return nil, fmt.Errorf("invalid %s: %s must be a mapping of parameters", ToolDefaultsFile, tool)
+		}
+	}
+	return defaults, nil
+}
+
+// SetDefaults replaces the defaults merged into the arguments of every
+// later call. A tool's own section wins over the all section, and explicit
+// arguments win over both. Defaults naming an unknown tool or parameter, or
+// of the wrong type, are left out and reported in the error.
+func (r *Registry) SetDefaults(defaults ToolDefaults) error {
+	resolved := map[string]map[string]json.RawMessage{}
+	var problems []error
+
+	add := func(tool Tool, key string, value interface{}, quiet bool) {
+		param, schemaType, ok := schemaParam(tool, key)
+		if !ok {
+			if !quiet {
+				problems = append(problems, fmt.Errorf("%s has no parameter %q", tool.Name(), key))
+			}
+			return
+		}
+		if !matchesSchemaType(value, schemaType) {
+			problems = append(problems, fmt.Errorf("%s.%s must be of type %s", tool.Name(), param, schemaType))
+			return
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("%s.%s: %w", tool.Name(), param, err))
+			return
+		}
+		if resolved[tool.Name()] == nil {
+			resolved[tool.Name()] = map[string]json.RawMessage{}
+		}
+		resolved[tool.Name()][param] = data
+	}
+
+	for _, name := range sortedKeys(defaults) {
+		if name == allToolsSection {
+			continue
+		}
+		tool, ok := r.Get(name)
+		if !ok {
+			problems = append(problems, fmt.Errorf("unknown tool %q", name))
+			continue
+		}
+		for _, key := range sortedKeys(defaults[name]) {
+			add(tool, key, defaults[name][key], false)
+		}
+	}
+
+	if all := defaults[allToolsSection]; len(all) > 0 {
+		for _, tool := range r.List() {
+			for _, key := range sortedKeys(all) {
+				if param, _, ok := schemaParam(tool, key); ok {
+					if _, set := resolved[tool.Name()][param]; set {
+						continue
+					}
+				}
+				add(tool, key, all[key], true)
+			}
+		}
+	}
+
+	r.mu.Lock()
+	r.defaults = resolved
+	r.mu.Unlock()
+	return errors.Join(problems...)
+}
+
+// withDefaults merges the defaults of the named tool into input, under the
+// arguments it already has. Input that is not an object is left for the
+// tool to reject.
+func (r *Registry) withDefaults(name string, input json.RawMessage) json.RawMessage {
+	r.mu.RLock()
+	defaults := r.defaults[name]
+	r.mu.RUnlock()
+	if len(defaults) == 0 {
+		return input
+	}
+
+	args := map[string]json.RawMessage{}
+	if trimmed := bytes.TrimSpace(input); len(trimmed) > 0 && !bytes.Equal(trimmed, []byte("null")) {
+		if err := json.Unmarshal(trimmed, &args); err != nil {
+			return input
+		}
+	}
+
+	changed := false
+	for key, value := range defaults {
+		if _, ok := args[key]; !ok {
+			args[key] = value
+			changed = true
+		}
+	}
+	if !changed {
+		return input
+	}
+	merged, err := json.Marshal(args)
+	if err != nil {
+		return input
+	}
+	return merged
+}
+
+// schemaParam finds the top-level parameter of tool's schema that key
+// names, returning its name and JSON schema type
+func schemaParam(tool Tool, key string) (string, string, bool) {
+	var schema struct {
+		Properties map[string]struct {
+			Type string `json:"type"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(tool.Schema(), &schema); err != nil {
+		return "", "", false
+	}
+	if prop, ok := schema.Properties[key]; ok {
+		return key, prop.Type, true
+	}
+	for name, prop := range schema.Properties {
+		if foldParam(name) == foldParam(key) {
+			return name, prop.Type, true
+		}
+	}
+	return "", "", false
+}
+
+func foldParam(name string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(name))
+}
+
+// matchesSchemaType reports whether a parsed YAML value can stand for a
+// parameter of the JSON schema type; an untyped parameter takes anything
+func matchesSchemaType(value interface{}, schemaType string) bool {
+	switch value.(type) {
+	case string:
+		return schemaType == "string" || schemaType == ""
+	case bool:
+		return schemaType == "boolean" || schemaType == ""
+	case int64:
+		return schemaType == "integer" || schemaType == "number" || schemaType == ""
+	case float64:
+		return schemaType == "number" || schemaType == ""
+	case []interface{}:
+		return schemaType == "array" || schemaType == ""
+	case map[string]interface{}:
+		return schemaType == "object" || schemaType == ""
+	}
+	return schemaType == ""
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
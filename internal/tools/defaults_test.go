@@ -0,0 +1,106 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type echoTool struct {
+	name   string
+	schema string
+}
+
+func (t *echoTool) Name() string            { return t.name }
+func (t *echoTool) Description() string     { return t.name }
+func (t *echoTool) Schema() json.RawMessage { return json.RawMessage(t.schema) }
+func (t *echoTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	args := map[string]interface{}{}
+	if len(input) > 0 {
+		if err := json.Unmarshal(input, &args); err != nil {
+			return nil, err
+		}
+	}
+	return args, nil
+}
+
+func TestToolDefaults(t *testing.T) {
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, ".mayla"), 0755)
+	os.WriteFile(filepath.Join(root, ToolDefaultsFile), []byte(`# project defaults
+all:
+  max_results: 200
+search:
+  exclude:
+    - vendor/**
+    - "*.min.js"
+  max_results: 50
+edit:
+  match_mode: ignore_whitespace   # tolerate reindented code
+  fuzzy: yes
+read:
+  limit: "400"
+nope:
+  x: 1
+`), 0644)
+
+	defaults, err := LoadToolDefaults(root)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	r := NewRegistry()
+	r.RegisterIn("search", &echoTool{"search", `{"properties":{"pattern":{"type":"string"},"exclude":{"type":"array"},"max_results":{"type":"integer"}}}`})
+	r.RegisterIn("search", &echoTool{"find", `{"properties":{"max_results":{"type":"integer"}}}`})
+	r.RegisterIn("files", &echoTool{"edit", `{"properties":{"matchMode":{"type":"string"}}}`})
+	r.RegisterIn("files", &echoTool{"read", `{"properties":{"limit":{"type":"integer"}}}`})
+
+	err = r.SetDefaults(defaults)
+	for _, want := range []string{`unknown tool "nope"`, `edit has no parameter "fuzzy"`, "read.limit must be of type integer"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to mention %s, got %v", want, err)
+		}
+	}
+
+	run := func(name, input string) map[string]interface{} {
+		result, err := r.Execute(context.Background(), name, json.RawMessage(input))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		return result.(map[string]interface{})
+	}
+
+	search := run("search", `{"pattern":"x","max_results":5}`)
+	if search["max_results"] != float64(5) || search["pattern"] != "x" {
+		t.Errorf("expected explicit arguments to win, got %v", search)
+	}
+	if exclude, _ := search["exclude"].([]interface{}); len(exclude) != 2 || exclude[1] != "*.min.js" {
+		t.Errorf("expected the exclude default, got %v", search["exclude"])
+	}
+	if got := run("search", `{}`)["max_results"]; got != float64(50) {
+		t.Errorf("expected the tool's own default to win over all, got %v", got)
+	}
+	if got := run("search/find", ``)["max_results"]; got != float64(200) {
+		t.Errorf("expected the all default for find, got %v", got)
+	}
+	if got := run("edit", `{}`)["matchMode"]; got != "ignore_whitespace" {
+		t.Errorf("expected match_mode to set matchMode, got %v", got)
+	}
+	if got := run("read", `{}`); len(got) != 0 {
+		t.Errorf("expected the mistyped default to be left out, got %v", got)
+	}
+
+	if err := r.SetDefaults(ToolDefaults{}); err != nil {
+		t.Fatalf("clear: %v", err)
+	}
+	if got := run("edit", `{}`); len(got) != 0 {
+		t.Errorf("expected no defaults after clearing, got %v", got)
+	}
+
+	if defaults, err := LoadToolDefaults(t.TempDir()); err != nil || len(defaults) != 0 {
+		t.Errorf("expected no defaults without the file, got %v (%v)", defaults, err)
+	}
+}
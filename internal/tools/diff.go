@@ -0,0 +1,212 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// diffContext is the number of unchanged lines shown around each change
+	diffContext = 3
+	// maxDiffCells bounds the table used to find a minimal diff; larger
+	// changes are shown as one replaced block instead.
+	maxDiffCells = 4_000_000
+	// maxDiffBytes caps a single rendered diff
+	maxDiffBytes = 64 << 10
+)
+
+// FileDiff is a unified diff of one file's content before and after a change
+type FileDiff struct {
+	Path      string `json:"path"`
+	Diff      string `json:"diff"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// DiffFile renders the change from before to after as a unified diff.
+// created marks a file that does not exist yet, diffed against /dev/null.
+func DiffFile(path, before, after string, created bool) FileDiff {
+	oldName := path
+	if created {
+		oldName = "/dev/null"
+	}
+
+	diff := UnifiedDiff(oldName, path, before, after)
+	truncated := false
+	if len(diff) > maxDiffBytes {
+		cut := strings.LastIndexByte(diff[:maxDiffBytes], '\n') + 1
+		diff = diff[:cut]
+		truncated = true
+	}
+
+	return FileDiff{Path: path, Diff: diff, Truncated: truncated}
+}
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// UnifiedDiff returns the unified diff between before and after, or an
+// empty string when they are equal.
+func UnifiedDiff(oldName, newName, before, after string) string {
+	if before == after {
+		return ""
+	}
+
+	ops := diffLines(splitLines(before), splitLines(after))
+
+	// oldPos and newPos hold the number of old and new lines preceding op i
+	oldPos := make([]int, len(ops)+1)
+	newPos := make([]int, len(ops)+1)
+	for i, op := range ops {
+		oldPos[i+1], newPos[i+1] = oldPos[i], newPos[i]
+		if op.kind != '+' {
+			oldPos[i+1]++
+		}
+		if op.kind != '-' {
+			newPos[i+1]++
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+
+		// Extend the hunk over every change separated by at most two
+		// contexts' worth of unchanged lines
+		last := i
+		for {
+			next := last + 1
+			for next < len(ops) && ops[next].kind == ' ' {
+				next++
+			}
+			if next == len(ops) || next-last-1 > 2*diffContext {
+				break
+			}
+			last = next
+		}
+
+		start := max(i-diffContext, 0)
+		stop := min(last+1+diffContext, len(ops))
+
+		oldCount := oldPos[stop] - oldPos[start]
+		newCount := newPos[stop] - newPos[start]
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(oldPos[start], oldCount), hunkRange(newPos[start], newCount))
+
+		for _, op := range ops[start:stop] {
+			sb.WriteByte(op.kind)
+			sb.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				sb.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+
+		i = stop
+	}
+
+	return sb.String()
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// splitLines splits s into lines that keep their trailing newline
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines computes a line-level edit script from a to b. Common leading
+// and trailing lines are matched directly and the rest with a longest
+// common subsequence table.
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+
+	midA := a[prefix : len(a)-suffix]
+	midB := b[prefix : len(b)-suffix]
+	if len(midA)*len(midB) > maxDiffCells {
+		for _, line := range midA {
+			ops = append(ops, diffOp{'-', line})
+		}
+		for _, line := range midB {
+			ops = append(ops, diffOp{'+', line})
+		}
+	} else {
+		ops = append(ops, lcsDiff(midA, midB)...)
+	}
+
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops
+}
+
+func lcsDiff(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	width := m + 1
+	// lcs[i*width+j] is the length of the LCS of a[i:] and b[j:]
+	lcs := make([]int32, (n+1)*width)
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i*width+j] = lcs[(i+1)*width+j+1] + 1
+			} else {
+				lcs[i*width+j] = max(lcs[(i+1)*width+j], lcs[i*width+j+1])
+			}
+		}
+	}
+
+	ops := make([]diffOp, 0, n+m)
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[(i+1)*width+j] >= lcs[i*width+j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
@@ -0,0 +1,29 @@
+package tools
+
+import "testing"
+
+func TestUnifiedDiff(t *testing.T) {
+	before := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	after := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\n"
+
+	want := "--- old\n+++ new\n" +
+		"@@ -1,5 +1,5 @@\n a\n-b\n+B\n c\n d\n e\n" +
+		"@@ -8,3 +8,4 @@\n h\n i\n j\n+k\n"
+	if got := UnifiedDiff("old", "new", before, after); got != want {
+		t.Errorf("diff mismatch\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUnifiedDiffCreatedFile(t *testing.T) {
+	got := DiffFile("/tmp/x", "", "one\ntwo", true)
+	want := "--- /dev/null\n+++ /tmp/x\n@@ -0,0 +1,2 @@\n+one\n+two\n\\ No newline at end of file\n"
+	if got.Diff != want {
+		t.Errorf("diff mismatch\ngot:\n%s\nwant:\n%s", got.Diff, want)
+	}
+}
+
+func TestUnifiedDiffEqual(t *testing.T) {
+	if got := UnifiedDiff("a", "b", "same\n", "same\n"); got != "" {
+		t.Errorf("expected empty diff, got %q", got)
+	}
+}
@@ -0,0 +1,466 @@
+package docs
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/alucardeht/may-la-mcp/internal/index"
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+func writeDocs(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for rel, content := range files {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+var projectDocs = map[string]string{
+	".gitignore":          "/build/\n*.draft.md\n",
+	"README.md":           "# Shop\n\nSells things.\n",
+	"docs/setup.md":       "# Setup Guide\n\nIntro.\n\n## Install the CLI\n\nRun the installer, then restart the daemon.\n\n## Install the CLI\n\nAgain.\n",
+	"docs/notes.txt":      "Release notes\n\nNothing yet.\n",
+	"docs/.gitignore":     "private/\n",
+	"docs/private/key.md": "# Secret\n",
+	"build/out.md":        "# Generated\n",
+	"plan.draft.md":       "# Draft\n",
+	"main.go":             "package main\n",
+}
+
+func TestDocList(t *testing.T) {
+	root := t.TempDir()
+	writeDocs(t, root, projectDocs)
+
+	input, _ := json.Marshal(DocListRequest{ProjectRoot: root})
+	result, err := (&DocListTool{}).Execute(context.Background(), input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	titles := map[string]string{}
+	for _, doc := range result.(*DocListResponse).Docs {
+		titles[doc.Path] = doc.Title
+	}
+	want := map[string]string{"README.md": "Shop", "docs/setup.md": "Setup Guide", "docs/notes.txt": "Release notes"}
+	if len(titles) != len(want) {
+		t.Errorf("expected %v without ignored files, got %v", want, titles)
+	}
+	for path, title := range want {
+		if titles[path] != title {
+			t.Errorf("%s: expected title %q, got %q", path, title, titles[path])
+		}
+	}
+
+	input, _ = json.Marshal(DocListRequest{ProjectRoot: root, Path: "docs"})
+	result, err = (&DocListTool{}).Execute(context.Background(), input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp := result.(*DocListResponse); resp.Count != 2 {
+		t.Errorf("expected the 2 docs under docs/, got %+v", resp.Docs)
+	}
+}
+
+func TestDocSearch(t *testing.T) {
+	root := t.TempDir()
+	writeDocs(t, root, projectDocs)
+
+	store, err := index.NewIndexStore(filepath.Join(t.TempDir(), "index.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	search := func(tool *DocSearchTool, query string) *DocSearchResponse {
+		input, _ := json.Marshal(DocSearchRequest{Query: query, ProjectRoot: root})
+		result, err := tool.Execute(context.Background(), input)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return result.(*DocSearchResponse)
+	}
+
+	// Nothing indexed yet, so both tools scan the files
+	for _, tool := range []*DocSearchTool{NewDocSearchTool(nil), NewDocSearchTool(store)} {
+		resp := search(tool, "restart installer")
+		if resp.Source != docSourceScan || resp.Count != 1 {
+			t.Fatalf("expected one scanned match, got %+v", resp)
+		}
+		if m := resp.Matches[0]; m.Link != "docs/setup.md#install-the-cli" || m.Line != 5 || !strings.Contains(m.Snippet, "**installer**") {
+			t.Errorf("unexpected match %+v", m)
+		}
+		if resp := search(tool, "secret"); resp.Count != 0 {
+			t.Errorf("expected ignored docs to be skipped, got %+v", resp.Matches)
+		}
+	}
+
+	path := filepath.Join(root, "docs", "setup.md")
+	id, err := store.UpsertFile(&index.IndexedFile{Path: path, Language: "markdown", Status: index.StatusIndexed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.InsertDocSections(id, index.SplitMarkdown(projectDocs["docs/setup.md"])); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := search(NewDocSearchTool(store), "again")
+	if resp.Source != docSourceIndex || resp.Count != 1 {
+		t.Fatalf("expected one indexed match, got %+v", resp)
+	}
+	if m := resp.Matches[0]; m.Link != "docs/setup.md#install-the-cli-1" || m.Snippet != "**Again**." {
+		t.Errorf("expected the second heading's anchor and an FTS snippet, got %+v", m)
+	}
+}
+
+const guideDoc = `# Guide
+
+Intro.
+
+## Setup
+
+Before you start.
+
+### Install
+
+Run the installer.
+
+## Usage
+
+### Install
+
+Nothing to install.
+`
+
+func TestDocSectionRead(t *testing.T) {
+	root := t.TempDir()
+	writeDocs(t, root, map[string]string{"guide.md": guideDoc})
+
+	read := func(heading string, subsections bool) (*DocSectionResponse, error) {
+		input, _ := json.Marshal(DocSectionReadRequest{Path: "guide.md", Heading: heading, IncludeSubsections: &subsections, ProjectRoot: root})
+		result, err := (&DocSectionReadTool{}).Execute(context.Background(), input)
+		if err != nil {
+			return nil, err
+		}
+		return result.(*DocSectionResponse), nil
+	}
+
+	resp, err := read("setup", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Content != "Before you start.\n\n### Install\n\nRun the installer." || resp.LineStart != 5 || resp.LineEnd != 12 {
+		t.Errorf("expected Setup with its subsection, got %+v", resp)
+	}
+
+	if resp, err = read("Setup", false); err != nil || resp.Content != "Before you start." {
+		t.Errorf("expected only the text before the subsection, got %+v (%v)", resp, err)
+	}
+
+	if _, err = read("Install", true); err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Errorf("expected an ambiguous heading error, got %v", err)
+	}
+	resp, err = read("Usage > Install", true)
+	if err != nil || resp.Content != "Nothing to install." || strings.Join(resp.HeadingPath, " > ") != "Guide > Usage > Install" {
+		t.Errorf("expected the Install under Usage, got %+v (%v)", resp, err)
+	}
+	if resp, err = read("#install-1", true); err != nil || resp.LineStart != 15 {
+		t.Errorf("expected the second Install by anchor, got %+v (%v)", resp, err)
+	}
+
+	if _, err = read("Missing", true); err == nil || !strings.Contains(err.Error(), `"Guide > Setup"`) {
+		t.Errorf("expected a not found error listing the headings, got %v", err)
+	}
+}
+
+func TestDocSectionWrite(t *testing.T) {
+	root := t.TempDir()
+
+	write := func(req DocSectionWriteRequest) string {
+		t.Helper()
+		writeDocs(t, root, map[string]string{"guide.md": guideDoc})
+		req.Path, req.ProjectRoot = "guide.md", root
+		input, _ := json.Marshal(req)
+		if _, err := (&DocSectionWriteTool{}).Execute(context.Background(), input); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		data, err := os.ReadFile(filepath.Join(root, "guide.md"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	got := write(DocSectionWriteRequest{Heading: "Setup", Content: "Just run it.\n"})
+	want := "# Guide\n\nIntro.\n\n## Setup\n\nJust run it.\n\n## Usage\n\n### Install\n\nNothing to install.\n"
+	if got != want {
+		t.Errorf("replacing with subsections:\nwant %q\ngot  %q", want, got)
+	}
+
+	no := false
+	got = write(DocSectionWriteRequest{Heading: "Setup", Content: "Read this first.", IncludeSubsections: &no})
+	if !strings.Contains(got, "## Setup\n\nRead this first.\n\n### Install\n\nRun the installer.\n") {
+		t.Errorf("expected the subsection to be kept, got %q", got)
+	}
+
+	got = write(DocSectionWriteRequest{Heading: "Usage > Install", Content: ""})
+	if !strings.HasSuffix(got, "## Usage\n\n### Install\n") {
+		t.Errorf("expected an emptied last section, got %q", got)
+	}
+
+	got = write(DocSectionWriteRequest{Heading: "Setup > Configure", Content: "Edit the config.", Create: true})
+	if !strings.Contains(got, "Run the installer.\n\n### Configure\n\nEdit the config.\n\n## Usage\n") {
+		t.Errorf("expected Configure at the end of Setup, got %q", got)
+	}
+
+	got = write(DocSectionWriteRequest{Heading: "FAQ", Content: "None yet.", Create: true})
+	if !strings.HasSuffix(got, "Nothing to install.\n\n## FAQ\n\nNone yet.\n") {
+		t.Errorf("expected FAQ at the end of the document, got %q", got)
+	}
+
+	writeDocs(t, root, map[string]string{"guide.md": guideDoc})
+	input, _ := json.Marshal(DocSectionWriteRequest{Path: "guide.md", Heading: "FAQ", Content: "x", ProjectRoot: root})
+	if _, err := (&DocSectionWriteTool{}).Execute(context.Background(), input); err == nil {
+		t.Error("expected a missing section to be an error without create")
+	}
+}
+
+func TestDocTOC(t *testing.T) {
+	root := t.TempDir()
+	writeDocs(t, root, map[string]string{"guide.md": guideDoc})
+
+	toc := func(update bool) *DocTOCResponse {
+		t.Helper()
+		input, _ := json.Marshal(DocTOCRequest{Path: "guide.md", Update: update, ProjectRoot: root})
+		result, err := (&DocTOCTool{}).Execute(context.Background(), input)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return result.(*DocTOCResponse)
+	}
+
+	want := "- [Setup](#setup)\n  - [Install](#install)\n- [Usage](#usage)\n  - [Install](#install-1)"
+	if resp := toc(false); resp.TOC != want || resp.Entries != 4 || resp.Updated {
+		t.Errorf("unexpected table of contents %+v", resp)
+	}
+
+	if resp := toc(true); !resp.Updated {
+		t.Error("expected the table to be inserted")
+	}
+	data, _ := os.ReadFile(filepath.Join(root, "guide.md"))
+	if !strings.HasPrefix(string(data), "# Guide\n\nIntro.\n\n"+tocStart+"\n"+want+"\n"+tocEnd+"\n\n## Setup\n") {
+		t.Errorf("expected the table before the first listed heading, got %q", data)
+	}
+
+	if resp := toc(true); resp.Updated {
+		t.Error("expected an up to date table to be left alone")
+	}
+
+	edited := strings.Replace(string(data), "## Usage", "## Running", 1)
+	writeDocs(t, root, map[string]string{"guide.md": edited})
+	if resp := toc(true); !resp.Updated {
+		t.Error("expected the table to be refreshed")
+	}
+	data, _ = os.ReadFile(filepath.Join(root, "guide.md"))
+	if strings.Count(string(data), tocStart) != 1 || !strings.Contains(string(data), "- [Running](#running)") {
+		t.Errorf("expected the table replaced in place, got %q", data)
+	}
+}
+
+const adrDoc = `---
+# decision record
+title: Use SQLite
+status: proposed
+tags: [storage, "index"]
+authors:
+  - ana
+  - bo
+review:
+  by: ana
+  rounds: 2
+notes: |
+  First line.
+  Second line.
+---
+# Use SQLite
+
+Body text.
+`
+
+func TestFrontmatter(t *testing.T) {
+	fm, body := splitFrontmatter(adrDoc)
+	if fm == nil || body != "# Use SQLite\n\nBody text.\n" {
+		t.Fatalf("expected the block split from the body, got %q", body)
+	}
+
+	fields := fm.Fields()
+	got, _ := json.Marshal(fields)
+	want := `{"authors":["ana","bo"],"notes":"First line.\nSecond line.\n","review":{"by":"ana","rounds":2},"status":"proposed","tags":["storage","index"],"title":"Use SQLite"}`
+	if string(got) != want {
+		t.Errorf("unexpected fields\nwant %s\ngot  %s", want, got)
+	}
+
+	patched := applyFrontmatter(adrDoc, map[string]interface{}{
+		"status":  "accepted",
+		"authors": nil,
+		"review":  map[string]interface{}{"rounds": 3.0},
+		"date":    "2024-05-01",
+	})
+	want = "---\n# decision record\ntitle: Use SQLite\nstatus: accepted\ntags: [storage, \"index\"]\nreview:\n  by: ana\n  rounds: 3\nnotes: |\n  First line.\n  Second line.\ndate: 2024-05-01\n---\n# Use SQLite\n\nBody text.\n"
+	if patched != want {
+		t.Errorf("patch should only touch patched keys\nwant %q\ngot  %q", want, patched)
+	}
+
+	added := applyFrontmatter("# Title\n", map[string]interface{}{"title": "Yes: no", "draft": true})
+	if added != "---\ndraft: true\ntitle: \"Yes: no\"\n---\n\n# Title\n" {
+		t.Errorf("expected a new block above the body, got %q", added)
+	}
+	if fm, _ := splitFrontmatter(added); fm.Fields()["title"] != "Yes: no" {
+		t.Errorf("expected the quoted title to read back, got %v", fm.Fields())
+	}
+
+	if title := index.MarkdownTitle(adrDoc); title != "Use SQLite" {
+		t.Errorf("expected the frontmatter comment not to be taken as a heading, got %q", title)
+	}
+}
+
+func TestDocWriteFrontmatter(t *testing.T) {
+	root := t.TempDir()
+	writeDocs(t, root, map[string]string{
+		"docs/adr/001.md":  adrDoc,
+		".mayla/docs.json": `{"frontmatter": {"required": {"docs/adr/*.md": ["title", "status"], "docs/**": ["owner"]}}}`,
+	})
+
+	run := func(tool tools.Tool, req map[string]interface{}) (interface{}, error) {
+		req["project_root"] = root
+		input, _ := json.Marshal(req)
+		result, err := tool.Execute(context.Background(), input)
+		if err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+
+	result, err := run(&DocReadTool{}, map[string]interface{}{"path": "docs/adr/001.md"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp := result.(*DocReadResponse)
+	if resp.Frontmatter["status"] != "proposed" {
+		t.Errorf("expected parsed frontmatter, got %v", resp.Frontmatter)
+	}
+	if missing := resp.MissingFrontmatter; !reflect.DeepEqual(missing, []string{"owner"}) {
+		t.Errorf("expected owner to be reported missing, got %v", missing)
+	}
+
+	_, err = run(&DocWriteTool{}, map[string]interface{}{"path": "docs/adr/001.md", "frontmatter": map[string]interface{}{"status": "accepted"}})
+	if err == nil || !strings.Contains(err.Error(), "owner") {
+		t.Fatalf("expected the missing owner to reject the write, got %v", err)
+	}
+
+	if _, err = run(&DocWriteTool{}, map[string]interface{}{"path": "docs/adr/001.md", "frontmatter": map[string]interface{}{"status": "accepted", "owner": "ana"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(root, "docs", "adr", "001.md"))
+	if !strings.Contains(string(data), "status: accepted\n") || !strings.Contains(string(data), "owner: ana\n---\n# Use SQLite\n\nBody text.\n") {
+		t.Errorf("expected the frontmatter patched and the body untouched, got %q", data)
+	}
+
+	if _, err = run(&DocWriteTool{}, map[string]interface{}{"path": "README.md", "content": "# Shop\n"}); err != nil {
+		t.Errorf("expected docs outside the rules to be written freely, got %v", err)
+	}
+}
+
+func TestDocCheckLinks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	root := t.TempDir()
+	writeDocs(t, root, map[string]string{
+		"README.md": "# Shop\n\nSee [setup](docs/setup.md#install-the-cli), [usage](docs/usage.md) and [intro](#shop).\n" +
+			"Broken: [anchor](docs/setup.md#missing), [self](#nope), [dir](docs/).\n\n" +
+			"`[code](nowhere.md)`\n\n```\n[fenced](nowhere.md)\n```\n\n" +
+			"[ok](" + server.URL + "/ok) [gone](" + server.URL + "/gone) [mail](mailto:a@b.c)\n\n[ref]: docs/old.md\n",
+		"docs/setup.md": projectDocs["docs/setup.md"] + "\n![logo](../img/logo.png)\n",
+	})
+
+	check := func(external bool) *DocCheckLinksResponse {
+		t.Helper()
+		input, _ := json.Marshal(DocCheckLinksRequest{ProjectRoot: root, External: external})
+		result, err := (&DocCheckLinksTool{}).Execute(context.Background(), input)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return result.(*DocCheckLinksResponse)
+	}
+
+	resp := check(false)
+	broken := map[string][]string{}
+	for _, file := range resp.Files {
+		for _, link := range file.Broken {
+			broken[file.Path] = append(broken[file.Path], link.Link)
+		}
+	}
+	want := map[string][]string{
+		"README.md":     {"docs/usage.md", "docs/setup.md#missing", "#nope", "docs/old.md"},
+		"docs/setup.md": {"../img/logo.png"},
+	}
+	if !reflect.DeepEqual(broken, want) {
+		t.Errorf("expected broken links %v, got %v", want, broken)
+	}
+	if resp.FilesChecked != 2 || resp.ExternalLinks != 2 {
+		t.Errorf("unexpected counts %+v", resp)
+	}
+
+	resp = check(true)
+	if resp.BrokenCount != 6 || resp.Files[0].Broken[3].Link != server.URL+"/gone" || resp.Files[0].Broken[3].Reason != "HTTP 404" {
+		t.Errorf("expected the 404 URL to be reported, got %+v", resp.Files)
+	}
+}
+
+func TestDocsDeniedPath(t *testing.T) {
+	root := t.TempDir()
+	writeDocs(t, root, map[string]string{
+		"README.md":        "# Shop\n\nSells things.\n",
+		".aws/credentials": "aws_secret_access_key = wJalrXUtnFEMI\n",
+		".aws/notes.md":    "# Keys\n\nThe secret rotation runbook.\n",
+		"docs/secret.md":   "# Rotation\n\nNothing secret here.\n",
+	})
+	denied := filepath.Join(root, ".aws")
+	tools.SetDeniedPaths([]string{denied})
+	defer tools.SetDeniedPaths(tools.DefaultDeniedPaths())
+
+	for _, path := range []string{filepath.Join(denied, "credentials"), ".aws/notes.md"} {
+		input, _ := json.Marshal(map[string]string{"path": path, "project_root": root})
+		if result, err := (&DocReadTool{}).Execute(context.Background(), input); err == nil {
+			t.Errorf("%s: expected the denied path to be refused, got %+v", path, result)
+		}
+	}
+
+	input, _ := json.Marshal(DocSearchRequest{Query: "secret", ProjectRoot: root})
+	result, err := NewDocSearchTool(nil).Execute(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range result.(*DocSearchResponse).Matches {
+		if strings.HasPrefix(m.Path, ".aws") {
+			t.Errorf("expected docs under the denied path to be skipped, got %+v", m)
+		}
+	}
+}
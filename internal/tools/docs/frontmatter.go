@@ -0,0 +1,243 @@
+package docs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+
+	"github.com/alucardeht/may-la-mcp/internal/index"
+	"github.com/alucardeht/may-la-mcp/internal/miniyaml"
+)
+
+// docRulesFile holds the per-project doc rules, relative to the project root
+const docRulesFile = ".mayla/docs.json"
+
+// frontmatterEntry is one top-level key of a frontmatter block with the raw
+// lines holding it, so keys a patch leaves alone are written back as they
+// were. Comments and blank lines between keys are entries without a key.
+type frontmatterEntry struct {
+	Key   string
+	Lines []string
+}
+
+// frontmatter is the YAML block at the top of a markdown document. Only
+// the YAML that frontmatter commonly holds is understood: scalars, flow and
+// block lists, nested mappings and | or > block scalars.
+type frontmatter struct {
+	open, close string
+	entries     []*frontmatterEntry
+}
+
+// splitFrontmatter separates the frontmatter of content from its body. It
+// returns a nil frontmatter and the whole content when there is none.
+func splitFrontmatter(content string) (*frontmatter, string) {
+	lines := strings.Split(content, "\n")
+	n := index.FrontmatterLines(lines)
+	if n == 0 {
+		return nil, content
+	}
+
+	fm := &frontmatter{open: lines[0], close: lines[n-1]}
+	var current *frontmatterEntry
+	for _, line := range lines[1 : n-1] {
+		trimmed := strings.TrimRight(line, " \t\r")
+		switch {
+		case trimmed == "" && current != nil:
+			current.Lines = append(current.Lines, line)
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+			fm.entries = append(fm.entries, &frontmatterEntry{Lines: []string{line}})
+			current = nil
+		case miniyaml.IsIndented(line) || strings.HasPrefix(trimmed, "-"):
+			if current == nil {
+				current = &frontmatterEntry{}
+				fm.entries = append(fm.entries, current)
+			}
+			current.Lines = append(current.Lines, line)
+		default:
+			key, _ := miniyaml.SplitMappingLine(trimmed)
+			current = &frontmatterEntry{Key: key, Lines: []string{line}}
+			fm.entries = append(fm.entries, current)
+		}
+	}
+	return fm, strings.Join(lines[n:], "\n")
+}
+
+// String renders the block back, delimiters included
+func (fm *frontmatter) String() string {
+	lines := []string{fm.open}
+	for _, e := range fm.entries {
+		lines = append(lines, e.Lines...)
+	}
+	return strings.Join(append(lines, fm.close), "\n")
+}
+
+// Fields returns the parsed keys; a repeated key keeps its last value
+func (fm *frontmatter) Fields() map[string]interface{} {
+	fields := map[string]interface{}{}
+	if fm == nil {
+		return fields
+	}
+	for _, e := range fm.entries {
+		if e.Key != "" {
+			fields[e.Key] = e.value()
+		}
+	}
+	return fields
+}
+
+// Patch applies patch as a JSON merge patch (RFC 7396): a null removes a
+// key, a mapping merges into a mapping and any other value replaces the
+// key. Only the keys patched are re-rendered.
+func (fm *frontmatter) Patch(patch map[string]interface{}) {
+	keys := make([]string, 0, len(patch))
+	for key := range patch {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		at := -1
+		for i, e := range fm.entries {
+			if e.Key == key {
+				at = i
+			}
+		}
+
+		value := patch[key]
+		if value == nil {
+			if at >= 0 {
+				fm.entries = append(fm.entries[:at], fm.entries[at+1:]...)
+			}
+			continue
+		}
+		if at < 0 {
+			fm.entries = append(fm.entries, &frontmatterEntry{Key: key, Lines: miniyaml.Render(key, mergePatch(nil, value), 0)})
+			continue
+		}
+		entry := fm.entries[at]
+		lines := miniyaml.Render(key, mergePatch(entry.value(), value), 0)
+		// keep the blank lines that separated the entry from the next one
+		for i := len(entry.Lines) - 1; i > 0 && strings.TrimSpace(entry.Lines[i]) == ""; i-- {
+			lines = append(lines, entry.Lines[i])
+		}
+		entry.Lines = lines
+	}
+}
+
+// applyFrontmatter patches the frontmatter of content, adding a block when
+// it has none, and leaves the body as it is
+func applyFrontmatter(content string, patch map[string]interface{}) string {
+	if len(patch) == 0 {
+		return content
+	}
+	fm, body := splitFrontmatter(content)
+	if fm == nil {
+		fm = &frontmatter{open: "---", close: "---"}
+		if body != "" {
+			body = "\n" + strings.TrimLeft(body, "\n")
+		}
+	}
+	fm.Patch(patch)
+	return fm.String() + "\n" + body
+}
+
+func mergePatch(target, patch interface{}) interface{} {
+	patchMap, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	merged := map[string]interface{}{}
+	if targetMap, ok := target.(map[string]interface{}); ok {
+		for k, v := range targetMap {
+			merged[k] = v
+		}
+	}
+	for k, v := range patchMap {
+		if v == nil {
+			delete(merged, k)
+		} else {
+			merged[k] = mergePatch(merged[k], v)
+		}
+	}
+	return merged
+}
+
+func (e *frontmatterEntry) value() interface{} {
+	_, inline := miniyaml.SplitMappingLine(strings.TrimRight(e.Lines[0], " \t\r"))
+	return miniyaml.ParseValue(inline, e.Lines[1:])
+}
+
+// docRules are the per-project rules for docs, read from .mayla/docs.json
+// in the project root:
+//
+//	{"frontmatter": {"required": {"docs/adr/*.md": ["title", "status"]}}}
+//
+// maps globs over paths relative to the root to the frontmatter keys the
+// markdown docs they match must have.
+type docRules struct {
+	Frontmatter struct {
+		Required map[string][]string `json:"required"`
+	} `json:"frontmatter"`
+}
+
+// loadDocRules reads the doc rules of the project at root; a project
+// without the file has no rules
+func loadDocRules(root string) (*docRules, error) {
+	rules := &docRules{}
+	data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(docRulesFile)))
+	if os.IsNotExist(err) {
+		return rules, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", docRulesFile, err)
+	}
+	if err := json.Unmarshal(data, rules); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", docRulesFile, err)
+	}
+	return rules, nil
+}
+
+// missingFrontmatter returns the required keys the doc at target lacks,
+// sorted; only markdown docs under root are checked
+func (r *docRules) missingFrontmatter(root, target, content string) []string {
+	if len(r.Frontmatter.Required) == 0 || !isMarkdown(target) {
+		return nil
+	}
+	rel, err := filepath.Rel(root, target)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return nil
+	}
+	rel = filepath.ToSlash(rel)
+
+	fm, _ := splitFrontmatter(content)
+	fields := fm.Fields()
+	seen := map[string]bool{}
+	var missing []string
+	for glob, keys := range r.Frontmatter.Required {
+		if ok, _ := doublestar.Match(glob, rel); !ok {
+			continue
+		}
+		for _, key := range keys {
+			if _, ok := fields[key]; !ok && !seen[key] {
+				seen[key] = true
+				missing = append(missing, key)
+			}
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+func isMarkdown(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".md", ".markdown", ".mdx":
+		return true
+	}
+	return false
+}
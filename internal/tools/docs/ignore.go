@@ -0,0 +1,75 @@
+package docs
+
+import (
+	"context"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/alucardeht/may-la-mcp/internal/gitignore"
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+// skippedDirs are never walked, ignored or not
+var skippedDirs = map[string]bool{
+	".git": true, "node_modules": true, ".hg": true, ".svn": true,
+}
+
+// walkDocs calls fn for every documentation file under root that no
+// .gitignore along the way ignores and the denylist allows, with its
+// slash-separated relative path
+func walkDocs(ctx context.Context, root string, fn func(rel, abs string, info fs.FileInfo) error) error {
+	rules := gitignore.Load(root, "")
+
+	return filepath.WalkDir(root, func(abs string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if abs == root {
+				return err
+			}
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if abs == root {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(root, abs)
+		if err != nil {
+			return nil
+		}
+		rel := filepath.ToSlash(relPath)
+
+		if d.IsDir() {
+			if skippedDirs[d.Name()] || rules.Ignored(rel, true) || tools.IsPathDenied(abs) {
+				return filepath.SkipDir
+			}
+			rules = append(rules, gitignore.Load(root, rel)...)
+			return nil
+		}
+		if !isDocFile(rel) || rules.Ignored(rel, false) || tools.IsPathDenied(abs) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			return nil
+		}
+		return fn(rel, abs, info)
+	})
+}
+
+// isDocFile reports whether rel is documentation: markdown anywhere, or
+// reStructuredText, AsciiDoc or plain text under a top-level docs directory
+func isDocFile(rel string) bool {
+	switch strings.ToLower(path.Ext(rel)) {
+	case ".md", ".markdown", ".mdx":
+		return true
+	case ".rst", ".adoc", ".txt":
+		top := strings.ToLower(strings.SplitN(rel, "/", 2)[0])
+		return strings.Contains(rel, "/") && (top == "docs" || top == "doc")
+	}
+	return false
+}
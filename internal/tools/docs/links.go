@@ -0,0 +1,380 @@
+package docs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/index"
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+const (
+	defaultLinkTimeout = 5 * time.Second
+
+	// linkCheckWorkers bounds the external URLs checked at once
+	linkCheckWorkers = 8
+)
+
+var (
+	// markdownLink matches inline links and images: [text](target "title")
+	markdownLink = regexp.MustCompile(`!?\[[^\]]*\]\(\s*<?([^)\s>]+)>?(?:\s+["'(][^)]*)?\)`)
+	// markdownRefDef matches reference definitions: [id]: target
+	markdownRefDef   = regexp.MustCompile(`^ {0,3}\[[^\]]+\]:\s*<?(\S+?)>?(?:\s+.*)?$`)
+	markdownAutoLink = regexp.MustCompile(`<(https?://[^>\s]+)>`)
+	// htmlAnchor matches the explicit anchors of HTML in markdown
+	htmlAnchor = regexp.MustCompile(`<a\s[^>]*?(?:name|id)\s*=\s*["']([^"']+)["']|\sid\s*=\s*["']([^"']+)["']`)
+	codeSpan   = regexp.MustCompile("`+[^`]*`+")
+)
+
+type DocCheckLinksRequest struct {
+	ProjectRoot string `json:"project_root,omitempty"`
+	Path        string `json:"path,omitempty"`
+	External    bool   `json:"external,omitempty"`
+	TimeoutMs   int    `json:"timeout_ms,omitempty"`
+}
+
+type DocBrokenLink struct {
+	Line   int    `json:"line"`
+	Link   string `json:"link"`
+	Reason string `json:"reason"`
+}
+
+type DocLinkFile struct {
+	Path   string          `json:"path"`
+	Broken []DocBrokenLink `json:"broken"`
+}
+
+type DocCheckLinksResponse struct {
+	Root          string        `json:"root"`
+	FilesChecked  int           `json:"files_checked"`
+	LinksChecked  int           `json:"links_checked"`
+	ExternalLinks int           `json:"external_links"`
+	BrokenCount   int           `json:"broken_count"`
+	Files         []DocLinkFile `json:"files"`
+}
+
+type DocCheckLinksTool struct{}
+
+func (t *DocCheckLinksTool) Name() string {
+	return "doc_check_links"
+}
+
+func (t *DocCheckLinksTool) Description() string {
+	return `Check the links in project documentation and report the broken ones,
+grouped by file.
+
+Scans the markdown files doc_list would list for inline links, images and
+reference definitions. Relative links must point at an existing file or
+directory, and their #anchor at a heading (or HTML id) of the target doc;
+#anchor alone is checked against the doc itself. With external, http(s)
+URLs are checked too with a HEAD request (GET when HEAD is refused) under
+timeout_ms; otherwise they are only counted.`
+}
+
+func (t *DocCheckLinksTool) Title() string {
+	return "Check Documentation Links"
+}
+
+func (t *DocCheckLinksTool) Annotations() map[string]bool {
+	annotations := tools.ReadOnlyAnnotations()
+	annotations["openWorldHint"] = true
+	return annotations
+}
+
+func (t *DocCheckLinksTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"project_root": {
+				"type": "string",
+				"description": "Project root (optional - defaults to current directory)"
+			},
+			"path": {
+				"type": "string",
+				"description": "Only check this doc, or the docs under this directory, relative to the project root (optional)"
+			},
+			"external": {
+				"type": "boolean",
+				"description": "Also check http(s) URLs over the network (default: false)"
+			},
+			"timeout_ms": {
+				"type": "integer",
+				"description": "Timeout of each external check in milliseconds (default: 5000)"
+			}
+		}
+	}`)
+}
+
+// docLink is a link found in a doc
+type docLink struct {
+	rel    string
+	line   int
+	target string
+}
+
+func (t *DocCheckLinksTool) OutputSchema() json.RawMessage {
+	return tools.OutputSchemaOf(DocCheckLinksResponse{})
+}
+
+func (t *DocCheckLinksTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var req DocCheckLinksRequest
+	if len(input) > 0 {
+		if err := json.Unmarshal(input, &req); err != nil {
+			return nil, fmt.Errorf("invalid request: %w", err)
+		}
+	}
+	timeout := defaultLinkTimeout
+	if req.TimeoutMs > 0 {
+		timeout = time.Duration(req.TimeoutMs) * time.Millisecond
+	}
+
+	root, err := docRoot(req.ProjectRoot)
+	if err != nil {
+		return nil, err
+	}
+	only := ""
+	if req.Path != "" {
+		target, err := resolveDocPath(req.Path, root)
+		if err != nil {
+			return nil, err
+		}
+		if rel, err := filepath.Rel(root, target); err == nil && rel != "." {
+			only = filepath.ToSlash(rel)
+		}
+	}
+
+	resp := &DocCheckLinksResponse{Root: root, Files: []DocLinkFile{}}
+	broken := map[string][]DocBrokenLink{}
+	anchors := newAnchorCache(root)
+	var external []docLink
+
+	err = walkDocs(ctx, root, func(rel, abs string, info fs.FileInfo) error {
+		if !isMarkdown(rel) || (only != "" && rel != only && !strings.HasPrefix(rel, only+"/")) {
+			return nil
+		}
+		data, err := os.ReadFile(abs)
+		if err != nil {
+			return nil
+		}
+		resp.FilesChecked++
+
+		for _, link := range extractLinks(rel, string(data)) {
+			resp.LinksChecked++
+			u, err := url.Parse(link.target)
+			switch {
+			case err != nil:
+				broken[rel] = append(broken[rel], DocBrokenLink{link.line, link.target, "malformed link"})
+			case u.Scheme == "http" || u.Scheme == "https":
+				resp.ExternalLinks++
+				external = append(external, link)
+			case u.Scheme != "" || strings.HasPrefix(link.target, "//"):
+				// mailto:, tel: and the like are not checked
+			default:
+				if reason := checkLocalLink(root, rel, u, anchors); reason != "" {
+					broken[rel] = append(broken[rel], DocBrokenLink{link.line, link.target, reason})
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if req.External && len(external) > 0 {
+		failed := checkExternalLinks(ctx, external, timeout)
+		for _, link := range external {
+			if reason, ok := failed[link.target]; ok {
+				broken[link.rel] = append(broken[link.rel], DocBrokenLink{link.line, link.target, reason})
+			}
+		}
+	}
+
+	for rel, links := range broken {
+		sort.SliceStable(links, func(i, j int) bool { return links[i].Line < links[j].Line })
+		resp.Files = append(resp.Files, DocLinkFile{Path: rel, Broken: links})
+		resp.BrokenCount += len(links)
+	}
+	sort.Slice(resp.Files, func(i, j int) bool { return resp.Files[i].Path < resp.Files[j].Path })
+	return resp, nil
+}
+
+// extractLinks lists the link targets of a markdown doc with their lines,
+// leaving out code blocks, code spans and frontmatter
+func extractLinks(rel, content string) []docLink {
+	lines := strings.Split(content, "\n")
+	var links []docLink
+	inFence := false
+	for i := index.FrontmatterLines(lines); i < len(lines); i++ {
+		line := lines[i]
+		if strings.HasPrefix(strings.TrimSpace(line), "```") || strings.HasPrefix(strings.TrimSpace(line), "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		line = codeSpan.ReplaceAllString(line, "")
+
+		if m := markdownRefDef.FindStringSubmatch(line); m != nil {
+			links = append(links, docLink{rel, i + 1, m[1]})
+			continue
+		}
+		for _, m := range markdownLink.FindAllStringSubmatch(line, -1) {
+			links = append(links, docLink{rel, i + 1, m[1]})
+		}
+		for _, m := range markdownAutoLink.FindAllStringSubmatch(line, -1) {
+			links = append(links, docLink{rel, i + 1, m[1]})
+		}
+	}
+	return links
+}
+
+// checkLocalLink returns why a link without a scheme in the doc rel is
+// broken, or "" when it is not
+func checkLocalLink(root, rel string, u *url.URL, anchors *anchorCache) string {
+	target := rel
+	if u.Path != "" {
+		if strings.HasPrefix(u.Path, "/") {
+			target = path.Clean(strings.TrimPrefix(u.Path, "/"))
+		} else {
+			target = path.Join(path.Dir(rel), u.Path)
+		}
+		if target == ".." || strings.HasPrefix(target, "../") {
+			return "target is outside the project"
+		}
+		info, err := os.Stat(filepath.Join(root, filepath.FromSlash(target)))
+		if err != nil {
+			return "file not found"
+		}
+		if info.IsDir() {
+			return ""
+		}
+	}
+
+	if u.Fragment == "" || !isMarkdown(target) {
+		return ""
+	}
+	if !anchors.has(target, u.Fragment) {
+		if target == rel {
+			return fmt.Sprintf("no heading #%s in this doc", u.Fragment)
+		}
+		return fmt.Sprintf("no heading #%s in %s", u.Fragment, target)
+	}
+	return ""
+}
+
+// anchorCache holds the anchors of the docs links point at, read once each
+type anchorCache struct {
+	root    string
+	anchors map[string]map[string]bool
+}
+
+func newAnchorCache(root string) *anchorCache {
+	return &anchorCache{root: root, anchors: map[string]map[string]bool{}}
+}
+
+func (c *anchorCache) has(rel, anchor string) bool {
+	set, ok := c.anchors[rel]
+	if !ok {
+		set = map[string]bool{}
+		if data, err := os.ReadFile(filepath.Join(c.root, filepath.FromSlash(rel))); err == nil {
+			for _, section := range index.SplitMarkdown(string(data)) {
+				if section.Anchor != "" {
+					set[section.Anchor] = true
+				}
+			}
+			for _, m := range htmlAnchor.FindAllStringSubmatch(string(data), -1) {
+				set[m[1]+m[2]] = true
+			}
+		}
+		c.anchors[rel] = set
+	}
+	if decoded, err := url.PathUnescape(anchor); err == nil {
+		anchor = decoded
+	}
+	return set[anchor] || set[strings.ToLower(anchor)]
+}
+
+// checkExternalLinks checks each distinct URL once and returns why the
+// failing ones failed
+func checkExternalLinks(ctx context.Context, links []docLink, timeout time.Duration) map[string]string {
+	client := &http.Client{Timeout: timeout}
+	urls := make(chan string)
+	failed := map[string]string{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for i := 0; i < linkCheckWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for u := range urls {
+				if reason := checkURL(ctx, client, u); reason != "" {
+					mu.Lock()
+					failed[u] = reason
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	seen := map[string]bool{}
+	for _, link := range links {
+		if seen[link.target] {
+			continue
+		}
+		seen[link.target] = true
+		select {
+		case urls <- link.target:
+		case <-ctx.Done():
+		}
+	}
+	close(urls)
+	wg.Wait()
+	return failed
+}
+
+func checkURL(ctx context.Context, client *http.Client, target string) string {
+	status, err := requestStatus(ctx, client, http.MethodHead, target)
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented || status == http.StatusForbidden) {
+		status, err = requestStatus(ctx, client, http.MethodGet, target)
+	}
+	if err != nil {
+		return fmt.Sprintf("request failed: %v", err)
+	}
+	if status >= 400 {
+		return fmt.Sprintf("HTTP %d", status)
+	}
+	return ""
+}
+
+func requestStatus(ctx context.Context, client *http.Client, method, target string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", "may-la-mcp doc_check_links")
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
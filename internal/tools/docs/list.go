@@ -0,0 +1,190 @@
+package docs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/alucardeht/may-la-mcp/internal/index"
+	"github.com/alucardeht/may-la-mcp/internal/paths"
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+const (
+	defaultDocListResults = 500
+
+	// maxTitleScan bounds how much of a file is read to find its title
+	maxTitleScan = 64 * 1024
+)
+
+type DocListRequest struct {
+	ProjectRoot string `json:"project_root,omitempty"`
+	Path        string `json:"path,omitempty"`
+	MaxResults  int    `json:"max_results,omitempty"`
+}
+
+type DocEntry struct {
+	Path      string `json:"path"`
+	Title     string `json:"title,omitempty"`
+	SizeBytes int64  `json:"size_bytes"`
+}
+
+type DocListResponse struct {
+	Root      string     `json:"root"`
+	Docs      []DocEntry `json:"docs"`
+	Count     int        `json:"count"`
+	Truncated bool       `json:"truncated,omitempty"`
+}
+
+type DocListTool struct{}
+
+func (t *DocListTool) Name() string {
+	return "doc_list"
+}
+
+func (t *DocListTool) Description() string {
+	return `List project documentation with titles.
+
+Walks the project for markdown files (and .rst/.adoc/.txt under docs/),
+skipping anything .gitignore ignores, and returns each path with the title
+taken from its frontmatter or first heading. Use it to find the right doc before doc_read,
+or doc_search to search inside them.`
+}
+
+func (t *DocListTool) Title() string {
+	return "List Project Documentation"
+}
+
+func (t *DocListTool) Annotations() map[string]bool {
+	return tools.ReadOnlyAnnotations()
+}
+
+func (t *DocListTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"project_root": {
+				"type": "string",
+				"description": "Project root (optional - defaults to current directory)"
+			},
+			"path": {
+				"type": "string",
+				"description": "Only list docs under this directory, relative to the project root (optional)"
+			},
+			"max_results": {
+				"type": "integer",
+				"description": "Maximum number of docs returned (default: 500)"
+			}
+		}
+	}`)
+}
+
+func (t *DocListTool) OutputSchema() json.RawMessage {
+	return tools.OutputSchemaOf(DocListResponse{})
+}
+
+func (t *DocListTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var req DocListRequest
+	if len(input) > 0 {
+		if err := json.Unmarshal(input, &req); err != nil {
+			return nil, fmt.Errorf("invalid request: %w", err)
+		}
+	}
+	if req.MaxResults <= 0 {
+		req.MaxResults = defaultDocListResults
+	}
+
+	root, err := docRoot(req.ProjectRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := ""
+	if req.Path != "" {
+		dir, err := resolveDocPath(req.Path, root)
+		if err != nil {
+			return nil, err
+		}
+		if rel, err := filepath.Rel(root, dir); err == nil && rel != "." {
+			prefix = filepath.ToSlash(rel) + "/"
+		}
+	}
+
+	resp := &DocListResponse{Root: root, Docs: []DocEntry{}}
+	err = walkDocs(ctx, root, func(rel, abs string, info fs.FileInfo) error {
+		if !strings.HasPrefix(rel, prefix) {
+			return nil
+		}
+		resp.Count++
+		if len(resp.Docs) == req.MaxResults {
+			resp.Truncated = true
+			return nil
+		}
+		resp.Docs = append(resp.Docs, DocEntry{Path: rel, Title: docTitle(abs), SizeBytes: info.Size()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// docRoot resolves the project root of a docs request and checks it may be
+// read
+func docRoot(projectRoot string) (string, error) {
+	if projectRoot == "" {
+		projectRoot = "."
+	}
+	root := paths.Canonical(projectRoot)
+	if err := tools.CheckPath(root); err != nil {
+		return "", err
+	}
+	if info, err := os.Stat(root); err != nil {
+		return "", fmt.Errorf("project root not found: %w", err)
+	} else if !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", projectRoot)
+	}
+	return root, nil
+}
+
+// docTitle returns the frontmatter title or first heading of a markdown
+// file, or the first line of any other doc with its heading markers trimmed
+func docTitle(abs string) string {
+	f, err := os.Open(abs)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, maxTitleScan))
+	if err != nil {
+		return ""
+	}
+	content := string(data)
+
+	switch strings.ToLower(path.Ext(abs)) {
+	case ".md", ".markdown", ".mdx":
+		fm, _ := splitFrontmatter(content)
+		if title, ok := fm.Fields()["title"].(string); ok && title != "" {
+			return title
+		}
+		return index.MarkdownTitle(content)
+	}
+	for _, line := range strings.Split(content, "\n") {
+		if line = strings.TrimSpace(strings.TrimLeft(line, "=# ")); line != "" {
+			return line
+		}
+	}
+	return ""
+}
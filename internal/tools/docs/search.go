@@ -0,0 +1,267 @@
+package docs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/alucardeht/may-la-mcp/internal/index"
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+const (
+	defaultDocSearchResults = 20
+
+	// maxScannedDoc bounds the files read when searching without the index
+	maxScannedDoc = 1024 * 1024
+
+	// snippetRadius is how many characters around the first match a scanned
+	// snippet keeps on each side
+	snippetRadius = 100
+
+	docSourceIndex = "index"
+	docSourceScan  = "scan"
+)
+
+type DocSearchRequest struct {
+	Query       string `json:"query"`
+	ProjectRoot string `json:"project_root,omitempty"`
+	MaxResults  int    `json:"max_results,omitempty"`
+}
+
+type DocSearchMatch struct {
+	Path    string `json:"path"`
+	Heading string `json:"heading,omitempty"`
+	Link    string `json:"link"`
+	Line    int    `json:"line"`
+	Snippet string `json:"snippet"`
+}
+
+type DocSearchResponse struct {
+	Query   string           `json:"query"`
+	Root    string           `json:"root"`
+	Matches []DocSearchMatch `json:"matches"`
+	Count   int              `json:"count"`
+	Source  string           `json:"source"`
+}
+
+type DocSearchTool struct {
+	store index.Store
+}
+
+// NewDocSearchTool searches the doc sections in store, or scans the docs on
+// disk when store is nil or has none indexed under the project root
+func NewDocSearchTool(store index.Store) *DocSearchTool {
+	return &DocSearchTool{store: store}
+}
+
+func (t *DocSearchTool) Name() string {
+	return "doc_search"
+}
+
+func (t *DocSearchTool) Description() string {
+	return `Full-text search over project documentation.
+
+Returns the doc sections (text under a heading) containing every word of
+the query, best first, each with a path#anchor link to the heading, its line
+and a snippet with matches in **bold**. Uses the markdown sections in the
+index when available, otherwise scans the files doc_list would list.`
+}
+
+func (t *DocSearchTool) Title() string {
+	return "Search Project Documentation"
+}
+
+func (t *DocSearchTool) Annotations() map[string]bool {
+	return tools.ReadOnlyAnnotations()
+}
+
+func (t *DocSearchTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"query": {
+				"type": "string",
+				"description": "Words to search for; every one must appear in the section (required)"
+			},
+			"project_root": {
+				"type": "string",
+				"description": "Project root (optional - defaults to current directory)"
+			},
+			"max_results": {
+				"type": "integer",
+				"description": "Maximum number of sections returned (default: 20)"
+			}
+		},
+		"required": ["query"]
+	}`)
+}
+
+func (t *DocSearchTool) OutputSchema() json.RawMessage {
+	return tools.OutputSchemaOf(DocSearchResponse{})
+}
+
+func (t *DocSearchTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var req DocSearchRequest
+	if err := json.Unmarshal(input, &req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+	if req.MaxResults <= 0 {
+		req.MaxResults = defaultDocSearchResults
+	}
+
+	root, err := docRoot(req.ProjectRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &DocSearchResponse{Query: req.Query, Root: root, Matches: []DocSearchMatch{}}
+	if t.store != nil {
+		if n, err := t.store.CountDocSections(root); err == nil && n > 0 {
+			found, err := t.store.SearchDocs(req.Query, root, req.MaxResults)
+			if err != nil {
+				return nil, err
+			}
+			for _, m := range found {
+				resp.Matches = append(resp.Matches, docMatch(root, m.Path, &m.DocSection, m.Snippet))
+			}
+			resp.Count = len(resp.Matches)
+			resp.Source = docSourceIndex
+			return resp, nil
+		}
+	}
+
+	matches, err := scanDocs(ctx, root, req.Query, req.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	resp.Matches = matches
+	resp.Count = len(matches)
+	resp.Source = docSourceScan
+	return resp, nil
+}
+
+func docMatch(root, abs string, section *index.DocSection, snippet string) DocSearchMatch {
+	rel := abs
+	if r, err := filepath.Rel(root, abs); err == nil {
+		rel = filepath.ToSlash(r)
+	}
+	link := rel
+	if section.Anchor != "" {
+		link += "#" + section.Anchor
+	}
+	return DocSearchMatch{Path: rel, Heading: section.Heading, Link: link, Line: section.Line, Snippet: snippet}
+}
+
+// scanDocs searches the docs on disk section by section. A section matches
+// when it contains every word of query; words in its heading count more.
+func scanDocs(ctx context.Context, root, query string, limit int) ([]DocSearchMatch, error) {
+	words := strings.Fields(strings.ToLower(query))
+
+	type scored struct {
+		match DocSearchMatch
+		score int
+	}
+	var found []scored
+
+	err := walkDocs(ctx, root, func(rel, abs string, info fs.FileInfo) error {
+		if info.Size() > maxScannedDoc {
+			return nil
+		}
+		data, err := os.ReadFile(abs)
+		if err != nil {
+			return nil
+		}
+		content := string(data)
+
+		sections := []*index.DocSection{{Heading: docTitle(abs), Line: 1, Content: content}}
+		switch strings.ToLower(path.Ext(rel)) {
+		case ".md", ".markdown", ".mdx":
+			sections = index.SplitMarkdown(content)
+		}
+
+		for _, section := range sections {
+			heading := strings.ToLower(section.Heading)
+			text := strings.ToLower(section.Content)
+			score := 0
+			for _, word := range words {
+				inHeading, inText := strings.Count(heading, word), strings.Count(text, word)
+				if inHeading+inText == 0 {
+					score = 0
+					break
+				}
+				score += 5*inHeading + inText
+			}
+			if score > 0 {
+				found = append(found, scored{docMatch(root, abs, section, scanSnippet(section.Content, words)), score})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].score > found[j].score
+	})
+
+	matches := []DocSearchMatch{}
+	for i, f := range found {
+		if i == limit {
+			break
+		}
+		matches = append(matches, f.match)
+	}
+	return matches, nil
+}
+
+// scanSnippet cuts the text around the first word of words found in
+// content and marks that word with ** like the index snippets
+func scanSnippet(content string, words []string) string {
+	lower := strings.ToLower(content)
+	at, word := -1, ""
+	for _, w := range words {
+		if i := strings.Index(lower, w); i >= 0 && (at < 0 || i < at) {
+			at, word = i, w
+		}
+	}
+	if at < 0 || at+len(word) > len(content) {
+		return ""
+	}
+
+	start, end := at-snippetRadius, at+len(word)+snippetRadius
+	prefix, suffix := "...", "..."
+	if start <= 0 {
+		start, prefix = 0, ""
+	}
+	if end >= len(content) {
+		end, suffix = len(content), ""
+	}
+	for start > 0 && !isRuneStart(content[start]) {
+		start--
+	}
+	for end < len(content) && !isRuneStart(content[end]) {
+		end++
+	}
+
+	snippet := content[start:at] + "**" + content[at:at+len(word)] + "**" + content[at+len(word):end]
+	return prefix + strings.Join(strings.Fields(snippet), " ") + suffix
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
@@ -0,0 +1,551 @@
+package docs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/alucardeht/may-la-mcp/internal/index"
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+// headingSeparator separates the levels of a heading path, as in
+// "Setup > Install"
+const headingSeparator = ">"
+
+var errHeadingNotFound = errors.New("heading not found")
+
+// docHeading is a heading of a markdown document with the lines its section
+// spans: Line is the heading itself, OwnEnd the last line before the first
+// subsection and End the last line including subsections (all 1-based)
+type docHeading struct {
+	Text   string
+	Anchor string
+	Level  int
+	Line   int
+	OwnEnd int
+	End    int
+	Path   []string
+}
+
+// docLines splits content into lines, leaving out the empty line after a
+// final newline; joinDocLines puts it back
+func docLines(content string) ([]string, bool) {
+	lines := strings.Split(content, "\n")
+	if len(lines) > 1 && lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1], true
+	}
+	return lines, false
+}
+
+func joinDocLines(lines []string, finalNewline bool) string {
+	joined := strings.Join(lines, "\n")
+	if finalNewline {
+		joined += "\n"
+	}
+	return joined
+}
+
+// outlineMarkdown lists the headings of content with their section spans
+func outlineMarkdown(content string) []*docHeading {
+	lines, _ := docLines(content)
+
+	var headings []*docHeading
+	var stack []*docHeading
+	for _, section := range index.SplitMarkdown(content) {
+		if section.Heading == "" {
+			continue
+		}
+		h := &docHeading{Text: section.Heading, Anchor: section.Anchor, Level: section.Level, Line: section.Line}
+		for len(stack) > 0 && stack[len(stack)-1].Level >= h.Level {
+			stack = stack[:len(stack)-1]
+		}
+		for _, parent := range stack {
+			h.Path = append(h.Path, parent.Text)
+		}
+		h.Path = append(h.Path, h.Text)
+		stack = append(stack, h)
+		headings = append(headings, h)
+	}
+
+	for i, h := range headings {
+		h.OwnEnd, h.End = len(lines), len(lines)
+		if i+1 < len(headings) {
+			h.OwnEnd = headings[i+1].Line - 1
+		}
+		for _, next := range headings[i+1:] {
+			if next.Level <= h.Level {
+				h.End = next.Line - 1
+				break
+			}
+		}
+	}
+	return headings
+}
+
+// splitHeadingPath splits "Setup > Install" into its trimmed levels
+func splitHeadingPath(path string) []string {
+	var parts []string
+	for _, part := range strings.Split(path, headingSeparator) {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+// findHeading resolves a heading path: its last level names the heading
+// (by text, case-insensitively, or by #anchor) and the levels before it
+// name ancestors, in order but not necessarily direct parents
+func findHeading(headings []*docHeading, path string) (*docHeading, error) {
+	parts := splitHeadingPath(path)
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("heading is required")
+	}
+
+	var found []*docHeading
+	for _, h := range headings {
+		if headingPathMatches(h, parts) {
+			found = append(found, h)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("%w: %q; headings: %s", errHeadingNotFound, path, headingList(headings))
+	case 1:
+		return found[0], nil
+	default:
+		var where []string
+		for _, h := range found {
+			where = append(where, fmt.Sprintf("%q at line %d", strings.Join(h.Path, " > "), h.Line))
+		}
+		return nil, fmt.Errorf("heading %q is ambiguous (%s); give more of its path", path, strings.Join(where, ", "))
+	}
+}
+
+func headingPathMatches(h *docHeading, parts []string) bool {
+	if !headingNamed(h.Text, h.Anchor, parts[len(parts)-1]) {
+		return false
+	}
+	ancestors := h.Path[:len(h.Path)-1]
+	next := 0
+	for _, ancestor := range ancestors {
+		if next < len(parts)-1 && headingNamed(ancestor, index.HeadingAnchor(ancestor), parts[next]) {
+			next++
+		}
+	}
+	return next == len(parts)-1
+}
+
+func headingNamed(text, anchor, name string) bool {
+	if strings.HasPrefix(name, "#") {
+		return anchor == strings.TrimPrefix(name, "#")
+	}
+	return strings.EqualFold(text, name)
+}
+
+func headingList(headings []*docHeading) string {
+	if len(headings) == 0 {
+		return "none"
+	}
+	var names []string
+	for i, h := range headings {
+		if i == 20 {
+			names = append(names, "...")
+			break
+		}
+		names = append(names, fmt.Sprintf("%q", strings.Join(h.Path, " > ")))
+	}
+	return strings.Join(names, ", ")
+}
+
+// readDoc resolves and reads a markdown document for the section tools
+func readDoc(path, projectRoot string) (string, string, error) {
+	if path == "" {
+		return "", "", fmt.Errorf("path is required")
+	}
+	target, err := resolveDocPath(path, projectRoot)
+	if err != nil {
+		return "", "", err
+	}
+	if err := tools.CheckPath(target); err != nil {
+		return "", "", err
+	}
+	content, err := os.ReadFile(target)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read file: %w", err)
+	}
+	return target, string(content), nil
+}
+
+// writeDoc replaces the content of an existing document, keeping its mode
+func writeDoc(target, content string) error {
+	info, err := os.Stat(target)
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+	if err := os.WriteFile(target, []byte(content), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
+
+type DocSectionReadRequest struct {
+	Path               string `json:"path"`
+	Heading            string `json:"heading"`
+	IncludeSubsections *bool  `json:"include_subsections,omitempty"`
+	ProjectRoot        string `json:"project_root,omitempty"`
+}
+
+type DocSectionResponse struct {
+	Path        string   `json:"path"`
+	Heading     string   `json:"heading"`
+	HeadingPath []string `json:"heading_path"`
+	Anchor      string   `json:"anchor"`
+	Level       int      `json:"level"`
+	LineStart   int      `json:"line_start"`
+	LineEnd     int      `json:"line_end"`
+	Content     string   `json:"content"`
+}
+
+type DocSectionReadTool struct{}
+
+func (t *DocSectionReadTool) Name() string {
+	return "doc_section_read"
+}
+
+func (t *DocSectionReadTool) Description() string {
+	return `Read one section of a markdown document instead of the whole file.
+
+The section is addressed by heading path: "Install" or, when the name is
+not unique, "Setup > Install" (each level an ancestor heading, in order).
+A level may also be an anchor such as "#install-the-cli". Returns the text
+under the heading, with its subsections unless include_subsections is false.`
+}
+
+func (t *DocSectionReadTool) Title() string {
+	return "Read Documentation Section"
+}
+
+func (t *DocSectionReadTool) Annotations() map[string]bool {
+	return tools.ReadOnlyAnnotations()
+}
+
+func (t *DocSectionReadTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"path": {
+				"type": "string",
+				"description": "Markdown file path relative to project root or absolute (required)"
+			},
+			"heading": {
+				"type": "string",
+				"description": "Heading path such as \"Setup > Install\" or an anchor such as \"#install\" (required)"
+			},
+			"include_subsections": {
+				"type": "boolean",
+				"description": "Include the subsections under the heading (default: true)"
+			},
+			"project_root": {
+				"type": "string",
+				"description": "Project root for relative paths (optional - defaults to current directory)"
+			}
+		},
+		"required": ["path", "heading"]
+	}`)
+}
+
+func (t *DocSectionReadTool) OutputSchema() json.RawMessage {
+	return tools.OutputSchemaOf(DocSectionResponse{})
+}
+
+func (t *DocSectionReadTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var req DocSectionReadRequest
+	if err := json.Unmarshal(input, &req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	target, content, err := readDoc(req.Path, req.ProjectRoot)
+	if err != nil {
+		return nil, err
+	}
+	h, err := findHeading(outlineMarkdown(content), req.Heading)
+	if err != nil {
+		return nil, err
+	}
+
+	end := h.End
+	if req.IncludeSubsections != nil && !*req.IncludeSubsections {
+		end = h.OwnEnd
+	}
+	lines, _ := docLines(content)
+
+	return &DocSectionResponse{
+		Path:        target,
+		Heading:     h.Text,
+		HeadingPath: h.Path,
+		Anchor:      h.Anchor,
+		Level:       h.Level,
+		LineStart:   h.Line,
+		LineEnd:     end,
+		Content:     strings.Trim(strings.Join(lines[h.Line:end], "\n"), "\n"),
+	}, nil
+}
+
+type DocSectionWriteRequest struct {
+	Path               string `json:"path"`
+	Heading            string `json:"heading"`
+	Content            string `json:"content"`
+	IncludeSubsections *bool  `json:"include_subsections,omitempty"`
+	Create             bool   `json:"create,omitempty"`
+	ProjectRoot        string `json:"project_root,omitempty"`
+}
+
+type DocSectionWriteResponse struct {
+	Success   bool   `json:"success"`
+	Path      string `json:"path"`
+	Heading   string `json:"heading"`
+	Created   bool   `json:"created,omitempty"`
+	LineStart int    `json:"line_start"`
+	LineEnd   int    `json:"line_end"`
+}
+
+type DocSectionWriteTool struct{}
+
+func (t *DocSectionWriteTool) Name() string {
+	return "doc_section_write"
+}
+
+func (t *DocSectionWriteTool) Description() string {
+	return `Replace the text of one section of a markdown document, leaving the
+rest of the file untouched.
+
+The section is addressed by heading path like doc_section_read, and content
+replaces the text under the heading (the heading line itself is kept). By
+default that includes the subsections, so content read with
+doc_section_read can be edited and written back; set include_subsections
+to false to replace only the text before the first subsection. With create,
+a missing section is added at the end of its parent ("Setup > New" adds
+"New" under "Setup") or of the document.`
+}
+
+func (t *DocSectionWriteTool) Title() string {
+	return "Write Documentation Section"
+}
+
+func (t *DocSectionWriteTool) Annotations() map[string]bool {
+	return tools.SafeWriteAnnotations()
+}
+
+func (t *DocSectionWriteTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"path": {
+				"type": "string",
+				"description": "Markdown file path relative to project root or absolute (required)"
+			},
+			"heading": {
+				"type": "string",
+				"description": "Heading path such as \"Setup > Install\" or an anchor such as \"#install\" (required)"
+			},
+			"content": {
+				"type": "string",
+				"description": "New text under the heading, without the heading line (required, may be empty)"
+			},
+			"include_subsections": {
+				"type": "boolean",
+				"description": "Replace the subsections too (default: true)"
+			},
+			"create": {
+				"type": "boolean",
+				"description": "Add the section if it does not exist (default: false)"
+			},
+			"project_root": {
+				"type": "string",
+				"description": "Project root for relative paths (optional - defaults to current directory)"
+			}
+		},
+		"required": ["path", "heading", "content"]
+	}`)
+}
+
+func (t *DocSectionWriteTool) OutputSchema() json.RawMessage {
+	return tools.OutputSchemaOf(DocSectionWriteResponse{})
+}
+
+func (t *DocSectionWriteTool) Examples() []tools.Example {
+	return []tools.Example{
+		{
+			Description: "Rewrite the Install section under Setup",
+			Arguments:   json.RawMessage(`{"path": "README.md", "heading": "Setup > Install", "content": "Run make install."}`),
+			Result:      json.RawMessage(`{"success": true, "path": "/home/user/app/README.md", "heading": "Install", "line_start": 12, "line_end": 14}`),
+		},
+		{
+			Description: "Add a FAQ section at the end of the document",
+			Arguments:   json.RawMessage(`{"path": "docs/guide.md", "heading": "FAQ", "content": "Nothing yet.", "create": true}`),
+		},
+	}
+}
+
+func (t *DocSectionWriteTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	target, before, after, resp, err := t.plan(input)
+	if err != nil {
+		return nil, err
+	}
+	if after != before {
+		if err := writeDoc(target, after); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+func (t *DocSectionWriteTool) DryRun(ctx context.Context, input json.RawMessage) (*tools.Preview, error) {
+	target, before, after, resp, err := t.plan(input)
+	if err != nil {
+		return nil, err
+	}
+
+	verb := "replace"
+	if resp.Created {
+		verb = "add"
+	}
+	return &tools.Preview{
+		Summary:  fmt.Sprintf("would %s section %q in %s", verb, resp.Heading, target),
+		Modified: []string{target},
+		Diffs:    []tools.FileDiff{tools.DiffFile(target, before, after, false)},
+	}, nil
+}
+
+// plan works out the document a section write produces
+func (t *DocSectionWriteTool) plan(input json.RawMessage) (string, string, string, *DocSectionWriteResponse, error) {
+	var req DocSectionWriteRequest
+	if err := json.Unmarshal(input, &req); err != nil {
+		return "", "", "", nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	target, content, err := readDoc(req.Path, req.ProjectRoot)
+	if err != nil {
+		return "", "", "", nil, err
+	}
+	lines, finalNewline := docLines(content)
+	headings := outlineMarkdown(content)
+
+	body := []string{}
+	if text := strings.Trim(req.Content, "\n"); text != "" {
+		body = strings.Split(text, "\n")
+	}
+
+	h, findErr := findHeading(headings, req.Heading)
+	if findErr != nil {
+		if !req.Create || !errors.Is(findErr, errHeadingNotFound) {
+			return "", "", "", nil, findErr
+		}
+		return t.create(target, content, lines, finalNewline, headings, req.Heading, body)
+	}
+
+	end := h.End
+	if req.IncludeSubsections != nil && !*req.IncludeSubsections {
+		end = h.OwnEnd
+	}
+
+	section := []string{}
+	if len(body) > 0 {
+		section = append(append(section, ""), body...)
+	}
+	if end < len(lines) {
+		section = append(section, "")
+	}
+
+	updated := append(append(append([]string{}, lines[:h.Line]...), section...), lines[end:]...)
+	resp := &DocSectionWriteResponse{
+		Success:   true,
+		Path:      target,
+		Heading:   h.Text,
+		LineStart: h.Line,
+		LineEnd:   h.Line,
+	}
+	if len(body) > 0 {
+		resp.LineEnd += 1 + len(body)
+	}
+	return target, content, joinDocLines(updated, finalNewline), resp, nil
+}
+
+// create adds a missing section at the end of its parent, or of the
+// document when the heading path has a single level
+func (t *DocSectionWriteTool) create(target, content string, lines []string, finalNewline bool, headings []*docHeading, path string, body []string) (string, string, string, *DocSectionWriteResponse, error) {
+	parts := splitHeadingPath(path)
+	name := parts[len(parts)-1]
+	if strings.HasPrefix(name, "#") {
+		return "", "", "", nil, fmt.Errorf("cannot create a section from anchor %q; name it by its heading", name)
+	}
+
+	at, level := len(lines), 1
+	for _, h := range headings {
+		if h.Level == 1 {
+			level = 2
+			break
+		}
+	}
+	if len(parts) > 1 {
+		parent, err := findHeading(headings, strings.Join(parts[:len(parts)-1], headingSeparator))
+		if err != nil {
+			return "", "", "", nil, err
+		}
+		at, level = parent.End, parent.Level+1
+	}
+	if level > 6 {
+		level = 6
+	}
+
+	for at > 0 && strings.TrimSpace(lines[at-1]) == "" {
+		at--
+	}
+	section := []string{}
+	if at > 0 {
+		section = append(section, "")
+	}
+	section = append(section, strings.Repeat("#", level)+" "+name)
+	if len(body) > 0 {
+		section = append(append(section, ""), body...)
+	}
+	rest := lines[at:]
+	if len(rest) > 0 {
+		section = append(section, "")
+		for len(rest) > 0 && strings.TrimSpace(rest[0]) == "" {
+			rest = rest[1:]
+		}
+	}
+
+	updated := append(append(append([]string{}, lines[:at]...), section...), rest...)
+	line := at + 1
+	if at > 0 {
+		line++
+	}
+	resp := &DocSectionWriteResponse{
+		Success:   true,
+		Path:      target,
+		Heading:   name,
+		Created:   true,
+		LineStart: line,
+		LineEnd:   line + len(body),
+	}
+	if len(body) > 0 {
+		resp.LineEnd++
+	}
+	return target, content, joinDocLines(updated, finalNewline || content == ""), resp, nil
+}
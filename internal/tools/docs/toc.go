@@ -0,0 +1,198 @@
+package docs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+const (
+	tocStart = "<!-- toc -->"
+	tocEnd   = "<!-- tocstop -->"
+
+	defaultTOCMinLevel = 2
+	defaultTOCMaxLevel = 3
+)
+
+// tocAnchors are headings of a table of contents itself, left out of it
+var tocAnchors = map[string]bool{"table-of-contents": true, "contents": true, "toc": true}
+
+type DocTOCRequest struct {
+	Path        string `json:"path"`
+	MinLevel    int    `json:"min_level,omitempty"`
+	MaxLevel    int    `json:"max_level,omitempty"`
+	Update      bool   `json:"update,omitempty"`
+	ProjectRoot string `json:"project_root,omitempty"`
+}
+
+type DocTOCResponse struct {
+	Path    string `json:"path"`
+	TOC     string `json:"toc"`
+	Entries int    `json:"entries"`
+	Updated bool   `json:"updated"`
+}
+
+type DocTOCTool struct{}
+
+func (t *DocTOCTool) Name() string {
+	return "doc_toc"
+}
+
+func (t *DocTOCTool) Description() string {
+	return `Generate the table of contents of a markdown document, linking each
+heading by its anchor.
+
+With update, the table is written into the document between
+<!-- toc --> and <!-- tocstop --> markers: replaced if they exist,
+otherwise inserted before the first heading it lists. Running it again
+after editing headings refreshes the table in place.`
+}
+
+func (t *DocTOCTool) Title() string {
+	return "Documentation Table of Contents"
+}
+
+func (t *DocTOCTool) Annotations() map[string]bool {
+	return tools.SafeWriteAnnotations()
+}
+
+func (t *DocTOCTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"path": {
+				"type": "string",
+				"description": "Markdown file path relative to project root or absolute (required)"
+			},
+			"min_level": {
+				"type": "integer",
+				"description": "Shallowest heading level listed (default: 2, leaving out the title)"
+			},
+			"max_level": {
+				"type": "integer",
+				"description": "Deepest heading level listed (default: 3)"
+			},
+			"update": {
+				"type": "boolean",
+				"description": "Write the table into the document between toc markers (default: false)"
+			},
+			"project_root": {
+				"type": "string",
+				"description": "Project root for relative paths (optional - defaults to current directory)"
+			}
+		},
+		"required": ["path"]
+	}`)
+}
+
+func (t *DocTOCTool) OutputSchema() json.RawMessage {
+	return tools.OutputSchemaOf(DocTOCResponse{})
+}
+
+func (t *DocTOCTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	target, before, after, resp, err := t.plan(input)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Updated && after != before {
+		if err := writeDoc(target, after); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+func (t *DocTOCTool) DryRun(ctx context.Context, input json.RawMessage) (*tools.Preview, error) {
+	target, before, after, resp, err := t.plan(input)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &tools.Preview{Summary: fmt.Sprintf("would leave %s unchanged", target), Details: resp}
+	if resp.Updated {
+		preview.Summary = fmt.Sprintf("would update the table of contents of %s", target)
+		preview.Modified = []string{target}
+		preview.Diffs = []tools.FileDiff{tools.DiffFile(target, before, after, false)}
+	}
+	return preview, nil
+}
+
+// plan builds the table of contents and, with update, the document holding
+// it; resp.Updated tells whether the document changes
+func (t *DocTOCTool) plan(input json.RawMessage) (string, string, string, *DocTOCResponse, error) {
+	var req DocTOCRequest
+	if err := json.Unmarshal(input, &req); err != nil {
+		return "", "", "", nil, fmt.Errorf("invalid request: %w", err)
+	}
+	if req.MinLevel <= 0 {
+		req.MinLevel = defaultTOCMinLevel
+	}
+	if req.MaxLevel <= 0 {
+		req.MaxLevel = defaultTOCMaxLevel
+	}
+	if req.MaxLevel < req.MinLevel {
+		return "", "", "", nil, fmt.Errorf("max_level %d is below min_level %d", req.MaxLevel, req.MinLevel)
+	}
+
+	target, content, err := readDoc(req.Path, req.ProjectRoot)
+	if err != nil {
+		return "", "", "", nil, err
+	}
+
+	var toc []string
+	var first *docHeading
+	for _, h := range outlineMarkdown(content) {
+		if h.Level < req.MinLevel || h.Level > req.MaxLevel || tocAnchors[h.Anchor] {
+			continue
+		}
+		if first == nil {
+			first = h
+		}
+		toc = append(toc, fmt.Sprintf("%s- [%s](#%s)", strings.Repeat("  ", h.Level-req.MinLevel), h.Text, h.Anchor))
+	}
+
+	resp := &DocTOCResponse{Path: target, TOC: strings.Join(toc, "\n"), Entries: len(toc)}
+	if !req.Update || len(toc) == 0 {
+		return target, content, content, resp, nil
+	}
+
+	lines, finalNewline := docLines(content)
+	block := append(append([]string{tocStart}, toc...), tocEnd)
+
+	var updated []string
+	start, end := markerLine(lines, tocStart, 0), -1
+	if start >= 0 {
+		end = markerLine(lines, tocEnd, start+1)
+	}
+	if start >= 0 && end >= 0 {
+		updated = append(append(append([]string{}, lines[:start]...), block...), lines[end+1:]...)
+	} else {
+		at := first.Line - 1
+		if at > 0 && strings.TrimSpace(lines[at-1]) != "" {
+			block = append([]string{""}, block...)
+		}
+		updated = append(append(append(append([]string{}, lines[:at]...), block...), ""), lines[at:]...)
+	}
+
+	after := joinDocLines(updated, finalNewline)
+	resp.Updated = after != content
+	return target, content, after, resp, nil
+}
+
+// markerLine returns the index of the first line from start that is marker,
+// or -1
+func markerLine(lines []string, marker string, start int) int {
+	for i := start; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == marker {
+			return i
+		}
+	}
+	return -1
+}
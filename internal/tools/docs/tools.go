@@ -6,17 +6,32 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/alucardeht/may-la-mcp/internal/index"
 	"github.com/alucardeht/may-la-mcp/internal/tools"
 )
 
-func GetTools() []tools.Tool {
+// GetTools returns the doc tools; doc_search uses store when it is not nil
+func GetTools(store index.Store) []tools.Tool {
 	return []tools.Tool{
 		&DocWriteTool{},
 		&DocReadTool{},
+		&DocListTool{},
+		NewDocSearchTool(store),
+		&DocSectionReadTool{},
+		&DocSectionWriteTool{},
+		&DocTOCTool{},
+		&DocCheckLinksTool{},
 	}
 }
 
+type DocWriteResponse struct {
+	Success bool   `json:"success"`
+	Path    string `json:"path"`
+	Size    int    `json:"size"`
+}
+
 type DocWriteTool struct{}
 
 func (t *DocWriteTool) Name() string {
@@ -43,7 +58,13 @@ WHEN TO USE memory_write INSTEAD:
 PATH RESOLUTION:
 - Relative paths resolve from project root (e.g., "docs/api.md")
 - Absolute paths used as-is
-- Auto-creates parent directories if needed`
+- Auto-creates parent directories if needed
+
+FRONTMATTER:
+- frontmatter is merged into the YAML frontmatter of the markdown written,
+  as a JSON merge patch: null removes a key, other keys are set
+- Without content, it patches the existing file and leaves its body untouched
+- Keys listed as required in the project's .mayla/docs.json must be present`
 }
 
 func (t *DocWriteTool) Title() string {
@@ -64,87 +85,179 @@ func (t *DocWriteTool) Schema() json.RawMessage {
 			},
 			"content": {
 				"type": "string",
-				"description": "File content (required)"
+				"description": "File content (required unless frontmatter is given)"
+			},
+			"frontmatter": {
+				"type": "object",
+				"description": "Frontmatter fields to merge into the document; null removes a field (optional)"
 			},
 			"project_root": {
 				"type": "string",
 				"description": "Project root for relative paths (optional - defaults to current directory)"
 			}
 		},
-		"required": ["path", "content"]
+		"required": ["path"]
 	}`)
 }
 
+type docWriteRequest struct {
+	Path        string                 `json:"path"`
+	Content     string                 `json:"content"`
+	Frontmatter map[string]interface{} `json:"frontmatter"`
+	ProjectRoot string                 `json:"project_root"`
+}
+
+func (t *DocWriteTool) OutputSchema() json.RawMessage {
+	return tools.OutputSchemaOf(DocWriteResponse{})
+}
+
 func (t *DocWriteTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
 	}
 
-	var req struct {
-		Path        string `json:"path"`
-		Content     string `json:"content"`
-		ProjectRoot string `json:"project_root"`
+	targetPath, _, _, content, err := t.plan(input)
+	if err != nil {
+		return nil, err
 	}
-	if err := json.Unmarshal(input, &req); err != nil {
+
+	dir := filepath.Dir(targetPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directories: %w", err)
+	}
+
+	if err := os.WriteFile(targetPath, []byte(content), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return &DocWriteResponse{
+		Success: true,
+		Path:    targetPath,
+		Size:    len(content),
+	}, nil
+}
+
+func (t *DocWriteTool) DryRun(ctx context.Context, input json.RawMessage) (*tools.Preview, error) {
+	targetPath, before, exists, content, err := t.plan(input)
+	if err != nil {
 		return nil, err
 	}
 
+	preview := &tools.Preview{
+		Created: tools.MissingDirs(filepath.Dir(targetPath)),
+		Diffs:   []tools.FileDiff{tools.DiffFile(targetPath, before, content, !exists)},
+	}
+	if exists {
+		preview.Summary = fmt.Sprintf("would overwrite %s", targetPath)
+		preview.Modified = []string{targetPath}
+	} else {
+		preview.Summary = fmt.Sprintf("would create %s", targetPath)
+		preview.Created = append(preview.Created, targetPath)
+	}
+	return preview, nil
+}
+
+// plan resolves the file a doc write targets and the content it ends up
+// with, frontmatter patched in, checked against the project's doc rules
+func (t *DocWriteTool) plan(input json.RawMessage) (string, string, bool, string, error) {
+	var req docWriteRequest
+	if err := json.Unmarshal(input, &req); err != nil {
+		return "", "", false, "", err
+	}
+
 	if req.Path == "" {
-		return nil, fmt.Errorf("path is required")
+		return "", "", false, "", fmt.Errorf("path is required")
 	}
 
-	if req.Content == "" {
-		return nil, fmt.Errorf("content is required")
+	if req.Content == "" && req.Frontmatter == nil {
+		return "", "", false, "", fmt.Errorf("content is required")
+	}
+
+	targetPath, err := resolveDocPath(req.Path, req.ProjectRoot)
+	if err != nil {
+		return "", "", false, "", err
+	}
+
+	before, exists, err := tools.ReadExisting(targetPath)
+	if err != nil {
+		return "", "", false, "", err
+	}
+
+	content := req.Content
+	if req.Frontmatter != nil {
+		if !isMarkdown(targetPath) {
+			return "", "", false, "", fmt.Errorf("frontmatter is only supported in markdown docs")
+		}
+		if content == "" {
+			content = before
+		}
+		content = applyFrontmatter(content, req.Frontmatter)
 	}
 
 	projectRoot := req.ProjectRoot
 	if projectRoot == "" {
 		projectRoot = "."
 	}
+	root, err := filepath.Abs(projectRoot)
+	if err != nil {
+		return "", "", false, "", fmt.Errorf("failed to resolve project root: %w", err)
+	}
+	rules, err := loadDocRules(root)
+	if err != nil {
+		return "", "", false, "", err
+	}
+	if missing := rules.missingFrontmatter(root, targetPath, content); len(missing) > 0 {
+		return "", "", false, "", fmt.Errorf("frontmatter is missing required keys: %s (required by %s)", strings.Join(missing, ", "), docRulesFile)
+	}
+
+	return targetPath, before, exists, content, nil
+}
+
+// resolveDocPath resolves path against projectRoot (default: the current
+// directory). Relative paths may not escape the project root.
+func resolveDocPath(path, projectRoot string) (string, error) {
+	if projectRoot == "" {
+		projectRoot = "."
+	}
 
 	var targetPath string
-	if filepath.IsAbs(req.Path) {
-		targetPath = req.Path
+	if filepath.IsAbs(path) {
+		targetPath = path
 	} else {
-		targetPath = filepath.Join(projectRoot, req.Path)
+		targetPath = filepath.Join(projectRoot, path)
 	}
 
-	if !filepath.IsAbs(req.Path) {
+	if !filepath.IsAbs(path) {
 		absRoot, err := filepath.Abs(projectRoot)
 		if err != nil {
-			return nil, fmt.Errorf("failed to resolve project root: %w", err)
+			return "", fmt.Errorf("failed to resolve project root: %w", err)
 		}
 
-		targetPath = filepath.Join(absRoot, req.Path)
+		targetPath = filepath.Join(absRoot, path)
 		absTarget, err := filepath.Abs(targetPath)
 		if err != nil {
-			return nil, fmt.Errorf("failed to resolve path: %w", err)
+			return "", fmt.Errorf("failed to resolve path: %w", err)
 		}
 
 		absRootCleaned := filepath.Clean(absRoot)
 		absTargetCleaned := filepath.Clean(absTarget)
 
 		if !isPathWithinRoot(absTargetCleaned, absRootCleaned) {
-			return nil, fmt.Errorf("path escapes project root: %s", req.Path)
+			return "", fmt.Errorf("path escapes project root: %s", path)
 		}
 
 		targetPath = absTargetCleaned
 	}
 
-	dir := filepath.Dir(targetPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create directories: %w", err)
-	}
-
-	if err := os.WriteFile(targetPath, []byte(req.Content), 0644); err != nil {
-		return nil, fmt.Errorf("failed to write file: %w", err)
-	}
+	return targetPath, nil
+}
 
-	return map[string]interface{}{
-		"success": true,
-		"path":    targetPath,
-		"size":    len(req.Content),
-	}, nil
+type DocReadResponse struct {
+	Path               string                 `json:"path"`
+	Content            string                 `json:"content"`
+	Size               int                    `json:"size"`
+	Frontmatter        map[string]interface{} `json:"frontmatter,omitempty"`
+	MissingFrontmatter []string               `json:"missing_frontmatter,omitempty"`
 }
 
 type DocReadTool struct{}
@@ -159,6 +272,10 @@ func (t *DocReadTool) Description() string {
 Complements doc_write for reading documentation that lives with the project.
 For cross-project knowledge, use memory_read instead.
 
+Markdown frontmatter is also returned parsed, as frontmatter, along with
+missing_frontmatter listing the keys .mayla/docs.json requires but the
+doc lacks.
+
 PATH RESOLUTION:
 - Relative paths resolve from project root
 - Absolute paths used as-is`
@@ -189,6 +306,10 @@ func (t *DocReadTool) Schema() json.RawMessage {
 	}`)
 }
 
+func (t *DocReadTool) OutputSchema() json.RawMessage {
+	return tools.OutputSchemaOf(DocReadResponse{})
+}
+
 func (t *DocReadTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
@@ -235,16 +356,39 @@ func (t *DocReadTool) Execute(ctx context.Context, input json.RawMessage) (inter
 		targetPath = absTargetCleaned
 	}
 
+	if err := tools.CheckPath(targetPath); err != nil {
+		return nil, err
+	}
 	content, err := os.ReadFile(targetPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
 
-	return map[string]interface{}{
-		"path":    targetPath,
-		"content": string(content),
-		"size":    len(content),
-	}, nil
+	resp := &DocReadResponse{
+		Path:    targetPath,
+		Content: string(content),
+		Size:    len(content),
+	}
+	if !isMarkdown(targetPath) {
+		return resp, nil
+	}
+
+	fm, _ := splitFrontmatter(string(content))
+	if fm != nil {
+		resp.Frontmatter = fm.Fields()
+	}
+	absRoot, err := filepath.Abs(projectRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve project root: %w", err)
+	}
+	rules, err := loadDocRules(absRoot)
+	if err != nil {
+		return nil, err
+	}
+	if missing := rules.missingFrontmatter(absRoot, targetPath, string(content)); len(missing) > 0 {
+		resp.MissingFrontmatter = missing
+	}
+	return resp, nil
 }
 
 func isPathWithinRoot(targetPath, rootPath string) bool {
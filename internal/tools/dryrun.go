@@ -0,0 +1,111 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const ErrCodeDryRunUnsupported = -32006
+
+// DryRunner is implemented by mutating tools that can describe what a call
+// would change without applying it. In a dry-run session the registry calls
+// DryRun instead of Execute.
+type DryRunner interface {
+	DryRun(ctx context.Context, input json.RawMessage) (*Preview, error)
+}
+
+// Preview is what a mutating tool returns in a dry-run session: the paths it
+// would create, modify, delete or move and a diff of every changed file.
+// Stored records such as memories are reported with their memory:// paths.
+type Preview struct {
+	DryRun   bool        `json:"dry_run"`
+	Tool     string      `json:"tool"`
+	Summary  string      `json:"summary"`
+	Created  []string    `json:"created,omitempty"`
+	Modified []string    `json:"modified,omitempty"`
+	Deleted  []string    `json:"deleted,omitempty"`
+	Moved    []PathMove  `json:"moved,omitempty"`
+	Diffs    []FileDiff  `json:"diffs,omitempty"`
+	Details  interface{} `json:"details,omitempty"`
+}
+
+type PathMove struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+func NewDryRunUnsupportedError(name string) *ToolError {
+	return &ToolError{
+		Code:    ErrCodeDryRunUnsupported,
+		Message: fmt.Sprintf("Tool %s cannot preview its changes and is disabled in dry-run mode", name),
+		Data: map[string]interface{}{
+			"reason": "dry_run_unsupported",
+			"tool":   name,
+		},
+	}
+}
+
+// dryRun previews a mutating call on behalf of Execute
+func dryRun(ctx context.Context, name string, tool Tool, input json.RawMessage) (interface{}, error) {
+	runner, ok := tool.(DryRunner)
+	if !ok {
+		return nil, NewDryRunUnsupportedError(name)
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	preview, err := runner.DryRun(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	preview.DryRun = true
+	preview.Tool = name
+	return preview, nil
+}
+
+// MissingDirs returns dir and each of its ancestors that do not exist yet,
+// outermost first: the directories MkdirAll(dir) would create.
+func MissingDirs(dir string) []string {
+	var missing []string
+	for dir != "" && dir != "." {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		}
+		missing = append([]string{dir}, missing...)
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return missing
+}
+
+// ReadExisting returns the content of the regular file at path and whether
+// it exists. A directory or a denied path is an error.
+func ReadExisting(path string) (string, bool, error) {
+	if err := CheckPath(path); err != nil {
+		return "", false, err
+	}
+
+	stat, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to stat path: %w", err)
+	}
+	if stat.IsDir() {
+		return "", false, fmt.Errorf("path is a directory")
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read file: %w", err)
+	}
+	return string(content), true, nil
+}
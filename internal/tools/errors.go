@@ -5,6 +5,7 @@ import "fmt"
 type ToolError struct {
 	Code    int
 	Message string
+	Data    map[string]interface{}
 }
 
 func (e *ToolError) Error() string {
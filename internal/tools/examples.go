@@ -0,0 +1,60 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// MaxExamples bounds the examples tools/list shows for a tool
+const MaxExamples = 3
+
+// Example is a worked call of a tool: the arguments it takes and the shape
+// of the result they give
+type Example struct {
+	Description string          `json:"description"`
+	Arguments   json.RawMessage `json:"arguments"`
+	Result      json.RawMessage `json:"result,omitempty"`
+}
+
+// ExampleTool is implemented by tools whose arguments are easy to get
+// wrong. tools/list includes the examples in the tool's _meta and lists
+// their arguments at the end of its description.
+type ExampleTool interface {
+	Tool
+	Examples() []Example
+}
+
+// ExamplesOf returns the first MaxExamples examples of tool, if it has any
+func ExamplesOf(tool Tool) []Example {
+	withExamples, ok := tool.(ExampleTool)
+	if !ok {
+		return nil
+	}
+	examples := withExamples.Examples()
+	if len(examples) > MaxExamples {
+		examples = examples[:MaxExamples]
+	}
+	return examples
+}
+
+// DescribeExamples renders the arguments of examples as a description
+// appendix, one compact line each
+func DescribeExamples(examples []Example) string {
+	if len(examples) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\n\nEXAMPLES:")
+	for _, example := range examples {
+		var args bytes.Buffer
+		if err := json.Compact(&args, example.Arguments); err != nil {
+			continue
+		}
+		b.WriteString("\n- ")
+		b.WriteString(example.Description)
+		b.WriteString(": ")
+		b.Write(args.Bytes())
+	}
+	return b.String()
+}
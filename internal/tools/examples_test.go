@@ -0,0 +1,51 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+type exampleTool struct {
+	examples []Example
+}
+
+func (t *exampleTool) Name() string            { return "example_tool" }
+func (t *exampleTool) Description() string     { return "Does things" }
+func (t *exampleTool) Schema() json.RawMessage { return json.RawMessage(`{"type":"object"}`) }
+func (t *exampleTool) Examples() []Example     { return t.examples }
+func (t *exampleTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	return nil, nil
+}
+
+func TestExamplesOf(t *testing.T) {
+	tool := &exampleTool{}
+	for i := 0; i < MaxExamples+2; i++ {
+		tool.examples = append(tool.examples, Example{
+			Description: "Call it",
+			Arguments:   json.RawMessage(`{ "path": "a.go",  "count": 1 }`),
+		})
+	}
+
+	examples := ExamplesOf(tool)
+	if len(examples) != MaxExamples {
+		t.Fatalf("expected %d examples, got %d", MaxExamples, len(examples))
+	}
+	if got := ExamplesOf(&namedTool{name: "plain"}); got != nil {
+		t.Errorf("expected no examples for a tool without them, got %v", got)
+	}
+
+	description := DescribeExamples(examples[:1])
+	if description != "\n\nEXAMPLES:\n- Call it: {\"path\":\"a.go\",\"count\":1}" {
+		t.Errorf("unexpected description appendix %q", description)
+	}
+	if DescribeExamples(nil) != "" {
+		t.Error("expected no appendix without examples")
+	}
+
+	examples[0].Arguments = json.RawMessage(`{broken`)
+	if strings.Contains(DescribeExamples(examples[:1]), "broken") {
+		t.Error("expected examples with invalid arguments to be left out")
+	}
+}
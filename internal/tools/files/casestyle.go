@@ -0,0 +1,126 @@
+package files
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// caseStyles are the spellings a preserveCase replace looks for, in the
+// order a tie between two styles spelling a word the same is settled
+var caseStyles = []func(words []string) string{
+	camelCase,
+	pascalCase,
+	snakeCase,
+	screamingCase,
+}
+
+// identifierPattern is what preserveCase accepts as search and replace
+var identifierPattern = regexp.MustCompile(`^[\p{L}_][\p{L}\p{N}_-]*$`)
+
+// replaceCases replaces every occurrence of search in any of its case
+// styles, camelCase, PascalCase, snake_case or SCREAMING_SNAKE_CASE, with
+// replace spelled in the same style. search itself is replaced by replace
+// as written. It reports the number of occurrences replaced.
+func (b *editBuffer) replaceCases(search, replace string) (int, error) {
+	if !identifierPattern.MatchString(search) || !identifierPattern.MatchString(replace) {
+		return 0, fmt.Errorf("preserveCase needs search and replace to be identifiers")
+	}
+
+	spellings := caseVariants(search, replace)
+	variants := make([]string, 0, len(spellings))
+	for variant := range spellings {
+		variants = append(variants, regexp.QuoteMeta(variant))
+	}
+	// longest first, so FOO_BAR wins over a shorter variant it contains
+	sort.Slice(variants, func(i, j int) bool {
+		if len(variants[i]) != len(variants[j]) {
+			return len(variants[i]) > len(variants[j])
+		}
+		return variants[i] < variants[j]
+	})
+	pattern := regexp.MustCompile(strings.Join(variants, "|"))
+
+	count := 0
+	for _, i := range b.live() {
+		b.lines[i] = pattern.ReplaceAllStringFunc(b.lines[i], func(match string) string {
+			count++
+			return spellings[match]
+		})
+	}
+	return count, nil
+}
+
+// caseVariants maps each case style spelling of search to the spelling of
+// replace in the same style
+func caseVariants(search, replace string) map[string]string {
+	from, to := identifierWords(search), identifierWords(replace)
+	spellings := map[string]string{search: replace}
+	for _, style := range caseStyles {
+		if variant := style(from); variant != "" {
+			if _, ok := spellings[variant]; !ok {
+				spellings[variant] = style(to)
+			}
+		}
+	}
+	return spellings
+}
+
+// identifierWords splits an identifier into lowercase words at underscores,
+// hyphens and case changes: parseHTTPResponse gives parse, http, response
+func identifierWords(identifier string) []string {
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+	}
+
+	runes := []rune(identifier)
+	for i, r := range runes {
+		switch {
+		case r == '_' || r == '-':
+			flush()
+			continue
+		case unicode.IsUpper(r) && i > 0:
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			// a new word starts at fooBar's B and at HTTPResponse's R
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				flush()
+			}
+		}
+		word = append(word, r)
+	}
+	flush()
+	return words
+}
+
+func camelCase(words []string) string {
+	if len(words) == 0 {
+		return ""
+	}
+	return words[0] + pascalCase(words[1:])
+}
+
+func pascalCase(words []string) string {
+	var b strings.Builder
+	for _, word := range words {
+		runes := []rune(word)
+		b.WriteString(strings.ToUpper(string(runes[:1])))
+		b.WriteString(string(runes[1:]))
+	}
+	return b.String()
+}
+
+func snakeCase(words []string) string {
+	return strings.Join(words, "_")
+}
+
+func screamingCase(words []string) string {
+	return strings.ToUpper(snakeCase(words))
+}
@@ -0,0 +1,471 @@
+package files
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+// maxChmodEntries bounds the files a recursive chmod may change at once
+const maxChmodEntries = 10000
+
+type ChmodRequest struct {
+	Path      string `json:"path"`
+	Mode      string `json:"mode,omitempty"`
+	Owner     string `json:"owner,omitempty"`
+	Group     string `json:"group,omitempty"`
+	Recursive bool   `json:"recursive,omitempty"`
+}
+
+type ModeChange struct {
+	Path   string `json:"path"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+type ChmodResponse struct {
+	Path      string       `json:"path"`
+	Changed   []ModeChange `json:"changed"`
+	Unchanged int          `json:"unchanged"`
+	Owner     string       `json:"owner,omitempty"`
+	Group     string       `json:"group,omitempty"`
+}
+
+type ChmodTool struct{}
+
+func (t *ChmodTool) Name() string {
+	return "chmod"
+}
+
+func (t *ChmodTool) Description() string {
+	return `Change the permissions, and optionally the owner and group, of a file or directory inside the workspace.
+
+mode is octal ("755", "0644") or symbolic like chmod(1): "+x", "u+x,go-w", "a=r", with X setting execute only on directories and files already executable by someone. Setuid and setgid bits are refused. With recursive, every file and directory under path is changed too; symlinks are skipped. Paths outside the workspace root, also through symlinks, are refused.`
+}
+
+func (t *ChmodTool) Title() string {
+	return "Change File Permissions"
+}
+
+func (t *ChmodTool) Annotations() map[string]bool {
+	return tools.SafeWriteAnnotations()
+}
+
+func (t *ChmodTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"path": {
+				"type": "string",
+				"description": "File or directory to change"
+			},
+			"mode": {
+				"type": "string",
+				"description": "Octal (\"755\") or symbolic (\"u+x,go-w\") permissions"
+			},
+			"owner": {
+				"type": "string",
+				"description": "New owner, by user name or numeric id (optional)"
+			},
+			"group": {
+				"type": "string",
+				"description": "New group, by group name or numeric id (optional)"
+			},
+			"recursive": {
+				"type": "boolean",
+				"description": "Also change everything under a directory (default: false)"
+			}
+		},
+		"required": ["path"]
+	}`)
+}
+
+func (t *ChmodTool) Examples() []tools.Example {
+	return []tools.Example{
+		{
+			Description: "Make a script executable",
+			Arguments:   json.RawMessage(`{"path": "/home/user/app/scripts/build.sh", "mode": "+x"}`),
+			Result:      json.RawMessage(`{"path": "/home/user/app/scripts/build.sh", "changed": [{"path": "/home/user/app/scripts/build.sh", "before": "0644", "after": "0755"}], "unchanged": 0}`),
+		},
+		{
+			Description: "Remove group and world write access from a directory tree",
+			Arguments:   json.RawMessage(`{"path": "/home/user/app/config", "mode": "go-w", "recursive": true}`),
+		},
+	}
+}
+
+// chmodEntry is a path chmod changes, with its mode before and after
+type chmodEntry struct {
+	path          string
+	before, after os.FileMode
+}
+
+// chmodPlan is what a chmod call changes
+type chmodPlan struct {
+	entries   []chmodEntry
+	uid, gid  int
+	unchanged int
+}
+
+func (t *ChmodTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var req ChmodRequest
+	if err := json.Unmarshal(input, &req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	unlock, err := lockPaths(ctx, req.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	plan, err := planChmod(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := ChmodResponse{Path: req.Path, Changed: []ModeChange{}, Unchanged: plan.unchanged, Owner: req.Owner, Group: req.Group}
+	for _, entry := range plan.entries {
+		if entry.after != entry.before {
+			if err := os.Chmod(entry.path, entry.after); err != nil {
+				return nil, fmt.Errorf("failed to chmod %s: %w", entry.path, err)
+			}
+			resp.Changed = append(resp.Changed, ModeChange{entry.path, formatMode(entry.before), formatMode(entry.after)})
+		}
+		if plan.uid != -1 || plan.gid != -1 {
+			if err := os.Chown(entry.path, plan.uid, plan.gid); err != nil {
+				return nil, fmt.Errorf("failed to chown %s: %w", entry.path, err)
+			}
+		}
+	}
+	return resp, nil
+}
+
+func (t *ChmodTool) DryRun(ctx context.Context, input json.RawMessage) (*tools.Preview, error) {
+	var req ChmodRequest
+	if err := json.Unmarshal(input, &req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	plan, err := planChmod(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []ModeChange{}
+	var modified []string
+	for _, entry := range plan.entries {
+		if entry.after != entry.before {
+			changes = append(changes, ModeChange{entry.path, formatMode(entry.before), formatMode(entry.after)})
+			modified = append(modified, entry.path)
+		} else if plan.uid != -1 || plan.gid != -1 {
+			modified = append(modified, entry.path)
+		}
+	}
+
+	summary := fmt.Sprintf("would change the mode of %d of %d paths", len(changes), len(plan.entries))
+	if plan.uid != -1 || plan.gid != -1 {
+		summary = fmt.Sprintf("would change the mode of %d and the ownership of %d paths", len(changes), len(plan.entries))
+	}
+	return &tools.Preview{Summary: summary, Modified: modified, Details: changes}, nil
+}
+
+func planChmod(ctx context.Context, req ChmodRequest) (*chmodPlan, error) {
+	if req.Path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+	if req.Mode == "" && req.Owner == "" && req.Group == "" {
+		return nil, fmt.Errorf("mode, owner or group is required")
+	}
+	if err := checkInWorkspace(req.Path); err != nil {
+		return nil, err
+	}
+
+	var change modeChange
+	if req.Mode != "" {
+		var err error
+		if change, err = parseModeChange(req.Mode); err != nil {
+			return nil, err
+		}
+	}
+
+	plan := &chmodPlan{uid: -1, gid: -1}
+	var err error
+	if req.Owner != "" {
+		if plan.uid, err = lookupUser(req.Owner); err != nil {
+			return nil, err
+		}
+	}
+	if req.Group != "" {
+		if plan.gid, err = lookupGroup(req.Group); err != nil {
+			return nil, err
+		}
+	}
+
+	add := func(path string, info fs.FileInfo) error {
+		before := info.Mode() & (fs.ModePerm | fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky)
+		after := before
+		if change != nil {
+			after = change(before, info.IsDir())
+		}
+		if after == before && plan.uid == -1 && plan.gid == -1 {
+			plan.unchanged++
+			return nil
+		}
+		if len(plan.entries) >= maxChmodEntries {
+			return fmt.Errorf("too many paths under %s: more than %d", req.Path, maxChmodEntries)
+		}
+		plan.entries = append(plan.entries, chmodEntry{path, before, after})
+		return nil
+	}
+
+	info, err := os.Stat(req.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("path does not exist: %s", req.Path)
+		}
+		return nil, fmt.Errorf("failed to stat path: %w", err)
+	}
+	if err := add(req.Path, info); err != nil {
+		return nil, err
+	}
+	if !req.Recursive || !info.IsDir() {
+		return plan, nil
+	}
+
+	err = filepath.WalkDir(req.Path, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if path == req.Path || d.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+		if tools.IsPathDenied(path) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return add(path, info)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// modeChange computes the new mode of a file from its current one
+type modeChange func(mode os.FileMode, isDir bool) os.FileMode
+
+// parseModeChange parses an octal or symbolic chmod(1) mode. Setuid and setgid
+// bits are refused; the sticky bit is allowed.
+func parseModeChange(spec string) (modeChange, error) {
+	spec = strings.TrimSpace(spec)
+	if spec != "" && strings.Trim(spec, "01234567") == "" {
+		if len(spec) > 4 {
+			return nil, fmt.Errorf("invalid mode %q: at most 4 octal digits", spec)
+		}
+		value, err := strconv.ParseUint(spec, 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mode %q: %w", spec, err)
+		}
+		if value&0o6000 != 0 {
+			return nil, fmt.Errorf("invalid mode %q: setuid and setgid bits are refused", spec)
+		}
+		mode := os.FileMode(value) & fs.ModePerm
+		if value&0o1000 != 0 {
+			mode |= fs.ModeSticky
+		}
+		return func(os.FileMode, bool) os.FileMode { return mode }, nil
+	}
+
+	var clauses []modeChange
+	for _, clause := range strings.Split(spec, ",") {
+		change, err := parseModeClause(clause)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mode %q: %w", spec, err)
+		}
+		clauses = append(clauses, change)
+	}
+	return func(mode os.FileMode, isDir bool) os.FileMode {
+		for _, change := range clauses {
+			mode = change(mode, isDir)
+		}
+		return mode
+	}, nil
+}
+
+// parseModeClause parses one clause of a symbolic mode: who letters
+// followed by one or more operators, each with its permission letters
+func parseModeClause(clause string) (modeChange, error) {
+	i := 0
+	var who os.FileMode
+	for ; i < len(clause) && strings.IndexByte("ugoa", clause[i]) >= 0; i++ {
+		switch clause[i] {
+		case 'u':
+			who |= 0o700
+		case 'g':
+			who |= 0o070
+		case 'o':
+			who |= 0o007
+		case 'a':
+			who |= 0o777
+		}
+	}
+	if who == 0 {
+		who = 0o777
+	}
+	if i == len(clause) {
+		return nil, fmt.Errorf("clause %q has no operator", clause)
+	}
+
+	type action struct {
+		op    byte
+		perms string
+	}
+	var actions []action
+	for i < len(clause) {
+		op := clause[i]
+		if op != '+' && op != '-' && op != '=' {
+			return nil, fmt.Errorf("unexpected %q in clause %q", op, clause)
+		}
+		i++
+		start := i
+		for ; i < len(clause) && strings.IndexByte("+-=", clause[i]) < 0; i++ {
+			switch clause[i] {
+			case 'r', 'w', 'x', 'X', 't':
+			case 's':
+				return nil, fmt.Errorf("setuid and setgid bits are refused")
+			default:
+				return nil, fmt.Errorf("unknown permission %q in clause %q", clause[i], clause)
+			}
+		}
+		actions = append(actions, action{op, clause[start:i]})
+	}
+
+	return func(mode os.FileMode, isDir bool) os.FileMode {
+		for _, a := range actions {
+			var bits, special os.FileMode
+			for _, perm := range a.perms {
+				switch perm {
+				case 'r':
+					bits |= 0o444
+				case 'w':
+					bits |= 0o222
+				case 'x':
+					bits |= 0o111
+				case 'X':
+					if isDir || mode&0o111 != 0 {
+						bits |= 0o111
+					}
+				case 't':
+					special |= fs.ModeSticky
+				}
+			}
+			bits &= who
+			switch a.op {
+			case '+':
+				mode |= bits | special
+			case '-':
+				mode &^= bits | special
+			case '=':
+				mode = mode&^who | bits | special
+			}
+		}
+		return mode
+	}, nil
+}
+
+// formatMode renders the permission bits of mode in octal, with the
+// sticky bit as the leading digit
+func formatMode(mode os.FileMode) string {
+	value := uint32(mode & fs.ModePerm)
+	if mode&fs.ModeSticky != 0 {
+		value |= 0o1000
+	}
+	if mode&fs.ModeSetgid != 0 {
+		value |= 0o2000
+	}
+	if mode&fs.ModeSetuid != 0 {
+		value |= 0o4000
+	}
+	return fmt.Sprintf("%04o", value)
+}
+
+func lookupUser(name string) (int, error) {
+	if id, err := strconv.Atoi(name); err == nil && id >= 0 {
+		return id, nil
+	}
+	u, err := user.Lookup(name)
+	if err != nil {
+		return 0, fmt.Errorf("unknown owner %q: %w", name, err)
+	}
+	return strconv.Atoi(u.Uid)
+}
+
+func lookupGroup(name string) (int, error) {
+	if id, err := strconv.Atoi(name); err == nil && id >= 0 {
+		return id, nil
+	}
+	g, err := user.LookupGroup(name)
+	if err != nil {
+		return 0, fmt.Errorf("unknown group %q: %w", name, err)
+	}
+	return strconv.Atoi(g.Gid)
+}
+
+// checkInWorkspace refuses paths on the sensitive-path denylist and paths
+// that are, or resolve through symlinks to, outside the workspace root:
+// the directory the daemon serves
+func checkInWorkspace(path string) error {
+	if err := tools.CheckPath(path); err != nil {
+		return err
+	}
+
+	root, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to determine the workspace root: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("invalid path %s: %w", path, err)
+	}
+	resolved, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		// a path that does not exist yet is judged by its directory
+		dir, dirErr := filepath.EvalSymlinks(filepath.Dir(abs))
+		if dirErr != nil {
+			dir = filepath.Dir(abs)
+		}
+		resolved = filepath.Join(dir, filepath.Base(abs))
+	}
+
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("%s is outside the workspace root %s", path, root)
+	}
+	return nil
+}
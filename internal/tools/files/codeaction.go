@@ -0,0 +1,394 @@
+package files
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/alucardeht/may-la-mcp/internal/lsp"
+	"github.com/alucardeht/may-la-mcp/internal/paths"
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+	"github.com/alucardeht/may-la-mcp/internal/types"
+)
+
+// CodeActionRequest selects a range of a file: from StartLine and
+// StartColumn to EndLine and EndColumn, 1-based with columns in characters.
+// The end defaults to the start, a cursor position.
+type CodeActionRequest struct {
+	Path        string   `json:"path"`
+	StartLine   int      `json:"start_line"`
+	StartColumn int      `json:"start_column,omitempty"`
+	EndLine     int      `json:"end_line,omitempty"`
+	EndColumn   int      `json:"end_column,omitempty"`
+	Kinds       []string `json:"kinds,omitempty"`
+}
+
+// ApplyCodeActionRequest picks one of the actions code_actions lists for
+// the same range, by its index or its title
+type ApplyCodeActionRequest struct {
+	CodeActionRequest
+	Index  *int   `json:"index,omitempty"`
+	Title  string `json:"title,omitempty"`
+	Backup bool   `json:"backup,omitempty"`
+}
+
+// CodeActionInfo is a code action as code_actions lists it
+type CodeActionInfo struct {
+	Index       int      `json:"index"`
+	Title       string   `json:"title"`
+	Kind        string   `json:"kind,omitempty"`
+	Preferred   bool     `json:"preferred,omitempty"`
+	Disabled    string   `json:"disabled,omitempty"`
+	Diagnostics []string `json:"diagnostics,omitempty"`
+	// Files are the files the action's edit changes, when the server sent
+	// the edit with the list
+	Files   []string `json:"files,omitempty"`
+	Command string   `json:"command,omitempty"`
+}
+
+type CodeActionsResponse struct {
+	Path    string           `json:"path"`
+	Actions []CodeActionInfo `json:"actions"`
+	Count   int              `json:"count"`
+}
+
+type ApplyCodeActionResponse struct {
+	Action CodeActionInfo `json:"action"`
+	*EditSetResult
+}
+
+const codeActionRangeSchema = `
+			"path": {
+				"type": "string",
+				"description": "File to get code actions for"
+			},
+			"start_line": {
+				"type": "integer",
+				"description": "First line of the range (1-based)"
+			},
+			"start_column": {
+				"type": "integer",
+				"description": "Column in characters where the range starts (1-based, default: 1)"
+			},
+			"end_line": {
+				"type": "integer",
+				"description": "Last line of the range (default: start_line)"
+			},
+			"end_column": {
+				"type": "integer",
+				"description": "Column just past the range (default: start_column on start_line, the end of the line otherwise)"
+			},
+			"kinds": {
+				"type": "array",
+				"items": {"type": "string"},
+				"description": "Only actions of these kinds or their sub-kinds, e.g. quickfix, refactor, refactor.extract, source.organizeImports"
+			}`
+
+// CodeActionsTool lists the quick fixes and refactorings a language server
+// offers for a range
+type CodeActionsTool struct {
+	manager *lsp.Manager
+}
+
+func NewCodeActionsTool(manager *lsp.Manager) *CodeActionsTool {
+	return &CodeActionsTool{manager: manager}
+}
+
+func (t *CodeActionsTool) Name() string {
+	return "code_actions"
+}
+
+func (t *CodeActionsTool) Description() string {
+	return "List the code actions the language server offers for a range of a file: quick fixes, refactorings such as extract or inline, and source actions such as organize imports. Apply one with apply_code_action, by index or title, for the same range"
+}
+
+func (t *CodeActionsTool) Title() string {
+	return "List Code Actions"
+}
+
+func (t *CodeActionsTool) Annotations() map[string]bool {
+	return tools.ReadOnlyAnnotations()
+}
+
+func (t *CodeActionsTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {` + codeActionRangeSchema + `
+		},
+		"required": ["path", "start_line"]
+	}`)
+}
+
+func (t *CodeActionsTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var req CodeActionRequest
+	if err := json.Unmarshal(input, &req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	path, _, actions, err := requestCodeActions(ctx, t.manager, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &CodeActionsResponse{Path: path, Actions: make([]CodeActionInfo, len(actions)), Count: len(actions)}
+	for i, action := range actions {
+		resp.Actions[i] = codeActionInfo(i, action)
+	}
+	return resp, nil
+}
+
+// ApplyCodeActionTool applies one of the code actions of a range
+type ApplyCodeActionTool struct {
+	manager *lsp.Manager
+}
+
+func NewApplyCodeActionTool(manager *lsp.Manager) *ApplyCodeActionTool {
+	return &ApplyCodeActionTool{manager: manager}
+}
+
+func (t *ApplyCodeActionTool) Name() string {
+	return "apply_code_action"
+}
+
+func (t *ApplyCodeActionTool) Description() string {
+	return "Apply a code action the language server offers for a range, chosen by its index or title in the code_actions list for the same range. The action's edit is applied to every file it touches at once, and its command, if any, is run and the edits it asks for applied too. Returns the diff of each changed file"
+}
+
+func (t *ApplyCodeActionTool) Title() string {
+	return "Apply Code Action"
+}
+
+func (t *ApplyCodeActionTool) Annotations() map[string]bool {
+	return tools.DestructiveAnnotations()
+}
+
+func (t *ApplyCodeActionTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {` + codeActionRangeSchema + `,
+			"index": {
+				"type": "integer",
+				"minimum": 0,
+				"description": "Index of the action in the code_actions list"
+			},
+			"title": {
+				"type": "string",
+				"description": "Title of the action, instead of index"
+			},
+			"backup": {
+				"type": "boolean",
+				"description": "Keep the previous content of each changed file as path.bak.<timestamp> (default: false)"
+			}
+		},
+		"required": ["path", "start_line"]
+	}`)
+}
+
+func (t *ApplyCodeActionTool) Examples() []tools.Example {
+	return []tools.Example{
+		{
+			Description: "Organize the imports of a Go file",
+			Arguments:   json.RawMessage(`{"path": "/home/user/app/main.go", "start_line": 1, "kinds": ["source.organizeImports"], "index": 0}`),
+		},
+	}
+}
+
+func (t *ApplyCodeActionTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return t.apply(ctx, input, false)
+}
+
+// DryRun shows the diffs of the action's edit. A command the action would
+// run is named in the preview but not run, since it may have other effects.
+func (t *ApplyCodeActionTool) DryRun(ctx context.Context, input json.RawMessage) (*tools.Preview, error) {
+	result, err := t.apply(ctx, input, true)
+	if err != nil {
+		return nil, err
+	}
+	resp := result.(*ApplyCodeActionResponse)
+
+	preview := resp.Preview(fmt.Sprintf("would apply %q to %d files", resp.Action.Title, len(resp.Files)))
+	if resp.Action.Command != "" {
+		preview.Details = map[string]interface{}{"command": resp.Action.Command}
+		preview.Summary += fmt.Sprintf(" and run the command %s, whose edits are not previewed", resp.Action.Command)
+	}
+	return preview, nil
+}
+
+func (t *ApplyCodeActionTool) apply(ctx context.Context, input json.RawMessage, dryRun bool) (interface{}, error) {
+	var req ApplyCodeActionRequest
+	if err := json.Unmarshal(input, &req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+	if (req.Index == nil) == (req.Title == "") {
+		return nil, fmt.Errorf("either index or title is required")
+	}
+
+	path, text, actions, err := requestCodeActions(ctx, t.manager, req.CodeActionRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	chosen := -1
+	if req.Index != nil {
+		if *req.Index < 0 || *req.Index >= len(actions) {
+			return nil, fmt.Errorf("no code action %d: the range has %d", *req.Index, len(actions))
+		}
+		chosen = *req.Index
+	} else {
+		for i, action := range actions {
+			if action.Title == req.Title {
+				chosen = i
+				break
+			}
+		}
+		if chosen < 0 {
+			return nil, fmt.Errorf("no code action titled %q for the range", req.Title)
+		}
+	}
+
+	action := actions[chosen]
+	if action.Disabled != nil {
+		return nil, fmt.Errorf("code action %q is disabled: %s", action.Title, action.Disabled.Reason)
+	}
+	if action.Edit == nil && action.Command == nil {
+		resolved, err := t.manager.ResolveCodeAction(ctx, path, text, action)
+		if err != nil {
+			return nil, err
+		}
+		action = *resolved
+	}
+
+	resp := &ApplyCodeActionResponse{
+		Action:        codeActionInfo(chosen, action),
+		EditSetResult: &EditSetResult{Files: []EditSetFile{}},
+	}
+
+	// the server computed the edits from the text it was sent, so the file
+	// must still have it
+	opts := WorkspaceEditOptions{DryRun: dryRun, Backup: req.Backup}
+	computedFrom := func(text string) {
+		opts.Hashes = map[string]string{path: contentHash(text)}
+		opts.Versions = map[string]int{lsp.PathToURI(path): lsp.DocumentVersion}
+	}
+
+	if action.Edit != nil {
+		computedFrom(text)
+		result, err := ApplyWorkspaceEdit(ctx, *action.Edit, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply %q: %w", action.Title, err)
+		}
+		resp.merge(result)
+	}
+	if action.Command != nil && !dryRun {
+		// the command sees the files as the edit left them
+		text, err := readText(path)
+		if err != nil {
+			return nil, err
+		}
+		edits, err := t.manager.ExecuteCommand(ctx, path, text, *action.Command)
+		if err != nil {
+			return nil, err
+		}
+		computedFrom(text)
+		for i, edit := range edits {
+			if i > 0 {
+				// a later edit builds on the file the earlier ones left
+				delete(opts.Hashes, path)
+			}
+			result, err := ApplyWorkspaceEdit(ctx, edit, opts)
+			if err != nil {
+				return nil, fmt.Errorf("failed to apply the edits of %s: %w", action.Command.Command, err)
+			}
+			resp.merge(result)
+		}
+	}
+	return resp, nil
+}
+
+// requestCodeActions validates req and asks the language server for the
+// code actions of its range
+func requestCodeActions(ctx context.Context, manager *lsp.Manager, req CodeActionRequest) (string, string, []lsp.CodeAction, error) {
+	if req.Path == "" {
+		return "", "", nil, fmt.Errorf("path is required")
+	}
+	if req.StartLine <= 0 {
+		return "", "", nil, fmt.Errorf("start_line is required")
+	}
+	if err := tools.CheckPath(req.Path); err != nil {
+		return "", "", nil, err
+	}
+	if manager == nil {
+		return "", "", nil, fmt.Errorf("code actions need a language server")
+	}
+
+	path := paths.Canonical(req.Path)
+	text, err := readText(path)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	if req.StartColumn == 0 {
+		req.StartColumn = 1
+	}
+	if req.EndLine == 0 {
+		req.EndLine = req.StartLine
+	}
+	start, err := lspPosition(text, req.StartLine, req.StartColumn)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if req.EndColumn == 0 {
+		req.EndColumn = req.StartColumn
+		if lines := types.SplitLines(text); req.EndLine != req.StartLine && req.EndLine <= len(lines) {
+			req.EndColumn = utf8.RuneCountInString(lines[req.EndLine-1]) + 1
+		}
+	}
+	end, err := lspPosition(text, req.EndLine, req.EndColumn)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if end.Line < start.Line || (end.Line == start.Line && end.Character < start.Character) {
+		return "", "", nil, fmt.Errorf("the range ends before it starts")
+	}
+
+	actions, err := manager.GetCodeActions(ctx, path, text, lsp.Range{Start: start, End: end}, req.Kinds)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("code actions: %w", err)
+	}
+	return path, text, actions, nil
+}
+
+func codeActionInfo(index int, action lsp.CodeAction) CodeActionInfo {
+	info := CodeActionInfo{Index: index, Title: action.Title, Kind: action.Kind, Preferred: action.IsPreferred}
+	if action.Disabled != nil {
+		info.Disabled = action.Disabled.Reason
+	}
+	for _, d := range action.Diagnostics {
+		info.Diagnostics = append(info.Diagnostics, d.Message)
+	}
+	if action.Command != nil {
+		info.Command = action.Command.Command
+	}
+	if action.Edit != nil {
+		if targets, err := workspaceEditPaths(*action.Edit); err == nil {
+			seen := make(map[string]bool)
+			for _, target := range targets {
+				if !seen[target] {
+					seen[target] = true
+					info.Files = append(info.Files, target)
+				}
+			}
+			sort.Strings(info.Files)
+		}
+	}
+	return info
+}
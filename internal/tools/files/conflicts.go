@@ -0,0 +1,529 @@
+package files
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alucardeht/may-la-mcp/internal/paths"
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+const (
+	defaultConflictFiles = 100
+	// maxConflictFileSize bounds the files searched for conflict markers
+	maxConflictFileSize = 10 * 1024 * 1024
+	// conflictSniffSize is how much of a file is checked for NUL bytes;
+	// binary files are not searched
+	conflictSniffSize = 8000
+)
+
+// Conflict sides a block can be resolved to
+const (
+	sideOurs       = "ours"
+	sideTheirs     = "theirs"
+	sideBase       = "base"
+	sideOursTheirs = "ours_theirs"
+	sideTheirsOurs = "theirs_ours"
+	sideNone       = "none"
+)
+
+// Conflict markers as git writes them; ||||||| opens the base section of
+// diff3 style conflicts
+const (
+	conflictOpen     = "<<<<<<<"
+	conflictBaseMark = "|||||||"
+	conflictSplit    = "======="
+	conflictClose    = ">>>>>>>"
+)
+
+type ConflictsRequest struct {
+	Path        string               `json:"path"`
+	Action      string               `json:"action,omitempty"`
+	Resolutions []ConflictResolution `json:"resolutions,omitempty"`
+	MaxFiles    int                  `json:"max_files,omitempty"`
+	Backup      bool                 `json:"backup,omitempty"`
+}
+
+// ConflictResolution settles one conflict block of a file, by choosing a
+// side or by supplying the merged text
+type ConflictResolution struct {
+	Conflict int     `json:"conflict"`
+	Choose   string  `json:"choose,omitempty"`
+	Content  *string `json:"content,omitempty"`
+}
+
+// Conflict is one block between <<<<<<< and >>>>>>> markers. Lines are
+// 1-based and include the markers; Base is only set for diff3 style
+// conflicts.
+type Conflict struct {
+	Index       int     `json:"index"`
+	StartLine   int     `json:"start_line"`
+	EndLine     int     `json:"end_line"`
+	OursLabel   string  `json:"ours_label,omitempty"`
+	TheirsLabel string  `json:"theirs_label,omitempty"`
+	BaseLabel   string  `json:"base_label,omitempty"`
+	Ours        string  `json:"ours"`
+	Base        *string `json:"base,omitempty"`
+	Theirs      string  `json:"theirs"`
+}
+
+type ConflictFile struct {
+	Path      string     `json:"path"`
+	Conflicts []Conflict `json:"conflicts"`
+	Error     string     `json:"error,omitempty"`
+}
+
+type ConflictsResponse struct {
+	Path      string         `json:"path"`
+	Files     []ConflictFile `json:"files"`
+	Count     int            `json:"count"`
+	Truncated bool           `json:"truncated,omitempty"`
+}
+
+type ConflictResolveResponse struct {
+	Path      string `json:"path"`
+	Resolved  int    `json:"resolved"`
+	Remaining int    `json:"remaining"`
+	Backup    string `json:"backup,omitempty"`
+	Diff      string `json:"diff,omitempty"`
+}
+
+type ConflictsTool struct{}
+
+func (t *ConflictsTool) Name() string {
+	return "conflicts"
+}
+
+func (t *ConflictsTool) Description() string {
+	return `Find and resolve merge conflicts.
+
+find (default) lists the files under path that hold conflict markers, each conflict split into its ours, theirs and, for diff3 style conflicts, base sections, with its lines and labels.
+
+resolve settles conflicts of one file, each by its index: choose a side (ours, theirs, base, ours_theirs, theirs_ours or none) or supply the merged content. Supplied content must hold no conflict markers; conflicts not listed are left as they are and counted as remaining.`
+}
+
+func (t *ConflictsTool) Title() string {
+	return "Merge Conflicts"
+}
+
+func (t *ConflictsTool) Annotations() map[string]bool {
+	return tools.DestructiveAnnotations()
+}
+
+func (t *ConflictsTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"path": {
+				"type": "string",
+				"description": "File or directory to search; the file to resolve"
+			},
+			"action": {
+				"type": "string",
+				"enum": ["find", "resolve"],
+				"description": "find lists conflicts, resolve settles them (default: find)"
+			},
+			"resolutions": {
+				"type": "array",
+				"items": {
+					"type": "object",
+					"properties": {
+						"conflict": {
+							"type": "integer",
+							"minimum": 1,
+							"description": "Index of the conflict in the file, as find reports it"
+						},
+						"choose": {
+							"type": "string",
+							"enum": ["ours", "theirs", "base", "ours_theirs", "theirs_ours", "none"],
+							"description": "Side to keep; ours_theirs and theirs_ours keep both in that order"
+						},
+						"content": {
+							"type": "string",
+							"description": "Merged text replacing the whole block, instead of choose"
+						}
+					},
+					"required": ["conflict"]
+				},
+				"description": "How to settle each conflict, for resolve"
+			},
+			"max_files": {
+				"type": "integer",
+				"description": "Maximum files listed by find (default: 100)"
+			},
+			"backup": {
+				"type": "boolean",
+				"description": "Keep the conflicted file as path.bak.<timestamp> when resolving (default: false)"
+			}
+		},
+		"required": ["path"]
+	}`)
+}
+
+func (t *ConflictsTool) Examples() []tools.Example {
+	return []tools.Example{
+		{
+			Description: "Keep their side of the first conflict and merge the second by hand",
+			Arguments:   json.RawMessage(`{"path": "/home/user/app/main.go", "action": "resolve", "resolutions": [{"conflict": 1, "choose": "theirs"}, {"conflict": 2, "content": "\treturn retry(ctx, fetch)\n"}]}`),
+			Result:      json.RawMessage(`{"path": "/home/user/app/main.go", "resolved": 2, "remaining": 0, "diff": "..."}`),
+		},
+	}
+}
+
+func (t *ConflictsTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	req, err := parseConflictsRequest(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Action == "find" {
+		return findConflicts(ctx, req)
+	}
+
+	unlock, err := lockPaths(ctx, req.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	planned, resp, err := planConflictResolution(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := commitEdits([]*plannedFile{planned}, editOptions{backup: req.Backup}); err != nil {
+		return nil, err
+	}
+	tools.RecordAccess(planned.path)
+	resp.Backup = planned.backup
+	return resp, nil
+}
+
+func (t *ConflictsTool) DryRun(ctx context.Context, input json.RawMessage) (*tools.Preview, error) {
+	req, err := parseConflictsRequest(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Action == "find" {
+		listing, err := findConflicts(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return &tools.Preview{Summary: "find makes no changes", Details: listing}, nil
+	}
+
+	planned, resp, err := planConflictResolution(req)
+	if err != nil {
+		return nil, err
+	}
+	summary := fmt.Sprintf("would resolve %d conflicts, leaving %d", resp.Resolved, resp.Remaining)
+	preview := newEditSetResult([]*plannedFile{planned}, false).Preview(summary)
+	preview.Details = map[string]interface{}{"resolved": resp.Resolved, "remaining": resp.Remaining}
+	return preview, nil
+}
+
+func parseConflictsRequest(input json.RawMessage) (ConflictsRequest, error) {
+	var req ConflictsRequest
+	if err := json.Unmarshal(input, &req); err != nil {
+		return req, fmt.Errorf("invalid request: %w", err)
+	}
+	if req.Path == "" {
+		return req, fmt.Errorf("path is required")
+	}
+	if err := tools.CheckPath(req.Path); err != nil {
+		return req, err
+	}
+	req.Path = paths.Canonical(req.Path)
+
+	switch req.Action {
+	case "", "find":
+		req.Action = "find"
+	case "resolve":
+		if len(req.Resolutions) == 0 {
+			return req, fmt.Errorf("resolutions are required to resolve")
+		}
+	default:
+		return req, fmt.Errorf("invalid action %q: expected find or resolve", req.Action)
+	}
+	return req, nil
+}
+
+// findConflicts lists the conflicts of req.Path, or of the files under it
+func findConflicts(ctx context.Context, req ConflictsRequest) (*ConflictsResponse, error) {
+	stat, err := os.Stat(req.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat path: %w", err)
+	}
+
+	maxFiles := req.MaxFiles
+	if maxFiles <= 0 {
+		maxFiles = defaultConflictFiles
+	}
+	resp := &ConflictsResponse{Path: req.Path, Files: []ConflictFile{}}
+
+	add := func(path string, content []byte) {
+		if !bytes.Contains(content, []byte(conflictOpen)) {
+			return
+		}
+		conflicts, err := parseConflicts(string(content))
+		if err != nil {
+			resp.Files = append(resp.Files, ConflictFile{Path: path, Conflicts: conflicts, Error: err.Error()})
+			resp.Count += len(conflicts)
+			return
+		}
+		if len(conflicts) > 0 {
+			resp.Files = append(resp.Files, ConflictFile{Path: path, Conflicts: conflicts})
+			resp.Count += len(conflicts)
+		}
+	}
+
+	if !stat.IsDir() {
+		content, err := os.ReadFile(req.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file: %w", err)
+		}
+		add(req.Path, content)
+		return resp, nil
+	}
+
+	err = filepath.WalkDir(req.Path, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" || (path != req.Path && tools.IsPathDenied(path)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || tools.IsPathDenied(path) {
+			return nil
+		}
+		if info, err := d.Info(); err != nil || info.Size() > maxConflictFileSize {
+			return nil
+		}
+		if len(resp.Files) >= maxFiles {
+			resp.Truncated = true
+			return filepath.SkipAll
+		}
+		content, err := os.ReadFile(path)
+		if err != nil || bytes.IndexByte(content[:min(len(content), conflictSniffSize)], 0) >= 0 {
+			return nil
+		}
+		add(path, content)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// conflictLine returns which marker line starts a conflict section, with
+// the label after it
+func conflictLine(line string) (string, string, bool) {
+	line = strings.TrimRight(line, "\r\n")
+	for _, marker := range []string{conflictOpen, conflictBaseMark, conflictSplit, conflictClose} {
+		rest, ok := strings.CutPrefix(line, marker)
+		if !ok {
+			continue
+		}
+		// a longer run of the marker character is not a marker
+		if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+			return "", "", false
+		}
+		if marker == conflictSplit && strings.TrimSpace(rest) != "" {
+			return "", "", false
+		}
+		return marker, strings.TrimSpace(rest), true
+	}
+	return "", "", false
+}
+
+// parseConflicts splits text into its conflict blocks. Conflicts found
+// before a malformed block are returned with the error.
+func parseConflicts(text string) ([]Conflict, error) {
+	lines := strings.SplitAfter(text, "\n")
+	var conflicts []Conflict
+	var current *Conflict
+	var section *strings.Builder
+	var ours, base, theirs strings.Builder
+	state := ""
+
+	for i, line := range lines {
+		marker, label, ok := conflictLine(line)
+		if !ok {
+			if current != nil {
+				section.WriteString(line)
+			}
+			continue
+		}
+		lineNo := i + 1
+
+		switch {
+		case marker == conflictOpen && state == "":
+			current = &Conflict{Index: len(conflicts) + 1, StartLine: lineNo, OursLabel: label}
+			ours.Reset()
+			base.Reset()
+			theirs.Reset()
+			section, state = &ours, conflictOpen
+		case marker == conflictBaseMark && state == conflictOpen:
+			current.BaseLabel = label
+			section, state = &base, conflictBaseMark
+		case marker == conflictSplit && (state == conflictOpen || state == conflictBaseMark):
+			if state == conflictBaseMark {
+				text := base.String()
+				current.Base = &text
+			}
+			section, state = &theirs, conflictSplit
+		case marker == conflictClose && state == conflictSplit:
+			current.EndLine = lineNo
+			current.TheirsLabel = label
+			current.Ours = ours.String()
+			current.Theirs = theirs.String()
+			conflicts = append(conflicts, *current)
+			current, section, state = nil, nil, ""
+		case state == "":
+			// a stray marker outside any conflict, e.g. in a heading
+			// underline; only an opening marker starts a block
+			continue
+		default:
+			return conflicts, fmt.Errorf("line %d: unexpected %s in the conflict starting at line %d", lineNo, marker, current.StartLine)
+		}
+	}
+	if current != nil {
+		return conflicts, fmt.Errorf("the conflict starting at line %d is not closed", current.StartLine)
+	}
+	return conflicts, nil
+}
+
+// resolveConflict returns the text a conflict block is replaced with
+func resolveConflict(c Conflict, r ConflictResolution) (string, error) {
+	if r.Content != nil {
+		if r.Choose != "" {
+			return "", fmt.Errorf("conflict %d: choose and content are exclusive", c.Index)
+		}
+		if hasConflictMarkers(*r.Content) {
+			return "", fmt.Errorf("conflict %d: the merged content still has conflict markers", c.Index)
+		}
+		content := *r.Content
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		return content, nil
+	}
+
+	switch r.Choose {
+	case sideOurs:
+		return c.Ours, nil
+	case sideTheirs:
+		return c.Theirs, nil
+	case sideBase:
+		if c.Base == nil {
+			return "", fmt.Errorf("conflict %d has no base section; it was not written in diff3 style", c.Index)
+		}
+		return *c.Base, nil
+	case sideOursTheirs:
+		return c.Ours + c.Theirs, nil
+	case sideTheirsOurs:
+		return c.Theirs + c.Ours, nil
+	case sideNone:
+		return "", nil
+	case "":
+		return "", fmt.Errorf("conflict %d: choose or content is required", c.Index)
+	default:
+		return "", fmt.Errorf("conflict %d: invalid choice %q", c.Index, r.Choose)
+	}
+}
+
+// hasConflictMarkers reports whether text holds a line that opens or
+// closes a conflict. A lone ======= is allowed, as it also underlines
+// headings.
+func hasConflictMarkers(text string) bool {
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if marker, _, ok := conflictLine(line); ok && marker != conflictSplit {
+			return true
+		}
+	}
+	return false
+}
+
+// planConflictResolution works out the content of req.Path with the
+// requested conflicts resolved
+func planConflictResolution(req ConflictsRequest) (*plannedFile, *ConflictResolveResponse, error) {
+	planned, err := loadPlannedFile(req.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !planned.existed {
+		return nil, nil, fmt.Errorf("file does not exist: %s", req.Path)
+	}
+
+	conflicts, err := parseConflicts(planned.before)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(conflicts) == 0 {
+		return nil, nil, fmt.Errorf("%s has no conflicts", req.Path)
+	}
+
+	replacements := make(map[int]string, len(req.Resolutions))
+	for _, r := range req.Resolutions {
+		if r.Conflict < 1 || r.Conflict > len(conflicts) {
+			return nil, nil, fmt.Errorf("no conflict %d: the file has %d", r.Conflict, len(conflicts))
+		}
+		if _, ok := replacements[r.Conflict]; ok {
+			return nil, nil, fmt.Errorf("conflict %d is resolved twice", r.Conflict)
+		}
+		text, err := resolveConflict(conflicts[r.Conflict-1], r)
+		if err != nil {
+			return nil, nil, err
+		}
+		replacements[r.Conflict] = text
+	}
+
+	lines := strings.SplitAfter(planned.before, "\n")
+	var b strings.Builder
+	next := 0
+	for _, c := range conflicts {
+		text, ok := replacements[c.Index]
+		if !ok {
+			continue
+		}
+		b.WriteString(strings.Join(lines[next:c.StartLine-1], ""))
+		b.WriteString(text)
+		next = c.EndLine
+	}
+	b.WriteString(strings.Join(lines[next:], ""))
+	planned.after = b.String()
+	planned.action = "modify"
+
+	remaining, err := parseConflicts(planned.after)
+	if err != nil {
+		return nil, nil, fmt.Errorf("the resolved file is malformed: %w", err)
+	}
+	if want := len(conflicts) - len(replacements); len(remaining) != want {
+		return nil, nil, fmt.Errorf("the resolved file has %d conflicts, expected %d", len(remaining), want)
+	}
+
+	diff := tools.DiffFile(planned.path, planned.before, planned.after, false)
+	return planned, &ConflictResolveResponse{
+		Path:      planned.path,
+		Resolved:  len(replacements),
+		Remaining: len(remaining),
+		Diff:      diff.Diff,
+	}, nil
+}
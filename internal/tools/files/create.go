@@ -74,38 +74,22 @@ func (t *CreateTool) Execute(ctx context.Context, input json.RawMessage) (interf
 	if err := json.Unmarshal(input, &req); err != nil {
 		return nil, fmt.Errorf("invalid request: %w", err)
 	}
-
-	if req.Path == "" {
-		return nil, fmt.Errorf("path is required")
+	if err := tools.CheckPath(req.Path); err != nil {
+		return nil, err
 	}
 
-	if req.Type != "file" && req.Type != "dir" {
-		return nil, fmt.Errorf("type must be 'file' or 'dir'")
+	unlock, err := lockPaths(ctx, req.Path)
+	if err != nil {
+		return nil, err
 	}
+	defer unlock()
 
-	stat, err := os.Stat(req.Path)
-	if err == nil {
-		if !req.Force {
-			return nil, fmt.Errorf("path already exists")
-		}
-		if req.Type == "dir" && !stat.IsDir() {
-			return nil, fmt.Errorf("path exists and is not a directory")
-		}
-		if req.Type == "file" && stat.IsDir() {
-			return nil, fmt.Errorf("path exists and is not a file")
-		}
+	_, mode, err := checkCreate(req)
+	if err != nil {
+		return nil, err
 	}
 
 	if req.Type == "dir" {
-		var mode os.FileMode = 0755
-		if req.Mode != "" {
-			parsedMode, err := parseMode(req.Mode)
-			if err != nil {
-				return nil, fmt.Errorf("invalid mode: %w", err)
-			}
-			mode = parsedMode
-		}
-
 		if err := os.MkdirAll(req.Path, mode); err != nil {
 			return nil, fmt.Errorf("failed to create directory: %w", err)
 		}
@@ -125,15 +109,6 @@ func (t *CreateTool) Execute(ctx context.Context, input json.RawMessage) (interf
 		}
 	}
 
-	var mode os.FileMode = 0644
-	if req.Mode != "" {
-		parsedMode, err := parseMode(req.Mode)
-		if err != nil {
-			return nil, fmt.Errorf("invalid mode: %w", err)
-		}
-		mode = parsedMode
-	}
-
 	if err := os.WriteFile(req.Path, []byte(req.Content), mode); err != nil {
 		return nil, fmt.Errorf("failed to create file: %w", err)
 	}
@@ -150,6 +125,89 @@ func (t *CreateTool) Execute(ctx context.Context, input json.RawMessage) (interf
 	}, nil
 }
 
+// checkCreate validates req and returns whether the path already exists
+// and the permissions to create it with.
+func checkCreate(req CreateRequest) (bool, os.FileMode, error) {
+	if req.Path == "" {
+		return false, 0, fmt.Errorf("path is required")
+	}
+
+	if req.Type != "file" && req.Type != "dir" {
+		return false, 0, fmt.Errorf("type must be 'file' or 'dir'")
+	}
+
+	stat, err := os.Stat(req.Path)
+	exists := err == nil
+	if exists {
+		if !req.Force {
+			return false, 0, fmt.Errorf("path already exists")
+		}
+		if req.Type == "dir" && !stat.IsDir() {
+			return false, 0, fmt.Errorf("path exists and is not a directory")
+		}
+		if req.Type == "file" && stat.IsDir() {
+			return false, 0, fmt.Errorf("path exists and is not a file")
+		}
+	}
+
+	var mode os.FileMode = 0644
+	if req.Type == "dir" {
+		mode = 0755
+	}
+	if req.Mode != "" {
+		parsedMode, err := parseMode(req.Mode)
+		if err != nil {
+			return false, 0, fmt.Errorf("invalid mode: %w", err)
+		}
+		mode = parsedMode
+	}
+
+	return exists, mode, nil
+}
+
+func (t *CreateTool) DryRun(ctx context.Context, input json.RawMessage) (*tools.Preview, error) {
+	var req CreateRequest
+	if err := json.Unmarshal(input, &req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+	if err := tools.CheckPath(req.Path); err != nil {
+		return nil, err
+	}
+
+	exists, _, err := checkCreate(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Type == "dir" {
+		if exists {
+			return &tools.Preview{Summary: fmt.Sprintf("directory %s already exists; nothing would change", req.Path)}, nil
+		}
+		return &tools.Preview{
+			Summary: fmt.Sprintf("would create directory %s", req.Path),
+			Created: tools.MissingDirs(req.Path),
+		}, nil
+	}
+
+	if exists {
+		before, _, err := tools.ReadExisting(req.Path)
+		if err != nil {
+			return nil, err
+		}
+		return &tools.Preview{
+			Summary:  fmt.Sprintf("would replace %s (%d -> %d bytes)", req.Path, len(before), len(req.Content)),
+			Modified: []string{req.Path},
+			Diffs:    []tools.FileDiff{tools.DiffFile(req.Path, before, req.Content, false)},
+		}, nil
+	}
+
+	return &tools.Preview{
+		Summary: fmt.Sprintf("would create file %s (%d bytes)", req.Path, len(req.Content)),
+		Created: append(tools.MissingDirs(filepath.Dir(req.Path)), req.Path),
+		Diffs:   []tools.FileDiff{tools.DiffFile(req.Path, "", req.Content, true)},
+	}, nil
+}
+
 func parseMode(modeStr string) (os.FileMode, error) {
 	var mode os.FileMode
 	_, err := fmt.Sscanf(modeStr, "%o", &mode)
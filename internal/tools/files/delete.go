@@ -62,17 +62,19 @@ func (t *DeleteTool) Execute(ctx context.Context, input json.RawMessage) (interf
 	if err := json.Unmarshal(input, &req); err != nil {
 		return nil, fmt.Errorf("invalid request: %w", err)
 	}
+	if err := tools.CheckPath(req.Path); err != nil {
+		return nil, err
+	}
 
-	if req.Path == "" {
-		return nil, fmt.Errorf("path is required")
+	unlock, err := lockPaths(ctx, req.Path)
+	if err != nil {
+		return nil, err
 	}
+	defer unlock()
 
-	stat, err := os.Stat(req.Path)
+	stat, err := checkDelete(req)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("path does not exist")
-		}
-		return nil, fmt.Errorf("failed to stat path: %w", err)
+		return nil, err
 	}
 
 	itemType := "file"
@@ -82,13 +84,6 @@ func (t *DeleteTool) Execute(ctx context.Context, input json.RawMessage) (interf
 		itemType = "dir"
 		size = 0
 
-		if !req.Recursive && !req.Force {
-			entries, err := os.ReadDir(req.Path)
-			if err == nil && len(entries) > 0 {
-				return nil, fmt.Errorf("directory not empty, use recursive=true to delete")
-			}
-		}
-
 		if err := os.RemoveAll(req.Path); err != nil {
 			return nil, fmt.Errorf("failed to delete directory: %w", err)
 		}
@@ -106,6 +101,64 @@ func (t *DeleteTool) Execute(ctx context.Context, input json.RawMessage) (interf
 	}, nil
 }
 
+// checkDelete validates req and returns the path's file info. A non-empty
+// directory needs recursive or force.
+func checkDelete(req DeleteRequest) (os.FileInfo, error) {
+	if req.Path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+
+	stat, err := os.Stat(req.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("path does not exist")
+		}
+		return nil, fmt.Errorf("failed to stat path: %w", err)
+	}
+
+	if stat.IsDir() && !req.Recursive && !req.Force {
+		entries, err := os.ReadDir(req.Path)
+		if err == nil && len(entries) > 0 {
+			return nil, fmt.Errorf("directory not empty, use recursive=true to delete")
+		}
+	}
+
+	return stat, nil
+}
+
+func (t *DeleteTool) DryRun(ctx context.Context, input json.RawMessage) (*tools.Preview, error) {
+	var req DeleteRequest
+	if err := json.Unmarshal(input, &req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+	if err := tools.CheckPath(req.Path); err != nil {
+		return nil, err
+	}
+
+	stat, err := checkDelete(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if !stat.IsDir() {
+		return &tools.Preview{
+			Summary: fmt.Sprintf("would delete file %s (%d bytes)", req.Path, stat.Size()),
+			Deleted: []string{req.Path},
+		}, nil
+	}
+
+	// Contents are listed before the directory itself, in removal order
+	contents, total := listTree(req.Path, maxPreviewEntries)
+	return &tools.Preview{
+		Summary: fmt.Sprintf("would delete directory %s and %d entries inside it", req.Path, total),
+		Deleted: append(contents, req.Path),
+		Details: map[string]interface{}{
+			"entries":   total,
+			"truncated": total > len(contents),
+		},
+	}, nil
+}
+
 func (t *DeleteTool) Title() string {
 	return "Delete File or Directory"
 }
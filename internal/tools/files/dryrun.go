@@ -0,0 +1,26 @@
+package files
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// maxPreviewEntries bounds the directory entries listed by a delete preview
+const maxPreviewEntries = 200
+
+// listTree returns the paths under dir, up to limit, and the total count
+func listTree(dir string, limit int) ([]string, int) {
+	var paths []string
+	total := 0
+	filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || path == dir {
+			return nil
+		}
+		total++
+		if len(paths) < limit {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	return paths, total
+}
@@ -0,0 +1,258 @@
+package files
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/alucardeht/may-la-mcp/internal/gitignore"
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+const (
+	defaultDuDepth = 2
+	maxDuDepth     = 10
+	defaultDuTop   = 20
+	maxDuTop       = 200
+)
+
+type DuRequest struct {
+	Path           string `json:"path"`
+	Depth          int    `json:"depth,omitempty"`
+	Top            int    `json:"top,omitempty"`
+	ExcludeIgnored bool   `json:"exclude_ignored,omitempty"`
+}
+
+type DuEntry struct {
+	Path    string `json:"path"`
+	Size    int64  `json:"size"`
+	Files   int    `json:"files,omitempty"`
+	Ignored bool   `json:"ignored,omitempty"`
+}
+
+type DuResponse struct {
+	Path         string    `json:"path"`
+	Size         int64     `json:"size"`
+	Files        int       `json:"files"`
+	Dirs         int       `json:"dirs"`
+	IgnoredSize  int64     `json:"ignored_size"`
+	Directories  []DuEntry `json:"directories"`
+	LargestFiles []DuEntry `json:"largest_files"`
+	Unreadable   int       `json:"unreadable,omitempty"`
+}
+
+type DuTool struct{}
+
+func (t *DuTool) Name() string {
+	return "du"
+}
+
+func (t *DuTool) Description() string {
+	return `Report disk usage under a directory: the total, the largest subdirectories down to depth, and the largest files anywhere below.
+
+Sizes are apparent sizes in bytes, summed over regular files; symlinks are not followed. Entries that a .gitignore ignores (node_modules, build output, ...) are marked ignored and their share of the total is reported as ignored_size, which points at what can be cleaned up; with exclude_ignored they are left out altogether.`
+}
+
+func (t *DuTool) Title() string {
+	return "Disk Usage"
+}
+
+func (t *DuTool) Annotations() map[string]bool {
+	return tools.ReadOnlyAnnotations()
+}
+
+func (t *DuTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"path": {
+				"type": "string",
+				"description": "Directory to measure"
+			},
+			"depth": {
+				"type": "integer",
+				"description": "Deepest level of subdirectories reported, 1 for direct children (default: 2, max: 10)"
+			},
+			"top": {
+				"type": "integer",
+				"description": "Number of largest directories and files reported (default: 20, max: 200)"
+			},
+			"exclude_ignored": {
+				"type": "boolean",
+				"description": "Leave out everything .gitignore ignores (default: false)"
+			}
+		},
+		"required": ["path"]
+	}`)
+}
+
+func (t *DuTool) OutputSchema() json.RawMessage {
+	return tools.OutputSchemaOf(DuResponse{})
+}
+
+func (t *DuTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var req DuRequest
+	if err := json.Unmarshal(input, &req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+	if req.Path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+	if err := tools.CheckPath(req.Path); err != nil {
+		return nil, err
+	}
+
+	depth := req.Depth
+	if depth <= 0 {
+		depth = defaultDuDepth
+	}
+	depth = min(depth, maxDuDepth)
+	top := req.Top
+	if top <= 0 {
+		top = defaultDuTop
+	}
+	top = min(top, maxDuTop)
+
+	stat, err := os.Stat(req.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat path: %w", err)
+	}
+	if !stat.IsDir() {
+		return nil, fmt.Errorf("path is not a directory: %s", req.Path)
+	}
+
+	root := filepath.Clean(req.Path)
+	resp := &DuResponse{Path: root}
+	rules := gitignore.Load(root, "")
+	dirs := map[string]*DuEntry{}
+	largest := newTopEntries(top)
+
+	err = filepath.WalkDir(root, func(abs string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if abs == root {
+				return err
+			}
+			resp.Unreadable++
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if abs == root {
+			return nil
+		}
+		if tools.IsPathDenied(abs) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		relPath, err := filepath.Rel(root, abs)
+		if err != nil {
+			return nil
+		}
+		rel := filepath.ToSlash(relPath)
+		parent := dirs[path.Dir(rel)]
+		ignored := (parent != nil && parent.Ignored) || rules.Ignored(rel, d.IsDir())
+		if ignored && req.ExcludeIgnored {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if d.IsDir() {
+			resp.Dirs++
+			dirs[rel] = &DuEntry{Path: abs, Ignored: ignored}
+			if !ignored {
+				rules = append(rules, gitignore.Load(root, rel)...)
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			resp.Unreadable++
+			return nil
+		}
+
+		size := info.Size()
+		resp.Size += size
+		resp.Files++
+		if ignored {
+			resp.IgnoredSize += size
+		}
+		for dir := path.Dir(rel); dir != "."; dir = path.Dir(dir) {
+			if entry := dirs[dir]; entry != nil {
+				entry.Size += size
+				entry.Files++
+			}
+		}
+		largest.add(DuEntry{Path: abs, Size: size, Ignored: ignored})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	shallow := newTopEntries(top)
+	for rel, entry := range dirs {
+		if strings.Count(rel, "/") < depth {
+			shallow.add(*entry)
+		}
+	}
+	resp.Directories = shallow.sorted()
+	resp.LargestFiles = largest.sorted()
+	return resp, nil
+}
+
+// topEntries keeps the n largest entries added to it
+type topEntries struct {
+	n       int
+	entries []DuEntry
+}
+
+func newTopEntries(n int) *topEntries {
+	return &topEntries{n: n}
+}
+
+func (t *topEntries) add(entry DuEntry) {
+	if len(t.entries) < t.n {
+		t.entries = append(t.entries, entry)
+		return
+	}
+	smallest := 0
+	for i := range t.entries {
+		if t.entries[i].Size < t.entries[smallest].Size {
+			smallest = i
+		}
+	}
+	if entry.Size > t.entries[smallest].Size {
+		t.entries[smallest] = entry
+	}
+}
+
+// sorted returns the entries largest first, by path among equal sizes
+func (t *topEntries) sorted() []DuEntry {
+	entries := append([]DuEntry{}, t.entries...)
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Size != entries[j].Size {
+			return entries[i].Size > entries[j].Size
+		}
+		return entries[i].Path < entries[j].Path
+	})
+	return entries
+}
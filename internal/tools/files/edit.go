@@ -5,24 +5,57 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
-	"strconv"
+	"slices"
+	"sort"
 	"strings"
-	"time"
 
+	"github.com/alucardeht/may-la-mcp/internal/notebook"
 	"github.com/alucardeht/may-la-mcp/internal/tools"
 )
 
+// Edit operations. An edit without an operation is a replace: search/replace
+// when search is set, otherwise a startLine-endLine range replacement.
+const (
+	opReplace      = "replace"
+	opInsertAfter  = "insert_after"
+	opInsertBefore = "insert_before"
+	opAppend       = "append"
+	opPrepend      = "prepend"
+	opDeleteLines  = "delete_lines"
+)
+
+// Edit orders. original, the default, resolves line numbers and patterns
+// against the file before the call and applies line edits bottom-up;
+// sequential applies each edit to the result of the ones before it.
+const (
+	orderSequential = "sequential"
+	orderOriginal   = "original"
+)
+
 type EditOperation struct {
+	Operation  string `json:"operation,omitempty"`
 	StartLine  int    `json:"startLine,omitempty"`
 	EndLine    int    `json:"endLine,omitempty"`
+	Line       int    `json:"line,omitempty"`
+	Pattern    string `json:"pattern,omitempty"`
 	NewContent string `json:"newContent,omitempty"`
 	Search     string `json:"search,omitempty"`
 	Replace    string `json:"replace,omitempty"`
+	// PreserveCase replaces search in all its case styles across the file,
+	// each with replace spelled the same way
+	PreserveCase bool `json:"preserveCase,omitempty"`
 }
 
 type EditRequest struct {
-	Path  string          `json:"path"`
-	Edits []EditOperation `json:"edits"`
+	Path           string          `json:"path"`
+	Edits          []EditOperation `json:"edits"`
+	MatchMode      string          `json:"matchMode,omitempty"`
+	FuzzyThreshold float64         `json:"fuzzyThreshold,omitempty"`
+	LineEndings    string          `json:"lineEndings,omitempty"`
+	BOM            string          `json:"bom,omitempty"`
+	Order          string          `json:"order,omitempty"`
+	// Cell targets the source of one notebook cell, by its 0-based index
+	Cell *int `json:"cell,omitempty"`
 }
 
 type EditResponse struct {
@@ -40,7 +73,7 @@ func (t *EditTool) Name() string {
 }
 
 func (t *EditTool) Description() string {
-	return "Edit file contents with multiple operations using line ranges or text search/replace"
+	return "Edit file contents with multiple operations: text search/replace, line range replacement, insert_after/insert_before a line or pattern, append, prepend and delete_lines. Line numbers and patterns refer to the file before the call: search/replace edits run first, then line-based edits apply bottom-up so they do not shift each other; with order sequential, edits apply in request order, each to the result of the ones before it. Use matchMode ignore_whitespace or fuzzy when search text may differ from the file in indentation, tabs vs spaces or small details. Edits work on LF lines without the BOM; the file keeps its line endings and BOM unless lineEndings/bom say otherwise."
 }
 
 func (t *EditTool) Schema() json.RawMessage {
@@ -57,17 +90,30 @@ func (t *EditTool) Schema() json.RawMessage {
 				"items": {
 					"type": "object",
 					"properties": {
+						"operation": {
+							"type": "string",
+							"enum": ["replace", "insert_after", "insert_before", "append", "prepend", "delete_lines"],
+							"description": "Operation to apply (default: replace)"
+						},
 						"startLine": {
 							"type": "integer",
-							"description": "Start line number (1-indexed)"
+							"description": "Start line number for replace and delete_lines (1-indexed)"
 						},
 						"endLine": {
 							"type": "integer",
-							"description": "End line number (1-indexed, inclusive)"
+							"description": "End line number for replace and delete_lines (1-indexed, inclusive; delete_lines defaults to startLine)"
+						},
+						"line": {
+							"type": "integer",
+							"description": "Anchor line for insert_after and insert_before (1-indexed)"
+						},
+						"pattern": {
+							"type": "string",
+							"description": "Anchor text for insert_after and insert_before; the first line containing it is used"
 						},
 						"newContent": {
 							"type": "string",
-							"description": "Replacement content"
+							"description": "Replacement content for a line range, or the lines to insert"
 						},
 						"search": {
 							"type": "string",
@@ -76,16 +122,75 @@ func (t *EditTool) Schema() json.RawMessage {
 						"replace": {
 							"type": "string",
 							"description": "Replacement text"
+						},
+						"preserveCase": {
+							"type": "boolean",
+							"description": "For renames: search and replace are identifiers, and every occurrence of search across the file in camelCase, PascalCase, snake_case or SCREAMING_SNAKE_CASE is replaced by replace in the same style (fooBar -> bazQux also turns FOO_BAR into BAZ_QUX). Only with matchMode exact"
 						}
 					}
 				},
 				"minItems": 1
+			},
+			"matchMode": {
+				"type": "string",
+				"enum": ["exact", "ignore_whitespace", "fuzzy"],
+				"description": "How search text is matched. exact (default) replaces every occurrence in the first line containing it; ignore_whitespace replaces the first match where any whitespace run matches any other, across lines; fuzzy tries ignore_whitespace, then replaces the most similar block of whole lines and fails with the best match location when it is below fuzzyThreshold"
+			},
+			"fuzzyThreshold": {
+				"type": "number",
+				"description": "Minimum similarity (0-1) for fuzzy matches (default: 0.8)"
+			},
+			"lineEndings": {
+				"type": "string",
+				"enum": ["lf", "crlf", "preserve"],
+				"description": "Line endings of the edited file (default: preserve)"
+			},
+			"bom": {
+				"type": "string",
+				"enum": ["add", "strip", "preserve"],
+				"description": "UTF-8 byte order mark of the edited file (default: preserve)"
+			},
+			"order": {
+				"type": "string",
+				"enum": ["original", "sequential"],
+				"description": "original (default) runs search/replace edits first, then applies line-based edits bottom-up with line numbers and patterns referring to the file before the call; overlapping line ranges are an error. sequential applies edits in request order, each seeing the changes of the ones before it, so line numbers refer to the file as earlier edits left it"
+			},
+			"cell": {
+				"type": "integer",
+				"minimum": 0,
+				"description": "For Jupyter notebooks: index of the cell to edit (0-based, as read returns them); line numbers and search text then refer to that cell's source"
 			}
 		},
 		"required": ["path", "edits"]
 	}`)
 }
 
+func (t *EditTool) Examples() []tools.Example {
+	return []tools.Example{
+		{
+			Description: "Replace text in the first line that contains it",
+			Arguments:   json.RawMessage(`{"path": "/home/user/app/main.go", "edits": [{"search": "timeout := 5", "replace": "timeout := 30"}]}`),
+			Result:      json.RawMessage(`{"path": "/home/user/app/main.go", "modified": true, "size": 1482, "lines": 61, "editsApplied": 1}`),
+		},
+		{
+			Description: "Replace lines 10 to 12 with new content",
+			Arguments:   json.RawMessage(`{"path": "/home/user/app/main.go", "edits": [{"startLine": 10, "endLine": 12, "newContent": "func main() {\n\trun()\n}"}]}`),
+		},
+		{
+			Description: "Rename a variable in the third cell of a notebook",
+			Arguments:   json.RawMessage(`{"path": "/home/user/analysis.ipynb", "cell": 2, "edits": [{"search": "df = load()", "replace": "sales = load()"}]}`),
+		},
+		{
+			Description: "Rename an identifier in every case style it is spelled in",
+			Arguments:   json.RawMessage(`{"path": "/home/user/app/cart.go", "edits": [{"search": "fooBar", "replace": "bazQux", "preserveCase": true}]}`),
+		},
+		{
+			Description: "Insert a line after the first line containing a pattern, tolerating whitespace differences",
+			Arguments:   json.RawMessage(`{"path": "/home/user/app/main.go", "edits": [{"operation": "insert_after", "pattern": "import (", "newContent": "\t\"os\""}], "matchMode": "ignore_whitespace"}`),
+		},
+	}
+}
+
 func (t *EditTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
@@ -102,82 +207,355 @@ func (t *EditTool) Execute(ctx context.Context, input json.RawMessage) (interfac
 	if len(req.Edits) == 0 {
 		return nil, fmt.Errorf("at least one edit operation is required")
 	}
+	if err := tools.CheckPath(req.Path); err != nil {
+		return nil, err
+	}
+
+	unlock, err := lockPaths(ctx, req.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
 
 	content, err := os.ReadFile(req.Path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
 
-	lines := strings.Split(string(content), "\n")
-	if len(lines) > 0 && lines[len(lines)-1] == "" {
-		lines = lines[:len(lines)-1]
+	newContent, appliedCount, err := editContent(string(content), req)
+	if err != nil {
+		return nil, err
+	}
+
+	tempPath, err := writeTemp(req.Path, []byte(newContent), 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := os.Rename(tempPath, req.Path); err != nil {
+		os.Remove(tempPath)
+		return nil, fmt.Errorf("failed to rename temp file: %w", err)
 	}
 
-	originalLines := make([]string, len(lines))
-	copy(originalLines, lines)
+	stat, err := os.Stat(req.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat edited file: %w", err)
+	}
+
+	tools.RecordAccess(req.Path)
+	finalLines := strings.Count(newContent, "\n")
+	if newContent == "" {
+		finalLines = 0
+	}
+
+	return EditResponse{
+		Path:      req.Path,
+		Modified:  newContent != string(content),
+		Size:      stat.Size(),
+		Lines:     finalLines,
+		EditsApplied: appliedCount,
+	}, nil
+}
+
+// editContent applies the edits of req to content, or to the source of the
+// notebook cell req.Cell targets
+func editContent(content string, req EditRequest) (string, int, error) {
+	if req.Cell == nil {
+		return applyEdits(content, req)
+	}
+
+	nb, err := notebook.Parse([]byte(content))
+	if err != nil {
+		return "", 0, fmt.Errorf("cell is only valid for Jupyter notebooks: %w", err)
+	}
+	cell, err := nb.Cell(*req.Cell)
+	if err != nil {
+		return "", 0, err
+	}
+	source, appliedCount, err := applyEdits(cell.Source, req)
+	if err != nil {
+		return "", 0, err
+	}
+	// Jupyter saves a cell without a final newline; keep it that way
+	if !strings.HasSuffix(cell.Source, "\n") {
+		source = strings.TrimSuffix(source, "\n")
+	}
+	if source == cell.Source {
+		return content, appliedCount, nil
+	}
+	if err := nb.SetSource(*req.Cell, source); err != nil {
+		return "", 0, err
+	}
+	data, err := nb.Marshal()
+	if err != nil {
+		return "", 0, err
+	}
+	return string(data), appliedCount, nil
+}
+
+// lineEdit replaces the lines [start, end) with lines; start == end
+// inserts before the line at start.
+type lineEdit struct {
+	start, end int
+	lines      []string
+	index      int
+}
+
+// applyEdits applies edits to content and returns the new content and the
+// number of edits that matched. By default search/replace edits run first,
+// in request order, and line-based edits address the original lines and
+// are applied bottom-up, so they do not shift each other; overlapping
+// ranges are an error and insertions at the same point keep their request
+// order. With order sequential, edits run in request order, each on the
+// result of the ones before it. Search/replace edits use the request's
+// match mode. The file's BOM and CRLF line endings are removed before
+// editing and laid out again according to the request's policy.
+func applyEdits(content string, req EditRequest) (string, int, error) {
+	match, err := newMatchOptions(req)
+	if err != nil {
+		return "", 0, err
+	}
+
+	order := req.Order
+	switch order {
+	case "":
+		order = orderOriginal
+	case orderSequential, orderOriginal:
+	default:
+		return "", 0, fmt.Errorf("invalid order %q: expected sequential or original", req.Order)
+	}
+
+	policy, err := newFormatPolicy(req.LineEndings, req.BOM)
+	if err != nil {
+		return "", 0, err
+	}
+	format := detectFormat(content)
+	content = format.decode(content)
+
+	var original []string
+	if content != "" {
+		original = strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	}
+
+	buf := newEditBuffer(original)
 
 	appliedCount := 0
-	for _, edit := range req.Edits {
-		if edit.Search != "" {
-			for i := 0; i < len(lines); i++ {
-				if strings.Contains(lines[i], edit.Search) {
-					lines[i] = strings.ReplaceAll(lines[i], edit.Search, edit.Replace)
-					appliedCount++
-					break
-				}
+	var pending []lineEdit
+	for i, edit := range req.Edits {
+		operation := edit.Operation
+		if operation == "" {
+			operation = opReplace
+		}
+
+		if operation == opReplace && edit.Search != "" {
+			matched, err := replaceSearch(buf, edit, match)
+			if err != nil {
+				return "", 0, fmt.Errorf("edit %d: %w", i, err)
 			}
-		} else if edit.StartLine > 0 && edit.EndLine > 0 {
-			if edit.StartLine < 1 || edit.EndLine < edit.StartLine || edit.EndLine > len(lines) {
-				return nil, fmt.Errorf("invalid line range: %d-%d (file has %d lines)", edit.StartLine, edit.EndLine, len(lines))
+			if matched {
+				appliedCount++
 			}
+			continue
+		}
 
-			startIdx := edit.StartLine - 1
-			endIdx := edit.EndLine
-
-			newLines := append([]string{}, lines[:startIdx]...)
-			if edit.NewContent != "" {
-				newLines = append(newLines, strings.Split(edit.NewContent, "\n")...)
+		if order == orderSequential {
+			lines := buf.result()
+			le, ok, err := resolveEdit(operation, edit, lines)
+			if err != nil {
+				return "", 0, fmt.Errorf("edit %d: %w", i, err)
+			}
+			if ok {
+				buf = newEditBuffer(slices.Replace(lines, le.start, le.end, le.lines...))
+				appliedCount++
 			}
-			newLines = append(newLines, lines[endIdx:]...)
-			lines = newLines
-			appliedCount++
+			continue
+		}
+
+		le, ok, err := resolveEdit(operation, edit, original)
+		if err != nil {
+			return "", 0, fmt.Errorf("edit %d: %w", i, err)
+		}
+		if !ok {
+			continue
 		}
+		le.index = i
+		pending = append(pending, le)
+		appliedCount++
 	}
 
+	// Bottom-up: later positions first; at the same position a range goes
+	// before insertions, and insertions go in reverse request order so each
+	// one lands above the ones requested after it.
+	sort.Slice(pending, func(a, b int) bool {
+		ea, eb := pending[a], pending[b]
+		if ea.start != eb.start {
+			return ea.start > eb.start
+		}
+		if (ea.end > ea.start) != (eb.end > eb.start) {
+			return ea.end > ea.start
+		}
+		return ea.index > eb.index
+	})
+
+	for k, le := range pending {
+		if k > 0 && le.end > pending[k-1].start {
+			return "", 0, fmt.Errorf("edits %d and %d overlap", le.index, pending[k-1].index)
+		}
+		buf.splice(le.start, le.end, le.lines)
+	}
+
+	lines := buf.result()
 	if len(lines) == 0 {
 		lines = []string{""}
 	}
 
 	newContent := strings.Join(lines, "\n")
-	if !strings.HasSuffix(newContent, "\n") && len(originalLines) > 0 && strings.Contains(string(content), "\n") {
+	if !strings.HasSuffix(newContent, "\n") && len(original) > 0 && strings.Contains(content, "\n") {
 		newContent += "\n"
 	}
 
-	tempPath := req.Path + ".tmp." + strconv.FormatInt(time.Now().UnixNano(), 10)
-	if err := os.WriteFile(tempPath, []byte(newContent), 0644); err != nil {
-		return nil, fmt.Errorf("failed to write temp file: %w", err)
+	return policy.apply(newContent, &format), appliedCount, nil
+}
+
+// replaceSearch applies a search/replace edit to buf and reports whether
+// it matched
+func replaceSearch(buf *editBuffer, edit EditOperation, match matchOptions) (bool, error) {
+	switch {
+	case edit.PreserveCase && match.mode != matchExact:
+		return false, fmt.Errorf("preserveCase only works with matchMode exact")
+	case edit.PreserveCase:
+		count, err := buf.replaceCases(edit.Search, edit.Replace)
+		return count > 0, err
+	case match.mode == matchIgnoreWhitespace:
+		return buf.replaceLoose(edit.Search, edit.Replace)
+	case match.mode == matchFuzzy:
+		err := buf.replaceFuzzy(edit.Search, edit.Replace, match.threshold)
+		return err == nil, err
+	default:
+		return buf.replaceExact(edit.Search, edit.Replace), nil
 	}
-	if err := os.Rename(tempPath, req.Path); err != nil {
-		os.Remove(tempPath)
-		return nil, fmt.Errorf("failed to rename temp file: %w", err)
+}
+
+// resolveEdit maps a line-based edit onto lines. It reports
+// false for an edit that matches nothing, such as a pattern not found.
+func resolveEdit(operation string, edit EditOperation, lines []string) (lineEdit, bool, error) {
+	switch operation {
+	case opReplace:
+		if edit.StartLine <= 0 || edit.EndLine <= 0 {
+			return lineEdit{}, false, nil
+		}
+		if err := checkLineRange(edit.StartLine, edit.EndLine, len(lines)); err != nil {
+			return lineEdit{}, false, err
+		}
+		var replacement []string
+		if edit.NewContent != "" {
+			replacement = strings.Split(edit.NewContent, "\n")
+		}
+		return lineEdit{start: edit.StartLine - 1, end: edit.EndLine, lines: replacement}, true, nil
+
+	case opDeleteLines:
+		if edit.StartLine <= 0 {
+			return lineEdit{}, false, fmt.Errorf("delete_lines requires startLine")
+		}
+		endLine := edit.EndLine
+		if endLine == 0 {
+			endLine = edit.StartLine
+		}
+		if err := checkLineRange(edit.StartLine, endLine, len(lines)); err != nil {
+			return lineEdit{}, false, err
+		}
+		return lineEdit{start: edit.StartLine - 1, end: endLine}, true, nil
+
+	case opInsertAfter, opInsertBefore:
+		at, ok, err := findAnchor(operation, edit, lines)
+		if err != nil || !ok {
+			return lineEdit{}, false, err
+		}
+		if operation == opInsertAfter {
+			at++
+		}
+		return lineEdit{start: at, end: at, lines: insertedLines(edit.NewContent)}, true, nil
+
+	case opAppend:
+		return lineEdit{start: len(lines), end: len(lines), lines: insertedLines(edit.NewContent)}, true, nil
+
+	case opPrepend:
+		return lineEdit{start: 0, end: 0, lines: insertedLines(edit.NewContent)}, true, nil
+
+	default:
+		return lineEdit{}, false, fmt.Errorf("unknown operation %q", operation)
 	}
+}
 
-	stat, err := os.Stat(req.Path)
+// findAnchor returns the index of the line an insertion is anchored to: the
+// first line containing pattern, or line when no pattern is given.
+func findAnchor(operation string, edit EditOperation, lines []string) (int, bool, error) {
+	if edit.Pattern != "" {
+		for i, line := range lines {
+			if strings.Contains(line, edit.Pattern) {
+				return i, true, nil
+			}
+		}
+		return 0, false, nil
+	}
+
+	if edit.Line <= 0 {
+		return 0, false, fmt.Errorf("%s requires line or pattern", operation)
+	}
+	if edit.Line > len(lines) {
+		return 0, false, fmt.Errorf("invalid line: %d (file has %d lines)", edit.Line, len(lines))
+	}
+	return edit.Line - 1, true, nil
+}
+
+func checkLineRange(startLine, endLine, total int) error {
+	if startLine < 1 || endLine < startLine || endLine > total {
+		return fmt.Errorf("invalid line range: %d-%d (file has %d lines)", startLine, endLine, total)
+	}
+	return nil
+}
+
+// insertedLines splits content into the lines to insert. One trailing
+// newline is ignored, so "text" and "text\n" insert the same line.
+func insertedLines(content string) []string {
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+func (t *EditTool) DryRun(ctx context.Context, input json.RawMessage) (*tools.Preview, error) {
+	var req EditRequest
+	if err := json.Unmarshal(input, &req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	if req.Path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+
+	if len(req.Edits) == 0 {
+		return nil, fmt.Errorf("at least one edit operation is required")
+	}
+	if err := tools.CheckPath(req.Path); err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(req.Path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to stat edited file: %w", err)
+		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
-	finalLines := strings.Count(newContent, "\n")
-	if newContent == "" {
-		finalLines = 0
+
+	newContent, appliedCount, err := editContent(string(content), req)
+	if err != nil {
+		return nil, err
 	}
 
-	return EditResponse{
-		Path:      req.Path,
-		Modified:  newContent != string(content),
-		Size:      stat.Size(),
-		Lines:     finalLines,
-		EditsApplied: appliedCount,
-	}, nil
+	preview := &tools.Preview{
+		Summary: fmt.Sprintf("would apply %d of %d edits to %s", appliedCount, len(req.Edits), req.Path),
+		Details: map[string]interface{}{"editsApplied": appliedCount},
+	}
+	if newContent != string(content) {
+		preview.Modified = []string{req.Path}
+		preview.Diffs = []tools.FileDiff{tools.DiffFile(req.Path, string(content), newContent, false)}
+	}
+	return preview, nil
 }
 
 func (t *EditTool) Title() string {
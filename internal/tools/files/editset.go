@@ -0,0 +1,237 @@
+package files
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+// plannedFile is the planned outcome of a multi-file edit, such as a patch
+// or a WorkspaceEdit, for one path
+type plannedFile struct {
+	path    string
+	action  string
+	existed bool
+	mode    os.FileMode
+	before  string
+	after   string
+	// hash is the SHA-256 of before; the file must still have it when the
+	// edit is committed
+	hash   string
+	hunks  []HunkResult
+	backup string
+}
+
+// editOptions tune how commitEdits writes the planned files
+type editOptions struct {
+	// backup keeps the previous content of modified and deleted files as
+	// path.bak.<unix nanos>, as write does
+	backup bool
+}
+
+// EditSetFile is one file of a multi-file edit. Hash and NewHash are the
+// SHA-256 of its content before and after; Backup is where the previous
+// content was kept.
+type EditSetFile struct {
+	Path    string `json:"path"`
+	Action  string `json:"action"`
+	Hash    string `json:"hash,omitempty"`
+	NewHash string `json:"new_hash,omitempty"`
+	Backup  string `json:"backup,omitempty"`
+}
+
+// EditSetResult is the outcome of a multi-file edit: the files it changes
+// and one unified diff of them all
+type EditSetResult struct {
+	Applied bool          `json:"applied"`
+	Files   []EditSetFile `json:"files"`
+	Diff    string        `json:"diff,omitempty"`
+	// Truncated is set when the diff of a large file was cut
+	Truncated bool `json:"truncated,omitempty"`
+
+	diffs []tools.FileDiff
+}
+
+func loadPlannedFile(path string) (*plannedFile, error) {
+	if err := tools.CheckPath(path); err != nil {
+		return nil, err
+	}
+	f := &plannedFile{path: path, mode: 0644}
+
+	stat, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	if stat.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", path)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	f.existed = true
+	f.mode = stat.Mode().Perm()
+	f.before = string(content)
+	f.after = f.before
+	f.hash = contentHash(f.before)
+	return f, nil
+}
+
+// contentHash is the hex SHA-256 of content
+func contentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// checkPlanned fails when a planned file no longer has the content it was
+// planned against. The path locks only keep out the daemon's own writers,
+// so another program may have changed the file since.
+func checkPlanned(planned []*plannedFile) error {
+	for _, f := range planned {
+		content, err := os.ReadFile(f.path)
+		switch {
+		case os.IsNotExist(err):
+			if f.existed {
+				return fmt.Errorf("%s was deleted since the edit was planned", f.path)
+			}
+		case err != nil:
+			return fmt.Errorf("failed to read %s: %w", f.path, err)
+		case !f.existed:
+			return fmt.Errorf("%s was created since the edit was planned", f.path)
+		case contentHash(string(content)) != f.hash:
+			return fmt.Errorf("%s changed since the edit was planned", f.path)
+		}
+	}
+	return nil
+}
+
+// commitEdits writes every planned file. The files are checked against the
+// content they were planned from, and new contents are staged in temporary
+// files first; if a later step fails, files already replaced are restored
+// and backups removed, so the edit lands on all files or none.
+func commitEdits(planned []*plannedFile, opts editOptions) error {
+	if err := checkPlanned(planned); err != nil {
+		return err
+	}
+
+	staged := make(map[*plannedFile]string)
+	var backups []string
+	discard := func() {
+		for _, temp := range staged {
+			os.Remove(temp)
+		}
+		for _, backup := range backups {
+			os.Remove(backup)
+		}
+	}
+
+	stamp := time.Now().UnixNano()
+	for _, f := range planned {
+		if opts.backup && f.existed {
+			f.backup = f.path + ".bak." + strconv.FormatInt(stamp, 10)
+			if err := os.WriteFile(f.backup, []byte(f.before), f.mode); err != nil {
+				f.backup = ""
+				discard()
+				return fmt.Errorf("failed to back up %s: %w", f.path, err)
+			}
+			backups = append(backups, f.backup)
+		}
+		if f.action == "delete" {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(f.path), 0755); err != nil {
+			discard()
+			return fmt.Errorf("failed to create directories for %s: %w", f.path, err)
+		}
+		temp, err := writeTemp(f.path, []byte(f.after), f.mode)
+		if err != nil {
+			discard()
+			return fmt.Errorf("failed to write temp file for %s: %w", f.path, err)
+		}
+		staged[f] = temp
+	}
+
+	var done []*plannedFile
+	for _, f := range planned {
+		var err error
+		if f.action == "delete" {
+			err = os.Remove(f.path)
+		} else {
+			err = os.Rename(staged[f], f.path)
+			delete(staged, f)
+		}
+		if err != nil {
+			discard()
+			for _, applied := range done {
+				if applied.existed {
+					os.WriteFile(applied.path, []byte(applied.before), applied.mode)
+				} else {
+					os.Remove(applied.path)
+				}
+			}
+			for _, p := range planned {
+				p.backup = ""
+			}
+			return fmt.Errorf("failed to write %s: %w", f.path, err)
+		}
+		done = append(done, f)
+	}
+
+	return nil
+}
+
+// newEditSetResult describes planned files, applied or not
+func newEditSetResult(planned []*plannedFile, applied bool) *EditSetResult {
+	result := &EditSetResult{Applied: applied, Files: make([]EditSetFile, 0, len(planned))}
+	var diff strings.Builder
+	for _, f := range planned {
+		file := EditSetFile{Path: f.path, Action: f.action, Hash: f.hash, Backup: f.backup}
+		if f.action != "delete" {
+			file.NewHash = contentHash(f.after)
+		}
+		result.Files = append(result.Files, file)
+
+		fileDiff := tools.DiffFile(f.path, f.before, f.after, !f.existed)
+		result.diffs = append(result.diffs, fileDiff)
+		diff.WriteString(fileDiff.Diff)
+		result.Truncated = result.Truncated || fileDiff.Truncated
+	}
+	result.Diff = diff.String()
+	return result
+}
+
+// merge adds the files of a later edit to r
+func (r *EditSetResult) merge(other *EditSetResult) {
+	r.Applied = r.Applied || other.Applied
+	r.Files = append(r.Files, other.Files...)
+	r.Diff += other.Diff
+	r.Truncated = r.Truncated || other.Truncated
+	r.diffs = append(r.diffs, other.diffs...)
+}
+
+// Preview turns r into the preview of a dry-run session
+func (r *EditSetResult) Preview(summary string) *tools.Preview {
+	preview := &tools.Preview{Summary: summary, Diffs: r.diffs}
+	for _, f := range r.Files {
+		switch f.Action {
+		case "create":
+			preview.Created = append(preview.Created, f.Path)
+		case "delete":
+			preview.Deleted = append(preview.Deleted, f.Path)
+		default:
+			preview.Modified = append(preview.Modified, f.Path)
+		}
+	}
+	return preview
+}
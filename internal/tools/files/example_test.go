@@ -6,6 +6,8 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/alucardeht/may-la-mcp/internal/tools"
 )
 
 func TestReadWrite(t *testing.T) {
@@ -47,6 +49,26 @@ func TestReadWrite(t *testing.T) {
 	}
 }
 
+func TestReadResponseMode(t *testing.T) {
+	ctx := context.Background()
+	testFile := filepath.Join(t.TempDir(), "test.txt")
+	os.WriteFile(testFile, []byte("one\ntwo\nthree"), 0644)
+
+	readData, _ := json.Marshal(ReadRequest{Path: testFile, ResponseOptions: tools.ResponseOptions{ResponseMode: "detailed"}})
+	result, err := (&ReadTool{}).Execute(ctx, readData)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	text := result.(*tools.TextResponse)
+	if text.ResponseMode != "detailed" || text.Content != "   1 | one\n   2 | two\n   3 | three" {
+		t.Errorf("expected numbered lines, got %q", text.Content)
+	}
+	if text.Metadata["path"] != testFile {
+		t.Errorf("expected the path in the metadata, got %v", text.Metadata)
+	}
+}
+
 func TestEdit(t *testing.T) {
 	ctx := context.Background()
 	tempDir := t.TempDir()
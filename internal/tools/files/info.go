@@ -66,6 +66,9 @@ func (t *InfoTool) Execute(ctx context.Context, input json.RawMessage) (interfac
 	if req.Path == "" {
 		return nil, fmt.Errorf("path is required")
 	}
+	if err := tools.CheckPath(req.Path); err != nil {
+		return nil, err
+	}
 
 	stat, err := os.Lstat(req.Path)
 	if err != nil {
@@ -3,10 +3,16 @@ package files
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/lsp"
+	"github.com/alucardeht/may-la-mcp/internal/tools"
 )
 
 func TestCompleteWorkflow(t *testing.T) {
@@ -278,6 +284,147 @@ func TestEditEdgeCases(t *testing.T) {
 	})
 }
 
+func TestEditOperations(t *testing.T) {
+	ctx := context.Background()
+	tempDir := t.TempDir()
+	editTool := &EditTool{}
+
+	run := func(t *testing.T, content, order string, edits ...EditOperation) (string, error) {
+		testFile := filepath.Join(tempDir, strings.ReplaceAll(t.Name(), "/", "_")+".txt")
+		os.WriteFile(testFile, []byte(content), 0644)
+
+		editData, _ := json.Marshal(EditRequest{Path: testFile, Edits: edits, Order: order})
+		if _, err := editTool.Execute(ctx, editData); err != nil {
+			return "", err
+		}
+		result, _ := os.ReadFile(testFile)
+		return string(result), nil
+	}
+
+	tests := []struct {
+		name     string
+		content  string
+		order    string
+		edits    []EditOperation
+		expected string
+	}{
+		{
+			name:    "insert after and before pattern",
+			content: "package main\n\nfunc main() {}\n",
+			edits: []EditOperation{
+				{Operation: "insert_after", Pattern: "package", NewContent: "\nimport \"fmt\""},
+				{Operation: "insert_before", Pattern: "func main", NewContent: "// main runs\n"},
+			},
+			expected: "package main\n\nimport \"fmt\"\n\n// main runs\nfunc main() {}\n",
+		},
+		{
+			name:     "append and prepend",
+			content:  "b",
+			edits:    []EditOperation{{Operation: "append", NewContent: "c"}, {Operation: "prepend", NewContent: "a"}},
+			expected: "a\nb\nc",
+		},
+		{
+			name:    "line numbers follow earlier edits with order sequential",
+			content: "1\n2\n3\n4\n5\n",
+			order:   "sequential",
+			edits: []EditOperation{
+				{Operation: "delete_lines", StartLine: 1, EndLine: 2},
+				{Operation: "insert_after", Line: 2, NewContent: "4.5"},
+				{StartLine: 4, EndLine: 4, NewContent: "five"},
+			},
+			expected: "3\n4\n4.5\nfive\n",
+		},
+		{
+			name:    "search sees earlier line edits with order sequential",
+			content: "a\n",
+			order:   "sequential",
+			edits: []EditOperation{
+				{Operation: "append", NewContent: "b"},
+				{Search: "b", Replace: "c"},
+			},
+			expected: "a\nc\n",
+		},
+		{
+			name:    "insertions apply in request order with order sequential",
+			content: "x\n",
+			order:   "sequential",
+			edits: []EditOperation{
+				{Operation: "append", NewContent: "first"},
+				{Operation: "insert_after", Line: 1, NewContent: "second"},
+			},
+			expected: "x\nsecond\nfirst\n",
+		},
+		{
+			name:    "line numbers refer to the original file by default",
+			content: "1\n2\n3\n4\n5\n",
+			edits: []EditOperation{
+				{Operation: "delete_lines", StartLine: 1, EndLine: 2},
+				{Operation: "insert_after", Line: 4, NewContent: "4.5"},
+				{StartLine: 5, EndLine: 5, NewContent: "five"},
+			},
+			expected: "3\n4\n4.5\nfive\n",
+		},
+		{
+			name:    "search runs before line edits by default",
+			content: "a\n",
+			edits: []EditOperation{
+				{Operation: "append", NewContent: "b"},
+				{Search: "b", Replace: "c"},
+			},
+			expected: "a\nb\n",
+		},
+		{
+			name:    "insertions at one point keep request order with order original",
+			content: "x\n",
+			order:   "original",
+			edits: []EditOperation{
+				{Operation: "append", NewContent: "first"},
+				{Operation: "insert_after", Line: 1, NewContent: "second"},
+			},
+			expected: "x\nfirst\nsecond\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := run(t, tt.content, tt.order, tt.edits...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+
+	t.Run("overlapping edits by default", func(t *testing.T) {
+		_, err := run(t, "1\n2\n3\n", "",
+			EditOperation{Operation: "delete_lines", StartLine: 1, EndLine: 2},
+			EditOperation{Operation: "insert_before", Line: 2, NewContent: "x"},
+		)
+		if err == nil {
+			t.Error("Expected error for overlapping edits")
+		}
+	})
+	t.Run("edits may touch lines changed before them with order sequential", func(t *testing.T) {
+		got, err := run(t, "1\n2\n3\n", "sequential",
+			EditOperation{Operation: "delete_lines", StartLine: 1, EndLine: 2},
+			EditOperation{Operation: "insert_before", Line: 1, NewContent: "x"},
+		)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got != "x\n3\n" {
+			t.Errorf("Expected %q, got %q", "x\n3\n", got)
+		}
+	})
+	t.Run("unknown order", func(t *testing.T) {
+		if _, err := run(t, "1\n", "bottom_up", EditOperation{Operation: "append", NewContent: "2"}); err == nil {
+			t.Error("Expected error for an unknown order")
+		}
+	})
+}
+
 func TestEncoding(t *testing.T) {
 	ctx := context.Background()
 	tempDir := t.TempDir()
@@ -329,3 +476,908 @@ func TestEncoding(t *testing.T) {
 		})
 	}
 }
+
+func TestApplyPatch(t *testing.T) {
+	ctx := context.Background()
+	tempDir := t.TempDir()
+	patchTool := &ApplyPatchTool{}
+
+	apply := func(patch string) PatchResponse {
+		data, _ := json.Marshal(PatchRequest{Patch: patch, BaseDir: tempDir})
+		result, err := patchTool.Execute(ctx, data)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return result.(PatchResponse)
+	}
+	read := func(name string) string {
+		content, _ := os.ReadFile(filepath.Join(tempDir, name))
+		return string(content)
+	}
+
+	os.WriteFile(filepath.Join(tempDir, "a.txt"), []byte("zero\none\ntwo\nthree\nfour\nfive\n"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "gone.txt"), []byte("bye\n"), 0644)
+
+	t.Run("multi-file patch with offset and whitespace drift", func(t *testing.T) {
+		resp := apply(`diff --git a/a.txt b/a.txt
+--- a/a.txt
++++ b/a.txt
+@@ -1,4 +1,4 @@
+ one
+-two
++TWO
+ three
+   four
+--- /dev/null
++++ b/new/b.txt
+@@ -0,0 +1,2 @@
++hello
++world
+--- a/gone.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-bye
+`)
+		if !resp.Applied || len(resp.Rejected) != 0 {
+			t.Fatalf("Expected patch to apply, got %+v", resp)
+		}
+		if got := read("a.txt"); got != "zero\none\nTWO\nthree\nfour\nfive\n" {
+			t.Errorf("Unexpected a.txt content %q", got)
+		}
+		if hunk := resp.Files[0].Hunks[0]; hunk.Offset != 1 || !hunk.IgnoredWhitespace {
+			t.Errorf("Expected offset 1 with whitespace ignored, got %+v", hunk)
+		}
+		if got := read("new/b.txt"); got != "hello\nworld\n" {
+			t.Errorf("Unexpected new/b.txt content %q", got)
+		}
+		if _, err := os.Stat(filepath.Join(tempDir, "gone.txt")); !os.IsNotExist(err) {
+			t.Error("gone.txt still exists")
+		}
+	})
+
+	t.Run("rejected hunk leaves every file unchanged", func(t *testing.T) {
+		resp := apply(`--- a/a.txt
++++ b/a.txt
+@@ -1,2 +1,2 @@
+-zero
++ZERO
+ one
+--- a/new/b.txt
++++ b/new/b.txt
+@@ -1,2 +1,2 @@
+-goodbye
++farewell
+ world
+`)
+		if resp.Applied || len(resp.Rejected) != 1 {
+			t.Fatalf("Expected one rejected hunk, got %+v", resp)
+		}
+		if resp.Rejected[0].Reason == "" {
+			t.Error("Rejected hunk has no reason")
+		}
+		if got := read("a.txt"); !strings.HasPrefix(got, "zero\n") {
+			t.Errorf("a.txt was modified: %q", got)
+		}
+	})
+}
+
+func TestEditMatchModes(t *testing.T) {
+	ctx := context.Background()
+	tempDir := t.TempDir()
+	editTool := &EditTool{}
+	content := "func main() {\n\tif ok {\n\t\tprintln(\"hi\")  \n\t}\n\treturn\n}\n"
+
+	run := func(t *testing.T, req EditRequest) (string, error) {
+		req.Path = filepath.Join(tempDir, strings.ReplaceAll(t.Name(), "/", "_")+".go")
+		os.WriteFile(req.Path, []byte(content), 0644)
+
+		editData, _ := json.Marshal(req)
+		if _, err := editTool.Execute(ctx, editData); err != nil {
+			return "", err
+		}
+		result, _ := os.ReadFile(req.Path)
+		return string(result), nil
+	}
+
+	t.Run("exact leaves whitespace mismatches alone", func(t *testing.T) {
+		got, err := run(t, EditRequest{Edits: []EditOperation{{Search: "if ok {\n    println(\"hi\")", Replace: "x"}}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got != content {
+			t.Errorf("Expected no change, got %q", got)
+		}
+	})
+
+	t.Run("ignore_whitespace matches across lines", func(t *testing.T) {
+		got, err := run(t, EditRequest{
+			MatchMode: "ignore_whitespace",
+			Order:     "original",
+			Edits: []EditOperation{
+				{Search: "if ok {\n    println(\"hi\")\n}", Replace: "if ok {\n\t\tprintln(\"bye\")\n\t}"},
+				{Operation: "insert_after", Line: 5, NewContent: "\t// done"},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := "func main() {\n\tif ok {\n\t\tprintln(\"bye\")\n\t}\n\treturn\n\t// done\n}\n"
+		if got != expected {
+			t.Errorf("Expected %q, got %q", expected, got)
+		}
+	})
+
+	t.Run("fuzzy replaces the most similar lines", func(t *testing.T) {
+		got, err := run(t, EditRequest{
+			MatchMode: "fuzzy",
+			Edits:     []EditOperation{{Search: "if ok {\n  println(\"hello\")\n}\n", Replace: "\tprintln(\"hi\")\n"}},
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := "func main() {\n\tprintln(\"hi\")\n\treturn\n}\n"
+		if got != expected {
+			t.Errorf("Expected %q, got %q", expected, got)
+		}
+	})
+
+	t.Run("fuzzy below threshold reports the best match", func(t *testing.T) {
+		_, err := run(t, EditRequest{
+			MatchMode: "fuzzy",
+			Edits:     []EditOperation{{Search: "for i := range items {\n  process(i)", Replace: "x"}},
+		})
+		if err == nil || !strings.Contains(err.Error(), "best match is lines") {
+			t.Errorf("Expected best match error, got %v", err)
+		}
+	})
+}
+
+func TestEditPreserveCase(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cart.go")
+	content := "const FOO_BAR = 1\n\ntype FooBar struct{}\n\nfunc newFooBar() *FooBar {\n\tfooBar := &FooBar{} // foo_bar, foobar\n\treturn fooBar\n}\n"
+	os.WriteFile(path, []byte(content), 0644)
+
+	editTool := &EditTool{}
+	editData, _ := json.Marshal(EditRequest{Path: path, Edits: []EditOperation{{Search: "fooBar", Replace: "bazQux", PreserveCase: true}}})
+	if _, err := editTool.Execute(ctx, editData); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	got, _ := os.ReadFile(path)
+	expected := "const BAZ_QUX = 1\n\ntype BazQux struct{}\n\nfunc newBazQux() *BazQux {\n\tbazQux := &BazQux{} // baz_qux, foobar\n\treturn bazQux\n}\n"
+	if string(got) != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	if words := identifierWords("parseHTTPResponse2_v"); strings.Join(words, ",") != "parse,http,response2,v" {
+		t.Errorf("Unexpected words %v", words)
+	}
+
+	editData, _ = json.Marshal(EditRequest{Path: path, MatchMode: "fuzzy", Edits: []EditOperation{{Search: "bazQux", Replace: "x", PreserveCase: true}}})
+	if _, err := editTool.Execute(ctx, editData); err == nil {
+		t.Error("Expected preserveCase to require matchMode exact")
+	}
+	editData, _ = json.Marshal(EditRequest{Path: path, Edits: []EditOperation{{Search: "baz qux", Replace: "x", PreserveCase: true}}})
+	if _, err := editTool.Execute(ctx, editData); err == nil {
+		t.Error("Expected preserveCase to require identifiers")
+	}
+}
+
+func TestApplyWorkspaceEdit(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	main := filepath.Join(dir, "main.go")
+	old := filepath.Join(dir, "old.go")
+	os.WriteFile(main, []byte("package main\n\nfunc main() {\n\tprintln(\"héllo\")\n}\n"), 0644)
+	os.WriteFile(old, []byte("package main\n"), 0644)
+
+	edit := lsp.WorkspaceEdit{DocumentChanges: []lsp.DocumentChange{
+		{
+			TextDocument: &lsp.OptionalVersionedTextDocumentIdentifier{URI: lsp.PathToURI(main)},
+			Edits: []lsp.TextEdit{
+				// the character offsets count é as one UTF-16 unit
+				{Range: lsp.Range{Start: lsp.Position{Line: 3, Character: 12}, End: lsp.Position{Line: 3, Character: 15}}, NewText: "y!"},
+				{Range: lsp.Range{Start: lsp.Position{Line: 1, Character: 0}, End: lsp.Position{Line: 1, Character: 0}}, NewText: "import \"os\"\n"},
+			},
+		},
+		{Kind: "rename", OldURI: lsp.PathToURI(old), NewURI: lsp.PathToURI(filepath.Join(dir, "new.go"))},
+		{Kind: "create", URI: lsp.PathToURI(filepath.Join(dir, "empty.go"))},
+	}}
+
+	mainContent, _ := os.ReadFile(main)
+	opts := WorkspaceEditOptions{DryRun: true, Hashes: map[string]string{main: contentHash(string(mainContent))}}
+	result, err := ApplyWorkspaceEdit(ctx, edit, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	preview := result.Preview("")
+	if result.Applied || len(preview.Modified) != 1 || len(preview.Created) != 2 || len(preview.Deleted) != 1 {
+		t.Errorf("Unexpected dry run %+v", result)
+	}
+	if !strings.Contains(result.Diff, "+import \"os\"") || !strings.Contains(result.Diff, "new.go") {
+		t.Errorf("Expected one diff of every file, got %q", result.Diff)
+	}
+	if _, err := os.Stat(old); err != nil {
+		t.Error("Expected the dry run to leave old.go")
+	}
+
+	opts.DryRun, opts.Backup = false, true
+	result, err = ApplyWorkspaceEdit(ctx, edit, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	backups := 0
+	for _, f := range result.Files {
+		if f.Backup != "" {
+			backups++
+			if content, _ := os.ReadFile(f.Backup); f.Path == main && string(content) != string(mainContent) {
+				t.Errorf("Expected the backup of main.go to hold its old content, got %q", content)
+			}
+		}
+	}
+	if backups != 2 {
+		t.Errorf("Expected backups of main.go and old.go, got %+v", result.Files)
+	}
+	got, _ := os.ReadFile(main)
+	expected := "package main\nimport \"os\"\n\nfunc main() {\n\tprintln(\"héy!\")\n}\n"
+	if string(got) != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Error("Expected old.go to be renamed")
+	}
+	if content, _ := os.ReadFile(filepath.Join(dir, "new.go")); string(content) != "package main\n" {
+		t.Errorf("Expected new.go to hold old.go, got %q", content)
+	}
+
+	// a failing change leaves every file as it was
+	before, _ := os.ReadFile(main)
+	_, err = ApplyWorkspaceEdit(ctx, lsp.WorkspaceEdit{DocumentChanges: []lsp.DocumentChange{
+		{TextDocument: &lsp.OptionalVersionedTextDocumentIdentifier{URI: lsp.PathToURI(main)}, Edits: []lsp.TextEdit{{NewText: "// x\n"}}},
+		{Kind: "delete", URI: lsp.PathToURI(filepath.Join(dir, "missing.go"))},
+	}}, WorkspaceEditOptions{})
+	if err == nil {
+		t.Error("Expected deleting a missing file to fail")
+	}
+
+	// edits computed from other content are refused
+	stale := lsp.WorkspaceEdit{Changes: map[string][]lsp.TextEdit{lsp.PathToURI(main): {{NewText: "// x\n"}}}}
+	if _, err := ApplyWorkspaceEdit(ctx, stale, WorkspaceEditOptions{Hashes: map[string]string{main: contentHash("package main\n")}}); err == nil {
+		t.Error("Expected an edit computed from other content to fail")
+	}
+	version := 2
+	_, err = ApplyWorkspaceEdit(ctx, lsp.WorkspaceEdit{DocumentChanges: []lsp.DocumentChange{
+		{TextDocument: &lsp.OptionalVersionedTextDocumentIdentifier{URI: lsp.PathToURI(main), Version: &version}, Edits: []lsp.TextEdit{{NewText: "// x\n"}}},
+	}}, WorkspaceEditOptions{Versions: map[string]int{lsp.PathToURI(main): lsp.DocumentVersion}})
+	if err == nil {
+		t.Error("Expected an edit of another document version to fail")
+	}
+	if after, _ := os.ReadFile(main); string(after) != string(before) {
+		t.Errorf("Expected main.go unchanged, got %q", after)
+	}
+}
+
+func TestLineEndingsAndBOM(t *testing.T) {
+	ctx := context.Background()
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "win.txt")
+	os.WriteFile(testFile, []byte("\xEF\xBB\xBFone\r\ntwo\r\n"), 0644)
+
+	read := func() string {
+		content, _ := os.ReadFile(testFile)
+		return string(content)
+	}
+
+	editData, _ := json.Marshal(EditRequest{
+		Path:  testFile,
+		Edits: []EditOperation{{Search: "one", Replace: "uno"}, {Operation: "append", NewContent: "three"}},
+	})
+	if _, err := (&EditTool{}).Execute(ctx, editData); err != nil {
+		t.Fatalf("Edit failed: %v", err)
+	}
+	if got := read(); got != "\xEF\xBB\xBFuno\r\ntwo\r\nthree\r\n" {
+		t.Errorf("Edit did not preserve CRLF and BOM: %q", got)
+	}
+
+	writeTool := &WriteTool{}
+	writeData, _ := json.Marshal(WriteRequest{Path: testFile, Content: "a\nb\n"})
+	if _, err := writeTool.Execute(ctx, writeData); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if got := read(); got != "\xEF\xBB\xBFa\r\nb\r\n" {
+		t.Errorf("Write did not preserve CRLF and BOM: %q", got)
+	}
+
+	writeData, _ = json.Marshal(WriteRequest{Path: testFile, Content: "a\r\nb\r\n", LineEndings: "lf", BOM: "strip"})
+	if _, err := writeTool.Execute(ctx, writeData); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if got := read(); got != "a\nb\n" {
+		t.Errorf("Expected LF without BOM, got %q", got)
+	}
+
+	writeData, _ = json.Marshal(WriteRequest{Path: testFile, Content: "a", BOM: "sometimes"})
+	if _, err := writeTool.Execute(ctx, writeData); err == nil {
+		t.Error("Expected error for invalid bom policy")
+	}
+}
+
+func TestNotebook(t *testing.T) {
+	ctx := context.Background()
+	testFile := filepath.Join(t.TempDir(), "analysis.ipynb")
+	os.WriteFile(testFile, []byte(`{
+ "cells": [
+  {"cell_type": "markdown", "metadata": {}, "source": ["# Sales\n"]},
+  {"cell_type": "code", "execution_count": 3, "metadata": {"tags": ["setup"]},
+   "outputs": [{"name": "stdout", "output_type": "stream", "text": ["loaded\n"]}],
+   "source": ["def load():\n", "    return read_csv(\"sales.csv\")\n", "\n", "df = load()"]}
+ ],
+ "metadata": {"kernelspec": {"display_name": "Python 3", "language": "python", "name": "python3"}},
+ "nbformat": 4,
+ "nbformat_minor": 5
+}`), 0644)
+
+	readData, _ := json.Marshal(ReadRequest{Path: testFile})
+	result, err := (&ReadTool{}).Execute(ctx, readData)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	resp := result.(ReadResponse)
+	if resp.Format != "notebook" || resp.Language != "python" || len(resp.Cells) != 2 {
+		t.Fatalf("Expected 2 python cells, got %+v", resp)
+	}
+	if cell := resp.Cells[1]; cell.Type != "code" || !strings.HasPrefix(cell.Source, "def load():\n") || len(cell.Outputs) != 1 || cell.Outputs[0].Text != "loaded\n" {
+		t.Errorf("Unexpected code cell %+v", cell)
+	}
+
+	cell := 1
+	editData, _ := json.Marshal(EditRequest{
+		Path:  testFile,
+		Cell:  &cell,
+		Edits: []EditOperation{{Search: "df = load()", Replace: "sales = load()"}, {Operation: "delete_lines", StartLine: 3}},
+	})
+	if _, err := (&EditTool{}).Execute(ctx, editData); err != nil {
+		t.Fatalf("Edit failed: %v", err)
+	}
+
+	result, _ = (&ReadTool{}).Execute(ctx, readData)
+	resp = result.(ReadResponse)
+	if want := "def load():\n    return read_csv(\"sales.csv\")\nsales = load()"; resp.Cells[1].Source != want {
+		t.Errorf("Expected cell source %q, got %q", want, resp.Cells[1].Source)
+	}
+	content, _ := os.ReadFile(testFile)
+	for _, kept := range []string{`"tags": [`, `"execution_count": 3`, `"display_name": "Python 3"`, `"nbformat_minor": 5`} {
+		if !strings.Contains(string(content), kept) {
+			t.Errorf("Edit dropped %s from the notebook:\n%s", kept, content)
+		}
+	}
+
+	cell = 5
+	editData, _ = json.Marshal(EditRequest{Path: testFile, Cell: &cell, Edits: []EditOperation{{Search: "a", Replace: "b"}}})
+	if _, err := (&EditTool{}).Execute(ctx, editData); err == nil {
+		t.Error("Expected error for a cell out of range")
+	}
+}
+
+func TestPathLocks(t *testing.T) {
+	ctx := context.Background()
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "log.txt")
+	os.WriteFile(testFile, []byte("start\n"), 0644)
+
+	t.Run("ConcurrentEdits", func(t *testing.T) {
+		const writers = 50
+		editTool := &EditTool{}
+		errs := make(chan error, writers)
+		for i := 0; i < writers; i++ {
+			go func(i int) {
+				data, _ := json.Marshal(EditRequest{
+					Path:  testFile,
+					Edits: []EditOperation{{Operation: "insert_after", Pattern: "start", NewContent: fmt.Sprintf("entry %d", i)}},
+				})
+				_, err := editTool.Execute(ctx, data)
+				errs <- err
+			}(i)
+		}
+		for i := 0; i < writers; i++ {
+			if err := <-errs; err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		content, _ := os.ReadFile(testFile)
+		for i := 0; i < writers; i++ {
+			if !strings.Contains(string(content), fmt.Sprintf("entry %d\n", i)) {
+				t.Errorf("entry %d lost:\n%s", i, content)
+			}
+		}
+		if temps, _ := filepath.Glob(testFile + ".tmp.*"); len(temps) > 0 {
+			t.Errorf("temporary files left behind: %v", temps)
+		}
+	})
+
+	t.Run("LockTimeout", func(t *testing.T) {
+		defer func(timeout time.Duration) { lockTimeout = timeout }(lockTimeout)
+		lockTimeout = 50 * time.Millisecond
+
+		unlock, err := lockPaths(ctx, filepath.Join(tempDir, ".", "log.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := json.Marshal(WriteRequest{Path: testFile, Content: "replaced"})
+		_, err = (&WriteTool{}).Execute(ctx, data)
+		unlock()
+		if !errors.Is(err, ErrLockTimeout) {
+			t.Fatalf("expected a lock timeout, got %v", err)
+		}
+
+		if _, err := (&WriteTool{}).Execute(ctx, data); err != nil {
+			t.Fatalf("write after unlock failed: %v", err)
+		}
+		if len(pathLocks.locks) != 0 {
+			t.Errorf("unused locks kept: %d", len(pathLocks.locks))
+		}
+	})
+}
+
+func TestChmodTouch(t *testing.T) {
+	ctx := context.Background()
+	tempDir, _ := filepath.EvalSymlinks(t.TempDir())
+	t.Chdir(tempDir)
+
+	script := filepath.Join(tempDir, "bin", "build.sh")
+	os.MkdirAll(filepath.Dir(script), 0755)
+	os.WriteFile(script, []byte("#!/bin/sh\n"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "bin", "notes.txt"), []byte("notes"), 0644)
+
+	chmod := func(req ChmodRequest) (ChmodResponse, error) {
+		data, _ := json.Marshal(req)
+		result, err := (&ChmodTool{}).Execute(ctx, data)
+		if err != nil {
+			return ChmodResponse{}, err
+		}
+		return result.(ChmodResponse), nil
+	}
+	modeOf := func(path string) os.FileMode {
+		stat, _ := os.Stat(path)
+		return stat.Mode().Perm()
+	}
+
+	t.Run("Symbolic", func(t *testing.T) {
+		resp, err := chmod(ChmodRequest{Path: script, Mode: "+x"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if modeOf(script) != 0755 || len(resp.Changed) != 1 || resp.Changed[0].After != "0755" {
+			t.Errorf("mode = %o, changed = %+v", modeOf(script), resp.Changed)
+		}
+
+		if _, err := chmod(ChmodRequest{Path: script, Mode: "go-rx,u-w"}); err != nil {
+			t.Fatal(err)
+		}
+		if modeOf(script) != 0500 {
+			t.Errorf("mode = %o, want 500", modeOf(script))
+		}
+	})
+
+	t.Run("OctalRecursive", func(t *testing.T) {
+		resp, err := chmod(ChmodRequest{Path: filepath.Join(tempDir, "bin"), Mode: "u=rwX,go=rX", Recursive: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if modeOf(script) != 0755 || modeOf(filepath.Join(tempDir, "bin", "notes.txt")) != 0644 {
+			t.Errorf("modes = %o, %o", modeOf(script), modeOf(filepath.Join(tempDir, "bin", "notes.txt")))
+		}
+		if len(resp.Changed) != 1 || resp.Unchanged != 2 {
+			t.Errorf("changed = %+v, unchanged = %d", resp.Changed, resp.Unchanged)
+		}
+
+		if _, err := chmod(ChmodRequest{Path: script, Mode: "0700"}); err != nil || modeOf(script) != 0700 {
+			t.Errorf("octal mode: %v, mode = %o", err, modeOf(script))
+		}
+	})
+
+	t.Run("Refusals", func(t *testing.T) {
+		for _, mode := range []string{"4755", "2755", "u+s", "g=rxs", "+q", "u"} {
+			if _, err := chmod(ChmodRequest{Path: script, Mode: mode}); err == nil {
+				t.Errorf("mode %q should be refused", mode)
+			}
+		}
+
+		outside := filepath.Join(t.TempDir(), "outside.sh")
+		os.WriteFile(outside, []byte(""), 0644)
+		if _, err := chmod(ChmodRequest{Path: outside, Mode: "+x"}); err == nil || !strings.Contains(err.Error(), "outside the workspace") {
+			t.Errorf("path outside the workspace: %v", err)
+		}
+
+		link := filepath.Join(tempDir, "link.sh")
+		if err := os.Symlink(outside, link); err == nil {
+			if _, err := chmod(ChmodRequest{Path: link, Mode: "+x"}); err == nil {
+				t.Error("symlink out of the workspace should be refused")
+			}
+		}
+		if modeOf(outside) != 0644 {
+			t.Errorf("file outside the workspace changed to %o", modeOf(outside))
+		}
+	})
+
+	t.Run("DryRun", func(t *testing.T) {
+		data, _ := json.Marshal(ChmodRequest{Path: script, Mode: "644"})
+		preview, err := (&ChmodTool{}).DryRun(ctx, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(preview.Modified) != 1 || modeOf(script) != 0700 {
+			t.Errorf("preview = %+v, mode = %o", preview, modeOf(script))
+		}
+	})
+
+	t.Run("Touch", func(t *testing.T) {
+		stamp := filepath.Join(tempDir, "stamp")
+		data, _ := json.Marshal(TouchRequest{Path: stamp, Mtime: "2024-01-02T03:04:05Z"})
+		result, err := (&TouchTool{}).Execute(ctx, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp := result.(TouchResponse)
+		want := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		if !resp.Created || !resp.Modified.Equal(want) {
+			t.Errorf("response = %+v", resp)
+		}
+
+		data, _ = json.Marshal(TouchRequest{Path: stamp})
+		result, err = (&TouchTool{}).Execute(ctx, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp := result.(TouchResponse); resp.Created || !resp.Modified.After(want) {
+			t.Errorf("second touch = %+v", resp)
+		}
+
+		data, _ = json.Marshal(TouchRequest{Path: filepath.Join(tempDir, "missing"), NoCreate: true})
+		if _, err := (&TouchTool{}).Execute(ctx, data); err == nil {
+			t.Error("no_create should refuse a missing file")
+		}
+		data, _ = json.Marshal(TouchRequest{Path: stamp, Mtime: "yesterday"})
+		if _, err := (&TouchTool{}).Execute(ctx, data); err == nil {
+			t.Error("invalid time should be refused")
+		}
+	})
+}
+
+func TestDu(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	write := func(rel string, size int) {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(strings.Repeat("x", size)), 0644)
+	}
+	os.WriteFile(filepath.Join(root, ".gitignore"), []byte("node_modules/\n*.log\n"), 0644)
+	write("src/main.go", 100)
+	write("src/pkg/util.go", 50)
+	write("node_modules/lib/index.js", 1000)
+	write("node_modules/lib/deep/more.js", 500)
+	write("debug.log", 300)
+
+	du := func(req DuRequest) DuResponse {
+		t.Helper()
+		data, _ := json.Marshal(req)
+		result, err := (&DuTool{}).Execute(ctx, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return *result.(*DuResponse)
+	}
+	gitignoreSize := int64(len("node_modules/\n*.log\n"))
+
+	resp := du(DuRequest{Path: root, Depth: 1})
+	if resp.Size != 1950+gitignoreSize || resp.Files != 6 || resp.IgnoredSize != 1800 {
+		t.Errorf("size = %d, files = %d, ignored = %d", resp.Size, resp.Files, resp.IgnoredSize)
+	}
+	if len(resp.Directories) != 2 {
+		t.Fatalf("directories = %+v", resp.Directories)
+	}
+	if dir := resp.Directories[0]; filepath.Base(dir.Path) != "node_modules" || dir.Size != 1500 || dir.Files != 2 || !dir.Ignored {
+		t.Errorf("largest directory = %+v", dir)
+	}
+	if dir := resp.Directories[1]; filepath.Base(dir.Path) != "src" || dir.Size != 150 || dir.Ignored {
+		t.Errorf("second directory = %+v", dir)
+	}
+	if first := resp.LargestFiles[0]; filepath.Base(first.Path) != "index.js" || !first.Ignored {
+		t.Errorf("largest file = %+v", first)
+	}
+
+	resp = du(DuRequest{Path: root, Depth: 3, Top: 2})
+	if len(resp.Directories) != 2 || len(resp.LargestFiles) != 2 {
+		t.Errorf("top 2: %d directories, %d files", len(resp.Directories), len(resp.LargestFiles))
+	}
+
+	resp = du(DuRequest{Path: root, Depth: 3, ExcludeIgnored: true})
+	if resp.Size != 150+gitignoreSize || resp.IgnoredSize != 0 || len(resp.Directories) != 2 {
+		t.Errorf("excluding ignored: size = %d, directories = %+v", resp.Size, resp.Directories)
+	}
+
+	data, _ := json.Marshal(DuRequest{Path: filepath.Join(root, "debug.log")})
+	if _, err := (&DuTool{}).Execute(ctx, data); err == nil {
+		t.Error("du on a file should fail")
+	}
+}
+
+func TestListIgnored(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	write := func(rel string) {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte("x"), 0644)
+	}
+	os.WriteFile(filepath.Join(root, ".gitignore"), []byte("node_modules/\n*.log\n"), 0644)
+	write("src/main.go")
+	write("node_modules/lib/index.js")
+	write("debug.log")
+
+	list := func(req ListRequest) ListResponse {
+		t.Helper()
+		data, _ := json.Marshal(req)
+		result, err := (&ListTool{}).Execute(ctx, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result.(ListResponse)
+	}
+
+	resp := list(ListRequest{Path: root, Recursive: true})
+	if resp.Count != 2 || resp.Ignored != 2 {
+		t.Errorf("recursive: count = %d, ignored = %d, files = %+v", resp.Count, resp.Ignored, resp.Files)
+	}
+	resp = list(ListRequest{Path: root})
+	if resp.Count != 1 || resp.Files[0].Name != "src" || resp.Ignored != 2 {
+		t.Errorf("flat: count = %d, ignored = %d, files = %+v", resp.Count, resp.Ignored, resp.Files)
+	}
+	resp = list(ListRequest{Path: root, Recursive: true, IncludeIgnored: true})
+	if resp.Count != 6 || resp.Ignored != 0 {
+		t.Errorf("include_ignored: count = %d, ignored = %d", resp.Count, resp.Ignored)
+	}
+}
+
+func TestConflicts(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	file := filepath.Join(root, "src", "main.go")
+	os.MkdirAll(filepath.Dir(file), 0755)
+	conflicted := "package main\n" +
+		"<<<<<<< HEAD\nconst a = 1\n=======\nconst a = 2\n>>>>>>> feature\n" +
+		"\n" +
+		"<<<<<<< HEAD\nfunc f() {}\n||||||| base\nfunc g() {}\n=======\nfunc h() {}\n>>>>>>> feature\n"
+	os.WriteFile(file, []byte(conflicted), 0644)
+	os.WriteFile(filepath.Join(root, "clean.go"), []byte("package main\n"), 0644)
+
+	tool := &ConflictsTool{}
+	data, _ := json.Marshal(ConflictsRequest{Path: root})
+	result, err := tool.Execute(ctx, data)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	found := result.(*ConflictsResponse)
+	if found.Count != 2 || len(found.Files) != 1 {
+		t.Fatalf("Expected two conflicts in one file, got %+v", found)
+	}
+	first, second := found.Files[0].Conflicts[0], found.Files[0].Conflicts[1]
+	if first.StartLine != 2 || first.EndLine != 6 || first.Ours != "const a = 1\n" || first.Theirs != "const a = 2\n" || first.TheirsLabel != "feature" || first.Base != nil {
+		t.Errorf("Unexpected first conflict %+v", first)
+	}
+	if second.Base == nil || *second.Base != "func g() {}\n" || second.BaseLabel != "base" {
+		t.Errorf("Expected the diff3 base of the second conflict, got %+v", second)
+	}
+
+	resolve := func(resolutions ...ConflictResolution) (*ConflictResolveResponse, error) {
+		data, _ := json.Marshal(ConflictsRequest{Path: file, Action: "resolve", Resolutions: resolutions})
+		result, err := tool.Execute(ctx, data)
+		if err != nil {
+			return nil, err
+		}
+		return result.(*ConflictResolveResponse), nil
+	}
+	read := func() string {
+		content, _ := os.ReadFile(file)
+		return string(content)
+	}
+
+	merged := "<<<<<<< HEAD\nfunc f() {}\n"
+	if _, err := resolve(ConflictResolution{Conflict: 1, Content: &merged}); err == nil {
+		t.Error("Expected merged content with markers to be refused")
+	}
+	if _, err := resolve(ConflictResolution{Conflict: 1, Choose: "base"}); err == nil {
+		t.Error("Expected base to need a diff3 conflict")
+	}
+	if _, err := resolve(ConflictResolution{Conflict: 3, Choose: "ours"}); err == nil {
+		t.Error("Expected a missing conflict to fail")
+	}
+	if read() != conflicted {
+		t.Fatal("Expected failed resolutions to leave the file")
+	}
+
+	resp, err := resolve(ConflictResolution{Conflict: 2, Choose: "theirs_ours"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if resp.Resolved != 1 || resp.Remaining != 1 {
+		t.Errorf("Expected one conflict left, got %+v", resp)
+	}
+	merged = "const a = 3"
+	if _, err := resolve(ConflictResolution{Conflict: 1, Content: &merged}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := "package main\nconst a = 3\n\nfunc h() {}\nfunc f() {}\n"; read() != expected {
+		t.Errorf("Expected %q, got %q", expected, read())
+	}
+}
+
+func TestFixMojibake(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+
+	// "café — naïve" saved as UTF-8 after being read as Windows-1252, and
+	// "résumé" after two such round trips
+	mangled := filepath.Join(root, "notes.md")
+	os.WriteFile(mangled, []byte("# Notes\ncafÃ© â€” naÃ¯ve\nrÃƒÂ©sumÃƒÂ©\n"), 0644)
+	clean := filepath.Join(root, "clean.md")
+	os.WriteFile(clean, []byte("café — naïve, Ärger\n"), 0644)
+	legacy := filepath.Join(root, "legacy.txt")
+	os.WriteFile(legacy, []byte("caf\xe9\n"), 0644)
+
+	tool := &FixMojibakeTool{}
+	data, _ := json.Marshal(MojibakeRequest{Path: root})
+
+	preview, err := tool.DryRun(ctx, data)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(preview.Modified) != 1 || preview.Modified[0] != mangled {
+		t.Errorf("Expected a preview of notes.md only, got %+v", preview.Modified)
+	}
+	if content, _ := os.ReadFile(mangled); !strings.Contains(string(content), "cafÃ©") {
+		t.Fatal("Expected the dry run to leave the file")
+	}
+
+	result, err := tool.Execute(ctx, data)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	resp := result.(*MojibakeResponse)
+	if !resp.Applied || resp.Repairs != 5 || len(resp.Files) != 1 {
+		t.Fatalf("Expected five repairs in one file, got %+v", resp)
+	}
+	if sample := resp.Files[0].Samples[0]; sample.Line != 2 || sample.Before != "Ã©" || sample.After != "é" {
+		t.Errorf("Unexpected first repair %+v", sample)
+	}
+	if content, _ := os.ReadFile(mangled); string(content) != "# Notes\ncafé — naïve\nrésumé\n" {
+		t.Errorf("Unexpected repaired content %q", content)
+	}
+	if content, _ := os.ReadFile(clean); string(content) != "café — naïve, Ärger\n" {
+		t.Errorf("Expected correct text to be left, got %q", content)
+	}
+	if content, _ := os.ReadFile(legacy); string(content) != "caf\xe9\n" {
+		t.Errorf("Expected a Latin-1 file to be left, got %q", content)
+	}
+
+	data, _ = json.Marshal(MojibakeRequest{Path: legacy})
+	if _, err := tool.Execute(ctx, data); err == nil {
+		t.Error("Expected a file that is not UTF-8 to be refused")
+	}
+}
+
+func TestReadRecordsAccess(t *testing.T) {
+	ctx := context.Background()
+	tempDir := t.TempDir()
+
+	var recorded []string
+	tools.SetAccessRecorder(func(path string) {
+		recorded = append(recorded, path)
+	})
+	defer tools.SetAccessRecorder(nil)
+
+	missing := filepath.Join(tempDir, "missing.txt")
+	data, _ := json.Marshal(ReadRequest{Path: missing})
+	if _, err := (&ReadTool{}).Execute(ctx, data); err == nil {
+		t.Fatal("Expected reading a missing file to fail")
+	}
+	if len(recorded) != 0 {
+		t.Fatalf("Expected a failed read not to be recorded, got %v", recorded)
+	}
+
+	existing := filepath.Join(tempDir, "notes.txt")
+	os.WriteFile(existing, []byte("hello\n"), 0644)
+	data, _ = json.Marshal(ReadRequest{Path: existing})
+	if _, err := (&ReadTool{}).Execute(ctx, data); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(recorded) != 1 || recorded[0] != existing {
+		t.Errorf("Expected one access to %s, got %v", existing, recorded)
+	}
+}
+
+func TestExecuteDeniedPath(t *testing.T) {
+	ctx := context.Background()
+	secrets := filepath.Join(t.TempDir(), ".ssh")
+	os.MkdirAll(secrets, 0755)
+	key := filepath.Join(secrets, "id_rsa")
+	os.WriteFile(key, []byte("PRIVATE KEY MATERIAL\n"), 0600)
+	stolen := filepath.Join(t.TempDir(), "k")
+
+	tools.SetDeniedPaths([]string{secrets})
+	defer tools.SetDeniedPaths(tools.DefaultDeniedPaths())
+
+	cases := []struct {
+		name  string
+		tool  tools.Tool
+		input interface{}
+	}{
+		{"move out", &MoveTool{}, MoveRequest{Source: key, Destination: stolen}},
+		{"move in", &MoveTool{}, MoveRequest{Source: stolen, Destination: filepath.Join(secrets, "authorized_keys")}},
+		{"write", &WriteTool{}, WriteRequest{Path: filepath.Join(secrets, "authorized_keys"), Content: "ssh-ed25519 AAAA\n"}},
+		{"edit", &EditTool{}, EditRequest{Path: key, Edits: []EditOperation{{Search: "KEY", Replace: "LOCK"}}}},
+		{"create", &CreateTool{}, CreateRequest{Path: filepath.Join(secrets, "config"), Type: "file", Content: "Host *\n"}},
+		{"delete", &DeleteTool{}, DeleteRequest{Path: key}},
+		{"info", &InfoTool{}, InfoRequest{Path: key}},
+	}
+	os.WriteFile(stolen, []byte("ssh-ed25519 AAAA\n"), 0644)
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, _ := json.Marshal(tc.input)
+			if result, err := tc.tool.Execute(ctx, data); err == nil {
+				t.Fatalf("Expected the denied path to be refused, got %+v", result)
+			}
+		})
+	}
+
+	if content, err := os.ReadFile(key); err != nil || string(content) != "PRIVATE KEY MATERIAL\n" {
+		t.Errorf("Expected the key to be left alone, got %q %v", content, err)
+	}
+	if _, err := os.Stat(filepath.Join(secrets, "authorized_keys")); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing written into the denied directory, got %v", err)
+	}
+}
+
+func TestDryRunDeniedPath(t *testing.T) {
+	ctx := context.Background()
+	secrets := filepath.Join(t.TempDir(), "secrets")
+	os.MkdirAll(secrets, 0755)
+	key := filepath.Join(secrets, "id_rsa")
+	os.WriteFile(key, []byte("PRIVATE KEY MATERIAL\n"), 0600)
+
+	tools.SetDeniedPaths([]string{secrets})
+	defer tools.SetDeniedPaths(tools.DefaultDeniedPaths())
+
+	cases := []struct {
+		name  string
+		tool  tools.DryRunner
+		input interface{}
+	}{
+		{"write", &WriteTool{}, WriteRequest{Path: key, Content: "replaced\n"}},
+		{"edit", &EditTool{}, EditRequest{Path: key, Edits: []EditOperation{{Search: "KEY", Replace: "LOCK"}}}},
+		{"create", &CreateTool{}, CreateRequest{Path: key, Type: "file", Content: "replaced\n", Force: true}},
+		{"delete", &DeleteTool{}, DeleteRequest{Path: secrets, Recursive: true}},
+		{"move", &MoveTool{}, MoveRequest{Source: key, Destination: filepath.Join(t.TempDir(), "id_rsa")}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, _ := json.Marshal(tc.input)
+			preview, err := tc.tool.DryRun(ctx, data)
+			if err == nil {
+				t.Fatalf("Expected the denied path to be refused, got %+v", preview)
+			}
+			if preview != nil {
+				t.Errorf("Expected no preview, got %+v", preview)
+			}
+			if strings.Contains(err.Error(), "PRIVATE KEY") {
+				t.Errorf("Expected the error not to leak content, got %v", err)
+			}
+		})
+	}
+
+	if content, exists, err := tools.ReadExisting(key); err == nil || exists || content != "" {
+		t.Errorf("Expected ReadExisting to refuse the denied path, got %q %v %v", content, exists, err)
+	}
+}
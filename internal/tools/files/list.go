@@ -10,15 +10,18 @@ import (
 	"strings"
 	"time"
 
+	"github.com/alucardeht/may-la-mcp/internal/gitignore"
+	"github.com/alucardeht/may-la-mcp/internal/glob"
 	"github.com/alucardeht/may-la-mcp/internal/tools"
 )
 
 type ListRequest struct {
-	Path      string `json:"path"`
-	Recursive bool   `json:"recursive,omitempty"`
-	Pattern   string `json:"pattern,omitempty"`
-	ShowHidden bool  `json:"showHidden,omitempty"`
-	SortBy    string `json:"sortBy,omitempty"`
+	Path           string `json:"path"`
+	Recursive      bool   `json:"recursive,omitempty"`
+	Pattern        string `json:"pattern,omitempty"`
+	ShowHidden     bool   `json:"showHidden,omitempty"`
+	SortBy         string `json:"sortBy,omitempty"`
+	IncludeIgnored bool   `json:"include_ignored,omitempty"`
 }
 
 type FileInfo struct {
@@ -31,9 +34,10 @@ type FileInfo struct {
 }
 
 type ListResponse struct {
-	Path  string     `json:"path"`
-	Files []FileInfo `json:"files"`
-	Count int        `json:"count"`
+	Path    string     `json:"path"`
+	Files   []FileInfo `json:"files"`
+	Count   int        `json:"count"`
+	Ignored int        `json:"ignored,omitempty"`
 }
 
 type ListTool struct{}
@@ -43,7 +47,9 @@ func (t *ListTool) Name() string {
 }
 
 func (t *ListTool) Description() string {
-	return "List directory contents with filtering and sorting options"
+	return `List directory contents with filtering and sorting options.
+
+Entries a .gitignore ignores, .git directories and paths matching the index exclude patterns (node_modules, build output, ...) are left out and counted as ignored; pass include_ignored to list them too.`
 }
 
 func (t *ListTool) Schema() json.RawMessage {
@@ -60,7 +66,7 @@ func (t *ListTool) Schema() json.RawMessage {
 			},
 			"pattern": {
 				"type": "string",
-				"description": "Glob pattern to filter files (e.g., *.go)"
+				"description": "Glob pattern matched against names; supports {a,b} (e.g., *.go, *.{js,ts})"
 			},
 			"showHidden": {
 				"type": "boolean",
@@ -70,6 +76,10 @@ func (t *ListTool) Schema() json.RawMessage {
 				"type": "string",
 				"description": "Sort field",
 				"enum": ["name", "size", "date"]
+			},
+			"include_ignored": {
+				"type": "boolean",
+				"description": "Also list entries ignored by .gitignore or the exclude patterns (default: false)"
 			}
 		},
 		"required": ["path"]
@@ -88,6 +98,15 @@ func (t *ListTool) Execute(ctx context.Context, input json.RawMessage) (interfac
 	if req.Path == "" {
 		return nil, fmt.Errorf("path is required")
 	}
+	if req.Pattern != "" {
+		if err := glob.Validate(req.Pattern); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tools.CheckPath(req.Path); err != nil {
+		return nil, err
+	}
 
 	stat, err := os.Stat(req.Path)
 	if err != nil {
@@ -102,6 +121,11 @@ func (t *ListTool) Execute(ctx context.Context, input json.RawMessage) (interfac
 	}
 
 	var files []FileInfo
+	var matcher *gitignore.Matcher
+	if !req.IncludeIgnored {
+		matcher = gitignore.NewMatcher(req.Path)
+	}
+	ignored := 0
 
 	if req.Recursive {
 		err = filepath.Walk(req.Path, func(path string, info os.FileInfo, err error) error {
@@ -118,6 +142,13 @@ func (t *ListTool) Execute(ctx context.Context, input json.RawMessage) (interfac
 				return nil
 			}
 
+			if tools.IsPathDenied(path) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
 			if !req.ShowHidden && strings.HasPrefix(info.Name(), ".") {
 				if info.IsDir() {
 					return filepath.SkipDir
@@ -125,11 +156,21 @@ func (t *ListTool) Execute(ctx context.Context, input json.RawMessage) (interfac
 				return nil
 			}
 
-			if req.Pattern != "" {
-				matched, err := filepath.Match(req.Pattern, info.Name())
-				if err != nil || !matched {
+			if matcher != nil {
+				if matcher.Ignored(path, info.IsDir()) {
+					ignored++
+					if info.IsDir() {
+						return filepath.SkipDir
+					}
 					return nil
 				}
+				if info.IsDir() {
+					matcher.Enter(path)
+				}
+			}
+
+			if req.Pattern != "" && !glob.Match(req.Pattern, info.Name()) {
+				return nil
 			}
 
 			itemType := "file"
@@ -159,11 +200,17 @@ func (t *ListTool) Execute(ctx context.Context, input json.RawMessage) (interfac
 				continue
 			}
 
-			if req.Pattern != "" {
-				matched, err := filepath.Match(req.Pattern, entry.Name())
-				if err != nil || !matched {
-					continue
-				}
+			if tools.IsPathDenied(filepath.Join(req.Path, entry.Name())) {
+				continue
+			}
+
+			if matcher != nil && matcher.Ignored(filepath.Join(req.Path, entry.Name()), entry.IsDir()) {
+				ignored++
+				continue
+			}
+
+			if req.Pattern != "" && !glob.Match(req.Pattern, entry.Name()) {
+				continue
 			}
 
 			info, err := entry.Info()
@@ -190,9 +237,10 @@ func (t *ListTool) Execute(ctx context.Context, input json.RawMessage) (interfac
 	sortFiles(files, req.SortBy)
 
 	return ListResponse{
-		Path:  req.Path,
-		Files: files,
-		Count: len(files),
+		Path:    req.Path,
+		Files:   files,
+		Count:   len(files),
+		Ignored: ignored,
 	}, nil
 }
 
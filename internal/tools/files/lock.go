@@ -0,0 +1,143 @@
+package files
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/paths"
+)
+
+// ErrLockTimeout is returned when a path stays locked by another mutation
+// for longer than lockTimeout
+var ErrLockTimeout = errors.New("timed out waiting for file lock")
+
+// lockTimeout bounds how long a mutation waits for the paths it changes
+var lockTimeout = 30 * time.Second
+
+// pathLocks serializes the tools that mutate the same path across the
+// daemon. Locks are advisory and per path: locking a directory does not
+// lock the files under it.
+var pathLocks = &lockTable{locks: make(map[string]*pathLock)}
+
+type lockTable struct {
+	mu    sync.Mutex
+	locks map[string]*pathLock
+}
+
+// pathLock is held by whoever has sent into sem; refs counts the holder
+// and waiters, so an unused lock can be dropped from the table
+type pathLock struct {
+	sem  chan struct{}
+	refs int
+}
+
+func (t *lockTable) acquire(ctx context.Context, key string, timeout time.Duration) error {
+	t.mu.Lock()
+	lock, ok := t.locks[key]
+	if !ok {
+		lock = &pathLock{sem: make(chan struct{}, 1)}
+		t.locks[key] = lock
+	}
+	lock.refs++
+	t.mu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case lock.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		t.drop(key, lock)
+		return ctx.Err()
+	case <-timer.C:
+		t.drop(key, lock)
+		return fmt.Errorf("%w: %s is being modified by another call (waited %s)", ErrLockTimeout, key, timeout)
+	}
+}
+
+func (t *lockTable) release(key string) {
+	t.mu.Lock()
+	lock := t.locks[key]
+	t.mu.Unlock()
+
+	<-lock.sem
+	t.drop(key, lock)
+}
+
+func (t *lockTable) drop(key string, lock *pathLock) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	lock.refs--
+	if lock.refs == 0 {
+		delete(t.locks, key)
+	}
+}
+
+// lockPaths locks every path for a mutation and returns the function that
+// unlocks them. Paths are locked in a fixed order, so calls locking
+// overlapping sets of paths cannot deadlock.
+func lockPaths(ctx context.Context, paths ...string) (func(), error) {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, path := range paths {
+		key := lockKey(path)
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	unlock := func(keys []string) {
+		for i := len(keys) - 1; i >= 0; i-- {
+			pathLocks.release(keys[i])
+		}
+	}
+	for i, key := range keys {
+		if err := pathLocks.acquire(ctx, key, lockTimeout); err != nil {
+			unlock(keys[:i])
+			return nil, err
+		}
+	}
+	return func() { unlock(keys) }, nil
+}
+
+// lockKey names path the same way however it was spelled
+func lockKey(path string) string {
+	return paths.Canonical(path)
+}
+
+// writeTemp writes data to a new temporary file next to path and returns
+// its name. The file is created with O_EXCL, so a temporary file is never
+// shared with another writer, even one outside the daemon.
+func writeTemp(path string, data []byte, mode os.FileMode) (string, error) {
+	stamp := time.Now().UnixNano()
+	for attempt := 0; ; attempt++ {
+		temp := fmt.Sprintf("%s.tmp.%d", path, stamp+int64(attempt))
+		f, err := os.OpenFile(temp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+		if errors.Is(err, fs.ErrExist) && attempt < 100 {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+
+		_, err = f.Write(data)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(temp)
+			return "", err
+		}
+		return temp, nil
+	}
+}
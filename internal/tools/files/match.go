@@ -0,0 +1,241 @@
+package files
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Match modes for search/replace edits
+const (
+	matchExact            = "exact"
+	matchIgnoreWhitespace = "ignore_whitespace"
+	matchFuzzy            = "fuzzy"
+)
+
+const defaultFuzzyThreshold = 0.8
+
+// maxFuzzyCells bounds the character comparisons of one fuzzy search
+const maxFuzzyCells = 100_000_000
+
+type matchOptions struct {
+	mode      string
+	threshold float64
+}
+
+func newMatchOptions(req EditRequest) (matchOptions, error) {
+	opts := matchOptions{mode: req.MatchMode, threshold: defaultFuzzyThreshold}
+	switch opts.mode {
+	case "":
+		opts.mode = matchExact
+	case matchExact, matchIgnoreWhitespace, matchFuzzy:
+	default:
+		return opts, fmt.Errorf("invalid matchMode %q: expected exact, ignore_whitespace or fuzzy", req.MatchMode)
+	}
+
+	if req.FuzzyThreshold != 0 {
+		if req.FuzzyThreshold < 0 || req.FuzzyThreshold > 1 {
+			return opts, fmt.Errorf("fuzzyThreshold must be between 0 and 1")
+		}
+		opts.threshold = req.FuzzyThreshold
+	}
+	return opts, nil
+}
+
+// editBuffer holds the file's lines while edits are applied. A search that
+// spans several lines merges them into the first one and marks the others
+// removed, so indexes keep pointing at the original lines until the
+// line-based edits have been applied.
+type editBuffer struct {
+	lines   []string
+	removed []bool
+}
+
+func newEditBuffer(lines []string) *editBuffer {
+	return &editBuffer{lines: slices.Clone(lines), removed: make([]bool, len(lines))}
+}
+
+func (b *editBuffer) splice(start, end int, lines []string) {
+	b.lines = slices.Replace(b.lines, start, end, lines...)
+	b.removed = slices.Replace(b.removed, start, end, make([]bool, len(lines))...)
+}
+
+// live returns the indexes of the lines not merged away
+func (b *editBuffer) live() []int {
+	idx := make([]int, 0, len(b.lines))
+	for i := range b.lines {
+		if !b.removed[i] {
+			idx = append(idx, i)
+		}
+	}
+	return idx
+}
+
+func (b *editBuffer) result() []string {
+	lines := make([]string, 0, len(b.lines))
+	for _, i := range b.live() {
+		lines = append(lines, b.lines[i])
+	}
+	return lines
+}
+
+// replaceExact replaces every occurrence of search in the first line that
+// contains it
+func (b *editBuffer) replaceExact(search, replace string) bool {
+	for _, i := range b.live() {
+		if strings.Contains(b.lines[i], search) {
+			b.lines[i] = strings.ReplaceAll(b.lines[i], search, replace)
+			return true
+		}
+	}
+	return false
+}
+
+// replaceLoose replaces the first match of search where any run of
+// whitespace matches any other, ignoring indentation and trailing spaces
+func (b *editBuffer) replaceLoose(search, replace string) (bool, error) {
+	pattern, err := whitespacePattern(search)
+	if err != nil {
+		return false, err
+	}
+
+	idx := b.live()
+	starts := make([]int, len(idx))
+	var text strings.Builder
+	for k, i := range idx {
+		if k > 0 {
+			text.WriteByte('\n')
+		}
+		starts[k] = text.Len()
+		text.WriteString(b.lines[i])
+	}
+
+	loc := pattern.FindStringIndex(text.String())
+	if loc == nil {
+		return false, nil
+	}
+
+	// The lines holding the match start and end
+	first, _ := slices.BinarySearch(starts, loc[0]+1)
+	last, _ := slices.BinarySearch(starts, loc[1]+1)
+	first, last = first-1, last-1
+
+	merged := b.lines[idx[first]][:loc[0]-starts[first]] + replace + b.lines[idx[last]][loc[1]-starts[last]:]
+	b.lines[idx[first]] = merged
+	for _, i := range idx[first+1 : last+1] {
+		b.removed[i] = true
+	}
+	return true, nil
+}
+
+// whitespacePattern compiles search into a regexp that matches it with any
+// whitespace between its words. Leading and trailing whitespace in search
+// absorbs the file's own indentation and trailing spaces.
+func whitespacePattern(search string) (*regexp.Regexp, error) {
+	words := strings.Fields(search)
+	if len(words) == 0 {
+		return nil, fmt.Errorf("search must contain non-whitespace text")
+	}
+	for i, word := range words {
+		words[i] = regexp.QuoteMeta(word)
+	}
+
+	pattern := strings.Join(words, `\s+`)
+	if strings.TrimLeftFunc(search, unicode.IsSpace) != search {
+		pattern = `[ \t]*` + pattern
+	}
+	switch trimmed := strings.TrimRight(search, " \t\r"); {
+	case strings.HasSuffix(trimmed, "\n"):
+		pattern += `[ \t]*\r?(?:\n|$)`
+	case trimmed != search:
+		pattern += `[ \t]*`
+	}
+	return regexp.Compile(pattern)
+}
+
+// replaceFuzzy tries a whitespace-insensitive match first, then replaces
+// the block of whole lines most similar to search. A best match below
+// threshold is an error naming where it was found.
+func (b *editBuffer) replaceFuzzy(search, replace string, threshold float64) error {
+	if ok, err := b.replaceLoose(search, replace); ok || err != nil {
+		return err
+	}
+
+	want := strings.Split(strings.TrimSuffix(search, "\n"), "\n")
+	wantChars := 0
+	for i := range want {
+		want[i] = strings.Join(strings.Fields(want[i]), " ")
+		wantChars += len(want[i])
+	}
+
+	idx := b.live()
+	if len(want) > len(idx) {
+		return fmt.Errorf("no fuzzy match for search: it has %d lines but the file has %d", len(want), len(idx))
+	}
+
+	got := make([]string, len(idx))
+	gotChars := 0
+	for k, i := range idx {
+		got[k] = strings.Join(strings.Fields(b.lines[i]), " ")
+		gotChars += len(got[k])
+	}
+	if gotChars*wantChars > maxFuzzyCells {
+		return fmt.Errorf("file is too large for fuzzy matching; use exact or ignore_whitespace")
+	}
+
+	best, bestAt := -1.0, 0
+	for s := 0; s+len(want) <= len(got); s++ {
+		dist, total := 0, 0
+		for i, w := range want {
+			dist += levenshtein(got[s+i], w)
+			total += max(len(got[s+i]), len(w))
+		}
+		similarity := 1.0
+		if total > 0 {
+			similarity = 1 - float64(dist)/float64(total)
+		}
+		if similarity > best {
+			best, bestAt = similarity, s
+		}
+	}
+
+	if best < threshold {
+		return fmt.Errorf("no fuzzy match for search: best match is lines %d-%d at %.0f%% similarity (threshold %.0f%%)",
+			bestAt+1, bestAt+len(want), best*100, threshold*100)
+	}
+
+	block := idx[bestAt : bestAt+len(want)]
+	b.lines[block[0]] = strings.TrimSuffix(replace, "\n")
+	b.removed[block[0]] = replace == ""
+	for _, i := range block[1:] {
+		b.removed[i] = true
+	}
+	return nil
+}
+
+// levenshtein returns the edit distance between a and b in runes
+func levenshtein(a, b string) int {
+	if a == b {
+		return 0
+	}
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
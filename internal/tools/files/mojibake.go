@@ -0,0 +1,369 @@
+package files
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+
+	"github.com/alucardeht/may-la-mcp/internal/paths"
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+const (
+	defaultMojibakeFiles = 100
+	// maxMojibakeLayers bounds how many rounds of double encoding are
+	// undone in one run
+	maxMojibakeLayers = 3
+	// maxMojibakeSamples bounds the repairs listed per file
+	maxMojibakeSamples = 5
+)
+
+// latin1Bytes maps the runes of Windows-1252, and of the C1 controls
+// Latin-1 decodes its unassigned bytes to, back to their byte. UTF-8 text
+// read as either of them turns into runes of this table only.
+var latin1Bytes = func() map[rune]byte {
+	m := make(map[rune]byte, 160)
+	for b := 0x80; b <= 0xFF; b++ {
+		if r := charmap.Windows1252.DecodeByte(byte(b)); r != utf8.RuneError {
+			m[r] = byte(b)
+		}
+		if _, ok := m[rune(b)]; !ok {
+			m[rune(b)] = byte(b)
+		}
+	}
+	return m
+}()
+
+type MojibakeRequest struct {
+	Path     string `json:"path"`
+	MaxFiles int    `json:"max_files,omitempty"`
+	Backup   bool   `json:"backup,omitempty"`
+}
+
+// MojibakeRepair is one mangled run of text and what it decodes back to
+type MojibakeRepair struct {
+	Line   int    `json:"line"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+type MojibakeFile struct {
+	Path    string           `json:"path"`
+	Repairs int              `json:"repairs"`
+	Samples []MojibakeRepair `json:"samples"`
+	Backup  string           `json:"backup,omitempty"`
+}
+
+type MojibakeResponse struct {
+	Path    string         `json:"path"`
+	Files   []MojibakeFile `json:"files"`
+	Repairs int            `json:"repairs"`
+	Applied bool           `json:"applied"`
+	Diff    string         `json:"diff,omitempty"`
+	// Truncated is set when more files than max_files needed repairs
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+type FixMojibakeTool struct{}
+
+func (t *FixMojibakeTool) Name() string {
+	return "fix_mojibake"
+}
+
+func (t *FixMojibakeTool) Description() string {
+	return `Repair text mangled by double encoding, where UTF-8 was read as Latin-1 or Windows-1252 and saved again as UTF-8, turning "é" into "Ã©" or "—" into "â€”".
+
+Each run of such characters is re-decoded, up to three layers deep; text that does not decode to valid UTF-8 is left alone. Only UTF-8 files are changed, so files still in a legacy encoding are not mistaken for mangled ones. All files are written together or not at all; use a dry run to preview the diff.`
+}
+
+func (t *FixMojibakeTool) Title() string {
+	return "Fix Mojibake"
+}
+
+func (t *FixMojibakeTool) Annotations() map[string]bool {
+	return tools.DestructiveAnnotations()
+}
+
+func (t *FixMojibakeTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"path": {
+				"type": "string",
+				"description": "File or directory to repair"
+			},
+			"max_files": {
+				"type": "integer",
+				"description": "Maximum files repaired in one run (default: 100)"
+			},
+			"backup": {
+				"type": "boolean",
+				"description": "Keep each repaired file as path.bak.<timestamp> (default: false)"
+			}
+		},
+		"required": ["path"]
+	}`)
+}
+
+func (t *FixMojibakeTool) Examples() []tools.Example {
+	return []tools.Example{
+		{
+			Description: "Repair the docs of a project",
+			Arguments:   json.RawMessage(`{"path": "/home/user/app/docs", "backup": true}`),
+			Result:      json.RawMessage(`{"path": "/home/user/app/docs", "files": [{"path": "/home/user/app/docs/intro.md", "repairs": 2, "samples": [{"line": 3, "before": "cafÃ©", "after": "café"}]}], "repairs": 2, "applied": true, "diff": "..."}`),
+		},
+	}
+}
+
+func (t *FixMojibakeTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	req, err := parseMojibakeRequest(input)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates, err := mojibakeCandidates(ctx, req.Path)
+	if err != nil {
+		return nil, err
+	}
+	planned, resp, err := planMojibakeRepair(candidates, req)
+	if err != nil {
+		return nil, err
+	}
+	if len(planned) == 0 {
+		return resp, nil
+	}
+
+	// only the files to repair are locked; commitEdits fails if one of
+	// them changed since it was read
+	locked := make([]string, len(planned))
+	for i, f := range planned {
+		locked[i] = f.path
+	}
+	unlock, err := lockPaths(ctx, locked...)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if err := commitEdits(planned, editOptions{backup: req.Backup}); err != nil {
+		return nil, err
+	}
+	for i, f := range planned {
+		tools.RecordAccess(f.path)
+		resp.Files[i].Backup = f.backup
+	}
+	resp.Applied = true
+	return resp, nil
+}
+
+func (t *FixMojibakeTool) DryRun(ctx context.Context, input json.RawMessage) (*tools.Preview, error) {
+	req, err := parseMojibakeRequest(input)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates, err := mojibakeCandidates(ctx, req.Path)
+	if err != nil {
+		return nil, err
+	}
+	planned, resp, err := planMojibakeRepair(candidates, req)
+	if err != nil {
+		return nil, err
+	}
+	summary := fmt.Sprintf("would make %d repairs in %d files", resp.Repairs, len(resp.Files))
+	preview := newEditSetResult(planned, false).Preview(summary)
+	preview.Details = resp.Files
+	return preview, nil
+}
+
+func parseMojibakeRequest(input json.RawMessage) (MojibakeRequest, error) {
+	var req MojibakeRequest
+	if err := json.Unmarshal(input, &req); err != nil {
+		return req, fmt.Errorf("invalid request: %w", err)
+	}
+	if req.Path == "" {
+		return req, fmt.Errorf("path is required")
+	}
+	if err := tools.CheckPath(req.Path); err != nil {
+		return req, err
+	}
+	req.Path = paths.Canonical(req.Path)
+	if req.MaxFiles <= 0 {
+		req.MaxFiles = defaultMojibakeFiles
+	}
+	return req, nil
+}
+
+// mojibakeCandidates lists path, or the text files under it, skipping
+// .git, denied paths and files too large to search
+func mojibakeCandidates(ctx context.Context, path string) ([]string, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat path: %w", err)
+	}
+	if !stat.IsDir() {
+		return []string{path}, nil
+	}
+
+	var candidates []string
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" || (p != path && tools.IsPathDenied(p)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || tools.IsPathDenied(p) {
+			return nil
+		}
+		if info, err := d.Info(); err != nil || info.Size() > maxConflictFileSize {
+			return nil
+		}
+		candidates = append(candidates, p)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return candidates, nil
+}
+
+// planMojibakeRepair works out the repaired content of the candidates that
+// need it. Binary files and files that are not valid UTF-8 are skipped.
+func planMojibakeRepair(candidates []string, req MojibakeRequest) ([]*plannedFile, *MojibakeResponse, error) {
+	resp := &MojibakeResponse{Path: req.Path, Files: []MojibakeFile{}}
+	var planned []*plannedFile
+	for _, path := range candidates {
+		f, err := loadPlannedFile(path)
+		if err != nil {
+			if path == req.Path {
+				return nil, nil, err
+			}
+			continue
+		}
+		if !f.existed {
+			return nil, nil, fmt.Errorf("file does not exist: %s", path)
+		}
+		if !utf8.ValidString(f.before) || strings.IndexByte(f.before[:min(len(f.before), conflictSniffSize)], 0) >= 0 {
+			if path == req.Path {
+				return nil, nil, fmt.Errorf("%s is not a UTF-8 text file", path)
+			}
+			continue
+		}
+
+		after, repairs := fixMojibake(f.before)
+		if len(repairs) == 0 {
+			continue
+		}
+		if len(planned) == req.MaxFiles {
+			resp.Truncated = true
+			break
+		}
+		f.after = after
+		f.action = "modify"
+		planned = append(planned, f)
+
+		resp.Repairs += len(repairs)
+		resp.Files = append(resp.Files, MojibakeFile{
+			Path:    path,
+			Repairs: len(repairs),
+			Samples: repairs[:min(len(repairs), maxMojibakeSamples)],
+		})
+	}
+	if len(planned) > 0 {
+		resp.Diff = newEditSetResult(planned, false).Diff
+	}
+	return planned, resp, nil
+}
+
+// fixMojibake re-decodes the double encoded runs of text, returning the
+// repaired text and each run it changed
+func fixMojibake(text string) (string, []MojibakeRepair) {
+	var repairs []MojibakeRepair
+	var b strings.Builder
+	line := 1
+	for len(text) > 0 {
+		// runs of non-ASCII runes are what double encoding produces; the
+		// ASCII between them is copied as it is
+		start := strings.IndexFunc(text, func(r rune) bool { return r >= utf8.RuneSelf })
+		if start < 0 {
+			b.WriteString(text)
+			break
+		}
+		b.WriteString(text[:start])
+		line += strings.Count(text[:start], "\n")
+		text = text[start:]
+
+		end := strings.IndexFunc(text, func(r rune) bool { return r < utf8.RuneSelf })
+		if end < 0 {
+			end = len(text)
+		}
+		run := text[:end]
+		text = text[end:]
+
+		fixed := run
+		for range maxMojibakeLayers {
+			next, ok := undoDoubleEncoding(fixed)
+			if !ok {
+				break
+			}
+			fixed = next
+		}
+		if fixed != run {
+			repairs = append(repairs, MojibakeRepair{Line: line, Before: run, After: fixed})
+		}
+		b.WriteString(fixed)
+	}
+	return b.String(), repairs
+}
+
+// undoDoubleEncoding turns run back into the bytes it was decoded from as
+// Windows-1252 and decodes each valid multi-byte UTF-8 sequence among
+// them. Runes outside the table, and bytes that start no valid sequence,
+// are kept as they are, so text that was never mangled stays put.
+func undoDoubleEncoding(run string) (string, bool) {
+	runes := []rune(run)
+	raw := make([]byte, len(runes))
+	for i, r := range runes {
+		b, ok := latin1Bytes[r]
+		if !ok {
+			// a zero byte starts no sequence, so the rune is kept
+			b = 0
+		}
+		raw[i] = b
+	}
+
+	var b bytes.Buffer
+	changed := false
+	for i := 0; i < len(raw); {
+		if raw[i] >= 0xC2 {
+			if r, size := utf8.DecodeRune(raw[i:]); r != utf8.RuneError && size > 1 {
+				b.WriteRune(r)
+				i += size
+				changed = true
+				continue
+			}
+		}
+		b.WriteRune(runes[i])
+		i++
+	}
+	return b.String(), changed
+}
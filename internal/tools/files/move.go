@@ -63,37 +63,26 @@ func (t *MoveTool) Execute(ctx context.Context, input json.RawMessage) (interfac
 	if err := json.Unmarshal(input, &req); err != nil {
 		return nil, fmt.Errorf("invalid request: %w", err)
 	}
-
-	if req.Source == "" {
-		return nil, fmt.Errorf("source is required")
+	for _, path := range []string{req.Source, req.Destination} {
+		if err := tools.CheckPath(path); err != nil {
+			return nil, err
+		}
 	}
 
-	if req.Destination == "" {
-		return nil, fmt.Errorf("destination is required")
+	unlock, err := lockPaths(ctx, req.Source, req.Destination)
+	if err != nil {
+		return nil, err
 	}
+	defer unlock()
 
-	sourceStat, err := os.Stat(req.Source)
+	sourceStat, destExists, err := checkMove(req)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("source does not exist")
-		}
-		return nil, fmt.Errorf("failed to stat source: %w", err)
+		return nil, err
 	}
 
-	destStat, err := os.Stat(req.Destination)
-	if err == nil {
-		if !req.Overwrite {
-			return nil, fmt.Errorf("destination already exists, use overwrite=true")
-		}
-
-		if sourceStat.IsDir() != destStat.IsDir() {
-			return nil, fmt.Errorf("source and destination types do not match")
-		}
-
-		if !sourceStat.IsDir() {
-			if err := os.Remove(req.Destination); err != nil {
-				return nil, fmt.Errorf("failed to remove existing destination: %w", err)
-			}
+	if destExists && !sourceStat.IsDir() {
+		if err := os.Remove(req.Destination); err != nil {
+			return nil, fmt.Errorf("failed to remove existing destination: %w", err)
 		}
 	}
 
@@ -124,6 +113,66 @@ func (t *MoveTool) Execute(ctx context.Context, input json.RawMessage) (interfac
 	}, nil
 }
 
+// checkMove validates req and returns the source's file info and whether
+// the destination already exists.
+func checkMove(req MoveRequest) (os.FileInfo, bool, error) {
+	if req.Source == "" {
+		return nil, false, fmt.Errorf("source is required")
+	}
+
+	if req.Destination == "" {
+		return nil, false, fmt.Errorf("destination is required")
+	}
+
+	sourceStat, err := os.Stat(req.Source)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, false, fmt.Errorf("source does not exist")
+		}
+		return nil, false, fmt.Errorf("failed to stat source: %w", err)
+	}
+
+	destStat, err := os.Stat(req.Destination)
+	if err != nil {
+		return sourceStat, false, nil
+	}
+	if !req.Overwrite {
+		return nil, false, fmt.Errorf("destination already exists, use overwrite=true")
+	}
+	if sourceStat.IsDir() != destStat.IsDir() {
+		return nil, false, fmt.Errorf("source and destination types do not match")
+	}
+	return sourceStat, true, nil
+}
+
+func (t *MoveTool) DryRun(ctx context.Context, input json.RawMessage) (*tools.Preview, error) {
+	var req MoveRequest
+	if err := json.Unmarshal(input, &req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+	for _, path := range []string{req.Source, req.Destination} {
+		if err := tools.CheckPath(path); err != nil {
+			return nil, err
+		}
+	}
+
+	_, destExists, err := checkMove(req)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &tools.Preview{
+		Summary: fmt.Sprintf("would move %s to %s", req.Source, req.Destination),
+		Created: tools.MissingDirs(filepath.Dir(req.Destination)),
+		Moved:   []tools.PathMove{{From: req.Source, To: req.Destination}},
+	}
+	if destExists {
+		preview.Summary += ", replacing the existing destination"
+		preview.Modified = []string{req.Destination}
+	}
+	return preview, nil
+}
+
 func (t *MoveTool) Title() string {
 	return "Move or Rename File"
 }
@@ -0,0 +1,703 @@
+package files
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+// defaultPatchFuzz is how many context lines a hunk may drop from each end
+// when its full context no longer matches
+const defaultPatchFuzz = 2
+
+const devNull = "/dev/null"
+
+type PatchRequest struct {
+	Patch   string `json:"patch"`
+	BaseDir string `json:"baseDir,omitempty"`
+	Strip   *int   `json:"strip,omitempty"`
+	Fuzz    *int   `json:"fuzz,omitempty"`
+	Backup  bool   `json:"backup,omitempty"`
+}
+
+type PatchResponse struct {
+	Applied  bool              `json:"applied"`
+	Files    []PatchFileResult `json:"files"`
+	Rejected []RejectedHunk    `json:"rejected,omitempty"`
+}
+
+type PatchFileResult struct {
+	Path   string       `json:"path"`
+	Action string       `json:"action"`
+	Hunks  []HunkResult `json:"hunks"`
+	Backup string       `json:"backup,omitempty"`
+}
+
+// HunkResult tells where a hunk was applied and how loosely it matched
+type HunkResult struct {
+	Header            string `json:"header"`
+	Line              int    `json:"line"`
+	Offset            int    `json:"offset,omitempty"`
+	Fuzz              int    `json:"fuzz,omitempty"`
+	IgnoredWhitespace bool   `json:"ignoredWhitespace,omitempty"`
+}
+
+type RejectedHunk struct {
+	Path   string `json:"path"`
+	Header string `json:"header"`
+	Reason string `json:"reason"`
+}
+
+type ApplyPatchTool struct{}
+
+func (t *ApplyPatchTool) Name() string {
+	return "apply_patch"
+}
+
+func (t *ApplyPatchTool) Description() string {
+	return "Apply a unified diff to one or more files. Hunks are matched against the current content, tolerating shifted line numbers, whitespace differences and (with fuzz) stale outer context lines. The patch is applied atomically: if any hunk is rejected, no file is changed and the rejected hunks are reported with reasons."
+}
+
+func (t *ApplyPatchTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"patch": {
+				"type": "string",
+				"description": "Unified diff, optionally covering several files (git diff output is accepted)"
+			},
+			"baseDir": {
+				"type": "string",
+				"description": "Directory that relative paths in the patch resolve against (absolute path required when the patch uses relative paths)"
+			},
+			"strip": {
+				"type": "integer",
+				"description": "Leading path components to strip from patch paths, like patch -p (default: 1 for git-style a/ b/ paths, otherwise 0)"
+			},
+			"fuzz": {
+				"type": "integer",
+				"description": "Context lines a hunk may ignore at each end when it does not match exactly (default: 2)"
+			},
+			"backup": {
+				"type": "boolean",
+				"description": "Keep the previous content of each patched file as path.bak.<timestamp> (default: false)"
+			}
+		},
+		"required": ["patch"]
+	}`)
+}
+
+func (t *ApplyPatchTool) Examples() []tools.Example {
+	return []tools.Example{
+		{
+			Description: "Apply a git diff to a project",
+			Arguments:   json.RawMessage(`{"patch": "--- a/main.go\n+++ b/main.go\n@@ -3,3 +3,3 @@\n func main() {\n-\tfmt.Println(\"hi\")\n+\tfmt.Println(\"hello\")\n }\n", "baseDir": "/home/user/app"}`),
+			Result:      json.RawMessage(`{"applied": true, "files": [{"path": "/home/user/app/main.go", "action": "modified", "hunks": [{"header": "@@ -3,3 +3,3 @@", "line": 3}]}]}`),
+		},
+	}
+}
+
+func (t *ApplyPatchTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var req PatchRequest
+	if err := json.Unmarshal(input, &req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	targets, err := patchTargets(req)
+	if err != nil {
+		return nil, err
+	}
+	unlock, err := lockPaths(ctx, targets...)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	planned, rejected, err := planPatch(req)
+	if err != nil {
+		return nil, err
+	}
+
+	response := PatchResponse{Files: patchFileResults(planned), Rejected: rejected}
+	if len(rejected) > 0 {
+		return response, nil
+	}
+
+	if err := commitEdits(planned, editOptions{backup: req.Backup}); err != nil {
+		return nil, err
+	}
+	for _, f := range planned {
+		tools.RecordAccess(f.path)
+	}
+
+	response.Files = patchFileResults(planned)
+	response.Applied = true
+	return response, nil
+}
+
+func (t *ApplyPatchTool) DryRun(ctx context.Context, input json.RawMessage) (*tools.Preview, error) {
+	var req PatchRequest
+	if err := json.Unmarshal(input, &req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	planned, rejected, err := planPatch(req)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &tools.Preview{
+		Details: map[string]interface{}{
+			"files":    patchFileResults(planned),
+			"rejected": rejected,
+		},
+	}
+	if len(rejected) > 0 {
+		preview.Summary = fmt.Sprintf("patch would be rejected: %d hunks do not apply", len(rejected))
+		return preview, nil
+	}
+
+	result := newEditSetResult(planned, false).Preview(fmt.Sprintf("would patch %d files", len(planned)))
+	result.Details = preview.Details
+	return result, nil
+}
+
+func (t *ApplyPatchTool) Title() string {
+	return "Apply Patch"
+}
+
+func (t *ApplyPatchTool) Annotations() map[string]bool {
+	return tools.DestructiveAnnotations()
+}
+
+func patchFileResults(planned []*plannedFile) []PatchFileResult {
+	results := make([]PatchFileResult, 0, len(planned))
+	for _, f := range planned {
+		results = append(results, PatchFileResult{Path: f.path, Action: f.action, Hunks: f.hunks, Backup: f.backup})
+	}
+	return results
+}
+
+// planPatch parses the patch and applies it in memory. Files patched more
+// than once see the result of the earlier sections.
+func planPatch(req PatchRequest) ([]*plannedFile, []RejectedHunk, error) {
+	if req.Patch == "" {
+		return nil, nil, fmt.Errorf("patch is required")
+	}
+
+	fuzz := defaultPatchFuzz
+	if req.Fuzz != nil {
+		if *req.Fuzz < 0 {
+			return nil, nil, fmt.Errorf("fuzz must not be negative")
+		}
+		fuzz = *req.Fuzz
+	}
+
+	sections, err := parsePatch(req.Patch)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid patch: %w", err)
+	}
+
+	var planned []*plannedFile
+	byPath := make(map[string]*plannedFile)
+	var rejected []RejectedHunk
+
+	for _, section := range sections {
+		path, action, err := section.target(req)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		f, seen := byPath[path]
+		if !seen {
+			if err := tools.CheckPath(path); err != nil {
+				return nil, nil, err
+			}
+			if f, err = loadPlannedFile(path); err != nil {
+				return nil, nil, err
+			}
+			byPath[path] = f
+			planned = append(planned, f)
+		}
+
+		exists := f.existed
+		if seen {
+			exists = f.action != "delete"
+		}
+		switch {
+		case action == "create" && exists:
+			rejected = append(rejected, section.rejectAll(path, "file already exists")...)
+			continue
+		case action != "create" && !exists:
+			rejected = append(rejected, section.rejectAll(path, "file does not exist")...)
+			continue
+		}
+
+		after, results, failures := applyHunks(f.after, section.hunks, fuzz)
+		for _, failure := range failures {
+			failure.Path = path
+			rejected = append(rejected, failure)
+		}
+
+		f.after = after
+		f.hunks = append(f.hunks, results...)
+		switch {
+		case action == "delete":
+			f.action, f.after = "delete", ""
+		case !f.existed:
+			f.action = "create"
+		default:
+			f.action = "modify"
+		}
+	}
+
+	return planned, rejected, nil
+}
+
+// patchTargets lists the paths of the files a patch touches; an empty
+// patch is left for planPatch to reject
+func patchTargets(req PatchRequest) ([]string, error) {
+	if req.Patch == "" {
+		return nil, nil
+	}
+	sections, err := parsePatch(req.Patch)
+	if err != nil {
+		return nil, fmt.Errorf("invalid patch: %w", err)
+	}
+	var paths []string
+	for _, section := range sections {
+		path, _, err := section.target(req)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+type patchLine struct {
+	kind byte // ' ', '-' or '+'
+	text string
+}
+
+type patchHunk struct {
+	header   string
+	oldStart int
+	oldCount int
+	newStart int
+	newCount int
+	lines    []patchLine
+	// oldNoEOL and newNoEOL record "\ No newline at end of file" markers
+	oldNoEOL bool
+	newNoEOL bool
+}
+
+// patchSection is the part of a patch that changes one file
+type patchSection struct {
+	oldPath    string
+	newPath    string
+	hasHeaders bool
+	hunks      []*patchHunk
+}
+
+var hunkHeaderPattern = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// parsePatch splits a unified diff into per-file sections. Lines outside
+// file headers and hunks (git extended headers, commit messages) are
+// ignored.
+func parsePatch(text string) ([]*patchSection, error) {
+	lines := strings.Split(text, "\n")
+
+	var sections []*patchSection
+	var current *patchSection
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			current = &patchSection{}
+			sections = append(sections, current)
+			if parts := strings.SplitN(strings.TrimPrefix(line, "diff --git "), " ", 2); len(parts) == 2 {
+				current.oldPath, current.newPath = parts[0], parts[1]
+			}
+
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			if current == nil || current.hasHeaders || len(current.hunks) > 0 {
+				current = &patchSection{}
+				sections = append(sections, current)
+			}
+			current.oldPath = headerPath(line[4:])
+			current.newPath = headerPath(lines[i+1][4:])
+			current.hasHeaders = true
+			i++
+
+		case strings.HasPrefix(line, "@@ "):
+			if current == nil {
+				return nil, fmt.Errorf("line %d: hunk without a file header", i+1)
+			}
+			hunk, next, err := parseHunk(lines, i)
+			if err != nil {
+				return nil, err
+			}
+			current.hunks = append(current.hunks, hunk)
+			i = next - 1
+		}
+	}
+
+	if len(sections) == 0 {
+		return nil, fmt.Errorf("no file changes found")
+	}
+	for _, section := range sections {
+		if len(section.hunks) == 0 {
+			return nil, fmt.Errorf("%s has no hunks; renames and mode changes are not supported", section.newPath)
+		}
+	}
+	return sections, nil
+}
+
+// parseHunk reads the hunk starting at lines[start] and returns the index
+// of the first line after it. The header's line counts decide where the
+// hunk ends.
+func parseHunk(lines []string, start int) (*patchHunk, int, error) {
+	m := hunkHeaderPattern.FindStringSubmatch(lines[start])
+	if m == nil {
+		return nil, 0, fmt.Errorf("line %d: malformed hunk header %q", start+1, lines[start])
+	}
+
+	count := func(s string) int {
+		if s == "" {
+			return 1
+		}
+		n, _ := strconv.Atoi(s)
+		return n
+	}
+	hunk := &patchHunk{header: m[0]}
+	hunk.oldStart, _ = strconv.Atoi(m[1])
+	hunk.oldCount = count(m[2])
+	hunk.newStart, _ = strconv.Atoi(m[3])
+	hunk.newCount = count(m[4])
+
+	oldSeen, newSeen := 0, 0
+	i := start + 1
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if strings.HasPrefix(line, `\`) {
+			hunk.markNoEOL()
+			continue
+		}
+		if oldSeen >= hunk.oldCount && newSeen >= hunk.newCount {
+			break
+		}
+
+		kind, text := byte(' '), ""
+		if line != "" {
+			kind, text = line[0], line[1:]
+		}
+		switch kind {
+		case ' ':
+			oldSeen++
+			newSeen++
+		case '-':
+			oldSeen++
+		case '+':
+			newSeen++
+		default:
+			return nil, 0, fmt.Errorf("line %d: unexpected line in hunk %s", i+1, hunk.header)
+		}
+		hunk.lines = append(hunk.lines, patchLine{kind: kind, text: text})
+	}
+
+	if oldSeen != hunk.oldCount || newSeen != hunk.newCount {
+		return nil, 0, fmt.Errorf("hunk %s is truncated or its line counts are wrong", hunk.header)
+	}
+	return hunk, i, nil
+}
+
+// markNoEOL applies a "\ No newline at end of file" marker to the side(s)
+// of the line before it
+func (h *patchHunk) markNoEOL() {
+	if len(h.lines) == 0 {
+		return
+	}
+	switch h.lines[len(h.lines)-1].kind {
+	case '-':
+		h.oldNoEOL = true
+	case '+':
+		h.newNoEOL = true
+	default:
+		h.oldNoEOL = true
+		h.newNoEOL = true
+	}
+}
+
+// headerPath extracts the path from a ---/+++ header, dropping the
+// timestamp some diff tools append after a tab
+func headerPath(s string) string {
+	if tab := strings.IndexByte(s, '\t'); tab >= 0 {
+		s = s[:tab]
+	}
+	s = strings.TrimSpace(s)
+	if unquoted, err := strconv.Unquote(s); err == nil && strings.HasPrefix(s, `"`) {
+		s = unquoted
+	}
+	return s
+}
+
+// target resolves the file the section changes and whether it creates,
+// deletes or modifies it
+func (s *patchSection) target(req PatchRequest) (string, string, error) {
+	strip := 0
+	if req.Strip != nil {
+		strip = *req.Strip
+	} else if (s.oldPath == devNull || strings.HasPrefix(s.oldPath, "a/")) &&
+		(s.newPath == devNull || strings.HasPrefix(s.newPath, "b/")) {
+		strip = 1
+	}
+
+	action, raw := "modify", s.newPath
+	switch {
+	case s.oldPath == devNull && s.newPath == devNull:
+		return "", "", fmt.Errorf("invalid patch: both paths are %s", devNull)
+	case s.oldPath == devNull:
+		action = "create"
+	case s.newPath == devNull:
+		action, raw = "delete", s.oldPath
+	}
+
+	path, err := resolvePatchPath(raw, strip, req.BaseDir)
+	if err != nil {
+		return "", "", err
+	}
+
+	if action == "modify" {
+		oldPath, err := resolvePatchPath(s.oldPath, strip, req.BaseDir)
+		if err != nil {
+			return "", "", err
+		}
+		if oldPath != path {
+			return "", "", fmt.Errorf("renaming %s to %s is not supported", oldPath, path)
+		}
+	}
+	return path, action, nil
+}
+
+func resolvePatchPath(raw string, strip int, baseDir string) (string, error) {
+	path := raw
+	for i := 0; i < strip; i++ {
+		slash := strings.IndexByte(strings.TrimLeft(path, "/"), '/')
+		if slash < 0 {
+			return "", fmt.Errorf("cannot strip %d components from %s", strip, raw)
+		}
+		path = strings.TrimLeft(path, "/")[slash+1:]
+	}
+
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path), nil
+	}
+	if baseDir == "" {
+		return "", fmt.Errorf("patch path %s is relative: baseDir is required", raw)
+	}
+	if !filepath.IsAbs(baseDir) {
+		return "", fmt.Errorf("baseDir must be an absolute path")
+	}
+
+	resolved := filepath.Join(baseDir, path)
+	if resolved != filepath.Clean(baseDir) && !strings.HasPrefix(resolved, filepath.Clean(baseDir)+string(filepath.Separator)) {
+		return "", fmt.Errorf("patch path %s escapes baseDir", raw)
+	}
+	return resolved, nil
+}
+
+func (s *patchSection) rejectAll(path, reason string) []RejectedHunk {
+	rejected := make([]RejectedHunk, 0, len(s.hunks))
+	for _, h := range s.hunks {
+		rejected = append(rejected, RejectedHunk{Path: path, Header: h.header, Reason: reason})
+	}
+	return rejected
+}
+
+// applyHunks applies hunks in order to content. Each hunk is located
+// nearest to the line its header names, shifted by the offset of the
+// previous hunk; a hunk that cannot be placed is returned as rejected.
+func applyHunks(content string, hunks []*patchHunk, fuzz int) (string, []HunkResult, []RejectedHunk) {
+	var lines []string
+	trailingNewline := strings.HasSuffix(content, "\n")
+	if content != "" {
+		lines = strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	}
+
+	var out []string
+	var results []HunkResult
+	var rejected []RejectedHunk
+	cursor, offset := 0, 0
+
+	for _, h := range hunks {
+		m, ok := locateHunk(lines, h, cursor, offset, fuzz)
+		if !ok {
+			rejected = append(rejected, RejectedHunk{Header: h.header, Reason: mismatchReason(lines, h, offset)})
+			continue
+		}
+
+		out = append(out, lines[cursor:m.pos]...)
+		i := m.pos
+		for _, l := range m.lines {
+			switch l.kind {
+			case ' ':
+				out = append(out, lines[i])
+				i++
+			case '-':
+				i++
+			case '+':
+				out = append(out, l.text)
+			}
+		}
+
+		if i == len(lines) {
+			if h.newNoEOL {
+				trailingNewline = false
+			} else if h.oldNoEOL || len(lines) == 0 {
+				trailingNewline = true
+			}
+		}
+
+		results = append(results, HunkResult{
+			Header:            h.header,
+			Line:              m.pos + 1,
+			Offset:            m.pos - m.expected,
+			Fuzz:              m.fuzz,
+			IgnoredWhitespace: m.loose,
+		})
+		cursor = i
+		offset = m.pos - m.expected
+	}
+	out = append(out, lines[cursor:]...)
+
+	result := strings.Join(out, "\n")
+	if trailingNewline && len(out) > 0 {
+		result += "\n"
+	}
+	return result, results, rejected
+}
+
+type hunkMatch struct {
+	pos      int
+	expected int
+	lines    []patchLine
+	fuzz     int
+	loose    bool
+}
+
+// locateHunk finds where the hunk's old side occurs at or after minPos.
+// It tries an exact match, then one ignoring whitespace, then the same
+// again with up to fuzz context lines dropped from each end.
+func locateHunk(lines []string, h *patchHunk, minPos, offset, fuzz int) (hunkMatch, bool) {
+	trimmedBefore := -1
+	for f := 0; f <= fuzz; f++ {
+		trimmed, lead := trimContext(h.lines, f)
+		if len(trimmed) == trimmedBefore {
+			break
+		}
+		trimmedBefore = len(trimmed)
+
+		var old []string
+		for _, l := range trimmed {
+			if l.kind != '+' {
+				old = append(old, l.text)
+			}
+		}
+
+		expected := h.oldStart - 1 + lead + offset
+		if h.oldCount == 0 {
+			expected = h.oldStart + offset
+		}
+
+		for _, loose := range []bool{false, true} {
+			if pos, ok := searchLines(lines, old, expected, minPos, loose); ok {
+				return hunkMatch{pos: pos, expected: expected, lines: trimmed, fuzz: f, loose: loose}, true
+			}
+		}
+	}
+	return hunkMatch{}, false
+}
+
+// trimContext drops up to n context lines from each end of a hunk and
+// returns the rest with the number dropped from the start
+func trimContext(lines []patchLine, n int) ([]patchLine, int) {
+	lead := 0
+	for lead < n && lead < len(lines) && lines[lead].kind == ' ' {
+		lead++
+	}
+	end := len(lines)
+	for trail := 0; trail < n && end > lead && lines[end-1].kind == ' '; trail++ {
+		end--
+	}
+	return lines[lead:end], lead
+}
+
+// searchLines returns the position of old in lines closest to expected,
+// not before minPos
+func searchLines(lines, old []string, expected, minPos int, loose bool) (int, bool) {
+	last := len(lines) - len(old)
+	if last < minPos {
+		return 0, false
+	}
+	if len(old) == 0 {
+		return min(max(expected, minPos), len(lines)), true
+	}
+
+	for d := 0; expected-d >= minPos || expected+d <= last; d++ {
+		for _, pos := range []int{expected + d, expected - d} {
+			if pos >= minPos && pos <= last && linesMatch(lines[pos:pos+len(old)], old, loose) {
+				return pos, true
+			}
+			if d == 0 {
+				break
+			}
+		}
+	}
+	return 0, false
+}
+
+func linesMatch(got, want []string, loose bool) bool {
+	for i := range want {
+		if got[i] == want[i] {
+			continue
+		}
+		if !loose || strings.Join(strings.Fields(got[i]), " ") != strings.Join(strings.Fields(want[i]), " ") {
+			return false
+		}
+	}
+	return true
+}
+
+// mismatchReason explains why a hunk could not be placed, pointing at the
+// first line that differs where the hunk expected to apply
+func mismatchReason(lines []string, h *patchHunk, offset int) string {
+	expected := h.oldStart - 1 + offset
+	var old []string
+	for _, l := range h.lines {
+		if l.kind != '+' {
+			old = append(old, l.text)
+		}
+	}
+
+	if expected < 0 || expected+len(old) > len(lines) {
+		return fmt.Sprintf("hunk expects lines %d-%d but the file has %d lines and its context was not found elsewhere",
+			expected+1, expected+len(old), len(lines))
+	}
+	for i, want := range old {
+		if lines[expected+i] != want {
+			return fmt.Sprintf("context not found; at line %d expected %q but found %q", expected+i+1, want, lines[expected+i])
+		}
+	}
+	return "context not found"
+}
@@ -9,7 +9,10 @@ import (
 	"strings"
 	"unicode/utf8"
 
+	"github.com/alucardeht/may-la-mcp/internal/extract"
+	"github.com/alucardeht/may-la-mcp/internal/notebook"
 	"github.com/alucardeht/may-la-mcp/internal/tools"
+	"github.com/alucardeht/may-la-mcp/internal/types"
 )
 
 const maxMmapSize = 1024 * 1024
@@ -19,6 +22,7 @@ type ReadRequest struct {
 	Offset   int64  `json:"offset,omitempty"`
 	Limit    int64  `json:"limit,omitempty"`
 	Encoding string `json:"encoding,omitempty"`
+	tools.ResponseOptions
 }
 
 type ReadResponse struct {
@@ -26,6 +30,13 @@ type ReadResponse struct {
 	Size     int64  `json:"size"`
 	Encoding string `json:"encoding"`
 	Lines    int    `json:"lines"`
+	// Format is the document format text was extracted from (pdf, docx),
+	// or notebook
+	Format string `json:"format,omitempty"`
+	// Cells are the cells of a notebook, returned instead of its JSON
+	Cells []notebook.Cell `json:"cells,omitempty"`
+	// Language is the kernel language of a notebook
+	Language string `json:"language,omitempty"`
 }
 
 type ReadTool struct{}
@@ -35,7 +46,7 @@ func (t *ReadTool) Name() string {
 }
 
 func (t *ReadTool) Description() string {
-	return "Efficiently read file contents with streaming and encoding detection"
+	return "Efficiently read file contents with streaming and encoding detection; returns the cells of Jupyter notebooks, and the text of PDF and DOCX documents when document extraction is enabled"
 }
 
 func (t *ReadTool) Schema() json.RawMessage {
@@ -48,7 +59,7 @@ func (t *ReadTool) Schema() json.RawMessage {
 			},
 			"offset": {
 				"type": "integer",
-				"description": "Starting byte offset (optional, default: 0)",
+				"description": "Starting byte offset (optional, default: 0); of the extracted text for documents. Reading a notebook with an offset or limit returns its JSON instead of its cells",
 				"minimum": 0
 			},
 			"limit": {
@@ -60,6 +71,16 @@ func (t *ReadTool) Schema() json.RawMessage {
 				"type": "string",
 				"description": "Encoding (auto-detect if omitted)",
 				"enum": ["utf-8", "utf-16", "iso-8859-1", "auto"]
+			},
+			"response_mode": {
+				"type": "string",
+				"enum": ["compact", "detailed", "raw"],
+				"description": "Return the result as text instead of JSON: compact (summary plus content cut to max_length, default 2000 chars), detailed (numbered lines with counts, default 5000 chars) or raw (unformatted)"
+			},
+			"max_length": {
+				"type": "integer",
+				"minimum": 0,
+				"description": "Maximum characters of the text result; selects compact when response_mode is omitted"
 			}
 		},
 		"required": ["path"]
@@ -79,12 +100,27 @@ func (t *ReadTool) Execute(ctx context.Context, input json.RawMessage) (interfac
 	if req.Path == "" {
 		return nil, fmt.Errorf("path is required")
 	}
+	if err := req.ResponseOptions.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := tools.CheckPath(req.Path); err != nil {
+		return nil, err
+	}
+
+	if format := extract.Format(req.Path); format != "" {
+		return readDocument(req, format)
+	}
+	if notebook.IsNotebook(req.Path) && req.Offset == 0 && req.Limit == 0 {
+		return readNotebook(req)
+	}
 
 	file, err := os.Open(req.Path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
 	defer file.Close()
+	tools.RecordAccess(req.Path)
 
 	stat, err := file.Stat()
 	if err != nil {
@@ -132,6 +168,14 @@ func (t *ReadTool) Execute(ctx context.Context, input json.RawMessage) (interfac
 		lineCount = 0
 	}
 
+	if req.ResponseOptions.Enabled() {
+		return req.Format(contentStr, map[string]interface{}{
+			"path":     req.Path,
+			"size":     fileSize,
+			"encoding": encoding,
+		}), nil
+	}
+
 	return ReadResponse{
 		Content:  contentStr,
 		Size:     fileSize,
@@ -140,6 +184,84 @@ func (t *ReadTool) Execute(ctx context.Context, input json.RawMessage) (interfac
 	}, nil
 }
 
+// readDocument returns the text extracted from a PDF or DOCX document, with
+// offset and limit applied to the text
+func readDocument(req ReadRequest, format string) (interface{}, error) {
+	text, err := extract.File(req.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract %s text: %w", format, err)
+	}
+	tools.RecordAccess(req.Path)
+	size := int64(len(text))
+
+	if req.Offset > 0 {
+		text = text[min(req.Offset, size):]
+	}
+	if req.Limit > 0 && int64(len(text)) > req.Limit {
+		text = text[:req.Limit]
+	}
+	text = strings.ToValidUTF8(text, "")
+
+	if req.ResponseOptions.Enabled() {
+		return req.Format(text, map[string]interface{}{
+			"path":     req.Path,
+			"size":     size,
+			"encoding": "utf-8",
+			"format":   format,
+		}), nil
+	}
+
+	lines := strings.Count(text, "\n") + 1
+	if text == "" {
+		lines = 0
+	}
+	return ReadResponse{
+		Content:  text,
+		Size:     size,
+		Encoding: "utf-8",
+		Lines:    lines,
+		Format:   format,
+	}, nil
+}
+
+// readNotebook returns the cells of a notebook; text response modes render
+// them as a percent-format script
+func readNotebook(req ReadRequest) (interface{}, error) {
+	data, err := os.ReadFile(req.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	tools.RecordAccess(req.Path)
+
+	nb, err := notebook.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.ResponseOptions.Enabled() {
+		return req.Format(nb.Render(), map[string]interface{}{
+			"path":     req.Path,
+			"size":     len(data),
+			"format":   "notebook",
+			"language": nb.Language,
+			"cells":    len(nb.Cells),
+		}), nil
+	}
+
+	lines := 0
+	for _, cell := range nb.Cells {
+		lines += len(types.SplitLines(cell.Source))
+	}
+	return ReadResponse{
+		Size:     int64(len(data)),
+		Encoding: "utf-8",
+		Lines:    lines,
+		Format:   "notebook",
+		Cells:    nb.Cells,
+		Language: nb.Language,
+	}, nil
+}
+
 func detectEncoding(data []byte) string {
 	if len(data) == 0 {
 		return "utf-8"
@@ -0,0 +1,103 @@
+package files
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Line ending and BOM policies for write and edit
+const (
+	policyPreserve  = "preserve"
+	lineEndingsLF   = "lf"
+	lineEndingsCRLF = "crlf"
+	bomAdd          = "add"
+	bomStrip        = "strip"
+)
+
+const utf8BOM = "\xEF\xBB\xBF"
+
+// textFormat is how a file lays out its text on disk. lineEnding is empty
+// when the file has no line breaks or mixes LF and CRLF.
+type textFormat struct {
+	lineEnding string
+	bom        bool
+}
+
+func detectFormat(content string) textFormat {
+	format := textFormat{bom: strings.HasPrefix(content, utf8BOM)}
+	crlf := strings.Count(content, "\r\n")
+	lf := strings.Count(content, "\n") - crlf
+	switch {
+	case crlf > 0 && lf == 0:
+		format.lineEnding = lineEndingsCRLF
+	case lf > 0 && crlf == 0:
+		format.lineEnding = lineEndingsLF
+	}
+	return format
+}
+
+// decode strips the BOM and turns CRLF line endings into LF, so edits see
+// plain lines
+func (f textFormat) decode(content string) string {
+	content = strings.TrimPrefix(content, utf8BOM)
+	if f.lineEnding == lineEndingsCRLF {
+		content = strings.ReplaceAll(content, "\r\n", "\n")
+	}
+	return content
+}
+
+type formatPolicy struct {
+	lineEndings string
+	bom         string
+}
+
+func newFormatPolicy(lineEndings, bom string) (formatPolicy, error) {
+	policy := formatPolicy{lineEndings: lineEndings, bom: bom}
+	switch policy.lineEndings {
+	case "":
+		policy.lineEndings = policyPreserve
+	case policyPreserve, lineEndingsLF, lineEndingsCRLF:
+	default:
+		return policy, fmt.Errorf("invalid lineEndings %q: expected lf, crlf or preserve", lineEndings)
+	}
+	switch policy.bom {
+	case "":
+		policy.bom = policyPreserve
+	case policyPreserve, bomAdd, bomStrip:
+	default:
+		return policy, fmt.Errorf("invalid bom %q: expected add, strip or preserve", bom)
+	}
+	return policy, nil
+}
+
+// apply lays content out according to the policy. existing is the format
+// of the file being replaced, or nil for a new file; preserve keeps its
+// conventions and leaves content as given when there is nothing to keep.
+func (p formatPolicy) apply(content string, existing *textFormat) string {
+	body, hasBOM := strings.CutPrefix(content, utf8BOM)
+
+	lineEnding := p.lineEndings
+	if lineEnding == policyPreserve && existing != nil {
+		lineEnding = existing.lineEnding
+	}
+	switch lineEnding {
+	case lineEndingsLF:
+		body = strings.ReplaceAll(body, "\r\n", "\n")
+	case lineEndingsCRLF:
+		body = strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n")
+	}
+
+	switch {
+	case p.bom == bomAdd:
+		hasBOM = true
+	case p.bom == bomStrip:
+		hasBOM = false
+	case existing != nil:
+		hasBOM = existing.bom
+	}
+
+	if hasBOM {
+		return utf8BOM + body
+	}
+	return body
+}
@@ -9,11 +9,17 @@ func GetTools() []tools.Tool {
 		&ReadTool{},
 		&WriteTool{},
 		&EditTool{},
+		&ApplyPatchTool{},
+		&ConflictsTool{},
+		&FixMojibakeTool{},
 		&CreateTool{},
 		&DeleteTool{},
 		&MoveTool{},
+		&ChmodTool{},
+		&TouchTool{},
 		&ListTool{},
 		&InfoTool{},
+		&DuTool{},
 	}
 }
 
@@ -0,0 +1,195 @@
+package files
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+type TouchRequest struct {
+	Path     string `json:"path"`
+	Mtime    string `json:"mtime,omitempty"`
+	Atime    string `json:"atime,omitempty"`
+	NoCreate bool   `json:"no_create,omitempty"`
+}
+
+type TouchResponse struct {
+	Path     string    `json:"path"`
+	Created  bool      `json:"created"`
+	Modified time.Time `json:"modified"`
+}
+
+type TouchTool struct{}
+
+func (t *TouchTool) Name() string {
+	return "touch"
+}
+
+func (t *TouchTool) Description() string {
+	return `Update the modification and access times of a file or directory inside the workspace, creating an empty file if it does not exist.
+
+Times are RFC 3339 timestamps or "now". Without mtime and atime both are set to now; with only one of them the other is left as it is. With no_create, a missing file is an error instead of being created. Paths outside the workspace root, also through symlinks, are refused.`
+}
+
+func (t *TouchTool) Title() string {
+	return "Touch File"
+}
+
+func (t *TouchTool) Annotations() map[string]bool {
+	return tools.NonIdempotentWriteAnnotations()
+}
+
+func (t *TouchTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"path": {
+				"type": "string",
+				"description": "File to touch"
+			},
+			"mtime": {
+				"type": "string",
+				"description": "Modification time, RFC 3339 or \"now\" (optional)"
+			},
+			"atime": {
+				"type": "string",
+				"description": "Access time, RFC 3339 or \"now\" (optional)"
+			},
+			"no_create": {
+				"type": "boolean",
+				"description": "Fail instead of creating a missing file (default: false)"
+			}
+		},
+		"required": ["path"]
+	}`)
+}
+
+// touchPlan is what a touch call changes: the times to set, zero for the
+// ones left alone, and whether the file must be created
+type touchPlan struct {
+	mtime, atime time.Time
+	create       bool
+}
+
+func (t *TouchTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var req TouchRequest
+	if err := json.Unmarshal(input, &req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	unlock, err := lockPaths(ctx, req.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	plan, err := planTouch(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if plan.create {
+		f, err := os.OpenFile(req.Path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create file: %w", err)
+		}
+		f.Close()
+	}
+	if err := os.Chtimes(req.Path, plan.atime, plan.mtime); err != nil {
+		return nil, fmt.Errorf("failed to set times: %w", err)
+	}
+
+	resp := TouchResponse{Path: req.Path, Created: plan.create}
+	if stat, err := os.Stat(req.Path); err == nil {
+		resp.Modified = stat.ModTime()
+	}
+	tools.RecordAccess(req.Path)
+	return resp, nil
+}
+
+func (t *TouchTool) DryRun(ctx context.Context, input json.RawMessage) (*tools.Preview, error) {
+	var req TouchRequest
+	if err := json.Unmarshal(input, &req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+	if err := tools.CheckPath(req.Path); err != nil {
+		return nil, err
+	}
+
+	plan, err := planTouch(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if plan.create {
+		return &tools.Preview{
+			Summary: fmt.Sprintf("would create empty file %s", req.Path),
+			Created: []string{req.Path},
+		}, nil
+	}
+	return &tools.Preview{
+		Summary:  fmt.Sprintf("would update the times of %s", req.Path),
+		Modified: []string{req.Path},
+	}, nil
+}
+
+func planTouch(req TouchRequest) (*touchPlan, error) {
+	if req.Path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+	if err := checkInWorkspace(req.Path); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	plan := &touchPlan{}
+	var err error
+	if plan.mtime, err = parseTouchTime("mtime", req.Mtime, now); err != nil {
+		return nil, err
+	}
+	if plan.atime, err = parseTouchTime("atime", req.Atime, now); err != nil {
+		return nil, err
+	}
+	if req.Mtime == "" && req.Atime == "" {
+		plan.mtime, plan.atime = now, now
+	}
+
+	_, err = os.Stat(req.Path)
+	if os.IsNotExist(err) {
+		if req.NoCreate {
+			return nil, fmt.Errorf("file does not exist: %s", req.Path)
+		}
+		if _, err := os.Stat(filepath.Dir(req.Path)); err != nil {
+			return nil, fmt.Errorf("parent directory does not exist: %s", filepath.Dir(req.Path))
+		}
+		plan.create = true
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+	return plan, nil
+}
+
+// parseTouchTime parses an RFC 3339 time or "now"; an empty value is the
+// zero time, which os.Chtimes leaves unchanged
+func parseTouchTime(name, value string, now time.Time) (time.Time, error) {
+	switch value {
+	case "":
+		return time.Time{}, nil
+	case "now":
+		return now, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q: expected an RFC 3339 time or \"now\"", name, value)
+	}
+	return parsed, nil
+}
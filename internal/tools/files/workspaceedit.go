@@ -0,0 +1,328 @@
+package files
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alucardeht/may-la-mcp/internal/lsp"
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+	"github.com/alucardeht/may-la-mcp/internal/types"
+)
+
+// WorkspaceEditOptions say what a WorkspaceEdit was computed against and
+// how to apply it
+type WorkspaceEditOptions struct {
+	// DryRun only describes the change
+	DryRun bool
+	// Backup keeps the previous content of each changed file
+	Backup bool
+	// Hashes maps paths to the SHA-256 of the content the edit was computed
+	// from; a file that no longer has it is not edited
+	Hashes map[string]string
+	// Versions maps document URIs to the version sent to the server; a
+	// text document edit for another version is rejected
+	Versions map[string]int
+}
+
+// ApplyWorkspaceEdit applies an LSP WorkspaceEdit: its text edits and its
+// create, rename and delete operations, on all files or none. It returns
+// the files created, modified and deleted, with one diff of them all.
+func ApplyWorkspaceEdit(ctx context.Context, edit lsp.WorkspaceEdit, opts WorkspaceEditOptions) (*EditSetResult, error) {
+	if !opts.DryRun {
+		targets, err := workspaceEditPaths(edit)
+		if err != nil {
+			return nil, err
+		}
+		unlock, err := lockPaths(ctx, targets...)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+
+	planned, err := planWorkspaceEdit(edit, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.DryRun {
+		return newEditSetResult(planned, false), nil
+	}
+
+	if err := commitEdits(planned, editOptions{backup: opts.Backup}); err != nil {
+		return nil, err
+	}
+	for _, f := range planned {
+		if f.action != "delete" {
+			tools.RecordAccess(f.path)
+		}
+	}
+	return newEditSetResult(planned, true), nil
+}
+
+// workspaceEditPaths returns every path edit touches, checked against the
+// path guard
+func workspaceEditPaths(edit lsp.WorkspaceEdit) ([]string, error) {
+	var uris []string
+	if len(edit.DocumentChanges) > 0 {
+		for _, change := range edit.DocumentChanges {
+			switch {
+			case change.Kind == "rename":
+				uris = append(uris, change.OldURI, change.NewURI)
+			case change.Kind != "":
+				uris = append(uris, change.URI)
+			case change.TextDocument != nil:
+				uris = append(uris, change.TextDocument.URI)
+			}
+		}
+	} else {
+		for uri := range edit.Changes {
+			uris = append(uris, uri)
+		}
+		sort.Strings(uris)
+	}
+
+	paths := make([]string, 0, len(uris))
+	for _, uri := range uris {
+		path := lsp.URIToPath(uri)
+		if path == uri && strings.Contains(uri, "://") {
+			return nil, fmt.Errorf("cannot edit %s: not a file URI", uri)
+		}
+		if err := tools.CheckPath(path); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// planWorkspaceEdit works out the content each path of edit ends up with,
+// applying its changes in order, without writing anything
+func planWorkspaceEdit(edit lsp.WorkspaceEdit, opts WorkspaceEditOptions) ([]*plannedFile, error) {
+	if _, err := workspaceEditPaths(edit); err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]*plannedFile)
+	present := make(map[string]bool)
+	var order []string
+	load := func(uri string) (*plannedFile, error) {
+		path := lsp.URIToPath(uri)
+		if f, ok := files[path]; ok {
+			return f, nil
+		}
+		f, err := loadPlannedFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if hash, ok := opts.Hashes[path]; ok && hash != f.hash {
+			return nil, fmt.Errorf("%s changed since the edit was computed", path)
+		}
+		files[path] = f
+		present[path] = f.existed
+		order = append(order, path)
+		return f, nil
+	}
+	editText := func(uri string, edits []lsp.TextEdit) error {
+		f, err := load(uri)
+		if err != nil {
+			return err
+		}
+		if !present[f.path] {
+			return fmt.Errorf("cannot edit %s: file does not exist", f.path)
+		}
+		f.after, err = applyTextEdits(f.after, edits)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.path, err)
+		}
+		return nil
+	}
+
+	if len(edit.DocumentChanges) == 0 {
+		uris := make([]string, 0, len(edit.Changes))
+		for uri := range edit.Changes {
+			uris = append(uris, uri)
+		}
+		sort.Strings(uris)
+		for _, uri := range uris {
+			if err := editText(uri, edit.Changes[uri]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, change := range edit.DocumentChanges {
+		options := lsp.FileOperationOptions{}
+		if change.Options != nil {
+			options = *change.Options
+		}
+
+		switch change.Kind {
+		case "":
+			if change.TextDocument == nil {
+				return nil, fmt.Errorf("document change without a text document")
+			}
+			if v := change.TextDocument.Version; v != nil {
+				if sent, ok := opts.Versions[change.TextDocument.URI]; ok && sent != *v {
+					return nil, fmt.Errorf("edit of %s is for version %d, not %d", change.TextDocument.URI, *v, sent)
+				}
+			}
+			if err := editText(change.TextDocument.URI, change.Edits); err != nil {
+				return nil, err
+			}
+
+		case "create":
+			f, err := load(change.URI)
+			if err != nil {
+				return nil, err
+			}
+			if present[f.path] {
+				if options.IgnoreIfExists && !options.Overwrite {
+					continue
+				}
+				if !options.Overwrite {
+					return nil, fmt.Errorf("cannot create %s: file exists", f.path)
+				}
+			}
+			f.after = ""
+			present[f.path] = true
+
+		case "rename":
+			from, err := load(change.OldURI)
+			if err != nil {
+				return nil, err
+			}
+			to, err := load(change.NewURI)
+			if err != nil {
+				return nil, err
+			}
+			if !present[from.path] {
+				return nil, fmt.Errorf("cannot rename %s: file does not exist", from.path)
+			}
+			if present[to.path] {
+				if options.IgnoreIfExists && !options.Overwrite {
+					continue
+				}
+				if !options.Overwrite {
+					return nil, fmt.Errorf("cannot rename %s to %s: file exists", from.path, to.path)
+				}
+			}
+			to.after, to.mode = from.after, from.mode
+			present[to.path] = true
+			from.after = ""
+			present[from.path] = false
+
+		case "delete":
+			f, err := load(change.URI)
+			if err != nil {
+				return nil, err
+			}
+			if !present[f.path] {
+				if options.IgnoreIfNotExists {
+					continue
+				}
+				return nil, fmt.Errorf("cannot delete %s: file does not exist", f.path)
+			}
+			f.after = ""
+			present[f.path] = false
+
+		default:
+			return nil, fmt.Errorf("unknown document change kind %q", change.Kind)
+		}
+	}
+
+	var planned []*plannedFile
+	for _, path := range order {
+		f := files[path]
+		switch {
+		case f.existed && !present[path]:
+			f.action = "delete"
+		case !f.existed && present[path]:
+			f.action = "create"
+		case f.existed && f.after != f.before:
+			f.action = "modify"
+		default:
+			continue
+		}
+		planned = append(planned, f)
+	}
+	return planned, nil
+}
+
+// applyTextEdits applies LSP text edits to text. The edits address the
+// text before any of them; edits at the same position are applied in
+// order, and overlapping edits are an error.
+func applyTextEdits(text string, edits []lsp.TextEdit) (string, error) {
+	type span struct {
+		start, end int
+		newText    string
+	}
+	spans := make([]span, len(edits))
+	for i, edit := range edits {
+		start, end := textOffset(text, edit.Range.Start), textOffset(text, edit.Range.End)
+		if end < start {
+			return "", fmt.Errorf("edit %d ends before it starts", i)
+		}
+		spans[i] = span{start, end, edit.NewText}
+	}
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	var b strings.Builder
+	last := 0
+	for _, s := range spans {
+		if s.start < last {
+			return "", fmt.Errorf("overlapping edits at offset %d", s.start)
+		}
+		b.WriteString(text[last:s.start])
+		b.WriteString(s.newText)
+		last = s.end
+	}
+	b.WriteString(text[last:])
+	return b.String(), nil
+}
+
+// textOffset returns the byte offset of an LSP position in text. Positions
+// past the end of their line or of the text are clamped to it.
+func textOffset(text string, pos lsp.Position) int {
+	start := 0
+	for line := 0; line < pos.Line; line++ {
+		i := strings.IndexByte(text[start:], '\n')
+		if i < 0 {
+			return len(text)
+		}
+		start += i + 1
+	}
+	end := len(text)
+	if i := strings.IndexByte(text[start:], '\n'); i >= 0 {
+		end = start + i
+	}
+	line := strings.TrimSuffix(text[start:end], "\r")
+	return start + types.ByteFromColumn(line, types.ColumnFromUTF16(line, pos.Character))
+}
+
+// lspPosition converts a 1-based line and character column of text into
+// an LSP position
+func lspPosition(text string, line, column int) (lsp.Position, error) {
+	lines := types.SplitLines(text)
+	if line < 1 || line > len(lines) {
+		return lsp.Position{}, fmt.Errorf("line %d is outside the file (1-%d)", line, len(lines))
+	}
+	lineText := lines[line-1]
+	chars := utf8.RuneCountInString(lineText)
+	if column < 1 || column > chars+1 {
+		return lsp.Position{}, fmt.Errorf("column %d is outside line %d (1-%d)", column, line, chars+1)
+	}
+	return lsp.Position{Line: line - 1, Character: types.UTF16FromColumn(lineText, column)}, nil
+}
+
+// readText reads a file as the text sent to a language server
+func readText(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	return string(content), nil
+}
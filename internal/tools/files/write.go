@@ -17,6 +17,8 @@ type WriteRequest struct {
 	Content   string `json:"content"`
 	CreateDirs bool  `json:"createDirs,omitempty"`
 	Backup    bool   `json:"backup,omitempty"`
+	LineEndings string `json:"lineEndings,omitempty"`
+	BOM       string `json:"bom,omitempty"`
 }
 
 type WriteResponse struct {
@@ -33,7 +35,7 @@ func (t *WriteTool) Name() string {
 }
 
 func (t *WriteTool) Description() string {
-	return "Write file contents with atomic operations and optional backup"
+	return "Write file contents with atomic operations and optional backup. Line endings and the UTF-8 BOM follow the existing file unless lineEndings/bom say otherwise"
 }
 
 func (t *WriteTool) Schema() json.RawMessage {
@@ -55,6 +57,16 @@ func (t *WriteTool) Schema() json.RawMessage {
 			"backup": {
 				"type": "boolean",
 				"description": "Create backup .bak file before overwriting (default: false)"
+			},
+			"lineEndings": {
+				"type": "string",
+				"enum": ["lf", "crlf", "preserve"],
+				"description": "Line endings to write; preserve keeps those of the existing file, or the content's own for a new file (default: preserve)"
+			},
+			"bom": {
+				"type": "string",
+				"enum": ["add", "strip", "preserve"],
+				"description": "UTF-8 byte order mark; preserve keeps the existing file's, or the content's own for a new file (default: preserve)"
 			}
 		},
 		"required": ["path", "content"]
@@ -74,6 +86,20 @@ func (t *WriteTool) Execute(ctx context.Context, input json.RawMessage) (interfa
 	if req.Path == "" {
 		return nil, fmt.Errorf("path is required")
 	}
+	if err := tools.CheckPath(req.Path); err != nil {
+		return nil, err
+	}
+
+	policy, err := newFormatPolicy(req.LineEndings, req.BOM)
+	if err != nil {
+		return nil, err
+	}
+
+	unlock, err := lockPaths(ctx, req.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
 
 	dir := filepath.Dir(req.Path)
 	if dir != "" && dir != "." {
@@ -83,10 +109,18 @@ func (t *WriteTool) Execute(ctx context.Context, input json.RawMessage) (interfa
 	}
 
 	var backupPath string
+	var existing *textFormat
 	fileExists := false
 	if stat, err := os.Stat(req.Path); err == nil && !stat.IsDir() {
 		fileExists = true
 
+		before, err := os.ReadFile(req.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read existing file: %w", err)
+		}
+		format := detectFormat(string(before))
+		existing = &format
+
 		if req.Backup {
 			backupPath = req.Path + ".bak." + strconv.FormatInt(time.Now().UnixNano(), 10)
 			if err := os.Rename(req.Path, backupPath); err != nil {
@@ -95,8 +129,9 @@ func (t *WriteTool) Execute(ctx context.Context, input json.RawMessage) (interfa
 		}
 	}
 
-	tempPath := req.Path + ".tmp." + strconv.FormatInt(time.Now().UnixNano(), 10)
-	if err := os.WriteFile(tempPath, []byte(req.Content), 0644); err != nil {
+	content := policy.apply(req.Content, existing)
+	tempPath, err := writeTemp(req.Path, []byte(content), 0644)
+	if err != nil {
 		if backupPath != "" {
 			os.Rename(backupPath, req.Path)
 		}
@@ -124,6 +159,54 @@ func (t *WriteTool) Execute(ctx context.Context, input json.RawMessage) (interfa
 	}, nil
 }
 
+func (t *WriteTool) DryRun(ctx context.Context, input json.RawMessage) (*tools.Preview, error) {
+	var req WriteRequest
+	if err := json.Unmarshal(input, &req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	if req.Path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+	if err := tools.CheckPath(req.Path); err != nil {
+		return nil, err
+	}
+
+	policy, err := newFormatPolicy(req.LineEndings, req.BOM)
+	if err != nil {
+		return nil, err
+	}
+
+	before, exists, err := tools.ReadExisting(req.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var existing *textFormat
+	if exists {
+		format := detectFormat(before)
+		existing = &format
+	}
+	content := policy.apply(req.Content, existing)
+
+	preview := &tools.Preview{
+		Created: tools.MissingDirs(filepath.Dir(req.Path)),
+		Diffs:   []tools.FileDiff{tools.DiffFile(req.Path, before, content, !exists)},
+	}
+	if !exists {
+		preview.Created = append(preview.Created, req.Path)
+		preview.Summary = fmt.Sprintf("would create %s (%d bytes)", req.Path, len(content))
+		return preview, nil
+	}
+
+	preview.Modified = []string{req.Path}
+	preview.Summary = fmt.Sprintf("would overwrite %s (%d -> %d bytes)", req.Path, len(before), len(content))
+	if req.Backup {
+		preview.Created = append(preview.Created, req.Path+".bak.<timestamp>")
+	}
+	return preview, nil
+}
+
 func (t *WriteTool) Title() string {
 	return "Write File"
 }
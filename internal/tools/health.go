@@ -4,12 +4,39 @@ import (
 	"context"
 	"encoding/json"
 	"os"
+	"time"
 )
 
-type HealthTool struct{}
+type ComponentStatus string
 
-func NewHealthTool() *HealthTool {
-	return &HealthTool{}
+const (
+	StatusOK       ComponentStatus = "ok"
+	StatusDegraded ComponentStatus = "degraded"
+	StatusDown     ComponentStatus = "down"
+)
+
+type ComponentHealth struct {
+	Status ComponentStatus `json:"status"`
+	Reason string          `json:"reason,omitempty"`
+	// Details carries component-specific state, such as the last run of
+	// each scheduled job
+	Details interface{} `json:"details,omitempty"`
+}
+
+// HealthProbe checks a single daemon component
+type HealthProbe struct {
+	Name  string
+	Check func(ctx context.Context) ComponentHealth
+}
+
+const healthProbeTimeout = 2 * time.Second
+
+type HealthTool struct {
+	probes []HealthProbe
+}
+
+func NewHealthTool(probes ...HealthProbe) *HealthTool {
+	return &HealthTool{probes: probes}
 }
 
 func (t *HealthTool) Name() string {
@@ -17,7 +44,7 @@ func (t *HealthTool) Name() string {
 }
 
 func (t *HealthTool) Description() string {
-	return "Check daemon health status"
+	return "Check daemon health status with per-component probes (index, watcher, LSP, disk, scheduled jobs)"
 }
 
 func (t *HealthTool) Title() string {
@@ -41,10 +68,62 @@ func (t *HealthTool) Execute(ctx context.Context, input json.RawMessage) (interf
 		return nil, ctx.Err()
 	}
 
+	components := make(map[string]ComponentHealth, len(t.probes))
+	overall := StatusOK
+	for _, probe := range t.probes {
+		result := runProbe(ctx, probe)
+		components[probe.Name] = result
+		if severity(result.Status) > severity(overall) {
+			overall = result.Status
+		}
+	}
+
+	status := "healthy"
+	switch overall {
+	case StatusDegraded:
+		status = "degraded"
+	case StatusDown:
+		status = "unhealthy"
+	}
+
 	cwd, _ := os.Getwd()
 	return map[string]interface{}{
-		"status":    "healthy",
-		"tools":     "loaded",
-		"workspace": cwd,
+		"status":     status,
+		"tools":      "loaded",
+		"workspace":  cwd,
+		"components": components,
 	}, nil
 }
+
+func runProbe(ctx context.Context, probe HealthProbe) ComponentHealth {
+	probeCtx, cancel := context.WithTimeout(ctx, healthProbeTimeout)
+	defer cancel()
+
+	done := make(chan ComponentHealth, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- ComponentHealth{Status: StatusDown, Reason: "probe panicked"}
+			}
+		}()
+		done <- probe.Check(probeCtx)
+	}()
+
+	select {
+	case result := <-done:
+		return result
+	case <-probeCtx.Done():
+		return ComponentHealth{Status: StatusDown, Reason: "probe timed out"}
+	}
+}
+
+func severity(s ComponentStatus) int {
+	switch s {
+	case StatusDown:
+		return 2
+	case StatusDegraded:
+		return 1
+	default:
+		return 0
+	}
+}
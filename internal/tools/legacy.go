@@ -0,0 +1,72 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"runtime/debug"
+)
+
+// LegacyTool is a tool written before Execute took a context
+type LegacyTool interface {
+	Name() string
+	Description() string
+	Schema() json.RawMessage
+	Execute(input json.RawMessage) (interface{}, error)
+}
+
+// Metadata is the title and annotations an adapted legacy tool is listed
+// with. Without annotations it is treated as mutating, like any tool.
+type Metadata struct {
+	Title       string
+	Annotations map[string]bool
+}
+
+type legacyAdapter struct {
+	LegacyTool
+	meta Metadata
+}
+
+// Adapt turns a legacy tool into a Tool. A legacy tool cannot be
+// interrupted, so the adapted Execute returns as soon as ctx is done and
+// leaves the call to finish in the background.
+func Adapt(legacy LegacyTool, meta Metadata) AnnotatedTool {
+	return &legacyAdapter{LegacyTool: legacy, meta: meta}
+}
+
+func (a *legacyAdapter) Title() string {
+	return a.meta.Title
+}
+
+func (a *legacyAdapter) Annotations() map[string]bool {
+	return a.meta.Annotations
+}
+
+func (a *legacyAdapter) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	type result struct {
+		value interface{}
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				component := "tool " + a.Name()
+				crashID := ReportPanic(component, p, debug.Stack())
+				done <- result{nil, NewPanicError(component, p, crashID)}
+			}
+		}()
+		value, err := a.LegacyTool.Execute(input)
+		done <- result{value, err}
+	}()
+
+	select {
+	case res := <-done:
+		return res.value, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,116 @@
+package memory
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// branchTagPrefix marks the tag that ties a memory to a git branch, so
+// context written on a feature branch can be filtered or dropped after merge
+const branchTagPrefix = "branch:"
+
+// BranchTag is the tag of memories tied to branch
+func BranchTag(branch string) string {
+	return branchTagPrefix + branch
+}
+
+// branchOf returns the branch a memory's tags tie it to, if any
+func branchOf(tags []string) string {
+	for _, tag := range tags {
+		if branch, ok := strings.CutPrefix(tag, branchTagPrefix); ok {
+			return branch
+		}
+	}
+	return ""
+}
+
+// withBranch ties tags to branch, replacing any branch they had
+func withBranch(tags []string, branch string) []string {
+	result := make([]string, 0, len(tags)+1)
+	for _, tag := range tags {
+		if !strings.HasPrefix(tag, branchTagPrefix) {
+			result = append(result, tag)
+		}
+	}
+	return append(result, BranchTag(branch))
+}
+
+// branchTagsOf parses the tags column into the branch they name
+func branchTagsOf(tagsJSON sql.NullString) string {
+	if !tagsJSON.Valid {
+		return ""
+	}
+	var tags []string
+	if err := json.Unmarshal([]byte(tagsJSON.String), &tags); err != nil {
+		return ""
+	}
+	return branchOf(tags)
+}
+
+// branchFilter is the condition on the tags column matching memories tied
+// to branch, with its argument. Tags are stored as a JSON array, so the
+// quoted tag is looked for.
+func branchFilter(column, branch string) (string, string) {
+	quoted, _ := json.Marshal(BranchTag(branch))
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(string(quoted))
+	return fmt.Sprintf(` AND %s LIKE ? ESCAPE '\'`, column), "%" + escaped + "%"
+}
+
+// DeleteBranch deletes every memory tied to branch and returns their
+// memory:// paths
+func (s *MemoryStore) DeleteBranch(branch string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	names, err := deleteBranch(tx, branch)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+func deleteBranch(tx *sql.Tx, branch string) ([]string, error) {
+	if branch == "" {
+		return nil, fmt.Errorf("branch is required")
+	}
+
+	condition, arg := branchFilter("tags", branch)
+	rows, err := tx.Query("SELECT name, category FROM memories WHERE deleted_at IS NULL"+condition+" ORDER BY name", arg)
+	if err != nil {
+		return nil, err
+	}
+	var names, deleted []string
+	for rows.Next() {
+		var name string
+		var category Category
+		if err := rows.Scan(&name, &category); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		names = append(names, name)
+		deleted = append(deleted, memoryPath(category, name))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+		if err := deleteMemory(tx, name); err != nil {
+			return nil, err
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,405 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+// builtinCategories are seeded into every store. Apart from general, which
+// is the default and the fallback for deleted categories, they can be
+// renamed or deleted like user-defined ones.
+var builtinCategories = []CategoryInfo{
+	{Name: CategoryArchitecture, Description: "System design patterns"},
+	{Name: CategoryConventions, Description: "Coding standards, naming patterns"},
+	{Name: CategoryDecisions, Description: "Why choices were made"},
+	{Name: CategoryContext, Description: "Background information"},
+	{Name: CategoryGeneral, Description: "General observations and notes"},
+}
+
+var categoryNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+type CategoryInfo struct {
+	Name        Category  `json:"name"`
+	Description string    `json:"description"`
+	Builtin     bool      `json:"builtin"`
+	Memories    int       `json:"memories"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// MemoryCategoriesResponse holds the fields of the memory_categories action
+// that ran: total and categories for list, category for create, from and
+// to for rename, deleted and reassign_to for delete, with the memories
+// moved by the last two
+type MemoryCategoriesResponse struct {
+	Success    bool            `json:"success,omitempty"`
+	Total      int             `json:"total,omitempty"`
+	Categories []*CategoryInfo `json:"categories,omitempty"`
+	Category   *CategoryInfo   `json:"category,omitempty"`
+	From       string          `json:"from,omitempty"`
+	To         string          `json:"to,omitempty"`
+	Deleted    string          `json:"deleted,omitempty"`
+	ReassignTo Category        `json:"reassign_to,omitempty"`
+	Memories   *int64          `json:"memories,omitempty"`
+}
+
+// seedCategories installs the built-in categories and adopts any category
+// already used by stored memories, so databases written before categories
+// were managed keep validating.
+func (s *MemoryStore) seedCategories() error {
+	for _, c := range builtinCategories {
+		if _, err := s.db.Exec(
+			"INSERT OR IGNORE INTO categories (name, description, builtin) VALUES (?, ?, 1)",
+			c.Name, c.Description,
+		); err != nil {
+			return err
+		}
+	}
+
+	_, err := s.db.Exec(
+		"INSERT OR IGNORE INTO categories (name) SELECT DISTINCT category FROM memories WHERE category IS NOT NULL AND category != ''",
+	)
+	return err
+}
+
+func requireCategory(tx *sql.Tx, category Category) error {
+	var exists bool
+	if err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM categories WHERE name = ?)", category).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("unknown category '%s'; create it with memory_categories first", category)
+	}
+	return nil
+}
+
+func validCategoryName(name Category) error {
+	if !categoryNamePattern.MatchString(string(name)) {
+		return fmt.Errorf("invalid category name '%s': use lowercase letters, digits, '-' or '_' (max 64)", name)
+	}
+	return nil
+}
+
+func (s *MemoryStore) ListCategories() ([]*CategoryInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query(`
+		SELECT c.name, c.description, c.builtin, c.created_at,
+			(SELECT COUNT(*) FROM memories m WHERE m.category = c.name AND m.deleted_at IS NULL)
+		FROM categories c
+		ORDER BY c.builtin DESC, c.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []*CategoryInfo
+	for rows.Next() {
+		c := &CategoryInfo{}
+		var description sql.NullString
+		if err := rows.Scan(&c.Name, &description, &c.Builtin, &c.CreatedAt, &c.Memories); err != nil {
+			return nil, err
+		}
+		c.Description = description.String
+		categories = append(categories, c)
+	}
+	return categories, rows.Err()
+}
+
+func (s *MemoryStore) CreateCategory(name Category, description string) (*CategoryInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	category, err := createCategory(tx, name, description)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return category, nil
+}
+
+func createCategory(tx *sql.Tx, name Category, description string) (*CategoryInfo, error) {
+	if err := validCategoryName(name); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	result, err := tx.Exec(
+		"INSERT OR IGNORE INTO categories (name, description, builtin, created_at) VALUES (?, ?, 0, ?)",
+		name, description, now,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, fmt.Errorf("category '%s' already exists", name)
+	}
+
+	return &CategoryInfo{Name: name, Description: description, CreatedAt: now}, nil
+}
+
+// RenameCategory renames a category and moves every memory in it, including
+// soft-deleted ones, to the new name. It returns the number of memories moved.
+func (s *MemoryStore) RenameCategory(from, to Category) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	moved, err := renameCategory(tx, from, to)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return moved, nil
+}
+
+func renameCategory(tx *sql.Tx, from, to Category) (int64, error) {
+	if from == CategoryGeneral {
+		return 0, fmt.Errorf("category '%s' is the default and cannot be renamed", from)
+	}
+	if err := validCategoryName(to); err != nil {
+		return 0, err
+	}
+
+	if err := requireCategory(tx, from); err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(
+		"INSERT OR IGNORE INTO categories (name, description, builtin, created_at) SELECT ?, description, builtin, created_at FROM categories WHERE name = ?",
+		to, from,
+	)
+	if err != nil {
+		return 0, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return 0, fmt.Errorf("category '%s' already exists", to)
+	}
+
+	moved, err := tx.Exec("UPDATE memories SET category = ? WHERE category = ?", to, from)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec("DELETE FROM categories WHERE name = ?", from); err != nil {
+		return 0, err
+	}
+
+	count, _ := moved.RowsAffected()
+	return count, nil
+}
+
+// DeleteCategory removes a category, moving its memories to reassignTo
+func (s *MemoryStore) DeleteCategory(name, reassignTo Category) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	moved, err := deleteCategory(tx, name, reassignTo)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return moved, nil
+}
+
+func deleteCategory(tx *sql.Tx, name, reassignTo Category) (int64, error) {
+	if name == CategoryGeneral {
+		return 0, fmt.Errorf("category '%s' is the default and cannot be deleted", name)
+	}
+	if name == reassignTo {
+		return 0, fmt.Errorf("cannot reassign memories of '%s' to itself", name)
+	}
+
+	if err := requireCategory(tx, name); err != nil {
+		return 0, err
+	}
+	if err := requireCategory(tx, reassignTo); err != nil {
+		return 0, err
+	}
+
+	moved, err := tx.Exec("UPDATE memories SET category = ? WHERE category = ?", reassignTo, name)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec("DELETE FROM categories WHERE name = ?", name); err != nil {
+		return 0, err
+	}
+
+	count, _ := moved.RowsAffected()
+	return count, nil
+}
+
+type MemoryCategoriesTool struct {
+	store MemoryRepo
+}
+
+func NewMemoryCategoriesTool(store MemoryRepo) *MemoryCategoriesTool {
+	return &MemoryCategoriesTool{store: store}
+}
+
+func (t *MemoryCategoriesTool) Name() string {
+	return "memory_categories"
+}
+
+func (t *MemoryCategoriesTool) Description() string {
+	return `Manage memory categories.
+
+ACTIONS:
+- list: all categories with descriptions and memory counts
+- create: add a category (name, optional description)
+- rename: rename a category (name → new_name); its memories move with it
+- delete: remove a category; its memories move to reassign_to (default: general)
+
+Built-in categories: architecture, conventions, decisions, context, general. The general category cannot be renamed or deleted.`
+}
+
+func (t *MemoryCategoriesTool) Title() string {
+	return "Manage Memory Categories"
+}
+
+func (t *MemoryCategoriesTool) Annotations() map[string]bool {
+	return tools.DestructiveAnnotations()
+}
+
+func (t *MemoryCategoriesTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"action": {
+				"type": "string",
+				"enum": ["list", "create", "rename", "delete"],
+				"description": "Operation to perform"
+			},
+			"name": {
+				"type": "string",
+				"description": "Category to create, rename or delete"
+			},
+			"new_name": {
+				"type": "string",
+				"description": "New name for rename"
+			},
+			"description": {
+				"type": "string",
+				"description": "Description for create"
+			},
+			"reassign_to": {
+				"type": "string",
+				"description": "Category receiving the memories of a deleted category (default: general)"
+			}
+		},
+		"required": ["action"]
+	}`)
+}
+
+type categoriesRequest struct {
+	Action      string `json:"action"`
+	Name        string `json:"name"`
+	NewName     string `json:"new_name"`
+	Description string `json:"description"`
+	ReassignTo  string `json:"reassign_to"`
+}
+
+func parseCategoriesRequest(input json.RawMessage) (categoriesRequest, error) {
+	var req categoriesRequest
+	if err := json.Unmarshal(input, &req); err != nil {
+		return req, err
+	}
+
+	if req.Action != "list" && req.Name == "" {
+		return req, fmt.Errorf("category name is required for %s", req.Action)
+	}
+	return req, nil
+}
+
+func (req categoriesRequest) reassignTo() Category {
+	if req.ReassignTo != "" {
+		return Category(req.ReassignTo)
+	}
+	return CategoryGeneral
+}
+
+func (t *MemoryCategoriesTool) OutputSchema() json.RawMessage {
+	return tools.OutputSchemaOf(MemoryCategoriesResponse{})
+}
+
+func (t *MemoryCategoriesTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	req, err := parseCategoriesRequest(input)
+	if err != nil {
+		return nil, err
+	}
+
+	switch req.Action {
+	case "list":
+		categories, err := t.store.ListCategories()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list categories: %w", err)
+		}
+		return &MemoryCategoriesResponse{Total: len(categories), Categories: categories}, nil
+
+	case "create":
+		category, err := t.store.CreateCategory(Category(req.Name), req.Description)
+		if err != nil {
+			return nil, err
+		}
+		return &MemoryCategoriesResponse{Success: true, Category: category}, nil
+
+	case "rename":
+		if req.NewName == "" {
+			return nil, fmt.Errorf("new_name is required for rename")
+		}
+		moved, err := t.store.RenameCategory(Category(req.Name), Category(req.NewName))
+		if err != nil {
+			return nil, err
+		}
+		return &MemoryCategoriesResponse{Success: true, From: req.Name, To: req.NewName, Memories: &moved}, nil
+
+	case "delete":
+		reassignTo := req.reassignTo()
+		moved, err := t.store.DeleteCategory(Category(req.Name), reassignTo)
+		if err != nil {
+			return nil, err
+		}
+		return &MemoryCategoriesResponse{Success: true, Deleted: req.Name, ReassignTo: reassignTo, Memories: &moved}, nil
+
+	default:
+		return nil, fmt.Errorf("invalid action %q: expected list, create, rename or delete", req.Action)
+	}
+}
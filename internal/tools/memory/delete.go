@@ -1 +1,9 @@
 package memory
+
+type MemoryDeleteResponse struct {
+	Success    bool   `json:"success"`
+	Identifier string `json:"identifier"`
+	DeletedAt  string `json:"deleted_at"`
+	// Deleted lists the memories deleted with branch
+	Deleted []string `json:"deleted,omitempty"`
+}
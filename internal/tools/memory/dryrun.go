@@ -0,0 +1,277 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+// rehearse runs fn inside a transaction that is always rolled back, so a dry
+// run goes through the same validation and statements as the real write.
+func (s *MemoryStore) rehearse(fn func(tx *sql.Tx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	return fn(tx)
+}
+
+func (t *MemoryWriteTool) DryRun(ctx context.Context, input json.RawMessage) (*tools.Preview, error) {
+	entry, err := parseMemoryWrite(input)
+	if err != nil {
+		return nil, err
+	}
+
+	err = rehearse(t.store, func(tx *sql.Tx) error {
+		_, err := insertMemory(tx, entry)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	path := memoryPath(entry.Category, entry.Name)
+	return &tools.Preview{
+		Summary: fmt.Sprintf("would create memory %s", path),
+		Created: []string{path},
+		Diffs:   []tools.FileDiff{tools.DiffFile(path, "", entry.Content, true)},
+	}, nil
+}
+
+func (t *MemoryWriteBatchTool) DryRun(ctx context.Context, input json.RawMessage) (*tools.Preview, error) {
+	plan, err := planBatch(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	err = rehearse(t.store, func(tx *sql.Tx) error {
+		var err error
+		_, errs, err = createBatch(tx, plan.entries, plan.atomic)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &tools.Preview{}
+	results := plan.results
+	for j, i := range plan.positions {
+		entry := plan.entries[j]
+		if errs[j] != nil {
+			results[i] = MemoryBatchResult{Name: entry.Name, Error: errs[j].Error()}
+			continue
+		}
+		path := memoryPath(entry.Category, entry.Name)
+		preview.Created = append(preview.Created, path)
+		preview.Diffs = append(preview.Diffs, tools.DiffFile(path, "", entry.Content, true))
+		results[i] = MemoryBatchResult{Success: true, Name: entry.Name, Path: path}
+	}
+
+	preview.Summary = fmt.Sprintf("would write %d of %d memories (%s)", len(preview.Created), len(results), plan.mode)
+	preview.Details = map[string]interface{}{"results": results}
+	return preview, nil
+}
+
+func (t *MemoryUpdateTool) DryRun(ctx context.Context, input json.RawMessage) (*tools.Preview, error) {
+	req, err := parseMemoryUpdate(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var existing, updated *Memory
+	err = rehearse(t.store, func(tx *sql.Tx) error {
+		var err error
+		if existing, err = scanMemory(tx.QueryRow(selectMemory, req.Name, req.Name)); err != nil {
+			return fmt.Errorf("memory not found: %w", err)
+		}
+		content, category, tags := req.merge(existing)
+		if updated, err = updateMemory(tx, existing.ID, content, category, tags); err != nil {
+			return fmt.Errorf("failed to update memory: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	before := memoryPath(existing.Category, existing.Name)
+	after := memoryPath(updated.Category, updated.Name)
+	preview := &tools.Preview{
+		Summary:  fmt.Sprintf("would update memory %s", before),
+		Modified: []string{before},
+		Details: map[string]interface{}{
+			"category": map[string]interface{}{"from": existing.Category, "to": updated.Category},
+			"tags":     map[string]interface{}{"from": existing.Tags, "to": updated.Tags},
+		},
+	}
+	if before != after {
+		preview.Moved = []tools.PathMove{{From: before, To: after}}
+	}
+	if existing.Content != updated.Content {
+		preview.Diffs = []tools.FileDiff{tools.DiffFile(after, existing.Content, updated.Content, false)}
+	}
+	return preview, nil
+}
+
+func (t *MemoryDeleteTool) DryRun(ctx context.Context, input json.RawMessage) (*tools.Preview, error) {
+	req, err := parseMemoryDelete(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Branch != "" {
+		var deleted []string
+		err := rehearse(t.store, func(tx *sql.Tx) error {
+			var err error
+			deleted, err = deleteBranch(tx, req.Branch)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete memories: %w", err)
+		}
+		return &tools.Preview{
+			Summary: fmt.Sprintf("would delete %d memories of branch %s", len(deleted), req.Branch),
+			Deleted: deleted,
+		}, nil
+	}
+
+	var existing *Memory
+	err = rehearse(t.store, func(tx *sql.Tx) error {
+		var err error
+		existing, err = scanMemory(tx.QueryRow(selectMemory, req.Name, req.Name))
+		if err != nil {
+			return fmt.Errorf("memory not found")
+		}
+		return deleteMemory(tx, req.Name)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete memory: %w", err)
+	}
+
+	path := memoryPath(existing.Category, existing.Name)
+	return &tools.Preview{
+		Summary: fmt.Sprintf("would delete memory %s", path),
+		Deleted: []string{path},
+	}, nil
+}
+
+func (t *MemoryCategoriesTool) DryRun(ctx context.Context, input json.RawMessage) (*tools.Preview, error) {
+	req, err := parseCategoriesRequest(input)
+	if err != nil {
+		return nil, err
+	}
+
+	categoryPath := func(name string) string { return "memory://" + name }
+
+	switch req.Action {
+	case "list":
+		listing, err := t.Execute(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		return &tools.Preview{Summary: "list makes no changes", Details: listing}, nil
+
+	case "create":
+		err := rehearse(t.store, func(tx *sql.Tx) error {
+			_, err := createCategory(tx, Category(req.Name), req.Description)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &tools.Preview{
+			Summary: fmt.Sprintf("would create category %s", req.Name),
+			Created: []string{categoryPath(req.Name)},
+		}, nil
+
+	case "rename":
+		if req.NewName == "" {
+			return nil, fmt.Errorf("new_name is required for rename")
+		}
+		var moved int64
+		err := rehearse(t.store, func(tx *sql.Tx) error {
+			var err error
+			moved, err = renameCategory(tx, Category(req.Name), Category(req.NewName))
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &tools.Preview{
+			Summary: fmt.Sprintf("would rename category %s to %s, moving %d memories", req.Name, req.NewName, moved),
+			Moved:   []tools.PathMove{{From: categoryPath(req.Name), To: categoryPath(req.NewName)}},
+			Details: map[string]interface{}{"memories": moved},
+		}, nil
+
+	case "delete":
+		reassignTo := req.reassignTo()
+		var moved int64
+		err := rehearse(t.store, func(tx *sql.Tx) error {
+			var err error
+			moved, err = deleteCategory(tx, Category(req.Name), reassignTo)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &tools.Preview{
+			Summary: fmt.Sprintf("would delete category %s, moving %d memories to %s", req.Name, moved, reassignTo),
+			Deleted: []string{categoryPath(req.Name)},
+			Details: map[string]interface{}{"reassign_to": reassignTo, "memories": moved},
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("invalid action %q: expected list, create, rename or delete", req.Action)
+	}
+}
+
+func (t *MemorySuggestionsTool) DryRun(ctx context.Context, input json.RawMessage) (*tools.Preview, error) {
+	req, err := parseSuggestionsRequest(input)
+	if err != nil {
+		return nil, err
+	}
+
+	switch req.Action {
+	case "accept":
+		suggestion, ok := t.suggester.Get(req.ID)
+		if !ok {
+			return nil, fmt.Errorf("suggestion not found: %s", req.ID)
+		}
+		entry := req.entry(suggestion)
+		err := rehearse(t.store, func(tx *sql.Tx) error {
+			_, err := insertMemory(tx, entry)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		path := memoryPath(entry.Category, entry.Name)
+		return &tools.Preview{
+			Summary: fmt.Sprintf("would accept suggestion %s as memory %s", req.ID, path),
+			Created: []string{path},
+			Diffs:   []tools.FileDiff{tools.DiffFile(path, "", entry.Content, true)},
+		}, nil
+
+	case "reject":
+		if _, ok := t.suggester.Get(req.ID); !ok {
+			return nil, fmt.Errorf("suggestion not found: %s", req.ID)
+		}
+		return &tools.Preview{Summary: fmt.Sprintf("would reject suggestion %s", req.ID)}, nil
+
+	default:
+		listing, err := t.Execute(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		return &tools.Preview{Summary: "list makes no changes", Details: listing}, nil
+	}
+}
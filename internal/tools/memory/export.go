@@ -0,0 +1,92 @@
+package memory
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+)
+
+// Export returns the full memories of the given categories, or of every
+// category when none is given, for syncing them elsewhere
+func (s *MemoryStore) Export(categories []Category) ([]*Memory, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query := "SELECT id, name, content, category, tags, created_at, updated_at, accessed_at, access_count, deleted_at FROM memories WHERE deleted_at IS NULL"
+	var args []interface{}
+	if len(categories) > 0 {
+		query += " AND category IN (?" + strings.Repeat(", ?", len(categories)-1) + ")"
+		for _, c := range categories {
+			args = append(args, c)
+		}
+	}
+	query += " ORDER BY name"
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var memories []*Memory
+	for rows.Next() {
+		memory := &Memory{}
+		var tagsJSON sql.NullString
+		err := rows.Scan(
+			&memory.ID, &memory.Name, &memory.Content, &memory.Category, &tagsJSON,
+			&memory.CreatedAt, &memory.UpdatedAt, &memory.AccessedAt, &memory.AccessCount, &memory.DeletedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		memory.Tags = []string{}
+		if tagsJSON.Valid {
+			json.Unmarshal([]byte(tagsJSON.String), &memory.Tags)
+		}
+		memories = append(memories, memory)
+	}
+	return memories, rows.Err()
+}
+
+// Import stores a memory synced from elsewhere under its name, replacing
+// the local one and keeping its ID and timestamps so both sides agree on
+// which version is newer. Its category is created when missing.
+func (s *MemoryStore) Import(memory *Memory) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tagsJSON, err := json.Marshal(memory.Tags)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("INSERT OR IGNORE INTO categories (name) VALUES (?)", memory.Category); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM memories_fts WHERE name = ?", memory.Name); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM memories WHERE name = ? OR id = ?", memory.Name, memory.ID); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		"INSERT INTO memories (id, name, content, category, tags, created_at, updated_at, accessed_at, access_count) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		memory.ID, memory.Name, memory.Content, memory.Category, string(tagsJSON),
+		memory.CreatedAt, memory.UpdatedAt, memory.UpdatedAt, 0,
+	)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("INSERT INTO memories_fts (name, content) VALUES (?, ?)", memory.Name, memory.Content); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
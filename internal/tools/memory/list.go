@@ -1 +1,18 @@
 package memory
+
+// MemorySummary is a memory as memory_list shows it, without its content
+type MemorySummary struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Category    Category `json:"category"`
+	Preview     string   `json:"preview"`
+	CreatedAt   string   `json:"created_at"`
+	AccessedAt  string   `json:"accessed_at"`
+	AccessCount int      `json:"access_count"`
+	Branch      string   `json:"branch,omitempty"`
+}
+
+type MemoryListResponse struct {
+	Total    int             `json:"total"`
+	Memories []MemorySummary `json:"memories"`
+}
@@ -1 +1,14 @@
 package memory
+
+// MemoryReadResponse is a memory with its timestamps in RFC 3339
+type MemoryReadResponse struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Content     string   `json:"content"`
+	Category    Category `json:"category"`
+	Tags        []string `json:"tags"`
+	CreatedAt   string   `json:"created_at"`
+	UpdatedAt   string   `json:"updated_at"`
+	AccessedAt  string   `json:"accessed_at"`
+	AccessCount int      `json:"access_count"`
+}
@@ -0,0 +1,78 @@
+package memory
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/dbcrypt"
+)
+
+// BackendSQLite is the default storage backend, an embedded SQLite file
+const BackendSQLite = "sqlite"
+
+// MemoryRepo is a memory storage backend. MemoryStore, on SQLite, is the
+// default; other backends implement the same interface.
+type MemoryRepo interface {
+	Create(id, name, content string, category Category, tags []string) (*Memory, error)
+	CreateBatch(entries []NewMemory, atomic bool) (created []*Memory, errs []error, err error)
+	Read(identifier string) (*Memory, error)
+	Update(id, content string, tags []string) (*Memory, error)
+	UpdateFull(id, content string, category Category, tags []string) (*Memory, error)
+	Delete(identifier string) (string, *time.Time, error)
+	// DeleteBranch deletes the memories tied to a git branch with
+	// BranchTag and returns their memory:// paths
+	DeleteBranch(branch string) ([]string, error)
+	// List and Search leave out memories not tied to branch, unless it is
+	// empty
+	List(category *Category, branch string, limit int) ([]*MemoryListItem, error)
+	Search(query string, category *Category, branch string, limit int) ([]*SearchResult, error)
+
+	ListCategories() ([]*CategoryInfo, error)
+	CreateCategory(name Category, description string) (*CategoryInfo, error)
+	RenameCategory(from, to Category) (int64, error)
+	DeleteCategory(name, reassignTo Category) (int64, error)
+
+	Export(categories []Category) ([]*Memory, error)
+	Import(memory *Memory) error
+
+	// PurgeDeleted permanently removes memories soft-deleted more than
+	// olderThan ago
+	PurgeDeleted(olderThan time.Duration) (int64, error)
+	// Backup writes a consistent copy of the memories to dest
+	Backup(dest string) error
+
+	Close() error
+}
+
+var _ MemoryRepo = (*MemoryStore)(nil)
+
+// rehearser is implemented by SQL backends that can run a write in a
+// transaction that is rolled back, which is how dry runs are previewed
+type rehearser interface {
+	rehearse(fn func(tx *sql.Tx) error) error
+}
+
+// rehearse previews a write against repo, when its backend supports it
+func rehearse(repo MemoryRepo, fn func(tx *sql.Tx) error) error {
+	r, ok := repo.(rehearser)
+	if !ok {
+		return fmt.Errorf("dry run is not supported by this memory backend")
+	}
+	return r.rehearse(fn)
+}
+
+// Open opens the memories of the named backend at dbPath; encryption
+// applies to backends that keep them in a local file
+func Open(backend, dbPath string, encryption dbcrypt.Config) (MemoryRepo, error) {
+	switch backend {
+	case "", BackendSQLite:
+		store, err := OpenMemoryStore(dbPath, encryption)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unknown memory backend %q (want %s)", backend, BackendSQLite)
+	}
+}
@@ -1 +1,17 @@
 package memory
+
+type MemorySearchHit struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	Category  Category `json:"category"`
+	Score     float64  `json:"score"`
+	Snippet   string   `json:"snippet"`
+	CreatedAt string   `json:"created_at"`
+	Branch    string   `json:"branch,omitempty"`
+}
+
+type MemorySearchResponse struct {
+	Query   string            `json:"query"`
+	Total   int               `json:"total"`
+	Results []MemorySearchHit `json:"results"`
+}
@@ -9,18 +9,28 @@ import (
 	"time"
 
 	_ "modernc.org/sqlite"
+
+	"github.com/alucardeht/may-la-mcp/internal/dbcrypt"
 )
 
 type MemoryStore struct {
-	db *sql.DB
-	mu sync.RWMutex
+	db       *sql.DB
+	database *dbcrypt.Database
+	mu       sync.RWMutex
 }
 
 func NewMemoryStore(dbPath string) (*MemoryStore, error) {
-	db, err := sql.Open("sqlite", dbPath)
+	return OpenMemoryStore(dbPath, dbcrypt.Config{})
+}
+
+// OpenMemoryStore opens the memory database at dbPath, kept encrypted at
+// rest when encryption is enabled
+func OpenMemoryStore(dbPath string, encryption dbcrypt.Config) (*MemoryStore, error) {
+	database, err := dbcrypt.Open(dbPath, encryption)
 	if err != nil {
 		return nil, err
 	}
+	db := database.DB
 
 	if err := db.Ping(); err != nil {
 		return nil, err
@@ -34,23 +44,45 @@ func NewMemoryStore(dbPath string) (*MemoryStore, error) {
 		return nil, err
 	}
 
-	store := &MemoryStore{db: db}
+	store := &MemoryStore{db: db, database: database}
 	if err := store.initSchema(); err != nil {
 		return nil, err
 	}
 
-	if _, err := db.Exec(`DELETE FROM memories_fts WHERE name IN (SELECT name FROM memories WHERE deleted_at IS NOT NULL AND deleted_at < datetime('now', '-30 days'))`); err != nil {
-	}
-	result, err := db.Exec(`DELETE FROM memories WHERE deleted_at IS NOT NULL AND deleted_at < datetime('now', '-30 days')`)
-	if err == nil {
-		if rows, _ := result.RowsAffected(); rows > 0 {
-			fmt.Printf("Purged %d soft-deleted memories older than 30 days\n", rows)
-		}
+	if rows, err := store.PurgeDeleted(DefaultTrashRetention); err == nil && rows > 0 {
+		fmt.Printf("Purged %d soft-deleted memories older than 30 days\n", rows)
 	}
 
 	return store, nil
 }
 
+// DefaultTrashRetention is how long soft-deleted memories are kept
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+// PurgeDeleted permanently removes the memories soft-deleted more than
+// olderThan ago
+func (s *MemoryStore) PurgeDeleted(olderThan time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cutoff := fmt.Sprintf("-%d seconds", int64(olderThan.Seconds()))
+	if _, err := s.db.Exec(`DELETE FROM memories_fts WHERE name IN (SELECT name FROM memories WHERE deleted_at IS NOT NULL AND deleted_at < datetime('now', ?))`, cutoff); err != nil {
+		return 0, err
+	}
+	result, err := s.db.Exec(`DELETE FROM memories WHERE deleted_at IS NOT NULL AND deleted_at < datetime('now', ?)`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Backup writes a consistent copy of the memories to dest
+func (s *MemoryStore) Backup(dest string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.database.Backup(dest)
+}
+
 func (s *MemoryStore) initSchema() error {
 	schema := `
 	CREATE TABLE IF NOT EXISTS memories (
@@ -70,6 +102,13 @@ func (s *MemoryStore) initSchema() error {
 	CREATE INDEX IF NOT EXISTS idx_memories_name ON memories(name);
 
 	CREATE VIRTUAL TABLE IF NOT EXISTS memories_fts USING fts5(name, content);
+
+	CREATE TABLE IF NOT EXISTS categories (
+		name TEXT PRIMARY KEY,
+		description TEXT DEFAULT '',
+		builtin INTEGER DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
 	`
 
 	for _, stmt := range strings.Split(schema, ";") {
@@ -82,61 +121,125 @@ func (s *MemoryStore) initSchema() error {
 		}
 	}
 
-	return nil
+	return s.seedCategories()
 }
 
 func (s *MemoryStore) Create(id, name, content string, category Category, tags []string) (*Memory, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	memory, err := insertMemory(tx, NewMemory{ID: id, Name: name, Content: content, Category: category, Tags: tags})
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return memory, nil
+}
+
+// CreateBatch creates several memories in one transaction. When atomic is
+// set, the first failing entry rolls back the whole batch and is returned as
+// the error. Otherwise every entry runs inside its own savepoint, so failures
+// are reported per entry in errs while the rest of the batch is kept.
+func (s *MemoryStore) CreateBatch(entries []NewMemory, atomic bool) (created []*Memory, errs []error, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	if created, errs, err = createBatch(tx, entries, atomic); err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+
+	return created, errs, nil
+}
+
+func createBatch(tx *sql.Tx, entries []NewMemory, atomic bool) (created []*Memory, errs []error, err error) {
+	created = make([]*Memory, len(entries))
+	errs = make([]error, len(entries))
+	for i, entry := range entries {
+		if atomic {
+			if created[i], err = insertMemory(tx, entry); err != nil {
+				return nil, nil, fmt.Errorf("entry %d (%s): %w", i, entry.Name, err)
+			}
+			continue
+		}
+
+		if _, err := tx.Exec("SAVEPOINT batch_entry"); err != nil {
+			return nil, nil, err
+		}
+		created[i], errs[i] = insertMemory(tx, entry)
+		if errs[i] != nil {
+			if _, err := tx.Exec("ROLLBACK TO batch_entry"); err != nil {
+				return nil, nil, err
+			}
+		}
+		if _, err := tx.Exec("RELEASE batch_entry"); err != nil {
+			return nil, nil, err
+		}
+	}
+	return created, errs, nil
+}
+
+func insertMemory(tx *sql.Tx, entry NewMemory) (*Memory, error) {
+	if err := requireCategory(tx, entry.Category); err != nil {
+		return nil, err
+	}
+
 	var exists bool
-	err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM memories WHERE name = ? AND deleted_at IS NULL)", name).Scan(&exists)
+	err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM memories WHERE name = ? AND deleted_at IS NULL)", entry.Name).Scan(&exists)
 	if err == nil && exists {
-		return nil, fmt.Errorf("memory with name '%s' already exists", name)
+		return nil, fmt.Errorf("memory with name '%s' already exists", entry.Name)
 	}
 
-	tagsJSON, err := json.Marshal(tags)
+	tagsJSON, err := json.Marshal(entry.Tags)
 	if err != nil {
 		return nil, err
 	}
 
 	now := time.Now().UTC()
 	memory := &Memory{
-		ID:          id,
-		Name:        name,
-		Content:     content,
-		Category:    category,
-		Tags:        tags,
+		ID:          entry.ID,
+		Name:        entry.Name,
+		Content:     entry.Content,
+		Category:    entry.Category,
+		Tags:        entry.Tags,
 		CreatedAt:   now,
 		UpdatedAt:   now,
 		AccessedAt:  now,
 		AccessCount: 0,
 	}
 
-	tx, err := s.db.Begin()
-	if err != nil {
-		return nil, err
-	}
-
 	_, err = tx.Exec(
 		"INSERT INTO memories (id, name, content, category, tags, created_at, updated_at, accessed_at, access_count) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
-		id, name, content, category, string(tagsJSON), now, now, now, 0,
+		entry.ID, entry.Name, entry.Content, entry.Category, string(tagsJSON), now, now, now, 0,
 	)
 	if err != nil {
-		tx.Rollback()
 		return nil, err
 	}
 
 	_, err = tx.Exec(
 		"INSERT INTO memories_fts (name, content) VALUES (?, ?)",
-		name, content,
+		entry.Name, entry.Content,
 	)
 	if err != nil {
-		tx.Rollback()
-		return nil, err
-	}
-
-	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
 
@@ -147,11 +250,22 @@ func (s *MemoryStore) Read(identifier string) (*Memory, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	row := s.db.QueryRow(
-		"SELECT id, name, content, category, tags, created_at, updated_at, accessed_at, access_count, deleted_at FROM memories WHERE (id = ? OR name = ?) AND deleted_at IS NULL",
-		identifier, identifier,
+	memory, err := scanMemory(s.db.QueryRow(selectMemory, identifier, identifier))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.db.Exec(
+		"UPDATE memories SET accessed_at = ?, access_count = access_count + 1 WHERE id = ?",
+		time.Now().UTC(), memory.ID,
 	)
 
+	return memory, nil
+}
+
+const selectMemory = "SELECT id, name, content, category, tags, created_at, updated_at, accessed_at, access_count, deleted_at FROM memories WHERE (id = ? OR name = ?) AND deleted_at IS NULL"
+
+func scanMemory(row *sql.Row) (*Memory, error) {
 	memory := &Memory{}
 	var tagsJSON sql.NullString
 
@@ -159,7 +273,6 @@ func (s *MemoryStore) Read(identifier string) (*Memory, error) {
 		&memory.ID, &memory.Name, &memory.Content, &memory.Category, &tagsJSON,
 		&memory.CreatedAt, &memory.UpdatedAt, &memory.AccessedAt, &memory.AccessCount, &memory.DeletedAt,
 	)
-
 	if err != nil {
 		return nil, err
 	}
@@ -171,12 +284,6 @@ func (s *MemoryStore) Read(identifier string) (*Memory, error) {
 	} else {
 		memory.Tags = []string{}
 	}
-
-	_, err = s.db.Exec(
-		"UPDATE memories SET accessed_at = ?, access_count = access_count + 1 WHERE id = ?",
-		time.Now().UTC(), memory.ID,
-	)
-
 	return memory, nil
 }
 
@@ -259,6 +366,25 @@ func (s *MemoryStore) UpdateFull(id, content string, category Category, tags []s
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	memory, err := updateMemory(tx, id, content, category, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return memory, nil
+}
+
+func updateMemory(tx *sql.Tx, id, content string, category Category, tags []string) (*Memory, error) {
 	tagsJSON, err := json.Marshal(tags)
 	if err != nil {
 		return nil, err
@@ -266,8 +392,7 @@ func (s *MemoryStore) UpdateFull(id, content string, category Category, tags []s
 
 	now := time.Now().UTC()
 
-	tx, err := s.db.Begin()
-	if err != nil {
+	if err := requireCategory(tx, category); err != nil {
 		return nil, err
 	}
 
@@ -276,7 +401,6 @@ func (s *MemoryStore) UpdateFull(id, content string, category Category, tags []s
 		content, category, string(tagsJSON), now, id,
 	)
 	if err != nil {
-		tx.Rollback()
 		return nil, err
 	}
 
@@ -293,7 +417,6 @@ func (s *MemoryStore) UpdateFull(id, content string, category Category, tags []s
 		&memory.CreatedAt, &memory.UpdatedAt, &memory.AccessedAt, &memory.AccessCount,
 	)
 	if err != nil {
-		tx.Rollback()
 		return nil, err
 	}
 
@@ -310,7 +433,6 @@ func (s *MemoryStore) UpdateFull(id, content string, category Category, tags []s
 		memory.Name,
 	)
 	if err != nil {
-		tx.Rollback()
 		return nil, err
 	}
 
@@ -319,11 +441,6 @@ func (s *MemoryStore) UpdateFull(id, content string, category Category, tags []s
 		memory.Name, content,
 	)
 	if err != nil {
-		tx.Rollback()
-		return nil, err
-	}
-
-	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
 
@@ -342,39 +459,51 @@ func (s *MemoryStore) Delete(identifier string) (string, *time.Time, error) {
 	}
 	defer tx.Rollback()
 
-	_, err = tx.Exec(`DELETE FROM memories_fts WHERE name = ? OR rowid IN (SELECT rowid FROM memories WHERE id = ?)`, identifier, identifier)
-	if err != nil {
+	if err := deleteMemory(tx, identifier); err != nil {
 		return "", nil, err
 	}
 
-	result, err := tx.Exec(`DELETE FROM memories WHERE (id = ? OR name = ?)`, identifier, identifier)
-	if err != nil {
+	if err := tx.Commit(); err != nil {
 		return "", nil, err
 	}
 
-	rows, _ := result.RowsAffected()
-	if rows == 0 {
-		return "", nil, fmt.Errorf("memory '%s' not found", identifier)
+	return identifier, &now, nil
+}
+
+func deleteMemory(tx *sql.Tx, identifier string) error {
+	_, err := tx.Exec(`DELETE FROM memories_fts WHERE name = ? OR rowid IN (SELECT rowid FROM memories WHERE id = ?)`, identifier, identifier)
+	if err != nil {
+		return err
 	}
 
-	if err := tx.Commit(); err != nil {
-		return "", nil, err
+	result, err := tx.Exec(`DELETE FROM memories WHERE (id = ? OR name = ?)`, identifier, identifier)
+	if err != nil {
+		return err
 	}
 
-	return identifier, &now, nil
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return fmt.Errorf("memory '%s' not found", identifier)
+	}
+	return nil
 }
 
-func (s *MemoryStore) List(category *Category, limit int) ([]*MemoryListItem, error) {
+func (s *MemoryStore) List(category *Category, branch string, limit int) ([]*MemoryListItem, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	query := "SELECT id, name, category, content, created_at, accessed_at, access_count FROM memories WHERE deleted_at IS NULL"
+	query := "SELECT id, name, category, content, tags, created_at, accessed_at, access_count FROM memories WHERE deleted_at IS NULL"
 	var args []interface{}
 
 	if category != nil {
 		query += " AND category = ?"
 		args = append(args, *category)
 	}
+	if branch != "" {
+		condition, arg := branchFilter("tags", branch)
+		query += condition
+		args = append(args, arg)
+	}
 
 	query += " ORDER BY accessed_at DESC, created_at DESC LIMIT ?"
 	args = append(args, limit)
@@ -390,14 +519,16 @@ func (s *MemoryStore) List(category *Category, limit int) ([]*MemoryListItem, er
 	for rows.Next() {
 		item := &MemoryListItem{}
 		var content string
+		var tagsJSON sql.NullString
 
 		err := rows.Scan(
-			&item.ID, &item.Name, &item.Category, &content,
+			&item.ID, &item.Name, &item.Category, &content, &tagsJSON,
 			&item.CreatedAt, &item.AccessedAt, &item.AccessCount,
 		)
 		if err != nil {
 			return nil, err
 		}
+		item.Branch = branchTagsOf(tagsJSON)
 
 		preview := truncate(content, 100)
 		item.Preview = preview
@@ -408,16 +539,16 @@ func (s *MemoryStore) List(category *Category, limit int) ([]*MemoryListItem, er
 	return items, rows.Err()
 }
 
-func (s *MemoryStore) Search(query string, category *Category, limit int) ([]*SearchResult, error) {
+func (s *MemoryStore) Search(query string, category *Category, branch string, limit int) ([]*SearchResult, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	sqlQuery := "SELECT m.id, m.name, m.category, m.content, m.created_at FROM memories m WHERE m.deleted_at IS NULL"
+	sqlQuery := "SELECT m.id, m.name, m.category, m.content, m.tags, m.created_at FROM memories m WHERE m.deleted_at IS NULL"
 	var args []interface{}
 
 	if query != "" {
 		sqlQuery = fmt.Sprintf(
-			"SELECT m.id, m.name, m.category, m.content, m.created_at FROM memories m "+
+			"SELECT m.id, m.name, m.category, m.content, m.tags, m.created_at FROM memories m "+
 				"INNER JOIN memories_fts fts ON m.name = fts.name "+
 				"WHERE fts.memories_fts MATCH ? AND m.deleted_at IS NULL",
 		)
@@ -431,6 +562,11 @@ func (s *MemoryStore) Search(query string, category *Category, limit int) ([]*Se
 		sqlQuery += " AND category = ?"
 		args = append(args, *category)
 	}
+	if branch != "" {
+		condition, arg := branchFilter("m.tags", branch)
+		sqlQuery += condition
+		args = append(args, arg)
+	}
 
 	sqlQuery += " LIMIT ?"
 	args = append(args, limit)
@@ -446,13 +582,15 @@ func (s *MemoryStore) Search(query string, category *Category, limit int) ([]*Se
 	for rows.Next() {
 		result := &SearchResult{}
 		var content string
+		var tagsJSON sql.NullString
 
 		err := rows.Scan(
-			&result.ID, &result.Name, &result.Category, &content, &result.CreatedAt,
+			&result.ID, &result.Name, &result.Category, &content, &tagsJSON, &result.CreatedAt,
 		)
 		if err != nil {
 			return nil, err
 		}
+		result.Branch = branchTagsOf(tagsJSON)
 
 		result.Score = calculateRelevance(result.Name, content, query)
 		result.Snippet = truncate(content, 150)
@@ -475,7 +613,7 @@ func (s *MemoryStore) Close() error {
 	if _, err := s.db.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
 		// Checkpoint failure is not critical - DB will close normally even if truncation fails
 	}
-	return s.db.Close()
+	return s.database.Close()
 }
 
 func truncate(s string, length int) string {
@@ -0,0 +1,615 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+// Kinds of suggested memories
+const (
+	// SuggestRepeatedSearch is a concept searched for again and again
+	SuggestRepeatedSearch = "repeated_search"
+	// SuggestDecision is a decision record or spec written through the tools
+	SuggestDecision = "decision"
+	// SuggestResolvedError is a call that failed and later succeeded on the
+	// same file
+	SuggestResolvedError = "resolved_error"
+)
+
+const (
+	defaultRepeatThreshold = 3
+	defaultRepeatWindow    = time.Hour
+	defaultMaxPending      = 50
+
+	// maxTracked bounds the searches and failures remembered between calls
+	maxTracked = 500
+	// maxSuggestionPaths is how many result paths a repeated search lists
+	maxSuggestionPaths = 5
+	maxSummaryLen      = 400
+	maxDecisionRead    = 64 * 1024
+)
+
+// searchQueryFields are the arguments holding what each search tool looks
+// for
+var searchQueryFields = map[string]string{
+	"search":          "pattern",
+	"find":            "pattern",
+	"search_archives": "pattern",
+	"symbols":         "query",
+	"references":      "symbol",
+	"memory_search":   "query",
+}
+
+// decisionWriters are the tools whose writes can record a decision
+var decisionWriters = map[string]bool{
+	"write":             true,
+	"create":            true,
+	"edit":              true,
+	"doc_write":         true,
+	"doc_section_write": true,
+}
+
+// decisionDirs are directories holding decision records and specs
+var decisionDirs = map[string]bool{
+	"adr": true, "adrs": true, "decisions": true, "rfc": true, "rfcs": true, "specs": true,
+}
+
+// SuggestConfig turns on memory suggestions; they are off unless enabled
+type SuggestConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// RepeatThreshold is how many times a query is searched for within
+	// RepeatWindow before it is suggested
+	RepeatThreshold int           `yaml:"repeat_threshold"`
+	RepeatWindow    time.Duration `yaml:"repeat_window"`
+	// MaxPending bounds the queue; the oldest suggestions make room
+	MaxPending int `yaml:"max_pending"`
+}
+
+// Suggestion is a memory drafted from tool activity, waiting to be
+// accepted or rejected
+type Suggestion struct {
+	ID        string    `json:"id"`
+	Kind      string    `json:"kind"`
+	Name      string    `json:"name"`
+	Content   string    `json:"content"`
+	Category  Category  `json:"category"`
+	Tags      []string  `json:"tags"`
+	CreatedAt time.Time `json:"created_at"`
+	// key identifies what the suggestion is about, so it is not suggested
+	// twice
+	key string
+}
+
+type searchTally struct {
+	count int
+	first time.Time
+}
+
+type failedCall struct {
+	input json.RawMessage
+	err   string
+}
+
+// Suggester watches tool activity and queues memories worth keeping:
+// concepts searched for repeatedly, decision records written and errors
+// that were worked around. Nothing is stored until a suggestion is
+// accepted.
+type Suggester struct {
+	cfg SuggestConfig
+
+	mu       sync.Mutex
+	searches map[string]*searchTally
+	failures map[string]failedCall
+	pending  []*Suggestion
+	// seen holds the keys already suggested, whatever became of them
+	seen map[string]bool
+	now  func() time.Time
+}
+
+func NewSuggester(cfg SuggestConfig) *Suggester {
+	if cfg.RepeatThreshold <= 0 {
+		cfg.RepeatThreshold = defaultRepeatThreshold
+	}
+	if cfg.RepeatWindow <= 0 {
+		cfg.RepeatWindow = defaultRepeatWindow
+	}
+	if cfg.MaxPending <= 0 {
+		cfg.MaxPending = defaultMaxPending
+	}
+	return &Suggester{
+		cfg:      cfg,
+		searches: make(map[string]*searchTally),
+		failures: make(map[string]failedCall),
+		seen:     make(map[string]bool),
+		now:      time.Now,
+	}
+}
+
+// Observe looks at a finished tool call; it is meant to be installed with
+// tools.SetActivityRecorder
+func (s *Suggester) Observe(activity tools.Activity) {
+	var args map[string]interface{}
+	if err := json.Unmarshal(activity.Input, &args); err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if field, ok := searchQueryFields[activity.Tool]; ok && activity.Err == nil {
+		if query, _ := args[field].(string); strings.TrimSpace(query) != "" {
+			s.observeSearch(query, activity.Result)
+		}
+	}
+
+	path := stringArg(args, "path", "file")
+	if path == "" {
+		return
+	}
+	if activity.Err == nil && decisionWriters[activity.Tool] && isDecisionPath(path) {
+		s.observeDecision(path)
+	}
+	if _, search := searchQueryFields[activity.Tool]; !search {
+		s.observeOutcome(activity, path)
+	}
+}
+
+// observeSearch counts a search and suggests its query once it reaches the
+// threshold, naming where the last search found it
+func (s *Suggester) observeSearch(query string, result interface{}) {
+	now := s.now()
+	key := SuggestRepeatedSearch + ":" + strings.ToLower(strings.TrimSpace(query))
+	tally := s.searches[key]
+	if tally == nil || now.Sub(tally.first) > s.cfg.RepeatWindow {
+		if len(s.searches) >= maxTracked {
+			clear(s.searches)
+		}
+		tally = &searchTally{first: now}
+		s.searches[key] = tally
+	}
+	tally.count++
+	if tally.count < s.cfg.RepeatThreshold {
+		return
+	}
+	delete(s.searches, key)
+
+	var content strings.Builder
+	fmt.Fprintf(&content, "%q was searched for %d times within %s.", query, tally.count, s.cfg.RepeatWindow)
+	if paths := resultPaths(result, maxSuggestionPaths); len(paths) > 0 {
+		content.WriteString(" It was found in:\n")
+		for _, p := range paths {
+			fmt.Fprintf(&content, "- %s\n", p)
+		}
+	}
+	s.suggest(&Suggestion{
+		Kind:     SuggestRepeatedSearch,
+		Name:     "where-to-find-" + slug(query),
+		Content:  strings.TrimSpace(content.String()),
+		Category: CategoryContext,
+		key:      key,
+	})
+}
+
+// observeDecision suggests the title and opening paragraph of a decision
+// record that was written
+func (s *Suggester) observeDecision(path string) {
+	title, summary := readDecision(path)
+	if title == "" {
+		title = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	content := fmt.Sprintf("%s (%s)", title, path)
+	if summary != "" {
+		content += "\n\n" + summary
+	}
+	s.suggest(&Suggestion{
+		Kind:     SuggestDecision,
+		Name:     "decision-" + slug(title),
+		Content:  content,
+		Category: CategoryDecisions,
+		key:      SuggestDecision + ":" + path + ":" + title,
+	})
+}
+
+// observeOutcome remembers a failed call on path and, when the same tool
+// later succeeds on it in the same session, suggests what changed.
+// Refusals by the server policies are not errors to learn from.
+func (s *Suggester) observeOutcome(activity tools.Activity, path string) {
+	key := activity.SessionID + "\x00" + activity.Tool + "\x00" + path
+	if activity.Err != nil {
+		var toolErr *tools.ToolError
+		if errors.As(activity.Err, &toolErr) {
+			return
+		}
+		if len(s.failures) >= maxTracked {
+			clear(s.failures)
+		}
+		s.failures[key] = failedCall{input: activity.Input, err: activity.Err.Error()}
+		return
+	}
+
+	failed, ok := s.failures[key]
+	if !ok {
+		return
+	}
+	delete(s.failures, key)
+
+	content := fmt.Sprintf("%s on %s failed with:\n%s\n\nFailing input: %s\nWorking input: %s",
+		activity.Tool, path, failed.err,
+		truncate(string(failed.input), maxSummaryLen), truncate(string(activity.Input), maxSummaryLen))
+	s.suggest(&Suggestion{
+		Kind:     SuggestResolvedError,
+		Name:     "resolved-" + activity.Tool + "-" + slug(filepath.Base(path)),
+		Content:  content,
+		Category: CategoryGeneral,
+		key:      SuggestResolvedError + ":" + activity.Tool + ":" + path + ":" + failed.err,
+	})
+}
+
+// suggest queues a suggestion unless its key was already suggested
+func (s *Suggester) suggest(suggestion *Suggestion) {
+	if s.seen[suggestion.key] {
+		return
+	}
+	s.seen[suggestion.key] = true
+
+	suggestion.ID = generateID()[:12]
+	suggestion.Tags = []string{"auto", suggestion.Kind}
+	suggestion.CreatedAt = s.now().UTC()
+	if len(s.pending) >= s.cfg.MaxPending {
+		s.pending = s.pending[1:]
+	}
+	s.pending = append(s.pending, suggestion)
+}
+
+// Pending returns the queued suggestions, oldest first
+func (s *Suggester) Pending() []*Suggestion {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*Suggestion(nil), s.pending...)
+}
+
+// Get returns the queued suggestion id
+func (s *Suggester) Get(id string) (*Suggestion, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, suggestion := range s.pending {
+		if suggestion.ID == id {
+			return suggestion, true
+		}
+	}
+	return nil, false
+}
+
+// Remove takes suggestion id off the queue; it is not suggested again
+func (s *Suggester) Remove(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, suggestion := range s.pending {
+		if suggestion.ID == id {
+			s.pending = append(s.pending[:i], s.pending[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func stringArg(args map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if value, ok := args[key].(string); ok && value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// isDecisionPath reports whether path is a text document in a decision
+// record or spec directory, or named after one
+func isDecisionPath(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown", ".rst", ".adoc", ".txt":
+	default:
+		return false
+	}
+	base := strings.ToLower(filepath.Base(path))
+	if strings.HasPrefix(base, "adr-") || strings.Contains(base, "decision") || base == "spec.md" {
+		return true
+	}
+	for _, dir := range strings.Split(filepath.ToSlash(filepath.Dir(path)), "/") {
+		if decisionDirs[strings.ToLower(dir)] {
+			return true
+		}
+	}
+	return false
+}
+
+// readDecision returns the first heading of the document at path and the
+// paragraph that follows it
+func readDecision(path string) (title, summary string) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", ""
+	}
+	defer f.Close()
+	buf := make([]byte, maxDecisionRead)
+	n, _ := f.Read(buf)
+
+	var paragraph []string
+	for _, line := range strings.Split(string(buf[:n]), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case title == "" && strings.HasPrefix(line, "#"):
+			title = strings.TrimSpace(strings.TrimLeft(line, "#"))
+		case line == "" || strings.HasPrefix(line, "#"):
+			if len(paragraph) > 0 {
+				return title, truncate(strings.Join(paragraph, " "), maxSummaryLen)
+			}
+		case title != "":
+			paragraph = append(paragraph, line)
+		}
+	}
+	return title, truncate(strings.Join(paragraph, " "), maxSummaryLen)
+}
+
+// resultPaths collects up to limit distinct path or file values from a
+// tool result, going through lists in order and objects by key
+func resultPaths(result interface{}, limit int) []string {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil
+	}
+
+	var paths []string
+	seen := make(map[string]bool)
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for _, key := range []string{"path", "file"} {
+				if p, ok := v[key].(string); ok && p != "" && !seen[p] && len(paths) < limit {
+					seen[p] = true
+					paths = append(paths, p)
+				}
+			}
+			keys := make([]string, 0, len(v))
+			for key := range v {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				walk(v[key])
+			}
+		case []interface{}:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(decoded)
+	return paths
+}
+
+// slug turns s into a lowercase, dash-separated memory name
+func slug(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+		if b.Len() >= 48 {
+			break
+		}
+	}
+	return strings.Trim(b.String(), "-")
+}
+
+// MemorySuggestionsResponse holds the fields of the memory_suggestions
+// action that ran: total and suggestions for list, the memory written for
+// accept and the suggestion dropped for reject
+type MemorySuggestionsResponse struct {
+	Success     bool                 `json:"success,omitempty"`
+	Total       int                  `json:"total"`
+	Suggestions []*Suggestion        `json:"suggestions,omitempty"`
+	Accepted    *MemoryWriteResponse `json:"accepted,omitempty"`
+	Rejected    string               `json:"rejected,omitempty"`
+}
+
+type MemorySuggestionsTool struct {
+	store     MemoryRepo
+	suggester *Suggester
+}
+
+func NewMemorySuggestionsTool(store MemoryRepo, suggester *Suggester) *MemorySuggestionsTool {
+	return &MemorySuggestionsTool{store: store, suggester: suggester}
+}
+
+func (t *MemorySuggestionsTool) Name() string {
+	return "memory_suggestions"
+}
+
+func (t *MemorySuggestionsTool) Description() string {
+	return `Review memories suggested from tool activity.
+
+Suggestions are drafted, never stored, when:
+- repeated_search: the same query is searched for several times, with where it was found
+- decision: a decision record or spec (adr/, decisions/, specs/, ...) is written
+- resolved_error: a call on a file fails and the same tool later succeeds on it, with both inputs
+
+ACTIONS:
+- list: the pending suggestions
+- accept: store suggestion id as a memory; name, content, category, tags and branch override the draft
+- reject: drop suggestion id
+
+Accepted and rejected suggestions are not suggested again.`
+}
+
+func (t *MemorySuggestionsTool) Title() string {
+	return "Review Memory Suggestions"
+}
+
+func (t *MemorySuggestionsTool) Annotations() map[string]bool {
+	return tools.NonIdempotentWriteAnnotations()
+}
+
+func (t *MemorySuggestionsTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"action": {
+				"type": "string",
+				"enum": ["list", "accept", "reject"],
+				"description": "Operation to perform (default: list)"
+			},
+			"id": {
+				"type": "string",
+				"description": "Suggestion to accept or reject"
+			},
+			"name": {
+				"type": "string",
+				"description": "Memory name replacing the suggested one"
+			},
+			"content": {
+				"type": "string",
+				"description": "Memory content replacing the suggested one"
+			},
+			"category": {
+				"type": "string",
+				"description": "Memory category replacing the suggested one"
+			},
+			"tags": {
+				"type": "array",
+				"items": {"type": "string"},
+				"description": "Tags replacing the suggested ones"
+			},
+			"branch": {
+				"type": "string",
+				"description": "Git branch the accepted memory belongs to"
+			}
+		}
+	}`)
+}
+
+func (t *MemorySuggestionsTool) OutputSchema() json.RawMessage {
+	return tools.OutputSchemaOf(MemorySuggestionsResponse{})
+}
+
+type suggestionsRequest struct {
+	Action   string   `json:"action"`
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Content  string   `json:"content"`
+	Category string   `json:"category"`
+	Tags     []string `json:"tags"`
+	Branch   string   `json:"branch"`
+}
+
+func parseSuggestionsRequest(input json.RawMessage) (suggestionsRequest, error) {
+	var req suggestionsRequest
+	if err := json.Unmarshal(input, &req); err != nil {
+		return req, err
+	}
+	if req.Action == "" {
+		req.Action = "list"
+	}
+	switch req.Action {
+	case "list":
+	case "accept", "reject":
+		if req.ID == "" {
+			return req, fmt.Errorf("id is required for %s", req.Action)
+		}
+	default:
+		return req, fmt.Errorf("invalid action %q: expected list, accept or reject", req.Action)
+	}
+	return req, nil
+}
+
+// entry is the memory accepting suggestion would write
+func (req suggestionsRequest) entry(suggestion *Suggestion) NewMemory {
+	entry := NewMemory{
+		ID:       generateID(),
+		Name:     suggestion.Name,
+		Content:  suggestion.Content,
+		Category: suggestion.Category,
+		Tags:     append([]string(nil), suggestion.Tags...),
+	}
+	if req.Name != "" {
+		entry.Name = req.Name
+	}
+	if req.Content != "" {
+		entry.Content = req.Content
+	}
+	if req.Category != "" {
+		entry.Category = Category(req.Category)
+	}
+	if req.Tags != nil {
+		entry.Tags = req.Tags
+	}
+	if req.Branch != "" {
+		entry.Tags = withBranch(entry.Tags, req.Branch)
+	}
+	return entry
+}
+
+func (t *MemorySuggestionsTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	req, err := parseSuggestionsRequest(input)
+	if err != nil {
+		return nil, err
+	}
+
+	switch req.Action {
+	case "accept":
+		suggestion, ok := t.suggester.Get(req.ID)
+		if !ok {
+			return nil, fmt.Errorf("suggestion not found: %s", req.ID)
+		}
+		entry := req.entry(suggestion)
+		memory, err := t.store.Create(entry.ID, entry.Name, entry.Content, entry.Category, entry.Tags)
+		if err != nil {
+			return nil, err
+		}
+		t.suggester.Remove(req.ID)
+		return &MemorySuggestionsResponse{
+			Success: true,
+			Total:   len(t.suggester.Pending()),
+			Accepted: &MemoryWriteResponse{
+				Success: true,
+				ID:      memory.ID,
+				Name:    memory.Name,
+				Path:    memoryPath(entry.Category, entry.Name),
+				Created: memory.CreatedAt,
+			},
+		}, nil
+
+	case "reject":
+		if !t.suggester.Remove(req.ID) {
+			return nil, fmt.Errorf("suggestion not found: %s", req.ID)
+		}
+		return &MemorySuggestionsResponse{Success: true, Total: len(t.suggester.Pending()), Rejected: req.ID}, nil
+
+	default:
+		pending := t.suggester.Pending()
+		return &MemorySuggestionsResponse{Total: len(pending), Suggestions: pending}, nil
+	}
+}
@@ -0,0 +1,117 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+func TestSuggester(t *testing.T) {
+	s := NewSuggester(SuggestConfig{Enabled: true, RepeatThreshold: 2})
+
+	search := tools.Activity{
+		Tool:   "search",
+		Input:  json.RawMessage(`{"pattern": "retryPolicy", "path": "/repo"}`),
+		Result: map[string]interface{}{"matches": []map[string]string{{"file": "/repo/net/retry.go"}}},
+	}
+	s.Observe(search)
+	if len(s.Pending()) != 0 {
+		t.Fatal("a single search should not be suggested")
+	}
+	s.Observe(search)
+	s.Observe(search)
+	s.Observe(search)
+
+	dir := t.TempDir()
+	adr := filepath.Join(dir, "docs", "adr", "0003-use-sqlite.md")
+	os.MkdirAll(filepath.Dir(adr), 0755)
+	os.WriteFile(adr, []byte("# Use SQLite for the index\n\nWe embed SQLite so the daemon\nneeds no server.\n\n## Status\n"), 0644)
+	s.Observe(tools.Activity{Tool: "write", Input: json.RawMessage(`{"path": "` + adr + `"}`)})
+	s.Observe(tools.Activity{Tool: "write", Input: json.RawMessage(`{"path": "` + filepath.Join(dir, "main.go") + `"}`)})
+
+	edit := tools.Activity{Tool: "edit", SessionID: "s1", Input: json.RawMessage(`{"path": "/repo/a.go", "search": "Foo"}`)}
+	s.Observe(tools.Activity{Tool: edit.Tool, SessionID: "s1", Input: edit.Input, Err: errors.New("search text not found")})
+	s.Observe(tools.Activity{Tool: edit.Tool, SessionID: "s2", Input: json.RawMessage(`{"path": "/repo/a.go", "search": "foo"}`)})
+	s.Observe(tools.Activity{Tool: edit.Tool, SessionID: "s1", Input: json.RawMessage(`{"path": "/repo/a.go", "search": "foo"}`)})
+
+	pending := s.Pending()
+	if len(pending) != 3 {
+		t.Fatalf("expected a search, a decision and a resolved error, got %+v", pending)
+	}
+	if p := pending[0]; p.Kind != SuggestRepeatedSearch || p.Name != "where-to-find-retrypolicy" || !strings.Contains(p.Content, "/repo/net/retry.go") {
+		t.Errorf("repeated search suggestion = %+v", p)
+	}
+	if p := pending[1]; p.Kind != SuggestDecision || p.Category != CategoryDecisions ||
+		!strings.Contains(p.Content, "Use SQLite for the index") || !strings.Contains(p.Content, "needs no server.") {
+		t.Errorf("decision suggestion = %+v", p)
+	}
+	if p := pending[2]; p.Kind != SuggestResolvedError || !strings.Contains(p.Content, "search text not found") {
+		t.Errorf("resolved error suggestion = %+v", p)
+	}
+
+	if !s.Remove(pending[0].ID) || s.Remove(pending[0].ID) {
+		t.Error("a suggestion should be removed once")
+	}
+	s.Observe(search)
+	s.Observe(search)
+	if len(s.Pending()) != 2 {
+		t.Error("a rejected suggestion should not come back")
+	}
+}
+
+func TestMemorySuggestionsTool(t *testing.T) {
+	store, err := NewMemoryStore(filepath.Join(t.TempDir(), "memory.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	s := NewSuggester(SuggestConfig{Enabled: true, RepeatThreshold: 1})
+	s.Observe(tools.Activity{Tool: "symbols", Input: json.RawMessage(`{"query": "Router"}`)})
+	s.Observe(tools.Activity{Tool: "references", Input: json.RawMessage(`{"symbol": "Dispatch"}`)})
+	tool := NewMemorySuggestionsTool(store, s)
+	ctx := context.Background()
+
+	result, err := tool.Execute(ctx, json.RawMessage(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	listed := result.(*MemorySuggestionsResponse)
+	if listed.Total != 2 {
+		t.Fatalf("expected 2 suggestions, got %+v", listed)
+	}
+
+	input, _ := json.Marshal(map[string]interface{}{"action": "accept", "id": listed.Suggestions[0].ID, "name": "router", "branch": "feat/x"})
+	result, err = tool.Execute(ctx, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	accepted := result.(*MemorySuggestionsResponse)
+	if accepted.Accepted == nil || accepted.Accepted.Path != "memory://context/router" || accepted.Total != 1 {
+		t.Fatalf("accept = %+v", accepted)
+	}
+	memory, err := store.Read("router")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(memory.Content, `"Router"`) || strings.Join(memory.Tags, ",") != "auto,repeated_search,branch:feat/x" {
+		t.Errorf("stored memory = %+v", memory)
+	}
+
+	input, _ = json.Marshal(map[string]string{"action": "reject", "id": listed.Suggestions[1].ID})
+	if _, err := tool.Execute(ctx, input); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tool.Execute(ctx, input); err == nil {
+		t.Error("rejecting twice should fail")
+	}
+	if _, err := tool.Execute(ctx, json.RawMessage(`{"action": "accept"}`)); err == nil {
+		t.Error("accept without id should fail")
+	}
+}
@@ -18,30 +18,34 @@ func GetTools(dbPath string) ([]tools.Tool, error) {
 
 	return []tools.Tool{
 		NewMemoryWriteTool(store),
+		NewMemoryWriteBatchTool(store),
 		NewMemoryReadTool(store),
 		NewMemoryUpdateTool(store),
 		NewMemoryListTool(store),
 		NewMemorySearchTool(store),
 		NewMemoryDeleteTool(store),
+		NewMemoryCategoriesTool(store),
 	}, nil
 }
 
-func GetToolsFromStore(store *MemoryStore) []tools.Tool {
+func GetToolsFromStore(store MemoryRepo) []tools.Tool {
 	return []tools.Tool{
 		NewMemoryWriteTool(store),
+		NewMemoryWriteBatchTool(store),
 		NewMemoryReadTool(store),
 		NewMemoryUpdateTool(store),
 		NewMemoryListTool(store),
 		NewMemorySearchTool(store),
 		NewMemoryDeleteTool(store),
+		NewMemoryCategoriesTool(store),
 	}
 }
 
 type MemoryWriteTool struct {
-	store *MemoryStore
+	store MemoryRepo
 }
 
-func NewMemoryWriteTool(store *MemoryStore) *MemoryWriteTool {
+func NewMemoryWriteTool(store MemoryRepo) *MemoryWriteTool {
 	return &MemoryWriteTool{store: store}
 }
 
@@ -67,7 +71,7 @@ WHEN TO USE doc_write INSTEAD:
 - Setup guides for THIS codebase
 - Anything that should be version-controlled with the project
 
-CATEGORIES:
+CATEGORIES (built-in; manage your own with memory_categories):
 - architecture: System design patterns
 - conventions: Coding standards, naming patterns
 - decisions: Why choices were made
@@ -97,40 +101,69 @@ func (t *MemoryWriteTool) Schema() json.RawMessage {
 			},
 			"category": {
 				"type": "string",
-				"enum": ["architecture", "conventions", "decisions", "context", "general"],
-				"description": "Memory category"
+				"description": "Memory category: a built-in one or one created with memory_categories (default: general)"
 			},
 			"tags": {
 				"type": "array",
 				"items": {"type": "string"},
 				"description": "Tags for searchability"
+			},
+			"branch": {
+				"type": "string",
+				"description": "Git branch the memory belongs to, stored as a branch:<name> tag so it can be filtered or deleted after merge"
 			}
 		},
 		"required": ["name", "content"]
 	}`)
 }
 
+func (t *MemoryWriteTool) OutputSchema() json.RawMessage {
+	return tools.OutputSchemaOf(MemoryWriteResponse{})
+}
+
 func (t *MemoryWriteTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
 	}
 
+	entry, err := parseMemoryWrite(input)
+	if err != nil {
+		return nil, err
+	}
+
+	memory, err := t.store.Create(entry.ID, entry.Name, entry.Content, entry.Category, entry.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MemoryWriteResponse{
+		Success: true,
+		ID:      memory.ID,
+		Name:    memory.Name,
+		Path:    memoryPath(entry.Category, entry.Name),
+		Created: memory.CreatedAt,
+	}, nil
+}
+
+// parseMemoryWrite validates a memory_write request and fills in defaults
+func parseMemoryWrite(input json.RawMessage) (NewMemory, error) {
 	var req struct {
 		Name     string   `json:"name"`
 		Content  string   `json:"content"`
 		Category string   `json:"category"`
 		Tags     []string `json:"tags"`
+		Branch   string   `json:"branch"`
 	}
 	if err := json.Unmarshal(input, &req); err != nil {
-		return nil, err
+		return NewMemory{}, err
 	}
 
 	if req.Name == "" {
-		return nil, fmt.Errorf("memory name is required")
+		return NewMemory{}, fmt.Errorf("memory name is required")
 	}
 
 	if req.Content == "" {
-		return nil, fmt.Errorf("memory content is required")
+		return NewMemory{}, fmt.Errorf("memory content is required")
 	}
 
 	if req.Category == "" {
@@ -140,27 +173,239 @@ func (t *MemoryWriteTool) Execute(ctx context.Context, input json.RawMessage) (i
 	if req.Tags == nil {
 		req.Tags = []string{}
 	}
+	if req.Branch != "" {
+		req.Tags = withBranch(req.Tags, req.Branch)
+	}
+
+	return NewMemory{
+		ID:       generateID(),
+		Name:     req.Name,
+		Content:  req.Content,
+		Category: Category(req.Category),
+		Tags:     req.Tags,
+	}, nil
+}
+
+// maxBatchMemories bounds a single memory_write_batch call
+const maxBatchMemories = 100
 
-	id := generateID()
-	memory, err := t.store.Create(id, req.Name, req.Content, Category(req.Category), req.Tags)
+type MemoryWriteBatchTool struct {
+	store MemoryRepo
+}
+
+func NewMemoryWriteBatchTool(store MemoryRepo) *MemoryWriteBatchTool {
+	return &MemoryWriteBatchTool{store: store}
+}
+
+func (t *MemoryWriteBatchTool) Name() string {
+	return "memory_write_batch"
+}
+
+func (t *MemoryWriteBatchTool) Description() string {
+	return `Write many memories in one call, e.g. when importing project context.
+
+Each entry takes the same fields as memory_write (name, content, category, tags, branch).
+
+MODES:
+- atomic (default): all entries are written or none are; the first invalid or conflicting entry fails the call
+- best_effort: valid entries are written and failures are reported per entry`
+}
+
+func (t *MemoryWriteBatchTool) Title() string {
+	return "Write Memories in Batch"
+}
+
+func (t *MemoryWriteBatchTool) Annotations() map[string]bool {
+	return tools.NonIdempotentWriteAnnotations()
+}
+
+func (t *MemoryWriteBatchTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"memories": {
+				"type": "array",
+				"maxItems": 100,
+				"items": {
+					"type": "object",
+					"properties": {
+						"name": {
+							"type": "string",
+							"description": "Memory name/identifier"
+						},
+						"content": {
+							"type": "string",
+							"description": "Content to store"
+						},
+						"category": {
+							"type": "string",
+							"description": "Memory category: a built-in one or one created with memory_categories (default: general)"
+						},
+						"tags": {
+							"type": "array",
+							"items": {"type": "string"},
+							"description": "Tags for searchability"
+						},
+						"branch": {
+							"type": "string",
+							"description": "Git branch the memory belongs to"
+						}
+					},
+					"required": ["name", "content"]
+				},
+				"description": "Memories to create"
+			},
+			"mode": {
+				"type": "string",
+				"enum": ["atomic", "best_effort"],
+				"description": "atomic writes all or nothing; best_effort keeps the entries that succeed (default: atomic)"
+			}
+		},
+		"required": ["memories"]
+	}`)
+}
+
+func (t *MemoryWriteBatchTool) OutputSchema() json.RawMessage {
+	return tools.OutputSchemaOf(MemoryBatchResponse{})
+}
+
+func (t *MemoryWriteBatchTool) Examples() []tools.Example {
+	return []tools.Example{
+		{
+			Description: "Store two memories, keeping whichever succeed",
+			Arguments:   json.RawMessage(`{"memories": [{"name": "api-style", "content": "Handlers return JSON errors", "category": "conventions", "tags": ["api"]}, {"name": "db-choice", "content": "SQLite for the index", "category": "decisions"}], "mode": "best_effort"}`),
+			Result:      json.RawMessage(`{"success": true, "mode": "best_effort", "written": 2, "failed": 0, "results": [{"success": true, "id": "...", "name": "api-style", "path": "memory://conventions/api-style"}, {"success": true, "id": "...", "name": "db-choice", "path": "memory://decisions/db-choice"}]}`),
+		},
+	}
+}
+
+func (t *MemoryWriteBatchTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	plan, err := planBatch(input)
+	if err != nil {
+		return nil, err
+	}
+
+	created, errs, err := t.store.CreateBatch(plan.entries, plan.atomic)
 	if err != nil {
 		return nil, err
 	}
 
-	return map[string]interface{}{
-		"success": true,
-		"id":      memory.ID,
-		"name":    memory.Name,
-		"path":    fmt.Sprintf("memory://%s/%s", req.Category, req.Name),
-		"created": memory.CreatedAt,
+	results := plan.results
+	succeeded := 0
+	for j, i := range plan.positions {
+		if errs[j] != nil {
+			results[i] = MemoryBatchResult{Name: plan.entries[j].Name, Error: errs[j].Error()}
+			continue
+		}
+		succeeded++
+		results[i] = MemoryBatchResult{
+			Success: true,
+			ID:      created[j].ID,
+			Name:    created[j].Name,
+			Path:    memoryPath(created[j].Category, created[j].Name),
+			Created: &created[j].CreatedAt,
+		}
+	}
+
+	return &MemoryBatchResponse{
+		Success: succeeded == len(results),
+		Mode:    plan.mode,
+		Written: succeeded,
+		Failed:  len(results) - succeeded,
+		Results: results,
 	}, nil
 }
 
+// batchPlan is a validated memory_write_batch request. entries holds the
+// valid memories and positions their index in the request; results already
+// records the entries rejected before reaching the store.
+type batchPlan struct {
+	mode      string
+	atomic    bool
+	entries   []NewMemory
+	positions []int
+	results   []MemoryBatchResult
+}
+
+func planBatch(input json.RawMessage) (*batchPlan, error) {
+	var req struct {
+		Memories []struct {
+			Name     string   `json:"name"`
+			Content  string   `json:"content"`
+			Category string   `json:"category"`
+			Tags     []string `json:"tags"`
+		} `json:"memories"`
+		Mode string `json:"mode"`
+	}
+	if err := json.Unmarshal(input, &req); err != nil {
+		return nil, err
+	}
+
+	if len(req.Memories) == 0 {
+		return nil, fmt.Errorf("memories must not be empty")
+	}
+	if len(req.Memories) > maxBatchMemories {
+		return nil, fmt.Errorf("too many memories: %d (max %d)", len(req.Memories), maxBatchMemories)
+	}
+
+	if req.Mode == "" {
+		req.Mode = "atomic"
+	}
+	if req.Mode != "atomic" && req.Mode != "best_effort" {
+		return nil, fmt.Errorf("invalid mode %q: expected atomic or best_effort", req.Mode)
+	}
+	plan := &batchPlan{
+		mode:    req.Mode,
+		atomic:  req.Mode == "atomic",
+		results: make([]MemoryBatchResult, len(req.Memories)),
+	}
+
+	// Invalid entries never reach the store: in atomic mode they fail the
+	// call up front, in best_effort mode they are reported and skipped.
+	for i, m := range req.Memories {
+		var problem string
+		switch {
+		case m.Name == "":
+			problem = "memory name is required"
+		case m.Content == "":
+			problem = "memory content is required"
+		}
+		if problem != "" {
+			if plan.atomic {
+				return nil, fmt.Errorf("entry %d: %s", i, problem)
+			}
+			plan.results[i] = MemoryBatchResult{Name: m.Name, Error: problem}
+			continue
+		}
+
+		if m.Category == "" {
+			m.Category = string(CategoryGeneral)
+		}
+		if m.Tags == nil {
+			m.Tags = []string{}
+		}
+		plan.entries = append(plan.entries, NewMemory{
+			ID:       generateID(),
+			Name:     m.Name,
+			Content:  m.Content,
+			Category: Category(m.Category),
+			Tags:     m.Tags,
+		})
+		plan.positions = append(plan.positions, i)
+	}
+
+	return plan, nil
+}
+
 type MemoryReadTool struct {
-	store *MemoryStore
+	store MemoryRepo
 }
 
-func NewMemoryReadTool(store *MemoryStore) *MemoryReadTool {
+func NewMemoryReadTool(store MemoryRepo) *MemoryReadTool {
 	return &MemoryReadTool{store: store}
 }
 
@@ -193,6 +438,10 @@ func (t *MemoryReadTool) Schema() json.RawMessage {
 	}`)
 }
 
+func (t *MemoryReadTool) OutputSchema() json.RawMessage {
+	return tools.OutputSchemaOf(MemoryReadResponse{})
+}
+
 func (t *MemoryReadTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
@@ -217,24 +466,24 @@ func (t *MemoryReadTool) Execute(ctx context.Context, input json.RawMessage) (in
 		mem.Tags = []string{}
 	}
 
-	return map[string]interface{}{
-		"id":            mem.ID,
-		"name":          mem.Name,
-		"content":       mem.Content,
-		"category":      mem.Category,
-		"tags":          mem.Tags,
-		"created_at":    mem.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		"updated_at":    mem.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		"accessed_at":   mem.AccessedAt.Format("2006-01-02T15:04:05Z07:00"),
-		"access_count":  mem.AccessCount,
+	return &MemoryReadResponse{
+		ID:          mem.ID,
+		Name:        mem.Name,
+		Content:     mem.Content,
+		Category:    mem.Category,
+		Tags:        mem.Tags,
+		CreatedAt:   mem.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:   mem.UpdatedAt.Format(time.RFC3339),
+		AccessedAt:  mem.AccessedAt.Format(time.RFC3339),
+		AccessCount: mem.AccessCount,
 	}, nil
 }
 
 type MemoryUpdateTool struct {
-	store *MemoryStore
+	store MemoryRepo
 }
 
-func NewMemoryUpdateTool(store *MemoryStore) *MemoryUpdateTool {
+func NewMemoryUpdateTool(store MemoryRepo) *MemoryUpdateTool {
 	return &MemoryUpdateTool{store: store}
 }
 
@@ -279,13 +528,16 @@ func (t *MemoryUpdateTool) Schema() json.RawMessage {
 			},
 			"category": {
 				"type": "string",
-				"enum": ["architecture", "conventions", "decisions", "context", "general"],
-				"description": "New category (optional - omit to keep current)"
+				"description": "New category, built-in or created with memory_categories (optional - omit to keep current)"
 			},
 			"tags": {
 				"type": "array",
 				"items": {"type": "string"},
-				"description": "New tags (optional - omit to keep current)"
+				"description": "New tags (optional - omit to keep current); the branch tag is kept"
+			},
+			"branch": {
+				"type": "string",
+				"description": "Git branch to tie the memory to (optional - omit to keep current)"
 			},
 			"append": {
 				"type": "boolean",
@@ -296,30 +548,69 @@ func (t *MemoryUpdateTool) Schema() json.RawMessage {
 	}`)
 }
 
+func (t *MemoryUpdateTool) OutputSchema() json.RawMessage {
+	return tools.OutputSchemaOf(MemoryUpdateResponse{})
+}
+
 func (t *MemoryUpdateTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
 	}
-	var req struct {
-		Name     string   `json:"name"`
-		Content  string   `json:"content"`
-		Category string   `json:"category"`
-		Tags     []string `json:"tags"`
-		Append   bool     `json:"append"`
-	}
-	if err := json.Unmarshal(input, &req); err != nil {
+	req, err := parseMemoryUpdate(input)
+	if err != nil {
 		return nil, err
 	}
 
-	if req.Name == "" {
-		return nil, fmt.Errorf("memory name is required")
-	}
-
 	existing, err := t.store.Read(req.Name)
 	if err != nil {
 		return nil, fmt.Errorf("memory not found: %w", err)
 	}
 
+	finalContent, finalCategory, finalTags := req.merge(existing)
+
+	updated, err := t.store.UpdateFull(existing.ID, finalContent, finalCategory, finalTags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update memory: %w", err)
+	}
+
+	if updated.Tags == nil {
+		updated.Tags = []string{}
+	}
+
+	return &MemoryUpdateResponse{
+		Success:   true,
+		ID:        updated.ID,
+		Name:      updated.Name,
+		Content:   updated.Content,
+		Category:  updated.Category,
+		Tags:      updated.Tags,
+		UpdatedAt: updated.UpdatedAt,
+	}, nil
+}
+
+type memoryUpdateRequest struct {
+	Name     string   `json:"name"`
+	Content  string   `json:"content"`
+	Category string   `json:"category"`
+	Tags     []string `json:"tags"`
+	Branch   string   `json:"branch"`
+	Append   bool     `json:"append"`
+}
+
+func parseMemoryUpdate(input json.RawMessage) (memoryUpdateRequest, error) {
+	var req memoryUpdateRequest
+	if err := json.Unmarshal(input, &req); err != nil {
+		return req, err
+	}
+
+	if req.Name == "" {
+		return req, fmt.Errorf("memory name is required")
+	}
+	return req, nil
+}
+
+// merge applies the request to existing; omitted fields keep their value
+func (req memoryUpdateRequest) merge(existing *Memory) (string, Category, []string) {
 	finalContent := existing.Content
 	if req.Content != "" {
 		if req.Append {
@@ -332,6 +623,12 @@ func (t *MemoryUpdateTool) Execute(ctx context.Context, input json.RawMessage) (
 	finalTags := existing.Tags
 	if len(req.Tags) > 0 {
 		finalTags = req.Tags
+		if branch := branchOf(existing.Tags); branch != "" && branchOf(req.Tags) == "" {
+			finalTags = withBranch(finalTags, branch)
+		}
+	}
+	if req.Branch != "" {
+		finalTags = withBranch(finalTags, req.Branch)
 	}
 
 	finalCategory := existing.Category
@@ -339,27 +636,14 @@ func (t *MemoryUpdateTool) Execute(ctx context.Context, input json.RawMessage) (
 		finalCategory = Category(req.Category)
 	}
 
-	updated, err := t.store.UpdateFull(existing.ID, finalContent, finalCategory, finalTags)
-	if err != nil {
-		return nil, fmt.Errorf("failed to update memory: %w", err)
-	}
-
-	return map[string]interface{}{
-		"success":    true,
-		"id":         updated.ID,
-		"name":       updated.Name,
-		"content":    updated.Content,
-		"category":   updated.Category,
-		"tags":       updated.Tags,
-		"updated_at": updated.UpdatedAt,
-	}, nil
+	return finalContent, finalCategory, finalTags
 }
 
 type MemoryListTool struct {
-	store *MemoryStore
+	store MemoryRepo
 }
 
-func NewMemoryListTool(store *MemoryStore) *MemoryListTool {
+func NewMemoryListTool(store MemoryRepo) *MemoryListTool {
 	return &MemoryListTool{store: store}
 }
 
@@ -368,7 +652,7 @@ func (t *MemoryListTool) Name() string {
 }
 
 func (t *MemoryListTool) Description() string {
-	return "List all memories with optional filtering"
+	return "List all memories with optional filtering by category or git branch"
 }
 
 func (t *MemoryListTool) Title() string {
@@ -387,6 +671,10 @@ func (t *MemoryListTool) Schema() json.RawMessage {
 				"type": "string",
 				"description": "Filter by category"
 			},
+			"branch": {
+				"type": "string",
+				"description": "Only memories tied to this git branch"
+			},
 			"limit": {
 				"type": "integer",
 				"description": "Max results to return"
@@ -395,12 +683,17 @@ func (t *MemoryListTool) Schema() json.RawMessage {
 	}`)
 }
 
+func (t *MemoryListTool) OutputSchema() json.RawMessage {
+	return tools.OutputSchemaOf(MemoryListResponse{})
+}
+
 func (t *MemoryListTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
 	}
 	var req struct {
 		Category string `json:"category"`
+		Branch   string `json:"branch"`
 		Limit    int    `json:"limit"`
 	}
 	json.Unmarshal(input, &req)
@@ -409,35 +702,36 @@ func (t *MemoryListTool) Execute(ctx context.Context, input json.RawMessage) (in
 		req.Limit = 50
 	}
 
-	memories, err := t.store.List(categoryFromString(req.Category), req.Limit)
+	memories, err := t.store.List(categoryFromString(req.Category), req.Branch, req.Limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list memories: %w", err)
 	}
 
-	items := make([]map[string]interface{}, 0, len(memories))
+	items := make([]MemorySummary, 0, len(memories))
 	for _, mem := range memories {
-		items = append(items, map[string]interface{}{
-			"id":            mem.ID,
-			"name":          mem.Name,
-			"category":      mem.Category,
-			"preview":       mem.Preview,
-			"created_at":    mem.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-			"accessed_at":   mem.AccessedAt.Format("2006-01-02T15:04:05Z07:00"),
-			"access_count":  mem.AccessCount,
+		items = append(items, MemorySummary{
+			ID:          mem.ID,
+			Name:        mem.Name,
+			Category:    mem.Category,
+			Preview:     mem.Preview,
+			CreatedAt:   mem.CreatedAt.Format(time.RFC3339),
+			AccessedAt:  mem.AccessedAt.Format(time.RFC3339),
+			AccessCount: mem.AccessCount,
+			Branch:      mem.Branch,
 		})
 	}
 
-	return map[string]interface{}{
-		"total":     len(memories),
-		"memories":  items,
+	return &MemoryListResponse{
+		Total:    len(memories),
+		Memories: items,
 	}, nil
 }
 
 type MemorySearchTool struct {
-	store *MemoryStore
+	store MemoryRepo
 }
 
-func NewMemorySearchTool(store *MemoryStore) *MemorySearchTool {
+func NewMemorySearchTool(store MemoryRepo) *MemorySearchTool {
 	return &MemorySearchTool{store: store}
 }
 
@@ -469,6 +763,10 @@ func (t *MemorySearchTool) Schema() json.RawMessage {
 				"type": "string",
 				"description": "Filter by category"
 			},
+			"branch": {
+				"type": "string",
+				"description": "Only memories tied to this git branch"
+			},
 			"limit": {
 				"type": "integer",
 				"description": "Max results"
@@ -478,6 +776,10 @@ func (t *MemorySearchTool) Schema() json.RawMessage {
 	}`)
 }
 
+func (t *MemorySearchTool) OutputSchema() json.RawMessage {
+	return tools.OutputSchemaOf(MemorySearchResponse{})
+}
+
 func (t *MemorySearchTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
@@ -485,6 +787,7 @@ func (t *MemorySearchTool) Execute(ctx context.Context, input json.RawMessage) (
 	var req struct {
 		Query    string `json:"query"`
 		Category string `json:"category"`
+		Branch   string `json:"branch"`
 		Limit    int    `json:"limit"`
 	}
 	if err := json.Unmarshal(input, &req); err != nil {
@@ -499,35 +802,36 @@ func (t *MemorySearchTool) Execute(ctx context.Context, input json.RawMessage) (
 		req.Limit = 50
 	}
 
-	results, err := t.store.Search(req.Query, categoryFromString(req.Category), req.Limit)
+	results, err := t.store.Search(req.Query, categoryFromString(req.Category), req.Branch, req.Limit)
 	if err != nil {
 		return nil, fmt.Errorf("search failed: %w", err)
 	}
 
-	items := make([]map[string]interface{}, 0, len(results))
+	items := make([]MemorySearchHit, 0, len(results))
 	for _, result := range results {
-		items = append(items, map[string]interface{}{
-			"id":         result.ID,
-			"name":       result.Name,
-			"category":   result.Category,
-			"score":      result.Score,
-			"snippet":    result.Snippet,
-			"created_at": result.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		items = append(items, MemorySearchHit{
+			ID:        result.ID,
+			Name:      result.Name,
+			Category:  result.Category,
+			Score:     result.Score,
+			Snippet:   result.Snippet,
+			CreatedAt: result.CreatedAt.Format(time.RFC3339),
+			Branch:    result.Branch,
 		})
 	}
 
-	return map[string]interface{}{
-		"query":   req.Query,
-		"total":   len(results),
-		"results": items,
+	return &MemorySearchResponse{
+		Query:   req.Query,
+		Total:   len(results),
+		Results: items,
 	}, nil
 }
 
 type MemoryDeleteTool struct {
-	store *MemoryStore
+	store MemoryRepo
 }
 
-func NewMemoryDeleteTool(store *MemoryStore) *MemoryDeleteTool {
+func NewMemoryDeleteTool(store MemoryRepo) *MemoryDeleteTool {
 	return &MemoryDeleteTool{store: store}
 }
 
@@ -536,7 +840,7 @@ func (t *MemoryDeleteTool) Name() string {
 }
 
 func (t *MemoryDeleteTool) Description() string {
-	return "Delete a memory by name"
+	return "Delete a memory by name, or with branch every memory tied to a git branch, e.g. once it is merged"
 }
 
 func (t *MemoryDeleteTool) Title() string {
@@ -554,25 +858,39 @@ func (t *MemoryDeleteTool) Schema() json.RawMessage {
 			"name": {
 				"type": "string",
 				"description": "Memory name to delete"
+			},
+			"branch": {
+				"type": "string",
+				"description": "Delete every memory tied to this git branch instead"
 			}
-		},
-		"required": ["name"]
+		}
 	}`)
 }
 
+func (t *MemoryDeleteTool) OutputSchema() json.RawMessage {
+	return tools.OutputSchemaOf(MemoryDeleteResponse{})
+}
+
 func (t *MemoryDeleteTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
 	}
-	var req struct {
-		Name string `json:"name"`
-	}
-	if err := json.Unmarshal(input, &req); err != nil {
+	req, err := parseMemoryDelete(input)
+	if err != nil {
 		return nil, err
 	}
 
-	if req.Name == "" {
-		return nil, fmt.Errorf("memory name is required")
+	if req.Branch != "" {
+		deleted, err := t.store.DeleteBranch(req.Branch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete memories: %w", err)
+		}
+		return &MemoryDeleteResponse{
+			Success:    true,
+			Identifier: BranchTag(req.Branch),
+			DeletedAt:  time.Now().UTC().Format(time.RFC3339),
+			Deleted:    deleted,
+		}, nil
 	}
 
 	identifier, deletedAt, err := t.store.Delete(req.Name)
@@ -584,13 +902,30 @@ func (t *MemoryDeleteTool) Execute(ctx context.Context, input json.RawMessage) (
 		return nil, fmt.Errorf("memory not found")
 	}
 
-	return map[string]interface{}{
-		"success":    true,
-		"identifier": identifier,
-		"deleted_at": deletedAt.Format("2006-01-02T15:04:05Z07:00"),
+	return &MemoryDeleteResponse{
+		Success:    true,
+		Identifier: identifier,
+		DeletedAt:  deletedAt.Format(time.RFC3339),
 	}, nil
 }
 
+type memoryDeleteRequest struct {
+	Name   string `json:"name"`
+	Branch string `json:"branch"`
+}
+
+func parseMemoryDelete(input json.RawMessage) (memoryDeleteRequest, error) {
+	var req memoryDeleteRequest
+	if err := json.Unmarshal(input, &req); err != nil {
+		return req, err
+	}
+
+	if (req.Name == "") == (req.Branch == "") {
+		return req, fmt.Errorf("either memory name or branch is required")
+	}
+	return req, nil
+}
+
 func generateID() string {
 	b := make([]byte, 16)
 	_, err := rand.Read(b)
@@ -600,6 +935,10 @@ func generateID() string {
 	return fmt.Sprintf("%x", b)
 }
 
+func memoryPath(category Category, name string) string {
+	return fmt.Sprintf("memory://%s/%s", category, name)
+}
+
 func categoryFromString(s string) *Category {
 	if s == "" {
 		return nil
@@ -25,6 +25,15 @@ type Memory struct {
 	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
 }
 
+// NewMemory is one entry of a batch write
+type NewMemory struct {
+	ID       string
+	Name     string
+	Content  string
+	Category Category
+	Tags     []string
+}
+
 type SearchResult struct {
 	ID       string    `json:"id"`
 	Name     string    `json:"name"`
@@ -32,6 +41,7 @@ type SearchResult struct {
 	Score    float64   `json:"score"`
 	Snippet  string    `json:"snippet"`
 	CreatedAt time.Time `json:"created_at"`
+	Branch   string    `json:"branch,omitempty"`
 }
 
 type MemoryListItem struct {
@@ -42,4 +52,5 @@ type MemoryListItem struct {
 	CreatedAt time.Time `json:"created_at"`
 	AccessedAt time.Time `json:"accessed_at"`
 	AccessCount int     `json:"access_count"`
+	Branch     string    `json:"branch,omitempty"`
 }
@@ -1 +1,39 @@
 package memory
+
+import "time"
+
+type MemoryWriteResponse struct {
+	Success bool      `json:"success"`
+	ID      string    `json:"id"`
+	Name    string    `json:"name"`
+	Path    string    `json:"path"`
+	Created time.Time `json:"created"`
+}
+
+// MemoryBatchResult is the outcome of one entry of memory_write_batch
+type MemoryBatchResult struct {
+	Success bool       `json:"success"`
+	ID      string     `json:"id,omitempty"`
+	Name    string     `json:"name"`
+	Path    string     `json:"path,omitempty"`
+	Created *time.Time `json:"created,omitempty"`
+	Error   string     `json:"error,omitempty"`
+}
+
+type MemoryBatchResponse struct {
+	Success bool                `json:"success"`
+	Mode    string              `json:"mode"`
+	Written int                 `json:"written"`
+	Failed  int                 `json:"failed"`
+	Results []MemoryBatchResult `json:"results"`
+}
+
+type MemoryUpdateResponse struct {
+	Success   bool      `json:"success"`
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Content   string    `json:"content"`
+	Category  Category  `json:"category"`
+	Tags      []string  `json:"tags"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
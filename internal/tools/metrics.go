@@ -0,0 +1,35 @@
+package tools
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// ExecutionRecorder is told about every tool call run through the registry,
+// including calls that failed, timed out or were cancelled.
+type ExecutionRecorder func(name string, input json.RawMessage, latency time.Duration, err error)
+
+var (
+	executionMu       sync.RWMutex
+	executionRecorder ExecutionRecorder
+)
+
+// SetExecutionRecorder installs the recorder used by RecordExecution; nil
+// disables it.
+func SetExecutionRecorder(r ExecutionRecorder) {
+	executionMu.Lock()
+	executionRecorder = r
+	executionMu.Unlock()
+}
+
+// RecordExecution reports a finished tool call to the installed recorder
+func RecordExecution(name string, input json.RawMessage, latency time.Duration, err error) {
+	executionMu.RLock()
+	r := executionRecorder
+	executionMu.RUnlock()
+
+	if r != nil {
+		r(name, input, latency, err)
+	}
+}
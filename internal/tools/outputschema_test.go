@@ -0,0 +1,42 @@
+package tools
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+type schemaBase struct {
+	ID string `json:"id"`
+}
+
+type schemaSample struct {
+	schemaBase
+	Name    string                 `json:"name"`
+	Count   int64                  `json:"count,omitempty"`
+	Score   float64                `json:"score"`
+	When    time.Time              `json:"when"`
+	Parent  *schemaBase            `json:"parent"`
+	Tags    []string               `json:"tags"`
+	Meta    map[string]interface{} `json:"meta,omitempty"`
+	Skipped string                 `json:"-"`
+	hidden  string
+}
+
+func TestOutputSchemaOf(t *testing.T) {
+	got := string(OutputSchemaOf(schemaSample{}))
+	want := `{"properties":{"count":{"type":"integer"},"id":{"type":"string"},` +
+		`"meta":{"additionalProperties":{},"type":"object"},"name":{"type":"string"},` +
+		`"parent":{"properties":{"id":{"type":"string"}},"required":["id"],"type":"object"},` +
+		`"score":{"type":"number"},"tags":{"items":{"type":"string"},"type":"array"},` +
+		`"when":{"format":"date-time","type":"string"}},` +
+		`"required":["id","name","score","when","tags"],"type":"object"}`
+	if got != want {
+		t.Errorf("schema mismatch\ngot:  %s\nwant: %s", got, want)
+	}
+
+	var schema map[string]interface{}
+	if err := json.Unmarshal(OutputSchemaOf(&schemaSample{}), &schema); err != nil || schema["type"] != "object" {
+		t.Errorf("expected a pointer to give the same object schema, got %v (%v)", schema, err)
+	}
+}
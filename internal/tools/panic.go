@@ -0,0 +1,53 @@
+package tools
+
+import (
+	"fmt"
+	"sync"
+)
+
+const ErrCodeInternalPanic = -32004
+
+// PanicHandler records a recovered panic and returns an identifier for the
+// crash report, or "" if none was written.
+type PanicHandler func(component string, value interface{}, stack []byte) string
+
+var (
+	panicMu      sync.RWMutex
+	panicHandler PanicHandler
+)
+
+// SetPanicHandler installs the handler used by ReportPanic
+func SetPanicHandler(h PanicHandler) {
+	panicMu.Lock()
+	panicHandler = h
+	panicMu.Unlock()
+}
+
+// ReportPanic hands a recovered panic to the installed handler, falling back
+// to the log when none is set.
+func ReportPanic(component string, value interface{}, stack []byte) string {
+	panicMu.RLock()
+	h := panicHandler
+	panicMu.RUnlock()
+
+	if h == nil {
+		log.Error("panic recovered", "component", component, "panic", value, "stack", string(stack))
+		return ""
+	}
+	return h(component, value, stack)
+}
+
+func NewPanicError(component string, value interface{}, crashID string) *ToolError {
+	data := map[string]interface{}{
+		"reason":    "panic",
+		"component": component,
+	}
+	if crashID != "" {
+		data["crash_id"] = crashID
+	}
+	return &ToolError{
+		Code:    ErrCodeInternalPanic,
+		Message: fmt.Sprintf("Internal error in %s: %v", component, value),
+		Data:    data,
+	}
+}
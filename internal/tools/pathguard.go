@@ -0,0 +1,231 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const ErrCodePathDenied = -32003
+
+// DefaultDeniedPaths lists credential and browser-profile locations that tools
+// refuse to touch unless the configuration overrides the list.
+func DefaultDeniedPaths() []string {
+	return []string{
+		"~/.ssh",
+		"~/.aws",
+		"~/.gnupg",
+		"~/.azure",
+		"~/.kube",
+		"~/.docker/config.json",
+		"~/.netrc",
+		"~/.password-store",
+		"~/.config/gcloud",
+		"~/.config/google-chrome",
+		"~/.config/chromium",
+		"~/.config/BraveSoftware",
+		"~/.mozilla",
+		"~/Library/Keychains",
+		"~/Library/Application Support/Google/Chrome",
+		"~/Library/Application Support/Firefox",
+		"~/Library/Application Support/BraveSoftware",
+		"~/AppData/Local/Google/Chrome/User Data",
+		"~/AppData/Roaming/Mozilla/Firefox",
+	}
+}
+
+type PathGuard struct {
+	mu     sync.RWMutex
+	denied []string
+	// allowed are exceptions to denied; see Match
+	allowed []string
+}
+
+var defaultGuard = NewPathGuard(DefaultDeniedPaths())
+
+func NewPathGuard(paths []string) *PathGuard {
+	g := &PathGuard{}
+	g.SetDenied(paths)
+	return g
+}
+
+// SetDenied replaces the denylist. Entries may start with ~ for the home dir.
+func (g *PathGuard) SetDenied(paths []string) {
+	denied := expandPaths(paths)
+	g.mu.Lock()
+	g.denied = denied
+	g.mu.Unlock()
+}
+
+// SetAllowed replaces the exceptions to the denylist, written as its
+// entries are
+func (g *PathGuard) SetAllowed(paths []string) {
+	allowed := expandPaths(paths)
+	g.mu.Lock()
+	g.allowed = allowed
+	g.mu.Unlock()
+}
+
+// expandPaths makes paths absolute, expanding a leading ~ to the home dir
+func expandPaths(paths []string) []string {
+	home, _ := os.UserHomeDir()
+
+	expanded := make([]string, 0, len(paths))
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		if home != "" && (p == "~" || strings.HasPrefix(p, "~/")) {
+			p = filepath.Join(home, strings.TrimPrefix(p, "~"))
+		}
+		if abs, err := filepath.Abs(p); err == nil {
+			p = abs
+		}
+		expanded = append(expanded, filepath.Clean(p))
+	}
+	return expanded
+}
+
+// Match returns the denylist entry covering path, or "" if the path is allowed.
+// Symlinks are resolved so a link into a denied directory is also refused.
+// A path under both a denied and an allowed entry goes by the longer of the
+// two, so allowing ~/.kube lifts that entry but allowing ~ does not.
+func (g *PathGuard) Match(path string) string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	if len(g.denied) == 0 {
+		return ""
+	}
+
+	candidates := []string{path}
+	if abs, err := filepath.Abs(path); err == nil {
+		candidates[0] = abs
+	}
+	if resolved, err := filepath.EvalSymlinks(candidates[0]); err == nil && resolved != candidates[0] {
+		candidates = append(candidates, resolved)
+	}
+
+	for _, candidate := range candidates {
+		for _, entry := range g.denied {
+			if pathWithin(candidate, entry) && !g.allows(candidate, entry) {
+				return entry
+			}
+		}
+	}
+	return ""
+}
+
+// allows reports whether an allowed entry at least as long as the denied
+// entry covers path
+func (g *PathGuard) allows(path, denied string) bool {
+	for _, entry := range g.allowed {
+		if len(entry) >= len(denied) && pathWithin(path, entry) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns a structured tool error when path is denied
+func (g *PathGuard) Check(path string) error {
+	if entry := g.Match(path); entry != "" {
+		return NewPathDeniedError(path, entry)
+	}
+	return nil
+}
+
+func pathWithin(path, root string) bool {
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		path = strings.ToLower(path)
+		root = strings.ToLower(root)
+	}
+	if path == root {
+		return true
+	}
+	return strings.HasPrefix(path, strings.TrimSuffix(root, string(filepath.Separator))+string(filepath.Separator))
+}
+
+// SetDeniedPaths configures the denylist shared by all file and search tools
+func SetDeniedPaths(paths []string) {
+	defaultGuard.SetDenied(paths)
+}
+
+// SetAllowedPaths configures the exceptions to the shared denylist
+func SetAllowedPaths(paths []string) {
+	defaultGuard.SetAllowed(paths)
+}
+
+// CheckPath validates path against the shared denylist
+func CheckPath(path string) error {
+	return defaultGuard.Check(path)
+}
+
+// IsPathDenied reports whether path is covered by the shared denylist; walkers
+// use it to silently skip denied entries below an allowed root.
+func IsPathDenied(path string) bool {
+	return defaultGuard.Match(path) != ""
+}
+
+// pathArgs are the argument names tools take paths under, as a string or a
+// list of strings
+var pathArgs = map[string]bool{
+	"path": true, "paths": true, "file": true, "source": true, "destination": true,
+	"root": true, "roots": true, "project_root": true,
+}
+
+// CheckPathArgs validates every path argument of a tool request, at any
+// depth, against the shared denylist. Tools that resolve a path against
+// another argument or walk a directory still check what they reach.
+func CheckPathArgs(input json.RawMessage) error {
+	var args interface{}
+	if len(input) == 0 || json.Unmarshal(input, &args) != nil {
+		// the tool reports a malformed request itself
+		return nil
+	}
+	return checkPathValue(args, "")
+}
+
+func checkPathValue(v interface{}, key string) error {
+	switch val := v.(type) {
+	case string:
+		if pathArgs[key] && val != "" {
+			return CheckPath(val)
+		}
+	case []interface{}:
+		for _, item := range val {
+			if err := checkPathValue(item, key); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if err := checkPathValue(val[k], k); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func NewPathDeniedError(path, rule string) *ToolError {
+	return &ToolError{
+		Code:    ErrCodePathDenied,
+		Message: fmt.Sprintf("Access denied: %s is within sensitive path %s", path, rule),
+		Data: map[string]interface{}{
+			"reason": "sensitive_path",
+			"path":   path,
+			"rule":   rule,
+		},
+	}
+}
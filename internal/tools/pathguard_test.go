@@ -0,0 +1,31 @@
+package tools
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestPathGuardAllowed(t *testing.T) {
+	home := t.TempDir()
+	ssh := filepath.Join(home, ".ssh")
+	kube := filepath.Join(home, ".kube")
+
+	g := NewPathGuard([]string{ssh, kube})
+	g.SetAllowed([]string{home, kube, filepath.Join(ssh, "known_hosts")})
+
+	for path, want := range map[string]string{
+		filepath.Join(ssh, "id_rsa"):      ssh,
+		filepath.Join(ssh, "known_hosts"): "",
+		filepath.Join(kube, "config"):     "",
+		filepath.Join(home, "notes.txt"):  "",
+	} {
+		if got := g.Match(path); got != want {
+			t.Errorf("Match(%s) = %q, want %q", path, got, want)
+		}
+	}
+
+	g.SetAllowed(nil)
+	if got := g.Match(filepath.Join(kube, "config")); got != kube {
+		t.Errorf("without exceptions, Match = %q, want %q", got, kube)
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"runtime/debug"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -25,54 +28,236 @@ type AnnotatedTool interface {
 	Annotations() map[string]bool
 }
 
+// Entry is a registered tool with the names it answers to: its own, the
+// qualified one of its namespace and any aliases
+type Entry struct {
+	Name      string   `json:"name"`
+	Namespace string   `json:"namespace,omitempty"`
+	Qualified string   `json:"qualified,omitempty"`
+	Aliases   []string `json:"aliases,omitempty"`
+	Tool      Tool     `json:"-"`
+}
+
+// Listed is the name tools/list advertises: the qualified name when the
+// registry is namespaced and the tool has one, its own name otherwise
+func (e Entry) Listed(namespaced bool) string {
+	if namespaced && e.Qualified != "" {
+		return e.Qualified
+	}
+	return e.Name
+}
+
 type Registry struct {
-	mu    sync.RWMutex
-	tools map[string]Tool
+	mu      sync.RWMutex
+	tools   map[string]Tool
+	entries map[string]*Entry
+	// aliases maps qualified names and aliases to tool names
+	aliases    map[string]string
+	namespaced bool
+	// defaults are the per-project arguments of each tool, see SetDefaults
+	defaults map[string]map[string]json.RawMessage
 }
 
 func NewRegistry() *Registry {
 	return &Registry{
-		tools: make(map[string]Tool),
+		tools:   make(map[string]Tool),
+		entries: make(map[string]*Entry),
+		aliases: make(map[string]string),
 	}
 }
 
+// Register adds tool under its name. Every name a registry answers to is
+// unique: one already taken, as a tool name or an alias, is an error.
 func (r *Registry) Register(tool Tool) error {
+	return r.RegisterIn("", tool)
+}
+
+// RegisterIn adds tool to namespace, where it also answers to
+// namespace/<name>, its name stripped of a namespace prefix: memory_read
+// in "memory" is memory/read, read in "files" is files/read.
+func (r *Registry) RegisterIn(namespace string, tool Tool) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	name := tool.Name()
-	if _, exists := r.tools[name]; exists {
-		return fmt.Errorf("tool already registered: %s", name)
+	if name == "" {
+		return fmt.Errorf("tool name cannot be empty")
+	}
+	if err := r.checkFree(name); err != nil {
+		return err
+	}
+
+	entry := &Entry{Name: name, Namespace: namespace, Tool: tool}
+	if namespace != "" {
+		entry.Qualified = namespace + "/" + localName(namespace, name)
+		if err := r.checkFree(entry.Qualified); err != nil {
+			return err
+		}
+		r.aliases[entry.Qualified] = name
 	}
 
 	r.tools[name] = tool
+	r.entries[name] = entry
+	return nil
+}
+
+// Alias makes the registered tool name also answer to alias, so a renamed
+// tool keeps working under its old name
+func (r *Registry) Alias(alias, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.entries[name]
+	if !ok {
+		return fmt.Errorf("tool not found: %s", name)
+	}
+	if err := r.checkFree(alias); err != nil {
+		return err
+	}
+	r.aliases[alias] = name
+	entry.Aliases = append(entry.Aliases, alias)
 	return nil
 }
 
+// checkFree fails when name is already a tool name or alias
+func (r *Registry) checkFree(name string) error {
+	if _, exists := r.tools[name]; exists {
+		return fmt.Errorf("tool already registered: %s", name)
+	}
+	if owner, exists := r.aliases[name]; exists {
+		return fmt.Errorf("tool name %s already used as an alias of %s", name, owner)
+	}
+	return nil
+}
+
+// localName strips the namespace, or its singular, from the front of name:
+// memory_read in "memory" and doc_read in "docs" are both read
+func localName(namespace, name string) string {
+	for _, prefix := range []string{namespace + "_", strings.TrimSuffix(namespace, "s") + "_"} {
+		if local := strings.TrimPrefix(name, prefix); local != name && local != "" {
+			return local
+		}
+	}
+	return name
+}
+
+// SetNamespaced makes tools/list advertise qualified names. Tools answer
+// to both names either way.
+func (r *Registry) SetNamespaced(namespaced bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.namespaced = namespaced
+}
+
+func (r *Registry) Namespaced() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.namespaced
+}
+
+// Resolve returns the tool name that name, a tool name, qualified name or
+// alias, stands for
+func (r *Registry) Resolve(name string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if _, ok := r.tools[name]; ok {
+		return name, true
+	}
+	target, ok := r.aliases[name]
+	return target, ok
+}
+
 func (r *Registry) Get(name string) (Tool, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
+	if target, ok := r.aliases[name]; ok {
+		name = target
+	}
 	tool, ok := r.tools[name]
 	return tool, ok
 }
 
+// Dump returns every registered tool with its names, sorted by name
+func (r *Registry) Dump() []Entry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries := make([]Entry, 0, len(r.entries))
+	for _, entry := range r.entries {
+		e := *entry
+		e.Aliases = append([]string(nil), entry.Aliases...)
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries
+}
+
 func (r *Registry) Execute(ctx context.Context, name string, input json.RawMessage) (result interface{}, err error) {
 	tool, ok := r.Get(name)
 	if !ok {
 		return nil, fmt.Errorf("tool not found: %s", name)
 	}
 
+	input = r.withDefaults(tool.Name(), input)
+	defer func() {
+		reportViolation(ctx, name, err)
+	}()
+
+	session := SessionFrom(ctx)
+	mutating := IsMutating(tool)
+	if session.ReadOnly && mutating {
+		return nil, NewReadOnlyError(name)
+	}
+	if mutating && !session.DryRun {
+		// deferred first so that it sees the error of a recovered panic
+		defer func() {
+			recorded := name
+			if canonical, ok := r.Resolve(name); ok {
+				recorded = canonical
+			}
+			RecordWrite(ctx, recorded, input, err)
+		}()
+	}
+	if !session.DryRun || !mutating {
+		defer func() {
+			recorded := name
+			if canonical, ok := r.Resolve(name); ok {
+				recorded = canonical
+			}
+			RecordActivity(ctx, recorded, input, result, err)
+		}()
+	}
+
 	defer func() {
 		if p := recover(); p != nil {
-			err = fmt.Errorf("panic in tool %s: %v", name, p)
+			component := "tool " + name
+			crashID := ReportPanic(component, p, debug.Stack())
+			result, err = nil, NewPanicError(component, p, crashID)
 		}
 	}()
 
+	if err := CheckPathArgs(input); err != nil {
+		return nil, err
+	}
+	if session.DryRun && mutating {
+		return dryRun(ctx, name, tool, input)
+	}
 	return tool.Execute(ctx, input)
 }
 
-func (r *Registry) ExecuteWithTimeout(name string, input json.RawMessage, timeout time.Duration) (interface{}, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+// ExecuteWithTimeout runs the named tool, giving up when timeout elapses or
+// parent is cancelled.
+func (r *Registry) ExecuteWithTimeout(parent context.Context, name string, input json.RawMessage, timeout time.Duration) (value interface{}, err error) {
+	start := time.Now()
+	defer func() {
+		recorded := name
+		if canonical, ok := r.Resolve(name); ok {
+			recorded = canonical
+		}
+		RecordExecution(recorded, input, time.Since(start), err)
+	}()
+
+	ctx, cancel := context.WithTimeout(parent, timeout)
 	defer cancel()
 
 	type result struct {
@@ -95,6 +280,9 @@ func (r *Registry) ExecuteWithTimeout(name string, input json.RawMessage, timeou
 	case res := <-resultChan:
 		return res.value, res.err
 	case <-ctx.Done():
+		if parent.Err() != nil {
+			return nil, fmt.Errorf("tool execution cancelled: %w", parent.Err())
+		}
 		return nil, fmt.Errorf("tool execution timeout after %v", timeout)
 	}
 }
@@ -0,0 +1,135 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type namedTool struct{ name string }
+
+func (t *namedTool) Name() string            { return t.name }
+func (t *namedTool) Description() string     { return t.name }
+func (t *namedTool) Schema() json.RawMessage { return json.RawMessage(`{"type":"object"}`) }
+func (t *namedTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	return t.name, nil
+}
+
+func TestRegistryNames(t *testing.T) {
+	r := NewRegistry()
+	for _, reg := range []struct{ namespace, name string }{
+		{"files", "read"},
+		{"memory", "memory_read"},
+		{"docs", "doc_read"},
+		{"", "health"},
+	} {
+		if err := r.RegisterIn(reg.namespace, &namedTool{reg.name}); err != nil {
+			t.Fatalf("register %s: %v", reg.name, err)
+		}
+	}
+	if err := r.Alias("read_file", "read"); err != nil {
+		t.Fatalf("alias: %v", err)
+	}
+
+	for name, want := range map[string]string{
+		"read": "read", "files/read": "read", "read_file": "read",
+		"memory/read": "memory_read", "docs/read": "doc_read", "health": "health",
+	} {
+		result, err := r.Execute(context.Background(), name, nil)
+		if err != nil || result != want {
+			t.Errorf("%s: expected %s, got %v (%v)", name, want, result, err)
+		}
+	}
+
+	for _, collision := range []func() error{
+		func() error { return r.Register(&namedTool{"read"}) },
+		func() error { return r.Register(&namedTool{"files/read"}) },
+		func() error { return r.Register(&namedTool{"read_file"}) },
+		func() error { return r.RegisterIn("files", &namedTool{"files_read"}) },
+		func() error { return r.Alias("health", "read") },
+		func() error { return r.Alias("memory/read", "read") },
+	} {
+		if err := collision(); err == nil || !strings.Contains(err.Error(), "already") {
+			t.Errorf("expected a name collision error, got %v", err)
+		}
+	}
+	if err := r.Alias("old", "missing"); err == nil {
+		t.Error("expected an alias of a missing tool to fail")
+	}
+
+	var listed []string
+	for _, e := range r.Dump() {
+		listed = append(listed, e.Listed(true))
+	}
+	if got := strings.Join(listed, " "); got != "docs/read health memory/read files/read" {
+		t.Errorf("expected namespaced names sorted by tool name, got %s", got)
+	}
+	if e := r.Dump()[3]; e.Listed(false) != "read" || len(e.Aliases) != 1 {
+		t.Errorf("unexpected entry %+v", e)
+	}
+}
+
+type legacyTool struct {
+	release chan struct{}
+}
+
+func (t *legacyTool) Name() string            { return "legacy" }
+func (t *legacyTool) Description() string     { return "legacy" }
+func (t *legacyTool) Schema() json.RawMessage { return json.RawMessage(`{"type":"object"}`) }
+func (t *legacyTool) Execute(input json.RawMessage) (interface{}, error) {
+	<-t.release
+	return string(input), nil
+}
+
+func TestAdaptLegacyTool(t *testing.T) {
+	legacy := &legacyTool{release: make(chan struct{})}
+	r := NewRegistry()
+	if err := r.Register(Adapt(legacy, Metadata{Title: "Legacy", Annotations: ReadOnlyAnnotations()})); err != nil {
+		t.Fatal(err)
+	}
+	tool, _ := r.Get("legacy")
+	if IsMutating(tool) || tool.(AnnotatedTool).Title() != "Legacy" {
+		t.Errorf("expected the adapted tool to keep its metadata")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := r.Execute(ctx, "legacy", nil); err != context.Canceled {
+		t.Errorf("expected a cancelled call not to run, got %v", err)
+	}
+
+	close(legacy.release)
+	result, err := r.Execute(context.Background(), "legacy", json.RawMessage(`{}`))
+	if err != nil || result != "{}" {
+		t.Errorf("expected the legacy result, got %v (%v)", result, err)
+	}
+}
+
+func TestRegistryDeniesPathArgs(t *testing.T) {
+	secrets := t.TempDir()
+	SetDeniedPaths([]string{secrets})
+	defer SetDeniedPaths(DefaultDeniedPaths())
+
+	r := NewRegistry()
+	r.Register(&namedTool{"read"})
+	for _, input := range []string{
+		`{"path": "` + secrets + `/id_rsa"}`,
+		`{"source": "` + secrets + `/id_rsa", "destination": "/tmp/k"}`,
+		`{"paths": ["README.md", "` + secrets + `"]}`,
+		`{"calls": [{"name": "read", "arguments": {"path": "` + secrets + `/id_rsa"}}]}`,
+	} {
+		_, err := r.Execute(context.Background(), "read", json.RawMessage(input))
+		var toolErr *ToolError
+		if !errors.As(err, &toolErr) || toolErr.Code != ErrCodePathDenied {
+			t.Errorf("%s: expected a path denied error, got %v", input, err)
+		}
+	}
+
+	for _, input := range []string{`{"path": "README.md"}`, `{"content": "` + secrets + `"}`, `not json`} {
+		if _, err := r.Execute(context.Background(), "read", json.RawMessage(input)); err != nil {
+			t.Errorf("%s: unexpected error %v", input, err)
+		}
+	}
+}
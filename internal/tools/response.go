@@ -0,0 +1,95 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alucardeht/may-la-mcp/internal/intel"
+)
+
+// Default maximum lengths of the text responses, in characters
+const (
+	defaultCompactLength  = 2000
+	defaultDetailedLength = 5000
+)
+
+// ResponseOptions are the response_mode and max_length parameters of tools
+// with verbose results. Without either the tool returns its usual JSON;
+// with them its result is rendered as text by intel's formatter.
+type ResponseOptions struct {
+	ResponseMode string `json:"response_mode,omitempty"`
+	MaxLength    int    `json:"max_length,omitempty"`
+}
+
+// TextResponse is a tool result rendered as text in a response mode
+type TextResponse struct {
+	ResponseMode string                 `json:"response_mode"`
+	Summary      string                 `json:"summary,omitempty"`
+	Content      string                 `json:"content"`
+	Indicators   []string               `json:"indicators,omitempty"`
+	Metadata     map[string]interface{} `json:"metadata,omitempty"`
+	Truncated    bool                   `json:"truncated,omitempty"`
+}
+
+// Enabled reports whether the caller asked for a text response
+func (o ResponseOptions) Enabled() bool {
+	return o.ResponseMode != "" || o.MaxLength > 0
+}
+
+func (o ResponseOptions) Validate() error {
+	if o.MaxLength < 0 {
+		return fmt.Errorf("max_length must not be negative")
+	}
+	if o.ResponseMode == "" {
+		return nil
+	}
+	_, err := intel.ParseResponseMode(o.ResponseMode)
+	return err
+}
+
+// Format renders content in the requested mode. compact adds a summary and
+// cuts the content to max_length (default 2000); detailed numbers the lines
+// and adds counts and complexity, cut to max_length (default 5000); raw
+// leaves the content as is, cut only when max_length is set. max_length
+// alone selects compact.
+func (o ResponseOptions) Format(content string, metadata map[string]interface{}) *TextResponse {
+	mode := intel.ResponseModeCompact
+	if o.ResponseMode != "" {
+		if parsed, err := intel.ParseResponseMode(o.ResponseMode); err == nil {
+			mode = parsed
+		}
+	}
+
+	maxLength := o.MaxLength
+	if maxLength <= 0 {
+		switch mode {
+		case intel.ResponseModeCompact:
+			maxLength = defaultCompactLength
+		case intel.ResponseModeDetailed:
+			maxLength = defaultDetailedLength
+		}
+	}
+
+	builder := intel.NewFormatterBuilder().WithMode(mode)
+	if maxLength > 0 {
+		builder = builder.WithMaxLength(maxLength)
+	}
+	if mode == intel.ResponseModeDetailed {
+		builder = builder.WithLineLimits(strings.Count(content, "\n")+1, 0)
+	}
+	formatter := builder.Build()
+	formatted := formatter.Format(content, metadata)
+
+	resp := &TextResponse{
+		ResponseMode: strings.ToLower(string(mode)),
+		Summary:      formatted.Summary,
+		Content:      formatted.Content,
+		Indicators:   formatted.Indicators,
+		Metadata:     formatted.Metadata,
+	}
+	if maxLength > 0 && len(resp.Content) > maxLength {
+		resp.Content = intel.Truncate(resp.Content, maxLength, intel.TruncateModeSmart)
+	}
+	resp.Truncated = maxLength > 0 && (len(content) > maxLength || len(formatted.Content) > maxLength)
+	return resp
+}
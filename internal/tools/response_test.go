@@ -0,0 +1,45 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResponseOptionsFormat(t *testing.T) {
+	content := strings.Repeat("func main() { println(\"hello\") }\n", 100)
+
+	if (ResponseOptions{}).Enabled() {
+		t.Error("expected no text response without options")
+	}
+	if err := (ResponseOptions{ResponseMode: "verbose"}).Validate(); err == nil {
+		t.Error("expected error for an unknown response_mode")
+	}
+	if err := (ResponseOptions{MaxLength: -1}).Validate(); err == nil {
+		t.Error("expected error for a negative max_length")
+	}
+
+	compact := ResponseOptions{MaxLength: 200}.Format(content, map[string]interface{}{"match_count": 100})
+	if compact.ResponseMode != "compact" || !compact.Truncated || len(compact.Content) > 210 {
+		t.Errorf("expected max_length alone to give truncated compact text, got %s (%d chars, truncated %v)",
+			compact.ResponseMode, len(compact.Content), compact.Truncated)
+	}
+	if compact.Summary == "" || !strings.Contains(strings.Join(compact.Indicators, " "), "Matches: 100") {
+		t.Errorf("expected a summary and indicators, got %q %v", compact.Summary, compact.Indicators)
+	}
+
+	detailed := ResponseOptions{ResponseMode: "DETAILED"}.Format("a\nb\nc", nil)
+	if detailed.ResponseMode != "detailed" || !strings.Contains(detailed.Content, "   3 | c") || detailed.Truncated {
+		t.Errorf("expected every line numbered, got %q", detailed.Content)
+	}
+	if detailed.Metadata["line_count"] != 3 {
+		t.Errorf("expected detailed metadata, got %v", detailed.Metadata)
+	}
+
+	raw := ResponseOptions{ResponseMode: "raw"}.Format(content, nil)
+	if raw.Content != content || raw.Truncated {
+		t.Error("expected raw text to be left as is")
+	}
+	if raw := (ResponseOptions{ResponseMode: "raw", MaxLength: 100}).Format(content, nil); !raw.Truncated || len(raw.Content) >= len(content) {
+		t.Error("expected raw text to be cut to max_length")
+	}
+}
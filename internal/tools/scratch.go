@@ -0,0 +1,387 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	MaxScratchNotes    = 100
+	MaxScratchNoteSize = 64 * 1024
+
+	scratchPreviewLength = 80
+)
+
+// Scratchpad holds the scratch notes of one session: planning notes kept
+// in memory only, apart from the memory store, and dropped with the session.
+type Scratchpad struct {
+	mu    sync.Mutex
+	notes map[string]*ScratchNote
+}
+
+type ScratchNote struct {
+	Name    string    `json:"name"`
+	Content string    `json:"content"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+}
+
+func NewScratchpad() *Scratchpad {
+	return &Scratchpad{notes: make(map[string]*ScratchNote)}
+}
+
+type scratchpadKey struct{}
+
+func WithScratchpad(ctx context.Context, pad *Scratchpad) context.Context {
+	return context.WithValue(ctx, scratchpadKey{}, pad)
+}
+
+// ScratchpadFrom returns the scratchpad of the session ctx belongs to, or
+// nil outside of a session
+func ScratchpadFrom(ctx context.Context) *Scratchpad {
+	pad, _ := ctx.Value(scratchpadKey{}).(*Scratchpad)
+	return pad
+}
+
+// Write sets the content of the named note, or adds to its end with
+// appendContent, creating the note if needed
+func (p *Scratchpad) Write(name, content string, appendContent bool) (ScratchNote, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	note, exists := p.notes[name]
+	if !exists {
+		if len(p.notes) >= MaxScratchNotes {
+			return ScratchNote{}, fmt.Errorf("scratchpad is full: %d notes (max %d)", len(p.notes), MaxScratchNotes)
+		}
+		note = &ScratchNote{Name: name, Created: now}
+	}
+
+	if appendContent {
+		content = note.Content + content
+	}
+	if len(content) > MaxScratchNoteSize {
+		return ScratchNote{}, fmt.Errorf("note %s too large: %d bytes (max %d)", name, len(content), MaxScratchNoteSize)
+	}
+
+	note.Content = content
+	note.Updated = now
+	p.notes[name] = note
+	return *note, nil
+}
+
+func (p *Scratchpad) Read(name string) (ScratchNote, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	note, ok := p.notes[name]
+	if !ok {
+		return ScratchNote{}, false
+	}
+	return *note, true
+}
+
+// List returns the notes ordered by name
+func (p *Scratchpad) List() []ScratchNote {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	notes := make([]ScratchNote, 0, len(p.notes))
+	for _, note := range p.notes {
+		notes = append(notes, *note)
+	}
+	sort.Slice(notes, func(i, j int) bool { return notes[i].Name < notes[j].Name })
+	return notes
+}
+
+func (p *Scratchpad) Delete(name string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, ok := p.notes[name]
+	delete(p.notes, name)
+	return ok
+}
+
+func (p *Scratchpad) Clear() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.notes = make(map[string]*ScratchNote)
+}
+
+// sessionScoped is implemented by tools that only change state private to
+// the session, such as its scratchpad; read-only and dry-run sessions run
+// them as they are.
+type sessionScoped interface {
+	sessionScoped()
+}
+
+// NewScratchTools returns the tools working on the scratchpad of the
+// calling session
+func NewScratchTools() []Tool {
+	return []Tool{&ScratchWriteTool{}, &ScratchReadTool{}, &ScratchListTool{}}
+}
+
+func sessionScratchpad(ctx context.Context) (*Scratchpad, error) {
+	pad := ScratchpadFrom(ctx)
+	if pad == nil {
+		return nil, fmt.Errorf("no scratchpad: scratch notes need a client session")
+	}
+	return pad, nil
+}
+
+type ScratchWriteRequest struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+	Append  bool   `json:"append,omitempty"`
+	Delete  bool   `json:"delete,omitempty"`
+}
+
+type ScratchWriteResponse struct {
+	Name    string    `json:"name"`
+	Size    int       `json:"size"`
+	Created bool      `json:"created,omitempty"`
+	Deleted bool      `json:"deleted,omitempty"`
+	Updated time.Time `json:"updated,omitempty"`
+}
+
+type ScratchWriteTool struct{}
+
+func (t *ScratchWriteTool) sessionScoped() {}
+
+func (t *ScratchWriteTool) Name() string {
+	return "scratch_write"
+}
+
+func (t *ScratchWriteTool) Description() string {
+	return `Write a scratch note for this session: plans, todo lists, intermediate findings.
+
+Scratch notes live in memory and are dropped when the session ends; use memory_write for anything worth keeping across sessions. Writing replaces the note's content, append adds to its end instead, and delete removes it. A session holds up to 100 notes of 64KB each.`
+}
+
+func (t *ScratchWriteTool) Title() string {
+	return "Write Scratch Note"
+}
+
+func (t *ScratchWriteTool) Annotations() map[string]bool {
+	return NonIdempotentWriteAnnotations()
+}
+
+func (t *ScratchWriteTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"name": {
+				"type": "string",
+				"description": "Note name"
+			},
+			"content": {
+				"type": "string",
+				"description": "Note content"
+			},
+			"append": {
+				"type": "boolean",
+				"description": "Add content to the end of the note instead of replacing it (default: false)"
+			},
+			"delete": {
+				"type": "boolean",
+				"description": "Remove the note; content is ignored (default: false)"
+			}
+		},
+		"required": ["name"]
+	}`)
+}
+
+func (t *ScratchWriteTool) OutputSchema() json.RawMessage {
+	return OutputSchemaOf(ScratchWriteResponse{})
+}
+
+func (t *ScratchWriteTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var req ScratchWriteRequest
+	if err := json.Unmarshal(input, &req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+
+	pad, err := sessionScratchpad(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Delete {
+		if !pad.Delete(req.Name) {
+			return nil, fmt.Errorf("scratch note not found: %s", req.Name)
+		}
+		return &ScratchWriteResponse{Name: req.Name, Deleted: true}, nil
+	}
+
+	_, existed := pad.Read(req.Name)
+	note, err := pad.Write(req.Name, req.Content, req.Append)
+	if err != nil {
+		return nil, err
+	}
+	return &ScratchWriteResponse{
+		Name:    note.Name,
+		Size:    len(note.Content),
+		Created: !existed,
+		Updated: note.Updated,
+	}, nil
+}
+
+type ScratchReadRequest struct {
+	Name string `json:"name"`
+}
+
+type ScratchReadTool struct{}
+
+func (t *ScratchReadTool) Name() string {
+	return "scratch_read"
+}
+
+func (t *ScratchReadTool) Description() string {
+	return `Read a scratch note written earlier in this session with scratch_write.`
+}
+
+func (t *ScratchReadTool) Title() string {
+	return "Read Scratch Note"
+}
+
+func (t *ScratchReadTool) Annotations() map[string]bool {
+	return ReadOnlyAnnotations()
+}
+
+func (t *ScratchReadTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"name": {
+				"type": "string",
+				"description": "Note name"
+			}
+		},
+		"required": ["name"]
+	}`)
+}
+
+func (t *ScratchReadTool) OutputSchema() json.RawMessage {
+	return OutputSchemaOf(ScratchNote{})
+}
+
+func (t *ScratchReadTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var req ScratchReadRequest
+	if err := json.Unmarshal(input, &req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+
+	pad, err := sessionScratchpad(ctx)
+	if err != nil {
+		return nil, err
+	}
+	note, ok := pad.Read(req.Name)
+	if !ok {
+		return nil, fmt.Errorf("scratch note not found: %s", req.Name)
+	}
+	return &note, nil
+}
+
+type ScratchNoteInfo struct {
+	Name    string    `json:"name"`
+	Size    int       `json:"size"`
+	Preview string    `json:"preview"`
+	Updated time.Time `json:"updated"`
+}
+
+type ScratchListResponse struct {
+	Notes []ScratchNoteInfo `json:"notes"`
+	Count int               `json:"count"`
+}
+
+type ScratchListTool struct{}
+
+func (t *ScratchListTool) Name() string {
+	return "scratch_list"
+}
+
+func (t *ScratchListTool) Description() string {
+	return `List the scratch notes of this session by name, with their size and the start of their first line.`
+}
+
+func (t *ScratchListTool) Title() string {
+	return "List Scratch Notes"
+}
+
+func (t *ScratchListTool) Annotations() map[string]bool {
+	return ReadOnlyAnnotations()
+}
+
+func (t *ScratchListTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {}
+	}`)
+}
+
+func (t *ScratchListTool) OutputSchema() json.RawMessage {
+	return OutputSchemaOf(ScratchListResponse{})
+}
+
+func (t *ScratchListTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	pad, err := sessionScratchpad(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &ScratchListResponse{Notes: []ScratchNoteInfo{}}
+	for _, note := range pad.List() {
+		resp.Notes = append(resp.Notes, ScratchNoteInfo{
+			Name:    note.Name,
+			Size:    len(note.Content),
+			Preview: scratchPreview(note.Content),
+			Updated: note.Updated,
+		})
+	}
+	resp.Count = len(resp.Notes)
+	return resp, nil
+}
+
+// scratchPreview is the first non-blank line of content, shortened to
+// scratchPreviewLength runes
+func scratchPreview(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if utf8.RuneCountInString(line) > scratchPreviewLength {
+			line = string([]rune(line)[:scratchPreviewLength]) + "..."
+		}
+		return line
+	}
+	return ""
+}
@@ -0,0 +1,109 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestScratchTools(t *testing.T) {
+	r := NewRegistry()
+	for _, tool := range NewScratchTools() {
+		if err := r.RegisterIn("scratch", tool); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pad := NewScratchpad()
+	ctx := WithScratchpad(context.Background(), pad)
+
+	call := func(ctx context.Context, name, args string) (interface{}, error) {
+		return r.Execute(ctx, name, json.RawMessage(args))
+	}
+
+	t.Run("WriteAppendRead", func(t *testing.T) {
+		result, err := call(ctx, "scratch_write", `{"name":"plan","content":"1. read\n"}`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.(*ScratchWriteResponse).Created {
+			t.Error("first write should create the note")
+		}
+		result, err = call(ctx, "scratch/write", `{"name":"plan","content":"2. edit\n","append":true}`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.(*ScratchWriteResponse).Created {
+			t.Error("append should not create the note again")
+		}
+
+		result, err = call(ctx, "scratch_read", `{"name":"plan"}`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := result.(*ScratchNote).Content; got != "1. read\n2. edit\n" {
+			t.Errorf("content = %q", got)
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		if _, err := call(ctx, "scratch_write", `{"name":"findings","content":"\n  auth lives in middleware.go\nmore"}`); err != nil {
+			t.Fatal(err)
+		}
+		result, err := call(ctx, "scratch_list", `{}`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		list := result.(*ScratchListResponse)
+		if list.Count != 2 || list.Notes[0].Name != "findings" || list.Notes[1].Name != "plan" {
+			t.Fatalf("notes = %+v", list.Notes)
+		}
+		if list.Notes[0].Preview != "auth lives in middleware.go" {
+			t.Errorf("preview = %q", list.Notes[0].Preview)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		if _, err := call(ctx, "scratch_write", `{"name":"findings","delete":true}`); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := call(ctx, "scratch_read", `{"name":"findings"}`); err == nil {
+			t.Error("deleted note should not be readable")
+		}
+	})
+
+	t.Run("ReadOnlyAndDryRunSessions", func(t *testing.T) {
+		for _, session := range []SessionOptions{{ReadOnly: true}, {DryRun: true}} {
+			ctx := WithSession(ctx, session)
+			result, err := call(ctx, "scratch_write", `{"name":"session","content":"x"}`)
+			if err != nil {
+				t.Fatalf("%+v: %v", session, err)
+			}
+			if _, ok := result.(*ScratchWriteResponse); !ok {
+				t.Fatalf("%+v: result = %#v", session, result)
+			}
+		}
+	})
+
+	t.Run("Limits", func(t *testing.T) {
+		big := strings.Repeat("x", MaxScratchNoteSize+1)
+		if _, err := pad.Write("big", big, false); err == nil {
+			t.Error("oversized note should be rejected")
+		}
+		pad.Clear()
+		for i := 0; i < MaxScratchNotes; i++ {
+			if _, err := pad.Write(strings.Repeat("n", i+1), "", false); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := pad.Write("one-more", "", false); err == nil {
+			t.Error("a full scratchpad should reject new notes")
+		}
+	})
+
+	t.Run("NoSession", func(t *testing.T) {
+		if _, err := call(context.Background(), "scratch_list", `{}`); err == nil {
+			t.Error("scratch tools should fail without a session scratchpad")
+		}
+	})
+}
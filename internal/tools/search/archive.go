@@ -0,0 +1,256 @@
+package search
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+// ArchiveSeparator joins the path of an archive and the path of an entry
+// inside it in match locations: dist/app.jar!/com/acme/App.java, and
+// outer.zip!/lib/inner.jar!/Main.java for nested archives
+const ArchiveSeparator = "!/"
+
+// Bounds of a search inside archives. Archives nest at most
+// archiveMaxDepth levels deep, archives on disk over archiveMaxSize and
+// entries over archiveMaxEntrySize are skipped, and no more than
+// archiveMaxTotal bytes are decompressed from one archive on disk, which
+// keeps a zip bomb from exhausting memory.
+const (
+	archiveMaxDepth     = 3
+	archiveMaxSize      = 512 << 20
+	archiveMaxEntrySize = 16 << 20
+	archiveMaxTotal     = 256 << 20
+
+	// binarySniffSize is how much of an entry is checked for NUL bytes;
+	// binary entries, such as class files, are not searched
+	binarySniffSize = 8000
+)
+
+type archiveKind int
+
+const (
+	notArchive archiveKind = iota
+	zipArchive
+	tarArchive
+	tarGzArchive
+	gzipFile
+)
+
+// archiveGlobs are the names archiveKindOf recognizes, for engines that
+// must leave archives to the archive search
+var archiveGlobs = []string{"*.zip", "*.jar", "*.war", "*.ear", "*.whl", "*.aar", "*.tar", "*.tgz", "*.gz"}
+
+func archiveKindOf(name string) archiveKind {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return tarGzArchive
+	case strings.HasSuffix(lower, ".tar"):
+		return tarArchive
+	case strings.HasSuffix(lower, ".gz"):
+		return gzipFile
+	}
+	switch filepath.Ext(lower) {
+	case ".zip", ".jar", ".war", ".ear", ".whl", ".aar":
+		return zipArchive
+	}
+	return notArchive
+}
+
+// archiveSearch looks for the matches of a search inside archives
+type archiveSearch struct {
+	req     SearchRequest
+	pattern *regexp.Regexp
+	max     int
+	matches []Match
+	// budget is what may still be decompressed from the current archive
+	budget int64
+}
+
+// searchArchives returns the matches of req inside the zip, jar, tar,
+// tar.gz and gz files under req.Path, at most max of them
+func searchArchives(ctx context.Context, req SearchRequest, max int) ([]Match, error) {
+	pattern, err := compilePattern(req)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &archiveSearch{req: req, pattern: pattern, max: max}
+	err = filepath.WalkDir(req.Path, func(file string, d os.DirEntry, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			return nil
+		}
+
+		if file != req.Path && (tools.IsPathDenied(file) || excluded(req.Path, file, req.Exclude)) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if !req.Recursive && file != req.Path {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		kind := archiveKindOf(d.Name())
+		if kind == notArchive {
+			return nil
+		}
+		if info, err := d.Info(); err != nil || info.Size() > archiveMaxSize {
+			return nil
+		}
+
+		s.budget = archiveMaxTotal
+		s.searchArchive(file, kind)
+		if s.done() {
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.matches, nil
+}
+
+func (s *archiveSearch) done() bool {
+	return len(s.matches) >= s.max
+}
+
+// searchArchive searches the archive on disk at name; unreadable archives
+// are skipped
+func (s *archiveSearch) searchArchive(name string, kind archiveKind) {
+	if kind == zipArchive {
+		r, err := zip.OpenReader(name)
+		if err != nil {
+			return
+		}
+		defer r.Close()
+		s.searchZip(&r.Reader, name, 1)
+		return
+	}
+
+	file, err := os.Open(name)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	s.searchStream(file, kind, name, 1)
+}
+
+// searchZip searches the entries of a zip archive at the given depth
+func (s *archiveSearch) searchZip(r *zip.Reader, name string, depth int) {
+	for _, f := range r.File {
+		if s.done() {
+			return
+		}
+		if f.FileInfo().IsDir() || f.UncompressedSize64 > archiveMaxEntrySize {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			continue
+		}
+		s.searchEntry(rc, name, f.Name, depth)
+		rc.Close()
+	}
+}
+
+// searchStream searches a tar, tar.gz or gz archive read from r
+func (s *archiveSearch) searchStream(r io.Reader, kind archiveKind, name string, depth int) {
+	if kind == tarGzArchive || kind == gzipFile {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return
+		}
+		defer gz.Close()
+		if kind == gzipFile {
+			// a gz file holds one file, named after the archive
+			base := path.Base(filepath.ToSlash(name))
+			s.searchEntry(gz, name, strings.TrimSuffix(base, path.Ext(base)), depth)
+			return
+		}
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for !s.done() {
+		header, err := tr.Next()
+		if err != nil {
+			return
+		}
+		if header.Typeflag != tar.TypeReg || header.Size > archiveMaxEntrySize {
+			continue
+		}
+		s.searchEntry(tr, name, header.Name, depth)
+	}
+}
+
+// searchEntry searches one entry of the archive name, descending into it
+// when it is an archive itself and the depth allows
+func (s *archiveSearch) searchEntry(r io.Reader, name, entry string, depth int) {
+	data, ok := s.read(r)
+	if !ok {
+		return
+	}
+	location := name + ArchiveSeparator + strings.TrimPrefix(entry, "/")
+
+	if kind := archiveKindOf(entry); kind != notArchive {
+		if depth >= archiveMaxDepth {
+			return
+		}
+		if kind == zipArchive {
+			zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+			if err == nil {
+				s.searchZip(zr, location, depth+1)
+			}
+			return
+		}
+		s.searchStream(bytes.NewReader(data), kind, location, depth+1)
+		return
+	}
+
+	if bytes.IndexByte(data[:min(len(data), binarySniffSize)], 0) >= 0 {
+		return
+	}
+	matches := matchLines(location, scanLines(bytes.NewReader(data)), s.req, s.pattern)
+	if remaining := s.max - len(s.matches); len(matches) > remaining {
+		matches = matches[:remaining]
+	}
+	s.matches = append(s.matches, matches...)
+}
+
+// read decompresses an entry within the entry size limit and what is left
+// of the archive's budget, reporting false for an entry over either
+func (s *archiveSearch) read(r io.Reader) ([]byte, bool) {
+	limit := min(int64(archiveMaxEntrySize), s.budget)
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	s.budget -= int64(len(data))
+	if err != nil || int64(len(data)) > limit {
+		return nil, false
+	}
+	return data, true
+}
+
+// archiveOf returns the archive on disk holding the file at location, or
+// location itself when it is not inside an archive
+func archiveOf(location string) string {
+	archive, _, _ := strings.Cut(location, ArchiveSeparator)
+	return archive
+}
@@ -0,0 +1,119 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/alucardeht/may-la-mcp/internal/router"
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+	"github.com/alucardeht/may-la-mcp/internal/types"
+)
+
+type CompleteRequest struct {
+	Path       string  `json:"path"`
+	Line       int     `json:"line"`
+	Column     int     `json:"column"`
+	Content    *string `json:"content,omitempty"`
+	MaxResults int     `json:"max_results,omitempty"`
+}
+
+type CompleteResponse struct {
+	Completions []types.Completion `json:"completions"`
+	Count       int                `json:"count"`
+	Incomplete  bool               `json:"incomplete,omitempty"`
+	Path        string             `json:"path"`
+	LatencyMs   int64              `json:"latency_ms"`
+}
+
+type CompleteTool struct {
+	router *router.Router
+}
+
+func NewCompleteTool(r *router.Router) *CompleteTool {
+	return &CompleteTool{router: r}
+}
+
+func (t *CompleteTool) Name() string {
+	return "complete"
+}
+
+func (t *CompleteTool) Description() string {
+	return "Get code completions at a position from the language server: identifiers, members and keywords valid there, with kind, detail and documentation. Pass content to complete unsaved text"
+}
+
+func (t *CompleteTool) Title() string {
+	return "Code Completion"
+}
+
+func (t *CompleteTool) Annotations() map[string]bool {
+	return tools.ReadOnlyAnnotations()
+}
+
+func (t *CompleteTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"path": {
+				"type": "string",
+				"description": "Source file to complete in"
+			},
+			"line": {
+				"type": "integer",
+				"description": "Line number (1-based)"
+			},
+			"column": {
+				"type": "integer",
+				"description": "Column in characters (1-based); completion happens before this character, so use line length + 1 for the end of the line"
+			},
+			"content": {
+				"type": "string",
+				"description": "File content to complete against instead of the file on disk"
+			},
+			"max_results": {
+				"type": "integer",
+				"description": "Maximum number of completions (default: 50)"
+			}
+		},
+		"required": ["path", "line", "column"]
+	}`)
+}
+
+func (t *CompleteTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var req CompleteRequest
+	if err := json.Unmarshal(input, &req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	if req.Path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+	if req.MaxResults <= 0 {
+		req.MaxResults = 50
+	}
+
+	if err := tools.CheckPath(req.Path); err != nil {
+		return nil, err
+	}
+
+	if t.router == nil {
+		return nil, router.ErrNoLanguageServer
+	}
+
+	result, err := t.router.QueryCompletions(ctx, req.Path, req.Line, req.Column, req.Content, req.MaxResults)
+	if err != nil {
+		return nil, fmt.Errorf("complete: %w", err)
+	}
+
+	return &CompleteResponse{
+		Completions: result.Items,
+		Count:       result.Count,
+		Incomplete:  result.Incomplete,
+		Path:        req.Path,
+		LatencyMs:   result.Latency.Milliseconds(),
+	}, nil
+}
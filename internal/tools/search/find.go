@@ -9,15 +9,18 @@ import (
 	"strings"
 	"time"
 
+	"github.com/alucardeht/may-la-mcp/internal/gitignore"
+	"github.com/alucardeht/may-la-mcp/internal/glob"
 	"github.com/alucardeht/may-la-mcp/internal/tools"
 )
 
 type FindRequest struct {
-	Pattern   string `json:"pattern"`
-	Path      string `json:"path"`
-	Type      string `json:"type,omitempty"`
-	MaxDepth  int    `json:"max_depth,omitempty"`
-	MaxResults int    `json:"max_results,omitempty"`
+	Pattern        string `json:"pattern"`
+	Path           string `json:"path"`
+	Type           string `json:"type,omitempty"`
+	MaxDepth       int    `json:"max_depth,omitempty"`
+	MaxResults     int    `json:"max_results,omitempty"`
+	IncludeIgnored bool   `json:"include_ignored,omitempty"`
 }
 
 type FileInfo struct {
@@ -28,10 +31,11 @@ type FileInfo struct {
 }
 
 type FindResponse struct {
-	Files  []FileInfo `json:"files"`
-	Count  int        `json:"count"`
-	Path   string     `json:"path"`
-	Total  int64      `json:"total_size"`
+	Files   []FileInfo `json:"files"`
+	Count   int        `json:"count"`
+	Path    string     `json:"path"`
+	Total   int64      `json:"total_size"`
+	Ignored int        `json:"ignored,omitempty"`
 }
 
 type FindTool struct{}
@@ -41,7 +45,9 @@ func (t *FindTool) Name() string {
 }
 
 func (t *FindTool) Description() string {
-	return "Find files by name pattern with glob matching"
+	return `Find files by name pattern with glob matching.
+
+The walk skips what a .gitignore ignores, .git directories and paths matching the index exclude patterns (node_modules, build output, ...), counting the entries it skipped as ignored; pass include_ignored to search them too.`
 }
 
 func (t *FindTool) Title() string {
@@ -58,7 +64,7 @@ func (t *FindTool) Schema() json.RawMessage {
 		"properties": {
 			"pattern": {
 				"type": "string",
-				"description": "Glob pattern to match (e.g., *.go, src/*/index.js)"
+				"description": "Glob pattern to match: without a slash it matches names, with one paths relative to path; supports ** and {a,b} (e.g., *.go, src/**/index.{js,ts})"
 			},
 			"path": {
 				"type": "string",
@@ -76,6 +82,10 @@ func (t *FindTool) Schema() json.RawMessage {
 			"max_results": {
 				"type": "integer",
 				"description": "Maximum number of results (default: 1000)"
+			},
+			"include_ignored": {
+				"type": "boolean",
+				"description": "Also search paths ignored by .gitignore or the exclude patterns (default: false)"
 			}
 		},
 		"required": ["pattern", "path"]
@@ -94,10 +104,17 @@ func (t *FindTool) Execute(ctx context.Context, input json.RawMessage) (interfac
 	if req.Pattern == "" {
 		return nil, fmt.Errorf("pattern is required")
 	}
+	if err := glob.Validate(req.Pattern); err != nil {
+		return nil, err
+	}
 	if req.Path == "" {
 		return nil, fmt.Errorf("path is required")
 	}
 
+	if err := tools.CheckPath(req.Path); err != nil {
+		return nil, err
+	}
+
 	if req.MaxResults == 0 {
 		req.MaxResults = 1000
 	}
@@ -107,6 +124,11 @@ func (t *FindTool) Execute(ctx context.Context, input json.RawMessage) (interfac
 
 	files := []FileInfo{}
 	totalSize := int64(0)
+	var matcher *gitignore.Matcher
+	if !req.IncludeIgnored {
+		matcher = gitignore.NewMatcher(req.Path)
+	}
+	ignored := 0
 
 	err := filepath.WalkDir(req.Path, func(path string, d os.DirEntry, err error) error {
 		// Check for context cancellation to respect timeouts
@@ -118,6 +140,13 @@ func (t *FindTool) Execute(ctx context.Context, input json.RawMessage) (interfac
 			return nil
 		}
 
+		if path != req.Path && tools.IsPathDenied(path) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
 		if req.MaxDepth > 0 {
 			depth := strings.Count(strings.TrimPrefix(path, req.Path), string(filepath.Separator))
 			if depth > req.MaxDepth {
@@ -132,6 +161,19 @@ func (t *FindTool) Execute(ctx context.Context, input json.RawMessage) (interfac
 			return filepath.SkipDir
 		}
 
+		if matcher != nil && path != req.Path {
+			if matcher.Ignored(path, d.IsDir()) {
+				ignored++
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() {
+				matcher.Enter(path)
+			}
+		}
+
 		relPath, err := filepath.Rel(req.Path, path)
 		if err != nil {
 			return nil
@@ -171,24 +213,18 @@ func (t *FindTool) Execute(ctx context.Context, input json.RawMessage) (interfac
 	}
 
 	return &FindResponse{
-		Files:  files,
-		Count:  len(files),
-		Path:   req.Path,
-		Total:  totalSize,
+		Files:   files,
+		Count:   len(files),
+		Path:    req.Path,
+		Total:   totalSize,
+		Ignored: ignored,
 	}, nil
 }
 
+// matchesPattern matches the path relative to the search root: patterns
+// with a slash match all of it, others its name
 func matchesPattern(name string, pattern string) bool {
-	matched, err := filepath.Match(pattern, filepath.Base(name))
-	if err != nil {
-		return false
-	}
-	if matched {
-		return true
-	}
-
-	matched, err = filepath.Match(pattern, name)
-	return err == nil && matched
+	return glob.Match(pattern, filepath.ToSlash(name))
 }
 
 func shouldInclude(d os.DirEntry, typeFilter string) bool {
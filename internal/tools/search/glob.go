@@ -0,0 +1,185 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/alucardeht/may-la-mcp/internal/glob"
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+const (
+	defaultGlobResults = 1000
+	maxGlobResults     = 10000
+)
+
+type GlobRequest struct {
+	Patterns   []string `json:"patterns"`
+	Path       string   `json:"path"`
+	Type       string   `json:"type,omitempty"`
+	MaxResults int      `json:"max_results,omitempty"`
+}
+
+type GlobResponse struct {
+	Path      string   `json:"path"`
+	Matches   []string `json:"matches"`
+	Count     int      `json:"count"`
+	Truncated bool     `json:"truncated,omitempty"`
+}
+
+type GlobTool struct{}
+
+func (t *GlobTool) Name() string {
+	return "glob"
+}
+
+func (t *GlobTool) Description() string {
+	return `Expand glob patterns under a directory and return the matching paths, relative to it.
+
+Patterns support * and ? within a name, [...] classes, ** across directories, {a,b} alternatives and the extglob groups @(a|b) and ?(a|b). A pattern without a slash matches names at any depth ("*.go"), one with a slash the whole relative path ("src/**/*.test.{js,ts}"). Patterns starting with ! exclude, also everything under an excluded directory ("!node_modules"). .git directories are never searched. The same matching applies to find, list, the exclude option of search and the watcher and index exclude patterns.`
+}
+
+func (t *GlobTool) Title() string {
+	return "Expand Glob Patterns"
+}
+
+func (t *GlobTool) Annotations() map[string]bool {
+	return tools.ReadOnlyAnnotations()
+}
+
+func (t *GlobTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"patterns": {
+				"type": "array",
+				"items": {"type": "string"},
+				"minItems": 1,
+				"description": "Globs to match; those starting with ! exclude (e.g., [\"**/*.{go,rs}\", \"!vendor\", \"!*_test.go\"])"
+			},
+			"path": {
+				"type": "string",
+				"description": "Directory to expand the patterns in"
+			},
+			"type": {
+				"type": "string",
+				"description": "Type filter (default: file)",
+				"enum": ["file", "dir", "all"]
+			},
+			"max_results": {
+				"type": "integer",
+				"description": "Maximum paths returned (default: 1000, max: 10000)"
+			}
+		},
+		"required": ["patterns", "path"]
+	}`)
+}
+
+func (t *GlobTool) Examples() []tools.Example {
+	return []tools.Example{
+		{
+			Description: "Go and Rust sources outside vendor, without tests",
+			Arguments:   json.RawMessage(`{"path": "/home/user/app", "patterns": ["*.@(go|rs)", "!vendor", "!*_test.go"]}`),
+			Result:      json.RawMessage(`{"path": "/home/user/app", "matches": ["cmd/main.go", "internal/lib.rs"], "count": 2}`),
+		},
+	}
+}
+
+func (t *GlobTool) OutputSchema() json.RawMessage {
+	return tools.OutputSchemaOf(GlobResponse{})
+}
+
+func (t *GlobTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var req GlobRequest
+	if err := json.Unmarshal(input, &req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+	if len(req.Patterns) == 0 {
+		return nil, fmt.Errorf("patterns is required")
+	}
+	if req.Path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+	if err := tools.CheckPath(req.Path); err != nil {
+		return nil, err
+	}
+	switch req.Type {
+	case "":
+		req.Type = "file"
+	case "file", "dir", "all":
+	default:
+		return nil, fmt.Errorf("invalid type %q: expected file, dir or all", req.Type)
+	}
+	if req.MaxResults <= 0 {
+		req.MaxResults = defaultGlobResults
+	}
+	req.MaxResults = min(req.MaxResults, maxGlobResults)
+
+	set, err := glob.NewSet(req.Patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := os.Stat(req.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat path: %w", err)
+	}
+	if !stat.IsDir() {
+		return nil, fmt.Errorf("path is not a directory: %s", req.Path)
+	}
+
+	resp := &GlobResponse{Path: req.Path, Matches: []string{}}
+	err = filepath.WalkDir(req.Path, func(path string, d fs.DirEntry, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil || path == req.Path {
+			return nil
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if tools.IsPathDenied(path) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		relPath, err := filepath.Rel(req.Path, path)
+		if err != nil {
+			return nil
+		}
+		rel := filepath.ToSlash(relPath)
+		if set.Excludes(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !shouldInclude(d, req.Type) || !set.Match(rel) {
+			return nil
+		}
+
+		if len(resp.Matches) >= req.MaxResults {
+			resp.Truncated = true
+			return filepath.SkipAll
+		}
+		resp.Matches = append(resp.Matches, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walk error: %w", err)
+	}
+
+	resp.Count = len(resp.Matches)
+	return resp, nil
+}
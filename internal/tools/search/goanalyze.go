@@ -0,0 +1,174 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/goanalysis"
+	"github.com/alucardeht/may-la-mcp/internal/router"
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+	"github.com/alucardeht/may-la-mcp/internal/types"
+)
+
+// Queries answered by go_analyze
+const (
+	goQueryDefinition      = "definition"
+	goQueryReferences      = "references"
+	goQueryImplementations = "implementations"
+	goQueryMethodSet       = "method_set"
+)
+
+type GoAnalyzeRequest struct {
+	Query      string `json:"query"`
+	Path       string `json:"path"`
+	Line       int    `json:"line,omitempty"`
+	Column     int    `json:"column,omitempty"`
+	Symbol     string `json:"symbol,omitempty"`
+	MaxResults int    `json:"max_results,omitempty"`
+}
+
+type GoAnalyzeResponse struct {
+	Query           string                      `json:"query"`
+	Symbol          *types.Symbol               `json:"symbol,omitempty"`
+	References      []types.Reference           `json:"references,omitempty"`
+	Implementations []goanalysis.Implementation `json:"implementations,omitempty"`
+	Methods         []goanalysis.Method         `json:"methods,omitempty"`
+	Count           int                         `json:"count"`
+	LatencyMs       int64                       `json:"latency_ms"`
+}
+
+type GoAnalyzeTool struct {
+	router *router.Router
+}
+
+func NewGoAnalyzeTool(r *router.Router) *GoAnalyzeTool {
+	return &GoAnalyzeTool{router: r}
+}
+
+func (t *GoAnalyzeTool) Name() string {
+	return "go_analyze"
+}
+
+func (t *GoAnalyzeTool) Description() string {
+	return "Answer Go questions from the type checker: the real definition of an identifier, every use of a declaration, the types implementing an interface (or the interfaces a type implements) and a type's method set"
+}
+
+func (t *GoAnalyzeTool) Title() string {
+	return "Go Type Analysis"
+}
+
+func (t *GoAnalyzeTool) Annotations() map[string]bool {
+	return tools.ReadOnlyAnnotations()
+}
+
+func (t *GoAnalyzeTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"query": {
+				"type": "string",
+				"enum": ["definition", "references", "implementations", "method_set"],
+				"description": "What to find"
+			},
+			"path": {
+				"type": "string",
+				"description": "Go file holding the position, or with symbol the file or directory whose package is searched first"
+			},
+			"line": {
+				"type": "integer",
+				"description": "Line number (1-based); required without symbol"
+			},
+			"column": {
+				"type": "integer",
+				"description": "Column in characters (1-based); required without symbol"
+			},
+			"symbol": {
+				"type": "string",
+				"description": "Package-level name, or Type.Method and Type.Field, instead of a position"
+			},
+			"max_results": {
+				"type": "integer",
+				"description": "Maximum references (default: 1000)"
+			}
+		},
+		"required": ["query", "path"]
+	}`)
+}
+
+func (t *GoAnalyzeTool) Examples() []tools.Example {
+	return []tools.Example{
+		{
+			Description: "Types implementing an interface, by name",
+			Arguments:   json.RawMessage(`{"query": "implementations", "path": "/home/user/app/internal/store", "symbol": "Store"}`),
+			Result:      json.RawMessage(`{"query": "implementations", "symbol": {"name": "Store", "kind": "interface", "file": "/home/user/app/internal/store/store.go", "line": 12}, "implementations": [{"name": "SQLiteStore", "kind": "struct", "file": "/home/user/app/internal/store/sqlite.go", "line": 20, "package": "example.com/app/internal/store", "pointer": true}], "count": 1}`),
+		},
+	}
+}
+
+func (t *GoAnalyzeTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var req GoAnalyzeRequest
+	if err := json.Unmarshal(input, &req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	if req.Path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+	if req.Symbol == "" && (req.Line == 0 || req.Column == 0) {
+		return nil, fmt.Errorf("line and column are required without symbol")
+	}
+	if req.MaxResults <= 0 {
+		req.MaxResults = 1000
+	}
+
+	if err := tools.CheckPath(req.Path); err != nil {
+		return nil, err
+	}
+
+	var analyzer *goanalysis.Analyzer
+	if t.router != nil {
+		analyzer = t.router.GoAnalyzer()
+	}
+	if analyzer == nil {
+		return nil, fmt.Errorf("go analysis is not enabled")
+	}
+
+	start := time.Now()
+	target := goanalysis.Target{Path: req.Path, Line: req.Line, Column: req.Column, Name: req.Symbol}
+	resp := &GoAnalyzeResponse{Query: req.Query}
+
+	var err error
+	switch req.Query {
+	case goQueryDefinition:
+		resp.Symbol, err = analyzer.Definition(ctx, target)
+		if err == nil {
+			resp.Count = 1
+		}
+	case goQueryReferences:
+		resp.References, err = analyzer.References(ctx, target, req.MaxResults)
+		resp.Count = len(resp.References)
+	case goQueryImplementations:
+		resp.Symbol, err = analyzer.Definition(ctx, target)
+		if err == nil {
+			resp.Implementations, err = analyzer.Implementations(ctx, target)
+			resp.Count = len(resp.Implementations)
+		}
+	case goQueryMethodSet:
+		resp.Symbol, resp.Methods, err = analyzer.MethodSet(ctx, target)
+		resp.Count = len(resp.Methods)
+	default:
+		return nil, fmt.Errorf("unknown query %q (expected definition, references, implementations or method_set)", req.Query)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("go_analyze: %w", err)
+	}
+
+	resp.LatencyMs = time.Since(start).Milliseconds()
+	return resp, nil
+}
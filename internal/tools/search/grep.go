@@ -5,24 +5,58 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"math"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
+	"time"
+	"unicode"
 
+	"github.com/alucardeht/may-la-mcp/internal/glob"
+	"github.com/alucardeht/may-la-mcp/internal/intel"
+	"github.com/alucardeht/may-la-mcp/internal/paths"
+	"github.com/alucardeht/may-la-mcp/internal/router"
 	"github.com/alucardeht/may-la-mcp/internal/tools"
+	"github.com/alucardeht/may-la-mcp/internal/types"
 )
 
 const MaxGrepFileSize = 100 * 1024 * 1024
 
+// Output modes of the search tool
+const (
+	outputContent          = "content"
+	outputFilesWithMatches = "files_with_matches"
+	outputCount            = "count"
+)
+
 type SearchRequest struct {
-	Pattern       string `json:"pattern"`
-	Path          string `json:"path"`
-	Recursive     bool   `json:"recursive,omitempty"`
-	CaseSensitive bool   `json:"case_sensitive,omitempty"`
-	Regex         bool   `json:"regex,omitempty"`
-	ContextLines  int    `json:"context_lines,omitempty"`
-	MaxResults    int    `json:"max_results,omitempty"`
+	Pattern       string   `json:"pattern"`
+	Path          string   `json:"path"`
+	Recursive     bool     `json:"recursive,omitempty"`
+	CaseSensitive bool     `json:"case_sensitive,omitempty"`
+	SmartCase     bool     `json:"smart_case,omitempty"`
+	Word          bool     `json:"word,omitempty"`
+	Regex         bool     `json:"regex,omitempty"`
+	ContextLines  int      `json:"context_lines,omitempty"`
+	MaxResults    int      `json:"max_results,omitempty"`
+	Language      string   `json:"language,omitempty"`
+	WithinKind    string   `json:"within_kind,omitempty"`
+	WithinName    string   `json:"within_name,omitempty"`
+	OutputMode    string   `json:"output_mode,omitempty"`
+	MaxTokens     int      `json:"max_tokens,omitempty"`
+	Exclude       []string `json:"exclude,omitempty"`
+	Stream        bool     `json:"stream,omitempty"`
+	// SearchArchives also searches inside zip, jar, tar, tar.gz and gz
+	// files, reporting matches as archive.zip!/inner/path
+	SearchArchives bool `json:"search_archives,omitempty"`
+	// Roots are searched at once along with Path, each match attributed
+	// to its root; TimeoutMs is their shared deadline
+	Roots     []string `json:"roots,omitempty"`
+	TimeoutMs int      `json:"timeout_ms,omitempty"`
+	tools.ResponseOptions
 }
 
 type Match struct {
@@ -31,22 +65,58 @@ type Match struct {
 	Column  int      `json:"column"`
 	Content string   `json:"content"`
 	Context []string `json:"context,omitempty"`
+	// Root is the root the match was found in, in a multi-root search
+	Root string `json:"root,omitempty"`
 }
 
 type SearchResponse struct {
-	Matches []Match `json:"matches"`
-	Count   int     `json:"count"`
-	Path    string  `json:"path"`
+	Matches []Match          `json:"matches"`
+	Count   int              `json:"count"`
+	Path    string           `json:"path"`
+	Tokens  int              `json:"tokens,omitempty"`
+	Omitted *intel.Omissions `json:"omitted,omitempty"`
+	// Streamed is set on the summary ending a streamed search, whose
+	// matches were sent in batches as they were found
+	Streamed bool `json:"streamed,omitempty"`
+	// Roots has the statistics of each root of a multi-root search
+	Roots []RootStats `json:"roots,omitempty"`
+}
+
+// FileMatches is a file hit by a search; Count is the number of matching
+// lines and is only set in count mode
+type FileMatches struct {
+	File  string `json:"file"`
+	Count int    `json:"count,omitempty"`
+	Root  string `json:"root,omitempty"`
 }
 
-type SearchTool struct{}
+// SearchSummary is the response of the files_with_matches and count output
+// modes
+type SearchSummary struct {
+	OutputMode string        `json:"output_mode"`
+	Files      []FileMatches `json:"files"`
+	FileCount  int           `json:"file_count"`
+	Count      int           `json:"count,omitempty"`
+	Truncated  bool          `json:"truncated,omitempty"`
+	Path       string        `json:"path"`
+	Roots      []RootStats   `json:"roots,omitempty"`
+}
+
+type SearchTool struct {
+	router  *router.Router
+	history *HistoryStore
+}
+
+func NewSearchTool(r *router.Router) *SearchTool {
+	return &SearchTool{router: r}
+}
 
 func (t *SearchTool) Name() string {
 	return "search"
 }
 
 func (t *SearchTool) Description() string {
-	return "Search for pattern in files with regex and context support"
+	return "Search for pattern in files with regex and context support. Use output_mode files_with_matches or count to see which files match, and how often, before fetching matching lines"
 }
 
 func (t *SearchTool) Title() string {
@@ -69,6 +139,15 @@ func (t *SearchTool) Schema() json.RawMessage {
 				"type": "string",
 				"description": "Root path to search in"
 			},
+			"roots": {
+				"type": "array",
+				"items": {"type": "string"},
+				"description": "More roots to search at once along with path, or instead of it; each match and file is attributed to its root and each root gets statistics. A root that fails or times out is reported without failing the others, max_results applies to the merged result and max_tokens is shared evenly"
+			},
+			"timeout_ms": {
+				"type": "integer",
+				"description": "With roots, the deadline shared by the roots' searches (default: 30000)"
+			},
 			"recursive": {
 				"type": "boolean",
 				"description": "Search subdirs"
@@ -77,6 +156,14 @@ func (t *SearchTool) Schema() json.RawMessage {
 				"type": "boolean",
 				"description": "Case-sensitive search (default: false)"
 			},
+			"smart_case": {
+				"type": "boolean",
+				"description": "Case-insensitive unless the pattern contains an uppercase letter, like rg --smart-case (default: false)"
+			},
+			"word": {
+				"type": "boolean",
+				"description": "Only match the pattern at word boundaries (default: false)"
+			},
 			"regex": {
 				"type": "boolean",
 				"description": "Treat pattern as regex (default: false)"
@@ -87,13 +174,77 @@ func (t *SearchTool) Schema() json.RawMessage {
 			},
 			"max_results": {
 				"type": "integer",
-				"description": "Maximum number of results (default: 1000)"
+				"description": "Maximum number of results (default: 1000); in files_with_matches and count modes, the maximum number of files listed"
+			},
+			"output_mode": {
+				"type": "string",
+				"enum": ["content", "files_with_matches", "count"],
+				"description": "content returns matching lines (default); files_with_matches lists the matching files; count lists matching line counts per file, most matches first"
+			},
+			"language": {
+				"type": "string",
+				"description": "Only search indexed files of this language (e.g. go, typescript, python)"
+			},
+			"within_kind": {
+				"type": "string",
+				"description": "Only report matches inside indexed symbols of this kind (e.g. function, method, class)"
+			},
+			"within_name": {
+				"type": "string",
+				"description": "With within_kind, only inside symbols with this exact name"
+			},
+			"max_tokens": {
+				"type": "integer",
+				"description": "In content mode, keep only the best matches that fit this approximate token budget, preferring files with many matches, recently modified and near the search root; omitted matches are summarized"
+			},
+			"exclude": {
+				"type": "array",
+				"items": {"type": "string"},
+				"description": "Globs of files and directories to skip, like rg --glob '!pattern': globs with a slash match paths relative to path (e.g. vendor/**), others match any file or directory name (e.g. *.min.js)"
+			},
+			"search_archives": {
+				"type": "boolean",
+				"description": "Also search inside zip, jar, war, whl, tar, tar.gz and gz files, up to 3 nested archives deep, skipping entries over 16MB and binary entries; matches inside are reported as archive.zip!/inner/path. Not combined with language or within_kind (default: false)"
+			},
+			"stream": {
+				"type": "boolean",
+				"description": "In content mode, send matches in batches as they are found, as progress notifications of a call made with a progress token; the result then only sums up the search (count, streamed). Ignored with max_tokens, roots or a text response (default: false)"
+			},
+			"response_mode": {
+				"type": "string",
+				"enum": ["compact", "detailed", "raw"],
+				"description": "Return the result as text instead of JSON: compact (summary plus content cut to max_length, default 2000 chars), detailed (numbered lines with counts, default 5000 chars) or raw (unformatted)"
+			},
+			"max_length": {
+				"type": "integer",
+				"minimum": 0,
+				"description": "Maximum characters of the text result; selects compact when response_mode is omitted"
 			}
 		},
-		"required": ["pattern", "path"]
+		"required": ["pattern"]
 	}`)
 }
 
+func (t *SearchTool) Examples() []tools.Example {
+	return []tools.Example{
+		{
+			Description: "Find a literal string with two lines of context",
+			Arguments:   json.RawMessage(`{"pattern": "NewRouter(", "path": "/home/user/app", "recursive": true, "context_lines": 2}`),
+			Result:      json.RawMessage(`{"matches": [{"file": "/home/user/app/main.go", "line": 14, "column": 9, "content": "\tr := NewRouter(cfg)", "context": ["..."]}], "count": 1, "path": "/home/user/app"}`),
+		},
+		{
+			Description: "List the files matching a regex before reading the matches",
+			Arguments:   json.RawMessage(`{"pattern": "func \\w+Handler", "path": "/home/user/app", "recursive": true, "regex": true, "output_mode": "files_with_matches"}`),
+			Result:      json.RawMessage(`{"output_mode": "files_with_matches", "files": [{"file": "/home/user/app/api.go"}], "file_count": 1, "path": "/home/user/app"}`),
+		},
+		{
+			Description: "Search two workspace roots in one call",
+			Arguments:   json.RawMessage(`{"pattern": "TODO", "roots": ["/home/user/app", "/home/user/lib"], "recursive": true}`),
+			Result:      json.RawMessage(`{"matches": [{"file": "/home/user/lib/io.go", "line": 8, "column": 4, "content": "// TODO: retry", "root": "/home/user/lib"}], "count": 1, "path": "", "roots": [{"root": "/home/user/app", "count": 0, "files": 0, "elapsed": "12ms"}, {"root": "/home/user/lib", "count": 1, "files": 1, "elapsed": "9ms"}]}`),
+		},
+	}
+}
+
 func (t *SearchTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
@@ -103,13 +254,46 @@ func (t *SearchTool) Execute(ctx context.Context, input json.RawMessage) (interf
 		return nil, fmt.Errorf("invalid request: %w", err)
 	}
 
+	stream := newMatchStream(ctx, req)
+
+	start := time.Now()
+	result, err := t.search(ctx, req, stream)
+	if err != nil {
+		return nil, err
+	}
+	elapsed := time.Since(start)
+	t.record(req, result, elapsed)
+
+	if resp, ok := result.(*SearchResponse); ok {
+		stream.summary(resp)
+	}
+
+	if req.ResponseOptions.Enabled() {
+		return req.Format(renderSearchText(result), map[string]interface{}{
+			"match_count": searchMatchCount(result),
+			"search_time": elapsed.Round(time.Millisecond).String(),
+		}), nil
+	}
+	return result, nil
+}
+
+// search validates req and runs it on the best available engine, sending
+// the matches to stream as they are found
+func (t *SearchTool) search(ctx context.Context, req SearchRequest, stream *matchStream) (interface{}, error) {
 	if req.Pattern == "" {
 		return nil, fmt.Errorf("pattern is required")
 	}
+	if len(req.Roots) > 0 {
+		return t.searchRoots(ctx, req)
+	}
 	if req.Path == "" {
 		return nil, fmt.Errorf("path is required")
 	}
 
+	if err := tools.CheckPath(req.Path); err != nil {
+		return nil, err
+	}
+
 	if req.MaxResults == 0 {
 		req.MaxResults = 1000
 	}
@@ -117,27 +301,307 @@ func (t *SearchTool) Execute(ctx context.Context, input json.RawMessage) (interf
 		req.ContextLines = 0
 	}
 
-	rgOutput, err := executeRipgrep(req)
-	if err == nil && rgOutput != nil {
-		return rgOutput, nil
+	switch req.OutputMode {
+	case "":
+		req.OutputMode = outputContent
+	case outputContent, outputFilesWithMatches, outputCount:
+	default:
+		return nil, fmt.Errorf("invalid output_mode %q: expected content, files_with_matches or count", req.OutputMode)
+	}
+
+	req.normalizeMatching()
+
+	if err := req.ResponseOptions.Validate(); err != nil {
+		return nil, err
+	}
+	if req.WithinName != "" && req.WithinKind == "" {
+		return nil, fmt.Errorf("within_name requires within_kind")
+	}
+	if req.WithinKind != "" {
+		kind, err := types.ParseKind(req.WithinKind)
+		if err != nil {
+			return nil, fmt.Errorf("invalid within_kind: %w", err)
+		}
+		req.WithinKind = kind
+	}
+	for _, pattern := range req.Exclude {
+		if err := glob.Validate(pattern); err != nil {
+			return nil, fmt.Errorf("invalid exclude: %w", err)
+		}
+	}
+
+	var scope *router.SearchScope
+	if req.Language != "" || req.WithinKind != "" {
+		var err error
+		scope, err = t.router.ResolveSearchScope(req.Path, req.Language, req.WithinKind, req.WithinName)
+		if err != nil {
+			return nil, fmt.Errorf("language/within_kind filters need the index: %w", err)
+		}
+	}
+
+	if req.OutputMode != outputContent {
+		return recordMatchedFiles(summarize(ctx, req, scope))
 	}
 
-	return searchWithGo(ctx, req)
+	result, err := searchContent(ctx, req, scope, stream)
+	if resp, ok := result.(*SearchResponse); ok && err == nil && req.MaxTokens > 0 {
+		packMatches(resp, req.Path, req.MaxTokens)
+	}
+	return recordMatchedFiles(result, err)
 }
 
-func searchWithGo(ctx context.Context, req SearchRequest) (interface{}, error) {
-	var pattern *regexp.Regexp
-	var err error
+// searchContent runs a search in content mode
+func searchContent(ctx context.Context, req SearchRequest, scope *router.SearchScope, stream *matchStream) (interface{}, error) {
+	if scope != nil {
+		return searchScope(ctx, req, scope, stream)
+	}
+
+	result, err := executeRipgrep(ctx, req, stream)
+	if err != nil || result == nil {
+		var goResult interface{}
+		goResult, err = searchWithGo(ctx, req, stream)
+		if err != nil {
+			return nil, err
+		}
+		result = goResult.(*SearchResponse)
+	}
+
+	if req.SearchArchives && len(result.Matches) < req.MaxResults {
+		matches, err := searchArchives(ctx, req, req.MaxResults-len(result.Matches))
+		if err != nil {
+			return nil, fmt.Errorf("archive search: %w", err)
+		}
+		result.Matches = append(result.Matches, matches...)
+		result.Count = len(result.Matches)
+		stream.add(matches...)
+	}
+	return result, nil
+}
+
+// record adds a finished search to the history, when one is kept
+func (t *SearchTool) record(req SearchRequest, result interface{}, elapsed time.Duration) {
+	if t.history == nil {
+		return
+	}
+
+	count, fileCount := 0, 0
+	switch resp := result.(type) {
+	case *SearchResponse:
+		files := make(map[string]bool)
+		for _, m := range resp.Matches {
+			files[m.File] = true
+		}
+		count, fileCount = resp.Count, len(files)
+	case *SearchSummary:
+		count, fileCount = resp.Count, resp.FileCount
+	}
+
+	t.history.Record(req, count, fileCount, elapsed)
+}
+
+// recordMatchedFiles reports the files a search hit as recently accessed
+func recordMatchedFiles(result interface{}, err error) (interface{}, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp := result.(type) {
+	case *SearchResponse:
+		seen := make(map[string]bool)
+		for _, m := range resp.Matches {
+			if file := archiveOf(m.File); !seen[file] {
+				seen[file] = true
+				tools.RecordAccess(file)
+			}
+		}
+	case *SearchSummary:
+		for _, f := range resp.Files {
+			tools.RecordAccess(archiveOf(f.File))
+		}
+	}
+	return result, nil
+}
+
+// summarize runs a search in files_with_matches or count mode. ripgrep
+// reports per-file counts directly; otherwise every match is collected and
+// counted per file.
+func summarize(ctx context.Context, req SearchRequest, scope *router.SearchScope) (interface{}, error) {
+	maxFiles := req.MaxResults
+
+	req.MaxResults = math.MaxInt32
+	req.ContextLines = 0
+
+	var counts map[string]int
+	if scope == nil {
+		counts, _ = executeRipgrepSummary(req)
+	}
+
+	if counts == nil {
+		var result interface{}
+		var err error
+		if scope != nil {
+			result, err = searchScope(ctx, req, scope, nil)
+		} else {
+			result, err = searchWithGo(ctx, req, nil)
+		}
+		if err != nil {
+			return nil, err
+		}
 
-	if req.Regex {
-		flags := ""
-		if !req.CaseSensitive {
-			flags = "(?i)"
+		counts = make(map[string]int)
+		for _, m := range result.(*SearchResponse).Matches {
+			counts[m.File]++
 		}
-		pattern, err = regexp.Compile(flags + req.Pattern)
+	}
+
+	if req.SearchArchives && scope == nil {
+		matches, err := searchArchives(ctx, req, math.MaxInt32)
 		if err != nil {
-			return nil, fmt.Errorf("invalid regex: %w", err)
+			return nil, fmt.Errorf("archive search: %w", err)
+		}
+		for _, m := range matches {
+			counts[m.File]++
+		}
+	}
+	return newSearchSummary(req, counts, maxFiles), nil
+}
+
+func newSearchSummary(req SearchRequest, counts map[string]int, maxFiles int) *SearchSummary {
+	summary := &SearchSummary{OutputMode: req.OutputMode, FileCount: len(counts), Path: req.Path}
+
+	files := make([]FileMatches, 0, len(counts))
+	for file, count := range counts {
+		summary.Count += count
+		if req.OutputMode != outputCount {
+			count = 0
+		}
+		files = append(files, FileMatches{File: file, Count: count})
+	}
+	if req.OutputMode != outputCount {
+		summary.Count = 0
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].Count != files[j].Count {
+			return files[i].Count > files[j].Count
 		}
+		return files[i].File < files[j].File
+	})
+
+	if len(files) > maxFiles {
+		files = files[:maxFiles]
+		summary.Truncated = true
+	}
+	summary.Files = files
+	return summary
+}
+
+// normalizeMatching folds smart_case into case_sensitive and word into a
+// \b-wrapped regex, so ripgrep and the Go engine match the same way
+func (req *SearchRequest) normalizeMatching() {
+	if req.SmartCase && !req.CaseSensitive && hasUppercase(req.Pattern, req.Regex) {
+		req.CaseSensitive = true
+	}
+	req.SmartCase = false
+
+	if req.Word {
+		pattern := req.Pattern
+		if !req.Regex {
+			pattern = regexp.QuoteMeta(pattern)
+		}
+		req.Pattern = `\b(?:` + pattern + `)\b`
+		req.Regex = true
+		req.Word = false
+	}
+}
+
+// hasUppercase reports whether pattern has an uppercase letter. In a regex,
+// escapes such as \S or \W are not literal letters and do not count.
+func hasUppercase(pattern string, regex bool) bool {
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			escaped = false
+		case regex && r == '\\':
+			escaped = true
+		case unicode.IsUpper(r):
+			return true
+		}
+	}
+	return false
+}
+
+func compilePattern(req SearchRequest) (*regexp.Regexp, error) {
+	if !req.Regex {
+		return nil, nil
+	}
+
+	flags := ""
+	if !req.CaseSensitive {
+		flags = "(?i)"
+	}
+	pattern, err := regexp.Compile(flags + req.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex: %w", err)
+	}
+	return pattern, nil
+}
+
+// searchScope searches only the files pre-selected from the index, keeping
+// matches that fall inside the scope's symbol ranges.
+func searchScope(ctx context.Context, req SearchRequest, scope *router.SearchScope, stream *matchStream) (interface{}, error) {
+	pattern, err := compilePattern(req)
+	if err != nil {
+		return nil, err
+	}
+
+	root := paths.Canonical(req.Path)
+	fileReq := req
+	if scope.Ranges != nil {
+		fileReq.MaxResults = math.MaxInt32
+	}
+
+	matches := []Match{}
+	for _, file := range scope.Files {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		if !req.Recursive && file != root && filepath.Dir(file) != root {
+			continue
+		}
+		if tools.IsPathDenied(file) || excluded(root, file, req.Exclude) {
+			continue
+		}
+
+		for _, m := range searchFile(file, fileReq, pattern) {
+			if !scope.Contains(file, m.Line) {
+				continue
+			}
+			matches = append(matches, m)
+			stream.add(m)
+			if len(matches) >= req.MaxResults {
+				break
+			}
+		}
+
+		if len(matches) >= req.MaxResults {
+			break
+		}
+	}
+
+	return &SearchResponse{
+		Matches: matches,
+		Count:   len(matches),
+		Path:    req.Path,
+	}, nil
+}
+
+func searchWithGo(ctx context.Context, req SearchRequest, stream *matchStream) (interface{}, error) {
+	pattern, err := compilePattern(req)
+	if err != nil {
+		return nil, err
 	}
 
 	matches := []Match{}
@@ -153,6 +617,13 @@ func searchWithGo(ctx context.Context, req SearchRequest) (interface{}, error) {
 			return nil
 		}
 
+		if path != req.Path && (tools.IsPathDenied(path) || excluded(req.Path, path, req.Exclude)) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
 		if d.IsDir() {
 			if !req.Recursive && path != req.Path {
 				return filepath.SkipDir
@@ -165,16 +636,21 @@ func searchWithGo(ctx context.Context, req SearchRequest) (interface{}, error) {
 		}
 		visited[path] = true
 
+		// archives are searched inside by searchArchives instead
+		if req.SearchArchives && archiveKindOf(d.Name()) != notArchive {
+			return nil
+		}
+
 		if len(matches) >= req.MaxResults {
 			return filepath.SkipDir
 		}
 
 		fileMatches := searchFile(path, req, pattern)
-		matches = append(matches, fileMatches...)
-
-		if len(matches) > req.MaxResults {
-			matches = matches[:req.MaxResults]
+		if len(matches)+len(fileMatches) > req.MaxResults {
+			fileMatches = fileMatches[:req.MaxResults-len(matches)]
 		}
+		matches = append(matches, fileMatches...)
+		stream.add(fileMatches...)
 
 		return nil
 	})
@@ -190,6 +666,67 @@ func searchWithGo(ctx context.Context, req SearchRequest) (interface{}, error) {
 	}, nil
 }
 
+// renderSearchText renders a search result the way grep prints it: one
+// file:line:column: content line per match with its context indented
+// under it, or one file (with its count) per line in the summary modes
+func renderSearchText(result interface{}) string {
+	var lines []string
+	switch resp := result.(type) {
+	case *SearchResponse:
+		for _, m := range resp.Matches {
+			lines = append(lines, fmt.Sprintf("%s:%d:%d: %s", m.File, m.Line, m.Column, strings.TrimRight(m.Content, "\r\n")))
+			for _, ctx := range m.Context {
+				lines = append(lines, "    "+ctx)
+			}
+		}
+	case *SearchSummary:
+		for _, f := range resp.Files {
+			if resp.OutputMode == outputCount {
+				lines = append(lines, fmt.Sprintf("%s: %d", f.File, f.Count))
+			} else {
+				lines = append(lines, f.File)
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// searchMatchCount returns the number of matching lines of a search result
+// or, in files_with_matches mode, of matching files
+func searchMatchCount(result interface{}) int {
+	switch resp := result.(type) {
+	case *SearchResponse:
+		return resp.Count
+	case *SearchSummary:
+		if resp.OutputMode == outputCount {
+			return resp.Count
+		}
+		return resp.FileCount
+	}
+	return 0
+}
+
+// excluded reports whether path, under the search root, matches one of the
+// exclude globs. As with ripgrep's --glob, globs with a slash match the path
+// relative to root and others match any of its names.
+func excluded(root, path string, patterns []string) bool {
+	if len(patterns) == 0 {
+		return false
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+
+	for _, pattern := range patterns {
+		if glob.Covers(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
 func searchFile(filePath string, req SearchRequest, pattern *regexp.Regexp) []Match {
 	fileInfo, err := os.Stat(filePath)
 	if err == nil && fileInfo.Size() > MaxGrepFileSize {
@@ -202,24 +739,25 @@ func searchFile(filePath string, req SearchRequest, pattern *regexp.Regexp) []Ma
 	}
 	defer file.Close()
 
-	matches := []Match{}
-	scanner := bufio.NewScanner(file)
-	lineNum := 0
+	return matchLines(filePath, scanLines(file), req, pattern)
+}
 
+// scanLines reads the lines of r without their terminators
+func scanLines(r io.Reader) []string {
 	var lines []string
+	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
-		lineNum++
-		line := scanner.Text()
-		lines = append(lines, line)
+		lines = append(lines, strings.TrimSuffix(scanner.Text(), "\r"))
 	}
+	return lines
+}
 
-	scanner = bufio.NewScanner(file)
-	file.Seek(0, 0)
-	lineNum = 0
-
-	for scanner.Scan() {
-		lineNum++
-		line := scanner.Text()
+// matchLines returns the matches of req among the lines of file, at most
+// max_results of them
+func matchLines(file string, lines []string, req SearchRequest, pattern *regexp.Regexp) []Match {
+	matches := []Match{}
+	for i, line := range lines {
+		lineNum := i + 1
 
 		var found bool
 		var column int
@@ -228,27 +766,30 @@ func searchFile(filePath string, req SearchRequest, pattern *regexp.Regexp) []Ma
 			loc := pattern.FindStringIndex(line)
 			if loc != nil {
 				found = true
-				column = loc[0] + 1
+				column = types.ColumnFromByte(line, loc[0])
 			}
 		} else {
 			searchStr := req.Pattern
 			if !req.CaseSensitive {
+				// lowering keeps the number of characters, so the column
+				// found in the lowered line holds for the line
 				searchStr = strings.ToLower(searchStr)
-				if idx := strings.Index(strings.ToLower(line), searchStr); idx >= 0 {
+				lower := strings.ToLower(line)
+				if idx := strings.Index(lower, searchStr); idx >= 0 {
 					found = true
-					column = idx + 1
+					column = types.ColumnFromByte(lower, idx)
 				}
 			} else {
 				if idx := strings.Index(line, searchStr); idx >= 0 {
 					found = true
-					column = idx + 1
+					column = types.ColumnFromByte(line, idx)
 				}
 			}
 		}
 
 		if found {
 			m := Match{
-				File:    filePath,
+				File:    file,
 				Line:    lineNum,
 				Column:  column,
 				Content: line,
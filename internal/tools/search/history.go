@@ -0,0 +1,305 @@
+package search
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// maxHistoryEntries is how many recent searches are kept
+const maxHistoryEntries = 500
+
+// HistoryEntry is one search that ran, with the request as it was sent
+type HistoryEntry struct {
+	ID         int64         `json:"id"`
+	Request    SearchRequest `json:"request"`
+	Count      int           `json:"count"`
+	FileCount  int           `json:"file_count"`
+	DurationMs int64         `json:"duration_ms"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
+// SavedSearch is a named search request that can be run again
+type SavedSearch struct {
+	Name        string        `json:"name"`
+	Description string        `json:"description,omitempty"`
+	Request     SearchRequest `json:"request"`
+	RunCount    int           `json:"run_count"`
+	CreatedAt   time.Time     `json:"created_at"`
+	LastRunAt   *time.Time    `json:"last_run_at,omitempty"`
+}
+
+// HistoryStore persists recent searches and saved searches
+type HistoryStore struct {
+	db *sql.DB
+	mu sync.RWMutex
+}
+
+func NewHistoryStore(dbPath string) (*HistoryStore, error) {
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Ping(); err != nil {
+		return nil, err
+	}
+
+	if _, err := db.Exec("PRAGMA journal_mode=WAL"); err != nil {
+		return nil, err
+	}
+
+	if _, err := db.Exec("PRAGMA busy_timeout=5000"); err != nil {
+		return nil, err
+	}
+
+	store := &HistoryStore{db: db}
+	if err := store.initSchema(); err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+func (s *HistoryStore) initSchema() error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS search_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		request TEXT NOT NULL,
+		pattern TEXT NOT NULL,
+		path TEXT NOT NULL,
+		result_count INTEGER NOT NULL,
+		file_count INTEGER NOT NULL,
+		duration_ms INTEGER NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS saved_searches (
+		name TEXT PRIMARY KEY,
+		description TEXT DEFAULT '',
+		request TEXT NOT NULL,
+		run_count INTEGER DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		last_run_at DATETIME
+	);
+	`
+
+	for _, stmt := range strings.Split(schema, ";") {
+		stmt = strings.TrimSpace(stmt)
+		if stmt == "" {
+			continue
+		}
+		if _, err := s.db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Record adds a search to the history, dropping the oldest entries beyond
+// maxHistoryEntries
+func (s *HistoryStore) Record(req SearchRequest, count, fileCount int, duration time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	request, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(
+		"INSERT INTO search_history (request, pattern, path, result_count, file_count, duration_ms) VALUES (?, ?, ?, ?, ?, ?)",
+		string(request), req.Pattern, req.Path, count, fileCount, duration.Milliseconds(),
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(
+		"DELETE FROM search_history WHERE id NOT IN (SELECT id FROM search_history ORDER BY id DESC LIMIT ?)",
+		maxHistoryEntries,
+	)
+	return err
+}
+
+// Recent returns the latest searches, newest first. A non-empty query keeps
+// searches whose pattern or path contains it.
+func (s *HistoryStore) Recent(limit int, query string) ([]HistoryEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query(
+		`SELECT id, request, result_count, file_count, duration_ms, created_at FROM search_history
+		WHERE ? = '' OR instr(pattern, ?) > 0 OR instr(path, ?) > 0
+		ORDER BY id DESC LIMIT ?`,
+		query, query, query, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []HistoryEntry{}
+	for rows.Next() {
+		entry, err := scanHistoryEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *entry)
+	}
+	return entries, rows.Err()
+}
+
+// Entry returns one history entry by id
+func (s *HistoryStore) Entry(id int64) (*HistoryEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	row := s.db.QueryRow(
+		"SELECT id, request, result_count, file_count, duration_ms, created_at FROM search_history WHERE id = ?",
+		id,
+	)
+	entry, err := scanHistoryEntry(row)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("search history entry %d not found", id)
+	}
+	return entry, err
+}
+
+func scanHistoryEntry(row interface{ Scan(...any) error }) (*HistoryEntry, error) {
+	entry := &HistoryEntry{}
+	var request string
+	if err := row.Scan(&entry.ID, &request, &entry.Count, &entry.FileCount, &entry.DurationMs, &entry.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(request), &entry.Request); err != nil {
+		return nil, fmt.Errorf("corrupt search history entry %d: %w", entry.ID, err)
+	}
+	return entry, nil
+}
+
+// Save stores a named search. An existing search with the same name is only
+// replaced when overwrite is set.
+func (s *HistoryStore) Save(name, description string, req SearchRequest, overwrite bool) (*SavedSearch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	request, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := "INSERT INTO saved_searches (name, description, request) VALUES (?, ?, ?)"
+	if overwrite {
+		stmt += " ON CONFLICT(name) DO UPDATE SET description = excluded.description, request = excluded.request"
+	}
+	if _, err := s.db.Exec(stmt, name, description, string(request)); err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return nil, fmt.Errorf("saved search '%s' already exists", name)
+		}
+		return nil, err
+	}
+
+	return s.saved(name)
+}
+
+// Saved returns a saved search by name
+func (s *HistoryStore) Saved(name string) (*SavedSearch, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.saved(name)
+}
+
+const selectSavedSearch = "SELECT name, description, request, run_count, created_at, last_run_at FROM saved_searches"
+
+func (s *HistoryStore) saved(name string) (*SavedSearch, error) {
+	saved, err := scanSavedSearch(s.db.QueryRow(selectSavedSearch+" WHERE name = ?", name))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("saved search '%s' not found", name)
+	}
+	return saved, err
+}
+
+// ListSaved returns every saved search by name
+func (s *HistoryStore) ListSaved() ([]SavedSearch, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query(selectSavedSearch + " ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	searches := []SavedSearch{}
+	for rows.Next() {
+		saved, err := scanSavedSearch(rows)
+		if err != nil {
+			return nil, err
+		}
+		searches = append(searches, *saved)
+	}
+	return searches, rows.Err()
+}
+
+func scanSavedSearch(row interface{ Scan(...any) error }) (*SavedSearch, error) {
+	saved := &SavedSearch{}
+	var request string
+	if err := row.Scan(&saved.Name, &saved.Description, &request, &saved.RunCount, &saved.CreatedAt, &saved.LastRunAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(request), &saved.Request); err != nil {
+		return nil, fmt.Errorf("corrupt saved search '%s': %w", saved.Name, err)
+	}
+	return saved, nil
+}
+
+// MarkRun counts a run of a saved search
+func (s *HistoryStore) MarkRun(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec(
+		"UPDATE saved_searches SET run_count = run_count + 1, last_run_at = CURRENT_TIMESTAMP WHERE name = ?",
+		name,
+	)
+	return err
+}
+
+// DeleteSaved removes a saved search
+func (s *HistoryStore) DeleteSaved(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, err := s.db.Exec("DELETE FROM saved_searches WHERE name = ?", name)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("saved search '%s' not found", name)
+	}
+	return nil
+}
+
+// Backup writes a consistent copy of the search history to dest
+func (s *HistoryStore) Backup(dest string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := os.Remove(dest); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	_, err := s.db.Exec("VACUUM INTO ?", dest)
+	return err
+}
+
+func (s *HistoryStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,323 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+type SearchHistoryTool struct {
+	store *HistoryStore
+}
+
+func NewSearchHistoryTool(store *HistoryStore) *SearchHistoryTool {
+	return &SearchHistoryTool{store: store}
+}
+
+func (t *SearchHistoryTool) Name() string {
+	return "search_history"
+}
+
+func (t *SearchHistoryTool) Description() string {
+	return "List recent searches, newest first, with their pattern, filters and result counts. Entries persist across sessions and can be saved by id with search_saved"
+}
+
+func (t *SearchHistoryTool) Title() string {
+	return "Search History"
+}
+
+func (t *SearchHistoryTool) Annotations() map[string]bool {
+	return tools.ReadOnlyAnnotations()
+}
+
+func (t *SearchHistoryTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"limit": {
+				"type": "integer",
+				"description": "Maximum number of entries (default: 20, max: 500)"
+			},
+			"query": {
+				"type": "string",
+				"description": "Only searches whose pattern or path contains this text"
+			}
+		}
+	}`)
+}
+
+func (t *SearchHistoryTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var req struct {
+		Limit int    `json:"limit"`
+		Query string `json:"query"`
+	}
+	if err := json.Unmarshal(input, &req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	if req.Limit <= 0 {
+		req.Limit = 20
+	}
+	if req.Limit > maxHistoryEntries {
+		req.Limit = maxHistoryEntries
+	}
+
+	entries, err := t.store.Recent(req.Limit, req.Query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read search history: %w", err)
+	}
+
+	return map[string]interface{}{
+		"total":    len(entries),
+		"searches": entries,
+	}, nil
+}
+
+type SavedSearchTool struct {
+	store  *HistoryStore
+	search *SearchTool
+}
+
+func NewSavedSearchTool(store *HistoryStore, search *SearchTool) *SavedSearchTool {
+	return &SavedSearchTool{store: store, search: search}
+}
+
+func (t *SavedSearchTool) Name() string {
+	return "search_saved"
+}
+
+func (t *SavedSearchTool) Description() string {
+	return `Manage named searches that can be re-run across sessions.
+
+ACTIONS:
+- list: all saved searches with their request and run count
+- save: save a search under name, from a search request (search) or a search_history entry (history_id); overwrite replaces an existing one
+- run: run a saved search and return its result
+- delete: remove a saved search`
+}
+
+func (t *SavedSearchTool) Title() string {
+	return "Saved Searches"
+}
+
+func (t *SavedSearchTool) Annotations() map[string]bool {
+	return tools.DestructiveAnnotations()
+}
+
+func (t *SavedSearchTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"action": {
+				"type": "string",
+				"enum": ["list", "save", "run", "delete"],
+				"description": "Action to perform"
+			},
+			"name": {
+				"type": "string",
+				"description": "Saved search name (required for save, run and delete)"
+			},
+			"description": {
+				"type": "string",
+				"description": "What the search is for (save)"
+			},
+			"search": {
+				"type": "object",
+				"description": "Search tool arguments to save (pattern, path, regex, output_mode, ...)"
+			},
+			"history_id": {
+				"type": "integer",
+				"description": "Save the search of this search_history entry instead of search"
+			},
+			"overwrite": {
+				"type": "boolean",
+				"description": "Replace an existing saved search with the same name (default: false)"
+			}
+		},
+		"required": ["action"]
+	}`)
+}
+
+type savedSearchRequest struct {
+	Action      string         `json:"action"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Search      *SearchRequest `json:"search"`
+	HistoryID   int64          `json:"history_id"`
+	Overwrite   bool           `json:"overwrite"`
+}
+
+func parseSavedSearchRequest(input json.RawMessage) (savedSearchRequest, error) {
+	var req savedSearchRequest
+	if err := json.Unmarshal(input, &req); err != nil {
+		return req, fmt.Errorf("invalid request: %w", err)
+	}
+
+	switch req.Action {
+	case "list":
+	case "save", "run", "delete":
+		if req.Name == "" {
+			return req, fmt.Errorf("name is required for %s", req.Action)
+		}
+	default:
+		return req, fmt.Errorf("invalid action %q: expected list, save, run or delete", req.Action)
+	}
+	return req, nil
+}
+
+// searchToSave resolves the request a save action stores
+func (t *SavedSearchTool) searchToSave(req savedSearchRequest) (SearchRequest, error) {
+	var search SearchRequest
+	switch {
+	case req.Search != nil && req.HistoryID != 0:
+		return search, fmt.Errorf("use either search or history_id, not both")
+	case req.Search != nil:
+		search = *req.Search
+	case req.HistoryID != 0:
+		entry, err := t.store.Entry(req.HistoryID)
+		if err != nil {
+			return search, err
+		}
+		search = entry.Request
+	default:
+		return search, fmt.Errorf("save requires search or history_id")
+	}
+
+	if search.Pattern == "" || search.Path == "" {
+		return search, fmt.Errorf("saved search needs a pattern and a path")
+	}
+	return search, nil
+}
+
+func (t *SavedSearchTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	req, err := parseSavedSearchRequest(input)
+	if err != nil {
+		return nil, err
+	}
+
+	switch req.Action {
+	case "list":
+		searches, err := t.store.ListSaved()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list saved searches: %w", err)
+		}
+		return map[string]interface{}{
+			"total":    len(searches),
+			"searches": searches,
+		}, nil
+
+	case "save":
+		search, err := t.searchToSave(req)
+		if err != nil {
+			return nil, err
+		}
+		saved, err := t.store.Save(req.Name, req.Description, search, req.Overwrite)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"success": true,
+			"search":  saved,
+		}, nil
+
+	case "run":
+		saved, err := t.store.Saved(req.Name)
+		if err != nil {
+			return nil, err
+		}
+		searchInput, err := json.Marshal(saved.Request)
+		if err != nil {
+			return nil, err
+		}
+		result, err := t.search.Execute(ctx, searchInput)
+		if err != nil {
+			return nil, err
+		}
+		if err := t.store.MarkRun(req.Name); err != nil {
+			return nil, fmt.Errorf("failed to update saved search: %w", err)
+		}
+		return map[string]interface{}{
+			"name":   req.Name,
+			"result": result,
+		}, nil
+
+	default:
+		if err := t.store.DeleteSaved(req.Name); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"success": true,
+			"deleted": req.Name,
+		}, nil
+	}
+}
+
+func (t *SavedSearchTool) DryRun(ctx context.Context, input json.RawMessage) (*tools.Preview, error) {
+	req, err := parseSavedSearchRequest(input)
+	if err != nil {
+		return nil, err
+	}
+
+	savedPath := "search://saved/" + req.Name
+
+	switch req.Action {
+	case "list":
+		listing, err := t.Execute(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		return &tools.Preview{Summary: "list makes no changes", Details: listing}, nil
+
+	case "save":
+		search, err := t.searchToSave(req)
+		if err != nil {
+			return nil, err
+		}
+		preview := &tools.Preview{Details: map[string]interface{}{"request": search}}
+		if _, err := t.store.Saved(req.Name); err != nil {
+			preview.Summary = fmt.Sprintf("would save search %s", req.Name)
+			preview.Created = []string{savedPath}
+		} else if req.Overwrite {
+			preview.Summary = fmt.Sprintf("would replace saved search %s", req.Name)
+			preview.Modified = []string{savedPath}
+		} else {
+			return nil, fmt.Errorf("saved search '%s' already exists", req.Name)
+		}
+		return preview, nil
+
+	case "run":
+		// A search changes no files, so the preview runs it, skipping the
+		// history and run count
+		saved, err := t.store.Saved(req.Name)
+		if err != nil {
+			return nil, err
+		}
+		result, err := t.search.search(ctx, saved.Request, nil)
+		if err != nil {
+			return nil, err
+		}
+		return &tools.Preview{
+			Summary: fmt.Sprintf("ran saved search %s without recording it", req.Name),
+			Details: map[string]interface{}{"name": req.Name, "result": result},
+		}, nil
+
+	default:
+		if _, err := t.store.Saved(req.Name); err != nil {
+			return nil, err
+		}
+		return &tools.Preview{
+			Summary: fmt.Sprintf("would delete saved search %s", req.Name),
+			Deleted: []string{savedPath},
+		}, nil
+	}
+}
@@ -0,0 +1,135 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/alucardeht/may-la-mcp/internal/router"
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+type HoverRequest struct {
+	Path      string `json:"path"`
+	Line      int    `json:"line,omitempty"`
+	Column    int    `json:"column,omitempty"`
+	Symbol    string `json:"symbol,omitempty"`
+	MaxLength int    `json:"max_length,omitempty"`
+}
+
+type HoverResponse struct {
+	Symbol        string `json:"symbol,omitempty"`
+	File          string `json:"file"`
+	Line          int    `json:"line"`
+	Signature     string `json:"signature,omitempty"`
+	Documentation string `json:"documentation,omitempty"`
+	Source        string `json:"source"`
+	Fallback      bool   `json:"fallback,omitempty"`
+	Truncated     bool   `json:"truncated,omitempty"`
+	LatencyMs     int64  `json:"latency_ms"`
+}
+
+type HoverTool struct {
+	router *router.Router
+}
+
+func NewHoverTool(r *router.Router) *HoverTool {
+	return &HoverTool{router: r}
+}
+
+func (t *HoverTool) Name() string {
+	return "hover"
+}
+
+func (t *HoverTool) Description() string {
+	return "Get the type signature and documentation of a symbol, at a file position or by name, from the language server with the indexed signature and doc comment as fallback"
+}
+
+func (t *HoverTool) Title() string {
+	return "Symbol Hover Info"
+}
+
+func (t *HoverTool) Annotations() map[string]bool {
+	return tools.ReadOnlyAnnotations()
+}
+
+func (t *HoverTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"path": {
+				"type": "string",
+				"description": "File holding the position, or with symbol the file or directory to prefer definitions from"
+			},
+			"line": {
+				"type": "integer",
+				"description": "Line number (1-based); required without symbol"
+			},
+			"column": {
+				"type": "integer",
+				"description": "Column in characters (1-based); required without symbol"
+			},
+			"symbol": {
+				"type": "string",
+				"description": "Symbol name to look up in the index instead of a position"
+			},
+			"max_length": {
+				"type": "integer",
+				"description": "Maximum characters of signature and of documentation (default: 2000)"
+			}
+		},
+		"required": ["path"]
+	}`)
+}
+
+func (t *HoverTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var req HoverRequest
+	if err := json.Unmarshal(input, &req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	if req.Path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+	if req.Symbol == "" && (req.Line == 0 || req.Column == 0) {
+		return nil, fmt.Errorf("line and column are required without symbol")
+	}
+	if req.MaxLength <= 0 {
+		req.MaxLength = 2000
+	}
+
+	if err := tools.CheckPath(req.Path); err != nil {
+		return nil, err
+	}
+
+	if t.router == nil {
+		return nil, fmt.Errorf("hover needs the index or a language server")
+	}
+
+	result, err := t.router.QueryHover(ctx, router.HoverQuery{
+		Path:      req.Path,
+		Line:      req.Line,
+		Column:    req.Column,
+		Symbol:    req.Symbol,
+		MaxLength: req.MaxLength,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("hover: %w", err)
+	}
+
+	return &HoverResponse{
+		Symbol:        result.Symbol,
+		File:          result.File,
+		Line:          result.Line,
+		Signature:     result.Signature,
+		Documentation: result.Documentation,
+		Source:        string(result.Source),
+		Fallback:      result.Fallback,
+		Truncated:     result.Truncated,
+		LatencyMs:     result.Latency.Milliseconds(),
+	}, nil
+}
@@ -0,0 +1,151 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/alucardeht/may-la-mcp/internal/router"
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+type ImplementationsRequest struct {
+	Path       string `json:"path"`
+	Line       int    `json:"line,omitempty"`
+	Column     int    `json:"column,omitempty"`
+	Symbol     string `json:"symbol,omitempty"`
+	Root       string `json:"root,omitempty"`
+	MaxResults int    `json:"max_results,omitempty"`
+}
+
+type ImplementationsResponse struct {
+	Symbol    string                       `json:"symbol"`
+	Packages  []router.ImplementationGroup `json:"packages"`
+	Count     int                          `json:"count"`
+	Source    string                       `json:"source"`
+	Fallback  bool                         `json:"fallback,omitempty"`
+	LatencyMs int64                        `json:"latency_ms"`
+}
+
+type ImplementationsTool struct {
+	router *router.Router
+}
+
+func NewImplementationsTool(r *router.Router) *ImplementationsTool {
+	return &ImplementationsTool{router: r}
+}
+
+func (t *ImplementationsTool) Name() string {
+	return "implementations"
+}
+
+func (t *ImplementationsTool) Description() string {
+	return "Find the types implementing a Go interface, TypeScript interface or abstract class, or Python abstract class or protocol, grouped by package: from the Go type checker or the language server, with declaration heuristics as fallback"
+}
+
+func (t *ImplementationsTool) Title() string {
+	return "Interface Implementations"
+}
+
+func (t *ImplementationsTool) Annotations() map[string]bool {
+	return tools.ReadOnlyAnnotations()
+}
+
+func (t *ImplementationsTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"path": {
+				"type": "string",
+				"description": "File holding the position, or with symbol the file or directory to prefer definitions from"
+			},
+			"line": {
+				"type": "integer",
+				"description": "Line number (1-based); required without symbol"
+			},
+			"column": {
+				"type": "integer",
+				"description": "Column in characters (1-based); required without symbol"
+			},
+			"symbol": {
+				"type": "string",
+				"description": "Interface, abstract class or protocol name instead of a position"
+			},
+			"root": {
+				"type": "string",
+				"description": "Directory the fallback heuristics search (default: the directory of path)"
+			},
+			"max_results": {
+				"type": "integer",
+				"description": "Maximum number of implementations (default: 200)"
+			}
+		},
+		"required": ["path"]
+	}`)
+}
+
+func (t *ImplementationsTool) Examples() []tools.Example {
+	return []tools.Example{
+		{
+			Description: "Classes implementing a TypeScript interface, by name",
+			Arguments:   json.RawMessage(`{"path": "/home/user/app/src", "symbol": "Repository"}`),
+			Result:      json.RawMessage(`{"symbol": "Repository", "packages": [{"package": "db", "items": [{"name": "SqlRepository", "kind": "class", "file": "/home/user/app/src/db/sql.ts", "line": 8, "column": 14}]}], "count": 1, "source": "lsp"}`),
+		},
+	}
+}
+
+func (t *ImplementationsTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var req ImplementationsRequest
+	if err := json.Unmarshal(input, &req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	if req.Path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+	if req.Symbol == "" && (req.Line == 0 || req.Column == 0) {
+		return nil, fmt.Errorf("line and column are required without symbol")
+	}
+	if req.MaxResults <= 0 {
+		req.MaxResults = 200
+	}
+
+	if err := tools.CheckPath(req.Path); err != nil {
+		return nil, err
+	}
+	if req.Root != "" {
+		if err := tools.CheckPath(req.Root); err != nil {
+			return nil, err
+		}
+	}
+
+	// without a router the declaration heuristics still answer
+	r := t.router
+	if r == nil {
+		r = router.NewRouter(nil, nil)
+	}
+	result, err := r.QueryImplementations(ctx, router.ImplementationsQuery{
+		Path:       req.Path,
+		Line:       req.Line,
+		Column:     req.Column,
+		Symbol:     req.Symbol,
+		Root:       req.Root,
+		MaxResults: req.MaxResults,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("implementations: %w", err)
+	}
+
+	return &ImplementationsResponse{
+		Symbol:    result.Symbol,
+		Packages:  result.Groups,
+		Count:     result.Count,
+		Source:    string(result.Source),
+		Fallback:  result.Fallback,
+		LatencyMs: result.Latency.Milliseconds(),
+	}, nil
+}
@@ -0,0 +1,83 @@
+package search
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/intel"
+)
+
+// packMatches keeps the matches of resp that intel.PackContext fits into
+// maxTokens, in their original order. Matches rank higher in files with
+// more matches, files modified recently and files near root.
+func packMatches(resp *SearchResponse, root string, maxTokens int) {
+	perFile := make(map[string]int)
+	for _, m := range resp.Matches {
+		perFile[m.File]++
+	}
+	modTimes := make(map[string]time.Time, len(perFile))
+	for file := range perFile {
+		if info, err := os.Stat(file); err == nil {
+			modTimes[file] = info.ModTime()
+		}
+	}
+
+	snippets := make([]intel.Snippet, len(resp.Matches))
+	for i, m := range resp.Matches {
+		snippets[i] = intel.Snippet{
+			ID:        matchID(m),
+			Source:    "search",
+			Content:   matchText(m),
+			Relevance: float64(perFile[m.File]),
+			Timestamp: modTimes[m.File],
+			Frequency: perFile[m.File],
+			Proximity: 1 / float64(1+depthBelow(root, m.File)),
+		}
+	}
+
+	packed := intel.PackContext(snippets, maxTokens, intel.RankCriteria{})
+
+	kept := make(map[string]bool, len(packed.Snippets))
+	for _, snippet := range packed.Snippets {
+		kept[snippet.ID] = true
+	}
+	matches := make([]Match, 0, len(packed.Snippets))
+	for _, m := range resp.Matches {
+		if kept[matchID(m)] {
+			matches = append(matches, m)
+		}
+	}
+
+	resp.Matches = matches
+	resp.Count = len(matches)
+	resp.Tokens = packed.Tokens
+	if packed.Omitted.Count > 0 {
+		resp.Omitted = &packed.Omitted
+	}
+}
+
+// matchID names a match by its location
+func matchID(m Match) string {
+	return fmt.Sprintf("%s:%d:%d", m.File, m.Line, m.Column)
+}
+
+// matchText is the text a match adds to the response, used to estimate its
+// tokens and to spot repeated matches
+func matchText(m Match) string {
+	text := fmt.Sprintf("%s:%d: %s", m.File, m.Line, m.Content)
+	if len(m.Context) > 0 {
+		text += "\n" + strings.Join(m.Context, "\n")
+	}
+	return text
+}
+
+func depthBelow(root, path string) int {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return 0
+	}
+	return strings.Count(rel, string(filepath.Separator))
+}
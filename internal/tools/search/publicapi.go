@@ -0,0 +1,370 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/gitignore"
+	"github.com/alucardeht/may-la-mcp/internal/index"
+	"github.com/alucardeht/may-la-mcp/internal/intel"
+	"github.com/alucardeht/may-la-mcp/internal/router"
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+	"github.com/alucardeht/may-la-mcp/internal/types"
+)
+
+const (
+	defaultAPISymbols = 500
+
+	// maxSignatureLines bounds how far a declaration split over several
+	// lines is followed to complete its signature
+	maxSignatureLines = 10
+)
+
+// goReceiver captures the receiver type of a Go method declaration
+var goReceiver = regexp.MustCompile(`^func\s*\(\s*(?:\w+\s+)?\*?\s*([A-Za-z_]\w*)`)
+
+// parseVisibility checks the visibility filter of symbols and file_summary,
+// defaulting to def
+func parseVisibility(visibility, def string) (string, error) {
+	switch visibility {
+	case "":
+		return def, nil
+	case "all", "exported":
+		return visibility, nil
+	default:
+		return "", fmt.Errorf("unknown visibility %q (expected exported or all)", visibility)
+	}
+}
+
+// exportChecker tells exported symbols from the rest by their declaration
+// line, reading each file once
+type exportChecker struct {
+	lines map[string][]string
+}
+
+func newExportChecker() *exportChecker {
+	return &exportChecker{lines: make(map[string][]string)}
+}
+
+func (c *exportChecker) exported(sym types.Symbol) bool {
+	lines, ok := c.lines[sym.File]
+	if !ok {
+		if content, _, err := index.ReadFileAsUTF8(sym.File); err == nil {
+			lines = strings.Split(content, "\n")
+		}
+		c.lines[sym.File] = lines
+	}
+
+	decl := strings.TrimSpace(sym.Signature)
+	if sym.Cell == nil && sym.Line >= 1 && sym.Line <= len(lines) {
+		decl = strings.TrimSpace(lines[sym.Line-1])
+	}
+	return exportedDecl(sym.Name, decl, index.DetectLanguage(sym.File))
+}
+
+// filterExported keeps the exported symbols, at most maxResults of them
+func filterExported(symbols []types.Symbol, maxResults int) []types.Symbol {
+	checker := newExportChecker()
+	kept := []types.Symbol{}
+	for _, sym := range symbols {
+		if len(kept) == maxResults {
+			break
+		}
+		if checker.exported(sym) {
+			kept = append(kept, sym)
+		}
+	}
+	return kept
+}
+
+type PublicAPIRequest struct {
+	Path         string `json:"path"`
+	Recursive    bool   `json:"recursive,omitempty"`
+	IncludeTests bool   `json:"include_tests,omitempty"`
+	MaxResults   int    `json:"max_results,omitempty"`
+}
+
+type APISymbol struct {
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`
+	Line      int    `json:"line"`
+	Signature string `json:"signature"`
+	Doc       string `json:"doc,omitempty"`
+}
+
+type APIFile struct {
+	File     string      `json:"file"`
+	Language string      `json:"language"`
+	Symbols  []APISymbol `json:"symbols"`
+}
+
+type PublicAPIResponse struct {
+	Path      string    `json:"path"`
+	Files     []APIFile `json:"files"`
+	Count     int       `json:"count"`
+	Truncated bool      `json:"truncated,omitempty"`
+	Source    string    `json:"source"`
+	LatencyMs int64     `json:"latency_ms"`
+}
+
+type PublicAPITool struct {
+	router *router.Router
+}
+
+func NewPublicAPITool(r *router.Router) *PublicAPITool {
+	return &PublicAPITool{router: r}
+}
+
+func (t *PublicAPITool) Name() string {
+	return "public_api"
+}
+
+func (t *PublicAPITool) Description() string {
+	return `List the exported surface of a package or module: every exported type, function, method, constant and variable with its full signature and doc comment, file by file.
+
+This is the view to read before writing code against a package. Exported means what the language makes visible outside the package: capitalized names in Go, pub items in Rust, non-private members in Java, Kotlin, Scala and C#, and names without a leading underscore elsewhere; Go methods of unexported types are left out. path is a file or a directory; a directory lists the files directly in it unless recursive is set. Test files are skipped unless include_tests is set.`
+}
+
+func (t *PublicAPITool) Title() string {
+	return "Public API"
+}
+
+func (t *PublicAPITool) Annotations() map[string]bool {
+	return tools.ReadOnlyAnnotations()
+}
+
+func (t *PublicAPITool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"path": {
+				"type": "string",
+				"description": "Source file, or directory of the package or module"
+			},
+			"recursive": {
+				"type": "boolean",
+				"description": "Also list the files of subdirectories, for a module spanning several (default: false)"
+			},
+			"include_tests": {
+				"type": "boolean",
+				"description": "Also list test files (default: false)"
+			},
+			"max_results": {
+				"type": "integer",
+				"description": "Maximum number of symbols (default: 500)"
+			}
+		},
+		"required": ["path"]
+	}`)
+}
+
+func (t *PublicAPITool) OutputSchema() json.RawMessage {
+	return tools.OutputSchemaOf(PublicAPIResponse{})
+}
+
+func (t *PublicAPITool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var req PublicAPIRequest
+	if err := json.Unmarshal(input, &req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+	if req.Path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+	if req.MaxResults <= 0 {
+		req.MaxResults = defaultAPISymbols
+	}
+	if err := tools.CheckPath(req.Path); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	files, err := apiFiles(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &PublicAPIResponse{Path: req.Path, Files: []APIFile{}, Source: string(router.SourceRegex)}
+	for _, path := range files {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		symbols, source, err := fileSymbols(ctx, t.router, path)
+		if err != nil {
+			return nil, err
+		}
+		content, _, err := index.ReadFileAsUTF8(path)
+		if err != nil {
+			continue
+		}
+		api := apiSymbols(symbols, strings.Split(content, "\n"), index.DetectLanguage(path))
+		if len(api) == 0 {
+			continue
+		}
+		if resp.Count == req.MaxResults {
+			resp.Truncated = true
+			break
+		}
+		if room := req.MaxResults - resp.Count; len(api) > room {
+			api = api[:room]
+			resp.Truncated = true
+		}
+
+		resp.Files = append(resp.Files, APIFile{File: path, Language: index.DetectLanguage(path), Symbols: api})
+		resp.Count += len(api)
+		resp.Source = source
+	}
+
+	resp.LatencyMs = time.Since(start).Milliseconds()
+	return resp, nil
+}
+
+// apiFiles lists the source files the request covers, sorted, leaving out
+// ignored paths and, unless asked for, tests
+func apiFiles(ctx context.Context, req PublicAPIRequest) ([]string, error) {
+	info, err := os.Stat(req.Path)
+	if err != nil {
+		return nil, fmt.Errorf("path not found: %w", err)
+	}
+	if !info.IsDir() {
+		return []string{req.Path}, nil
+	}
+
+	var files []string
+	matcher := gitignore.NewMatcher(req.Path)
+	err = filepath.WalkDir(req.Path, func(path string, d os.DirEntry, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil || path == req.Path {
+			return nil
+		}
+		if tools.IsPathDenied(path) || matcher.Ignored(path, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if !req.Recursive {
+				return filepath.SkipDir
+			}
+			matcher.Enter(path)
+			return nil
+		}
+
+		lang := index.DetectLanguage(path)
+		if lang == "" || lang == "markdown" || (!req.IncludeTests && isTestSource(path)) {
+			return nil
+		}
+		files = append(files, path)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walk error: %w", err)
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// apiSymbols keeps the exported declarations of symbols in line order, each
+// with its whole signature and doc comment
+func apiSymbols(symbols []types.Symbol, lines []string, lang string) []APISymbol {
+	sorted := append([]types.Symbol(nil), symbols...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Line < sorted[j].Line
+	})
+
+	api := []APISymbol{}
+	seen := make(map[string]bool)
+	for _, sym := range sorted {
+		if sym.Kind == types.KindField || sym.Cell != nil || sym.Line < 1 || sym.Line > len(lines) {
+			continue
+		}
+		key := fmt.Sprintf("%s:%d", sym.Name, sym.Line)
+		if seen[key] {
+			continue
+		}
+
+		decl := strings.TrimSpace(lines[sym.Line-1])
+		if !exportedDecl(sym.Name, decl, lang) {
+			continue
+		}
+		if m := goReceiver.FindStringSubmatch(decl); lang == "go" && m != nil && !exportedDecl(m[1], decl, lang) {
+			continue
+		}
+		seen[key] = true
+
+		doc := intel.DocComment(lines, sym.Line)
+		if doc == "" {
+			doc = sym.Documentation
+		}
+		api = append(api, APISymbol{
+			Name:      sym.Name,
+			Kind:      sym.Kind,
+			Line:      sym.Line,
+			Signature: signature(lines, sym.Line),
+			Doc:       doc,
+		})
+	}
+	return api
+}
+
+// signature returns the declaration starting on line (1-based), followed
+// over the next lines while its parentheses are open, up to its body
+func signature(lines []string, line int) string {
+	var parts []string
+	depth := 0
+	for i := line - 1; i < len(lines) && i < line-1+maxSignatureLines; i++ {
+		text := strings.TrimSpace(lines[i])
+		parts = append(parts, text)
+		depth += strings.Count(text, "(") + strings.Count(text, "[") - strings.Count(text, ")") - strings.Count(text, "]")
+		if depth <= 0 {
+			break
+		}
+	}
+
+	sig := strings.Join(parts, " ")
+	sig = strings.ReplaceAll(sig, "( ", "(")
+	sig = strings.ReplaceAll(sig, ", )", ")")
+	sig = strings.ReplaceAll(sig, ",)", ")")
+
+	// the body starts at the first brace outside the parameter list
+	depth = 0
+	for i, r := range sig {
+		switch r {
+		case '(', '[':
+			depth++
+		case ')', ']':
+			depth--
+		case '{':
+			if depth == 0 {
+				return strings.TrimSpace(sig[:i])
+			}
+		}
+	}
+	return strings.TrimSpace(sig)
+}
+
+// isTestSource reports whether path is a test file by the naming
+// conventions of the common test runners
+func isTestSource(path string) bool {
+	base := filepath.Base(path)
+	return strings.HasSuffix(base, "_test.go") ||
+		strings.HasPrefix(base, "test_") ||
+		strings.HasSuffix(base, "_test.py") ||
+		strings.Contains(base, ".test.") ||
+		strings.Contains(base, ".spec.")
+}
@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/alucardeht/may-la-mcp/internal/router"
 	"github.com/alucardeht/may-la-mcp/internal/tools"
@@ -15,16 +16,21 @@ import (
 )
 
 type ReferencesRequest struct {
-	Symbol     string `json:"symbol"`
-	Path       string `json:"path"`
-	Recursive  bool   `json:"recursive,omitempty"`
-	MaxResults int    `json:"max_results,omitempty"`
+	Symbol        string `json:"symbol"`
+	Path          string `json:"path"`
+	Recursive     bool   `json:"recursive,omitempty"`
+	MaxResults    int    `json:"max_results,omitempty"`
+	Source        string `json:"source,omitempty"`
+	AllowFallback *bool  `json:"allow_fallback,omitempty"`
 }
 
 type ReferencesResponse struct {
 	References []types.Reference `json:"references"`
 	Count      int               `json:"count"`
 	Symbol     string            `json:"symbol"`
+	Source     string            `json:"source"`
+	Fallback   bool              `json:"fallback,omitempty"`
+	LatencyMs  int64             `json:"latency_ms"`
 }
 
 type ReferencesTool struct {
@@ -67,6 +73,15 @@ func (t *ReferencesTool) Schema() json.RawMessage {
 				"type": "boolean",
 				"description": "Search subdirs"
 			},
+			"source": {
+				"type": "string",
+				"enum": ["auto", "index", "go", "lsp", "regex"],
+				"description": "Force a single lookup tier when debugging results (default: auto tries index, the Go type checker for Go modules, LSP, then regex)"
+			},
+			"allow_fallback": {
+				"type": "boolean",
+				"description": "Fall back to regex when the selected tiers find nothing (default: true for auto, false for index/go/lsp)"
+			},
 			"max_results": {
 				"type": "integer",
 				"description": "Maximum number of results (default: 1000)"
@@ -92,6 +107,9 @@ func (t *ReferencesTool) Execute(ctx context.Context, input json.RawMessage) (in
 	if req.Path == "" {
 		return nil, fmt.Errorf("path is required")
 	}
+	if err := tools.CheckPath(req.Path); err != nil {
+		return nil, err
+	}
 
 	if req.MaxResults == 0 {
 		req.MaxResults = 1000
@@ -99,9 +117,9 @@ func (t *ReferencesTool) Execute(ctx context.Context, input json.RawMessage) (in
 
 	// Use the passed context to respect timeouts - DO NOT override with context.Background()
 
-	opts := router.QueryOptions{
-		MaxResults:    req.MaxResults,
-		AllowFallback: true,
+	opts, err := routerOptions(req.MaxResults, req.Source, req.AllowFallback)
+	if err != nil {
+		return nil, err
 	}
 
 	if t.router != nil {
@@ -125,6 +143,9 @@ func (t *ReferencesTool) Execute(ctx context.Context, input json.RawMessage) (in
 			References: references,
 			Count:      len(references),
 			Symbol:     req.Symbol,
+			Source:     string(result.Source),
+			Fallback:   result.Fallback,
+			LatencyMs:  result.Latency.Milliseconds(),
 		}, nil
 	}
 
@@ -132,6 +153,7 @@ func (t *ReferencesTool) Execute(ctx context.Context, input json.RawMessage) (in
 }
 
 func (t *ReferencesTool) executeRegex(ctx context.Context, symbol, path string, maxResults int) (interface{}, error) {
+	start := time.Now()
 	result, err := findReferencesRegex(ctx, symbol, path, maxResults)
 	if err != nil {
 		return nil, fmt.Errorf("find references: %w", err)
@@ -141,6 +163,8 @@ func (t *ReferencesTool) executeRegex(ctx context.Context, symbol, path string,
 		References: result,
 		Count:      len(result),
 		Symbol:     symbol,
+		Source:     string(router.SourceRegex),
+		LatencyMs:  time.Since(start).Milliseconds(),
 	}, nil
 }
 
@@ -177,12 +201,12 @@ func findReferencesRegex(ctx context.Context, symbol string, searchPath string,
 			return nil
 		}
 
-		lines := strings.Split(string(content), "\n")
+		lines := types.SplitLines(string(content))
 		for lineNum, line := range lines {
 			if pattern.MatchString(line) {
 				locs := pattern.FindAllStringIndex(line, -1)
 				for _, loc := range locs {
-					column := loc[0] + 1
+					column := types.ColumnFromByte(line, loc[0])
 					kind := classifyReferenceKind(line, loc[0], symbol)
 
 					references = append(references, types.Reference{
@@ -214,13 +238,13 @@ func classifyReferenceKind(line string, position int, symbol string) string {
 	beforeContext := line[:position]
 
 	if strings.Contains(beforeContext, "//") {
-		return "comment"
+		return types.RefComment
 	}
 
 	if strings.Contains(beforeContext, "\"") || strings.Contains(beforeContext, "'") {
 		quoteCount := strings.Count(beforeContext, "\"") + strings.Count(beforeContext, "'")
 		if quoteCount%2 != 0 {
-			return "string"
+			return types.RefString
 		}
 	}
 
@@ -229,13 +253,13 @@ func classifyReferenceKind(line string, position int, symbol string) string {
 	if strings.HasSuffix(beforeTrimmed, "func") || strings.HasSuffix(beforeTrimmed, "type") ||
 		strings.HasSuffix(beforeTrimmed, "const") || strings.HasSuffix(beforeTrimmed, "var") ||
 		strings.HasSuffix(beforeTrimmed, "class") || strings.HasSuffix(beforeTrimmed, "interface") {
-		return "definition"
+		return types.RefDefinition
 	}
 
 	if strings.HasSuffix(beforeTrimmed, "import") || strings.HasSuffix(beforeTrimmed, "from") ||
 		strings.HasSuffix(beforeTrimmed, "require") {
-		return "import"
+		return types.RefImport
 	}
 
-	return "usage"
+	return types.RefUsage
 }
@@ -3,12 +3,18 @@ package search
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 	"sync"
+
+	"github.com/alucardeht/may-la-mcp/internal/glob"
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+	"github.com/alucardeht/may-la-mcp/internal/types"
 )
 
 type ripgrepResult struct {
@@ -20,7 +26,12 @@ type ripgrepData struct {
 	Path   ripgrepPath `json:"path"`
 	Lines  ripgrepLines `json:"lines"`
 	LineNum uint64 `json:"line_number"`
-	Column  uint64 `json:"column"`
+	// Submatches give byte offsets into Lines.Text; rg reports no column
+	Submatches []ripgrepSubmatch `json:"submatches"`
+}
+
+type ripgrepSubmatch struct {
+	Start int `json:"start"`
 }
 
 type ripgrepPath struct {
@@ -44,7 +55,12 @@ func isRipgrepAvailable() bool {
 	return rgAvailable
 }
 
-func executeRipgrep(req SearchRequest) (*SearchResponse, error) {
+// maxRipgrepLine bounds one line of ripgrep's JSON output
+const maxRipgrepLine = 64 << 20
+
+// executeRipgrep runs a content search with ripgrep, reading its output as
+// it comes so matches reach stream while ripgrep is still searching
+func executeRipgrep(ctx context.Context, req SearchRequest, stream *matchStream) (*SearchResponse, error) {
 	if !isRipgrepAvailable() {
 		return nil, fmt.Errorf("ripgrep not available")
 	}
@@ -53,16 +69,8 @@ func executeRipgrep(req SearchRequest) (*SearchResponse, error) {
 		"--json",
 		"--color=never",
 	}
-
-	if !req.CaseSensitive {
-		args = append(args, "-i")
-	}
-
-	if req.Regex {
-		args = append(args, "-e", req.Pattern)
-	} else {
-		args = append(args, "-F", req.Pattern)
-	}
+	args = append(args, ripgrepPatternArgs(req)...)
+	args = append(args, ripgrepExcludeArgs(req)...)
 
 	if req.MaxResults > 0 {
 		args = append(args, fmt.Sprintf("--max-count=%d", req.MaxResults))
@@ -70,36 +78,48 @@ func executeRipgrep(req SearchRequest) (*SearchResponse, error) {
 
 	args = append(args, req.Path)
 
-	cmd := exec.Command("rg", args...)
-	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, "rg", args...)
 	var stderr bytes.Buffer
-	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
-
-	err := cmd.Run()
-	if err != nil && !strings.Contains(err.Error(), "exit status 1") {
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("ripgrep error: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
 		return nil, fmt.Errorf("ripgrep error: %w", err)
 	}
 
 	matches := []Match{}
-	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRipgrepLine)
 
-	for _, line := range lines {
-		if line == "" {
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
 			continue
 		}
 
 		var result ripgrepResult
-		if err := json.Unmarshal([]byte(line), &result); err != nil {
+		if err := json.Unmarshal(line, &result); err != nil {
 			continue
 		}
 
 		if result.Type == "match" {
+			if tools.IsPathDenied(result.Data.Path.Text) {
+				continue
+			}
+
+			text := strings.TrimRight(result.Data.Lines.Text, "\r\n")
+			column := 1
+			if len(result.Data.Submatches) > 0 {
+				column = types.ColumnFromByte(text, result.Data.Submatches[0].Start)
+			}
+
 			match := Match{
 				File:    result.Data.Path.Text,
 				Line:    int(result.Data.LineNum),
-				Column:  int(result.Data.Column),
-				Content: result.Data.Lines.Text,
+				Column:  column,
+				Content: text,
 			}
 
 			if req.ContextLines > 0 {
@@ -107,9 +127,24 @@ func executeRipgrep(req SearchRequest) (*SearchResponse, error) {
 			}
 
 			matches = append(matches, match)
+			stream.add(match)
 		}
 	}
 
+	scanErr := scanner.Err()
+	if scanErr != nil {
+		cmd.Process.Kill()
+	}
+	err = cmd.Wait()
+	if scanErr != nil {
+		err = scanErr
+	}
+	// Matches already streamed cannot be taken back, so a run failing after
+	// sending some returns them instead of falling back to the Go engine
+	if err != nil && !strings.Contains(err.Error(), "exit status 1") && (stream == nil || len(matches) == 0) {
+		return nil, fmt.Errorf("ripgrep error: %w", err)
+	}
+
 	return &SearchResponse{
 		Matches: matches,
 		Count:   len(matches),
@@ -117,6 +152,88 @@ func executeRipgrep(req SearchRequest) (*SearchResponse, error) {
 	}, nil
 }
 
+// ripgrepPatternArgs returns the flags that select what ripgrep matches
+func ripgrepPatternArgs(req SearchRequest) []string {
+	var args []string
+	if !req.CaseSensitive {
+		args = append(args, "-i")
+	}
+
+	if req.Regex {
+		args = append(args, "-e", req.Pattern)
+	} else {
+		args = append(args, "-F", req.Pattern)
+	}
+	return args
+}
+
+// ripgrepExcludeArgs returns the negated globs that skip the excluded files,
+// and the archives when they are searched inside
+func ripgrepExcludeArgs(req SearchRequest) []string {
+	var args []string
+	for _, pattern := range req.Exclude {
+		if normalized, err := glob.Normalize(pattern); err == nil {
+			pattern = normalized
+		}
+		args = append(args, "--glob", "!"+pattern)
+	}
+	// archives are searched inside by searchArchives instead
+	if req.SearchArchives {
+		for _, pattern := range archiveGlobs {
+			args = append(args, "--glob", "!"+pattern)
+		}
+	}
+	return args
+}
+
+// executeRipgrepSummary runs ripgrep with -l or -c and returns the matching
+// files with their match counts (zero for -l)
+func executeRipgrepSummary(req SearchRequest) (map[string]int, error) {
+	if !isRipgrepAvailable() {
+		return nil, fmt.Errorf("ripgrep not available")
+	}
+
+	args := []string{"--color=never", "--null", "--with-filename"}
+	if req.OutputMode == outputCount {
+		args = append(args, "--count")
+	} else {
+		args = append(args, "--files-with-matches")
+	}
+	args = append(args, ripgrepPatternArgs(req)...)
+	args = append(args, ripgrepExcludeArgs(req)...)
+	args = append(args, req.Path)
+
+	cmd := exec.Command("rg", args...)
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+
+	err := cmd.Run()
+	if err != nil && !strings.Contains(err.Error(), "exit status 1") {
+		return nil, fmt.Errorf("ripgrep error: %w", err)
+	}
+
+	// --null ends each path with NUL: "path\0" for -l, "path\0count\n" for -c
+	counts := make(map[string]int)
+	if req.OutputMode == outputCount {
+		for _, line := range strings.Split(stdout.String(), "\n") {
+			path, count, ok := strings.Cut(line, "\x00")
+			if !ok || tools.IsPathDenied(path) {
+				continue
+			}
+			counts[path], _ = strconv.Atoi(count)
+		}
+	} else {
+		for _, path := range strings.Split(stdout.String(), "\x00") {
+			path = strings.TrimSpace(path)
+			if path == "" || tools.IsPathDenied(path) {
+				continue
+			}
+			counts[path] = 0
+		}
+	}
+	return counts, nil
+}
+
 func getContextFromRipgrep(searchPath string, filePath string, lineNum int, contextLines int) []string {
 	fileInfo, err := os.Stat(filePath)
 	if err == nil && fileInfo.Size() > MaxGrepFileSize {
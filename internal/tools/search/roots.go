@@ -0,0 +1,217 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+const defaultRootsTimeout = 30 * time.Second
+
+// RootStats is how the search of one root went. A root that failed or did
+// not finish in time has its error set and adds no matches, without failing
+// the search of the other roots.
+type RootStats struct {
+	Root     string `json:"root"`
+	Count    int    `json:"count"`
+	Files    int    `json:"files"`
+	Elapsed  string `json:"elapsed"`
+	Error    string `json:"error,omitempty"`
+	TimedOut bool   `json:"timed_out,omitempty"`
+}
+
+// rootResult is what the search of one root returned
+type rootResult struct {
+	index   int
+	result  interface{}
+	err     error
+	elapsed time.Duration
+}
+
+// searchRoots runs req in each of its roots at once, all under one
+// deadline, and merges the results with each match attributed to its root.
+func (t *SearchTool) searchRoots(ctx context.Context, req SearchRequest) (interface{}, error) {
+	roots, err := searchRootsOf(req)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := defaultRootsTimeout
+	if req.TimeoutMs > 0 {
+		timeout = time.Duration(req.TimeoutMs) * time.Millisecond
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// the token budget is shared evenly by the roots
+	maxTokens := req.MaxTokens
+	if maxTokens > 0 {
+		maxTokens = max(maxTokens/len(roots), 1)
+	}
+
+	// buffered so roots finishing after the deadline do not block
+	results := make(chan rootResult, len(roots))
+	start := time.Now()
+	for i, root := range roots {
+		sub := req
+		sub.Path = root
+		sub.Roots = nil
+		sub.MaxTokens = maxTokens
+		go func() {
+			var result interface{}
+			_, err := os.Stat(root)
+			if err == nil {
+				result, err = t.search(ctx, sub, nil)
+			}
+			results <- rootResult{index: i, result: result, err: err, elapsed: time.Since(start)}
+		}()
+	}
+
+	done := make([]*rootResult, len(roots))
+	for pending := len(roots); pending > 0; pending-- {
+		select {
+		case r := <-results:
+			done[r.index] = &r
+		case <-ctx.Done():
+			pending = 0
+		}
+	}
+
+	stats := make([]RootStats, len(roots))
+	var merged []interface{}
+	failed := 0
+	for i, root := range roots {
+		stats[i].Root = root
+		r := done[i]
+		if r == nil {
+			stats[i].Elapsed = time.Since(start).Round(time.Millisecond).String()
+			stats[i].Error, stats[i].TimedOut = "deadline exceeded", true
+			failed++
+			continue
+		}
+		stats[i].Elapsed = r.elapsed.Round(time.Millisecond).String()
+		if r.err != nil {
+			stats[i].Error = r.err.Error()
+			stats[i].TimedOut = errors.Is(r.err, context.DeadlineExceeded)
+			failed++
+			continue
+		}
+		merged = append(merged, r.result)
+		switch resp := r.result.(type) {
+		case *SearchResponse:
+			files := make(map[string]bool)
+			for j := range resp.Matches {
+				resp.Matches[j].Root = root
+				files[resp.Matches[j].File] = true
+			}
+			stats[i].Count, stats[i].Files = len(resp.Matches), len(files)
+		case *SearchSummary:
+			for j := range resp.Files {
+				resp.Files[j].Root = root
+			}
+			stats[i].Count, stats[i].Files = searchMatchCount(resp), resp.FileCount
+		}
+	}
+	if failed == len(roots) {
+		return nil, fmt.Errorf("search failed in every root: %s: %s", stats[0].Root, stats[0].Error)
+	}
+
+	if req.OutputMode != "" && req.OutputMode != outputContent {
+		return mergeSummaries(req, merged, stats), nil
+	}
+	return mergeMatches(req, merged, stats), nil
+}
+
+// searchRootsOf returns the roots of req, its path first when it has one,
+// without duplicates
+func searchRootsOf(req SearchRequest) ([]string, error) {
+	candidates := req.Roots
+	if req.Path != "" {
+		candidates = append([]string{req.Path}, candidates...)
+	}
+
+	seen := make(map[string]bool, len(candidates))
+	roots := make([]string, 0, len(candidates))
+	for _, root := range candidates {
+		if root == "" {
+			return nil, fmt.Errorf("roots must not be empty")
+		}
+		if err := tools.CheckPath(root); err != nil {
+			return nil, err
+		}
+		root = filepath.Clean(root)
+		if !seen[root] {
+			seen[root] = true
+			roots = append(roots, root)
+		}
+	}
+	return roots, nil
+}
+
+// mergeMatches joins the content results of the roots, in the order of the
+// roots, up to max_results matches
+func mergeMatches(req SearchRequest, results []interface{}, stats []RootStats) *SearchResponse {
+	maxResults := req.MaxResults
+	if maxResults == 0 {
+		maxResults = 1000
+	}
+
+	merged := &SearchResponse{Matches: []Match{}, Path: req.Path, Roots: stats}
+	for _, result := range results {
+		resp := result.(*SearchResponse)
+		merged.Matches = append(merged.Matches, resp.Matches...)
+		merged.Tokens += resp.Tokens
+		if resp.Omitted != nil {
+			if merged.Omitted == nil {
+				merged.Omitted = resp.Omitted
+			} else {
+				merged.Omitted.Count += resp.Omitted.Count
+				merged.Omitted.Tokens += resp.Omitted.Tokens
+				merged.Omitted.Duplicates += resp.Omitted.Duplicates
+				merged.Omitted.TopIDs = append(merged.Omitted.TopIDs, resp.Omitted.TopIDs...)
+			}
+		}
+	}
+	if len(merged.Matches) > maxResults {
+		merged.Matches = merged.Matches[:maxResults]
+	}
+	merged.Count = len(merged.Matches)
+	return merged
+}
+
+// mergeSummaries joins the files_with_matches or count results of the
+// roots, sorted as a single root's would be
+func mergeSummaries(req SearchRequest, results []interface{}, stats []RootStats) *SearchSummary {
+	maxFiles := req.MaxResults
+	if maxFiles == 0 {
+		maxFiles = 1000
+	}
+
+	merged := &SearchSummary{OutputMode: req.OutputMode, Files: []FileMatches{}, Path: req.Path, Roots: stats}
+	for _, result := range results {
+		summary := result.(*SearchSummary)
+		merged.Files = append(merged.Files, summary.Files...)
+		merged.FileCount += summary.FileCount
+		merged.Count += summary.Count
+		merged.Truncated = merged.Truncated || summary.Truncated
+	}
+
+	sort.SliceStable(merged.Files, func(i, j int) bool {
+		a, b := merged.Files[i], merged.Files[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.File < b.File
+	})
+	if len(merged.Files) > maxFiles {
+		merged.Files = merged.Files[:maxFiles]
+		merged.Truncated = true
+	}
+	return merged
+}
@@ -1,11 +1,23 @@
 package search
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
+
+	"github.com/alucardeht/may-la-mcp/internal/intel"
+	"github.com/alucardeht/may-la-mcp/internal/tools"
 )
 
 func TestSearchTool(t *testing.T) {
@@ -79,11 +91,11 @@ func TestReferencesTool(t *testing.T) {
 func TestGetTools(t *testing.T) {
 	tools := GetTools(nil)
 
-	if len(tools) != 4 {
-		t.Errorf("expected 4 tools, got %d", len(tools))
+	if len(tools) != 11 {
+		t.Errorf("expected 11 tools, got %d", len(tools))
 	}
 
-	names := []string{"search", "find", "symbols", "references"}
+	names := []string{"search", "find", "glob", "symbols", "references", "complete", "hover", "implementations", "go_analyze", "file_summary", "public_api"}
 	for i, expectedName := range names {
 		if tools[i].Name() != expectedName {
 			t.Errorf("expected tool %d to be '%s', got '%s'", i, expectedName, tools[i].Name())
@@ -131,7 +143,7 @@ func TestSearchWithGo(t *testing.T) {
 		Path:    tempDir,
 	}
 
-	resp, err := searchWithGo(ctx, req)
+	resp, err := searchWithGo(ctx, req, nil)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -154,6 +166,375 @@ func TestSearchWithGo(t *testing.T) {
 	}
 }
 
+func TestSearchOutputModes(t *testing.T) {
+	ctx := context.Background()
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "a.txt"), []byte("hello\nhello\nbye"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "b.txt"), []byte("hello"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "c.txt"), []byte("nothing"), 0644)
+
+	tool := NewSearchTool(nil)
+	run := func(mode string) *SearchSummary {
+		input, _ := json.Marshal(SearchRequest{Pattern: "hello", Path: tempDir, OutputMode: mode})
+		result, err := tool.Execute(ctx, input)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return result.(*SearchSummary)
+	}
+
+	count := run("count")
+	if count.FileCount != 2 || count.Count != 3 {
+		t.Errorf("expected 3 matches in 2 files, got %d in %d", count.Count, count.FileCount)
+	}
+	if len(count.Files) != 2 || filepath.Base(count.Files[0].File) != "a.txt" || count.Files[0].Count != 2 {
+		t.Errorf("expected a.txt first with 2 matches, got %+v", count.Files)
+	}
+
+	files := run("files_with_matches")
+	if len(files.Files) != 2 || files.Files[0].Count != 0 || files.Count != 0 {
+		t.Errorf("expected 2 files without counts, got %+v", files)
+	}
+
+	input, _ := json.Marshal(SearchRequest{Pattern: "hello", Path: tempDir, OutputMode: "lines"})
+	if _, err := tool.Execute(ctx, input); err == nil {
+		t.Error("expected error for invalid output_mode")
+	}
+}
+
+func TestSearchRoots(t *testing.T) {
+	ctx := context.Background()
+	app, lib := t.TempDir(), t.TempDir()
+	os.WriteFile(filepath.Join(app, "main.go"), []byte("// TODO: wire\n// TODO: test\n"), 0644)
+	os.WriteFile(filepath.Join(lib, "io.go"), []byte("// TODO: retry\n"), 0644)
+	missing := filepath.Join(t.TempDir(), "missing")
+
+	tool := NewSearchTool(nil)
+	run := func(req SearchRequest) interface{} {
+		t.Helper()
+		input, _ := json.Marshal(req)
+		result, err := tool.Execute(ctx, input)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return result
+	}
+
+	resp := run(SearchRequest{Pattern: "TODO", Path: app, Roots: []string{lib, app, missing}}).(*SearchResponse)
+	if resp.Count != 3 || len(resp.Roots) != 3 {
+		t.Fatalf("expected 3 matches from 3 distinct roots, got %+v", resp)
+	}
+	if resp.Matches[0].Root != app || resp.Matches[2].Root != lib {
+		t.Errorf("expected matches attributed to their roots in order, got %+v", resp.Matches)
+	}
+	if resp.Roots[0].Count != 2 || resp.Roots[1].Count != 1 || resp.Roots[1].Files != 1 {
+		t.Errorf("unexpected root statistics %+v", resp.Roots)
+	}
+	if resp.Roots[2].Error == "" {
+		t.Errorf("expected the missing root to report its error, got %+v", resp.Roots[2])
+	}
+
+	summary := run(SearchRequest{Pattern: "TODO", Roots: []string{lib, app}, OutputMode: outputCount}).(*SearchSummary)
+	if summary.FileCount != 2 || summary.Count != 3 || summary.Files[0].Root != app || summary.Files[0].Count != 2 {
+		t.Errorf("expected app's file first with 2 matches, got %+v", summary)
+	}
+
+	input, _ := json.Marshal(SearchRequest{Pattern: "TODO", Roots: []string{missing}})
+	if _, err := tool.Execute(ctx, input); err == nil {
+		t.Error("expected an error when every root fails")
+	}
+}
+
+func TestSearchMaxTokens(t *testing.T) {
+	ctx := context.Background()
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "many.txt"), []byte("hello one\nhello two\nhello three"), 0644)
+	os.MkdirAll(filepath.Join(tempDir, "deep", "er"), 0755)
+	os.WriteFile(filepath.Join(tempDir, "deep", "er", "few.txt"), []byte("hello four"), 0644)
+
+	tool := NewSearchTool(nil)
+	input, _ := json.Marshal(SearchRequest{Pattern: "hello", Path: tempDir, Recursive: true, MaxTokens: 40})
+	result, err := tool.Execute(ctx, input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resp := result.(*SearchResponse)
+	if resp.Count == 0 || resp.Count == 4 || resp.Tokens > 40 {
+		t.Fatalf("expected some of the 4 matches within 40 tokens, got %d using %d", resp.Count, resp.Tokens)
+	}
+	for _, m := range resp.Matches {
+		if filepath.Base(m.File) != "many.txt" {
+			t.Errorf("expected matches from the file with most hits to be kept first, got %s", m.File)
+		}
+	}
+	if resp.Omitted == nil || resp.Omitted.Count != 4-resp.Count {
+		t.Errorf("expected the omitted matches to be summarized, got %+v", resp.Omitted)
+	}
+}
+
+func TestSearchExclude(t *testing.T) {
+	ctx := context.Background()
+	tempDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tempDir, "vendor", "lib"), 0755)
+	os.MkdirAll(filepath.Join(tempDir, "web"), 0755)
+	os.WriteFile(filepath.Join(tempDir, "main.go"), []byte("hello"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "vendor", "lib", "lib.go"), []byte("hello"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "web", "app.min.js"), []byte("hello"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "web", "app.js"), []byte("hello"), 0644)
+
+	tool := NewSearchTool(nil)
+	input, _ := json.Marshal(SearchRequest{
+		Pattern: "hello", Path: tempDir, Recursive: true, OutputMode: "files_with_matches",
+		Exclude: []string{"vendor/**", "*.min.js"},
+	})
+	result, err := tool.Execute(ctx, input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var names []string
+	for _, f := range result.(*SearchSummary).Files {
+		rel, _ := filepath.Rel(tempDir, f.File)
+		names = append(names, filepath.ToSlash(rel))
+	}
+	sort.Strings(names)
+	if strings.Join(names, ",") != "main.go,web/app.js" {
+		t.Errorf("expected vendor and minified files to be skipped, got %v", names)
+	}
+
+	input, _ = json.Marshal(SearchRequest{Pattern: "hello", Path: tempDir, Exclude: []string{"[bad"}})
+	if _, err := tool.Execute(ctx, input); err == nil {
+		t.Error("expected error for an invalid exclude glob")
+	}
+}
+
+func TestSearchStream(t *testing.T) {
+	tempDir := t.TempDir()
+	for i := 0; i < 3; i++ {
+		os.WriteFile(filepath.Join(tempDir, fmt.Sprintf("f%d.txt", i)), []byte(strings.Repeat("needle\n", 150)), 0644)
+	}
+
+	var batches []MatchBatch
+	ctx := tools.WithStream(context.Background(), func(partial interface{}) {
+		batches = append(batches, partial.(MatchBatch))
+	})
+
+	tool := NewSearchTool(nil)
+	input, _ := json.Marshal(SearchRequest{Pattern: "needle", Path: tempDir, Recursive: true, MaxResults: 400, Stream: true})
+	result, err := tool.Execute(ctx, input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resp := result.(*SearchResponse)
+	if !resp.Streamed || len(resp.Matches) != 0 || resp.Count != 400 {
+		t.Errorf("expected a summary of 400 streamed matches, got streamed=%v matches=%d count=%d", resp.Streamed, len(resp.Matches), resp.Count)
+	}
+	streamed := 0
+	for _, batch := range batches {
+		streamed += len(batch.Matches)
+		if batch.Sent != streamed {
+			t.Errorf("batch reports %d sent, %d were", batch.Sent, streamed)
+		}
+	}
+	if len(batches) < 2 || streamed != 400 {
+		t.Errorf("expected 400 matches in several batches, got %d in %d", streamed, len(batches))
+	}
+
+	// without a stream to send to, the whole result is returned
+	result, err = tool.Execute(context.Background(), input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp := result.(*SearchResponse); resp.Streamed || len(resp.Matches) != 400 {
+		t.Errorf("expected all 400 matches unstreamed, got streamed=%v matches=%d", resp.Streamed, len(resp.Matches))
+	}
+}
+
+func TestSearchArchives(t *testing.T) {
+	tempDir := t.TempDir()
+	zipped := func(files map[string]string) []byte {
+		var buf bytes.Buffer
+		w := zip.NewWriter(&buf)
+		for name, content := range files {
+			f, _ := w.Create(name)
+			f.Write([]byte(content))
+		}
+		w.Close()
+		return buf.Bytes()
+	}
+	inner := zipped(map[string]string{"com/acme/App.java": "class App { // needle\n}\n"})
+	os.WriteFile(filepath.Join(tempDir, "bundle.zip"), zipped(map[string]string{
+		"docs/readme.txt": "first\nneedle here\n",
+		"lib/inner.jar":   string(inner),
+		"bin/tool":        "needle\x00binary",
+	}), 0644)
+
+	var tgz bytes.Buffer
+	gz := gzip.NewWriter(&tgz)
+	tw := tar.NewWriter(gz)
+	content := "x\ny\nneedle\n"
+	tw.WriteHeader(&tar.Header{Name: "pkg/index.js", Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+	tw.Write([]byte(content))
+	tw.Close()
+	gz.Close()
+	os.WriteFile(filepath.Join(tempDir, "dist.tar.gz"), tgz.Bytes(), 0644)
+
+	var log bytes.Buffer
+	gz = gzip.NewWriter(&log)
+	gz.Write([]byte("needle in a log\n"))
+	gz.Close()
+	os.WriteFile(filepath.Join(tempDir, "app.log.gz"), log.Bytes(), 0644)
+	os.WriteFile(filepath.Join(tempDir, "plain.txt"), []byte("needle\n"), 0644)
+
+	tool := NewSearchTool(nil)
+	input, _ := json.Marshal(SearchRequest{Pattern: "needle", Path: tempDir, SearchArchives: true})
+	result, err := tool.Execute(context.Background(), input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got []string
+	for _, m := range result.(*SearchResponse).Matches {
+		rel, _ := filepath.Rel(tempDir, m.File)
+		got = append(got, fmt.Sprintf("%s:%d", filepath.ToSlash(rel), m.Line))
+	}
+	sort.Strings(got)
+	want := []string{
+		"app.log.gz!/app.log:1",
+		"bundle.zip!/docs/readme.txt:2",
+		"bundle.zip!/lib/inner.jar!/com/acme/App.java:1",
+		"dist.tar.gz!/pkg/index.js:3",
+		"plain.txt:1",
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("expected matches %v, got %v", want, got)
+	}
+
+	input, _ = json.Marshal(SearchRequest{Pattern: "needle", Path: tempDir, SearchArchives: true, OutputMode: "count"})
+	result, err = tool.Execute(context.Background(), input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summary := result.(*SearchSummary); summary.FileCount != 5 || summary.Count != 5 {
+		t.Errorf("expected 5 matches in 5 files, got %+v", summary)
+	}
+}
+
+func TestGlobTool(t *testing.T) {
+	ctx := context.Background()
+	tempDir := t.TempDir()
+	for _, rel := range []string{
+		"main.go", "main_test.go", "cmd/tool/tool.go", "lib/core.rs", "vendor/dep/dep.go",
+		"web/app.js", "web/app.min.js", "web/app.ts", ".git/config.go",
+	} {
+		path := filepath.Join(tempDir, filepath.FromSlash(rel))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte("x"), 0644)
+	}
+
+	expand := func(req GlobRequest) *GlobResponse {
+		t.Helper()
+		req.Path = tempDir
+		input, _ := json.Marshal(req)
+		result, err := (&GlobTool{}).Execute(ctx, input)
+		if err != nil {
+			t.Fatalf("glob %v: %v", req.Patterns, err)
+		}
+		return result.(*GlobResponse)
+	}
+
+	cases := []struct {
+		patterns []string
+		want     string
+	}{
+		{[]string{"*.@(go|rs)", "!vendor", "!*_test.go"}, "cmd/tool/tool.go,lib/core.rs,main.go"},
+		{[]string{"web/*.{js,ts}", "!*.min.js"}, "web/app.js,web/app.ts"},
+		{[]string{"**/dep/*.go"}, "vendor/dep/dep.go"},
+		{[]string{"web/app?(.min).js"}, "web/app.js,web/app.min.js"},
+	}
+	for _, c := range cases {
+		if got := strings.Join(expand(GlobRequest{Patterns: c.patterns}).Matches, ","); got != c.want {
+			t.Errorf("glob %v = %s, want %s", c.patterns, got, c.want)
+		}
+	}
+
+	if got := expand(GlobRequest{Patterns: []string{"*"}, Type: "dir"}).Matches; strings.Join(got, ",") != "cmd,cmd/tool,lib,vendor,vendor/dep,web" {
+		t.Errorf("directories = %v", got)
+	}
+	if resp := expand(GlobRequest{Patterns: []string{"*.go"}, MaxResults: 2}); resp.Count != 2 || !resp.Truncated {
+		t.Errorf("expected 2 truncated results, got %+v", resp)
+	}
+
+	input, _ := json.Marshal(GlobRequest{Path: tempDir, Patterns: []string{"@(a|b"}})
+	if _, err := (&GlobTool{}).Execute(ctx, input); err == nil {
+		t.Error("expected error for an invalid glob")
+	}
+}
+
+func TestSearchResponseMode(t *testing.T) {
+	ctx := context.Background()
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "a.txt"), []byte("hello one\nbye\nhello two"), 0644)
+
+	tool := NewSearchTool(nil)
+	input, _ := json.Marshal(SearchRequest{Pattern: "hello", Path: tempDir, ResponseOptions: tools.ResponseOptions{ResponseMode: "compact"}})
+	result, err := tool.Execute(ctx, input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	text, ok := result.(*tools.TextResponse)
+	if !ok {
+		t.Fatalf("expected a text response, got %T", result)
+	}
+	if !strings.Contains(text.Content, "a.txt:3:1: hello two") {
+		t.Errorf("expected grep-style match lines, got %q", text.Content)
+	}
+	if !strings.Contains(strings.Join(text.Indicators, " "), "Matches: 2") {
+		t.Errorf("expected the match count indicator, got %v", text.Indicators)
+	}
+
+	input, _ = json.Marshal(SearchRequest{Pattern: "hello", Path: tempDir, ResponseOptions: tools.ResponseOptions{ResponseMode: "short"}})
+	if _, err := tool.Execute(ctx, input); err == nil {
+		t.Error("expected error for an invalid response_mode")
+	}
+}
+
+func TestSearchSmartCaseAndWord(t *testing.T) {
+	ctx := context.Background()
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "a.txt"), []byte("Config config\nconfigure\nreconfig"), 0644)
+
+	tool := NewSearchTool(nil)
+	tests := []struct {
+		name     string
+		req      SearchRequest
+		expected int
+	}{
+		{"smart case lowercase is insensitive", SearchRequest{Pattern: "config", SmartCase: true}, 3},
+		{"smart case uppercase is sensitive", SearchRequest{Pattern: "Config", SmartCase: true}, 1},
+		{"smart case ignores regex escapes", SearchRequest{Pattern: `con\S+`, Regex: true, SmartCase: true}, 3},
+		{"word", SearchRequest{Pattern: "config", Word: true}, 1},
+		{"word with regex", SearchRequest{Pattern: "(re)?config", Regex: true, Word: true}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.Path = tempDir
+			tt.req.OutputMode = "count"
+			input, _ := json.Marshal(tt.req)
+			result, err := tool.Execute(ctx, input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := result.(*SearchSummary).Count; got != tt.expected {
+				t.Errorf("expected %d matching lines, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
 func TestSearchRequestValidation(t *testing.T) {
 	tool := &SearchTool{}
 	ctx := context.Background()
@@ -265,3 +646,454 @@ func searchWithGoFind(req FindRequest) (interface{}, error) {
 		Total:  totalSize,
 	}, nil
 }
+
+func TestSearchHistoryAndSavedSearches(t *testing.T) {
+	ctx := context.Background()
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "a.txt"), []byte("hello\nhello"), 0644)
+
+	store, err := NewHistoryStore(filepath.Join(t.TempDir(), "search.db"))
+	if err != nil {
+		t.Fatalf("failed to open history store: %v", err)
+	}
+	defer store.Close()
+
+	tools := GetToolsWithHistory(nil, store)
+	call := func(name string, input interface{}) interface{} {
+		data, _ := json.Marshal(input)
+		for _, tool := range tools {
+			if tool.Name() == name {
+				result, err := tool.Execute(ctx, data)
+				if err != nil {
+					t.Fatalf("%s failed: %v", name, err)
+				}
+				return result
+			}
+		}
+		t.Fatalf("tool %s not found", name)
+		return nil
+	}
+
+	call("search", SearchRequest{Pattern: "hello", Path: tempDir, OutputMode: "count"})
+
+	history := call("search_history", map[string]interface{}{"query": "hello"}).(map[string]interface{})
+	entries := history["searches"].([]HistoryEntry)
+	if len(entries) != 1 || entries[0].Count != 2 || entries[0].FileCount != 1 {
+		t.Fatalf("expected one history entry with 2 matches in 1 file, got %+v", entries)
+	}
+
+	call("search_saved", map[string]interface{}{"action": "save", "name": "greetings", "history_id": entries[0].ID})
+	run := call("search_saved", map[string]interface{}{"action": "run", "name": "greetings"}).(map[string]interface{})
+	if summary := run["result"].(*SearchSummary); summary.Count != 2 {
+		t.Errorf("expected saved search to find 2 matches, got %d", summary.Count)
+	}
+
+	saved, err := store.Saved("greetings")
+	if err != nil || saved.RunCount != 1 || saved.LastRunAt == nil {
+		t.Errorf("expected one recorded run, got %+v (%v)", saved, err)
+	}
+	if entries, _ := store.Recent(10, ""); len(entries) != 2 {
+		t.Errorf("expected the run to be recorded in history, got %d entries", len(entries))
+	}
+
+	call("search_saved", map[string]interface{}{"action": "delete", "name": "greetings"})
+	if _, err := store.Saved("greetings"); err == nil {
+		t.Error("expected saved search to be deleted")
+	}
+}
+
+func TestFileSummary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shapes.go")
+	source := `package shapes
+
+import (
+	"fmt"
+	"math"
+)
+
+// Circle is a round shape. It has a radius.
+type Circle struct {
+	Radius float64
+}
+
+// Area returns the area of the circle
+func (c Circle) Area() float64 {
+	return math.Pi * c.Radius * c.Radius // TODO: cache
+}
+
+func describe(c Circle) string {
+	return fmt.Sprint(c.Radius)
+}
+
+// MaxRadius caps circles
+const MaxRadius = 10
+`
+	if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tool := NewFileSummaryTool(nil)
+	input, _ := json.Marshal(FileSummaryRequest{Path: path})
+	result, err := tool.Execute(context.Background(), input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	summary := result.(*FileSummaryResponse)
+	if summary.Language != "go" || summary.TODOCount != 1 {
+		t.Errorf("expected a go file with 1 TODO, got %q with %d", summary.Language, summary.TODOCount)
+	}
+	if len(summary.Imports) != 2 || summary.Imports[0] != "fmt" || summary.Imports[1] != "math" {
+		t.Errorf("expected imports fmt and math, got %v", summary.Imports)
+	}
+
+	docs := map[string]string{}
+	for _, entry := range summary.Outline {
+		docs[entry.Name] = entry.Doc
+	}
+	if _, ok := docs["describe"]; ok {
+		t.Error("unexported function should not be outlined")
+	}
+	if docs["Circle"] != "Circle is a round shape." || docs["Area"] != "Area returns the area of the circle" {
+		t.Errorf("unexpected docs: %v", docs)
+	}
+
+	input, _ = json.Marshal(FileSummaryRequest{Path: path, MaxTokens: 1})
+	result, err = tool.Execute(context.Background(), input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summary := result.(*FileSummaryResponse); !summary.Truncated || len(summary.Outline) != 0 {
+		t.Errorf("expected the outline to be dropped for a tiny budget, got %+v", summary.Outline)
+	}
+}
+
+func TestImplementations(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"src/store.ts": `export interface Store<T> {
+  get(key: string): T;
+}
+
+export abstract class BaseStore<T> implements Store<T> {
+  abstract get(key: string): T;
+}
+`,
+		"src/db/sql.ts": `import { BaseStore } from "../store";
+
+export class SqlStore extends BaseStore<Row> {
+  get(key: string): Row { return query(key); }
+}
+
+class Cache implements Store<string>, Disposable {
+  get(key: string): string { return ""; }
+}
+`,
+		"py/shapes.py": `from typing import Protocol
+import abc
+
+
+class Shape(Protocol):
+    def area(self) -> float: ...
+
+
+class Square:
+    def __init__(self, side):
+        self.side = side
+
+    def area(self):
+        return self.side ** 2
+
+
+class Named(abc.ABC):
+    @abc.abstractmethod
+    def name(self): ...
+
+
+class Point(Named):
+    def name(self):
+        return "point"
+`,
+		"shapes.go": `package shapes
+
+type Shape interface {
+	Area() float64
+	Stringer
+}
+
+type Stringer interface{ String() string }
+
+type Circle struct{ R float64 }
+
+func (c *Circle) Area() float64 { return c.R * c.R }
+
+func (c Circle) String() string { return "circle" }
+
+type Label string
+
+func (l Label) String() string { return string(l) }
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tool := NewImplementationsTool(nil)
+	implementations := func(symbol string) []string {
+		t.Helper()
+		input, _ := json.Marshal(ImplementationsRequest{Path: root, Symbol: symbol})
+		result, err := tool.Execute(context.Background(), input)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", symbol, err)
+		}
+		resp := result.(*ImplementationsResponse)
+		if resp.Source != "regex" {
+			t.Errorf("%s: expected the heuristics to answer, got %s", symbol, resp.Source)
+		}
+		var got []string
+		for _, group := range resp.Packages {
+			for _, item := range group.Items {
+				entry := group.Package + ":" + item.Name
+				if item.Pointer {
+					entry += "*"
+				}
+				got = append(got, entry)
+			}
+		}
+		return got
+	}
+
+	if got := strings.Join(implementations("Store"), " "); got != "src:BaseStore src/db:SqlStore src/db:Cache" {
+		t.Errorf("expected the abstract class, its subclass and Cache, got %s", got)
+	}
+	if got := strings.Join(implementations("Shape"), " "); got != ".:Circle* py:Square" {
+		t.Errorf("expected *Circle and the structural Square, got %s", got)
+	}
+	if got := strings.Join(implementations("Named"), " "); got != "py:Point" {
+		t.Errorf("expected Point, got %s", got)
+	}
+
+	input, _ := json.Marshal(ImplementationsRequest{Path: filepath.Join(root, "shapes.go"), Line: 8, Column: 7})
+	result, err := tool.Execute(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp := result.(*ImplementationsResponse); resp.Symbol != "Stringer" || resp.Count != 2 {
+		t.Errorf("expected Circle and Label to implement Stringer, got %+v", resp)
+	}
+}
+
+func TestFileSummaryDescribe(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.go")
+	source := "package notes\n\n" + strings.Repeat("// Notes keep track of things worth remembering.\n", 20)
+	if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	failing := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if failing || r.URL.Path != "/api/generate" {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"response": "Package notes documents what to remember."})
+	}))
+	defer server.Close()
+
+	summarizer, err := intel.NewSummarizer(intel.SummarizerConfig{Enabled: true, Provider: intel.ProviderOllama, Endpoint: server.URL, CacheSize: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	intel.SetSummarizer(summarizer)
+	defer intel.SetSummarizer(nil)
+
+	tool := NewFileSummaryTool(nil)
+	describe := func() *FileSummaryResponse {
+		input, _ := json.Marshal(FileSummaryRequest{Path: path, Describe: true})
+		result, err := tool.Execute(context.Background(), input)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return result.(*FileSummaryResponse)
+	}
+
+	for i := 0; i < 2; i++ {
+		if resp := describe(); resp.DescribedBy != intel.SummarySourceLLM || resp.Description != "Package notes documents what to remember." {
+			t.Errorf("expected the LLM description, got %q from %q", resp.Description, resp.DescribedBy)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected the second description to be cached, got %d calls", calls)
+	}
+
+	failing = true
+	os.WriteFile(path, []byte(source+"// Changed.\n"), 0644)
+	if resp := describe(); resp.DescribedBy != intel.SummarySourceHeuristic || resp.Description == "" {
+		t.Errorf("expected a heuristic fallback, got %q from %q", resp.Description, resp.DescribedBy)
+	}
+}
+
+const apiSource = `package shapes
+
+import "math"
+
+// Circle is a round shape.
+//
+// The zero value is a point.
+type Circle struct {
+	Radius float64
+}
+
+// Area returns the area of the circle
+func (c Circle) Area() float64 {
+	return math.Pi * c.Radius * c.Radius
+}
+
+// Scale returns c grown by factor,
+// which may be below one
+func Scale(
+	c Circle,
+	factor float64,
+) Circle {
+	return Circle{Radius: c.Radius * factor}
+}
+
+type square struct{}
+
+func (s square) Area() float64 { return 0 }
+
+func describe(c Circle) string {
+	return ""
+}
+`
+
+func TestPublicAPI(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"shapes.go":           apiSource,
+		"shapes_test.go":      "package shapes\n\nfunc TestArea(t *testing.T) {}\n",
+		"internal/helpers.go": "package internal\n\nfunc Helper() {}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tool := NewPublicAPITool(nil)
+	run := func(req PublicAPIRequest) *PublicAPIResponse {
+		t.Helper()
+		input, _ := json.Marshal(req)
+		result, err := tool.Execute(context.Background(), input)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return result.(*PublicAPIResponse)
+	}
+
+	resp := run(PublicAPIRequest{Path: dir})
+	if len(resp.Files) != 1 || filepath.Base(resp.Files[0].File) != "shapes.go" {
+		t.Fatalf("expected only shapes.go, got %+v", resp.Files)
+	}
+	symbols := map[string]APISymbol{}
+	for _, sym := range resp.Files[0].Symbols {
+		symbols[sym.Name] = sym
+	}
+	if len(symbols) != 3 || resp.Count != 3 {
+		t.Fatalf("expected Circle, Area and Scale, got %+v", resp.Files[0].Symbols)
+	}
+	if sym := symbols["Area"]; sym.Line != 13 {
+		t.Errorf("expected the Area of Circle, not of square, got line %d", sym.Line)
+	}
+	if sig := symbols["Scale"].Signature; sig != "func Scale(c Circle, factor float64) Circle" {
+		t.Errorf("unexpected multi-line signature %q", sig)
+	}
+	if doc := symbols["Circle"].Doc; doc != "Circle is a round shape.\n\nThe zero value is a point." {
+		t.Errorf("expected the whole doc comment, got %q", doc)
+	}
+
+	resp = run(PublicAPIRequest{Path: dir, Recursive: true, IncludeTests: true})
+	if len(resp.Files) != 3 {
+		t.Errorf("expected internal/helpers.go, shapes.go and shapes_test.go, got %+v", resp.Files)
+	} else if sig := resp.Files[0].Symbols[0].Signature; sig != "func Helper()" {
+		t.Errorf("expected the one-line body cut from the signature, got %q", sig)
+	}
+
+	if resp := run(PublicAPIRequest{Path: dir, MaxResults: 2}); resp.Count != 2 || !resp.Truncated {
+		t.Errorf("expected 2 symbols and truncated, got %d (truncated %v)", resp.Count, resp.Truncated)
+	}
+}
+
+func TestUnknownKindFilters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shapes.go")
+	if err := os.WriteFile(path, []byte(apiSource), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	input, _ := json.Marshal(SymbolsRequest{Path: path, Kinds: []string{"function", "fucntion"}})
+	if _, err := NewSymbolsTool(nil).Execute(context.Background(), input); err == nil || !strings.Contains(err.Error(), "fucntion") {
+		t.Errorf("expected the misspelled kind to be rejected, got %v", err)
+	}
+
+	input, _ = json.Marshal(SymbolsRequest{Path: path, Kinds: []string{"func"}})
+	result, err := NewSymbolsTool(nil).Execute(context.Background(), input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp := result.(*SymbolsResponse); resp.Count == 0 {
+		t.Error("expected the func spelling to find functions")
+	}
+
+	input, _ = json.Marshal(SearchRequest{Pattern: "Scale", Path: path, WithinKind: "bogus"})
+	if _, err := NewSearchTool(nil).Execute(context.Background(), input); err == nil || !strings.Contains(err.Error(), "within_kind") {
+		t.Errorf("expected an unknown within_kind to be rejected, got %v", err)
+	}
+}
+
+func TestVisibilityFilters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shapes.go")
+	if err := os.WriteFile(path, []byte(apiSource), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	symbols := NewSymbolsTool(nil)
+	input, _ := json.Marshal(SymbolsRequest{Path: path, Kinds: []string{"function"}, Visibility: "exported"})
+	result, err := symbols.Execute(context.Background(), input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp := result.(*SymbolsResponse); resp.Count != 1 || resp.Symbols[0].Name != "Scale" {
+		t.Errorf("expected only Scale, got %+v", resp.Symbols)
+	}
+
+	input, _ = json.Marshal(SymbolsRequest{Path: path, Visibility: "public"})
+	if _, err := symbols.Execute(context.Background(), input); err == nil {
+		t.Error("expected an unknown visibility to be rejected")
+	}
+
+	summary := NewFileSummaryTool(nil)
+	input, _ = json.Marshal(FileSummaryRequest{Path: path, Visibility: "all"})
+	result, err = summary.Execute(context.Background(), input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp := result.(*FileSummaryResponse)
+	unexported := map[string]bool{}
+	for _, entry := range resp.Outline {
+		unexported[entry.Name] = entry.Unexported
+	}
+	if hidden, ok := unexported["describe"]; !ok || !hidden || unexported["Scale"] {
+		t.Errorf("expected describe outlined as unexported, got %+v", resp.Outline)
+	}
+	if resp.ExportedCount >= len(resp.Outline) {
+		t.Errorf("expected exported_count to leave out unexported entries, got %d of %d", resp.ExportedCount, len(resp.Outline))
+	}
+}
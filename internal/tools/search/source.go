@@ -0,0 +1,28 @@
+package search
+
+import (
+	"github.com/alucardeht/may-la-mcp/internal/router"
+)
+
+// routerOptions builds the router options for a tool request. Pinning the
+// index, Go or LSP tier disables the regex fallback unless asked for, so a debug
+// query shows what that tier alone returns.
+func routerOptions(maxResults int, source string, allowFallback *bool) (router.QueryOptions, error) {
+	opts, err := router.QueryOptions{
+		MaxResults:    maxResults,
+		AllowFallback: true,
+	}.WithSource(source)
+	if err != nil {
+		return opts, err
+	}
+
+	switch router.QuerySource(source) {
+	case router.SourceIndex, router.SourceGo, router.SourceLSP:
+		opts.AllowFallback = false
+	}
+	if allowFallback != nil && router.QuerySource(source) != router.SourceRegex {
+		opts.AllowFallback = *allowFallback
+	}
+
+	return opts, nil
+}
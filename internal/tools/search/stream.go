@@ -0,0 +1,82 @@
+package search
+
+import (
+	"context"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+const (
+	// streamBatchSize and streamInterval bound how long found matches wait
+	// before they are sent: a batch goes out once it is full or the interval
+	// has passed since the previous one, so the first match goes at once
+	streamBatchSize = 200
+	streamInterval  = 250 * time.Millisecond
+)
+
+// MatchBatch is a partial result of a streamed search: the matches found
+// since the previous batch, and how many were sent so far in all
+type MatchBatch struct {
+	Matches []Match `json:"matches"`
+	Sent    int     `json:"sent"`
+}
+
+// matchStream sends the matches of a content search to the client while it
+// runs. A nil matchStream sends nothing, so engines call it unconditionally.
+type matchStream struct {
+	ctx      context.Context
+	batch    []Match
+	lastSent time.Time
+	sent     int
+}
+
+// newMatchStream returns the stream for req, or nil when req did not ask
+// to stream or the call cannot
+func newMatchStream(ctx context.Context, req SearchRequest) *matchStream {
+	if !req.streams() || !tools.Streaming(ctx) {
+		return nil
+	}
+	return &matchStream{ctx: ctx}
+}
+
+// streams reports whether req asks to stream in a mode that allows it;
+// token packing, text responses and multi-root merging need every match
+// before they answer
+func (req SearchRequest) streams() bool {
+	return req.Stream && len(req.Roots) == 0 && (req.OutputMode == "" || req.OutputMode == outputContent) && req.MaxTokens == 0 && !req.ResponseOptions.Enabled()
+}
+
+// add queues matches the search keeps
+func (s *matchStream) add(matches ...Match) {
+	if s == nil || len(matches) == 0 {
+		return
+	}
+	s.batch = append(s.batch, matches...)
+	if len(s.batch) >= streamBatchSize || time.Since(s.lastSent) >= streamInterval {
+		s.flush()
+	}
+}
+
+// flush sends the queued matches
+func (s *matchStream) flush() {
+	if s == nil || len(s.batch) == 0 {
+		return
+	}
+	s.sent += len(s.batch)
+	s.lastSent = time.Now()
+	tools.Stream(s.ctx, MatchBatch{Matches: s.batch, Sent: s.sent})
+	s.batch = nil
+}
+
+// summary ends the stream: it sends what is still queued and turns resp
+// into the summary closing the stream, its matches left out since they were
+// all sent
+func (s *matchStream) summary(resp *SearchResponse) {
+	if s == nil {
+		return
+	}
+	s.flush()
+	resp.Matches = []Match{}
+	resp.Streamed = true
+}
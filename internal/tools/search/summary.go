@@ -0,0 +1,375 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/index"
+	"github.com/alucardeht/may-la-mcp/internal/intel"
+	"github.com/alucardeht/may-la-mcp/internal/router"
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+	"github.com/alucardeht/may-la-mcp/internal/types"
+)
+
+const (
+	defaultSummaryTokens = 1000
+
+	// summaryKeptImports is how many imports survive before the outline
+	// starts losing symbols to the token budget
+	summaryKeptImports = 10
+
+	// summaryDescriptionLen caps the prose description asked for with
+	// describe
+	summaryDescriptionLen = 400
+)
+
+type FileSummaryRequest struct {
+	Path       string `json:"path"`
+	MaxTokens  int    `json:"max_tokens,omitempty"`
+	Describe   bool   `json:"describe,omitempty"`
+	Visibility string `json:"visibility,omitempty"`
+}
+
+type OutlineEntry struct {
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`
+	Line      int    `json:"line"`
+	Signature string `json:"signature,omitempty"`
+	Doc       string `json:"doc,omitempty"`
+	// Unexported marks the declarations only listed with visibility all
+	Unexported bool `json:"unexported,omitempty"`
+}
+
+type FileComplexity struct {
+	Level        string `json:"level"`
+	Cyclomatic   int    `json:"cyclomatic"`
+	NestingDepth int    `json:"nesting_depth"`
+	Functions    int    `json:"functions"`
+}
+
+type FileSummaryResponse struct {
+	Path            string         `json:"path"`
+	Language        string         `json:"language,omitempty"`
+	Lines           int            `json:"lines"`
+	SizeBytes       int64          `json:"size_bytes"`
+	Imports         []string       `json:"imports"`
+	Outline         []OutlineEntry `json:"outline"`
+	ExportedCount   int            `json:"exported_count"`
+	Complexity      FileComplexity `json:"complexity"`
+	TODOCount       int            `json:"todo_count"`
+	Description     string         `json:"description,omitempty"`
+	DescribedBy     string         `json:"described_by,omitempty"`
+	OmittedImports  int            `json:"omitted_imports,omitempty"`
+	OmittedSymbols  int            `json:"omitted_symbols,omitempty"`
+	Truncated       bool           `json:"truncated,omitempty"`
+	EstimatedTokens int            `json:"estimated_tokens"`
+	Source          string         `json:"source"`
+	LatencyMs       int64          `json:"latency_ms"`
+}
+
+type FileSummaryTool struct {
+	router *router.Router
+}
+
+func NewFileSummaryTool(r *router.Router) *FileSummaryTool {
+	return &FileSummaryTool{router: r}
+}
+
+func (t *FileSummaryTool) Name() string {
+	return "file_summary"
+}
+
+func (t *FileSummaryTool) Description() string {
+	return "Get a compact digest of a source file instead of reading it: language, exported symbols with signatures and one-line docs, imports, complexity rating and TODO count, fitted to a token budget"
+}
+
+func (t *FileSummaryTool) Title() string {
+	return "Summarize File"
+}
+
+func (t *FileSummaryTool) Annotations() map[string]bool {
+	return tools.ReadOnlyAnnotations()
+}
+
+func (t *FileSummaryTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"path": {
+				"type": "string",
+				"description": "Source file to summarize"
+			},
+			"max_tokens": {
+				"type": "integer",
+				"description": "Approximate token budget for the summary; imports and then the least important symbols are dropped to fit (default: 1000)"
+			},
+			"describe": {
+				"type": "boolean",
+				"description": "Also add a short prose description of the file, written by the configured LLM summarizer or extracted heuristically when none is enabled (default: false)"
+			},
+			"visibility": {
+				"type": "string",
+				"enum": ["exported", "all"],
+				"description": "Outline only the exported declarations, or all of them with the unexported ones marked and dropped first to fit the budget (default: exported)"
+			}
+		},
+		"required": ["path"]
+	}`)
+}
+
+func (t *FileSummaryTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var req FileSummaryRequest
+	if err := json.Unmarshal(input, &req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	if req.Path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+	if req.MaxTokens <= 0 {
+		req.MaxTokens = defaultSummaryTokens
+	}
+	visibility, err := parseVisibility(req.Visibility, "exported")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tools.CheckPath(req.Path); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+
+	info, err := os.Stat(req.Path)
+	if err != nil {
+		return nil, fmt.Errorf("path not found: %w", err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", req.Path)
+	}
+
+	content, _, err := index.ReadFileAsUTF8(req.Path)
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+	lines := strings.Split(content, "\n")
+	lang := index.DetectLanguage(req.Path)
+
+	symbols, source, err := fileSymbols(ctx, t.router, req.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	complexity := intel.AnalyzeComplexity(content)
+	resp := &FileSummaryResponse{
+		Path:      req.Path,
+		Language:  lang,
+		Lines:     len(lines),
+		SizeBytes: info.Size(),
+		Imports:   intel.ExtractImports(content, lang),
+		Outline:   outline(symbols, lines, lang, visibility == "all"),
+		Complexity: FileComplexity{
+			Level:        complexity.Level,
+			Cyclomatic:   complexity.CyclomaticComplexity,
+			NestingDepth: complexity.NestingDepth,
+			Functions:    complexity.FunctionCount,
+		},
+		TODOCount: intel.CountTODOs(content),
+		Source:    source,
+	}
+	if resp.Imports == nil {
+		resp.Imports = []string{}
+	}
+	for _, entry := range resp.Outline {
+		if !entry.Unexported {
+			resp.ExportedCount++
+		}
+	}
+	if req.Describe {
+		resp.Description, resp.DescribedBy = intel.SummarizeContent(ctx, content, summaryDescriptionLen)
+	}
+
+	fitSummary(resp, req.MaxTokens)
+
+	resp.LatencyMs = time.Since(start).Milliseconds()
+	if data, err := json.Marshal(resp); err == nil {
+		resp.EstimatedTokens = intel.EstimateTokens(string(data))
+	}
+	return resp, nil
+}
+
+// fileSymbols lists the symbols of path through r, or by regex when there
+// is no router
+func fileSymbols(ctx context.Context, r *router.Router, path string) ([]types.Symbol, string, error) {
+	if r == nil {
+		kinds := make(map[string]bool)
+		for _, k := range types.SymbolKinds {
+			kinds[k] = true
+		}
+		return extractSymbols(path, kinds, ""), string(router.SourceRegex), nil
+	}
+
+	opts, err := routerOptions(5000, "", nil)
+	if err != nil {
+		return nil, "", err
+	}
+	result, err := r.QuerySymbols(ctx, path, "", nil, opts)
+	if err != nil {
+		return nil, "", fmt.Errorf("query symbols: %w", err)
+	}
+	return result.Items, string(result.Source), nil
+}
+
+// outline keeps the exported declarations of symbols in line order, or all
+// of them when all is set, with the declaration line as signature and the
+// first line of their doc
+func outline(symbols []types.Symbol, lines []string, lang string, all bool) []OutlineEntry {
+	sorted := append([]types.Symbol(nil), symbols...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Line < sorted[j].Line
+	})
+
+	entries := []OutlineEntry{}
+	seen := make(map[string]bool)
+	for _, sym := range sorted {
+		if sym.Kind == types.KindField || sym.Line < 1 || sym.Line > len(lines) {
+			continue
+		}
+		key := fmt.Sprintf("%s:%d", sym.Name, sym.Line)
+		if seen[key] {
+			continue
+		}
+
+		decl := strings.TrimSpace(lines[sym.Line-1])
+		exported := exportedDecl(sym.Name, decl, lang)
+		if !exported && !all {
+			continue
+		}
+		seen[key] = true
+
+		signature := strings.TrimSpace(strings.TrimSuffix(decl, "{"))
+		if signature == "" {
+			signature = sym.Signature
+		}
+		doc := sym.Documentation
+		if i := strings.IndexByte(doc, '\n'); i >= 0 {
+			doc = doc[:i]
+		}
+		if doc == "" {
+			doc = intel.DocLine(lines, sym.Line)
+		}
+
+		entries = append(entries, OutlineEntry{
+			Name:       sym.Name,
+			Kind:       sym.Kind,
+			Line:       sym.Line,
+			Signature:  intel.Truncate(signature, 160, intel.TruncateModeSmart),
+			Doc:        doc,
+			Unexported: !exported,
+		})
+	}
+	return entries
+}
+
+// exportedDecl reports whether the symbol declared on decl is visible
+// outside its file or package
+func exportedDecl(name, decl, lang string) bool {
+	if name == "" {
+		return false
+	}
+	switch lang {
+	case "go":
+		return name[0] >= 'A' && name[0] <= 'Z'
+	case "rust":
+		return strings.HasPrefix(decl, "pub")
+	case "java", "kotlin", "scala", "csharp":
+		return !strings.Contains(decl, "private ")
+	case "typescript", "javascript":
+		return strings.HasPrefix(decl, "export ") || (!strings.HasPrefix(name, "_") && !strings.HasPrefix(name, "#"))
+	default:
+		return !strings.HasPrefix(name, "_")
+	}
+}
+
+// fitSummary drops imports past summaryKeptImports, then the least
+// important symbols, then the remaining imports until resp fits maxTokens
+func fitSummary(resp *FileSummaryResponse, maxTokens int) {
+	imports, entries := resp.Imports, resp.Outline
+	resp.Imports, resp.Outline = []string{}, []OutlineEntry{}
+
+	total := tokensOf(resp)
+	for _, imp := range imports {
+		total += tokensOf(imp)
+	}
+	entryTokens := make([]int, len(entries))
+	for i, entry := range entries {
+		entryTokens[i] = tokensOf(entry)
+		total += entryTokens[i]
+	}
+
+	for len(imports) > summaryKeptImports && total > maxTokens {
+		total -= tokensOf(imports[len(imports)-1])
+		imports = imports[:len(imports)-1]
+		resp.OmittedImports++
+	}
+	for len(entries) > 0 && total > maxTokens {
+		drop := leastImportant(entries)
+		total -= entryTokens[drop]
+		entries = append(entries[:drop], entries[drop+1:]...)
+		entryTokens = append(entryTokens[:drop], entryTokens[drop+1:]...)
+		resp.OmittedSymbols++
+	}
+	for len(imports) > 0 && total > maxTokens {
+		total -= tokensOf(imports[len(imports)-1])
+		imports = imports[:len(imports)-1]
+		resp.OmittedImports++
+	}
+
+	resp.Imports, resp.Outline = imports, entries
+	resp.Truncated = resp.OmittedImports > 0 || resp.OmittedSymbols > 0
+}
+
+// leastImportant picks the last entry of the lowest ranked kind:
+// unexported declarations go first, then variables and constants, then
+// methods, then types and functions
+func leastImportant(entries []OutlineEntry) int {
+	rank := func(entry OutlineEntry) int {
+		switch {
+		case entry.Unexported:
+			return 0
+		case entry.Kind == types.KindVariable, entry.Kind == types.KindConst:
+			return 1
+		case entry.Kind == types.KindMethod:
+			return 2
+		default:
+			return 3
+		}
+	}
+
+	drop := len(entries) - 1
+	for i := len(entries) - 1; i >= 0; i-- {
+		if rank(entries[i]) < rank(entries[drop]) {
+			drop = i
+		}
+	}
+	return drop
+}
+
+// tokensOf estimates the tokens v takes in the JSON response, separator
+// included
+func tokensOf(v interface{}) int {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return 0
+	}
+	return intel.EstimateTokens(string(data)) + 1
+}
@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/alucardeht/may-la-mcp/internal/index"
 	"github.com/alucardeht/may-la-mcp/internal/router"
@@ -16,16 +17,27 @@ import (
 	"github.com/alucardeht/may-la-mcp/internal/types"
 )
 
+// exportedScanLimit is how many symbols are looked up to find max_results
+// exported ones
+const exportedScanLimit = 5000
+
 type SymbolsRequest struct {
-	Path       string   `json:"path"`
-	Kinds      []string `json:"kinds,omitempty"`
-	Query      string   `json:"query,omitempty"`
-	MaxResults int      `json:"max_results,omitempty"`
+	Path          string   `json:"path"`
+	Kinds         []string `json:"kinds,omitempty"`
+	Query         string   `json:"query,omitempty"`
+	MaxResults    int      `json:"max_results,omitempty"`
+	Source        string   `json:"source,omitempty"`
+	AllowFallback *bool    `json:"allow_fallback,omitempty"`
+	Visibility    string   `json:"visibility,omitempty"`
+	tools.ResponseOptions
 }
 
 type SymbolsResponse struct {
-	Symbols []types.Symbol `json:"symbols"`
-	Count   int            `json:"count"`
+	Symbols   []types.Symbol `json:"symbols"`
+	Count     int            `json:"count"`
+	Source    string         `json:"source"`
+	Fallback  bool           `json:"fallback,omitempty"`
+	LatencyMs int64          `json:"latency_ms"`
 }
 
 type SymbolsTool struct {
@@ -64,16 +76,41 @@ func (t *SymbolsTool) Schema() json.RawMessage {
 				"type": "array",
 				"items": {
 					"type": "string",
-					"enum": ["function", "class", "method", "variable", "interface", "type", "const"]
-				}
+					"enum": ["function", "method", "class", "interface", "struct", "enum", "type", "const", "variable", "field", "module", "other"]
+				},
+				"description": "Canonical symbol kinds to include; language-specific spellings (var, constructor, trait, ...) are normalized and unknown kinds rejected"
 			},
 			"query": {
 				"type": "string",
 				"description": "Filter symbols by name pattern"
 			},
+			"visibility": {
+				"type": "string",
+				"enum": ["exported", "all"],
+				"description": "Keep only the symbols visible outside their package (capitalized in Go, pub in Rust, not private in Java, no leading underscore elsewhere), or all of them (default: all)"
+			},
+			"source": {
+				"type": "string",
+				"enum": ["auto", "index", "lsp", "regex"],
+				"description": "Force a single lookup tier when debugging results (default: auto tries index, LSP, then regex)"
+			},
+			"allow_fallback": {
+				"type": "boolean",
+				"description": "Fall back to regex when the selected tiers find nothing (default: true for auto, false for index/lsp)"
+			},
 			"max_results": {
 				"type": "integer",
 				"description": "Maximum number of results (default: 500)"
+			},
+			"response_mode": {
+				"type": "string",
+				"enum": ["compact", "detailed", "raw"],
+				"description": "Return the result as text instead of JSON: compact (summary plus content cut to max_length, default 2000 chars), detailed (numbered lines with counts, default 5000 chars) or raw (unformatted)"
+			},
+			"max_length": {
+				"type": "integer",
+				"minimum": 0,
+				"description": "Maximum characters of the text result; selects compact when response_mode is omitted"
 			}
 		},
 		"required": ["path"]
@@ -93,16 +130,51 @@ func (t *SymbolsTool) Execute(ctx context.Context, input json.RawMessage) (inter
 	if req.Path == "" {
 		return nil, fmt.Errorf("path is required")
 	}
+	if err := tools.CheckPath(req.Path); err != nil {
+		return nil, err
+	}
 
 	if req.MaxResults == 0 {
 		req.MaxResults = 500
 	}
+	visibility, err := parseVisibility(req.Visibility, "all")
+	if err != nil {
+		return nil, err
+	}
+	req.Visibility = visibility
+	if len(req.Kinds) > 0 {
+		if req.Kinds, err = types.ParseKinds(req.Kinds); err != nil {
+			return nil, err
+		}
+	}
+	if err := req.ResponseOptions.Validate(); err != nil {
+		return nil, err
+	}
 
 	// Use the passed context to respect timeouts - DO NOT override with context.Background()
 
-	opts := router.QueryOptions{
-		MaxResults:   req.MaxResults,
-		AllowFallback: true,
+	result, err := t.query(ctx, req)
+	if err != nil || !req.ResponseOptions.Enabled() {
+		return result, err
+	}
+	resp := result.(*SymbolsResponse)
+	return req.Format(renderSymbolsText(resp), map[string]interface{}{
+		"symbol_count": resp.Count,
+		"source":       resp.Source,
+	}), nil
+}
+
+// query looks the symbols up through the router, or with regexes when
+// there is none. With visibility exported, up to exportedScanLimit symbols
+// are looked up so max_results counts the exported ones.
+func (t *SymbolsTool) query(ctx context.Context, req SymbolsRequest) (interface{}, error) {
+	limit := req.MaxResults
+	if req.Visibility == "exported" {
+		limit = max(limit, exportedScanLimit)
+	}
+	opts, err := routerOptions(limit, req.Source, req.AllowFallback)
+	if err != nil {
+		return nil, err
 	}
 
 	if t.router != nil {
@@ -111,8 +183,12 @@ func (t *SymbolsTool) Execute(ctx context.Context, input json.RawMessage) (inter
 			return nil, fmt.Errorf("query symbols: %w", err)
 		}
 
-		symbols := make([]types.Symbol, len(result.Items))
-		for i, sym := range result.Items {
+		items := result.Items
+		if req.Visibility == "exported" {
+			items = filterExported(items, req.MaxResults)
+		}
+		symbols := make([]types.Symbol, len(items))
+		for i, sym := range items {
 			symbols[i] = types.Symbol{
 				Name:      sym.Name,
 				Kind:      sym.Kind,
@@ -123,22 +199,33 @@ func (t *SymbolsTool) Execute(ctx context.Context, input json.RawMessage) (inter
 		}
 
 		return &SymbolsResponse{
-			Symbols: symbols,
-			Count:   len(symbols),
+			Symbols:   symbols,
+			Count:     len(symbols),
+			Source:    string(result.Source),
+			Fallback:  result.Fallback,
+			LatencyMs: result.Latency.Milliseconds(),
 		}, nil
 	}
 
-	return t.executeRegex(ctx, req.Path, req.Query, req.Kinds, req.MaxResults)
+	result, err := t.executeRegex(ctx, req.Path, req.Query, req.Kinds, limit)
+	if err != nil || req.Visibility != "exported" {
+		return result, err
+	}
+	resp := result.(*SymbolsResponse)
+	resp.Symbols = filterExported(resp.Symbols, req.MaxResults)
+	resp.Count = len(resp.Symbols)
+	return resp, nil
 }
 
 func (t *SymbolsTool) executeRegex(ctx context.Context, path, query string, kinds []string, maxResults int) (interface{}, error) {
+	start := time.Now()
 	kindMap := make(map[string]bool)
 	if len(kinds) == 0 {
-		for _, k := range []string{"function", "class", "method", "variable", "interface", "type", "const"} {
+		for _, k := range types.SymbolKinds {
 			kindMap[k] = true
 		}
 	} else {
-		for _, k := range kinds {
+		for _, k := range types.NormalizeKinds(kinds) {
 			kindMap[k] = true
 		}
 	}
@@ -183,11 +270,27 @@ func (t *SymbolsTool) executeRegex(ctx context.Context, path, query string, kind
 	}
 
 	return &SymbolsResponse{
-		Symbols: symbols,
-		Count:   len(symbols),
+		Symbols:   symbols,
+		Count:     len(symbols),
+		Source:    string(router.SourceRegex),
+		LatencyMs: time.Since(start).Milliseconds(),
 	}, nil
 }
 
+// renderSymbolsText renders symbols one per line as file:line kind name,
+// followed by the signature when there is one
+func renderSymbolsText(resp *SymbolsResponse) string {
+	lines := make([]string, 0, len(resp.Symbols))
+	for _, sym := range resp.Symbols {
+		line := fmt.Sprintf("%s:%d %s %s", sym.File, sym.Line, sym.Kind, sym.Name)
+		if sym.Signature != "" {
+			line += "  " + sym.Signature
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
 func isSourceFile(path string) bool {
 	ext := filepath.Ext(path)
 	sourceExts := map[string]bool{
@@ -260,13 +363,10 @@ func extractGoSymbols(content string, filePath string, kindMap map[string]bool,
 			}
 		}
 
-		if match := typeRe.FindStringSubmatch(line); len(match) > 1 && kindMap["type"] {
+		if match := typeRe.FindStringSubmatch(line); len(match) > 2 && kindMap[match[2]] {
 			name := match[1]
 			if matchesQuery(name, query) {
-				kind := "type"
-				if len(match) > 2 && match[2] == "interface" {
-					kind = "interface"
-				}
+				kind := match[2]
 				symbols = append(symbols, types.Symbol{
 					Name:      name,
 					Kind:      kind,
@@ -333,16 +433,14 @@ func extractJSSymbols(content string, filePath string, kindMap map[string]bool,
 		}
 
 		if match := funcRe.FindStringSubmatch(line); len(match) > 2 {
-			kind := match[1]
 			name := match[2]
-			symbolKind := "function"
-			if (kind == "const" || kind == "let" || kind == "var") && !kindMap["variable"] {
+			if !kindMap[types.KindFunction] {
 				continue
 			}
 			if matchesQuery(name, query) {
 				symbols = append(symbols, types.Symbol{
 					Name:      name,
-					Kind:      symbolKind,
+					Kind:      types.KindFunction,
 					File:      filePath,
 					Line:      lineNum,
 					Signature: strings.TrimSpace(line),
@@ -421,10 +519,8 @@ func extractJavaSymbols(content string, filePath string, kindMap map[string]bool
 
 		if match := classRe.FindStringSubmatch(line); len(match) > 2 {
 			name := match[2]
-			kind := "class"
-			if match[1] == "interface" && kindMap["interface"] {
-				kind = "interface"
-			} else if !kindMap["class"] {
+			kind := types.NormalizeKind(match[1])
+			if !kindMap[kind] {
 				continue
 			}
 
@@ -7,10 +7,38 @@ import (
 
 func GetTools(r *router.Router) []tools.Tool {
 	return []tools.Tool{
-		&SearchTool{},
+		NewSearchTool(r),
 		&FindTool{},
+		&GlobTool{},
 		NewSymbolsTool(r),
 		NewReferencesTool(r),
+		NewCompleteTool(r),
+		NewHoverTool(r),
+		NewImplementationsTool(r),
+		NewGoAnalyzeTool(r),
+		NewFileSummaryTool(r),
+		NewPublicAPITool(r),
+	}
+}
+
+// GetToolsWithHistory returns the search tools with every search recorded
+// in history, plus the search_history and search_saved tools
+func GetToolsWithHistory(r *router.Router, history *HistoryStore) []tools.Tool {
+	searchTool := &SearchTool{router: r, history: history}
+	return []tools.Tool{
+		searchTool,
+		&FindTool{},
+		&GlobTool{},
+		NewSymbolsTool(r),
+		NewReferencesTool(r),
+		NewCompleteTool(r),
+		NewHoverTool(r),
+		NewImplementationsTool(r),
+		NewGoAnalyzeTool(r),
+		NewFileSummaryTool(r),
+		NewPublicAPITool(r),
+		NewSearchHistoryTool(history),
+		NewSavedSearchTool(history, searchTool),
 	}
 }
 
@@ -0,0 +1,64 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+)
+
+const ErrCodeReadOnly = -32005
+
+// SessionOptions are per-client switches negotiated when a session opens.
+// They travel with every request's context.
+type SessionOptions struct {
+	// ReadOnly rejects every tool that can modify files or stored data
+	ReadOnly bool
+	// DryRun makes every mutating tool report what it would change instead
+	// of applying it
+	DryRun bool
+}
+
+type sessionKey struct{}
+
+func WithSession(ctx context.Context, opts SessionOptions) context.Context {
+	return context.WithValue(ctx, sessionKey{}, opts)
+}
+
+// SessionFrom returns the session options carried by ctx, or the zero value
+func SessionFrom(ctx context.Context) SessionOptions {
+	opts, _ := ctx.Value(sessionKey{}).(SessionOptions)
+	return opts
+}
+
+// dispatcher is implemented by tools that only run other tools through the
+// registry; session restrictions are checked on the inner calls instead.
+type dispatcher interface {
+	dispatchesTools()
+}
+
+// IsMutating reports whether tool may modify files or stored data, judged by
+// its readOnlyHint. Tools without annotations are treated as mutating, and
+// tools changing only session state never are.
+func IsMutating(tool Tool) bool {
+	if _, ok := tool.(dispatcher); ok {
+		return false
+	}
+	if _, ok := tool.(sessionScoped); ok {
+		return false
+	}
+	annotated, ok := tool.(AnnotatedTool)
+	if !ok {
+		return true
+	}
+	return !annotated.Annotations()["readOnlyHint"]
+}
+
+func NewReadOnlyError(name string) *ToolError {
+	return &ToolError{
+		Code:    ErrCodeReadOnly,
+		Message: fmt.Sprintf("Tool %s is disabled: the server is in read-only mode", name),
+		Data: map[string]interface{}{
+			"reason": "read_only",
+			"tool":   name,
+		},
+	}
+}
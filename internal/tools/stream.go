@@ -0,0 +1,33 @@
+package tools
+
+import "context"
+
+// StreamFunc delivers one partial result of a running tool call to the
+// client. Partial results precede the call's final result, which sums them
+// up.
+type StreamFunc func(partial interface{})
+
+type streamKey struct{}
+
+// WithStream makes the tool call run under ctx stream its partial results
+// to fn. The transport installs it only when the client can receive them.
+func WithStream(ctx context.Context, fn StreamFunc) context.Context {
+	return context.WithValue(ctx, streamKey{}, fn)
+}
+
+// Streaming reports whether partial results of the call ctx belongs to
+// reach the client
+func Streaming(ctx context.Context) bool {
+	_, ok := ctx.Value(streamKey{}).(StreamFunc)
+	return ok
+}
+
+// Stream sends a partial result of the call ctx belongs to. It does nothing
+// when the call does not stream or has already ended.
+func Stream(ctx context.Context, partial interface{}) {
+	fn, ok := ctx.Value(streamKey{}).(StreamFunc)
+	if !ok || ctx.Err() != nil {
+		return
+	}
+	fn(partial)
+}
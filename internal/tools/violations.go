@@ -0,0 +1,27 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/alucardeht/may-la-mcp/internal/events"
+)
+
+// reportViolation publishes a policy violation event when err is a refusal
+// by the read-only mode or the denied paths
+func reportViolation(ctx context.Context, name string, err error) {
+	var toolErr *ToolError
+	if !errors.As(err, &toolErr) || (toolErr.Code != ErrCodeReadOnly && toolErr.Code != ErrCodePathDenied) {
+		return
+	}
+
+	data := map[string]interface{}{"tool": name}
+	for key, value := range toolErr.Data {
+		data[key] = value
+	}
+	if attribution, ok := AttributionFrom(ctx); ok {
+		data["attribution"] = attribution
+	}
+	events.Publish(events.PolicyViolation, fmt.Sprintf("Refused %s: %s", name, toolErr.Message), data)
+}
@@ -0,0 +1,39 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alucardeht/may-la-mcp/internal/events"
+)
+
+func TestPolicyViolationEvent(t *testing.T) {
+	bus := &events.Bus{}
+	var got []events.Event
+	bus.Subscribe([]string{events.PolicyViolation}, deliverFunc(func(e events.Event) error {
+		got = append(got, e)
+		return nil
+	}))
+	events.SetDefault(bus)
+	defer events.SetDefault(nil)
+
+	r := NewRegistry()
+	r.RegisterIn("files", &namedTool{name: "write"})
+	r.RegisterIn("files", &readTool{namedTool{name: "read"}})
+
+	ctx := WithSession(WithAttribution(context.Background(), "s1"), SessionOptions{ReadOnly: true})
+	r.Execute(ctx, "read", nil)
+	r.Execute(ctx, "write", nil)
+	bus.Close()
+
+	if len(got) != 1 || got[0].Data["tool"] != "write" || got[0].Data["reason"] != "read_only" {
+		t.Fatalf("expected one read-only violation for write, got %+v", got)
+	}
+	if a, ok := got[0].Data["attribution"].(Attribution); !ok || a.SessionID != "s1" {
+		t.Errorf("expected the violation to be attributed, got %v", got[0].Data["attribution"])
+	}
+}
+
+type deliverFunc func(events.Event) error
+
+func (f deliverFunc) Deliver(e events.Event) error { return f(e) }
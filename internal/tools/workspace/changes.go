@@ -0,0 +1,694 @@
+package workspace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/intel"
+	"github.com/alucardeht/may-la-mcp/internal/paths"
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+const (
+	defaultChangeTokens = 800
+	minChangeTokens     = 200
+
+	// emptyTree is git's empty tree, diffed against before the first commit
+	emptyTree = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+	maxSubjectLen = 72
+	// maxListedFiles bounds the file list of a draft
+	maxListedFiles = 20
+)
+
+// Where summarize_changes reads changes from
+const (
+	ChangeSourceAuto    = "auto"
+	ChangeSourceGit     = "git"
+	ChangeSourceBackups = "backups"
+)
+
+// File change statuses
+const (
+	ChangeAdded    = "added"
+	ChangeModified = "modified"
+	ChangeDeleted  = "deleted"
+	ChangeRenamed  = "renamed"
+)
+
+var (
+	// backupSuffix is what the write tool appends to the files it backs up
+	backupSuffix = regexp.MustCompile(`\.bak\.(\d+)$`)
+	diffHeader   = regexp.MustCompile(`^diff --git a/(.+) b/(.+)$`)
+	declaration  = regexp.MustCompile(`^[+-]\s*(?:export\s+)?(?:default\s+)?(?:pub(?:\([a-z]+\))?\s+)?(?:async\s+)?(?:func|def|class|interface|type|struct|enum|trait|fn|function)\s+(?:\([^)]*\)\s*)?([A-Za-z_$][\w$]*)`)
+)
+
+type SummarizeChangesRequest struct {
+	Path      string   `json:"path,omitempty"`
+	Source    string   `json:"source,omitempty"`
+	Paths     []string `json:"paths,omitempty"`
+	Staged    bool     `json:"staged,omitempty"`
+	Since     string   `json:"since,omitempty"`
+	MaxTokens int      `json:"max_tokens,omitempty"`
+}
+
+// FileChange is one changed file with the declarations its diff adds and
+// removes
+type FileChange struct {
+	Path     string   `json:"path"`
+	Status   string   `json:"status"`
+	Added    int      `json:"added"`
+	Removed  int      `json:"removed"`
+	NewDecl  []string `json:"new_declarations,omitempty"`
+	GoneDecl []string `json:"removed_declarations,omitempty"`
+
+	diff string
+}
+
+type SummarizeChangesResponse struct {
+	Root   string       `json:"root"`
+	Source string       `json:"source"`
+	Files  []FileChange `json:"files"`
+	// CommitMessage is a conventional-commit draft: type(scope): subject,
+	// a blank line and a body
+	CommitMessage string `json:"commit_message"`
+	Type          string `json:"type"`
+	Scope         string `json:"scope,omitempty"`
+	// Changelog is a Keep a Changelog entry draft
+	Changelog     string `json:"changelog"`
+	Summary       string `json:"summary"`
+	SummarySource string `json:"summary_source"`
+	// Tokens estimates what the drafts and summary take of MaxTokens
+	Tokens    int   `json:"tokens"`
+	MaxTokens int   `json:"max_tokens"`
+	Truncated bool  `json:"truncated,omitempty"`
+	LatencyMs int64 `json:"latency_ms"`
+}
+
+type SummarizeChangesTool struct{}
+
+func NewSummarizeChangesTool() *SummarizeChangesTool {
+	return &SummarizeChangesTool{}
+}
+
+func (t *SummarizeChangesTool) Name() string {
+	return "summarize_changes"
+}
+
+func (t *SummarizeChangesTool) Description() string {
+	return "Draft a conventional-commit message and a changelog entry from recent edits. Changes are read from git (working tree or staged) or from the .bak backups the write tool leaves, summarized with the configured summarizer, and the drafts are kept within a token budget"
+}
+
+func (t *SummarizeChangesTool) Title() string {
+	return "Summarize Changes"
+}
+
+func (t *SummarizeChangesTool) Annotations() map[string]bool {
+	return tools.ReadOnlyAnnotations()
+}
+
+func (t *SummarizeChangesTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"path": {
+				"type": "string",
+				"description": "Project root (default: the daemon's working directory)"
+			},
+			"source": {
+				"type": "string",
+				"enum": ["auto", "git", "backups"],
+				"description": "Where changes come from: git diff against HEAD, or .bak backups against the current files (default: auto, git when the root is in a repository)"
+			},
+			"paths": {
+				"type": "array",
+				"items": {"type": "string"},
+				"description": "Only these files or patterns, relative to the root"
+			},
+			"staged": {
+				"type": "boolean",
+				"description": "With git, only staged changes"
+			},
+			"since": {
+				"type": "string",
+				"description": "With backups, only backups made within this duration, e.g. 2h (default: all)"
+			},
+			"max_tokens": {
+				"type": "integer",
+				"description": "Token budget for the summary and drafts together (default: 800, minimum: 200)"
+			}
+		}
+	}`)
+}
+
+func (t *SummarizeChangesTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var req SummarizeChangesRequest
+	if len(input) > 0 {
+		if err := json.Unmarshal(input, &req); err != nil {
+			return nil, fmt.Errorf("invalid request: %w", err)
+		}
+	}
+	if req.Path == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("working directory: %w", err)
+		}
+		req.Path = cwd
+	}
+	switch req.Source {
+	case "":
+		req.Source = ChangeSourceAuto
+	case ChangeSourceAuto, ChangeSourceGit, ChangeSourceBackups:
+	default:
+		return nil, fmt.Errorf("unknown source %q (expected auto, git or backups)", req.Source)
+	}
+	var since time.Duration
+	if req.Since != "" {
+		var err error
+		if since, err = time.ParseDuration(req.Since); err != nil || since <= 0 {
+			return nil, fmt.Errorf("invalid since %q: expected a duration such as 2h", req.Since)
+		}
+	}
+	for _, pattern := range req.Paths {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid path pattern %q: %w", pattern, err)
+		}
+	}
+	if req.MaxTokens <= 0 {
+		req.MaxTokens = defaultChangeTokens
+	} else if req.MaxTokens < minChangeTokens {
+		req.MaxTokens = minChangeTokens
+	}
+
+	if err := tools.CheckPath(req.Path); err != nil {
+		return nil, err
+	}
+	root := paths.Canonical(req.Path)
+	if info, err := os.Stat(root); err != nil {
+		return nil, fmt.Errorf("path not found: %w", err)
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", req.Path)
+	}
+
+	start := time.Now()
+	source := req.Source
+	if source == ChangeSourceAuto {
+		source = ChangeSourceBackups
+		if _, err := exec.LookPath("git"); err == nil && detectVCS(root) != nil {
+			source = ChangeSourceGit
+		}
+	}
+
+	var changes []FileChange
+	var err error
+	if source == ChangeSourceGit {
+		changes, err = gitChanges(ctx, root, req.Staged)
+	} else {
+		changes, err = backupChanges(ctx, root, since)
+	}
+	if err != nil {
+		return nil, err
+	}
+	changes = filterChanges(changes, req.Paths)
+	if len(changes) == 0 {
+		return nil, fmt.Errorf("no changes found in %s (source: %s)", root, source)
+	}
+
+	resp := &SummarizeChangesResponse{
+		Root:      root,
+		Source:    source,
+		Files:     changes,
+		MaxTokens: req.MaxTokens,
+	}
+	resp.Type, resp.Scope = commitType(changes), commitScope(changes)
+	subject := commitSubject(resp.Type, changes)
+
+	// the summary gets what the subject, file list and changelog leave of
+	// the budget; the changelog repeats the subject, so it is counted twice
+	fixed := intel.EstimateTokens(subject)*2 + intel.EstimateTokens(fileList(changes)) + 20
+	summaryTokens := req.MaxTokens - fixed
+	if summaryTokens < req.MaxTokens/4 {
+		summaryTokens = req.MaxTokens / 4
+	}
+	digest, clipped := changeDigest(changes, summaryTokens*4*4)
+	resp.Summary, resp.SummarySource = intel.SummarizeContent(ctx, digest, summaryTokens*4)
+	resp.Truncated = clipped
+
+	resp.CommitMessage = commitMessage(resp.Type, resp.Scope, subject, resp.Summary, changes)
+	resp.Changelog = changelogEntry(resp.Type, resp.Scope, subject, changes)
+	resp.Tokens = intel.EstimateTokens(resp.CommitMessage) + intel.EstimateTokens(resp.Changelog)
+	for resp.Tokens > req.MaxTokens && len(resp.Summary) > 0 {
+		// the file list and subject are what a draft cannot do without
+		cut := len(resp.Summary) - (resp.Tokens-req.MaxTokens)*4
+		if cut < 0 {
+			cut = 0
+		}
+		resp.Summary = intel.Truncate(resp.Summary, cut, intel.TruncateModeSmart)
+		if cut == 0 {
+			resp.Summary = ""
+		}
+		resp.Truncated = true
+		resp.CommitMessage = commitMessage(resp.Type, resp.Scope, subject, resp.Summary, changes)
+		resp.Tokens = intel.EstimateTokens(resp.CommitMessage) + intel.EstimateTokens(resp.Changelog)
+	}
+
+	resp.LatencyMs = time.Since(start).Milliseconds()
+	return resp, nil
+}
+
+// gitChanges reads the working tree's changes against HEAD, or the staged
+// ones, plus untracked files, all relative to root
+func gitChanges(ctx context.Context, root string, staged bool) ([]FileChange, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("git is not installed: %w", err)
+	}
+	if detectVCS(root) == nil {
+		return nil, fmt.Errorf("%s is not in a git repository", root)
+	}
+
+	base := "HEAD"
+	if _, err := runGit(ctx, root, "rev-parse", "--verify", "--quiet", "HEAD"); err != nil {
+		base = emptyTree
+	}
+	args := []string{"diff", "--relative", "--no-color", "--no-ext-diff", "-M"}
+	if staged {
+		args = append(args, "--cached")
+	}
+	out, err := runGit(ctx, root, append(args, base)...)
+	if err != nil {
+		return nil, err
+	}
+	changes := parseGitDiff(out)
+	if staged {
+		return changes, nil
+	}
+
+	untracked, err := runGit(ctx, root, "ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+	for _, rel := range strings.Split(strings.TrimSpace(untracked), "\n") {
+		if rel == "" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(rel)))
+		if err != nil || bytes.IndexByte(data, 0) >= 0 {
+			continue
+		}
+		diff := tools.DiffFile(rel, "", string(data), true)
+		changes = append(changes, newFileChange(rel, ChangeAdded, diff.Diff))
+	}
+	return changes, nil
+}
+
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// parseGitDiff splits git's diff output into one change per file
+func parseGitDiff(out string) []FileChange {
+	var changes []FileChange
+	var file, status string
+	var body strings.Builder
+	flush := func() {
+		if file != "" {
+			changes = append(changes, newFileChange(file, status, body.String()))
+		}
+		body.Reset()
+	}
+
+	for _, line := range strings.Split(out, "\n") {
+		if match := diffHeader.FindStringSubmatch(line); match != nil {
+			flush()
+			file, status = match[2], ChangeModified
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "new file mode"):
+			status = ChangeAdded
+		case strings.HasPrefix(line, "deleted file mode"):
+			status = ChangeDeleted
+		case strings.HasPrefix(line, "rename from"):
+			status = ChangeRenamed
+		}
+		if file != "" {
+			body.WriteString(line)
+			body.WriteByte('\n')
+		}
+	}
+	flush()
+	return changes
+}
+
+// backupChanges diffs each file the write tool backed up against its
+// oldest backup, so the change covers every write since then
+func backupChanges(ctx context.Context, root string, since time.Duration) ([]FileChange, error) {
+	oldest := make(map[string]string)
+	stamps := make(map[string]int64)
+	cutoff := int64(0)
+	if since > 0 {
+		cutoff = time.Now().Add(-since).UnixNano()
+	}
+
+	err := filepath.WalkDir(root, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if entry.IsDir() {
+			if file != root && (strings.HasPrefix(entry.Name(), ".") || vendoredDirs[entry.Name()]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		match := backupSuffix.FindStringSubmatch(file)
+		if match == nil {
+			return nil
+		}
+		stamp, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || stamp < cutoff {
+			return nil
+		}
+		original := strings.TrimSuffix(file, match[0])
+		if prev, ok := stamps[original]; !ok || stamp < prev {
+			oldest[original], stamps[original] = file, stamp
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []FileChange
+	for original, backup := range oldest {
+		before, err := os.ReadFile(backup)
+		if err != nil {
+			continue
+		}
+		rel := filepath.ToSlash(relPath(root, original))
+		after, err := os.ReadFile(original)
+		status := ChangeModified
+		if err != nil {
+			status = ChangeDeleted
+		}
+		if string(before) == string(after) {
+			continue
+		}
+		diff := tools.DiffFile(rel, string(before), string(after), false)
+		changes = append(changes, newFileChange(rel, status, diff.Diff))
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+// newFileChange counts a file's diff lines and the declarations it adds
+// and removes; a declaration on both sides only changed its signature
+func newFileChange(file, status, diff string) FileChange {
+	change := FileChange{Path: file, Status: status, diff: diff}
+	added := make(map[string]bool)
+	removed := make(map[string]bool)
+	inHunk := false
+	for _, line := range strings.Split(diff, "\n") {
+		if strings.HasPrefix(line, "@@") {
+			inHunk = true
+			continue
+		}
+		if !inHunk || line == "" {
+			continue
+		}
+		switch line[0] {
+		case '+':
+			change.Added++
+			if match := declaration.FindStringSubmatch(line); match != nil {
+				added[match[1]] = true
+			}
+		case '-':
+			change.Removed++
+			if match := declaration.FindStringSubmatch(line); match != nil {
+				removed[match[1]] = true
+			}
+		}
+	}
+	for name := range added {
+		if !removed[name] {
+			change.NewDecl = append(change.NewDecl, name)
+		}
+	}
+	for name := range removed {
+		if !added[name] {
+			change.GoneDecl = append(change.GoneDecl, name)
+		}
+	}
+	sort.Strings(change.NewDecl)
+	sort.Strings(change.GoneDecl)
+	return change
+}
+
+// filterChanges keeps the changes whose path, or base name, matches one of
+// the patterns or lies under one of them
+func filterChanges(changes []FileChange, patterns []string) []FileChange {
+	if len(patterns) == 0 {
+		return changes
+	}
+	var kept []FileChange
+	for _, change := range changes {
+		for _, pattern := range patterns {
+			pattern = strings.TrimSuffix(filepath.ToSlash(pattern), "/")
+			matched, _ := path.Match(pattern, change.Path)
+			if !matched {
+				matched, _ = path.Match(pattern, path.Base(change.Path))
+			}
+			if matched || strings.HasPrefix(change.Path, pattern+"/") {
+				kept = append(kept, change)
+				break
+			}
+		}
+	}
+	return kept
+}
+
+// changeKind classifies a file for picking the commit type
+func changeKind(file string) string {
+	base := strings.ToLower(path.Base(file))
+	switch {
+	case isTestFile(file):
+		return "test"
+	case strings.HasPrefix(file, ".github/") || strings.HasPrefix(file, ".gitlab-ci") || strings.HasPrefix(file, ".circleci/"):
+		return "ci"
+	case strings.HasPrefix(file, "docs/") || strings.HasSuffix(base, ".md") || strings.HasSuffix(base, ".rst") || strings.HasSuffix(base, ".txt"):
+		return "docs"
+	case base == "go.mod" || base == "go.sum" || base == "package.json" || strings.HasSuffix(base, "lock.json") ||
+		base == "yarn.lock" || base == "cargo.toml" || base == "cargo.lock" || base == "pyproject.toml" ||
+		base == "makefile" || base == "dockerfile":
+		return "build"
+	}
+	return "code"
+}
+
+// commitType picks the conventional-commit type. Changes touching only
+// tests, docs, CI or build files take that type; otherwise new files or
+// declarations make a feat, removed code a refactor and the rest a fix.
+func commitType(changes []FileChange) string {
+	kinds := make(map[string]bool)
+	for _, change := range changes {
+		kinds[changeKind(change.Path)] = true
+	}
+	if len(kinds) == 1 {
+		for kind := range kinds {
+			if kind != "code" {
+				return kind
+			}
+		}
+	}
+
+	added, removed, newDecls, newFiles := 0, 0, 0, 0
+	for _, change := range changes {
+		if changeKind(change.Path) != "code" {
+			continue
+		}
+		added += change.Added
+		removed += change.Removed
+		newDecls += len(change.NewDecl)
+		if change.Status == ChangeAdded {
+			newFiles++
+		}
+	}
+	switch {
+	case newFiles > 0 || newDecls > 0:
+		return "feat"
+	case removed > added:
+		return "refactor"
+	}
+	return "fix"
+}
+
+// commitScope is the directory all changed files share, by its last
+// element, or "" when they share none
+func commitScope(changes []FileChange) string {
+	dir := path.Dir(changes[0].Path)
+	for _, change := range changes[1:] {
+		for dir != "." && dir != path.Dir(change.Path) && !strings.HasPrefix(change.Path, dir+"/") {
+			dir = path.Dir(dir)
+		}
+	}
+	if dir == "." || dir == "/" {
+		return ""
+	}
+	return path.Base(dir)
+}
+
+// commitSubject names what changed: the declarations added or removed,
+// else the file or the number of files
+func commitSubject(kind string, changes []FileChange) string {
+	var added, removed, deleted []string
+	for _, change := range changes {
+		added = append(added, change.NewDecl...)
+		removed = append(removed, change.GoneDecl...)
+		if change.Status == ChangeDeleted {
+			deleted = append(deleted, path.Base(change.Path))
+		}
+	}
+
+	var subject string
+	switch {
+	case kind == "feat" && len(added) > 0:
+		subject = "add " + nameList(added)
+	case kind == "feat":
+		subject = "add " + nameList(changedNames(changes))
+	case len(deleted) == len(changes):
+		subject = "remove " + nameList(deleted)
+	case kind == "refactor" && len(removed) > 0:
+		subject = "remove " + nameList(removed)
+	case len(changes) == 1:
+		subject = "update " + path.Base(changes[0].Path)
+	default:
+		subject = fmt.Sprintf("update %d files", len(changes))
+	}
+	if runes := []rune(subject); len(runes) > maxSubjectLen {
+		subject = string(runes[:maxSubjectLen-3]) + "..."
+	}
+	return subject
+}
+
+func changedNames(changes []FileChange) []string {
+	names := make([]string, 0, len(changes))
+	for _, change := range changes {
+		names = append(names, path.Base(change.Path))
+	}
+	return names
+}
+
+// nameList joins up to three names, counting the rest
+func nameList(names []string) string {
+	if len(names) <= 3 {
+		return strings.Join(names, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(names[:3], ", "), len(names)-3)
+}
+
+func fileList(changes []FileChange) string {
+	var b strings.Builder
+	for i, change := range changes {
+		if i == maxListedFiles {
+			fmt.Fprintf(&b, "- and %d more files\n", len(changes)-i)
+			break
+		}
+		fmt.Fprintf(&b, "- %s (%s, +%d -%d)\n", change.Path, change.Status, change.Added, change.Removed)
+	}
+	return b.String()
+}
+
+// changeDigest is the text summarized: the file list then the diffs,
+// each diff cut to its share of maxLen
+func changeDigest(changes []FileChange, maxLen int) (string, bool) {
+	var b strings.Builder
+	b.WriteString("Changed files:\n")
+	b.WriteString(fileList(changes))
+
+	share := (maxLen - b.Len()) / len(changes)
+	clipped := false
+	for _, change := range changes {
+		diff := change.diff
+		if share > 0 && len(diff) > share {
+			diff = intel.Truncate(diff, share, intel.TruncateModeSmart)
+			clipped = true
+		}
+		b.WriteString("\n")
+		b.WriteString(diff)
+	}
+	return b.String(), clipped
+}
+
+func commitMessage(kind, scope, subject, summary string, changes []FileChange) string {
+	header := kind
+	if scope != "" {
+		header += "(" + scope + ")"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s\n", header, subject)
+	if summary = strings.TrimSpace(summary); summary != "" {
+		b.WriteString("\n")
+		b.WriteString(summary)
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+	b.WriteString(fileList(changes))
+	return b.String()
+}
+
+// changelogSections maps commit types to Keep a Changelog sections
+var changelogSections = map[string]string{
+	"feat":     "Added",
+	"fix":      "Fixed",
+	"refactor": "Changed",
+	"docs":     "Changed",
+	"build":    "Changed",
+	"ci":       "Changed",
+	"test":     "Changed",
+}
+
+func changelogEntry(kind, scope, subject string, changes []FileChange) string {
+	section := changelogSections[kind]
+	deleted := 0
+	for _, change := range changes {
+		if change.Status == ChangeDeleted {
+			deleted++
+		}
+	}
+	if deleted == len(changes) {
+		section = "Removed"
+	}
+
+	entry := strings.ToUpper(subject[:1]) + subject[1:]
+	if scope != "" {
+		entry = "**" + scope + "**: " + entry
+	}
+	return fmt.Sprintf("### %s\n\n- %s\n", section, entry)
+}
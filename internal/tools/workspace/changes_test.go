@@ -0,0 +1,111 @@
+package workspace
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSummarizeChangesGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = root
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write := func(rel, content string) {
+		t.Helper()
+		path := filepath.Join(root, rel)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git("init", "--quiet")
+	write("cart/cart.go", "package cart\n\nfunc Total() int {\n\treturn 0\n}\n")
+	write("README.md", "# Shop\n")
+	git("add", "-A")
+	git("commit", "--quiet", "-m", "initial")
+
+	write("cart/cart.go", "package cart\n\nfunc Total() int {\n\treturn 0\n}\n\nfunc ApplyDiscount(percent int) int {\n\treturn percent\n}\n")
+	write("cart/coupon.go", "package cart\n\ntype Coupon struct{}\n")
+
+	tool := NewSummarizeChangesTool()
+	call := func(req SummarizeChangesRequest) *SummarizeChangesResponse {
+		t.Helper()
+		req.Path = root
+		input, _ := json.Marshal(req)
+		result, err := tool.Execute(context.Background(), input)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return result.(*SummarizeChangesResponse)
+	}
+
+	resp := call(SummarizeChangesRequest{})
+	if resp.Source != ChangeSourceGit || len(resp.Files) != 2 {
+		t.Fatalf("expected two changed files from git, got %+v", resp)
+	}
+	if resp.Type != "feat" || resp.Scope != "cart" {
+		t.Errorf("expected feat(cart), got %s(%s)", resp.Type, resp.Scope)
+	}
+	if !strings.HasPrefix(resp.CommitMessage, "feat(cart): add ApplyDiscount, Coupon\n") {
+		t.Errorf("unexpected commit message %q", resp.CommitMessage)
+	}
+	if !strings.HasPrefix(resp.Changelog, "### Added\n\n- **cart**: Add ApplyDiscount, Coupon") {
+		t.Errorf("unexpected changelog %q", resp.Changelog)
+	}
+	if resp.Tokens > resp.MaxTokens {
+		t.Errorf("expected the drafts within %d tokens, got %d", resp.MaxTokens, resp.Tokens)
+	}
+
+	git("add", "cart/coupon.go")
+	resp = call(SummarizeChangesRequest{Staged: true})
+	if len(resp.Files) != 1 || resp.Files[0].Path != "cart/coupon.go" || resp.Files[0].Status != ChangeAdded {
+		t.Errorf("expected only the staged coupon.go, got %+v", resp.Files)
+	}
+
+	write("README.md", "# Shop\n\nA small shop.\n")
+	resp = call(SummarizeChangesRequest{Paths: []string{"*.md"}})
+	if resp.Type != "docs" || !strings.HasPrefix(resp.CommitMessage, "docs: update README.md") {
+		t.Errorf("expected a docs commit for README.md, got %q", resp.CommitMessage)
+	}
+}
+
+func TestSummarizeChangesBackups(t *testing.T) {
+	root := t.TempDir()
+	original := filepath.Join(root, "util.py")
+	os.WriteFile(original+".bak.100", []byte("def parse(text):\n    return text\n\ndef legacy():\n    pass\n"), 0644)
+	os.WriteFile(original+".bak.200", []byte("unrelated\n"), 0644)
+	os.WriteFile(original, []byte("def parse(text):\n    return text.strip()\n"), 0644)
+
+	tool := NewSummarizeChangesTool()
+	input, _ := json.Marshal(SummarizeChangesRequest{Path: root, Source: ChangeSourceBackups, MaxTokens: 200})
+	result, err := tool.Execute(context.Background(), input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp := result.(*SummarizeChangesResponse)
+	if len(resp.Files) != 1 || resp.Files[0].Path != "util.py" {
+		t.Fatalf("expected util.py diffed against its oldest backup, got %+v", resp.Files)
+	}
+	if resp.Type != "refactor" || !strings.HasPrefix(resp.CommitMessage, "refactor: remove legacy") {
+		t.Errorf("unexpected commit message %q", resp.CommitMessage)
+	}
+
+	input, _ = json.Marshal(SummarizeChangesRequest{Path: root, Source: ChangeSourceBackups, Since: "1h"})
+	if _, err := tool.Execute(context.Background(), input); err == nil {
+		t.Error("expected no changes for backups older than an hour")
+	}
+}
@@ -0,0 +1,382 @@
+package workspace
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+	"unicode/utf8"
+
+	"github.com/alucardeht/may-la-mcp/internal/checkpoint"
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+// maxRestoreDiffs bounds the diffs a restore preview carries
+const maxRestoreDiffs = 20
+
+// NewCheckpointTools returns the tools snapshotting workspaces into store
+// and rolling them back
+func NewCheckpointTools(store *checkpoint.Store) []tools.Tool {
+	return []tools.Tool{
+		&CheckpointCreateTool{store: store},
+		&CheckpointRestoreTool{store: store},
+		&CheckpointListTool{store: store},
+	}
+}
+
+// checkpointRoot resolves the workspace a checkpoint call is about: path,
+// or the daemon's working directory
+func checkpointRoot(path string) (string, error) {
+	if path == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return "", fmt.Errorf("working directory: %w", err)
+		}
+		path = cwd
+	}
+	if err := tools.CheckPath(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+type CheckpointSummary struct {
+	ID      string    `json:"id"`
+	Name    string    `json:"name,omitempty"`
+	Created time.Time `json:"created"`
+	Files   int       `json:"files"`
+	Bytes   int64     `json:"bytes"`
+}
+
+func summarizeCheckpoint(cp *checkpoint.Checkpoint) CheckpointSummary {
+	return CheckpointSummary{ID: cp.ID, Name: cp.Name, Created: cp.Created, Files: len(cp.Files), Bytes: cp.Bytes()}
+}
+
+type CheckpointCreateRequest struct {
+	Path string `json:"path,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+type CheckpointCreateResponse struct {
+	CheckpointSummary
+	Root string `json:"root"`
+	// Skipped are files left out for being over the size limit
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+type CheckpointCreateTool struct {
+	store *checkpoint.Store
+}
+
+func (t *CheckpointCreateTool) Name() string {
+	return "checkpoint_create"
+}
+
+func (t *CheckpointCreateTool) Description() string {
+	return `Snapshot the files of a workspace so they can be rolled back with checkpoint_restore.
+
+Take one before a change spanning many files. Every file that is not ignored (.gitignore, .git, the index exclude patterns) is saved, tracked by git or not; contents are stored once, so checkpoints of a mostly unchanged tree are cheap. Returns the checkpoint id. Only the newest checkpoints of each workspace are kept.`
+}
+
+func (t *CheckpointCreateTool) Title() string {
+	return "Create Checkpoint"
+}
+
+func (t *CheckpointCreateTool) Annotations() map[string]bool {
+	return tools.NonIdempotentWriteAnnotations()
+}
+
+func (t *CheckpointCreateTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"path": {
+				"type": "string",
+				"description": "Workspace root to snapshot (default: the daemon's working directory)"
+			},
+			"name": {
+				"type": "string",
+				"description": "Label for the checkpoint, such as what is about to change"
+			}
+		}
+	}`)
+}
+
+func (t *CheckpointCreateTool) OutputSchema() json.RawMessage {
+	return tools.OutputSchemaOf(CheckpointCreateResponse{})
+}
+
+func (t *CheckpointCreateTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var req CheckpointCreateRequest
+	if len(input) > 0 {
+		if err := json.Unmarshal(input, &req); err != nil {
+			return nil, fmt.Errorf("invalid request: %w", err)
+		}
+	}
+	root, err := checkpointRoot(req.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	cp, err := t.store.Create(root, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &CheckpointCreateResponse{
+		CheckpointSummary: summarizeCheckpoint(cp),
+		Root:              cp.Root,
+		Skipped:           cp.Skipped,
+	}, nil
+}
+
+func (t *CheckpointCreateTool) DryRun(ctx context.Context, input json.RawMessage) (*tools.Preview, error) {
+	var req CheckpointCreateRequest
+	if len(input) > 0 {
+		if err := json.Unmarshal(input, &req); err != nil {
+			return nil, fmt.Errorf("invalid request: %w", err)
+		}
+	}
+	root, err := checkpointRoot(req.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := t.store.Files(root)
+	if err != nil {
+		return nil, err
+	}
+	return &tools.Preview{
+		Summary: fmt.Sprintf("would snapshot %d files of %s", len(files), root),
+		Details: map[string]interface{}{"files": len(files)},
+	}, nil
+}
+
+type CheckpointRestoreRequest struct {
+	Path    string `json:"path,omitempty"`
+	ID      string `json:"id,omitempty"`
+	KeepNew bool   `json:"keep_new,omitempty"`
+}
+
+type CheckpointRestoreResponse struct {
+	Checkpoint CheckpointSummary `json:"checkpoint"`
+	Root       string            `json:"root"`
+	Created    []string          `json:"created,omitempty"`
+	Modified   []string          `json:"modified,omitempty"`
+	Deleted    []string          `json:"deleted,omitempty"`
+	// Undo is the checkpoint of the workspace as it was before the
+	// restore, to restore in turn to take it back
+	Undo string `json:"undo,omitempty"`
+}
+
+type CheckpointRestoreTool struct {
+	store *checkpoint.Store
+}
+
+func (t *CheckpointRestoreTool) Name() string {
+	return "checkpoint_restore"
+}
+
+func (t *CheckpointRestoreTool) Description() string {
+	return `Roll a workspace back to a checkpoint taken with checkpoint_create, the newest one when id is left out.
+
+Files changed or deleted since the checkpoint get their saved content and mode back, and files made since are deleted unless keep_new is set; ignored paths are left alone. The state before the restore is saved as a checkpoint first, returned as undo, which makes it the newest checkpoint. In a dry-run session, returns the files that would change with their diffs.`
+}
+
+func (t *CheckpointRestoreTool) Title() string {
+	return "Restore Checkpoint"
+}
+
+func (t *CheckpointRestoreTool) Annotations() map[string]bool {
+	return tools.DestructiveAnnotations()
+}
+
+func (t *CheckpointRestoreTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"path": {
+				"type": "string",
+				"description": "Workspace root to restore (default: the daemon's working directory)"
+			},
+			"id": {
+				"type": "string",
+				"description": "Checkpoint to restore (default: the newest)"
+			},
+			"keep_new": {
+				"type": "boolean",
+				"description": "Keep files made since the checkpoint instead of deleting them (default: false)"
+			}
+		}
+	}`)
+}
+
+func (t *CheckpointRestoreTool) OutputSchema() json.RawMessage {
+	return tools.OutputSchemaOf(CheckpointRestoreResponse{})
+}
+
+func (t *CheckpointRestoreTool) request(input json.RawMessage) (CheckpointRestoreRequest, string, error) {
+	var req CheckpointRestoreRequest
+	if len(input) > 0 {
+		if err := json.Unmarshal(input, &req); err != nil {
+			return req, "", fmt.Errorf("invalid request: %w", err)
+		}
+	}
+	root, err := checkpointRoot(req.Path)
+	return req, root, err
+}
+
+func (t *CheckpointRestoreTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	req, root, err := t.request(input)
+	if err != nil {
+		return nil, err
+	}
+
+	plan, undo, err := t.store.Restore(root, req.ID, req.KeepNew)
+	if err != nil {
+		return nil, err
+	}
+	resp := &CheckpointRestoreResponse{
+		Checkpoint: summarizeCheckpoint(plan.Checkpoint),
+		Root:       plan.Checkpoint.Root,
+		Created:    plan.Created,
+		Modified:   plan.Modified,
+		Deleted:    plan.Deleted,
+	}
+	if undo != nil {
+		resp.Undo = undo.ID
+	}
+	return resp, nil
+}
+
+func (t *CheckpointRestoreTool) DryRun(ctx context.Context, input json.RawMessage) (*tools.Preview, error) {
+	req, root, err := t.request(input)
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := t.store.Plan(root, req.ID, req.KeepNew)
+	if err != nil {
+		return nil, err
+	}
+	base := plan.Checkpoint.Root
+	abs := func(rels []string) []string {
+		out := make([]string, len(rels))
+		for i, rel := range rels {
+			out[i] = filepath.Join(base, filepath.FromSlash(rel))
+		}
+		return out
+	}
+
+	preview := &tools.Preview{
+		Summary:  fmt.Sprintf("would restore checkpoint %s: %d files recreated, %d modified, %d deleted", plan.Checkpoint.ID, len(plan.Created), len(plan.Modified), len(plan.Deleted)),
+		Created:  abs(plan.Created),
+		Modified: abs(plan.Modified),
+		Deleted:  abs(plan.Deleted),
+	}
+
+	hashes := make(map[string]string, len(plan.Checkpoint.Files))
+	for _, f := range plan.Checkpoint.Files {
+		hashes[f.Path] = f.Hash
+	}
+	for _, rel := range plan.Modified {
+		if len(preview.Diffs) == maxRestoreDiffs {
+			break
+		}
+		path := filepath.Join(base, filepath.FromSlash(rel))
+		current, _, err := tools.ReadExisting(path)
+		if err != nil || !utf8.ValidString(current) {
+			continue
+		}
+		saved, err := t.store.Object(hashes[rel])
+		if err != nil || !utf8.Valid(saved) {
+			continue
+		}
+		if current != string(saved) {
+			preview.Diffs = append(preview.Diffs, tools.DiffFile(path, current, string(saved), false))
+		}
+	}
+	return preview, nil
+}
+
+type CheckpointListRequest struct {
+	Path string `json:"path,omitempty"`
+}
+
+type CheckpointListResponse struct {
+	Root        string              `json:"root"`
+	Checkpoints []CheckpointSummary `json:"checkpoints"`
+}
+
+type CheckpointListTool struct {
+	store *checkpoint.Store
+}
+
+func (t *CheckpointListTool) Name() string {
+	return "checkpoint_list"
+}
+
+func (t *CheckpointListTool) Description() string {
+	return "List the checkpoints of a workspace, newest first, with their id, name, time and file count."
+}
+
+func (t *CheckpointListTool) Title() string {
+	return "List Checkpoints"
+}
+
+func (t *CheckpointListTool) Annotations() map[string]bool {
+	return tools.ReadOnlyAnnotations()
+}
+
+func (t *CheckpointListTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"path": {
+				"type": "string",
+				"description": "Workspace root (default: the daemon's working directory)"
+			}
+		}
+	}`)
+}
+
+func (t *CheckpointListTool) OutputSchema() json.RawMessage {
+	return tools.OutputSchemaOf(CheckpointListResponse{})
+}
+
+func (t *CheckpointListTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var req CheckpointListRequest
+	if len(input) > 0 {
+		if err := json.Unmarshal(input, &req); err != nil {
+			return nil, fmt.Errorf("invalid request: %w", err)
+		}
+	}
+	root, err := checkpointRoot(req.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoints, err := t.store.List(root)
+	if err != nil {
+		return nil, err
+	}
+	resp := &CheckpointListResponse{Root: root, Checkpoints: []CheckpointSummary{}}
+	for _, cp := range checkpoints {
+		resp.Root = cp.Root
+		resp.Checkpoints = append(resp.Checkpoints, summarizeCheckpoint(cp))
+	}
+	return resp, nil
+}
@@ -0,0 +1,64 @@
+package workspace
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alucardeht/may-la-mcp/internal/checkpoint"
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+func TestCheckpointTools(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "notes.txt")
+	os.WriteFile(path, []byte("first\n"), 0644)
+
+	r := tools.NewRegistry()
+	for _, tool := range NewCheckpointTools(checkpoint.New(checkpoint.Config{Dir: t.TempDir()})) {
+		if err := r.RegisterIn("workspace", tool); err != nil {
+			t.Fatal(err)
+		}
+	}
+	call := func(ctx context.Context, name string, args interface{}) interface{} {
+		t.Helper()
+		input, _ := json.Marshal(args)
+		result, err := r.Execute(ctx, name, input)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		return result
+	}
+	ctx := context.Background()
+
+	created := call(ctx, "checkpoint_create", CheckpointCreateRequest{Path: root, Name: "start"}).(*CheckpointCreateResponse)
+	if created.Files != 1 || created.Name != "start" {
+		t.Fatalf("unexpected checkpoint %+v", created)
+	}
+	os.WriteFile(path, []byte("second\n"), 0644)
+
+	// a dry run previews the restore with a diff and changes nothing
+	dryRun := tools.WithSession(ctx, tools.SessionOptions{DryRun: true})
+	preview := call(dryRun, "workspace/checkpoint_restore", CheckpointRestoreRequest{Path: root}).(*tools.Preview)
+	if len(preview.Modified) != 1 || len(preview.Diffs) != 1 {
+		t.Fatalf("unexpected preview %+v", preview)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "second\n" {
+		t.Fatal("a dry run changed the file")
+	}
+
+	restored := call(ctx, "checkpoint_restore", CheckpointRestoreRequest{Path: root, ID: created.ID}).(*CheckpointRestoreResponse)
+	if len(restored.Modified) != 1 || restored.Undo == "" {
+		t.Fatalf("unexpected restore %+v", restored)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "first\n" {
+		t.Errorf("file not restored: %q", data)
+	}
+
+	list := call(ctx, "checkpoint_list", CheckpointListRequest{Path: root}).(*CheckpointListResponse)
+	if len(list.Checkpoints) != 2 || list.Checkpoints[0].ID != restored.Undo {
+		t.Errorf("expected the undo checkpoint listed first, got %+v", list.Checkpoints)
+	}
+}
@@ -0,0 +1,134 @@
+package workspace
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/alucardeht/may-la-mcp/internal/index"
+	"github.com/alucardeht/may-la-mcp/internal/paths"
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+const defaultEncodingExamples = 5
+
+// unicodeEncodings are the Unicode encodings the index detects; files in
+// any other are reported as legacy
+var unicodeEncodings = map[string]bool{
+	"ascii": true, "utf-8": true, "utf-16le": true, "utf-16be": true,
+}
+
+type EncodingReportRequest struct {
+	Path     string `json:"path,omitempty"`
+	Examples int    `json:"examples,omitempty"`
+}
+
+type EncodingReportResponse struct {
+	Root      string                 `json:"root"`
+	Files     int                    `json:"files"`
+	Bytes     int64                  `json:"bytes"`
+	Encodings []*index.EncodingStats `json:"encodings"`
+	// Legacy counts the files in a single or double byte legacy encoding,
+	// such as windows-1252 or shift-jis
+	Legacy int `json:"legacy"`
+	// Unknown counts the files whose encoding was not detected
+	Unknown int `json:"unknown,omitempty"`
+}
+
+type EncodingReportTool struct {
+	store index.Store
+}
+
+func NewEncodingReportTool(store index.Store) *EncodingReportTool {
+	return &EncodingReportTool{store: store}
+}
+
+func (t *EncodingReportTool) Name() string {
+	return "encoding_report"
+}
+
+func (t *EncodingReportTool) Description() string {
+	return "Summarize the encodings detected in the indexed files under a root: file and byte counts per encoding, with example paths, and how many files are in a legacy encoding. Use fix_mojibake on UTF-8 files whose text was mangled by double encoding"
+}
+
+func (t *EncodingReportTool) Title() string {
+	return "Encoding Report"
+}
+
+func (t *EncodingReportTool) Annotations() map[string]bool {
+	return tools.ReadOnlyAnnotations()
+}
+
+func (t *EncodingReportTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"path": {
+				"type": "string",
+				"description": "Project root to report on (default: the daemon's working directory)"
+			},
+			"examples": {
+				"type": "integer",
+				"description": "Example paths listed per encoding (default: 5)"
+			}
+		}
+	}`)
+}
+
+func (t *EncodingReportTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var req EncodingReportRequest
+	if len(input) > 0 {
+		if err := json.Unmarshal(input, &req); err != nil {
+			return nil, fmt.Errorf("invalid request: %w", err)
+		}
+	}
+	if req.Path == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("working directory: %w", err)
+		}
+		req.Path = cwd
+	}
+	if req.Examples <= 0 {
+		req.Examples = defaultEncodingExamples
+	}
+
+	if err := tools.CheckPath(req.Path); err != nil {
+		return nil, err
+	}
+	root := paths.Canonical(req.Path)
+	if info, err := os.Stat(root); err != nil {
+		return nil, fmt.Errorf("path not found: %w", err)
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", req.Path)
+	}
+
+	stats, err := t.store.GetEncodingStats(root, req.Examples)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &EncodingReportResponse{Root: root, Encodings: stats}
+	if resp.Encodings == nil {
+		resp.Encodings = []*index.EncodingStats{}
+	}
+	for _, st := range stats {
+		for i, example := range st.Examples {
+			st.Examples[i] = relPath(root, example)
+		}
+		resp.Files += st.Files
+		resp.Bytes += st.Bytes
+		switch {
+		case st.Encoding == "":
+			resp.Unknown += st.Files
+		case !unicodeEncodings[st.Encoding]:
+			resp.Legacy += st.Files
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,50 @@
+package workspace
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/alucardeht/may-la-mcp/internal/index"
+)
+
+func TestEncodingReport(t *testing.T) {
+	root, store, _, _ := testWorkspace(t)
+
+	for _, f := range []*index.IndexedFile{
+		{Path: filepath.Join(root, "docs", "legacy.txt"), Encoding: "windows-1252", Status: index.StatusIndexed, Size: 40},
+		{Path: filepath.Join(root, "dist", "bundle.js"), Encoding: "utf-8", Status: index.StatusSkippedGenerated, Size: 1000},
+		{Path: filepath.Join(root, "docs", "broken.txt"), Status: index.StatusFailed, Size: 10},
+	} {
+		if _, err := store.UpsertFile(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tool := NewEncodingReportTool(store)
+	input, _ := json.Marshal(EncodingReportRequest{Path: root, Examples: 2})
+	result, err := tool.Execute(context.Background(), input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp := result.(*EncodingReportResponse)
+
+	// the workspace's Go files were indexed with no encoding recorded
+	if resp.Files != 7 || resp.Legacy != 1 || resp.Unknown != 5 {
+		t.Errorf("expected 7 files, 1 legacy and 5 unknown, got %+v", resp)
+	}
+	byEncoding := make(map[string]*index.EncodingStats)
+	for _, st := range resp.Encodings {
+		byEncoding[st.Encoding] = st
+	}
+	if st := byEncoding["windows-1252"]; st == nil || st.Files != 1 || st.Bytes != 40 || len(st.Examples) != 1 || st.Examples[0] != "docs/legacy.txt" {
+		t.Errorf("unexpected windows-1252 stats %+v", st)
+	}
+	if st := byEncoding["utf-8"]; st == nil || st.Files != 1 || st.Examples[0] != "dist/bundle.js" {
+		t.Errorf("expected the generated bundle to be counted, got %+v", st)
+	}
+	if st := byEncoding[""]; st == nil || len(st.Examples) != 2 {
+		t.Errorf("expected two examples of the unknown encoding, got %+v", st)
+	}
+}
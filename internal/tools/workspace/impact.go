@@ -0,0 +1,491 @@
+package workspace
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/index"
+	"github.com/alucardeht/may-la-mcp/internal/paths"
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+	"github.com/alucardeht/may-la-mcp/internal/types"
+)
+
+const (
+	defaultImpactResults = 100
+	// maxImpactLines bounds the lines listed per referencing file
+	maxImpactLines = 10
+)
+
+// Operations impact_analysis simulates
+const (
+	ImpactRename = "rename"
+	ImpactDelete = "delete"
+)
+
+// Blast radius levels
+const (
+	BlastLow    = "low"
+	BlastMedium = "medium"
+	BlastHigh   = "high"
+)
+
+type ImpactAnalysisRequest struct {
+	Path       string `json:"path,omitempty"`
+	Symbol     string `json:"symbol,omitempty"`
+	File       string `json:"file,omitempty"`
+	Operation  string `json:"operation,omitempty"`
+	NewName    string `json:"new_name,omitempty"`
+	MaxResults int    `json:"max_results,omitempty"`
+}
+
+// ImpactDeclaration is an indexed declaration of a name
+type ImpactDeclaration struct {
+	Name     string `json:"name"`
+	Kind     string `json:"kind"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Exported bool   `json:"exported"`
+}
+
+// ImpactFile is a file that would break: the lines naming the target and
+// the symbols those lines belong to
+type ImpactFile struct {
+	File    string   `json:"file"`
+	Count   int      `json:"count"`
+	Lines   []int    `json:"lines"`
+	Symbols []string `json:"symbols,omitempty"`
+	Test    bool     `json:"test,omitempty"`
+}
+
+// ImpactTest is a test file mentioning the target, with the tests that do
+type ImpactTest struct {
+	File  string   `json:"file"`
+	Tests []string `json:"tests,omitempty"`
+}
+
+// BlastRadius scores how far a change reaches, from 0 to 100
+type BlastRadius struct {
+	Score       int    `json:"score"`
+	Level       string `json:"level"`
+	Files       int    `json:"files"`
+	Directories int    `json:"directories"`
+	Symbols     int    `json:"symbols"`
+	Tests       int    `json:"tests"`
+	Exported    bool   `json:"exported"`
+}
+
+type ImpactAnalysisResponse struct {
+	Root      string `json:"root"`
+	Target    string `json:"target"`
+	Operation string `json:"operation"`
+	NewName   string `json:"new_name,omitempty"`
+	// Declarations are where the symbol, or the names the file declares,
+	// are defined
+	Declarations []ImpactDeclaration `json:"declarations"`
+	References   []ImpactFile        `json:"references"`
+	Tests        []ImpactTest        `json:"tests"`
+	// IndexedReferences counts the references recorded in the index, on
+	// top of the name scan
+	IndexedReferences int `json:"indexed_references,omitempty"`
+	// Conflicts are declarations of new_name in the directories of the
+	// renamed symbol, which the rename would clash with
+	Conflicts   []ImpactDeclaration `json:"conflicts,omitempty"`
+	BlastRadius BlastRadius         `json:"blast_radius"`
+	Partial     bool                `json:"partial,omitempty"`
+	Truncated   bool                `json:"truncated,omitempty"`
+	LatencyMs   int64               `json:"latency_ms"`
+}
+
+type ImpactAnalysisTool struct {
+	store index.Store
+}
+
+func NewImpactAnalysisTool(store index.Store) *ImpactAnalysisTool {
+	return &ImpactAnalysisTool{store: store}
+}
+
+func (t *ImpactAnalysisTool) Name() string {
+	return "impact_analysis"
+}
+
+func (t *ImpactAnalysisTool) Description() string {
+	return `Simulate renaming or deleting a symbol or a file before doing it: list the indexed files and symbols that refer to it, the tests that mention it, and a blast radius score.
+
+Pass symbol for a function, type, method or variable, or file for a source file, whose declared names and import paths are followed. References come from a scan of the indexed files for the name as a whole word (comments and strings included) plus the references recorded in the index, so the list errs on the side of too many. The score (0-100, low/medium/high) grows with the files, directories, symbols and tests reached; exported targets and deletions score higher. For a rename, new_name is checked against the names already declared next to the target.`
+}
+
+func (t *ImpactAnalysisTool) Title() string {
+	return "Impact Analysis"
+}
+
+func (t *ImpactAnalysisTool) Annotations() map[string]bool {
+	return tools.ReadOnlyAnnotations()
+}
+
+func (t *ImpactAnalysisTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"path": {
+				"type": "string",
+				"description": "Project root to analyze (default: the daemon's working directory)"
+			},
+			"symbol": {
+				"type": "string",
+				"description": "Symbol to rename or delete; Type.Method names a member"
+			},
+			"file": {
+				"type": "string",
+				"description": "File to rename or delete, or with symbol, the file declaring it when the name is declared in several"
+			},
+			"operation": {
+				"type": "string",
+				"enum": ["rename", "delete"],
+				"description": "Change to simulate (default: rename)"
+			},
+			"new_name": {
+				"type": "string",
+				"description": "Name the symbol would be renamed to, checked for clashes"
+			},
+			"max_results": {
+				"type": "integer",
+				"description": "Maximum referencing files listed (default: 100)"
+			}
+		}
+	}`)
+}
+
+func (t *ImpactAnalysisTool) OutputSchema() json.RawMessage {
+	return tools.OutputSchemaOf(ImpactAnalysisResponse{})
+}
+
+func (t *ImpactAnalysisTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var req ImpactAnalysisRequest
+	if len(input) > 0 {
+		if err := json.Unmarshal(input, &req); err != nil {
+			return nil, fmt.Errorf("invalid request: %w", err)
+		}
+	}
+	if req.Symbol == "" && req.File == "" {
+		return nil, fmt.Errorf("symbol or file is required")
+	}
+	switch req.Operation {
+	case "":
+		req.Operation = ImpactRename
+	case ImpactRename, ImpactDelete:
+	default:
+		return nil, fmt.Errorf("unknown operation %q (expected rename or delete)", req.Operation)
+	}
+	if req.MaxResults <= 0 {
+		req.MaxResults = defaultImpactResults
+	}
+	if req.Path == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("working directory: %w", err)
+		}
+		req.Path = cwd
+	}
+
+	if err := tools.CheckPath(req.Path); err != nil {
+		return nil, err
+	}
+	root := paths.Canonical(req.Path)
+	if info, err := os.Stat(root); err != nil {
+		return nil, fmt.Errorf("path not found: %w", err)
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", req.Path)
+	}
+	file := ""
+	if req.File != "" {
+		file = req.File
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(root, file)
+		}
+		file = paths.Canonical(file)
+	}
+
+	start := time.Now()
+	symbols, err := t.store.GetSymbolUses(types.SymbolKinds, root)
+	if err != nil {
+		return nil, err
+	}
+	indexed, err := t.store.GetIndexedPaths(root)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &ImpactAnalysisResponse{
+		Root:         root,
+		Operation:    req.Operation,
+		NewName:      req.NewName,
+		Declarations: []ImpactDeclaration{},
+		References:   []ImpactFile{},
+		Tests:        []ImpactTest{},
+	}
+
+	// the names whose uses break, and the files mentioning the target
+	// file by path
+	var targets []*index.SymbolUse
+	var pathMention *regexp.Regexp
+	if req.Symbol != "" {
+		name := req.Symbol
+		if i := strings.LastIndex(name, "."); i >= 0 {
+			name = name[i+1:]
+		}
+		resp.Target = req.Symbol
+		for _, sym := range symbols {
+			if sym.Name == name && (file == "" || sym.Path == file) {
+				targets = append(targets, sym)
+			}
+		}
+		if len(targets) == 0 {
+			return nil, fmt.Errorf("symbol %s is not declared in any indexed file under %s", req.Symbol, root)
+		}
+	} else {
+		resp.Target = relPath(root, file)
+		if _, err := os.Stat(file); err != nil {
+			return nil, fmt.Errorf("file not found: %w", err)
+		}
+		// a name also declared in another file does not go with this one
+		declaredElsewhere := make(map[string]bool)
+		for _, sym := range symbols {
+			if sym.Path != file {
+				declaredElsewhere[sym.Name] = true
+			}
+		}
+		for _, sym := range symbols {
+			if sym.Path == file && !declaredElsewhere[sym.Name] {
+				targets = append(targets, sym)
+			}
+		}
+		pathMention = importPattern(file)
+	}
+
+	declared := make(map[string]map[string]bool)
+	for _, sym := range targets {
+		if declared[sym.Name] == nil {
+			declared[sym.Name] = make(map[string]bool)
+		}
+		declared[sym.Name][site(sym.Path, sym.LineStart)] = true
+		resp.IndexedReferences += sym.References
+		resp.Declarations = append(resp.Declarations, ImpactDeclaration{
+			Name:     sym.Name,
+			Kind:     sym.Kind,
+			File:     relPath(root, sym.Path),
+			Line:     sym.LineStart,
+			Exported: sym.Exported,
+		})
+		resp.BlastRadius.Exported = resp.BlastRadius.Exported || sym.Exported
+	}
+
+	// uses inside a file that goes away go with it
+	skip := ""
+	if req.Symbol == "" {
+		skip = file
+	}
+	hits, partial, err := scanImpact(ctx, indexed, skip, declared, pathMention)
+	if err != nil {
+		return nil, err
+	}
+	resp.Partial = partial
+
+	enclosing := enclosingSymbols(symbols)
+	dirs := make(map[string]bool)
+	reached := make(map[string]bool)
+	for _, path := range sortedKeys(hits) {
+		lines := hits[path]
+		ref := ImpactFile{File: relPath(root, path), Count: len(lines), Test: isTestPath(path)}
+		seen := make(map[string]bool)
+		for _, line := range lines {
+			if len(ref.Lines) < maxImpactLines {
+				ref.Lines = append(ref.Lines, line)
+			}
+			if sym := enclosing(path, line); sym != "" && !seen[sym] {
+				seen[sym] = true
+				ref.Symbols = append(ref.Symbols, sym)
+			}
+		}
+
+		dirs[filepath.Dir(path)] = true
+		for _, sym := range ref.Symbols {
+			reached[path+"#"+sym] = true
+		}
+		if ref.Test {
+			resp.Tests = append(resp.Tests, ImpactTest{File: ref.File, Tests: ref.Symbols})
+		}
+		if len(resp.References) == req.MaxResults {
+			resp.Truncated = true
+			continue
+		}
+		resp.References = append(resp.References, ref)
+	}
+
+	if req.Operation == ImpactRename && req.NewName != "" {
+		resp.Conflicts = renameConflicts(root, symbols, targets, req.NewName)
+	}
+
+	resp.BlastRadius.Files = len(hits)
+	resp.BlastRadius.Directories = len(dirs)
+	resp.BlastRadius.Symbols = len(reached)
+	resp.BlastRadius.Tests = len(resp.Tests)
+	resp.BlastRadius.Score, resp.BlastRadius.Level = blastScore(resp.BlastRadius, resp.IndexedReferences, req.Operation)
+	resp.LatencyMs = time.Since(start).Milliseconds()
+	return resp, nil
+}
+
+// scanImpact reads the indexed files other than skip and returns, per
+// file, the lines using a declared name outside its declarations or, with
+// pathMention, naming the target file in an import
+func scanImpact(ctx context.Context, indexed []string, skip string, declared map[string]map[string]bool, pathMention *regexp.Regexp) (map[string][]int, bool, error) {
+	hits := make(map[string][]int)
+	partial := false
+	for i, file := range indexed {
+		if i == maxScannedFiles {
+			partial = true
+			break
+		}
+		if ctx.Err() != nil {
+			return nil, false, ctx.Err()
+		}
+		if file == skip {
+			continue
+		}
+		if info, err := os.Stat(file); err != nil || info.Size() > maxScannedSize {
+			continue
+		}
+		content, _, err := index.ReadFileAsUTF8(file)
+		if err != nil {
+			continue
+		}
+
+		for n, line := range types.SplitLines(content) {
+			lineNo := n + 1
+			hit := pathMention != nil && pathMention.MatchString(line)
+			if !hit {
+				for _, loc := range identifier.FindAllStringIndex(line, -1) {
+					if sites, ok := declared[line[loc[0]:loc[1]]]; ok && !sites[site(file, lineNo)] {
+						hit = true
+						break
+					}
+				}
+			}
+			if hit {
+				hits[file] = append(hits[file], lineNo)
+			}
+		}
+	}
+	return hits, partial, nil
+}
+
+// importPattern matches lines importing file by path: a quoted path ending
+// in its name, with or without extension, or a dotted Python module path
+func importPattern(file string) *regexp.Regexp {
+	base := filepath.Base(file)
+	stem := regexp.QuoteMeta(strings.TrimSuffix(base, filepath.Ext(base)))
+	return regexp.MustCompile(`["'/]` + stem + `(?:\.[A-Za-z0-9]+)?["']|\b(?:from|import)\s+[\w.]*\b` + stem + `\b`)
+}
+
+// enclosingSymbols returns the function naming the innermost symbol of
+// symbols that spans a line of a file, or ""
+func enclosingSymbols(symbols []*index.SymbolUse) func(path string, line int) string {
+	byFile := make(map[string][]*index.SymbolUse)
+	for _, sym := range symbols {
+		byFile[sym.Path] = append(byFile[sym.Path], sym)
+	}
+	return func(path string, line int) string {
+		var best *index.SymbolUse
+		for _, sym := range byFile[path] {
+			end := max(sym.LineEnd, sym.LineStart)
+			if line < sym.LineStart || line > end {
+				continue
+			}
+			if best == nil || sym.LineStart >= best.LineStart && end <= max(best.LineEnd, best.LineStart) {
+				best = sym
+			}
+		}
+		if best == nil {
+			return ""
+		}
+		return best.Name
+	}
+}
+
+// renameConflicts returns the declarations of newName in the directories
+// declaring the targets
+func renameConflicts(root string, symbols, targets []*index.SymbolUse, newName string) []ImpactDeclaration {
+	dirs := make(map[string]bool)
+	for _, sym := range targets {
+		dirs[filepath.Dir(sym.Path)] = true
+	}
+	var conflicts []ImpactDeclaration
+	for _, sym := range symbols {
+		if sym.Name == newName && dirs[filepath.Dir(sym.Path)] {
+			conflicts = append(conflicts, ImpactDeclaration{
+				Name:     sym.Name,
+				Kind:     sym.Kind,
+				File:     relPath(root, sym.Path),
+				Line:     sym.LineStart,
+				Exported: sym.Exported,
+			})
+		}
+	}
+	return conflicts
+}
+
+// blastScore weighs what a change reaches: each file, and more so each
+// other directory, adds to the score, as do the symbols and tests to
+// revisit. Exported targets may be used outside the root, and a deletion
+// leaves every use without a replacement, so both raise it.
+func blastScore(radius BlastRadius, indexedRefs int, operation string) (int, string) {
+	score := float64(radius.Files*3+radius.Directories*5+radius.Symbols+radius.Tests*2) + float64(indexedRefs)/2
+	if radius.Exported {
+		score += 10
+	}
+	if operation == ImpactDelete {
+		score *= 1.5
+	}
+	clamped := int(math.Min(100, math.Round(score)))
+	switch {
+	case clamped >= 50:
+		return clamped, BlastHigh
+	case clamped >= 15:
+		return clamped, BlastMedium
+	}
+	return clamped, BlastLow
+}
+
+// isTestPath reports whether path is a test file or lives under a test
+// directory
+func isTestPath(path string) bool {
+	if isTestFile(path) {
+		return true
+	}
+	for _, dir := range strings.Split(filepath.ToSlash(filepath.Dir(path)), "/") {
+		if dir == "test" || dir == "tests" || dir == "__tests__" || dir == "spec" {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m map[string][]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
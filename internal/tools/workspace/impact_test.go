@@ -0,0 +1,87 @@
+package workspace
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+)
+
+func TestImpactAnalysis(t *testing.T) {
+	root, store, files, add := testWorkspace(t)
+	files["orders/orders_test.go"] = "package orders\n\nfunc TestCheckout() {\n\tCheckout(cart.NewCart())\n}\n"
+	add("orders/orders_test.go")
+
+	tool := NewImpactAnalysisTool(store)
+	call := func(req ImpactAnalysisRequest) *ImpactAnalysisResponse {
+		t.Helper()
+		req.Path = root
+		input, _ := json.Marshal(req)
+		result, err := tool.Execute(context.Background(), input)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return result.(*ImpactAnalysisResponse)
+	}
+	referenced := func(resp *ImpactAnalysisResponse) map[string]ImpactFile {
+		byFile := make(map[string]ImpactFile)
+		for _, ref := range resp.References {
+			byFile[filepath.ToSlash(ref.File)] = ref
+		}
+		return byFile
+	}
+
+	t.Run("Symbol", func(t *testing.T) {
+		resp := call(ImpactAnalysisRequest{Symbol: "Cart", NewName: "NewCart"})
+		if len(resp.Declarations) != 1 || resp.Declarations[0].Line != 3 {
+			t.Fatalf("unexpected declarations %+v", resp.Declarations)
+		}
+		refs := referenced(resp)
+		if ref, ok := refs["cart/cart.go"]; !ok || ref.Lines[0] != 5 || ref.Symbols[0] != "NewCart" {
+			t.Errorf("expected the use in NewCart, got %+v", ref)
+		}
+		if ref, ok := refs["orders/orders.go"]; !ok || ref.Symbols[0] != "Checkout" {
+			t.Errorf("expected the use in Checkout, got %+v", ref)
+		}
+		if len(resp.Conflicts) != 1 || resp.Conflicts[0].Name != "NewCart" {
+			t.Errorf("expected the rename to clash with NewCart, got %+v", resp.Conflicts)
+		}
+		if !resp.BlastRadius.Exported || resp.BlastRadius.Files != len(resp.References) || resp.BlastRadius.Score == 0 {
+			t.Errorf("unexpected blast radius %+v", resp.BlastRadius)
+		}
+	})
+
+	t.Run("Tests", func(t *testing.T) {
+		resp := call(ImpactAnalysisRequest{Symbol: "Checkout", Operation: ImpactDelete})
+		if len(resp.Tests) != 1 || filepath.ToSlash(resp.Tests[0].File) != "orders/orders_test.go" {
+			t.Fatalf("expected the test mentioning Checkout, got %+v", resp.Tests)
+		}
+		rename := call(ImpactAnalysisRequest{Symbol: "Checkout"})
+		if resp.BlastRadius.Score <= rename.BlastRadius.Score {
+			t.Errorf("a deletion should score above a rename: %d vs %d", resp.BlastRadius.Score, rename.BlastRadius.Score)
+		}
+	})
+
+	t.Run("File", func(t *testing.T) {
+		resp := call(ImpactAnalysisRequest{File: "cart/cart.go", Operation: ImpactDelete})
+		if len(resp.Declarations) != 2 {
+			t.Fatalf("expected the file's two declarations, got %+v", resp.Declarations)
+		}
+		refs := referenced(resp)
+		for _, rel := range []string{"cmd/shop/main.go", "orders/orders.go", "orders/orders_test.go"} {
+			if _, ok := refs[rel]; !ok {
+				t.Errorf("expected %s among the references, got %+v", rel, resp.References)
+			}
+		}
+		if _, ok := refs["cart/cart.go"]; ok {
+			t.Error("the deleted file's own uses should not be listed")
+		}
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		input, _ := json.Marshal(ImpactAnalysisRequest{Path: root, Symbol: "Missing"})
+		if _, err := tool.Execute(context.Background(), input); err == nil {
+			t.Error("expected an error for an undeclared symbol")
+		}
+	})
+}
@@ -0,0 +1,376 @@
+package workspace
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/index"
+	"github.com/alucardeht/may-la-mcp/internal/paths"
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+	"github.com/alucardeht/may-la-mcp/internal/vcs"
+)
+
+// npmDefaultTest is the test script npm init writes, which runs no tests
+const npmDefaultTest = `echo "Error: no test specified" && exit 1`
+
+var (
+	goModule       = regexp.MustCompile(`(?m)^module\s+"?([^\s"]+)"?`)
+	makeTestTarget = regexp.MustCompile(`(?m)^test\s*:`)
+	cmakeProject   = regexp.MustCompile(`(?i)project\s*\(\s*([A-Za-z0-9_.-]+)`)
+	pomParent      = regexp.MustCompile(`(?s)<parent>.*?</parent>`)
+	pomArtifactID  = regexp.MustCompile(`<artifactId>\s*([^<\s]+)\s*</artifactId>`)
+	gradleRootName = regexp.MustCompile(`rootProject\.name\s*=\s*["']([^"']+)["']`)
+)
+
+type ProjectInfoRequest struct {
+	Path string `json:"path,omitempty"`
+}
+
+// BuildSystem is a build manifest found at the project root with what it
+// tells about the project
+type BuildSystem struct {
+	Name        string `json:"name"`
+	Manifest    string `json:"manifest"`
+	Language    string `json:"language,omitempty"`
+	Module      string `json:"module,omitempty"`
+	TestCommand string `json:"test_command,omitempty"`
+}
+
+// VCSInfo is the repository the project is checked out in
+type VCSInfo struct {
+	Kind   string `json:"kind"`
+	Root   string `json:"root"`
+	Branch string `json:"branch,omitempty"`
+	// Commit is only set for a detached HEAD
+	Commit string `json:"commit,omitempty"`
+}
+
+type ProjectInfoResponse struct {
+	Root            string                 `json:"root"`
+	PrimaryLanguage string                 `json:"primary_language,omitempty"`
+	Languages       []*index.LanguageStats `json:"languages"`
+	BuildSystems    []BuildSystem          `json:"build_systems"`
+	Module          string                 `json:"module,omitempty"`
+	TestCommand     string                 `json:"test_command,omitempty"`
+	VCS             *VCSInfo               `json:"vcs,omitempty"`
+	CI              []string               `json:"ci"`
+	Linters         []string               `json:"linters"`
+	LatencyMs       int64                  `json:"latency_ms"`
+}
+
+// buildDetector recognizes one kind of build manifest. module and test
+// read the manifest's content; either may be nil.
+type buildDetector struct {
+	manifest string
+	name     string
+	language string
+	module   func(root string, data []byte) string
+	test     func(root string, data []byte) string
+}
+
+var buildDetectors = []buildDetector{
+	{
+		manifest: "go.mod", name: "go", language: "go",
+		module: func(root string, data []byte) string { return firstGroup(goModule, data) },
+		test:   func(root string, data []byte) string { return "go test ./..." },
+	},
+	{
+		manifest: "package.json", name: "npm", language: "javascript",
+		module: func(root string, data []byte) string {
+			var pkg struct {
+				Name string `json:"name"`
+			}
+			json.Unmarshal(data, &pkg)
+			return pkg.Name
+		},
+		test: func(root string, data []byte) string {
+			var pkg struct {
+				Scripts map[string]string `json:"scripts"`
+			}
+			json.Unmarshal(data, &pkg)
+			if script := pkg.Scripts["test"]; script == "" || script == npmDefaultTest {
+				return ""
+			}
+			return nodePackageManager(root) + " test"
+		},
+	},
+	{
+		manifest: "Cargo.toml", name: "cargo", language: "rust",
+		module: func(root string, data []byte) string { return tomlString(data, "package", "name") },
+		test:   func(root string, data []byte) string { return "cargo test" },
+	},
+	{
+		manifest: "pyproject.toml", name: "python", language: "python",
+		module: func(root string, data []byte) string {
+			if name := tomlString(data, "project", "name"); name != "" {
+				return name
+			}
+			return tomlString(data, "tool.poetry", "name")
+		},
+		test: func(root string, data []byte) string { return "pytest" },
+	},
+	{
+		manifest: "setup.py", name: "python", language: "python",
+		test: func(root string, data []byte) string { return "pytest" },
+	},
+	{
+		manifest: "requirements.txt", name: "pip", language: "python",
+	},
+	{
+		manifest: "pom.xml", name: "maven", language: "java",
+		module: func(root string, data []byte) string {
+			return firstGroup(pomArtifactID, pomParent.ReplaceAll(data, nil))
+		},
+		test: func(root string, data []byte) string { return "mvn test" },
+	},
+	{
+		manifest: "build.gradle", name: "gradle", language: "java",
+		module: gradleModule, test: gradleTest,
+	},
+	{
+		manifest: "build.gradle.kts", name: "gradle", language: "kotlin",
+		module: gradleModule, test: gradleTest,
+	},
+	{
+		manifest: "CMakeLists.txt", name: "cmake", language: "cpp",
+		module: func(root string, data []byte) string { return firstGroup(cmakeProject, data) },
+		test:   func(root string, data []byte) string { return "ctest" },
+	},
+	{
+		manifest: "Makefile", name: "make",
+		test: func(root string, data []byte) string {
+			if makeTestTarget.Match(data) {
+				return "make test"
+			}
+			return ""
+		},
+	},
+}
+
+type ProjectInfoTool struct {
+	store index.Store
+}
+
+func NewProjectInfoTool(store index.Store) *ProjectInfoTool {
+	return &ProjectInfoTool{store: store}
+}
+
+func (t *ProjectInfoTool) Name() string {
+	return "project_info"
+}
+
+func (t *ProjectInfoTool) Description() string {
+	return "Report what the project is built with: languages (from the index), build systems with the module name and test command each manifest implies, the VCS root and branch, and the CI and linter configuration present. Call it first instead of guessing the stack"
+}
+
+func (t *ProjectInfoTool) Title() string {
+	return "Project Info"
+}
+
+func (t *ProjectInfoTool) Annotations() map[string]bool {
+	return tools.ReadOnlyAnnotations()
+}
+
+func (t *ProjectInfoTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"path": {
+				"type": "string",
+				"description": "Project root to describe (default: the daemon's working directory)"
+			}
+		}
+	}`)
+}
+
+func (t *ProjectInfoTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var req ProjectInfoRequest
+	if len(input) > 0 {
+		if err := json.Unmarshal(input, &req); err != nil {
+			return nil, fmt.Errorf("invalid request: %w", err)
+		}
+	}
+	if req.Path == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("working directory: %w", err)
+		}
+		req.Path = cwd
+	}
+	if err := tools.CheckPath(req.Path); err != nil {
+		return nil, err
+	}
+
+	root := paths.Canonical(req.Path)
+	if info, err := os.Stat(root); err != nil {
+		return nil, fmt.Errorf("path not found: %w", err)
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", req.Path)
+	}
+
+	start := time.Now()
+	resp := &ProjectInfoResponse{
+		Root:         root,
+		Languages:    []*index.LanguageStats{},
+		BuildSystems: detectBuildSystems(root),
+		VCS:          detectVCS(root),
+		CI:           existing(root, ciFiles),
+		Linters:      existing(root, lintFiles),
+	}
+	if resp.CI == nil {
+		resp.CI = []string{}
+	}
+	if resp.Linters == nil {
+		resp.Linters = []string{}
+	}
+
+	if t.store != nil {
+		languages, err := t.store.GetLanguageStats(root)
+		if err != nil {
+			return nil, err
+		}
+		for _, lang := range languages {
+			if lang.Language != "" {
+				resp.Languages = append(resp.Languages, lang)
+			}
+		}
+	}
+	if len(resp.Languages) > 0 {
+		resp.PrimaryLanguage = resp.Languages[0].Language
+	}
+
+	for _, build := range resp.BuildSystems {
+		if resp.Module == "" {
+			resp.Module = build.Module
+		}
+		if resp.PrimaryLanguage == "" {
+			resp.PrimaryLanguage = build.Language
+		}
+		// a Makefile test target usually wraps the language's test runner
+		// with the setup it needs, so it wins
+		if build.TestCommand != "" && (resp.TestCommand == "" || build.Name == "make") {
+			resp.TestCommand = build.TestCommand
+		}
+	}
+
+	resp.LatencyMs = time.Since(start).Milliseconds()
+	return resp, nil
+}
+
+// detectBuildSystems lists the build manifests at root in the order of
+// buildDetectors
+func detectBuildSystems(root string) []BuildSystem {
+	found := []BuildSystem{}
+	for _, detector := range buildDetectors {
+		data, err := os.ReadFile(filepath.Join(root, detector.manifest))
+		if err != nil {
+			continue
+		}
+		build := BuildSystem{Name: detector.name, Manifest: detector.manifest, Language: detector.language}
+		if detector.module != nil {
+			build.Module = detector.module(root, data)
+		}
+		if detector.test != nil {
+			build.TestCommand = detector.test(root, data)
+		}
+		if detector.manifest == "package.json" {
+			build.Name = nodePackageManager(root)
+			if _, err := os.Stat(filepath.Join(root, "tsconfig.json")); err == nil {
+				build.Language = "typescript"
+			}
+		}
+		found = append(found, build)
+	}
+	return found
+}
+
+// detectVCS finds the git repository holding root, looking upwards
+func detectVCS(root string) *VCSInfo {
+	repo := vcs.Find(root)
+	if repo == nil {
+		return nil
+	}
+	info := &VCSInfo{Kind: "git", Root: repo.Root}
+	branch, commit := repo.Head()
+	info.Branch = branch
+	if len(commit) >= 12 {
+		info.Commit = commit[:12]
+	}
+	return info
+}
+
+// nodePackageManager names the package manager a lockfile at root points
+// to, npm when there is none
+func nodePackageManager(root string) string {
+	for _, lock := range []struct{ file, manager string }{
+		{"pnpm-lock.yaml", "pnpm"}, {"yarn.lock", "yarn"}, {"bun.lockb", "bun"}, {"bun.lock", "bun"},
+	} {
+		if _, err := os.Stat(filepath.Join(root, lock.file)); err == nil {
+			return lock.manager
+		}
+	}
+	return "npm"
+}
+
+func gradleModule(root string, data []byte) string {
+	for _, settings := range []string{"settings.gradle", "settings.gradle.kts"} {
+		if data, err := os.ReadFile(filepath.Join(root, settings)); err == nil {
+			if name := firstGroup(gradleRootName, data); name != "" {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+func gradleTest(root string, data []byte) string {
+	if _, err := os.Stat(filepath.Join(root, "gradlew")); err == nil {
+		return "./gradlew test"
+	}
+	return "gradle test"
+}
+
+// tomlString returns the string value of key in the TOML table section,
+// enough for the name of a Cargo or Python package
+func tomlString(data []byte, section, key string) string {
+	current := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			current = strings.TrimSpace(strings.Trim(line, "[]"))
+			continue
+		}
+		if current != section {
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(name) != key {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			return value[1 : len(value)-1]
+		}
+	}
+	return ""
+}
+
+func firstGroup(re *regexp.Regexp, data []byte) string {
+	if m := re.FindSubmatch(data); m != nil {
+		return string(m[1])
+	}
+	return ""
+}
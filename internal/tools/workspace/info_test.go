@@ -0,0 +1,62 @@
+package workspace
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProjectInfo(t *testing.T) {
+	root, store, _, _ := testWorkspace(t)
+
+	for rel, content := range map[string]string{
+		".git/HEAD":                "ref: refs/heads/feature/login\n",
+		"Makefile":                 "build:\n\tgo build ./...\n\ntest: build\n\tgo test -race ./...\n",
+		"package.json":             `{"name": "shop-web", "scripts": {"test": "vitest"}}`,
+		"yarn.lock":                "",
+		".github/workflows/ci.yml": "on: push\n",
+		".golangci.yml":            "linters: {}\n",
+	} {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tool := NewProjectInfoTool(store)
+	input, _ := json.Marshal(ProjectInfoRequest{Path: root})
+	result, err := tool.Execute(context.Background(), input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp := result.(*ProjectInfoResponse)
+
+	if resp.PrimaryLanguage != "go" || len(resp.Languages) != 1 || resp.Languages[0].Files != 5 {
+		t.Errorf("expected 5 indexed go files, got %q %+v", resp.PrimaryLanguage, resp.Languages)
+	}
+	if resp.Module != "example.com/shop" || resp.TestCommand != "make test" {
+		t.Errorf("expected the go module and the Makefile test target, got %q and %q", resp.Module, resp.TestCommand)
+	}
+	if len(resp.BuildSystems) != 3 || resp.BuildSystems[1].Name != "yarn" || resp.BuildSystems[1].TestCommand != "yarn test" || resp.BuildSystems[1].Module != "shop-web" {
+		t.Errorf("expected go, yarn and make build systems, got %+v", resp.BuildSystems)
+	}
+	if resp.VCS == nil || resp.VCS.Kind != "git" || resp.VCS.Root != root || resp.VCS.Branch != "feature/login" {
+		t.Errorf("expected the git branch feature/login, got %+v", resp.VCS)
+	}
+	if len(resp.CI) != 1 || resp.CI[0] != ".github/workflows" || len(resp.Linters) != 1 {
+		t.Errorf("expected the CI workflows and golangci config, got %v %v", resp.CI, resp.Linters)
+	}
+
+	sub, _ := json.Marshal(ProjectInfoRequest{Path: filepath.Join(root, "cart")})
+	if result, err = tool.Execute(context.Background(), sub); err != nil {
+		t.Fatalf("unexpected error for a subdirectory: %v", err)
+	}
+	if vcs := result.(*ProjectInfoResponse).VCS; vcs == nil || vcs.Root != root {
+		t.Errorf("expected the repository to be found above a subdirectory, got %+v", vcs)
+	}
+}
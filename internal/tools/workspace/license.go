@@ -0,0 +1,328 @@
+package workspace
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/license"
+	"github.com/alucardeht/may-la-mcp/internal/paths"
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+const defaultLicenseResults = 500
+
+// headerExtensions are the source files checked for the header by default
+var headerExtensions = []string{
+	".go", ".py", ".js", ".jsx", ".ts", ".tsx", ".mjs", ".cjs", ".java", ".kt",
+	".rs", ".c", ".h", ".cc", ".cpp", ".hpp", ".cs", ".rb", ".php", ".swift",
+	".scala", ".sh", ".lua",
+}
+
+// vendoredDirs hold code the project does not own
+var vendoredDirs = map[string]bool{
+	"node_modules": true, "vendor": true, "third_party": true, "dist": true,
+	"build": true, "target": true, "__pycache__": true, "venv": true, "env": true,
+}
+
+type LicenseCheckRequest struct {
+	Path       string   `json:"path,omitempty"`
+	Allow      []string `json:"allow,omitempty"`
+	Header     string   `json:"header,omitempty"`
+	Extensions []string `json:"extensions,omitempty"`
+	Exclude    []string `json:"exclude,omitempty"`
+	MaxResults int      `json:"max_results,omitempty"`
+}
+
+type ProjectLicense struct {
+	// SPDX is the identifier, empty when the license is not recognized
+	SPDX string `json:"spdx,omitempty"`
+	File string `json:"file,omitempty"`
+	// Declared is the license the manifest declares, when it has one
+	Declared string `json:"declared,omitempty"`
+}
+
+type HeaderReport struct {
+	// Enabled is false when no header template is configured
+	Enabled   bool     `json:"enabled"`
+	Checked   int      `json:"checked"`
+	Missing   []string `json:"missing"`
+	Truncated bool     `json:"truncated,omitempty"`
+}
+
+type LicenseCheckResponse struct {
+	Root string `json:"root"`
+	// Passed is false when a file lacks the header or a dependency
+	// conflicts with the allowlist; unknown licenses do not fail the check
+	Passed       bool                 `json:"passed"`
+	License      ProjectLicense       `json:"license"`
+	Headers      HeaderReport         `json:"headers"`
+	Allow        []string             `json:"allow,omitempty"`
+	Dependencies []license.Dependency `json:"dependencies"`
+	// Conflicts and Unknown name the dependencies with those statuses
+	Conflicts []string `json:"conflicts"`
+	Unknown   []string `json:"unknown"`
+	LatencyMs int64    `json:"latency_ms"`
+}
+
+type LicenseCheckTool struct {
+	config license.Config
+}
+
+func NewLicenseCheckTool(config license.Config) *LicenseCheckTool {
+	return &LicenseCheckTool{config: config}
+}
+
+func (t *LicenseCheckTool) Name() string {
+	return "license_check"
+}
+
+func (t *LicenseCheckTool) Description() string {
+	return "Check licensing of a project: detect its license, list source files missing the required header template, and check the licenses dependencies declare (go.mod, package.json, Python virtualenv) against an allowlist. Returns passed plus structured findings for CI or agents"
+}
+
+func (t *LicenseCheckTool) Title() string {
+	return "License Check"
+}
+
+func (t *LicenseCheckTool) Annotations() map[string]bool {
+	return tools.ReadOnlyAnnotations()
+}
+
+func (t *LicenseCheckTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"path": {
+				"type": "string",
+				"description": "Project root to check (default: the daemon's working directory)"
+			},
+			"allow": {
+				"type": "array",
+				"items": {"type": "string"},
+				"description": "SPDX identifiers dependencies may use (default: MAYLA_LICENSE_ALLOW); empty leaves dependencies unchecked"
+			},
+			"header": {
+				"type": "string",
+				"description": "Header template files must start with, {year} matching any year (default: MAYLA_LICENSE_HEADER_FILE); empty skips the header check"
+			},
+			"extensions": {
+				"type": "array",
+				"items": {"type": "string"},
+				"description": "File extensions checked for the header (default: common source extensions)"
+			},
+			"exclude": {
+				"type": "array",
+				"items": {"type": "string"},
+				"description": "Path patterns, relative to the root, exempt from the header, e.g. gen/* or *.pb.go"
+			},
+			"max_results": {
+				"type": "integer",
+				"description": "Maximum files listed as missing the header (default: 500)"
+			}
+		}
+	}`)
+}
+
+func (t *LicenseCheckTool) Examples() []tools.Example {
+	return []tools.Example{
+		{
+			Description: "Check dependencies against a permissive allowlist",
+			Arguments:   json.RawMessage(`{"allow": ["MIT", "Apache-2.0", "BSD-3-Clause", "ISC"]}`),
+			Result:      json.RawMessage(`{"passed": false, "license": {"spdx": "MIT", "file": "LICENSE"}, "conflicts": ["github.com/example/gpl-lib"], "unknown": []}`),
+		},
+	}
+}
+
+func (t *LicenseCheckTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var req LicenseCheckRequest
+	if len(input) > 0 {
+		if err := json.Unmarshal(input, &req); err != nil {
+			return nil, fmt.Errorf("invalid request: %w", err)
+		}
+	}
+	if req.Path == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("working directory: %w", err)
+		}
+		req.Path = cwd
+	}
+	if req.Allow == nil {
+		req.Allow = t.config.Allow
+	}
+	if req.Header == "" {
+		req.Header = t.config.Header
+	}
+	if len(req.Extensions) == 0 {
+		req.Extensions = headerExtensions
+	}
+	for _, pattern := range req.Exclude {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern %q: %w", pattern, err)
+		}
+	}
+	if req.MaxResults <= 0 {
+		req.MaxResults = defaultLicenseResults
+	}
+
+	var header *license.Header
+	if strings.TrimSpace(req.Header) != "" {
+		var err error
+		if header, err = license.NewHeader(req.Header); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tools.CheckPath(req.Path); err != nil {
+		return nil, err
+	}
+	root := paths.Canonical(req.Path)
+	if info, err := os.Stat(root); err != nil {
+		return nil, fmt.Errorf("path not found: %w", err)
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", req.Path)
+	}
+
+	start := time.Now()
+	resp := &LicenseCheckResponse{
+		Root:      root,
+		License:   projectLicense(root),
+		Headers:   HeaderReport{Enabled: header != nil, Missing: []string{}},
+		Allow:     req.Allow,
+		Conflicts: []string{},
+		Unknown:   []string{},
+	}
+
+	if header != nil {
+		if err := checkHeaders(ctx, root, header, req, &resp.Headers); err != nil {
+			return nil, err
+		}
+	}
+
+	resp.Dependencies = license.Dependencies(root, req.Allow)
+	if resp.Dependencies == nil {
+		resp.Dependencies = []license.Dependency{}
+	}
+	for _, dep := range resp.Dependencies {
+		switch dep.Status {
+		case license.StatusConflict:
+			resp.Conflicts = append(resp.Conflicts, dep.Name)
+		case license.StatusUnknown:
+			resp.Unknown = append(resp.Unknown, dep.Name)
+		}
+	}
+
+	resp.Passed = len(resp.Headers.Missing) == 0 && len(resp.Conflicts) == 0
+	resp.LatencyMs = time.Since(start).Milliseconds()
+	return resp, nil
+}
+
+// projectLicense identifies the license file at root and the license the
+// manifest declares
+func projectLicense(root string) ProjectLicense {
+	var result ProjectLicense
+	if id, file := license.IdentifyDir(root); file != "" {
+		result.SPDX, result.File = id, relPath(root, file)
+	}
+	result.Declared = declaredLicense(root)
+	if result.SPDX == "" {
+		result.SPDX = result.Declared
+	}
+	return result
+}
+
+// declaredLicense reads the license field of package.json, Cargo.toml or
+// pyproject.toml
+func declaredLicense(root string) string {
+	if data, err := os.ReadFile(filepath.Join(root, "package.json")); err == nil {
+		var pkg struct {
+			License json.RawMessage `json:"license"`
+		}
+		var id string
+		if json.Unmarshal(data, &pkg) == nil && json.Unmarshal(pkg.License, &id) == nil && id != "" {
+			return id
+		}
+	}
+	for _, manifest := range []string{"Cargo.toml", "pyproject.toml"} {
+		data, err := os.ReadFile(filepath.Join(root, manifest))
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			key, value, ok := strings.Cut(line, "=")
+			if !ok || strings.TrimSpace(key) != "license" {
+				continue
+			}
+			if value = strings.Trim(strings.TrimSpace(value), `"'`); value != "" && !strings.HasPrefix(value, "{") {
+				return license.Normalize(value)
+			}
+		}
+	}
+	return ""
+}
+
+// checkHeaders records every source file under root that does not start
+// with the header
+func checkHeaders(ctx context.Context, root string, header *license.Header, req LicenseCheckRequest, report *HeaderReport) error {
+	extensions := make(map[string]bool, len(req.Extensions))
+	for _, ext := range req.Extensions {
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		extensions[strings.ToLower(ext)] = true
+	}
+
+	return filepath.WalkDir(root, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		name := entry.Name()
+		if entry.IsDir() {
+			if file != root && (strings.HasPrefix(name, ".") || vendoredDirs[name]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !extensions[strings.ToLower(filepath.Ext(name))] {
+			return nil
+		}
+		rel := filepath.ToSlash(relPath(root, file))
+		for _, pattern := range req.Exclude {
+			if matched, _ := path.Match(pattern, rel); matched {
+				return nil
+			}
+			if matched, _ := path.Match(pattern, name); matched {
+				return nil
+			}
+		}
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil
+		}
+		report.Checked++
+		if header.Matches(string(data)) {
+			return nil
+		}
+		if len(report.Missing) == req.MaxResults {
+			report.Truncated = true
+			return nil
+		}
+		report.Missing = append(report.Missing, rel)
+		return nil
+	})
+}
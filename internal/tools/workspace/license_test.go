@@ -0,0 +1,65 @@
+package workspace
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alucardeht/may-la-mcp/internal/license"
+)
+
+func TestLicenseCheck(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("GOMODCACHE", t.TempDir())
+
+	files := map[string]string{
+		"LICENSE":                           "Permission is hereby granted, free of charge, to any person obtaining a copy",
+		"main.go":                           "// Copyright 2024 Acme\n\npackage main\n",
+		"util.go":                           "package main\n",
+		"gen/api.pb.go":                     "package gen\n",
+		"vendor/x/x.go":                     "package x\n",
+		"package.json":                      `{"dependencies": {"gpl-lib": "1.0.0"}}`,
+		"node_modules/gpl-lib/package.json": `{"license": "GPL-3.0"}`,
+	}
+	for rel, content := range files {
+		path := filepath.Join(root, rel)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tool := NewLicenseCheckTool(license.Config{Allow: []string{"MIT"}, Header: "Copyright {year} Acme"})
+	call := func(req LicenseCheckRequest) *LicenseCheckResponse {
+		t.Helper()
+		req.Path = root
+		input, _ := json.Marshal(req)
+		result, err := tool.Execute(context.Background(), input)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return result.(*LicenseCheckResponse)
+	}
+
+	resp := call(LicenseCheckRequest{Exclude: []string{"*.pb.go"}})
+	if resp.License.SPDX != "MIT" || resp.License.File != "LICENSE" {
+		t.Errorf("expected the MIT license file, got %+v", resp.License)
+	}
+	if resp.Headers.Checked != 2 || len(resp.Headers.Missing) != 1 || resp.Headers.Missing[0] != "util.go" {
+		t.Errorf("expected only util.go missing the header, got %+v", resp.Headers)
+	}
+	if len(resp.Conflicts) != 1 || resp.Conflicts[0] != "gpl-lib" || resp.Passed {
+		t.Errorf("expected gpl-lib to conflict and the check to fail, got %+v", resp)
+	}
+
+	resp = call(LicenseCheckRequest{Allow: []string{"MIT", "GPL-3.0"}, Extensions: []string{"py"}})
+	if !resp.Passed || resp.Headers.Checked != 0 {
+		t.Errorf("expected the check to pass with GPL-3.0 allowed and no Python files, got %+v", resp)
+	}
+
+	if _, err := tool.Execute(context.Background(), json.RawMessage(`{"path": "`+root+`", "exclude": ["["]}`)); err == nil {
+		t.Error("expected an error for an invalid exclude pattern")
+	}
+}
@@ -0,0 +1,547 @@
+package workspace
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/index"
+	"github.com/alucardeht/may-la-mcp/internal/paths"
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+	"github.com/alucardeht/may-la-mcp/internal/types"
+)
+
+const (
+	defaultMapDepth    = 2
+	defaultMapPackages = 20
+	defaultMapSymbols  = 20
+
+	// packageSymbols is how many exported names are listed per package
+	packageSymbols = 10
+
+	// maxScannedFiles and maxScannedSize bound the reference count, which
+	// reads every indexed file under the root
+	maxScannedFiles = 5000
+	maxScannedSize  = 512 * 1024
+
+	// maxCachedMaps is how many maps (one per root and limits) are kept
+	maxCachedMaps = 8
+)
+
+// apiKinds are the symbol kinds listed as a package's exported API
+var apiKinds = []string{
+	types.KindInterface, types.KindStruct, types.KindClass, types.KindEnum,
+	types.KindType, types.KindFunction,
+}
+
+// manifestFiles mark a project root and its build entry points
+var manifestFiles = []string{
+	"go.mod", "package.json", "Cargo.toml", "pyproject.toml", "setup.py",
+	"requirements.txt", "pom.xml", "build.gradle", "build.gradle.kts",
+	"Makefile", "Dockerfile", "docker-compose.yml",
+}
+
+var identifier = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+
+type RepoMapRequest struct {
+	Path        string `json:"path,omitempty"`
+	MaxDepth    int    `json:"max_depth,omitempty"`
+	MaxPackages int    `json:"max_packages,omitempty"`
+	MaxSymbols  int    `json:"max_symbols,omitempty"`
+	Refresh     bool   `json:"refresh,omitempty"`
+}
+
+type WorkspaceStats struct {
+	Files     int                    `json:"files"`
+	Bytes     int64                  `json:"bytes"`
+	Symbols   int                    `json:"symbols"`
+	Languages []*index.LanguageStats `json:"languages"`
+}
+
+type DirSummary struct {
+	Path     string `json:"path"`
+	Files    int    `json:"files"`
+	Language string `json:"language,omitempty"`
+}
+
+type EntryPoint struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+	Name string `json:"name"`
+}
+
+type PackageAPI struct {
+	Path     string   `json:"path"`
+	Exported int      `json:"exported"`
+	Symbols  []string `json:"symbols"`
+}
+
+type SymbolUsage struct {
+	Name       string `json:"name"`
+	Kind       string `json:"kind"`
+	File       string `json:"file"`
+	Line       int    `json:"line"`
+	References int    `json:"references"`
+	Files      int    `json:"files"`
+}
+
+type RepoMap struct {
+	Root        string         `json:"root"`
+	Stats       WorkspaceStats `json:"stats"`
+	Tree        []DirSummary   `json:"tree"`
+	Manifests   []string       `json:"manifests"`
+	EntryPoints []EntryPoint   `json:"entry_points"`
+	Packages    []PackageAPI   `json:"packages"`
+	TopSymbols  []SymbolUsage  `json:"top_symbols"`
+	GeneratedAt time.Time      `json:"generated_at"`
+}
+
+type RepoMapResponse struct {
+	*RepoMap
+	Cached    bool  `json:"cached"`
+	LatencyMs int64 `json:"latency_ms"`
+}
+
+// cachedMap is a map with the index state it was built from
+type cachedMap struct {
+	repoMap *RepoMap
+	version index.IndexStats
+}
+
+type RepoMapTool struct {
+	store index.Store
+
+	mu    sync.Mutex
+	cache map[string]*cachedMap
+}
+
+func NewRepoMapTool(store index.Store) *RepoMapTool {
+	return &RepoMapTool{
+		store: store,
+		cache: make(map[string]*cachedMap),
+	}
+}
+
+func (t *RepoMapTool) Name() string {
+	return "repo_map"
+}
+
+func (t *RepoMapTool) Description() string {
+	return "Get a structured map of an unfamiliar codebase in one call: workspace stats per language, the directory tree, build manifests, entry points (main functions), each package's exported API and the most referenced symbols. Built from the index and cached until it changes"
+}
+
+func (t *RepoMapTool) Title() string {
+	return "Repository Map"
+}
+
+func (t *RepoMapTool) Annotations() map[string]bool {
+	return tools.ReadOnlyAnnotations()
+}
+
+func (t *RepoMapTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"path": {
+				"type": "string",
+				"description": "Project root to map (default: the daemon's working directory)"
+			},
+			"max_depth": {
+				"type": "integer",
+				"description": "Directory levels shown in the tree (default: 2)"
+			},
+			"max_packages": {
+				"type": "integer",
+				"description": "Maximum packages listed with their exported API (default: 20)"
+			},
+			"max_symbols": {
+				"type": "integer",
+				"description": "Maximum most-referenced symbols (default: 20)"
+			},
+			"refresh": {
+				"type": "boolean",
+				"description": "Rebuild the map even if the index has not changed"
+			}
+		}
+	}`)
+}
+
+func (t *RepoMapTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var req RepoMapRequest
+	if len(input) > 0 {
+		if err := json.Unmarshal(input, &req); err != nil {
+			return nil, fmt.Errorf("invalid request: %w", err)
+		}
+	}
+
+	return t.mapRepo(ctx, req)
+}
+
+// mapRepo validates req, fills in its defaults and returns the map of its
+// root, from the cache unless the index changed or req.Refresh is set
+func (t *RepoMapTool) mapRepo(ctx context.Context, req RepoMapRequest) (*RepoMapResponse, error) {
+	if req.Path == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("working directory: %w", err)
+		}
+		req.Path = cwd
+	}
+	if req.MaxDepth <= 0 {
+		req.MaxDepth = defaultMapDepth
+	}
+	if req.MaxPackages <= 0 {
+		req.MaxPackages = defaultMapPackages
+	}
+	if req.MaxSymbols <= 0 {
+		req.MaxSymbols = defaultMapSymbols
+	}
+
+	if err := tools.CheckPath(req.Path); err != nil {
+		return nil, err
+	}
+
+	root := paths.Canonical(req.Path)
+	if info, err := os.Stat(root); err != nil {
+		return nil, fmt.Errorf("path not found: %w", err)
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", req.Path)
+	}
+
+	start := time.Now()
+
+	version, err := t.store.GetStats()
+	if err != nil {
+		return nil, fmt.Errorf("index stats: %w", err)
+	}
+
+	key := fmt.Sprintf("%s|%d|%d|%d", root, req.MaxDepth, req.MaxPackages, req.MaxSymbols)
+	if !req.Refresh {
+		if repoMap := t.cached(key, version); repoMap != nil {
+			return &RepoMapResponse{RepoMap: repoMap, Cached: true, LatencyMs: time.Since(start).Milliseconds()}, nil
+		}
+	}
+
+	repoMap, err := t.build(ctx, root, req)
+	if err != nil {
+		return nil, err
+	}
+	t.remember(key, version, repoMap)
+
+	return &RepoMapResponse{RepoMap: repoMap, LatencyMs: time.Since(start).Milliseconds()}, nil
+}
+
+// cached returns the map stored under key if the index has not changed
+// since it was built
+func (t *RepoMapTool) cached(key string, version *index.IndexStats) *RepoMap {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry, ok := t.cache[key]
+	if !ok || !sameIndex(entry.version, *version) {
+		return nil
+	}
+	return entry.repoMap
+}
+
+// remember caches repoMap under key with the index state it was built from
+func (t *RepoMapTool) remember(key string, version *index.IndexStats, repoMap *RepoMap) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.cache[key]; !ok && len(t.cache) >= maxCachedMaps {
+		t.cache = make(map[string]*cachedMap)
+	}
+	t.cache[key] = &cachedMap{repoMap: repoMap, version: *version}
+}
+
+// sameIndex reports whether nothing was indexed, removed or re-indexed
+// between two snapshots of the index stats
+func sameIndex(a, b index.IndexStats) bool {
+	return a.TotalFiles == b.TotalFiles &&
+		a.IndexedFiles == b.IndexedFiles &&
+		a.FailedFiles == b.FailedFiles &&
+		a.SkippedFiles == b.SkippedFiles &&
+		a.TotalSymbols == b.TotalSymbols &&
+		a.LastIndexedAt.Equal(b.LastIndexedAt)
+}
+
+func (t *RepoMapTool) build(ctx context.Context, root string, req RepoMapRequest) (*RepoMap, error) {
+	repoMap := &RepoMap{
+		Root:        root,
+		Tree:        []DirSummary{},
+		Manifests:   []string{},
+		EntryPoints: []EntryPoint{},
+		Packages:    []PackageAPI{},
+		TopSymbols:  []SymbolUsage{},
+		GeneratedAt: time.Now(),
+	}
+
+	languages, err := t.store.GetLanguageStats(root)
+	if err != nil {
+		return nil, err
+	}
+	repoMap.Stats.Languages = []*index.LanguageStats{}
+	for _, lang := range languages {
+		repoMap.Stats.Files += lang.Files
+		repoMap.Stats.Bytes += lang.Bytes
+		repoMap.Stats.Symbols += lang.Symbols
+		if lang.Language == "" {
+			lang.Language = "other"
+		}
+		repoMap.Stats.Languages = append(repoMap.Stats.Languages, lang)
+	}
+
+	paths, err := t.store.GetIndexedPaths(root)
+	if err != nil {
+		return nil, err
+	}
+	repoMap.Tree = directoryTree(root, paths, req.MaxDepth)
+
+	for _, name := range manifestFiles {
+		if _, err := os.Stat(filepath.Join(root, name)); err == nil {
+			repoMap.Manifests = append(repoMap.Manifests, name)
+		}
+	}
+
+	mains, err := t.store.GetSymbolRanges([]string{types.KindFunction}, "main", "", root)
+	if err != nil {
+		return nil, err
+	}
+	for _, main := range mains {
+		if isTestFile(main.Path) {
+			continue
+		}
+		repoMap.EntryPoints = append(repoMap.EntryPoints, EntryPoint{
+			File: relPath(root, main.Path),
+			Line: main.LineStart,
+			Name: main.Name,
+		})
+	}
+
+	exported, err := t.store.GetExportedSymbols(apiKinds, root)
+	if err != nil {
+		return nil, err
+	}
+	var api []*index.SymbolRange
+	for _, sym := range exported {
+		if !isTestFile(sym.Path) {
+			api = append(api, sym)
+		}
+	}
+	repoMap.Packages = packageAPIs(root, api, req.MaxPackages)
+
+	top, err := topReferenced(ctx, root, api, paths, req.MaxSymbols)
+	if err != nil {
+		return nil, err
+	}
+	repoMap.TopSymbols = top
+
+	return repoMap, nil
+}
+
+// directoryTree counts the indexed files under each directory down to
+// maxDepth levels below root, naming the language most of them are in
+func directoryTree(root string, paths []string, maxDepth int) []DirSummary {
+	files := make(map[string]int)
+	langs := make(map[string]map[string]int)
+
+	for _, path := range paths {
+		dir := filepath.Dir(relPath(root, path))
+		parts := []string{}
+		if dir != "." {
+			parts = strings.Split(dir, string(filepath.Separator))
+		}
+		lang := index.DetectLanguage(path)
+
+		for depth := 0; depth <= len(parts) && depth <= maxDepth; depth++ {
+			key := "."
+			if depth > 0 {
+				key = filepath.Join(parts[:depth]...)
+			}
+			files[key]++
+			if lang != "" {
+				if langs[key] == nil {
+					langs[key] = make(map[string]int)
+				}
+				langs[key][lang]++
+			}
+		}
+	}
+
+	tree := make([]DirSummary, 0, len(files))
+	for dir, count := range files {
+		tree = append(tree, DirSummary{Path: dir, Files: count, Language: dominant(langs[dir])})
+	}
+	sort.Slice(tree, func(i, j int) bool {
+		return tree[i].Path < tree[j].Path
+	})
+	return tree
+}
+
+func dominant(counts map[string]int) string {
+	best := ""
+	for lang, count := range counts {
+		if count > counts[best] || (count == counts[best] && lang < best) {
+			best = lang
+		}
+	}
+	return best
+}
+
+// packageAPIs groups the exported API by directory, packages with the most
+// exported symbols first, and types listed before functions
+func packageAPIs(root string, api []*index.SymbolRange, maxPackages int) []PackageAPI {
+	byDir := make(map[string][]*index.SymbolRange)
+	for _, sym := range api {
+		dir := filepath.Dir(relPath(root, sym.Path))
+		byDir[dir] = append(byDir[dir], sym)
+	}
+
+	packages := make([]PackageAPI, 0, len(byDir))
+	for dir, syms := range byDir {
+		sort.SliceStable(syms, func(i, j int) bool {
+			return syms[i].Kind != types.KindFunction && syms[j].Kind == types.KindFunction
+		})
+
+		names := []string{}
+		seen := make(map[string]bool)
+		for _, sym := range syms {
+			if len(names) == packageSymbols {
+				break
+			}
+			if !seen[sym.Name] {
+				seen[sym.Name] = true
+				names = append(names, sym.Name)
+			}
+		}
+		packages = append(packages, PackageAPI{Path: dir, Exported: len(syms), Symbols: names})
+	}
+
+	sort.Slice(packages, func(i, j int) bool {
+		if packages[i].Exported != packages[j].Exported {
+			return packages[i].Exported > packages[j].Exported
+		}
+		return packages[i].Path < packages[j].Path
+	})
+	if len(packages) > maxPackages {
+		packages = packages[:maxPackages]
+	}
+	return packages
+}
+
+// topReferenced counts how often each exported API symbol is named in the
+// indexed files other than its own. Names declared more than once are left
+// out, since their counts would mix unrelated symbols.
+func topReferenced(ctx context.Context, root string, api []*index.SymbolRange, paths []string, maxSymbols int) ([]SymbolUsage, error) {
+	defs := make(map[string]*index.SymbolRange)
+	ambiguous := make(map[string]bool)
+	for _, sym := range api {
+		if len(sym.Name) < 3 || ambiguous[sym.Name] {
+			continue
+		}
+		if prev, ok := defs[sym.Name]; ok {
+			if prev.Path != sym.Path {
+				ambiguous[sym.Name] = true
+				delete(defs, sym.Name)
+			}
+			continue
+		}
+		defs[sym.Name] = sym
+	}
+
+	usage := make(map[string]*SymbolUsage)
+	for i, path := range paths {
+		if i == maxScannedFiles {
+			break
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if info, err := os.Stat(path); err != nil || info.Size() > maxScannedSize {
+			continue
+		}
+		content, _, err := index.ReadFileAsUTF8(path)
+		if err != nil {
+			continue
+		}
+
+		counted := make(map[string]bool)
+		for _, loc := range identifier.FindAllStringIndex(content, -1) {
+			name := content[loc[0]:loc[1]]
+			def, ok := defs[name]
+			if !ok || def.Path == path || foreignQualifier(content, loc[0], def.Path) {
+				continue
+			}
+			u, ok := usage[name]
+			if !ok {
+				u = &SymbolUsage{Name: name, Kind: def.Kind, File: relPath(root, def.Path), Line: def.LineStart}
+				usage[name] = u
+			}
+			u.References++
+			if !counted[name] {
+				counted[name] = true
+				u.Files++
+			}
+		}
+	}
+
+	top := make([]SymbolUsage, 0, len(usage))
+	for _, u := range usage {
+		top = append(top, *u)
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].References != top[j].References {
+			return top[i].References > top[j].References
+		}
+		return top[i].Name < top[j].Name
+	})
+	if len(top) > maxSymbols {
+		top = top[:maxSymbols]
+	}
+	return top, nil
+}
+
+// foreignQualifier reports whether the Go identifier at offset start is
+// qualified by a package other than the one declaring it, as with
+// context.Context for a Context declared elsewhere
+func foreignQualifier(content string, start int, defPath string) bool {
+	if filepath.Ext(defPath) != ".go" || start == 0 || content[start-1] != '.' {
+		return false
+	}
+	qualifier := start - 1
+	for qualifier > 0 && isIdentByte(content[qualifier-1]) {
+		qualifier--
+	}
+	return content[qualifier:start-1] != filepath.Base(filepath.Dir(defPath))
+}
+
+func isIdentByte(b byte) bool {
+	return b == '_' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9'
+}
+
+func isTestFile(path string) bool {
+	base := filepath.Base(path)
+	return strings.HasSuffix(base, "_test.go") ||
+		strings.HasPrefix(base, "test_") ||
+		strings.Contains(base, ".test.") ||
+		strings.Contains(base, ".spec.")
+}
+
+func relPath(root, path string) string {
+	if rel, err := filepath.Rel(root, path); err == nil {
+		return rel
+	}
+	return path
+}
@@ -0,0 +1,108 @@
+package workspace
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alucardeht/may-la-mcp/internal/index"
+)
+
+// testWorkspace indexes a small Go project in a temporary root. add writes
+// and indexes files[rel], so tests can grow the project afterwards.
+func testWorkspace(t *testing.T) (string, *index.IndexStore, map[string]string, func(rel string)) {
+	root := t.TempDir()
+	store, err := index.NewIndexStore(filepath.Join(t.TempDir(), "index.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	files := map[string]string{
+		"go.mod":               "module example.com/shop\n",
+		"cmd/shop/main.go":     "package main\n\nfunc main() {\n\tcart.NewCart()\n}\n",
+		"cart/cart.go":         "package cart\n\ntype Cart struct{}\n\nfunc NewCart() *Cart { return &Cart{} }\n",
+		"cart/cart_test.go":    "package cart\n\nfunc TestHelper() {}\n",
+		"orders/orders.go":     "package orders\n\nfunc Checkout(c *cart.Cart) {}\n",
+		"orders/orders_ctx.go": "package orders\n\nvar ctx context.Cart\n",
+	}
+	symbols := map[string][]*index.IndexedSymbol{
+		"cmd/shop/main.go":  {{Name: "main", Kind: "function", LineStart: 3}},
+		"cart/cart.go":      {{Name: "Cart", Kind: "struct", LineStart: 3, IsExported: true}, {Name: "NewCart", Kind: "function", LineStart: 5, IsExported: true}},
+		"cart/cart_test.go": {{Name: "TestHelper", Kind: "function", LineStart: 3, IsExported: true}},
+		"orders/orders.go":  {{Name: "Checkout", Kind: "function", LineStart: 3, IsExported: true}},
+	}
+
+	add := func(rel string) {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(files[rel]), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if rel == "go.mod" {
+			return
+		}
+		id, err := store.UpsertFile(&index.IndexedFile{Path: path, Language: "go", Status: index.StatusIndexed, Size: int64(len(files[rel]))})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.InsertSymbols(id, symbols[rel]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for rel := range files {
+		add(rel)
+	}
+
+	return root, store, files, add
+}
+
+func TestRepoMap(t *testing.T) {
+	root, store, files, add := testWorkspace(t)
+
+	tool := NewRepoMapTool(store)
+	call := func() *RepoMapResponse {
+		input, _ := json.Marshal(RepoMapRequest{Path: root})
+		result, err := tool.Execute(context.Background(), input)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return result.(*RepoMapResponse)
+	}
+
+	resp := call()
+	if resp.Cached || resp.Stats.Files != 5 || resp.Stats.Symbols != 5 {
+		t.Errorf("expected a fresh map of 5 files and 5 symbols, got %+v", resp.Stats)
+	}
+	if len(resp.Manifests) != 1 || resp.Manifests[0] != "go.mod" {
+		t.Errorf("expected go.mod manifest, got %v", resp.Manifests)
+	}
+	if len(resp.EntryPoints) != 1 || resp.EntryPoints[0].File != filepath.Join("cmd", "shop", "main.go") {
+		t.Errorf("expected the main entry point, got %+v", resp.EntryPoints)
+	}
+	if len(resp.Packages) != 2 || resp.Packages[0].Path != "cart" || resp.Packages[0].Symbols[0] != "Cart" {
+		t.Errorf("expected cart then orders, types first and tests left out, got %+v", resp.Packages)
+	}
+
+	refs := map[string]int{}
+	for _, sym := range resp.TopSymbols {
+		refs[sym.Name] = sym.References
+	}
+	if refs["Cart"] != 1 || refs["NewCart"] != 1 {
+		t.Errorf("expected cart.Cart and cart.NewCart once each, not context.Cart, got %v", refs)
+	}
+
+	if !call().Cached {
+		t.Error("expected the second map to come from the cache")
+	}
+
+	files["orders/refund.go"] = "package orders\n\nfunc Refund(c *cart.Cart) {}\n"
+	add("orders/refund.go")
+	if resp := call(); resp.Cached || resp.Stats.Files != 6 {
+		t.Errorf("expected the map to be rebuilt after indexing, got cached=%v files=%d", resp.Cached, resp.Stats.Files)
+	}
+}
@@ -0,0 +1,428 @@
+package workspace
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+	"github.com/alucardeht/may-la-mcp/internal/vcs"
+)
+
+const (
+	// SpecPath and ConstitutionPath are where spec-driven tooling expects
+	// the drafts, relative to the project root
+	SpecPath         = "specs/000-current-architecture/spec.md"
+	ConstitutionPath = ".specify/memory/constitution.md"
+
+	// specComponents is how many packages get a section in the spec
+	specComponents = 12
+
+	// maxReadmeSize bounds how much of the README is read for its summary
+	maxReadmeSize = 64 * 1024
+)
+
+// needsClarification marks what the code cannot tell, as spec templates do
+const needsClarification = "[NEEDS CLARIFICATION]"
+
+var readmeFiles = []string{"README.md", "README", "README.rst", "README.txt", "readme.md"}
+
+// ciFiles mark a continuous integration setup
+var ciFiles = []string{
+	".github/workflows", ".gitlab-ci.yml", ".circleci", "Jenkinsfile",
+	"azure-pipelines.yml", ".travis.yml",
+}
+
+// lintFiles mark an enforced code style
+var lintFiles = []string{
+	".golangci.yml", ".golangci.yaml", ".eslintrc", ".eslintrc.js", ".eslintrc.json",
+	"eslint.config.js", ".prettierrc", "ruff.toml", ".flake8", "rustfmt.toml",
+	".clang-format", ".editorconfig",
+}
+
+type SpecReverseRequest struct {
+	Path    string `json:"path,omitempty"`
+	Project string `json:"project,omitempty"`
+	Refresh bool   `json:"refresh,omitempty"`
+}
+
+type SpecReverseResponse struct {
+	Root    string `json:"root"`
+	Project string `json:"project"`
+	// Branch is the git branch the drafts were made on; tag memories
+	// written from them with it to drop them after merge
+	Branch           string   `json:"branch,omitempty"`
+	SpecPath         string   `json:"spec_path"`
+	Spec             string   `json:"spec"`
+	ConstitutionPath string   `json:"constitution_path"`
+	Constitution     string   `json:"constitution"`
+	Sources          []string `json:"sources"`
+	Clarifications   int      `json:"clarifications"`
+	LatencyMs        int64    `json:"latency_ms"`
+}
+
+// projectFacts is what the drafts are written from
+type projectFacts struct {
+	name      string
+	branch    string
+	purpose   string
+	repoMap   *RepoMap
+	testFiles int
+	ci        []string
+	linters   []string
+	license   string
+}
+
+type SpecReverseTool struct {
+	maps *RepoMapTool
+}
+
+// NewSpecReverseTool drafts specs from the maps built by maps, sharing its
+// cache
+func NewSpecReverseTool(maps *RepoMapTool) *SpecReverseTool {
+	return &SpecReverseTool{maps: maps}
+}
+
+func (t *SpecReverseTool) Name() string {
+	return "spec_reverse"
+}
+
+func (t *SpecReverseTool) Description() string {
+	return "Draft an initial spec.md and constitution skeleton for an existing codebase from its index (languages, structure, entry points, exported API, key abstractions), README and build files, to adopt spec-driven development on a legacy project. Returns the drafts with suggested paths; nothing is written"
+}
+
+func (t *SpecReverseTool) Title() string {
+	return "Reverse-Engineer Spec"
+}
+
+func (t *SpecReverseTool) Annotations() map[string]bool {
+	return tools.ReadOnlyAnnotations()
+}
+
+func (t *SpecReverseTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"path": {
+				"type": "string",
+				"description": "Project root to analyze (default: the daemon's working directory)"
+			},
+			"project": {
+				"type": "string",
+				"description": "Project name used in the drafts (default: the README title or the directory name)"
+			},
+			"refresh": {
+				"type": "boolean",
+				"description": "Rebuild the underlying repository map even if the index has not changed (default: false)"
+			}
+		}
+	}`)
+}
+
+func (t *SpecReverseTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var req SpecReverseRequest
+	if len(input) > 0 {
+		if err := json.Unmarshal(input, &req); err != nil {
+			return nil, fmt.Errorf("invalid request: %w", err)
+		}
+	}
+
+	start := time.Now()
+
+	mapped, err := t.maps.mapRepo(ctx, RepoMapRequest{Path: req.Path, MaxPackages: specComponents, Refresh: req.Refresh})
+	if err != nil {
+		return nil, err
+	}
+	root := mapped.Root
+
+	facts := &projectFacts{repoMap: mapped.RepoMap, branch: vcs.Branch(root)}
+	sources := []string{"index"}
+
+	title, purpose, readme := readReadme(root)
+	if readme != "" {
+		sources = append(sources, readme)
+	}
+	facts.purpose = purpose
+	facts.name = req.Project
+	if facts.name == "" {
+		facts.name = title
+	}
+	if facts.name == "" {
+		facts.name = filepath.Base(root)
+	}
+	sources = append(sources, mapped.Manifests...)
+
+	paths, err := t.maps.store.GetIndexedPaths(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		if isTestFile(path) {
+			facts.testFiles++
+		}
+	}
+	facts.ci = existing(root, ciFiles)
+	facts.linters = existing(root, lintFiles)
+	if license := existing(root, []string{"LICENSE", "LICENSE.md", "LICENSE.txt", "COPYING"}); len(license) > 0 {
+		facts.license = license[0]
+	}
+
+	spec := draftSpec(facts)
+	constitution := draftConstitution(facts)
+
+	return &SpecReverseResponse{
+		Root:             root,
+		Project:          facts.name,
+		Branch:           facts.branch,
+		SpecPath:         SpecPath,
+		Spec:             spec,
+		ConstitutionPath: ConstitutionPath,
+		Constitution:     constitution,
+		Sources:          sources,
+		Clarifications:   strings.Count(spec, needsClarification) + strings.Count(constitution, needsClarification),
+		LatencyMs:        time.Since(start).Milliseconds(),
+	}, nil
+}
+
+// readReadme returns the title and first prose paragraph of the README at
+// root, and the README's name
+func readReadme(root string) (string, string, string) {
+	for _, name := range readmeFiles {
+		f, err := os.Open(filepath.Join(root, name))
+		if err != nil {
+			continue
+		}
+		defer f.Close()
+
+		var title string
+		var paragraph []string
+		inCode := false
+		scanner := bufio.NewScanner(io.LimitReader(f, maxReadmeSize))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if strings.HasPrefix(line, "```") {
+				inCode = !inCode
+				continue
+			}
+			switch {
+			case inCode:
+			case strings.HasPrefix(line, "#"):
+				if title == "" {
+					title = strings.TrimSpace(strings.TrimLeft(line, "#"))
+				} else if len(paragraph) > 0 {
+					return title, strings.Join(paragraph, " "), name
+				}
+			case line == "":
+				if len(paragraph) > 0 {
+					return title, strings.Join(paragraph, " "), name
+				}
+			case strings.HasPrefix(line, "!["), strings.HasPrefix(line, "[!["), strings.HasPrefix(line, "<"),
+				strings.HasPrefix(line, ">"), strings.HasPrefix(line, "|"), strings.HasPrefix(line, "---"):
+				// badges, HTML, quotes, tables and rules say little about purpose
+			default:
+				paragraph = append(paragraph, line)
+			}
+		}
+		return title, strings.Join(paragraph, " "), name
+	}
+	return "", "", ""
+}
+
+// existing returns the names in candidates that exist under root
+func existing(root string, candidates []string) []string {
+	var found []string
+	for _, name := range candidates {
+		if _, err := os.Stat(filepath.Join(root, name)); err == nil {
+			found = append(found, name)
+		}
+	}
+	return found
+}
+
+func draftSpec(facts *projectFacts) string {
+	m := facts.repoMap
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# Specification: %s (current architecture)\n\n", facts.name)
+	fmt.Fprintf(&b, "> Drafted on %s from the existing code. It describes what the code does today, not what it should do: review every section and resolve each %s before treating it as the source of truth.\n\n",
+		m.GeneratedAt.Format("2006-01-02"), needsClarification)
+	if facts.branch != "" {
+		fmt.Fprintf(&b, "**Branch**: `%s`\n\n", facts.branch)
+	}
+
+	b.WriteString("## Overview\n\n")
+	if facts.purpose != "" {
+		b.WriteString(facts.purpose + "\n\n")
+	} else {
+		fmt.Fprintf(&b, "%s What problem does %s solve, and for whom?\n\n", needsClarification, facts.name)
+	}
+
+	b.WriteString("## Technology\n\n")
+	fmt.Fprintf(&b, "- Size: %d indexed files, %d symbols\n", m.Stats.Files, m.Stats.Symbols)
+	if langs := languageShares(m); langs != "" {
+		fmt.Fprintf(&b, "- Languages: %s\n", langs)
+	}
+	if len(m.Manifests) > 0 {
+		fmt.Fprintf(&b, "- Build files: %s\n", codeList(m.Manifests))
+	}
+	b.WriteString("\n")
+
+	b.WriteString("## Entry Points\n\n")
+	if len(m.EntryPoints) == 0 {
+		fmt.Fprintf(&b, "%s No `main` function was found; how is this project run or consumed?\n\n", needsClarification)
+	}
+	for _, ep := range m.EntryPoints {
+		fmt.Fprintf(&b, "- `%s:%d`\n", ep.File, ep.Line)
+	}
+	if len(m.EntryPoints) > 0 {
+		b.WriteString("\n")
+	}
+
+	b.WriteString("## Structure\n\n")
+	b.WriteString("| Directory | Files | Language |\n|---|---|---|\n")
+	for _, dir := range m.Tree {
+		fmt.Fprintf(&b, "| `%s` | %d | %s |\n", dir.Path, dir.Files, dir.Language)
+	}
+	b.WriteString("\n")
+
+	b.WriteString("## Components\n\n")
+	if len(m.Packages) == 0 {
+		fmt.Fprintf(&b, "%s No exported API was found in the index.\n\n", needsClarification)
+	}
+	for _, pkg := range m.Packages {
+		fmt.Fprintf(&b, "### `%s`\n\n", pkg.Path)
+		fmt.Fprintf(&b, "- Responsibility: %s\n", needsClarification)
+		fmt.Fprintf(&b, "- Public API (%d exported): %s", pkg.Exported, codeList(pkg.Symbols))
+		if pkg.Exported > len(pkg.Symbols) {
+			b.WriteString(", ...")
+		}
+		b.WriteString("\n\n")
+	}
+
+	if len(m.TopSymbols) > 0 {
+		b.WriteString("## Key Abstractions\n\n")
+		b.WriteString("The most referenced exported symbols, which most changes will touch:\n\n")
+		for _, sym := range m.TopSymbols {
+			fmt.Fprintf(&b, "- `%s` (%s, `%s:%d`): %d references in %d files\n",
+				sym.Name, sym.Kind, sym.File, sym.Line, sym.References, sym.Files)
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("## Requirements\n\n")
+	b.WriteString("### Functional\n\n")
+	fmt.Fprintf(&b, "- **FR-001**: %s Describe, per component above, what it must do for its users.\n\n", needsClarification)
+	b.WriteString("### Non-Functional\n\n")
+	fmt.Fprintf(&b, "- **NFR-001**: %s Performance, security and compatibility constraints the current design relies on.\n\n", needsClarification)
+
+	b.WriteString("## Out of Scope\n\n")
+	fmt.Fprintf(&b, "- %s\n", needsClarification)
+
+	return b.String()
+}
+
+func draftConstitution(facts *projectFacts) string {
+	m := facts.repoMap
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# %s Constitution\n\n", facts.name)
+	b.WriteString("> Skeleton inferred from the current codebase. Keep the principles that hold, rewrite the ones that do not, and ratify it before writing new specs against it.\n\n")
+
+	b.WriteString("## Core Principles\n\n")
+
+	b.WriteString("### I. Follow the Existing Stack\n\n")
+	if langs := languageShares(m); langs != "" {
+		fmt.Fprintf(&b, "The codebase is written in %s. New code uses the same languages", langs)
+		if len(m.Manifests) > 0 {
+			fmt.Fprintf(&b, " and builds through %s", codeList(m.Manifests))
+		}
+		b.WriteString("; adding a language or build system needs an amendment.\n\n")
+	} else {
+		fmt.Fprintf(&b, "%s The index holds no files yet; which languages and build tools are allowed?\n\n", needsClarification)
+	}
+
+	b.WriteString("### II. Respect Component Boundaries\n\n")
+	if len(m.Packages) > 0 {
+		fmt.Fprintf(&b, "The project is organized in %d components with a public API (largest: %s). Components depend on each other only through exported symbols; new behavior goes in the component that owns it.\n\n",
+			len(m.Packages), codeList(packagePaths(m.Packages, 3)))
+	} else {
+		fmt.Fprintf(&b, "%s How is the code split into components, and what may depend on what?\n\n", needsClarification)
+	}
+
+	b.WriteString("### III. Tested Changes\n\n")
+	if facts.testFiles > 0 {
+		fmt.Fprintf(&b, "The project keeps its tests next to the code (%d test files). Every change ships with tests in the same layout, and the suite stays green.\n\n", facts.testFiles)
+	} else {
+		fmt.Fprintf(&b, "%s No test files were found. Decide the testing policy before new work starts.\n\n", needsClarification)
+	}
+
+	b.WriteString("### IV. Automated Quality Gates\n\n")
+	var gates []string
+	gates = append(gates, facts.ci...)
+	gates = append(gates, facts.linters...)
+	if len(gates) > 0 {
+		fmt.Fprintf(&b, "Changes pass the checks already configured (%s) before they merge.\n\n", codeList(gates))
+	} else {
+		fmt.Fprintf(&b, "%s No CI or linter configuration was found. Which checks must pass before a change merges?\n\n", needsClarification)
+	}
+
+	b.WriteString("### V. Stable Public API\n\n")
+	fmt.Fprintf(&b, "Exported symbols are the contract with callers. Renaming or removing one is a breaking change that needs a spec. %s Versioning and deprecation policy.\n\n", needsClarification)
+
+	b.WriteString("## Governance\n\n")
+	b.WriteString("This constitution overrides other practices. Amendments are proposed in a spec, reviewed and recorded with a version bump.\n\n")
+	if facts.license != "" {
+		fmt.Fprintf(&b, "Contributions are made under the terms of `%s`.\n\n", facts.license)
+	}
+	fmt.Fprintf(&b, "**Version**: 0.1.0 | **Ratified**: %s | **Last Amended**: %s\n", needsClarification, m.GeneratedAt.Format("2006-01-02"))
+
+	return b.String()
+}
+
+// languageShares lists the languages of the indexed files with their share,
+// largest first, leaving out unknown files
+func languageShares(m *RepoMap) string {
+	langs := append(m.Stats.Languages[:0:0], m.Stats.Languages...)
+	sort.SliceStable(langs, func(i, j int) bool {
+		return langs[i].Files > langs[j].Files
+	})
+
+	var parts []string
+	for _, lang := range langs {
+		if lang.Language == "other" || m.Stats.Files == 0 {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s (%d%%)", lang.Language, lang.Files*100/m.Stats.Files))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func packagePaths(packages []PackageAPI, limit int) []string {
+	var paths []string
+	for i, pkg := range packages {
+		if i == limit {
+			break
+		}
+		paths = append(paths, pkg.Path)
+	}
+	return paths
+}
+
+func codeList(items []string) string {
+	quoted := make([]string, len(items))
+	for i, item := range items {
+		quoted[i] = "`" + item + "`"
+	}
+	return strings.Join(quoted, ", ")
+}
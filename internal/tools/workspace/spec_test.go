@@ -0,0 +1,56 @@
+package workspace
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSpecReverse(t *testing.T) {
+	root, store, _, _ := testWorkspace(t)
+
+	readme := "# Shop\n\n[![ci](badge.svg)](ci)\n\nShop sells things online\nto people.\n\n## Install\n\nRun it.\n"
+	if err := os.WriteFile(filepath.Join(root, "README.md"), []byte(readme), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, ".github", "workflows"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, ".git", "HEAD"), []byte("ref: refs/heads/spec-draft\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tool := NewSpecReverseTool(NewRepoMapTool(store))
+	input, _ := json.Marshal(SpecReverseRequest{Path: root})
+	result, err := tool.Execute(context.Background(), input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resp := result.(*SpecReverseResponse)
+	if resp.Project != "Shop" || resp.SpecPath != SpecPath || resp.ConstitutionPath != ConstitutionPath {
+		t.Errorf("expected the Shop project at the default paths, got %q, %q, %q", resp.Project, resp.SpecPath, resp.ConstitutionPath)
+	}
+	if resp.Branch != "spec-draft" {
+		t.Errorf("expected the drafts to name the branch spec-draft, got %q", resp.Branch)
+	}
+	for _, want := range []string{"**Branch**: `spec-draft`", "Shop sells things online to people.", "`cmd/shop/main.go:3`", "### `cart`", "`Cart`, `NewCart`", "go (100%)"} {
+		if !strings.Contains(resp.Spec, want) {
+			t.Errorf("expected the spec to contain %q:\n%s", want, resp.Spec)
+		}
+	}
+	for _, want := range []string{"# Shop Constitution", "(1 test files)", "`.github/workflows`", "`go.mod`"} {
+		if !strings.Contains(resp.Constitution, want) {
+			t.Errorf("expected the constitution to contain %q:\n%s", want, resp.Constitution)
+		}
+	}
+	if resp.Clarifications == 0 || strings.Count(resp.Spec+resp.Constitution, needsClarification) != resp.Clarifications {
+		t.Errorf("expected the open questions to be counted, got %d", resp.Clarifications)
+	}
+}
@@ -0,0 +1,397 @@
+package workspace
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/index"
+	"github.com/alucardeht/may-la-mcp/internal/paths"
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+	"github.com/alucardeht/may-la-mcp/internal/types"
+)
+
+const defaultUnusedResults = 200
+
+// Confidence levels of an unused symbol
+const (
+	ConfidenceHigh   = "high"
+	ConfidenceMedium = "medium"
+	ConfidenceLow    = "low"
+)
+
+// Reasons a symbol is left out of the report
+const (
+	allowEntryPoint = "entry_point"
+	allowTest       = "test"
+	allowBuildTags  = "build_tags"
+	allowPattern    = "pattern"
+)
+
+// unusedKinds are the kinds checked by default; fields and modules are
+// reached in too many ways a name scan cannot see
+var unusedKinds = []string{
+	types.KindFunction, types.KindMethod, types.KindClass, types.KindInterface,
+	types.KindStruct, types.KindEnum, types.KindType, types.KindConst, types.KindVariable,
+}
+
+// dynamicLanguages reach symbols through reflection, getattr or string
+// dispatch, so a missing name is weak evidence
+var dynamicLanguages = map[string]bool{
+	".py": true, ".js": true, ".jsx": true, ".mjs": true, ".cjs": true,
+	".rb": true, ".php": true, ".lua": true,
+}
+
+type UnusedSymbolsRequest struct {
+	Path         string   `json:"path,omitempty"`
+	Kinds        []string `json:"kinds,omitempty"`
+	Visibility   string   `json:"visibility,omitempty"`
+	Allow        []string `json:"allow,omitempty"`
+	IncludeTests bool     `json:"include_tests,omitempty"`
+	MaxResults   int      `json:"max_results,omitempty"`
+}
+
+type UnusedSymbol struct {
+	Name       string `json:"name"`
+	Kind       string `json:"kind"`
+	File       string `json:"file"`
+	Line       int    `json:"line"`
+	Exported   bool   `json:"exported"`
+	Confidence string `json:"confidence"`
+	Note       string `json:"note,omitempty"`
+}
+
+type UnusedSymbolsResponse struct {
+	Root    string         `json:"root"`
+	Symbols []UnusedSymbol `json:"symbols"`
+	Count   int            `json:"count"`
+	// Checked is how many symbols were looked at, Allowlisted how many of
+	// them were skipped and why
+	Checked     int            `json:"checked"`
+	Allowlisted map[string]int `json:"allowlisted"`
+	// Partial is set when the root holds more files than are scanned, so
+	// some uses may have been missed
+	Partial   bool  `json:"partial,omitempty"`
+	Truncated bool  `json:"truncated,omitempty"`
+	LatencyMs int64 `json:"latency_ms"`
+}
+
+type UnusedSymbolsTool struct {
+	store index.Store
+}
+
+func NewUnusedSymbolsTool(store index.Store) *UnusedSymbolsTool {
+	return &UnusedSymbolsTool{store: store}
+}
+
+func (t *UnusedSymbolsTool) Name() string {
+	return "unused_symbols"
+}
+
+func (t *UnusedSymbolsTool) Description() string {
+	return "List indexed symbols nothing refers to: no reference in the index and no use of the name in any other indexed file under the root. Entry points (main, init, constructors, dunder methods), tests and build-tagged files are allowlisted, and each result carries a confidence note, low for dynamic languages"
+}
+
+func (t *UnusedSymbolsTool) Title() string {
+	return "Unused Symbols"
+}
+
+func (t *UnusedSymbolsTool) Annotations() map[string]bool {
+	return tools.ReadOnlyAnnotations()
+}
+
+func (t *UnusedSymbolsTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"path": {
+				"type": "string",
+				"description": "Project root to check (default: the daemon's working directory)"
+			},
+			"kinds": {
+				"type": "array",
+				"items": {"type": "string"},
+				"description": "Symbol kinds to check (default: functions, methods, types, constants and variables)"
+			},
+			"visibility": {
+				"type": "string",
+				"enum": ["all", "exported", "unexported"],
+				"description": "Which symbols to report (default: all)"
+			},
+			"allow": {
+				"type": "array",
+				"items": {"type": "string"},
+				"description": "Name patterns never reported, e.g. Handle* or on_*"
+			},
+			"include_tests": {
+				"type": "boolean",
+				"description": "Also check helpers declared in test files; test functions themselves stay allowlisted"
+			},
+			"max_results": {
+				"type": "integer",
+				"description": "Maximum symbols listed (default: 200)"
+			}
+		}
+	}`)
+}
+
+func (t *UnusedSymbolsTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var req UnusedSymbolsRequest
+	if len(input) > 0 {
+		if err := json.Unmarshal(input, &req); err != nil {
+			return nil, fmt.Errorf("invalid request: %w", err)
+		}
+	}
+	if req.Path == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("working directory: %w", err)
+		}
+		req.Path = cwd
+	}
+	switch req.Visibility {
+	case "":
+		req.Visibility = "all"
+	case "all", "exported", "unexported":
+	default:
+		return nil, fmt.Errorf("unknown visibility %q (expected all, exported or unexported)", req.Visibility)
+	}
+	for _, pattern := range req.Allow {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid allow pattern %q: %w", pattern, err)
+		}
+	}
+	if req.MaxResults <= 0 {
+		req.MaxResults = defaultUnusedResults
+	}
+	kinds := unusedKinds
+	if len(req.Kinds) > 0 {
+		var err error
+		if kinds, err = types.ParseKinds(req.Kinds); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tools.CheckPath(req.Path); err != nil {
+		return nil, err
+	}
+	root := paths.Canonical(req.Path)
+	if info, err := os.Stat(root); err != nil {
+		return nil, fmt.Errorf("path not found: %w", err)
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", req.Path)
+	}
+
+	start := time.Now()
+	symbols, err := t.store.GetSymbolUses(kinds, root)
+	if err != nil {
+		return nil, err
+	}
+	indexed, err := t.store.GetIndexedPaths(root)
+	if err != nil {
+		return nil, err
+	}
+
+	// every declaration of a name is a site that is not a use of it
+	declared := make(map[string]map[string]bool)
+	for _, sym := range symbols {
+		if declared[sym.Name] == nil {
+			declared[sym.Name] = make(map[string]bool)
+		}
+		declared[sym.Name][site(sym.Path, sym.LineStart)] = true
+	}
+
+	scan, err := scanUses(ctx, indexed, declared)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &UnusedSymbolsResponse{
+		Root:        root,
+		Symbols:     []UnusedSymbol{},
+		Allowlisted: make(map[string]int),
+		Partial:     scan.partial,
+	}
+	for _, sym := range symbols {
+		if req.Visibility == "exported" && !sym.Exported || req.Visibility == "unexported" && sym.Exported {
+			continue
+		}
+		resp.Checked++
+		if reason := allowlisted(sym, req, scan.buildTagged[sym.Path]); reason != "" {
+			resp.Allowlisted[reason]++
+			continue
+		}
+		if sym.References > 0 || scan.used[sym.Name] {
+			continue
+		}
+
+		if len(resp.Symbols) == req.MaxResults {
+			resp.Truncated = true
+			break
+		}
+		confidence, note := unusedConfidence(sym)
+		resp.Symbols = append(resp.Symbols, UnusedSymbol{
+			Name:       sym.Name,
+			Kind:       sym.Kind,
+			File:       relPath(root, sym.Path),
+			Line:       sym.LineStart,
+			Exported:   sym.Exported,
+			Confidence: confidence,
+			Note:       note,
+		})
+	}
+	sort.SliceStable(resp.Symbols, func(i, j int) bool {
+		return confidenceRank(resp.Symbols[i].Confidence) < confidenceRank(resp.Symbols[j].Confidence)
+	})
+
+	resp.Count = len(resp.Symbols)
+	resp.LatencyMs = time.Since(start).Milliseconds()
+	return resp, nil
+}
+
+// useScan is what reading the indexed files found
+type useScan struct {
+	// used holds the names that appear somewhere other than a declaration
+	used map[string]bool
+	// buildTagged holds the Go files with a build constraint
+	buildTagged map[string]bool
+	partial     bool
+}
+
+// scanUses reads the indexed files and records which declared names appear
+// outside their declarations. Comments and strings count as uses, which
+// keeps the report on the side of caution.
+func scanUses(ctx context.Context, indexed []string, declared map[string]map[string]bool) (*useScan, error) {
+	scan := &useScan{used: make(map[string]bool), buildTagged: make(map[string]bool)}
+	for i, file := range indexed {
+		if i == maxScannedFiles {
+			scan.partial = true
+			break
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if info, err := os.Stat(file); err != nil || info.Size() > maxScannedSize {
+			continue
+		}
+		content, _, err := index.ReadFileAsUTF8(file)
+		if err != nil {
+			continue
+		}
+		if filepath.Ext(file) == ".go" && hasBuildConstraint(content) {
+			scan.buildTagged[file] = true
+		}
+
+		line, offset := 1, 0
+		for _, loc := range identifier.FindAllStringIndex(content, -1) {
+			name := content[loc[0]:loc[1]]
+			sites, ok := declared[name]
+			if !ok || scan.used[name] {
+				continue
+			}
+			line += strings.Count(content[offset:loc[0]], "\n")
+			offset = loc[0]
+			if !sites[site(file, line)] {
+				scan.used[name] = true
+			}
+		}
+	}
+	return scan, nil
+}
+
+func site(file string, line int) string {
+	return file + ":" + strconv.Itoa(line)
+}
+
+// hasBuildConstraint reports whether a Go file has a //go:build or
+// // +build line before its package clause
+func hasBuildConstraint(content string) bool {
+	for _, line := range types.SplitLines(content) {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "package ") {
+			return false
+		}
+		if strings.HasPrefix(line, "//go:build ") || strings.HasPrefix(line, "// +build ") {
+			return true
+		}
+	}
+	return false
+}
+
+// allowlisted returns why sym is never reported, or ""
+func allowlisted(sym *index.SymbolUse, req UnusedSymbolsRequest, buildTagged bool) string {
+	name := sym.Name
+	ext := filepath.Ext(sym.Path)
+
+	switch {
+	case ext == ".go" && (name == "main" || name == "init") && sym.Kind == types.KindFunction:
+		return allowEntryPoint
+	case name == "constructor", strings.HasPrefix(name, "__") && strings.HasSuffix(name, "__"):
+		return allowEntryPoint
+	}
+
+	if isTestFile(sym.Path) || filepath.Base(sym.Path) == "conftest.py" {
+		if !req.IncludeTests || isTestEntryPoint(name, ext) {
+			return allowTest
+		}
+	}
+	if buildTagged {
+		return allowBuildTags
+	}
+	for _, pattern := range req.Allow {
+		if ok, _ := path.Match(pattern, name); ok {
+			return allowPattern
+		}
+	}
+	return ""
+}
+
+// isTestEntryPoint reports whether name is run by a test framework rather
+// than called
+func isTestEntryPoint(name, ext string) bool {
+	if ext == ".go" {
+		for _, prefix := range []string{"Test", "Benchmark", "Example", "Fuzz"} {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+	return strings.HasPrefix(name, "test") || strings.HasPrefix(name, "Test") ||
+		name == "setUp" || name == "tearDown" || name == "setUpClass" || name == "tearDownClass"
+}
+
+// unusedConfidence says how sure the report is that sym is dead
+func unusedConfidence(sym *index.SymbolUse) (string, string) {
+	ext := filepath.Ext(sym.Path)
+	switch {
+	case dynamicLanguages[ext]:
+		return ConfidenceLow, "dynamic language: getattr, reflection or string dispatch may reach it"
+	case sym.Exported:
+		return ConfidenceMedium, "exported: code outside this root may use it"
+	case sym.Kind == types.KindMethod:
+		return ConfidenceMedium, "method: it may satisfy an interface without being named"
+	}
+	return ConfidenceHigh, ""
+}
+
+func confidenceRank(confidence string) int {
+	switch confidence {
+	case ConfidenceHigh:
+		return 0
+	case ConfidenceMedium:
+		return 1
+	}
+	return 2
+}
@@ -0,0 +1,103 @@
+package workspace
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alucardeht/may-la-mcp/internal/index"
+)
+
+func TestUnusedSymbols(t *testing.T) {
+	root, store, _, _ := testWorkspace(t)
+
+	extra := map[string]struct {
+		content string
+		symbols []*index.IndexedSymbol
+	}{
+		"cart/discount.go": {
+			"package cart\n\nfunc applyDiscount(c *Cart) {}\n\nfunc round(x float64) float64 { return x }\n\nvar total = round(1)\n",
+			[]*index.IndexedSymbol{
+				{Name: "applyDiscount", Kind: "function", LineStart: 3},
+				{Name: "round", Kind: "function", LineStart: 5},
+				{Name: "total", Kind: "variable", LineStart: 7},
+			},
+		},
+		"cart/windows.go": {
+			"//go:build windows\n\npackage cart\n\nfunc winOnly() {}\n",
+			[]*index.IndexedSymbol{{Name: "winOnly", Kind: "function", LineStart: 5}},
+		},
+		"scripts/tasks.py": {
+			"class Task:\n    def __init__(self):\n        pass\n\ndef on_start():\n    pass\n",
+			[]*index.IndexedSymbol{
+				{Name: "Task", Kind: "class", LineStart: 1, IsExported: true},
+				{Name: "__init__", Kind: "method", LineStart: 2},
+				{Name: "on_start", Kind: "function", LineStart: 5, IsExported: true},
+			},
+		},
+	}
+	for rel, file := range extra {
+		path := filepath.Join(root, rel)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(file.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		id, err := store.UpsertFile(&index.IndexedFile{Path: path, Status: index.StatusIndexed, Size: int64(len(file.content))})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.InsertSymbols(id, file.symbols); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tool := NewUnusedSymbolsTool(store)
+	call := func(req UnusedSymbolsRequest) *UnusedSymbolsResponse {
+		t.Helper()
+		req.Path = root
+		input, _ := json.Marshal(req)
+		result, err := tool.Execute(context.Background(), input)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return result.(*UnusedSymbolsResponse)
+	}
+
+	resp := call(UnusedSymbolsRequest{})
+	got := map[string]string{}
+	for _, sym := range resp.Symbols {
+		got[sym.Name] = sym.Confidence
+	}
+	want := map[string]string{
+		"applyDiscount": ConfidenceHigh,
+		"total":         ConfidenceHigh,
+		"Checkout":      ConfidenceMedium,
+		"Task":          ConfidenceLow,
+		"on_start":      ConfidenceLow,
+	}
+	if len(got) != len(want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	for name, confidence := range want {
+		if got[name] != confidence {
+			t.Errorf("expected %s with %s confidence, got %q", name, confidence, got[name])
+		}
+	}
+	if resp.Symbols[0].Confidence != ConfidenceHigh || resp.Symbols[len(resp.Symbols)-1].Confidence != ConfidenceLow {
+		t.Errorf("expected the surest results first, got %+v", resp.Symbols)
+	}
+	if a := resp.Allowlisted; a[allowEntryPoint] != 2 || a[allowTest] != 1 || a[allowBuildTags] != 1 {
+		t.Errorf("expected main and __init__, TestHelper and winOnly allowlisted, got %v", a)
+	}
+
+	resp = call(UnusedSymbolsRequest{Visibility: "unexported", Allow: []string{"apply*"}})
+	if resp.Count != 1 || resp.Symbols[0].Name != "total" || resp.Allowlisted[allowPattern] != 1 {
+		t.Errorf("expected only total once applyDiscount is allowed, got %+v", resp)
+	}
+
+	if _, err := tool.Execute(context.Background(), json.RawMessage(`{"path": "`+root+`", "visibility": "private"}`)); err == nil {
+		t.Error("expected an error for an unknown visibility")
+	}
+}
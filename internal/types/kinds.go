@@ -0,0 +1,208 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Canonical symbol kinds. Every symbol source (index, LSP, regex fallback)
+// reports one of these, and kind filters are normalized to them before
+// matching, so a filter means the same thing regardless of which tier
+// answered the query.
+const (
+	KindFunction  = "function"
+	KindMethod    = "method"
+	KindClass     = "class"
+	KindInterface = "interface"
+	KindStruct    = "struct"
+	KindEnum      = "enum"
+	KindType      = "type"
+	KindConst     = "const"
+	KindVariable  = "variable"
+	KindField     = "field"
+	KindModule    = "module"
+	KindOther     = "other"
+)
+
+// SymbolKinds lists the canonical kinds in the order tools document them
+var SymbolKinds = []string{
+	KindFunction, KindMethod, KindClass, KindInterface, KindStruct, KindEnum,
+	KindType, KindConst, KindVariable, KindField, KindModule, KindOther,
+}
+
+// Reference kinds reported by the references tool and router
+const (
+	RefDefinition = "definition"
+	RefUsage      = "usage"
+	RefImport     = "import"
+	RefComment    = "comment"
+	RefString     = "string"
+)
+
+// kindAliases maps language- and source-specific spellings to canonical kinds
+var kindAliases = map[string]string{
+	"function":       KindFunction,
+	"func":           KindFunction,
+	"fn":             KindFunction,
+	"def":            KindFunction,
+	"operator":       KindFunction,
+	"method":         KindMethod,
+	"constructor":    KindMethod,
+	"class":          KindClass,
+	"interface":      KindInterface,
+	"trait":          KindInterface,
+	"protocol":       KindInterface,
+	"struct":         KindStruct,
+	"enum":           KindEnum,
+	"type":           KindType,
+	"typealias":      KindType,
+	"type_alias":     KindType,
+	"typeparameter":  KindType,
+	"type_parameter": KindType,
+	"const":          KindConst,
+	"constant":       KindConst,
+	"enummember":     KindConst,
+	"enum_member":    KindConst,
+	"variable":       KindVariable,
+	"var":            KindVariable,
+	"let":            KindVariable,
+	"field":          KindField,
+	"property":       KindField,
+	"key":            KindField,
+	"event":          KindField,
+	"module":         KindModule,
+	"namespace":      KindModule,
+	"package":        KindModule,
+	"file":           KindModule,
+	"impl":           KindOther,
+	"other":          KindOther,
+}
+
+// NormalizeKind maps a raw kind reported by a symbol source to its
+// canonical kind. Unknown kinds (Rust impl blocks, LSP literal kinds, ...)
+// become KindOther; filters given by the user go through ParseKind instead.
+func NormalizeKind(kind string) string {
+	if canonical, ok := kindAliases[strings.ToLower(strings.TrimSpace(kind))]; ok {
+		return canonical
+	}
+	return KindOther
+}
+
+// NormalizeKinds normalizes a kind filter, dropping duplicates
+func NormalizeKinds(kinds []string) []string {
+	seen := make(map[string]bool, len(kinds))
+	result := make([]string, 0, len(kinds))
+	for _, k := range kinds {
+		canonical := NormalizeKind(k)
+		if !seen[canonical] {
+			seen[canonical] = true
+			result = append(result, canonical)
+		}
+	}
+	return result
+}
+
+// ParseKind normalizes a kind given as a filter. Unlike NormalizeKind it
+// rejects spellings it does not know, so a typo fails instead of silently
+// filtering on KindOther.
+func ParseKind(kind string) (string, error) {
+	canonical, ok := kindAliases[strings.ToLower(strings.TrimSpace(kind))]
+	if !ok {
+		return "", fmt.Errorf("unknown kind %q (expected one of %s)", kind, strings.Join(SymbolKinds, ", "))
+	}
+	return canonical, nil
+}
+
+// ParseKinds normalizes a kind filter like NormalizeKinds, rejecting
+// unknown kinds
+func ParseKinds(kinds []string) ([]string, error) {
+	for _, k := range kinds {
+		if _, err := ParseKind(k); err != nil {
+			return nil, err
+		}
+	}
+	return NormalizeKinds(kinds), nil
+}
+
+// KindAliases returns every raw spelling that normalizes to the same
+// canonical kind as kind, for matching against stored raw values.
+func KindAliases(kind string) []string {
+	canonical := NormalizeKind(kind)
+	var aliases []string
+	for raw, c := range kindAliases {
+		if c == canonical {
+			aliases = append(aliases, raw)
+		}
+	}
+	return aliases
+}
+
+// PreferSpecific drops generic kinds shadowed by a more specific match on the
+// same declaration: a Go "type X struct" line matches both the type and the
+// struct pattern, and an indented Python def matches function and method.
+func PreferSpecific(kinds []string) []string {
+	has := make(map[string]bool, len(kinds))
+	for _, k := range kinds {
+		has[k] = true
+	}
+
+	shadowed := map[string]bool{
+		KindType:     has[KindStruct] || has[KindInterface] || has[KindClass] || has[KindEnum],
+		KindFunction: has[KindMethod],
+	}
+
+	result := kinds[:0:0]
+	for _, k := range kinds {
+		if !shadowed[k] {
+			result = append(result, k)
+		}
+	}
+	return result
+}
+
+// MatchesKind reports whether kind passes the filter. An empty filter
+// matches everything; otherwise both sides are compared canonically.
+func MatchesKind(filter []string, kind string) bool {
+	if len(filter) == 0 {
+		return true
+	}
+	canonical := NormalizeKind(kind)
+	for _, k := range filter {
+		if NormalizeKind(k) == canonical {
+			return true
+		}
+	}
+	return false
+}
+
+// lspKinds maps LSP SymbolKind codes (1-26) to canonical kinds
+var lspKinds = map[int]string{
+	1:  KindModule,    // File
+	2:  KindModule,    // Module
+	3:  KindModule,    // Namespace
+	4:  KindModule,    // Package
+	5:  KindClass,     // Class
+	6:  KindMethod,    // Method
+	7:  KindField,     // Property
+	8:  KindField,     // Field
+	9:  KindMethod,    // Constructor
+	10: KindEnum,      // Enum
+	11: KindInterface, // Interface
+	12: KindFunction,  // Function
+	13: KindVariable,  // Variable
+	14: KindConst,     // Constant
+	20: KindField,     // Key
+	22: KindConst,     // EnumMember
+	23: KindStruct,    // Struct
+	24: KindField,     // Event
+	25: KindFunction,  // Operator
+	26: KindType,      // TypeParameter
+}
+
+// KindFromLSP converts an LSP SymbolKind code to a canonical kind
+func KindFromLSP(code int) string {
+	if kind, ok := lspKinds[code]; ok {
+		return kind
+	}
+	return KindOther
+}
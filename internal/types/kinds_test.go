@@ -0,0 +1,56 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeKind(t *testing.T) {
+	cases := map[string]string{
+		"var":         KindVariable,
+		"Constructor": KindMethod,
+		"trait":       KindInterface,
+		"struct":      KindStruct,
+		"impl":        KindOther,
+		"bogus":       KindOther,
+	}
+	for in, want := range cases {
+		if got := NormalizeKind(in); got != want {
+			t.Errorf("NormalizeKind(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParseKinds(t *testing.T) {
+	got, err := ParseKinds([]string{"var", "Constructor", "method", "impl"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if want := []string{KindVariable, KindMethod, KindOther}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseKinds = %v, want %v", got, want)
+	}
+
+	if _, err := ParseKinds([]string{"function", "fucntion"}); err == nil {
+		t.Error("Expected an unknown kind to be rejected")
+	}
+	if _, err := ParseKind("bogus"); err == nil {
+		t.Error("Expected ParseKind to reject an unknown kind")
+	}
+}
+
+func TestPreferSpecific(t *testing.T) {
+	got := PreferSpecific([]string{KindType, KindStruct, KindFunction, KindMethod})
+	want := []string{KindStruct, KindMethod}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PreferSpecific = %v, want %v", got, want)
+	}
+}
+
+func TestMatchesKindAcrossSources(t *testing.T) {
+	if !MatchesKind([]string{"var"}, KindFromLSP(13)) {
+		t.Error("var filter should match an LSP Variable")
+	}
+	if MatchesKind([]string{"struct"}, "interface") {
+		t.Error("struct filter should not match an interface")
+	}
+}
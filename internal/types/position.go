@@ -0,0 +1,81 @@
+package types
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Coordinates. Every line and column the tools report or accept follows
+// one convention, whichever tier (index, LSP, regex, ripgrep) produced it:
+//
+//   - lines are 1-based, and both \n and \r\n end a line
+//   - columns are 1-based and count Unicode characters (code points) of
+//     the line's text, decoded from the file's encoding and without its
+//     line terminator
+//   - an end column, such as Symbol.ColumnEnd, is exclusive: the column
+//     just past the range
+//
+// LSP positions are 0-based and count UTF-16 code units, and Go string
+// indexes count UTF-8 bytes; the functions below convert both.
+
+// SplitLines splits text into lines without their \n or \r\n terminators
+func SplitLines(text string) []string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines
+}
+
+// ColumnFromByte returns the column of the byte offset within line
+func ColumnFromByte(line string, offset int) int {
+	offset = min(max(offset, 0), len(line))
+	return utf8.RuneCountInString(line[:offset]) + 1
+}
+
+// ByteFromColumn returns the byte offset of column within line; columns
+// past the end give the line's length
+func ByteFromColumn(line string, column int) int {
+	for i := range line {
+		if column <= 1 {
+			return i
+		}
+		column--
+	}
+	return len(line)
+}
+
+// ColumnFromUTF16 returns the column of the zero-based UTF-16 offset an
+// LSP position gives within line
+func ColumnFromUTF16(line string, character int) int {
+	column, units := 1, 0
+	for _, r := range line {
+		if units >= character {
+			break
+		}
+		units += utf16Len(r)
+		column++
+	}
+	return column
+}
+
+// UTF16FromColumn returns the zero-based UTF-16 offset LSP expects for
+// column within line
+func UTF16FromColumn(line string, column int) int {
+	units := 0
+	for _, r := range line {
+		if column <= 1 {
+			break
+		}
+		units += utf16Len(r)
+		column--
+	}
+	return units
+}
+
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
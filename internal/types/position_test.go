@@ -0,0 +1,50 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitLines(t *testing.T) {
+	got := SplitLines("a\r\nb\nc\r\n")
+	if want := []string{"a", "b", "c", ""}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SplitLines = %q, want %q", got, want)
+	}
+}
+
+func TestColumnConversions(t *testing.T) {
+	// é is 2 bytes and 1 UTF-16 unit, 😀 is 4 bytes and 2 units
+	line := "é😀x = 1"
+	tests := []struct {
+		column int
+		byte   int
+		utf16  int
+	}{
+		{1, 0, 0},
+		{2, 2, 1},
+		{3, 6, 3},
+		{4, 7, 4},
+		{8, 11, 8},
+	}
+	for _, tt := range tests {
+		if got := ColumnFromByte(line, tt.byte); got != tt.column {
+			t.Errorf("ColumnFromByte(%d) = %d, want %d", tt.byte, got, tt.column)
+		}
+		if got := ByteFromColumn(line, tt.column); got != tt.byte {
+			t.Errorf("ByteFromColumn(%d) = %d, want %d", tt.column, got, tt.byte)
+		}
+		if got := ColumnFromUTF16(line, tt.utf16); got != tt.column {
+			t.Errorf("ColumnFromUTF16(%d) = %d, want %d", tt.utf16, got, tt.column)
+		}
+		if got := UTF16FromColumn(line, tt.column); got != tt.utf16 {
+			t.Errorf("UTF16FromColumn(%d) = %d, want %d", tt.column, got, tt.utf16)
+		}
+	}
+
+	if got := ByteFromColumn(line, 50); got != len(line) {
+		t.Errorf("ByteFromColumn past the end = %d, want %d", got, len(line))
+	}
+	if got := ColumnFromUTF16(line, 50); got != 8 {
+		t.Errorf("ColumnFromUTF16 past the end = %d, want 8", got)
+	}
+}
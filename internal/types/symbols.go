@@ -11,6 +11,9 @@ type Symbol struct {
 	Signature     string `json:"signature,omitempty"`
 	Documentation string `json:"documentation,omitempty"`
 	IsExported    bool   `json:"is_exported,omitempty"`
+	// Cell is the index of the notebook cell holding the symbol; Line and
+	// LineEnd then count from the start of that cell
+	Cell *int `json:"cell,omitempty"`
 }
 
 type Reference struct {
@@ -20,3 +23,12 @@ type Reference struct {
 	Context string `json:"context"`
 	Kind    string `json:"kind"`
 }
+
+type Completion struct {
+	Label         string `json:"label"`
+	Kind          string `json:"kind"`
+	Detail        string `json:"detail,omitempty"`
+	Documentation string `json:"documentation,omitempty"`
+	InsertText    string `json:"insert_text,omitempty"`
+	Deprecated    bool   `json:"deprecated,omitempty"`
+}
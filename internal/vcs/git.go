@@ -0,0 +1,82 @@
+// Package vcs finds the git repository a path is checked out in and the
+// branch it is on, reading .git directly so no git binary is needed.
+package vcs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Repo is a git checkout: its work tree root and its git directory, which
+// for a worktree or submodule lies outside the root
+type Repo struct {
+	Root   string
+	GitDir string
+}
+
+// Find returns the repository holding dir, looking upwards, or nil when
+// there is none
+func Find(dir string) *Repo {
+	for {
+		gitPath := filepath.Join(dir, ".git")
+		if info, err := os.Stat(gitPath); err == nil {
+			repo := &Repo{Root: dir, GitDir: gitPath}
+			if !info.IsDir() {
+				repo.GitDir = worktreeGitDir(dir, gitPath)
+			}
+			return repo
+		}
+		if filepath.Dir(dir) == dir {
+			return nil
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// Head returns the branch checked out in r, or for a detached HEAD the
+// commit it points to
+func (r *Repo) Head() (branch, commit string) {
+	head, err := os.ReadFile(filepath.Join(r.GitDir, "HEAD"))
+	if err != nil {
+		return "", ""
+	}
+	ref := strings.TrimSpace(string(head))
+	if branch, ok := strings.CutPrefix(ref, "ref: refs/heads/"); ok {
+		return branch, ""
+	}
+	return "", ref
+}
+
+// Branch returns the branch checked out where path lives, or "" outside a
+// repository and on a detached HEAD
+func Branch(path string) string {
+	dir := path
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		dir = filepath.Dir(path)
+	}
+	repo := Find(dir)
+	if repo == nil {
+		return ""
+	}
+	branch, _ := repo.Head()
+	return branch
+}
+
+// worktreeGitDir follows the "gitdir: path" of a .git file, as worktrees
+// and submodules have
+func worktreeGitDir(dir, gitFile string) string {
+	data, err := os.ReadFile(gitFile)
+	if err != nil {
+		return gitFile
+	}
+	target, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+	if !ok {
+		return gitFile
+	}
+	target = strings.TrimSpace(target)
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(dir, target)
+	}
+	return target
+}
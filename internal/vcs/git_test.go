@@ -0,0 +1,57 @@
+package vcs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBranch(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, ".git", "HEAD"), []byte("ref: refs/heads/feature/login\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(root, "src", "main.go")
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := Branch(file); got != "feature/login" {
+		t.Errorf("expected feature/login for a file, got %q", got)
+	}
+	if got := Branch(filepath.Dir(file)); got != "feature/login" {
+		t.Errorf("expected feature/login for a directory, got %q", got)
+	}
+
+	// a worktree's .git file points at its own git directory
+	gitDir := filepath.Join(root, ".git", "worktrees", "wt")
+	worktree := filepath.Join(t.TempDir(), "wt")
+	if err := os.MkdirAll(gitDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(worktree, 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(gitDir, "HEAD"), []byte("ref: refs/heads/fix\n"), 0644)
+	os.WriteFile(filepath.Join(worktree, ".git"), []byte("gitdir: "+gitDir+"\n"), 0644)
+	if repo := Find(worktree); repo == nil || repo.Root != worktree || repo.GitDir != gitDir {
+		t.Errorf("expected the worktree's git directory, got %+v", repo)
+	}
+	if got := Branch(worktree); got != "fix" {
+		t.Errorf("expected fix in the worktree, got %q", got)
+	}
+
+	os.WriteFile(filepath.Join(root, ".git", "HEAD"), []byte("0123456789abcdef0123456789abcdef01234567\n"), 0644)
+	if branch, commit := Find(root).Head(); branch != "" || commit != "0123456789abcdef0123456789abcdef01234567" {
+		t.Errorf("expected a detached HEAD, got %q %q", branch, commit)
+	}
+	if got := Branch(file); got != "" {
+		t.Errorf("expected no branch on a detached HEAD, got %q", got)
+	}
+}
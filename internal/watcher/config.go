@@ -8,6 +8,8 @@ type WatcherConfig struct {
 	MaxBatchSize   int           `json:"max_batch_size"`
 	IgnorePatterns []string      `json:"ignore_patterns"`
 	WatchHidden    bool          `json:"watch_hidden"`
+	// JournalPath is where the change journal is kept; empty disables it
+	JournalPath string `json:"journal_path"`
 }
 
 func DefaultWatcherConfig() WatcherConfig {
@@ -0,0 +1,115 @@
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/alucardeht/may-la-mcp/internal/glob"
+	"github.com/alucardeht/may-la-mcp/internal/index"
+)
+
+// ExcludeUpdate reports what changing the index's exclude patterns did
+type ExcludeUpdate struct {
+	Patterns []string `json:"patterns"`
+	// Purged is the number of indexed files removed because they are now
+	// excluded
+	Purged int `json:"purged"`
+	// Unwatched and Watched count the directories dropped from and added
+	// to the watch list
+	Unwatched int `json:"unwatched_dirs"`
+	Watched   int `json:"watched_dirs"`
+	// Enqueued is the number of files no longer excluded queued for
+	// indexing
+	Enqueued int `json:"enqueued"`
+}
+
+// UpdateExcludes replaces the index's exclude patterns and applies them at
+// once: files they now cover leave the index and the watch list, and the
+// files they no longer cover are watched and queued for indexing
+func (w *Watcher) UpdateExcludes(ctx context.Context, patterns []string) (*ExcludeUpdate, error) {
+	if w.indexer == nil {
+		return nil, fmt.Errorf("indexing is disabled")
+	}
+	for _, pattern := range patterns {
+		if _, err := glob.Normalize(pattern); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	previous := w.indexer.SetExcludePatterns(patterns)
+	update := &ExcludeUpdate{Patterns: w.indexer.ExcludePatterns()}
+
+	purged, err := w.indexer.PurgeExcluded(ctx)
+	update.Purged = purged
+	if err != nil {
+		return update, fmt.Errorf("purge excluded files: %w", err)
+	}
+
+	update.Unwatched, update.Watched, update.Enqueued = w.rescan(func(path string) bool {
+		return index.MatchesAny(previous, path)
+	})
+	log.Info("index excludes updated", "patterns", len(patterns), "purged", update.Purged,
+		"unwatched", update.Unwatched, "watched", update.Watched, "enqueued", update.Enqueued)
+	return update, nil
+}
+
+// rescan brings the watch list in line with shouldIgnore after the ignore
+// rules changed, and queues the files wasExcluded kept from the index
+func (w *Watcher) rescan(wasExcluded func(path string) bool) (unwatched, watched, enqueued int) {
+	w.fsWatcherMu.Lock()
+	current := make(map[string]bool)
+	for _, dir := range w.fsWatcher.WatchList() {
+		current[dir] = true
+	}
+	w.fsWatcherMu.Unlock()
+
+	w.mu.RLock()
+	roots := append([]string(nil), w.roots...)
+	w.mu.RUnlock()
+	isRoot := make(map[string]bool)
+	for _, root := range roots {
+		isRoot[root] = true
+	}
+
+	for dir := range current {
+		if !isRoot[dir] && w.shouldIgnore(dir) {
+			w.removeFromWatcher(dir)
+			unwatched++
+		}
+	}
+
+	var walk func(dir string)
+	walk = func(dir string) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			log.Debug("failed to read directory", "path", dir, "error", err)
+			return
+		}
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			if w.shouldIgnore(path) {
+				continue
+			}
+			if entry.IsDir() {
+				if !current[path] {
+					if err := w.addToWatcher(path); err != nil {
+						log.Debug("failed to watch directory", "path", path, "error", err)
+						continue
+					}
+					watched++
+				}
+				walk(path)
+				continue
+			}
+			if wasExcluded(path) && w.indexer.Enqueue(index.IndexJob{Path: path, Priority: index.PriorityNormal}) {
+				enqueued++
+			}
+		}
+	}
+	for _, root := range roots {
+		walk(root)
+	}
+	return unwatched, watched, enqueued
+}
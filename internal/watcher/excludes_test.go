@@ -0,0 +1,78 @@
+package watcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alucardeht/may-la-mcp/internal/index"
+)
+
+func TestUpdateExcludes(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"src/main.go", "gen/types.go"} {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("package x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store, err := index.NewIndexStore(filepath.Join(t.TempDir(), "index.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	for _, name := range []string{"src/main.go", "gen/types.go"} {
+		if _, err := store.UpsertFile(&index.IndexedFile{Path: filepath.Join(root, name), Status: index.StatusIndexed}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := index.DefaultWorkerConfig()
+	config.ExcludePatterns = nil
+	config.RateLimit = 0
+	worker := index.NewIndexWorker(store, config)
+	w, err := New(DefaultWatcherConfig(), worker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+	if err := w.AddRoot(root); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	update, err := w.UpdateExcludes(ctx, []string{"**/gen/**"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if update.Purged != 1 || update.Unwatched != 1 || update.Watched != 0 || update.Enqueued != 0 {
+		t.Errorf("excluding gen: got %+v", update)
+	}
+	paths, err := store.GetIndexedPaths("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 1 || paths[0] != filepath.Join(root, "src/main.go") {
+		t.Errorf("indexed paths after excluding gen: %v", paths)
+	}
+	if !worker.IsExcluded(filepath.Join(root, "gen", "types.go")) {
+		t.Error("gen/types.go is not excluded")
+	}
+
+	update, err = w.UpdateExcludes(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if update.Purged != 0 || update.Unwatched != 0 || update.Watched != 1 || update.Enqueued != 1 {
+		t.Errorf("including gen again: got %+v", update)
+	}
+
+	if _, err := w.UpdateExcludes(ctx, []string{"[broken"}); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
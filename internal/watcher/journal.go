@@ -0,0 +1,162 @@
+package watcher
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const defaultJournalMaxSize = 10 * 1024 * 1024
+
+// JournalEntry is one file change the watcher saw
+type JournalEntry struct {
+	Time  time.Time `json:"time"`
+	Path  string    `json:"path"`
+	Event string    `json:"event"`
+	// Size is the file's size after the change, 0 once it is gone
+	Size int64 `json:"size"`
+	// SizeDelta is how much the change grew the file; it is left out when
+	// the size before the change is not known
+	SizeDelta *int64 `json:"size_delta,omitempty"`
+}
+
+// Journal keeps the changes the watcher sees as JSON lines, so changes
+// made by builds or other editors can be looked up later, across daemon
+// restarts. Like the audit log, it is rotated past a size limit, keeping
+// one previous file.
+type Journal struct {
+	path    string
+	maxSize int64
+
+	mu    sync.Mutex
+	sizes map[string]int64
+	// previousSize is asked for the size of a file the journal has not
+	// seen yet, such as the index's record of it
+	previousSize func(path string) (int64, bool)
+}
+
+// OpenJournal opens the journal at path, reading the last known size of
+// each file from what it already holds
+func OpenJournal(path string) (*Journal, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create change journal directory: %w", err)
+	}
+	j := &Journal{path: path, maxSize: defaultJournalMaxSize, sizes: make(map[string]int64)}
+	if err := j.scan(time.Time{}, func(entry JournalEntry) {
+		j.sizes[entry.Path] = entry.Size
+	}); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+func (j *Journal) Path() string {
+	return j.path
+}
+
+// Record appends the events of one debounced batch
+func (j *Journal) Record(events []FileEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var lines []byte
+	for _, event := range events {
+		entry := JournalEntry{Time: event.Timestamp.UTC(), Path: event.Path, Event: event.Type.String()}
+		if event.Type != EventDelete {
+			if info, err := os.Stat(event.Path); err == nil {
+				if info.IsDir() {
+					continue
+				}
+				entry.Size = info.Size()
+			}
+		}
+
+		previous, known := j.sizes[event.Path]
+		if !known && event.Type == EventCreate {
+			previous, known = 0, true
+		}
+		if !known && j.previousSize != nil {
+			previous, known = j.previousSize(event.Path)
+		}
+		if known {
+			delta := entry.Size - previous
+			entry.SizeDelta = &delta
+		}
+		if entry.Size == 0 && (event.Type == EventDelete || event.Type == EventRename) {
+			delete(j.sizes, event.Path)
+		} else {
+			j.sizes[event.Path] = entry.Size
+		}
+
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		lines = append(append(lines, line...), '\n')
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+
+	if info, err := os.Stat(j.path); err == nil && info.Size()+int64(len(lines)) > j.maxSize {
+		if err := os.Rename(j.path, j.path+".1"); err != nil {
+			return fmt.Errorf("failed to rotate change journal: %w", err)
+		}
+	}
+	file, err := os.OpenFile(j.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open change journal: %w", err)
+	}
+	defer file.Close()
+	_, err = file.Write(lines)
+	return err
+}
+
+// Since returns the entries recorded at or after since, oldest first
+func (j *Journal) Since(since time.Time) ([]JournalEntry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var entries []JournalEntry
+	err := j.scan(since, func(entry JournalEntry) {
+		entries = append(entries, entry)
+	})
+	return entries, err
+}
+
+// scan calls fn with every entry at or after since, from the rotated file
+// and then the current one; lines that do not parse are skipped
+func (j *Journal) scan(since time.Time, fn func(JournalEntry)) error {
+	for _, path := range []string{j.path + ".1", j.path} {
+		file, err := os.Open(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read change journal: %w", err)
+		}
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			var entry JournalEntry
+			if json.Unmarshal(scanner.Bytes(), &entry) != nil || entry.Time.Before(since) {
+				continue
+			}
+			fn(entry)
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read change journal: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package watcher
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJournal(t *testing.T) {
+	root := t.TempDir()
+	journalPath := filepath.Join(t.TempDir(), "changes.jsonl")
+	main := filepath.Join(root, "main.go")
+	notes := filepath.Join(root, "docs", "notes.md")
+	os.MkdirAll(filepath.Dir(notes), 0755)
+
+	journal, err := OpenJournal(journalPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	journal.previousSize = func(path string) (int64, bool) {
+		if path == notes {
+			return 4, true
+		}
+		return 0, false
+	}
+
+	old := time.Now().Add(-2 * time.Hour)
+	os.WriteFile(main, []byte("package main\n"), 0644)
+	if err := journal.Record([]FileEvent{{Path: main, Type: EventCreate, Timestamp: old}}); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(main, []byte("package main\n\nfunc main() {}\n"), 0644)
+	os.WriteFile(notes, []byte("# Notes\n"), 0644)
+	now := time.Now()
+	if err := journal.Record([]FileEvent{
+		{Path: main, Type: EventModify, Timestamp: now},
+		{Path: notes, Type: EventModify, Timestamp: now},
+		{Path: filepath.Join(root, "docs"), Type: EventModify, Timestamp: now},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// a reopened journal still knows the sizes it recorded
+	journal, err = OpenJournal(journalPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(main)
+	if err := journal.Record([]FileEvent{{Path: main, Type: EventDelete, Timestamp: now}}); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := journal.Since(time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		path  string
+		event string
+		delta int64
+	}{
+		{main, "create", 13},
+		{main, "modify", 16},
+		{notes, "modify", 4},
+		{main, "delete", -29},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("expected %d entries, got %+v", len(want), entries)
+	}
+	for i, w := range want {
+		e := entries[i]
+		if e.Path != w.path || e.Event != w.event || e.SizeDelta == nil || *e.SizeDelta != w.delta {
+			t.Errorf("entry %d: expected %s %s %+d, got %+v", i, w.event, w.path, w.delta, e)
+		}
+	}
+
+	w := &Watcher{journal: journal}
+	tool := NewRecentChangesTool(w)
+	call := func(req RecentChangesRequest) *RecentChangesResponse {
+		t.Helper()
+		input, _ := json.Marshal(req)
+		result, err := tool.Execute(context.Background(), input)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return result.(*RecentChangesResponse)
+	}
+
+	resp := call(RecentChangesRequest{})
+	if resp.Count != 3 || resp.Changes[0].Event != "delete" {
+		t.Errorf("expected the last hour's 3 changes newest first, got %+v", resp.Changes)
+	}
+	resp = call(RecentChangesRequest{Since: "3h", ByFile: true})
+	if resp.Count != 2 || resp.Files[0].Path != main || resp.Files[0].Changes != 3 || resp.Files[0].SizeDelta != 0 {
+		t.Errorf("expected main.go with 3 changes netting 0 bytes, got %+v", resp.Files)
+	}
+	resp = call(RecentChangesRequest{Since: "3h", Path: filepath.Join(root, "docs"), Events: []string{"modify"}})
+	if resp.Count != 1 || resp.Changes[0].Path != notes {
+		t.Errorf("expected only notes.md under docs, got %+v", resp.Changes)
+	}
+
+	if _, err := tool.Execute(context.Background(), json.RawMessage(`{"since": "yesterday"}`)); err == nil {
+		t.Error("expected an error for an invalid since")
+	}
+}
@@ -0,0 +1,231 @@
+package watcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alucardeht/may-la-mcp/internal/paths"
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+const (
+	defaultRecentWindow  = time.Hour
+	defaultRecentResults = 200
+)
+
+type RecentChangesRequest struct {
+	Since      string   `json:"since,omitempty"`
+	Path       string   `json:"path,omitempty"`
+	Events     []string `json:"events,omitempty"`
+	ByFile     bool     `json:"by_file,omitempty"`
+	MaxResults int      `json:"max_results,omitempty"`
+}
+
+// FileActivity sums up the journal entries of one file
+type FileActivity struct {
+	Path      string    `json:"path"`
+	Changes   int       `json:"changes"`
+	LastEvent string    `json:"last_event"`
+	LastTime  time.Time `json:"last_time"`
+	Size      int64     `json:"size"`
+	// SizeDelta adds up the deltas that are known
+	SizeDelta int64 `json:"size_delta"`
+}
+
+type RecentChangesResponse struct {
+	Since time.Time `json:"since"`
+	// Changes are newest first; Files replaces them with by_file
+	Changes   []JournalEntry `json:"changes,omitempty"`
+	Files     []FileActivity `json:"files,omitempty"`
+	Count     int            `json:"count"`
+	Truncated bool           `json:"truncated,omitempty"`
+}
+
+// RecentChangesTool answers what changed lately from the change journal,
+// whoever made the changes
+type RecentChangesTool struct {
+	watcher *Watcher
+}
+
+func NewRecentChangesTool(w *Watcher) *RecentChangesTool {
+	return &RecentChangesTool{watcher: w}
+}
+
+func (t *RecentChangesTool) Name() string {
+	return "recent_changes"
+}
+
+func (t *RecentChangesTool) Description() string {
+	return "List the file changes the watcher recorded in the last minutes or hours, including those made outside the agent by builds, other editors or git. Each change has its event, time, size and size delta; by_file sums them up per file"
+}
+
+func (t *RecentChangesTool) Title() string {
+	return "Recent Changes"
+}
+
+func (t *RecentChangesTool) Annotations() map[string]bool {
+	return tools.ReadOnlyAnnotations()
+}
+
+func (t *RecentChangesTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"since": {
+				"type": "string",
+				"description": "How far back to look, as a duration such as 15m or 2h, or an RFC 3339 time (default: 1h)"
+			},
+			"path": {
+				"type": "string",
+				"description": "Only changes to this file or under this directory"
+			},
+			"events": {
+				"type": "array",
+				"items": {"type": "string", "enum": ["create", "modify", "delete", "rename"]},
+				"description": "Only these kinds of change (default: all)"
+			},
+			"by_file": {
+				"type": "boolean",
+				"description": "One entry per file with its number of changes and net size delta, most recently changed first"
+			},
+			"max_results": {
+				"type": "integer",
+				"description": "Maximum changes or files listed (default: 200)"
+			}
+		}
+	}`)
+}
+
+func (t *RecentChangesTool) Examples() []tools.Example {
+	return []tools.Example{
+		{
+			Description: "What changed in the last 30 minutes, per file",
+			Arguments:   json.RawMessage(`{"since": "30m", "by_file": true}`),
+		},
+	}
+}
+
+func (t *RecentChangesTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var req RecentChangesRequest
+	if len(input) > 0 {
+		if err := json.Unmarshal(input, &req); err != nil {
+			return nil, fmt.Errorf("invalid request: %w", err)
+		}
+	}
+	since, err := parseSince(req.Since, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	events := make(map[string]bool, len(req.Events))
+	for _, event := range req.Events {
+		switch event {
+		case "create", "modify", "delete", "rename":
+			events[event] = true
+		default:
+			return nil, fmt.Errorf("unknown event %q (want create, modify, delete or rename)", event)
+		}
+	}
+	if req.MaxResults <= 0 {
+		req.MaxResults = defaultRecentResults
+	}
+	prefix := ""
+	if req.Path != "" {
+		if err := tools.CheckPath(req.Path); err != nil {
+			return nil, err
+		}
+		prefix = paths.Canonical(req.Path)
+	}
+
+	journal := t.watcher.Journal()
+	if journal == nil {
+		return nil, fmt.Errorf("the change journal is disabled")
+	}
+	entries, err := journal.Since(since)
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []JournalEntry
+	for _, entry := range entries {
+		if len(events) > 0 && !events[entry.Event] {
+			continue
+		}
+		if prefix != "" && entry.Path != prefix && !strings.HasPrefix(entry.Path, prefix+string(filepath.Separator)) {
+			continue
+		}
+		matched = append(matched, entry)
+	}
+	// newest first
+	for i, j := 0, len(matched)-1; i < j; i, j = i+1, j-1 {
+		matched[i], matched[j] = matched[j], matched[i]
+	}
+
+	resp := &RecentChangesResponse{Since: since}
+	if req.ByFile {
+		resp.Files = byFile(matched)
+		if len(resp.Files) > req.MaxResults {
+			resp.Files, resp.Truncated = resp.Files[:req.MaxResults], true
+		}
+		resp.Count = len(resp.Files)
+		return resp, nil
+	}
+
+	if len(matched) > req.MaxResults {
+		matched, resp.Truncated = matched[:req.MaxResults], true
+	}
+	resp.Changes = matched
+	if resp.Changes == nil {
+		resp.Changes = []JournalEntry{}
+	}
+	resp.Count = len(resp.Changes)
+	return resp, nil
+}
+
+// parseSince reads a duration back from now or an absolute time
+func parseSince(since string, now time.Time) (time.Time, error) {
+	if since == "" {
+		return now.Add(-defaultRecentWindow), nil
+	}
+	if d, err := time.ParseDuration(since); err == nil && d > 0 {
+		return now.Add(-d), nil
+	}
+	if at, err := time.Parse(time.RFC3339, since); err == nil {
+		return at, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid since %q: want a duration such as 30m or an RFC 3339 time", since)
+}
+
+// byFile folds newest-first entries into one activity per file, in the
+// order the files last changed
+func byFile(entries []JournalEntry) []FileActivity {
+	index := make(map[string]int)
+	var files []FileActivity
+	for _, entry := range entries {
+		i, ok := index[entry.Path]
+		if !ok {
+			i = len(files)
+			index[entry.Path] = i
+			files = append(files, FileActivity{
+				Path:      entry.Path,
+				LastEvent: entry.Event,
+				LastTime:  entry.Time,
+				Size:      entry.Size,
+			})
+		}
+		files[i].Changes++
+		if entry.SizeDelta != nil {
+			files[i].SizeDelta += *entry.SizeDelta
+		}
+	}
+	sort.SliceStable(files, func(i, j int) bool { return files[i].LastTime.After(files[j].LastTime) })
+	return files
+}
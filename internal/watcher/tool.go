@@ -0,0 +1,139 @@
+package watcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+type ExcludesRequest struct {
+	Action   string   `json:"action,omitempty"`
+	Patterns []string `json:"patterns,omitempty"`
+}
+
+// ExcludesTool lists and changes the index's exclude patterns without a
+// daemon restart
+type ExcludesTool struct {
+	watcher *Watcher
+}
+
+func NewExcludesTool(w *Watcher) *ExcludesTool {
+	return &ExcludesTool{watcher: w}
+}
+
+func (t *ExcludesTool) Name() string {
+	return "index_excludes"
+}
+
+func (t *ExcludesTool) Description() string {
+	return "List or change the glob patterns of paths the index and file watcher skip. Changes apply at once: newly excluded files leave the index and newly included ones are watched and indexed. They last until the daemon restarts."
+}
+
+func (t *ExcludesTool) Title() string {
+	return "Index Exclude Patterns"
+}
+
+func (t *ExcludesTool) Annotations() map[string]bool {
+	return tools.SafeWriteAnnotations()
+}
+
+func (t *ExcludesTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"action": {
+				"type": "string",
+				"enum": ["list", "add", "remove", "set"],
+				"description": "list the patterns, add or remove some, or set the whole list (default: list)"
+			},
+			"patterns": {
+				"type": "array",
+				"items": {"type": "string"},
+				"description": "Glob patterns such as **/generated/**, for add, remove and set"
+			}
+		},
+		"required": []
+	}`)
+}
+
+func (t *ExcludesTool) OutputSchema() json.RawMessage {
+	return tools.OutputSchemaOf(ExcludeUpdate{})
+}
+
+func (t *ExcludesTool) Execute(ctx context.Context, input json.RawMessage) (interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	patterns, change, err := t.resolve(input)
+	if err != nil {
+		return nil, err
+	}
+	if !change {
+		return &ExcludeUpdate{Patterns: patterns}, nil
+	}
+	return t.watcher.UpdateExcludes(ctx, patterns)
+}
+
+func (t *ExcludesTool) DryRun(ctx context.Context, input json.RawMessage) (*tools.Preview, error) {
+	patterns, change, err := t.resolve(input)
+	if err != nil {
+		return nil, err
+	}
+	summary := "Would leave the exclude patterns unchanged"
+	if change {
+		summary = fmt.Sprintf("Would set %d exclude patterns", len(patterns))
+	}
+	return &tools.Preview{
+		DryRun:  true,
+		Tool:    t.Name(),
+		Summary: summary,
+		Details: map[string]interface{}{"patterns": patterns},
+	}, nil
+}
+
+// resolve returns the patterns a request asks for and whether they
+// replace the current ones
+func (t *ExcludesTool) resolve(input json.RawMessage) ([]string, bool, error) {
+	var req ExcludesRequest
+	if len(input) > 0 {
+		if err := json.Unmarshal(input, &req); err != nil {
+			return nil, false, fmt.Errorf("invalid request: %w", err)
+		}
+	}
+	if t.watcher.indexer == nil {
+		return nil, false, fmt.Errorf("indexing is disabled")
+	}
+
+	current := t.watcher.indexer.ExcludePatterns()
+	if req.Action != "" && req.Action != "list" && req.Action != "set" && len(req.Patterns) == 0 {
+		return nil, false, fmt.Errorf("patterns is required for %s", req.Action)
+	}
+
+	patterns := []string{}
+	switch req.Action {
+	case "", "list":
+		return current, false, nil
+	case "add":
+		patterns = append(patterns, current...)
+		for _, p := range req.Patterns {
+			if !slices.Contains(patterns, p) {
+				patterns = append(patterns, p)
+			}
+		}
+	case "remove":
+		for _, p := range current {
+			if !slices.Contains(req.Patterns, p) {
+				patterns = append(patterns, p)
+			}
+		}
+	case "set":
+		patterns = append(patterns, req.Patterns...)
+	default:
+		return nil, false, fmt.Errorf("unknown action %q (want list, add, remove or set)", req.Action)
+	}
+	return patterns, true, nil
+}
@@ -6,12 +6,14 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/bmatcuk/doublestar/v4"
 	"github.com/fsnotify/fsnotify"
+	"github.com/alucardeht/may-la-mcp/internal/glob"
 	"github.com/alucardeht/may-la-mcp/internal/index"
 	"github.com/alucardeht/may-la-mcp/internal/logger"
+	"github.com/alucardeht/may-la-mcp/internal/paths"
 )
 
 var log = logger.ForComponent("watcher")
@@ -26,10 +28,17 @@ type Watcher struct {
 	roots       []string
 	mu          sync.RWMutex
 	running     bool
+	alive       atomic.Bool
 	ctx         context.Context
 	cancel      context.CancelFunc
+	onChange    atomic.Pointer[ChangeHandler]
+	journal     *Journal
 }
 
+// ChangeHandler receives each debounced batch of file events that fall
+// inside a watched root.
+type ChangeHandler func(events []FileEvent)
+
 func New(config WatcherConfig, indexer *index.IndexWorker) (*Watcher, error) {
 	fsWatcher, err := fsnotify.NewWatcher()
 	if err != nil {
@@ -44,6 +53,17 @@ func New(config WatcherConfig, indexer *index.IndexWorker) (*Watcher, error) {
 		roots:      make([]string, 0),
 	}
 
+	if config.JournalPath != "" {
+		if journal, err := OpenJournal(config.JournalPath); err != nil {
+			log.Warn("change journal disabled", "error", err)
+		} else {
+			if indexer != nil {
+				journal.previousSize = indexer.IndexedSize
+			}
+			w.journal = journal
+		}
+	}
+
 	w.debouncer = NewDebouncer(config.DebounceWindow, config.MaxBatchSize, w.onFlush)
 
 	return w, nil
@@ -62,6 +82,7 @@ func (w *Watcher) removeFromWatcher(path string) {
 }
 
 func (w *Watcher) AddRoot(path string) error {
+	path = paths.Canonical(path)
 	log.Info("adding root to watch", "path", path)
 
 	if err := w.addToWatcher(path); err != nil {
@@ -118,6 +139,7 @@ func (w *Watcher) walkAndAdd(path string) error {
 }
 
 func (w *Watcher) RemoveRoot(path string) error {
+	path = paths.Canonical(path)
 	w.removeFromWatcher(path)
 
 	w.mu.Lock()
@@ -146,12 +168,15 @@ func (w *Watcher) Start(ctx context.Context) error {
 	w.ctx, w.cancel = context.WithCancel(ctx)
 	w.mu.Unlock()
 
+	w.alive.Store(true)
 	go w.handleEvents()
 
 	return nil
 }
 
 func (w *Watcher) handleEvents() {
+	defer w.alive.Store(false)
+
 	for {
 		select {
 		case <-w.ctx.Done():
@@ -216,6 +241,15 @@ func (w *Watcher) convertEvent(event fsnotify.Event) *FileEvent {
 	}
 }
 
+// SetChangeHandler installs h to be told about file changes; nil removes it
+func (w *Watcher) SetChangeHandler(h ChangeHandler) {
+	if h == nil {
+		w.onChange.Store(nil)
+		return
+	}
+	w.onChange.Store(&h)
+}
+
 func (w *Watcher) onFlush(events []FileEvent) {
 	log.Info("flushing events", "count", len(events))
 
@@ -223,6 +257,22 @@ func (w *Watcher) onFlush(events []FileEvent) {
 		return
 	}
 
+	h := w.onChange.Load()
+	if h != nil || w.journal != nil {
+		if inRoots := w.withinRoots(events); len(inRoots) > 0 {
+			// the journal asks the index for sizes before the batch is
+			// re-indexed below
+			if w.journal != nil {
+				if err := w.journal.Record(inRoots); err != nil {
+					log.Warn("failed to write change journal", "error", err)
+				}
+			}
+			if h != nil {
+				(*h)(inRoots)
+			}
+		}
+	}
+
 	if w.indexer == nil {
 		log.Error("CRITICAL: indexer is nil in onFlush!")
 		return
@@ -244,6 +294,23 @@ func (w *Watcher) onFlush(events []FileEvent) {
 	}
 }
 
+func (w *Watcher) withinRoots(events []FileEvent) []FileEvent {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	result := make([]FileEvent, 0, len(events))
+	for _, event := range events {
+		for _, root := range w.roots {
+			rel, err := filepath.Rel(root, event.Path)
+			if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				result = append(result, event)
+				break
+			}
+		}
+	}
+	return result
+}
+
 func (w *Watcher) shouldIgnore(path string) bool {
 	basename := filepath.Base(path)
 
@@ -252,12 +319,33 @@ func (w *Watcher) shouldIgnore(path string) bool {
 	}
 
 	for _, pattern := range w.config.IgnorePatterns {
-		if match, _ := doublestar.Match(pattern, path); match {
+		if glob.Covers(pattern, filepath.ToSlash(path)) {
 			return true
 		}
 	}
 
-	return false
+	// nothing under the index's excludes would be indexed, so it is not
+	// watched either
+	return w.indexer != nil && w.indexer.IsExcluded(path)
+}
+
+// IsRunning reports whether the watcher was started and its event loop is
+// still processing events.
+func (w *Watcher) IsRunning() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.running && w.alive.Load()
+}
+
+// Journal returns the change journal, or nil when it is disabled
+func (w *Watcher) Journal() *Journal {
+	return w.journal
+}
+
+func (w *Watcher) RootCount() int {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return len(w.roots)
 }
 
 func (w *Watcher) Stop() error {
@@ -0,0 +1,184 @@
+package protocol
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// WireVersion is the version of the daemon<->shim framing protocol. Peers
+// exchange it in the Hello frame and refuse to talk across versions.
+const WireVersion = 1
+
+// MaxFrameSize bounds the payload of a single frame
+const MaxFrameSize = 64 << 20
+
+// frameHeaderSize is version (1) + type (1) + request ID (8)
+const frameHeaderSize = 10
+
+// FrameType identifies the purpose of a frame on the daemon socket
+type FrameType uint8
+
+const (
+	// FrameHello opens a connection. The client sends a Hello payload and
+	// the daemon answers with a Hello frame or an Error frame.
+	FrameHello FrameType = iota + 1
+	// FrameRequest carries a JSON-RPC request or batch
+	FrameRequest
+	// FrameResponse carries the JSON-RPC response to the request with the
+	// same frame ID
+	FrameResponse
+	// FrameCancel asks the daemon to abandon the request with the same ID
+	FrameCancel
+	// FramePing and FramePong are heartbeats; a pong echoes the ping's ID
+	FramePing
+	FramePong
+	// FrameError reports a protocol-level failure; the payload is a message
+	FrameError
+	// FrameNotification carries a server-initiated JSON-RPC notification.
+	// It is sent by the daemon with ID 0 and is never answered.
+	FrameNotification
+	// FramePartial carries a JSON-RPC notification with a partial result of
+	// the request with the same ID. A request's partial frames all precede
+	// its response frame, which carries the final result. The daemon only
+	// sends them to clients that asked for streaming in their Hello.
+	FramePartial
+)
+
+func (t FrameType) String() string {
+	switch t {
+	case FrameHello:
+		return "hello"
+	case FrameRequest:
+		return "request"
+	case FrameResponse:
+		return "response"
+	case FrameCancel:
+		return "cancel"
+	case FramePing:
+		return "ping"
+	case FramePong:
+		return "pong"
+	case FrameError:
+		return "error"
+	case FrameNotification:
+		return "notification"
+	case FramePartial:
+		return "partial"
+	default:
+		return fmt.Sprintf("frame(%d)", uint8(t))
+	}
+}
+
+var (
+	ErrFrameTooLarge      = errors.New("frame exceeds maximum size")
+	ErrUnsupportedVersion = errors.New("unsupported wire protocol version")
+)
+
+// Frame is one message on the daemon socket. On the wire it is a 4-byte
+// big-endian length followed by the version, type, request ID and payload.
+type Frame struct {
+	Type    FrameType
+	ID      uint64
+	Payload []byte
+}
+
+// Hello is the payload of the FrameHello exchanged when a connection opens
+type Hello struct {
+	Version int    `json:"version"`
+	Token   string `json:"token,omitempty"`
+	// ReadOnly asks the daemon to refuse mutating tools for this session
+	ReadOnly bool `json:"read_only,omitempty"`
+	// DryRun asks the daemon to preview mutating tools instead of running them
+	DryRun bool `json:"dry_run,omitempty"`
+	// Streaming asks the daemon to send the partial results of tool calls
+	// as FramePartial frames
+	Streaming bool `json:"streaming,omitempty"`
+}
+
+// FrameReader decodes frames from a stream
+type FrameReader struct {
+	r *bufio.Reader
+}
+
+func NewFrameReader(r io.Reader) *FrameReader {
+	return &FrameReader{r: bufio.NewReader(r)}
+}
+
+// Read returns the next frame. Frames written with a different wire version
+// are rejected with ErrUnsupportedVersion.
+func (fr *FrameReader) Read() (*Frame, error) {
+	return fr.ReadLimited(MaxFrameSize)
+}
+
+// ReadLimited returns the next frame like Read, rejecting one whose payload
+// exceeds limit bytes with ErrFrameTooLarge before reading it
+func (fr *FrameReader) ReadLimited(limit uint32) (*Frame, error) {
+	var lenBuf [4]byte
+	if _, err := io.ReadFull(fr.r, lenBuf[:]); err != nil {
+		return nil, err
+	}
+
+	size := binary.BigEndian.Uint32(lenBuf[:])
+	if size < frameHeaderSize {
+		return nil, fmt.Errorf("frame too short: %d bytes", size)
+	}
+	if size-frameHeaderSize > limit {
+		return nil, fmt.Errorf("%w: %d bytes", ErrFrameTooLarge, size)
+	}
+
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(fr.r, buf); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	if buf[0] != WireVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, buf[0])
+	}
+
+	return &Frame{
+		Type:    FrameType(buf[1]),
+		ID:      binary.BigEndian.Uint64(buf[2:10]),
+		Payload: buf[frameHeaderSize:],
+	}, nil
+}
+
+// FrameWriter encodes frames onto a stream. It is safe for concurrent use;
+// each frame is flushed as soon as it is written.
+type FrameWriter struct {
+	mu sync.Mutex
+	w  *bufio.Writer
+}
+
+func NewFrameWriter(w io.Writer) *FrameWriter {
+	return &FrameWriter{w: bufio.NewWriter(w)}
+}
+
+func (fw *FrameWriter) Write(f *Frame) error {
+	if len(f.Payload) > MaxFrameSize {
+		return fmt.Errorf("%w: %d bytes", ErrFrameTooLarge, len(f.Payload))
+	}
+
+	var header [4 + frameHeaderSize]byte
+	binary.BigEndian.PutUint32(header[0:4], uint32(frameHeaderSize+len(f.Payload)))
+	header[4] = WireVersion
+	header[5] = byte(f.Type)
+	binary.BigEndian.PutUint64(header[6:14], f.ID)
+
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	if _, err := fw.w.Write(header[:]); err != nil {
+		return err
+	}
+	if _, err := fw.w.Write(f.Payload); err != nil {
+		return err
+	}
+	return fw.w.Flush()
+}
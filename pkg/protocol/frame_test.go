@@ -0,0 +1,75 @@
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestFrameRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewFrameWriter(&buf)
+
+	frames := []*Frame{
+		{Type: FrameHello, ID: 1, Payload: []byte(`{"version":1}`)},
+		{Type: FrameRequest, ID: 2, Payload: []byte(`{"jsonrpc":"2.0","id":7,"method":"ping"}`)},
+		{Type: FrameCancel, ID: 2},
+		{Type: FramePing, ID: 1 << 40},
+	}
+	for _, f := range frames {
+		if err := w.Write(f); err != nil {
+			t.Fatalf("write %s: %v", f.Type, err)
+		}
+	}
+
+	r := NewFrameReader(&buf)
+	for _, want := range frames {
+		got, err := r.Read()
+		if err != nil {
+			t.Fatalf("read %s: %v", want.Type, err)
+		}
+		if got.Type != want.Type || got.ID != want.ID || !bytes.Equal(got.Payload, want.Payload) {
+			t.Errorf("got %s/%d %q, want %s/%d %q", got.Type, got.ID, got.Payload, want.Type, want.ID, want.Payload)
+		}
+	}
+}
+
+func TestFrameReaderRejectsOtherVersions(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewFrameWriter(&buf).Write(&Frame{Type: FramePing, ID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	raw := buf.Bytes()
+	raw[4] = WireVersion + 1
+
+	_, err := NewFrameReader(bytes.NewReader(raw)).Read()
+	if !errors.Is(err, ErrUnsupportedVersion) {
+		t.Fatalf("expected ErrUnsupportedVersion, got %v", err)
+	}
+}
+
+func TestFrameReaderRejectsRawJSON(t *testing.T) {
+	_, err := NewFrameReader(bytes.NewReader([]byte(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))).Read()
+	if !errors.Is(err, ErrFrameTooLarge) {
+		t.Fatalf("expected ErrFrameTooLarge, got %v", err)
+	}
+}
+
+func TestFrameReaderLimit(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewFrameWriter(&buf)
+	if err := w.Write(&Frame{Type: FrameHello, ID: 1, Payload: make([]byte, 64)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(&Frame{Type: FrameHello, ID: 2, Payload: make([]byte, 65)}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewFrameReader(&buf)
+	if _, err := r.ReadLimited(64); err != nil {
+		t.Fatalf("expected a frame at the limit to be read, got %v", err)
+	}
+	if _, err := r.ReadLimited(64); !errors.Is(err, ErrFrameTooLarge) {
+		t.Fatalf("expected ErrFrameTooLarge, got %v", err)
+	}
+}
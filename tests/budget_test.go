@@ -0,0 +1,61 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+)
+
+// TestTokenEstimatesOverMCP checks that every tool result carries its
+// estimated tokens and, in a session, the running total of the session
+func TestTokenEstimatesOverMCP(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.txt")
+	os.WriteFile(path, bytes.Repeat([]byte("token "), 200), 0644)
+
+	server := replayServer(t)
+	var input []byte
+	for i := 1; i <= 2; i++ {
+		line, _ := json.Marshal(map[string]interface{}{
+			"jsonrpc": "2.0", "id": i, "method": "tools/call",
+			"params": map[string]interface{}{"name": "read", "arguments": map[string]interface{}{"path": path}},
+		})
+		input = append(append(input, line...), '\n')
+	}
+	budget := tools.NewContextBudget()
+	var output bytes.Buffer
+	if err := server.ProcessStream(tools.WithContextBudget(context.Background(), budget), bytes.NewReader(input), &output); err != nil {
+		t.Fatal(err)
+	}
+
+	var totals []int
+	decoder := json.NewDecoder(&output)
+	for decoder.More() {
+		var resp struct {
+			Result struct {
+				Meta struct {
+					EstimatedTokens int `json:"estimated_tokens"`
+					SessionTokens   int `json:"session_tokens"`
+				} `json:"_meta"`
+			} `json:"result"`
+		}
+		if err := decoder.Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Result.Meta.EstimatedTokens < 300 {
+			t.Fatalf("estimated_tokens = %d for a 1200-byte file", resp.Result.Meta.EstimatedTokens)
+		}
+		totals = append(totals, resp.Result.Meta.SessionTokens)
+	}
+	if len(totals) != 2 || totals[1] <= totals[0] {
+		t.Fatalf("session totals should grow, got %v", totals)
+	}
+	if calls, tokens, _ := budget.Usage(); calls != 2 || tokens != totals[1] {
+		t.Errorf("budget holds %d calls and %d tokens, want 2 and %d", calls, tokens, totals[1])
+	}
+}
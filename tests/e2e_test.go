@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/alucardeht/may-la-mcp/internal/tools"
@@ -12,6 +13,7 @@ import (
 	"github.com/alucardeht/may-la-mcp/internal/tools/files"
 	"github.com/alucardeht/may-la-mcp/internal/tools/memory"
 	"github.com/alucardeht/may-la-mcp/internal/tools/search"
+	"github.com/alucardeht/may-la-mcp/tests/fixtures"
 )
 
 func TestAllToolsE2E(t *testing.T) {
@@ -30,7 +32,7 @@ func TestAllToolsE2E(t *testing.T) {
 		for _, tool := range files.GetTools() {
 			registry.Register(tool)
 		}
-		for _, tool := range docs.GetTools() {
+		for _, tool := range docs.GetTools(nil) {
 			registry.Register(tool)
 		}
 		for _, tool := range search.GetTools(nil) {
@@ -47,11 +49,23 @@ func TestAllToolsE2E(t *testing.T) {
 		}
 
 		names := registry.Names()
-		expectedCount := 21
+		expectedCount := 42
 		if len(names) != expectedCount {
 			t.Errorf("Expected %d tools, got %d: %v", expectedCount, len(names), names)
 		}
 
+		for _, entry := range registry.Dump() {
+			annotated, ok := entry.Tool.(tools.AnnotatedTool)
+			if !ok || annotated.Title() == "" || annotated.Annotations() == nil {
+				t.Errorf("tool %s has no title or annotations", entry.Name)
+			}
+			if strings.HasPrefix(entry.Name, "memory_") || strings.HasPrefix(entry.Name, "doc_") {
+				if _, ok := entry.Tool.(tools.OutputTool); !ok {
+					t.Errorf("tool %s has no output schema", entry.Name)
+				}
+			}
+		}
+
 		t.Logf("Registered %d tools: %v", len(names), names)
 	})
 
@@ -156,25 +170,15 @@ func TestAllToolsE2E(t *testing.T) {
 	})
 
 	t.Run("Search_GrepFindSymbolsReferences", func(t *testing.T) {
-		testDir := filepath.Join(tmpDir, "search-test")
-		os.MkdirAll(testDir, 0755)
-
-		goFile := filepath.Join(testDir, "sample.go")
-		os.WriteFile(goFile, []byte(`package main
-
-func HelloWorld() {
-	println("Hello")
-}
-
-func main() {
-	HelloWorld()
-}
-`), 0644)
+		repo := fixtures.Generate(t, fixtures.Spec{Seed: 1, Files: 3, SymbolsPerFile: 2})
+		testDir := repo.Root
+		goFile := repo.Abs(repo.Files[0])
+		symbol := repo.Files[0].Symbols[0]
 
 		searchTool := &search.SearchTool{}
 		input, _ := json.Marshal(map[string]interface{}{
 			"path":    testDir,
-			"pattern": "HelloWorld",
+			"pattern": symbol,
 		})
 		result, err := searchTool.Execute(ctx, input)
 		if err != nil {
@@ -206,7 +210,7 @@ func main() {
 		refsTool := search.NewReferencesTool(nil)
 		input, _ = json.Marshal(map[string]interface{}{
 			"path":   testDir,
-			"symbol": "HelloWorld",
+			"symbol": symbol,
 		})
 		result, err = refsTool.Execute(ctx, input)
 		if err != nil {
@@ -222,9 +226,11 @@ func main() {
 			t.Fatalf("Failed to initialize memory tools: %v", err)
 		}
 
-		var writeTool, readTool, listTool, searchTool, deleteTool tools.Tool
+		var categoriesTool, writeTool, readTool, listTool, searchTool, deleteTool tools.Tool
 		for _, tool := range memTools {
 			switch tool.Name() {
+			case "memory_categories":
+				categoriesTool = tool
 			case "memory_write":
 				writeTool = tool
 			case "memory_read":
@@ -239,6 +245,14 @@ func main() {
 		}
 
 		input, _ := json.Marshal(map[string]interface{}{
+			"action": "create",
+			"name":   "notes",
+		})
+		if _, err := categoriesTool.Execute(ctx, input); err != nil {
+			t.Fatalf("Memory category create failed: %v", err)
+		}
+
+		input, _ = json.Marshal(map[string]interface{}{
 			"name":     "test-memory",
 			"content":  "This is a test memory for E2E testing",
 			"category": "notes",
@@ -444,6 +458,61 @@ func TestToolMetadata(t *testing.T) {
 
 		t.Logf("All %d tools have valid metadata", len(allTools))
 	})
+
+	t.Run("Examples_MatchSchema", func(t *testing.T) {
+		memTools, err := memory.GetTools(filepath.Join(t.TempDir(), "examples-memory.db"))
+		if err != nil {
+			t.Fatalf("Failed to create memory tools: %v", err)
+		}
+
+		allTools := make([]tools.Tool, 0)
+		allTools = append(allTools, files.GetTools()...)
+		allTools = append(allTools, docs.GetTools(nil)...)
+		allTools = append(allTools, search.GetTools(nil)...)
+		allTools = append(allTools, memTools...)
+
+		checked := 0
+		for _, tool := range allTools {
+			examples := tools.ExamplesOf(tool)
+			if _, ok := tool.(tools.ExampleTool); ok && len(examples) == 0 {
+				t.Errorf("Tool %s implements Examples but has none", tool.Name())
+			}
+
+			var schema struct {
+				Properties map[string]json.RawMessage `json:"properties"`
+				Required   []string                   `json:"required"`
+			}
+			if err := json.Unmarshal(tool.Schema(), &schema); err != nil {
+				t.Fatalf("Tool %s has invalid JSON schema: %v", tool.Name(), err)
+			}
+			for _, example := range examples {
+				var args map[string]interface{}
+				if err := json.Unmarshal(example.Arguments, &args); err != nil {
+					t.Errorf("Tool %s example %q has invalid arguments: %v", tool.Name(), example.Description, err)
+					continue
+				}
+				for key := range args {
+					if _, ok := schema.Properties[key]; !ok {
+						t.Errorf("Tool %s example %q uses unknown argument %q", tool.Name(), example.Description, key)
+					}
+				}
+				for _, key := range schema.Required {
+					if _, ok := args[key]; !ok {
+						t.Errorf("Tool %s example %q is missing required argument %q", tool.Name(), example.Description, key)
+					}
+				}
+				if len(example.Result) > 0 && !json.Valid(example.Result) {
+					t.Errorf("Tool %s example %q has an invalid result", tool.Name(), example.Description)
+				}
+				checked++
+			}
+		}
+
+		if checked == 0 {
+			t.Error("Expected at least one tool example")
+		}
+		t.Logf("%d tool examples match their schemas", checked)
+	})
 }
 
 func TestMemoryIntegration(t *testing.T) {
@@ -460,10 +529,12 @@ func TestMemoryIntegration(t *testing.T) {
 		t.Fatalf("Failed to get memory tools: %v", err)
 	}
 
-	var writeMemory, readMemory, listMemory, searchMemory, deleteMemory tools.Tool
+	var categories, writeMemory, readMemory, listMemory, searchMemory, deleteMemory tools.Tool
 
 	for _, tool := range memTools {
 		switch tool.Name() {
+		case "memory_categories":
+			categories = tool
 		case "memory_write":
 			writeMemory = tool
 		case "memory_read":
@@ -477,10 +548,17 @@ func TestMemoryIntegration(t *testing.T) {
 		}
 	}
 
-	if writeMemory == nil || readMemory == nil || listMemory == nil || searchMemory == nil || deleteMemory == nil {
+	if categories == nil || writeMemory == nil || readMemory == nil || listMemory == nil || searchMemory == nil || deleteMemory == nil {
 		t.Fatal("Not all memory tools found")
 	}
 
+	for _, name := range []string{"testing", "multi-test"} {
+		input, _ := json.Marshal(map[string]interface{}{"action": "create", "name": name})
+		if _, err := categories.Execute(ctx, input); err != nil {
+			t.Fatalf("Category create failed for %s: %v", name, err)
+		}
+	}
+
 	t.Run("Memory_FullLifecycle", func(t *testing.T) {
 		writeInput, _ := json.Marshal(map[string]interface{}{
 			"name":     "lifecycle-test",
@@ -0,0 +1,357 @@
+// Package fixtures generates deterministic fake repositories for tests and
+// benchmarks: source files in several languages with a known set of
+// symbols, in a chosen mix of encodings and sizes, optionally with git
+// history. The same Spec always produces the same files, byte for byte,
+// and the same commit hashes.
+package fixtures
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Languages a Spec can ask for
+const (
+	Go         = "go"
+	TypeScript = "typescript"
+	Python     = "python"
+)
+
+// Encodings a Spec can ask for
+const (
+	UTF8    = "utf-8"
+	UTF8BOM = "utf-8-bom"
+	UTF16LE = "utf-16le"
+	Latin1  = "latin1"
+)
+
+// Spec describes a repository to generate. Languages and Encodings are
+// assigned to the files in turn.
+type Spec struct {
+	// Seed picks the names and padding; the same seed gives the same repo
+	Seed int64
+	// Files is the number of source files
+	Files int
+	// Languages defaults to Go only
+	Languages []string
+	// SymbolsPerFile is the number of top-level functions in each file,
+	// 5 by default
+	SymbolsPerFile int
+	// Encodings defaults to UTF-8 only
+	Encodings []string
+	// MinSize pads each file with comment lines to at least this many
+	// characters before encoding
+	MinSize int
+	// Dirs spreads the files over this many directories; with 0 or 1 they
+	// all go in the root
+	Dirs int
+	// Commits, when positive, makes the repo a git repository whose files
+	// are added over this many commits
+	Commits int
+}
+
+// File is a generated source file
+type File struct {
+	// Path is relative to the repo root, with forward slashes
+	Path     string
+	Language string
+	Encoding string
+	// Symbols are the names of the functions the file defines, each
+	// unique in the repo
+	Symbols []string
+	// Size is the size of the file on disk
+	Size int
+}
+
+// Repo is a generated repository
+type Repo struct {
+	Root  string
+	Files []File
+	// Commits are the commit hashes, oldest first
+	Commits []string
+}
+
+// Abs returns the absolute path of a file of the repo
+func (r *Repo) Abs(f File) string {
+	return filepath.Join(r.Root, filepath.FromSlash(f.Path))
+}
+
+// Symbols returns the number of symbols over all files
+func (r *Repo) Symbols() int {
+	n := 0
+	for _, f := range r.Files {
+		n += len(f.Symbols)
+	}
+	return n
+}
+
+// Generate creates the repository described by spec in a temporary
+// directory removed with the test. Tests that ask for history are skipped
+// when git is not installed.
+func Generate(t testing.TB, spec Spec) *Repo {
+	t.Helper()
+	if spec.Commits > 0 {
+		if _, err := exec.LookPath("git"); err != nil {
+			t.Skip("git is not installed")
+		}
+	}
+	repo, err := GenerateAt(t.TempDir(), spec)
+	if err != nil {
+		t.Fatalf("failed to generate the fixture repo: %v", err)
+	}
+	return repo
+}
+
+// GenerateAt creates the repository described by spec in dir, which should
+// be empty
+func GenerateAt(dir string, spec Spec) (*Repo, error) {
+	if spec.Files < 0 || spec.SymbolsPerFile < 0 || spec.Commits < 0 {
+		return nil, fmt.Errorf("negative counts in spec")
+	}
+	if len(spec.Languages) == 0 {
+		spec.Languages = []string{Go}
+	}
+	if len(spec.Encodings) == 0 {
+		spec.Encodings = []string{UTF8}
+	}
+	if spec.SymbolsPerFile == 0 {
+		spec.SymbolsPerFile = 5
+	}
+	for _, lang := range spec.Languages {
+		if _, ok := extensions[lang]; !ok {
+			return nil, fmt.Errorf("unsupported language %q", lang)
+		}
+	}
+	for _, enc := range spec.Encodings {
+		if !validEncodings[enc] {
+			return nil, fmt.Errorf("unsupported encoding %q", enc)
+		}
+	}
+
+	g := &generator{
+		rng:   rand.New(rand.NewSource(spec.Seed)),
+		spec:  spec,
+		width: len(fmt.Sprint(spec.Files * spec.SymbolsPerFile)),
+	}
+	repo := &Repo{Root: dir}
+	contents := make([][]byte, spec.Files)
+	for i := 0; i < spec.Files; i++ {
+		file, source := g.file(i)
+		data := encode(source, file.Encoding)
+		file.Size = len(data)
+		repo.Files = append(repo.Files, file)
+		contents[i] = data
+	}
+
+	if spec.Commits == 0 {
+		for i, file := range repo.Files {
+			if err := writeFile(repo.Abs(file), contents[i]); err != nil {
+				return nil, err
+			}
+		}
+		return repo, nil
+	}
+
+	commits, err := writeHistory(repo, contents, spec.Commits)
+	if err != nil {
+		return nil, err
+	}
+	repo.Commits = commits
+	return repo, nil
+}
+
+var extensions = map[string]string{Go: ".go", TypeScript: ".ts", Python: ".py"}
+
+var validEncodings = map[string]bool{UTF8: true, UTF8BOM: true, UTF16LE: true, Latin1: true}
+
+var (
+	verbs = []string{"parse", "load", "build", "render", "merge", "split", "check", "fetch", "store", "scan", "format", "resolve"}
+	nouns = []string{"widget", "record", "token", "buffer", "config", "header", "report", "cursor", "schema", "bundle", "index", "ledger"}
+	// padding words are all in Latin-1 so every encoding can hold them
+	padding = []string{"lorem", "ipsum", "café", "naïve", "résumé", "façade", "über", "señor", "déjà", "vu", "smörgåsbord", "jalapeño"}
+)
+
+type generator struct {
+	rng    *rand.Rand
+	spec   Spec
+	symbol int
+	// width pads the symbol numbers so no name is a prefix of another
+	width int
+}
+
+func (g *generator) pick(words []string) string {
+	return words[g.rng.Intn(len(words))]
+}
+
+func (g *generator) file(i int) (File, string) {
+	lang := g.spec.Languages[i%len(g.spec.Languages)]
+	file := File{
+		Path:     fmt.Sprintf("%s_%03d%s", g.pick(nouns), i, extensions[lang]),
+		Language: lang,
+		Encoding: g.spec.Encodings[i%len(g.spec.Encodings)],
+	}
+	pkg := "fixture"
+	if g.spec.Dirs > 1 {
+		pkg = fmt.Sprintf("pkg%02d", i%g.spec.Dirs)
+		file.Path = path.Join(pkg, file.Path)
+	}
+
+	comment := "// "
+	if lang == Python {
+		comment = "# "
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s%s: %s %s\n", comment, path.Base(file.Path), g.pick(padding), g.pick(padding))
+	if lang == Go {
+		fmt.Fprintf(&b, "package %s\n", pkg)
+	}
+	for s := 0; s < g.spec.SymbolsPerFile; s++ {
+		verb, noun := g.pick(verbs), g.pick(nouns)
+		g.symbol++
+		name := symbolName(lang, verb, noun, fmt.Sprintf("%0*d", g.width, g.symbol))
+		file.Symbols = append(file.Symbols, name)
+		writeFunction(&b, lang, name, g.rng.Intn(100))
+	}
+	for chars := utf8.RuneCountInString(b.String()); chars < g.spec.MinSize; {
+		line := comment
+		for w := 0; w < 8; w++ {
+			if w > 0 {
+				line += " "
+			}
+			line += g.pick(padding)
+		}
+		b.WriteString(line + "\n")
+		chars += utf8.RuneCountInString(line) + 1
+	}
+	return file, b.String()
+}
+
+// symbolName follows each language's convention: ParseWidget01 in Go,
+// parseWidget01 in TypeScript and parse_widget_01 in Python
+func symbolName(lang, verb, noun, n string) string {
+	switch lang {
+	case Go:
+		return title(verb) + title(noun) + n
+	case TypeScript:
+		return verb + title(noun) + n
+	default:
+		return verb + "_" + noun + "_" + n
+	}
+}
+
+func title(s string) string {
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+func writeFunction(b *strings.Builder, lang, name string, k int) {
+	switch lang {
+	case Go:
+		fmt.Fprintf(b, "\nfunc %s(x int) int {\n\treturn x + %d\n}\n", name, k)
+	case TypeScript:
+		fmt.Fprintf(b, "\nexport function %s(x: number): number {\n  return x + %d;\n}\n", name, k)
+	case Python:
+		fmt.Fprintf(b, "\n\ndef %s(x):\n    return x + %d\n", name, k)
+	}
+}
+
+func encode(s, encoding string) []byte {
+	switch encoding {
+	case UTF8BOM:
+		return append([]byte{0xEF, 0xBB, 0xBF}, s...)
+	case UTF16LE:
+		units := utf16.Encode([]rune(s))
+		data := make([]byte, 2, 2+2*len(units))
+		data[0], data[1] = 0xFF, 0xFE
+		for _, u := range units {
+			data = append(data, byte(u), byte(u>>8))
+		}
+		return data
+	case Latin1:
+		data := make([]byte, 0, len(s))
+		for _, r := range s {
+			data = append(data, byte(r))
+		}
+		return data
+	default:
+		return []byte(s)
+	}
+}
+
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// writeHistory adds the files over n commits with fixed authors and dates,
+// so the hashes only depend on the spec. Each commit also appends a line
+// to CHANGES.md, which keeps commits without files of their own non-empty.
+func writeHistory(repo *Repo, contents [][]byte, n int) ([]string, error) {
+	if err := runGit(repo.Root, 0, "init", "--quiet", "--initial-branch=main"); err != nil {
+		return nil, err
+	}
+
+	var changes bytes.Buffer
+	commits := make([]string, 0, n)
+	for c := 0; c < n; c++ {
+		start, end := c*len(repo.Files)/n, (c+1)*len(repo.Files)/n
+		for i := start; i < end; i++ {
+			if err := writeFile(repo.Abs(repo.Files[i]), contents[i]); err != nil {
+				return nil, err
+			}
+		}
+		fmt.Fprintf(&changes, "- change %d: %d files\n", c+1, end-start)
+		if err := writeFile(filepath.Join(repo.Root, "CHANGES.md"), changes.Bytes()); err != nil {
+			return nil, err
+		}
+
+		if err := runGit(repo.Root, c, "add", "--all"); err != nil {
+			return nil, err
+		}
+		if err := runGit(repo.Root, c, "commit", "--quiet", "--no-verify", "-m", fmt.Sprintf("Change %d", c+1)); err != nil {
+			return nil, err
+		}
+		out, err := gitCommand(repo.Root, c, "rev-parse", "HEAD").Output()
+		if err != nil {
+			return nil, fmt.Errorf("git rev-parse: %w", err)
+		}
+		commits = append(commits, strings.TrimSpace(string(out)))
+	}
+	return commits, nil
+}
+
+// gitCommand runs git isolated from the user's configuration, dated an
+// hour after the previous commit
+func gitCommand(dir string, commit int, args ...string) *exec.Cmd {
+	date := fmt.Sprintf("@%d +0000", 1700000000+commit*3600)
+	cmd := exec.Command("git", append([]string{"-c", "commit.gpgsign=false", "-c", "core.autocrlf=false"}, args...)...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_CONFIG_GLOBAL="+os.DevNull,
+		"GIT_CONFIG_NOSYSTEM=1",
+		"GIT_AUTHOR_NAME=Fixture",
+		"GIT_AUTHOR_EMAIL=fixture@example.com",
+		"GIT_COMMITTER_NAME=Fixture",
+		"GIT_COMMITTER_EMAIL=fixture@example.com",
+		"GIT_AUTHOR_DATE="+date,
+		"GIT_COMMITTER_DATE="+date,
+	)
+	return cmd
+}
+
+func runGit(dir string, commit int, args ...string) error {
+	if out, err := gitCommand(dir, commit, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("git %s: %w: %s", args[0], err, bytes.TrimSpace(out))
+	}
+	return nil
+}
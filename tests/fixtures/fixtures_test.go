@@ -0,0 +1,146 @@
+package fixtures
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+func TestGenerateIsDeterministic(t *testing.T) {
+	spec := Spec{
+		Seed:      42,
+		Files:     12,
+		Languages: []string{Go, TypeScript, Python},
+		Encodings: []string{UTF8, UTF8BOM, UTF16LE, Latin1},
+		MinSize:   2048,
+		Dirs:      3,
+	}
+	a, b := Generate(t, spec), Generate(t, spec)
+
+	if !reflect.DeepEqual(a.Files, b.Files) {
+		t.Fatalf("files differ between runs:\n%+v\n%+v", a.Files, b.Files)
+	}
+	for _, file := range a.Files {
+		x, err := os.ReadFile(a.Abs(file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		y, err := os.ReadFile(b.Abs(file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(x, y) {
+			t.Errorf("%s differs between runs", file.Path)
+		}
+	}
+
+	spec.Seed = 43
+	if c := Generate(t, spec); reflect.DeepEqual(a.Files, c.Files) {
+		t.Error("another seed generated the same files")
+	}
+}
+
+func TestGenerateCounts(t *testing.T) {
+	repo := Generate(t, Spec{Seed: 1, Files: 9, Languages: []string{Go, TypeScript, Python}, SymbolsPerFile: 4, Dirs: 2})
+
+	if len(repo.Files) != 9 || repo.Symbols() != 36 {
+		t.Fatalf("got %d files and %d symbols, want 9 and 36", len(repo.Files), repo.Symbols())
+	}
+	seen := make(map[string]bool)
+	for _, file := range repo.Files {
+		data, err := os.ReadFile(repo.Abs(file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if file.Size != len(data) {
+			t.Errorf("%s: size %d, file has %d bytes", file.Path, file.Size, len(data))
+		}
+		if !strings.HasSuffix(file.Path, extensions[file.Language]) {
+			t.Errorf("%s: wrong extension for %s", file.Path, file.Language)
+		}
+		if dir := filepath.Dir(file.Path); dir != "pkg00" && dir != "pkg01" {
+			t.Errorf("%s: unexpected directory", file.Path)
+		}
+		for _, symbol := range file.Symbols {
+			if seen[symbol] {
+				t.Errorf("symbol %s defined twice", symbol)
+			}
+			seen[symbol] = true
+			if strings.Count(string(data), symbol+"(") != 1 {
+				t.Errorf("%s: symbol %s not defined exactly once", file.Path, symbol)
+			}
+		}
+	}
+}
+
+func TestGenerateEncodings(t *testing.T) {
+	repo := Generate(t, Spec{Seed: 7, Files: 4, Encodings: []string{UTF8, UTF8BOM, UTF16LE, Latin1}, MinSize: 512})
+
+	for _, file := range repo.Files {
+		data, err := os.ReadFile(repo.Abs(file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var text string
+		switch file.Encoding {
+		case UTF8:
+			text = string(data)
+		case UTF8BOM:
+			if !bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}) {
+				t.Fatalf("%s: no UTF-8 BOM", file.Path)
+			}
+			text = string(data[3:])
+		case UTF16LE:
+			if !bytes.HasPrefix(data, []byte{0xFF, 0xFE}) || len(data)%2 != 0 {
+				t.Fatalf("%s: not UTF-16LE with a BOM", file.Path)
+			}
+			units := make([]uint16, 0, len(data)/2)
+			for i := 2; i < len(data); i += 2 {
+				units = append(units, uint16(data[i])|uint16(data[i+1])<<8)
+			}
+			text = string(utf16.Decode(units))
+		case Latin1:
+			runes := make([]rune, len(data))
+			for i, c := range data {
+				runes[i] = rune(c)
+			}
+			text = string(runes)
+		}
+		if len([]rune(text)) < 512 {
+			t.Errorf("%s: %d characters, want at least 512", file.Path, len([]rune(text)))
+		}
+		if !strings.Contains(text, "func "+file.Symbols[0]+"(") {
+			t.Errorf("%s (%s): symbol %s not found after decoding", file.Path, file.Encoding, file.Symbols[0])
+		}
+	}
+}
+
+func TestGenerateHistory(t *testing.T) {
+	spec := Spec{Seed: 3, Files: 5, Commits: 3}
+	a, b := Generate(t, spec), Generate(t, spec)
+
+	if len(a.Commits) != 3 {
+		t.Fatalf("got %d commits, want 3", len(a.Commits))
+	}
+	if !reflect.DeepEqual(a.Commits, b.Commits) {
+		t.Errorf("commit hashes differ between runs: %v %v", a.Commits, b.Commits)
+	}
+	for _, file := range a.Files {
+		if _, err := os.Stat(a.Abs(file)); err != nil {
+			t.Errorf("%s missing from the work tree: %v", file.Path, err)
+		}
+	}
+}
+
+func TestGenerateRejectsUnknownOptions(t *testing.T) {
+	if _, err := GenerateAt(t.TempDir(), Spec{Files: 1, Languages: []string{"cobol"}}); err == nil {
+		t.Error("expected an error for an unknown language")
+	}
+	if _, err := GenerateAt(t.TempDir(), Spec{Files: 1, Encodings: []string{"ebcdic"}}); err == nil {
+		t.Error("expected an error for an unknown encoding")
+	}
+}
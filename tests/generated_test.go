@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alucardeht/may-la-mcp/internal/tools/files"
+	"github.com/alucardeht/may-la-mcp/internal/tools/search"
+	"github.com/alucardeht/may-la-mcp/tests/fixtures"
+)
+
+// execute runs a tool and decodes its result into out
+func execute(t testing.TB, tool interface {
+	Execute(context.Context, json.RawMessage) (interface{}, error)
+}, args map[string]interface{}, out interface{}) {
+	t.Helper()
+	input, _ := json.Marshal(args)
+	result, err := tool.Execute(context.Background(), input)
+	if err != nil {
+		t.Fatalf("%v failed: %v", args, err)
+	}
+	data, _ := json.Marshal(result)
+	if err := json.Unmarshal(data, out); err != nil {
+		t.Fatalf("unexpected result %s: %v", data, err)
+	}
+}
+
+func TestSearchGeneratedRepo(t *testing.T) {
+	repo := fixtures.Generate(t, fixtures.Spec{
+		Seed:      2451,
+		Files:     60,
+		Languages: []string{fixtures.Go, fixtures.TypeScript, fixtures.Python},
+		Dirs:      4,
+		MinSize:   4096,
+	})
+	tool := &search.SearchTool{}
+
+	var summary search.SearchSummary
+	execute(t, tool, map[string]interface{}{
+		"path": repo.Root, "pattern": "return x + ", "recursive": true, "output_mode": "count",
+	}, &summary)
+	if summary.FileCount != len(repo.Files) || summary.Count != repo.Symbols() {
+		t.Errorf("got %d lines in %d files, want %d in %d", summary.Count, summary.FileCount, repo.Symbols(), len(repo.Files))
+	}
+
+	for _, file := range repo.Files[:10] {
+		symbol := file.Symbols[len(file.Symbols)-1]
+		var resp search.SearchResponse
+		execute(t, tool, map[string]interface{}{
+			"path": repo.Root, "pattern": symbol, "recursive": true, "case_sensitive": true,
+		}, &resp)
+		if resp.Count != 1 || len(resp.Matches) != 1 {
+			t.Errorf("%s: got %d matches, want 1", symbol, resp.Count)
+			continue
+		}
+		if got := resp.Matches[0].File; got != repo.Abs(file) && !strings.HasSuffix(got, filepath.FromSlash(file.Path)) {
+			t.Errorf("%s: found in %s, want %s", symbol, got, file.Path)
+		}
+	}
+}
+
+func TestReadGeneratedEncodings(t *testing.T) {
+	repo := fixtures.Generate(t, fixtures.Spec{
+		Seed:      2452,
+		Files:     8,
+		Encodings: []string{fixtures.UTF8, fixtures.UTF8BOM, fixtures.UTF16LE, fixtures.Latin1},
+		MinSize:   1024,
+	})
+	tool := &files.ReadTool{}
+
+	for _, file := range repo.Files {
+		var resp files.ReadResponse
+		execute(t, tool, map[string]interface{}{"path": repo.Abs(file)}, &resp)
+		for _, symbol := range file.Symbols {
+			if !strings.Contains(resp.Content, "func "+symbol+"(") {
+				t.Errorf("%s (%s): %s missing from the content read as %s", file.Path, file.Encoding, symbol, resp.Encoding)
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkSearchGeneratedRepo(b *testing.B) {
+	repo := fixtures.Generate(b, fixtures.Spec{
+		Seed:      2452,
+		Files:     500,
+		Languages: []string{fixtures.Go, fixtures.TypeScript, fixtures.Python},
+		Dirs:      20,
+		MinSize:   16 * 1024,
+	})
+	tool := &search.SearchTool{}
+	input, _ := json.Marshal(map[string]interface{}{
+		"path": repo.Root, "pattern": repo.Files[len(repo.Files)/2].Symbols[0], "recursive": true,
+	})
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := tool.Execute(context.Background(), input); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alucardeht/may-la-mcp/internal/mcp"
+	"github.com/alucardeht/may-la-mcp/internal/recording"
+	"github.com/alucardeht/may-la-mcp/internal/redact"
+	"github.com/alucardeht/may-la-mcp/internal/spill"
+	"github.com/alucardeht/may-la-mcp/internal/tools"
+	"github.com/alucardeht/may-la-mcp/internal/tools/docs"
+	"github.com/alucardeht/may-la-mcp/internal/tools/files"
+	"github.com/alucardeht/may-la-mcp/internal/tools/memory"
+	"github.com/alucardeht/may-la-mcp/internal/tools/search"
+)
+
+var updateSessions = flag.Bool("update-sessions", false, "rewrite the recorded responses of testdata/sessions with the current ones")
+
+// volatileFields differ between runs, so replays do not compare them
+var volatileFields = map[string]bool{
+	"time": true, "timestamp": true, "uptime": true, "duration": true,
+	"duration_ms": true, "latency_ms": true, "elapsed": true,
+	"modified": true, "mtime": true, "created_at": true, "updated_at": true,
+	"accessed_at": true, "indexed_at": true, "expires": true,
+	// token estimates follow the length of workspace paths
+	"estimated_tokens": true, "session_tokens": true,
+}
+
+// TestReplaySessions replays every session recorded with mayla --record
+// under testdata/sessions. A session's workspace, when it needs files, is
+// the directory of the same name with a .workspace suffix.
+func TestReplaySessions(t *testing.T) {
+	sessions, err := filepath.Glob(filepath.Join("testdata", "sessions", "*.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range sessions {
+		t.Run(strings.TrimSuffix(filepath.Base(path), ".jsonl"), func(t *testing.T) {
+			replaySession(t, path)
+		})
+	}
+}
+
+// replaySession feeds the client messages of a recording through a fresh
+// server and compares each response with the recorded one
+func replaySession(t *testing.T, path string) {
+	t.Helper()
+	header, entries, err := recording.Load(path)
+	if err != nil {
+		t.Fatalf("failed to load recording: %v", err)
+	}
+
+	workspace := t.TempDir()
+	if resolved, err := filepath.EvalSymlinks(workspace); err == nil {
+		workspace = resolved
+	}
+	if fixture := strings.TrimSuffix(path, ".jsonl") + ".workspace"; isDir(fixture) {
+		if err := os.CopyFS(workspace, os.DirFS(fixture)); err != nil {
+			t.Fatalf("failed to copy the workspace: %v", err)
+		}
+	}
+	toReplay := pathRewriter(header.Workspace, workspace)
+	toRecorded := pathRewriter(workspace, header.Workspace)
+
+	server := replayServer(t)
+	recorded := make(map[string]int)
+	for i, entry := range entries {
+		if id, ok := responseID(entry); ok {
+			recorded[id] = i
+		}
+	}
+
+	ctx := context.Background()
+	changed := false
+	for _, entry := range entries {
+		if entry.Direction != recording.In {
+			continue
+		}
+		var req mcp.Request
+		if err := json.Unmarshal(toReplay(entry.Message), &req); err != nil {
+			t.Fatalf("bad recorded request %s: %v", entry.Message, err)
+		}
+		resp := server.HandleRequest(ctx, &req)
+		if req.ID == nil {
+			continue
+		}
+
+		key := fmt.Sprint(req.ID)
+		i, ok := recorded[key]
+		if !ok {
+			t.Errorf("%s %s: no recorded response", req.Method, key)
+			continue
+		}
+		actual, err := json.Marshal(resp)
+		if err != nil {
+			t.Fatalf("%s %s: %v", req.Method, key, err)
+		}
+
+		want, got := normalize(t, toReplay(entries[i].Message)), normalize(t, actual)
+		if want == got {
+			continue
+		}
+		if *updateSessions {
+			entries[i].Message = toRecorded(actual)
+			changed = true
+			continue
+		}
+		t.Errorf("%s %s: response differs from the recording\nrecorded: %s\nreplayed: %s", req.Method, key, want, got)
+	}
+
+	if changed {
+		writeRecording(t, path, header, entries)
+	}
+}
+
+func replayServer(t *testing.T) *mcp.Server {
+	t.Helper()
+	registry := tools.NewRegistry()
+	registry.Register(tools.NewHealthTool())
+	for _, tool := range files.GetTools() {
+		registry.Register(tool)
+	}
+	for _, tool := range docs.GetTools(nil) {
+		registry.Register(tool)
+	}
+	for _, tool := range search.GetTools(nil) {
+		registry.Register(tool)
+	}
+	memTools, err := memory.GetTools(filepath.Join(t.TempDir(), "memory.db"))
+	if err != nil {
+		t.Fatalf("failed to open memory tools: %v", err)
+	}
+	for _, tool := range memTools {
+		registry.Register(tool)
+	}
+
+	server := mcp.NewServer(registry)
+	redactor, err := redact.New(redact.DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.SetRedactor(redactor)
+	results := spill.DefaultConfig()
+	results.Dir = t.TempDir()
+	server.SetSpill(spill.New(results))
+	return server
+}
+
+// responseID returns the ID of a recorded response; notifications have none
+func responseID(entry recording.Entry) (string, bool) {
+	if entry.Direction != recording.Out {
+		return "", false
+	}
+	var msg struct {
+		ID     interface{} `json:"id"`
+		Method string      `json:"method"`
+	}
+	if json.Unmarshal(entry.Message, &msg) != nil || msg.ID == nil || msg.Method != "" {
+		return "", false
+	}
+	return fmt.Sprint(msg.ID), true
+}
+
+// pathRewriter replaces one workspace path with another in a JSON message,
+// escaped as the message escapes it
+func pathRewriter(from, to string) func([]byte) []byte {
+	quoted := func(s string) []byte {
+		b, _ := json.Marshal(s)
+		return b[1 : len(b)-1]
+	}
+	if from == "" || from == to {
+		return func(b []byte) []byte { return b }
+	}
+	// a JSON string nested in another, like a tool result, escapes twice
+	once, twice := quoted(from), quoted(string(quoted(from)))
+	onceTo, twiceTo := quoted(to), quoted(string(quoted(to)))
+	return func(b []byte) []byte {
+		b = bytes.ReplaceAll(b, twice, twiceTo)
+		return bytes.ReplaceAll(b, once, onceTo)
+	}
+}
+
+// normalize decodes a message, including the JSON inside tool results,
+// blanks its volatile fields and encodes it again with sorted keys
+func normalize(t *testing.T, message []byte) string {
+	t.Helper()
+	var v interface{}
+	if err := json.Unmarshal(message, &v); err != nil {
+		t.Fatalf("bad message %s: %v", message, err)
+	}
+	out, _ := json.Marshal(blankVolatile(v))
+	return string(out)
+}
+
+func blankVolatile(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if volatileFields[key] {
+				v[key] = "<volatile>"
+			} else {
+				v[key] = blankVolatile(value)
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = blankVolatile(v[i])
+		}
+	case string:
+		trimmed := strings.TrimSpace(v)
+		if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+			var nested interface{}
+			if json.Unmarshal([]byte(trimmed), &nested) == nil {
+				return blankVolatile(nested)
+			}
+		}
+	}
+	return v
+}
+
+func writeRecording(t *testing.T, path string, header *recording.Header, entries []recording.Entry) {
+	t.Helper()
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	meta, _ := json.Marshal(header)
+	enc.Encode(recording.Entry{Direction: recording.Meta, Message: meta})
+	for _, entry := range entries {
+		enc.Encode(entry)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("failed to update %s: %v", path, err)
+	}
+	t.Logf("updated %s", path)
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
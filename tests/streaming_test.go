@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/alucardeht/may-la-mcp/internal/tools/search"
+	"github.com/alucardeht/may-la-mcp/tests/fixtures"
+)
+
+// TestStreamedSearchOverMCP checks that a search called with a progress
+// token and stream set reaches the client as progress notifications carrying
+// match batches, ended by a response summing them up
+func TestStreamedSearchOverMCP(t *testing.T) {
+	repo := fixtures.Generate(t, fixtures.Spec{Seed: 2456, Files: 40, Languages: []string{fixtures.Go}})
+	server := replayServer(t)
+
+	call := func(id int, token interface{}) []byte {
+		params := map[string]interface{}{
+			"name": "search",
+			"arguments": map[string]interface{}{
+				"pattern": "func ", "path": repo.Root, "recursive": true, "stream": true,
+			},
+		}
+		if token != nil {
+			params["_meta"] = map[string]interface{}{"progressToken": token}
+		}
+		line, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": id, "method": "tools/call", "params": params})
+		return append(line, '\n')
+	}
+
+	input := append(call(1, "search-1"), call(2, nil)...)
+	var output bytes.Buffer
+	if err := server.ProcessStream(context.Background(), bytes.NewReader(input), &output); err != nil {
+		t.Fatal(err)
+	}
+
+	type message struct {
+		ID     interface{} `json:"id"`
+		Method string      `json:"method"`
+		Params struct {
+			ProgressToken string `json:"progressToken"`
+			Progress      int    `json:"progress"`
+			Message       string `json:"message"`
+		} `json:"params"`
+		Result struct {
+			Content []struct {
+				Text string `json:"text"`
+			} `json:"content"`
+		} `json:"result"`
+	}
+
+	var streamed, notifications int
+	var responses []search.SearchResponse
+	scanner := bufio.NewScanner(&output)
+	scanner.Buffer(nil, 16<<20)
+	for scanner.Scan() {
+		var msg message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			t.Fatalf("bad message %s: %v", scanner.Bytes(), err)
+		}
+		if msg.Method == "notifications/progress" {
+			if len(responses) > 0 {
+				t.Error("progress notification after the response")
+			}
+			notifications++
+			if msg.Params.ProgressToken != "search-1" || msg.Params.Progress != notifications {
+				t.Errorf("unexpected progress params %+v", msg.Params)
+			}
+			var batch search.MatchBatch
+			if err := json.Unmarshal([]byte(msg.Params.Message), &batch); err != nil {
+				t.Fatalf("progress message is not a match batch: %v", err)
+			}
+			streamed += len(batch.Matches)
+			continue
+		}
+		var resp search.SearchResponse
+		if err := json.Unmarshal([]byte(msg.Result.Content[0].Text), &resp); err != nil {
+			t.Fatalf("bad search result: %v", err)
+		}
+		responses = append(responses, resp)
+	}
+
+	if len(responses) != 2 {
+		t.Fatalf("expected 2 responses, got %d", len(responses))
+	}
+	if notifications == 0 || !responses[0].Streamed || len(responses[0].Matches) != 0 || streamed != responses[0].Count {
+		t.Errorf("expected %d matches streamed in notifications, got %d in %d (summary %+v)", responses[0].Count, streamed, notifications, responses[0])
+	}
+	if responses[1].Streamed || len(responses[1].Matches) != responses[0].Count {
+		t.Errorf("expected the call without a progress token to return all %d matches, got %d", responses[0].Count, len(responses[1].Matches))
+	}
+}
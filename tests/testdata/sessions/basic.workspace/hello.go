@@ -0,0 +1,6 @@
+package greet
+
+// Hello returns a greeting for name
+func Hello(name string) string {
+	return "Hello, " + name
+}